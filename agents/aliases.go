@@ -9,9 +9,27 @@ type PlannerAgent = pattern.PlannerAgent
 // ReActAgent re-exports the ReAct agent implementation.
 type ReActAgent = pattern.ReActAgent
 
+// ExplainAgent re-exports the structured explanation agent.
+type ExplainAgent = pattern.ExplainAgent
+
+// QAAgent re-exports the cited question-answering agent.
+type QAAgent = pattern.QAAgent
+
+// ReviewAgent re-exports the diff review agent.
+type ReviewAgent = pattern.ReviewAgent
+
+// CodeReview re-exports the review agent's structured comments.
+type CodeReview = pattern.CodeReview
+
+// TestGenAgent re-exports the coverage-driven test generation agent.
+type TestGenAgent = pattern.TestGenAgent
+
 // ReflectionAgent re-exports the reviewer agent.
 type ReflectionAgent = pattern.ReflectionAgent
 
+// GraphAgent re-exports the agent that runs YAML-declared workflows.
+type GraphAgent = pattern.GraphAgent
+
 // ModeRuntimeProfile exposes the pattern runtime profile struct.
 type ModeRuntimeProfile = pattern.ModeRuntimeProfile
 
@@ -0,0 +1,33 @@
+package agents
+
+import "github.com/lexcodex/relurpify/framework"
+
+// ReadOnlyScope is the tool envelope of the analysis-only ask preset.
+var ReadOnlyScope = ToolScope{AllowRead: true}
+
+// ReadOnlyTools copies the tools of registry that fit ReadOnlyScope into a new
+// registry. Unlike the mode scoping, tools that declare no permissions and
+// tools that run commands are dropped too, so nothing in the result can
+// modify the workspace.
+func ReadOnlyTools(registry *framework.ToolRegistry) *framework.ToolRegistry {
+	scoped := framework.NewToolRegistry()
+	if registry == nil {
+		return scoped
+	}
+	for _, tool := range registry.All() {
+		if tool.Permissions().Permissions == nil || framework.IsExecTool(tool) {
+			continue
+		}
+		if toolAllowed(tool, ReadOnlyScope) {
+			_ = scoped.Register(tool)
+		}
+	}
+	return scoped
+}
+
+// NewAskAgent builds the ask preset: a question-answering agent limited to the
+// read-only tools of registry, for exploring a codebase without any risk of
+// changing it.
+func NewAskAgent(model framework.LanguageModel, registry *framework.ToolRegistry, memory framework.MemoryStore) *QAAgent {
+	return &QAAgent{Model: model, Tools: ReadOnlyTools(registry), Memory: memory}
+}
@@ -0,0 +1,49 @@
+package agents
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// undeclaredTool declares no permissions, like many plugin and MCP tools.
+type undeclaredTool struct{}
+
+func (undeclaredTool) Name() string                          { return "undeclared" }
+func (undeclaredTool) Description() string                   { return "undeclared" }
+func (undeclaredTool) Category() string                      { return "plugin" }
+func (undeclaredTool) Parameters() []framework.ToolParameter { return nil }
+func (undeclaredTool) Execute(context.Context, *framework.Context, map[string]interface{}) (*framework.ToolResult, error) {
+	return &framework.ToolResult{Success: true}, nil
+}
+func (undeclaredTool) IsAvailable(context.Context, *framework.Context) bool { return true }
+func (undeclaredTool) Permissions() framework.ToolPermissions               { return framework.ToolPermissions{} }
+
+// TestReadOnlyToolsDropsWriteAndExecTools checks the ask preset only sees
+// tools that declare read-only permissions.
+func TestReadOnlyToolsDropsWriteAndExecTools(t *testing.T) {
+	dir := t.TempDir()
+	registry := framework.NewToolRegistry()
+	for _, tool := range []framework.Tool{
+		&tools.ReadFileTool{BasePath: dir},
+		&tools.ListFilesTool{BasePath: dir},
+		&tools.WriteFileTool{BasePath: dir},
+		&tools.RunTestsTool{Workdir: dir},
+		undeclaredTool{},
+	} {
+		require.NoError(t, registry.Register(tool))
+	}
+
+	scoped := ReadOnlyTools(registry)
+	var names []string
+	for _, tool := range scoped.All() {
+		names = append(names, tool.Name())
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"file_list", "file_read"}, names)
+}
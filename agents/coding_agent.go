@@ -105,18 +105,26 @@ func (a *CodingAgent) Execute(ctx context.Context, task *framework.Task, state *
 	enriched.Context = cloneContext(task.Context)
 	enriched.Context["mode"] = string(profile.Name)
 	enriched.Context["restrictions"] = profile.Restrictions
-	enriched.Instruction = a.decorateInstruction(profile, task.Instruction)
+	if profile.Name != ModeExplain && profile.Name != ModeQuestion {
+		enriched.Instruction = a.decorateInstruction(profile, task.Instruction)
+	}
 	state.Set("coding_agent.mode", profile.Name)
 	result, err := delegate.Execute(ctx, &enriched, state)
 	if err != nil {
 		return nil, err
 	}
-	if final, ok := state.Get("react.final_output"); ok {
+	if final, ok := state.Get(finalOutputKey(profile.Name)); ok {
 		if result.Data == nil {
 			result.Data = map[string]any{}
 		}
 		result.Data["final_output"] = final
 	}
+	if sources := framework.ContextSources(state); len(sources) > 0 {
+		if result.Data == nil {
+			result.Data = map[string]any{}
+		}
+		result.Data["sources"] = sources
+	}
 	return result, nil
 }
 
@@ -126,6 +134,14 @@ func (a *CodingAgent) modeFromTask(task *framework.Task) Mode {
 	if task == nil {
 		return defaultMode
 	}
+	switch task.Type {
+	case framework.TaskTypeExplain:
+		return ModeExplain
+	case framework.TaskTypeQuestion:
+		return ModeQuestion
+	case framework.TaskTypeTestGeneration:
+		return ModeTest
+	}
 	if task.Metadata != nil {
 		if mode, ok := task.Metadata["mode"]; ok {
 			return Mode(strings.ToLower(mode))
@@ -153,13 +169,32 @@ func (a *CodingAgent) delegateForMode(mode Mode) (framework.Agent, error) {
 	if !ok {
 		return nil, fmt.Errorf("mode %s not configured", mode)
 	}
+	// Architect mode plans, so it takes the planning route; every other mode
+	// runs on the coding agent's own model.
+	model := a.Model
 	var agent framework.Agent
 	switch mode {
 	case ModeArchitect:
-		agent = &PlannerAgent{Model: a.Model, Tools: a.scopedTools(profile.ToolScope), Memory: a.Memory}
+		agent = &PlannerAgent{Model: a.Config.ModelFor(framework.ModelRolePlanning, a.Model), Tools: a.scopedTools(profile.ToolScope), Memory: a.Memory}
+	case ModeExplain:
+		agent = &ExplainAgent{Model: model, Tools: a.scopedTools(profile.ToolScope), Memory: a.Memory}
+	case ModeQuestion:
+		agent = &QAAgent{Model: model, Tools: a.scopedTools(profile.ToolScope), Memory: a.Memory}
+	case ModeTest:
+		tools := a.scopedTools(profile.ToolScope)
+		agent = &TestGenAgent{
+			Writer: &ReActAgent{
+				Model:       model,
+				Tools:       tools,
+				Memory:      a.Memory,
+				Mode:        string(profile.Name),
+				ModeProfile: convertModeRuntimeProfile(profile),
+			},
+			Tools: tools,
+		}
 	case ModeAsk:
 		agent = &ReActAgent{
-			Model:       a.Model,
+			Model:       model,
 			Tools:       a.scopedTools(profile.ToolScope),
 			Memory:      a.Memory,
 			Mode:        string(profile.Name),
@@ -167,7 +202,7 @@ func (a *CodingAgent) delegateForMode(mode Mode) (framework.Agent, error) {
 		}
 	case ModeDocument:
 		agent = &ReActAgent{
-			Model:       a.Model,
+			Model:       model,
 			Tools:       a.scopedTools(profile.ToolScope),
 			Memory:      a.Memory,
 			Mode:        string(profile.Name),
@@ -175,7 +210,7 @@ func (a *CodingAgent) delegateForMode(mode Mode) (framework.Agent, error) {
 		}
 	default:
 		agent = &ReActAgent{
-			Model:       a.Model,
+			Model:       model,
 			Tools:       a.scopedTools(profile.ToolScope),
 			Memory:      a.Memory,
 			Mode:        string(profile.Name),
@@ -185,6 +220,18 @@ func (a *CodingAgent) delegateForMode(mode Mode) (framework.Agent, error) {
 	if err := agent.Initialize(a.Config); err != nil {
 		return nil, err
 	}
+	// With cross-review, a second model critiques every mode that can
+	// change files, and rejected work runs again.
+	if a.Config != nil && a.Config.ReviewModel != nil && profile.ToolScope.AllowWrite {
+		rounds := a.Config.ReviewRounds
+		if rounds <= 0 {
+			rounds = framework.DefaultReviewRounds
+		}
+		agent = &ReflectionAgent{Reviewer: a.Config.ReviewModel, Delegate: agent, Rounds: rounds, CrossReview: true}
+		if err := agent.Initialize(a.Config); err != nil {
+			return nil, err
+		}
+	}
 	a.delegates[mode] = agent
 	return agent, nil
 }
@@ -248,6 +295,21 @@ func (a *CodingAgent) decorateInstruction(profile ModeProfile, instruction strin
 	return builder.String()
 }
 
+// finalOutputKey names the state key where the mode's delegate leaves its
+// user-facing answer.
+func finalOutputKey(mode Mode) string {
+	switch mode {
+	case ModeExplain:
+		return "explain.final_output"
+	case ModeQuestion:
+		return "qa.final_output"
+	case ModeTest:
+		return "testgen.final_output"
+	default:
+		return "react.final_output"
+	}
+}
+
 func convertModeRuntimeProfile(profile ModeProfile) ModeRuntimeProfile {
 	contextPrefs := ContextPreferences{
 		PreferredDetailLevel: profile.ContextProfile.PreferredDetailLevel,
@@ -16,6 +16,7 @@ type AgentCoordinator struct {
 	sharedContext *framework.SharedContext
 	contextBroker *ContextBroker
 	telemetry     framework.Telemetry
+	delegator     *framework.Delegator
 	Config        CoordinatorConfig
 }
 
@@ -85,6 +86,7 @@ func NewAgentCoordinator(telemetry framework.Telemetry, budget *framework.Contex
 			budget:         budget,
 		},
 		telemetry: telemetry,
+		delegator: framework.NewDelegator(),
 		Config: CoordinatorConfig{
 			MaxRecoveryAttempts: 3,
 			MaxReviewIterations: 5,
@@ -96,6 +98,7 @@ func NewAgentCoordinator(telemetry framework.Telemetry, budget *framework.Contex
 // RegisterAgent adds an agent to coordination pool.
 func (ac *AgentCoordinator) RegisterAgent(name string, agent framework.Agent) {
 	ac.agents[name] = agent
+	ac.delegator.Register(name, agent)
 }
 
 // Execute implements the agent execution interface, allowing the coordinator to be used as a sub-agent.
@@ -103,6 +106,10 @@ func (ac *AgentCoordinator) Execute(ctx context.Context, task *framework.Task, s
 	if task == nil {
 		return nil, fmt.Errorf("task is required")
 	}
+	// Delegates may spawn sub-tasks on the other registered agents.
+	if _, ok := framework.DelegatorFromContext(ctx); !ok {
+		ctx = framework.WithDelegator(ctx, ac.delegator)
+	}
 	
 	// If external state is provided, we sync it with our internal shared context
 	if state != nil {
@@ -115,7 +122,7 @@ func (ac *AgentCoordinator) Execute(ctx context.Context, task *framework.Task, s
 
 	switch strategy {
 	case "plan_execute":
-		result, err = ac.executePlanExecuteStrategy(task)
+		result, err = ac.executePlanExecuteStrategy(ctx, task)
 	case "explore_modify":
 		result, err = ac.executeExploreModifyStrategy(task)
 	case "review_iterate":
@@ -136,7 +143,7 @@ func (ac *AgentCoordinator) ExecuteTask(task *framework.Task) (*framework.Result
 	return ac.Execute(context.Background(), task, nil)
 }
 
-func (ac *AgentCoordinator) executePlanExecuteStrategy(task *framework.Task) (*framework.Result, error) {
+func (ac *AgentCoordinator) executePlanExecuteStrategy(ctx context.Context, task *framework.Task) (*framework.Result, error) {
 	indexer, ok := ac.agents["indexer"]
 	if ok {
 		ac.emitEvent("indexer_start")
@@ -155,7 +162,9 @@ func (ac *AgentCoordinator) executePlanExecuteStrategy(task *framework.Task) (*f
 	ac.emitEvent("planner_start")
 	ac.contextBroker.LoadSummariesIntoContext(ac.sharedContext.Context)
 	planTask := cloneTask(task)
-	planResult, err := planner.Execute(context.Background(), planTask, ac.sharedContext.Context)
+	// The planner runs under ctx so a plan reviewer attached by the caller
+	// sees the plan before the executor does.
+	planResult, err := planner.Execute(ctx, planTask, ac.sharedContext.Context)
 	if err != nil {
 		return nil, fmt.Errorf("planner failed: %w", err)
 	}
@@ -219,55 +228,31 @@ func (ac *AgentCoordinator) executePlanExecuteStrategy(task *framework.Task) (*f
 		}
 
 		// Execute ready steps
-		// If 1 step, run inline. If multiple, run parallel.
+		// If 1 step, run inline. If multiple, fan them out as sub-tasks and
+		// retry the ones that failed inline, with diagnosis.
 		if len(readySteps) == 1 {
 			step := readySteps[0]
-			if err := ac.executeSingleStep(context.Background(), step, executor, task, plan); err != nil {
+			if err := ac.executeSingleStep(ctx, step, executor, task, plan, nil); err != nil {
 				return nil, err
 			}
 			completedSteps[step.ID] = true
 		} else {
-			var wg sync.WaitGroup
-			errChan := make(chan error, len(readySteps))
-			
-			for _, step := range readySteps {
-				wg.Add(1)
-				step := step
-				go func() {
-					defer wg.Done()
-					// Clone context for isolation
-					branchCtx := ac.sharedContext.Context.Clone()
-					
-					// We need a thread-safe way to run the agent. 
-					// Agents are stateless usually, but we need to ensure we don't race on shared resources if tools aren't safe.
-					// Most framework tools are safe (file locks, etc).
-					
-					// Create a transient coordinator/wrapper to run this step?
-					// No, just call executor.Execute.
-					
-					sErr := ac.executeSingleStep(context.Background(), step, executor, task, plan)
-					if sErr != nil {
-						errChan <- sErr
-						return
-					}
-					
-					// In a real implementation we would merge branchCtx back.
-					// For now, we assume steps are modifying FS state (side effects), 
-					// so we don't strictly need to merge memory unless they output new variables.
-					// To be safe, we acquire lock and merge "step results" only?
-					// framework.Context.Merge handles this.
-					ac.sharedContext.Context.Merge(branchCtx)
-				}()
+			// Steps are spawned individually rather than with Run so one
+			// failing step does not cancel its siblings.
+			handles := make([]*framework.SubTaskHandle, len(readySteps))
+			for i, step := range readySteps {
+				handles[i] = ac.delegator.Spawn(ctx, ac.sharedContext.Context, framework.SubTask{Agent: "executor", Task: stepTask(step, task, plan)})
 			}
-			wg.Wait()
-			close(errChan)
-			for err := range errChan {
-				if err != nil {
-					return nil, err // Fail fast on parallel error
+			for i, step := range readySteps {
+				if err := handles[i].Wait().Err; err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					if err := ac.executeSingleStep(ctx, step, executor, task, plan, err); err != nil {
+						return nil, err
+					}
 				}
-			}
-			for _, s := range readySteps {
-				completedSteps[s.ID] = true
+				completedSteps[step.ID] = true
 			}
 		}
 	}
@@ -304,19 +289,33 @@ func (ac *AgentCoordinator) executePlanExecuteStrategy(task *framework.Task) (*f
 	return execResult, nil
 }
 
-func (ac *AgentCoordinator) executeSingleStep(ctx context.Context, step PlanStep, executor framework.Agent, originalTask *framework.Task, plan *PlanContext) error {
+// stepTask focuses a copy of the original task on one plan step.
+func stepTask(step PlanStep, originalTask *framework.Task, plan *PlanContext) *framework.Task {
 	stepTask := cloneTask(originalTask)
 	if stepTask.Context == nil {
 		stepTask.Context = make(map[string]any)
 	}
-	// Focus instruction
+	stepTask.ID = ""
 	stepTask.Instruction = fmt.Sprintf("Execute step %s: %s\nFiles: %v", step.ID, step.Description, step.Files)
 	stepTask.Context["plan"] = plan
 	stepTask.Context["current_step"] = step
-	
+	return stepTask
+}
+
+// executeSingleStep runs step with retries. firstErr is the failure of an
+// attempt already made elsewhere (a parallel sub-task), which counts as the
+// first attempt.
+func (ac *AgentCoordinator) executeSingleStep(ctx context.Context, step PlanStep, executor framework.Agent, originalTask *framework.Task, plan *PlanContext, firstErr error) error {
+	stepTask := stepTask(step, originalTask, plan)
+	stepTask.ID = originalTask.ID
+
 	// Retry logic per step
-	var stepErr error
-	for attempt := 0; attempt <= ac.Config.MaxRecoveryAttempts; attempt++ {
+	stepErr := firstErr
+	attempt := 0
+	if firstErr != nil {
+		attempt = 1
+	}
+	for ; attempt <= ac.Config.MaxRecoveryAttempts; attempt++ {
 		if attempt > 0 {
 			stepTask.Instruction += fmt.Sprintf("\nRetry %d: Last error: %v", attempt, stepErr)
 			
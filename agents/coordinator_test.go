@@ -0,0 +1,61 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// scriptedAgent runs a function as an agent.
+type scriptedAgent func(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error)
+
+func (f scriptedAgent) Initialize(*framework.Config) error   { return nil }
+func (f scriptedAgent) Capabilities() []framework.Capability { return nil }
+func (f scriptedAgent) BuildGraph(*framework.Task) (*framework.Graph, error) {
+	return framework.NewGraph(), nil
+}
+func (f scriptedAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	return f(ctx, task, state)
+}
+
+// TestCoordinatorRunsParallelStepsAsSubTasks checks independent plan steps
+// run as sub-tasks with their own IDs and that a failed one is retried
+// inline rather than failing the plan.
+func TestCoordinatorRunsParallelStepsAsSubTasks(t *testing.T) {
+	coordinator := NewAgentCoordinator(nil, nil)
+	coordinator.RegisterAgent("planner", scriptedAgent(func(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+		return &framework.Result{Success: true, Data: map[string]any{
+			"plan_steps": []PlanStep{{ID: "a", Description: "first"}, {ID: "b", Description: "second"}},
+		}}, nil
+	}))
+	var mu sync.Mutex
+	var depths []int
+	var ids []string
+	failedOnce := false
+	coordinator.RegisterAgent("executor", scriptedAgent(func(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		depths = append(depths, framework.SubTaskDepth(ctx))
+		ids = append(ids, task.ID)
+		if task.Context["current_step"].(PlanStep).ID == "b" && !failedOnce {
+			failedOnce = true
+			return nil, errors.New("flaky")
+		}
+		_, ok := framework.DelegatorFromContext(ctx)
+		require.True(t, ok, "executors can delegate further")
+		return &framework.Result{Success: true}, nil
+	}))
+
+	task := &framework.Task{ID: "job", Instruction: "do it", Metadata: map[string]string{"strategy": "plan_execute"}}
+	ctx := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "job"})
+	result, err := coordinator.Execute(ctx, task, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Data["steps_completed"])
+	require.ElementsMatch(t, []int{1, 1, 0}, depths, "two sub-tasks plus one inline retry")
+	require.ElementsMatch(t, []string{"job.sub1", "job.sub2", "job"}, ids)
+}
@@ -0,0 +1,5 @@
+// Package agents is the public entry point for the agent implementations.
+// Pattern agents from agents/pattern are re-exported here as aliases, so
+// embedders should import this package rather than agents/pattern, whose
+// layout may change between minor versions.
+package agents
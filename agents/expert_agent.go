@@ -16,6 +16,10 @@ type ExpertCoderAgent struct {
 	Tools  *framework.ToolRegistry
 	Memory framework.MemoryStore
 	Config *framework.Config
+	// SpecialistModels overrides the model for individual delegates, keyed by
+	// specialist name ("planner", "executor", "ask"). Unlisted specialists use
+	// the config's model routing, then Model.
+	SpecialistModels map[string]framework.LanguageModel
 
 	coordinator *AgentCoordinator
 }
@@ -27,12 +31,12 @@ func (a *ExpertCoderAgent) Initialize(cfg *framework.Config) error {
 		a.Tools = framework.NewToolRegistry()
 	}
 
-	planner := &PlannerAgent{Model: a.Model, Tools: a.Tools, Memory: a.Memory}
+	planner := &PlannerAgent{Model: a.specialistModel(cfg, "planner", framework.ModelRolePlanning), Tools: a.Tools, Memory: a.Memory}
 	if err := planner.Initialize(cfg); err != nil {
 		return err
 	}
 
-	coder := &CodingAgent{Model: a.Model, Tools: a.Tools, Memory: a.Memory}
+	coder := &CodingAgent{Model: a.specialistModel(cfg, "executor", framework.ModelRoleCoding), Tools: a.Tools, Memory: a.Memory}
 	if err := coder.Initialize(cfg); err != nil {
 		return err
 	}
@@ -44,7 +48,7 @@ func (a *ExpertCoderAgent) Initialize(cfg *framework.Config) error {
 	
 	// Register an 'ask' agent for self-healing diagnostics if available
 	asker := &ReActAgent{
-		Model: a.Model, 
+		Model: a.specialistModel(cfg, "ask", framework.ModelRoleReview), 
 		Tools: a.Tools, 
 		Memory: a.Memory,
 		Mode: "ask",
@@ -56,6 +60,15 @@ func (a *ExpertCoderAgent) Initialize(cfg *framework.Config) error {
 	return nil
 }
 
+// specialistModel resolves a delegate's model: explicit override first, then
+// the role routed in cfg, then the agent's default model.
+func (a *ExpertCoderAgent) specialistModel(cfg *framework.Config, name string, role framework.ModelRole) framework.LanguageModel {
+	if model, ok := a.SpecialistModels[name]; ok && model != nil {
+		return model
+	}
+	return cfg.ModelFor(role, a.Model)
+}
+
 // Capabilities merges planning and coding skills.
 func (a *ExpertCoderAgent) Capabilities() []framework.Capability {
 	return []framework.Capability{
@@ -11,6 +11,9 @@ const (
 	ModeAsk       Mode = "ask"
 	ModeDebug     Mode = "debug"
 	ModeDocument  Mode = "docs"
+	ModeExplain   Mode = "explain"
+	ModeQuestion  Mode = "question"
+	ModeTest      Mode = "test"
 	defaultMode        = ModeCode
 )
 
@@ -244,6 +247,115 @@ var ModeProfiles = map[Mode]ModeProfile{
 		},
 		PreferredStrategy: "balanced",
 	},
+	ModeExplain: {
+		Name:        ModeExplain,
+		Title:       "Explain Mode",
+		Description: "Structured explanations of files and symbols with navigable references.",
+		Temperature: 0.2,
+		Capabilities: []framework.Capability{
+			framework.CapabilityExplain,
+		},
+		ToolScope: ToolScope{
+			AllowRead:    true,
+			AllowWrite:   false,
+			AllowExecute: false,
+			AllowNetwork: false,
+		},
+		Restrictions: []string{
+			"No filesystem writes",
+			"No shell command execution",
+		},
+		ContextProfile: ContextProfile{
+			PreferredDetailLevel: DetailFull,
+			MaxWorkingSetSize:    3,
+			MaxConciseFiles:      20,
+			CompressionThreshold: 0.85,
+			MinHistorySize:       10,
+			SearchMode:           framework.SearchHybrid,
+			MaxSearchResults:     10,
+			PreloadDependencies:  true,
+			DependencyDepth:      1,
+			LoadASTUpfront:       true,
+			PreferSignatures:     false,
+			UseProjectMemory:     true,
+			UseGlobalMemory:      false,
+			MemoryQueryDepth:     5,
+		},
+		PreferredStrategy: "conservative",
+	},
+	ModeQuestion: {
+		Name:        ModeQuestion,
+		Title:       "Question Mode",
+		Description: "Answers workspace questions with file:line citations and refuses when evidence is weak.",
+		Temperature: 0.1,
+		Capabilities: []framework.Capability{
+			framework.CapabilityExplain,
+		},
+		ToolScope: ToolScope{
+			AllowRead:    true,
+			AllowWrite:   false,
+			AllowExecute: false,
+			AllowNetwork: false,
+		},
+		Restrictions: []string{
+			"No filesystem writes",
+			"Answers must cite workspace sources",
+		},
+		ContextProfile: ContextProfile{
+			PreferredDetailLevel: DetailConcise,
+			MaxWorkingSetSize:    0,
+			MaxConciseFiles:      20,
+			CompressionThreshold: 0.85,
+			MinHistorySize:       3,
+			SearchMode:           framework.SearchHybrid,
+			MaxSearchResults:     20,
+			PreloadDependencies:  false,
+			DependencyDepth:      0,
+			LoadASTUpfront:       true,
+			PreferSignatures:     true,
+			UseProjectMemory:     true,
+			UseGlobalMemory:      false,
+			MemoryQueryDepth:     5,
+		},
+		PreferredStrategy: "aggressive",
+	},
+	ModeTest: {
+		Name:        ModeTest,
+		Title:       "Test Mode",
+		Description: "Writes tests for functions the coverage report marks as untested, then reruns them.",
+		Temperature: 0.2,
+		Capabilities: []framework.Capability{
+			framework.CapabilityCode,
+			framework.CapabilityExecute,
+		},
+		ToolScope: ToolScope{
+			AllowRead:    true,
+			AllowWrite:   true,
+			AllowExecute: true,
+			AllowNetwork: false,
+		},
+		Restrictions: []string{
+			"Do not change the code under test",
+		},
+		ContextProfile: ContextProfile{
+			PreferredDetailLevel: DetailDetailed,
+			MaxWorkingSetSize:    8,
+			MaxConciseFiles:      20,
+			CompressionThreshold: 0.8,
+			MinHistorySize:       5,
+			SearchMode:           framework.SearchHybrid,
+			MaxSearchResults:     15,
+			PreloadPatterns:      []string{"**/*_test.go", "**/test_*.py"},
+			PreloadDependencies:  true,
+			DependencyDepth:      1,
+			LoadASTUpfront:       true,
+			PreferSignatures:     false,
+			UseProjectMemory:     true,
+			UseGlobalMemory:      false,
+			MemoryQueryDepth:     5,
+		},
+		PreferredStrategy: "balanced",
+	},
 }
 
 // GetStrategyForMode returns a context strategy tuned to the mode.
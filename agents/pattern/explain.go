@@ -0,0 +1,426 @@
+package pattern
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ExplainAgent produces structured explanations of a file or symbol. Loaded
+// source is stored on the shared Context so follow-up questions and jumps to
+// referenced definitions reuse it instead of reloading from scratch.
+type ExplainAgent struct {
+	Model  framework.LanguageModel
+	Tools  *framework.ToolRegistry
+	Memory framework.MemoryStore
+	Config *framework.Config
+}
+
+// Explanation is the structured answer returned by the explain pipeline.
+type Explanation struct {
+	Target     string                 `json:"target"`
+	Symbol     string                 `json:"symbol,omitempty"`
+	Summary    string                 `json:"summary"`
+	Details    []string               `json:"details"`
+	References []ExplanationReference `json:"references"`
+}
+
+// ExplanationReference links an explanation to a definition elsewhere in the
+// workspace. File/Line are filled in from the LSP when the model omits them.
+type ExplanationReference struct {
+	Symbol string `json:"symbol"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Note   string `json:"note,omitempty"`
+}
+
+func init() {
+	gob.Register(Explanation{})
+	gob.Register([]Explanation{})
+}
+
+const (
+	explainSourceLimit  = 12000
+	explainReadTool     = "file_read"
+	explainDefineTool   = "lsp_get_definition"
+	explainFallbackTool = "query_ast"
+)
+
+// Initialize configures the agent.
+func (a *ExplainAgent) Initialize(cfg *framework.Config) error {
+	a.Config = cfg
+	if a.Tools == nil {
+		a.Tools = framework.NewToolRegistry()
+	}
+	return nil
+}
+
+// Execute runs the explain workflow.
+func (a *ExplainAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph, err := a.BuildGraph(task)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
+		graph.SetTelemetry(cfg.Telemetry)
+	}
+	return graph.Execute(ctx, state)
+}
+
+// Capabilities enumerates features.
+func (a *ExplainAgent) Capabilities() []framework.Capability {
+	return []framework.Capability{framework.CapabilityExplain}
+}
+
+// BuildGraph wires load → jump → answer. Load and jump are no-ops when the
+// context already holds the requested source or no definition was requested.
+func (a *ExplainAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	if a.Model == nil {
+		return nil, fmt.Errorf("explain agent missing model")
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task required")
+	}
+	graph := framework.NewGraph()
+	load := &explainLoadNode{id: "explain_load", agent: a, task: task}
+	jump := &explainJumpNode{id: "explain_jump", agent: a, task: task}
+	answer := &explainAnswerNode{id: "explain_answer", agent: a, task: task}
+	done := framework.NewTerminalNode("explain_done")
+	for _, node := range []framework.Node{load, jump, answer, done} {
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(load.ID()); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(load.ID(), jump.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(jump.ID(), answer.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(answer.ID(), done.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+type explainLoadNode struct {
+	id    string
+	agent *ExplainAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *explainLoadNode) ID() string { return n.id }
+
+// Type marks the node as tool-backed.
+func (n *explainLoadNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute loads the target file unless the context already holds it.
+func (n *explainLoadNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("loading")
+	target := taskString(n.task, "explain_target")
+	symbol := taskString(n.task, "explain_symbol")
+	loaded := state.GetString("explain.target")
+	if target == "" {
+		target = loaded
+	}
+	if target == "" {
+		return nil, fmt.Errorf("explain target required")
+	}
+	if symbol != "" {
+		state.Set("explain.symbol", symbol)
+	}
+	if target == loaded && state.GetString("explain.source") != "" {
+		state.Set("explain.reused", true)
+		return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"target": target, "reused": true}}, nil
+	}
+	tool, ok := n.agent.Tools.Get(explainReadTool)
+	if !ok {
+		return nil, fmt.Errorf("tool %s not registered", explainReadTool)
+	}
+	res, err := tool.Execute(ctx, state, map[string]interface{}{"path": target})
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprint(res.Data["content"])
+	state.Set("explain.target", target)
+	state.Set("explain.source", source)
+	state.Set("explain.definitions", map[string]interface{}{})
+	state.Set("explain.history", []Explanation{})
+	state.Set("explain.reused", false)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"target": target, "reused": false}}, nil
+}
+
+type explainJumpNode struct {
+	id    string
+	agent *ExplainAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *explainJumpNode) ID() string { return n.id }
+
+// Type marks the node as tool-backed.
+func (n *explainJumpNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute resolves a requested definition through the LSP (falling back to the
+// AST index) and adds it to the loaded context.
+func (n *explainJumpNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	symbol := taskString(n.task, "jump_to")
+	if symbol == "" {
+		return &framework.Result{NodeID: n.id, Success: true}, nil
+	}
+	state.SetExecutionPhase("navigating")
+	definition, err := n.agent.resolveDefinition(ctx, state, symbol)
+	if err != nil {
+		return nil, err
+	}
+	defs, _ := state.Get("explain.definitions")
+	definitions, _ := defs.(map[string]interface{})
+	updated := make(map[string]interface{}, len(definitions)+1)
+	for k, v := range definitions {
+		updated[k] = v
+	}
+	updated[symbol] = definition
+	state.Set("explain.definitions", updated)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"symbol": symbol, "definition": definition}}, nil
+}
+
+// resolveDefinition locates the symbol inside the loaded source to derive an
+// LSP position, then asks the language server for the definition.
+func (a *ExplainAgent) resolveDefinition(ctx context.Context, state *framework.Context, symbol string) (map[string]interface{}, error) {
+	target := state.GetString("explain.target")
+	if tool, ok := a.Tools.Get(explainDefineTool); ok {
+		line, col := locateSymbol(state.GetString("explain.source"), symbol)
+		res, err := tool.Execute(ctx, state, map[string]interface{}{
+			"file":      target,
+			"symbol":    symbol,
+			"line":      line,
+			"character": col,
+		})
+		if err == nil && res != nil {
+			return normalizeData(res.Data), nil
+		}
+	}
+	if tool, ok := a.Tools.Get(explainFallbackTool); ok {
+		res, err := tool.Execute(ctx, state, map[string]interface{}{
+			"action": "get_signature",
+			"symbol": symbol,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return normalizeData(res.Data), nil
+	}
+	return nil, fmt.Errorf("no navigation tool available for %s", symbol)
+}
+
+type explainAnswerNode struct {
+	id    string
+	agent *ExplainAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *explainAnswerNode) ID() string { return n.id }
+
+// Type marks the node as an LLM step.
+func (n *explainAnswerNode) Type() framework.NodeType { return framework.NodeTypeSystem }
+
+// Execute asks the model for a structured explanation grounded in the loaded
+// source, prior answers, and any resolved definitions.
+func (n *explainAnswerNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("explaining")
+	target := state.GetString("explain.target")
+	symbol := state.GetString("explain.symbol")
+	source := state.GetString("explain.source")
+	if len(source) > explainSourceLimit {
+		source = source[:explainSourceLimit] + "\n... (truncated)"
+	}
+	var history []Explanation
+	if raw, ok := state.Get("explain.history"); ok {
+		history, _ = raw.([]Explanation)
+	}
+	defs, _ := state.Get("explain.definitions")
+
+	var prompt strings.Builder
+	prompt.WriteString("You are a code explanation assistant. Explain the code below accurately and concisely.\n")
+	fmt.Fprintf(&prompt, "File: %s\n", target)
+	if symbol != "" {
+		fmt.Fprintf(&prompt, "Focus symbol: %s\n", symbol)
+	}
+	fmt.Fprintf(&prompt, "Source:\n```\n%s\n```\n", source)
+	if definitions, ok := defs.(map[string]interface{}); ok && len(definitions) > 0 {
+		encoded, _ := json.Marshal(definitions)
+		fmt.Fprintf(&prompt, "Resolved definitions: %s\n", encoded)
+	}
+	if len(history) > 0 {
+		prompt.WriteString("Previous explanations:\n")
+		for _, prev := range history {
+			fmt.Fprintf(&prompt, "- %s\n", prev.Summary)
+		}
+	}
+	fmt.Fprintf(&prompt, "Question: %s\n", n.task.Instruction)
+	prompt.WriteString(`Return JSON {"summary": string, "details": [string], "references": [{"symbol": string, "file": string, "line": int, "note": string}]}.`)
+
+	model := ""
+	if n.agent.Config != nil {
+		model = n.agent.Config.Model
+	}
+	// A response that never matches the schema becomes the summary.
+	var explanation Explanation
+	resp, err := generateJSON(ctx, n.agent.Config, n.agent.Model, "explain.explanation", prompt.String(), explanationSchema, framework.LLMOptions{
+		Model:       model,
+		Temperature: 0.2,
+		MaxTokens:   1200,
+	}, &explanation)
+	var invalid *framework.StructuredOutputError
+	if err != nil && !errors.As(err, &invalid) {
+		return nil, err
+	}
+	state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
+	if invalid != nil || explanation.Summary == "" {
+		explanation = Explanation{Summary: strings.TrimSpace(resp.Text)}
+	}
+	explanation.Target = target
+	explanation.Symbol = symbol
+	n.agent.linkReferences(ctx, state, &explanation)
+	state.Set("explain.explanation", explanation)
+	state.Set("explain.final_output", formatExplanation(explanation))
+	state.Set("explain.history", append(history, explanation))
+	return &framework.Result{
+		NodeID:  n.id,
+		Success: true,
+		Data: map[string]interface{}{
+			"explanation":  explanation,
+			"final_output": formatExplanation(explanation),
+		},
+	}, nil
+}
+
+// linkReferences fills in missing file/line locations for references the
+// model mentioned but could not place.
+func (a *ExplainAgent) linkReferences(ctx context.Context, state *framework.Context, explanation *Explanation) {
+	for i := range explanation.References {
+		ref := &explanation.References[i]
+		if ref.Symbol == "" || (ref.File != "" && ref.Line > 0) {
+			continue
+		}
+		data, err := a.resolveDefinition(ctx, state, ref.Symbol)
+		if err != nil {
+			continue
+		}
+		if ref.File == "" {
+			if loc, ok := data["location"].(map[string]interface{}); ok {
+				ref.File = fmt.Sprint(loc["uri"])
+			} else if fileID, ok := data["file_id"]; ok {
+				ref.File = fmt.Sprint(fileID)
+			}
+		}
+		if ref.Line == 0 {
+			if line, ok := data["line"].(float64); ok {
+				ref.Line = int(line)
+			}
+		}
+	}
+}
+
+// normalizeData round-trips tool output through JSON so it only contains
+// maps, slices, and scalars that survive Context.Clone.
+func normalizeData(data map[string]interface{}) map[string]interface{} {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return map[string]interface{}{}
+	}
+	return normalized
+}
+
+// explanationSchema is the JSON schema of the explanation the model returns.
+var explanationSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"summary"},
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{"type": "string"},
+		"details": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+		"references": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"symbol"},
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{"type": "string"},
+					"file":   map[string]interface{}{"type": "string"},
+					"line":   map[string]interface{}{"type": "integer"},
+					"note":   map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	},
+}
+
+// formatExplanation renders the explanation for text-only surfaces.
+func formatExplanation(explanation Explanation) string {
+	var b strings.Builder
+	b.WriteString(explanation.Summary)
+	for _, detail := range explanation.Details {
+		fmt.Fprintf(&b, "\n- %s", detail)
+	}
+	if len(explanation.References) > 0 {
+		b.WriteString("\n\nReferences:")
+		for _, ref := range explanation.References {
+			loc := ref.File
+			if ref.Line > 0 {
+				loc = fmt.Sprintf("%s:%d", ref.File, ref.Line)
+			}
+			fmt.Fprintf(&b, "\n- %s %s", ref.Symbol, loc)
+			if ref.Note != "" {
+				fmt.Fprintf(&b, " (%s)", ref.Note)
+			}
+		}
+	}
+	return b.String()
+}
+
+// locateSymbol returns the zero-based line/character of the first occurrence
+// of symbol in source, which is good enough to seed an LSP definition query.
+func locateSymbol(source, symbol string) (int, int) {
+	for i, line := range strings.Split(source, "\n") {
+		if col := strings.Index(line, symbol); col >= 0 {
+			return i, col
+		}
+	}
+	return 0, 0
+}
+
+// taskString reads a string value from the task context or metadata.
+func taskString(task *framework.Task, key string) string {
+	if task == nil {
+		return ""
+	}
+	if task.Context != nil {
+		if raw, ok := task.Context[key]; ok && raw != nil {
+			if value := strings.TrimSpace(fmt.Sprint(raw)); value != "" {
+				return value
+			}
+		}
+	}
+	if task.Metadata != nil {
+		return strings.TrimSpace(task.Metadata[key])
+	}
+	return ""
+}
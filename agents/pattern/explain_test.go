@@ -0,0 +1,57 @@
+package pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type countingReadTool struct {
+	stubTool
+	reads *int
+}
+
+// Execute returns fixed source and counts how often the file was loaded.
+func (t countingReadTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	*t.reads++
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"content": "package main\n\nfunc Run() {}\n"}}, nil
+}
+
+// TestExplainAgentReusesLoadedContext ensures follow-ups skip reloading.
+func TestExplainAgentReusesLoadedContext(t *testing.T) {
+	reads := 0
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(countingReadTool{stubTool: stubTool{name: "file_read"}, reads: &reads}))
+	llm := &stubLLM{responses: []*framework.LLMResponse{
+		{Text: `{"summary":"Defines Run.","details":["entry point"],"references":[{"symbol":"Run","file":"main.go","line":3}]}`},
+		{Text: "Run does nothing yet."},
+	}}
+	agent := &ExplainAgent{Model: llm, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{Model: "test-model"}))
+
+	shared := framework.NewContext()
+	first := &framework.Task{Type: framework.TaskTypeExplain, Instruction: "Explain main.go", Context: map[string]any{"explain_target": "main.go"}}
+	state := shared.Clone()
+	_, err := agent.Execute(context.Background(), first, state)
+	assert.NoError(t, err)
+	shared.Merge(state)
+
+	explanation, ok := state.Get("explain.explanation")
+	assert.True(t, ok)
+	assert.Equal(t, "Defines Run.", explanation.(Explanation).Summary)
+	assert.Len(t, explanation.(Explanation).References, 1)
+
+	followUp := &framework.Task{Type: framework.TaskTypeExplain, Instruction: "What does Run do?"}
+	state = shared.Clone()
+	_, err = agent.Execute(context.Background(), followUp, state)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, reads)
+	reused, _ := state.Get("explain.reused")
+	assert.Equal(t, true, reused)
+	history, _ := state.Get("explain.history")
+	assert.Len(t, history.([]Explanation), 2)
+}
@@ -0,0 +1,87 @@
+package pattern
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// GraphAgent runs a workflow declared in YAML (see framework.GraphSpec)
+// instead of one wired in Go. Human nodes ask Config.HITL for approval.
+type GraphAgent struct {
+	Model  framework.LanguageModel
+	Tools  *framework.ToolRegistry
+	Memory framework.MemoryStore
+	Config *framework.Config
+	// Spec is the declared workflow; it defaults to Config.AgentSpec.Graph.
+	Spec *framework.GraphSpec
+}
+
+// Initialize stores configuration and picks up the manifest's graph.
+func (a *GraphAgent) Initialize(cfg *framework.Config) error {
+	a.Config = cfg
+	if a.Tools == nil {
+		a.Tools = framework.NewToolRegistry()
+	}
+	if a.Spec == nil && cfg != nil && cfg.AgentSpec != nil {
+		a.Spec = cfg.AgentSpec.Graph
+	}
+	if a.Spec == nil {
+		return fmt.Errorf("graph agent %s has no graph", a.name())
+	}
+	return a.Spec.Validate()
+}
+
+// Capabilities reports what declared node types allow.
+func (a *GraphAgent) Capabilities() []framework.Capability {
+	caps := []framework.Capability{framework.CapabilityExecute}
+	if a.Spec == nil {
+		return caps
+	}
+	for _, node := range a.Spec.Nodes {
+		if node.Type == framework.GraphNodeHuman {
+			return append(caps, framework.CapabilityHumanInLoop)
+		}
+	}
+	return caps
+}
+
+// BuildGraph loads the declared workflow for task.
+func (a *GraphAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	loader := &framework.GraphLoader{Model: a.Model, Tools: a.Tools}
+	if a.Config != nil {
+		loader.HITL = a.Config.HITL
+	}
+	return loader.Load(a.Spec, task)
+}
+
+// Execute runs the workflow and reports the last node output as the final
+// output.
+func (a *GraphAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph, err := a.BuildGraph(task)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
+		graph.SetTelemetry(cfg.Telemetry)
+	}
+	result, err := graph.Execute(ctx, state)
+	if err != nil {
+		return result, err
+	}
+	if output, ok := state.Get("graph.final_output"); ok {
+		if result.Data == nil {
+			result.Data = make(map[string]any)
+		}
+		result.Data["final_output"] = output
+	}
+	return result, nil
+}
+
+func (a *GraphAgent) name() string {
+	if a.Config != nil && a.Config.Name != "" {
+		return a.Config.Name
+	}
+	return "agent"
+}
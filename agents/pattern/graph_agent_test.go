@@ -0,0 +1,80 @@
+package pattern
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type answeringHITL struct {
+	approve  bool
+	requests []framework.PermissionRequest
+}
+
+func (h *answeringHITL) RequestPermission(ctx context.Context, req framework.PermissionRequest) (*framework.PermissionGrant, error) {
+	h.requests = append(h.requests, req)
+	if !h.approve {
+		return nil, errors.New("denied")
+	}
+	return &framework.PermissionGrant{Permission: req.Permission}, nil
+}
+
+func reviewGraphSpec() *framework.GraphSpec {
+	return &framework.GraphSpec{
+		Start: "draft",
+		Nodes: []framework.GraphNodeSpec{
+			{ID: "draft", Type: framework.GraphNodeLLM, Prompt: "Draft: {{.Instruction}}"},
+			{ID: "approve", Type: framework.GraphNodeHuman, Prompt: `Ship "{{state "draft.output"}}"?`},
+			{ID: "publish", Type: framework.GraphNodeTool, Tool: "echo", Args: map[string]any{"value": `{{state "draft.output"}}`}},
+			{ID: "done", Type: framework.GraphNodeTerminal},
+		},
+		Edges: []framework.GraphEdgeSpec{
+			{From: "draft", To: "approve"},
+			{From: "approve", To: "publish", When: "approve.approved"},
+			{From: "approve", To: "done", When: "not approve.approved"},
+			{From: "publish", To: "done"},
+		},
+	}
+}
+
+// TestGraphAgentRunsDeclaredWorkflow checks prompts render the task and
+// earlier outputs, human approval branches, and tool output becomes final.
+func TestGraphAgentRunsDeclaredWorkflow(t *testing.T) {
+	for _, approve := range []bool{true, false} {
+		hitl := &answeringHITL{approve: approve}
+		tools := framework.NewToolRegistry()
+		assert.NoError(t, tools.Register(stubTool{name: "echo"}))
+		agent := &GraphAgent{
+			Model: &stubLLM{responses: []*framework.LLMResponse{{Text: "v2 notes"}}},
+			Tools: tools,
+			Spec:  reviewGraphSpec(),
+		}
+		assert.NoError(t, agent.Initialize(&framework.Config{HITL: hitl}))
+
+		state := framework.NewContext()
+		result, err := agent.Execute(context.Background(), &framework.Task{Instruction: "release"}, state)
+		assert.NoError(t, err)
+		assert.Len(t, hitl.requests, 1)
+		assert.Equal(t, `Ship "v2 notes"?`, hitl.requests[0].Justification)
+
+		published, ok := state.Get("publish.output")
+		assert.Equal(t, approve, ok)
+		if approve {
+			assert.Equal(t, map[string]interface{}{"echo": "v2 notes"}, published)
+			assert.Equal(t, published, result.Data["final_output"])
+		} else {
+			assert.Equal(t, "v2 notes", result.Data["final_output"])
+		}
+	}
+}
+
+// TestGraphAgentRequiresGraph checks agents without a declared workflow fail
+// to initialize.
+func TestGraphAgentRequiresGraph(t *testing.T) {
+	agent := &GraphAgent{Model: &stubLLM{}}
+	assert.Error(t, agent.Initialize(&framework.Config{AgentSpec: &framework.AgentRuntimeSpec{}}))
+}
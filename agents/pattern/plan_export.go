@@ -0,0 +1,237 @@
+package pattern
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// PlanExportFormat selects how the planner hands its plan to humans instead of
+// executing it.
+type PlanExportFormat string
+
+const (
+	// PlanExportNone executes the plan (default behaviour).
+	PlanExportNone PlanExportFormat = ""
+	// PlanExportMarkdown renders the plan as a markdown task list.
+	PlanExportMarkdown PlanExportFormat = "markdown"
+	// PlanExportGitHub files one issue per step plus an epic linking them.
+	PlanExportGitHub PlanExportFormat = "github"
+)
+
+// PlanIssue is a single exported work item.
+type PlanIssue struct {
+	StepID    int      `json:"step_id"`
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Labels    []string `json:"labels,omitempty"`
+	DependsOn []int    `json:"depends_on,omitempty"`
+	Number    int      `json:"number,omitempty"`
+	URL       string   `json:"url,omitempty"`
+}
+
+func init() {
+	gob.Register(PlanIssue{})
+	gob.Register([]PlanIssue{})
+}
+
+const plannerIssueTool = "github_create_issue"
+
+// exportFormat resolves the export option from the task, falling back to the
+// agent default.
+func (a *PlannerAgent) exportFormat(task *framework.Task) PlanExportFormat {
+	if task != nil && task.Context != nil {
+		if raw, ok := task.Context["plan_export"]; ok && raw != nil {
+			return PlanExportFormat(strings.ToLower(strings.TrimSpace(fmt.Sprint(raw))))
+		}
+	}
+	return a.Export
+}
+
+type plannerExportNode struct {
+	id     string
+	agent  *PlannerAgent
+	task   *framework.Task
+	format PlanExportFormat
+}
+
+// ID returns the export node identifier.
+func (n *plannerExportNode) ID() string { return n.id }
+
+// Type marks the export as a tool-backed step since GitHub export files issues.
+func (n *plannerExportNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute converts the plan into work items. Markdown export is always
+// available; GitHub export needs the issue tool and otherwise falls back to
+// markdown so the breakdown is never lost.
+func (n *plannerExportNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("exporting")
+	value, ok := state.Get("planner.plan")
+	if !ok {
+		return nil, fmt.Errorf("plan not available")
+	}
+	plan, _ := value.(framework.Plan)
+	issues := planIssues(plan)
+	format := n.format
+	var epic *PlanIssue
+	if format == PlanExportGitHub {
+		tool, ok := n.agent.Tools.Get(plannerIssueTool)
+		if ok {
+			var err error
+			epic, err = fileIssues(ctx, state, tool, plan, issues)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			format = PlanExportMarkdown
+		}
+	}
+	markdown := renderPlanMarkdown(plan, issues, epic)
+	state.Set("planner.issues", issues)
+	state.Set("planner.export", markdown)
+	data := map[string]interface{}{
+		"format":       string(format),
+		"issues":       issues,
+		"final_output": markdown,
+	}
+	if epic != nil {
+		data["epic"] = *epic
+	}
+	return &framework.Result{NodeID: n.id, Success: true, Data: data}, nil
+}
+
+// planIssues builds one work item per plan step, ordered by step ID.
+func planIssues(plan framework.Plan) []PlanIssue {
+	steps := append([]framework.PlanStep(nil), plan.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].ID < steps[j].ID })
+	issues := make([]PlanIssue, 0, len(steps))
+	for _, step := range steps {
+		issue := PlanIssue{
+			StepID:    step.ID,
+			Title:     step.Description,
+			DependsOn: append([]int(nil), plan.Dependencies[step.ID]...),
+		}
+		if issue.Title == "" {
+			issue.Title = fmt.Sprintf("Step %d", step.ID)
+		}
+		var body strings.Builder
+		if step.Expected != "" {
+			body.WriteString("**Expected:** " + step.Expected + "\n\n")
+		}
+		if step.Verification != "" {
+			body.WriteString("**Verification:** " + step.Verification + "\n\n")
+		}
+		if step.Tool != "" {
+			body.WriteString(fmt.Sprintf("**Suggested tool:** `%s`\n\n", step.Tool))
+		}
+		if file := planStepFile(step); file != "" {
+			body.WriteString(fmt.Sprintf("**File:** `%s`\n\n", file))
+		}
+		issue.Body = body.String()
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// fileIssues creates the step issues in order, rewriting dependency references
+// to issue numbers, then files an epic whose task list links every step.
+func fileIssues(ctx context.Context, state *framework.Context, tool framework.Tool, plan framework.Plan, issues []PlanIssue) (*PlanIssue, error) {
+	numbers := make(map[int]int)
+	for i := range issues {
+		body := issues[i].Body
+		var deps []string
+		for _, dep := range issues[i].DependsOn {
+			if number, ok := numbers[dep]; ok {
+				deps = append(deps, fmt.Sprintf("#%d", number))
+			} else {
+				deps = append(deps, fmt.Sprintf("step %d", dep))
+			}
+		}
+		if len(deps) > 0 {
+			body += "Depends on " + strings.Join(deps, ", ") + "\n"
+		}
+		number, url, err := createIssue(ctx, state, tool, issues[i].Title, body, issues[i].Labels)
+		if err != nil {
+			return nil, err
+		}
+		issues[i].Body = body
+		issues[i].Number = number
+		issues[i].URL = url
+		numbers[issues[i].StepID] = number
+	}
+	goal := plan.Goal
+	if goal == "" {
+		goal = "Agent plan"
+	}
+	epic := &PlanIssue{Title: goal}
+	var body strings.Builder
+	body.WriteString("Work breakdown generated by the planner.\n\n")
+	for _, issue := range issues {
+		body.WriteString(fmt.Sprintf("- [ ] %s\n", issueRef(issue)))
+	}
+	epic.Body = body.String()
+	number, url, err := createIssue(ctx, state, tool, epic.Title, epic.Body, epic.Labels)
+	if err != nil {
+		return nil, err
+	}
+	epic.Number = number
+	epic.URL = url
+	return epic, nil
+}
+
+func createIssue(ctx context.Context, state *framework.Context, tool framework.Tool, title, body string, labels []string) (int, string, error) {
+	res, err := tool.Execute(ctx, state, map[string]interface{}{
+		"title":  title,
+		"body":   body,
+		"labels": labels,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("create issue %q: %w", title, err)
+	}
+	if res == nil || !res.Success {
+		return 0, "", fmt.Errorf("create issue %q failed", title)
+	}
+	number, _ := res.Data["number"].(int)
+	url, _ := res.Data["url"].(string)
+	return number, url, nil
+}
+
+// issueRef prefers the filed issue number and falls back to the step title.
+func issueRef(issue PlanIssue) string {
+	if issue.Number > 0 {
+		return fmt.Sprintf("#%d", issue.Number)
+	}
+	return issue.Title
+}
+
+// renderPlanMarkdown renders the plan as a markdown task list.
+func renderPlanMarkdown(plan framework.Plan, issues []PlanIssue, epic *PlanIssue) string {
+	var builder strings.Builder
+	goal := plan.Goal
+	if goal == "" {
+		goal = "Plan"
+	}
+	builder.WriteString("## " + goal + "\n\n")
+	if epic != nil && epic.URL != "" {
+		builder.WriteString("Epic: " + epic.URL + "\n\n")
+	}
+	for _, issue := range issues {
+		line := fmt.Sprintf("- [ ] %d. %s", issue.StepID, issue.Title)
+		if issue.URL != "" {
+			line += " (" + issue.URL + ")"
+		}
+		if len(issue.DependsOn) > 0 {
+			deps := make([]string, 0, len(issue.DependsOn))
+			for _, dep := range issue.DependsOn {
+				deps = append(deps, fmt.Sprint(dep))
+			}
+			line += " — after " + strings.Join(deps, ", ")
+		}
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,62 @@
+package pattern
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type issueTool struct {
+	stubTool
+	created *[]map[string]interface{}
+}
+
+// Execute records the issue and returns a sequential issue number.
+func (t issueTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	*t.created = append(*t.created, args)
+	number := len(*t.created)
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"number": number,
+		"url":    fmt.Sprintf("https://github.com/acme/repo/issues/%d", number),
+	}}, nil
+}
+
+const exportPlanJSON = `{"goal":"Add caching","steps":[{"id":1,"description":"Add cache type","tool":"file_write","params":{"path":"cache.go"}},{"id":2,"description":"Wire cache into handler"}],"dependencies":{"2":[1]}}`
+
+func TestPlannerExportsGitHubIssues(t *testing.T) {
+	var created []map[string]interface{}
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(issueTool{stubTool: stubTool{name: plannerIssueTool}, created: &created}))
+	agent := &PlannerAgent{Model: &stubLLM{responses: []*framework.LLMResponse{{Text: exportPlanJSON}}}, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	task := &framework.Task{Instruction: "Add caching", Context: map[string]any{"plan_export": "github"}}
+	_, err := agent.Execute(context.Background(), task, state)
+	assert.NoError(t, err)
+
+	assert.Len(t, created, 3)
+	assert.Contains(t, created[1]["body"], "Depends on #1")
+	assert.Equal(t, "Add caching", created[2]["title"])
+	assert.Contains(t, created[2]["body"], "- [ ] #1\n- [ ] #2")
+	raw, _ := state.Get("planner.issues")
+	assert.Equal(t, 2, raw.([]PlanIssue)[1].Number)
+}
+
+func TestPlannerMarkdownExportSkipsExecution(t *testing.T) {
+	agent := &PlannerAgent{Model: &stubLLM{responses: []*framework.LLMResponse{{Text: exportPlanJSON}}}, Export: PlanExportMarkdown}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Instruction: "Add caching"}, state)
+	assert.NoError(t, err)
+
+	markdown, _ := state.Get("planner.export")
+	assert.Equal(t, "## Add caching\n\n- [ ] 1. Add cache type\n- [ ] 2. Wire cache into handler — after 1\n", markdown)
+	_, executed := state.Get("planner.results")
+	assert.False(t, executed)
+}
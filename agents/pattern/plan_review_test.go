@@ -0,0 +1,55 @@
+package pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type planReviewFunc func(plan framework.Plan) (framework.Plan, error)
+
+// ReviewPlan delegates to the wrapped function.
+func (f planReviewFunc) ReviewPlan(ctx context.Context, task *framework.Task, plan framework.Plan) (framework.Plan, error) {
+	return f(plan)
+}
+
+const reviewPlanJSON = `{"goal":"Echo","steps":[{"id":1,"description":"Echo a","tool":"echo","params":{"value":"a"}},{"id":2,"description":"Echo b","tool":"echo","params":{"value":"b"}}]}`
+
+func TestPlannerExecutesReviewedPlan(t *testing.T) {
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(stubTool{name: "echo"}))
+	agent := &PlannerAgent{Model: &stubLLM{responses: []*framework.LLMResponse{{Text: reviewPlanJSON}}}, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	ctx := framework.WithPlanReviewer(context.Background(), planReviewFunc(func(plan framework.Plan) (framework.Plan, error) {
+		plan.Steps = plan.Steps[1:]
+		return plan, nil
+	}))
+	state := framework.NewContext()
+	_, err := agent.Execute(ctx, &framework.Task{Instruction: "Echo"}, state)
+	assert.NoError(t, err)
+
+	_, ranFirst := state.Get("planner.step.1")
+	assert.False(t, ranFirst)
+	second, _ := state.Get("planner.step.2")
+	assert.Equal(t, "b", second.(map[string]interface{})["echo"])
+}
+
+func TestPlannerStopsOnRejectedPlan(t *testing.T) {
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(stubTool{name: "echo"}))
+	agent := &PlannerAgent{Model: &stubLLM{responses: []*framework.LLMResponse{{Text: reviewPlanJSON}}}, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	ctx := framework.WithPlanReviewer(context.Background(), planReviewFunc(func(plan framework.Plan) (framework.Plan, error) {
+		return plan, framework.ErrPlanRejected
+	}))
+	state := framework.NewContext()
+	_, err := agent.Execute(ctx, &framework.Task{Instruction: "Echo"}, state)
+	assert.ErrorIs(t, err, framework.ErrPlanRejected)
+	_, executed := state.Get("planner.results")
+	assert.False(t, executed)
+}
@@ -2,10 +2,11 @@ package pattern
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/internal/agentutil"
+	"github.com/lexcodex/relurpify/tools"
 )
 
 // PlannerAgent builds a plan before executing. It is intentionally explicit:
@@ -18,6 +19,10 @@ type PlannerAgent struct {
 	Tools  *framework.ToolRegistry
 	Memory framework.MemoryStore
 	Config *framework.Config
+	// Export hands the plan off as a markdown task list or GitHub issues
+	// instead of executing it. Tasks override it via the "plan_export"
+	// context key.
+	Export PlanExportFormat
 }
 
 // Initialize configures the agent.
@@ -49,7 +54,9 @@ func (a *PlannerAgent) Capabilities() []framework.Capability {
 	}
 }
 
-// BuildGraph builds planning pipeline with explicit plan→execute→verify stages.
+// BuildGraph builds planning pipeline with explicit plan→review→execute→verify
+// stages. The review stage is a no-op unless the context carries a
+// framework.PlanReviewer.
 // Returning a Graph instead of hiding the workflow inside Execute keeps the
 // system debuggable and allows other packages to analyze the structure.
 func (a *PlannerAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
@@ -58,11 +65,15 @@ func (a *PlannerAgent) BuildGraph(task *framework.Task) (*framework.Graph, error
 	}
 	graph := framework.NewGraph()
 	planNode := &plannerPlanNode{id: "planner_plan", agent: a, task: task}
+	if format := a.exportFormat(task); format != PlanExportNone {
+		return a.buildExportGraph(graph, planNode, format)
+	}
+	reviewNode := &plannerReviewNode{id: "planner_review", task: task}
 	execNode := &plannerExecuteNode{id: "planner_execute", agent: a}
 	verifyNode := &plannerVerifyNode{id: "planner_verify", agent: a, task: task}
 	done := framework.NewTerminalNode("planner_done")
 
-	for _, node := range []framework.Node{planNode, execNode, verifyNode, done} {
+	for _, node := range []framework.Node{planNode, reviewNode, execNode, verifyNode, done} {
 		if err := graph.AddNode(node); err != nil {
 			return nil, err
 		}
@@ -70,7 +81,10 @@ func (a *PlannerAgent) BuildGraph(task *framework.Task) (*framework.Graph, error
 	if err := graph.SetStart(planNode.ID()); err != nil {
 		return nil, err
 	}
-	if err := graph.AddEdge(planNode.ID(), execNode.ID(), nil, false); err != nil {
+	if err := graph.AddEdge(planNode.ID(), reviewNode.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(reviewNode.ID(), execNode.ID(), nil, false); err != nil {
 		return nil, err
 	}
 	if err := graph.AddEdge(execNode.ID(), verifyNode.ID(), nil, false); err != nil {
@@ -82,6 +96,30 @@ func (a *PlannerAgent) BuildGraph(task *framework.Task) (*framework.Graph, error
 	return graph, nil
 }
 
+// buildExportGraph wires plan→export for work-breakdown runs.
+func (a *PlannerAgent) buildExportGraph(graph *framework.Graph, planNode *plannerPlanNode, format PlanExportFormat) (*framework.Graph, error) {
+	if format != PlanExportMarkdown && format != PlanExportGitHub {
+		return nil, fmt.Errorf("unknown plan export format %q", format)
+	}
+	exportNode := &plannerExportNode{id: "planner_export", agent: a, task: planNode.task, format: format}
+	done := framework.NewTerminalNode("planner_done")
+	for _, node := range []framework.Node{planNode, exportNode, done} {
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(planNode.ID()); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(planNode.ID(), exportNode.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(exportNode.ID(), done.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
 type plannerPlanNode struct {
 	id    string
 	agent *PlannerAgent
@@ -102,22 +140,34 @@ func (n *plannerPlanNode) Execute(ctx context.Context, state *framework.Context)
 Task: %s
 Return valid JSON Plan struct with fields goal, steps (array of {id, description, tool, params, expected, verification}).
 `, n.task.Instruction)
-	resp, err := n.agent.Model.Generate(ctx, prompt, &framework.LLMOptions{
+	if previous, ok := framework.PreviousTaskOf(n.task); ok {
+		prompt += "Previous task (this one may follow up on it):\n" + previous.Render()
+	}
+	if files := framework.TaskFiles(n.task); len(files) > 0 {
+		prompt += "Files:\n" + framework.RenderTaskFiles(files)
+	}
+	if conventions := conventionsGuidance(ctx, n.agent.Memory, state); conventions != "" {
+		prompt += conventions
+	}
+	var plan framework.Plan
+	resp, err := generateJSON(ctx, n.agent.Config, n.agent.Model, "planner.plan", prompt, planSchema, framework.LLMOptions{
 		Model:       n.agent.Config.Model,
 		Temperature: 0.2,
 		MaxTokens:   800,
-	})
-	if err != nil {
-		return nil, err
+	}, &plan)
+	if resp != nil {
+		state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
 	}
-	state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
-	plan, err := parsePlan(resp.Text)
 	if err != nil {
 		return nil, err
 	}
+	if plan.Dependencies == nil {
+		plan.Dependencies = make(map[int][]int)
+	}
+	plan = n.agent.scheduleRiskVerification(ctx, state, plan)
 	state.Set("planner.plan", plan)
 	if n.agent.Memory != nil {
-		_ = n.agent.Memory.Remember(ctx, NewUUID(), map[string]interface{}{
+		_ = n.agent.Memory.Remember(ctx, agentutil.NewUUID(), map[string]interface{}{
 			"type": "plan",
 			"plan": plan,
 		}, framework.MemoryScopeSession)
@@ -125,6 +175,39 @@ Return valid JSON Plan struct with fields goal, steps (array of {id, description
 	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"plan": plan}}, nil
 }
 
+type plannerReviewNode struct {
+	id   string
+	task *framework.Task
+}
+
+// ID returns the review node identifier.
+func (n *plannerReviewNode) ID() string { return n.id }
+
+// Type marks the review as a human checkpoint.
+func (n *plannerReviewNode) Type() framework.NodeType { return framework.NodeTypeHuman }
+
+// Execute hands the plan to the reviewer attached to ctx, if any, and
+// replaces it with the approved (possibly edited) version. A rejected plan
+// fails the run before any step executes.
+func (n *plannerReviewNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	reviewer, ok := framework.PlanReviewerFromContext(ctx)
+	if !ok {
+		return &framework.Result{NodeID: n.id, Success: true}, nil
+	}
+	state.SetExecutionPhase("reviewing")
+	value, ok := state.Get("planner.plan")
+	if !ok {
+		return nil, fmt.Errorf("plan not available")
+	}
+	plan, _ := value.(framework.Plan)
+	reviewed, err := reviewer.ReviewPlan(ctx, n.task, plan)
+	if err != nil {
+		return nil, err
+	}
+	state.Set("planner.plan", reviewed)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"plan": reviewed}}, nil
+}
+
 type plannerExecuteNode struct {
 	id    string
 	agent *PlannerAgent
@@ -155,7 +238,7 @@ func (n *plannerExecuteNode) Execute(ctx context.Context, state *framework.Conte
 		if !ok {
 			return nil, fmt.Errorf("tool %s not registered", step.Tool)
 		}
-		result, err := tool.Execute(ctx, state, step.Params)
+		result, err := executeToolWithConfig(ctx, n.agent.Config, state, tool, step.Params)
 		if err != nil {
 			return nil, err
 		}
@@ -192,7 +275,7 @@ func (n *plannerVerifyNode) Execute(ctx context.Context, state *framework.Contex
 	summary := fmt.Sprintf("Executed plan for task '%s' with %d steps.", n.task.Instruction, len(plan.Steps))
 	state.Set("planner.summary", summary)
 	if n.agent.Memory != nil {
-		_ = n.agent.Memory.Remember(ctx, NewUUID(), map[string]interface{}{
+		_ = n.agent.Memory.Remember(ctx, agentutil.NewUUID(), map[string]interface{}{
 			"type":    "verification",
 			"summary": summary,
 			"results": results,
@@ -207,15 +290,112 @@ func (n *plannerVerifyNode) Execute(ctx context.Context, state *framework.Contex
 	}, nil
 }
 
-// parsePlan pulls the JSON payload out of the model response. The helper keeps
-// PlannerAgent.Execute easy to read and doubles as a seam for unit tests.
-func parsePlan(raw string) (framework.Plan, error) {
-	var plan framework.Plan
-	if err := json.Unmarshal([]byte(ExtractJSON(raw)), &plan); err != nil {
-		return plan, err
+// scheduleRiskVerification consults the file risk tool (when registered) for
+// every file the plan touches and appends a verification step after each step
+// that modifies a high-risk file. Historically fragile files therefore get a
+// test run even when the LLM did not ask for one.
+func (a *PlannerAgent) scheduleRiskVerification(ctx context.Context, state *framework.Context, plan framework.Plan) framework.Plan {
+	if a.Tools == nil {
+		return plan
 	}
-	if plan.Dependencies == nil {
-		plan.Dependencies = make(map[int][]int)
+	riskTool, ok := a.Tools.Get(plannerRiskTool)
+	if !ok {
+		return plan
+	}
+	verifyTool, hasVerify := a.Tools.Get(plannerVerifyTool)
+	nextID := 0
+	for _, step := range plan.Steps {
+		if step.ID > nextID {
+			nextID = step.ID
+		}
+	}
+	risks := make(map[string]interface{})
+	steps := make([]framework.PlanStep, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		steps = append(steps, step)
+		file := planStepFile(step)
+		if file == "" {
+			continue
+		}
+		result, err := riskTool.Execute(ctx, state, map[string]interface{}{"file": file})
+		if err != nil || result == nil {
+			continue
+		}
+		risks[file] = result.Data
+		if fmt.Sprint(result.Data["level"]) != tools.RiskLevelHigh {
+			continue
+		}
+		nextID++
+		extra := framework.PlanStep{
+			ID:           nextID,
+			Description:  fmt.Sprintf("Verify changes to high-risk file %s", file),
+			Expected:     "tests pass",
+			Verification: "risk",
+		}
+		if hasVerify {
+			extra.Tool = verifyTool.Name()
+			extra.Params = map[string]interface{}{}
+			for _, param := range verifyTool.Parameters() {
+				if param.Name == "target" {
+					// Run only the tests covering the risky file.
+					extra.Params["target"] = file
+				}
+			}
+		}
+		steps = append(steps, extra)
+		if plan.Dependencies == nil {
+			plan.Dependencies = make(map[int][]int)
+		}
+		plan.Dependencies[extra.ID] = append(plan.Dependencies[extra.ID], step.ID)
+	}
+	plan.Steps = steps
+	if len(risks) > 0 {
+		state.Set("planner.risk", risks)
 	}
-	return plan, nil
+	return plan
+}
+
+const (
+	plannerRiskTool   = "analyze_file_risk"
+	plannerVerifyTool = "exec_run_tests"
+)
+
+// planStepFile returns the file a plan step targets, if any. Tools disagree on
+// the parameter name so the common spellings are checked in order.
+func planStepFile(step framework.PlanStep) string {
+	for _, key := range []string{"path", "file", "filename"} {
+		if value, ok := step.Params[key]; ok && value != nil {
+			if file := fmt.Sprint(value); file != "" {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// planSchema is the JSON schema of framework.Plan that planner responses must
+// match.
+var planSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"goal", "steps"},
+	"properties": map[string]interface{}{
+		"goal": map[string]interface{}{"type": "string"},
+		"steps": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id", "description"},
+				"properties": map[string]interface{}{
+					"id":           map[string]interface{}{"type": "integer"},
+					"description":  map[string]interface{}{"type": "string"},
+					"tool":         map[string]interface{}{"type": "string"},
+					"params":       map[string]interface{}{"type": "object"},
+					"expected":     map[string]interface{}{"type": "string"},
+					"verification": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+		"dependencies": map[string]interface{}{"type": "object"},
+	},
 }
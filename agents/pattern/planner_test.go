@@ -0,0 +1,46 @@
+package pattern
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// promptRecordingLLM records Generate prompts before answering like stubLLM.
+type promptRecordingLLM struct {
+	stubLLM
+	prompts []string
+}
+
+func (p *promptRecordingLLM) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	p.prompts = append(p.prompts, prompt)
+	return p.stubLLM.Generate(ctx, prompt, options)
+}
+
+func TestPlannerPromptIncludesProjectConventions(t *testing.T) {
+	memory, err := framework.NewHybridMemory(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, framework.SaveConventions(context.Background(), memory, &framework.ProjectConventions{
+		Linters: []framework.ConventionTool{{Name: "golangci-lint", Config: ".golangci.yml"}},
+		Tests:   []framework.TestPattern{{Pattern: "*_test.go", Count: 3}},
+	}))
+	model := &promptRecordingLLM{stubLLM: stubLLM{responses: []*framework.LLMResponse{{Text: reviewPlanJSON}}}}
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(stubTool{name: "echo"}))
+	agent := &PlannerAgent{Model: model, Tools: registry, Memory: memory}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err = agent.Execute(context.Background(), &framework.Task{Instruction: "Echo"}, state)
+	assert.NoError(t, err)
+
+	if assert.NotEmpty(t, model.prompts) {
+		assert.True(t, strings.Contains(model.prompts[0], "- Linters: golangci-lint"), model.prompts[0])
+		assert.True(t, strings.Contains(model.prompts[0], "- Test files: *_test.go (3 files)"), model.prompts[0])
+	}
+	assert.Contains(t, framework.ContextSources(state), framework.ContextSource{Kind: framework.SourceMemory, Key: framework.ConventionsMemoryKey})
+}
@@ -0,0 +1,521 @@
+package pattern
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// QAAgent answers questions about the workspace. It retrieves candidate
+// chunks through the search, AST, and LSP tools, refuses when the evidence is
+// weak, and otherwise asks the model for an answer that cites file:line
+// locations.
+type QAAgent struct {
+	Model  framework.LanguageModel
+	Tools  *framework.ToolRegistry
+	Memory framework.MemoryStore
+	Config *framework.Config
+	// MinConfidence is the retrieval score required before answering.
+	MinConfidence float64
+	// MaxChunks bounds how many retrieved chunks reach the prompt.
+	MaxChunks int
+}
+
+// QAChunk is a retrieved snippet of source with its location.
+type QAChunk struct {
+	File      string  `json:"file"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Content   string  `json:"content"`
+	Score     float64 `json:"score"`
+	Source    string  `json:"source"`
+}
+
+// QACitation points at the evidence backing an answer.
+type QACitation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// String renders the citation as file:line.
+func (c QACitation) String() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// QAAnswer is the structured output of the QA pipeline. Unverified marks an
+// answer none of whose citations point into the retrieved excerpts.
+type QAAnswer struct {
+	Question   string       `json:"question"`
+	Answer     string       `json:"answer"`
+	Citations  []QACitation `json:"citations"`
+	Confidence float64      `json:"confidence"`
+	Refused    bool         `json:"refused"`
+	Unverified bool         `json:"unverified,omitempty"`
+}
+
+func init() {
+	gob.Register(QAChunk{})
+	gob.Register([]QAChunk{})
+	gob.Register(QAAnswer{})
+}
+
+const (
+	qaDefaultConfidence = 0.5
+	qaDefaultChunks     = 6
+	qaMaxKeywords       = 4
+	qaChunkBefore       = 3
+	qaChunkAfter        = 6
+	qaRefusal           = "I could not find enough evidence in the workspace to answer this confidently."
+)
+
+// Initialize configures the agent.
+func (a *QAAgent) Initialize(cfg *framework.Config) error {
+	a.Config = cfg
+	if a.Tools == nil {
+		a.Tools = framework.NewToolRegistry()
+	}
+	if a.MinConfidence <= 0 {
+		a.MinConfidence = qaDefaultConfidence
+	}
+	if a.MaxChunks <= 0 {
+		a.MaxChunks = qaDefaultChunks
+	}
+	return nil
+}
+
+// Execute runs the QA workflow.
+func (a *QAAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph, err := a.BuildGraph(task)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
+		graph.SetTelemetry(cfg.Telemetry)
+	}
+	return graph.Execute(ctx, state)
+}
+
+// Capabilities enumerates features.
+func (a *QAAgent) Capabilities() []framework.Capability {
+	return []framework.Capability{framework.CapabilityExplain}
+}
+
+// BuildGraph wires retrieve → answer.
+func (a *QAAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	if a.Model == nil {
+		return nil, fmt.Errorf("qa agent missing model")
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task required")
+	}
+	graph := framework.NewGraph()
+	retrieve := &qaRetrieveNode{id: "qa_retrieve", agent: a, task: task}
+	answer := &qaAnswerNode{id: "qa_answer", agent: a, task: task}
+	done := framework.NewTerminalNode("qa_done")
+	for _, node := range []framework.Node{retrieve, answer, done} {
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(retrieve.ID()); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(retrieve.ID(), answer.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(answer.ID(), done.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+type qaRetrieveNode struct {
+	id    string
+	agent *QAAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *qaRetrieveNode) ID() string { return n.id }
+
+// Type marks the node as tool-backed.
+func (n *qaRetrieveNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute gathers chunks from keyword search, semantic search, the AST index,
+// and LSP workspace symbols, then scores each by how many question keywords
+// it covers.
+func (n *qaRetrieveNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("retrieving")
+	keywords := questionKeywords(n.task.Instruction)
+	chunks := n.agent.retrieve(ctx, state, keywords)
+	confidence := 0.0
+	if len(chunks) > 0 {
+		confidence = chunks[0].Score
+	}
+	state.Set("qa.keywords", keywords)
+	state.Set("qa.chunks", chunks)
+	state.Set("qa.confidence", confidence)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{
+		"chunks":     len(chunks),
+		"confidence": confidence,
+	}}, nil
+}
+
+// retrieve collects hits per keyword and expands them into scored chunks.
+func (a *QAAgent) retrieve(ctx context.Context, state *framework.Context, keywords []string) []QAChunk {
+	type hit struct {
+		file   string
+		line   int
+		source string
+	}
+	var hits []hit
+	for _, keyword := range keywords {
+		if data := a.runTool(ctx, state, "search_grep", map[string]interface{}{"pattern": keyword, "directory": "."}); data != nil {
+			matches, _ := data["matches"].([]interface{})
+			for i, raw := range matches {
+				if i >= 5 {
+					break
+				}
+				if match, ok := raw.(map[string]interface{}); ok {
+					hits = append(hits, hit{file: fmt.Sprint(match["file"]), line: toLine(match["line"]), source: "keyword"})
+				}
+			}
+		}
+		if data := a.runTool(ctx, state, "search_semantic", map[string]interface{}{"query": keyword}); data != nil {
+			results, _ := data["results"].([]interface{})
+			for i, raw := range results {
+				if i >= 3 {
+					break
+				}
+				if result, ok := raw.(map[string]interface{}); ok {
+					hits = append(hits, hit{file: fmt.Sprint(result["file"]), line: toLine(result["start_line"]), source: "semantic"})
+				}
+			}
+		}
+		if data := a.runTool(ctx, state, "query_ast", map[string]interface{}{"action": "get_signature", "symbol": keyword}); data != nil {
+			if file, ok := data["file"].(string); ok && file != "" {
+				hits = append(hits, hit{file: file, line: toLine(data["line"]), source: "ast"})
+			}
+		}
+		if data := a.runTool(ctx, state, "lsp_search_symbols", map[string]interface{}{"query": keyword}); data != nil {
+			symbols, _ := data["symbols"].([]interface{})
+			for i, raw := range symbols {
+				if i >= 3 {
+					break
+				}
+				if symbol, ok := raw.(map[string]interface{}); ok {
+					if file, line := symbolLocation(fmt.Sprint(symbol["location"])); file != "" {
+						hits = append(hits, hit{file: file, line: line, source: "lsp"})
+					}
+				}
+			}
+		}
+	}
+
+	sources := make(map[string][]string)
+	seen := make(map[string]bool)
+	var chunks []QAChunk
+	for _, h := range hits {
+		if h.file == "" {
+			continue
+		}
+		lines, ok := sources[h.file]
+		if !ok {
+			data := a.runTool(ctx, state, "file_read", map[string]interface{}{"path": h.file})
+			if data == nil {
+				sources[h.file] = nil
+				continue
+			}
+			lines = strings.Split(fmt.Sprint(data["content"]), "\n")
+			sources[h.file] = lines
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		start := h.line - qaChunkBefore
+		if start < 1 {
+			start = 1
+		}
+		end := h.line + qaChunkAfter
+		if end > len(lines) {
+			end = len(lines)
+		}
+		key := fmt.Sprintf("%s:%d", h.file, start)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		content := strings.Join(lines[start-1:end], "\n")
+		score := keywordCoverage(content, keywords)
+		if h.source == "ast" || h.source == "lsp" {
+			score += 0.2
+		}
+		if score > 1 {
+			score = 1
+		}
+		chunks = append(chunks, QAChunk{File: h.file, StartLine: start, EndLine: end, Content: content, Score: score, Source: h.source})
+	}
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].Score > chunks[j].Score })
+	if len(chunks) > a.MaxChunks {
+		chunks = chunks[:a.MaxChunks]
+	}
+	return chunks
+}
+
+// symbolLocation splits an LSP symbol location ("file:///path:line", with a
+// zero-based line) into a path and a one-based line.
+func symbolLocation(location string) (string, int) {
+	location = strings.TrimPrefix(location, "file://")
+	idx := strings.LastIndex(location, ":")
+	if idx <= 0 {
+		return location, 1
+	}
+	line, err := strconv.Atoi(location[idx+1:])
+	if err != nil {
+		return location, 1
+	}
+	return location[:idx], line + 1
+}
+
+// runTool executes a registered tool and returns JSON-normalized data, or nil
+// when the tool is missing or fails. Retrieval is best-effort by design.
+func (a *QAAgent) runTool(ctx context.Context, state *framework.Context, name string, args map[string]interface{}) map[string]interface{} {
+	tool, ok := a.Tools.Get(name)
+	if !ok {
+		return nil
+	}
+	res, err := tool.Execute(ctx, state, args)
+	if err != nil || res == nil || !res.Success {
+		return nil
+	}
+	return normalizeData(res.Data)
+}
+
+type qaAnswerNode struct {
+	id    string
+	agent *QAAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *qaAnswerNode) ID() string { return n.id }
+
+// Type marks the node as an LLM step.
+func (n *qaAnswerNode) Type() framework.NodeType { return framework.NodeTypeSystem }
+
+// Execute refuses when retrieval confidence is below the threshold; otherwise
+// it asks the model for a cited answer and keeps only citations that point at
+// retrieved chunks.
+func (n *qaAnswerNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("answering")
+	raw, _ := state.Get("qa.chunks")
+	chunks, _ := raw.([]QAChunk)
+	confidenceRaw, _ := state.Get("qa.confidence")
+	confidence, _ := confidenceRaw.(float64)
+	answer := QAAnswer{Question: n.task.Instruction, Confidence: confidence}
+
+	if len(chunks) == 0 || confidence < n.agent.MinConfidence {
+		answer.Refused = true
+		answer.Answer = qaRefusal
+		return n.finish(state, answer), nil
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Answer the question using only the numbered workspace excerpts below.\n")
+	prompt.WriteString("Cite every claim with the file and line it came from. If the excerpts do not contain the answer, say so.\n\n")
+	for i, chunk := range chunks {
+		fmt.Fprintf(&prompt, "[%d] %s:%d-%d\n```\n%s\n```\n", i+1, chunk.File, chunk.StartLine, chunk.EndLine, chunk.Content)
+	}
+	fmt.Fprintf(&prompt, "\nQuestion: %s\n", n.task.Instruction)
+	prompt.WriteString(`Return JSON {"answer": string, "citations": [{"file": string, "line": int}]}.`)
+
+	model := ""
+	if n.agent.Config != nil {
+		model = n.agent.Config.Model
+	}
+	var parsed struct {
+		Answer    string       `json:"answer"`
+		Citations []QACitation `json:"citations"`
+	}
+	// A response that never matches the schema is still an answer; it is
+	// used as plain text without citations.
+	resp, err := generateJSON(ctx, n.agent.Config, n.agent.Model, "qa.answer", prompt.String(), qaAnswerSchema, framework.LLMOptions{
+		Model:       model,
+		Temperature: 0.1,
+		MaxTokens:   800,
+	}, &parsed)
+	var invalid *framework.StructuredOutputError
+	if err != nil && !errors.As(err, &invalid) {
+		return nil, err
+	}
+	state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
+	if invalid != nil || parsed.Answer == "" {
+		parsed.Answer = strings.TrimSpace(resp.Text)
+		parsed.Citations = nil
+	}
+	answer.Answer = parsed.Answer
+	answer.Citations = validCitations(parsed.Citations, chunks)
+	answer.Unverified = len(answer.Citations) == 0
+	return n.finish(state, answer), nil
+}
+
+// qaAnswerSchema is the JSON schema of the answer the model returns.
+var qaAnswerSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"answer", "citations"},
+	"properties": map[string]interface{}{
+		"answer": map[string]interface{}{"type": "string"},
+		"citations": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"file", "line"},
+				"properties": map[string]interface{}{
+					"file": map[string]interface{}{"type": "string"},
+					"line": map[string]interface{}{"type": "integer"},
+				},
+			},
+		},
+	},
+}
+
+func (n *qaAnswerNode) finish(state *framework.Context, answer QAAnswer) *framework.Result {
+	state.Set("qa.answer", answer)
+	state.Set("qa.final_output", formatQAAnswer(answer))
+	return &framework.Result{
+		NodeID:  n.id,
+		Success: true,
+		Data: map[string]interface{}{
+			"answer":       answer,
+			"final_output": formatQAAnswer(answer),
+		},
+	}
+}
+
+// validCitations drops citations that do not fall inside a retrieved chunk so
+// the model cannot point at evidence it never saw.
+func validCitations(citations []QACitation, chunks []QAChunk) []QACitation {
+	var valid []QACitation
+	for _, citation := range citations {
+		for _, chunk := range chunks {
+			if sameFile(citation.File, chunk.File) && citation.Line >= chunk.StartLine && citation.Line <= chunk.EndLine {
+				valid = append(valid, QACitation{File: chunk.File, Line: citation.Line})
+				break
+			}
+		}
+	}
+	return valid
+}
+
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	return a != "" && (strings.HasSuffix(b, "/"+a) || strings.HasSuffix(a, "/"+b))
+}
+
+func formatQAAnswer(answer QAAnswer) string {
+	if answer.Refused {
+		return fmt.Sprintf("%s (retrieval confidence %.2f)", answer.Answer, answer.Confidence)
+	}
+	var b strings.Builder
+	b.WriteString(answer.Answer)
+	if answer.Unverified {
+		b.WriteString("\n\nUnverified: the answer cites none of the retrieved sources.")
+	}
+	if len(answer.Citations) > 0 {
+		b.WriteString("\n\nSources:")
+		for _, citation := range answer.Citations {
+			fmt.Fprintf(&b, "\n- %s", citation)
+		}
+	}
+	return b.String()
+}
+
+// questionKeywords pulls identifier-like terms out of a question, preferring
+// longer tokens and those that look like code (CamelCase, snake_case, dotted).
+func questionKeywords(question string) []string {
+	fields := strings.FieldsFunc(question, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-')
+	})
+	type candidate struct {
+		word   string
+		weight int
+	}
+	seen := make(map[string]bool)
+	var candidates []candidate
+	for _, field := range fields {
+		word := strings.Trim(field, ".-")
+		lower := strings.ToLower(word)
+		if len(word) < 3 || qaStopWords[lower] || seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		weight := len(word)
+		if strings.ContainsAny(word, "_.") || hasInnerUpper(word) {
+			weight += 10
+		}
+		candidates = append(candidates, candidate{word: word, weight: weight})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].weight > candidates[j].weight })
+	var keywords []string
+	for i, c := range candidates {
+		if i >= qaMaxKeywords {
+			break
+		}
+		keywords = append(keywords, c.word)
+	}
+	return keywords
+}
+
+func hasInnerUpper(word string) bool {
+	for i, r := range word {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordCoverage is the fraction of keywords present in content.
+func keywordCoverage(content string, keywords []string) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+	lower := strings.ToLower(content)
+	found := 0
+	for _, keyword := range keywords {
+		if strings.Contains(lower, strings.ToLower(keyword)) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(keywords))
+}
+
+func toLine(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 1
+	}
+}
+
+var qaStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "where": true, "what": true, "which": true,
+	"how": true, "does": true, "did": true, "are": true, "was": true, "were": true,
+	"this": true, "that": true, "with": true, "from": true, "into": true, "when": true,
+	"why": true, "who": true, "can": true, "should": true, "would": true, "could": true,
+	"configured": true, "defined": true, "used": true, "set": true, "get": true,
+	"there": true, "their": true, "have": true, "has": true, "code": true, "file": true,
+	"files": true, "find": true, "show": true, "located": true, "implemented": true,
+}
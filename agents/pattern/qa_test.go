@@ -0,0 +1,106 @@
+package pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type fixedTool struct {
+	stubTool
+	data map[string]interface{}
+}
+
+// Execute returns the canned payload regardless of arguments.
+func (t fixedTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	return &framework.ToolResult{Success: true, Data: t.data}, nil
+}
+
+func TestQuestionKeywordsPrefersIdentifiers(t *testing.T) {
+	keywords := questionKeywords("Where is the OllamaEndpoint configured for the runtime?")
+	assert.Equal(t, "OllamaEndpoint", keywords[0])
+	assert.NotContains(t, keywords, "configured")
+}
+
+func TestQAAgentRefusesWithoutEvidence(t *testing.T) {
+	llm := &stubLLM{}
+	agent := &QAAgent{Model: llm}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeQuestion, Instruction: "Where is FooBar defined?"}, state)
+	assert.NoError(t, err)
+	raw, _ := state.Get("qa.answer")
+	answer := raw.(QAAnswer)
+	assert.True(t, answer.Refused)
+	assert.Equal(t, 0, llm.generateCalls)
+}
+
+func TestQAAgentCitesRetrievedChunks(t *testing.T) {
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "search_grep"}, data: map[string]interface{}{
+		"matches": []map[string]interface{}{{"file": "config.go", "line": 4, "content": "OllamaEndpoint string"}},
+	}}))
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "file_read"}, data: map[string]interface{}{
+		"content": "package runtime\n\ntype Config struct {\n\tOllamaEndpoint string\n}\n",
+	}}))
+	llm := &stubLLM{responses: []*framework.LLMResponse{
+		{Text: `{"answer":"Config.OllamaEndpoint holds it.","citations":[{"file":"config.go","line":4},{"file":"other.go","line":9}]}`},
+	}}
+	agent := &QAAgent{Model: llm, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeQuestion, Instruction: "Where is OllamaEndpoint configured?"}, state)
+	assert.NoError(t, err)
+	raw, _ := state.Get("qa.answer")
+	answer := raw.(QAAnswer)
+	assert.False(t, answer.Refused)
+	assert.Equal(t, []QACitation{{File: "config.go", Line: 4}}, answer.Citations)
+}
+
+func TestQAAgentMarksAnswersWithoutValidCitationsUnverified(t *testing.T) {
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "search_grep"}, data: map[string]interface{}{
+		"matches": []map[string]interface{}{{"file": "config.go", "line": 4, "content": "OllamaEndpoint string"}},
+	}}))
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "file_read"}, data: map[string]interface{}{
+		"content": "package runtime\n\ntype Config struct {\n\tOllamaEndpoint string\n}\n",
+	}}))
+	llm := &stubLLM{responses: []*framework.LLMResponse{
+		{Text: `{"answer":"It is set in main.go.","citations":[{"file":"main.go","line":12}]}`},
+	}}
+	agent := &QAAgent{Model: llm, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeQuestion, Instruction: "Where is OllamaEndpoint configured?"}, state)
+	assert.NoError(t, err)
+	raw, _ := state.Get("qa.answer")
+	answer := raw.(QAAnswer)
+	assert.Empty(t, answer.Citations, "no citation is made up from the excerpts")
+	assert.True(t, answer.Unverified)
+	output, _ := state.Get("qa.final_output")
+	assert.Contains(t, output, "Unverified")
+}
+
+func TestQAAgentRetrievesLSPSymbols(t *testing.T) {
+	registry := framework.NewToolRegistry()
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "lsp_search_symbols"}, data: map[string]interface{}{
+		"symbols": []map[string]interface{}{{"name": "OllamaEndpoint", "kind": "field", "location": "file:///ws/config.go:3"}},
+	}}))
+	assert.NoError(t, registry.Register(fixedTool{stubTool: stubTool{name: "file_read"}, data: map[string]interface{}{
+		"content": "package runtime\n\ntype Config struct {\n\tOllamaEndpoint string\n}\n",
+	}}))
+	agent := &QAAgent{Model: &stubLLM{}, Tools: registry}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+
+	chunks := agent.retrieve(context.Background(), framework.NewContext(), []string{"OllamaEndpoint"})
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, "/ws/config.go", chunks[0].File)
+	assert.Equal(t, "lsp", chunks[0].Source)
+	assert.Contains(t, chunks[0].Content, "OllamaEndpoint string")
+}
@@ -11,6 +11,7 @@ import (
 
 	agentctx "github.com/lexcodex/relurpify/agents/contextual"
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/internal/agentutil"
 )
 
 // ReActAgent implements the Reason+Act pattern.
@@ -60,10 +61,13 @@ func (a *ReActAgent) Initialize(config *framework.Config) error {
 	if a.Tools == nil {
 		a.Tools = framework.NewToolRegistry()
 	}
+	// Initialize runs again when the model changes, so the budget is resized
+	// rather than only built once.
+	sizing := config.ContextSizing()
 	if a.budget == nil {
-		a.budget = framework.NewContextBudget(8000)
+		a.budget = framework.NewContextBudget(sizing.MaxTokens)
 	}
-	a.budget.SetReservations(1000, 2000, 1000)
+	a.budget.Resize(sizing)
 	if a.contextManager == nil {
 		a.contextManager = framework.NewContextManager(a.budget)
 	}
@@ -95,6 +99,10 @@ func (a *ReActAgent) Initialize(config *framework.Config) error {
 			a.contextStrategy = agentctx.NewAdaptiveStrategy()
 		}
 	}
+	if config.Summarizer != nil && config.Summarizer != a.summarizer {
+		a.summarizer = config.Summarizer
+		a.progressive = nil
+	}
 	if a.summarizer == nil {
 		a.summarizer = &framework.SimpleSummarizer{}
 	}
@@ -104,6 +112,24 @@ func (a *ReActAgent) Initialize(config *framework.Config) error {
 	return nil
 }
 
+// summaryModel returns the model used to compress history, which may be a
+// smaller model than the one driving the agent.
+func (a *ReActAgent) summaryModel() framework.LanguageModel {
+	return a.Config.ModelFor(framework.ModelRoleSummarization, a.Model)
+}
+
+// retry runs fn under the configured retry policy, reporting retries to the
+// config's telemetry.
+func (a *ReActAgent) retry(ctx context.Context, op string, fn func() error) error {
+	return retryWithConfig(ctx, a.Config, op, fn)
+}
+
+// executeTool runs tool, retrying transient failures and truncating
+// oversized results (see executeToolWithConfig).
+func (a *ReActAgent) executeTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}) (*framework.ToolResult, error) {
+	return executeToolWithConfig(ctx, a.Config, state, tool, args)
+}
+
 // debugf logs formatted messages whenever agent debug logging is enabled.
 func (a *ReActAgent) debugf(format string, args ...interface{}) {
 	if a == nil || a.Config == nil || !a.Config.DebugAgent {
@@ -195,6 +221,12 @@ func (a *ReActAgent) BuildGraph(task *framework.Task) (*framework.Graph, error)
 	}, false); err != nil {
 		return nil, err
 	}
+	if err := graph.LabelEdge(observe.ID(), think.ID(), "not react.done"); err != nil {
+		return nil, err
+	}
+	if err := graph.LabelEdge(observe.ID(), terminal.ID(), "react.done"); err != nil {
+		return nil, err
+	}
 	return graph, nil
 }
 
@@ -219,7 +251,7 @@ func (a *ReActAgent) enforceBudget(state *framework.Context) {
 				if keep <= 0 {
 					keep = 5
 				}
-				if err := a.sharedContext.CompressHistory(keep, a.Model, a.compressionStrategy); err != nil {
+				if err := a.sharedContext.CompressHistory(keep, a.summaryModel(), a.compressionStrategy); err != nil {
 					a.debugf("shared context compression failed: %v", err)
 				} else {
 					compressed = true
@@ -227,7 +259,7 @@ func (a *ReActAgent) enforceBudget(state *framework.Context) {
 			}
 		}
 		if !compressed && a.compressionStrategy != nil {
-			if err := state.CompressHistory(a.compressionStrategy.KeepRecent(), a.Model, a.compressionStrategy); err != nil {
+			if err := state.CompressHistory(a.compressionStrategy.KeepRecent(), a.summaryModel(), a.compressionStrategy); err != nil {
 				a.debugf("compression failed: %v", err)
 			} else {
 				compressed = true
@@ -375,11 +407,15 @@ func (n *reactThinkNode) Execute(ctx context.Context, state *framework.Context)
 	tools := n.agent.Tools.All()
 	useToolCalling := len(tools) > 0 && (n.agent.Config == nil || n.agent.Config.OllamaToolCalling)
 	if useToolCalling {
-		messages := n.ensureMessages(state, tools)
-		resp, err = n.agent.Model.ChatWithTools(ctx, messages, tools, &framework.LLMOptions{
-			Model:       n.agent.Config.Model,
-			Temperature: 0.1,
-			MaxTokens:   512,
+		messages := n.ensureMessages(ctx, state, tools)
+		err = n.agent.retry(ctx, "llm.chat_with_tools", func() error {
+			var callErr error
+			resp, callErr = n.agent.Model.ChatWithTools(ctx, messages, tools, &framework.LLMOptions{
+				Model:       n.agent.Config.Model,
+				Temperature: 0.1,
+				MaxTokens:   512,
+			})
+			return callErr
 		})
 		if err == nil {
 			messages = append(messages, framework.Message{
@@ -390,12 +426,18 @@ func (n *reactThinkNode) Execute(ctx context.Context, state *framework.Context)
 			saveReactMessages(state, messages)
 		}
 	} else {
-		prompt := n.buildPrompt(state)
-		resp, err = n.agent.Model.Generate(ctx, prompt, &framework.LLMOptions{
+		prompt := n.buildPrompt(ctx, state)
+		resp, err = generateJSON(ctx, n.agent.Config, n.agent.Model, "llm.generate", prompt, decisionSchema, framework.LLMOptions{
 			Model:       n.agent.Config.Model,
 			Temperature: 0.1,
 			MaxTokens:   512,
-		})
+		}, nil)
+		// A response that never matches the schema still goes through the
+		// lenient parsing below.
+		var invalid *framework.StructuredOutputError
+		if errors.As(err, &invalid) {
+			err = nil
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -462,7 +504,7 @@ func (n *reactThinkNode) Execute(ctx context.Context, state *framework.Context)
 
 // buildPrompt returns a textual prompt when tool-calling chat APIs are not
 // available.
-func (n *reactThinkNode) buildPrompt(state *framework.Context) string {
+func (n *reactThinkNode) buildPrompt(ctx context.Context, state *framework.Context) string {
 	var hasLSP, hasAST bool
 	for _, tool := range n.agent.Tools.All() {
 		if strings.HasPrefix(tool.Name(), "lsp_") {
@@ -472,6 +514,7 @@ func (n *reactThinkNode) buildPrompt(state *framework.Context) string {
 			hasAST = true
 		}
 	}
+	lazy := lazyToolNames(n.agent.Tools.All())
 	var last string
 	if res, ok := state.Get("react.last_tool_result"); ok {
 		last = fmt.Sprint(res)
@@ -489,6 +532,9 @@ func (n *reactThinkNode) buildPrompt(state *framework.Context) string {
 			guidance.WriteString("- Prefer AST tools for structure queries.\n")
 		}
 	}
+	if len(lazy) > 0 {
+		guidance.WriteString(fmt.Sprintf("- These tools activate on first call, so the first call may be slow: %s\n", strings.Join(lazy, ", ")))
+	}
 	if val, ok := n.task.Context["plan"]; ok {
 		if planJSON, err := json.MarshalIndent(val, "", "  "); err == nil {
 			guidance.WriteString("\nPlan:\n")
@@ -496,6 +542,26 @@ func (n *reactThinkNode) buildPrompt(state *framework.Context) string {
 			guidance.WriteRune('\n')
 		}
 	}
+	if previous, ok := framework.PreviousTaskOf(n.task); ok {
+		guidance.WriteString("\nPrevious task (this one may follow up on it):\n")
+		guidance.WriteString(previous.Render())
+	}
+	if files := framework.TaskFiles(n.task); len(files) > 0 {
+		guidance.WriteString("\nFiles:\n")
+		guidance.WriteString(framework.RenderTaskFiles(files))
+	}
+	if diags := framework.PostEditDiagnostics(state); len(diags) > 0 {
+		guidance.WriteString("\nErrors in edited files (fix new ones before completing):\n")
+		guidance.WriteString(framework.RenderEditDiagnostics(diags))
+	}
+	if vocab := n.agent.glossaryGuidance(ctx, state, n.task.Instruction); vocab != "" {
+		guidance.WriteString("\n")
+		guidance.WriteString(vocab)
+	}
+	if conventions := conventionsGuidance(ctx, n.agent.Memory, state); conventions != "" {
+		guidance.WriteString("\n")
+		guidance.WriteString(conventions)
+	}
 
 	return fmt.Sprintf(`You are a ReAct agent tasked with "%s".
 %s
@@ -506,13 +572,25 @@ Provide your response as a JSON object with "thought" and "tool"/"arguments" fie
 
 // ensureMessages seeds the chat history when tool calling is enabled so each
 // iteration builds on prior reasoning.
-func (n *reactThinkNode) ensureMessages(state *framework.Context, tools []framework.Tool) []framework.Message {
+func (n *reactThinkNode) ensureMessages(ctx context.Context, state *framework.Context, tools []framework.Tool) []framework.Message {
 	messages := getReactMessages(state)
 	if len(messages) > 0 {
 		return messages
 	}
 	systemPrompt := n.buildSystemPrompt(tools)
+	if vocab := n.agent.glossaryGuidance(ctx, state, n.task.Instruction); vocab != "" {
+		systemPrompt += "\n\n### " + vocab
+	}
+	if conventions := conventionsGuidance(ctx, n.agent.Memory, state); conventions != "" {
+		systemPrompt += "\n\n### " + conventions
+	}
 	userPrompt := fmt.Sprintf("Task: %s", n.task.Instruction)
+	if previous, ok := framework.PreviousTaskOf(n.task); ok {
+		userPrompt = "Previous task (this one may follow up on it):\n" + previous.Render() + "\n" + userPrompt
+	}
+	if files := framework.TaskFiles(n.task); len(files) > 0 {
+		userPrompt += "\n\nFiles:\n" + framework.RenderTaskFiles(files)
+	}
 	messages = []framework.Message{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: userPrompt},
@@ -526,7 +604,11 @@ func (n *reactThinkNode) buildSystemPrompt(tools []framework.Tool) string {
 	var lines []string
 	var hasLSP, hasAST bool
 	for _, tool := range tools {
-		lines = append(lines, fmt.Sprintf("- %s: %s", tool.Name(), tool.Description()))
+		line := fmt.Sprintf("- %s: %s", tool.Name(), tool.Description())
+		if framework.PendingActivation(tool) {
+			line += " (activates on first call)"
+		}
+		lines = append(lines, line)
 		if strings.HasPrefix(tool.Name(), "lsp_") {
 			hasLSP = true
 		}
@@ -563,6 +645,48 @@ Available tools:
 When you call a tool, wait for its response before continuing. When the work is complete, provide the final answer as plain text.`, strings.Join(lines, "\n"), guidance.String())
 }
 
+// lazyToolNames lists tools whose language server or index has not started
+// yet.
+func lazyToolNames(tools []framework.Tool) []string {
+	var names []string
+	for _, tool := range tools {
+		if framework.PendingActivation(tool) {
+			names = append(names, tool.Name())
+		}
+	}
+	return names
+}
+
+// glossaryGuidance renders the project vocabulary relevant to the
+// instruction so the model reuses the workspace's own terms, recording the
+// glossary as a source when it contributes.
+func (a *ReActAgent) glossaryGuidance(ctx context.Context, state *framework.Context, instruction string) string {
+	glossary, err := framework.LoadGlossary(ctx, a.Memory)
+	if err != nil || glossary == nil {
+		return ""
+	}
+	section := glossary.PromptSection(instruction, 10)
+	if section != "" {
+		framework.RecordContextSources(state, framework.ContextSource{Kind: framework.SourceMemory, Key: framework.GlossaryMemoryKey})
+	}
+	return section
+}
+
+// conventionsGuidance renders the project conventions captured in memory
+// so planned and written code follows the project's habits, recording them
+// as a source when present.
+func conventionsGuidance(ctx context.Context, memory framework.MemoryStore, state *framework.Context) string {
+	conventions, err := framework.LoadConventions(ctx, memory)
+	if err != nil || conventions == nil {
+		return ""
+	}
+	section := conventions.PromptSection()
+	if section != "" {
+		framework.RecordContextSources(state, framework.ContextSource{Kind: framework.SourceMemory, Key: framework.ConventionsMemoryKey})
+	}
+	return section
+}
+
 type reactActNode struct {
 	id    string
 	agent *ReActAgent
@@ -591,7 +715,7 @@ func (n *reactActNode) Execute(ctx context.Context, state *framework.Context) (*
 					return nil, fmt.Errorf("unknown tool %s", call.Name)
 				}
 				n.agent.debugf("%s executing tool=%s args=%v", n.id, call.Name, call.Args)
-				res, err := tool.Execute(ctx, state, call.Args)
+				res, err := n.agent.executeTool(ctx, state, tool, call.Args)
 				if err != nil {
 					return nil, err
 				}
@@ -646,7 +770,7 @@ func (n *reactActNode) Execute(ctx context.Context, state *framework.Context) (*
 		}
 		return nil, fmt.Errorf("unknown tool %s", toolName)
 	}
-	res, err := tool.Execute(ctx, state, decision.Arguments)
+	res, err := n.agent.executeTool(ctx, state, tool, decision.Arguments)
 	if err != nil {
 		return nil, err
 	}
@@ -702,10 +826,16 @@ func (n *reactObserveNode) Execute(ctx context.Context, state *framework.Context
 			completed = false
 		}
 	}
+	if feedback := n.newEditErrors(state); feedback != "" {
+		diagnostic.WriteString(feedback)
+		if iter < n.agent.maxIterations {
+			completed = false
+		}
+	}
 	state.Set("react.done", completed)
 
 	if n.agent.Memory != nil {
-		_ = n.agent.Memory.Remember(ctx, NewUUID(), map[string]interface{}{
+		_ = n.agent.Memory.Remember(ctx, agentutil.NewUUID(), map[string]interface{}{
 			"task":      n.task.Instruction,
 			"iteration": iter,
 			"decision":  decision,
@@ -713,10 +843,15 @@ func (n *reactObserveNode) Execute(ctx context.Context, state *framework.Context
 	}
 
 	if completed {
-		state.Set("react.final_output", map[string]interface{}{
+		final := map[string]interface{}{
 			"summary": diagnostic.String(),
 			"result":  lastMap,
-		})
+		}
+		if sources := framework.ContextSources(state); len(sources) > 0 {
+			final["summary"] = diagnostic.String() + "\n" + framework.RenderContextSources(sources)
+			final["sources"] = sources
+		}
+		state.Set("react.final_output", final)
 	}
 	n.agent.debugf("%s completed=%v diagnostic=%s", n.id, completed, diagnostic.String())
 	result := &framework.Result{
@@ -731,6 +866,32 @@ func (n *reactObserveNode) Execute(ctx context.Context, state *framework.Context
 	return result, nil
 }
 
+// newEditErrors reports errors the latest edits introduced, once per batch.
+// In tool-calling mode they are also added to the transcript so the next
+// think step sees them; text prompts list them from the context.
+func (n *reactObserveNode) newEditErrors(state *framework.Context) string {
+	val, _ := state.Get(framework.PostEditNewErrorsKey)
+	if count, _ := val.(int); count == 0 {
+		return ""
+	}
+	state.Set(framework.PostEditNewErrorsKey, 0)
+	var fresh []framework.EditDiagnostic
+	for _, d := range framework.PostEditDiagnostics(state) {
+		if d.New {
+			fresh = append(fresh, d)
+		}
+	}
+	if len(fresh) == 0 {
+		return ""
+	}
+	feedback := "New errors after edits:\n" + framework.RenderEditDiagnostics(fresh)
+	if messages := getReactMessages(state); len(messages) > 0 {
+		messages = append(messages, framework.Message{Role: "user", Content: feedback + "Fix these before completing the task."})
+		saveReactMessages(state, messages)
+	}
+	return feedback
+}
+
 // decisionPayload models the JSON output of the think step.
 type decisionPayload struct {
 	Thought   string                 `json:"thought"`
@@ -741,11 +902,25 @@ type decisionPayload struct {
 	Timestamp time.Time              `json:"timestamp"`
 }
 
+// decisionSchema is the JSON schema of decisionPayload that prompted (not
+// tool-calling) think steps must match.
+var decisionSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"thought"},
+	"properties": map[string]interface{}{
+		"thought":   map[string]interface{}{"type": "string"},
+		"tool":      map[string]interface{}{"type": "string"},
+		"arguments": map[string]interface{}{"type": "object"},
+		"complete":  map[string]interface{}{"type": "boolean"},
+		"reason":    map[string]interface{}{"type": "string"},
+	},
+}
+
 // parseDecision extracts the model's JSON payload (or falls back to the raw
 // text) and normalizes it into the decisionPayload struct.
 func parseDecision(raw string) (decisionPayload, error) {
 	var payload decisionPayload
-	snippet := ExtractJSON(raw)
+	snippet := agentutil.ExtractJSON(raw)
 	if snippet == "{}" {
 		payload.Thought = strings.TrimSpace(raw)
 		payload.Complete = true
@@ -243,3 +243,84 @@ func TestReActAgentToolCalling(t *testing.T) {
 	}
 	assert.Equal(t, 1, toolMessages)
 }
+
+type lazyStubTool struct {
+	stubTool
+	active bool
+}
+
+func (t lazyStubTool) Activated() bool { return t.active }
+
+// TestReActPromptMarksLazyTools ensures tools whose backends start on first
+// call are flagged in the system prompt.
+func TestReActPromptMarksLazyTools(t *testing.T) {
+	node := &reactThinkNode{task: &framework.Task{}}
+	prompt := node.buildSystemPrompt([]framework.Tool{
+		lazyStubTool{stubTool: stubTool{name: "lsp_get_definition"}},
+		lazyStubTool{stubTool: stubTool{name: "ast_callers"}, active: true},
+		stubTool{name: "file_read"},
+	})
+	assert.Contains(t, prompt, "- lsp_get_definition: stub tool (activates on first call)")
+	assert.Contains(t, prompt, "- ast_callers: stub tool\n")
+	assert.NotContains(t, prompt, "file_read: stub tool (activates")
+}
+
+func TestReActAgentSizesBudgetFromModel(t *testing.T) {
+	agent := &ReActAgent{}
+	assert.NoError(t, agent.Initialize(&framework.Config{}))
+	assert.Equal(t, framework.DefaultContextWindow, agent.budget.MaxTokens)
+	assert.Equal(t, 2000, agent.budget.ReservedForTools)
+
+	assert.NoError(t, agent.Initialize(&framework.Config{ModelContext: framework.ModelContext{ContextLength: 16384}}))
+	assert.Equal(t, 16384, agent.budget.MaxTokens, "reinitializing after a model switch resizes the budget")
+	assert.Equal(t, 4096, agent.budget.ReservedForTools)
+}
+
+func TestReactObserveLoopsOnNewEditErrors(t *testing.T) {
+	agent := &ReActAgent{}
+	assert.NoError(t, agent.Initialize(&framework.Config{Model: "test-model", MaxIterations: 3}))
+	task := &framework.Task{ID: "task-3", Instruction: "edit"}
+	observe := &reactObserveNode{id: "observe", agent: agent, task: task}
+	state := framework.NewContext()
+	state.Set("react.decision", decisionPayload{Thought: "done", Complete: true})
+	saveReactMessages(state, []framework.Message{{Role: "user", Content: "Task: edit"}})
+	state.Set(framework.PostEditDiagnosticsKey, []framework.EditDiagnostic{
+		{File: "main.go", Line: 2, Message: "undefined: legacy"},
+		{File: "main.go", Line: 3, Message: "undefined: broken", New: true},
+	})
+	state.Set(framework.PostEditNewErrorsKey, 1)
+
+	result, err := observe.Execute(context.Background(), state)
+	assert.NoError(t, err)
+	assert.Equal(t, false, result.Data["complete"], "new errors keep the loop going")
+	assert.Contains(t, result.Data["diagnostic"], "- main.go:3 (new) undefined: broken")
+	assert.NotContains(t, result.Data["diagnostic"], "legacy")
+	messages := getReactMessages(state)
+	assert.Len(t, messages, 2)
+	assert.Contains(t, messages[1].Content, "undefined: broken")
+
+	result, err = observe.Execute(context.Background(), state)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result.Data["complete"], "errors are reported once per edit")
+}
+
+// TestReActFinalOutputCitesSources checks the final answer lists the files
+// the agent read.
+func TestReActFinalOutputCitesSources(t *testing.T) {
+	agent := &ReActAgent{Model: &stubLLM{}, Tools: framework.NewToolRegistry()}
+	assert.NoError(t, agent.Initialize(&framework.Config{Model: "test-model", MaxIterations: 2}))
+	task := &framework.Task{ID: "task-1", Instruction: "where is main defined?"}
+	state := framework.NewContext()
+	state.Set("react.decision", decisionPayload{Thought: "main is in main.go", Complete: true})
+	framework.RecordContextSources(state, framework.ContextSource{Kind: framework.SourceFile, Path: "main.go", StartLine: 1, EndLine: 12})
+
+	observe := &reactObserveNode{id: "observe", agent: agent, task: task}
+	_, err := observe.Execute(context.Background(), state)
+	assert.NoError(t, err)
+
+	final, ok := state.Get("react.final_output")
+	assert.True(t, ok)
+	output := final.(map[string]interface{})
+	assert.Contains(t, output["summary"], "Sources:\n- main.go:1-12\n")
+	assert.Equal(t, framework.ContextSources(state), output["sources"])
+}
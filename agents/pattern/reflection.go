@@ -4,30 +4,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/lexcodex/relurpify/framework"
 )
 
 // ReflectionAgent reviews outputs and triggers revisions when needed.
 type ReflectionAgent struct {
-	Reviewer      framework.LanguageModel
-	Delegate      framework.Agent
-	Config        *framework.Config
+	Reviewer framework.LanguageModel
+	Delegate framework.Agent
+	Config   *framework.Config
+	// Rounds caps delegate runs. Zero uses Config.MaxIterations, or 3.
+	Rounds int
+	// CrossReview marks Reviewer as a dedicated critic model: review calls
+	// keep the critic's own model instead of requesting Config.Model.
+	CrossReview   bool
 	maxIterations int
 }
 
 // Initialize configures the reviewer.
 func (a *ReflectionAgent) Initialize(cfg *framework.Config) error {
 	a.Config = cfg
-	if cfg.MaxIterations <= 0 {
-		a.maxIterations = 3
-	} else {
+	switch {
+	case a.Rounds > 0:
+		a.maxIterations = a.Rounds
+	case cfg.MaxIterations > 0:
 		a.maxIterations = cfg.MaxIterations
+	default:
+		a.maxIterations = 3
 	}
 	return nil
 }
 
-// Execute runs the review workflow.
+// Execute runs the review workflow and returns the last delegate result,
+// annotated with the final review and the number of rounds. When the
+// reviewer asks for a person and no framework.ConflictResolver is attached
+// to ctx, the run stops and the result carries the conflict.
 func (a *ReflectionAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
 	graph, err := a.BuildGraph(task)
 	if err != nil {
@@ -36,7 +48,53 @@ func (a *ReflectionAgent) Execute(ctx context.Context, task *framework.Task, sta
 	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
 		graph.SetTelemetry(cfg.Telemetry)
 	}
-	return graph.Execute(ctx, state)
+	// State outlives tasks, so a previous task's review must not steer
+	// this one.
+	state.Set("reflection.iteration", 0)
+	state.Set("reflection.review", nil)
+	state.Set("reflection.revise", false)
+	state.Set("reflection.task", nil)
+	state.Set("reflection.conflict", nil)
+	final, err := graph.Execute(ctx, state)
+	if err != nil {
+		return final, err
+	}
+	resultVal, _ := state.Get("reflection.last_result")
+	result, ok := resultVal.(*framework.Result)
+	if !ok || result == nil {
+		return final, nil
+	}
+	annotated := *result
+	annotated.Data = make(map[string]any, len(result.Data)+2)
+	for key, value := range result.Data {
+		annotated.Data[key] = value
+	}
+	if review, ok := lastReview(state); ok {
+		annotated.Data["review"] = review
+	}
+	iterVal, _ := state.Get("reflection.iteration")
+	annotated.Data["review_rounds"] = iterVal
+	if conflict, ok := state.Get("reflection.conflict"); ok && conflict != nil {
+		annotated.Data["conflict"] = conflict
+	}
+	return &annotated, nil
+}
+
+// currentTask returns the task as revised by conflict resolutions so far.
+func currentTask(state *framework.Context, task *framework.Task) *framework.Task {
+	if value, ok := state.Get("reflection.task"); ok {
+		if revised, ok := value.(*framework.Task); ok && revised != nil {
+			return revised
+		}
+	}
+	return task
+}
+
+// lastReview returns the review recorded by the most recent review node.
+func lastReview(state *framework.Context) (reviewPayload, bool) {
+	reviewVal, _ := state.Get("reflection.review")
+	review, ok := reviewVal.(reviewPayload)
+	return review, ok
 }
 
 // Capabilities returns capabilities.
@@ -55,7 +113,7 @@ func (a *ReflectionAgent) BuildGraph(task *framework.Task) (*framework.Graph, er
 	graph := framework.NewGraph()
 	run := &reflectionDelegateNode{id: "reflection_execute", agent: a, task: task}
 	review := &reflectionReviewNode{id: "reflection_review", agent: a, task: task}
-	decision := &reflectionDecisionNode{id: "reflection_decide", agent: a}
+	decision := &reflectionDecisionNode{id: "reflection_decide", agent: a, task: task}
 	done := framework.NewTerminalNode("reflection_done")
 	for _, node := range []framework.Node{run, review, decision, done} {
 		if err := graph.AddNode(node); err != nil {
@@ -83,6 +141,12 @@ func (a *ReflectionAgent) BuildGraph(task *framework.Task) (*framework.Graph, er
 	}, false); err != nil {
 		return nil, err
 	}
+	if err := graph.LabelEdge(decision.ID(), run.ID(), "reflection.revise"); err != nil {
+		return nil, err
+	}
+	if err := graph.LabelEdge(decision.ID(), done.ID(), "not reflection.revise"); err != nil {
+		return nil, err
+	}
 	return graph, nil
 }
 
@@ -101,11 +165,17 @@ func (n *reflectionDelegateNode) Type() framework.NodeType {
 }
 
 // Execute runs the delegate agent while isolating state mutations until the
-// child run succeeds.
+// child run succeeds. After a rejection the delegate also gets the review.
 func (n *reflectionDelegateNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
 	state.SetExecutionPhase("executing")
+	task := currentTask(state, n.task)
+	if review, ok := lastReview(state); ok && !review.Approve {
+		revised := *task
+		revised.Instruction = task.Instruction + "\n\n" + review.feedback()
+		task = &revised
+	}
 	child := state.Clone()
-	result, err := n.agent.Delegate.Execute(ctx, n.task, child)
+	result, err := n.agent.Delegate.Execute(ctx, task, child)
 	if err != nil {
 		return nil, err
 	}
@@ -133,20 +203,22 @@ func (n *reflectionReviewNode) Type() framework.NodeType {
 func (n *reflectionReviewNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
 	resultVal, _ := state.Get("reflection.last_result")
 	lastResult, _ := resultVal.(*framework.Result)
+	task := currentTask(state, n.task)
 	prompt := fmt.Sprintf(`Review the following result for task "%s".
-Consider correctness, completeness, quality, security, performance.
-Respond JSON {"issues":[{"severity":"high|medium|low","description":"...","suggestion":"..."}],"approve":bool}
-Result: %+v`, n.task.Instruction, lastResult)
-	resp, err := n.agent.Reviewer.Generate(ctx, prompt, &framework.LLMOptions{
-		Model:       n.agent.Config.Model,
+Consider correctness, completeness, quality, security, performance.%s
+Respond JSON {"issues":[{"severity":"high|medium|low","description":"...","suggestion":"..."}],"approve":bool,"require_human":bool,"conflict":"..."}
+Result: %+v`, task.Instruction, planReviewSection(task), lastResult)
+	model := n.agent.Config.Model
+	if n.agent.CrossReview {
+		prompt = crossReviewPrompt(task, lastResult)
+		model = ""
+	}
+	var review reviewPayload
+	if _, err := generateJSON(ctx, n.agent.Config, n.agent.Reviewer, "reflection.review", prompt, reviewSchema, framework.LLMOptions{
+		Model:       model,
 		Temperature: 0.2,
 		MaxTokens:   600,
-	})
-	if err != nil {
-		return nil, err
-	}
-	review, err := parseReview(resp.Text)
-	if err != nil {
+	}, &review); err != nil {
 		return nil, err
 	}
 	state.Set("reflection.review", review)
@@ -156,6 +228,7 @@ Result: %+v`, n.task.Instruction, lastResult)
 type reflectionDecisionNode struct {
 	id    string
 	agent *ReflectionAgent
+	task  *framework.Task
 }
 
 // ID returns the decision node identifier.
@@ -167,7 +240,9 @@ func (n *reflectionDecisionNode) Type() framework.NodeType {
 }
 
 // Execute inspects review feedback and decides if another delegate iteration
-// should run.
+// should run. A review that asks for a person is settled through the
+// framework.ConflictResolver on ctx; a clarification or revised plan runs
+// the delegate again even when the rounds are used up.
 func (n *reflectionDecisionNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
 	reviewVal, _ := state.Get("reflection.review")
 	review, _ := reviewVal.(reviewPayload)
@@ -176,10 +251,77 @@ func (n *reflectionDecisionNode) Execute(ctx context.Context, state *framework.C
 	iter++
 	state.Set("reflection.iteration", iter)
 	revise := !review.Approve && iter < n.agent.maxIterations
+	if review.RequireHuman {
+		var err error
+		if revise, err = n.resolve(ctx, state, review); err != nil {
+			return nil, err
+		}
+	}
 	state.Set("reflection.revise", revise)
 	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"revise": revise}}, nil
 }
 
+// resolve asks a person to settle the conflict review raised and reports
+// whether the delegate should run again.
+func (n *reflectionDecisionNode) resolve(ctx context.Context, state *framework.Context, review reviewPayload) (bool, error) {
+	task := currentTask(state, n.task)
+	conflict := review.conflict(task)
+	resolver, ok := framework.ConflictResolverFromContext(ctx)
+	if !ok {
+		state.Set("reflection.conflict", conflict)
+		return false, nil
+	}
+	resolution, err := resolver.ResolveConflict(ctx, task, conflict)
+	if err != nil {
+		return false, fmt.Errorf("resolve conflict: %w", err)
+	}
+	if resolution.Action == framework.ConflictAccept {
+		return false, nil
+	}
+	resumed, err := resolution.Resume(task)
+	if err != nil {
+		return false, fmt.Errorf("resolve conflict: %w", err)
+	}
+	state.Set("reflection.task", resumed)
+	return true, nil
+}
+
+// crossReviewPrompt asks the critic model to review another model's work on
+// task. It is phrased for a reviewer that did not write the code.
+func crossReviewPrompt(task *framework.Task, result *framework.Result) string {
+	var data []byte
+	if result != nil {
+		data, _ = json.MarshalIndent(result.Data, "", "  ")
+	}
+	return fmt.Sprintf(`You are reviewing changes another engineer made for this task:
+%s
+
+Their report of what they did:
+%s
+
+Approve only if the changes complete the task correctly. Otherwise list each
+problem with its severity and a concrete fix; the engineer will revise the
+work using your list. Do not flag style preferences as high severity.%s
+Respond JSON {"issues":[{"severity":"high|medium|low","description":"...","suggestion":"..."}],"approve":bool,"require_human":bool,"conflict":"..."}`, task.Instruction, data, planReviewSection(task))
+}
+
+// planReviewSection shows the reviewer the task's plan, when it has one,
+// and when to hand the decision to a person.
+func planReviewSection(task *framework.Task) string {
+	var b strings.Builder
+	if plan, ok := framework.PlanOf(task); ok {
+		if data, err := json.MarshalIndent(plan, "", "  "); err == nil {
+			fmt.Fprintf(&b, "\n\nThe work follows this plan:\n%s", data)
+		}
+	}
+	b.WriteString(`
+
+Set require_human and describe the problem in conflict only when the work
+cannot continue without the user deciding: the instruction contradicts the
+plan, or the changes diverge from the plan for a reason that may be valid.`)
+	return b.String()
+}
+
 type reviewPayload struct {
 	Issues []struct {
 		Severity    string `json:"severity"`
@@ -187,13 +329,60 @@ type reviewPayload struct {
 		Suggestion  string `json:"suggestion"`
 	} `json:"issues"`
 	Approve bool `json:"approve"`
+	// RequireHuman asks for a person to settle Conflict before the work
+	// continues.
+	RequireHuman bool   `json:"require_human,omitempty"`
+	Conflict     string `json:"conflict,omitempty"`
+}
+
+// conflict describes the review's request for a person.
+func (r reviewPayload) conflict(task *framework.Task) framework.Conflict {
+	conflict := framework.Conflict{Reason: r.Conflict}
+	if conflict.Reason == "" {
+		conflict.Reason = "The reviewer needs a decision before the work continues."
+	}
+	for _, issue := range r.Issues {
+		conflict.Issues = append(conflict.Issues, fmt.Sprintf("[%s] %s", issue.Severity, issue.Description))
+	}
+	conflict.Plan, _ = framework.PlanOf(task)
+	return conflict
 }
 
-// parseReview decodes the reviewer JSON into a strongly typed payload.
-func parseReview(raw string) (reviewPayload, error) {
-	var payload reviewPayload
-	if err := json.Unmarshal([]byte(ExtractJSON(raw)), &payload); err != nil {
-		return payload, err
+// feedback renders a rejection for the delegate's next attempt.
+func (r reviewPayload) feedback() string {
+	var b strings.Builder
+	b.WriteString("A reviewer rejected the previous attempt. Address these issues:")
+	if len(r.Issues) == 0 {
+		b.WriteString("\n- The reviewer gave no details; re-check the work against the task.")
 	}
-	return payload, nil
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&b, "\n- [%s] %s", issue.Severity, issue.Description)
+		if issue.Suggestion != "" {
+			fmt.Fprintf(&b, " (suggestion: %s)", issue.Suggestion)
+		}
+	}
+	return b.String()
+}
+
+// reviewSchema is the JSON schema of reviewPayload.
+var reviewSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"issues", "approve"},
+	"properties": map[string]interface{}{
+		"issues": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"severity", "description"},
+				"properties": map[string]interface{}{
+					"severity":    map[string]interface{}{"type": "string", "enum": []string{"high", "medium", "low"}},
+					"description": map[string]interface{}{"type": "string"},
+					"suggestion":  map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+		"approve":       map[string]interface{}{"type": "boolean"},
+		"require_human": map[string]interface{}{"type": "boolean"},
+		"conflict":      map[string]interface{}{"type": "string"},
+	},
 }
@@ -0,0 +1,125 @@
+package pattern
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// recordingAgent records the instructions it is asked to carry out.
+type recordingAgent struct {
+	instructions []string
+}
+
+func (a *recordingAgent) Initialize(cfg *framework.Config) error { return nil }
+
+func (a *recordingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	a.instructions = append(a.instructions, task.Instruction)
+	return &framework.Result{NodeID: "coder", Success: true, Data: map[string]any{"attempt": len(a.instructions)}}, nil
+}
+
+func (a *recordingAgent) Capabilities() []framework.Capability { return nil }
+
+func (a *recordingAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	return nil, errors.New("not implemented")
+}
+
+// criticLLM answers review prompts with queued verdicts and records the
+// model each call asked for.
+type criticLLM struct {
+	stubLLM
+	models []string
+}
+
+func (c *criticLLM) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	c.models = append(c.models, options.Model)
+	return c.stubLLM.Generate(ctx, prompt, options)
+}
+
+func TestReflectionAgentCrossReviewRevisesUntilApproved(t *testing.T) {
+	critic := &criticLLM{stubLLM: stubLLM{responses: []*framework.LLMResponse{
+		{Text: `{"issues":[{"severity":"high","description":"handler ignores errors","suggestion":"return 500"}],"approve":false}`},
+		{Text: `{"issues":[],"approve":true}`},
+		{Text: `{"issues":[],"approve":true}`},
+	}}}
+	coder := &recordingAgent{}
+	agent := &ReflectionAgent{Reviewer: critic, Delegate: coder, Rounds: 3, CrossReview: true}
+	require.NoError(t, agent.Initialize(&framework.Config{Model: "primary:14b", MaxIterations: 8}))
+	state := framework.NewContext()
+
+	res, err := agent.Execute(context.Background(), &framework.Task{Instruction: "add a users endpoint"}, state)
+	require.NoError(t, err)
+	require.Len(t, coder.instructions, 2)
+	assert.Equal(t, "add a users endpoint", coder.instructions[0])
+	assert.Contains(t, coder.instructions[1], "[high] handler ignores errors (suggestion: return 500)")
+	assert.Equal(t, []string{"", ""}, critic.models, "the critic keeps its own model")
+	assert.Equal(t, 2, res.Data["attempt"], "the last delegate result is returned")
+	assert.Equal(t, 2, res.Data["review_rounds"])
+	assert.True(t, res.Data["review"].(reviewPayload).Approve)
+
+	_, err = agent.Execute(context.Background(), &framework.Task{Instruction: "add a teams endpoint"}, state)
+	require.NoError(t, err)
+	assert.Equal(t, "add a teams endpoint", coder.instructions[2], "earlier reviews do not leak into the next task")
+}
+
+func TestReflectionAgentStopsAfterRounds(t *testing.T) {
+	reject := &framework.LLMResponse{Text: `{"issues":[],"approve":false}`}
+	critic := &stubLLM{responses: []*framework.LLMResponse{reject, reject, reject}}
+	coder := &recordingAgent{}
+	agent := &ReflectionAgent{Reviewer: critic, Delegate: coder, Rounds: 2}
+	require.NoError(t, agent.Initialize(&framework.Config{MaxIterations: 8}))
+
+	res, err := agent.Execute(context.Background(), &framework.Task{Instruction: "fix it"}, framework.NewContext())
+	require.NoError(t, err)
+	assert.Len(t, coder.instructions, 2)
+	assert.Contains(t, coder.instructions[1], "re-check the work against the task")
+	assert.False(t, res.Data["review"].(reviewPayload).Approve)
+}
+
+// scriptedResolver answers conflicts with a fixed resolution.
+type scriptedResolver struct {
+	resolution framework.ConflictResolution
+	conflicts  []framework.Conflict
+}
+
+func (r *scriptedResolver) ResolveConflict(ctx context.Context, task *framework.Task, conflict framework.Conflict) (framework.ConflictResolution, error) {
+	r.conflicts = append(r.conflicts, conflict)
+	return r.resolution, nil
+}
+
+func TestReflectionAgentResolvesConflictsWithAPerson(t *testing.T) {
+	needsHuman := &framework.LLMResponse{Text: `{"issues":[{"severity":"high","description":"routes still on v1"}],"approve":false,"require_human":true,"conflict":"the instruction asks for v2 but the plan targets v1"}`}
+	critic := &stubLLM{responses: []*framework.LLMResponse{needsHuman, {Text: `{"issues":[],"approve":true}`}}}
+	coder := &recordingAgent{}
+	agent := &ReflectionAgent{Reviewer: critic, Delegate: coder, Rounds: 1, CrossReview: true}
+	require.NoError(t, agent.Initialize(&framework.Config{}))
+	resolver := &scriptedResolver{resolution: framework.ConflictResolution{Action: framework.ConflictClarify, Clarification: "target v2"}}
+	plan := framework.Plan{Goal: "port routes", Steps: []framework.PlanStep{{ID: 1, Description: "update v1 routes"}}}
+	task := &framework.Task{Instruction: "move the routes to v2", Context: map[string]any{"plan": plan}}
+
+	res, err := agent.Execute(framework.WithConflictResolver(context.Background(), resolver), task, framework.NewContext())
+	require.NoError(t, err)
+	require.Len(t, resolver.conflicts, 1)
+	assert.Equal(t, "the instruction asks for v2 but the plan targets v1", resolver.conflicts[0].Reason)
+	assert.Equal(t, []string{"[high] routes still on v1"}, resolver.conflicts[0].Issues)
+	assert.Equal(t, &plan, resolver.conflicts[0].Plan)
+	require.Len(t, coder.instructions, 2, "a clarification resumes the task even after the last round")
+	assert.Contains(t, coder.instructions[1], "Clarification from the user: target v2")
+	assert.True(t, res.Data["review"].(reviewPayload).Approve)
+	assert.NotContains(t, res.Data, "conflict")
+	assert.NotContains(t, task.Context, framework.ClarificationsKey, "the caller's task is left alone")
+
+	critic = &stubLLM{responses: []*framework.LLMResponse{needsHuman}}
+	coder = &recordingAgent{}
+	agent = &ReflectionAgent{Reviewer: critic, Delegate: coder, Rounds: 3}
+	require.NoError(t, agent.Initialize(&framework.Config{}))
+	res, err = agent.Execute(context.Background(), task, framework.NewContext())
+	require.NoError(t, err)
+	assert.Len(t, coder.instructions, 1, "without a resolver the task stops")
+	assert.Equal(t, "the instruction asks for v2 but the plan targets v1", res.Data["conflict"].(framework.Conflict).Reason)
+}
@@ -0,0 +1,52 @@
+package pattern
+
+import (
+	"context"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// retryWithConfig runs fn under cfg's retry policy and telemetry. A nil cfg
+// uses the default policy without telemetry.
+func retryWithConfig(ctx context.Context, cfg *framework.Config, op string, fn func() error) error {
+	var policy framework.RetryPolicy
+	var telemetry framework.Telemetry
+	if cfg != nil {
+		policy = cfg.Retry
+		telemetry = cfg.Telemetry
+	}
+	return framework.Retry(ctx, policy, telemetry, op, fn)
+}
+
+// executeToolWithConfig runs tool, retrying transient failures of tools
+// that are safe to run again (see framework.ToolIdempotent), and bounds its
+// result with cfg's tool output policy. Tools report ordinary failures
+// through ToolResult, so only returned errors are retried.
+func executeToolWithConfig(ctx context.Context, cfg *framework.Config, state *framework.Context, tool framework.Tool, args map[string]interface{}) (*framework.ToolResult, error) {
+	var res *framework.ToolResult
+	run := func() error {
+		var execErr error
+		res, execErr = tool.Execute(ctx, state, args)
+		return execErr
+	}
+	var err error
+	if framework.ToolIdempotent(tool) {
+		var retry framework.RetryPolicy
+		var telemetry framework.Telemetry
+		if cfg != nil {
+			retry = cfg.Retry
+			telemetry = cfg.Telemetry
+		}
+		err = framework.Retry(ctx, retry.ForTools(), telemetry, "tool."+tool.Name(), run)
+	} else {
+		err = run()
+	}
+	if err != nil {
+		return res, err
+	}
+	var policy framework.ToolOutputPolicy
+	if cfg != nil {
+		policy = cfg.ToolOutput
+	}
+	return policy.Apply(tool.Name(), res), nil
+}
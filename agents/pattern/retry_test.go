@@ -0,0 +1,134 @@
+package pattern
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// flakyLLM fails its first calls with a connection error before delegating
+// to the wrapped stub.
+type flakyLLM struct {
+	*stubLLM
+	failures int
+}
+
+func (f *flakyLLM) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, syscall.ECONNREFUSED
+	}
+	return f.stubLLM.Generate(ctx, prompt, options)
+}
+
+func retryTestConfig() *framework.Config {
+	return &framework.Config{
+		Model: "test-model",
+		Retry: framework.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	}
+}
+
+func TestReActThinkRetriesTransientLLMErrors(t *testing.T) {
+	llm := &flakyLLM{
+		stubLLM:  &stubLLM{responses: []*framework.LLMResponse{{Text: `{"thought":"done","tool":"none","complete":true}`}}},
+		failures: 2,
+	}
+	agent := &ReActAgent{Model: llm, Tools: framework.NewToolRegistry()}
+	assert.NoError(t, agent.Initialize(retryTestConfig()))
+
+	think := &reactThinkNode{id: "think", agent: agent, task: &framework.Task{Instruction: "do something"}}
+	_, err := think.Execute(context.Background(), framework.NewContext())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, llm.generateCalls)
+	assert.Equal(t, 0, llm.failures)
+}
+
+func TestPlannerRegeneratesMalformedPlan(t *testing.T) {
+	llm := &stubLLM{responses: []*framework.LLMResponse{
+		{Text: `{"goal": "Add caching", "steps": [}`},
+		{Text: exportPlanJSON},
+	}}
+	agent := &PlannerAgent{Model: llm, Tools: framework.NewToolRegistry()}
+	assert.NoError(t, agent.Initialize(retryTestConfig()))
+
+	plan := &plannerPlanNode{id: "plan", agent: agent, task: &framework.Task{Instruction: "Add caching"}}
+	state := framework.NewContext()
+	_, err := plan.Execute(context.Background(), state)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, llm.generateCalls)
+	raw, _ := state.Get("planner.plan")
+	assert.Equal(t, "Add caching", raw.(framework.Plan).Goal)
+}
+
+func TestReActActTruncatesLargeToolResults(t *testing.T) {
+	tools := framework.NewToolRegistry()
+	assert.NoError(t, tools.Register(stubTool{name: "echo"}))
+	cfg := retryTestConfig()
+	cfg.ToolOutput = framework.ToolOutputPolicy{MaxBytes: 1024}
+	agent := &ReActAgent{Model: &stubLLM{}, Tools: tools}
+	assert.NoError(t, agent.Initialize(cfg))
+
+	state := framework.NewContext()
+	state.Set("react.decision", decisionPayload{Tool: "echo", Arguments: map[string]interface{}{"value": strings.Repeat("line\n", 1000)}})
+	act := &reactActNode{id: "act", agent: agent}
+	res, err := act.Execute(context.Background(), state)
+	require.NoError(t, err)
+	echo, _ := res.Data["echo"].(string)
+	assert.LessOrEqual(t, len(echo), 1024)
+	assert.Contains(t, echo, "[truncated ")
+}
+
+// flakyTool fails its first calls with err. Writes marks it as changing the
+// workspace.
+type flakyTool struct {
+	stubTool
+	writes   bool
+	err      error
+	failures int
+	calls    int
+}
+
+func (f *flakyTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return nil, f.err
+	}
+	return f.stubTool.Execute(ctx, state, args)
+}
+
+func (f *flakyTool) Permissions() framework.ToolPermissions {
+	if !f.writes {
+		return f.stubTool.Permissions()
+	}
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemWrite)}
+}
+
+func TestExecuteToolRetriesOnlyIdempotentTools(t *testing.T) {
+	cfg := retryTestConfig()
+	ctx := context.Background()
+
+	read := &flakyTool{stubTool: stubTool{name: "read"}, err: syscall.ECONNRESET, failures: 2}
+	_, err := executeToolWithConfig(ctx, cfg, framework.NewContext(), read, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, read.calls)
+
+	write := &flakyTool{stubTool: stubTool{name: "write"}, writes: true, err: syscall.ECONNRESET, failures: 2}
+	_, err = executeToolWithConfig(ctx, cfg, framework.NewContext(), write, nil)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, 1, write.calls, "a tool with side effects runs once")
+
+	var syntaxErr error = &json.SyntaxError{}
+	decode := &flakyTool{stubTool: stubTool{name: "read"}, err: syntaxErr, failures: 2}
+	_, err = executeToolWithConfig(ctx, cfg, framework.NewContext(), decode, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, decode.calls, "malformed JSON is only retried for model calls")
+}
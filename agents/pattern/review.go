@@ -0,0 +1,402 @@
+package pattern
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ReviewAgent reviews a unified diff and returns inline comments anchored to
+// the changed lines. It reads the diff from the "diff" task context entry,
+// falling back to the instruction, and never touches the workspace.
+type ReviewAgent struct {
+	Model  framework.LanguageModel
+	Config *framework.Config
+	// MaxDiffBytes bounds how much of the diff reaches the prompt.
+	MaxDiffBytes int
+}
+
+// ReviewSeverity ranks a review comment.
+type ReviewSeverity string
+
+const (
+	ReviewSeverityInfo    ReviewSeverity = "info"
+	ReviewSeverityWarning ReviewSeverity = "warning"
+	ReviewSeverityError   ReviewSeverity = "error"
+)
+
+// ReviewComment is one inline comment on lines of the new side of a diff.
+type ReviewComment struct {
+	File      string         `json:"file"`
+	StartLine int            `json:"start_line"`
+	EndLine   int            `json:"end_line"`
+	Severity  ReviewSeverity `json:"severity"`
+	Body      string         `json:"body"`
+	// Suggestion, when set, is replacement code for the commented lines.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// CodeReview is the structured output of the review pipeline.
+type CodeReview struct {
+	Summary  string          `json:"summary"`
+	Comments []ReviewComment `json:"comments"`
+}
+
+// DiffLineRange is an inclusive range of new-side lines covered by a hunk.
+type DiffLineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func init() {
+	gob.Register(CodeReview{})
+	gob.Register(ReviewComment{})
+	gob.Register([]ReviewComment{})
+	gob.Register(map[string][]DiffLineRange{})
+}
+
+const reviewDefaultDiffBytes = 24000
+
+// Initialize configures the agent.
+func (a *ReviewAgent) Initialize(cfg *framework.Config) error {
+	a.Config = cfg
+	if a.MaxDiffBytes <= 0 {
+		a.MaxDiffBytes = reviewDefaultDiffBytes
+	}
+	return nil
+}
+
+// Execute runs the review workflow.
+func (a *ReviewAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph, err := a.BuildGraph(task)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
+		graph.SetTelemetry(cfg.Telemetry)
+	}
+	return graph.Execute(ctx, state)
+}
+
+// Capabilities enumerates features.
+func (a *ReviewAgent) Capabilities() []framework.Capability {
+	return []framework.Capability{framework.CapabilityReview}
+}
+
+// BuildGraph wires diff → comment.
+func (a *ReviewAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	if a.Model == nil {
+		return nil, fmt.Errorf("review agent missing model")
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task required")
+	}
+	graph := framework.NewGraph()
+	diff := &reviewDiffNode{id: "review_diff", task: task}
+	comment := &reviewCommentNode{id: "review_comment", agent: a, task: task}
+	done := framework.NewTerminalNode("review_done")
+	for _, node := range []framework.Node{diff, comment, done} {
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(diff.ID()); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(diff.ID(), comment.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	if err := graph.AddEdge(comment.ID(), done.ID(), nil, false); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+type reviewDiffNode struct {
+	id   string
+	task *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *reviewDiffNode) ID() string { return n.id }
+
+// Type marks the node as a system step.
+func (n *reviewDiffNode) Type() framework.NodeType { return framework.NodeTypeSystem }
+
+// Execute parses the diff into the changed line ranges of each file.
+func (n *reviewDiffNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("reading diff")
+	diff := taskString(n.task, "diff")
+	if diff == "" {
+		diff = strings.TrimSpace(n.task.Instruction)
+	}
+	hunks := DiffHunks(diff)
+	if len(hunks) == 0 {
+		return nil, errors.New("review: no changed lines in diff")
+	}
+	state.Set("review.diff", diff)
+	state.Set("review.hunks", hunks)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"files": len(hunks)}}, nil
+}
+
+type reviewCommentNode struct {
+	id    string
+	agent *ReviewAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *reviewCommentNode) ID() string { return n.id }
+
+// Type marks the node as a system step.
+func (n *reviewCommentNode) Type() framework.NodeType { return framework.NodeTypeSystem }
+
+// Execute asks the model for comments and keeps those that land on changed
+// lines.
+func (n *reviewCommentNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("reviewing")
+	diff := state.GetString("review.diff")
+	if len(diff) > n.agent.MaxDiffBytes {
+		diff = diff[:n.agent.MaxDiffBytes] + "\n... (truncated)"
+	}
+	raw, _ := state.Get("review.hunks")
+	hunks, _ := raw.(map[string][]DiffLineRange)
+
+	var prompt strings.Builder
+	prompt.WriteString("You are a careful code reviewer. Review the diff below for bugs, missing error handling, security problems, unclear code, and missing tests.\n")
+	prompt.WriteString("Comment only on added or changed lines, using line numbers of the new file. Skip praise and style nits a formatter would fix.\n")
+	prompt.WriteString("Severity is error for bugs that must be fixed, warning for likely problems, and info for suggestions.\n")
+	prompt.WriteString("Set suggestion only to replacement code for exactly the commented lines.\n")
+	if focus := taskString(n.task, "review_focus"); focus != "" {
+		fmt.Fprintf(&prompt, "Focus: %s\n", focus)
+	}
+	fmt.Fprintf(&prompt, "Diff:\n```diff\n%s\n```\n", diff)
+	prompt.WriteString(`Return JSON {"summary": string, "comments": [{"file": string, "start_line": int, "end_line": int, "severity": "info"|"warning"|"error", "body": string, "suggestion": string}]}.`)
+
+	model := ""
+	if n.agent.Config != nil {
+		model = n.agent.Config.Model
+	}
+	// A response that never matches the schema becomes the summary.
+	var review CodeReview
+	resp, err := generateJSON(ctx, n.agent.Config, n.agent.Model, "review.comments", prompt.String(), diffReviewSchema, framework.LLMOptions{
+		Model:       model,
+		Temperature: 0.1,
+		MaxTokens:   2000,
+	}, &review)
+	var invalid *framework.StructuredOutputError
+	if err != nil && !errors.As(err, &invalid) {
+		return nil, err
+	}
+	state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
+	if invalid != nil {
+		review = CodeReview{Summary: strings.TrimSpace(resp.Text)}
+	}
+	review.Comments = anchorComments(review.Comments, hunks)
+	state.Set("review.result", review)
+	state.Set("review.final_output", review.Markdown())
+	return &framework.Result{
+		NodeID:  n.id,
+		Success: true,
+		Data: map[string]interface{}{
+			"review":       review,
+			"final_output": review.Markdown(),
+		},
+	}, nil
+}
+
+// diffReviewSchema is the JSON schema of the review the model returns.
+var diffReviewSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"summary", "comments"},
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{"type": "string"},
+		"comments": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"file", "start_line", "severity", "body"},
+				"properties": map[string]interface{}{
+					"file":       map[string]interface{}{"type": "string"},
+					"start_line": map[string]interface{}{"type": "integer"},
+					"end_line":   map[string]interface{}{"type": "integer"},
+					"severity":   map[string]interface{}{"type": "string", "enum": []string{"info", "warning", "error"}},
+					"body":       map[string]interface{}{"type": "string"},
+					"suggestion": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	},
+}
+
+// anchorComments keeps comments on files in the diff and clamps each to the
+// hunk containing its start line, so every comment can be posted inline.
+// Comments are ordered by file and line.
+func anchorComments(comments []ReviewComment, hunks map[string][]DiffLineRange) []ReviewComment {
+	var anchored []ReviewComment
+	for _, comment := range comments {
+		comment.File = strings.TrimPrefix(strings.TrimPrefix(comment.File, "a/"), "b/")
+		if comment.Body == "" {
+			continue
+		}
+		if comment.EndLine < comment.StartLine {
+			comment.EndLine = comment.StartLine
+		}
+		switch comment.Severity {
+		case ReviewSeverityInfo, ReviewSeverityWarning, ReviewSeverityError:
+		default:
+			comment.Severity = ReviewSeverityInfo
+		}
+		for _, r := range hunks[comment.File] {
+			if comment.StartLine < r.Start || comment.StartLine > r.End {
+				continue
+			}
+			if comment.EndLine > r.End {
+				comment.EndLine = r.End
+				comment.Suggestion = ""
+			}
+			anchored = append(anchored, comment)
+			break
+		}
+	}
+	sort.SliceStable(anchored, func(i, j int) bool {
+		if anchored[i].File != anchored[j].File {
+			return anchored[i].File < anchored[j].File
+		}
+		return anchored[i].StartLine < anchored[j].StartLine
+	})
+	return anchored
+}
+
+// DiffHunks maps each file a unified diff adds or changes to the new-side
+// line ranges of its hunks. Deleted files are left out.
+func DiffHunks(diff string) map[string][]DiffLineRange {
+	hunks := make(map[string][]DiffLineRange)
+	file := ""
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+			if tab := strings.IndexByte(file, '\t'); tab >= 0 {
+				file = file[:tab]
+			}
+			if file == "/dev/null" {
+				file = ""
+			}
+			file = strings.TrimPrefix(file, "b/")
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			if r, ok := parseHunkHeader(line); ok {
+				hunks[file] = append(hunks[file], r)
+			}
+		}
+	}
+	return hunks
+}
+
+// parseHunkHeader reads the new-side range of "@@ -a,b +c,d @@".
+func parseHunkHeader(line string) (DiffLineRange, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return DiffLineRange{}, false
+	}
+	spec := strings.TrimPrefix(fields[2], "+")
+	count := 1
+	if comma := strings.IndexByte(spec, ','); comma >= 0 {
+		n, err := strconv.Atoi(spec[comma+1:])
+		if err != nil {
+			return DiffLineRange{}, false
+		}
+		count = n
+		spec = spec[:comma]
+	}
+	start, err := strconv.Atoi(spec)
+	if err != nil || count == 0 {
+		return DiffLineRange{}, false
+	}
+	return DiffLineRange{Start: start, End: start + count - 1}, true
+}
+
+// Markdown renders the review for a terminal or a PR description.
+func (r CodeReview) Markdown() string {
+	var b strings.Builder
+	if r.Summary != "" {
+		b.WriteString(r.Summary)
+		b.WriteString("\n")
+	}
+	if len(r.Comments) == 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("No comments.\n")
+		return b.String()
+	}
+	for _, comment := range r.Comments {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "**%s** `%s`\n\n%s\n", comment.Severity, comment.Location(), comment.Body)
+		if comment.Suggestion != "" {
+			fmt.Fprintf(&b, "\n```\n%s\n```\n", strings.TrimRight(comment.Suggestion, "\n"))
+		}
+	}
+	return b.String()
+}
+
+// Location renders the comment's file and line range as file:start[-end].
+func (c ReviewComment) Location() string {
+	if c.EndLine > c.StartLine {
+		return fmt.Sprintf("%s:%d-%d", c.File, c.StartLine, c.EndLine)
+	}
+	return fmt.Sprintf("%s:%d", c.File, c.StartLine)
+}
+
+// GitHubReview is the request body of GitHub's create-review endpoint
+// (POST /repos/{owner}/{repo}/pulls/{number}/reviews).
+type GitHubReview struct {
+	Body     string                `json:"body"`
+	Event    string                `json:"event"`
+	Comments []GitHubReviewComment `json:"comments"`
+}
+
+// GitHubReviewComment is one inline comment of a GitHubReview.
+type GitHubReviewComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Body      string `json:"body"`
+}
+
+// GitHubReview converts the review into a GitHub PR review. It requests
+// changes when any comment is an error and only comments otherwise;
+// suggestions become suggested changes.
+func (r CodeReview) GitHubReview() GitHubReview {
+	review := GitHubReview{Body: r.Summary, Event: "COMMENT", Comments: []GitHubReviewComment{}}
+	for _, comment := range r.Comments {
+		if comment.Severity == ReviewSeverityError {
+			review.Event = "REQUEST_CHANGES"
+		}
+		body := fmt.Sprintf("**%s**: %s", comment.Severity, comment.Body)
+		if comment.Suggestion != "" {
+			body += fmt.Sprintf("\n\n```suggestion\n%s\n```", strings.TrimRight(comment.Suggestion, "\n"))
+		}
+		inline := GitHubReviewComment{Path: comment.File, Line: comment.EndLine, Side: "RIGHT", Body: body}
+		if comment.EndLine > comment.StartLine {
+			inline.StartLine = comment.StartLine
+			inline.StartSide = "RIGHT"
+		}
+		review.Comments = append(review.Comments, inline)
+	}
+	return review
+}
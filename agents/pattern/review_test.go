@@ -0,0 +1,75 @@
+package pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const reviewTestDiff = `diff --git a/server.go b/server.go
+index 1111111..2222222 100644
+--- a/server.go
++++ b/server.go
+@@ -10,3 +10,6 @@ func serve() {
+ 	conn := accept()
++	data, _ := read(conn)
++	handle(data)
++	conn.Close()
+ 	return
+ }
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-
+`
+
+func TestDiffHunksTracksNewSideRanges(t *testing.T) {
+	assert.Equal(t, map[string][]DiffLineRange{"server.go": {{Start: 10, End: 15}}}, DiffHunks(reviewTestDiff))
+}
+
+// TestReviewAgentAnchorsComments keeps comments on changed lines, clamps
+// ranges that run past a hunk, and renders both output formats.
+func TestReviewAgentAnchorsComments(t *testing.T) {
+	llm := &stubLLM{responses: []*framework.LLMResponse{{Text: `{"summary":"Errors are dropped.","comments":[
+		{"file":"b/server.go","start_line":11,"end_line":11,"severity":"error","body":"The read error is ignored.","suggestion":"data, err := read(conn)"},
+		{"file":"server.go","start_line":13,"end_line":40,"severity":"info","body":"Defer the close.","suggestion":"defer conn.Close()"},
+		{"file":"server.go","start_line":90,"severity":"warning","body":"Outside the diff."},
+		{"file":"other.go","start_line":1,"severity":"info","body":"Not in the diff."}
+	]}`}}}
+	agent := &ReviewAgent{Model: llm}
+	require.NoError(t, agent.Initialize(&framework.Config{Model: "test-model"}))
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeReview, Context: map[string]any{"diff": reviewTestDiff}}, state)
+	require.NoError(t, err)
+
+	raw, ok := state.Get("review.result")
+	require.True(t, ok)
+	review := raw.(CodeReview)
+	assert.Equal(t, []ReviewComment{
+		{File: "server.go", StartLine: 11, EndLine: 11, Severity: ReviewSeverityError, Body: "The read error is ignored.", Suggestion: "data, err := read(conn)"},
+		{File: "server.go", StartLine: 13, EndLine: 15, Severity: ReviewSeverityInfo, Body: "Defer the close."},
+	}, review.Comments)
+
+	assert.Equal(t, "Errors are dropped.\n\n**error** `server.go:11`\n\nThe read error is ignored.\n\n```\ndata, err := read(conn)\n```\n\n**info** `server.go:13-15`\n\nDefer the close.\n", review.Markdown())
+
+	gh := review.GitHubReview()
+	assert.Equal(t, "REQUEST_CHANGES", gh.Event)
+	assert.Equal(t, []GitHubReviewComment{
+		{Path: "server.go", Line: 11, Side: "RIGHT", Body: "**error**: The read error is ignored.\n\n```suggestion\ndata, err := read(conn)\n```"},
+		{Path: "server.go", Line: 15, Side: "RIGHT", StartLine: 13, StartSide: "RIGHT", Body: "**info**: Defer the close."},
+	}, gh.Comments)
+}
+
+func TestReviewAgentRejectsEmptyDiff(t *testing.T) {
+	agent := &ReviewAgent{Model: &stubLLM{}}
+	require.NoError(t, agent.Initialize(nil))
+	_, err := agent.Execute(context.Background(), &framework.Task{Instruction: "looks fine"}, framework.NewContext())
+	assert.ErrorContains(t, err, "no changed lines")
+}
@@ -0,0 +1,29 @@
+package pattern
+
+import (
+	"context"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// generateJSON asks model for JSON matching schema and decodes it into out,
+// under cfg's structured output policy. Each request is retried on
+// transient failures; responses that do not match the schema are sent back
+// with the validation error. op names the call in retry telemetry.
+func generateJSON(ctx context.Context, cfg *framework.Config, model framework.LanguageModel, op, prompt string, schema map[string]interface{}, opts framework.LLMOptions, out interface{}) (*framework.LLMResponse, error) {
+	var policy framework.StructuredOutputPolicy
+	if cfg != nil {
+		policy = cfg.StructuredOutput
+	}
+	return policy.GenerateJSON(ctx, schema, prompt, func(ctx context.Context, prompt string, schema map[string]interface{}) (*framework.LLMResponse, error) {
+		options := opts
+		options.Schema = schema
+		var resp *framework.LLMResponse
+		err := retryWithConfig(ctx, cfg, op, func() error {
+			var callErr error
+			resp, callErr = model.Generate(ctx, prompt, &options)
+			return callErr
+		})
+		return resp, err
+	}, out)
+}
@@ -0,0 +1,320 @@
+package pattern
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TestGenAgent writes tests for the functions coverage reports mark as
+// untested. It measures coverage with the coverage_gaps tool, hands the
+// largest gaps to Writer, and reruns the tests afterwards: the task fails when
+// the new tests fail, and otherwise reports how far coverage moved.
+type TestGenAgent struct {
+	// Writer edits the workspace; usually a ReAct agent with write access.
+	Writer framework.Agent
+	Tools  *framework.ToolRegistry
+	Config *framework.Config
+	// MaxTargets bounds how many functions one run writes tests for.
+	MaxTargets int
+}
+
+// TestGenTarget is one function (or, without AST data, one file region) with
+// uncovered statements.
+type TestGenTarget struct {
+	File       string `json:"file"`
+	Function   string `json:"function,omitempty"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Covered    int    `json:"covered"`
+	Statements int    `json:"statements"`
+}
+
+// TestGenReport compares coverage before and after the tests were written.
+type TestGenReport struct {
+	Before  float64         `json:"before"`
+	After   float64         `json:"after"`
+	Delta   float64         `json:"delta"`
+	Targets []TestGenTarget `json:"targets"`
+	// Remaining holds the targets as measured after the run; targets left
+	// out are now fully covered.
+	Remaining []TestGenTarget `json:"remaining,omitempty"`
+	Failures  []string        `json:"failures,omitempty"`
+}
+
+func init() {
+	gob.Register(TestGenTarget{})
+	gob.Register([]TestGenTarget{})
+	gob.Register(TestGenReport{})
+}
+
+const (
+	testGenCoverageTool    = "coverage_gaps"
+	testGenDefaultTargets  = 5
+	testGenVerifyGapsLimit = 1000
+)
+
+// Initialize configures the agent and its writer.
+func (a *TestGenAgent) Initialize(cfg *framework.Config) error {
+	a.Config = cfg
+	if a.Tools == nil {
+		a.Tools = framework.NewToolRegistry()
+	}
+	if a.MaxTargets <= 0 {
+		a.MaxTargets = testGenDefaultTargets
+	}
+	if a.Writer != nil {
+		return a.Writer.Initialize(cfg)
+	}
+	return nil
+}
+
+// Execute runs the test generation workflow.
+func (a *TestGenAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph, err := a.BuildGraph(task)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := a.Config; cfg != nil && cfg.Telemetry != nil {
+		graph.SetTelemetry(cfg.Telemetry)
+	}
+	return graph.Execute(ctx, state)
+}
+
+// Capabilities enumerates features.
+func (a *TestGenAgent) Capabilities() []framework.Capability {
+	return []framework.Capability{framework.CapabilityCode, framework.CapabilityExecute}
+}
+
+// BuildGraph wires measure → write → verify.
+func (a *TestGenAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	if a.Writer == nil {
+		return nil, fmt.Errorf("test generation agent missing writer")
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task required")
+	}
+	graph := framework.NewGraph()
+	measure := &testGenMeasureNode{id: "testgen_measure", agent: a, task: task}
+	write := &testGenWriteNode{id: "testgen_write", agent: a, task: task}
+	verify := &testGenVerifyNode{id: "testgen_verify", agent: a, task: task}
+	done := framework.NewTerminalNode("testgen_done")
+	for _, node := range []framework.Node{measure, write, verify, done} {
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(measure.ID()); err != nil {
+		return nil, err
+	}
+	for _, edge := range [][2]string{{measure.ID(), write.ID()}, {write.ID(), verify.ID()}, {verify.ID(), done.ID()}} {
+		if err := graph.AddEdge(edge[0], edge[1], nil, false); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+// measure runs coverage_gaps with the task's target and language.
+func (a *TestGenAgent) measure(ctx context.Context, task *framework.Task, state *framework.Context, limit int) (*framework.ToolResult, float64, []TestGenTarget, error) {
+	tool, ok := a.Tools.Get(testGenCoverageTool)
+	if !ok {
+		return nil, 0, nil, fmt.Errorf("tool %s not registered", testGenCoverageTool)
+	}
+	args := map[string]interface{}{"limit": limit}
+	for _, key := range []string{"target", "language"} {
+		if value := taskString(task, key); value != "" {
+			args[key] = value
+		}
+	}
+	res, err := tool.Execute(ctx, state, args)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	percent, _ := res.Data["coverage"].(float64)
+	return res, percent, coverageTargets(res.Data["gaps"]), nil
+}
+
+// coverageTargets reads the gaps list of a coverage_gaps result.
+func coverageTargets(raw interface{}) []TestGenTarget {
+	gaps, _ := raw.([]map[string]interface{})
+	targets := make([]TestGenTarget, 0, len(gaps))
+	for _, gap := range gaps {
+		targets = append(targets, TestGenTarget{
+			File:       fmt.Sprint(gap["file"]),
+			Function:   strings.TrimSpace(fmt.Sprint(gap["function"])),
+			StartLine:  intValue(gap["start_line"]),
+			EndLine:    intValue(gap["end_line"]),
+			Covered:    intValue(gap["covered"]),
+			Statements: intValue(gap["statements"]),
+		})
+	}
+	return targets
+}
+
+func intValue(raw interface{}) int {
+	switch v := raw.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		n, _ := strconv.Atoi(fmt.Sprint(raw))
+		return n
+	}
+}
+
+// Label renders the target as file:function or file:start-end.
+func (t TestGenTarget) Label() string {
+	if t.Function != "" {
+		return t.File + ":" + t.Function
+	}
+	return fmt.Sprintf("%s:%d-%d", t.File, t.StartLine, t.EndLine)
+}
+
+type testGenMeasureNode struct {
+	id    string
+	agent *TestGenAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *testGenMeasureNode) ID() string { return n.id }
+
+// Type marks the node as tool-backed.
+func (n *testGenMeasureNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute records the baseline coverage and picks the functions to test. It
+// refuses to start from a red suite, since the gate could not tell the new
+// tests' failures apart.
+func (n *testGenMeasureNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("measuring coverage")
+	res, percent, targets, err := n.agent.measure(ctx, n.task, state, n.agent.MaxTargets)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Success {
+		return nil, fmt.Errorf("testgen: the existing tests fail, fix them first: %s", res.Error)
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("testgen: no uncovered functions found")
+	}
+	state.Set("testgen.before", percent)
+	state.Set("testgen.targets", targets)
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"coverage": percent, "targets": len(targets)}}, nil
+}
+
+type testGenWriteNode struct {
+	id    string
+	agent *TestGenAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *testGenWriteNode) ID() string { return n.id }
+
+// Type marks the node as a system step.
+func (n *testGenWriteNode) Type() framework.NodeType { return framework.NodeTypeSystem }
+
+// Execute asks the writer for tests covering each target.
+func (n *testGenWriteNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("writing tests")
+	raw, _ := state.Get("testgen.targets")
+	targets, _ := raw.([]TestGenTarget)
+
+	var instruction strings.Builder
+	instruction.WriteString("Write tests for the functions below, which no test currently executes. ")
+	instruction.WriteString("Follow the test layout and helpers the package already uses, exercise the uncovered branches, and do not change the code under test.\n")
+	for _, target := range targets {
+		fmt.Fprintf(&instruction, "- %s (lines %d-%d, %d of %d statements covered)\n", target.Label(), target.StartLine, target.EndLine, target.Covered, target.Statements)
+	}
+	if extra := strings.TrimSpace(n.task.Instruction); extra != "" {
+		fmt.Fprintf(&instruction, "\n%s\n", extra)
+	}
+	task := *n.task
+	task.Type = framework.TaskTypeCodeGeneration
+	task.Instruction = instruction.String()
+	child := state.Clone()
+	result, err := n.agent.Writer.Execute(ctx, &task, child)
+	if err != nil {
+		return nil, err
+	}
+	state.Merge(child)
+	if result == nil {
+		result = &framework.Result{Success: true}
+	}
+	result.NodeID = n.id
+	return result, nil
+}
+
+type testGenVerifyNode struct {
+	id    string
+	agent *TestGenAgent
+	task  *framework.Task
+}
+
+// ID returns the node identifier.
+func (n *testGenVerifyNode) ID() string { return n.id }
+
+// Type marks the node as tool-backed.
+func (n *testGenVerifyNode) Type() framework.NodeType { return framework.NodeTypeTool }
+
+// Execute reruns the tests with coverage and reports the delta. Failing
+// tests fail the task; the report is stored either way.
+func (n *testGenVerifyNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	state.SetExecutionPhase("verifying tests")
+	res, after, gaps, err := n.agent.measure(ctx, n.task, state, testGenVerifyGapsLimit)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := state.Get("testgen.targets")
+	targets, _ := raw.([]TestGenTarget)
+	before, _ := state.Get("testgen.before")
+	report := TestGenReport{After: after, Targets: targets}
+	report.Before, _ = before.(float64)
+	report.Delta = report.After - report.Before
+	remaining := make(map[string]TestGenTarget, len(gaps))
+	for _, gap := range gaps {
+		remaining[gap.Label()] = gap
+	}
+	for _, target := range targets {
+		if gap, ok := remaining[target.Label()]; ok {
+			report.Remaining = append(report.Remaining, gap)
+		}
+	}
+	if !res.Success {
+		report.Failures = append(report.Failures, res.Error)
+	}
+	state.Set("testgen.report", report)
+	state.Set("testgen.final_output", report.Markdown())
+	if !res.Success {
+		return nil, fmt.Errorf("testgen: the new tests fail: %s", res.Error)
+	}
+	return &framework.Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"before": report.Before, "after": report.After, "delta": report.Delta}}, nil
+}
+
+// Markdown renders the report for the CLI and TUI.
+func (r TestGenReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Coverage %.1f%% → %.1f%% (%+.1f points)\n\n", r.Before, r.After, r.Delta)
+	remaining := make(map[string]TestGenTarget, len(r.Remaining))
+	for _, target := range r.Remaining {
+		remaining[target.Label()] = target
+	}
+	for _, target := range r.Targets {
+		if now, ok := remaining[target.Label()]; ok {
+			fmt.Fprintf(&b, "- `%s` %d/%d → %d/%d statements\n", target.Label(), target.Covered, target.Statements, now.Covered, now.Statements)
+			continue
+		}
+		fmt.Fprintf(&b, "- `%s` fully covered\n", target.Label())
+	}
+	for _, failure := range r.Failures {
+		fmt.Fprintf(&b, "\nFailing: %s\n", failure)
+	}
+	return b.String()
+}
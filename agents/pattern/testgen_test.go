@@ -0,0 +1,78 @@
+package pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// coverageTool replays queued coverage_gaps results.
+type coverageTool struct {
+	stubTool
+	results *[]*framework.ToolResult
+}
+
+func (t coverageTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	next := (*t.results)[0]
+	*t.results = (*t.results)[1:]
+	return next, nil
+}
+
+func coverageResult(percent float64, gaps ...map[string]interface{}) *framework.ToolResult {
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"coverage": percent, "gaps": gaps}}
+}
+
+// TestTestGenAgentReportsCoverageDelta hands the gaps to the writer and
+// reports which targets the new tests closed.
+func TestTestGenAgentReportsCoverageDelta(t *testing.T) {
+	results := []*framework.ToolResult{
+		coverageResult(40,
+			map[string]interface{}{"file": "calc.go", "function": "Div", "start_line": 10, "end_line": 18, "covered": 0, "statements": 4},
+			map[string]interface{}{"file": "calc.go", "function": "Mod", "start_line": 20, "end_line": 25, "covered": 1, "statements": 3},
+		),
+		coverageResult(70,
+			map[string]interface{}{"file": "calc.go", "function": "Mod", "start_line": 20, "end_line": 25, "covered": 2, "statements": 3},
+		),
+	}
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(coverageTool{stubTool: stubTool{name: "coverage_gaps"}, results: &results}))
+	writer := &recordingAgent{}
+	agent := &TestGenAgent{Writer: writer, Tools: registry}
+	require.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeTestGeneration}, state)
+	require.NoError(t, err)
+
+	require.Len(t, writer.instructions, 1)
+	assert.Contains(t, writer.instructions[0], "- calc.go:Div (lines 10-18, 0 of 4 statements covered)")
+	raw, ok := state.Get("testgen.report")
+	require.True(t, ok)
+	report := raw.(TestGenReport)
+	assert.Equal(t, 30.0, report.Delta)
+	assert.Equal(t, []TestGenTarget{{File: "calc.go", Function: "Mod", StartLine: 20, EndLine: 25, Covered: 2, Statements: 3}}, report.Remaining)
+	assert.Equal(t, "Coverage 40.0% → 70.0% (+30.0 points)\n\n- `calc.go:Div` fully covered\n- `calc.go:Mod` 1/3 → 2/3 statements\n", state.GetString("testgen.final_output"))
+}
+
+// TestTestGenAgentFailsWhenNewTestsFail keeps the report but fails the task.
+func TestTestGenAgentFailsWhenNewTestsFail(t *testing.T) {
+	results := []*framework.ToolResult{
+		coverageResult(40, map[string]interface{}{"file": "calc.go", "function": "Div", "start_line": 10, "end_line": 18, "covered": 0, "statements": 4}),
+		{Success: false, Error: "1 of 5 tests failed", Data: map[string]interface{}{"coverage": 45.0}},
+	}
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(coverageTool{stubTool: stubTool{name: "coverage_gaps"}, results: &results}))
+	agent := &TestGenAgent{Writer: &recordingAgent{}, Tools: registry}
+	require.NoError(t, agent.Initialize(&framework.Config{}))
+
+	state := framework.NewContext()
+	_, err := agent.Execute(context.Background(), &framework.Task{Type: framework.TaskTypeTestGeneration}, state)
+	assert.ErrorContains(t, err, "the new tests fail: 1 of 5 tests failed")
+	raw, ok := state.Get("testgen.report")
+	require.True(t, ok)
+	assert.Equal(t, []string{"1 of 5 tests failed"}, raw.(TestGenReport).Failures)
+}
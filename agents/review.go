@@ -0,0 +1,10 @@
+package agents
+
+import "github.com/lexcodex/relurpify/framework"
+
+// NewReviewAgent builds the review preset: an agent that turns a unified diff
+// into inline review comments. It registers no tools, so reviewing can never
+// change the workspace.
+func NewReviewAgent(model framework.LanguageModel) *ReviewAgent {
+	return &ReviewAgent{Model: model}
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// auditFilter collects the flags shared by the audit subcommands.
+type auditFilter struct {
+	file   string
+	agent  string
+	kind   string
+	action string
+	result string
+	since  string
+	until  string
+	limit  int
+}
+
+func (f *auditFilter) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.file, "file", "", "Audit JSONL file (defaults to the first file sink in config.yaml)")
+	cmd.Flags().StringVar(&f.agent, "agent", "", "Only records for this agent ID")
+	cmd.Flags().StringVar(&f.kind, "type", "", "Only this permission type (filesystem, executable, network, capability, ipc, hitl)")
+	cmd.Flags().StringVar(&f.action, "action", "", "Only this action")
+	cmd.Flags().StringVar(&f.result, "result", "", "Only this result (granted, denied, tool_allowed)")
+	cmd.Flags().StringVar(&f.since, "since", "", "Start of the time range: RFC3339 timestamp or duration ago (e.g. 24h)")
+	cmd.Flags().StringVar(&f.until, "until", "", "End of the time range: RFC3339 timestamp or duration ago")
+}
+
+func (f *auditFilter) query(now time.Time) (framework.AuditQuery, error) {
+	query := framework.AuditQuery{
+		AgentID: f.agent,
+		Type:    f.kind,
+		Action:  f.action,
+		Result:  f.result,
+	}
+	var err error
+	if query.TimeStart, err = parseAuditTime(f.since, now); err != nil {
+		return query, fmt.Errorf("--since: %w", err)
+	}
+	if query.TimeEnd, err = parseAuditTime(f.until, now); err != nil {
+		return query, fmt.Errorf("--until: %w", err)
+	}
+	return query, nil
+}
+
+// read loads matching records, keeping only the newest limit when set.
+func (f *auditFilter) read() ([]framework.AuditFileEntry, error) {
+	path, err := f.path()
+	if err != nil {
+		return nil, err
+	}
+	query, err := f.query(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := framework.ReadAuditFile(path, query)
+	if err != nil {
+		return nil, err
+	}
+	if f.limit > 0 && len(entries) > f.limit {
+		entries = entries[len(entries)-f.limit:]
+	}
+	return entries, nil
+}
+
+func (f *auditFilter) path() (string, error) {
+	if f.file != "" {
+		return f.file, nil
+	}
+	ws := ensureWorkspace()
+	cfg, err := runtime.LoadWorkspaceConfig(cfgFile)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if path := cfg.AuditFilePath(ws); path != "" {
+		return path, nil
+	}
+	return "", fmt.Errorf("no file audit sink configured in %s; add one under audit_sinks or pass --file", cfgFile)
+}
+
+// parseAuditTime accepts an RFC3339 timestamp or a duration before now.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts, nil
+	}
+	ago, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("want RFC3339 timestamp or duration, got %q", value)
+	}
+	return now.Add(-ago), nil
+}
+
+// newAuditCmd groups the commands that inspect the permission audit trail.
+func newAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect and export permission audit records",
+	}
+	cmd.AddCommand(newAuditListCmd(), newAuditShowCmd(), newAuditExportCmd())
+	return cmd
+}
+
+// newAuditListCmd prints matching records as a table.
+func newAuditListCmd() *cobra.Command {
+	var filter auditFilter
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List audit records",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := filter.read()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No audit records.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTIME\tAGENT\tTYPE\tACTION\tRESULT\tPERMISSION")
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Line, entry.Timestamp.Format(time.RFC3339),
+					entry.AgentID, entry.Type, entry.Action, entry.Result, entry.Permission)
+			}
+			return w.Flush()
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().IntVar(&filter.limit, "limit", 50, "Show at most the newest N records (0 for all)")
+	return cmd
+}
+
+// newAuditShowCmd prints one record, including metadata, as JSON.
+func newAuditShowCmd() *cobra.Command {
+	var filter auditFilter
+	cmd := &cobra.Command{
+		Use:   "show [id]",
+		Short: "Show one audit record by the ID from audit list",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid audit record id %q", args[0])
+			}
+			path, err := filter.path()
+			if err != nil {
+				return err
+			}
+			entries, err := framework.ReadAuditFile(path, framework.AuditQuery{})
+			if err != nil {
+				return err
+			}
+			idx := sort.Search(len(entries), func(i int) bool { return entries[i].Line >= id })
+			if idx == len(entries) || entries[idx].Line != id {
+				return fmt.Errorf("audit record %d not found", id)
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries[idx])
+		},
+	}
+	cmd.Flags().StringVar(&filter.file, "file", "", "Audit JSONL file (defaults to the first file sink in config.yaml)")
+	return cmd
+}
+
+// newAuditExportCmd writes matching records as JSONL or CSV for compliance
+// review.
+func newAuditExportCmd() *cobra.Command {
+	var filter auditFilter
+	var format string
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export audit records as JSONL or CSV",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var write func(io.Writer, []framework.AuditFileEntry) error
+			switch strings.ToLower(format) {
+			case "jsonl", "json":
+				write = writeAuditJSONL
+			case "csv":
+				write = writeAuditCSV
+			default:
+				return fmt.Errorf("unknown export format %q (jsonl, csv)", format)
+			}
+			entries, err := filter.read()
+			if err != nil {
+				return err
+			}
+			if output == "" || output == "-" {
+				return write(cmd.OutOrStdout(), entries)
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := write(f, entries); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d audit records to %s\n", len(entries), output)
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().StringVar(&format, "format", "jsonl", "Export format (jsonl, csv)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file (defaults to stdout)")
+	return cmd
+}
+
+func writeAuditJSONL(w io.Writer, entries []framework.AuditFileEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAuditCSV flattens metadata into a JSON column so every record fits
+// one row.
+func writeAuditCSV(w io.Writer, entries []framework.AuditFileEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "timestamp", "agent_id", "type", "action", "permission", "result", "user", "correlation_id", "metadata"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		metadata := ""
+		if len(entry.Metadata) > 0 {
+			data, err := json.Marshal(entry.Metadata)
+			if err != nil {
+				return err
+			}
+			metadata = string(data)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(entry.Line),
+			entry.Timestamp.Format(time.RFC3339Nano),
+			entry.AgentID,
+			entry.Type,
+			entry.Action,
+			entry.Permission,
+			entry.Result,
+			entry.User,
+			entry.Correlation,
+			metadata,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func auditTestWorkspace(t *testing.T) (string, string) {
+	t.Helper()
+	ws := t.TempDir()
+	cfgDir := filepath.Join(ws, "relurpify_cfg")
+	require.NoError(t, os.MkdirAll(filepath.Join(cfgDir, "logs"), 0o755))
+	cfgPath := filepath.Join(cfgDir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("audit_sinks:\n  - type: file\n    path: relurpify_cfg/logs/audit.jsonl\n"), 0o644))
+	sink, err := framework.NewFileAuditSink(filepath.Join(cfgDir, "logs", "audit.jsonl"))
+	require.NoError(t, err)
+	base := time.Now().UTC().Add(-2 * time.Hour)
+	require.NoError(t, sink.WriteAudit(context.Background(), []framework.AuditRecord{
+		{Timestamp: base, AgentID: "coder", Type: "filesystem", Action: "fs_read", Permission: "main.go", Result: "granted"},
+		{Timestamp: base.Add(90 * time.Minute), AgentID: "coder", Type: "executable", Action: "exec", Permission: "rm", Result: "denied",
+			Metadata: map[string]interface{}{"reason": "binary not allowed"}},
+	}))
+	require.NoError(t, sink.Close())
+	return ws, cfgPath
+}
+
+func runAuditCmd(t *testing.T, ws, cfgPath string, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	root := NewRootCmd()
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(append([]string{"--workspace", ws, "--config", cfgPath, "audit"}, args...))
+	require.NoError(t, root.Execute())
+	return out.String()
+}
+
+func TestAuditListFiltersConfiguredFileSink(t *testing.T) {
+	ws, cfgPath := auditTestWorkspace(t)
+
+	out := runAuditCmd(t, ws, cfgPath, "list", "--result", "denied")
+	require.Contains(t, out, "exec")
+	require.NotContains(t, out, "fs_read")
+
+	out = runAuditCmd(t, ws, cfgPath, "list", "--since", "1h", "--type", "filesystem")
+	require.Contains(t, out, "No audit records.")
+
+	out = runAuditCmd(t, ws, cfgPath, "show", "2")
+	require.Contains(t, out, `"reason": "binary not allowed"`)
+}
+
+func TestAuditExportCSV(t *testing.T) {
+	ws, cfgPath := auditTestWorkspace(t)
+	target := filepath.Join(t.TempDir(), "audit.csv")
+
+	runAuditCmd(t, ws, cfgPath, "export", "--format", "csv", "--agent", "coder", "-o", target)
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, "id", rows[0][0])
+	require.Equal(t, "denied", rows[2][6])
+	require.Equal(t, `{"reason":"binary not allowed"}`, rows[2][9])
+
+	out := runAuditCmd(t, ws, cfgPath, "export", "--action", "fs_read")
+	require.Equal(t, 1, strings.Count(out, "\n"))
+	require.Contains(t, out, `"line":1`)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// newManifestCmd groups the commands that review agent manifests.
+func newManifestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Review agent manifests",
+	}
+	cmd.AddCommand(newManifestLintCmd(), newManifestSuggestCmd())
+	return cmd
+}
+
+// newManifestSuggestCmd turns the permissions a task used under
+// `relurpish --learn-permissions` into a least-privilege manifest.
+func newManifestSuggestCmd() *cobra.Command {
+	var taskID, manifestPath, output string
+	cmd := &cobra.Command{
+		Use:   "suggest --from-task <id>",
+		Short: "Write a manifest granting exactly the permissions a learning-mode task used",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if taskID == "" {
+				return fmt.Errorf("--from-task is required")
+			}
+			ws := ensureWorkspace()
+			if manifestPath == "" {
+				manifestPath = filepath.Join(ws, "relurpify_cfg", "agent.manifest.yaml")
+			}
+			data, err := runtime.SuggestManifest(ws, manifestPath, taskID)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&taskID, "from-task", "", "ID of a task run with --learn-permissions")
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Manifest to base the suggestion on (default relurpify_cfg/agent.manifest.yaml)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the manifest to this file instead of stdout")
+	return cmd
+}
+
+// newManifestLintCmd validates manifests and flags risky grants. It exits
+// non-zero when a manifest has errors, or warnings under --strict, so it can
+// gate manifest changes in CI.
+func newManifestLintCmd() *cobra.Command {
+	var format string
+	var strict bool
+	cmd := &cobra.Command{
+		Use:   "lint <path>...",
+		Short: "Validate agent manifests and flag overly broad or undeclared permissions",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unknown format %q (text, json)", format)
+			}
+			tools := runtime.CommandTools(ensureWorkspace(), nil)
+			findings := []framework.ManifestFinding{}
+			for _, path := range args {
+				found, err := framework.LintManifestFile(path, tools)
+				if err != nil {
+					return err
+				}
+				findings = append(findings, found...)
+			}
+			if format == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(findings); err != nil {
+					return err
+				}
+			} else {
+				writeManifestFindings(cmd, findings)
+			}
+			errs, warnings := countManifestFindings(findings)
+			if errs > 0 || (strict && warnings > 0) {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("manifest lint failed: %d error(s), %d warning(s)", errs, warnings)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings as well as errors")
+	return cmd
+}
+
+func writeManifestFindings(cmd *cobra.Command, findings []framework.ManifestFinding) {
+	out := cmd.OutOrStdout()
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "No findings.")
+		return
+	}
+	for _, f := range findings {
+		location := f.File
+		if f.Field != "" {
+			location += ": " + f.Field
+		}
+		fmt.Fprintf(out, "%s: %s [%s] %s\n", location, strings.ToUpper(f.Severity), f.Rule, f.Message)
+	}
+}
+
+func countManifestFindings(findings []framework.ManifestFinding) (errs, warnings int) {
+	for _, f := range findings {
+		if f.Severity == framework.LintSeverityError {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	return errs, warnings
+}
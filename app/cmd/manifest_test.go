@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestManifestLintReportsFindingsAsJSON(t *testing.T) {
+	ws := t.TempDir()
+	path := filepath.Join(ws, "agent.manifest.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`apiVersion: relurpify/v1alpha1
+kind: AgentManifest
+metadata:
+  name: lint
+spec:
+  image: runtime:latest
+  runtime: gvisor
+  permissions:
+    filesystem:
+      - action: fs:read
+        path: ${workspace}/**
+      - action: fs:write
+        path: ${workspace}/docs/**
+`), 0o644))
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := NewRootCmd()
+		root.SetOut(&out)
+		root.SetErr(&out)
+		root.SetArgs(append([]string{"--workspace", ws, "--config", filepath.Join(ws, "config.yaml"), "manifest", "lint"}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	out, err := run(path)
+	require.NoError(t, err)
+	require.Contains(t, out, "No findings.")
+
+	require.NoError(t, os.WriteFile(path, []byte("kind: AgentManifest\nspec:\n  permissions:\n    filesystem:\n      - action: fs:write\n        path: /**\n"), 0o644))
+	out, err = run("--format", "json", path)
+	require.Error(t, err)
+	var findings []framework.ManifestFinding
+	require.NoError(t, json.Unmarshal([]byte(out), &findings))
+	require.Len(t, findings, 2)
+	require.Equal(t, framework.LintRuleSchema, findings[0].Rule)
+	require.Equal(t, framework.LintRuleBroadGlob, findings[1].Rule)
+	require.Equal(t, path, findings[1].File)
+}
+
+func TestManifestSuggestFromLearnedTask(t *testing.T) {
+	ws := t.TempDir()
+	cfgDir := filepath.Join(ws, "relurpify_cfg")
+	require.NoError(t, os.MkdirAll(cfgDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(cfgDir, "agent.manifest.yaml"), []byte(`apiVersion: relurpify/v1alpha1
+kind: AgentManifest
+metadata:
+  name: learner
+spec:
+  image: runtime:latest
+  runtime: gvisor
+  permissions:
+    filesystem:
+      - action: fs:read
+        path: /**
+`), 0o644))
+	learner := framework.NewPermissionLearner(runtime.LearnedPermissionsDir(ws))
+	ctx := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "task-7"})
+	learner.RecordPermission(ctx, framework.PermissionUse{Type: framework.PermissionTypeFilesystem, Action: "fs:read", Resource: filepath.Join(ws, "go.mod")})
+	learner.RecordPermission(ctx, framework.PermissionUse{Type: framework.PermissionTypeExecutable, Resource: "go", Args: []string{"test", "./..."}})
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := NewRootCmd()
+		root.SetOut(&out)
+		root.SetErr(&out)
+		root.SetArgs(append([]string{"--workspace", ws, "--config", filepath.Join(ws, "config.yaml"), "manifest", "suggest"}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("--from-task", "task-7")
+	require.NoError(t, err)
+	var manifest framework.AgentManifest
+	require.NoError(t, yaml.Unmarshal([]byte(out), &manifest))
+	require.Equal(t, "learner", manifest.Metadata.Name)
+	require.Equal(t, []framework.FileSystemPermission{{Action: framework.FileSystemRead, Path: "${workspace}/go.mod"}}, manifest.Spec.Permissions.FileSystem)
+	require.Equal(t, []framework.ExecutablePermission{{Binary: "go", Args: []string{"test", "./..."}}}, manifest.Spec.Permissions.Executables)
+
+	_, err = run("--from-task", "missing")
+	require.ErrorContains(t, err, "run it with --learn-permissions first")
+}
@@ -59,6 +59,8 @@ func NewRootCmd() *cobra.Command {
 		newAgentsCmd(),
 		newConfigCmd(),
 		newSessionCmd(),
+		newAuditCmd(),
+		newManifestCmd(),
 	)
 	return root
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -22,6 +23,8 @@ func newStartCmd() *cobra.Command {
 	var agentName string
 	var instruction string
 	var dryRun bool
+	var requireSandbox bool
+	var autonomy string
 
 	cmd := &cobra.Command{
 		Use:   "start",
@@ -68,6 +71,10 @@ func newStartCmd() *cobra.Command {
 					logAgent = *spec.Logging.Agent
 				}
 			}
+			autonomyLevel, err := framework.ParseAutonomyLevel(autonomy)
+			if err != nil {
+				return err
+			}
 			if instruction == "" {
 				fmt.Fprintf(cmd.OutOrStdout(), "Agent %s ready in %s mode. Provide --instruction to execute a task.\n", agentName, mode)
 				return nil
@@ -89,16 +96,20 @@ func newStartCmd() *cobra.Command {
 				return err
 			}
 			registration, err := framework.RegisterAgent(runCtx, framework.RuntimeConfig{
-				ManifestPath: runtimeCfg.ManifestPath,
-				Sandbox:      runtimeCfg.Sandbox,
-				AuditLimit:   runtimeCfg.AuditLimit,
-				BaseFS:       runtimeCfg.Workspace,
-				HITLTimeout:  runtimeCfg.HITLTimeout,
+				ManifestPath:   runtimeCfg.ManifestPath,
+				Sandbox:        runtimeCfg.Sandbox,
+				AuditLimit:     runtimeCfg.AuditLimit,
+				BaseFS:         runtimeCfg.Workspace,
+				HITLTimeout:    runtimeCfg.HITLTimeout,
+				RequireSandbox: requireSandbox,
 			})
 			if err != nil {
 				return err
 			}
-			runner, err := framework.NewSandboxCommandRunner(registration.Manifest, registration.Runtime, runtimeCfg.Workspace)
+			if registration.Sandbox.Degraded {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: sandbox unavailable, commands run on the host and need approval: %s\n", registration.Sandbox.Reason)
+			}
+			runner, err := registration.CommandRunner(runtimeCfg.Workspace)
 			if err != nil {
 				return err
 			}
@@ -106,24 +117,33 @@ func newStartCmd() *cobra.Command {
 				AgentID:           registration.ID,
 				PermissionManager: registration.Permissions,
 				AgentSpec:         spec,
+				GateCommands:      registration.Sandbox.Degraded,
 			})
 			if err != nil {
 				return err
 			}
 			framework.RestrictToolRegistryByMatrix(tools, spec.Tools)
 			tools.UseAgentSpec(registration.ID, spec)
+			if registration.Sandbox.Degraded {
+				tools.RequireApprovalFor(framework.RunsCommands)
+			}
 			telemetry := framework.LoggerTelemetry{Logger: log.Default()}
 			tools.UseTelemetry(telemetry)
 			if registration.Permissions != nil {
 				tools.UsePermissionManager(registration.ID, registration.Permissions)
 			}
 			memoryPath := filepath.Join(ws, "relurpify_cfg", "memory")
-			memory, err := framework.NewHybridMemory(memoryPath)
+			memory, err := framework.NewLayeredMemory(framework.DefaultMemoryLayout(memoryPath))
 			if err != nil {
 				return err
 			}
+			usage := framework.NewUsageTracker(nil)
+			model := llm.NewInstrumentedModel(client, telemetry, logLLM)
+			model.Usage = usage
+			controller := framework.NewAutonomyController(autonomyLevel, framework.AutonomyQuota{}, usage)
+			tools.UseAutonomy(controller)
 			agent := &agents.CodingAgent{
-				Model:  llm.NewInstrumentedModel(client, telemetry, logLLM),
+				Model:  model,
 				Tools:  tools,
 				Memory: memory,
 			}
@@ -137,6 +157,14 @@ func newStartCmd() *cobra.Command {
 				DebugLLM:          logLLM,
 				DebugAgent:        logAgent,
 			}
+			timeouts, err := spec.Timeouts.GraphTimeouts()
+			if err != nil {
+				return err
+			}
+			cfg.Timeouts = timeouts
+			if cfg.Retry, err = spec.Retry.RetryPolicy(); err != nil {
+				return err
+			}
 			if err := agent.Initialize(cfg); err != nil {
 				return err
 			}
@@ -154,11 +182,22 @@ func newStartCmd() *cobra.Command {
 			state.Set("task.id", task.ID)
 			state.Set("task.type", string(task.Type))
 			state.Set("task.instruction", task.Instruction)
-			result, err := agent.Execute(ctx, task, state)
+			result, err := agent.Execute(framework.WithGraphTimeouts(ctx, cfg.Timeouts), task, state)
+			var timeoutErr *framework.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				fmt.Fprintf(cmd.OutOrStdout(), "Agent %s: %v (completed nodes: %v)\n", framework.ResultStatusTimedOut, err, timeoutErr.Completed)
+				return err
+			}
 			if err != nil {
 				return err
 			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Agent complete (node=%s): %+v\n", result.NodeID, result.Data)
+			total := usage.Summary(framework.UsageFilter{TaskID: task.ID}).Total
+			fmt.Fprintf(cmd.OutOrStdout(), "Tokens: %d prompt + %d completion = %d over %d calls\n",
+				total.PromptTokens, total.CompletionTokens, total.TotalTokens, total.Calls)
+			for _, suggestion := range controller.Suggestions() {
+				fmt.Fprintf(cmd.OutOrStdout(), "Suggested (not applied): %s %v\n", suggestion.Tool, suggestion.Args)
+			}
 			return nil
 		},
 	}
@@ -166,6 +205,8 @@ func newStartCmd() *cobra.Command {
 	cmd.Flags().StringVar(&agentName, "agent", "", "Agent name from manifest registry")
 	cmd.Flags().StringVar(&instruction, "instruction", "", "Instruction to execute")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate configuration without executing")
+	cmd.Flags().BoolVar(&requireSandbox, "require-sandbox", false, "Fail instead of running commands unsandboxed when gVisor is unavailable")
+	cmd.Flags().StringVar(&autonomy, "autonomy", string(framework.AutonomyAutonomous), "Autonomy level (suggest, approve, autonomous)")
 	return cmd
 }
 
@@ -0,0 +1,39 @@
+// Command ollama-stub serves a scripted Ollama-compatible API (see
+// llm/ollamatest) for end-to-end tests that run outside the Go test process,
+// for example in a container next to the CLI under test:
+//
+//	ollama-stub -addr :11434 -script script.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:11434", "Listen address")
+	scriptPath := flag.String("script", "", "JSON script of rules and fallback reply")
+	flag.Parse()
+
+	var script ollamatest.Script
+	if *scriptPath != "" {
+		data, err := os.ReadFile(*scriptPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &script); err != nil {
+			log.Fatalf("parse %s: %v", *scriptPath, err)
+		}
+	}
+	if script.Fallback == nil && len(script.Rules) == 0 {
+		script.Fallback = &ollamatest.Reply{Text: `{"thought":"done","complete":true}`}
+	}
+	fmt.Fprintf(os.Stderr, "ollama-stub listening on %s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, ollamatest.New(script)))
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,6 +21,11 @@ import (
 
 	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
 	"github.com/lexcodex/relurpify/app/relurpish/tui"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/persistence"
+	"github.com/lexcodex/relurpify/server"
 )
 
 var (
@@ -20,6 +33,10 @@ var (
 	startServer bool
 )
 
+// defaultPprofAddr is used when --pprof is passed without a value. It binds
+// loopback only since the endpoints expose process internals.
+const defaultPprofAddr = "localhost:6060"
+
 // main bootstraps the relurpish CLI/TUI entrypoint.
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -27,10 +44,24 @@ func main() {
 	root := newRootCmd()
 	if err := root.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		code := 1
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+		os.Exit(code)
 	}
 }
 
+// exitError makes the process exit with code instead of 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
 // newRootCmd wires all subcommands and persistent flags.
 func newRootCmd() *cobra.Command {
 	root := &cobra.Command{
@@ -46,28 +77,59 @@ func newRootCmd() *cobra.Command {
 	root.PersistentFlags().StringVar(&cfg.ManifestPath, "manifest", cfg.ManifestPath, "Agent manifest path")
 	root.PersistentFlags().StringVar(&cfg.OllamaEndpoint, "ollama-endpoint", cfg.OllamaEndpoint, "Ollama endpoint URL")
 	root.PersistentFlags().StringVar(&cfg.OllamaModel, "ollama-model", cfg.OllamaModel, "Ollama model name")
-	root.PersistentFlags().StringVar(&cfg.AgentName, "agent", cfg.AgentLabel(), "Agent preset (coding, planner, react, reflection)")
+	root.PersistentFlags().StringVar(&cfg.Profile, "profile", cfg.Profile, "Config profile from config.yaml (default $"+runtimesvc.ProfileEnv+" or the profile key)")
+	root.PersistentFlags().StringVar(&cfg.AgentName, "agent", cfg.AgentLabel(), "Agent preset (coding, planner, react, reflection) or the name of a definition in the agents directory")
 	root.PersistentFlags().StringVar(&cfg.ServerAddr, "addr", cfg.ServerAddr, "HTTP server listen address")
+	root.PersistentFlags().IntVar(&cfg.ServerWorkers, "workers", cfg.ServerWorkers, "Concurrent task workers for the HTTP API queue (default jobs.max_concurrent in config.yaml, else 2)")
 	root.PersistentFlags().StringVar(&cfg.Sandbox.RunscPath, "runsc", cfg.Sandbox.RunscPath, "runsc binary path")
 	root.PersistentFlags().StringVar(&cfg.Sandbox.ContainerRuntime, "container-runtime", cfg.Sandbox.ContainerRuntime, "Container runtime (docker/containerd)")
 	root.PersistentFlags().StringVar(&cfg.Sandbox.Platform, "sandbox-platform", cfg.Sandbox.Platform, "gVisor platform (kvm/ptrace)")
+	root.PersistentFlags().BoolVar(&cfg.RequireSandbox, "require-sandbox", false, "Fail instead of running commands unsandboxed when gVisor is unavailable")
+	root.PersistentFlags().StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", "Export traces of graph runs to this OTLP/HTTP collector (see tracing in config.yaml)")
+	root.PersistentFlags().StringArrayVar(&cfg.HITLWebhooks, "hitl-webhook", nil, "POST approval requests to this URL while serving (repeatable; see hitl_webhooks in config.yaml)")
+	root.PersistentFlags().StringVar(&cfg.Project, "project", "", "File or directory in a monorepo; scope test, build, and lint tools to the project containing it")
 	root.PersistentFlags().BoolVar(&startServer, "serve", false, "Launch the HTTP API server alongside the TUI")
+	root.PersistentFlags().StringVar(&cfg.Autonomy, "autonomy", "", "Session autonomy level (suggest, approve, autonomous)")
+	root.PersistentFlags().DurationVar(&cfg.AutonomyFor, "autonomy-for", 0, "Time-box the autonomy level; falls back to approve when it ends")
+	root.PersistentFlags().BoolVar(&cfg.RefreshCache, "refresh-cache", false, "Recompute cached sandbox verification, plugin discovery, and model capabilities")
+	root.PersistentFlags().BoolVar(&cfg.NoCache, "no-cache", false, "Skip the startup cache and the LLM response cache")
+	root.PersistentFlags().BoolVar(&cfg.Record, "record", false, "Record each task's model and tool calls for `relurpish replay`")
+	root.PersistentFlags().BoolVar(&cfg.LearnPermissions, "learn-permissions", false, "Allow and record every permission tasks use, for `coding-agent manifest suggest`")
+	root.PersistentFlags().StringVar(&cfg.PprofAddr, "pprof", "", "Expose pprof endpoints on this address (bare --pprof uses "+defaultPprofAddr+")")
+	root.PersistentFlags().Lookup("pprof").NoOptDefVal = defaultPprofAddr
 
-	root.AddCommand(newWizardCmd(), newStatusCmd(), newChatCmd(), newServeCmd())
+	root.AddCommand(newWizardCmd(), newStatusCmd(), newChatCmd(), newServeCmd(), newIndexCmd(), newTaskCmd(), newBatchCmd(), newWorkflowCmd(), newArtifactsCmd(), newJobCmd(), newMemoryCmd(), newContextCmd(), newReplayCmd(), newProfileCmd(), newProjectsCmd(), newInspectCmd(), newAskCmd(), newReviewCmd(), newEditorServerCmd(), newLSPCmd(), newDoctorCmd(), newRecipeCmd())
 	return root
 }
 
-// newWizardCmd launches the wizard UI flow.
+// newWizardCmd launches the wizard UI flow. With --permissions it applies a
+// permission preset to the manifest instead.
 func newWizardCmd() *cobra.Command {
+	var permissions string
 	cmd := &cobra.Command{
 		Use:   "wizard",
 		Short: "Run the configuration wizard",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if permissions != "" {
+				summary, err := runtimesvc.ApplyPermissionPreset(cfg, runtimesvc.PermissionProfile(permissions))
+				if err != nil {
+					return err
+				}
+				profile, _ := runtimesvc.ParsePermissionProfile(permissions)
+				fmt.Fprintf(cmd.OutOrStdout(), "Applied %s to %s: %s (%d permissions, %d network rules)\n",
+					profile, summary.Path, profile.Description(), summary.Permissions, summary.Network)
+				return nil
+			}
 			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
-				return runTUI(ctx, rt)
+				return runTUI(ctx, rt, tui.Options{})
 			})
 		},
 	}
+	presets := make([]string, 0, len(runtimesvc.PermissionProfiles()))
+	for _, profile := range runtimesvc.PermissionProfiles() {
+		presets = append(presets, fmt.Sprintf("%s (%s)", profile, profile.Description()))
+	}
+	cmd.Flags().StringVar(&permissions, "permissions", "", "Replace the manifest's permissions with a preset: "+strings.Join(presets, ", "))
 	return cmd
 }
 
@@ -78,7 +140,7 @@ func newStatusCmd() *cobra.Command {
 		Short: "Show workspace diagnostics",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
-				return runTUI(ctx, rt)
+				return runTUI(ctx, rt, tui.Options{})
 			})
 		},
 	}
@@ -87,30 +149,59 @@ func newStatusCmd() *cobra.Command {
 
 // newChatCmd starts the chat-first TUI.
 func newChatCmd() *cobra.Command {
+	var resume string
 	cmd := &cobra.Command{
 		Use:   "chat",
 		Short: "Start the relurpish chat shell",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
-				return runTUI(ctx, rt)
+				return runTUI(ctx, rt, tui.Options{ResumeSession: resume})
 			})
 		},
 	}
+	cmd.Flags().StringVar(&resume, "resume", "", "Resume a saved chat session by ID (or \"last\")")
+	cmd.Flags().BoolVar(&cfg.Watch, "watch", false, "Watch the workspace, refresh LSP/AST caches, and show changes in the feed")
 	return cmd
 }
 
-// newServeCmd runs only the HTTP server, useful for automation.
+// newServeCmd runs only the HTTP server, useful for automation. With
+// --selftest it instead load-tests the task queue against a mock model. With
+// --watch it also reports workspace changes and watch analysis results.
 func newServeCmd() *cobra.Command {
+	var selfTest int
+	var selfTestLatency time.Duration
 	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Run only the HTTP API server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if selfTest > 0 {
+				return runSelfTest(cmd, selfTest, selfTestLatency)
+			}
 			return runWithRuntime(cmd, func(cmdCtx context.Context, rt *runtimesvc.Runtime) error {
 				stop, err := rt.StartServer(cmdCtx, cfg.ServerAddr)
 				if err != nil {
 					return err
 				}
 				fmt.Fprintf(cmd.OutOrStdout(), "relurpish API listening on %s\n", cfg.ServerAddr)
+				modelEvents, unsubscribeModel := rt.SubscribeModelEvents(16)
+				defer unsubscribeModel()
+				go printModelEvents(cmd.OutOrStdout(), modelEvents)
+				stopLifecycle := rt.StartModelLifecycle(cmdCtx)
+				defer stopLifecycle()
+				if sandbox := rt.SandboxStatus(); sandbox.Degraded {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: sandbox unavailable, commands run on the host and need approval: %s\n", sandbox.Reason)
+				}
+				if rt.WatchEnabled() {
+					events, unsubscribe := rt.SubscribeWatch(32)
+					defer unsubscribe()
+					stopWatch, err := rt.StartWatch(cmdCtx)
+					if err != nil {
+						return err
+					}
+					defer stopWatch()
+					fmt.Fprintf(cmd.OutOrStdout(), "watching %s\n", cfg.Workspace)
+					go printWatchEvents(cmd.OutOrStdout(), events)
+				}
 				<-cmdCtx.Done()
 				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
@@ -118,9 +209,1336 @@ func newServeCmd() *cobra.Command {
 			})
 		},
 	}
+	cmd.Flags().IntVar(&selfTest, "selftest", 0, "Submit N synthetic tasks through the queue with a mock LLM and print a load report")
+	cmd.Flags().DurationVar(&selfTestLatency, "selftest-latency", 20*time.Millisecond, "Simulated mock LLM latency per call during --selftest")
+	cmd.Flags().BoolVar(&cfg.Watch, "watch", false, "Watch the workspace, refresh LSP/AST caches, and run watch.analysis on changed files")
+	return cmd
+}
+
+// printWatchEvents writes one line per watcher event until events closes.
+func printWatchEvents(out io.Writer, events <-chan runtimesvc.WatchEvent) {
+	for event := range events {
+		fmt.Fprintln(out, runtimesvc.FormatWatchEvent(event))
+		if event.Result != nil && len(event.Result.Data) > 0 {
+			fmt.Fprintf(out, "  %+v\n", event.Result.Data)
+		}
+	}
+}
+
+// printModelEvents writes warm-up and unload events until events closes.
+// Only the first of a warm-up's progress ticks is printed.
+func printModelEvents(out io.Writer, events <-chan llm.ModelEvent) {
+	for event := range events {
+		if event.Type == llm.ModelLoading && event.Elapsed > 0 {
+			continue
+		}
+		fmt.Fprintln(out, runtimesvc.FormatModelEvent(event))
+	}
+}
+
+// runSelfTest needs no Ollama or sandbox, so it skips runtime startup.
+func runSelfTest(cmd *cobra.Command, tasks int, latency time.Duration) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "selftest: %d tasks, %s mock latency\n", tasks, latency)
+	report, err := runtimesvc.RunSelfTest(ctx, cfg, runtimesvc.SelfTestOptions{Tasks: tasks, Latency: latency})
+	if err != nil {
+		return err
+	}
+	report.Write(out)
+	if !report.OK() {
+		return errors.New("selftest failed")
+	}
+	return nil
+}
+
+// newIndexCmd builds or refreshes the workspace AST index without starting
+// the agent runtime. Unchanged files are skipped by content hash.
+func newIndexCmd() *cobra.Command {
+	var watch bool
+	var interval time.Duration
+	var quiet bool
+	var embeddings bool
+	var embeddingModel string
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Build or incrementally refresh the workspace AST index",
+		Long: "Build or incrementally refresh the workspace AST index and capture the project's conventions\n" +
+			"(linters, formatting, test naming, layout, commit style) into project memory. With --embeddings it\n" +
+			"also embeds chunked source files with an Ollama embedding model so search_semantic ranks results by meaning.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			manager, store, err := runtimesvc.OpenASTIndex(cfg.Workspace)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			out := cmd.OutOrStdout()
+			counts := make(map[ast.IndexFileStatus]int)
+			progress := func(p ast.IndexProgress) {
+				counts[p.Status]++
+				if quiet || p.Status == ast.IndexStatusUnchanged || p.Status == ast.IndexStatusSkipped {
+					return
+				}
+				if p.Err != nil {
+					fmt.Fprintf(out, "[%d/%d] %s %s: %v\n", p.Done, p.Total, p.Status, p.Path, p.Err)
+					return
+				}
+				fmt.Fprintf(out, "[%d/%d] %s %s\n", p.Done, p.Total, p.Status, p.Path)
+			}
+			manager.SetProgressHandler(progress)
+			var embedIndex *ast.EmbeddingIndex
+			if embeddings {
+				var embedCfg *runtimesvc.EmbeddingsConfig
+				ws, err := runtimesvc.LoadWorkspaceProfile(cfg)
+				if err != nil {
+					return err
+				}
+				cfg.ApplyProfileEndpoint(ws)
+				if ws.Embeddings != nil {
+					copied := *ws.Embeddings
+					embedCfg = &copied
+				}
+				if embeddingModel != "" {
+					if embedCfg == nil {
+						embedCfg = &runtimesvc.EmbeddingsConfig{}
+					}
+					embedCfg.Model = embeddingModel
+				}
+				embedIndex = runtimesvc.NewEmbeddingIndex(cfg.Workspace, cfg.OllamaEndpoint, store, embedCfg, ws.LLMThrottle.Throttle(cfg.OllamaEndpoint))
+				embedIndex.SetProgressHandler(progress)
+			}
+			summarize := func(label string, start time.Time) {
+				fmt.Fprintf(out, "%sindexed %d, unchanged %d, removed %d, failed %d, skipped %d (%s)\n", label,
+					counts[ast.IndexStatusIndexed], counts[ast.IndexStatusUnchanged], counts[ast.IndexStatusRemoved],
+					counts[ast.IndexStatusFailed], counts[ast.IndexStatusSkipped], time.Since(start).Round(time.Millisecond))
+				for status := range counts {
+					delete(counts, status)
+				}
+			}
+			runPass := func() {
+				start := time.Now()
+				if err := manager.IndexWorkspace(); err != nil {
+					fmt.Fprintf(out, "index warning: %v\n", err)
+				}
+				summarize("", start)
+				if embedIndex == nil {
+					return
+				}
+				start = time.Now()
+				if err := embedIndex.IndexWorkspace(ctx); err != nil {
+					fmt.Fprintf(out, "embeddings warning: %v\n", err)
+				}
+				summarize("embeddings: ", start)
+			}
+			runPass()
+			// Conventions change rarely, so they are captured once per run.
+			if conventions, err := runtimesvc.CaptureConventions(ctx, cfg); err != nil {
+				fmt.Fprintf(out, "conventions warning: %v\n", err)
+			} else if section := conventions.PromptSection(); section != "" && !quiet {
+				fmt.Fprint(out, section)
+			}
+			if !watch {
+				return nil
+			}
+			fmt.Fprintf(out, "watching %s (every %s); press Ctrl+C to stop\n", cfg.Workspace, interval)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					runPass()
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&watch, "watch", false, "Keep running and re-index changed files")
+	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Polling interval used with --watch")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Only print the per-pass summary")
+	cmd.Flags().BoolVar(&embeddings, "embeddings", false, "Also build the embeddings index used by search_semantic")
+	cmd.Flags().StringVar(&embeddingModel, "embedding-model", "", "Ollama embedding model (default: embeddings.model or "+runtimesvc.DefaultEmbeddingModel+")")
+	return cmd
+}
+
+// newTaskCmd runs a single instruction headlessly and prints the result and
+// its token usage. With --output json|yaml it prints a TaskReport instead, and
+// the exit code tells agent failures, tool denials, an unreachable model,
+// timeouts, and exceeded budgets apart. Planner agents save their plan under relurpify_cfg/plans
+// and wait for approval unless --auto-approve is set. Each --file is loaded
+// into the task context, summarized when the set exceeds the context budget,
+// and the text output then includes a diff per changed file.
+func newTaskCmd() *cobra.Command {
+	var run headlessTask
+	cmd := &cobra.Command{
+		Use:   "task <instruction>",
+		Short: "Run one instruction without the TUI",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run.Instruction = strings.Join(args, " ")
+			return runHeadlessTask(cmd, run)
+		},
+	}
+	cmd.Flags().StringVar(&run.Type, "type", string(framework.TaskTypeCodeModification), "Task type (code_modification, analysis, planning, review, ...)")
+	run.bindFlags(cmd)
+	return cmd
+}
+
+// headlessTask is one task run without the TUI by `relurpish task` and
+// `relurpish recipe run`.
+type headlessTask struct {
+	Type        string
+	Instruction string
+	Files       []string
+	// Context seeds the task context before project and files are added.
+	Context     map[string]interface{}
+	Output      string
+	AutoApprove bool
+	Isolated    bool
+	// Permissions names a permission preset that replaces the manifest's
+	// permissions for this run.
+	Permissions string
+	// Check, when set, vets the assembled task before it runs.
+	Check func(*framework.Task) error
+	// Budget stops the task once it exceeds a limit.
+	Budget framework.TaskBudget
+	// Resume restores the agent context saved by a budget-stopped run
+	// before the task starts.
+	Resume *framework.ContextSnapshot
+}
+
+func (run *headlessTask) bindFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&run.Output, "output", "text", "Output format: text, json, or yaml")
+	cmd.Flags().BoolVar(&run.AutoApprove, "auto-approve", false, "Execute planner plans without waiting for review")
+	cmd.Flags().StringArrayVar(&run.Files, "file", nil, "File, directory, or glob to include in the task context (repeatable)")
+	cmd.Flags().BoolVar(&run.Isolated, "isolated", false, "Run against a temporary copy of the workspace and merge the changes only on success (default: the manifest's isolated flag)")
+	cmd.Flags().StringVar(&run.Permissions, "permissions", "", "Permission preset for this run instead of the manifest's: read-only, workspace-write, workspace-write+net, or full")
+	cmd.Flags().DurationVar(&run.Budget.MaxDuration, "max-duration", 0, "Stop the task after this much wall-clock time, e.g. 10m")
+	cmd.Flags().IntVar(&run.Budget.MaxLLMCalls, "max-llm-calls", 0, "Stop the task after this many model calls")
+	cmd.Flags().IntVar(&run.Budget.MaxTokens, "max-tokens", 0, "Stop the task after its model calls use this many tokens")
+	cmd.Flags().IntVar(&run.Budget.MaxFilesModified, "max-files", 0, "Stop the task once it has changed more than this many files")
+}
+
+// runHeadlessTask runs one task, prints its report, and maps failure to
+// the report's exit code.
+func runHeadlessTask(cmd *cobra.Command, run headlessTask) error {
+	switch run.Output {
+	case "text", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown --output %q (want text, json, or yaml)", run.Output)
+	}
+	if err := run.Budget.Validate(); err != nil {
+		return err
+	}
+	if run.Permissions != "" {
+		profile, err := runtimesvc.ParsePermissionProfile(run.Permissions)
+		if err != nil {
+			return err
+		}
+		cfg.Permissions = profile
+	}
+	isolated := run.Isolated
+	if !isolated {
+		if manifest, err := framework.LoadAgentManifest(cfg.ManifestPath); err == nil && manifest.Spec.Agent != nil {
+			isolated = manifest.Spec.Agent.Isolated
+		}
+	}
+	var iso *runtimesvc.IsolatedWorkspace
+	checkpoints := cfg.CheckpointPath
+	if isolated {
+		var err error
+		iso, err = runtimesvc.IsolateWorkspace(cmd.Context(), cfg.Workspace)
+		if err != nil {
+			return fmt.Errorf("isolate workspace: %w", err)
+		}
+		defer iso.Close()
+		original := cfg
+		cfg = iso.Config(cfg)
+		defer func() { cfg = original }()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Running in an isolated %s at %s\n", iso.Mode, iso.Dir)
+	}
+	return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+		task := &framework.Task{
+			ID:          fmt.Sprintf("task-%d", time.Now().UnixNano()),
+			Type:        framework.TaskType(run.Type),
+			Instruction: run.Instruction,
+		}
+		if !run.Budget.IsZero() {
+			budget := run.Budget
+			task.Budget = &budget
+		}
+		if run.Resume != nil {
+			if err := rt.Context.Restore(run.Resume); err != nil {
+				return err
+			}
+		}
+		task.Context = map[string]interface{}{}
+		for key, value := range run.Context {
+			task.Context[key] = value
+		}
+		if rt.Project != nil {
+			task.Context["project"] = rt.Project.Path
+		}
+		if len(run.Files) > 0 {
+			paths, err := runtimesvc.ResolveTaskFiles(rt.Config.Workspace, run.Files)
+			if err != nil {
+				return err
+			}
+			attached, err := runtimesvc.LoadTaskFiles(rt.Config.Workspace, paths, rt.TaskFileBudget(), nil)
+			if err != nil {
+				return err
+			}
+			task.Context["files"] = attached
+		}
+		if run.Check != nil {
+			if err := run.Check(task); err != nil {
+				return err
+			}
+		}
+		ctx = framework.WithPlanReviewer(ctx, &runtimesvc.PlanFileReviewer{
+			Dir:         runtimesvc.PlanDir(rt.Config.Workspace),
+			AutoApprove: run.AutoApprove,
+			In:          cmd.InOrStdin(),
+			Out:         cmd.ErrOrStderr(),
+		})
+		if !run.AutoApprove {
+			ctx = framework.WithConflictResolver(ctx, &runtimesvc.ConflictPrompter{
+				Dir: runtimesvc.PlanDir(rt.Config.Workspace),
+				In:  cmd.InOrStdin(),
+				Out: cmd.ErrOrStderr(),
+			})
+		}
+		report, err := rt.RunTaskReport(ctx, task)
+		if report == nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if run.Output == "text" {
+			printTaskReport(out, report, len(run.Files) > 0)
+		} else if encErr := report.Encode(out, run.Output); encErr != nil {
+			return encErr
+		}
+		if report.ExitCode == 0 {
+			if iso != nil {
+				return mergeIsolated(cmd, iso, checkpoints, task.ID, run.AutoApprove)
+			}
+			return nil
+		}
+		if iso != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Task failed; discarded the isolated changes")
+		}
+		if err == nil {
+			err = errors.New("task failed")
+			if report.Error != "" {
+				err = fmt.Errorf("task failed: %s", report.Error)
+			}
+		}
+		return &exitError{code: report.ExitCode, err: err}
+	})
+}
+
+// newRecipeCmd lists and runs the task recipes in relurpify_cfg/recipes.
+func newRecipeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recipe",
+		Short: "Run reviewed task templates",
+	}
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the workspace's recipes and their parameters",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recipes, err := runtimesvc.LoadRecipes(runtimesvc.RecipeDir(cfg.Workspace))
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(recipes) == 0 {
+				fmt.Fprintf(out, "No recipes in %s.\n", runtimesvc.RecipeDir(cfg.Workspace))
+				return nil
+			}
+			for _, recipe := range recipes {
+				fmt.Fprintf(out, "%s\t%s\n", recipe.Name, recipe.Description)
+				for _, param := range recipe.Params {
+					line := "  --param " + param.Name + "=..."
+					if param.Default != nil {
+						line += fmt.Sprintf(" (default %q)", *param.Default)
+					}
+					if param.Description != "" {
+						line += "  " + param.Description
+					}
+					fmt.Fprintln(out, line)
+				}
+			}
+			return nil
+		},
+	}
+	var run headlessTask
+	var params []string
+	runCmd := &cobra.Command{
+		Use:   "run <recipe>",
+		Short: "Run a recipe as a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recipe, err := runtimesvc.FindRecipe(runtimesvc.RecipeDir(cfg.Workspace), args[0])
+			if err != nil {
+				return err
+			}
+			values, err := runtimesvc.ParseRecipeParams(params)
+			if err != nil {
+				return err
+			}
+			rendered, err := recipe.Render(values)
+			if err != nil {
+				return err
+			}
+			run.Type = string(rendered.Type)
+			run.Instruction = rendered.Instruction
+			run.Files = append(rendered.Files, run.Files...)
+			run.Context = rendered.Context
+			run.Context["recipe"] = recipe.Name
+			run.Check = func(task *framework.Task) error { return recipe.CheckContext(task.Context) }
+			return runHeadlessTask(cmd, run)
+		},
+	}
+	runCmd.Flags().StringArrayVar(&params, "param", nil, "Recipe parameter as key=value (repeatable)")
+	run.bindFlags(runCmd)
+	cmd.AddCommand(list, runCmd)
+	return cmd
+}
+
+// mergeIsolated copies a successful isolated task's changes into the real
+// workspace, after confirmation unless autoApprove is set. The merge is
+// checkpointed under the task ID in checkpoints, so `relurpish job rollback`
+// undoes it.
+func mergeIsolated(cmd *cobra.Command, iso *runtimesvc.IsolatedWorkspace, checkpoints, taskID string, autoApprove bool) error {
+	stderr := cmd.ErrOrStderr()
+	changes, err := iso.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(stderr, "No changes to merge")
+		return nil
+	}
+	if !autoApprove && !runtimesvc.ConfirmMerge(cmd.InOrStdin(), stderr, iso.Source, changes) {
+		fmt.Fprintln(stderr, "Discarded the isolated changes")
+		return nil
+	}
+	store, err := persistence.NewFileCheckpointStore(checkpoints)
+	if err != nil {
+		return err
+	}
+	if err := iso.Merge(changes, store, taskID); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Merged %d files; undo with relurpish job rollback %s\n", len(changes), taskID)
+	return nil
+}
+
+// printTaskReport renders report for people, with each file's diff when
+// showDiffs is set.
+func printTaskReport(out io.Writer, report *runtimesvc.TaskReport, showDiffs bool) {
+	if report.Node != "" || report.Output != nil {
+		fmt.Fprintf(out, "Result (node=%s): %+v\n", report.Node, report.Output)
+	}
+	if conflict, ok := report.Output["conflict"].(framework.Conflict); ok {
+		fmt.Fprintf(out, "Needs your decision: %s\n", conflict.Reason)
+	}
+	if report.Status == runtimesvc.TaskStatusTimedOut || report.Status == runtimesvc.TaskStatusBudgetExceeded {
+		fmt.Fprintf(out, "Status: %s (%s)\n", report.Status, report.Error)
+		fmt.Fprintf(out, "Completed nodes: %s\n", strings.Join(report.CompletedNodes, ", "))
+	}
+	if report.Status == runtimesvc.TaskStatusBudgetExceeded {
+		fmt.Fprintf(out, "Resume with: relurpish workflow resume %s\n", report.TaskID)
+	}
+	if len(report.FilesChanged) > 0 {
+		fmt.Fprintf(out, "Files changed: %s\n", strings.Join(report.FilesChanged, ", "))
+	}
+	if showDiffs {
+		for _, diff := range report.Diffs {
+			fmt.Fprintf(out, "\n=== %s\n%s", diff.Path, diff.Diff)
+			if diff.Truncated {
+				fmt.Fprintln(out, "(diff truncated)")
+			}
+		}
+	}
+	if tests := report.Tests; tests != nil {
+		fmt.Fprintf(out, "Tests (%s): %d passed, %d failed, %d skipped\n", tests.Language, tests.Passed, tests.Failed, tests.Skipped)
+	}
+	for _, denial := range report.Denials {
+		fmt.Fprintf(out, "Denied: %s %s\n", denial.Action, denial.Resource)
+	}
+	for _, suggestion := range report.Suggestions {
+		fmt.Fprintf(out, "Suggested (not applied): %s %v\n", suggestion.Tool, suggestion.Args)
+	}
+	fmt.Fprintf(out, "Usage: %s\n", formatUsage(report.Usage))
+	fmt.Fprintf(out, "Workflow: %s\n", report.TaskID)
+}
+
+// newBatchCmd runs a YAML file of tasks without the TUI for scripted jobs
+// such as nightly refactors. It exits non-zero when any task fails.
+func newBatchCmd() *cobra.Command {
+	var file string
+	var outDir string
+	var parallel int
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run a file of tasks non-interactively",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch, err := runtimesvc.LoadBatchFile(file)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("parallel") {
+				batch.Parallel = parallel
+			}
+			if outDir == "" {
+				outDir = filepath.Join(cfg.ArtifactsPath, "batch", time.Now().UTC().Format("20060102-150405"))
+			}
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				out := cmd.OutOrStdout()
+				report, err := rt.RunBatch(ctx, batch, runtimesvc.BatchOptions{
+					Parallel:  batch.Parallel,
+					OutputDir: outDir,
+					OnResult: func(result runtimesvc.BatchTaskResult) {
+						line := fmt.Sprintf("%-10s %s (%s)", result.Status, result.ID, result.Duration)
+						if result.Error != "" {
+							line += ": " + result.Error
+						}
+						fmt.Fprintln(out, line)
+					},
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Batch: %d succeeded, %d failed in %s\n", report.Succeeded, report.Failed, report.Duration)
+				fmt.Fprintf(out, "Results: %s\n", outDir)
+				if report.Failed > 0 {
+					return fmt.Errorf("%d of %d batch tasks failed", report.Failed, report.Total)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "YAML file listing the tasks to run")
+	cmd.Flags().StringVarP(&outDir, "out", "o", "", "Directory for per-task result JSON (default <artifacts>/batch/<timestamp>)")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Run up to N tasks at once (overrides parallel in the file)")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// representativeInstruction is profiled when `profile capture` gets no
+// instruction: it exercises planning, file reads, and the AST index without
+// modifying the workspace.
+const representativeInstruction = "Summarize the workspace layout and the purpose of its main packages"
+
+// newProfileCmd groups performance investigation helpers.
+func newProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Capture performance profiles",
+	}
+	var taskType string
+	var outDir string
+	var heap bool
+	capture := &cobra.Command{
+		Use:   "capture [instruction]",
+		Short: "Record CPU and heap profiles while running one task",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			instruction := strings.Join(args, " ")
+			if instruction == "" {
+				instruction = representativeInstruction
+			}
+			if outDir == "" {
+				outDir = filepath.Join(cfg.ArtifactsPath, "profiles")
+			}
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				task := &framework.Task{
+					ID:          fmt.Sprintf("task-%d", time.Now().UnixNano()),
+					Type:        framework.TaskType(taskType),
+					Instruction: instruction,
+				}
+				result, err := runtimesvc.CaptureProfile(outDir, heap, func() error {
+					_, err := rt.RunTask(ctx, task)
+					return err
+				})
+				if result == nil {
+					return err
+				}
+				out := cmd.OutOrStdout()
+				fmt.Fprintf(out, "Task:    %s (%s)\n", task.ID, result.Duration.Round(time.Millisecond))
+				fmt.Fprintf(out, "CPU:     %s\n", result.CPUPath)
+				if result.HeapPath != "" {
+					fmt.Fprintf(out, "Heap:    %s\n", result.HeapPath)
+				}
+				fmt.Fprintf(out, "Inspect with: go tool pprof -http=:0 %s\n", result.CPUPath)
+				return err
+			})
+		},
+	}
+	capture.Flags().StringVar(&taskType, "type", string(framework.TaskTypeAnalysis), "Task type for the profiled instruction")
+	capture.Flags().StringVar(&outDir, "out", "", "Output directory (default <artifacts>/profiles)")
+	capture.Flags().BoolVar(&heap, "heap", true, "Also write a heap profile after the task")
+	cmd.AddCommand(capture)
 	return cmd
 }
 
+// newWorkflowCmd inspects workflows recorded by previous runs.
+func newWorkflowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workflow",
+		Short: "Inspect and resume recorded workflows",
+	}
+	var (
+		statuses []string
+		agent    string
+		since    time.Duration
+		limit    int
+		offset   int
+	)
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded workflows, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := runtimesvc.OpenWorkflowStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer runtimesvc.CloseWorkflowStore(store)
+			query := persistence.WorkflowQuery{Agent: agent, Limit: limit, Offset: offset}
+			for _, status := range statuses {
+				query.Statuses = append(query.Statuses, persistence.WorkflowStatus(status))
+			}
+			if since > 0 {
+				query.Since = time.Now().Add(-since)
+			}
+			snapshots, err := persistence.QueryWorkflows(cmd.Context(), store, query)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, snap := range snapshots {
+				tokens := 0
+				if snap.Usage != nil {
+					tokens = snap.Usage.Total.TotalTokens
+				}
+				fmt.Fprintf(out, "%s\t%s\t%s\t%d tokens\n", snap.ID, snap.Status, snap.UpdatedAt.Local().Format(time.DateTime), tokens)
+			}
+			return nil
+		},
+	}
+	list.Flags().StringSliceVar(&statuses, "status", nil, "Only list workflows with these statuses (completed, failed, timed_out, ...)")
+	list.Flags().StringVar(&agent, "agent", "", "Only list workflows run by this agent")
+	list.Flags().DurationVar(&since, "since", 0, "Only list workflows updated within this duration, e.g. 24h")
+	list.Flags().IntVar(&limit, "limit", 0, "Maximum number of workflows to list")
+	list.Flags().IntVar(&offset, "offset", 0, "Skip this many workflows before listing")
+	cmd.AddCommand(list)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a workflow with its token usage breakdown",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := runtimesvc.OpenWorkflowStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer runtimesvc.CloseWorkflowStore(store)
+			snap, ok, err := store.Load(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("workflow %s not found", args[0])
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Workflow: %s\nStatus:   %s\nUpdated:  %s\n", snap.ID, snap.Status, snap.UpdatedAt.Local().Format(time.DateTime))
+			if snap.Task != nil {
+				fmt.Fprintf(out, "Task:     [%s] %s\n", snap.Task.Type, snap.Task.Instruction)
+			}
+			if msg, ok := snap.Metadata["error"]; ok {
+				fmt.Fprintf(out, "Error:    %v\n", msg)
+			}
+			if limit, ok := snap.Metadata["budget_limit"]; ok {
+				fmt.Fprintf(out, "Budget:   %v exceeded\n", limit)
+			}
+			if nodes, ok := snap.Metadata["completed_nodes"]; ok {
+				fmt.Fprintf(out, "Completed nodes: %v\n", nodes)
+			}
+			if snap.Usage == nil {
+				fmt.Fprintln(out, "Usage:    not recorded")
+				return nil
+			}
+			fmt.Fprintf(out, "Usage:    %s\n", formatUsage(snap.Usage.Total))
+			printUsageBreakdown(cmd, "By node", snap.Usage.ByNode)
+			printUsageBreakdown(cmd, "By model", snap.Usage.ByModel)
+			return nil
+		},
+	})
+	var resume headlessTask
+	resumeCmd := &cobra.Command{
+		Use:   "resume <id>",
+		Short: "Run a task stopped by its budget again from its saved context",
+		Long:  "Run a task that exceeded its budget again, starting from the agent context saved when it stopped. The original budget applies unless budget flags are given.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := runtimesvc.OpenWorkflowStore(cfg)
+			if err != nil {
+				return err
+			}
+			snap, ok, err := store.Load(cmd.Context(), args[0])
+			runtimesvc.CloseWorkflowStore(store)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("workflow %s not found", args[0])
+			}
+			if snap.Task == nil || snap.Graph == nil || snap.Graph.State == nil {
+				return fmt.Errorf("workflow %s (%s) has no saved context to resume", snap.ID, snap.Status)
+			}
+			resume.Type = string(snap.Task.Type)
+			resume.Instruction = snap.Task.Instruction
+			resume.Context = map[string]interface{}{}
+			for key, value := range snap.Task.Context {
+				resume.Context[key] = value
+			}
+			resume.Context["resumed_from"] = snap.ID
+			if resume.Budget.IsZero() && snap.Task.Budget != nil {
+				resume.Budget = *snap.Task.Budget
+			}
+			resume.Resume = snap.Graph.State
+			return runHeadlessTask(cmd, resume)
+		},
+	}
+	resume.bindFlags(resumeCmd)
+	cmd.AddCommand(resumeCmd)
+	return cmd
+}
+
+// newArtifactsCmd lists and extracts the reports, diagrams, and data files
+// tasks saved with artifact_save.
+func newArtifactsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "List and download the artifacts tasks produced",
+	}
+	var taskID string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List saved artifacts, oldest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := persistence.NewFileArtifactStore(cfg.ArtifactsPath)
+			if err != nil {
+				return err
+			}
+			artifacts, err := store.ListArtifacts(cmd.Context(), taskID)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, artifact := range artifacts {
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%d bytes\t%s\n", artifact.ID, artifact.TaskID, artifact.Type, artifact.Name, artifact.Size, artifact.CreatedAt.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+	list.Flags().StringVar(&taskID, "task", "", "Only list artifacts of this task")
+	cmd.AddCommand(list)
+	var output string
+	get := &cobra.Command{
+		Use:   "get <id>",
+		Short: "Write an artifact's content to stdout or a file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := persistence.NewFileArtifactStore(cfg.ArtifactsPath)
+			if err != nil {
+				return err
+			}
+			artifact, content, ok, err := store.LoadArtifact(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("artifact %s not found", args[0])
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(content)
+				return err
+			}
+			if info, err := os.Stat(output); err == nil && info.IsDir() {
+				output = filepath.Join(output, filepath.Base(artifact.Name))
+			}
+			if err := os.WriteFile(output, content, 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %s\n", output)
+			return nil
+		},
+	}
+	get.Flags().StringVarP(&output, "output", "o", "", "Write to this file, or into this directory under the artifact's name")
+	cmd.AddCommand(get)
+	return cmd
+}
+
+// newJobCmd inspects and reverts the file changes recorded per task. Job
+// IDs are task IDs, as listed by `relurpish workflow list`.
+func newJobCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "job",
+		Short: "Inspect queued tasks and roll back the file changes of past tasks",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List jobs with file checkpoints, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := persistence.NewFileCheckpointStore(cfg.CheckpointPath)
+			if err != nil {
+				return err
+			}
+			checkpoints, err := store.List()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, checkpoint := range checkpoints {
+				status := "active"
+				if checkpoint.RolledBackAt != nil {
+					status = "rolled back"
+				}
+				fmt.Fprintf(out, "%s\t%s\t%d files\t%s\n", checkpoint.JobID, checkpoint.CreatedAt.Local().Format(time.DateTime), len(checkpoint.Files), status)
+			}
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rollback <job-id>",
+		Short: "Restore every file a job modified and delete the files it created",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := persistence.NewFileCheckpointStore(cfg.CheckpointPath)
+			if err != nil {
+				return err
+			}
+			restored, err := store.Rollback(args[0])
+			out := cmd.OutOrStdout()
+			for _, path := range restored {
+				fmt.Fprintf(out, "restored %s\n", relativeToWorkspace(path))
+			}
+			return err
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "queue",
+		Short: "List the tasks queued or running on the server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := runtimesvc.NewJobClient(cfg.ServerAddr).List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, record := range records {
+				switch record.Status {
+				case server.TaskStatusQueued, server.TaskStatusPaused, server.TaskStatusRunning:
+					fmt.Fprintf(out, "%s\t%s\tpriority %d\t%s\n", record.ID, record.Status, record.Priority, record.Instruction)
+				}
+			}
+			return nil
+		},
+	})
+	for _, action := range []struct {
+		name  string
+		short string
+		run   func(*runtimesvc.JobClient, context.Context, string) (server.TaskRecord, error)
+	}{
+		{"cancel", "Cancel a queued task, or stop a running one", (*runtimesvc.JobClient).Cancel},
+		{"pause", "Hold a queued task back until it is resumed", (*runtimesvc.JobClient).Pause},
+		{"resume", "Queue a paused task again", (*runtimesvc.JobClient).Resume},
+	} {
+		action := action
+		cmd.AddCommand(&cobra.Command{
+			Use:   action.name + " <job-id>",
+			Short: action.short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				record, err := action.run(runtimesvc.NewJobClient(cfg.ServerAddr), cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", record.ID, record.Status)
+				return nil
+			},
+		})
+	}
+	return cmd
+}
+
+// relativeToWorkspace shortens paths under the workspace for display.
+func relativeToWorkspace(path string) string {
+	if rel, err := filepath.Rel(cfg.Workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// newProjectsCmd lists the projects detected in the workspace, merged with
+// the projects entries of config.yaml.
+func newProjectsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "projects",
+		Short: "List the projects in a monorepo workspace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := runtimesvc.LoadWorkspaceConfig(cfg.ConfigPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			projects, err := runtimesvc.ResolveProjects(cfg.Workspace, ws.Projects)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(projects) == 0 {
+				fmt.Fprintln(out, "No projects found.")
+				return nil
+			}
+			for _, project := range projects {
+				fmt.Fprintf(out, "%s (%s)\n", project.Path, strings.Join(project.Languages, ", "))
+				fmt.Fprintf(out, "  build: %s\n  lint:  %s\n", strings.Join(project.BuildCommand(), " "), strings.Join(project.LintCommand(), " "))
+			}
+			return nil
+		},
+	}
+}
+
+// newAskCmd answers one question about the codebase with the read-only ask
+// agent, whatever --agent is, so it is safe to point at any workspace.
+func newAskCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ask <question>",
+		Short: "Answer a question about the codebase without modifying it",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				res, err := rt.Ask(ctx, strings.Join(args, " "))
+				if err != nil {
+					return err
+				}
+				answer, _ := res.Data["final_output"].(string)
+				fmt.Fprintln(cmd.OutOrStdout(), answer)
+				return nil
+			})
+		},
+	}
+}
+
+// newReviewCmd reviews a diff with the review agent, whatever --agent is,
+// and prints inline comments as Markdown or as a GitHub PR review.
+func newReviewCmd() *cobra.Command {
+	var (
+		opts     runtimesvc.ReviewOptions
+		diffPath string
+		format   string
+	)
+	cmd := &cobra.Command{
+		Use:   "review",
+		Short: "Review a diff or the changes since a git ref and print inline comments",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "markdown" && format != "github" {
+				return fmt.Errorf("unknown --format %q (want markdown or github)", format)
+			}
+			if diffPath != "" {
+				var data []byte
+				var err error
+				if diffPath == "-" {
+					data, err = io.ReadAll(cmd.InOrStdin())
+				} else {
+					data, err = os.ReadFile(diffPath)
+				}
+				if err != nil {
+					return err
+				}
+				opts.Diff = string(data)
+			}
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				review, err := rt.Review(ctx, opts)
+				if err != nil {
+					return err
+				}
+				out := cmd.OutOrStdout()
+				if format == "github" {
+					enc := json.NewEncoder(out)
+					enc.SetIndent("", "  ")
+					return enc.Encode(review.GitHubReview())
+				}
+				fmt.Fprint(out, review.Markdown())
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&opts.Base, "base", "", "Git ref to review changes against (default HEAD)")
+	cmd.Flags().StringVar(&opts.Head, "head", "", "Review the commits this ref adds since it forked from --base instead of the working tree")
+	cmd.Flags().StringVar(&diffPath, "diff", "", "Review this unified diff file instead of asking git (- reads stdin)")
+	cmd.Flags().StringVar(&opts.Focus, "focus", "", "What the reviewer should pay most attention to")
+	cmd.Flags().StringVar(&format, "format", "markdown", "Output format: markdown or github (PR review JSON)")
+	return cmd
+}
+
+// newEditorServerCmd speaks JSON-RPC over stdin and stdout so editor plugins
+// can run the agent on a buffer. Logs go to the log file, never stdout.
+func newEditorServerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "editor-server",
+		Short: "Serve the agent to an editor plugin over JSON-RPC on stdio",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				return rt.ServeEditor(ctx, stdioStream{Reader: cmd.InOrStdin(), Writer: cmd.OutOrStdout()})
+			})
+		},
+	}
+}
+
+// newDoctorCmd checks the whole setup without starting the runtime, so it
+// still runs when a broken setup keeps the runtime from starting.
+func newDoctorCmd() *cobra.Command {
+	var output string
+	var modelTimeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check Ollama, the model, language servers, the sandbox, and workspace state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			report := runtimesvc.RunDoctor(ctx, cfg, runtimesvc.DoctorOptions{ModelTimeout: modelTimeout})
+			out := cmd.OutOrStdout()
+			switch output {
+			case "text":
+				report.Write(out)
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown output format %q (want text or json)", output)
+			}
+			if !report.OK() {
+				return &exitError{code: 1, err: errors.New("doctor found problems")}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "text", "Output format: text or json")
+	cmd.Flags().DurationVar(&modelTimeout, "model-timeout", 2*time.Minute, "Time allowed for the tool-calling probe, including loading the model")
+	return cmd
+}
+
+// newLSPCmd queries the configured language servers directly.
+func newLSPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Query the configured language servers",
+	}
+	var language string
+	var limit int
+	symbols := &cobra.Command{
+		Use:   "symbols <query>",
+		Short: "Search symbols across every language in the workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				proxy := rt.LSP()
+				if proxy == nil {
+					return errors.New("no language servers configured; enable lsp in the agent manifest")
+				}
+				// Languages that fail to index are still searched live.
+				if err := proxy.IndexSymbols(ctx); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+				}
+				found, err := proxy.SearchSymbols(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				out := cmd.OutOrStdout()
+				shown := 0
+				root, err := filepath.Abs(cfg.Workspace)
+				if err != nil {
+					return err
+				}
+				prefix := root + string(filepath.Separator)
+				for _, symbol := range found {
+					if language != "" && symbol.Language != language {
+						continue
+					}
+					if limit > 0 && shown == limit {
+						break
+					}
+					shown++
+					fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", symbol.Name, symbol.Kind, symbol.Language, strings.TrimPrefix(symbol.Location, prefix))
+				}
+				if shown == 0 {
+					fmt.Fprintln(out, "No symbols found.")
+				}
+				return nil
+			})
+		},
+	}
+	symbols.Flags().StringVar(&language, "language", "", "Only show symbols of this language (e.g. go, typescript)")
+	symbols.Flags().IntVar(&limit, "limit", 50, "Show at most this many symbols; 0 shows all")
+	cmd.AddCommand(symbols)
+	return cmd
+}
+
+// stdioStream joins stdin and stdout into one stream. Closing it is a no-op;
+// the process owns both.
+type stdioStream struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioStream) Close() error { return nil }
+
+// newInspectCmd shows how agents are put together without running them.
+func newInspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Inspect agent structure",
+	}
+	var format string
+	graphCmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Print the --agent graph as Graphviz DOT or Mermaid",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			graph, err := runtimesvc.AgentGraph(cfg)
+			if err != nil {
+				return err
+			}
+			out, err := graph.Export(framework.GraphFormat(format))
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), out)
+			return nil
+		},
+	}
+	graphCmd.Flags().StringVar(&format, "format", string(framework.GraphFormatMermaid), "Output format (mermaid, dot)")
+	cmd.AddCommand(graphCmd)
+	return cmd
+}
+
+// newMemoryCmd maintains the workspace memory store.
+func newMemoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Maintain the workspace memory store",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "consolidate",
+		Short: "Summarize raw memories into long-term entries and merge old summaries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := runtimesvc.ConsolidateMemory(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Summarized %d records, merged %d summaries\n", report.Consolidated, report.Merged)
+			for _, key := range report.Written {
+				fmt.Fprintf(out, "  wrote %s\n", key)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newContextCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "context",
+		Short: "Move saved session context between machines",
+	}
+	var output string
+	var redact bool
+	exportCmd := &cobra.Command{
+		Use:   "export [session]",
+		Short: "Bundle a session's context snapshot, memory, and plans into an archive",
+		Long:  "Bundle a saved chat session (default: the most recent) with its context snapshot, session and project memory, plan files, and workflow snapshots into a .tar.gz archive.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessionID := "last"
+			if len(args) == 1 {
+				sessionID = args[0]
+			}
+			var buf bytes.Buffer
+			manifest, err := runtimesvc.ExportContext(cmd.Context(), cfg, sessionID, &buf, runtimesvc.ContextExportOptions{Redact: redact})
+			if err != nil {
+				return err
+			}
+			path := output
+			if path == "" {
+				path = fmt.Sprintf("relurpify-context-%s.tar.gz", manifest.SessionID)
+			}
+			if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported session %s (%d memory records, %d plans, %d workflows) to %s\n",
+				manifest.SessionID, manifest.Memory, len(manifest.Plans), manifest.Workflows, path)
+			return nil
+		},
+	}
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "Archive path (default relurpify-context-<session>.tar.gz)")
+	exportCmd.Flags().BoolVar(&redact, "redact", false, "Mask secrets so the archive can be attached to a bug report")
+
+	var force bool
+	importCmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Restore a context bundle into this workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			report, err := runtimesvc.ImportContext(cmd.Context(), cfg, f, runtimesvc.ContextImportOptions{Force: force})
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Imported session %s: %d memory records (%d already present), %d plans, %d workflows\n",
+				report.Manifest.SessionID, report.MemoryImported, report.MemorySkipped, len(report.Plans), report.Workflows)
+			if report.Manifest.Redacted {
+				fmt.Fprintln(out, "Warning: the bundle was exported with --redact; masked secrets must be restored by hand.")
+			}
+			fmt.Fprintf(out, "Resume with: relurpish chat --resume %s\n", report.Manifest.SessionID)
+			return nil
+		},
+	}
+	importCmd.Flags().BoolVar(&force, "force", false, "Replace an existing session, plans, and memory records")
+
+	cmd.AddCommand(exportCmd, importCmd)
+	return cmd
+}
+
+// newReplayCmd re-runs a task recorded with --record from its replay log.
+func newReplayCmd() *cobra.Command {
+	var step bool
+	cmd := &cobra.Command{
+		Use:   "replay <task-id>",
+		Short: "Re-run a task recorded with --record, without a model",
+		Long: "Re-run a task recorded with --record. Model calls are answered from the recording and tools " +
+			"return their recorded results, so no model is needed and no files change. Each call is printed " +
+			"as it is replayed; --step pauses after each one.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := framework.ReplayPath(runtimesvc.ReplayDir(cfg.Workspace), args[0])
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("no recording for task %s; run it with --record first", args[0])
+			}
+			cfg.ReplayPath = path
+			cfg.Record = false
+			return runWithRuntime(cmd, func(ctx context.Context, rt *runtimesvc.Runtime) error {
+				out := cmd.OutOrStdout()
+				in := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprintf(out, "Replaying %s: [%s] %s\n", rt.Replayer.Task.ID, rt.Replayer.Task.Type, rt.Replayer.Task.Instruction)
+				rt.Replayer.OnStep = func(entry framework.ReplayEntry) {
+					printReplayStep(out, entry)
+					if step {
+						fmt.Fprint(out, "  -- enter to continue --")
+						_, _ = in.ReadString('\n')
+					}
+				}
+				report, err := rt.Replay(ctx)
+				if report != nil {
+					fmt.Fprintf(out, "Replayed %d of %d recorded calls\n", report.Served, report.Steps)
+					for _, mismatch := range report.Mismatches {
+						fmt.Fprintf(out, "  warning: %s\n", mismatch)
+					}
+					if report.Result != nil && err == nil {
+						fmt.Fprintf(out, "Result: success=%t\n", report.Result.Success)
+					}
+				}
+				return err
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&step, "step", false, "Pause after each replayed call")
+	return cmd
+}
+
+// printReplayStep prints one recorded call on a line or two.
+func printReplayStep(out io.Writer, entry framework.ReplayEntry) {
+	switch entry.Kind {
+	case framework.ReplayKindModel:
+		fmt.Fprintf(out, "[%d] model %s\n", entry.Seq, entry.Method)
+		if entry.Error != "" {
+			fmt.Fprintf(out, "    error: %s\n", entry.Error)
+			return
+		}
+		if entry.Response != nil {
+			if text := strings.TrimSpace(entry.Response.Text); text != "" {
+				fmt.Fprintf(out, "    %s\n", clipLine(text, 200))
+			}
+			for _, call := range entry.Response.ToolCalls {
+				fmt.Fprintf(out, "    -> %s\n", call.Name)
+			}
+		}
+	case framework.ReplayKindTool:
+		args, _ := json.Marshal(entry.Args)
+		fmt.Fprintf(out, "[%d] tool %s %s\n", entry.Seq, entry.Tool, clipLine(string(args), 200))
+		switch {
+		case entry.Error != "":
+			fmt.Fprintf(out, "    error: %s\n", entry.Error)
+		case entry.Result != nil && !entry.Result.Success:
+			fmt.Fprintf(out, "    failed: %s\n", entry.Result.Error)
+		}
+	}
+}
+
+// clipLine flattens s to one line of at most max bytes.
+func clipLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
+
+// formatUsage renders a one-line token summary.
+func formatUsage(u framework.LLMUsage) string {
+	line := fmt.Sprintf("%d calls, %d prompt + %d completion = %d tokens", u.Calls, u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+	if u.Cost > 0 {
+		line += fmt.Sprintf(", cost %.4f", u.Cost)
+	}
+	return line
+}
+
+func printUsageBreakdown(cmd *cobra.Command, title string, buckets map[string]framework.LLMUsage) {
+	if len(buckets) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(cmd.OutOrStdout(), "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(cmd.OutOrStdout(), "  %-24s %s\n", key, formatUsage(buckets[key]))
+	}
+}
+
 // runWithRuntime ensures the runtime is created and cleaned up for the command.
 func runWithRuntime(cmd *cobra.Command, fn func(context.Context, *runtimesvc.Runtime) error) error {
 	ctx := cmd.Context()
@@ -132,11 +1550,20 @@ func runWithRuntime(cmd *cobra.Command, fn func(context.Context, *runtimesvc.Run
 		return err
 	}
 	defer rt.Close()
+	if cfg.PprofAddr != "" {
+		addr, stop, err := runtimesvc.StartProfiler(ctx, cfg.PprofAddr)
+		if err != nil {
+			return err
+		}
+		defer stop(context.Background())
+		rt.Logger.Printf("pprof listening on http://%s/debug/pprof/", addr)
+		fmt.Fprintf(cmd.ErrOrStderr(), "pprof listening on http://%s/debug/pprof/\n", addr)
+	}
 	return fn(ctx, rt)
 }
 
 // runTUI optionally starts the server and then launches the Bubble Tea program.
-func runTUI(ctx context.Context, rt *runtimesvc.Runtime) error {
+func runTUI(ctx context.Context, rt *runtimesvc.Runtime, opts tui.Options) error {
 	var stop func(context.Context) error
 	var err error
 	if startServer {
@@ -146,9 +1573,18 @@ func runTUI(ctx context.Context, rt *runtimesvc.Runtime) error {
 		}
 		defer stop(context.Background())
 	}
+	if rt.WatchEnabled() {
+		stopWatch, err := rt.StartWatch(ctx)
+		if err != nil {
+			return err
+		}
+		defer stopWatch()
+	}
+	stopLifecycle := rt.StartModelLifecycle(ctx)
+	defer stopLifecycle()
 	// Prevent stdlib logger output (used by some debug paths) from drawing over the TUI.
 	if rt != nil && rt.Logger != nil {
 		log.SetOutput(rt.Logger.Writer())
 	}
-	return tui.Run(ctx, rt)
+	return tui.Run(ctx, rt, opts)
 }
@@ -0,0 +1,21 @@
+package runtime
+
+import (
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// AgentGraph builds the graph of the agent cfg selects, a preset or a
+// definition in AgentsDir, without contacting a model or opening the
+// workspace, so its structure can be inspected and exported. Agents get a
+// mock model, since building a graph never calls it.
+func AgentGraph(cfg Config) (*framework.Graph, error) {
+	agentCfg := &framework.Config{Name: cfg.AgentLabel()}
+	defs, _ := LoadAgentDefinitions(cfg.AgentsDir)
+	def := applyAgentDefinition(cfg, defs, agentCfg)
+	agent := instantiateAgent(cfg, def, &llm.MockModel{}, framework.NewToolRegistry(), nil, agentCfg)
+	if err := agent.Initialize(agentCfg); err != nil {
+		return nil, err
+	}
+	return agent.BuildGraph(&framework.Task{ID: "inspect", Type: framework.TaskTypeAnalysis})
+}
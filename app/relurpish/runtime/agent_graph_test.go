@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const triageDefinition = `kind: AgentDefinition
+name: triage
+spec:
+  implementation: graph
+  mode: primary
+  model: {provider: ollama, name: qwen2.5-coder}
+  tools: {file_read: true}
+  graph:
+    start: classify
+    nodes:
+      - {id: classify, type: llm-prompt, prompt: "Classify: {{.Instruction}}"}
+      - {id: confirm, type: human, prompt: "Label as {{state \"classify.output\"}}?"}
+      - {id: done, type: terminal}
+    edges:
+      - {from: classify, to: confirm}
+      - {from: confirm, to: classify, when: not confirm.approved}
+      - {from: confirm, to: done, when: confirm.approved}
+`
+
+// TestAgentGraphLoadsDeclaredAgent selects a YAML-defined agent by name and
+// renders its declared workflow.
+func TestAgentGraphLoadsDeclaredAgent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "triage.yaml"), []byte(triageDefinition), 0o644))
+
+	graph, err := AgentGraph(Config{AgentName: "triage", AgentsDir: dir})
+	require.NoError(t, err)
+	out, err := graph.Export(framework.GraphFormatMermaid)
+	require.NoError(t, err)
+	require.Contains(t, out, `confirm[/"confirm (human)"/]`)
+	require.Contains(t, out, `confirm -.->|"loop: not confirm.approved"| classify`)
+}
+
+// TestLoadAgentDefinitionsRejectsBrokenGraph reports graph errors with the
+// file that declared them.
+func TestLoadAgentDefinitionsRejectsBrokenGraph(t *testing.T) {
+	dir := t.TempDir()
+	broken := []byte("kind: AgentDefinition\nname: broken\nspec:\n  implementation: graph\n  mode: primary\n  model: {provider: ollama, name: m}\n  tools: {file_read: true}\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), broken, 0o644))
+
+	_, err := LoadAgentDefinitions(dir)
+	require.ErrorContains(t, err, "broken.yaml")
+	require.ErrorContains(t, err, "graph")
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/server"
+)
+
+// apiKeyEnv holds an admin key for the HTTP API, for deployments that
+// configure the server through the environment alone.
+const apiKeyEnv = "RELURPIFY_API_KEY"
+
+// APIKeyConfig declares an HTTP API key in config.yaml:
+//
+//	api_keys:
+//	  - name: ci
+//	    key_env: RELURPIFY_CI_KEY
+//	    roles: [submit-tasks]
+//	  - name: oncall
+//	    key_env: RELURPIFY_ONCALL_KEY
+//	    roles: [approve-hitl]
+//
+// KeyEnv names an environment variable holding the key so it stays out of the
+// workspace config. Roles are read-only, submit-tasks, approve-hitl, and
+// admin; every role can also read.
+type APIKeyConfig struct {
+	Name   string   `yaml:"name"`
+	KeyEnv string   `yaml:"key_env"`
+	Roles  []string `yaml:"roles"`
+}
+
+// buildAPIAuth combines config.yaml keys with RELURPIFY_API_KEY. It returns
+// nil when no key is configured, leaving the API open.
+func buildAPIAuth(ws WorkspaceConfig, audit framework.AuditLogger) (*server.APIAuth, error) {
+	var keys []server.APIKey
+	for i, kc := range ws.APIKeys {
+		if kc.Name == "" {
+			return nil, fmt.Errorf("api key %d: name required", i)
+		}
+		if kc.KeyEnv == "" {
+			return nil, fmt.Errorf("api key %s: key_env required", kc.Name)
+		}
+		key := server.APIKey{Name: kc.Name, Key: os.Getenv(kc.KeyEnv)}
+		if key.Key == "" {
+			return nil, fmt.Errorf("api key %s: %s is not set", kc.Name, kc.KeyEnv)
+		}
+		if len(kc.Roles) == 0 {
+			return nil, fmt.Errorf("api key %s: at least one role required", kc.Name)
+		}
+		for _, name := range kc.Roles {
+			role, err := server.ParseAPIRole(name)
+			if err != nil {
+				return nil, fmt.Errorf("api key %s: %w", kc.Name, err)
+			}
+			key.Roles = append(key.Roles, role)
+		}
+		keys = append(keys, key)
+	}
+	if value := os.Getenv(apiKeyEnv); value != "" {
+		keys = append(keys, server.APIKey{Name: "env", Key: value, Roles: []server.APIRole{server.APIRoleAdmin}})
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &server.APIAuth{Keys: keys, Audit: audit}, nil
+}
+
+// loopbackAddr reports whether addr only accepts local connections.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/agents"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// AskAgentName labels tasks answered by the read-only ask preset.
+const AskAgentName = "ask"
+
+// Ask answers question about the workspace with the ask preset, whatever
+// agent the session runs. The preset only registers read-only tools and
+// authorizes them against a read-only copy of the manifest permissions, so
+// asking can never change the workspace. Its state is not merged into the
+// session context.
+func (r *Runtime) Ask(ctx context.Context, question string) (*framework.Result, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, errors.New("question required")
+	}
+	agent, err := r.askAgentInstance()
+	if err != nil {
+		return nil, err
+	}
+	task := &framework.Task{
+		ID:          fmt.Sprintf("ask-%d", time.Now().UnixNano()),
+		Type:        framework.TaskTypeQuestion,
+		Instruction: question,
+		Context:     map[string]any{"source": AskAgentName},
+	}
+	ctx = framework.WithGraphTimeouts(ctx, r.timeouts)
+	state := r.Context.Clone()
+	state.Set("task.id", task.ID)
+	state.Set("task.type", string(task.Type))
+	state.Set("task.instruction", task.Instruction)
+	state.Set("task.source", AskAgentName)
+	state.Set("task.agent", AskAgentName)
+	start := time.Now()
+	res, err := agent.Execute(ctx, task, state)
+	if r.Metrics != nil {
+		r.Metrics.ObserveTask(task, res, err, time.Since(start))
+	}
+	r.saveWorkflow(ctx, task, state, err)
+	return res, err
+}
+
+// askAgentInstance builds the ask preset on first use from the session's
+// tools, model, and memory.
+func (r *Runtime) askAgentInstance() (*agents.QAAgent, error) {
+	r.askMu.Lock()
+	defer r.askMu.Unlock()
+	if r.askAgent != nil {
+		return r.askAgent, nil
+	}
+	if r.Registration == nil || r.Registration.Manifest == nil {
+		return nil, errors.New("ask requires a registered agent manifest")
+	}
+	perms := askPermissionSet(r.Registration.Manifest.Spec.Permissions)
+	manager, err := framework.NewPermissionManager(r.Config.Workspace, &perms, r.Registration.Audit, r.Registration.HITL)
+	if err != nil {
+		return nil, fmt.Errorf("ask permissions: %w", err)
+	}
+	cfg := &framework.Config{Name: AskAgentName}
+	if r.agentConfig != nil {
+		copied := *r.agentConfig
+		copied.Name = AskAgentName
+		cfg = &copied
+	}
+	agent := agents.NewAskAgent(cfg.ModelFor(framework.ModelRoleCoding, r.Model), r.Tools, r.Memory)
+	agent.Tools.UsePermissionManager(r.Registration.ID, manager)
+	if err := agent.Initialize(cfg); err != nil {
+		return nil, err
+	}
+	r.askAgent = agent
+	return agent, nil
+}
+
+// askPermissionSet keeps the filesystem read and list grants of declared and
+// drops everything else, giving the read-only permission profile without
+// widening what the manifest allows.
+func askPermissionSet(declared framework.PermissionSet) framework.PermissionSet {
+	var perms framework.PermissionSet
+	for _, fs := range declared.FileSystem {
+		if fs.Action == framework.FileSystemRead || fs.Action == framework.FileSystemList {
+			perms.FileSystem = append(perms.FileSystem, fs)
+		}
+	}
+	return perms
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+func TestAskPermissionSetKeepsOnlyReadGrants(t *testing.T) {
+	dir := t.TempDir()
+	perms := askPermissionSet(buildPermissionSet(dir, PermissionProfileWorkspaceWrite))
+	require.Len(t, perms.FileSystem, 2)
+	for _, fs := range perms.FileSystem {
+		require.Contains(t, []framework.FileSystemAction{framework.FileSystemRead, framework.FileSystemList}, fs.Action)
+	}
+	require.Empty(t, perms.Executables)
+	require.Empty(t, perms.Network)
+}
+
+// TestAskAgentOnlySeesReadOnlyTools checks the ask preset drops write tools
+// from the session registry and can still read the workspace.
+func TestAskAgentOnlySeesReadOnlyTools(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	manifest := &framework.AgentManifest{}
+	manifest.Spec.Permissions = buildPermissionSet(dir, PermissionProfileWorkspaceWrite)
+	manager, err := framework.NewPermissionManager(dir, &manifest.Spec.Permissions, nil, nil)
+	require.NoError(t, err)
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&tools.ReadFileTool{BasePath: dir}))
+	require.NoError(t, registry.Register(&tools.WriteFileTool{BasePath: dir}))
+	registry.UsePermissionManager("coding", manager)
+
+	rt := &Runtime{
+		Config:       Config{Workspace: dir},
+		Tools:        registry,
+		Context:      framework.NewContext(),
+		Registration: &framework.AgentRegistration{ID: "coding", Manifest: manifest, Permissions: manager},
+	}
+	agent, err := rt.askAgentInstance()
+	require.NoError(t, err)
+	_, ok := agent.Tools.Get("file_write")
+	require.False(t, ok)
+	read, ok := agent.Tools.Get("file_read")
+	require.True(t, ok)
+	res, err := read.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go"})
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", res.Data["content"])
+}
@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// AuditSinkConfig declares an audit export target in config.yaml:
+//
+//	audit_sinks:
+//	  - type: file
+//	    path: relurpify_cfg/logs/audit.jsonl
+//	    primary: true
+//	  - type: http
+//	    url: https://collector.example/audit
+type AuditSinkConfig struct {
+	Type          string            `yaml:"type"` // file, syslog, http
+	Name          string            `yaml:"name,omitempty"`
+	Path          string            `yaml:"path,omitempty"`
+	URL           string            `yaml:"url,omitempty"`
+	Headers       map[string]string `yaml:"headers,omitempty"`
+	Tag           string            `yaml:"tag,omitempty"`
+	Primary       bool              `yaml:"primary,omitempty"`
+	BatchSize     int               `yaml:"batch_size,omitempty"`
+	FlushInterval string            `yaml:"flush_interval,omitempty"`
+	QueueSize     int               `yaml:"queue_size,omitempty"`
+}
+
+// buildAuditSinks instantiates the configured sinks. Relative file paths
+// resolve against the workspace. The returned closers release file and syslog
+// handles after the audit logger has been flushed.
+func buildAuditSinks(workspace string, configs []AuditSinkConfig) ([]framework.AuditSinkRegistration, []io.Closer, error) {
+	var regs []framework.AuditSinkRegistration
+	var closers []io.Closer
+	fail := func(err error) ([]framework.AuditSinkRegistration, []io.Closer, error) {
+		for _, c := range closers {
+			c.Close()
+		}
+		return nil, nil, err
+	}
+	for i, sc := range configs {
+		name := sc.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", sc.Type, i)
+		}
+		opts := framework.AuditSinkOptions{
+			Primary:   sc.Primary,
+			BatchSize: sc.BatchSize,
+			QueueSize: sc.QueueSize,
+		}
+		if sc.FlushInterval != "" {
+			interval, err := time.ParseDuration(sc.FlushInterval)
+			if err != nil {
+				return fail(fmt.Errorf("audit sink %s: flush_interval: %w", name, err))
+			}
+			opts.FlushInterval = interval
+		}
+		var sink framework.AuditSink
+		switch strings.ToLower(sc.Type) {
+		case "file":
+			path := sc.Path
+			if path == "" {
+				return fail(fmt.Errorf("audit sink %s: path required", name))
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(workspace, path)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return fail(fmt.Errorf("audit sink %s: %w", name, err))
+			}
+			fileSink, err := framework.NewFileAuditSink(path)
+			if err != nil {
+				return fail(fmt.Errorf("audit sink %s: %w", name, err))
+			}
+			closers = append(closers, fileSink)
+			sink = fileSink
+		case "syslog":
+			syslogSink, err := framework.NewSyslogAuditSink(sc.Tag)
+			if err != nil {
+				return fail(fmt.Errorf("audit sink %s: %w", name, err))
+			}
+			closers = append(closers, syslogSink)
+			sink = syslogSink
+		case "http":
+			if sc.URL == "" {
+				return fail(fmt.Errorf("audit sink %s: url required", name))
+			}
+			sink = &framework.HTTPAuditSink{URL: sc.URL, Headers: sc.Headers}
+		default:
+			return fail(fmt.Errorf("audit sink %s: unknown type %q", name, sc.Type))
+		}
+		regs = append(regs, framework.AuditSinkRegistration{Name: name, Sink: sink, Options: opts})
+	}
+	return regs, closers, nil
+}
+
+// AuditFilePath returns the resolved path of the first file audit sink, or ""
+// when none is configured. The audit CLI reads records back from it.
+func (c WorkspaceConfig) AuditFilePath(workspace string) string {
+	for _, sc := range c.AuditSinks {
+		if strings.ToLower(sc.Type) != "file" || sc.Path == "" {
+			continue
+		}
+		if filepath.IsAbs(sc.Path) {
+			return sc.Path
+		}
+		return filepath.Join(workspace, sc.Path)
+	}
+	return ""
+}
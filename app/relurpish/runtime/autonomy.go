@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// buildAutonomy resolves the session's starting autonomy level: the CLI
+// flag wins over config.yaml. Without either, sessions start autonomous with
+// no quota, which matches the behaviour before autonomy levels existed.
+func buildAutonomy(cfg Config, ws WorkspaceConfig, usage *framework.UsageTracker) (*framework.AutonomyController, error) {
+	levelName := string(framework.AutonomyAutonomous)
+	var duration time.Duration
+	var quota framework.AutonomyQuota
+	if ws.Autonomy != nil {
+		if ws.Autonomy.Level != "" {
+			levelName = ws.Autonomy.Level
+		}
+		if ws.Autonomy.Duration != "" {
+			parsed, err := time.ParseDuration(ws.Autonomy.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("autonomy duration invalid: %w", err)
+			}
+			duration = parsed
+		}
+		quota = ws.Autonomy.Quota
+	}
+	if cfg.Autonomy != "" {
+		levelName = cfg.Autonomy
+		duration = cfg.AutonomyFor
+	}
+	level, err := framework.ParseAutonomyLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+	controller := framework.NewAutonomyController(level, quota, usage)
+	if duration > 0 {
+		if err := controller.Set(level, duration); err != nil {
+			return nil, err
+		}
+	}
+	return controller, nil
+}
@@ -0,0 +1,282 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// BatchFile is a list of tasks run non-interactively by `relurpish batch`:
+//
+//	parallel: 2
+//	tasks:
+//	  - id: rename-logger
+//	    type: code_modification
+//	    instruction: Rename Logger to EventLogger
+//	    files: [framework/telemetry.go]
+//	  - instruction: Summarize open TODOs
+//	    type: analysis
+//	    context:
+//	      source: nightly
+type BatchFile struct {
+	// Parallel bounds concurrently running tasks; the --parallel flag
+	// overrides it. Zero runs tasks sequentially.
+	Parallel int         `yaml:"parallel,omitempty"`
+	Tasks    []BatchTask `yaml:"tasks"`
+}
+
+// BatchTask is one entry of a BatchFile. Files are passed to the agent as
+// context_files, the same key the TUI uses for pinned files.
+type BatchTask struct {
+	ID          string                 `yaml:"id,omitempty"`
+	Type        string                 `yaml:"type,omitempty"`
+	Instruction string                 `yaml:"instruction"`
+	Context     map[string]interface{} `yaml:"context,omitempty"`
+	Files       []string               `yaml:"files,omitempty"`
+}
+
+// LoadBatchFile parses and validates a batch file.
+func LoadBatchFile(path string) (*BatchFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var batch BatchFile
+	if err := yaml.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := batch.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &batch, nil
+}
+
+// Validate checks every task has an instruction and that explicit IDs are
+// unique and usable as file names.
+func (b *BatchFile) Validate() error {
+	if len(b.Tasks) == 0 {
+		return errors.New("batch has no tasks")
+	}
+	seen := make(map[string]int)
+	for i, task := range b.Tasks {
+		if strings.TrimSpace(task.Instruction) == "" {
+			return fmt.Errorf("task %d: instruction required", i+1)
+		}
+		if task.ID == "" {
+			continue
+		}
+		if task.ID == "." || task.ID == ".." || strings.ContainsAny(task.ID, `/\`) {
+			return fmt.Errorf("task %d: id %q must not contain path separators", i+1, task.ID)
+		}
+		if prev, ok := seen[task.ID]; ok {
+			return fmt.Errorf("task %d: id %q already used by task %d", i+1, task.ID, prev)
+		}
+		seen[task.ID] = i + 1
+	}
+	return nil
+}
+
+// BatchOptions configures RunBatch.
+type BatchOptions struct {
+	// Parallel bounds concurrently running tasks; values below one run
+	// sequentially.
+	Parallel int
+	// OutputDir receives one <task id>.json per task plus summary.json.
+	OutputDir string
+	// Usage, when set, adds each task's token usage to its result file.
+	Usage *framework.UsageTracker
+	// OnResult is called as each task finishes, e.g. to print progress.
+	// Calls are serialized even when tasks run in parallel.
+	OnResult func(BatchTaskResult)
+}
+
+// BatchTaskResult is written to <OutputDir>/<id>.json.
+type BatchTaskResult struct {
+	ID          string              `json:"id"`
+	Type        framework.TaskType  `json:"type"`
+	Instruction string              `json:"instruction"`
+	Status      string              `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	Result      *framework.Result   `json:"result,omitempty"`
+	Usage       *framework.LLMUsage `json:"usage,omitempty"`
+	StartedAt   time.Time           `json:"started_at"`
+	Duration    string              `json:"duration"`
+}
+
+// BatchReport is written to <OutputDir>/summary.json.
+type BatchReport struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	StartedAt time.Time         `json:"started_at"`
+	Duration  string            `json:"duration"`
+	Tasks     []BatchTaskResult `json:"tasks"`
+}
+
+// Batch result statuses; timed out tasks use framework.ResultStatusTimedOut.
+const (
+	BatchStatusSucceeded = "succeeded"
+	BatchStatusFailed    = "failed"
+)
+
+// TaskRunFunc executes one task; Runtime.RunTask satisfies it.
+type TaskRunFunc func(ctx context.Context, task *framework.Task) (*framework.Result, error)
+
+// RunBatch runs the batch through run, writing a result file per task as it
+// finishes and a summary once all are done. Task failures are recorded, not
+// returned: the error is non-nil only when results cannot be written. Tasks
+// without an ID get "<batch>-NN" so their workflow snapshots stay distinct.
+func RunBatch(ctx context.Context, batch *BatchFile, opts BatchOptions, run TaskRunFunc) (*BatchReport, error) {
+	if err := batch.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.OutputDir == "" {
+		return nil, errors.New("batch output directory required")
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0o755); err != nil {
+		return nil, err
+	}
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	started := time.Now().UTC()
+	prefix := fmt.Sprintf("batch-%d", started.UnixNano())
+	tasks := make([]*framework.Task, len(batch.Tasks))
+	for i, bt := range batch.Tasks {
+		tasks[i] = batchTask(bt, fmt.Sprintf("%s-%02d", prefix, i+1))
+	}
+
+	report := &BatchReport{Total: len(tasks), StartedAt: started, Tasks: make([]BatchTaskResult, len(tasks))}
+	var mu sync.Mutex
+	var writeErr error
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, task *framework.Task) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := runBatchTask(ctx, task, opts.Usage, run)
+			err := writeBatchJSON(filepath.Join(opts.OutputDir, task.ID+".json"), result)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Tasks[i] = result
+			if err != nil && writeErr == nil {
+				writeErr = err
+			}
+			if opts.OnResult != nil {
+				opts.OnResult(result)
+			}
+		}(i, task)
+	}
+	wg.Wait()
+
+	for i, result := range report.Tasks {
+		if result.ID == "" {
+			// Never started because ctx was cancelled.
+			report.Tasks[i] = BatchTaskResult{
+				ID:          tasks[i].ID,
+				Type:        tasks[i].Type,
+				Instruction: tasks[i].Instruction,
+				Status:      BatchStatusFailed,
+				Error:       "not started: " + ctx.Err().Error(),
+			}
+		}
+		if report.Tasks[i].Status == BatchStatusSucceeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	report.Duration = time.Since(started).Round(time.Millisecond).String()
+	if err := writeBatchJSON(filepath.Join(opts.OutputDir, "summary.json"), report); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return report, writeErr
+}
+
+// RunBatch runs batch against the runtime's agent.
+func (r *Runtime) RunBatch(ctx context.Context, batch *BatchFile, opts BatchOptions) (*BatchReport, error) {
+	if opts.Usage == nil {
+		opts.Usage = r.Usage
+	}
+	return RunBatch(ctx, batch, opts, r.RunTask)
+}
+
+func batchTask(bt BatchTask, defaultID string) *framework.Task {
+	task := &framework.Task{
+		ID:          bt.ID,
+		Type:        framework.TaskType(bt.Type),
+		Instruction: bt.Instruction,
+		Context:     map[string]any{"source": "batch"},
+	}
+	if task.ID == "" {
+		task.ID = defaultID
+	}
+	if task.Type == "" {
+		task.Type = framework.TaskTypeCodeModification
+	}
+	for k, v := range bt.Context {
+		task.Context[k] = v
+	}
+	if len(bt.Files) > 0 {
+		task.Context["context_files"] = append([]string(nil), bt.Files...)
+	}
+	return task
+}
+
+func runBatchTask(ctx context.Context, task *framework.Task, usage *framework.UsageTracker, run TaskRunFunc) BatchTaskResult {
+	started := time.Now()
+	res, err := run(ctx, task)
+	result := BatchTaskResult{
+		ID:          task.ID,
+		Type:        task.Type,
+		Instruction: task.Instruction,
+		Status:      BatchStatusSucceeded,
+		Result:      res,
+		StartedAt:   started.UTC(),
+		Duration:    time.Since(started).Round(time.Millisecond).String(),
+	}
+	var timeoutErr *framework.TimeoutError
+	switch {
+	case errors.As(err, &timeoutErr):
+		result.Status = framework.ResultStatusTimedOut
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = BatchStatusFailed
+		result.Error = err.Error()
+	case res != nil && !res.Success:
+		result.Status = BatchStatusFailed
+		if res.Error != nil {
+			result.Error = res.Error.Error()
+		}
+	}
+	if usage != nil {
+		total := usage.Summary(framework.UsageFilter{TaskID: task.ID}).Total
+		result.Usage = &total
+	}
+	return result
+}
+
+func writeBatchJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestLoadBatchFileValidates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`parallel: 2
+tasks:
+  - id: rename
+    instruction: Rename Logger
+    files: [a.go]
+  - instruction: Summarize TODOs
+    type: analysis
+`), 0o644))
+	batch, err := LoadBatchFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, batch.Parallel)
+	require.Len(t, batch.Tasks, 2)
+	require.Equal(t, []string{"a.go"}, batch.Tasks[0].Files)
+
+	for name, body := range map[string]string{
+		"empty":     "tasks: []\n",
+		"no-instr":  "tasks:\n  - id: a\n",
+		"dup-id":    "tasks:\n  - {id: a, instruction: x}\n  - {id: a, instruction: y}\n",
+		"path-id":   "tasks:\n  - {id: ../a, instruction: x}\n",
+		"malformed": "tasks: [",
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+		_, err := LoadBatchFile(path)
+		require.Error(t, err, name)
+	}
+}
+
+func TestRunBatchWritesResultsAndCountsFailures(t *testing.T) {
+	out := t.TempDir()
+	batch := &BatchFile{Tasks: []BatchTask{
+		{ID: "ok", Instruction: "do it", Files: []string{"main.go"}},
+		{Instruction: "break it", Type: "analysis"},
+		{ID: "slow", Instruction: "time out"},
+	}}
+	var seen sync.Map
+	run := func(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+		seen.Store(task.ID, task)
+		switch task.Instruction {
+		case "break it":
+			return nil, errors.New("boom")
+		case "time out":
+			return nil, &framework.TimeoutError{Scope: framework.TimeoutScopeGraph, Timeout: time.Second}
+		}
+		return &framework.Result{NodeID: "done", Success: true}, nil
+	}
+	var reported []string
+	report, err := RunBatch(context.Background(), batch, BatchOptions{
+		OutputDir: out,
+		OnResult:  func(r BatchTaskResult) { reported = append(reported, r.ID) },
+	}, run)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Total)
+	require.Equal(t, 1, report.Succeeded)
+	require.Equal(t, 2, report.Failed)
+	require.Len(t, reported, 3)
+
+	okTask, _ := seen.Load("ok")
+	require.Equal(t, []string{"main.go"}, okTask.(*framework.Task).Context["context_files"])
+	require.Equal(t, framework.TaskTypeCodeModification, okTask.(*framework.Task).Type)
+
+	generated := report.Tasks[1].ID
+	require.True(t, strings.HasPrefix(generated, "batch-"), generated)
+	require.Equal(t, BatchStatusFailed, report.Tasks[1].Status)
+	require.Equal(t, "boom", report.Tasks[1].Error)
+	require.Equal(t, framework.ResultStatusTimedOut, report.Tasks[2].Status)
+
+	var written BatchTaskResult
+	data, err := os.ReadFile(filepath.Join(out, generated+".json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &written))
+	require.Equal(t, "break it", written.Instruction)
+	require.Equal(t, framework.TaskType("analysis"), written.Type)
+
+	var summary BatchReport
+	data, err = os.ReadFile(filepath.Join(out, "summary.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &summary))
+	require.Equal(t, 2, summary.Failed)
+}
+
+func TestRunBatchBoundsParallelism(t *testing.T) {
+	batch := &BatchFile{}
+	for i := 0; i < 6; i++ {
+		batch.Tasks = append(batch.Tasks, BatchTask{Instruction: "work"})
+	}
+	var running, peak int32
+	run := func(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return &framework.Result{Success: true}, nil
+	}
+	report, err := RunBatch(context.Background(), batch, BatchOptions{OutputDir: t.TempDir(), Parallel: 2}, run)
+	require.NoError(t, err)
+	require.Equal(t, 6, report.Succeeded)
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// maxChatTurns bounds how many turns each conversation can undo.
+const maxChatTurns = 50
+
+// ChatTurn checkpoints one shell task: the agent context and follow-up
+// before it ran, and the files it changed. TaskID names the task's file
+// checkpoint, so its writes can be rolled back too.
+type ChatTurn struct {
+	TaskID       string
+	Instruction  string
+	StartedAt    time.Time
+	FilesChanged []string
+
+	context  *framework.ContextSnapshot
+	followUp *framework.PreviousTask
+}
+
+// ChatHistory keeps the checkpointed turns of each shell conversation in
+// memory. Conversations are named by session ID; Use switches between them
+// and Fork starts a branch that shares the turns so far.
+type ChatHistory struct {
+	mu      sync.Mutex
+	current string
+	turns   map[string][]ChatTurn
+}
+
+// Use makes session the conversation new turns are recorded in.
+func (h *ChatHistory) Use(session string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current = session
+}
+
+// Fork starts session as a branch of the current conversation: it gets a
+// copy of the turns so far and becomes current. Undoing in one branch leaves
+// the other's turns alone.
+func (h *ChatHistory) Fork(session string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.turns == nil {
+		h.turns = make(map[string][]ChatTurn)
+	}
+	h.turns[session] = append([]ChatTurn(nil), h.turns[h.current]...)
+	h.current = session
+}
+
+// Turns lists the current conversation's undoable turns, oldest first.
+func (h *ChatHistory) Turns() []ChatTurn {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]ChatTurn(nil), h.turns[h.current]...)
+}
+
+// begin checkpoints the state before task runs.
+func (h *ChatHistory) begin(task *framework.Task, context *framework.ContextSnapshot, followUp *framework.PreviousTask) {
+	if h == nil || task == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.turns == nil {
+		h.turns = make(map[string][]ChatTurn)
+	}
+	turns := append(h.turns[h.current], ChatTurn{
+		TaskID:      task.ID,
+		Instruction: task.Instruction,
+		StartedAt:   time.Now().UTC(),
+		context:     context,
+		followUp:    followUp,
+	})
+	if len(turns) > maxChatTurns {
+		turns = turns[len(turns)-maxChatTurns:]
+	}
+	h.turns[h.current] = turns
+}
+
+// finish records the files task changed.
+func (h *ChatHistory) finish(task *framework.Task, changed []string) {
+	if h == nil || task == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	turns := h.turns[h.current]
+	for i := len(turns) - 1; i >= 0; i-- {
+		if turns[i].TaskID == task.ID {
+			turns[i].FilesChanged = changed
+			return
+		}
+	}
+}
+
+// pop removes and returns the current conversation's latest turn.
+func (h *ChatHistory) pop() (ChatTurn, bool) {
+	if h == nil {
+		return ChatTurn{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	turns := h.turns[h.current]
+	if len(turns) == 0 {
+		return ChatTurn{}, false
+	}
+	turn := turns[len(turns)-1]
+	h.turns[h.current] = turns[:len(turns)-1]
+	return turn, true
+}
+
+// UndoTurn reverts the latest shell turn: the agent context and follow-up
+// go back to what they were before it ran. With files, the task's file
+// checkpoint is rolled back as well and the restored paths are returned.
+// The context is restored even when the rollback fails.
+func (r *Runtime) UndoTurn(files bool) (ChatTurn, []string, error) {
+	turn, ok := r.Chat.pop()
+	if !ok {
+		return ChatTurn{}, nil, errors.New("nothing to undo")
+	}
+	if turn.context != nil {
+		if err := r.Context.Restore(turn.context); err != nil {
+			return turn, nil, err
+		}
+	}
+	r.FollowUps.restore(turn.followUp)
+	if !files {
+		return turn, nil, nil
+	}
+	if r.Checkpoints == nil {
+		return turn, nil, errors.New("file checkpoints unavailable")
+	}
+	restored, err := r.Checkpoints.Rollback(turn.TaskID)
+	if err != nil && len(restored) == 0 && len(turn.FilesChanged) == 0 {
+		// The turn wrote nothing, so there is no checkpoint to roll back.
+		return turn, nil, nil
+	}
+	return turn, restored, err
+}
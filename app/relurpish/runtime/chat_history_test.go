@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// writingAgent stores each instruction in the context and writes it to
+// notes.txt, checkpointing the file like the permission write hook does.
+type writingAgent struct {
+	path        string
+	checkpoints *persistence.FileCheckpointStore
+}
+
+func (a *writingAgent) Initialize(config *framework.Config) error { return nil }
+func (a *writingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	state.Set("last_instruction", task.Instruction)
+	if err := a.checkpoints.Snapshot(task.ID, a.path); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(a.path, []byte(task.Instruction), 0o644); err != nil {
+		return nil, err
+	}
+	return &framework.Result{NodeID: "write", Success: true, Data: map[string]interface{}{"final_output": task.Instruction}}, nil
+}
+func (a *writingAgent) Capabilities() []framework.Capability { return nil }
+func (a *writingAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	return nil, nil
+}
+
+func TestUndoTurnRestoresContextFollowUpAndFiles(t *testing.T) {
+	dir := t.TempDir()
+	checkpoints, err := persistence.NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints"))
+	require.NoError(t, err)
+	notes := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("original"), 0o644))
+	rt := &Runtime{
+		Config:      Config{Workspace: dir},
+		Context:     framework.NewContext(),
+		Agent:       &writingAgent{path: notes, checkpoints: checkpoints},
+		FollowUps:   &FollowUpSession{},
+		Chat:        &ChatHistory{},
+		Checkpoints: checkpoints,
+	}
+	rt.Chat.Use("session-1")
+	ctx := context.Background()
+	_, err = rt.ExecuteInstruction(ctx, "first", framework.TaskTypeCodeModification, nil)
+	require.NoError(t, err)
+	_, err = rt.ExecuteInstruction(ctx, "second", framework.TaskTypeCodeModification, nil)
+	require.NoError(t, err)
+	require.Len(t, rt.Chat.Turns(), 2)
+
+	turn, restored, err := rt.UndoTurn(true)
+	require.NoError(t, err)
+	require.Equal(t, "second", turn.Instruction)
+	require.Equal(t, []string{notes}, restored)
+	require.Equal(t, "first", rt.Context.GetString("last_instruction"))
+	previous, ok := rt.FollowUps.Last()
+	require.True(t, ok)
+	require.Equal(t, "first", previous.Instruction)
+	data, err := os.ReadFile(notes)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(data))
+
+	turn, restored, err = rt.UndoTurn(false)
+	require.NoError(t, err)
+	require.Equal(t, "first", turn.Instruction)
+	require.Empty(t, restored)
+	require.Equal(t, "", rt.Context.GetString("last_instruction"))
+	_, ok = rt.FollowUps.Last()
+	require.False(t, ok)
+	data, err = os.ReadFile(notes)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(data), "files stay unless asked")
+
+	_, _, err = rt.UndoTurn(false)
+	require.EqualError(t, err, "nothing to undo")
+}
+
+func TestChatHistoryForkKeepsBranchesApart(t *testing.T) {
+	history := &ChatHistory{}
+	history.Use("main")
+	history.begin(&framework.Task{ID: "t1", Instruction: "shared"}, nil, nil)
+	history.Fork("main-alt")
+	history.begin(&framework.Task{ID: "t2", Instruction: "alternative"}, nil, nil)
+	require.Len(t, history.Turns(), 2)
+
+	turn, ok := history.pop()
+	require.True(t, ok)
+	require.Equal(t, "t2", turn.TaskID)
+	turn, ok = history.pop()
+	require.True(t, ok)
+	require.Equal(t, "t1", turn.TaskID)
+
+	history.Use("main")
+	turns := history.Turns()
+	require.Len(t, turns, 1, "undo in a branch leaves the parent alone")
+	require.Equal(t, "shared", turns[0].Instruction)
+}
@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// openFileCheckpoints opens the checkpoint store and snapshots each file
+// before the agent writes it, keyed by the task doing the write. Writes
+// outside a task (no task ID on the context) are not checkpointed.
+func openFileCheckpoints(cfg Config, registration *framework.AgentRegistration, logger *log.Logger) *persistence.FileCheckpointStore {
+	store, err := persistence.NewFileCheckpointStore(cfg.CheckpointPath)
+	if err != nil {
+		logger.Printf("warning: file checkpoints unavailable: %v", err)
+		return nil
+	}
+	if registration != nil && registration.Permissions != nil {
+		registration.Permissions.SetFileWriteHook(func(ctx context.Context, path string) {
+			task, ok := framework.TaskContextFrom(ctx)
+			if !ok || task.ID == "" {
+				return
+			}
+			if err := store.Snapshot(task.ID, path); err != nil {
+				logger.Printf("checkpoint %s for %s failed: %v", path, task.ID, err)
+			}
+		})
+	}
+	return store
+}
+
+// Rollback restores the files job jobID modified to their content before
+// the job, deleting the files it created. An empty jobID rolls back the most
+// recent job that has not been rolled back yet.
+func (r *Runtime) Rollback(jobID string) (string, []string, error) {
+	if r.Checkpoints == nil {
+		return "", nil, errors.New("file checkpoints unavailable")
+	}
+	if jobID == "" {
+		checkpoints, err := r.Checkpoints.List()
+		if err != nil {
+			return "", nil, err
+		}
+		for _, checkpoint := range checkpoints {
+			if checkpoint.RolledBackAt == nil {
+				jobID = checkpoint.JobID
+				break
+			}
+		}
+		if jobID == "" {
+			return "", nil, errors.New("no job to roll back")
+		}
+	}
+	restored, err := r.Checkpoints.Rollback(jobID)
+	return jobID, restored, err
+}
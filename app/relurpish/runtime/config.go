@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+	"github.com/lexcodex/relurpify/tools/db"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,22 +16,90 @@ import (
 // entry points. Keeping it as a lightweight struct makes it trivial to reuse in
 // tests or future headless workflows.
 type Config struct {
-	Workspace      string
-	ManifestPath   string
-	AgentsDir      string
-	MemoryPath     string
-	LogPath        string
-	TelemetryPath  string
-	ConfigPath     string
-	OllamaEndpoint string
-	OllamaModel    string
-	AgentName      string
-	ServerAddr     string
-	Sandbox        framework.SandboxConfig
-	AuditLimit     int
-	HITLTimeout    time.Duration
+	Workspace    string
+	ManifestPath string
+	AgentsDir    string
+	MemoryPath   string
+	// UserMemoryPath and GlobalMemoryPath hold the user and global memory
+	// scopes, which every workspace shares; see framework.DefaultMemoryLayout.
+	UserMemoryPath   string
+	GlobalMemoryPath string
+	WorkflowPath     string
+	SessionPath      string
+	CheckpointPath   string
+	LogPath          string
+	TelemetryPath    string
+	ConfigPath       string
+	OllamaEndpoint   string
+	OllamaModel      string
+	// Profile selects a profiles entry of config.yaml; see ProfileConfig.
+	Profile    string
+	AgentName  string
+	ServerAddr string
+	// ServerWorkers caps concurrently running API queue tasks; zero defers
+	// to jobs.max_concurrent in config.yaml (see JobsConfig).
+	ServerWorkers int
+	// OTLPEndpoint exports traces to this OTLP/HTTP collector, overriding
+	// tracing.endpoint in config.yaml.
+	OTLPEndpoint string
+	Sandbox      framework.SandboxConfig
+	// RequireSandbox fails startup when gVisor is unavailable instead of
+	// degrading to host execution with approval-gated exec tools.
+	RequireSandbox bool
+	// HITLWebhooks are extra approval notification URLs added to the
+	// hitl_webhooks entries in config.yaml.
+	HITLWebhooks []string
+	// Watch starts the workspace watcher (see Runtime.StartWatch) even when
+	// watch.enabled is unset in config.yaml.
+	Watch bool
+	// Project is a file or directory in the workspace. The project
+	// containing it scopes the test, build, and lint tools; see
+	// ProjectConfig.
+	Project     string
+	AuditLimit  int
+	HITLTimeout time.Duration
+	// Autonomy selects the session autonomy level (suggest, approve,
+	// autonomous), overriding config.yaml; AutonomyFor time-boxes it.
+	Autonomy    string
+	AutonomyFor time.Duration
+	// CacheDir holds per-workspace startup caches (see StartupCache).
+	// NoCache bypasses them and the LLM response cache; RefreshCache
+	// recomputes and rewrites them.
+	CacheDir     string
+	NoCache      bool
+	RefreshCache bool
+	// Permissions replaces the manifest's permission set with a preset for
+	// this run; see PermissionPreset.
+	Permissions PermissionProfile
+	// Record writes a replay log of each task's model and tool calls under
+	// ReplayDir. ReplayPath instead answers those calls from one such log;
+	// see Runtime.Replay.
+	Record     bool
+	ReplayPath string
+	// LearnPermissions records every permission each task uses under
+	// LearnedPermissionsDir and allows the ones the manifest does not
+	// declare instead of denying them.
+	LearnPermissions bool
+	// PprofAddr exposes net/http/pprof on this address when set.
+	// ArtifactsPath collects generated reports such as captured profiles.
+	PprofAddr     string
+	ArtifactsPath string
+	// Memory caps for long-running shells: FeedLimit bounds chat messages,
+	// HistoryLimit the shared context history, and UsageLimit retained token
+	// records. Entries over a cap are appended to JSONL files in SpillPath.
+	FeedLimit    int
+	HistoryLimit int
+	UsageLimit   int
+	SpillPath    string
 }
 
+// Default memory caps; see Config.FeedLimit.
+const (
+	defaultFeedLimit    = 500
+	defaultHistoryLimit = 200
+	defaultUsageLimit   = 10000
+)
+
 // DefaultConfig infers sensible defaults based on the current working
 // directory. Errors from os.Getwd are ignored so callers can override manually.
 func DefaultConfig() Config {
@@ -41,9 +111,17 @@ func DefaultConfig() Config {
 	logsDir := filepath.Join(cfgDir, "logs")
 	return Config{
 		Workspace:     cwd,
+		Profile:       os.Getenv(ProfileEnv),
 		ManifestPath:  filepath.Join(cfgDir, "agent.manifest.yaml"),
 		AgentsDir:     filepath.Join(cfgDir, "agents"),
 		MemoryPath:    filepath.Join(cfgDir, "memory"),
+		WorkflowPath:  filepath.Join(cfgDir, "workflows"),
+		SessionPath:   filepath.Join(cfgDir, "memory", "sessions"),
+		ArtifactsPath: filepath.Join(cfgDir, "artifacts"),
+		SpillPath:     filepath.Join(cfgDir, "memory", "spill"),
+		FeedLimit:     defaultFeedLimit,
+		HistoryLimit:  defaultHistoryLimit,
+		UsageLimit:    defaultUsageLimit,
 		LogPath:       filepath.Join(logsDir, "relurpish.log"),
 		TelemetryPath: filepath.Join(cfgDir, "telemetry.jsonl"),
 		ConfigPath:    filepath.Join(cfgDir, "config.yaml"),
@@ -90,6 +168,31 @@ func (c *Config) Normalize() error {
 	if !filepath.IsAbs(c.MemoryPath) {
 		c.MemoryPath = filepath.Join(c.Workspace, c.MemoryPath)
 	}
+	shared := framework.DefaultMemoryLayout(c.MemoryPath)
+	if c.UserMemoryPath == "" {
+		c.UserMemoryPath = shared.User
+	}
+	if c.GlobalMemoryPath == "" {
+		c.GlobalMemoryPath = shared.Global
+	}
+	if c.WorkflowPath == "" {
+		c.WorkflowPath = filepath.Join(configDir, "workflows")
+	}
+	if !filepath.IsAbs(c.WorkflowPath) {
+		c.WorkflowPath = filepath.Join(c.Workspace, c.WorkflowPath)
+	}
+	if c.SessionPath == "" {
+		c.SessionPath = filepath.Join(c.MemoryPath, "sessions")
+	}
+	if !filepath.IsAbs(c.SessionPath) {
+		c.SessionPath = filepath.Join(c.Workspace, c.SessionPath)
+	}
+	if c.CheckpointPath == "" {
+		c.CheckpointPath = filepath.Join(configDir, "checkpoints")
+	}
+	if !filepath.IsAbs(c.CheckpointPath) {
+		c.CheckpointPath = filepath.Join(c.Workspace, c.CheckpointPath)
+	}
 	if c.LogPath == "" {
 		c.LogPath = filepath.Join(configDir, "logs", "relurpish.log")
 	}
@@ -108,11 +211,14 @@ func (c *Config) Normalize() error {
 	if !filepath.IsAbs(c.ConfigPath) {
 		c.ConfigPath = filepath.Join(c.Workspace, c.ConfigPath)
 	}
+	if c.Project != "" && !filepath.IsAbs(c.Project) {
+		c.Project = filepath.Join(c.Workspace, c.Project)
+	}
 	if c.AgentName == "" {
 		c.AgentName = "coding"
 	}
 	if c.OllamaEndpoint == "" {
-		c.OllamaEndpoint = "http://localhost:11434"
+		c.OllamaEndpoint = DefaultOllamaEndpoint
 	}
 	if c.ServerAddr == "" {
 		c.ServerAddr = ":8080"
@@ -123,9 +229,47 @@ func (c *Config) Normalize() error {
 	if c.HITLTimeout <= 0 {
 		c.HITLTimeout = 30 * time.Second
 	}
+	if c.CacheDir == "" {
+		c.CacheDir = defaultCacheDir(configDir)
+	}
+	if c.SpillPath == "" {
+		c.SpillPath = filepath.Join(c.MemoryPath, "spill")
+	}
+	if !filepath.IsAbs(c.SpillPath) {
+		c.SpillPath = filepath.Join(c.Workspace, c.SpillPath)
+	}
+	if c.FeedLimit <= 0 {
+		c.FeedLimit = defaultFeedLimit
+	}
+	if c.HistoryLimit <= 0 {
+		c.HistoryLimit = defaultHistoryLimit
+	}
+	if c.UsageLimit <= 0 {
+		c.UsageLimit = defaultUsageLimit
+	}
+	if c.ArtifactsPath == "" {
+		c.ArtifactsPath = filepath.Join(configDir, "artifacts")
+	}
+	if !filepath.IsAbs(c.ArtifactsPath) {
+		c.ArtifactsPath = filepath.Join(c.Workspace, c.ArtifactsPath)
+	}
 	return nil
 }
 
+// defaultCacheDir prefers the user cache directory so caches survive
+// `git clean` and stay out of the workspace, falling back to relurpify_cfg.
+func defaultCacheDir(configDir string) string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "relurpify", "startup")
+	}
+	return filepath.Join(configDir, "cache")
+}
+
+// MemoryLayout places the memory scopes for this config.
+func (c Config) MemoryLayout() framework.MemoryLayout {
+	return framework.MemoryLayout{Workspace: c.MemoryPath, User: c.UserMemoryPath, Global: c.GlobalMemoryPath}
+}
+
 // AgentLabel returns the normalized agent identifier used across telemetry and
 // UI views.
 func (c Config) AgentLabel() string {
@@ -140,12 +284,207 @@ func (c Config) AgentLabel() string {
 }
 
 // WorkspaceConfig captures persisted wizard selections for reuse across runs.
+// Models overrides the manifest's per-role model routing (for example
+// summarization: qwen2.5:3b) and ModelPrices prices tokens per model for usage
+// reports; unpriced models report zero cost. Policy adds an OPA check on top
+// of manifest permissions. Autonomy sets the default session autonomy level.
+// Profiles overlay Endpoint, Model, Languages, and AllowedTools per machine or
+// model; Profile names the one used by default.
 type WorkspaceConfig struct {
-	Model             string            `yaml:"model"`
-	Agents            []string          `yaml:"agents"`
-	AllowedTools      []string          `yaml:"allowed_tools"`
-	PermissionProfile PermissionProfile `yaml:"permission_profile"`
-	LastUpdated       int64             `yaml:"last_updated"`
+	Endpoint          string                          `yaml:"endpoint,omitempty"`
+	Model             string                          `yaml:"model"`
+	Models            map[framework.ModelRole]string  `yaml:"models,omitempty"`
+	Agents            []string                        `yaml:"agents"`
+	AllowedTools      []string                        `yaml:"allowed_tools"`
+	Languages         []string                        `yaml:"languages,omitempty"`
+	PermissionProfile PermissionProfile               `yaml:"permission_profile"`
+	AuditSinks        []AuditSinkConfig               `yaml:"audit_sinks,omitempty"`
+	Policy            *PolicyConfig                   `yaml:"policy,omitempty"`
+	Autonomy          *AutonomyConfig                 `yaml:"autonomy,omitempty"`
+	ModelPrices       map[string]framework.ModelPrice `yaml:"model_prices,omitempty"`
+	// RequireSandbox is the persistent form of --require-sandbox.
+	RequireSandbox bool                `yaml:"require_sandbox,omitempty"`
+	HITLWebhooks   []HITLWebhookConfig `yaml:"hitl_webhooks,omitempty"`
+	Watch          *WatchConfig        `yaml:"watch,omitempty"`
+	Tracing        *TracingConfig      `yaml:"tracing,omitempty"`
+	// ContextWindow overrides the context budget derived from the model's
+	// reported context length.
+	ContextWindow       *framework.ContextWindowConfig `yaml:"context_window,omitempty"`
+	MemoryConsolidation *MemoryConsolidationConfig     `yaml:"memory_consolidation,omitempty"`
+	Redaction           *RedactionConfig               `yaml:"redaction,omitempty"`
+	// APIKeys protect the HTTP API; without any, it is open to every client.
+	APIKeys []APIKeyConfig `yaml:"api_keys,omitempty"`
+	// StructuredOutput tunes how JSON responses are enforced; see
+	// framework.StructuredOutputPolicy.
+	StructuredOutput *framework.StructuredOutputPolicy `yaml:"structured_output,omitempty"`
+	// Projects adds or overrides the projects detected in a monorepo.
+	Projects       []ProjectConfig          `yaml:"projects,omitempty"`
+	Embeddings     *EmbeddingsConfig        `yaml:"embeddings,omitempty"`
+	LLMCache       *LLMCacheConfig          `yaml:"llm_cache,omitempty"`
+	LLMThrottle    *LLMThrottleConfig       `yaml:"llm_throttle,omitempty"`
+	OllamaPool     *OllamaPoolConfig        `yaml:"ollama_pool,omitempty"`
+	ModelLifecycle *ModelLifecycleConfig    `yaml:"model_lifecycle,omitempty"`
+	Review         *ReviewConfig            `yaml:"review,omitempty"`
+	FileEdit       *FileEditConfig          `yaml:"file_edit,omitempty"`
+	CodeExtraction *CodeExtractionConfig    `yaml:"code_extraction,omitempty"`
+	Formatting     *FormattingConfig        `yaml:"formatting,omitempty"`
+	Databases      []db.Config              `yaml:"databases,omitempty"`
+	ToolOutput     *ToolOutputConfig        `yaml:"tool_output,omitempty"`
+	Jobs           *JobsConfig              `yaml:"jobs,omitempty"`
+	Summarizer     *SummarizerConfig        `yaml:"summarizer,omitempty"`
+	WorkflowStore  *WorkflowStoreConfig     `yaml:"workflow_store,omitempty"`
+	Remote         *RemoteConfig            `yaml:"remote,omitempty"`
+	Profile        string                   `yaml:"profile,omitempty"`
+	Profiles       map[string]ProfileConfig `yaml:"profiles,omitempty"`
+	LastUpdated    int64                    `yaml:"last_updated"`
+}
+
+// defaultServerWorkers is how many API queue tasks run at once when neither
+// --workers nor jobs.max_concurrent says otherwise.
+const defaultServerWorkers = 2
+
+// JobsConfig tunes the API task queue:
+//
+//	jobs:
+//	  max_concurrent: 4
+//
+// --workers overrides MaxConcurrent.
+type JobsConfig struct {
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+}
+
+// Workers resolves how many queued tasks may run at once: flag when set,
+// then MaxConcurrent, then defaultServerWorkers.
+func (c *JobsConfig) Workers(flag int) int {
+	switch {
+	case flag > 0:
+		return flag
+	case c != nil && c.MaxConcurrent > 0:
+		return c.MaxConcurrent
+	default:
+		return defaultServerWorkers
+	}
+}
+
+// FileEditConfig tunes the file_edit tool:
+//
+//	file_edit:
+//	  max_shrink: 0.3
+//
+// MaxShrink is the largest share of a file one edit may remove; zero means
+// tools.DefaultMaxEditShrink and 1 disables the check.
+type FileEditConfig struct {
+	MaxShrink float64 `yaml:"max_shrink,omitempty"`
+}
+
+// CodeExtractionConfig picks the stages that clean the content models pass
+// to file_write and file_create:
+//
+//	code_extraction:
+//	  stages: [fences, language, trailing_prose, eol]
+//
+// Without the section every stage runs; an empty list writes content as
+// the model sent it. See framework.CodePipeline for what each stage does.
+type CodeExtractionConfig struct {
+	Stages []string `yaml:"stages"`
+}
+
+// Pipeline builds the configured pipeline; a nil config uses
+// framework.DefaultCodeStages.
+func (c *CodeExtractionConfig) Pipeline() (*framework.CodePipeline, error) {
+	if c == nil {
+		return framework.DefaultCodePipeline(), nil
+	}
+	stages, err := framework.ParseCodeStages(c.Stages)
+	if err != nil {
+		return nil, err
+	}
+	return framework.NewCodePipeline(stages...), nil
+}
+
+// FormattingConfig formats the files agents edit with each language's
+// formatter:
+//
+//	formatting:
+//	  enabled: true
+//	  languages:
+//	    go: {command: [goimports]}
+//	    python: {lsp: true}
+//	    yaml: {disabled: true}
+//
+// Languages override tools.DefaultFormatters by framework.CodeLanguage
+// name. A command reads the file on stdin and writes it formatted to
+// stdout, with {file} standing for the file's workspace path; lsp asks the
+// language server instead. Formatter binaries need the manifest's
+// permission like any other executable.
+type FormattingConfig struct {
+	Enabled   bool                       `yaml:"enabled"`
+	Languages map[string]FormatterConfig `yaml:"languages,omitempty"`
+}
+
+// FormatterConfig is one language's entry in FormattingConfig.
+type FormatterConfig struct {
+	Command  []string `yaml:"command,omitempty"`
+	LSP      bool     `yaml:"lsp,omitempty"`
+	Disabled bool     `yaml:"disabled,omitempty"`
+}
+
+// Formatters returns the formatter for each language, or nil when
+// formatting is off.
+func (c *FormattingConfig) Formatters() (map[string]tools.Formatter, error) {
+	if c == nil || !c.Enabled {
+		return nil, nil
+	}
+	formatters := tools.DefaultFormatters()
+	for language, override := range c.Languages {
+		switch {
+		case override.Disabled:
+			delete(formatters, language)
+		case len(override.Command) == 0 && !override.LSP:
+			return nil, fmt.Errorf("formatting.languages.%s: command or lsp required", language)
+		default:
+			formatters[language] = tools.Formatter{Command: override.Command, LSP: override.LSP}
+		}
+	}
+	return formatters, nil
+}
+
+// ReviewConfig turns on cross-review. A second model critiques the code
+// the primary model writes, and rejected work runs again with the critique:
+//
+//	review:
+//	  model: deepseek-coder-v2:16b
+//	  endpoint: http://gpu-box:11434
+//	  rounds: 2
+//
+// Endpoint defaults to the primary endpoint. Rounds caps the runs per task;
+// zero means framework.DefaultReviewRounds.
+type ReviewConfig struct {
+	Model    string `yaml:"model"`
+	Endpoint string `yaml:"endpoint,omitempty"`
+	Rounds   int    `yaml:"rounds,omitempty"`
+}
+
+// EndpointOr returns the critic's endpoint, or fallback when unset.
+func (c *ReviewConfig) EndpointOr(fallback string) string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return fallback
+}
+
+// AutonomyConfig is the default autonomy level for new sessions:
+//
+//	autonomy:
+//	  level: autonomous
+//	  duration: 30m
+//	  quota:
+//	    max_tool_calls: 100
+//	    max_tokens: 200000
+type AutonomyConfig struct {
+	Level    string                  `yaml:"level"`
+	Duration string                  `yaml:"duration,omitempty"`
+	Quota    framework.AutonomyQuota `yaml:"quota,omitempty"`
 }
 
 // LoadWorkspaceConfig loads the wizard configuration from disk. Missing files
@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ConflictPrompter settles conflicts with the person at In/Out: they accept
+// the current state, revise the plan in their editor (or describe the
+// change when the task has no plan), or add a clarification, and the task
+// resumes with the answer. Plans are edited in Dir like PlanFileReviewer's.
+type ConflictPrompter struct {
+	Dir string
+	// Editor opens the plan file; it defaults to $VISUAL, then $EDITOR.
+	Editor string
+	In     io.Reader
+	Out    io.Writer
+
+	in *bufio.Reader
+}
+
+// ResolveConflict implements framework.ConflictResolver.
+func (p *ConflictPrompter) ResolveConflict(ctx context.Context, task *framework.Task, conflict framework.Conflict) (framework.ConflictResolution, error) {
+	out := p.Out
+	if out == nil {
+		out = io.Discard
+	}
+	if p.in == nil {
+		p.in = bufio.NewReader(p.In)
+	}
+	fmt.Fprintf(out, "The task needs your decision: %s\n", conflict.Reason)
+	for _, issue := range conflict.Issues {
+		fmt.Fprintf(out, "  - %s\n", issue)
+	}
+	if conflict.Plan != nil {
+		writePlanSummary(out, *conflict.Plan)
+	}
+	for {
+		answer, err := p.ask(ctx, out, "[a]ccept current state, [r]evise plan, [c]larify: ")
+		if err != nil {
+			return framework.ConflictResolution{}, err
+		}
+		switch strings.ToLower(answer) {
+		case "a", "accept":
+			return framework.ConflictResolution{Action: framework.ConflictAccept}, nil
+		case "c", "clarify":
+			text, err := p.ask(ctx, out, "Clarification: ")
+			if err != nil {
+				return framework.ConflictResolution{}, err
+			}
+			if text != "" {
+				return framework.ConflictResolution{Action: framework.ConflictClarify, Clarification: text}, nil
+			}
+		case "r", "revise":
+			if conflict.Plan != nil {
+				if plan, ok := p.editPlan(ctx, out, task, *conflict.Plan); ok {
+					return framework.ConflictResolution{Action: framework.ConflictRevisePlan, Plan: &plan}, nil
+				}
+			}
+			text, err := p.ask(ctx, out, "Describe the plan change: ")
+			if err != nil {
+				return framework.ConflictResolution{}, err
+			}
+			if text != "" {
+				return framework.ConflictResolution{Action: framework.ConflictRevisePlan, Clarification: text}, nil
+			}
+		}
+	}
+}
+
+// editPlan opens plan in the editor and reads it back. It reports false
+// when the plan could not be edited, after saying why.
+func (p *ConflictPrompter) editPlan(ctx context.Context, out io.Writer, task *framework.Task, plan framework.Plan) (framework.Plan, bool) {
+	path := (&PlanFileReviewer{Dir: p.Dir}).PlanPath(task)
+	if err := framework.WritePlanFile(path, plan); err != nil {
+		fmt.Fprintf(out, "Cannot write %s: %v\n", path, err)
+		return plan, false
+	}
+	if err := openEditor(ctx, p.Editor, path); err != nil {
+		fmt.Fprintf(out, "Editor failed: %v\n", err)
+		return plan, false
+	}
+	edited, err := framework.ReadPlanFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "Edited plan is invalid: %v\n", err)
+		return plan, false
+	}
+	writePlanSummary(out, edited)
+	return edited, true
+}
+
+func (p *ConflictPrompter) ask(ctx context.Context, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no answer: %w", err)
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return strings.TrimSpace(line), nil
+}
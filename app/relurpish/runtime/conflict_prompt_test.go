@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestConflictPrompterResolutions(t *testing.T) {
+	conflict := framework.Conflict{
+		Reason: "the instruction asks for v2 but the plan targets v1",
+		Issues: []string{"[high] handler still on v1"},
+		Plan:   &framework.Plan{Goal: "port the handler", Steps: []framework.PlanStep{{ID: 1, Description: "update v1 routes"}}},
+	}
+	task := &framework.Task{ID: "task-1"}
+	var out bytes.Buffer
+	prompter := &ConflictPrompter{Dir: t.TempDir(), Editor: "false", In: strings.NewReader("x\nc\n\nc\nkeep v1 for now\nr\nsplit the routes first\na\n"), Out: &out}
+
+	resolution, err := prompter.ResolveConflict(context.Background(), task, conflict)
+	require.NoError(t, err)
+	assert.Equal(t, framework.ConflictResolution{Action: framework.ConflictClarify, Clarification: "keep v1 for now"}, resolution)
+	assert.Contains(t, out.String(), "The task needs your decision: the instruction asks for v2 but the plan targets v1")
+	assert.Contains(t, out.String(), "[high] handler still on v1")
+	assert.Contains(t, out.String(), "1. update v1 routes")
+
+	resolution, err = prompter.ResolveConflict(context.Background(), task, conflict)
+	require.NoError(t, err)
+	assert.Equal(t, framework.ConflictResolution{Action: framework.ConflictRevisePlan, Clarification: "split the routes first"}, resolution, "a failed editor falls back to describing the change")
+	assert.Contains(t, out.String(), "Editor failed")
+
+	resolution, err = prompter.ResolveConflict(context.Background(), task, conflict)
+	require.NoError(t, err)
+	assert.Equal(t, framework.ConflictAccept, resolution.Action)
+
+	_, err = prompter.ResolveConflict(context.Background(), task, conflict)
+	assert.Error(t, err, "no answer left")
+}
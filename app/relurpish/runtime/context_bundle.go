@@ -0,0 +1,390 @@
+package runtime
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// contextBundleVersion is bumped when the archive layout changes.
+const contextBundleVersion = 1
+
+// maxBundleEntry bounds each file read from a context bundle.
+const maxBundleEntry = 64 << 20
+
+// contextBundleScopes are the memory scopes exported with a session. User
+// and global memory stay behind; they are personal, not task state.
+var contextBundleScopes = []framework.MemoryScope{framework.MemoryScopeSession, framework.MemoryScopeProject}
+
+// ContextBundleManifest describes a context bundle. It is stored in the
+// archive as manifest.json next to:
+//
+//	session.json          the chat session, with its context snapshot
+//	memory/<scope>.json   session and project memory records
+//	plans/<file>.json     plan files written for the session's tasks
+//	workflows.json        workflow snapshots of the session's tasks
+type ContextBundleManifest struct {
+	Version   int       `json:"version"`
+	SessionID string    `json:"session_id"`
+	Workspace string    `json:"workspace"`
+	CreatedAt time.Time `json:"created_at"`
+	// Redacted bundles had secrets masked for sharing, so their context
+	// no longer holds the real values.
+	Redacted  bool     `json:"redacted"`
+	Memory    int      `json:"memory_records"`
+	Plans     []string `json:"plans,omitempty"`
+	Workflows int      `json:"workflows"`
+}
+
+// ContextExportOptions tunes ExportContext.
+type ContextExportOptions struct {
+	// Redact masks secrets with the workspace redaction rules, for bundles
+	// attached to bug reports.
+	Redact bool
+}
+
+// ExportContext writes the session with ID sessionID ("last" for the most
+// recent) and the state around it to w as a gzipped tar archive.
+func ExportContext(ctx context.Context, cfg Config, sessionID string, w io.Writer, opts ContextExportOptions) (*ContextBundleManifest, error) {
+	if err := cfg.Normalize(); err != nil {
+		return nil, err
+	}
+	sessions, err := persistence.NewFileSessionStore(cfg.SessionPath)
+	if err != nil {
+		return nil, err
+	}
+	record, err := findSession(ctx, sessions, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &ContextBundleManifest{
+		Version:   contextBundleVersion,
+		SessionID: record.ID,
+		Workspace: record.Workspace,
+		CreatedAt: time.Now().UTC(),
+		Redacted:  opts.Redact,
+	}
+	files := map[string]interface{}{"session.json": record}
+
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range contextBundleScopes {
+		records, err := memory.Search(ctx, "", scope)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+		files["memory/"+string(scope)+".json"] = records
+		manifest.Memory += len(records)
+	}
+
+	reviewer := &PlanFileReviewer{Dir: PlanDir(cfg.Workspace)}
+	workflows, _ := OpenWorkflowStore(cfg)
+	defer CloseWorkflowStore(workflows)
+	var snapshots []persistence.WorkflowSnapshot
+	for _, task := range record.Tasks {
+		planPath := reviewer.PlanPath(&framework.Task{ID: task.ID})
+		if data, err := os.ReadFile(planPath); err == nil {
+			name := "plans/" + filepath.Base(planPath)
+			files[name] = json.RawMessage(data)
+			manifest.Plans = append(manifest.Plans, filepath.Base(planPath))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if workflows == nil {
+			continue
+		}
+		snapshot, ok, err := workflows.Load(ctx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			snapshots = append(snapshots, *snapshot)
+		}
+	}
+	if len(snapshots) > 0 {
+		files["workflows.json"] = snapshots
+		manifest.Workflows = len(snapshots)
+	}
+	files["manifest.json"] = manifest
+
+	var redactor *framework.Redactor
+	if opts.Redact {
+		ws, err := LoadWorkspaceProfile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		redaction := ws.Redaction
+		if redaction != nil && redaction.Disabled {
+			// --redact asks for masking even where the workspace turned it
+			// off; fall back to the built-in rules.
+			redaction = nil
+		}
+		if redactor, err = buildRedactor(cfg.Workspace, redaction); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeContextBundle(w, files, redactor); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func findSession(ctx context.Context, store persistence.SessionStore, id string) (*persistence.SessionRecord, error) {
+	if id == "" || id == "last" {
+		sessions, err := store.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(sessions) == 0 {
+			return nil, errors.New("no saved sessions; sessions are saved by `relurpish chat`")
+		}
+		return &sessions[0], nil
+	}
+	record, ok, err := store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", id)
+	}
+	return record, nil
+}
+
+// writeContextBundle encodes each file as JSON, masks secrets when redactor
+// is set, and writes them in name order.
+func writeContextBundle(w io.Writer, files map[string]interface{}, redactor *framework.Redactor) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+		if redactor != nil && name != "manifest.json" {
+			data = []byte(redactor.Redact(string(data)))
+			if !json.Valid(data) {
+				return fmt.Errorf("redacting %s produced invalid JSON; check the custom redaction patterns", name)
+			}
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ContextImportOptions tunes ImportContext.
+type ContextImportOptions struct {
+	// Force replaces an existing session, plan files, and memory records
+	// with the bundle's copies.
+	Force bool
+}
+
+// ContextImportReport summarizes what ImportContext restored.
+type ContextImportReport struct {
+	Manifest ContextBundleManifest
+	// MemorySkipped counts bundle records left alone because the workspace
+	// already had the key.
+	MemoryImported int
+	MemorySkipped  int
+	Plans          []string
+	Workflows      int
+}
+
+// ImportContext restores a bundle written by ExportContext into the
+// workspace. The session is rebound to this workspace so `relurpish chat
+// --resume` picks it up.
+func ImportContext(ctx context.Context, cfg Config, r io.Reader, opts ContextImportOptions) (*ContextImportReport, error) {
+	if err := cfg.Normalize(); err != nil {
+		return nil, err
+	}
+	files, err := readContextBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	report := &ContextImportReport{}
+	if err := decodeBundleFile(files, "manifest.json", &report.Manifest); err != nil {
+		return nil, err
+	}
+	if report.Manifest.Version != contextBundleVersion {
+		return nil, fmt.Errorf("context bundle version %d is not supported (want %d)", report.Manifest.Version, contextBundleVersion)
+	}
+	var record persistence.SessionRecord
+	if err := decodeBundleFile(files, "session.json", &record); err != nil {
+		return nil, err
+	}
+
+	sessions, err := persistence.NewFileSessionStore(cfg.SessionPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists, err := sessions.Load(ctx, record.ID); err != nil {
+		return nil, err
+	} else if exists && !opts.Force {
+		return nil, fmt.Errorf("session %s already exists; pass --force to replace it", record.ID)
+	}
+
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range contextBundleScopes {
+		name := "memory/" + string(scope) + ".json"
+		if _, ok := files[name]; !ok {
+			continue
+		}
+		var records []framework.MemoryRecord
+		if err := decodeBundleFile(files, name, &records); err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			if !opts.Force {
+				if _, exists, err := memory.Recall(ctx, rec.Key, scope); err != nil {
+					return nil, err
+				} else if exists {
+					report.MemorySkipped++
+					continue
+				}
+			}
+			if err := memory.Remember(ctx, rec.Key, rec.Value, scope); err != nil {
+				return nil, err
+			}
+			report.MemoryImported++
+		}
+	}
+
+	planDir := PlanDir(cfg.Workspace)
+	for _, name := range report.Manifest.Plans {
+		if name != filepath.Base(name) || planFileUnsafe.MatchString(name) {
+			return nil, fmt.Errorf("context bundle names an invalid plan file %q", name)
+		}
+		data, ok := files["plans/"+name]
+		if !ok {
+			return nil, fmt.Errorf("context bundle is missing plans/%s", name)
+		}
+		target := filepath.Join(planDir, name)
+		if _, err := os.Stat(target); err == nil && !opts.Force {
+			continue
+		}
+		if err := os.MkdirAll(planDir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return nil, err
+		}
+		report.Plans = append(report.Plans, name)
+	}
+
+	if _, ok := files["workflows.json"]; ok {
+		var snapshots []persistence.WorkflowSnapshot
+		if err := decodeBundleFile(files, "workflows.json", &snapshots); err != nil {
+			return nil, err
+		}
+		workflows, err := OpenWorkflowStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer CloseWorkflowStore(workflows)
+		for i := range snapshots {
+			if _, exists, err := workflows.Load(ctx, snapshots[i].ID); err != nil {
+				return nil, err
+			} else if exists {
+				continue
+			}
+			if err := workflows.Save(ctx, &snapshots[i]); err != nil {
+				return nil, err
+			}
+			report.Workflows++
+		}
+	}
+
+	if record.Metadata == nil {
+		record.Metadata = map[string]string{}
+	}
+	if record.Workspace != "" && record.Workspace != cfg.Workspace {
+		record.Metadata["imported_from"] = record.Workspace
+	}
+	record.Workspace = cfg.Workspace
+	if err := sessions.Save(ctx, &record); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// readContextBundle reads every regular file in the archive into memory.
+func readContextBundle(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read context bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read context bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return nil, fmt.Errorf("context bundle entry %q escapes the archive", header.Name)
+		}
+		if header.Size > maxBundleEntry {
+			return nil, fmt.Errorf("context bundle entry %s is larger than %d bytes", name, maxBundleEntry)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleEntry))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+func decodeBundleFile(files map[string][]byte, name string, v interface{}) error {
+	data, ok := files[name]
+	if !ok {
+		return fmt.Errorf("context bundle has no %s", name)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
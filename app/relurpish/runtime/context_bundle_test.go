@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+const bundleSecret = "sk-abcdefghijklmnopqrstuvwxyz012345"
+
+// seedBundleWorkspace saves a session with one task, a plan and workflow
+// snapshot for it, and a project memory record.
+func seedBundleWorkspace(t *testing.T, cfg Config) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, cfg.Normalize())
+	sessions, err := persistence.NewFileSessionStore(cfg.SessionPath)
+	require.NoError(t, err)
+	require.NoError(t, sessions.Save(ctx, &persistence.SessionRecord{
+		ID:        "s1",
+		Workspace: cfg.Workspace,
+		Messages:  json.RawMessage(`[{"role":"user","content":"deploy with ` + bundleSecret + `"}]`),
+		Tasks:     []persistence.SessionTask{{ID: "task-1", Instruction: "refactor the parser"}},
+	}))
+	reviewer := &PlanFileReviewer{Dir: PlanDir(cfg.Workspace)}
+	require.NoError(t, os.MkdirAll(reviewer.Dir, 0o755))
+	require.NoError(t, os.WriteFile(reviewer.PlanPath(&framework.Task{ID: "task-1"}), []byte(`{"goal":"refactor"}`), 0o644))
+	workflows, err := persistence.NewFileWorkflowStore(cfg.WorkflowPath)
+	require.NoError(t, err)
+	require.NoError(t, workflows.Save(ctx, &persistence.WorkflowSnapshot{ID: "task-1", Status: persistence.WorkflowStatusRunning}))
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
+	require.NoError(t, err)
+	require.NoError(t, memory.Remember(ctx, "parser-notes", map[string]interface{}{"summary": "uses a pratt parser"}, framework.MemoryScopeProject))
+}
+
+func TestContextBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := Config{Workspace: t.TempDir(), MemoryPath: filepath.Join(t.TempDir(), "memory")}
+	seedBundleWorkspace(t, src)
+
+	var archive bytes.Buffer
+	manifest, err := ExportContext(ctx, src, "last", &archive, ContextExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "s1", manifest.SessionID)
+	assert.Equal(t, 1, manifest.Memory)
+	assert.Equal(t, []string{"plan-task-1.json"}, manifest.Plans)
+	assert.Equal(t, 1, manifest.Workflows)
+
+	dst := Config{Workspace: t.TempDir()}
+	report, err := ImportContext(ctx, dst, bytes.NewReader(archive.Bytes()), ContextImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.MemoryImported)
+	assert.Equal(t, 1, report.Workflows)
+
+	require.NoError(t, dst.Normalize())
+	sessions, err := persistence.NewFileSessionStore(dst.SessionPath)
+	require.NoError(t, err)
+	record, ok, err := sessions.Load(ctx, "s1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, dst.Workspace, record.Workspace)
+	assert.Equal(t, src.Workspace, record.Metadata["imported_from"])
+	assert.Contains(t, string(record.Messages), bundleSecret)
+	plan, err := os.ReadFile(filepath.Join(PlanDir(dst.Workspace), "plan-task-1.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"goal":"refactor"}`, string(plan))
+	memory, err := framework.NewLayeredMemory(dst.MemoryLayout())
+	require.NoError(t, err)
+	_, ok, err = memory.Recall(ctx, "parser-notes", framework.MemoryScopeProject)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = ImportContext(ctx, dst, bytes.NewReader(archive.Bytes()), ContextImportOptions{})
+	assert.ErrorContains(t, err, "already exists")
+	report, err = ImportContext(ctx, dst, bytes.NewReader(archive.Bytes()), ContextImportOptions{Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Workflows, "existing workflow snapshots are kept")
+}
+
+func TestContextBundleRedactsSecrets(t *testing.T) {
+	ctx := context.Background()
+	src := Config{Workspace: t.TempDir(), MemoryPath: filepath.Join(t.TempDir(), "memory")}
+	seedBundleWorkspace(t, src)
+
+	var archive bytes.Buffer
+	manifest, err := ExportContext(ctx, src, "s1", &archive, ContextExportOptions{Redact: true})
+	require.NoError(t, err)
+	assert.True(t, manifest.Redacted)
+
+	files, err := readContextBundle(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.NotContains(t, string(files["session.json"]), bundleSecret)
+	assert.Contains(t, string(files["session.json"]), "[REDACTED:api_key]")
+
+	_, err = ExportContext(ctx, src, "missing", &bytes.Buffer{}, ContextExportOptions{})
+	assert.ErrorContains(t, err, "session missing not found")
+}
@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"context"
+	"log"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// readableFilter limits workspace scans to the paths the agent may read and
+// list; it is nil when the agent has no permission manager.
+func readableFilter(registration *framework.AgentRegistration) func(path string, isDir bool) bool {
+	if registration == nil || registration.Permissions == nil {
+		return nil
+	}
+	return func(path string, isDir bool) bool {
+		action := framework.FileSystemRead
+		if isDir {
+			action = framework.FileSystemList
+		}
+		return registration.Permissions.CheckFileAccess(context.Background(), registration.ID, action, path) == nil
+	}
+}
+
+// refreshConventions captures the project's conventions into project memory
+// without blocking startup, like refreshGlossary.
+func refreshConventions(workspace string, runner framework.CommandRunner, memory framework.MemoryStore, registration *framework.AgentRegistration, logger *log.Logger) {
+	analyzer := &framework.ConventionsAnalyzer{Root: workspace, Runner: runner, Filter: readableFilter(registration)}
+	if _, err := framework.RefreshConventions(context.Background(), analyzer, memory); err != nil {
+		logger.Printf("conventions capture failed: %v", err)
+	}
+}
+
+// CaptureConventions analyzes the workspace's conventions and stores them in
+// its project memory, for the index command. Git runs on the host.
+func CaptureConventions(ctx context.Context, cfg Config) (*framework.ProjectConventions, error) {
+	if err := cfg.Normalize(); err != nil {
+		return nil, err
+	}
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
+	if err != nil {
+		return nil, err
+	}
+	runner, err := framework.NewLocalCommandRunner(cfg.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	analyzer := &framework.ConventionsAnalyzer{Root: cfg.Workspace, Runner: runner}
+	return framework.RefreshConventions(ctx, analyzer, memory)
+}
@@ -0,0 +1,410 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// DoctorStatus grades one doctor check.
+type DoctorStatus string
+
+const (
+	DoctorOK   DoctorStatus = "ok"
+	DoctorWarn DoctorStatus = "warn"
+	DoctorFail DoctorStatus = "fail"
+	// DoctorSkip marks checks that depend on one that failed.
+	DoctorSkip DoctorStatus = "skip"
+)
+
+// DoctorCheck is one diagnostic with the fix to apply when it is not ok.
+type DoctorCheck struct {
+	Name   string       `json:"name"`
+	Status DoctorStatus `json:"status"`
+	Detail string       `json:"detail"`
+	Fix    string       `json:"fix,omitempty"`
+}
+
+// DoctorReport collects every check RunDoctor ran.
+type DoctorReport struct {
+	Workspace string        `json:"workspace"`
+	Checks    []DoctorCheck `json:"checks"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// OK reports whether no check failed; warnings still pass.
+func (r *DoctorReport) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == DoctorFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints one line per check and the fix under each problem.
+func (r *DoctorReport) Write(w io.Writer) {
+	width := 0
+	for _, check := range r.Checks {
+		if len(check.Name) > width {
+			width = len(check.Name)
+		}
+	}
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "%-6s %-*s  %s\n", "["+string(check.Status)+"]", width, check.Name, check.Detail)
+		if check.Fix != "" && check.Status != DoctorOK {
+			fmt.Fprintf(w, "       %*s  fix: %s\n", width, "", check.Fix)
+		}
+	}
+	if r.OK() {
+		fmt.Fprintln(w, "result: PASS")
+		return
+	}
+	fmt.Fprintln(w, "result: FAIL")
+}
+
+// DoctorOptions bounds the slower doctor probes.
+type DoctorOptions struct {
+	// ModelTimeout bounds the tool-calling probe, which may have to load the
+	// model first. Zero means two minutes.
+	ModelTimeout time.Duration
+	// LSPTimeout bounds each language server handshake. Zero means 30s.
+	LSPTimeout time.Duration
+}
+
+// RunDoctor checks everything a session depends on without starting one,
+// so it works when the runtime itself cannot start: the manifest, Ollama and
+// the model's tool calling, each configured language server, the sandbox,
+// the state directories, and the AST index.
+func RunDoctor(ctx context.Context, cfg Config, opts DoctorOptions) DoctorReport {
+	if opts.ModelTimeout <= 0 {
+		opts.ModelTimeout = 2 * time.Minute
+	}
+	if opts.LSPTimeout <= 0 {
+		opts.LSPTimeout = 30 * time.Second
+	}
+	report := DoctorReport{Workspace: cfg.Workspace, Timestamp: time.Now()}
+	add := func(check DoctorCheck) { report.Checks = append(report.Checks, check) }
+
+	ws, err := LoadWorkspaceProfile(cfg)
+	if err != nil {
+		add(DoctorCheck{Name: "config", Status: DoctorFail, Detail: err.Error(), Fix: fmt.Sprintf("fix or remove %s", cfg.ConfigPath)})
+	}
+	cfg.ApplyProfileEndpoint(ws)
+	if ws.Model != "" {
+		cfg.OllamaModel = ws.Model
+	}
+
+	spec, check := doctorManifest(cfg)
+	add(check)
+	if cfg.OllamaModel == "" && spec != nil {
+		cfg.OllamaModel = spec.Model.Name
+	}
+	for _, check := range doctorOllama(ctx, cfg, spec, opts.ModelTimeout) {
+		add(check)
+	}
+	if spec != nil {
+		lsp := filterLSPLanguages(spec.LSP, ws.Languages)
+		for _, check := range doctorLSP(cfg.Workspace, lsp, opts.LSPTimeout) {
+			add(check)
+		}
+	}
+	add(doctorSandbox(ctx, cfg))
+	for _, check := range doctorStateDirs(cfg) {
+		add(check)
+	}
+	add(doctorASTIndex(cfg.Workspace))
+	return report
+}
+
+func doctorManifest(cfg Config) (*framework.AgentRuntimeSpec, DoctorCheck) {
+	check := DoctorCheck{Name: "manifest"}
+	manifest, err := framework.LoadAgentManifest(cfg.ManifestPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		check.Status = DoctorFail
+		check.Detail = fmt.Sprintf("%s not found", cfg.ManifestPath)
+		check.Fix = "run `relurpish wizard` to generate one"
+		return nil, check
+	case err != nil:
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = fmt.Sprintf("correct %s; `relurpish wizard` can regenerate it", cfg.ManifestPath)
+		return nil, check
+	}
+	check.Status = DoctorOK
+	check.Detail = fmt.Sprintf("%s (%s)", manifest.Metadata.Name, cfg.ManifestPath)
+	return manifest.Spec.Agent, check
+}
+
+// doctorProbeTool is offered to the model to see whether it calls tools.
+type doctorProbeTool struct{}
+
+func (doctorProbeTool) Name() string { return "report_status" }
+func (doctorProbeTool) Description() string {
+	return "Reports a status word. Call it whenever asked to report a status."
+}
+func (doctorProbeTool) Category() string { return "diagnostics" }
+func (doctorProbeTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{{Name: "status", Type: "string", Description: "The status word", Required: true}}
+}
+func (doctorProbeTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	return &framework.ToolResult{Success: true}, nil
+}
+func (doctorProbeTool) IsAvailable(ctx context.Context, state *framework.Context) bool { return true }
+func (doctorProbeTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{}
+}
+
+// doctorOllama checks the endpoint, that the model is pulled, and that the
+// model answers a tool-calling request with a tool call.
+func doctorOllama(ctx context.Context, cfg Config, spec *framework.AgentRuntimeSpec, timeout time.Duration) []DoctorCheck {
+	ollama := detectOllama(ctx, cfg)
+	reach := DoctorCheck{Name: "ollama", Status: DoctorOK, Detail: fmt.Sprintf("reachable at %s", cfg.OllamaEndpoint)}
+	model := DoctorCheck{Name: "model"}
+	toolCalling := DoctorCheck{Name: "tool_calling"}
+	if !ollama.Healthy {
+		reach.Status = DoctorFail
+		reach.Detail = fmt.Sprintf("%s: %s", cfg.OllamaEndpoint, ollama.Error)
+		reach.Fix = "start Ollama with `ollama serve`, or point --ollama-endpoint (or the profile endpoint) at a running instance"
+		model.Status, model.Detail = DoctorSkip, "ollama unreachable"
+		toolCalling.Status, toolCalling.Detail = DoctorSkip, "ollama unreachable"
+		return []DoctorCheck{reach, model, toolCalling}
+	}
+	switch {
+	case cfg.OllamaModel == "":
+		model.Status = DoctorFail
+		model.Detail = "no model configured"
+		model.Fix = "set model in config.yaml, spec.agent.model.name in the manifest, or pass --ollama-model"
+	case !ollamaHasModel(ollama.Models, cfg.OllamaModel):
+		model.Status = DoctorFail
+		model.Detail = fmt.Sprintf("%s is not pulled", cfg.OllamaModel)
+		model.Fix = fmt.Sprintf("ollama pull %s", cfg.OllamaModel)
+	default:
+		model.Status = DoctorOK
+		model.Detail = cfg.OllamaModel
+	}
+	if model.Status != DoctorOK {
+		toolCalling.Status, toolCalling.Detail = DoctorSkip, "model unavailable"
+		return []DoctorCheck{reach, model, toolCalling}
+	}
+	toolCalling = probeToolCalling(ctx, cfg, spec, timeout)
+	return []DoctorCheck{reach, model, toolCalling}
+}
+
+// ollamaHasModel matches names with and without the implicit :latest tag.
+func ollamaHasModel(models []string, want string) bool {
+	for _, name := range models {
+		if name == want || strings.TrimSuffix(name, ":latest") == want {
+			return true
+		}
+	}
+	return false
+}
+
+func probeToolCalling(ctx context.Context, cfg Config, spec *framework.AgentRuntimeSpec, timeout time.Duration) DoctorCheck {
+	check := DoctorCheck{Name: "tool_calling"}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := llm.NewClient(cfg.OllamaEndpoint, cfg.OllamaModel)
+	resp, err := client.ChatWithTools(probeCtx, []framework.Message{
+		{Role: "user", Content: "Report the status word \"ready\" using the report_status tool."},
+	}, []framework.Tool{doctorProbeTool{}}, &framework.LLMOptions{Temperature: 0})
+	switch {
+	case err != nil && strings.Contains(strings.ToLower(err.Error()), "does not support tools"):
+		check.Detail = fmt.Sprintf("%s does not support tool calling", cfg.OllamaModel)
+	case err != nil:
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = fmt.Sprintf("check the Ollama log; the model may not fit in memory (try `ollama run %s`)", cfg.OllamaModel)
+		return check
+	case resp == nil || len(resp.ToolCalls) == 0:
+		check.Detail = fmt.Sprintf("%s answered without calling the probe tool", cfg.OllamaModel)
+	default:
+		check.Status = DoctorOK
+		check.Detail = fmt.Sprintf("%s called %s", cfg.OllamaModel, resp.ToolCalls[0].Name)
+		return check
+	}
+	// Without native tool calls the agent only works when the manifest has
+	// it parse actions from text.
+	if spec != nil && !spec.ToolCallingEnabled() {
+		check.Status = DoctorOK
+		check.Detail += "; the manifest parses actions from text"
+		return check
+	}
+	check.Status = DoctorFail
+	check.Fix = fmt.Sprintf("set spec.agent.ollama_tool_calling: false in the manifest so the agent parses actions from text, or choose a model with tool support instead of %s", cfg.OllamaModel)
+	return check
+}
+
+// doctorLSP starts each configured language server, completes the
+// handshake, and shuts it down again.
+func doctorLSP(workspace string, spec framework.AgentLSPSpec, timeout time.Duration) []DoctorCheck {
+	if !spec.Enabled || len(spec.Servers) == 0 {
+		return nil
+	}
+	languages := make([]string, 0, len(spec.Servers))
+	for language := range spec.Servers {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	checks := make([]DoctorCheck, 0, len(languages))
+	for _, language := range languages {
+		check := DoctorCheck{Name: "lsp:" + language}
+		server := spec.Servers[language]
+		cfg, _, err := tools.LSPServerConfig(language, server, workspace)
+		if err != nil {
+			check.Status = DoctorFail
+			check.Detail = err.Error()
+			check.Fix = fmt.Sprintf("remove lsp.servers.%s from the manifest or use a supported language", language)
+			checks = append(checks, check)
+			continue
+		}
+		start := time.Now()
+		if err := lspHandshake(cfg, timeout); err != nil {
+			check.Status = DoctorFail
+			check.Detail = fmt.Sprintf("%s: %v", cfg.Command, err)
+			check.Fix = fmt.Sprintf("install %s and make sure it is on PATH, or change lsp.servers.%s in the manifest", cfg.Command, language)
+		} else {
+			check.Status = DoctorOK
+			check.Detail = fmt.Sprintf("%s initialized in %s", cfg.Command, time.Since(start).Round(time.Millisecond))
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// lspHandshake gives up after timeout; a server that finishes starting
+// later is closed as soon as it does.
+func lspHandshake(cfg tools.ProcessLSPConfig, timeout time.Duration) error {
+	type started struct {
+		client tools.LSPClient
+		err    error
+	}
+	done := make(chan started, 1)
+	go func() {
+		client, err := tools.NewProcessLSPClient(cfg)
+		done <- started{client, err}
+	}()
+	closeClient := func(client tools.LSPClient) {
+		if closer, ok := client.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return res.err
+		}
+		closeClient(res.client)
+		return nil
+	case <-time.After(timeout):
+		go func() {
+			if res := <-done; res.err == nil {
+				closeClient(res.client)
+			}
+		}()
+		return fmt.Errorf("no handshake within %s", timeout)
+	}
+}
+
+func doctorSandbox(ctx context.Context, cfg Config) DoctorCheck {
+	check := DoctorCheck{Name: "sandbox"}
+	report := detectSandbox(ctx, cfg)
+	if report.Verified {
+		check.Status = DoctorOK
+		check.Detail = report.Runsc.Version
+		return check
+	}
+	check.Status = DoctorWarn
+	check.Detail = "gVisor unavailable; commands run on the host and need approval (" + strings.Join(report.Errors, "; ") + ")"
+	if cfg.RequireSandbox {
+		check.Status = DoctorFail
+		check.Detail = "gVisor unavailable and --require-sandbox is set (" + strings.Join(report.Errors, "; ") + ")"
+	}
+	check.Fix = "install gVisor (https://gvisor.dev/docs/user_guide/install/) and register runsc with docker, or pass --runsc with its path"
+	return check
+}
+
+// doctorStateDirs checks that every directory the runtime writes to can
+// be written.
+func doctorStateDirs(cfg Config) []DoctorCheck {
+	dirs := []struct{ name, path string }{
+		{"memory", cfg.MemoryPath},
+		{"sessions", cfg.SessionPath},
+		{"workflows", cfg.WorkflowPath},
+		{"checkpoints", cfg.CheckpointPath},
+		{"logs", filepath.Dir(cfg.LogPath)},
+	}
+	checks := make([]DoctorCheck, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir.path == "" {
+			continue
+		}
+		check := DoctorCheck{Name: "write:" + dir.name, Status: DoctorOK, Detail: dir.path}
+		if err := probeWritable(dir.path); err != nil {
+			check.Status = DoctorFail
+			check.Detail = err.Error()
+			check.Fix = fmt.Sprintf("make %s writable by this user (check its owner and mode)", dir.path)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func probeWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	_, err = file.WriteString("ok")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func doctorASTIndex(workspace string) DoctorCheck {
+	check := DoctorCheck{Name: "ast_index"}
+	path := ASTIndexPath(workspace)
+	_, store, err := OpenASTIndex(workspace)
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = fmt.Sprintf("delete %s and run `relurpish index` to rebuild it", path)
+		return check
+	}
+	defer store.Close()
+	stats, err := store.GetStats()
+	switch {
+	case err != nil:
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = fmt.Sprintf("delete %s and run `relurpish index` to rebuild it", path)
+	case stats.TotalFiles == 0:
+		check.Status = DoctorWarn
+		check.Detail = "index is empty; AST tools index the workspace on first use"
+		check.Fix = "run `relurpish index` to build it ahead of time"
+	default:
+		check.Status = DoctorOK
+		check.Detail = fmt.Sprintf("%d files, %d symbols", stats.TotalFiles, stats.TotalNodes)
+	}
+	return check
+}
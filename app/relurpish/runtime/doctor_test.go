@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOllama serves the model list and answers chats with a tool call, or
+// with Ollama's error for models without tool support.
+func fakeOllama(t *testing.T, toolSupport bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"coder:7b"}]}`))
+		case "/api/chat":
+			if !toolSupport {
+				http.Error(w, `{"error":"registry.ollama.ai/library/coder:7b does not support tools"}`, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"report_status","arguments":{"status":"ready"}}}]},"done":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func doctorConfig(t *testing.T, endpoint, model string) Config {
+	t.Helper()
+	cfg := Config{Workspace: t.TempDir()}
+	require.NoError(t, cfg.Normalize())
+	cfg.OllamaEndpoint = endpoint
+	cfg.Sandbox.RunscPath = "runsc-missing"
+	_, err := SaveManifest(context.Background(), cfg, WizardSelection{
+		Model:   model,
+		Agents:  []string{"coding"},
+		Profile: PermissionProfileWorkspaceWrite,
+		Tools:   []string{"file_read"},
+	})
+	require.NoError(t, err)
+	return cfg
+}
+
+func doctorChecks(report DoctorReport) map[string]DoctorCheck {
+	checks := make(map[string]DoctorCheck, len(report.Checks))
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	return checks
+}
+
+func TestRunDoctorPassesWithAWorkingSetup(t *testing.T) {
+	srv := fakeOllama(t, true)
+	cfg := doctorConfig(t, srv.URL, "coder:7b")
+	report := RunDoctor(context.Background(), cfg, DoctorOptions{})
+	checks := doctorChecks(report)
+	for _, name := range []string{"manifest", "ollama", "model", "tool_calling", "write:memory", "write:logs"} {
+		require.Equal(t, DoctorOK, checks[name].Status, "%s: %s", name, checks[name].Detail)
+	}
+	require.Equal(t, DoctorWarn, checks["sandbox"].Status)
+	require.Equal(t, DoctorWarn, checks["ast_index"].Status, "an empty index only warns")
+	require.True(t, report.OK())
+
+	var out bytes.Buffer
+	report.Write(&out)
+	require.Contains(t, out.String(), "result: PASS")
+}
+
+func TestRunDoctorExplainsFailures(t *testing.T) {
+	srv := fakeOllama(t, false)
+	cfg := doctorConfig(t, srv.URL, "coder:7b")
+	checks := doctorChecks(RunDoctor(context.Background(), cfg, DoctorOptions{}))
+	require.Equal(t, DoctorFail, checks["tool_calling"].Status)
+	require.Contains(t, checks["tool_calling"].Fix, "ollama_tool_calling: false")
+
+	cfg = doctorConfig(t, srv.URL, "missing:1b")
+	checks = doctorChecks(RunDoctor(context.Background(), cfg, DoctorOptions{}))
+	require.Equal(t, DoctorFail, checks["model"].Status)
+	require.Equal(t, "ollama pull missing:1b", checks["model"].Fix)
+	require.Equal(t, DoctorSkip, checks["tool_calling"].Status)
+
+	srv.Close()
+	cfg.ManifestPath = filepath.Join(cfg.Workspace, "absent.yaml")
+	report := RunDoctor(context.Background(), cfg, DoctorOptions{})
+	checks = doctorChecks(report)
+	require.Equal(t, DoctorFail, checks["manifest"].Status)
+	require.Equal(t, DoctorFail, checks["ollama"].Status)
+	require.NotEmpty(t, checks["ollama"].Fix)
+	require.False(t, report.OK())
+}
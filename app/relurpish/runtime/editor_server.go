@@ -0,0 +1,394 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Editor server methods and notifications. Requests and replies use LSP
+// framing (Content-Length headers) so editor plugins can reuse their JSON-RPC
+// clients.
+const (
+	EditorMethodInitialize       = "initialize"
+	EditorMethodApplyInstruction = "applyInstruction"
+	EditorMethodExplainSelection = "explainSelection"
+	EditorMethodApproveHITL      = "approveHitl"
+	EditorMethodDenyHITL         = "denyHitl"
+	EditorMethodShutdown         = "shutdown"
+	// EditorMethodCancel cancels the request with the given id.
+	EditorMethodCancel = "$/cancelRequest"
+	EditorMethodExit   = "exit"
+
+	// EditorNotifyProgress streams a running task's telemetry events.
+	EditorNotifyProgress = "$/progress"
+	// EditorNotifyHITL announces a permission request waiting for
+	// approveHitl or denyHitl.
+	EditorNotifyHITL = "hitl/request"
+)
+
+// EditorPosition is a zero-based line and character offset, as in LSP.
+// Characters count runes.
+type EditorPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// EditorRange selects text from Start up to, but not including, End.
+type EditorRange struct {
+	Start EditorPosition `json:"start"`
+	End   EditorPosition `json:"end"`
+}
+
+// EditorApplyParams asks the agent to change a file. Without Range the
+// instruction applies to the whole file.
+type EditorApplyParams struct {
+	File        string       `json:"file"`
+	Range       *EditorRange `json:"range,omitempty"`
+	Instruction string       `json:"instruction"`
+}
+
+// EditorApplyResult reports the files the task changed so the plugin can
+// reload its buffers.
+type EditorApplyResult struct {
+	TaskID       string         `json:"taskId"`
+	Success      bool           `json:"success"`
+	Error        string         `json:"error,omitempty"`
+	Output       map[string]any `json:"output,omitempty"`
+	FilesChanged []string       `json:"filesChanged"`
+	Diffs        []FileDiff     `json:"diffs,omitempty"`
+}
+
+// EditorExplainParams asks the read-only ask agent about a selection.
+type EditorExplainParams struct {
+	File  string       `json:"file"`
+	Range *EditorRange `json:"range,omitempty"`
+	// Question replaces the default "explain this code" prompt.
+	Question string `json:"question,omitempty"`
+}
+
+// EditorExplainResult carries the ask agent's answer.
+type EditorExplainResult struct {
+	Answer string `json:"answer"`
+}
+
+// EditorProgress is one $/progress notification.
+type EditorProgress struct {
+	TaskID  string `json:"taskId"`
+	Type    string `json:"type"`
+	NodeID  string `json:"nodeId,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// EditorHITLDecision resolves a permission request announced by
+// hitl/request.
+type EditorHITLDecision struct {
+	ID     string               `json:"id"`
+	Scope  framework.GrantScope `json:"scope,omitempty"`
+	Reason string               `json:"reason,omitempty"`
+}
+
+// editorSession is the state of one ServeEditor connection.
+type editorSession struct {
+	rt     *Runtime
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	running map[jsonrpc2.ID]context.CancelFunc
+}
+
+// ServeEditor speaks JSON-RPC with an editor plugin over stream until the
+// plugin sends exit, closes the stream, or ctx is cancelled. Requests run
+// concurrently, so a plugin can cancel or approve while a task is running.
+func (r *Runtime) ServeEditor(ctx context.Context, stream io.ReadWriteCloser) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := &editorSession{rt: r, cancel: cancel, running: make(map[jsonrpc2.ID]context.CancelFunc)}
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.AsyncHandler(jsonrpc2.HandlerWithError(s.handle)))
+	defer conn.Close()
+
+	hitl, unsubscribe := r.SubscribeHITL()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.DisconnectNotify():
+			return nil
+		case event, ok := <-hitl:
+			if !ok {
+				hitl = nil
+				continue
+			}
+			if event.Type == framework.HITLEventRequested && event.Request != nil {
+				_ = conn.Notify(ctx, EditorNotifyHITL, event.Request)
+			}
+		}
+	}
+}
+
+func (s *editorSession) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+	switch req.Method {
+	case EditorMethodExit:
+		s.cancel()
+		return nil, nil
+	case EditorMethodCancel:
+		var params struct {
+			ID jsonrpc2.ID `json:"id"`
+		}
+		if err := decodeEditorParams(req, &params); err == nil {
+			s.mu.Lock()
+			if cancel, ok := s.running[params.ID]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+		return nil, nil
+	}
+	if req.Notif {
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mu.Lock()
+	s.running[req.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, req.ID)
+		s.mu.Unlock()
+	}()
+
+	switch req.Method {
+	case EditorMethodInitialize:
+		return map[string]any{
+			"serverInfo": map[string]string{"name": "relurpify", "agent": s.rt.Config.AgentLabel()},
+			"workspace":  s.rt.Config.Workspace,
+			"methods": []string{EditorMethodApplyInstruction, EditorMethodExplainSelection,
+				EditorMethodApproveHITL, EditorMethodDenyHITL, EditorMethodShutdown},
+		}, nil
+	case EditorMethodShutdown:
+		return nil, nil
+	case EditorMethodApplyInstruction:
+		var params EditorApplyParams
+		if err := decodeEditorParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.applyInstruction(ctx, conn, params)
+	case EditorMethodExplainSelection:
+		var params EditorExplainParams
+		if err := decodeEditorParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.explainSelection(ctx, params)
+	case EditorMethodApproveHITL, EditorMethodDenyHITL:
+		var params EditorHITLDecision
+		if err := decodeEditorParams(req, &params); err != nil {
+			return nil, err
+		}
+		var err error
+		if req.Method == EditorMethodApproveHITL {
+			err = s.rt.ApproveHITL(params.ID, "editor", params.Scope, time.Hour)
+		} else {
+			err = s.rt.DenyHITL(params.ID, params.Reason)
+		}
+		return nil, err
+	}
+	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not supported: " + req.Method}
+}
+
+func decodeEditorParams(req *jsonrpc2.Request, v any) error {
+	if req.Params == nil {
+		return &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "params required"}
+	}
+	if err := json.Unmarshal(*req.Params, v); err != nil {
+		return &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// applyInstruction runs a code modification task scoped to the file and
+// selection, forwarding the task's events as $/progress notifications.
+func (s *editorSession) applyInstruction(ctx context.Context, conn *jsonrpc2.Conn, params EditorApplyParams) (*EditorApplyResult, error) {
+	instruction := strings.TrimSpace(params.Instruction)
+	if instruction == "" {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "instruction required"}
+	}
+	rel, selection, err := s.rt.editorSelection(params.File, params.Range)
+	if err != nil {
+		return nil, err
+	}
+	task := &framework.Task{
+		ID:          fmt.Sprintf("editor-%d", time.Now().UnixNano()),
+		Type:        framework.TaskTypeCodeModification,
+		Instruction: instruction,
+		Context: map[string]any{
+			"source":        "editor",
+			"path":          rel,
+			"context_files": []string{rel},
+		},
+	}
+	if params.Range != nil {
+		task.Context["range"] = *params.Range
+		task.Context["selection"] = selection
+		task.Instruction = fmt.Sprintf("%s\n\nApply this to lines %d-%d of %s:\n```\n%s\n```",
+			instruction, params.Range.Start.Line+1, params.Range.End.Line+1, rel, selection)
+	}
+
+	if s.rt.Events != nil {
+		_, events, stop := s.rt.Events.Follow(task.ID)
+		quit, done := make(chan struct{}), make(chan struct{})
+		defer func() {
+			stop()
+			close(quit)
+			<-done
+		}()
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case event := <-events:
+					notifyEditorProgress(ctx, conn, event)
+				case <-quit:
+					// Flush what the task emitted before it returned.
+					for {
+						select {
+						case event := <-events:
+							notifyEditorProgress(ctx, conn, event)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	report, err := s.rt.RunTaskReport(ctx, task)
+	if report == nil {
+		return nil, err
+	}
+	result := &EditorApplyResult{
+		TaskID:       task.ID,
+		Success:      report.Status == TaskStatusSucceeded,
+		Error:        report.Error,
+		Output:       report.Output,
+		FilesChanged: report.FilesChanged,
+		Diffs:        report.Diffs,
+	}
+	if result.FilesChanged == nil {
+		result.FilesChanged = []string{}
+	}
+	return result, nil
+}
+
+func notifyEditorProgress(ctx context.Context, conn *jsonrpc2.Conn, event framework.Event) {
+	_ = conn.Notify(ctx, EditorNotifyProgress, EditorProgress{
+		TaskID:  event.TaskID,
+		Type:    string(event.Type),
+		NodeID:  event.NodeID,
+		Message: event.Message,
+	})
+}
+
+// explainSelection asks the read-only ask agent about the selected code.
+func (s *editorSession) explainSelection(ctx context.Context, params EditorExplainParams) (*EditorExplainResult, error) {
+	rel, selection, err := s.rt.editorSelection(params.File, params.Range)
+	if err != nil {
+		return nil, err
+	}
+	question := strings.TrimSpace(params.Question)
+	if question == "" {
+		question = "Explain what this code does and how it fits into the codebase."
+	}
+	where := rel
+	if params.Range != nil {
+		where = fmt.Sprintf("lines %d-%d of %s", params.Range.Start.Line+1, params.Range.End.Line+1, rel)
+	}
+	res, err := s.rt.Ask(ctx, fmt.Sprintf("%s\n\nCode from %s:\n```\n%s\n```", question, where, selection))
+	if err != nil {
+		return nil, err
+	}
+	answer, _ := res.Data["final_output"].(string)
+	return &EditorExplainResult{Answer: answer}, nil
+}
+
+// editorSelection resolves file against the workspace and returns its
+// workspace-relative path and the selected text, or the whole file when rng
+// is nil.
+func (r *Runtime) editorSelection(file string, rng *EditorRange) (string, string, error) {
+	if strings.TrimSpace(file) == "" {
+		return "", "", &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "file required"}
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.Config.Workspace, path)
+	}
+	rel, err := filepath.Rel(r.Config.Workspace, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: fmt.Sprintf("%s is outside the workspace", file)}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	if rng == nil {
+		return filepath.ToSlash(rel), string(data), nil
+	}
+	text, err := selectRange(string(data), *rng)
+	if err != nil {
+		return "", "", &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+	}
+	return filepath.ToSlash(rel), text, nil
+}
+
+// selectRange returns the text of rng in content. Positions past the end of
+// a line clamp to it, as LSP clients expect.
+func selectRange(content string, rng EditorRange) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	offset := func(pos EditorPosition) (int, error) {
+		if pos.Line < 0 || pos.Character < 0 {
+			return 0, errors.New("negative position")
+		}
+		if pos.Line >= len(lines) {
+			return len(content), nil
+		}
+		start := 0
+		for _, line := range lines[:pos.Line] {
+			start += len(line)
+		}
+		line := strings.TrimSuffix(lines[pos.Line], "\n")
+		runes := 0
+		for i := range line {
+			if runes == pos.Character {
+				return start + i, nil
+			}
+			runes++
+		}
+		return start + len(line), nil
+	}
+	start, err := offset(rng.Start)
+	if err != nil {
+		return "", err
+	}
+	end, err := offset(rng.End)
+	if err != nil {
+		return "", err
+	}
+	if end < start {
+		return "", errors.New("range end precedes start")
+	}
+	return content[start:end], nil
+}
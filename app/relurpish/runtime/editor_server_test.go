@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/server"
+)
+
+// progressAgent emits one telemetry event per task.
+type progressAgent struct {
+	recordingAgent
+	events *server.EventLog
+}
+
+func (a *progressAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	a.events.Emit(framework.Event{Type: framework.EventNodeStart, NodeID: "plan", TaskID: task.ID, Message: "planning"})
+	return a.recordingAgent.Execute(ctx, task, state)
+}
+
+// editorClient collects the server's notifications.
+type editorClient struct {
+	mu       sync.Mutex
+	progress []EditorProgress
+}
+
+func (c *editorClient) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Method != EditorNotifyProgress || req.Params == nil {
+		return
+	}
+	var p EditorProgress
+	if json.Unmarshal(*req.Params, &p) == nil {
+		c.mu.Lock()
+		c.progress = append(c.progress, p)
+		c.mu.Unlock()
+	}
+}
+
+func TestServeEditorAppliesInstructionToSelection(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc add(a, b int) int {\n\treturn a - b\n}\n"), 0o644))
+	events := server.NewEventLog()
+	agent := &progressAgent{events: events}
+	rt := &Runtime{Config: Config{Workspace: dir}, Context: framework.NewContext(), Agent: agent, Events: events}
+
+	serverSide, clientSide := net.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- rt.ServeEditor(context.Background(), serverSide) }()
+	client := &editorClient{}
+	conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{}), client)
+
+	var result EditorApplyResult
+	require.NoError(t, conn.Call(context.Background(), EditorMethodApplyInstruction, EditorApplyParams{
+		File:        "main.go",
+		Range:       &EditorRange{Start: EditorPosition{Line: 3, Character: 1}, End: EditorPosition{Line: 3, Character: 99}},
+		Instruction: "fix the operator",
+	}, &result))
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{}, result.FilesChanged)
+
+	agent.mu.Lock()
+	require.Len(t, agent.tasks, 1)
+	task := agent.tasks[0]
+	agent.mu.Unlock()
+	assert.Equal(t, result.TaskID, task.ID)
+	assert.Equal(t, framework.TaskTypeCodeModification, task.Type)
+	assert.Equal(t, "editor", task.Context["source"])
+	assert.Equal(t, "main.go", task.Context["path"])
+	assert.Equal(t, "return a - b", task.Context["selection"])
+	assert.Contains(t, task.Instruction, "lines 4-4 of main.go")
+
+	client.mu.Lock()
+	require.Len(t, client.progress, 1)
+	assert.Equal(t, EditorProgress{TaskID: task.ID, Type: string(framework.EventNodeStart), NodeID: "plan", Message: "planning"}, client.progress[0])
+	client.mu.Unlock()
+
+	err := conn.Call(context.Background(), EditorMethodApplyInstruction, EditorApplyParams{File: "../etc/passwd", Instruction: "x"}, &result)
+	var rpcErr *jsonrpc2.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, int64(jsonrpc2.CodeInvalidParams), rpcErr.Code)
+
+	err = conn.Call(context.Background(), "rewriteEverything", nil, nil)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, int64(jsonrpc2.CodeMethodNotFound), rpcErr.Code)
+
+	require.NoError(t, conn.Notify(context.Background(), EditorMethodExit, nil))
+	require.NoError(t, <-served)
+	conn.Close()
+}
+
+func TestSelectRange(t *testing.T) {
+	content := "héllo\nworld\n"
+	text, err := selectRange(content, EditorRange{Start: EditorPosition{Line: 0, Character: 1}, End: EditorPosition{Line: 1, Character: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, "éllo\nwor", text)
+
+	text, err = selectRange(content, EditorRange{End: EditorPosition{Line: 9}})
+	require.NoError(t, err)
+	assert.Equal(t, content, text, "positions past the end clamp")
+
+	_, err = selectRange(content, EditorRange{Start: EditorPosition{Line: 1}})
+	assert.Error(t, err)
+}
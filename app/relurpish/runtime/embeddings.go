@@ -0,0 +1,88 @@
+package runtime
+
+import (
+	"context"
+	"os"
+
+	"github.com/lexcodex/relurpify/framework/ast"
+	"github.com/lexcodex/relurpify/framework/watch"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// DefaultEmbeddingModel is the Ollama model used for the embeddings index
+// when the workspace config does not name one.
+const DefaultEmbeddingModel = "nomic-embed-text"
+
+// EmbeddingsConfig selects the model behind the embeddings index used by
+// search_semantic and search_find_similar:
+//
+//	embeddings:
+//	  model: nomic-embed-text
+//	  chunk_lines: 40
+//	  chunk_overlap: 8
+//	  batch_size: 16
+//	  cache_size: 4096
+//
+// batch_size bounds the chunks per embedding request and cache_size the
+// vectors kept in memory so unchanged text is not embedded again. The index
+// is built with `relurpish index --embeddings`; until then search_semantic
+// falls back to substring matching and search_find_similar to character
+// overlap.
+type EmbeddingsConfig struct {
+	Model        string `yaml:"model,omitempty"`
+	ChunkLines   int    `yaml:"chunk_lines,omitempty"`
+	ChunkOverlap int    `yaml:"chunk_overlap,omitempty"`
+	BatchSize    int    `yaml:"batch_size,omitempty"`
+	CacheSize    int    `yaml:"cache_size,omitempty"`
+}
+
+// EmbeddingModel returns the configured model or DefaultEmbeddingModel.
+func (c *EmbeddingsConfig) EmbeddingModel() string {
+	if c == nil || c.Model == "" {
+		return DefaultEmbeddingModel
+	}
+	return c.Model
+}
+
+// NewEmbeddingIndex builds the workspace embeddings index over store, the AST
+// index database, embedding chunks with the Ollama model at endpoint through
+// an in-memory llm.EmbeddingCache. throttle, when set, limits the embedding
+// requests.
+func NewEmbeddingIndex(workspace, endpoint string, store *ast.SQLiteStore, cfg *EmbeddingsConfig, throttle *llm.Throttle) *ast.EmbeddingIndex {
+	model := cfg.EmbeddingModel()
+	config := ast.EmbeddingConfig{WorkspacePath: workspace, Model: model}
+	cacheSize := 0
+	if cfg != nil {
+		config.ChunkLines = cfg.ChunkLines
+		config.ChunkOverlap = cfg.ChunkOverlap
+		config.BatchSize = cfg.BatchSize
+		cacheSize = cfg.CacheSize
+	}
+	client := llm.NewClient(endpoint, model)
+	client.Throttle = throttle
+	cache := llm.NewEmbeddingCache(client, model, cacheSize)
+	cache.BatchSize = config.BatchSize
+	return ast.NewEmbeddingIndex(store, cache, config)
+}
+
+// embeddingsInvalidator re-embeds changed files once the index has been
+// built; an empty index stays empty so sessions never embed the workspace
+// behind the user's back.
+func embeddingsInvalidator(index *ast.EmbeddingIndex) watch.Invalidator {
+	return watch.InvalidatorFunc(func(changes []watch.Change) {
+		if n, err := index.Count(); err != nil || n == 0 {
+			return
+		}
+		for _, change := range changes {
+			if change.Op.Removed() {
+				_ = index.RemoveFile(change.Path)
+				continue
+			}
+			info, err := os.Stat(change.Path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			_, _ = index.IndexFile(context.Background(), change.Path)
+		}
+	})
+}
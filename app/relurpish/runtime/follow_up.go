@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// maxFollowUpOutput bounds the previous task's output carried into the next
+// prompt.
+const maxFollowUpOutput = 4000
+
+// FollowUpSession carries the outcome of one shell task into the next: its
+// final output, the files it changed, and its plan. Reset forgets it so the
+// next task starts from scratch.
+type FollowUpSession struct {
+	mu   sync.Mutex
+	last *framework.PreviousTask
+}
+
+// Attach adds the previous task, if any, to task.Context["previous_task"].
+func (s *FollowUpSession) Attach(task *framework.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || task == nil {
+		return
+	}
+	if task.Context == nil {
+		task.Context = make(map[string]any)
+	}
+	task.Context["previous_task"] = *s.last
+}
+
+// Record remembers a finished task for the next Attach.
+func (s *FollowUpSession) Record(task *framework.Task, res *framework.Result, state *framework.Context, filesChanged []string) {
+	previous := framework.PreviousTask{
+		Instruction:  task.Instruction,
+		Output:       followUpOutput(res, state),
+		FilesChanged: filesChanged,
+	}
+	if state != nil {
+		if value, ok := state.Get("planner.plan"); ok {
+			if plan, ok := value.(framework.Plan); ok {
+				previous.Plan = &plan
+			}
+		}
+	}
+	s.mu.Lock()
+	s.last = &previous
+	s.mu.Unlock()
+}
+
+// Last returns the task the next Attach carries over.
+func (s *FollowUpSession) Last() (framework.PreviousTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return framework.PreviousTask{}, false
+	}
+	return *s.last, true
+}
+
+// Reset forgets the previous task.
+func (s *FollowUpSession) Reset() {
+	s.mu.Lock()
+	s.last = nil
+	s.mu.Unlock()
+}
+
+// restore puts back the previous task an undone turn replaced.
+func (s *FollowUpSession) restore(previous *framework.PreviousTask) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.last = previous
+	s.mu.Unlock()
+}
+
+// followUpOutput picks the human-facing result: the agent's final output,
+// then the planner's summary.
+func followUpOutput(res *framework.Result, state *framework.Context) string {
+	var output string
+	if res != nil && res.Data != nil {
+		if final, ok := res.Data["final_output"]; ok && final != nil {
+			output = fmt.Sprint(final)
+		}
+	}
+	if output == "" && state != nil {
+		output = state.GetString("planner.summary")
+	}
+	if len(output) > maxFollowUpOutput {
+		output = output[:maxFollowUpOutput] + "…"
+	}
+	return output
+}
+
+// runFollowUp runs a shell task with the previous task attached and records
+// its outcome for the next one. Failed tasks are not recorded, so a
+// follow-up refers to the last task that worked. Every task, failed or not,
+// is checkpointed in Chat so /undo can revert it.
+func (r *Runtime) runFollowUp(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+	if r.FollowUps == nil {
+		return r.RunTask(ctx, task)
+	}
+	var previous *framework.PreviousTask
+	if last, ok := r.FollowUps.Last(); ok {
+		previous = &last
+	}
+	r.FollowUps.Attach(task)
+	r.Chat.begin(task, r.Context.Snapshot(), previous)
+	before := snapshotWorkspace(ctx, r.Config.Workspace)
+	res, state, err := r.runTask(ctx, task)
+	var changed []string
+	if before != nil {
+		if after := snapshotWorkspace(context.WithoutCancel(ctx), r.Config.Workspace); after != nil {
+			changed = changedPaths(before, after)
+		}
+	}
+	r.Chat.finish(task, changed)
+	if err != nil {
+		return res, err
+	}
+	r.FollowUps.Record(task, res, state, changed)
+	return res, nil
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TestFollowUpSessionCarriesLastTask attaches the previous task's output,
+// files, and plan to the next task until Reset.
+func TestFollowUpSessionCarriesLastTask(t *testing.T) {
+	session := &FollowUpSession{}
+	first := &framework.Task{Instruction: "add a cache to the user handler"}
+	session.Attach(first)
+	_, attached := framework.PreviousTaskOf(first)
+	require.False(t, attached)
+
+	state := framework.NewContext()
+	state.Set("planner.plan", framework.Plan{Goal: "cache", Steps: []framework.PlanStep{{ID: 1, Description: "add cache"}}})
+	res := &framework.Result{Success: true, Data: map[string]any{"final_output": "Added an LRU cache."}}
+	session.Record(first, res, state, []string{"handler.go", "cache.go"})
+
+	second := &framework.Task{Instruction: "now add tests for that"}
+	session.Attach(second)
+	previous, ok := framework.PreviousTaskOf(second)
+	require.True(t, ok)
+	require.Equal(t, "add a cache to the user handler", previous.Instruction)
+	require.Equal(t, "Added an LRU cache.", previous.Output)
+	require.Equal(t, []string{"handler.go", "cache.go"}, previous.FilesChanged)
+	require.Equal(t, "cache", previous.Plan.Goal)
+	require.Contains(t, previous.Render(), "Files changed: handler.go, cache.go")
+
+	session.Reset()
+	third := &framework.Task{Instruction: "unrelated"}
+	session.Attach(third)
+	require.Nil(t, third.Context)
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/server"
+)
+
+// HITLWebhookConfig declares an approval notification target in config.yaml:
+//
+//	hitl_webhooks:
+//	  - url: https://hooks.slack.com/services/T000/B000/XXX
+//	    public_url: https://relurpify.internal:8080
+//	    events: [requested]
+//	  - url: https://ci.example/relurpify/hitl
+//	    secret_env: RELURPIFY_HITL_SECRET
+//
+// SecretEnv names an environment variable holding the signing secret so it
+// stays out of the workspace config.
+type HITLWebhookConfig struct {
+	URL       string            `yaml:"url"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	SecretEnv string            `yaml:"secret_env,omitempty"`
+	PublicURL string            `yaml:"public_url,omitempty"`
+	Events    []string          `yaml:"events,omitempty"`
+}
+
+// buildHITLWebhooks combines config.yaml hooks with --hitl-webhook URLs.
+func buildHITLWebhooks(cfg Config, ws WorkspaceConfig, logger *log.Logger) ([]*server.HITLWebhook, error) {
+	configs := append([]HITLWebhookConfig(nil), ws.HITLWebhooks...)
+	for _, raw := range cfg.HITLWebhooks {
+		configs = append(configs, HITLWebhookConfig{URL: raw})
+	}
+	hooks := make([]*server.HITLWebhook, 0, len(configs))
+	for i, hc := range configs {
+		if hc.URL == "" {
+			return nil, fmt.Errorf("hitl webhook %d: url required", i)
+		}
+		if _, err := url.ParseRequestURI(hc.URL); err != nil {
+			return nil, fmt.Errorf("hitl webhook %d: %w", i, err)
+		}
+		hook := &server.HITLWebhook{
+			URL:       hc.URL,
+			Headers:   hc.Headers,
+			PublicURL: hc.PublicURL,
+			Logger:    logger,
+		}
+		if hc.SecretEnv != "" {
+			hook.Secret = os.Getenv(hc.SecretEnv)
+			if hook.Secret == "" {
+				return nil, fmt.Errorf("hitl webhook %d: %s is not set", i, hc.SecretEnv)
+			}
+		}
+		for _, name := range hc.Events {
+			event := framework.HITLEventType(name)
+			switch event {
+			case framework.HITLEventRequested, framework.HITLEventResolved, framework.HITLEventExpired:
+			default:
+				return nil, fmt.Errorf("hitl webhook %d: unknown event %q (requested, resolved, expired)", i, name)
+			}
+			hook.Events = append(hook.Events, event)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
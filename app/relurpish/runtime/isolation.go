@@ -0,0 +1,357 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// Isolation modes. A git checkout is isolated in a detached worktree that
+// carries the uncommitted and untracked (not ignored) files over; anything
+// else is copied.
+const (
+	IsolationWorktree = "worktree"
+	IsolationCopy     = "copy"
+)
+
+// Isolated change operations.
+const (
+	ChangeCreated  = "created"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// IsolatedChange is one file a task changed in its isolated workspace. Path
+// is relative to the workspace.
+type IsolatedChange struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// IsolatedWorkspace is a temporary copy of a workspace that a task runs
+// against so its edits reach the real workspace only through Merge.
+type IsolatedWorkspace struct {
+	// Source is the real workspace and Dir its isolated copy.
+	Source string
+	Dir    string
+	Mode   string
+
+	root     string
+	gitTop   string
+	baseline map[string]string
+}
+
+// IsolateWorkspace copies source into a new temporary directory. Close
+// removes it.
+func IsolateWorkspace(ctx context.Context, source string) (*IsolatedWorkspace, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.MkdirTemp("", "relurpify-isolated-*")
+	if err != nil {
+		return nil, err
+	}
+	w := &IsolatedWorkspace{Source: source, root: root}
+	if err := w.populate(ctx); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.baseline, err = hashTree(w.Dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *IsolatedWorkspace) populate(ctx context.Context) error {
+	tree := filepath.Join(w.root, "workspace")
+	if top, err := gitOutput(ctx, w.Source, "rev-parse", "--show-toplevel"); err == nil {
+		top = strings.TrimSpace(top)
+		if _, err := gitOutput(ctx, top, "worktree", "add", "--detach", tree, "HEAD"); err == nil {
+			w.gitTop = top
+			w.Mode = IsolationWorktree
+			rel, err := filepath.Rel(top, w.Source)
+			if err != nil {
+				return err
+			}
+			w.Dir = filepath.Join(tree, rel)
+			return carryWorkingState(ctx, top, tree)
+		}
+	}
+	w.Mode = IsolationCopy
+	w.Dir = tree
+	return copyTree(w.Source, tree)
+}
+
+// carryWorkingState applies top's uncommitted changes and copies its
+// untracked files into the worktree at tree.
+func carryWorkingState(ctx context.Context, top, tree string) error {
+	diff, err := gitOutput(ctx, top, "diff", "--binary", "HEAD")
+	if err != nil {
+		return err
+	}
+	if diff != "" {
+		cmd := exec.CommandContext(ctx, "git", "-C", tree, "apply", "--binary", "--whitespace=nowarn")
+		cmd.Stdin = strings.NewReader(diff)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("carry uncommitted changes: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	untracked, err := gitOutput(ctx, top, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return err
+	}
+	for _, rel := range strings.Split(untracked, "\x00") {
+		if rel == "" || isolationSkipped(rel) {
+			continue
+		}
+		if err := copyFile(filepath.Join(top, rel), filepath.Join(tree, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config returns cfg retargeted at the isolated workspace. Configuration,
+// memory, and logs stay with the real workspace; checkpoints of the
+// isolated run are kept apart so rolling back a merge restores only real
+// files.
+func (w *IsolatedWorkspace) Config(cfg Config) Config {
+	if cfg.Project != "" {
+		if rel, err := filepath.Rel(w.Source, cfg.Project); err == nil && !strings.HasPrefix(rel, "..") {
+			cfg.Project = filepath.Join(w.Dir, rel)
+		}
+	}
+	cfg.Workspace = w.Dir
+	cfg.CheckpointPath = filepath.Join(w.root, "checkpoints")
+	return cfg
+}
+
+// Changes lists the files created, modified, or deleted in the isolated
+// workspace since it was made, sorted by path.
+func (w *IsolatedWorkspace) Changes() ([]IsolatedChange, error) {
+	current, err := hashTree(w.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var changes []IsolatedChange
+	for rel, hash := range current {
+		before, ok := w.baseline[rel]
+		switch {
+		case !ok:
+			changes = append(changes, IsolatedChange{Path: rel, Op: ChangeCreated})
+		case before != hash:
+			changes = append(changes, IsolatedChange{Path: rel, Op: ChangeModified})
+		}
+	}
+	for rel := range w.baseline {
+		if _, ok := current[rel]; !ok {
+			changes = append(changes, IsolatedChange{Path: rel, Op: ChangeDeleted})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Merge applies changes to the real workspace, snapshotting each target in
+// store under jobID first so the merge can be rolled back. Nothing is
+// applied when a target changed in the real workspace since isolation; the
+// error names the conflicting files.
+func (w *IsolatedWorkspace) Merge(changes []IsolatedChange, store *persistence.FileCheckpointStore, jobID string) error {
+	var conflicts []string
+	for _, change := range changes {
+		hash, err := hashFile(filepath.Join(w.Source, filepath.FromSlash(change.Path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if hash != w.baseline[change.Path] {
+			conflicts = append(conflicts, change.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("workspace changed during the isolated task: %s", strings.Join(conflicts, ", "))
+	}
+	for _, change := range changes {
+		target := filepath.Join(w.Source, filepath.FromSlash(change.Path))
+		if store != nil {
+			if err := store.Snapshot(jobID, target); err != nil {
+				return fmt.Errorf("checkpoint %s: %w", change.Path, err)
+			}
+		}
+		var err error
+		if change.Op == ChangeDeleted {
+			err = os.Remove(target)
+		} else {
+			err = copyFile(filepath.Join(w.Dir, filepath.FromSlash(change.Path)), target)
+		}
+		if err != nil {
+			return fmt.Errorf("merge %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// ConfirmMerge lists changes on out and asks the person at in whether to
+// merge them into workspace. No answer means no.
+func ConfirmMerge(in io.Reader, out io.Writer, workspace string, changes []IsolatedChange) bool {
+	for _, change := range changes {
+		fmt.Fprintf(out, "  %-8s %s\n", change.Op, change.Path)
+	}
+	fmt.Fprintf(out, "Merge %d changed files into %s? [y/N]: ", len(changes), workspace)
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// Close removes the isolated workspace.
+func (w *IsolatedWorkspace) Close() error {
+	if w == nil {
+		return nil
+	}
+	if w.gitTop != "" {
+		_, _ = gitOutput(context.Background(), w.gitTop, "worktree", "remove", "--force", filepath.Join(w.root, "workspace"))
+	}
+	return os.RemoveAll(w.root)
+}
+
+// isolationSkipped reports whether rel is left out of the isolated copy and
+// its change tracking: git metadata and relurpify_cfg, which the runtime
+// keeps using from the real workspace.
+func isolationSkipped(rel string) bool {
+	first, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return first == ".git" || first == "relurpify_cfg"
+}
+
+// hashTree hashes every regular file and symlink under dir.
+func hashTree(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if isolationSkipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+	return hashes, err
+}
+
+// hashFile hashes a file's content and mode, or a symlink's target. A
+// missing file hashes to "".
+func hashFile(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "link:%s", target)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "%o:", info.Mode().Perm())
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyTree copies src into dst, skipping what isolationSkipped excludes.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && isolationSkipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+// copyFile copies a regular file with its permissions, or recreates a
+// symlink, creating parent directories as needed.
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(dst)
+		return os.Symlink(target, dst)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+func writeIsolationFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestIsolatedWorkspaceMergeAndRollback(t *testing.T) {
+	source := t.TempDir()
+	writeIsolationFiles(t, source, map[string]string{
+		"keep.go":                   "package keep\n",
+		"edit.go":                   "package edit\n",
+		"drop.go":                   "package drop\n",
+		"relurpify_cfg/config.yaml": "model: x\n",
+	})
+	iso, err := IsolateWorkspace(context.Background(), source)
+	require.NoError(t, err)
+	defer iso.Close()
+	require.NotEqual(t, source, iso.Dir)
+	require.NoFileExists(t, filepath.Join(iso.Dir, "relurpify_cfg", "config.yaml"))
+
+	writeIsolationFiles(t, iso.Dir, map[string]string{
+		"edit.go":    "package edit\n\nfunc F() {}\n",
+		"new/add.go": "package add\n",
+	})
+	require.NoError(t, os.Remove(filepath.Join(iso.Dir, "drop.go")))
+
+	// The real workspace is untouched until the merge.
+	data, err := os.ReadFile(filepath.Join(source, "edit.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package edit\n", string(data))
+
+	changes, err := iso.Changes()
+	require.NoError(t, err)
+	require.Equal(t, []IsolatedChange{
+		{Path: "drop.go", Op: ChangeDeleted},
+		{Path: "edit.go", Op: ChangeModified},
+		{Path: "new/add.go", Op: ChangeCreated},
+	}, changes)
+
+	store, err := persistence.NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, iso.Merge(changes, store, "job-1"))
+	data, err = os.ReadFile(filepath.Join(source, "edit.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "func F()")
+	require.FileExists(t, filepath.Join(source, "new", "add.go"))
+	require.NoFileExists(t, filepath.Join(source, "drop.go"))
+
+	_, err = store.Rollback("job-1")
+	require.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join(source, "edit.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package edit\n", string(data))
+	require.FileExists(t, filepath.Join(source, "drop.go"))
+	require.NoFileExists(t, filepath.Join(source, "new", "add.go"))
+}
+
+func TestIsolatedWorkspaceMergeRefusesConflicts(t *testing.T) {
+	source := t.TempDir()
+	writeIsolationFiles(t, source, map[string]string{"a.go": "a\n", "b.go": "b\n"})
+	iso, err := IsolateWorkspace(context.Background(), source)
+	require.NoError(t, err)
+	defer iso.Close()
+
+	writeIsolationFiles(t, iso.Dir, map[string]string{"a.go": "a2\n", "b.go": "b2\n"})
+	writeIsolationFiles(t, source, map[string]string{"b.go": "edited meanwhile\n"})
+
+	changes, err := iso.Changes()
+	require.NoError(t, err)
+	err = iso.Merge(changes, nil, "job")
+	require.ErrorContains(t, err, "b.go")
+	data, err := os.ReadFile(filepath.Join(source, "a.go"))
+	require.NoError(t, err)
+	require.Equal(t, "a\n", string(data), "no change is applied when any file conflicts")
+}
+
+func TestIsolatedWorkspaceUsesGitWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	source := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", source, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	git("init", "-q")
+	writeIsolationFiles(t, source, map[string]string{"main.go": "package main\n", ".gitignore": "*.log\n"})
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	writeIsolationFiles(t, source, map[string]string{
+		"main.go":  "package main\n\n// dirty\n",
+		"notes.md": "untracked\n",
+		"run.log":  "ignored\n",
+	})
+
+	iso, err := IsolateWorkspace(context.Background(), source)
+	require.NoError(t, err)
+	require.Equal(t, IsolationWorktree, iso.Mode)
+	data, err := os.ReadFile(filepath.Join(iso.Dir, "main.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "// dirty")
+	require.FileExists(t, filepath.Join(iso.Dir, "notes.md"))
+	require.NoFileExists(t, filepath.Join(iso.Dir, "run.log"))
+
+	changes, err := iso.Changes()
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	require.NoError(t, iso.Close())
+	require.NoDirExists(t, iso.Dir)
+	require.NotContains(t, git("worktree", "list"), iso.Dir)
+}
+
+func TestConfirmMerge(t *testing.T) {
+	changes := []IsolatedChange{{Path: "a.go", Op: ChangeModified}}
+	var out bytes.Buffer
+	require.True(t, ConfirmMerge(strings.NewReader("y\n"), &out, "/ws", changes))
+	require.Contains(t, out.String(), "modified a.go")
+	require.False(t, ConfirmMerge(strings.NewReader("\n"), &out, "/ws", changes))
+	require.False(t, ConfirmMerge(strings.NewReader(""), &out, "/ws", changes))
+}
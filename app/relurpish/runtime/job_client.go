@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/server"
+)
+
+// JobClient controls the task queue of a running relurpish server over its
+// HTTP API.
+type JobClient struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+// NewJobClient targets the server listening on addr, such as ":8080",
+// authenticating with RELURPIFY_API_KEY when it is set.
+func NewJobClient(addr string) *JobClient {
+	host, port, err := net.SplitHostPort(addr)
+	if err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		addr = net.JoinHostPort("localhost", port)
+	}
+	return &JobClient{
+		BaseURL: "http://" + addr,
+		APIKey:  os.Getenv(apiKeyEnv),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// List returns every task the server knows about, newest first.
+func (c *JobClient) List(ctx context.Context) ([]server.TaskRecord, error) {
+	var records []server.TaskRecord
+	err := c.do(ctx, http.MethodGet, "/api/tasks", &records)
+	return records, err
+}
+
+// Cancel stops a queued or paused task, or cancels the context of a running
+// one.
+func (c *JobClient) Cancel(ctx context.Context, id string) (server.TaskRecord, error) {
+	return c.control(ctx, id, "cancel")
+}
+
+// Pause holds a queued task back until it is resumed.
+func (c *JobClient) Pause(ctx context.Context, id string) (server.TaskRecord, error) {
+	return c.control(ctx, id, "pause")
+}
+
+// Resume queues a paused task again.
+func (c *JobClient) Resume(ctx context.Context, id string) (server.TaskRecord, error) {
+	return c.control(ctx, id, "resume")
+}
+
+func (c *JobClient) control(ctx context.Context, id, action string) (server.TaskRecord, error) {
+	var record server.TaskRecord
+	err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/"+action, &record)
+	return record, err
+}
+
+func (c *JobClient) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/server"
+)
+
+func TestJobClientControlsTasks(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/tasks/task-1/cancel":
+			w.Write([]byte(`{"id":"task-1","status":"running"}`))
+		case "/api/tasks/task-2/resume":
+			http.Error(w, "task task-2 is queued, not paused", http.StatusConflict)
+		default:
+			w.Write([]byte(`[{"id":"task-1","status":"running","priority":3}]`))
+		}
+	}))
+	defer srv.Close()
+	client := &JobClient{BaseURL: srv.URL, APIKey: "secret"}
+	ctx := context.Background()
+
+	record, err := client.Cancel(ctx, "task-1")
+	require.NoError(t, err)
+	require.Equal(t, server.TaskStatusRunning, record.Status)
+	_, err = client.Resume(ctx, "task-2")
+	require.EqualError(t, err, "409 Conflict: task task-2 is queued, not paused")
+	records, err := client.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, records[0].Priority)
+	require.Equal(t, []string{"POST /api/tasks/task-1/cancel", "POST /api/tasks/task-2/resume", "GET /api/tasks"}, paths)
+}
+
+func TestNewJobClientDialsLocalhostForWildcardAddr(t *testing.T) {
+	require.Equal(t, "http://localhost:8080", NewJobClient(":8080").BaseURL)
+	require.Equal(t, "http://10.0.0.5:9000", NewJobClient("10.0.0.5:9000").BaseURL)
+}
+
+func TestJobsConfigWorkers(t *testing.T) {
+	require.Equal(t, 2, (*JobsConfig)(nil).Workers(0))
+	require.Equal(t, 4, (&JobsConfig{MaxConcurrent: 4}).Workers(0))
+	require.Equal(t, 1, (&JobsConfig{MaxConcurrent: 4}).Workers(1))
+}
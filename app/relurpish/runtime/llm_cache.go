@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// LLMCacheConfig enables the model response cache:
+//
+//	llm_cache:
+//	  enabled: true
+//	  ttl: 24h
+//	  max_mb: 64
+//
+// Identical calls to the same model with the same options are answered from
+// relurpify_cfg/cache/llm_responses.db instead of Ollama, which mostly helps
+// repeated planner and review prompts and re-run tasks. --no-cache bypasses
+// it for one run.
+type LLMCacheConfig struct {
+	Enabled bool   `yaml:"enabled,omitempty"`
+	TTL     string `yaml:"ttl,omitempty"`
+	MaxMB   int    `yaml:"max_mb,omitempty"`
+}
+
+// LLMCachePath returns the SQLite database backing the response cache.
+func LLMCachePath(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "cache", "llm_responses.db")
+}
+
+// openResponseCache returns the response cache, or nil when it is disabled
+// in the workspace config or bypassed with --no-cache.
+func openResponseCache(cfg Config, cacheCfg *LLMCacheConfig) (*llm.ResponseCache, error) {
+	if cfg.NoCache || cacheCfg == nil || !cacheCfg.Enabled {
+		return nil, nil
+	}
+	opts := llm.ResponseCacheConfig{Path: LLMCachePath(cfg.Workspace), MaxBytes: int64(cacheCfg.MaxMB) << 20}
+	if cacheCfg.TTL != "" {
+		ttl, err := time.ParseDuration(cacheCfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("llm_cache.ttl: %w", err)
+		}
+		opts.TTL = ttl
+	}
+	return llm.OpenResponseCache(opts)
+}
+
+// cacheModel serves the repeated calls model makes for client's model from
+// cache when it is set. model is client itself or a pool around it.
+func cacheModel(model framework.LanguageModel, client *llm.Client, cache *llm.ResponseCache) framework.LanguageModel {
+	if cache == nil {
+		return model
+	}
+	return llm.NewCachedModel(model, cache, client.Model)
+}
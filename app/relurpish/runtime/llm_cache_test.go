@@ -0,0 +1,30 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenResponseCacheHonoursConfigAndNoCache(t *testing.T) {
+	cfg := Config{Workspace: t.TempDir()}
+	cache, err := openResponseCache(cfg, nil)
+	require.NoError(t, err)
+	require.Nil(t, cache)
+
+	enabled := &LLMCacheConfig{Enabled: true, TTL: "1h"}
+	cache, err = openResponseCache(cfg, enabled)
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	require.FileExists(t, LLMCachePath(cfg.Workspace))
+	require.NoError(t, cache.Close())
+
+	cfg.NoCache = true
+	cache, err = openResponseCache(cfg, enabled)
+	require.NoError(t, err)
+	require.Nil(t, cache)
+
+	cfg.NoCache = false
+	_, err = openResponseCache(cfg, &LLMCacheConfig{Enabled: true, TTL: "soon"})
+	require.ErrorContains(t, err, "llm_cache.ttl")
+}
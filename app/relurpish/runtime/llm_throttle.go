@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"strings"
+
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// LLMThrottleConfig caps the requests sent to each model endpoint so shell
+// jobs, the server, and the embeddings index do not pile onto one local
+// Ollama and time out together:
+//
+//	llm_throttle:
+//	  max_concurrent: 2
+//	  requests_per_minute: 60
+//	  endpoints:
+//	    http://gpu-box:11434:
+//	      max_concurrent: 4
+//
+// An endpoints entry replaces the top-level limits for that endpoint.
+// Requests over the limits queue until a slot frees up or the task is
+// cancelled.
+type LLMThrottleConfig struct {
+	LLMLimits `yaml:",inline"`
+	Endpoints map[string]LLMLimits `yaml:"endpoints,omitempty"`
+}
+
+// LLMLimits bounds one endpoint. Zero means unlimited.
+type LLMLimits struct {
+	MaxConcurrent     int `yaml:"max_concurrent,omitempty"`
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty"`
+}
+
+// Limits returns the limits that apply to endpoint.
+func (c *LLMThrottleConfig) Limits(endpoint string) LLMLimits {
+	if c == nil {
+		return LLMLimits{}
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	for name, limits := range c.Endpoints {
+		if strings.TrimRight(name, "/") == endpoint {
+			return limits
+		}
+	}
+	return c.LLMLimits
+}
+
+// Throttle returns the process-wide throttle for endpoint, or nil when it
+// is unlimited.
+func (c *LLMThrottleConfig) Throttle(endpoint string) *llm.Throttle {
+	limits := c.Limits(endpoint)
+	return llm.SharedThrottle(endpoint, llm.ThrottleConfig{
+		MaxConcurrent:     limits.MaxConcurrent,
+		RequestsPerMinute: limits.RequestsPerMinute,
+	})
+}
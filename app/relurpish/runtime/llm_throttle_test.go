@@ -0,0 +1,27 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLLMThrottleLimitsPerEndpoint(t *testing.T) {
+	var ws WorkspaceConfig
+	err := yaml.Unmarshal([]byte(`
+llm_throttle:
+  max_concurrent: 2
+  requests_per_minute: 60
+  endpoints:
+    http://gpu-box:11434/:
+      max_concurrent: 4
+`), &ws)
+	assert.NoError(t, err)
+	assert.Equal(t, LLMLimits{MaxConcurrent: 2, RequestsPerMinute: 60}, ws.LLMThrottle.Limits("http://localhost:11434"))
+	assert.Equal(t, LLMLimits{MaxConcurrent: 4}, ws.LLMThrottle.Limits("http://gpu-box:11434"))
+	assert.NotNil(t, ws.LLMThrottle.Throttle("http://localhost:11434"))
+
+	var unset *LLMThrottleConfig
+	assert.Nil(t, unset.Throttle("http://localhost:11434"))
+}
@@ -0,0 +1,37 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// buildLSPProxy registers each configured language server lazily: nothing is
+// launched until a tool call touches a file of that language.
+func buildLSPProxy(workspace string, spec framework.AgentLSPSpec, opts ToolRegistryOptions) (*tools.Proxy, error) {
+	proxy := tools.NewProxy(0)
+	languages := make([]string, 0, len(spec.Servers))
+	for language := range spec.Servers {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		cfg, extensions, err := tools.LSPServerConfig(language, spec.Servers[language], workspace)
+		if err != nil {
+			return nil, fmt.Errorf("lsp: %w", err)
+		}
+		cfg.Remote = opts.Remote
+		proxy.RegisterLanguage(language, extensions, func() (tools.LSPClient, error) {
+			return tools.NewProcessLSPClientWithPermissions(cfg, opts.PermissionManager, opts.AgentID, opts.AgentSpec)
+		})
+	}
+	return proxy, nil
+}
+
+// LSP returns the proxy behind the LSP tools, or nil when no language
+// server is configured.
+func (r *Runtime) LSP() *tools.Proxy {
+	return r.lsp
+}
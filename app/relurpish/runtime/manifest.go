@@ -1,8 +1,10 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,15 +14,40 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// PermissionProfile controls how aggressively the generated manifest grants
-// filesystem access.
+// PermissionProfile is a permission preset: how much filesystem, command,
+// and network access the generated manifest grants. Each tier includes the
+// one before it.
 type PermissionProfile string
 
 const (
-	PermissionProfileReadOnly       PermissionProfile = "read_only"
-	PermissionProfileWorkspaceWrite PermissionProfile = "workspace_write"
+	PermissionProfileReadOnly          PermissionProfile = "read_only"
+	PermissionProfileWorkspaceWrite    PermissionProfile = "workspace_write"
+	PermissionProfileWorkspaceWriteNet PermissionProfile = "workspace_write_net"
+	PermissionProfileFull              PermissionProfile = "full"
 )
 
+// PermissionProfiles lists the presets from least to most access.
+func PermissionProfiles() []PermissionProfile {
+	return []PermissionProfile{
+		PermissionProfileReadOnly,
+		PermissionProfileWorkspaceWrite,
+		PermissionProfileWorkspaceWriteNet,
+		PermissionProfileFull,
+	}
+}
+
+// ParsePermissionProfile accepts a preset name as written in config.yaml or
+// on the command line, e.g. "workspace_write" or "workspace-write+net".
+func ParsePermissionProfile(name string) (PermissionProfile, error) {
+	normalized := strings.NewReplacer("-", "_", "+", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+	for _, profile := range PermissionProfiles() {
+		if string(profile) == normalized {
+			return profile, nil
+		}
+	}
+	return "", fmt.Errorf("unknown permission preset %q (want read-only, workspace-write, workspace-write+net, or full)", name)
+}
+
 // ManifestSummary is shared across wizard + status views.
 type ManifestSummary struct {
 	Path        string
@@ -64,6 +91,10 @@ func (p PermissionProfile) Description() string {
 		return "Read + list workspace (no writes)."
 	case PermissionProfileWorkspaceWrite:
 		return "Read/write workspace and run Go toolchain."
+	case PermissionProfileWorkspaceWriteNet:
+		return "Workspace write plus HTTP(S) to any host."
+	case PermissionProfileFull:
+		return "Any command and any host; trusted sandboxes only."
 	default:
 		return string(p)
 	}
@@ -123,12 +154,24 @@ func SaveManifest(ctx context.Context, cfg Config, selection WizardSelection) (M
 		PermissionProfile: profile,
 		LastUpdated:       time.Now().Unix(),
 	}
+	if existing, err := LoadWorkspaceConfig(cfg.ConfigPath); err == nil {
+		workspaceCfg = mergeWizardSelection(existing, workspaceCfg, cfg.Profile)
+	}
 	if err := SaveWorkspaceConfig(cfg.ConfigPath, workspaceCfg); err != nil {
 		return ManifestSummary{}, err
 	}
 	return summarizeManifest(cfg.ManifestPath), nil
 }
 
+// PermissionPreset returns the permission set profile grants in workspace.
+func PermissionPreset(workspace string, profile PermissionProfile) (framework.PermissionSet, error) {
+	profile, err := ParsePermissionProfile(string(profile))
+	if err != nil {
+		return framework.PermissionSet{}, err
+	}
+	return buildPermissionSet(workspace, profile), nil
+}
+
 // buildPermissionSet creates the filesystem/network grants implied by the
 // wizard profile selection.
 func buildPermissionSet(workspace string, profile PermissionProfile) framework.PermissionSet {
@@ -146,12 +189,94 @@ func buildPermissionSet(workspace string, profile PermissionProfile) framework.P
 			{Direction: "egress", Protocol: "tcp", Host: "localhost", Port: 11434, Description: "Ollama"},
 		},
 	}
-	if profile == PermissionProfileWorkspaceWrite {
-		perms.FileSystem = append(perms.FileSystem, framework.FileSystemPermission{Action: framework.FileSystemWrite, Path: glob, Justification: "Modify workspace"})
+	switch profile {
+	case PermissionProfileWorkspaceWrite, PermissionProfileWorkspaceWriteNet, PermissionProfileFull:
+	default:
+		return perms
+	}
+	perms.FileSystem = append(perms.FileSystem, framework.FileSystemPermission{Action: framework.FileSystemWrite, Path: glob, Justification: "Modify workspace"})
+	switch profile {
+	case PermissionProfileWorkspaceWriteNet:
+		perms.Network = append(perms.Network,
+			framework.NetworkPermission{Direction: "egress", Protocol: "tcp", Host: "*", Port: 80, Description: "HTTP"},
+			framework.NetworkPermission{Direction: "egress", Protocol: "tcp", Host: "*", Port: 443, Description: "HTTPS"},
+		)
+	case PermissionProfileFull:
+		perms.FileSystem = append(perms.FileSystem, framework.FileSystemPermission{Action: framework.FileSystemExecute, Path: glob, Justification: "Run workspace scripts"})
+		perms.Executables = []framework.ExecutablePermission{{Binary: framework.PermissionAnyBinary}}
+		perms.Network = []framework.NetworkPermission{{Direction: "egress", Protocol: "tcp", Host: "*", Description: "Any host"}}
 	}
 	return perms
 }
 
+// ApplyPermissionPreset replaces spec.permissions in the workspace manifest
+// with profile's permission set, leaving the rest of the file as written,
+// and records the choice as permission_profile in config.yaml.
+func ApplyPermissionPreset(cfg Config, profile PermissionProfile) (ManifestSummary, error) {
+	profile, err := ParsePermissionProfile(string(profile))
+	if err != nil {
+		return ManifestSummary{}, err
+	}
+	perms := buildPermissionSet(cfg.Workspace, profile)
+	data, err := os.ReadFile(cfg.ManifestPath)
+	if err != nil {
+		return ManifestSummary{}, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return ManifestSummary{}, fmt.Errorf("%s: %w", cfg.ManifestPath, err)
+	}
+	spec := yamlMappingValue(&doc, "spec")
+	if spec == nil || spec.Kind != yaml.MappingNode {
+		return ManifestSummary{}, fmt.Errorf("%s: manifest has no spec", cfg.ManifestPath)
+	}
+	var encoded yaml.Node
+	if err := encoded.Encode(perms); err != nil {
+		return ManifestSummary{}, err
+	}
+	if existing := yamlMappingValue(spec, "permissions"); existing != nil {
+		*existing = encoded
+	} else {
+		spec.Content = append(spec.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "permissions"}, &encoded)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return ManifestSummary{}, err
+	}
+	if err := os.WriteFile(cfg.ManifestPath, buf.Bytes(), 0o644); err != nil {
+		return ManifestSummary{}, err
+	}
+	workspaceCfg, err := LoadWorkspaceConfig(cfg.ConfigPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ManifestSummary{}, err
+	}
+	workspaceCfg.PermissionProfile = profile
+	workspaceCfg.LastUpdated = time.Now().Unix()
+	if err := SaveWorkspaceConfig(cfg.ConfigPath, workspaceCfg); err != nil {
+		return ManifestSummary{}, err
+	}
+	return summarizeManifest(cfg.ManifestPath), nil
+}
+
+// yamlMappingValue returns the value stored under key in a mapping node, or
+// in the mapping at the root of a document node.
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
 // buildManifestDescription converts the wizard selection into a readable
 // summary stored inside the manifest metadata.
 func buildManifestDescription(selection WizardSelection) string {
@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/mcp"
+)
+
+// registerMCPServers connects to each manifest MCP server and registers the
+// allowlisted tools. Servers that fail to start or list tools are logged and
+// skipped. The returned closers stop stdio servers and end HTTP sessions.
+func registerMCPServers(ctx context.Context, registry *framework.ToolRegistry, spec *framework.AgentRuntimeSpec, workspace string, registration *framework.AgentRegistration, logger *log.Logger) []io.Closer {
+	if spec == nil {
+		return nil
+	}
+	var closers []io.Closer
+	for _, server := range spec.MCPServers {
+		timeout := 30 * time.Second
+		if parsed, err := time.ParseDuration(server.Timeout); err == nil && parsed > 0 {
+			timeout = parsed
+		}
+		cfg := mcp.Config{
+			Name:    server.Name,
+			Command: server.Command,
+			Env:     server.Env,
+			Workdir: workspace,
+			URL:     server.URL,
+			Headers: server.Headers,
+		}
+		if registration != nil {
+			cfg.Permissions = registration.Permissions
+			cfg.AgentID = registration.ID
+		}
+		perms, err := mcp.ServerPermissions(cfg)
+		if err != nil {
+			logger.Printf("warning: mcp server %s unavailable: %v", server.Name, err)
+			continue
+		}
+		connectCtx, cancel := context.WithTimeout(ctx, timeout)
+		client, err := mcp.Connect(connectCtx, cfg)
+		if err != nil {
+			cancel()
+			logger.Printf("warning: mcp server %s unavailable: %v", server.Name, err)
+			continue
+		}
+		tools, err := mcp.LoadTools(connectCtx, client, perms, server.AllowsTool)
+		cancel()
+		if err != nil {
+			client.Close()
+			logger.Printf("warning: mcp server %s unavailable: %v", server.Name, err)
+			continue
+		}
+		closers = append(closers, client)
+		for _, tool := range tools {
+			if err := registry.Register(tool); err != nil {
+				logger.Printf("warning: mcp server %s: %v", server.Name, err)
+			}
+		}
+		logger.Printf("mcp server %s: registered %d tools", server.Name, len(tools))
+	}
+	return closers
+}
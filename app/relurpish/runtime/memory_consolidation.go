@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"context"
+	"log"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// defaultConsolidationEvery is the number of session memory writes between
+// background consolidation passes.
+const defaultConsolidationEvery = 50
+
+// MemoryConsolidationConfig tunes how session memories are folded into
+// project memory:
+//
+//	memory_consolidation:
+//	  every: 50          # session memory writes between passes
+//	  min_records: 20
+//	  keep_recent: 5
+//	  max_summaries: 20  # older summaries are merged beyond this
+//
+// Zero values use the framework defaults; disabled: true turns the
+// background job off.
+type MemoryConsolidationConfig struct {
+	Disabled     bool `yaml:"disabled,omitempty"`
+	Every        int  `yaml:"every,omitempty"`
+	MinRecords   int  `yaml:"min_records,omitempty"`
+	KeepRecent   int  `yaml:"keep_recent,omitempty"`
+	MaxSummaries int  `yaml:"max_summaries,omitempty"`
+}
+
+func newMemoryConsolidator(cfg *MemoryConsolidationConfig, model framework.LanguageModel) *framework.MemoryConsolidator {
+	consolidator := &framework.MemoryConsolidator{Model: model}
+	if cfg != nil {
+		consolidator.MinRecords = cfg.MinRecords
+		consolidator.KeepRecent = cfg.KeepRecent
+		consolidator.MaxSummaries = cfg.MaxSummaries
+	}
+	return consolidator
+}
+
+// consolidatingMemory wraps memory with the background consolidation job
+// unless the workspace disables it.
+func consolidatingMemory(memory framework.MemoryStore, cfg *MemoryConsolidationConfig, model framework.LanguageModel, logger *log.Logger) framework.MemoryStore {
+	if cfg != nil && cfg.Disabled {
+		return memory
+	}
+	every := defaultConsolidationEvery
+	if cfg != nil && cfg.Every > 0 {
+		every = cfg.Every
+	}
+	wrapped := framework.NewConsolidatingMemory(memory, newMemoryConsolidator(cfg, model), every)
+	wrapped.OnResult = func(report framework.ConsolidationReport, err error) {
+		if err != nil {
+			logger.Printf("warning: memory consolidation failed: %v", err)
+			return
+		}
+		if report.Consolidated > 0 || report.Merged > 0 {
+			logger.Printf("memory consolidation: %d records summarized, %d summaries merged", report.Consolidated, report.Merged)
+		}
+	}
+	return wrapped
+}
+
+// ConsolidateMemory runs one consolidation pass over the workspace memory
+// store. Session memory lives only in a running process, so from the CLI this
+// mostly merges old project summaries. Summaries are written by the workspace
+// model when one is configured.
+func ConsolidateMemory(ctx context.Context, cfg Config) (framework.ConsolidationReport, error) {
+	if err := cfg.Normalize(); err != nil {
+		return framework.ConsolidationReport{}, err
+	}
+	ws, err := LoadWorkspaceProfile(cfg)
+	if err != nil {
+		return framework.ConsolidationReport{}, err
+	}
+	if ws.Model != "" {
+		cfg.OllamaModel = ws.Model
+	}
+	cfg.ApplyProfileEndpoint(ws)
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
+	if err != nil {
+		return framework.ConsolidationReport{}, err
+	}
+	var model framework.LanguageModel
+	if cfg.OllamaModel != "" {
+		client := llm.NewClient(cfg.OllamaEndpoint, cfg.OllamaModel)
+		client.Throttle = ws.LLMThrottle.Throttle(cfg.OllamaEndpoint)
+		model = client
+	}
+	consolidator := newMemoryConsolidator(ws.MemoryConsolidation, model)
+	consolidator.Store = memory
+	return consolidator.Consolidate(ctx)
+}
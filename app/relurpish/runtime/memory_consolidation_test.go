@@ -0,0 +1,33 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestConsolidateMemoryMergesWorkspaceSummaries(t *testing.T) {
+	ws := t.TempDir()
+	cfg := Config{Workspace: ws}
+	require.NoError(t, os.MkdirAll(filepath.Join(ws, "relurpify_cfg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(ws, "relurpify_cfg", "config.yaml"), []byte("memory_consolidation:\n  max_summaries: 2\n"), 0o644))
+	store, err := framework.NewHybridMemory(filepath.Join(ws, "relurpify_cfg", "memory"))
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Remember(context.Background(), fmt.Sprintf("s%d", i), map[string]interface{}{
+			"type":    framework.ConsolidatedMemoryType,
+			"summary": fmt.Sprintf("note %d", i),
+		}, framework.MemoryScopeProject))
+	}
+
+	report, err := ConsolidateMemory(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Merged)
+	require.Len(t, report.Written, 1)
+}
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"log"
+	"path/filepath"
+	goruntime "runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// MemoryUsage summarizes what a long-running shell currently holds.
+type MemoryUsage struct {
+	HeapAlloc      uint64
+	HistoryEntries int
+	UsageRecords   int
+	Spilled        int
+}
+
+// GCReport describes one explicit collection pass.
+type GCReport struct {
+	HistoryDropped int
+	UsageDropped   int
+	HeapBefore     uint64
+	HeapAfter      uint64
+}
+
+// openSpillFile names spill files by day so a week of sessions stays easy to
+// browse and prune.
+func openSpillFile(cfg Config) (*persistence.SpillFile, error) {
+	name := time.Now().Format("2006-01-02") + ".jsonl"
+	return persistence.NewSpillFile(filepath.Join(cfg.SpillPath, name))
+}
+
+// applyMemoryLimits caps the shared context history and usage records,
+// spilling what they drop.
+func applyMemoryLimits(cfg Config, state *framework.Context, usage *framework.UsageTracker, spill *persistence.SpillFile, logger *log.Logger) {
+	report := func(kind string, err error) {
+		if err != nil && logger != nil {
+			logger.Printf("warning: %s spill failed: %v", kind, err)
+		}
+	}
+	var historySpill func([]framework.Interaction)
+	var usageSpill func([]framework.UsageRecord)
+	if spill != nil {
+		historySpill = func(dropped []framework.Interaction) {
+			entries := make([]interface{}, len(dropped))
+			for i := range dropped {
+				entries[i] = dropped[i]
+			}
+			report("history", spill.Append("history", entries...))
+		}
+		usageSpill = func(dropped []framework.UsageRecord) {
+			entries := make([]interface{}, len(dropped))
+			for i := range dropped {
+				entries[i] = dropped[i]
+			}
+			report("usage", spill.Append("usage", entries...))
+		}
+	}
+	if state != nil {
+		state.SetHistoryLimit(cfg.HistoryLimit, historySpill)
+	}
+	usage.SetLimit(cfg.UsageLimit, usageSpill)
+}
+
+// MemoryUsage samples heap and buffer sizes. ReadMemStats briefly stops the
+// world, so callers should poll it every few seconds at most.
+func (r *Runtime) MemoryUsage() MemoryUsage {
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	usage := MemoryUsage{HeapAlloc: stats.HeapAlloc, Spilled: r.Spill.Written()}
+	if r.Context != nil {
+		usage.HistoryEntries = r.Context.HistoryLen()
+	}
+	usage.UsageRecords = r.Usage.Len()
+	return usage
+}
+
+// CollectGarbage trims the context history and usage records to the given
+// sizes, spilling what it drops, then returns freed memory to the OS.
+func (r *Runtime) CollectGarbage(historyKeep, usageKeep int) GCReport {
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	report := GCReport{HeapBefore: stats.HeapAlloc}
+	if r.Context != nil {
+		report.HistoryDropped = r.Context.TrimHistory(historyKeep)
+	}
+	report.UsageDropped = r.Usage.Trim(usageKeep)
+	debug.FreeOSMemory()
+	goruntime.ReadMemStats(&stats)
+	report.HeapAfter = stats.HeapAlloc
+	return report
+}
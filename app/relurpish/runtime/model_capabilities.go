@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// modelProbeTimeout bounds the test request sent to models whose backend does
+// not report capabilities; it may have to load the model first.
+const modelProbeTimeout = 60 * time.Second
+
+// modelCacheTTL bounds how long a probed model's capabilities are trusted;
+// re-pulling a tag can change them without changing its name.
+const modelCacheTTL = 7 * 24 * time.Hour
+
+// Capability sources.
+const (
+	CapabilitySourceReported = "reported"
+	CapabilitySourceProbed   = "probed"
+)
+
+// ModelCapabilities are the features of a model that decide how the agent
+// is wired.
+type ModelCapabilities struct {
+	Model string `json:"model"`
+	// ToolCalling is native function calling; without it the agent parses
+	// actions from text.
+	ToolCalling bool `json:"tool_calling"`
+	// JSONMode is support for schema-constrained responses.
+	JSONMode bool `json:"json_mode"`
+	Vision   bool `json:"vision"`
+	// ContextLength is the trained context window, zero when unknown.
+	ContextLength int `json:"context_length,omitempty"`
+	// Source is CapabilitySourceReported when the backend listed the
+	// capabilities, CapabilitySourceProbed when a test request found them.
+	Source    string    `json:"source"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ProbeModelCapabilities reads the capabilities the backend reports in info.
+// When it reports none, a tool-calling request to model decides ToolCalling;
+// ok is false when that request fails, leaving the capabilities unknown.
+// Ollama constrains responses to a schema for every completion model, so
+// JSONMode only turns off when the model is reported without "completion".
+func ProbeModelCapabilities(ctx context.Context, model framework.LanguageModel, info framework.ModelContext) (ModelCapabilities, bool) {
+	caps := ModelCapabilities{Model: info.Name, JSONMode: true, ContextLength: info.ContextLength, CheckedAt: time.Now().UTC()}
+	if len(info.Capabilities) > 0 {
+		caps.Source = CapabilitySourceReported
+		caps.JSONMode = false
+		for _, capability := range info.Capabilities {
+			switch strings.ToLower(capability) {
+			case "tools":
+				caps.ToolCalling = true
+			case "vision":
+				caps.Vision = true
+			case "completion":
+				caps.JSONMode = true
+			}
+		}
+		return caps, true
+	}
+	caps.Source = CapabilitySourceProbed
+	probeCtx, cancel := context.WithTimeout(ctx, modelProbeTimeout)
+	defer cancel()
+	resp, err := model.ChatWithTools(probeCtx, []framework.Message{
+		{Role: "user", Content: "Report the status word \"ready\" using the report_status tool."},
+	}, []framework.Tool{doctorProbeTool{}}, &framework.LLMOptions{Model: info.Name, Temperature: 0})
+	switch {
+	case err != nil && strings.Contains(strings.ToLower(err.Error()), "does not support tools"):
+	case err != nil:
+		return caps, false
+	default:
+		caps.ToolCalling = resp != nil && len(resp.ToolCalls) > 0
+	}
+	return caps, true
+}
+
+// modelCapabilities returns the capabilities of the model agentCfg.ModelContext
+// describes, from cache when a probe already ran. Reported capabilities
+// cost nothing to read and are not cached.
+func modelCapabilities(ctx context.Context, endpoint string, model framework.LanguageModel, info framework.ModelContext, cache *StartupCache) (ModelCapabilities, bool) {
+	if len(info.Capabilities) == 0 && cache != nil {
+		if caps, ok := cache.LookupModel(endpoint, info.Name); ok {
+			caps.ContextLength = info.ContextLength
+			return caps, true
+		}
+	}
+	caps, ok := ProbeModelCapabilities(ctx, model, info)
+	if ok && caps.Source == CapabilitySourceProbed && cache != nil {
+		cache.StoreModel(endpoint, caps)
+	}
+	return caps, ok
+}
+
+// negotiateModel wires agentCfg for what the model supports: actions are
+// parsed from text when the model has no native tool calling, and schemas
+// are not sent to models without JSON mode. Manifest settings that turn a
+// feature off always win.
+func negotiateModel(agentCfg *framework.Config, caps ModelCapabilities, logger *log.Logger) {
+	agentCfg.OllamaToolCalling = agentCfg.AgentSpec.ToolCallingEnabled() && caps.ToolCalling
+	if agentCfg.AgentSpec.ToolCallingEnabled() && !caps.ToolCalling {
+		logger.Printf("%s does not support tool calling (%s); parsing actions from text", caps.Model, caps.Source)
+	}
+	if !caps.JSONMode && !agentCfg.StructuredOutput.Unconstrained {
+		agentCfg.StructuredOutput.Unconstrained = true
+		logger.Printf("%s does not support JSON mode (%s); validating responses without sending schemas", caps.Model, caps.Source)
+	}
+}
@@ -0,0 +1,63 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+// TestNegotiateModelParsesActionsWithoutToolSupport switches a model that
+// reports no "tools" capability to text-parsed actions.
+func TestNegotiateModelParsesActionsWithoutToolSupport(t *testing.T) {
+	stub := ollamatest.Start(t, ollamatest.Script{Capabilities: []string{"completion", "vision"}})
+	client := llm.NewClient(stub.URL, "stub")
+	agentCfg := &framework.Config{Model: "stub", OllamaToolCalling: true}
+	logger := log.New(io.Discard, "", 0)
+	sizeModelContext(context.Background(), client, agentCfg, logger)
+
+	caps, ok := modelCapabilities(context.Background(), stub.URL, client, agentCfg.ModelContext, nil)
+	require.True(t, ok)
+	require.Equal(t, CapabilitySourceReported, caps.Source)
+	require.False(t, caps.ToolCalling)
+	require.True(t, caps.Vision)
+	require.True(t, caps.JSONMode)
+	negotiateModel(agentCfg, caps, logger)
+	require.False(t, agentCfg.OllamaToolCalling)
+	require.False(t, agentCfg.StructuredOutput.Unconstrained)
+	require.Empty(t, stub.Requests(), "reported capabilities need no probe request")
+
+	off := false
+	agentCfg.AgentSpec = &framework.AgentRuntimeSpec{OllamaToolCalling: &off}
+	negotiateModel(agentCfg, ModelCapabilities{Model: "stub", ToolCalling: true, JSONMode: true}, logger)
+	require.False(t, agentCfg.OllamaToolCalling, "the manifest can still turn tool calling off")
+}
+
+// TestModelCapabilitiesProbesOnceAndCaches sends the probe request only when
+// the backend reports nothing, and reuses the cached answer afterwards.
+func TestModelCapabilitiesProbesOnceAndCaches(t *testing.T) {
+	stub := ollamatest.Start(t, ollamatest.Script{Rules: []ollamatest.Rule{
+		{Match: "report_status", Reply: ollamatest.Reply{ToolCalls: []ollamatest.ToolCall{{Name: "report_status", Args: map[string]interface{}{"status": "ready"}}}}},
+	}})
+	client := llm.NewClient(stub.URL, "stub")
+	cache := OpenStartupCache(t.TempDir(), t.TempDir())
+	info := framework.ModelContext{Name: "stub", ContextLength: 4096}
+
+	caps, ok := modelCapabilities(context.Background(), stub.URL, client, info, cache)
+	require.True(t, ok)
+	require.Equal(t, CapabilitySourceProbed, caps.Source)
+	require.True(t, caps.ToolCalling)
+	require.Len(t, stub.Requests(), 1)
+
+	caps, ok = modelCapabilities(context.Background(), stub.URL, client, info, cache)
+	require.True(t, ok)
+	require.True(t, caps.ToolCalling)
+	require.Equal(t, 4096, caps.ContextLength)
+	require.Len(t, stub.Requests(), 1, "the cached probe is reused")
+}
@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lexcodex/relurpify/agents"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// modelShowTimeout bounds the /api/show lookup so an unreachable Ollama does
+// not stall startup; the probe reports connectivity separately.
+const modelShowTimeout = 5 * time.Second
+
+// sizeModelContext reads the model's metadata into agentCfg and asks Ollama
+// for the matching window. Lookup failures keep the default sizing.
+func sizeModelContext(ctx context.Context, client *llm.Client, agentCfg *framework.Config, logger *log.Logger) {
+	info, err := showModel(ctx, client, client.Model)
+	if err != nil {
+		logger.Printf("model metadata unavailable for %s: %v", client.Model, err)
+		info = framework.ModelContext{Name: client.Model}
+	}
+	applyModelContext(client, agentCfg, info, logger)
+}
+
+func showModel(ctx context.Context, client *llm.Client, name string) (framework.ModelContext, error) {
+	ctx, cancel := context.WithTimeout(ctx, modelShowTimeout)
+	defer cancel()
+	return client.ShowModel(ctx, name)
+}
+
+func applyModelContext(client *llm.Client, agentCfg *framework.Config, info framework.ModelContext, logger *log.Logger) framework.ContextSizing {
+	agentCfg.ModelContext = info
+	sizing := agentCfg.ContextSizing()
+	client.ContextLength = sizing.MaxTokens
+	logger.Printf("context window for %s: %s", info.Name, sizing)
+	return sizing
+}
+
+// UseModel switches the agent to model, resizing its context budget from the
+// new model's metadata. The model must be known to Ollama.
+func (r *Runtime) UseModel(ctx context.Context, model string) (framework.ContextSizing, error) {
+	if r.client == nil || r.agentConfig == nil {
+		return framework.ContextSizing{}, fmt.Errorf("model switching unavailable")
+	}
+	info, err := showModel(ctx, r.client, model)
+	if err != nil {
+		return framework.ContextSizing{}, fmt.Errorf("model %s: %w", model, err)
+	}
+	r.client.Model = model
+	r.Config.OllamaModel = model
+	r.agentConfig.Model = model
+	sizing := applyModelContext(r.client, r.agentConfig, info, r.Logger)
+	if caps, ok := modelCapabilities(ctx, r.Config.OllamaEndpoint, r.client, info, nil); ok {
+		negotiateModel(r.agentConfig, caps, r.Logger)
+	}
+	if err := r.Agent.Initialize(r.agentConfig); err != nil {
+		return sizing, fmt.Errorf("reinitialize agent: %w", err)
+	}
+	if reflection, ok := r.Agent.(*agents.ReflectionAgent); ok && reflection.Delegate != nil {
+		_ = reflection.Delegate.Initialize(r.agentConfig)
+	}
+	return sizing, nil
+}
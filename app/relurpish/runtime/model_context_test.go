@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+type initRecorder struct {
+	framework.Agent
+	configs []*framework.Config
+}
+
+func (a *initRecorder) Initialize(cfg *framework.Config) error {
+	a.configs = append(a.configs, cfg)
+	return nil
+}
+
+func TestUseModelResizesContext(t *testing.T) {
+	stub := ollamatest.Start(t, ollamatest.Script{Models: []string{"small", "large"}, ContextLength: 131072})
+	client := llm.NewClient(stub.URL, "small")
+	agentCfg := &framework.Config{Model: "small", ContextWindow: framework.ContextWindowConfig{OutputReserved: 2000}}
+	logger := log.New(io.Discard, "", 0)
+	sizeModelContext(context.Background(), client, agentCfg, logger)
+	require.Equal(t, framework.MaxAutoContextWindow, client.ContextLength)
+
+	agent := &initRecorder{}
+	rt := &Runtime{Config: Config{OllamaModel: "small"}, Agent: agent, Logger: logger, client: client, agentConfig: agentCfg}
+	_, err := rt.UseModel(context.Background(), "missing")
+	require.Error(t, err)
+	require.Equal(t, "small", client.Model, "unknown models are not switched to")
+
+	sizing, err := rt.UseModel(context.Background(), "large")
+	require.NoError(t, err)
+	require.Equal(t, "large", client.Model)
+	require.Equal(t, "large", rt.Config.OllamaModel)
+	require.Equal(t, 131072, agentCfg.ModelContext.ContextLength)
+	require.Equal(t, 2000, sizing.OutputReserved, "workspace overrides survive the switch")
+	require.Len(t, agent.configs, 1)
+	require.Equal(t, "large", agent.configs[0].Model)
+}
@@ -0,0 +1,205 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// ModelLifecycleConfig controls how long Ollama keeps models loaded:
+//
+//	model_lifecycle:
+//	  warm_up: true
+//	  keep_alive: 30m
+//	  unload_after: 15m
+//
+// warm_up (the default) loads the session model when the shell or server
+// starts, so the first task does not wait for a cold load. keep_alive is
+// sent with every request; Ollama unloads a model that long after its last
+// request ("-1" keeps it loaded). unload_after unloads models relurpish
+// loaded once it has not used them for that long, e.g. to free the GPU when
+// keep_alive is -1 or a routed model is rarely needed.
+type ModelLifecycleConfig struct {
+	WarmUp      *bool  `yaml:"warm_up,omitempty"`
+	KeepAlive   string `yaml:"keep_alive,omitempty"`
+	UnloadAfter string `yaml:"unload_after,omitempty"`
+}
+
+// modelLifecycle is the resolved ModelLifecycleConfig.
+type modelLifecycle struct {
+	warmUp      bool
+	keepAlive   string
+	unloadAfter time.Duration
+}
+
+// resolve validates the config; a nil config warms up and leaves unloading
+// to Ollama.
+func (c *ModelLifecycleConfig) resolve() (modelLifecycle, error) {
+	lifecycle := modelLifecycle{warmUp: true}
+	if c == nil {
+		return lifecycle, nil
+	}
+	if c.WarmUp != nil {
+		lifecycle.warmUp = *c.WarmUp
+	}
+	if c.KeepAlive != "" {
+		if _, err := strconv.Atoi(c.KeepAlive); err != nil {
+			if _, err := time.ParseDuration(c.KeepAlive); err != nil {
+				return lifecycle, fmt.Errorf("model_lifecycle.keep_alive: invalid duration %q", c.KeepAlive)
+			}
+		}
+		lifecycle.keepAlive = c.KeepAlive
+	}
+	if c.UnloadAfter != "" {
+		d, err := time.ParseDuration(c.UnloadAfter)
+		if err != nil || d <= 0 {
+			return lifecycle, fmt.Errorf("model_lifecycle.unload_after: invalid duration %q", c.UnloadAfter)
+		}
+		lifecycle.unloadAfter = d
+	}
+	return lifecycle, nil
+}
+
+// StartModelLifecycle warms the session model in the background and, with
+// unload_after, unloads idle models until ctx is cancelled or the returned
+// stop function is called. Progress is logged and broadcast to
+// SubscribeModelEvents listeners. Replays use no model, so nothing starts.
+func (r *Runtime) StartModelLifecycle(ctx context.Context) func() {
+	if r.client == nil || r.Replayer != nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	if r.lifecycle.warmUp {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.client.Warm(ctx, r.broadcastModel); err != nil && ctx.Err() == nil {
+				r.logf("warm-up of %s failed: %v", r.client.Model, err)
+			}
+		}()
+	}
+	if r.lifecycle.unloadAfter > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			interval := r.lifecycle.unloadAfter / 4
+			if interval > time.Minute {
+				interval = time.Minute
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					r.unloadIdleModels(ctx, time.Now().Add(-r.lifecycle.unloadAfter))
+				}
+			}
+		}()
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// unloadIdleModels unloads every model relurpish used but not since cutoff.
+// Models it never used are left alone; another client may own them.
+func (r *Runtime) unloadIdleModels(ctx context.Context, cutoff time.Time) {
+	seen := make(map[string]bool)
+	for _, client := range r.clients {
+		key := client.Endpoint + " " + client.Model
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		last := client.LastUsed()
+		if last.IsZero() || last.After(cutoff) {
+			continue
+		}
+		if loaded, err := client.Loaded(ctx); err != nil || !loaded {
+			continue
+		}
+		if err := client.Unload(ctx); err != nil {
+			r.logf("unload of idle %s failed: %v", client.Model, err)
+			continue
+		}
+		r.broadcastModel(llm.ModelEvent{Type: llm.ModelUnloaded, Model: client.Model, Elapsed: time.Since(last)})
+	}
+}
+
+// SubscribeModelEvents streams warm-up progress and idle unloads. Slow
+// subscribers miss events. The returned cancel function unsubscribes.
+func (r *Runtime) SubscribeModelEvents(buffer int) (<-chan llm.ModelEvent, func()) {
+	if buffer <= 0 {
+		buffer = 16
+	}
+	ch := make(chan llm.ModelEvent, buffer)
+	r.modelMu.Lock()
+	if r.modelSubs == nil {
+		r.modelSubs = make(map[int]chan llm.ModelEvent)
+	}
+	id := r.modelSeq
+	r.modelSeq++
+	r.modelSubs[id] = ch
+	r.modelMu.Unlock()
+	return ch, func() {
+		r.modelMu.Lock()
+		sub, ok := r.modelSubs[id]
+		delete(r.modelSubs, id)
+		r.modelMu.Unlock()
+		if ok {
+			close(sub)
+		}
+	}
+}
+
+// ModelStatus returns the latest model event, so late subscribers can show
+// a warm-up that started before they subscribed.
+func (r *Runtime) ModelStatus() (llm.ModelEvent, bool) {
+	r.modelMu.Lock()
+	defer r.modelMu.Unlock()
+	return r.modelStatus, r.modelStatus.Type != ""
+}
+
+func (r *Runtime) broadcastModel(event llm.ModelEvent) {
+	if event.Type != llm.ModelLoading {
+		r.logf("%s", FormatModelEvent(event))
+	}
+	r.modelMu.Lock()
+	defer r.modelMu.Unlock()
+	r.modelStatus = event
+	for _, ch := range r.modelSubs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// FormatModelEvent renders the one-line message shown by `serve` and logged.
+func FormatModelEvent(event llm.ModelEvent) string {
+	switch event.Type {
+	case llm.ModelLoading:
+		if event.Elapsed == 0 {
+			return fmt.Sprintf("model: loading %s", event.Model)
+		}
+		return fmt.Sprintf("model: loading %s (%s)", event.Model, event.Elapsed.Round(time.Second))
+	case llm.ModelReady:
+		if event.Elapsed == 0 {
+			return fmt.Sprintf("model: %s already loaded", event.Model)
+		}
+		return fmt.Sprintf("model: %s loaded in %s", event.Model, event.Elapsed.Round(100*time.Millisecond))
+	case llm.ModelLoadFailed:
+		return fmt.Sprintf("model: loading %s failed: %v", event.Model, event.Err)
+	case llm.ModelUnloaded:
+		return fmt.Sprintf("model: unloaded %s after %s idle", event.Model, event.Elapsed.Round(time.Minute))
+	}
+	return fmt.Sprintf("model: %s %s", event.Model, event.Type)
+}
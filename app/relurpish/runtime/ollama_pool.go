@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// defaultPoolHealthInterval paces the pool's health checks when
+// health_interval is unset.
+const defaultPoolHealthInterval = 15 * time.Second
+
+// OllamaPoolConfig spreads model requests over more Ollama endpoints than
+// the primary one, such as a LAN GPU box next to a laptop:
+//
+//	ollama_pool:
+//	  endpoints: [http://gpu-box:11434]
+//	  health_interval: 15s
+//
+// The primary endpoint is always a member and wins ties. Each endpoint must
+// have the session's models pulled. Requests go to the least busy healthy
+// endpoint and move to another when one stops answering; see llm.Pool.
+type OllamaPoolConfig struct {
+	Endpoints      []string `yaml:"endpoints"`
+	HealthInterval string   `yaml:"health_interval,omitempty"`
+}
+
+// extraEndpoints returns the configured endpoints other than primary,
+// without duplicates.
+func (c *OllamaPoolConfig) extraEndpoints(primary string) []string {
+	if c == nil {
+		return nil
+	}
+	seen := map[string]bool{strings.TrimRight(primary, "/"): true}
+	var out []string
+	for _, endpoint := range c.Endpoints {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if endpoint == "" || seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		out = append(out, endpoint)
+	}
+	return out
+}
+
+func (c *OllamaPoolConfig) healthInterval() (time.Duration, error) {
+	if c == nil || c.HealthInterval == "" {
+		return defaultPoolHealthInterval, nil
+	}
+	interval, err := time.ParseDuration(c.HealthInterval)
+	if err != nil {
+		return 0, fmt.Errorf("ollama_pool.health_interval: %w", err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("ollama_pool.health_interval must be positive")
+	}
+	return interval, nil
+}
+
+// ollamaPools builds a pool around each client on the primary endpoint.
+// A nil *ollamaPools pools nothing.
+type ollamaPools struct {
+	config   *OllamaPoolConfig
+	primary  string
+	throttle *LLMThrottleConfig
+	logger   *log.Logger
+	interval time.Duration
+	pools    []*llm.Pool
+	members  []*llm.Client
+}
+
+// wrap returns client pooled with the extra endpoints, or client itself
+// when there are none or it serves another endpoint.
+func (p *ollamaPools) wrap(client *llm.Client) framework.LanguageModel {
+	if p == nil || strings.TrimRight(client.Endpoint, "/") != strings.TrimRight(p.primary, "/") {
+		return client
+	}
+	endpoints := p.config.extraEndpoints(p.primary)
+	if len(endpoints) == 0 {
+		return client
+	}
+	others := make([]*llm.Client, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		member := llm.NewClient(endpoint, client.Model)
+		member.Debug = client.Debug
+		member.Throttle = p.throttle.Throttle(endpoint)
+		others = append(others, member)
+	}
+	pool := llm.NewPool(client, others...)
+	pool.Logger = p.logger
+	p.pools = append(p.pools, pool)
+	p.members = append(p.members, others...)
+	return pool
+}
+
+// clients returns the clients the pools added, for the model lifecycle.
+func (p *ollamaPools) clients() []*llm.Client {
+	if p == nil {
+		return nil
+	}
+	return p.members
+}
+
+// watch health-checks every pool until ctx ends.
+func (p *ollamaPools) watch(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	for _, pool := range p.pools {
+		go pool.Watch(ctx, p.interval)
+	}
+}
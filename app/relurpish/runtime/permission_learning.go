@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// LearnedPermissionsDir holds the permission logs written by
+// --learn-permissions, one <task-id>.jsonl per task.
+func LearnedPermissionsDir(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "learned_permissions")
+}
+
+// SuggestManifest renders the manifest at manifestPath with its permissions
+// replaced by exactly those task taskID used while learning.
+func SuggestManifest(workspace, manifestPath, taskID string) ([]byte, error) {
+	uses, err := framework.ReadPermissionUses(LearnedPermissionsDir(workspace), taskID)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no learned permissions for task %s; run it with --learn-permissions first", taskID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	base, err := framework.LoadAgentManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(framework.SuggestManifest(base, workspace, uses))
+}
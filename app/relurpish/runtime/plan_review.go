@@ -0,0 +1,132 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// PlanDir holds the plan files written for review.
+func PlanDir(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "plans")
+}
+
+// PlanFileReviewer writes each plan to Dir/plan-<task>.json, shows it, and
+// waits for the person at In/Out to approve, edit, or reject it. The
+// approved file is read back, so hand edits change what executes. With
+// AutoApprove the file is still written but not waited on.
+type PlanFileReviewer struct {
+	Dir         string
+	AutoApprove bool
+	// Editor opens the plan file on "e"; it defaults to $VISUAL, then
+	// $EDITOR.
+	Editor string
+	In     io.Reader
+	Out    io.Writer
+}
+
+var planFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// PlanPath returns the plan file for task.
+func (r *PlanFileReviewer) PlanPath(task *framework.Task) string {
+	id := "plan"
+	if task != nil && task.ID != "" {
+		id = planFileUnsafe.ReplaceAllString(task.ID, "_")
+	}
+	return filepath.Join(r.Dir, "plan-"+id+".json")
+}
+
+// ReviewPlan implements framework.PlanReviewer.
+func (r *PlanFileReviewer) ReviewPlan(ctx context.Context, task *framework.Task, plan framework.Plan) (framework.Plan, error) {
+	path := r.PlanPath(task)
+	if err := framework.WritePlanFile(path, plan); err != nil {
+		return plan, fmt.Errorf("write plan: %w", err)
+	}
+	out := r.Out
+	if out == nil {
+		out = io.Discard
+	}
+	writePlanSummary(out, plan)
+	fmt.Fprintf(out, "Plan saved to %s\n", path)
+	if r.AutoApprove {
+		return plan, nil
+	}
+	in := bufio.NewReader(r.In)
+	for {
+		fmt.Fprint(out, "Execute this plan? [y]es, [e]dit, [n]o: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return plan, fmt.Errorf("%w: no answer (%v)", framework.ErrPlanRejected, err)
+		}
+		if ctx.Err() != nil {
+			return plan, ctx.Err()
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			edited, err := framework.ReadPlanFile(path)
+			if err != nil {
+				fmt.Fprintf(out, "Cannot read %s: %v\n", path, err)
+				continue
+			}
+			return edited, nil
+		case "e", "edit":
+			if err := r.edit(ctx, path); err != nil {
+				fmt.Fprintf(out, "Editor failed: %v; edit %s by hand and answer y when done\n", err, path)
+				continue
+			}
+			if edited, err := framework.ReadPlanFile(path); err == nil {
+				writePlanSummary(out, edited)
+			} else {
+				fmt.Fprintf(out, "Edited plan is invalid: %v\n", err)
+			}
+		case "n", "no":
+			return plan, framework.ErrPlanRejected
+		}
+	}
+}
+
+func (r *PlanFileReviewer) edit(ctx context.Context, path string) error {
+	return openEditor(ctx, r.Editor, path)
+}
+
+// openEditor runs editor, or $VISUAL, then $EDITOR, on path in the
+// terminal and waits for it to exit.
+func openEditor(ctx context.Context, editor, path string) error {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor == "" {
+			editor = os.Getenv(env)
+		}
+	}
+	if editor == "" {
+		return fmt.Errorf("no editor set ($VISUAL, $EDITOR)")
+	}
+	fields := strings.Fields(editor)
+	cmd := exec.CommandContext(ctx, fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// writePlanSummary prints one line per step.
+func writePlanSummary(out io.Writer, plan framework.Plan) {
+	if plan.Goal != "" {
+		fmt.Fprintf(out, "Plan: %s\n", plan.Goal)
+	}
+	for _, step := range plan.Steps {
+		line := fmt.Sprintf("  %d. %s", step.ID, step.Description)
+		if step.Tool != "" {
+			line += fmt.Sprintf(" [%s]", step.Tool)
+		}
+		if deps := plan.Dependencies[step.ID]; len(deps) > 0 {
+			line += fmt.Sprintf(" (after %s)", strings.Trim(fmt.Sprint(deps), "[]"))
+		}
+		fmt.Fprintln(out, line)
+	}
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+var reviewPlan = framework.Plan{
+	Goal: "Add caching",
+	Steps: []framework.PlanStep{
+		{ID: 1, Description: "Add cache type", Tool: "file_write"},
+		{ID: 2, Description: "Wire cache into handler"},
+	},
+	Dependencies: map[int][]int{2: {1}},
+}
+
+// TestPlanFileReviewerUsesEditedFile executes the plan as saved on disk when
+// approved, not as generated.
+func TestPlanFileReviewerUsesEditedFile(t *testing.T) {
+	var out bytes.Buffer
+	reviewer := &PlanFileReviewer{Dir: t.TempDir(), In: strings.NewReader("maybe\ny\n"), Out: &out}
+	task := &framework.Task{ID: "task/42"}
+	path := reviewer.PlanPath(task)
+	require.Equal(t, "plan-task_42.json", path[len(reviewer.Dir)+1:])
+
+	// Simulate the person editing the file while the prompt waits.
+	reviewer.In = &editOnRead{path: path, reader: reviewer.In, t: t}
+	plan, err := reviewer.ReviewPlan(context.Background(), task, reviewPlan)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 1)
+	require.Equal(t, "Wire cache into handler", plan.Steps[0].Description)
+	require.Contains(t, out.String(), "  2. Wire cache into handler (after 1)")
+}
+
+// TestPlanFileReviewerRejects returns ErrPlanRejected on "n" and on EOF.
+func TestPlanFileReviewerRejects(t *testing.T) {
+	for _, input := range []string{"n\n", ""} {
+		reviewer := &PlanFileReviewer{Dir: t.TempDir(), In: strings.NewReader(input)}
+		_, err := reviewer.ReviewPlan(context.Background(), &framework.Task{ID: "t"}, reviewPlan)
+		require.ErrorIs(t, err, framework.ErrPlanRejected)
+	}
+}
+
+// TestPlanFileReviewerAutoApprove writes the file without prompting.
+func TestPlanFileReviewerAutoApprove(t *testing.T) {
+	reviewer := &PlanFileReviewer{Dir: t.TempDir(), AutoApprove: true}
+	plan, err := reviewer.ReviewPlan(context.Background(), &framework.Task{ID: "t"}, reviewPlan)
+	require.NoError(t, err)
+	require.Equal(t, reviewPlan, plan)
+	saved, err := framework.ReadPlanFile(reviewer.PlanPath(&framework.Task{ID: "t"}))
+	require.NoError(t, err)
+	require.Equal(t, reviewPlan, saved)
+}
+
+// editOnRead drops the first plan step from the file on the first read.
+type editOnRead struct {
+	path   string
+	reader io.Reader
+	t      *testing.T
+	edited bool
+}
+
+func (e *editOnRead) Read(p []byte) (int, error) {
+	if !e.edited {
+		e.edited = true
+		plan, err := framework.ReadPlanFile(e.path)
+		require.NoError(e.t, err)
+		plan.Steps = plan.Steps[1:]
+		plan.Dependencies = nil
+		require.NoError(e.t, framework.WritePlanFile(e.path, plan))
+	}
+	return e.reader.Read(p)
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"log"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// registerPlugins loads the tools advertised by each manifest plugin. A plugin
+// that fails to describe itself or collides with an existing tool is logged
+// and skipped so one broken integration does not block startup. When cache is
+// set, describe only runs for plugins whose binary or spec changed.
+func registerPlugins(ctx context.Context, registry *framework.ToolRegistry, spec *framework.AgentRuntimeSpec, workspace string, runner framework.CommandRunner, cache *StartupCache, logger *log.Logger) {
+	if spec == nil {
+		return
+	}
+	for _, plugin := range spec.Plugins {
+		loaded, err := loadPluginTools(ctx, plugin, workspace, runner, cache)
+		if err != nil {
+			logger.Printf("warning: plugin %s unavailable: %v", plugin.Name, err)
+			continue
+		}
+		for _, tool := range loaded {
+			if err := registry.Register(tool); err != nil {
+				logger.Printf("warning: plugin %s: %v", plugin.Name, err)
+			}
+		}
+	}
+}
+
+// loadPluginTools describes plugin, consulting cache first.
+func loadPluginTools(ctx context.Context, plugin framework.AgentPluginSpec, workspace string, runner framework.CommandRunner, cache *StartupCache) ([]framework.Tool, error) {
+	if cache == nil {
+		return tools.LoadPluginTools(ctx, plugin, workspace, runner)
+	}
+	descriptors, ok := cache.LookupPlugin(plugin)
+	if !ok {
+		var err error
+		descriptors, err = tools.DescribePlugin(ctx, plugin, workspace, runner)
+		if err != nil {
+			return nil, err
+		}
+		cache.StorePlugin(plugin, descriptors)
+	}
+	return tools.NewPluginTools(plugin, workspace, runner, descriptors)
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// PolicyConfig points permission decisions at an Open Policy Agent server in
+// config.yaml:
+//
+//	policy:
+//	  opa_url: http://127.0.0.1:8181
+//	  path: relurpify/authz/decision
+//	  timeout: 2s
+type PolicyConfig struct {
+	OPAURL   string `yaml:"opa_url"`
+	Path     string `yaml:"path"`
+	Timeout  string `yaml:"timeout,omitempty"`
+	FailOpen bool   `yaml:"fail_open,omitempty"`
+}
+
+// buildPolicyEvaluator returns nil when no policy is configured.
+func buildPolicyEvaluator(cfg *PolicyConfig) (framework.PolicyEvaluator, error) {
+	if cfg == nil || cfg.OPAURL == "" {
+		return nil, nil
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("policy: path required")
+	}
+	timeout := 5 * time.Second
+	if cfg.Timeout != "" {
+		parsed, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("policy: invalid timeout: %w", err)
+		}
+		timeout = parsed
+	}
+	return &framework.OPAPolicyEvaluator{
+		URL:      cfg.OPAURL,
+		Path:     cfg.Path,
+		Client:   &http.Client{Timeout: timeout},
+		FailOpen: cfg.FailOpen,
+	}, nil
+}
@@ -64,13 +64,14 @@ type StatusSnapshot struct {
 // ProbeEnvironment inspects sandbox binaries, Ollama availability, and the
 // manifest so the wizard can display actionable suggestions.
 func ProbeEnvironment(ctx context.Context, cfg Config) EnvironmentReport {
-	sandbox := detectSandbox(ctx, cfg)
-	ollama := detectOllama(ctx, cfg)
-	manifest := summarizeManifest(cfg.ManifestPath)
 	var workspaceCfg WorkspaceConfig
-	if wcfg, err := LoadWorkspaceConfig(cfg.ConfigPath); err == nil {
+	if wcfg, err := LoadWorkspaceProfile(cfg); err == nil {
 		workspaceCfg = wcfg
+		cfg.ApplyProfileEndpoint(wcfg)
 	}
+	sandbox := detectSandbox(ctx, cfg)
+	ollama := detectOllama(ctx, cfg)
+	manifest := summarizeManifest(cfg.ManifestPath)
 	return EnvironmentReport{
 		Workspace: cfg.Workspace,
 		Sandbox:   sandbox,
@@ -0,0 +1,147 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ProfileEnv selects a config profile when --profile is not passed.
+const ProfileEnv = "RELURPIFY_PROFILE"
+
+// DefaultOllamaEndpoint is used when neither the flag nor the active profile
+// names an endpoint.
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// ProfileConfig is a named overlay on config.yaml for one machine or model:
+//
+//	endpoint: http://localhost:11434
+//	profile: home            # used when neither --profile nor RELURPIFY_PROFILE is set
+//	profiles:
+//	  home:
+//	    model: qwen2.5-coder:7b
+//	  work:
+//	    endpoint: http://gpu-box:11434
+//	    model: qwen2.5-coder:32b
+//	    languages: [go, typescript]
+//	  ci:
+//	    extends: work
+//	    allowed_tools: [file_read, search_grep, exec_run_tests]
+//
+// Set fields replace the ones a profile extends, and the top-level fields of
+// config.yaml are the base every profile starts from. Languages limits the
+// language servers started to those languages.
+type ProfileConfig struct {
+	Extends      string   `yaml:"extends,omitempty"`
+	Endpoint     string   `yaml:"endpoint,omitempty"`
+	Model        string   `yaml:"model,omitempty"`
+	Languages    []string `yaml:"languages,omitempty"`
+	AllowedTools []string `yaml:"allowed_tools,omitempty"`
+}
+
+// WithProfile returns the config with the named profile, or the config's own
+// default profile when name is empty, applied over its top-level fields.
+func (c WorkspaceConfig) WithProfile(name string) (WorkspaceConfig, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return c, nil
+	}
+	var chain []ProfileConfig
+	seen := map[string]bool{}
+	for next := name; next != ""; {
+		if seen[next] {
+			return c, fmt.Errorf("profile %s: extends cycle through %s", name, next)
+		}
+		seen[next] = true
+		profile, ok := c.Profiles[next]
+		if !ok {
+			return c, fmt.Errorf("profile %s not defined in config.yaml", next)
+		}
+		chain = append(chain, profile)
+		next = profile.Extends
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		profile := chain[i]
+		if profile.Endpoint != "" {
+			c.Endpoint = profile.Endpoint
+		}
+		if profile.Model != "" {
+			c.Model = profile.Model
+		}
+		if len(profile.Languages) > 0 {
+			c.Languages = profile.Languages
+		}
+		if len(profile.AllowedTools) > 0 {
+			c.AllowedTools = profile.AllowedTools
+		}
+	}
+	c.Profile = name
+	return c, nil
+}
+
+// LoadWorkspaceProfile loads config.yaml with cfg's profile applied. A
+// missing file is an empty config, unless a profile was asked for.
+func LoadWorkspaceProfile(cfg Config) (WorkspaceConfig, error) {
+	ws, err := LoadWorkspaceConfig(cfg.ConfigPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return WorkspaceConfig{}, err
+	}
+	return ws.WithProfile(cfg.Profile)
+}
+
+// ApplyProfileEndpoint uses the endpoint of ws unless --ollama-endpoint
+// named another one.
+func (c *Config) ApplyProfileEndpoint(ws WorkspaceConfig) {
+	if ws.Endpoint != "" && (c.OllamaEndpoint == "" || c.OllamaEndpoint == DefaultOllamaEndpoint) {
+		c.OllamaEndpoint = ws.Endpoint
+	}
+}
+
+// filterLSPLanguages drops the language servers outside languages; an empty
+// list keeps them all.
+func filterLSPLanguages(spec framework.AgentLSPSpec, languages []string) framework.AgentLSPSpec {
+	if len(languages) == 0 {
+		return spec
+	}
+	servers := make(map[string]string, len(spec.Servers))
+	for language, server := range spec.Servers {
+		for _, keep := range languages {
+			if strings.EqualFold(language, keep) {
+				servers[language] = server
+				break
+			}
+		}
+	}
+	spec.Servers = servers
+	return spec
+}
+
+// mergeWizardSelection keeps the rest of existing when the wizard saves its
+// selection. Under a profile, the model and tools go to that profile so the
+// other profiles and the base stay as they were.
+func mergeWizardSelection(existing, selection WorkspaceConfig, profile string) WorkspaceConfig {
+	merged := existing
+	merged.Agents = selection.Agents
+	merged.PermissionProfile = selection.PermissionProfile
+	merged.LastUpdated = selection.LastUpdated
+	if profile == "" {
+		merged.Model = selection.Model
+		merged.AllowedTools = selection.AllowedTools
+		return merged
+	}
+	profiles := make(map[string]ProfileConfig, len(existing.Profiles)+1)
+	for name, p := range existing.Profiles {
+		profiles[name] = p
+	}
+	p := profiles[profile]
+	p.Model = selection.Model
+	p.AllowedTools = selection.AllowedTools
+	profiles[profile] = p
+	merged.Profiles = profiles
+	return merged
+}
@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestWorkspaceConfigWithProfile(t *testing.T) {
+	ws := WorkspaceConfig{
+		Endpoint:     "http://localhost:11434",
+		Model:        "qwen2.5-coder:7b",
+		AllowedTools: []string{"file_read"},
+		Profile:      "home",
+		Profiles: map[string]ProfileConfig{
+			"home": {},
+			"work": {Endpoint: "http://gpu-box:11434", Model: "qwen2.5-coder:32b", Languages: []string{"go"}},
+			"ci":   {Extends: "work", AllowedTools: []string{"file_read", "search_grep"}},
+			"loop": {Extends: "loop"},
+		},
+	}
+
+	home, err := ws.WithProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "home", home.Profile)
+	assert.Equal(t, "qwen2.5-coder:7b", home.Model, "an empty profile keeps the base")
+
+	ci, err := ws.WithProfile("ci")
+	require.NoError(t, err)
+	assert.Equal(t, "http://gpu-box:11434", ci.Endpoint)
+	assert.Equal(t, "qwen2.5-coder:32b", ci.Model)
+	assert.Equal(t, []string{"go"}, ci.Languages)
+	assert.Equal(t, []string{"file_read", "search_grep"}, ci.AllowedTools)
+
+	_, err = ws.WithProfile("laptop")
+	assert.ErrorContains(t, err, "not defined")
+	_, err = ws.WithProfile("loop")
+	assert.ErrorContains(t, err, "cycle")
+}
+
+func TestConfigProfileEndpointYieldsToFlag(t *testing.T) {
+	ws := WorkspaceConfig{Endpoint: "http://gpu-box:11434"}
+	cfg := Config{OllamaEndpoint: DefaultOllamaEndpoint}
+	cfg.ApplyProfileEndpoint(ws)
+	assert.Equal(t, "http://gpu-box:11434", cfg.OllamaEndpoint)
+
+	cfg.OllamaEndpoint = "http://other:11434"
+	cfg.ApplyProfileEndpoint(ws)
+	assert.Equal(t, "http://other:11434", cfg.OllamaEndpoint)
+}
+
+func TestFilterLSPLanguages(t *testing.T) {
+	spec := framework.AgentLSPSpec{Enabled: true, Servers: map[string]string{"go": "gopls", "python": "pyright"}}
+	filtered := filterLSPLanguages(spec, []string{"Go"})
+	assert.Equal(t, map[string]string{"go": "gopls"}, filtered.Servers)
+	assert.Len(t, filterLSPLanguages(spec, nil).Servers, 2)
+}
+
+func TestSaveManifestWritesToActiveProfile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Workspace = dir
+	cfg.ManifestPath = filepath.Join(dir, "agent.manifest.yaml")
+	cfg.ConfigPath = filepath.Join(dir, "relurpify_cfg", "config.yaml")
+	cfg.Profile = "work"
+	require.NoError(t, SaveWorkspaceConfig(cfg.ConfigPath, WorkspaceConfig{
+		Model:    "base-model",
+		Profiles: map[string]ProfileConfig{"home": {Model: "small"}},
+		LLMCache: &LLMCacheConfig{Enabled: true},
+	}))
+
+	_, err := SaveManifest(context.Background(), cfg, WizardSelection{Model: "big", Agents: []string{"coding"}, Tools: []string{"file_read"}})
+	require.NoError(t, err)
+	ws, err := LoadWorkspaceConfig(cfg.ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, "base-model", ws.Model)
+	assert.Equal(t, "small", ws.Profiles["home"].Model)
+	assert.Equal(t, "big", ws.Profiles["work"].Model)
+	assert.Equal(t, []string{"file_read"}, ws.Profiles["work"].AllowedTools)
+	assert.NotNil(t, ws.LLMCache, "settings outside the wizard survive")
+}
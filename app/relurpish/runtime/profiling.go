@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// StartProfiler serves the net/http/pprof endpoints on addr. It uses a
+// private mux so the handlers never leak onto the API server, and returns the
+// bound address so callers can pass ":0".
+func StartProfiler(ctx context.Context, addr string) (string, func(context.Context) error, error) {
+	if addr == "" {
+		return "", nil, errors.New("pprof address required")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", nil, fmt.Errorf("pprof listen: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	stop := func(shutdownCtx context.Context) error {
+		return srv.Shutdown(shutdownCtx)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = stop(shutdownCtx)
+	}()
+	return listener.Addr().String(), stop, nil
+}
+
+// ProfileCapture describes the files written by CaptureProfile.
+type ProfileCapture struct {
+	CPUPath  string
+	HeapPath string
+	Duration time.Duration
+}
+
+// CaptureProfile records a CPU profile while fn runs and, when heap is set,
+// a heap profile once it returns. Files land in dir with a shared timestamp
+// so one capture's profiles sort together. fn's error is returned alongside
+// the capture so a failing task still yields its profile.
+func CaptureProfile(dir string, heap bool, fn func() error) (*ProfileCapture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	capture := &ProfileCapture{CPUPath: filepath.Join(dir, "cpu-"+stamp+".pprof")}
+	cpuFile, err := os.Create(capture.CPUPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cpuFile.Close()
+	if err := runtimepprof.StartCPUProfile(cpuFile); err != nil {
+		return nil, fmt.Errorf("start cpu profile: %w", err)
+	}
+	started := time.Now()
+	runErr := fn()
+	runtimepprof.StopCPUProfile()
+	capture.Duration = time.Since(started)
+	if heap {
+		capture.HeapPath = filepath.Join(dir, "heap-"+stamp+".pprof")
+		if err := writeHeapProfile(capture.HeapPath); err != nil {
+			return capture, errors.Join(runErr, fmt.Errorf("heap profile: %w", err))
+		}
+	}
+	return capture, runErr
+}
+
+// writeHeapProfile forces a GC first so the profile reflects live objects.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	goruntime.GC()
+	return runtimepprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProfilerServesIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, stop, err := StartProfiler(ctx, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer stop(context.Background())
+
+	resp, err := http.Get("http://" + addr + "/debug/pprof/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + addr + "/api/tasks")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "only pprof routes are exposed")
+}
+
+func TestCaptureProfileWritesFiles(t *testing.T) {
+	dir := t.TempDir()
+	taskErr := errors.New("task failed")
+	capture, err := CaptureProfile(dir, true, func() error { return taskErr })
+	require.ErrorIs(t, err, taskErr, "task errors are surfaced with the capture")
+	require.NotNil(t, capture)
+	for _, path := range []string{capture.CPUPath, capture.HeapPath} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NotZero(t, info.Size())
+	}
+}
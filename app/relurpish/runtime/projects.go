@@ -0,0 +1,215 @@
+package runtime
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// projectMarkers are the files that root a project, mapped to its language.
+var projectMarkers = []struct{ file, language string }{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"package.json", "node"},
+	{"pyproject.toml", "python"},
+}
+
+// maxProjectDepth bounds how deep DetectProjects looks below the workspace.
+const maxProjectDepth = 6
+
+// projectSkipDirs are never searched for projects: dependencies, build
+// output, and relurpify's own state.
+var projectSkipDirs = map[string]bool{
+	"node_modules":  true,
+	"vendor":        true,
+	"target":        true,
+	"dist":          true,
+	"build":         true,
+	"relurpify_cfg": true,
+	"testdata":      true,
+}
+
+// ProjectConfig describes one project in a monorepo workspace. Detected
+// projects fill Path and Languages; config.yaml entries with the same path
+// add or override settings:
+//
+//	projects:
+//	  - path: services/api
+//	    lint: [golangci-lint, run, --fast]
+//	  - path: web
+//	    build: [npm, run, build:prod]
+//	    lsp:
+//	      enabled: true
+//	      servers: {typescript: typescript-language-server}
+//
+// Build and Lint default to the project's language toolchain.
+type ProjectConfig struct {
+	// Path is relative to the workspace; "." is the workspace root.
+	Path      string                  `yaml:"path"`
+	Languages []string                `yaml:"languages,omitempty"`
+	Build     []string                `yaml:"build,omitempty"`
+	Lint      []string                `yaml:"lint,omitempty"`
+	LSP       *framework.AgentLSPSpec `yaml:"lsp,omitempty"`
+}
+
+// projectCommands are the default build and lint commands per language.
+var projectCommands = map[string]struct{ build, lint []string }{
+	"go":     {[]string{"go", "build", "./..."}, []string{"golangci-lint", "run"}},
+	"rust":   {[]string{"cargo", "build"}, []string{"cargo", "clippy"}},
+	"node":   {[]string{"npm", "run", "build"}, []string{"npm", "run", "lint"}},
+	"python": {[]string{"python", "-m", "compileall", "-q", "."}, []string{"ruff", "check", "."}},
+}
+
+// BuildCommand returns the configured build command or the language default.
+func (p ProjectConfig) BuildCommand() []string {
+	if len(p.Build) > 0 {
+		return p.Build
+	}
+	for _, language := range p.Languages {
+		if cmds, ok := projectCommands[language]; ok {
+			return cmds.build
+		}
+	}
+	return projectCommands["go"].build
+}
+
+// LintCommand returns the configured lint command or the language default.
+func (p ProjectConfig) LintCommand() []string {
+	if len(p.Lint) > 0 {
+		return p.Lint
+	}
+	for _, language := range p.Languages {
+		if cmds, ok := projectCommands[language]; ok {
+			return cmds.lint
+		}
+	}
+	return projectCommands["go"].lint
+}
+
+// Dir returns the project's absolute directory.
+func (p ProjectConfig) Dir(workspace string) string {
+	return filepath.Join(workspace, filepath.FromSlash(p.Path))
+}
+
+// DetectProjects finds every directory under workspace holding a go.mod,
+// Cargo.toml, package.json, or pyproject.toml. Hidden directories and
+// dependency or build output directories are skipped. Projects are sorted by
+// path.
+func DetectProjects(workspace string) ([]ProjectConfig, error) {
+	var projects []ProjectConfig
+	err := filepath.WalkDir(workspace, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == workspace {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(workspace, path)
+		rel = filepath.ToSlash(rel)
+		if rel != "." {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || projectSkipDirs[name] || strings.Count(rel, "/") >= maxProjectDepth {
+				return filepath.SkipDir
+			}
+		}
+		var languages []string
+		for _, marker := range projectMarkers {
+			if _, err := os.Stat(filepath.Join(path, marker.file)); err == nil {
+				languages = append(languages, marker.language)
+			}
+		}
+		if len(languages) > 0 {
+			projects = append(projects, ProjectConfig{Path: rel, Languages: languages})
+		}
+		return nil
+	})
+	return projects, err
+}
+
+// ResolveProjects merges detected projects with the config.yaml entries.
+// Configured projects need not have a marker file.
+func ResolveProjects(workspace string, configured []ProjectConfig) ([]ProjectConfig, error) {
+	projects, err := DetectProjects(workspace)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(projects))
+	for i, project := range projects {
+		index[project.Path] = i
+	}
+	for _, pc := range configured {
+		path := filepath.ToSlash(filepath.Clean(pc.Path))
+		if pc.Path == "" || filepath.IsAbs(pc.Path) || strings.HasPrefix(path, "../") || path == ".." {
+			return nil, fmt.Errorf("project path %q must be relative to the workspace", pc.Path)
+		}
+		pc.Path = path
+		i, ok := index[path]
+		if !ok {
+			index[path] = len(projects)
+			projects = append(projects, pc)
+			continue
+		}
+		merged := projects[i]
+		if len(pc.Languages) > 0 {
+			merged.Languages = pc.Languages
+		}
+		if len(pc.Build) > 0 {
+			merged.Build = pc.Build
+		}
+		if len(pc.Lint) > 0 {
+			merged.Lint = pc.Lint
+		}
+		if pc.LSP != nil {
+			merged.LSP = pc.LSP
+		}
+		projects[i] = merged
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Path < projects[j].Path })
+	return projects, nil
+}
+
+// ProjectFor returns the innermost project containing target, a path
+// relative to the workspace.
+func ProjectFor(projects []ProjectConfig, target string) (ProjectConfig, bool) {
+	target = filepath.ToSlash(filepath.Clean(target))
+	var best ProjectConfig
+	found := false
+	for _, project := range projects {
+		if project.Path != "." && target != project.Path && !strings.HasPrefix(target, project.Path+"/") {
+			continue
+		}
+		if !found || len(project.Path) > len(best.Path) || best.Path == "." {
+			best, found = project, true
+		}
+	}
+	return best, found
+}
+
+// resolveProject finds the project containing cfg.Project. It returns nil
+// when no target is set.
+func resolveProject(cfg Config, ws WorkspaceConfig) (*ProjectConfig, error) {
+	if cfg.Project == "" {
+		return nil, nil
+	}
+	rel, err := filepath.Rel(cfg.Workspace, cfg.Project)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("project target %s is outside the workspace", cfg.Project)
+	}
+	projects, err := ResolveProjects(cfg.Workspace, ws.Projects)
+	if err != nil {
+		return nil, err
+	}
+	project, ok := ProjectFor(projects, rel)
+	if !ok {
+		return nil, fmt.Errorf("no project contains %s; add one under projects in config.yaml", rel)
+	}
+	return &project, nil
+}
@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/tools"
+)
+
+func writeProjectFixture(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+}
+
+// TestDetectProjectsSkipsDependencies finds nested projects but ignores
+// dependency and hidden directories.
+func TestDetectProjectsSkipsDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFixture(t, dir,
+		"go.mod",
+		"services/api/go.mod",
+		"crates/parser/Cargo.toml",
+		"web/package.json",
+		"web/node_modules/left-pad/package.json",
+		".cache/tool/pyproject.toml",
+	)
+	projects, err := DetectProjects(dir)
+	require.NoError(t, err)
+	var paths []string
+	for _, project := range projects {
+		paths = append(paths, project.Path)
+	}
+	require.Equal(t, []string{".", "crates/parser", "services/api", "web"}, paths)
+	require.Equal(t, []string{"rust"}, projects[1].Languages)
+	require.Equal(t, []string{"cargo", "clippy"}, projects[1].LintCommand())
+}
+
+// TestResolveProjectsMergesConfig overrides detected projects by path and
+// adds configured ones without marker files.
+func TestResolveProjectsMergesConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFixture(t, dir, "web/package.json")
+	projects, err := ResolveProjects(dir, []ProjectConfig{
+		{Path: "./web", Build: []string{"npm", "run", "build:prod"}},
+		{Path: "scripts", Languages: []string{"python"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	require.Equal(t, "scripts", projects[0].Path)
+	require.Equal(t, []string{"ruff", "check", "."}, projects[0].LintCommand())
+	require.Equal(t, "web", projects[1].Path)
+	require.Equal(t, []string{"node"}, projects[1].Languages)
+	require.Equal(t, []string{"npm", "run", "build:prod"}, projects[1].BuildCommand())
+
+	_, err = ResolveProjects(dir, []ProjectConfig{{Path: "../elsewhere"}})
+	require.Error(t, err)
+}
+
+// TestProjectForPicksInnermost prefers the deepest project containing the
+// target and falls back to the workspace root.
+func TestProjectForPicksInnermost(t *testing.T) {
+	projects := []ProjectConfig{{Path: "."}, {Path: "services"}, {Path: "services/api"}, {Path: "services/api-gateway"}}
+
+	project, ok := ProjectFor(projects, "services/api/handlers/user.go")
+	require.True(t, ok)
+	require.Equal(t, "services/api", project.Path)
+
+	project, ok = ProjectFor(projects, "docs/index.md")
+	require.True(t, ok)
+	require.Equal(t, ".", project.Path)
+
+	_, ok = ProjectFor(projects[1:], "docs/index.md")
+	require.False(t, ok)
+}
+
+// TestCommandToolsScopedToProject runs build and lint in the project
+// directory while git stays at the workspace root.
+func TestCommandToolsScopedToProject(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFixture(t, dir, "go.mod", "crates/parser/Cargo.toml")
+	cfg := Config{Workspace: dir, Project: filepath.Join(dir, "crates", "parser", "src", "lib.rs")}
+	project, err := resolveProject(cfg, WorkspaceConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "crates/parser", project.Path)
+
+	projectDir := filepath.Join(dir, "crates", "parser")
+	for _, tool := range commandTools(dir, project, nil) {
+		switch tool := tool.(type) {
+		case *tools.RunBuildTool:
+			require.Equal(t, projectDir, tool.Workdir)
+			require.Equal(t, []string{"cargo", "build"}, tool.Command)
+		case *tools.RunLinterTool:
+			require.Equal(t, projectDir, tool.Workdir)
+		case *tools.GitCommandTool:
+			require.Equal(t, dir, tool.RepoPath)
+		}
+	}
+
+	cfg.Project = filepath.Join(filepath.Dir(dir), "outside")
+	_, err = resolveProject(cfg, WorkspaceConfig{})
+	require.Error(t, err)
+}
@@ -0,0 +1,349 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// RecipeDir holds the workspace's task recipes, one YAML file each.
+func RecipeDir(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "recipes")
+}
+
+// Recipe is a reviewed template for a recurring task, run with
+// `relurpish recipe run <name> --param key=value`:
+//
+//	name: add-endpoint
+//	description: Add a CRUD endpoint backed by the store
+//	type: code_modification
+//	params:
+//	  - name: name
+//	    required: true
+//	  - name: package
+//	    default: api
+//	instruction: |
+//	  Add list, get, create, update, and delete handlers for {{name}}
+//	  in package {{package}}, following the existing handlers.
+//	files: ["{{package}}/*.go"]
+//	context:
+//	  resource: "{{name}}"
+//	required_context: [project]
+//
+// Placeholders in the instruction, files, and string context values are
+// replaced by parameter values. RequiredContext names task context keys
+// that must be set when the task is assembled, such as project, which
+// --project sets.
+type Recipe struct {
+	Name            string                 `yaml:"name"`
+	Description     string                 `yaml:"description,omitempty"`
+	Type            string                 `yaml:"type,omitempty"`
+	Params          []RecipeParam          `yaml:"params,omitempty"`
+	Instruction     string                 `yaml:"instruction"`
+	Files           []string               `yaml:"files,omitempty"`
+	Context         map[string]interface{} `yaml:"context,omitempty"`
+	RequiredContext []string               `yaml:"required_context,omitempty"`
+	// Path is the file the recipe was loaded from.
+	Path string `yaml:"-"`
+}
+
+// RecipeParam declares one parameter. Parameters without a default are
+// required unless Required is explicitly false, in which case they expand
+// to the empty string.
+type RecipeParam struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description,omitempty"`
+	Default     *string `yaml:"default,omitempty"`
+	Required    *bool   `yaml:"required,omitempty"`
+}
+
+func (p RecipeParam) required() bool {
+	if p.Required != nil {
+		return *p.Required
+	}
+	return p.Default == nil
+}
+
+var (
+	recipeParamName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	recipePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+)
+
+// LoadRecipe parses and validates one recipe file. The name defaults to
+// the file name without its extension.
+func LoadRecipe(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recipe Recipe
+	if err := yaml.Unmarshal(data, &recipe); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if recipe.Name == "" {
+		recipe.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	recipe.Path = path
+	if err := recipe.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &recipe, nil
+}
+
+// LoadRecipes loads every recipe in dir, sorted by name. A missing
+// directory holds no recipes.
+func LoadRecipes(dir string) ([]*Recipe, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recipes []*Recipe
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || (!strings.HasSuffix(entry.Name(), ".yaml") && !strings.HasSuffix(entry.Name(), ".yml")) {
+			continue
+		}
+		recipe, err := LoadRecipe(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[recipe.Name]; ok {
+			return nil, fmt.Errorf("recipe %q is defined by both %s and %s", recipe.Name, prev, entry.Name())
+		}
+		seen[recipe.Name] = entry.Name()
+		recipes = append(recipes, recipe)
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i].Name < recipes[j].Name })
+	return recipes, nil
+}
+
+// FindRecipe loads the recipe called name from dir.
+func FindRecipe(dir, name string) (*Recipe, error) {
+	recipes, err := LoadRecipes(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(recipes))
+	for _, recipe := range recipes {
+		if recipe.Name == name {
+			return recipe, nil
+		}
+		names = append(names, recipe.Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("recipe %q not found: %s has no recipes", name, dir)
+	}
+	return nil, fmt.Errorf("recipe %q not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Validate checks the recipe has an instruction, that parameter names are
+// unique, and that every placeholder names a declared parameter, so typos
+// surface when the recipe is reviewed rather than when it runs.
+func (r *Recipe) Validate() error {
+	if strings.TrimSpace(r.Instruction) == "" {
+		return errors.New("instruction required")
+	}
+	declared := make(map[string]bool, len(r.Params))
+	for i, param := range r.Params {
+		if !recipeParamName.MatchString(param.Name) {
+			return fmt.Errorf("param %d: invalid name %q", i+1, param.Name)
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("param %q declared twice", param.Name)
+		}
+		declared[param.Name] = true
+	}
+	var undeclared []string
+	for _, name := range r.placeholders() {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("undeclared params: %s", strings.Join(undeclared, ", "))
+	}
+	return nil
+}
+
+// placeholders lists the parameter names referenced anywhere in the
+// recipe, sorted and without duplicates.
+func (r *Recipe) placeholders() []string {
+	seen := make(map[string]bool)
+	collect := func(s string) {
+		for _, match := range recipePlaceholder.FindAllStringSubmatch(s, -1) {
+			seen[match[1]] = true
+		}
+	}
+	collect(r.Instruction)
+	for _, file := range r.Files {
+		collect(file)
+	}
+	walkRecipeStrings(r.Context, func(s string) string {
+		collect(s)
+		return s
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderedRecipe is a recipe with its parameters substituted.
+type RenderedRecipe struct {
+	Type        framework.TaskType
+	Instruction string
+	Files       []string
+	Context     map[string]interface{}
+}
+
+// Render substitutes params into the recipe. Unknown and missing required
+// parameters are errors; omitted optional ones take their defaults.
+func (r *Recipe) Render(params map[string]string) (*RenderedRecipe, error) {
+	values := make(map[string]string, len(r.Params))
+	var missing []string
+	for _, param := range r.Params {
+		value, ok := params[param.Name]
+		switch {
+		case ok:
+		case param.Default != nil:
+			value = *param.Default
+		case param.required():
+			missing = append(missing, param.Name)
+		}
+		values[param.Name] = value
+	}
+	var unknown []string
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("recipe %s has no params %s", r.Name, strings.Join(unknown, ", "))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("recipe %s needs --param for %s", r.Name, strings.Join(missing, ", "))
+	}
+	expand := func(s string) string {
+		return recipePlaceholder.ReplaceAllStringFunc(s, func(match string) string {
+			return values[recipePlaceholder.FindStringSubmatch(match)[1]]
+		})
+	}
+	rendered := &RenderedRecipe{
+		Type:        framework.TaskType(r.Type),
+		Instruction: expand(r.Instruction),
+		Context:     make(map[string]interface{}, len(r.Context)),
+	}
+	if rendered.Type == "" {
+		rendered.Type = framework.TaskTypeCodeModification
+	}
+	for _, file := range r.Files {
+		rendered.Files = append(rendered.Files, expand(file))
+	}
+	for key, value := range r.Context {
+		rendered.Context[key] = walkRecipeStrings(value, expand)
+	}
+	return rendered, nil
+}
+
+// CheckContext reports the RequiredContext keys missing or empty in the
+// assembled task context.
+func (r *Recipe) CheckContext(ctx map[string]interface{}) error {
+	var missing []string
+	for _, key := range r.RequiredContext {
+		value, ok := ctx[key]
+		if !ok || value == nil || value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("recipe %s requires task context %s", r.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ParseRecipeParams turns repeated --param key=value flags into a map.
+func ParseRecipeParams(values []string) (map[string]string, error) {
+	params := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q (want key=value)", value)
+		}
+		if _, dup := params[key]; dup {
+			return nil, fmt.Errorf("--param %s given twice", key)
+		}
+		params[key] = val
+	}
+	return params, nil
+}
+
+// walkRecipeStrings returns value with fn applied to every string in it,
+// descending into YAML maps and lists.
+func walkRecipeStrings(value interface{}, fn func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = walkRecipeStrings(item, fn)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = walkRecipeStrings(item, fn)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// resolveRecipe renders a workspace recipe into a task for the server's
+// schedules, attaching its files and the project as `recipe run` does.
+func (r *Runtime) resolveRecipe(name string, params map[string]string) (*framework.Task, error) {
+	recipe, err := FindRecipe(RecipeDir(r.Config.Workspace), name)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := recipe.Render(params)
+	if err != nil {
+		return nil, err
+	}
+	task := &framework.Task{Type: rendered.Type, Instruction: rendered.Instruction, Context: rendered.Context}
+	if r.Project != nil {
+		task.Context["project"] = r.Project.Path
+	}
+	if len(rendered.Files) > 0 {
+		paths, err := ResolveTaskFiles(r.Config.Workspace, rendered.Files)
+		if err != nil {
+			return nil, err
+		}
+		attached, err := LoadTaskFiles(r.Config.Workspace, paths, r.TaskFileBudget(), nil)
+		if err != nil {
+			return nil, err
+		}
+		task.Context["files"] = attached
+	}
+	if err := recipe.CheckContext(task.Context); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const addEndpointRecipe = `description: Add a CRUD endpoint
+params:
+  - name: name
+  - name: package
+    default: api
+  - name: note
+    required: false
+instruction: |
+  Add handlers for {{name}} in package {{ package }}.{{note}}
+files: ["{{package}}/*.go"]
+context:
+  resource: "{{name}}"
+  tags: ["crud", "{{package}}"]
+  retries: 2
+required_context: [project]
+`
+
+func writeRecipe(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestRecipeRenderSubstitutesParams(t *testing.T) {
+	dir := RecipeDir(t.TempDir())
+	writeRecipe(t, dir, "add-endpoint.yaml", addEndpointRecipe)
+	recipe, err := FindRecipe(dir, "add-endpoint")
+	require.NoError(t, err)
+
+	rendered, err := recipe.Render(map[string]string{"name": "users"})
+	require.NoError(t, err)
+	require.Equal(t, framework.TaskTypeCodeModification, rendered.Type)
+	require.Equal(t, "Add handlers for users in package api.\n", rendered.Instruction)
+	require.Equal(t, []string{"api/*.go"}, rendered.Files)
+	require.Equal(t, map[string]interface{}{
+		"resource": "users",
+		"tags":     []interface{}{"crud", "api"},
+		"retries":  2,
+	}, rendered.Context)
+
+	_, err = recipe.Render(map[string]string{})
+	require.ErrorContains(t, err, "needs --param for name")
+	_, err = recipe.Render(map[string]string{"name": "users", "nmae": "x"})
+	require.ErrorContains(t, err, "has no params nmae")
+
+	require.ErrorContains(t, recipe.CheckContext(map[string]interface{}{}), "requires task context project")
+	require.NoError(t, recipe.CheckContext(map[string]interface{}{"project": "svc"}))
+}
+
+func TestLoadRecipesValidates(t *testing.T) {
+	dir := t.TempDir()
+	recipes, err := LoadRecipes(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, recipes)
+
+	writeRecipe(t, dir, "typo.yaml", "instruction: Add {{nmae}}\nparams: [{name: name}]\n")
+	_, err = LoadRecipes(dir)
+	require.ErrorContains(t, err, "undeclared params: nmae")
+
+	writeRecipe(t, dir, "typo.yaml", "name: b\ninstruction: Run checks\n")
+	writeRecipe(t, dir, "a.yml", "instruction: Add tests\ntype: analysis\n")
+	writeRecipe(t, dir, "notes.txt", "ignored")
+	recipes, err = LoadRecipes(dir)
+	require.NoError(t, err)
+	require.Len(t, recipes, 2)
+	require.Equal(t, "a", recipes[0].Name)
+	require.Equal(t, "b", recipes[1].Name)
+
+	_, err = FindRecipe(dir, "c")
+	require.ErrorContains(t, err, "available: a, b")
+}
+
+func TestParseRecipeParams(t *testing.T) {
+	params, err := ParseRecipeParams([]string{"name=users", "filter=a=b", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"name": "users", "filter": "a=b", "empty": ""}, params)
+	_, err = ParseRecipeParams([]string{"name"})
+	require.Error(t, err)
+	_, err = ParseRecipeParams([]string{"a=1", "a=2"})
+	require.ErrorContains(t, err, "given twice")
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// RedactionConfig tunes secret masking. Built-in credential patterns and the
+// values in the workspace .env are always masked unless disabled:
+//
+//	redaction:
+//	  patterns: ['corp-[a-z0-9]{24}']
+//	  env_files: [.env, deploy/.env.production]
+type RedactionConfig struct {
+	Disabled bool     `yaml:"disabled,omitempty"`
+	Patterns []string `yaml:"patterns,omitempty"`
+	// EnvFiles are read for secret values, relative to the workspace.
+	// Defaults to [.env]; missing files are skipped.
+	EnvFiles []string `yaml:"env_files,omitempty"`
+}
+
+// redactModel masks prompts before they reach model. Wrapping outside the
+// instrumentation keeps secrets out of the prompt telemetry too.
+func redactModel(model framework.LanguageModel, redactor *framework.Redactor) framework.LanguageModel {
+	if redactor == nil {
+		return model
+	}
+	return &framework.RedactingModel{Inner: model, Redactor: redactor}
+}
+
+// buildRedactor returns the workspace redactor, or nil when redaction is
+// disabled.
+func buildRedactor(workspace string, cfg *RedactionConfig) (*framework.Redactor, error) {
+	if cfg == nil {
+		cfg = &RedactionConfig{}
+	}
+	if cfg.Disabled {
+		return nil, nil
+	}
+	envFiles := cfg.EnvFiles
+	if len(envFiles) == 0 {
+		envFiles = []string{".env"}
+	}
+	var literals []string
+	for _, name := range envFiles {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspace, path)
+		}
+		values, err := framework.LoadEnvSecrets(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redaction env file %s: %w", name, err)
+		}
+		literals = append(literals, values...)
+	}
+	return framework.NewRedactor(framework.RedactionOptions{Patterns: cfg.Patterns, Literals: literals})
+}
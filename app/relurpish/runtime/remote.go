@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// RemoteConfig points the workspace at a directory on another machine, so
+// an agent on a laptop can work on code that lives on a dev server:
+//
+//	remote:
+//	  host: dev.example.com
+//	  user: alice
+//	  port: 2222
+//	  identity_file: ~/.ssh/id_ed25519
+//	  path: /home/alice/src/app
+//	  mount: ~/mnt/app
+//
+// The directory is mounted with sshfs, which speaks SFTP, so the file tools
+// work on it unchanged. Exec tools and language servers run on the remote
+// machine over ssh. Mount defaults to a temporary directory.
+type RemoteConfig struct {
+	Host         string   `yaml:"host"`
+	User         string   `yaml:"user,omitempty"`
+	Port         int      `yaml:"port,omitempty"`
+	IdentityFile string   `yaml:"identity_file,omitempty"`
+	Path         string   `yaml:"path"`
+	Mount        string   `yaml:"mount,omitempty"`
+	SSHOptions   []string `yaml:"ssh_options,omitempty"`
+}
+
+// RemoteHost resolves c into the host the runner and language servers use,
+// with ~ expanded in IdentityFile and Mount.
+func (c *RemoteConfig) RemoteHost() (framework.RemoteHost, error) {
+	host := framework.RemoteHost{
+		Host:         c.Host,
+		User:         c.User,
+		Port:         c.Port,
+		IdentityFile: expandHome(c.IdentityFile),
+		Options:      c.SSHOptions,
+		Root:         c.Path,
+	}
+	if c.Mount != "" {
+		mount, err := filepath.Abs(expandHome(c.Mount))
+		if err != nil {
+			return framework.RemoteHost{}, fmt.Errorf("remote mount: %w", err)
+		}
+		host.Local = mount
+	}
+	if err := host.Validate(); err != nil {
+		return framework.RemoteHost{}, fmt.Errorf("remote: %w", err)
+	}
+	return host, nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// RemoteWorkspace is a remote directory mounted locally with sshfs.
+// Host.Local is the mount point.
+type RemoteWorkspace struct {
+	Host framework.RemoteHost
+
+	// temp marks a mount point MountRemote created and Close removes.
+	temp bool
+}
+
+// MountRemote mounts the directory cfg names. It returns nil when cfg is
+// nil. Close unmounts it.
+func MountRemote(ctx context.Context, cfg *RemoteConfig) (*RemoteWorkspace, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	host, err := cfg.RemoteHost()
+	if err != nil {
+		return nil, err
+	}
+	w := &RemoteWorkspace{Host: host}
+	if host.Local == "" {
+		dir, err := os.MkdirTemp("", "relurpify-remote-*")
+		if err != nil {
+			return nil, err
+		}
+		w.Host.Local = dir
+		w.temp = true
+	} else if err := os.MkdirAll(host.Local, 0o755); err != nil {
+		return nil, fmt.Errorf("remote mount: %w", err)
+	}
+	out, err := exec.CommandContext(ctx, "sshfs", sshfsArgs(w.Host)...).CombinedOutput()
+	if err != nil {
+		if w.temp {
+			os.Remove(w.Host.Local)
+		}
+		return nil, fmt.Errorf("mount %s:%s: %w: %s", host.Destination(), host.Root, err, strings.TrimSpace(string(out)))
+	}
+	return w, nil
+}
+
+// sshfsArgs mounts host.Root at host.Local with the host's ssh settings.
+// reconnect keeps the mount usable across dropped connections.
+func sshfsArgs(host framework.RemoteHost) []string {
+	args := []string{host.Destination() + ":" + host.Root, host.Local, "-o", "reconnect,ServerAliveInterval=15,BatchMode=yes"}
+	if host.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(host.Port))
+	}
+	if host.IdentityFile != "" {
+		args = append(args, "-o", "IdentityFile="+host.IdentityFile)
+	}
+	for _, opt := range host.Options {
+		args = append(args, "-o", opt)
+	}
+	return args
+}
+
+// Config returns cfg retargeted at the mount, like IsolatedWorkspace.Config.
+// Configuration, memory, and logs stay with the local workspace.
+func (w *RemoteWorkspace) Config(cfg Config) Config {
+	cfg.Workspace = w.Host.Local
+	return cfg
+}
+
+// Close unmounts the remote directory and removes a temporary mount point.
+func (w *RemoteWorkspace) Close() error {
+	if w == nil {
+		return nil
+	}
+	unmount := exec.Command("umount", w.Host.Local)
+	if goruntime.GOOS == "linux" {
+		unmount = exec.Command("fusermount", "-u", w.Host.Local)
+	}
+	if out, err := unmount.CombinedOutput(); err != nil {
+		return fmt.Errorf("unmount %s: %w: %s", w.Host.Local, err, strings.TrimSpace(string(out)))
+	}
+	if w.temp {
+		return os.Remove(w.Host.Local)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+func TestRemoteConfigMountArgs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	var cfg WorkspaceConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+remote:
+  host: dev.example.com
+  user: alice
+  port: 2222
+  identity_file: ~/.ssh/id_ed25519
+  path: /home/alice/src/app
+  mount: /mnt/app
+  ssh_options: [StrictHostKeyChecking=yes]
+`), &cfg))
+	require.NotNil(t, cfg.Remote)
+
+	host, err := cfg.Remote.RemoteHost()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".ssh", "id_ed25519"), host.IdentityFile)
+	assert.Equal(t, "/mnt/app", host.Local)
+	assert.Equal(t, []string{
+		"alice@dev.example.com:/home/alice/src/app", "/mnt/app",
+		"-o", "reconnect,ServerAliveInterval=15,BatchMode=yes",
+		"-p", "2222",
+		"-o", "IdentityFile=" + host.IdentityFile,
+		"-o", "StrictHostKeyChecking=yes",
+	}, sshfsArgs(host))
+
+	mounted := &RemoteWorkspace{Host: host}
+	assert.Equal(t, "/mnt/app", mounted.Config(Config{Workspace: "/home/me/app"}).Workspace)
+}
+
+func TestMountRemoteRejectsIncompleteConfig(t *testing.T) {
+	w, err := MountRemote(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, w)
+
+	_, err = MountRemote(context.Background(), &RemoteConfig{Host: "dev", Path: "src/app"})
+	assert.ErrorContains(t, err, "must be absolute")
+	_, err = MountRemote(context.Background(), &RemoteConfig{Path: "/srv/app"})
+	assert.ErrorContains(t, err, "remote host required")
+}
+
+// denyingHITL refuses every request and records what was asked.
+type denyingHITL struct {
+	requests []framework.PermissionRequest
+}
+
+func (h *denyingHITL) RequestPermission(ctx context.Context, req framework.PermissionRequest) (*framework.PermissionGrant, error) {
+	h.requests = append(h.requests, req)
+	return nil, assert.AnError
+}
+
+// TestRemoteWorkspaceGatesHostCommands checks that everything running
+// commands over ssh asks first: git, go, and plugin tools as well as the
+// post-edit formatters, not only the execution category.
+func TestRemoteWorkspaceGatesHostCommands(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644))
+	invoked := filepath.Join(t.TempDir(), "invoked")
+	fakeSSH := filepath.Join(t.TempDir(), "ssh")
+	require.NoError(t, os.WriteFile(fakeSSH, []byte("#!/bin/sh\ntouch "+invoked+"\n"), 0o755))
+	runner, err := framework.NewSSHCommandRunner(framework.RemoteHost{Host: "dev", Root: "/srv/app", Local: dir})
+	require.NoError(t, err)
+	runner.Binary = fakeSSH
+	perms := framework.NewFileSystemPermissionSet(dir, framework.FileSystemRead, framework.FileSystemWrite, framework.FileSystemList)
+	perms.Executables = []framework.ExecutablePermission{{Binary: "gofmt", Args: []string{"*"}}}
+	hitl := &denyingHITL{}
+	manager, err := framework.NewPermissionManager(dir, perms, nil, hitl)
+	require.NoError(t, err)
+
+	registry, err := BuildToolRegistry(dir, runner, ToolRegistryOptions{
+		AgentID:           "agent",
+		PermissionManager: manager,
+		Formatting:        &FormattingConfig{Enabled: true, Languages: map[string]FormatterConfig{"go": {Command: []string{"gofmt"}}}},
+		GateCommands:      true,
+	})
+	require.NoError(t, err)
+	plugin, err := tools.NewPluginTools(framework.AgentPluginSpec{Name: "lint", Command: []string{"lint-plugin"}}, dir, runner, []tools.PluginToolDescriptor{{Name: "plugin_lint"}})
+	require.NoError(t, err)
+	require.NoError(t, registry.Register(plugin[0]))
+
+	gated := registry.RequireApprovalFor(framework.RunsCommands)
+	for _, name := range []string{"git_commit", "go_get", "go_mod_tidy", "plugin_lint", "exec_run_tests"} {
+		assert.Contains(t, gated, name)
+	}
+	for _, name := range []string{"file_read", "file_write", "search_grep"} {
+		assert.NotContains(t, gated, name)
+	}
+
+	write, ok := registry.Get("file_write")
+	require.True(t, ok)
+	res, err := write.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go", "content": "package main\n"})
+	require.NoError(t, err)
+	assert.Contains(t, res.Data["format_errors"], "main.go")
+	require.Len(t, hitl.requests, 1)
+	assert.Equal(t, "format:exec", hitl.requests[0].Permission.Action)
+	assert.NoFileExists(t, invoked, "the formatter never reached the remote host")
+}
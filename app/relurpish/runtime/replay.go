@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+// ReplayDir holds the replay logs written by --record, one
+// <task-id>.jsonl per task.
+func ReplayDir(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "replays")
+}
+
+// openReplay returns the recorder for --record or the replayer for
+// Config.ReplayPath; at most one is set.
+func openReplay(cfg Config) (*framework.ReplayRecorder, *framework.Replayer, error) {
+	if cfg.ReplayPath != "" {
+		replayer, err := framework.LoadReplay(cfg.ReplayPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, replayer, nil
+	}
+	if cfg.Record {
+		return framework.NewReplayRecorder(ReplayDir(cfg.Workspace)), nil, nil
+	}
+	return nil, nil, nil
+}
+
+// replayModel records inner's calls, or answers them from the replay
+// instead of inner.
+func replayModel(inner framework.LanguageModel, recorder *framework.ReplayRecorder, replayer *framework.Replayer) framework.LanguageModel {
+	switch {
+	case replayer != nil:
+		return &llm.ReplayModel{Replayer: replayer}
+	case recorder != nil:
+		return &llm.RecordingModel{Inner: inner, Recorder: recorder}
+	}
+	return inner
+}
+
+// startRecording opens task's replay log and makes sure calls made outside
+// the graph are attributed to it. The returned func closes the log.
+func (r *Runtime) startRecording(ctx context.Context, task *framework.Task) (context.Context, func()) {
+	if r.recorder == nil || task.ID == "" {
+		return ctx, func() {}
+	}
+	var info framework.ModelContext
+	if r.agentConfig != nil {
+		info = r.agentConfig.ModelContext
+	}
+	if err := r.recorder.StartTask(task, info); err != nil {
+		r.Logger.Printf("replay recording for %s failed: %v", task.ID, err)
+		return ctx, func() {}
+	}
+	if _, ok := framework.TaskContextFrom(ctx); !ok {
+		ctx = framework.WithTaskContext(ctx, framework.TaskContext{
+			ID:          task.ID,
+			Type:        task.Type,
+			Instruction: task.Instruction,
+			Agent:       r.Config.AgentLabel(),
+		})
+	}
+	return ctx, func() {
+		if err := r.recorder.FinishTask(task.ID); err != nil {
+			r.Logger.Printf("replay recording for %s: %v", task.ID, err)
+		}
+	}
+}
+
+// ReplayReport summarizes a replayed task.
+type ReplayReport struct {
+	Result *framework.Result
+	// Steps counts the recorded calls; Served how many the run used.
+	Steps  int
+	Served int
+	// Mismatches lists calls served although their prompt or arguments
+	// differed from the recording.
+	Mismatches []string
+}
+
+// Replay re-runs the task recorded at Config.ReplayPath. Model calls are
+// answered from the recording and tools return their recorded results, so
+// no model is needed and the workspace is left untouched.
+func (r *Runtime) Replay(ctx context.Context) (*ReplayReport, error) {
+	if r.Replayer == nil {
+		return nil, errors.New("runtime was not started for replay")
+	}
+	task := *r.Replayer.Task
+	res, _, err := r.runTask(ctx, &task)
+	report := &ReplayReport{
+		Result:     res,
+		Steps:      len(r.Replayer.Steps()),
+		Served:     r.Replayer.Served(),
+		Mismatches: r.Replayer.Mismatches(),
+	}
+	if err == nil && report.Served < report.Steps {
+		err = fmt.Errorf("%w: the task finished after %d of %d recorded calls", framework.ErrReplayDiverged, report.Served, report.Steps)
+	}
+	return report, err
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/agents"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ReviewAgentName labels tasks run by the review preset.
+const ReviewAgentName = "review"
+
+// ReviewOptions selects the changes Review comments on.
+type ReviewOptions struct {
+	// Diff is a unified diff to review; when set, Base and Head are ignored.
+	Diff string
+	// Base is the git ref to compare against, HEAD when empty. With Head the
+	// review covers the commits Head adds since it forked from Base;
+	// without, it covers the working tree against Base.
+	Base string
+	Head string
+	// Focus steers the reviewer, e.g. "concurrency" or "error handling".
+	Focus string
+}
+
+// Review comments on a diff with the review preset, whatever agent the
+// session runs. The preset reads nothing but the diff, so it needs no
+// permissions and never changes the workspace.
+func (r *Runtime) Review(ctx context.Context, opts ReviewOptions) (*agents.CodeReview, error) {
+	diff := opts.Diff
+	if strings.TrimSpace(diff) == "" {
+		var err error
+		if diff, err = reviewDiff(ctx, r.Config.Workspace, opts.Base, opts.Head); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, errors.New("nothing to review: the diff is empty")
+	}
+	cfg := &framework.Config{Name: ReviewAgentName}
+	if r.agentConfig != nil {
+		copied := *r.agentConfig
+		copied.Name = ReviewAgentName
+		cfg = &copied
+	}
+	agent := agents.NewReviewAgent(cfg.ModelFor(framework.ModelRoleReview, r.Model))
+	if err := agent.Initialize(cfg); err != nil {
+		return nil, err
+	}
+	task := &framework.Task{
+		ID:          fmt.Sprintf("review-%d", time.Now().UnixNano()),
+		Type:        framework.TaskTypeReview,
+		Instruction: "Review the diff",
+		Context:     map[string]any{"source": ReviewAgentName, "diff": diff, "review_focus": opts.Focus},
+	}
+	ctx = framework.WithGraphTimeouts(ctx, r.timeouts)
+	state := r.Context.Clone()
+	state.Set("task.id", task.ID)
+	state.Set("task.type", string(task.Type))
+	state.Set("task.instruction", task.Instruction)
+	state.Set("task.source", ReviewAgentName)
+	state.Set("task.agent", ReviewAgentName)
+	start := time.Now()
+	res, err := agent.Execute(ctx, task, state)
+	if r.Metrics != nil {
+		r.Metrics.ObserveTask(task, res, err, time.Since(start))
+	}
+	r.saveWorkflow(ctx, task, state, err)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := state.Get("review.result")
+	review, ok := raw.(agents.CodeReview)
+	if !ok {
+		return nil, errors.New("review agent returned no review")
+	}
+	return &review, nil
+}
+
+// reviewDiff asks git for the changes between base and head, or between
+// base and the working tree when head is empty.
+func reviewDiff(ctx context.Context, workspace, base, head string) (string, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	rangeArg := base
+	if head != "" {
+		rangeArg = base + "..." + head
+	}
+	return gitOutput(ctx, workspace, "diff", "--relative", "--no-color", "--no-ext-diff", rangeArg, "--")
+}
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,9 +16,13 @@ import (
 	"github.com/lexcodex/relurpify/agents"
 	"github.com/lexcodex/relurpify/framework"
 	"github.com/lexcodex/relurpify/framework/ast"
+	"github.com/lexcodex/relurpify/framework/watch"
 	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/persistence"
 	"github.com/lexcodex/relurpify/server"
 	"github.com/lexcodex/relurpify/tools"
+	"github.com/lexcodex/relurpify/tools/db"
+	"github.com/lexcodex/relurpify/tools/testrunner"
 )
 
 // Runtime wires the relurpish CLI, Bubble Tea UI, and API server to the shared
@@ -33,8 +38,80 @@ type Runtime struct {
 	Registration *framework.AgentRegistration
 	Logger       *log.Logger
 	Workspace    WorkspaceConfig
+	Usage        *framework.UsageTracker
+	Workflows    persistence.WorkflowStore
+	Sessions     persistence.SessionStore
+	// Schedules holds the server's cron schedules; nil when the store is
+	// unavailable.
+	Schedules persistence.ScheduleStore
+	// Artifacts holds the reports, diagrams, and data files tasks saved
+	// with artifact_save; nil when the store is unavailable.
+	Artifacts framework.ArtifactStore
+	// Spill archives entries evicted by the memory caps; nil when the spill
+	// directory is unavailable.
+	Spill *persistence.SpillFile
+	Autonomy     *framework.AutonomyController
+	// Events keeps recent telemetry per task for the dashboard timelines.
+	Events *server.EventLog
+	// Metrics backs the server's /metrics endpoint.
+	Metrics *server.Metrics
+	// Project is the monorepo project the toolchain is scoped to, or nil for
+	// the whole workspace.
+	Project *ProjectConfig
+	// FollowUps carries each shell instruction's outcome into the next one.
+	FollowUps *FollowUpSession
+	// Chat checkpoints shell turns for /undo and /branch.
+	Chat *ChatHistory
+	// Checkpoints holds the pre-task content of every file a task wrote;
+	// see Rollback. Nil when the store is unavailable.
+	Checkpoints *persistence.FileCheckpointStore
+	// Replayer serves the recording at Config.ReplayPath; nil otherwise.
+	Replayer *framework.Replayer
 
-	logFile io.Closer
+	// timeouts bounds every task's graph; see framework.WithGraphTimeouts.
+	timeouts framework.GraphTimeouts
+	// hitlWebhooks are notified of approval requests while the server runs.
+	hitlWebhooks []*server.HITLWebhook
+	// apiAuth guards the HTTP API; nil leaves it open.
+	apiAuth *server.APIAuth
+	// caches are invalidated by the workspace watcher; see StartWatch.
+	caches watch.Invalidator
+	// lsp routes the LSP tools; nil when no language server is configured.
+	lsp *tools.Proxy
+	// askAgent is the read-only preset behind Ask, built on first use.
+	askMu    sync.Mutex
+	askAgent *agents.QAAgent
+
+	watchMu   sync.Mutex
+	watchSubs map[int]chan WatchEvent
+	watchSeq  int
+
+	// lifecycle and clients drive warm-up and idle unloading; see
+	// StartModelLifecycle.
+	lifecycle   modelLifecycle
+	clients     []*llm.Client
+	modelMu     sync.Mutex
+	modelSubs   map[int]chan llm.ModelEvent
+	modelSeq    int
+	modelStatus llm.ModelEvent
+
+	logFile      io.Closer
+	tracing      *framework.OTLPTelemetry
+	// client and agentConfig are kept so UseModel can switch models.
+	client       *llm.Client
+	agentConfig  *framework.Config
+	auditClosers []io.Closer
+	mcpClosers   []io.Closer
+	// responseCache backs the cached models; nil when disabled.
+	responseCache *llm.ResponseCache
+	// recorder writes replay logs when Config.Record is set.
+	recorder *framework.ReplayRecorder
+	// stopPools ends the health checks of the Ollama endpoint pools.
+	stopPools context.CancelFunc
+	// remote is the mounted remote workspace, unmounted by Close.
+	remote *RemoteWorkspace
+	// newAgent builds an agent like Agent for each server task.
+	newAgent func() (framework.Agent, error)
 
 	serverMu     sync.Mutex
 	serverCancel context.CancelFunc
@@ -44,6 +121,7 @@ type Runtime struct {
 // sandbox or manifest verification fails so that the wizard/status views can
 // surface actionable diagnostics.
 func New(ctx context.Context, cfg Config) (*Runtime, error) {
+	started := time.Now()
 	if err := cfg.Normalize(); err != nil {
 		return nil, err
 	}
@@ -56,7 +134,7 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 	}
 	logger := log.New(logFile, "relurpish ", log.LstdFlags|log.Lmicroseconds)
 
-	memory, err := framework.NewHybridMemory(cfg.MemoryPath)
+	memory, err := framework.NewLayeredMemory(cfg.MemoryLayout())
 	if err != nil {
 		logFile.Close()
 		return nil, fmt.Errorf("memory init: %w", err)
@@ -65,28 +143,88 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 	var workspaceCfg WorkspaceConfig
 	var allowedTools []string
 	if cfg.ConfigPath != "" {
-		if loaded, err := LoadWorkspaceConfig(cfg.ConfigPath); err == nil {
-			workspaceCfg = loaded
-			if workspaceCfg.Model != "" {
-				cfg.OllamaModel = workspaceCfg.Model
-			}
-			if len(workspaceCfg.Agents) > 0 {
-				cfg.AgentName = workspaceCfg.Agents[0]
-			}
-			allowedTools = append(allowedTools, workspaceCfg.AllowedTools...)
-		} else if !errors.Is(err, os.ErrNotExist) {
+		loaded, err := LoadWorkspaceConfig(cfg.ConfigPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			logger.Printf("workspace config load failed: %v", err)
 		}
+		workspaceCfg, err = loaded.WithProfile(cfg.Profile)
+		if err != nil {
+			logFile.Close()
+			return nil, err
+		}
+		if workspaceCfg.Profile != "" {
+			logger.Printf("using config profile %s", workspaceCfg.Profile)
+		}
+		if workspaceCfg.Model != "" {
+			cfg.OllamaModel = workspaceCfg.Model
+		}
+		cfg.ApplyProfileEndpoint(workspaceCfg)
+		if len(workspaceCfg.Agents) > 0 {
+			cfg.AgentName = workspaceCfg.Agents[0]
+		}
+		allowedTools = append(allowedTools, workspaceCfg.AllowedTools...)
+		cfg.RequireSandbox = cfg.RequireSandbox || workspaceCfg.RequireSandbox
 	}
+	cfg.ServerWorkers = workspaceCfg.Jobs.Workers(cfg.ServerWorkers)
 
-	registration, err := framework.RegisterAgent(ctx, framework.RuntimeConfig{
-		ManifestPath: cfg.ManifestPath,
-		Sandbox:      cfg.Sandbox,
-		AuditLimit:   cfg.AuditLimit,
-		BaseFS:       cfg.Workspace,
-		HITLTimeout:  cfg.HITLTimeout,
-	})
+	policy, err := buildPolicyEvaluator(workspaceCfg.Policy)
 	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	redactor, err := buildRedactor(cfg.Workspace, workspaceCfg.Redaction)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	auditSinks, auditClosers, err := buildAuditSinks(cfg.Workspace, workspaceCfg.AuditSinks)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	remote, err := MountRemote(ctx, workspaceCfg.Remote)
+	if err != nil {
+		closeAll(auditClosers)
+		logFile.Close()
+		return nil, err
+	}
+	ready := false
+	if remote != nil {
+		defer func() {
+			if !ready {
+				remote.Close()
+			}
+		}()
+		cfg = remote.Config(cfg)
+		logger.Printf("remote workspace %s:%s mounted at %s", remote.Host.Destination(), remote.Host.Root, cfg.Workspace)
+	}
+	cache := openStartupCache(cfg)
+	runtimeCfg := framework.RuntimeConfig{
+		ManifestPath:   cfg.ManifestPath,
+		Sandbox:        cfg.Sandbox,
+		AuditLimit:     cfg.AuditLimit,
+		BaseFS:         cfg.Workspace,
+		HITLTimeout:    cfg.HITLTimeout,
+		AuditSinks:     auditSinks,
+		Policy:         policy,
+		RequireSandbox: cfg.RequireSandbox,
+	}
+	if cache != nil {
+		runtimeCfg.SandboxCache = cache
+	}
+	if cfg.Permissions != "" {
+		perms, err := PermissionPreset(cfg.Workspace, cfg.Permissions)
+		if err != nil {
+			closeAll(auditClosers)
+			logFile.Close()
+			return nil, err
+		}
+		runtimeCfg.Permissions = &perms
+		logger.Printf("using permission preset %s", cfg.Permissions)
+	}
+	registration, err := framework.RegisterAgent(ctx, runtimeCfg)
+	if err != nil {
+		closeAll(auditClosers)
 		logFile.Close()
 		return nil, fmt.Errorf("sandbox registration failed: %w", err)
 	}
@@ -99,20 +237,65 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 		logFile.Close()
 		return nil, fmt.Errorf("agent manifest missing spec.agent.model.name")
 	}
-	runner, err := framework.NewSandboxCommandRunner(registration.Manifest, registration.Runtime, cfg.Workspace)
+	if registration.Sandbox.Degraded {
+		logger.Printf("warning: sandbox unavailable, running commands on the host: %s", registration.Sandbox.Reason)
+	}
+	if cfg.LearnPermissions {
+		registration.Permissions.SetLearning(framework.NewPermissionLearner(LearnedPermissionsDir(cfg.Workspace)))
+		logger.Printf("warning: learning permissions; undeclared requests are recorded under %s and allowed", LearnedPermissionsDir(cfg.Workspace))
+	}
+	var runner framework.CommandRunner
+	var remoteHost *framework.RemoteHost
+	if remote != nil {
+		// Commands run on the remote machine, which the sandbox cannot
+		// reach; tools that run them need approval as on a degraded sandbox.
+		remoteHost = &remote.Host
+		runner, err = framework.NewSSHCommandRunner(remote.Host)
+	} else {
+		runner, err = registration.CommandRunner(cfg.Workspace)
+	}
 	if err != nil {
 		logFile.Close()
 		return nil, err
 	}
-	registry, err := BuildToolRegistry(cfg.Workspace, runner, ToolRegistryOptions{
+	project, err := resolveProject(cfg, workspaceCfg)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	if project != nil {
+		logger.Printf("scoping toolchain to project %s (%s)", project.Path, strings.Join(project.Languages, ", "))
+	}
+	var artifacts framework.ArtifactStore
+	if store, err := persistence.NewFileArtifactStore(cfg.ArtifactsPath); err != nil {
+		logger.Printf("warning: artifact store unavailable: %v", err)
+	} else {
+		artifacts = store
+	}
+	lspSpec := filterLSPLanguages(agentSpec.LSP, workspaceCfg.Languages)
+	registry, caches, lspProxy, err := buildToolRegistry(cfg.Workspace, runner, ToolRegistryOptions{
 		AgentID:            registration.ID,
 		PermissionManager:  registration.Permissions,
 		AgentSpec:          nil,
+		LSP:                &lspSpec,
+		Project:            project,
+		OllamaEndpoint:     cfg.OllamaEndpoint,
+		Embeddings:         workspaceCfg.Embeddings,
+		LLMThrottle:        workspaceCfg.LLMThrottle,
+		FileEdit:           workspaceCfg.FileEdit,
+		CodeExtraction:     workspaceCfg.CodeExtraction,
+		Formatting:         workspaceCfg.Formatting,
+		Databases:          workspaceCfg.Databases,
+		Artifacts:          artifacts,
+		Remote:             remoteHost,
+		GateCommands:       registration.Sandbox.Degraded || remote != nil,
 	})
 	if err != nil {
 		logFile.Close()
 		return nil, err
 	}
+	go refreshGlossary(cfg.Workspace, memory, registration, logger)
+	go refreshConventions(cfg.Workspace, runner, memory, registration, logger)
 	if cfg.AgentName == "" {
 		cfg.AgentName = registration.Manifest.Metadata.Name
 	}
@@ -145,16 +328,85 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 			}
 		}
 	}
-	telemetry := framework.MultiplexTelemetry{Sinks: sinks}
+	events := server.NewEventLog()
+	metrics := server.NewMetrics()
+	metrics.Memory = memory
+	sinks = append(sinks, events, metrics)
+	tracing, err := buildTracing(cfg, workspaceCfg)
+	if err != nil {
+		logger.Printf("warning: tracing disabled: %v", err)
+	} else if tracing != nil {
+		sinks = append(sinks, tracing)
+	}
+	var telemetry framework.Telemetry = framework.MultiplexTelemetry{Sinks: sinks}
+	if redactor != nil {
+		telemetry = framework.RedactingTelemetry{Inner: telemetry, Redactor: redactor}
+	}
 	registry.UseTelemetry(telemetry)
+	registry.UseRedactor(redactor)
 
 	logLLM := false
 	if agentSpec.Logging != nil && agentSpec.Logging.LLM != nil {
 		logLLM = *agentSpec.Logging.LLM
 	}
+	usage := framework.NewUsageTracker(workspaceCfg.ModelPrices)
+	autonomy, err := buildAutonomy(cfg, workspaceCfg, usage)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	registry.UseAutonomy(autonomy)
+	hitlWebhooks, err := buildHITLWebhooks(cfg, workspaceCfg, logger)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	apiAuth, err := buildAPIAuth(workspaceCfg, registration.Audit)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	lifecycle, err := workspaceCfg.ModelLifecycle.resolve()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	responseCache, err := openResponseCache(cfg, workspaceCfg.LLMCache)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	recorder, replayer, err := openReplay(cfg)
+	if err != nil {
+		responseCache.Close()
+		logFile.Close()
+		return nil, err
+	}
+	if recorder != nil {
+		registry.UseToolTap(recorder)
+	} else if replayer != nil {
+		registry.UseToolTap(replayer)
+	}
+	var pools *ollamaPools
+	if endpoints := workspaceCfg.OllamaPool.extraEndpoints(cfg.OllamaEndpoint); len(endpoints) > 0 && replayer == nil {
+		interval, err := workspaceCfg.OllamaPool.healthInterval()
+		if err != nil {
+			responseCache.Close()
+			logFile.Close()
+			return nil, err
+		}
+		pools = &ollamaPools{config: workspaceCfg.OllamaPool, primary: cfg.OllamaEndpoint, throttle: workspaceCfg.LLMThrottle, logger: logger, interval: interval}
+		logger.Printf("pooling ollama endpoints: %s, %s", cfg.OllamaEndpoint, strings.Join(endpoints, ", "))
+	}
+	throttle := workspaceCfg.LLMThrottle.Throttle(cfg.OllamaEndpoint)
 	modelClient := llm.NewClient(cfg.OllamaEndpoint, cfg.OllamaModel)
 	modelClient.SetDebugLogging(logLLM)
-	model := llm.NewInstrumentedModel(modelClient, telemetry, logLLM)
+	modelClient.Throttle = throttle
+	modelClient.KeepAlive = lifecycle.keepAlive
+	clients := []*llm.Client{modelClient}
+	instrumented := llm.NewInstrumentedModel(replayModel(cacheModel(pools.wrap(modelClient), modelClient, responseCache), recorder, replayer), telemetry, logLLM)
+	instrumented.Usage = usage
+	model := redactModel(instrumented, redactor)
 
 	// Create base config derived from manifest + CLI args
 	agentCfg := &framework.Config{
@@ -166,31 +418,144 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 		AgentSpec:         agentSpec, // Default to manifest spec
 		Telemetry:         telemetry,
 	}
+	if workspaceCfg.ContextWindow != nil {
+		agentCfg.ContextWindow = *workspaceCfg.ContextWindow
+	}
+	if workspaceCfg.StructuredOutput != nil {
+		agentCfg.StructuredOutput = *workspaceCfg.StructuredOutput
+	}
+	agentCfg.ToolOutput = toolOutputPolicy(cfg.Workspace, workspaceCfg.ToolOutput)
+	if agentCfg.ToolOutput.SpillDir != "" {
+		go pruneToolOutput(agentCfg.ToolOutput.SpillDir)
+	}
+	if registration.HITL != nil {
+		agentCfg.HITL = registration.HITL
+	}
+	if replayer != nil {
+		applyModelContext(modelClient, agentCfg, replayer.ModelContext, logger)
+	} else {
+		sizeModelContext(ctx, modelClient, agentCfg, logger)
+	}
 
-	agent := instantiateAgent(cfg, model, registry, memory, agentDefs, agentCfg)
+	def := applyAgentDefinition(cfg, agentDefs, agentCfg)
+	// A replay must not send the probe request, so only reported
+	// capabilities apply to it.
+	if replayer == nil || len(agentCfg.ModelContext.Capabilities) > 0 {
+		if caps, ok := modelCapabilities(ctx, cfg.OllamaEndpoint, modelClient, agentCfg.ModelContext, cache); ok {
+			negotiateModel(agentCfg, caps, logger)
+		}
+	}
+	registerPlugins(context.Background(), registry, agentCfg.AgentSpec, cfg.Workspace, runner, cache, logger)
+	mcpClosers := registerMCPServers(ctx, registry, agentCfg.AgentSpec, cfg.Workspace, registration, logger)
+	var specModels map[framework.ModelRole]string
+	if agentCfg.AgentSpec != nil {
+		specModels = agentCfg.AgentSpec.Models
+	}
+	newModel := func(endpoint, name string) framework.LanguageModel {
+		client := llm.NewClient(endpoint, name)
+		client.SetDebugLogging(logLLM)
+		client.Throttle = workspaceCfg.LLMThrottle.Throttle(endpoint)
+		client.KeepAlive = lifecycle.keepAlive
+		clients = append(clients, client)
+		routed := llm.NewInstrumentedModel(replayModel(cacheModel(pools.wrap(client), client, responseCache), recorder, replayer), telemetry, logLLM)
+		routed.Usage = usage
+		return redactModel(routed, redactor)
+	}
+	router, err := framework.BuildModelRouter(model, framework.MergeModelAssignments(specModels, workspaceCfg.Models), func(name string) framework.LanguageModel {
+		return newModel(cfg.OllamaEndpoint, name)
+	})
+	if err != nil {
+		closeAll(mcpClosers)
+		responseCache.Close()
+		logFile.Close()
+		return nil, fmt.Errorf("model routing: %w", err)
+	}
+	agentCfg.Models = router
+	agentCfg.Summarizer = buildSummarizer(workspaceCfg.Summarizer, agentCfg.ModelFor(framework.ModelRoleSummarization, model), func(name string) framework.LanguageModel {
+		return newModel(cfg.OllamaEndpoint, name)
+	})
+	if review := workspaceCfg.Review; review != nil && review.Model != "" {
+		agentCfg.ReviewModel = newModel(review.EndpointOr(cfg.OllamaEndpoint), review.Model)
+		agentCfg.ReviewRounds = review.Rounds
+	}
+	if agentCfg.AgentSpec != nil {
+		timeouts, err := agentCfg.AgentSpec.Timeouts.GraphTimeouts()
+		if err != nil {
+			closeAll(mcpClosers)
+			responseCache.Close()
+			logFile.Close()
+			return nil, err
+		}
+		agentCfg.Timeouts = timeouts
+		retry, err := agentCfg.AgentSpec.Retry.RetryPolicy()
+		if err != nil {
+			closeAll(mcpClosers)
+			responseCache.Close()
+			logFile.Close()
+			return nil, err
+		}
+		agentCfg.Retry = retry
+	}
+	if routes := router.Names(); len(routes) > 0 {
+		logger.Printf("model routes: %s", strings.Join(routes, ", "))
+	}
 
+	var memoryStore framework.MemoryStore = memory
+	if redactor != nil {
+		memoryStore = framework.RedactingMemory{MemoryStore: memory, Redactor: redactor}
+	}
+	agentMemory := consolidatingMemory(memoryStore, workspaceCfg.MemoryConsolidation, agentCfg.ModelFor(framework.ModelRoleSummarization, model), logger)
 	// Enforce the effective (post-definition) tool policies before initializing.
 	if agentCfg.AgentSpec != nil {
 		framework.RestrictToolRegistryByMatrix(registry, agentCfg.AgentSpec.Tools)
 		registry.UseAgentSpec(registration.ID, agentCfg.AgentSpec)
 	}
-
-	if err := agent.Initialize(agentCfg); err != nil {
-		logFile.Close()
-		return nil, fmt.Errorf("initialize agent: %w", err)
+	if registration.Sandbox.Degraded || remote != nil {
+		if gated := registry.RequireApprovalFor(framework.RunsCommands); len(gated) > 0 {
+			logger.Printf("degraded sandbox: approval required for %s", strings.Join(gated, ", "))
+		}
 	}
-	if reflection, ok := agent.(*agents.ReflectionAgent); ok {
-		if reflection.Delegate != nil {
-			_ = reflection.Delegate.Initialize(agentCfg)
+
+	// Agents keep per-run state, so the server builds one per task with
+	// newAgent rather than sharing the runtime's.
+	newAgent := func() (framework.Agent, error) {
+		agent := instantiateAgent(cfg, def, model, registry, agentMemory, agentCfg)
+		if err := agent.Initialize(agentCfg); err != nil {
+			return nil, fmt.Errorf("initialize agent: %w", err)
+		}
+		if reflection, ok := agent.(*agents.ReflectionAgent); ok {
+			if reflection.Delegate != nil {
+				_ = reflection.Delegate.Initialize(agentCfg)
+			}
 		}
+		return agent, nil
+	}
+	agent, err := newAgent()
+	if err != nil {
+		closeAll(mcpClosers)
+		responseCache.Close()
+		logFile.Close()
+		return nil, err
 	}
 	if len(allowedTools) > 0 {
 		registry.RestrictTo(allowedTools)
 	}
+	workflows, err := openWorkflowStore(cfg.WorkflowPath, workspaceCfg.WorkflowStore)
+	if err != nil {
+		logger.Printf("warning: workflow store unavailable: %v", err)
+	}
+	sessions, err := persistence.NewFileSessionStore(cfg.SessionPath)
+	if err != nil {
+		logger.Printf("warning: session store unavailable: %v", err)
+	}
+	schedules, err := persistence.NewFileScheduleStore(cfg.WorkflowPath)
+	if err != nil {
+		logger.Printf("warning: schedule store unavailable: %v", err)
+	}
 	rt := &Runtime{
 		Config:       cfg,
 		Tools:        registry,
-		Memory:       memory,
+		Memory:       agentMemory,
 		Context:      framework.NewContext(),
 		Agent:        agent,
 		Model:        model,
@@ -198,37 +563,181 @@ func New(ctx context.Context, cfg Config) (*Runtime, error) {
 		logFile:      logFile,
 		Workspace:    workspaceCfg,
 		Registration: registration,
+		Usage:        usage,
+		Autonomy:     autonomy,
+		Events:       events,
+		Metrics:      metrics,
+		Project:      project,
+		FollowUps:    &FollowUpSession{},
+		Chat:         &ChatHistory{},
+		tracing:      tracing,
+		client:       modelClient,
+		lifecycle:    lifecycle,
+		clients:      append(clients, pools.clients()...),
+		agentConfig:  agentCfg,
+		hitlWebhooks: hitlWebhooks,
+		apiAuth:      apiAuth,
+		caches:       caches,
+		lsp:          lspProxy,
+		timeouts:     agentCfg.Timeouts,
+		auditClosers: auditClosers,
+		mcpClosers:   mcpClosers,
+		responseCache: responseCache,
+		recorder:      recorder,
+		Replayer:      replayer,
+		remote:        remote,
+		newAgent:      newAgent,
+	}
+	if workflows != nil {
+		rt.Workflows = workflows
+	}
+	if schedules != nil {
+		rt.Schedules = schedules
+	}
+	rt.Artifacts = artifacts
+	if pools != nil {
+		poolCtx, cancel := context.WithCancel(context.Background())
+		rt.stopPools = cancel
+		pools.watch(poolCtx)
+	}
+	if sessions != nil {
+		rt.Sessions = sessions
+	}
+	spill, err := openSpillFile(cfg)
+	if err != nil {
+		logger.Printf("warning: spill file unavailable: %v", err)
+	}
+	rt.Spill = spill
+	applyMemoryLimits(cfg, rt.Context, usage, spill, logger)
+	rt.Checkpoints = openFileCheckpoints(cfg, registration, logger)
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			logger.Printf("warning: startup cache not saved: %v", err)
+		}
 	}
+	logger.Printf("runtime ready in %s", time.Since(started).Round(time.Millisecond))
+	ready = true
 	return rt, nil
 }
 
+// openStartupCache returns the workspace's startup cache, or nil when caching
+// is disabled.
+func openStartupCache(cfg Config) *StartupCache {
+	if cfg.NoCache {
+		return nil
+	}
+	cache := OpenStartupCache(cfg.CacheDir, cfg.Workspace)
+	if cfg.RefreshCache {
+		cache.Reset()
+	}
+	return cache
+}
+
+// refreshGlossary extracts project vocabulary into project memory without
+// blocking startup. Agents pick it up on their next prompt.
+func refreshGlossary(workspace string, memory framework.MemoryStore, registration *framework.AgentRegistration, logger *log.Logger) {
+	extractor := &framework.GlossaryExtractor{Root: workspace, Filter: readableFilter(registration)}
+	if err := framework.RefreshGlossary(context.Background(), extractor, memory); err != nil {
+		logger.Printf("glossary extraction failed: %v", err)
+	}
+}
+
 // Close releases resources managed by runtime.
 func (r *Runtime) Close() error {
+	if r.Registration != nil {
+		if batching, ok := r.Registration.Audit.(*framework.BatchingAuditLogger); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := batching.Close(ctx); err != nil && r.Logger != nil {
+				r.Logger.Printf("audit flush: %v", err)
+			}
+			cancel()
+		}
+	}
+	if r.tracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := r.tracing.Close(ctx); err != nil && r.Logger != nil {
+			r.Logger.Printf("trace flush: %v", err)
+		}
+		cancel()
+	}
+	if r.stopPools != nil {
+		r.stopPools()
+	}
+	closeAll(r.mcpClosers)
+	closeAll(r.auditClosers)
+	CloseWorkflowStore(r.Workflows)
+	r.responseCache.Close()
+	if r.recorder != nil {
+		r.recorder.Close()
+	}
+	if err := r.remote.Close(); err != nil && r.Logger != nil {
+		r.Logger.Printf("remote workspace: %v", err)
+	}
 	if r.logFile != nil {
 		return r.logFile.Close()
 	}
 	return nil
 }
 
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
 // ToolRegistryOptions carries optional manifest/runtime policies into tool construction.
 type ToolRegistryOptions struct {
 	AgentID           string
 	PermissionManager *framework.PermissionManager
 	AgentSpec         *framework.AgentRuntimeSpec
+	// LSP overrides AgentSpec.LSP, for callers that apply the agent spec to
+	// the registry later.
+	LSP *framework.AgentLSPSpec
+	// Project scopes the test, build, and lint tools to one project of a
+	// monorepo; its LSP settings, when present, replace LSP.
+	Project *ProjectConfig
+	// OllamaEndpoint enables the embeddings index behind search_semantic and
+	// search_find_similar, using the model selected by Embeddings.
+	OllamaEndpoint string
+	Embeddings     *EmbeddingsConfig
+	// LLMThrottle limits the embedding requests like the model's.
+	LLMThrottle    *LLMThrottleConfig
+	FileEdit       *FileEditConfig
+	CodeExtraction *CodeExtractionConfig
+	Formatting     *FormattingConfig
+	// Databases enables db_query and db_schema.
+	Databases []db.Config
+	// Artifacts, when set, enables artifact_save.
+	Artifacts framework.ArtifactStore
+	// Remote starts language servers on the remote machine of a remote
+	// workspace; see RemoteConfig.
+	Remote *framework.RemoteHost
+	// GateCommands makes post-edit formatters ask for approval, for
+	// sandboxes that cannot contain them.
+	GateCommands bool
 }
 
 // BuildToolRegistry registers builtin tools scoped to the workspace.
 func BuildToolRegistry(workspace string, runner framework.CommandRunner, opts ...ToolRegistryOptions) (*framework.ToolRegistry, error) {
+	var cfg ToolRegistryOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	registry, _, _, err := buildToolRegistry(workspace, runner, cfg)
+	return registry, err
+}
+
+// buildToolRegistry also returns the invalidator for the caches backing the
+// LSP and AST tools, so the workspace watcher can keep them fresh, and the
+// LSP proxy when language servers are configured.
+func buildToolRegistry(workspace string, runner framework.CommandRunner, cfg ToolRegistryOptions) (*framework.ToolRegistry, watch.Invalidator, *tools.Proxy, error) {
 	if workspace == "" {
 		workspace = "."
 	}
 	if runner == nil {
-		return nil, fmt.Errorf("command runner required")
-	}
-	var cfg ToolRegistryOptions
-	if len(opts) > 0 {
-		cfg = opts[0]
+		return nil, nil, nil, fmt.Errorf("command runner required")
 	}
+	var caches cacheInvalidators
 	registry := framework.NewToolRegistry()
 	if cfg.PermissionManager != nil {
 		registry.UsePermissionManager(cfg.AgentID, cfg.PermissionManager)
@@ -236,79 +745,240 @@ func BuildToolRegistry(workspace string, runner framework.CommandRunner, opts ..
 	if cfg.AgentSpec != nil {
 		registry.UseAgentSpec(cfg.AgentID, cfg.AgentSpec)
 	}
+	// Reads and searches are recorded so final answers can cite them.
+	registry.UseToolHook(&tools.ContextProvenance{BasePath: workspace})
+	// Edits count against the task's max_files_modified budget, if any.
+	registry.UseToolHook(&tools.TaskBudgetFiles{BasePath: workspace})
 	register := func(tool framework.Tool) error {
 		if err := registry.Register(tool); err != nil {
 			return err
 		}
 		return nil
 	}
+	code, err := cfg.CodeExtraction.Pipeline()
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	for _, tool := range tools.FileOperations(workspace) {
-		if err := register(tool); err != nil {
-			return nil, err
+		switch tool := tool.(type) {
+		case *tools.EditFileTool:
+			if cfg.FileEdit != nil {
+				tool.MaxShrink = cfg.FileEdit.MaxShrink
+			}
+		case *tools.WriteFileTool:
+			tool.Code = code
+		case *tools.CreateFileTool:
+			tool.Code = code
 		}
-	}
-	for _, tool := range []framework.Tool{
-		&tools.GrepTool{BasePath: workspace},
-		&tools.SimilarityTool{BasePath: workspace},
-		&tools.SemanticSearchTool{BasePath: workspace},
-	} {
 		if err := register(tool); err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 	}
-	for _, tool := range []framework.Tool{
-		&tools.GitCommandTool{RepoPath: workspace, Command: "diff", Runner: runner},
-		&tools.GitCommandTool{RepoPath: workspace, Command: "history", Runner: runner},
-		&tools.GitCommandTool{RepoPath: workspace, Command: "branch", Runner: runner},
-		&tools.GitCommandTool{RepoPath: workspace, Command: "commit", Runner: runner},
-		&tools.GitCommandTool{RepoPath: workspace, Command: "blame", Runner: runner},
-	} {
+	for _, tool := range tools.EnvTools(workspace) {
 		if err := register(tool); err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 	}
+	semantic := &tools.SemanticSearchTool{BasePath: workspace}
+	similar := &tools.SimilarityTool{BasePath: workspace}
+	grep := &tools.GrepTool{BasePath: workspace}
+	// search_grep only declares rg when it may use it, so manifests that do
+	// not list rg keep the built-in search instead of losing the tool.
+	if cfg.PermissionManager == nil || cfg.PermissionManager.DeclaresExecutable("rg") {
+		grep.Runner = runner
+	}
 	for _, tool := range []framework.Tool{
-		&tools.RunTestsTool{Command: []string{"go", "test", "./..."}, Workdir: workspace, Timeout: 10 * time.Minute, Runner: runner},
-		&tools.RunLinterTool{Command: []string{"golangci-lint", "run"}, Workdir: workspace, Timeout: 5 * time.Minute, Runner: runner},
-		&tools.RunBuildTool{Command: []string{"go", "build", "./..."}, Workdir: workspace, Timeout: 10 * time.Minute, Runner: runner},
-		&tools.ExecuteCodeTool{Command: []string{"bash", "-c"}, Workdir: workspace, Timeout: 1 * time.Minute, Runner: runner},
+		grep,
+		similar,
+		semantic,
 	} {
 		if err := register(tool); err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 	}
-	for _, tool := range tools.CommandLineTools(workspace, runner) {
+	for _, tool := range commandTools(workspace, cfg.Project, runner) {
 		if err := register(tool); err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 	}
-	indexDir := filepath.Join(workspace, "relurpify_cfg", "memory", "ast_index")
-	if err := os.MkdirAll(indexDir, 0o755); err != nil {
-		return nil, err
-	}
-	store, err := ast.NewSQLiteStore(filepath.Join(indexDir, "index.db"))
-	if err != nil {
-		return nil, err
-	}
-	manager := ast.NewIndexManager(store, ast.IndexConfig{
-		WorkspacePath:   workspace,
-		ParallelWorkers: 4,
-	})
+	var pathFilter func(path string, isDir bool) bool
 	if cfg.PermissionManager != nil {
-		manager.SetPathFilter(func(path string, isDir bool) bool {
+		pathFilter = func(path string, isDir bool) bool {
 			action := framework.FileSystemRead
 			if isDir {
 				action = framework.FileSystemList
 			}
 			return cfg.PermissionManager.CheckFileAccess(context.Background(), cfg.AgentID, action, path) == nil
-		})
+		}
+	}
+	lsp := cfg.LSP
+	if lsp == nil && cfg.AgentSpec != nil {
+		lsp = &cfg.AgentSpec.LSP
+	}
+	lspRoot := workspace
+	if cfg.Project != nil && cfg.Project.LSP != nil {
+		lsp = cfg.Project.LSP
+		lspRoot = cfg.Project.Dir(workspace)
+	}
+	formatters, err := cfg.Formatting.Formatters()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Edited files are formatted before any later hook reads them.
+	var format *tools.PostEditFormat
+	if len(formatters) > 0 {
+		format = &tools.PostEditFormat{Formatters: formatters, Runner: runner, BasePath: workspace, Manager: cfg.PermissionManager, AgentID: cfg.AgentID, RequireApproval: cfg.GateCommands}
+		registry.UseToolHook(format)
+	}
+	var proxy *tools.Proxy
+	if lsp != nil && lsp.Enabled && len(lsp.Servers) > 0 {
+		var err error
+		proxy, err = buildLSPProxy(lspRoot, *lsp, cfg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// Each server's files are indexed in the background once it starts.
+		if pathFilter != nil {
+			proxy.SetSymbolIndexFilter(pathFilter)
+		}
+		proxy.EnableSymbolIndex(lspRoot, 0)
+		caches = append(caches, lspInvalidator(proxy))
+		for _, tool := range tools.LSPTools(proxy, workspace) {
+			if err := register(tool); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		// Edited files are checked with their language server so agents
+		// see the errors they introduce.
+		if format != nil {
+			format.Proxy = proxy
+		}
+		registry.UseToolHook(&tools.PostEditDiagnostics{Proxy: proxy, BasePath: workspace})
+	}
+	manager, store, err := OpenASTIndex(workspace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if pathFilter != nil {
+		manager.SetPathFilter(pathFilter)
 	}
 	tools.AttachASTSymbolProvider(manager, registry)
+	caches = append(caches, astInvalidator(manager))
+	if cfg.OllamaEndpoint != "" {
+		semantic.Index = NewEmbeddingIndex(workspace, cfg.OllamaEndpoint, store, cfg.Embeddings, cfg.LLMThrottle.Throttle(cfg.OllamaEndpoint))
+		similar.Index = semantic.Index
+		if pathFilter != nil {
+			semantic.Index.SetPathFilter(pathFilter)
+		}
+		caches = append(caches, embeddingsInvalidator(semantic.Index))
+	}
 	if err := register(tools.NewASTTool(manager)); err != nil {
-		return nil, err
+		return nil, nil, nil, err
+	}
+	for _, tool := range tools.ASTQueryTools(manager) {
+		if err := register(tool); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if err := register(&tools.FileRiskTool{RepoPath: workspace, Runner: runner, Index: manager, Proxy: proxy}); err != nil {
+		return nil, nil, nil, err
+	}
+	testdir := workspace
+	if cfg.Project != nil {
+		testdir = cfg.Project.Dir(workspace)
 	}
-	go manager.IndexWorkspace()
-	return registry, nil
+	if err := register(&tools.CoverageGapsTool{Toolchains: testrunner.Detect(testdir), Workdir: testdir, Timeout: 10 * time.Minute, Runner: runner, Index: manager}); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(cfg.Databases) > 0 {
+		for _, database := range cfg.Databases {
+			if err := database.Validate(); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		for _, tool := range tools.DatabaseTools(cfg.Databases, workspace, runner) {
+			if err := register(tool); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	if cfg.Artifacts != nil {
+		if err := register(&tools.ArtifactTool{Store: cfg.Artifacts, BasePath: workspace}); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for _, tool := range []framework.Tool{&tools.BusPublishTool{}, &tools.BusReadTool{}} {
+		if err := register(tool); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	// The workspace scan starts on the first AST query (see
+	// ast.IndexManager.EnsureIndexed).
+	return registry, caches, proxy, nil
+}
+
+// CommandTools returns the builtin tools that shell out to binaries: git, the
+// test/lint/build runners, and the CLI wrappers. Manifests must declare
+// their binaries under spec.permissions.executables.
+func CommandTools(workspace string, runner framework.CommandRunner) []framework.Tool {
+	return commandTools(workspace, nil, runner)
+}
+
+// commandTools scopes the test, build, lint, and code runners to project when
+// set. Git and CLI tools always work on the whole workspace.
+func commandTools(workspace string, project *ProjectConfig, runner framework.CommandRunner) []framework.Tool {
+	workdir := workspace
+	build := []string{"go", "build", "./..."}
+	lint := []string{"golangci-lint", "run"}
+	if project != nil {
+		workdir = project.Dir(workspace)
+		build = project.BuildCommand()
+		lint = project.LintCommand()
+	}
+	result := []framework.Tool{
+		&tools.GitCommandTool{RepoPath: workspace, Command: "diff", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "history", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "branch", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "commit", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "blame", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "branch_create", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "stage", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "commit_with_message", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "stash", Runner: runner},
+		&tools.GitCommandTool{RepoPath: workspace, Command: "revert", Runner: runner},
+		&tools.GitHubIssueTool{RepoPath: workspace, Runner: runner},
+		&tools.RunTestsTool{Toolchains: testrunner.Detect(workdir), Workdir: workdir, Timeout: 10 * time.Minute, Runner: runner},
+		&tools.RunLinterTool{Command: lint, Workdir: workdir, Timeout: 5 * time.Minute, Runner: runner},
+		&tools.RunBuildTool{Command: build, Workdir: workdir, Timeout: 10 * time.Minute, Runner: runner},
+		&tools.ExecuteCodeTool{Command: []string{"bash", "-c"}, Workdir: workdir, Timeout: 1 * time.Minute, Runner: runner},
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "go.mod")); err == nil {
+		result = append(result, tools.GoTools(workdir, runner)...)
+	}
+	return append(result, tools.CommandLineTools(workspace, runner)...)
+}
+
+// ASTIndexPath returns the SQLite database backing the workspace AST index.
+func ASTIndexPath(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "memory", "ast_index", "index.db")
+}
+
+// OpenASTIndex opens (creating when needed) the workspace AST index. Callers
+// that outlive the process-wide registry should close the returned store.
+func OpenASTIndex(workspace string) (*ast.IndexManager, *ast.SQLiteStore, error) {
+	dbPath := ASTIndexPath(workspace)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, nil, err
+	}
+	store, err := ast.NewSQLiteStore(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	manager := ast.NewIndexManager(store, ast.IndexConfig{
+		WorkspacePath:   workspace,
+		ParallelWorkers: 4,
+	})
+	return manager, store, nil
 }
 
 // LoadAgentDefinitions scans the directory for YAML files and parses them.
@@ -338,55 +1008,89 @@ func LoadAgentDefinitions(dir string) (map[string]*framework.AgentDefinition, er
 	return defs, nil
 }
 
-// instantiateAgent picks the concrete agent implementation for the CLI preset.
-func instantiateAgent(cfg Config, model framework.LanguageModel, registry *framework.ToolRegistry, memory framework.MemoryStore, defs map[string]*framework.AgentDefinition, agentCfg *framework.Config) framework.Agent {
-	// Check file-based definitions first
-	if def, ok := defs[cfg.AgentName]; ok {
-		// Update config with the definition's spec
-		agentCfg.AgentSpec = &def.Spec
-		agentCfg.OllamaToolCalling = def.Spec.ToolCallingEnabled()
-		if def.Spec.Model.Name != "" {
-			agentCfg.Model = def.Spec.Model.Name
-		}
+// applyAgentDefinition applies the file-based definition selected by the CLI
+// preset to agentCfg and returns it, or nil when the preset is builtin.
+func applyAgentDefinition(cfg Config, defs map[string]*framework.AgentDefinition, agentCfg *framework.Config) *framework.AgentDefinition {
+	def, ok := defs[cfg.AgentName]
+	if !ok {
+		return nil
+	}
+	agentCfg.AgentSpec = &def.Spec
+	agentCfg.OllamaToolCalling = def.Spec.ToolCallingEnabled()
+	if def.Spec.Model.Name != "" {
+		agentCfg.Model = def.Spec.Model.Name
+	}
+	return def
+}
 
+// instantiateAgent picks the concrete agent implementation for the CLI preset.
+// Models are resolved through agentCfg's router so planners, reviewers, and
+// coders each get the model assigned to their role.
+func instantiateAgent(cfg Config, def *framework.AgentDefinition, model framework.LanguageModel, registry *framework.ToolRegistry, memory framework.MemoryStore, agentCfg *framework.Config) framework.Agent {
+	planning := agentCfg.ModelFor(framework.ModelRolePlanning, model)
+	coding := agentCfg.ModelFor(framework.ModelRoleCoding, model)
+	review := agentCfg.ModelFor(framework.ModelRoleReview, model)
+	if def != nil {
 		// Use the Implementation field to pick struct
 		switch def.Spec.Implementation {
 		case "planner":
-			return &agents.PlannerAgent{Model: model, Tools: registry, Memory: memory}
+			return &agents.PlannerAgent{Model: planning, Tools: registry, Memory: memory}
 		case "react":
-			return &agents.ReActAgent{Model: model, Tools: registry, Memory: memory}
+			return &agents.ReActAgent{Model: coding, Tools: registry, Memory: memory}
 		case "eternal":
 			return &agents.EternalAgent{Model: model}
+		case "explain":
+			return &agents.ExplainAgent{Model: coding, Tools: registry, Memory: memory}
+		case "qa":
+			return &agents.QAAgent{Model: coding, Tools: registry, Memory: memory}
+		case "graph":
+			return &agents.GraphAgent{Model: coding, Tools: registry, Memory: memory, Spec: def.Spec.Graph}
 		// TODO: Add support for creating agents directly from 'def' struct fields (system prompt, etc)
 		// For now we map them to existing Go structs.
 		default:
 			// Fallback to ReAct if unspecified but defined
-			return &agents.ReActAgent{Model: model, Tools: registry, Memory: memory, Mode: string(def.Spec.Mode)}
+			return &agents.ReActAgent{Model: coding, Tools: registry, Memory: memory, Mode: string(def.Spec.Mode)}
 		}
 	}
 
 	switch cfg.AgentLabel() {
 	case "planner":
-		return &agents.PlannerAgent{Model: model, Tools: registry, Memory: memory}
+		return &agents.PlannerAgent{Model: planning, Tools: registry, Memory: memory}
 	case "react":
-		return &agents.ReActAgent{Model: model, Tools: registry, Memory: memory}
+		return &agents.ReActAgent{Model: coding, Tools: registry, Memory: memory}
 	case "reflection":
 		return &agents.ReflectionAgent{
-			Reviewer: model,
-			Delegate: &agents.CodingAgent{Model: model, Tools: registry, Memory: memory},
+			Reviewer: review,
+			Delegate: &agents.CodingAgent{Model: coding, Tools: registry, Memory: memory},
 		}
 	case "expert":
 		return &agents.ExpertCoderAgent{Model: model, Tools: registry, Memory: memory}
 	default:
-		return &agents.CodingAgent{Model: model, Tools: registry, Memory: memory}
+		return &agents.CodingAgent{Model: coding, Tools: registry, Memory: memory}
 	}
 }
 
 // RunTask executes a task against the configured agent while preserving shared
 // context state for future status screens.
 func (r *Runtime) RunTask(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+	res, _, err := r.runTask(ctx, task)
+	return res, err
+}
+
+// runTask is RunTask that also returns the task's final state.
+func (r *Runtime) runTask(ctx context.Context, task *framework.Task) (*framework.Result, *framework.Context, error) {
 	if task == nil {
-		return nil, errors.New("task required")
+		return nil, nil, errors.New("task required")
+	}
+	ctx = framework.WithGraphTimeouts(ctx, r.timeouts)
+	if task.Budget != nil {
+		var stopBudget context.CancelFunc
+		ctx, _, stopBudget = framework.WithTaskBudget(ctx, *task.Budget)
+		defer stopBudget()
+	}
+	// The task's agents, delegates included, share findings on one bus.
+	if framework.MessageBusFrom(ctx) == nil {
+		ctx = framework.WithMessageBus(ctx, framework.NewMessageBus(task.ID, r.Memory))
 	}
 	state := r.Context.Clone()
 	state.Set("task.id", task.ID)
@@ -397,14 +1101,117 @@ func (r *Runtime) RunTask(ctx context.Context, task *framework.Task) (*framework
 			state.Set("task.source", fmt.Sprint(source))
 		}
 	}
+	state.Set("task.agent", r.Config.AgentLabel())
+	ctx, finishRecording := r.startRecording(ctx, task)
+	defer finishRecording()
+	start := time.Now()
 	res, err := r.Agent.Execute(ctx, task, state)
+	if exceeded := framework.BudgetExceeded(ctx); exceeded != nil && err != nil && !errors.As(err, new(*framework.BudgetExceededError)) {
+		// The agent surfaced the cancellation without the graph's report.
+		err = exceeded
+	}
+	r.Metrics.ObserveTask(task, res, err, time.Since(start))
 	if err == nil {
 		r.Context.Merge(state)
+	} else if res != nil && res.Error == nil {
+		res.Error = err
 	}
-	return res, err
+	if r.Replayer == nil {
+		// A replay must not overwrite the recorded task's workflow.
+		r.saveWorkflow(ctx, task, state, err)
+	}
+	return res, state, err
+}
+
+// saveWorkflow records the finished task and its token usage so `relurpish
+// workflow show` can report them after the process exits. Timed out tasks
+// also keep the outputs of the nodes that completed, and tasks stopped by
+// their budget keep their whole context so they can be resumed.
+func (r *Runtime) saveWorkflow(ctx context.Context, task *framework.Task, state *framework.Context, runErr error) {
+	if r.Workflows == nil {
+		return
+	}
+	snapshot := &persistence.WorkflowSnapshot{
+		ID:     task.ID,
+		Task:   task,
+		Status: persistence.WorkflowStatusCompleted,
+		Agent:  r.Config.AgentName,
+	}
+	if runErr != nil {
+		snapshot.Status = persistence.WorkflowStatusFailed
+		snapshot.Metadata = map[string]interface{}{"error": runErr.Error()}
+		if kind := framework.ErrorKindOf(runErr); kind != "" {
+			snapshot.Metadata["error_kind"] = string(kind)
+		}
+	}
+	var timeoutErr *framework.TimeoutError
+	if errors.As(runErr, &timeoutErr) {
+		snapshot.Status = persistence.WorkflowStatusTimedOut
+		snapshot.Metadata["timed_out_node"] = timeoutErr.NodeID
+		snapshot.Metadata["timeout_scope"] = string(timeoutErr.Scope)
+		snapshot.Metadata["completed_nodes"] = timeoutErr.Completed
+		if partial := partialNodeOutputs(state, timeoutErr.Completed); len(partial) > 0 {
+			snapshot.Metadata["partial"] = partial
+		}
+	}
+	var budgetErr *framework.BudgetExceededError
+	if errors.As(runErr, &budgetErr) {
+		snapshot.Status = persistence.WorkflowStatusBudgetExceeded
+		snapshot.Metadata["budget_limit"] = string(budgetErr.Limit)
+		snapshot.Metadata["stopped_node"] = budgetErr.NodeID
+		snapshot.Metadata["completed_nodes"] = budgetErr.Completed
+		if partial := partialNodeOutputs(state, budgetErr.Completed); len(partial) > 0 {
+			snapshot.Metadata["partial"] = partial
+		}
+		snapshot.Graph = r.resumeSnapshot(state, budgetErr.NodeID)
+	}
+	if r.Usage != nil {
+		summary := r.Usage.Summary(framework.UsageFilter{TaskID: task.ID})
+		snapshot.Usage = &summary
+	}
+	if err := r.Workflows.Save(context.WithoutCancel(ctx), snapshot); err != nil && r.Logger != nil {
+		r.Logger.Printf("workflow save failed: %v", err)
+	}
+}
+
+// resumeSnapshot captures state for `relurpish workflow resume`. It is nil
+// when the state holds values JSON cannot store, so the rest of the workflow
+// is still saved.
+func (r *Runtime) resumeSnapshot(state *framework.Context, nodeID string) *framework.GraphSnapshot {
+	if state == nil {
+		return nil
+	}
+	snapshot := &framework.GraphSnapshot{NodeID: nodeID, State: state.Snapshot()}
+	if _, err := json.Marshal(snapshot); err != nil {
+		if r.Logger != nil {
+			r.Logger.Printf("workflow resume snapshot skipped: %v", err)
+		}
+		return nil
+	}
+	return snapshot
+}
+
+// partialNodeOutputs collects the "<node>.<key>" entries the graph stored for
+// each completed node.
+func partialNodeOutputs(state *framework.Context, nodes []string) map[string]interface{} {
+	if state == nil || len(nodes) == 0 {
+		return nil
+	}
+	values := state.Snapshot().State
+	partial := make(map[string]interface{})
+	for _, node := range nodes {
+		prefix := node + "."
+		for key, value := range values {
+			if strings.HasPrefix(key, prefix) {
+				partial[key] = value
+			}
+		}
+	}
+	return partial
 }
 
-// ExecuteInstruction convenience helper.
+// ExecuteInstruction runs a shell instruction. It follows up on the previous
+// instruction's outcome until FollowUps is reset.
 func (r *Runtime) ExecuteInstruction(ctx context.Context, instruction string, taskType framework.TaskType, metadata map[string]any) (*framework.Result, error) {
 	if taskType == "" {
 		taskType = framework.TaskTypeCodeModification
@@ -426,7 +1233,16 @@ func (r *Runtime) ExecuteInstruction(ctx context.Context, instruction string, ta
 		Context:     metadata,
 		Metadata:    metaStrings,
 	}
-	return r.RunTask(ctx, task)
+	return r.runFollowUp(ctx, task)
+}
+
+// SandboxStatus reports whether commands run sandboxed or degraded on the
+// host.
+func (r *Runtime) SandboxStatus() framework.SandboxStatus {
+	if r.Registration == nil {
+		return framework.SandboxStatus{}
+	}
+	return r.Registration.Sandbox
 }
 
 // StartServer launches the HTTP API server. The returned stop function shuts
@@ -440,7 +1256,32 @@ func (r *Runtime) StartServer(ctx context.Context, addr string) (func(context.Co
 	if addr == "" {
 		addr = r.Config.ServerAddr
 	}
-	api := &server.APIServer{Agent: r.Agent, Context: r.Context, Logger: r.Logger}
+	api := &server.APIServer{
+		Agent:        r.Agent,
+		NewAgent:     r.newAgent,
+		Context:      r.Context,
+		Logger:       r.Logger,
+		Queue:        server.TaskQueueConfig{Workers: r.Config.ServerWorkers},
+		Usage:        r.Usage,
+		Autonomy:     r.Autonomy,
+		Timeouts:     r.timeouts,
+		Sandbox:      r.SandboxStatus(),
+		Events:       r.Events,
+		Memory:       r.Memory,
+		Workflows:    r.Workflows,
+		HITLWebhooks: r.hitlWebhooks,
+		Metrics:      r.Metrics,
+		Auth:         r.apiAuth,
+		Schedules:    r.Schedules,
+		Recipes:      r.resolveRecipe,
+		Artifacts:    r.Artifacts,
+	}
+	if !api.Auth.Enabled() && !loopbackAddr(addr) {
+		r.Logger.Printf("warning: API on %s has no api_keys; any client that can reach it may submit tasks and approve requests", addr)
+	}
+	if r.Registration != nil {
+		api.HITL = r.Registration.HITL
+	}
 	serverCtx, cancel := context.WithCancel(ctx)
 	errCh := make(chan error, 1)
 	go func() {
@@ -8,6 +8,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/server"
 )
 
 // TestWorkspaceGlob ensures workspace paths convert into recursive globs.
@@ -27,6 +30,77 @@ func TestBuildPermissionSetProfiles(t *testing.T) {
 	require.Greater(t, len(write.FileSystem), len(readonly.FileSystem))
 }
 
+// TestPermissionPresets checks that each tier widens the one before it.
+func TestPermissionPresets(t *testing.T) {
+	dir := t.TempDir()
+	for input, want := range map[string]PermissionProfile{
+		"read-only":           PermissionProfileReadOnly,
+		"workspace_write":     PermissionProfileWorkspaceWrite,
+		"Workspace-Write+Net": PermissionProfileWorkspaceWriteNet,
+		"full":                PermissionProfileFull,
+	} {
+		got, err := ParsePermissionProfile(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+	_, err := ParsePermissionProfile("root")
+	require.ErrorContains(t, err, "unknown permission preset")
+
+	net, err := PermissionPreset(dir, "workspace-write+net")
+	require.NoError(t, err)
+	require.Len(t, net.Network, 3)
+	require.Equal(t, "*", net.Network[2].Host)
+	full, err := PermissionPreset(dir, PermissionProfileFull)
+	require.NoError(t, err)
+	require.Equal(t, framework.PermissionAnyBinary, full.Executables[0].Binary)
+	for _, profile := range PermissionProfiles() {
+		perms := buildPermissionSet(dir, profile)
+		require.NoError(t, perms.Validate(), profile)
+	}
+}
+
+// TestApplyPermissionPresetKeepsAgentSpec rewrites only spec.permissions.
+func TestApplyPermissionPresetKeepsAgentSpec(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Workspace = dir
+	cfg.ManifestPath = filepath.Join(dir, "agent.manifest.yaml")
+	cfg.ConfigPath = filepath.Join(dir, "relurpify_cfg", "config.yaml")
+	manifest := `apiVersion: relurpify/v1alpha1
+kind: AgentManifest
+metadata:
+  name: coder
+spec:
+  image: ghcr.io/relurpify/runtime:latest
+  runtime: gvisor
+  permissions:
+    filesystem:
+      - action: fs:read
+        path: ` + workspaceGlob(dir) + `
+  agent:
+    implementation: coding
+    mode: primary
+    model:
+      provider: ollama
+      name: qwen2.5-coder
+    tools:
+      file_read: true
+`
+	require.NoError(t, os.WriteFile(cfg.ManifestPath, []byte(manifest), 0o644))
+
+	summary, err := ApplyPermissionPreset(cfg, "workspace-write+net")
+	require.NoError(t, err)
+	require.Empty(t, summary.Error)
+	require.Equal(t, 3, summary.Network)
+	loaded, err := framework.LoadAgentManifest(cfg.ManifestPath)
+	require.NoError(t, err)
+	require.Equal(t, "qwen2.5-coder", loaded.Spec.Agent.Model.Name)
+	require.Equal(t, buildPermissionSet(dir, PermissionProfileWorkspaceWriteNet), loaded.Spec.Permissions)
+	wcfg, err := LoadWorkspaceConfig(cfg.ConfigPath)
+	require.NoError(t, err)
+	require.Equal(t, PermissionProfileWorkspaceWriteNet, wcfg.PermissionProfile)
+}
+
 // TestSaveManifestCreatesFile confirms the wizard saves manifests + config.
 func TestSaveManifestCreatesFile(t *testing.T) {
 	dir := t.TempDir()
@@ -62,3 +136,53 @@ func TestProbeEnvironmentHandlesMissingRunsc(t *testing.T) {
 	report := ProbeEnvironment(context.Background(), cfg)
 	require.Contains(t, strings.Join(report.Sandbox.Errors, " "), "runsc not found")
 }
+
+// TestBuildHITLWebhooks merges config.yaml hooks with CLI URLs and validates
+// events and secrets.
+func TestBuildHITLWebhooks(t *testing.T) {
+	t.Setenv("TEST_HITL_SECRET", "s3cret")
+	ws := WorkspaceConfig{HITLWebhooks: []HITLWebhookConfig{{
+		URL:       "https://hooks.example/a",
+		SecretEnv: "TEST_HITL_SECRET",
+		Events:    []string{"requested"},
+	}}}
+	hooks, err := buildHITLWebhooks(Config{HITLWebhooks: []string{"https://hooks.example/b"}}, ws, nil)
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	require.Equal(t, "s3cret", hooks[0].Secret)
+	require.Len(t, hooks[0].Events, 1)
+	require.Equal(t, "https://hooks.example/b", hooks[1].URL)
+
+	_, err = buildHITLWebhooks(Config{}, WorkspaceConfig{HITLWebhooks: []HITLWebhookConfig{{URL: "https://x", Events: []string{"granted"}}}}, nil)
+	require.Error(t, err)
+	_, err = buildHITLWebhooks(Config{}, WorkspaceConfig{HITLWebhooks: []HITLWebhookConfig{{URL: "https://x", SecretEnv: "TEST_HITL_UNSET"}}}, nil)
+	require.Error(t, err)
+	_, err = buildHITLWebhooks(Config{HITLWebhooks: []string{"not a url"}}, WorkspaceConfig{}, nil)
+	require.Error(t, err)
+}
+
+func TestBuildAPIAuth(t *testing.T) {
+	t.Setenv(apiKeyEnv, "")
+	auth, err := buildAPIAuth(WorkspaceConfig{}, nil)
+	require.NoError(t, err)
+	require.False(t, auth.Enabled())
+
+	t.Setenv("TEST_CI_KEY", "ci-secret")
+	t.Setenv(apiKeyEnv, "admin-secret")
+	auth, err = buildAPIAuth(WorkspaceConfig{APIKeys: []APIKeyConfig{{Name: "ci", KeyEnv: "TEST_CI_KEY", Roles: []string{"submit-tasks"}}}}, nil)
+	require.NoError(t, err)
+	require.Len(t, auth.Keys, 2)
+	require.Equal(t, "ci-secret", auth.Keys[0].Key)
+	require.True(t, auth.Keys[0].Allows(server.APIRoleSubmitTasks))
+	require.False(t, auth.Keys[0].Allows(server.APIRoleApproveHITL))
+	require.True(t, auth.Keys[1].Allows(server.APIRoleApproveHITL))
+
+	_, err = buildAPIAuth(WorkspaceConfig{APIKeys: []APIKeyConfig{{Name: "ci", KeyEnv: "TEST_UNSET_KEY", Roles: []string{"admin"}}}}, nil)
+	require.Error(t, err)
+	_, err = buildAPIAuth(WorkspaceConfig{APIKeys: []APIKeyConfig{{Name: "ci", KeyEnv: "TEST_CI_KEY", Roles: []string{"root"}}}}, nil)
+	require.Error(t, err)
+
+	require.True(t, loopbackAddr("127.0.0.1:8080"))
+	require.True(t, loopbackAddr("localhost:8080"))
+	require.False(t, loopbackAddr(":8080"))
+}
@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexcodex/relurpify/agents"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/server"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// SelfTestOptions configures RunSelfTest.
+type SelfTestOptions struct {
+	Tasks int
+	// Latency is the mock model's simulated inference time per call.
+	Latency time.Duration
+	// Workspace is the fixture directory tasks write into. Empty uses a
+	// temporary directory that is removed afterwards.
+	Workspace string
+}
+
+// selfTestDir holds the marker files tasks write inside the fixture.
+const selfTestDir = "selftest"
+
+// RunSelfTest stress-tests the HTTP task queue without Ollama. A ReAct agent
+// backed by llm.MockModel handles every task: its first step writes a marker
+// file named after the task into the fixture workspace, its second completes.
+// Besides the queue report, the run checks each marker holds its own task ID
+// (no lost or crossed writes between workers) and that the model never saw
+// more concurrent calls than there are workers.
+func RunSelfTest(ctx context.Context, cfg Config, opts SelfTestOptions) (*server.SelfTestReport, error) {
+	workspace := opts.Workspace
+	if workspace == "" {
+		dir, err := os.MkdirTemp("", "relurpish-selftest-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		workspace = dir
+	}
+	if err := writeSelfTestFixture(workspace); err != nil {
+		return nil, err
+	}
+
+	registry := framework.NewToolRegistry()
+	for _, tool := range []framework.Tool{
+		&tools.ReadFileTool{BasePath: workspace},
+		&tools.WriteFileTool{BasePath: workspace},
+	} {
+		if err := registry.Register(tool); err != nil {
+			return nil, err
+		}
+	}
+	mock := &llm.MockModel{Latency: opts.Latency, Respond: selfTestResponse}
+	usage := framework.NewUsageTracker(nil)
+	model := llm.NewInstrumentedModel(mock, nil, false)
+	model.Usage = usage
+	agentCfg := &framework.Config{
+		Name:              "selftest",
+		Model:             "mock",
+		MaxIterations:     4,
+		OllamaToolCalling: true,
+	}
+	// Each task gets its own agent, as in the real server.
+	newAgent := func() (framework.Agent, error) {
+		agent := &agents.ReActAgent{Model: model, Tools: registry}
+		if err := agent.Initialize(agentCfg); err != nil {
+			return nil, err
+		}
+		return agent, nil
+	}
+	agent, err := newAgent()
+	if err != nil {
+		return nil, err
+	}
+	api := &server.APIServer{
+		Agent:    agent,
+		NewAgent: newAgent,
+		Context:  framework.NewContext(),
+		Queue:    server.TaskQueueConfig{Workers: (*JobsConfig)(nil).Workers(cfg.ServerWorkers)},
+		Usage:    usage,
+	}
+	report, err := server.RunSelfTest(ctx, api, server.SelfTestOptions{
+		Tasks: opts.Tasks,
+		Verify: func(record server.TaskRecord) error {
+			data, err := os.ReadFile(filepath.Join(workspace, selfTestDir, record.ID+".txt"))
+			if err != nil {
+				return fmt.Errorf("marker missing: %w", err)
+			}
+			if string(data) != record.ID {
+				return fmt.Errorf("marker holds %q", data)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if peak := mock.MaxInFlight(); peak > report.Workers {
+		report.Failures = append(report.Failures, fmt.Sprintf("model saw %d concurrent calls with %d workers", peak, report.Workers))
+	}
+	return report, nil
+}
+
+// selfTestResponse scripts the mock model per task: write the marker, then
+// finish.
+func selfTestResponse(call llm.MockCall) *framework.LLMResponse {
+	if call.Seq == 0 {
+		return &framework.LLMResponse{
+			Text: "writing marker",
+			ToolCalls: []framework.ToolCall{{
+				ID:   "call-" + call.TaskID,
+				Name: "file_write",
+				Args: map[string]interface{}{
+					"path":    filepath.Join(selfTestDir, call.TaskID+".txt"),
+					"content": call.TaskID,
+				},
+			}},
+		}
+	}
+	return &framework.LLMResponse{Text: `{"thought":"marker written","complete":true}`}
+}
+
+// writeSelfTestFixture seeds a tiny Go module so tasks run against a
+// realistic workspace layout.
+func writeSelfTestFixture(dir string) error {
+	files := map[string]string{
+		"go.mod":  "module selftest\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(filepath.Join(dir, selfTestDir), 0o755)
+}
@@ -0,0 +1,25 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSelfTestPasses(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report, err := RunSelfTest(ctx, Config{ServerWorkers: 3}, SelfTestOptions{
+		Tasks:     20,
+		Latency:   time.Millisecond,
+		Workspace: t.TempDir(),
+	})
+	require.NoError(t, err)
+	require.Empty(t, report.Failures)
+	require.True(t, report.OK())
+	require.Equal(t, 3, report.Workers)
+	require.LessOrEqual(t, report.MaxRunning, 3)
+	require.Equal(t, 40, report.LLMCalls)
+}
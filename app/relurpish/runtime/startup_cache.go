@@ -0,0 +1,253 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// startupCacheVersion invalidates every entry when the file layout changes.
+const startupCacheVersion = 1
+
+// sandboxCacheTTL bounds how long a sandbox verification is trusted even when
+// the binaries are unchanged; docker's runtime registration lives in daemon
+// config we do not fingerprint.
+const sandboxCacheTTL = 24 * time.Hour
+
+// StartupCache persists expensive startup results (sandbox verification and
+// plugin tool discovery) per workspace. Every entry carries a fingerprint of
+// the binaries and specs it was computed from, so upgrading runsc or a plugin
+// invalidates it with a stat call instead of a subprocess.
+type StartupCache struct {
+	path string
+	now  func() time.Time
+
+	mu    sync.Mutex
+	data  startupCacheFile
+	dirty bool
+}
+
+type startupCacheFile struct {
+	Version   int                          `json:"version"`
+	Workspace string                       `json:"workspace"`
+	Sandbox   *sandboxCacheEntry           `json:"sandbox,omitempty"`
+	Plugins   map[string]pluginCacheEntry  `json:"plugins,omitempty"`
+	Models    map[string]ModelCapabilities `json:"models,omitempty"`
+}
+
+type sandboxCacheEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Version     string    `json:"version"`
+	VerifiedAt  time.Time `json:"verified_at"`
+}
+
+type pluginCacheEntry struct {
+	Fingerprint string                       `json:"fingerprint"`
+	Tools       []tools.PluginToolDescriptor `json:"tools"`
+}
+
+// StartupCachePath returns the cache file for workspace inside dir. Files are
+// keyed by a hash of the workspace path so one cache directory serves every
+// checkout.
+func StartupCachePath(dir, workspace string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(workspace)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// OpenStartupCache loads the cache for workspace. A missing, corrupt, or
+// outdated file yields an empty cache rather than an error.
+func OpenStartupCache(dir, workspace string) *StartupCache {
+	c := &StartupCache{path: StartupCachePath(dir, workspace), now: time.Now}
+	c.data = startupCacheFile{Version: startupCacheVersion, Workspace: workspace}
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	var loaded startupCacheFile
+	if json.Unmarshal(raw, &loaded) == nil && loaded.Version == startupCacheVersion && loaded.Workspace == workspace {
+		c.data = loaded
+	}
+	return c
+}
+
+// Reset drops every entry, forcing the next startup to recompute them.
+func (c *StartupCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = startupCacheFile{Version: startupCacheVersion, Workspace: c.data.Workspace}
+	c.dirty = true
+}
+
+// Save writes the cache when it changed. The write goes through a temp file
+// so a concurrent shell never reads a partial cache.
+func (c *StartupCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	raw, err := json.Marshal(c.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// LookupSandbox implements framework.SandboxVerificationCache.
+func (c *StartupCache) LookupSandbox(cfg framework.SandboxConfig) (string, bool) {
+	fingerprint, err := sandboxFingerprint(cfg)
+	if err != nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.data.Sandbox
+	if entry == nil || entry.Fingerprint != fingerprint || c.now().Sub(entry.VerifiedAt) > sandboxCacheTTL {
+		return "", false
+	}
+	return entry.Version, true
+}
+
+// StoreSandbox implements framework.SandboxVerificationCache.
+func (c *StartupCache) StoreSandbox(cfg framework.SandboxConfig, version string) {
+	fingerprint, err := sandboxFingerprint(cfg)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Sandbox = &sandboxCacheEntry{Fingerprint: fingerprint, Version: version, VerifiedAt: c.now()}
+	c.dirty = true
+}
+
+// LookupPlugin returns the cached describe result for spec.
+func (c *StartupCache) LookupPlugin(spec framework.AgentPluginSpec) ([]tools.PluginToolDescriptor, bool) {
+	fingerprint, err := pluginFingerprint(spec)
+	if err != nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Plugins[spec.Name]
+	if !ok || entry.Fingerprint != fingerprint {
+		return nil, false
+	}
+	return entry.Tools, true
+}
+
+// StorePlugin records the describe result for spec.
+func (c *StartupCache) StorePlugin(spec framework.AgentPluginSpec, descriptors []tools.PluginToolDescriptor) {
+	fingerprint, err := pluginFingerprint(spec)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Plugins == nil {
+		c.data.Plugins = make(map[string]pluginCacheEntry)
+	}
+	c.data.Plugins[spec.Name] = pluginCacheEntry{Fingerprint: fingerprint, Tools: descriptors}
+	c.dirty = true
+}
+
+// LookupModel returns the probed capabilities of model at endpoint while
+// they are younger than modelCacheTTL.
+func (c *StartupCache) LookupModel(endpoint, model string) (ModelCapabilities, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	caps, ok := c.data.Models[modelCacheKey(endpoint, model)]
+	if !ok || c.now().Sub(caps.CheckedAt) > modelCacheTTL {
+		return ModelCapabilities{}, false
+	}
+	return caps, true
+}
+
+// StoreModel records the probed capabilities of a model at endpoint.
+func (c *StartupCache) StoreModel(endpoint string, caps ModelCapabilities) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Models == nil {
+		c.data.Models = make(map[string]ModelCapabilities)
+	}
+	c.data.Models[modelCacheKey(endpoint, caps.Model)] = caps
+	c.dirty = true
+}
+
+func modelCacheKey(endpoint, model string) string {
+	return endpoint + " " + model
+}
+
+// sandboxFingerprint covers the sandbox config plus the runsc and container
+// runtime binaries.
+func sandboxFingerprint(cfg framework.SandboxConfig) (string, error) {
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{string(encoded)}
+	for _, binary := range []string{cfg.RunscPath, cfg.ContainerRuntime} {
+		stat, err := binaryFingerprint(binary)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, stat)
+	}
+	return hashParts(parts), nil
+}
+
+// pluginFingerprint covers the plugin spec and its binary.
+func pluginFingerprint(spec framework.AgentPluginSpec) (string, error) {
+	if len(spec.Command) == 0 {
+		return "", errors.New("plugin command missing")
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	stat, err := binaryFingerprint(spec.Command[0])
+	if err != nil {
+		return "", err
+	}
+	return hashParts([]string{string(encoded), stat}), nil
+}
+
+// binaryFingerprint resolves binary on PATH and describes it by path, size,
+// and modification time.
+func binaryFingerprint(binary string) (string, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func hashParts(parts []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
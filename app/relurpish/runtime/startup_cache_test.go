@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// fakeBinaries puts failing runsc/docker/plugin stubs on PATH so cache hits
+// are the only way verification can succeed.
+func fakeBinaries(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\nexit 1\n"), 0o755))
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+// TestStartupCacheSkipsSandboxVerification primes the cache and confirms
+// RegisterAgent trusts it until the runsc binary changes.
+func TestStartupCacheSkipsSandboxVerification(t *testing.T) {
+	bin := fakeBinaries(t, "runsc", "docker")
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Workspace = dir
+	cfg.ManifestPath = filepath.Join(dir, "agent.manifest.yaml")
+	cfg.ConfigPath = filepath.Join(dir, "relurpify_cfg", "config.yaml")
+	_, err := SaveManifest(context.Background(), cfg, WizardSelection{
+		Model:   "qwen2.5-coder",
+		Agents:  []string{"coding"},
+		Profile: PermissionProfileReadOnly,
+	})
+	require.NoError(t, err)
+
+	runtimeCfg := framework.RuntimeConfig{ManifestPath: cfg.ManifestPath, Sandbox: cfg.Sandbox, BaseFS: dir, RequireSandbox: true}
+	_, err = framework.RegisterAgent(context.Background(), runtimeCfg)
+	require.Error(t, err, "stub runsc fails verification")
+
+	cacheDir := t.TempDir()
+	cache := OpenStartupCache(cacheDir, dir)
+	sandbox := framework.NewGVisorRuntime(cfg.Sandbox).RunConfig()
+	cache.StoreSandbox(sandbox, "runsc version cached")
+	require.NoError(t, cache.Save())
+
+	runtimeCfg.SandboxCache = OpenStartupCache(cacheDir, dir)
+	registration, err := framework.RegisterAgent(context.Background(), runtimeCfg)
+	require.NoError(t, err)
+	require.Equal(t, "runsc version cached", registration.Runtime.(*framework.GVisorRuntime).Version())
+	require.False(t, registration.Sandbox.Degraded)
+
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "runsc"), []byte("#!/bin/sh\necho upgraded\n"), 0o755))
+	_, ok := OpenStartupCache(cacheDir, dir).LookupSandbox(sandbox)
+	require.False(t, ok, "binary change invalidates the entry")
+}
+
+// TestRegisterAgentDegradesWithoutSandbox covers the default degraded mode
+// and the host runner it hands out.
+func TestRegisterAgentDegradesWithoutSandbox(t *testing.T) {
+	fakeBinaries(t, "runsc", "docker")
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Workspace = dir
+	cfg.ManifestPath = filepath.Join(dir, "agent.manifest.yaml")
+	cfg.ConfigPath = filepath.Join(dir, "relurpify_cfg", "config.yaml")
+	_, err := SaveManifest(context.Background(), cfg, WizardSelection{
+		Model:   "qwen2.5-coder",
+		Agents:  []string{"coding"},
+		Profile: PermissionProfileReadOnly,
+	})
+	require.NoError(t, err)
+
+	registration, err := framework.RegisterAgent(context.Background(), framework.RuntimeConfig{
+		ManifestPath: cfg.ManifestPath,
+		Sandbox:      cfg.Sandbox,
+		BaseFS:       dir,
+	})
+	require.NoError(t, err)
+	require.True(t, registration.Sandbox.Degraded)
+	require.Equal(t, "host", registration.Sandbox.Runtime)
+	require.NotEmpty(t, registration.Sandbox.Reason)
+
+	runner, err := registration.CommandRunner(dir)
+	require.NoError(t, err)
+	require.IsType(t, &framework.LocalCommandRunner{}, runner)
+}
+
+// TestStartupCachePluginsAndExpiry covers plugin descriptors, the TTL, and
+// workspace isolation.
+func TestStartupCachePluginsAndExpiry(t *testing.T) {
+	fakeBinaries(t, "jira-plugin", "runsc", "docker")
+	cacheDir := t.TempDir()
+	plugin := framework.AgentPluginSpec{Name: "jira", Command: []string{"jira-plugin"}}
+	descriptors := []tools.PluginToolDescriptor{{Name: "jira_search"}}
+
+	cache := OpenStartupCache(cacheDir, "/work/a")
+	cache.StorePlugin(plugin, descriptors)
+	cache.StoreSandbox(framework.SandboxConfig{RunscPath: "runsc", ContainerRuntime: "docker"}, "v1")
+	require.NoError(t, cache.Save())
+
+	reloaded := OpenStartupCache(cacheDir, "/work/a")
+	got, ok := reloaded.LookupPlugin(plugin)
+	require.True(t, ok)
+	require.Equal(t, descriptors, got)
+
+	plugin.Timeout = "5s"
+	_, ok = reloaded.LookupPlugin(plugin)
+	require.False(t, ok, "spec change invalidates the entry")
+
+	reloaded.now = func() time.Time { return time.Now().Add(2 * sandboxCacheTTL) }
+	_, ok = reloaded.LookupSandbox(framework.SandboxConfig{RunscPath: "runsc", ContainerRuntime: "docker"})
+	require.False(t, ok, "sandbox entries expire")
+
+	_, ok = OpenStartupCache(cacheDir, "/work/b").LookupPlugin(framework.AgentPluginSpec{Name: "jira", Command: []string{"jira-plugin"}})
+	require.False(t, ok, "caches are per workspace")
+}
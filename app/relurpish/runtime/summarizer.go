@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// SummarizerConfig has agents compress context with a language model
+// instead of truncating it:
+//
+//	summarizer:
+//	  model: qwen2.5:0.5b     # optional small model
+//	  max_tokens: 256         # cap on each summary
+//	  max_input_tokens: 4000  # how much source the model reads
+//	  length: 1.5             # scales summary length
+//
+// Model defaults to the summarization route in models, then the agent's
+// model. Zero values use the framework defaults. When a model call fails the
+// heuristic summarizer takes over for that summary.
+type SummarizerConfig struct {
+	Model          string  `yaml:"model,omitempty"`
+	MaxTokens      int     `yaml:"max_tokens,omitempty"`
+	MaxInputTokens int     `yaml:"max_input_tokens,omitempty"`
+	Length         float64 `yaml:"length,omitempty"`
+}
+
+// buildSummarizer returns nil without a summarizer block, leaving agents on
+// the heuristic summarizer. newModel builds a client for an explicit model
+// name; fallback serves otherwise.
+func buildSummarizer(cfg *SummarizerConfig, fallback framework.LanguageModel, newModel func(name string) framework.LanguageModel) framework.Summarizer {
+	if cfg == nil {
+		return nil
+	}
+	model := fallback
+	if cfg.Model != "" {
+		model = newModel(cfg.Model)
+	}
+	return &framework.LLMSummarizer{
+		Model:          model,
+		MaxTokens:      cfg.MaxTokens,
+		MaxInputTokens: cfg.MaxInputTokens,
+		Length:         cfg.Length,
+	}
+}
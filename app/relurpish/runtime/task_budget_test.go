@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// spendingAgent uses more tokens than any small budget allows and returns
+// the bare cancellation, like an agent without a graph.
+type spendingAgent struct{}
+
+func (a *spendingAgent) Initialize(config *framework.Config) error { return nil }
+func (a *spendingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	state.Set("plan.steps", "1. split config")
+	framework.TaskBudgetFrom(ctx).ChargeLLM(framework.LLMUsage{Calls: 1, TotalTokens: 5000})
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+func (a *spendingAgent) Capabilities() []framework.Capability { return nil }
+func (a *spendingAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	return nil, nil
+}
+
+func TestRunTaskReportStopsAtBudgetAndSavesContext(t *testing.T) {
+	workflows, err := persistence.NewFileWorkflowStore(t.TempDir())
+	require.NoError(t, err)
+	rt := &Runtime{
+		Config:    Config{Workspace: t.TempDir()},
+		Context:   framework.NewContext(),
+		Agent:     &spendingAgent{},
+		Workflows: workflows,
+	}
+	task := &framework.Task{ID: "t1", Instruction: "split config", Budget: &framework.TaskBudget{MaxTokens: 1000}}
+
+	report, err := rt.RunTaskReport(context.Background(), task)
+	require.Error(t, err)
+	require.Equal(t, TaskStatusBudgetExceeded, report.Status)
+	require.Equal(t, ErrorClassBudgetExceeded, report.ErrorClass)
+	require.Equal(t, ExitBudgetExceeded, report.ExitCode)
+	require.Equal(t, framework.BudgetLimitTokens, report.BudgetLimit)
+
+	snap, ok, err := workflows.Load(context.Background(), "t1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, persistence.WorkflowStatusBudgetExceeded, snap.Status)
+	require.Equal(t, "max_tokens", snap.Metadata["budget_limit"])
+	require.Equal(t, &framework.TaskBudget{MaxTokens: 1000}, snap.Task.Budget)
+	require.NotNil(t, snap.Graph)
+	require.Equal(t, "1. split config", snap.Graph.State.State["plan.steps"])
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ResolveTaskFiles expands --file values into workspace-relative paths.
+// Values are files, directories (every file below them), or globs such as
+// "internal/**/*.go". Hidden and dependency directories are skipped while
+// expanding. A value that matches nothing is an error.
+func ResolveTaskFiles(workspace string, values []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(rel string) {
+		rel = filepath.ToSlash(rel)
+		if !seen[rel] {
+			seen[rel] = true
+			paths = append(paths, rel)
+		}
+	}
+	for _, value := range values {
+		abs := value
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(workspace, value)
+		}
+		rel, err := filepath.Rel(workspace, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("file %s is outside the workspace", value)
+		}
+		pattern := filepath.ToSlash(rel)
+		glob := strings.ContainsAny(pattern, "*?[")
+		if !glob {
+			info, err := os.Stat(abs)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(pattern)
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/") + "/**"
+			if rel == "." {
+				pattern = "**"
+			}
+		}
+		matched := false
+		err = walkTaskFiles(workspace, func(path string) {
+			if framework.MatchGlob(pattern, path) || (strings.HasPrefix(pattern, "**/") && framework.MatchGlob(pattern[3:], path)) {
+				add(path)
+				matched = true
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, fmt.Errorf("no files match %s", value)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// walkTaskFiles calls visit with the slash-separated workspace-relative path
+// of every regular file, skipping the directories DetectProjects skips.
+func walkTaskFiles(workspace string, visit func(string)) error {
+	return filepath.WalkDir(workspace, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(workspace, path)
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || projectSkipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			visit(filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+// LoadTaskFiles reads paths for a task. When their estimated size exceeds
+// maxTokens, the largest files are replaced by summaries until the rest fit;
+// maxTokens <= 0 keeps every file whole.
+func LoadTaskFiles(workspace string, paths []string, maxTokens int, summarizer framework.Summarizer) ([]framework.TaskFile, error) {
+	if summarizer == nil {
+		summarizer = &framework.SimpleSummarizer{}
+	}
+	files := make([]framework.TaskFile, 0, len(paths))
+	total := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(path)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, framework.TaskFile{Path: path, Content: string(data)})
+		total += taskFileTokens(string(data))
+	}
+	if maxTokens <= 0 || total <= maxTokens {
+		return files, nil
+	}
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(files[order[a]].Content) > len(files[order[b]].Content)
+	})
+	for _, i := range order {
+		if total <= maxTokens {
+			break
+		}
+		summary, err := summarizer.Summarize(files[i].Content, framework.SummaryConcise)
+		if err != nil {
+			return nil, fmt.Errorf("summarize %s: %w", files[i].Path, err)
+		}
+		total -= taskFileTokens(files[i].Content) - taskFileTokens(summary)
+		files[i].Content = summary
+		files[i].Summarized = true
+	}
+	return files, nil
+}
+
+// taskFileTokens is the usual four-characters-per-token estimate.
+func taskFileTokens(content string) int {
+	return max(1, len(content)/4)
+}
+
+// TaskFileBudget returns the tokens attached files may use: half of the
+// context window left after the system, tool, and output reservations, so
+// the agent keeps room for its own history.
+func (r *Runtime) TaskFileBudget() int {
+	sizing := r.agentConfig.ContextSizing()
+	available := sizing.MaxTokens - sizing.SystemReserved - sizing.ToolsReserved - sizing.OutputReserved
+	return max(0, available/2)
+}
@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolveTaskFilesExpandsGlobsAndDirs accepts files, directories, and
+// globs, dropping duplicates and dependency directories.
+func TestResolveTaskFilesExpandsGlobsAndDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFixture(t, dir,
+		"main.go",
+		"internal/cache/cache.go",
+		"internal/cache/cache_test.go",
+		"internal/http/handler.go",
+		"web/app.ts",
+		"web/node_modules/pkg/index.ts",
+	)
+	paths, err := ResolveTaskFiles(dir, []string{"**/*.go", "web", "main.go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"internal/cache/cache.go",
+		"internal/cache/cache_test.go",
+		"internal/http/handler.go",
+		"main.go",
+		"web/app.ts",
+	}, paths)
+
+	_, err = ResolveTaskFiles(dir, []string{"**/*.rs"})
+	require.ErrorContains(t, err, "no files match")
+	_, err = ResolveTaskFiles(dir, []string{"../secrets.txt"})
+	require.ErrorContains(t, err, "outside the workspace")
+}
+
+// TestLoadTaskFilesSummarizesLargestFirst keeps small files whole and
+// summarizes the biggest file once the set exceeds the budget.
+func TestLoadTaskFilesSummarizesLargestFirst(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("func padding() {}\n", 200)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.go"), []byte(big), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.go"), []byte("package main\n"), 0o644))
+
+	files, err := LoadTaskFiles(dir, []string{"big.go", "small.go"}, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, big, files[0].Content)
+
+	files, err = LoadTaskFiles(dir, []string{"big.go", "small.go"}, 200, nil)
+	require.NoError(t, err)
+	require.True(t, files[0].Summarized)
+	require.Less(t, len(files[0].Content), len(big))
+	require.False(t, files[1].Summarized)
+	require.Equal(t, "package main\n", files[1].Content)
+}
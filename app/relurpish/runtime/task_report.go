@@ -0,0 +1,434 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// TaskReportVersion is bumped when a TaskReport field is removed or changes
+// meaning. New fields do not bump it.
+const TaskReportVersion = 1
+
+// Task report statuses. They match the batch result statuses.
+const (
+	TaskStatusSucceeded = BatchStatusSucceeded
+	TaskStatusFailed    = BatchStatusFailed
+	TaskStatusTimedOut  = framework.ResultStatusTimedOut
+	// TaskStatusBudgetExceeded means a TaskBudget limit stopped the task.
+	TaskStatusBudgetExceeded = framework.ResultStatusBudgetExceeded
+)
+
+// ErrorClass says why a task failed, so scripts can tell a broken setup from
+// an agent that could not finish.
+type ErrorClass string
+
+const (
+	// ErrorClassAgent covers agent errors and unsuccessful results.
+	ErrorClassAgent ErrorClass = "agent_failure"
+	// ErrorClassToolDenied means a permission check blocked a tool the
+	// task needed.
+	ErrorClassToolDenied ErrorClass = "tool_denied"
+	// ErrorClassLLMUnreachable means the model endpoint could not be reached
+	// or kept failing with server errors.
+	ErrorClassLLMUnreachable ErrorClass = "llm_unreachable"
+	// ErrorClassTimeout means a graph or node deadline expired.
+	ErrorClassTimeout ErrorClass = "timeout"
+	// ErrorClassCancelled means the run was interrupted.
+	ErrorClassCancelled ErrorClass = "cancelled"
+	// ErrorClassBudgetExceeded means the task hit one of its budget limits.
+	ErrorClassBudgetExceeded ErrorClass = "budget_exceeded"
+)
+
+// Process exit codes for each error class. Success exits 0.
+const (
+	ExitAgentFailure   = 1
+	ExitToolDenied     = 2
+	ExitLLMUnreachable = 3
+	ExitTimeout        = 4
+	ExitBudgetExceeded = 5
+	ExitCancelled      = 130
+)
+
+// ExitCode returns the process exit code for class.
+func (c ErrorClass) ExitCode() int {
+	switch c {
+	case "":
+		return 0
+	case ErrorClassToolDenied:
+		return ExitToolDenied
+	case ErrorClassLLMUnreachable:
+		return ExitLLMUnreachable
+	case ErrorClassTimeout:
+		return ExitTimeout
+	case ErrorClassBudgetExceeded:
+		return ExitBudgetExceeded
+	case ErrorClassCancelled:
+		return ExitCancelled
+	default:
+		return ExitAgentFailure
+	}
+}
+
+// ClassifyTaskError maps an error returned by RunTask onto an ErrorClass, or
+// "" for nil. Untagged network and 5xx failures are attributed to the model:
+// tool errors are fed back to the agent, so the model is the only remote
+// dependency whose failures end a task.
+func ClassifyTaskError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	switch framework.ErrorKindOf(err) {
+	case framework.ErrorKindTimeout:
+		return ErrorClassTimeout
+	case framework.ErrorKindBudgetExceeded:
+		return ErrorClassBudgetExceeded
+	case framework.ErrorKindCancelled:
+		return ErrorClassCancelled
+	case framework.ErrorKindToolDenied:
+		return ErrorClassToolDenied
+	case framework.ErrorKindLLMUnavailable:
+		return ErrorClassLLMUnreachable
+	}
+	switch framework.ClassifyError(err) {
+	case framework.RetryNetwork, framework.RetryServer, framework.RetryTimeout:
+		return ErrorClassLLMUnreachable
+	}
+	return ErrorClassAgent
+}
+
+// TaskReport is the machine-readable outcome of one task, printed by
+// `relurpish task --output json|yaml`. Field names are stable within a
+// TaskReportVersion.
+type TaskReport struct {
+	Version     int                `json:"version"`
+	TaskID      string             `json:"task_id"`
+	Type        framework.TaskType `json:"type"`
+	Instruction string             `json:"instruction"`
+	Status      string             `json:"status"`
+	ErrorClass  ErrorClass         `json:"error_class,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	ExitCode    int                `json:"exit_code"`
+	// Node is the graph node that produced Output.
+	Node   string         `json:"node,omitempty"`
+	Output map[string]any `json:"output,omitempty"`
+	// FilesChanged lists workspace files whose changes against HEAD differ
+	// from before the task. It is empty when the workspace is not a git
+	// checkout.
+	FilesChanged []string   `json:"files_changed"`
+	Diffs        []FileDiff `json:"diffs,omitempty"`
+	// Tests is the last exec_run_tests run of the task.
+	Tests   *tools.TestRun     `json:"tests,omitempty"`
+	Denials []PermissionDenial `json:"denials,omitempty"`
+	// CompletedNodes lists the nodes that finished before a timeout or a
+	// budget limit; BudgetLimit names the limit that stopped the task.
+	CompletedNodes []string                       `json:"completed_nodes,omitempty"`
+	BudgetLimit    framework.BudgetLimit          `json:"budget_limit,omitempty"`
+	Suggestions    []framework.AutonomySuggestion `json:"suggestions,omitempty"`
+	Usage          framework.LLMUsage             `json:"usage"`
+	StartedAt      time.Time                      `json:"started_at"`
+	Duration       string                         `json:"duration"`
+}
+
+// FileDiff is the diff of one file against HEAD after the task. Diff is
+// empty when the task restored the file to its committed content.
+type FileDiff struct {
+	Path      string `json:"path"`
+	Diff      string `json:"diff"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// PermissionDenial is a permission check that failed during the task.
+type PermissionDenial struct {
+	Type     string `json:"type"`
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// NewTaskReport describes a finished task from RunTask's return values.
+// Denials recorded during the run attribute an otherwise unexplained
+// failure to the permission check that caused it.
+func NewTaskReport(task *framework.Task, res *framework.Result, err error, denials []PermissionDenial) *TaskReport {
+	report := &TaskReport{
+		Version:      TaskReportVersion,
+		TaskID:       task.ID,
+		Type:         task.Type,
+		Instruction:  task.Instruction,
+		Status:       TaskStatusSucceeded,
+		FilesChanged: []string{},
+		Denials:      denials,
+	}
+	if res != nil {
+		report.Node = res.NodeID
+		report.Output = res.Data
+	}
+	switch {
+	case err != nil:
+		report.Status = TaskStatusFailed
+		report.ErrorClass = ClassifyTaskError(err)
+		report.Error = err.Error()
+		var timeoutErr *framework.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			report.Status = TaskStatusTimedOut
+			report.CompletedNodes = timeoutErr.Completed
+		}
+		var budgetErr *framework.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			report.Status = TaskStatusBudgetExceeded
+			report.BudgetLimit = budgetErr.Limit
+			report.CompletedNodes = budgetErr.Completed
+		}
+	case res != nil && !res.Success:
+		report.Status = TaskStatusFailed
+		report.ErrorClass = ErrorClassAgent
+		if res.Error != nil {
+			report.Error = res.Error.Error()
+		}
+	}
+	if report.ErrorClass == ErrorClassAgent && len(denials) > 0 {
+		report.ErrorClass = ErrorClassToolDenied
+	}
+	report.ExitCode = report.ErrorClass.ExitCode()
+	return report
+}
+
+// Encode writes the report as "json" or "yaml". YAML output is the JSON
+// document re-rendered, so both formats share field names and order.
+func (r *TaskReport) Encode(w io.Writer, format string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case "yaml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		resetYAMLStyle(&doc)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unknown report format %q (want json or yaml)", format)
+	}
+}
+
+// resetYAMLStyle drops the flow and quoting styles JSON input carries so the
+// document is written as block YAML.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// RunTaskReport runs task like RunTask and describes the outcome, including
+// the workspace files it changed and its last test run. The returned error
+// is the task's own; the report is nil only when task is nil.
+func (r *Runtime) RunTaskReport(ctx context.Context, task *framework.Task) (*TaskReport, error) {
+	if task == nil {
+		return nil, errors.New("task required")
+	}
+	started := time.Now()
+	before := snapshotWorkspace(ctx, r.Config.Workspace)
+	res, state, err := r.runTask(ctx, task)
+	report := NewTaskReport(task, res, err, r.denialsSince(ctx, started))
+	report.StartedAt = started.UTC()
+	report.Duration = time.Since(started).Round(time.Millisecond).String()
+	if before != nil {
+		if after := snapshotWorkspace(context.WithoutCancel(ctx), r.Config.Workspace); after != nil {
+			report.Diffs = workspaceChanges(context.WithoutCancel(ctx), r.Config.Workspace, before, after)
+			for _, diff := range report.Diffs {
+				report.FilesChanged = append(report.FilesChanged, diff.Path)
+			}
+		}
+	}
+	if state != nil {
+		if value, ok := state.Get(tools.TestRunStateKey); ok {
+			if run, ok := value.(tools.TestRun); ok {
+				report.Tests = &run
+			}
+		}
+	}
+	if r.Autonomy != nil {
+		report.Suggestions = r.Autonomy.Suggestions()
+	}
+	if r.Usage != nil {
+		report.Usage = r.Usage.Summary(framework.UsageFilter{TaskID: task.ID}).Total
+	}
+	return report, err
+}
+
+// denialsSince reads the permission denials audited for the agent since
+// started.
+func (r *Runtime) denialsSince(ctx context.Context, started time.Time) []PermissionDenial {
+	if r.Registration == nil || r.Registration.Audit == nil {
+		return nil
+	}
+	records, err := r.Registration.Audit.Query(context.WithoutCancel(ctx), framework.AuditQuery{
+		AgentID:   r.Registration.ID,
+		Result:    "denied",
+		TimeStart: started.UTC(),
+	})
+	if err != nil {
+		if r.Logger != nil {
+			r.Logger.Printf("audit query failed: %v", err)
+		}
+		return nil
+	}
+	denials := make([]PermissionDenial, 0, len(records))
+	for _, record := range records {
+		denial := PermissionDenial{Type: record.Type, Action: record.Action, Resource: record.Permission}
+		if reason, ok := record.Metadata["reason"]; ok {
+			denial.Reason = fmt.Sprint(reason)
+		}
+		denials = append(denials, denial)
+	}
+	return denials
+}
+
+// maxReportDiffBytes bounds each file's diff in a report.
+const maxReportDiffBytes = 64 << 10
+
+// untrackedPrefix marks snapshot entries of untracked files, which are
+// fingerprinted by content instead of by diff.
+const untrackedPrefix = "untracked:"
+
+// workspaceSnapshot maps each file that differs from HEAD to a fingerprint of
+// that difference.
+type workspaceSnapshot map[string]string
+
+// snapshotWorkspace records the uncommitted changes in dir, or returns nil
+// when dir is not a git checkout with at least one commit.
+func snapshotWorkspace(ctx context.Context, dir string) workspaceSnapshot {
+	diff, err := gitOutput(ctx, dir, "diff", "HEAD", "--relative", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return nil
+	}
+	snapshot := splitGitDiff(diff)
+	untracked, err := gitOutput(ctx, dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		snapshot[path] = untrackedPrefix + hex.EncodeToString(sum[:])
+	}
+	return snapshot
+}
+
+// splitGitDiff splits `git diff` output into per-file sections keyed by the
+// post-image path. Paths are relative to the workspace because of --relative.
+func splitGitDiff(diff string) workspaceSnapshot {
+	snapshot := make(workspaceSnapshot)
+	var path string
+	var section strings.Builder
+	flush := func() {
+		if path != "" {
+			snapshot[path] = section.String()
+		}
+		section.Reset()
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			header := strings.TrimRight(line, "\n")
+			path = ""
+			if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+				path = header[idx+len(" b/"):]
+			}
+		}
+		section.WriteString(line)
+	}
+	flush()
+	return snapshot
+}
+
+// workspaceChanges diffs the files whose snapshot entries differ between
+// before and after, sorted by path.
+func workspaceChanges(ctx context.Context, dir string, before, after workspaceSnapshot) []FileDiff {
+	paths := changedPaths(before, after)
+	diffs := make([]FileDiff, 0, len(paths))
+	for _, path := range paths {
+		diff := FileDiff{Path: path, Diff: after[path]}
+		if strings.HasPrefix(diff.Diff, untrackedPrefix) {
+			// --no-index exits 1 when the files differ, which they always do.
+			diff.Diff, _ = gitOutput(ctx, dir, "diff", "--no-index", "--no-color", "--no-ext-diff", "--", os.DevNull, path)
+		}
+		if len(diff.Diff) > maxReportDiffBytes {
+			diff.Diff = diff.Diff[:maxReportDiffBytes]
+			diff.Truncated = true
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// changedPaths lists the files whose snapshot entries differ between before
+// and after, sorted.
+func changedPaths(before, after workspaceSnapshot) []string {
+	var paths []string
+	for path, fingerprint := range after {
+		if before[path] != fingerprint {
+			paths = append(paths, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// gitOutput runs git in dir. Stdout is returned even when git fails.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(cctx, "git", append([]string{"-C", dir, "-c", "core.quotePath=false"}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return stdout.String(), fmt.Errorf("git %s: %s", strings.Join(args, " "), detail)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
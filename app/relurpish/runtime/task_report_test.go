@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+func TestNewTaskReportClassifiesFailures(t *testing.T) {
+	task := &framework.Task{ID: "t1", Type: framework.TaskTypeCodeModification, Instruction: "fix it"}
+	denial := []PermissionDenial{{Type: "executable", Action: "exec:rm", Resource: "rm"}}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	cases := []struct {
+		name    string
+		res     *framework.Result
+		err     error
+		denials []PermissionDenial
+		status  string
+		class   ErrorClass
+		code    int
+	}{
+		{name: "success", res: &framework.Result{Success: true}, status: TaskStatusSucceeded, code: 0},
+		{name: "unsuccessful result", res: &framework.Result{Success: false, Error: errors.New("gave up")}, status: TaskStatusFailed, class: ErrorClassAgent, code: ExitAgentFailure},
+		{name: "agent error", err: errors.New("plan invalid"), status: TaskStatusFailed, class: ErrorClassAgent, code: ExitAgentFailure},
+		{name: "denied error", err: fmt.Errorf("tool x blocked: %w", &framework.PermissionDeniedError{}), status: TaskStatusFailed, class: ErrorClassToolDenied, code: ExitToolDenied},
+		{name: "failure after denial", err: errors.New("could not finish"), denials: denial, status: TaskStatusFailed, class: ErrorClassToolDenied, code: ExitToolDenied},
+		{name: "success despite denial", res: &framework.Result{Success: true}, denials: denial, status: TaskStatusSucceeded, code: 0},
+		{name: "connection refused", err: fmt.Errorf("generate: %w", refused), status: TaskStatusFailed, class: ErrorClassLLMUnreachable, code: ExitLLMUnreachable},
+		{name: "server error", err: &llm.StatusError{Code: 503, Status: "503 Service Unavailable"}, status: TaskStatusFailed, class: ErrorClassLLMUnreachable, code: ExitLLMUnreachable},
+		{name: "timeout", err: &framework.TimeoutError{NodeID: "act", Completed: []string{"plan"}}, status: TaskStatusTimedOut, class: ErrorClassTimeout, code: ExitTimeout},
+		{name: "cancelled", err: context.Canceled, status: TaskStatusFailed, class: ErrorClassCancelled, code: ExitCancelled},
+		{name: "budget exceeded", err: &framework.BudgetExceededError{Limit: framework.BudgetLimitTokens, Max: 100, Used: 120}, status: TaskStatusBudgetExceeded, class: ErrorClassBudgetExceeded, code: ExitBudgetExceeded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := NewTaskReport(task, tc.res, tc.err, tc.denials)
+			require.Equal(t, tc.status, report.Status)
+			require.Equal(t, tc.class, report.ErrorClass)
+			require.Equal(t, tc.code, report.ExitCode)
+			require.Equal(t, "t1", report.TaskID)
+			require.NotNil(t, report.FilesChanged, "files_changed is always a list")
+		})
+	}
+
+	report := NewTaskReport(task, nil, &framework.TimeoutError{NodeID: "act", Completed: []string{"plan"}}, nil)
+	require.Equal(t, []string{"plan"}, report.CompletedNodes)
+
+	report = NewTaskReport(task, nil, &framework.BudgetExceededError{Limit: framework.BudgetLimitFilesModified, NodeID: "act", Completed: []string{"plan"}}, nil)
+	require.Equal(t, framework.BudgetLimitFilesModified, report.BudgetLimit)
+	require.Equal(t, []string{"plan"}, report.CompletedNodes)
+}
+
+func TestTaskReportEncode(t *testing.T) {
+	report := NewTaskReport(&framework.Task{ID: "t1", Instruction: "x"}, &framework.Result{Success: true}, nil, nil)
+	report.FilesChanged = []string{"a.go"}
+	report.Diffs = []FileDiff{{Path: "a.go", Diff: "-old\n+new\n"}}
+	report.Usage = framework.LLMUsage{Calls: 2, TotalTokens: 30}
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, report.Encode(&jsonOut, "json"))
+	require.Contains(t, jsonOut.String(), `"task_id": "t1"`)
+	require.NotContains(t, jsonOut.String(), "error_class", "empty error class is omitted")
+
+	var yamlOut bytes.Buffer
+	require.NoError(t, report.Encode(&yamlOut, "yaml"))
+	var decoded map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(yamlOut.Bytes(), &decoded))
+	require.Equal(t, "t1", decoded["task_id"])
+	require.Equal(t, []interface{}{"a.go"}, decoded["files_changed"])
+	require.Equal(t, 30, decoded["usage"].(map[string]interface{})["total_tokens"])
+	require.Contains(t, yamlOut.String(), "diff: |", "multi-line strings use block style")
+
+	require.Error(t, report.Encode(&bytes.Buffer{}, "xml"))
+}
+
+func TestWorkspaceChangesAgainstSnapshot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	ctx := context.Background()
+	require.Nil(t, snapshotWorkspace(ctx, dir), "not a git checkout")
+
+	git("init", "-q")
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	write("b.go", "package b // edited before\n")
+	write("notes.txt", "scratch\n")
+
+	before := snapshotWorkspace(ctx, dir)
+	require.NotNil(t, before)
+	write("a.go", "package a\n\nfunc A() {}\n")
+	write("new.go", "package a\n")
+
+	diffs := workspaceChanges(ctx, dir, before, snapshotWorkspace(ctx, dir))
+	require.Len(t, diffs, 2, "files already dirty before the task are not reported")
+	require.Equal(t, "a.go", diffs[0].Path)
+	require.Contains(t, diffs[0].Diff, "+func A() {}")
+	require.Equal(t, "new.go", diffs[1].Path)
+	require.Contains(t, diffs[1].Diff, "+package a")
+}
@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// toolOutputRetention is how long spilled tool output is kept.
+const toolOutputRetention = 24 * time.Hour
+
+// ToolOutputConfig bounds the tool results the agent reads back:
+//
+//	tool_output:
+//	  max_bytes: 32768
+//	  per_tool:
+//	    file_read: 65536
+//	    search_grep: 16384
+//	  spill: true
+//
+// Oversized results keep their head and tail around a truncation marker.
+// With spill (the default) the full output is written under
+// relurpify_cfg/tool_output and the marker names the file. A negative limit
+// disables truncation.
+type ToolOutputConfig struct {
+	MaxBytes int            `yaml:"max_bytes,omitempty"`
+	PerTool  map[string]int `yaml:"per_tool,omitempty"`
+	Spill    *bool          `yaml:"spill,omitempty"`
+}
+
+// ToolOutputDir holds the full output of truncated tool results.
+func ToolOutputDir(workspace string) string {
+	return filepath.Join(workspace, "relurpify_cfg", "tool_output")
+}
+
+// toolOutputPolicy builds the agent's policy from the workspace config.
+func toolOutputPolicy(workspace string, cfg *ToolOutputConfig) framework.ToolOutputPolicy {
+	policy := framework.ToolOutputPolicy{SpillDir: ToolOutputDir(workspace)}
+	if cfg == nil {
+		return policy
+	}
+	policy.MaxBytes = cfg.MaxBytes
+	policy.PerTool = cfg.PerTool
+	if cfg.Spill != nil && !*cfg.Spill {
+		policy.SpillDir = ""
+	}
+	return policy
+}
+
+// pruneToolOutput removes spilled output older than toolOutputRetention.
+func pruneToolOutput(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-toolOutputRetention)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
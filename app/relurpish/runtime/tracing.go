@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"os"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TracingConfig exports graph executions as OpenTelemetry traces:
+//
+//	tracing:
+//	  endpoint: http://localhost:4318
+//	  service_name: relurpify-ci
+//	  headers:
+//	    Authorization: Bearer ${OTLP_TOKEN}
+//
+// Header values expand environment variables so tokens stay out of the
+// workspace config. --otlp-endpoint overrides Endpoint.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`
+	ServiceName string            `yaml:"service_name,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+}
+
+// buildTracing returns the OTLP exporter, or nil when no endpoint is set.
+func buildTracing(cfg Config, ws WorkspaceConfig) (*framework.OTLPTelemetry, error) {
+	var tc TracingConfig
+	if ws.Tracing != nil {
+		tc = *ws.Tracing
+	}
+	if cfg.OTLPEndpoint != "" {
+		tc.Endpoint = cfg.OTLPEndpoint
+	}
+	if tc.Endpoint == "" {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(tc.Headers))
+	for key, value := range tc.Headers {
+		headers[key] = os.ExpandEnv(value)
+	}
+	return framework.NewOTLPTelemetry(framework.OTLPOptions{
+		Endpoint:    tc.Endpoint,
+		ServiceName: tc.ServiceName,
+		Headers:     headers,
+	})
+}
@@ -0,0 +1,372 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+	"github.com/lexcodex/relurpify/framework/watch"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// WatchConfig configures the workspace watcher in config.yaml:
+//
+//	watch:
+//	  enabled: true
+//	  debounce: 500ms
+//	  ignore: [dist, "*.log"]
+//	  analysis:
+//	    type: analysis
+//	    instruction: Run lsp_get_diagnostics on the changed files and report any problems.
+//
+// Enabled starts the watcher for the TUI and `serve` without --watch. When
+// Analysis is set, each batch of changed files runs that task with the files
+// passed as context_files.
+type WatchConfig struct {
+	Enabled  bool                 `yaml:"enabled,omitempty"`
+	Debounce string               `yaml:"debounce,omitempty"`
+	Ignore   []string             `yaml:"ignore,omitempty"`
+	Analysis *WatchAnalysisConfig `yaml:"analysis,omitempty"`
+}
+
+// WatchAnalysisConfig is the task run for changed files.
+type WatchAnalysisConfig struct {
+	Type        string `yaml:"type,omitempty"`
+	Instruction string `yaml:"instruction"`
+}
+
+// WatchEventType distinguishes watcher notifications.
+type WatchEventType string
+
+const (
+	// WatchEventChanged reports a batch of changed files after caches were
+	// invalidated.
+	WatchEventChanged WatchEventType = "changed"
+	// WatchEventAnalysis reports the outcome of the configured analysis task.
+	WatchEventAnalysis WatchEventType = "analysis"
+)
+
+// WatchEvent is delivered to SubscribeWatch listeners. Paths are relative to
+// the workspace.
+type WatchEvent struct {
+	Type    WatchEventType
+	Time    time.Time
+	Changes []watch.Change
+	// TaskID, Result, and Err describe analysis events.
+	TaskID string
+	Result *framework.Result
+	Err    error
+}
+
+// watchOptions resolves WatchConfig into watcher options.
+func (c *WatchConfig) watchOptions() (watch.Options, error) {
+	var opts watch.Options
+	if c == nil {
+		return opts, nil
+	}
+	if c.Debounce != "" {
+		d, err := time.ParseDuration(c.Debounce)
+		if err != nil || d < 0 {
+			return opts, fmt.Errorf("watch.debounce: invalid duration %q", c.Debounce)
+		}
+		opts.Debounce = d
+	}
+	if len(c.Ignore) > 0 {
+		opts.Ignore = append(append([]string(nil), watch.DefaultIgnore...), c.Ignore...)
+	}
+	if c.Analysis != nil && c.Analysis.Instruction == "" {
+		return opts, errors.New("watch.analysis: instruction required")
+	}
+	return opts, nil
+}
+
+// WatchEnabled reports whether the watcher should start for interactive and
+// server sessions.
+func (r *Runtime) WatchEnabled() bool {
+	return r.Config.Watch || (r.Workspace.Watch != nil && r.Workspace.Watch.Enabled)
+}
+
+// StartWatch watches the workspace until ctx is cancelled or the returned
+// stop function is called. Every batch of changes invalidates the LSP and AST
+// caches, is logged, and is broadcast to SubscribeWatch listeners; when
+// watch.analysis is configured the changed files are also analysed. Analysis
+// runs one at a time: changes arriving meanwhile are coalesced into the next
+// run.
+func (r *Runtime) StartWatch(ctx context.Context) (func(), error) {
+	opts, err := r.Workspace.Watch.watchOptions()
+	if err != nil {
+		return nil, err
+	}
+	w, err := watch.New(r.Config.Workspace, opts)
+	if err != nil {
+		return nil, fmt.Errorf("watch %s: %w", r.Config.Workspace, err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	analysis := &watchAnalysis{runtime: r}
+	if r.Workspace.Watch != nil {
+		analysis.config = r.Workspace.Watch.Analysis
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = w.Run(ctx, func(changes []watch.Change) {
+			r.handleWatchBatch(ctx, w.Root(), changes, analysis)
+		})
+	}()
+	r.logf("watching %s", r.Config.Workspace)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			w.Close()
+			<-done
+			analysis.wait()
+		})
+	}, nil
+}
+
+func (r *Runtime) handleWatchBatch(ctx context.Context, root string, changes []watch.Change, analysis *watchAnalysis) {
+	if r.caches != nil {
+		r.caches.Invalidate(changes)
+	}
+	rel := make([]watch.Change, 0, len(changes))
+	for _, change := range changes {
+		path, err := filepath.Rel(root, change.Path)
+		if err != nil {
+			path = change.Path
+		}
+		rel = append(rel, watch.Change{Path: path, Op: change.Op})
+	}
+	event := WatchEvent{Type: WatchEventChanged, Time: time.Now(), Changes: rel}
+	r.logf("%s", FormatWatchEvent(event))
+	r.broadcastWatch(event)
+	analysis.schedule(ctx, rel)
+}
+
+// SubscribeWatch streams watcher events. Slow subscribers miss events rather
+// than stalling the watcher. The returned cancel function unsubscribes.
+func (r *Runtime) SubscribeWatch(buffer int) (<-chan WatchEvent, func()) {
+	if buffer <= 0 {
+		buffer = 16
+	}
+	ch := make(chan WatchEvent, buffer)
+	r.watchMu.Lock()
+	if r.watchSubs == nil {
+		r.watchSubs = make(map[int]chan WatchEvent)
+	}
+	id := r.watchSeq
+	r.watchSeq++
+	r.watchSubs[id] = ch
+	r.watchMu.Unlock()
+	return ch, func() {
+		r.watchMu.Lock()
+		sub, ok := r.watchSubs[id]
+		delete(r.watchSubs, id)
+		r.watchMu.Unlock()
+		if ok {
+			close(sub)
+		}
+	}
+}
+
+func (r *Runtime) broadcastWatch(event WatchEvent) {
+	r.watchMu.Lock()
+	defer r.watchMu.Unlock()
+	for _, ch := range r.watchSubs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (r *Runtime) logf(format string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Printf(format, args...)
+	}
+}
+
+// FormatWatchEvent renders the one-line headline shown by `serve --watch` and
+// the TUI feed. Analysis results are left to the caller.
+func FormatWatchEvent(event WatchEvent) string {
+	switch event.Type {
+	case WatchEventAnalysis:
+		files := make([]string, len(event.Changes))
+		for i, change := range event.Changes {
+			files[i] = change.Path
+		}
+		subject := strings.Join(files, ", ")
+		if len(files) > 5 {
+			subject = fmt.Sprintf("%s, and %d more", strings.Join(files[:5], ", "), len(files)-5)
+		}
+		switch {
+		case event.Err != nil:
+			return fmt.Sprintf("watch: analysis %s of %s failed: %v", event.TaskID, subject, event.Err)
+		case event.Result != nil && !event.Result.Success:
+			return fmt.Sprintf("watch: analysis %s of %s reported failure", event.TaskID, subject)
+		default:
+			return fmt.Sprintf("watch: analysis %s of %s finished", event.TaskID, subject)
+		}
+	default:
+		noun := "files"
+		if len(event.Changes) == 1 {
+			noun = "file"
+		}
+		return fmt.Sprintf("watch: %d %s changed: %s", len(event.Changes), noun, describeChanges(event.Changes, 5))
+	}
+}
+
+// describeChanges lists up to limit changes as "op path".
+func describeChanges(changes []watch.Change, limit int) string {
+	parts := make([]string, 0, limit+1)
+	for i, change := range changes {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("and %d more", len(changes)-limit))
+			break
+		}
+		parts = append(parts, string(change.Op)+" "+change.Path)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// watchAnalysis serializes analysis runs and coalesces files changed while one
+// is in flight.
+type watchAnalysis struct {
+	runtime *Runtime
+	config  *WatchAnalysisConfig
+
+	mu      sync.Mutex
+	running bool
+	pending map[string]bool
+	wg      sync.WaitGroup
+}
+
+func (a *watchAnalysis) schedule(ctx context.Context, changes []watch.Change) {
+	if a.config == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending == nil {
+		a.pending = make(map[string]bool)
+	}
+	for _, change := range changes {
+		if change.Op.Removed() || change.Path == "." {
+			delete(a.pending, change.Path)
+			continue
+		}
+		a.pending[change.Path] = true
+	}
+	if a.running || len(a.pending) == 0 {
+		return
+	}
+	a.running = true
+	a.wg.Add(1)
+	go a.loop(ctx)
+}
+
+func (a *watchAnalysis) loop(ctx context.Context) {
+	defer a.wg.Done()
+	for {
+		a.mu.Lock()
+		if len(a.pending) == 0 || ctx.Err() != nil {
+			a.running = false
+			a.mu.Unlock()
+			return
+		}
+		files := make([]string, 0, len(a.pending))
+		for path := range a.pending {
+			files = append(files, path)
+		}
+		a.pending = make(map[string]bool)
+		a.mu.Unlock()
+		sort.Strings(files)
+		a.run(ctx, files)
+	}
+}
+
+func (a *watchAnalysis) run(ctx context.Context, files []string) {
+	r := a.runtime
+	task := &framework.Task{
+		ID:          fmt.Sprintf("watch-%d", time.Now().UnixNano()),
+		Type:        framework.TaskType(a.config.Type),
+		Instruction: a.config.Instruction,
+		Context: map[string]any{
+			"source":        "watch",
+			"context_files": files,
+		},
+	}
+	if task.Type == "" {
+		task.Type = framework.TaskTypeAnalysis
+	}
+	changes := make([]watch.Change, len(files))
+	for i, file := range files {
+		changes[i] = watch.Change{Path: file, Op: watch.OpWrite}
+	}
+	res, err := r.RunTask(ctx, task)
+	event := WatchEvent{
+		Type:    WatchEventAnalysis,
+		Time:    time.Now(),
+		Changes: changes,
+		TaskID:  task.ID,
+		Result:  res,
+		Err:     err,
+	}
+	r.logf("%s", FormatWatchEvent(event))
+	r.broadcastWatch(event)
+}
+
+func (a *watchAnalysis) wait() { a.wg.Wait() }
+
+// cacheInvalidators fans changes out to each cache.
+type cacheInvalidators []watch.Invalidator
+
+func (c cacheInvalidators) Invalidate(changes []watch.Change) {
+	for _, inv := range c {
+		inv.Invalidate(changes)
+	}
+}
+
+// lspInvalidator clears cached LSP responses on any change and has running
+// servers re-read the changed files.
+func lspInvalidator(proxy *tools.Proxy) watch.Invalidator {
+	return watch.InvalidatorFunc(func(changes []watch.Change) {
+		files := make([]string, len(changes))
+		for i, change := range changes {
+			files[i] = change.Path
+		}
+		proxy.FilesChanged(context.Background(), files)
+	})
+}
+
+// astInvalidator re-indexes changed files and drops removed ones. Until the
+// first AST query starts the workspace scan there is nothing to keep fresh,
+// and indexing single files would make a cold index look warm.
+func astInvalidator(manager *ast.IndexManager) watch.Invalidator {
+	return watch.InvalidatorFunc(func(changes []watch.Change) {
+		if !manager.Activated() {
+			return
+		}
+		for _, change := range changes {
+			if change.Op.Removed() {
+				_ = manager.RemoveFile(change.Path)
+				continue
+			}
+			info, err := os.Stat(change.Path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			// Files without a parser or outside the agent's permissions are
+			// skipped by IndexFile; their errors are not worth surfacing.
+			_ = manager.IndexFile(change.Path)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/watch"
+)
+
+// recordingAgent records the context files of each task it runs.
+type recordingAgent struct {
+	mu    sync.Mutex
+	tasks []*framework.Task
+}
+
+func (a *recordingAgent) Initialize(config *framework.Config) error { return nil }
+func (a *recordingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tasks = append(a.tasks, task)
+	return &framework.Result{NodeID: "analyze", Success: true, Data: map[string]interface{}{"diagnostics": 0}}, nil
+}
+func (a *recordingAgent) Capabilities() []framework.Capability { return nil }
+func (a *recordingAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	return nil, nil
+}
+
+func nextWatchEvent(t *testing.T, events <-chan WatchEvent, typ WatchEventType) WatchEvent {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == typ {
+				return event
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s watch event", typ)
+		}
+	}
+}
+
+func TestStartWatchInvalidatesCachesAndRunsAnalysis(t *testing.T) {
+	workspace, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	agent := &recordingAgent{}
+	var mu sync.Mutex
+	var invalidated []watch.Change
+	rt := &Runtime{
+		Config:  Config{Workspace: workspace},
+		Context: framework.NewContext(),
+		Agent:   agent,
+		Workspace: WorkspaceConfig{Watch: &WatchConfig{
+			Debounce: "20ms",
+			Analysis: &WatchAnalysisConfig{Instruction: "Report diagnostics for the changed files"},
+		}},
+		caches: watch.InvalidatorFunc(func(changes []watch.Change) {
+			mu.Lock()
+			defer mu.Unlock()
+			invalidated = append(invalidated, changes...)
+		}),
+	}
+	events, unsubscribe := rt.SubscribeWatch(16)
+	defer unsubscribe()
+	stop, err := rt.StartWatch(context.Background())
+	require.NoError(t, err)
+	defer stop()
+
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n"), 0o644))
+
+	changed := nextWatchEvent(t, events, WatchEventChanged)
+	require.Len(t, changed.Changes, 1)
+	assert.Equal(t, "main.go", changed.Changes[0].Path)
+	assert.Contains(t, FormatWatchEvent(changed), "1 file changed: create main.go")
+	mu.Lock()
+	require.NotEmpty(t, invalidated)
+	assert.Equal(t, filepath.Join(workspace, "main.go"), invalidated[0].Path, "caches see absolute paths")
+	mu.Unlock()
+
+	analysis := nextWatchEvent(t, events, WatchEventAnalysis)
+	require.NoError(t, analysis.Err)
+	require.NotNil(t, analysis.Result)
+	assert.Contains(t, FormatWatchEvent(analysis), "of main.go finished")
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	require.NotEmpty(t, agent.tasks)
+	task := agent.tasks[0]
+	assert.Equal(t, framework.TaskTypeAnalysis, task.Type)
+	assert.Equal(t, []string{"main.go"}, task.Context["context_files"])
+	assert.Equal(t, "watch", task.Context["source"])
+}
+
+func TestWatchConfigValidates(t *testing.T) {
+	opts, err := (&WatchConfig{Debounce: "1s", Ignore: []string{"dist"}}).watchOptions()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, opts.Debounce)
+	assert.Contains(t, opts.Ignore, "dist")
+	assert.Contains(t, opts.Ignore, ".git", "custom ignores extend the defaults")
+
+	_, err = (&WatchConfig{Debounce: "soon"}).watchOptions()
+	assert.Error(t, err)
+	_, err = (&WatchConfig{Analysis: &WatchAnalysisConfig{}}).watchOptions()
+	assert.Error(t, err)
+	_, err = (*WatchConfig)(nil).watchOptions()
+	assert.NoError(t, err)
+}
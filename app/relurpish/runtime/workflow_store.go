@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// WorkflowStoreConfig selects where workflow snapshots are kept:
+//
+//	workflow_store:
+//	  backend: sqlite
+//
+// The default "file" backend rewrites one workflows.json on every save.
+// "sqlite" keeps workflows.db in the same directory, indexed for filtered
+// listings, and imports an existing workflows.json the first time it opens.
+type WorkflowStoreConfig struct {
+	Backend string `yaml:"backend,omitempty"`
+}
+
+// OpenWorkflowStore opens the workflow store config.yaml selects under
+// cfg.WorkflowPath.
+func OpenWorkflowStore(cfg Config) (persistence.WorkflowStore, error) {
+	var ws WorkspaceConfig
+	if cfg.ConfigPath != "" {
+		loaded, err := LoadWorkspaceConfig(cfg.ConfigPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		ws = loaded
+	}
+	return openWorkflowStore(cfg.WorkflowPath, ws.WorkflowStore)
+}
+
+// CloseWorkflowStore releases stores holding a database handle.
+func CloseWorkflowStore(store persistence.WorkflowStore) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func openWorkflowStore(root string, storeCfg *WorkflowStoreConfig) (persistence.WorkflowStore, error) {
+	backend := ""
+	if storeCfg != nil {
+		backend = storeCfg.Backend
+	}
+	// Typed nil stores must not leak out as non-nil interfaces.
+	switch backend {
+	case "", "file":
+		store, err := persistence.NewFileWorkflowStore(root)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "sqlite":
+		store, err := persistence.NewSQLiteWorkflowStore(root)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("workflow_store.backend: unknown backend %q (want file or sqlite)", backend)
+	}
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// handleUndo reverts the latest turn in the runtime and drops it from the
+// transcript. --files also rolls back the files the turn wrote.
+func handleUndo(m Model, args []string) (Model, tea.Cmd) {
+	if m.runtime == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	if m.streaming {
+		return m.addSystemMessage("Wait for the current response before undoing"), nil
+	}
+	files := false
+	for _, arg := range args {
+		if arg != "--files" {
+			return m.addSystemMessage("Usage: /undo [--files]"), nil
+		}
+		files = true
+	}
+	turn, restored, err := m.runtime.UndoTurn(files)
+	if turn.TaskID == "" {
+		return m.addSystemMessage(fmt.Sprintf("Cannot undo: %v", err)), nil
+	}
+	m = m.dropTurn(turn.Instruction)
+	msg := fmt.Sprintf("Undid %q", turn.Instruction)
+	switch {
+	case len(restored) > 0:
+		msg += fmt.Sprintf("; restored %s", strings.Join(restored, ", "))
+	case files:
+		msg += "; no files to restore"
+	case len(turn.FilesChanged) > 0:
+		msg += fmt.Sprintf("; files kept: %s (use /undo --files to restore them)", strings.Join(turn.FilesChanged, ", "))
+	}
+	if err != nil {
+		msg += fmt.Sprintf(" (errors: %v)", err)
+	}
+	m = m.addSystemMessage(msg)
+	m.saveSession()
+	return m, nil
+}
+
+// dropTurn removes the latest prompt for instruction and everything after
+// it from the transcript, along with its task record.
+func (m Model) dropTurn(instruction string) Model {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == RoleUser && m.messages[i].Content.Text == instruction {
+			m.messages = m.messages[:i]
+			break
+		}
+	}
+	for i := len(m.tasks) - 1; i >= 0; i-- {
+		if m.tasks[i].Instruction == instruction {
+			m.tasks = append(m.tasks[:i:i], m.tasks[i+1:]...)
+			break
+		}
+	}
+	return m.refreshFeedContent()
+}
+
+// handleBranch forks the session under a new ID. The branch starts with the
+// transcript, agent context, and undo history so far; /sessions switches
+// between it and the original. Without a name it lists related branches.
+func handleBranch(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		return m.listBranches()
+	}
+	if m.runtime == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	if m.streaming {
+		return m.addSystemMessage("Wait for the current response before branching"), nil
+	}
+	name := strings.Join(args, "-")
+	m.saveSession()
+	parent := m.session.ID
+	session := *m.session
+	session.ID = fmt.Sprintf("session-%d", time.Now().UnixNano())
+	session.StartTime = time.Now()
+	session.Branch = name
+	session.BranchOf = parent
+	m.session = &session
+	m.messages = append([]Message(nil), m.messages...)
+	m.tasks = append([]persistence.SessionTask(nil), m.tasks...)
+	m.runtime.Chat.Fork(session.ID)
+	m = m.addSystemMessage(fmt.Sprintf("On branch %s (%s); /sessions %s returns to the original", name, session.ID, parent))
+	m.saveSession()
+	return m, nil
+}
+
+// listBranches shows the session this one branched from and the branches
+// forked from it.
+func (m Model) listBranches() (Model, tea.Cmd) {
+	var b strings.Builder
+	if m.session.BranchOf != "" {
+		b.WriteString(fmt.Sprintf("On branch %s, forked from %s\n", m.session.Branch, m.session.BranchOf))
+	}
+	if store := m.sessionStore(); store != nil {
+		sessions, err := store.List(context.Background())
+		if err != nil {
+			return m.addSystemMessage(fmt.Sprintf("List sessions: %v", err)), nil
+		}
+		var children []string
+		for _, s := range sessions {
+			if s.Metadata["branch_of"] == m.session.ID {
+				children = append(children, fmt.Sprintf("  %s  %s", s.ID, s.Metadata["branch"]))
+			}
+		}
+		if len(children) > 0 {
+			b.WriteString("Branches:\n")
+			b.WriteString(strings.Join(children, "\n"))
+			b.WriteString("\n")
+		}
+	}
+	if b.Len() == 0 {
+		return m.addSystemMessage("No branches; /branch <name> forks this session"), nil
+	}
+	return m.addSystemMessage(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// noteAgent stores each instruction in the agent context.
+type noteAgent struct{}
+
+func (noteAgent) Initialize(config *framework.Config) error { return nil }
+func (noteAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	state.Set("note", task.Instruction)
+	return &framework.Result{Success: true}, nil
+}
+func (noteAgent) Capabilities() []framework.Capability { return nil }
+func (noteAgent) BuildGraph(task *framework.Task) (*framework.Graph, error) {
+	return nil, nil
+}
+
+// runTurn runs prompt as the shell would and records it in the transcript.
+func runTurn(t *testing.T, m Model, prompt string) Model {
+	t.Helper()
+	m.messages = append(m.messages, Message{ID: generateID(), Role: RoleUser, Content: MessageContent{Text: prompt}})
+	res, err := m.runtime.ExecuteInstruction(context.Background(), prompt, framework.TaskTypeCodeModification, nil)
+	if err != nil {
+		t.Fatalf("run %q: %v", prompt, err)
+	}
+	m.messages = append(m.messages, Message{ID: generateID(), Role: RoleAgent, Content: MessageContent{Text: "done"}})
+	m.tasks = append(m.tasks, *sessionTask(prompt, res, nil))
+	return m
+}
+
+func newBranchTestModel(t *testing.T) Model {
+	t.Helper()
+	store, err := persistence.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	rt := &runtimesvc.Runtime{
+		Sessions:  store,
+		Context:   framework.NewContext(),
+		Agent:     noteAgent{},
+		FollowUps: &runtimesvc.FollowUpSession{},
+		Chat:      &runtimesvc.ChatHistory{},
+	}
+	return NewModel(rt)
+}
+
+func TestUndoDropsLastTurn(t *testing.T) {
+	m := newBranchTestModel(t)
+	m = runTurn(t, m, "add a cache")
+	m = runTurn(t, m, "make it an LRU")
+
+	m, _ = handleUndo(m, nil)
+	if got := m.runtime.Context.GetString("note"); got != "add a cache" {
+		t.Fatalf("expected context from before the undone turn, got %q", got)
+	}
+	if len(m.tasks) != 1 || m.tasks[0].Instruction != "add a cache" {
+		t.Fatalf("unexpected tasks %+v", m.tasks)
+	}
+	for _, msg := range m.messages {
+		if msg.Content.Text == "make it an LRU" {
+			t.Fatalf("undone prompt still in transcript")
+		}
+	}
+	if last := m.messages[len(m.messages)-1].Content.Text; !strings.Contains(last, `Undid "make it an LRU"`) {
+		t.Fatalf("unexpected message %q", last)
+	}
+
+	m, _ = handleUndo(m, []string{"--force"})
+	if last := m.messages[len(m.messages)-1].Content.Text; last != "Usage: /undo [--files]" {
+		t.Fatalf("unexpected message %q", last)
+	}
+	m, _ = handleUndo(m, nil)
+	m, _ = handleUndo(m, nil)
+	if last := m.messages[len(m.messages)-1].Content.Text; last != "Cannot undo: nothing to undo" {
+		t.Fatalf("unexpected message %q", last)
+	}
+}
+
+func TestBranchForksSession(t *testing.T) {
+	m := newBranchTestModel(t)
+	m = runTurn(t, m, "add a cache")
+	parent := m.session.ID
+
+	m, _ = handleBranch(m, []string{"redis"})
+	if m.session.ID == parent || m.session.Branch != "redis" || m.session.BranchOf != parent {
+		t.Fatalf("unexpected branch session %+v", m.session)
+	}
+	m = runTurn(t, m, "use redis instead")
+	m, _ = handleUndo(m, nil)
+	m, _ = handleUndo(m, nil)
+	if got := m.runtime.Context.GetString("note"); got != "" {
+		t.Fatalf("branch should undo through the shared turn, got %q", got)
+	}
+
+	m, _ = handleSessions(m, []string{parent})
+	if m.session.ID != parent {
+		t.Fatalf("expected switch back to %s, got %s", parent, m.session.ID)
+	}
+	if turns := m.runtime.Chat.Turns(); len(turns) != 1 {
+		t.Fatalf("parent turns should survive undo in the branch, got %d", len(turns))
+	}
+	m, _ = handleBranch(m, nil)
+	if listing := m.messages[len(m.messages)-1].Content.Text; !strings.Contains(listing, "redis") {
+		t.Fatalf("expected branch listed, got %q", listing)
+	}
+}
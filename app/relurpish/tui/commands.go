@@ -1,11 +1,15 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/framework"
 )
 
 // CommandHandler mutates model state for /commands in the prompt bar.
@@ -58,6 +62,32 @@ func init() {
 		Usage:       "/clear",
 		Handler:     handleClear,
 	})
+	registerCommand(Command{
+		Name:        "reset",
+		Description: "Start the next task fresh instead of following up on the last one",
+		Usage:       "/reset",
+		Handler:     handleReset,
+	})
+	registerCommand(Command{
+		Name:        "rollback",
+		Description: "Undo the file changes of a job (default: the latest)",
+		Usage:       "/rollback [job-id]",
+		Handler:     handleRollback,
+	})
+	registerCommand(Command{
+		Name:        "undo",
+		Aliases:     []string{"u"},
+		Description: "Revert the last turn's context, and with --files its file changes",
+		Usage:       "/undo [--files]",
+		Handler:     handleUndo,
+	})
+	registerCommand(Command{
+		Name:        "branch",
+		Aliases:     []string{"br"},
+		Description: "Fork the session to try another approach, or list its branches",
+		Usage:       "/branch [name]",
+		Handler:     handleBranch,
+	})
 	registerCommand(Command{
 		Name:        "approve",
 		Aliases:     []string{"ap"},
@@ -86,6 +116,39 @@ func init() {
 		Usage:       "/mode <mode>",
 		Handler:     handleMode,
 	})
+	registerCommand(Command{
+		Name:        "use",
+		Aliases:     []string{"model"},
+		Description: "Switch the Ollama model and resize the context window to fit it",
+		Usage:       "/use <model>",
+		Handler:     handleUse,
+	})
+	registerCommand(Command{
+		Name:        "explain",
+		Aliases:     []string{"ex"},
+		Description: "Explain a file or symbol; follow-up prompts reuse the loaded context",
+		Usage:       "/explain <path> [symbol]",
+		Handler:     handleExplain,
+	})
+	registerCommand(Command{
+		Name:        "ask",
+		Description: "Answer a question about the codebase with the read-only ask agent",
+		Usage:       "/ask <question>",
+		Handler:     handleAsk,
+	})
+	registerCommand(Command{
+		Name:        "review",
+		Description: "Review uncommitted changes, or the changes since a ref, with the review agent",
+		Usage:       "/review [base] [head]",
+		Handler:     handleReview,
+	})
+	registerCommand(Command{
+		Name:        "goto",
+		Aliases:     []string{"def"},
+		Description: "Jump to a referenced definition while explaining",
+		Usage:       "/goto <symbol>",
+		Handler:     handleGoto,
+	})
 	registerCommand(Command{
 		Name:        "strategy",
 		Aliases:     []string{"s", "strat"},
@@ -93,6 +156,26 @@ func init() {
 		Usage:       "/strategy <strategy>",
 		Handler:     handleStrategy,
 	})
+	registerCommand(Command{
+		Name:        "autonomy",
+		Aliases:     []string{"auto"},
+		Description: "Set autonomy level (suggest, approve, autonomous), optionally time-boxed",
+		Usage:       "/autonomy [level] [duration]",
+		Handler:     handleAutonomy,
+	})
+	registerCommand(Command{
+		Name:        "sessions",
+		Aliases:     []string{"ss"},
+		Description: "List saved sessions or switch to one",
+		Usage:       "/sessions [id|last]",
+		Handler:     handleSessions,
+	})
+	registerCommand(Command{
+		Name:        "gc",
+		Description: "Archive old messages, context, and usage records to disk and free memory",
+		Usage:       "/gc",
+		Handler:     handleGC,
+	})
 }
 
 func registerCommand(cmd Command) {
@@ -199,6 +282,40 @@ func handleClear(m Model, args []string) (Model, tea.Cmd) {
 	return m.addSystemMessage("History cleared"), nil
 }
 
+func handleReset(m Model, args []string) (Model, tea.Cmd) {
+	if m.runtime == nil || m.runtime.FollowUps == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	previous, ok := m.runtime.FollowUps.Last()
+	if !ok {
+		return m.addSystemMessage("Nothing to reset; the next task already starts fresh"), nil
+	}
+	m.runtime.FollowUps.Reset()
+	return m.addSystemMessage(fmt.Sprintf("Forgot %q; the next task starts fresh", previous.Instruction)), nil
+}
+
+func handleRollback(m Model, args []string) (Model, tea.Cmd) {
+	if m.runtime == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	jobID := ""
+	if len(args) > 0 {
+		jobID = args[0]
+	}
+	jobID, restored, err := m.runtime.Rollback(jobID)
+	if err != nil && len(restored) == 0 {
+		return m.addSystemMessage(fmt.Sprintf("Rollback failed: %v", err)), nil
+	}
+	msg := fmt.Sprintf("Rolled back %s: %s", jobID, strings.Join(restored, ", "))
+	if len(restored) == 0 {
+		msg = fmt.Sprintf("Rolled back %s: no files changed", jobID)
+	}
+	if err != nil {
+		msg += fmt.Sprintf(" (errors: %v)", err)
+	}
+	return m.addSystemMessage(msg), nil
+}
+
 func handleApprove(m Model, args []string) (Model, tea.Cmd) {
 	for i := len(m.messages) - 1; i >= 0; i-- {
 		msg := &m.messages[i]
@@ -258,3 +375,119 @@ func handleStrategy(m Model, args []string) (Model, tea.Cmd) {
 	m.statusBar.strategy = args[0]
 	return m.addSystemMessage(fmt.Sprintf("Set strategy to: %s", args[0])), nil
 }
+
+func handleAutonomy(m Model, args []string) (Model, tea.Cmd) {
+	if m.runtime == nil || m.runtime.Autonomy == nil {
+		return m.addSystemMessage("Autonomy levels are unavailable in this session"), nil
+	}
+	if len(args) == 0 {
+		status := m.runtime.Autonomy.Status()
+		msg := fmt.Sprintf("Autonomy: %s", status.Label())
+		if status.Reason != "" {
+			msg += fmt.Sprintf(" (%s)", status.Reason)
+		}
+		return m.addSystemMessage(msg), nil
+	}
+	level, err := framework.ParseAutonomyLevel(args[0])
+	if err != nil {
+		return m.addSystemMessage(err.Error()), nil
+	}
+	var duration time.Duration
+	if len(args) > 1 {
+		duration, err = time.ParseDuration(args[1])
+		if err != nil {
+			return m.addSystemMessage(fmt.Sprintf("Invalid duration %q: %v", args[1], err)), nil
+		}
+	}
+	if err := m.runtime.Autonomy.Set(level, duration); err != nil {
+		return m.addSystemMessage(err.Error()), nil
+	}
+	return m.addSystemMessage(fmt.Sprintf("Set autonomy to: %s", m.runtime.Autonomy.Status().Label())), nil
+}
+
+func handleUse(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		return m.addSystemMessage(fmt.Sprintf("Current model: %s", m.session.Model)), nil
+	}
+	if m.runtime == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	sizing, err := m.runtime.UseModel(context.Background(), args[0])
+	if err != nil {
+		return m.addSystemMessage(fmt.Sprintf("Cannot use %s: %v", args[0], err)), nil
+	}
+	m.session.Model = args[0]
+	m.statusBar.model = args[0]
+	return m.addSystemMessage(fmt.Sprintf("Using %s; context window %s", args[0], sizing)), nil
+}
+
+// explainMode is the session mode that routes prompts to explain tasks.
+const explainMode = "explain"
+
+func handleExplain(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		return m.addSystemMessage("Usage: /explain <path> [symbol]"), nil
+	}
+	m.session.Mode = explainMode
+	m.statusBar.mode = explainMode
+	m.session.ExplainTarget = args[0]
+	m.session.ExplainSymbol = ""
+	prompt := fmt.Sprintf("Explain %s", args[0])
+	if len(args) > 1 {
+		m.session.ExplainSymbol = args[1]
+		prompt = fmt.Sprintf("Explain %s in %s", args[1], args[0])
+	}
+	m.input.SetValue(prompt)
+	return m.submitPrompt()
+}
+
+// askPreset is the pendingMeta key that routes one prompt to Runtime.Ask.
+const askPreset = "ask"
+
+// handleAsk sends the question to the read-only ask agent without changing
+// the session mode, so the next prompt goes back to the session agent.
+func handleAsk(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		return m.addSystemMessage("Usage: /ask <question>"), nil
+	}
+	m.pendingMeta = map[string]any{"preset": askPreset}
+	m.input.SetValue(strings.Join(args, " "))
+	return m.submitPrompt()
+}
+
+// reviewPreset is the pendingMeta key that routes one prompt to
+// Runtime.Review.
+const reviewPreset = "review"
+
+// handleReview reviews the working tree against HEAD or the given base, or
+// the commits head adds to base, without changing the session mode.
+func handleReview(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) > 2 {
+		return m.addSystemMessage("Usage: /review [base] [head]"), nil
+	}
+	meta := map[string]any{"preset": reviewPreset}
+	prompt := "Review uncommitted changes"
+	if len(args) > 0 {
+		meta["review_base"] = args[0]
+		prompt = fmt.Sprintf("Review changes since %s", args[0])
+	}
+	if len(args) > 1 {
+		meta["review_head"] = args[1]
+		prompt = fmt.Sprintf("Review %s against %s", args[1], args[0])
+	}
+	m.pendingMeta = meta
+	m.input.SetValue(prompt)
+	return m.submitPrompt()
+}
+
+func handleGoto(m Model, args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		return m.addSystemMessage("Usage: /goto <symbol>"), nil
+	}
+	if m.session.Mode != explainMode || m.session.ExplainTarget == "" {
+		return m.addSystemMessage("Start with /explain <path> before jumping to definitions"), nil
+	}
+	m.pendingMeta = map[string]any{"jump_to": args[0]}
+	m.input.SetValue(fmt.Sprintf("Explain the definition of %s and how it relates to %s", args[0], m.session.ExplainTarget))
+	return m.submitPrompt()
+}
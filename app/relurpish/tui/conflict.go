@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// conflictRequestMsg asks the person to settle a conflict raised while a
+// task runs; the task waits for the answer on reply.
+type conflictRequestMsg struct {
+	conflict framework.Conflict
+	reply    chan<- framework.ConflictResolution
+}
+
+// streamConflictResolver forwards a running task's conflicts to the TUI
+// over the task's stream channel.
+type streamConflictResolver struct {
+	ch chan<- tea.Msg
+}
+
+// ResolveConflict implements framework.ConflictResolver.
+func (r *streamConflictResolver) ResolveConflict(ctx context.Context, task *framework.Task, conflict framework.Conflict) (framework.ConflictResolution, error) {
+	reply := make(chan framework.ConflictResolution, 1)
+	select {
+	case r.ch <- conflictRequestMsg{conflict: conflict, reply: reply}:
+	case <-ctx.Done():
+		return framework.ConflictResolution{}, ctx.Err()
+	}
+	select {
+	case resolution := <-reply:
+		return resolution, nil
+	case <-ctx.Done():
+		return framework.ConflictResolution{}, ctx.Err()
+	}
+}
+
+// handleConflictRequest shows the conflict and switches the prompt bar to
+// the resolution dialog.
+func (m Model) handleConflictRequest(msg conflictRequestMsg) (tea.Model, tea.Cmd) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Decision needed: %s", msg.conflict.Reason)
+	for _, issue := range msg.conflict.Issues {
+		fmt.Fprintf(&b, "\n - %s", issue)
+	}
+	m = m.addSystemMessage(b.String())
+	m.conflict = &msg
+	m.conflictAction = ""
+	m.conflictPreviousMode = m.mode
+	m.mode = ModeConflict
+	m.input.SetValue("")
+	m.input.Focus()
+	if m.streamCh != nil {
+		return m, listenToStream(m.streamCh)
+	}
+	return m, nil
+}
+
+// handleConflictMode picks a resolution: a accepts the current state, while
+// r and c collect the plan change or clarification to resume with.
+func (m Model) handleConflictMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.conflict == nil {
+		m.mode = m.conflictPreviousMode
+		return m, nil
+	}
+	if m.conflictAction == "" {
+		switch msg.String() {
+		case "a", "A", "esc":
+			return m.resolveConflict(framework.ConflictResolution{Action: framework.ConflictAccept}), nil
+		case "r", "R":
+			m.conflictAction = framework.ConflictRevisePlan
+			m.input.Placeholder = "How should the plan change?"
+		case "c", "C":
+			m.conflictAction = framework.ConflictClarify
+			m.input.Placeholder = "Clarify the task"
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc":
+		m.conflictAction = ""
+		m.input.SetValue("")
+		return m, nil
+	case "enter":
+		text := strings.TrimSpace(m.input.Value())
+		if text == "" {
+			return m, nil
+		}
+		return m.resolveConflict(framework.ConflictResolution{Action: m.conflictAction, Clarification: text}), nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// resolveConflict answers the waiting task and restores the prompt bar.
+func (m Model) resolveConflict(resolution framework.ConflictResolution) Model {
+	m.conflict.reply <- resolution
+	m.conflict = nil
+	m.conflictAction = ""
+	m.mode = m.conflictPreviousMode
+	m.input.SetValue("")
+	m.input.Placeholder = ""
+	switch resolution.Action {
+	case framework.ConflictAccept:
+		return m.addSystemMessage("Accepted the current state")
+	case framework.ConflictRevisePlan:
+		return m.addSystemMessage("Resuming with a revised plan: " + resolution.Clarification)
+	default:
+		return m.addSystemMessage("Resuming with clarification: " + resolution.Clarification)
+	}
+}
+
+// closeConflict drops a dialog whose task stopped waiting, such as one that
+// timed out.
+func (m Model) closeConflict() Model {
+	if m.conflict == nil {
+		return m
+	}
+	m.conflict = nil
+	m.conflictAction = ""
+	m.input.SetValue("")
+	m.input.Placeholder = ""
+	if m.mode == ModeConflict {
+		m.mode = m.conflictPreviousMode
+	}
+	return m
+}
+
+// conflictPrompt renders the prompt bar text and hint of the dialog.
+func (m Model) conflictPrompt() (string, string) {
+	if m.conflictAction != "" {
+		return m.input.View(), " Enter to resume | Esc back"
+	}
+	reason := "the reviewer needs a decision"
+	if m.conflict != nil {
+		reason = m.conflict.conflict.Reason
+	}
+	return "Decision needed: " + reason, " a accept current state | r revise plan | c clarify"
+}
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestConflictDialogClarifies(t *testing.T) {
+	ch := make(chan tea.Msg, 1)
+	resolver := &streamConflictResolver{ch: ch}
+	answers := make(chan framework.ConflictResolution, 1)
+	go func() {
+		resolution, err := resolver.ResolveConflict(context.Background(), &framework.Task{}, framework.Conflict{Reason: "the plan says v1, the task says v2"})
+		if err != nil {
+			t.Errorf("resolve: %v", err)
+		}
+		answers <- resolution
+	}()
+
+	input := textinput.New()
+	input.Focus()
+	m := Model{input: input, mode: ModeNormal, messages: []Message{}}
+	updated, _ := m.Update(<-ch)
+	m = updated.(Model)
+	if m.mode != ModeConflict {
+		t.Fatalf("expected ModeConflict, got %v", m.mode)
+	}
+	if prompt, _ := m.conflictPrompt(); prompt != "Decision needed: the plan says v1, the task says v2" {
+		t.Fatalf("prompt = %q", prompt)
+	}
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("c")},
+		{Type: tea.KeyRunes, Runes: []rune("use v2")},
+		{Type: tea.KeyEnter},
+	} {
+		updated, _ = m.Update(key)
+		m = updated.(Model)
+	}
+	if m.mode != ModeNormal || m.conflict != nil {
+		t.Fatalf("expected the dialog closed, mode %v", m.mode)
+	}
+	resolution := <-answers
+	if resolution.Action != framework.ConflictClarify || resolution.Clarification != "use v2" {
+		t.Fatalf("resolution = %+v", resolution)
+	}
+}
@@ -0,0 +1,247 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diffFile is one file's section of a multi-file unified diff.
+type diffFile struct {
+	Path string
+	Diff string
+}
+
+// diffPane reviews the diff attached to a pending write approval one file at
+// a time. The tool call is approved once every file is accepted and denied as
+// soon as any file is rejected, since approvals cover the whole call.
+type diffPane struct {
+	files      []diffFile
+	index      int
+	accepted   []bool
+	sideBySide bool
+	view       viewport.Model
+}
+
+func newDiffPane(diff string, width, height int) *diffPane {
+	files := splitDiffFiles(diff)
+	if len(files) == 0 {
+		return nil
+	}
+	p := &diffPane{
+		files:    files,
+		accepted: make([]bool, len(files)),
+		view:     viewport.New(width, max(1, height-1)),
+	}
+	p.refresh()
+	return p
+}
+
+// splitDiffFiles splits a unified diff at its file headers. Text before the
+// first header is dropped.
+func splitDiffFiles(diff string) []diffFile {
+	lines := strings.SplitAfter(diff, "\n")
+	var files []diffFile
+	var current *diffFile
+	var body strings.Builder
+	flush := func() {
+		if current != nil {
+			current.Diff = strings.TrimRight(body.String(), "\n")
+			files = append(files, *current)
+		}
+		body.Reset()
+	}
+	for i, line := range lines {
+		gitHeader := strings.HasPrefix(line, "diff --git ")
+		plainHeader := strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") &&
+			(i == 0 || !strings.HasPrefix(lines[i-1], "diff --git "))
+		if gitHeader || plainHeader {
+			flush()
+			current = &diffFile{}
+		}
+		if current == nil {
+			continue
+		}
+		body.WriteString(line)
+		if strings.HasPrefix(line, "+++ ") {
+			if path := diffHeaderPath(line[4:]); path != "" {
+				current.Path = path
+			}
+		} else if strings.HasPrefix(line, "--- ") && current.Path == "" {
+			current.Path = diffHeaderPath(line[4:])
+		}
+	}
+	flush()
+	return files
+}
+
+func diffHeaderPath(name string) string {
+	name = strings.TrimSpace(name)
+	if tab := strings.IndexByte(name, '\t'); tab >= 0 {
+		name = name[:tab]
+	}
+	if name == "/dev/null" {
+		return ""
+	}
+	for _, prefix := range []string{"a/", "b/"} {
+		if strings.HasPrefix(name, prefix) {
+			return name[len(prefix):]
+		}
+	}
+	return name
+}
+
+func (p *diffPane) current() diffFile {
+	return p.files[p.index]
+}
+
+func (p *diffPane) allAccepted() bool {
+	for _, ok := range p.accepted {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// advance moves to the next file still awaiting review, wrapping around.
+func (p *diffPane) advance() {
+	for step := 1; step <= len(p.files); step++ {
+		next := (p.index + step) % len(p.files)
+		if !p.accepted[next] {
+			p.show(next)
+			return
+		}
+	}
+}
+
+func (p *diffPane) show(index int) {
+	p.index = (index + len(p.files)) % len(p.files)
+	p.refresh()
+	p.view.GotoTop()
+}
+
+func (p *diffPane) resize(width, height int) {
+	p.view.Width = width
+	p.view.Height = max(1, height-1)
+	p.refresh()
+}
+
+func (p *diffPane) refresh() {
+	diff := p.current().Diff
+	if p.sideBySide {
+		p.view.SetContent(renderSideBySide(diff, p.view.Width))
+	} else {
+		p.view.SetContent(renderDiff(diff))
+	}
+}
+
+func (p *diffPane) header() string {
+	file := p.current()
+	status := dimStyle.Render("pending")
+	if p.accepted[p.index] {
+		status = completedStyle.Render("accepted")
+	}
+	layout := "unified"
+	if p.sideBySide {
+		layout = "side-by-side"
+	}
+	return fmt.Sprintf("%s %s %s %s",
+		headerStyle.Render(fmt.Sprintf("File %d/%d", p.index+1, len(p.files))),
+		filePathStyle.Render(file.Path), status, dimStyle.Render("("+layout+")"))
+}
+
+func (p *diffPane) View() string {
+	return p.header() + "\n" + p.view.View()
+}
+
+// handleDiffPaneKey reviews the pending approval's diff file by file.
+func (m Model) handleDiffPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.diff
+	switch msg.String() {
+	case "y", "Y":
+		p.accepted[p.index] = true
+		if p.allAccepted() {
+			return m, approveHITLCmd(m.hitl, m.hitlRequest.ID)
+		}
+		p.advance()
+	case "a", "A":
+		return m, approveHITLCmd(m.hitl, m.hitlRequest.ID)
+	case "n", "N":
+		return m, denyHITLCmd(m.hitl, m.hitlRequest.ID, "rejected "+p.current().Path+" in diff review")
+	case "esc":
+		return m, denyHITLCmd(m.hitl, m.hitlRequest.ID, "denied in TUI")
+	case "tab", "right", "l":
+		p.show(p.index + 1)
+	case "shift+tab", "left", "h":
+		p.show(p.index - 1)
+	case "s":
+		p.sideBySide = !p.sideBySide
+		p.refresh()
+	default:
+		var cmd tea.Cmd
+		p.view, cmd = p.view.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// renderSideBySide lays a unified diff out in two columns, pairing each run
+// of removed lines with the added lines that follow it.
+func renderSideBySide(diff string, width int) string {
+	col := max(10, (width-3)/2)
+	var rows []string
+	var removed, added []string
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			left, right := "", ""
+			leftStyle, rightStyle := diffContextStyle, diffContextStyle
+			if i < len(removed) {
+				left, leftStyle = removed[i], diffRemoveStyle
+			}
+			if i < len(added) {
+				right, rightStyle = added[i], diffAddStyle
+			}
+			rows = append(rows, leftStyle.Render(diffColumn(left, col))+dimStyle.Render(" │ ")+rightStyle.Render(diffColumn(right, col)))
+		}
+		removed, added = nil, nil
+	}
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+			flush()
+			rows = append(rows, diffHeaderStyle.Render(line))
+		case !inHunk:
+			// File headers span both columns.
+			rows = append(rows, diffHeaderStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" has no place in either column.
+		default:
+			flush()
+			text := strings.TrimPrefix(line, " ")
+			rows = append(rows, diffContextStyle.Render(diffColumn(text, col))+dimStyle.Render(" │ ")+diffContextStyle.Render(diffColumn(text, col)))
+		}
+	}
+	flush()
+	return strings.Join(rows, "\n")
+}
+
+// diffColumn expands tabs and clips or pads text to exactly width cells.
+func diffColumn(text string, width int) string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+	if len(runes) > width {
+		runes = append(runes[:width-1], '…')
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const twoFileDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@
+ package main
+-var x = 1
++var x = 2
+diff --git a/new.go b/new.go
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+`
+
+func TestSplitDiffFiles(t *testing.T) {
+	files := splitDiffFiles(twoFileDiff)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[0].Path != "main.go" || files[1].Path != "new.go" {
+		t.Fatalf("unexpected paths %q, %q", files[0].Path, files[1].Path)
+	}
+	if !strings.HasSuffix(files[0].Diff, "+var x = 2") {
+		t.Fatalf("first file diff truncated: %q", files[0].Diff)
+	}
+
+	plain := splitDiffFiles("--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n")
+	if len(plain) != 1 || plain[0].Path != "old.txt" {
+		t.Fatalf("expected deleted old.txt, got %+v", plain)
+	}
+}
+
+func diffApprovalModel(hitl *fakeHITL, id string) Model {
+	req := &framework.PermissionRequest{
+		ID: id,
+		Permission: framework.PermissionDescriptor{
+			Action:   "tool_exec:file_patch",
+			Metadata: map[string]string{framework.ApprovalDiffKey: twoFileDiff},
+		},
+	}
+	hitl.pending = []*framework.PermissionRequest{req}
+	input := textinput.New()
+	input.Focus()
+	m := Model{hitl: hitl, hitlCh: hitl.ch, input: input, mode: ModeNormal, width: 80, height: 24}
+	updated, _ := m.Update(hitlEventMsg{event: framework.HITLEvent{Type: framework.HITLEventRequested, Request: req}})
+	return updated.(Model)
+}
+
+func pressKey(t *testing.T, m Model, key string) (Model, tea.Cmd) {
+	t.Helper()
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return updated.(Model), cmd
+}
+
+func TestDiffPaneApprovesOnceEveryFileIsAccepted(t *testing.T) {
+	hitl := newFakeHITL()
+	m := diffApprovalModel(hitl, "hitl-diff")
+	if m.diff == nil || len(m.diff.files) != 2 {
+		t.Fatalf("expected diff pane with 2 files, got %+v", m.diff)
+	}
+
+	m, cmd := pressKey(t, m, "y")
+	if cmd != nil {
+		t.Fatalf("accepting one of two files must not approve the call")
+	}
+	if m.diff.index != 1 {
+		t.Fatalf("expected pane to advance to the next file, at %d", m.diff.index)
+	}
+	m, cmd = pressKey(t, m, "y")
+	if cmd == nil {
+		t.Fatalf("expected approve cmd after accepting every file")
+	}
+	updated, _ := m.Update(cmd())
+	if updated.(Model).diff != nil || len(hitl.approved) != 1 {
+		t.Fatalf("expected approval and closed pane, approved %v", hitl.approved)
+	}
+}
+
+func TestDiffPaneRejectingAFileDeniesTheCall(t *testing.T) {
+	hitl := newFakeHITL()
+	m := diffApprovalModel(hitl, "hitl-diff")
+
+	m, _ = pressKey(t, m, "s")
+	if !m.diff.sideBySide || !strings.Contains(m.diff.view.View(), "│") {
+		t.Fatalf("expected side-by-side layout")
+	}
+	m, _ = pressKey(t, m, "l")
+	m, cmd := pressKey(t, m, "n")
+	if cmd == nil {
+		t.Fatalf("expected deny cmd")
+	}
+	cmd()
+	if len(hitl.denied) != 1 || len(hitl.approved) != 0 {
+		t.Fatalf("expected the call denied, approved %v denied %v", hitl.approved, hitl.denied)
+	}
+}
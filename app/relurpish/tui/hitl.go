@@ -55,12 +55,12 @@ func approveHITLCmd(svc hitlService, requestID string) tea.Cmd {
 	}
 }
 
-func denyHITLCmd(svc hitlService, requestID string) tea.Cmd {
+func denyHITLCmd(svc hitlService, requestID, reason string) tea.Cmd {
 	return func() tea.Msg {
 		if svc == nil {
 			return hitlResolvedMsg{requestID: requestID, approved: false, err: fmt.Errorf("hitl service unavailable")}
 		}
-		err := svc.DenyHITL(requestID, "denied in TUI")
+		err := svc.DenyHITL(requestID, reason)
 		return hitlResolvedMsg{requestID: requestID, approved: false, err: err}
 	}
 }
@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultFeedLimit applies when the runtime config does not set one.
+	defaultFeedLimit = 500
+	// /gc keeps this much of each buffer in memory and archives the rest.
+	gcFeedKeep    = 50
+	gcHistoryKeep = 50
+	gcUsageKeep   = 1000
+	// memoryPollInterval paces status bar memory sampling; ReadMemStats
+	// stops the world briefly, so it is not done per frame.
+	memoryPollInterval = 5 * time.Second
+)
+
+// memoryTickMsg triggers a memory usage sample for the status bar.
+type memoryTickMsg struct{}
+
+// archivedMessage is the spill file record for a feed message.
+type archivedMessage struct {
+	Session string  `json:"session"`
+	Message Message `json:"message"`
+}
+
+func pollMemory() tea.Cmd {
+	return tea.Tick(memoryPollInterval, func(time.Time) tea.Msg { return memoryTickMsg{} })
+}
+
+func (m Model) handleMemoryTick() (tea.Model, tea.Cmd) {
+	return m.refreshMemoryStats(), pollMemory()
+}
+
+// refreshMemoryStats copies current usage into the status bar.
+func (m Model) refreshMemoryStats() Model {
+	m.statusBar.messages = len(m.messages)
+	if m.runtime == nil {
+		return m
+	}
+	m.statusBar.heap = m.runtime.MemoryUsage().HeapAlloc
+	return m
+}
+
+func (m Model) feedLimit() int {
+	if m.config.FeedLimit > 0 {
+		return m.config.FeedLimit
+	}
+	return defaultFeedLimit
+}
+
+// capFeed archives the oldest messages once the feed exceeds its limit. It
+// trims to three quarters of the limit so archiving does not run on every
+// new message.
+func (m Model) capFeed() Model {
+	limit := m.feedLimit()
+	if len(m.messages) <= limit {
+		return m
+	}
+	return m.archiveMessages(len(m.messages) - limit*3/4)
+}
+
+// archiveMessages spills the oldest n messages and drops them from memory.
+// The newest message is never archived so a streaming reply stays in place.
+func (m Model) archiveMessages(n int) Model {
+	if n >= len(m.messages) {
+		n = len(m.messages) - 1
+	}
+	if n <= 0 {
+		return m
+	}
+	if m.runtime != nil {
+		entries := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			entries[i] = archivedMessage{Session: m.session.ID, Message: m.messages[i]}
+		}
+		if err := m.runtime.Spill.Append("feed", entries...); err != nil && m.runtime.Logger != nil {
+			m.runtime.Logger.Printf("warning: feed spill failed: %v", err)
+		}
+	}
+	m.archived += n
+	m.messages = append([]Message(nil), m.messages[n:]...)
+	return m
+}
+
+func handleGC(m Model, args []string) (Model, tea.Cmd) {
+	if m.runtime == nil {
+		return m.addSystemMessage("Runtime unavailable"), nil
+	}
+	feedDropped := 0
+	if len(m.messages) > gcFeedKeep {
+		before := m.archived
+		m = m.archiveMessages(len(m.messages) - gcFeedKeep)
+		feedDropped = m.archived - before
+	}
+	report := m.runtime.CollectGarbage(gcHistoryKeep, gcUsageKeep)
+	m = m.refreshMemoryStats()
+	msg := fmt.Sprintf("GC: archived %d messages, %d context entries, %d usage records; heap %s → %s",
+		feedDropped, report.HistoryDropped, report.UsageDropped, formatBytes(report.HeapBefore), formatBytes(report.HeapAfter))
+	if path := m.runtime.Spill.Path(); path != "" {
+		msg += "\nArchive: " + path
+	}
+	return m.addSystemMessage(msg), nil
+}
+
+func formatBytes(n uint64) string {
+	const mb = 1 << 20
+	if n < mb {
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%.0fMB", float64(n)/mb)
+}
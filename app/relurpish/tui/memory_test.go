@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+func newMemoryTestModel(t *testing.T, feedLimit int) Model {
+	t.Helper()
+	spill, err := persistence.NewSpillFile(filepath.Join(t.TempDir(), "spill.jsonl"))
+	if err != nil {
+		t.Fatalf("new spill file: %v", err)
+	}
+	rt := &runtimesvc.Runtime{
+		Config:  runtimesvc.Config{FeedLimit: feedLimit},
+		Context: framework.NewContext(),
+		Usage:   framework.NewUsageTracker(nil),
+		Spill:   spill,
+	}
+	return NewModel(rt)
+}
+
+func TestFeedCapArchivesOldestMessages(t *testing.T) {
+	m := newMemoryTestModel(t, 8)
+	for i := 0; i < 9; i++ {
+		m = m.addSystemMessage(fmt.Sprintf("note %d", i))
+	}
+	if len(m.messages) != 6 {
+		t.Fatalf("expected feed trimmed to 6 messages, got %d", len(m.messages))
+	}
+	if m.archived != 3 || m.runtime.Spill.Written() != 3 {
+		t.Fatalf("expected 3 archived messages, got %d (spilled %d)", m.archived, m.runtime.Spill.Written())
+	}
+	if m.messages[len(m.messages)-1].Content.Text != "note 8" {
+		t.Fatalf("expected newest message retained")
+	}
+}
+
+func TestGCCommandArchivesFeedAndContext(t *testing.T) {
+	m := newMemoryTestModel(t, 1000)
+	for i := 0; i < gcFeedKeep+10; i++ {
+		m.messages = append(m.messages, Message{ID: fmt.Sprintf("msg-%d", i), Role: RoleSystem})
+	}
+	for i := 0; i < gcHistoryKeep+5; i++ {
+		m.runtime.Context.AddInteraction("user", "hi", nil)
+	}
+	m, _ = handleGC(m, nil)
+	// The report itself is appended after archiving.
+	if len(m.messages) != gcFeedKeep+1 {
+		t.Fatalf("expected %d messages after gc, got %d", gcFeedKeep+1, len(m.messages))
+	}
+	report := m.messages[len(m.messages)-1].Content.Text
+	if !strings.Contains(report, "archived 10 messages") || !strings.Contains(report, "4 context entries") {
+		t.Fatalf("unexpected gc report: %s", report)
+	}
+	if m.statusBar.heap == 0 {
+		t.Fatalf("expected status bar heap sample")
+	}
+}
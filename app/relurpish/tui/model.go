@@ -13,34 +13,58 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/lexcodex/relurpify/agents"
 	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/persistence"
 )
 
+// Options tunes how the shell starts.
+type Options struct {
+	// ResumeSession reloads a saved session by ID; "last" picks the most
+	// recently updated one.
+	ResumeSession string
+}
+
 // Run bootstraps the new agentic TUI experience.
-func Run(ctx context.Context, rt *runtimesvc.Runtime) error {
+func Run(ctx context.Context, rt *runtimesvc.Runtime, opts Options) error {
 	if rt == nil {
 		return fmt.Errorf("runtime is required")
 	}
 	model := NewModel(rt)
+	if opts.ResumeSession != "" {
+		resumed, err := model.resumeSession(opts.ResumeSession)
+		if err != nil {
+			return err
+		}
+		model = resumed
+	}
 	program := tea.NewProgram(
 		model,
 		tea.WithContext(ctx),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
-	_, err := program.Run()
+	final, err := program.Run()
+	if m, ok := final.(Model); ok {
+		m.saveSession()
+	}
 	return err
 }
 
 // Model implements the Bubble Tea Model interface and coordinates the feed,
 // prompt bar, and status bar components described in the new UX spec.
 type Model struct {
-	runtime *runtimesvc.Runtime
-	config  runtimesvc.Config
-	hitl    hitlService
-	hitlCh  <-chan framework.HITLEvent
-	hitlOff func()
+	runtime  *runtimesvc.Runtime
+	config   runtimesvc.Config
+	hitl     hitlService
+	hitlCh   <-chan framework.HITLEvent
+	hitlOff  func()
+	watchCh  <-chan runtimesvc.WatchEvent
+	watchOff func()
+	modelCh  <-chan llm.ModelEvent
+	modelOff func()
 
 	feed  *viewport.Model
 	input textinput.Model
@@ -51,6 +75,9 @@ type Model struct {
 	messages []Message
 	context  *AgentContext
 	session  *Session
+	tasks    []persistence.SessionTask
+	// archived counts feed messages spilled to disk by capFeed.
+	archived int
 
 	width  int
 	height int
@@ -65,11 +92,23 @@ type Model struct {
 	focusIndex int
 	autoFollow bool
 
+	// pendingMeta carries one-shot task metadata (e.g. explain jumps) into
+	// the next submitted prompt.
+	pendingMeta map[string]any
+
 	// HITL prompt state (temporarily replaces normal prompt)
 	hitlRequest        *framework.PermissionRequest
 	hitlPreviousMode   InputMode
 	hitlPreviousValue  string
 	hitlPreviousPrompt string
+	// diff reviews the pending approval's previewed change, when it has one.
+	diff *diffPane
+
+	// Conflict dialog state: the request the running task waits on, and
+	// the resolution whose text is being typed.
+	conflict             *conflictRequestMsg
+	conflictAction       framework.ConflictAction
+	conflictPreviousMode InputMode
 }
 
 // InputMode tracks the role of the prompt bar.
@@ -80,6 +119,7 @@ const (
 	ModeCommand
 	ModeFilePicker
 	ModeHITL
+	ModeConflict
 )
 
 // Message structures mirror the specification for rendering rich agent output.
@@ -185,6 +225,8 @@ type MessageMetadata struct {
 	Duration    time.Duration
 	TokensUsed  int
 	TokensTotal int
+	// ErrorKind colors system messages that report a failure.
+	ErrorKind framework.ErrorKind
 }
 
 // Session tracks high-level session metadata for the status bar.
@@ -196,6 +238,11 @@ type Session struct {
 	Agent         string
 	Mode          string
 	Strategy      string
+	ExplainTarget string
+	ExplainSymbol string
+	// Branch names a session forked with /branch from BranchOf.
+	Branch        string
+	BranchOf      string
 	TotalTokens   int
 	TotalDuration time.Duration
 }
@@ -256,6 +303,12 @@ func NewModel(rt *runtimesvc.Runtime) Model {
 	if hitlSvc != nil {
 		hitlCh, hitlOff = hitlSvc.SubscribeHITL()
 	}
+	var watchCh <-chan runtimesvc.WatchEvent
+	var watchOff func()
+	if rt.WatchEnabled() {
+		watchCh, watchOff = rt.SubscribeWatch(32)
+	}
+	modelCh, modelOff := rt.SubscribeModelEvents(16)
 	input := textinput.New()
 	input.Placeholder = "Type a message or /help for commands"
 	input.Focus()
@@ -275,6 +328,7 @@ func NewModel(rt *runtimesvc.Runtime) Model {
 		Agent:     cfg.AgentLabel(),
 		Mode:      string(framework.AgentModePrimary),
 	}
+	rt.Chat.Use(session.ID)
 
 	if rt.Registration != nil && rt.Registration.Manifest != nil {
 		manifest := rt.Registration.Manifest
@@ -297,6 +351,10 @@ func NewModel(rt *runtimesvc.Runtime) Model {
 		tokens:     session.TotalTokens,
 		duration:   session.TotalDuration,
 		lastUpdate: time.Now(),
+		autonomy:   rt.Autonomy,
+	}
+	if event, ok := rt.ModelStatus(); ok {
+		status.modelState = modelState(event)
 	}
 
 	ctx := &AgentContext{
@@ -315,6 +373,10 @@ func NewModel(rt *runtimesvc.Runtime) Model {
 		hitl:       hitlSvc,
 		hitlCh:     hitlCh,
 		hitlOff:    hitlOff,
+		watchCh:    watchCh,
+		watchOff:   watchOff,
+		modelCh:    modelCh,
+		modelOff:   modelOff,
 		feed:       vp,
 		input:      input,
 		spinner:    sp,
@@ -338,6 +400,14 @@ func (m Model) enterHITL(req *framework.PermissionRequest) Model {
 	}
 	m.hitlRequest = req
 	m.mode = ModeHITL
+	m.diff = nil
+	if diff := req.Permission.Metadata[framework.ApprovalDiffKey]; diff != "" {
+		width, height := m.width, m.height
+		if m.feed != nil {
+			width, height = m.feed.Width, m.feed.Height
+		}
+		m.diff = newDiffPane(diff, width, height)
+	}
 	m.input.SetValue("")
 	m.input.Placeholder = ""
 	m.input.Focus()
@@ -349,6 +419,7 @@ func (m Model) exitHITL() Model {
 		return m
 	}
 	m.hitlRequest = nil
+	m.diff = nil
 	m.mode = m.hitlPreviousMode
 	m.input.Placeholder = m.hitlPreviousPrompt
 	m.input.SetValue(m.hitlPreviousValue)
@@ -372,7 +443,7 @@ func (m Model) submitPrompt() (Model, tea.Cmd) {
 		},
 	}
 	m.messages = append(m.messages, userMsg)
-	m = m.refreshFeedContent()
+	m = m.capFeed().refreshFeedContent()
 
 	m.input.SetValue("")
 	m.mode = ModeNormal
@@ -383,6 +454,7 @@ func (m Model) submitPrompt() (Model, tea.Cmd) {
 	ch := make(chan tea.Msg)
 	m.streamCh = ch
 	go m.runAgentStream(ch, value)
+	m.pendingMeta = nil
 
 	return m, listenToStream(ch)
 }
@@ -404,6 +476,7 @@ func (m Model) runAgentStream(ch chan tea.Msg, prompt string) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	ctx = framework.WithConflictResolver(ctx, &streamConflictResolver{ch: ch})
 
 	metadata := map[string]any{
 		"source":        "relurpish",
@@ -415,6 +488,15 @@ func (m Model) runAgentStream(ch chan tea.Msg, prompt string) {
 	if _, ok := metadata["strategy"]; !ok && m.session != nil && m.session.Strategy != "" {
 		metadata["strategy"] = m.session.Strategy
 	}
+	taskType := framework.TaskTypeCodeGeneration
+	if m.session != nil && m.session.Mode == explainMode {
+		taskType = framework.TaskTypeExplain
+		metadata["explain_target"] = m.session.ExplainTarget
+		metadata["explain_symbol"] = m.session.ExplainSymbol
+	}
+	for k, v := range m.pendingMeta {
+		metadata[k] = v
+	}
 	
 	// Create a streaming callback if supported by the agent
 	if ch != nil {
@@ -423,20 +505,44 @@ func (m Model) runAgentStream(ch chan tea.Msg, prompt string) {
 		}
 	}
 
-	result, err := m.runtime.ExecuteInstruction(ctx, prompt, framework.TaskTypeCodeGeneration, metadata)
+	var result *framework.Result
+	var err error
+	var review *agents.CodeReview
+	switch metadata["preset"] {
+	case askPreset:
+		result, err = m.runtime.Ask(ctx, prompt)
+	case reviewPreset:
+		base, _ := metadata["review_base"].(string)
+		head, _ := metadata["review_head"].(string)
+		review, err = m.runtime.Review(ctx, runtimesvc.ReviewOptions{Base: base, Head: head})
+		if review != nil {
+			result = &framework.Result{NodeID: "review_comment", Success: true}
+		}
+	default:
+		result, err = m.runtime.ExecuteInstruction(ctx, prompt, taskType, metadata)
+	}
+	task := sessionTask(prompt, result, err)
 	if err != nil {
-		ch <- StreamErrorMsg{Error: err}
+		ch <- StreamErrorMsg{Error: err, Task: task}
 		ch <- StreamCompleteMsg{Duration: time.Since(start), TokensUsed: 0}
 		close(ch)
 		return
 	}
 
 	summary := summarizeResult(result)
+	if metadata["preset"] == askPreset && result != nil {
+		if answer, ok := result.Data["final_output"].(string); ok {
+			summary = answer
+		}
+	}
+	if review != nil {
+		summary = review.Markdown()
+	}
 	if summary != "" {
 		ch <- StreamTokenMsg{TokenType: TokenText, Token: summary}
 	}
 
-	ch <- StreamCompleteMsg{Duration: time.Since(start), TokensUsed: estimateTokens(summary)}
+	ch <- StreamCompleteMsg{Duration: time.Since(start), TokensUsed: estimateTokens(summary), Task: task}
 	close(ch)
 }
 
@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+type modelEventMsg struct{ event llm.ModelEvent }
+
+func listenModelEvents(ch <-chan llm.ModelEvent) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ev, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return modelEventMsg{event: ev}
+	}
+}
+
+// handleModelEvent shows warm-up progress and idle unloads next to the model
+// in the status bar. Failed loads are also noted in the feed.
+func (m Model) handleModelEvent(msg modelEventMsg) (tea.Model, tea.Cmd) {
+	m.statusBar.modelState = modelState(msg.event)
+	if msg.event.Type == llm.ModelLoadFailed {
+		m = m.addSystemMessage(runtimesvc.FormatModelEvent(msg.event))
+	}
+	return m, listenModelEvents(m.modelCh)
+}
+
+// modelState is the status bar label for event; empty once the model is
+// ready.
+func modelState(event llm.ModelEvent) string {
+	switch event.Type {
+	case llm.ModelLoading:
+		if event.Elapsed < time.Second {
+			return "loading…"
+		}
+		return fmt.Sprintf("loading %s", event.Elapsed.Round(time.Second))
+	case llm.ModelLoadFailed:
+		return "load failed"
+	case llm.ModelUnloaded:
+		return "unloaded"
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lexcodex/relurpify/llm"
+)
+
+func TestModelEventsShowInStatusBar(t *testing.T) {
+	ch := make(chan llm.ModelEvent, 1)
+	m := Model{modelCh: ch, messages: []Message{}, statusBar: StatusBar{model: "qwen"}}
+
+	updatedAny, cmd := m.Update(modelEventMsg{event: llm.ModelEvent{Type: llm.ModelLoading, Model: "qwen", Elapsed: 12 * time.Second}})
+	updated := updatedAny.(Model)
+	if cmd == nil {
+		t.Fatal("expected the model to keep listening for model events")
+	}
+	if view := updated.statusBar.View(200); !strings.Contains(view, "qwen (loading 12s)") {
+		t.Fatalf("expected load progress in status bar, got %q", view)
+	}
+
+	ch <- llm.ModelEvent{Type: llm.ModelReady, Model: "qwen", Elapsed: 15 * time.Second}
+	updatedAny, _ = updated.Update(cmd())
+	updated = updatedAny.(Model)
+	if updated.statusBar.modelState != "" {
+		t.Fatalf("expected ready model to clear the state, got %q", updated.statusBar.modelState)
+	}
+
+	updatedAny, _ = updated.Update(modelEventMsg{event: llm.ModelEvent{Type: llm.ModelLoadFailed, Model: "qwen", Err: errors.New("connection refused")}})
+	updated = updatedAny.(Model)
+	if updated.statusBar.modelState != "load failed" {
+		t.Fatalf("unexpected state %q", updated.statusBar.modelState)
+	}
+	if len(updated.messages) != 1 || !strings.Contains(updated.messages[0].Content.Text, "loading qwen failed: connection refused") {
+		t.Fatalf("expected failure in feed, got %+v", updated.messages)
+	}
+}
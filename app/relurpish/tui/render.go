@@ -58,6 +58,9 @@ func renderUserMessage(msg Message) string {
 }
 
 func renderSystemMessage(msg Message) string {
+	if msg.Metadata.ErrorKind != "" {
+		return errorKindStyle(msg.Metadata.ErrorKind).Render(msg.Content.Text)
+	}
 	return dimStyle.Render(msg.Content.Text)
 }
 
@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// lastSession is the resume alias for the most recently updated session.
+const lastSession = "last"
+
+// sessionListLimit caps how many sessions /sessions prints.
+const sessionListLimit = 10
+
+// sessionTask converts a finished instruction into its session record entry.
+func sessionTask(instruction string, result *framework.Result, err error) *persistence.SessionTask {
+	task := &persistence.SessionTask{
+		ID:          fmt.Sprintf("task-%d", time.Now().UnixNano()),
+		Instruction: instruction,
+		CompletedAt: time.Now().UTC(),
+	}
+	if result != nil {
+		task.Success = result.Success
+		task.Data = result.Data
+		if result.Error != nil {
+			task.Error = result.Error.Error()
+		}
+	}
+	if err != nil {
+		task.Success = false
+		task.Error = err.Error()
+	}
+	return task
+}
+
+// sessionStore returns the runtime's session store, if any.
+func (m Model) sessionStore() persistence.SessionStore {
+	if m.runtime == nil {
+		return nil
+	}
+	return m.runtime.Sessions
+}
+
+// sessionRecord captures the transcript, task results, and agent context.
+func (m Model) sessionRecord() (*persistence.SessionRecord, error) {
+	messages, err := json.Marshal(m.messages)
+	if err != nil {
+		return nil, err
+	}
+	record := &persistence.SessionRecord{
+		ID:            m.session.ID,
+		Title:         m.sessionTitle(),
+		Workspace:     m.session.Workspace,
+		Agent:         m.session.Agent,
+		Model:         m.session.Model,
+		Mode:          m.session.Mode,
+		Strategy:      m.session.Strategy,
+		CreatedAt:     m.session.StartTime.UTC(),
+		Messages:      messages,
+		Tasks:         m.tasks,
+		ContextFiles:  m.context.List(),
+		TotalTokens:   m.session.TotalTokens,
+		TotalDuration: m.session.TotalDuration,
+	}
+	metadata := map[string]string{}
+	if m.session.ExplainTarget != "" {
+		metadata["explain_target"] = m.session.ExplainTarget
+		metadata["explain_symbol"] = m.session.ExplainSymbol
+	}
+	if m.session.BranchOf != "" {
+		metadata["branch"] = m.session.Branch
+		metadata["branch_of"] = m.session.BranchOf
+	}
+	if len(metadata) > 0 {
+		record.Metadata = metadata
+	}
+	if m.runtime != nil && m.runtime.Context != nil {
+		record.Context = m.runtime.Context.Snapshot()
+	}
+	return record, nil
+}
+
+// sessionTitle uses the first prompt so /sessions is easy to scan.
+func (m Model) sessionTitle() string {
+	for _, msg := range m.messages {
+		if msg.Role != RoleUser {
+			continue
+		}
+		title := strings.Join(strings.Fields(msg.Content.Text), " ")
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		return title
+	}
+	return ""
+}
+
+// saveSession persists the current session. Sessions without any prompt are
+// skipped so opening and closing the shell leaves nothing behind.
+func (m Model) saveSession() {
+	store := m.sessionStore()
+	if store == nil || m.session == nil || m.sessionTitle() == "" {
+		return
+	}
+	record, err := m.sessionRecord()
+	if err == nil {
+		err = store.Save(context.Background(), record)
+	}
+	if err != nil && m.runtime.Logger != nil {
+		m.runtime.Logger.Printf("warning: session %s not saved: %v", m.session.ID, err)
+	}
+}
+
+// resumeSession replaces the current transcript, task list, and agent context
+// with a saved session.
+func (m Model) resumeSession(id string) (Model, error) {
+	store := m.sessionStore()
+	if store == nil {
+		return m, errors.New("session store unavailable")
+	}
+	ctx := context.Background()
+	if id == lastSession {
+		sessions, err := store.List(ctx)
+		if err != nil {
+			return m, err
+		}
+		if len(sessions) == 0 {
+			return m, errors.New("no saved sessions")
+		}
+		id = sessions[0].ID
+	}
+	record, ok, err := store.Load(ctx, id)
+	if err != nil {
+		return m, err
+	}
+	if !ok {
+		return m, fmt.Errorf("session %s not found", id)
+	}
+	var messages []Message
+	if len(record.Messages) > 0 {
+		if err := json.Unmarshal(record.Messages, &messages); err != nil {
+			return m, fmt.Errorf("decode session %s: %w", id, err)
+		}
+	}
+	if m.runtime.Context != nil {
+		snapshot := record.Context
+		if snapshot == nil {
+			snapshot = framework.NewContext().Snapshot()
+		}
+		if err := m.runtime.Context.Restore(snapshot); err != nil {
+			return m, fmt.Errorf("restore session %s: %w", id, err)
+		}
+	}
+
+	session := *m.session
+	session.ID = record.ID
+	session.StartTime = record.CreatedAt
+	session.Strategy = record.Strategy
+	session.ExplainTarget = record.Metadata["explain_target"]
+	session.ExplainSymbol = record.Metadata["explain_symbol"]
+	session.Branch = record.Metadata["branch"]
+	session.BranchOf = record.Metadata["branch_of"]
+	session.TotalTokens = record.TotalTokens
+	session.TotalDuration = record.TotalDuration
+	if record.Mode != "" {
+		session.Mode = record.Mode
+	}
+	m.session = &session
+	m.runtime.Chat.Use(session.ID)
+	m.messages = messages
+	m.tasks = append([]persistence.SessionTask(nil), record.Tasks...)
+	m.context = &AgentContext{
+		Files:       append([]string{}, record.ContextFiles...),
+		Directories: m.context.Directories,
+		MaxTokens:   m.context.MaxTokens,
+	}
+
+	m.statusBar.mode = session.Mode
+	m.statusBar.strategy = session.Strategy
+	m.statusBar.tokens = session.TotalTokens
+	m.statusBar.duration = session.TotalDuration
+	m.statusBar.lastUpdate = time.Now()
+	return m.refreshFeedContent(), nil
+}
+
+func handleSessions(m Model, args []string) (Model, tea.Cmd) {
+	store := m.sessionStore()
+	if store == nil {
+		return m.addSystemMessage("Session persistence is unavailable"), nil
+	}
+	if len(args) == 0 {
+		sessions, err := store.List(context.Background())
+		if err != nil {
+			return m.addSystemMessage(fmt.Sprintf("List sessions: %v", err)), nil
+		}
+		if len(sessions) == 0 {
+			return m.addSystemMessage("No saved sessions"), nil
+		}
+		var b strings.Builder
+		b.WriteString("Sessions (newest first):\n")
+		for i, s := range sessions {
+			if i == sessionListLimit {
+				b.WriteString(fmt.Sprintf("  … %d more\n", len(sessions)-sessionListLimit))
+				break
+			}
+			marker := " "
+			if s.ID == m.session.ID {
+				marker = "*"
+			}
+			title := s.Title
+			if branch := s.Metadata["branch"]; branch != "" {
+				title = fmt.Sprintf("[%s] %s", branch, title)
+			}
+			b.WriteString(fmt.Sprintf("%s %s  %s  %d tasks  %s\n", marker, s.ID, s.UpdatedAt.Local().Format("2006-01-02 15:04"), len(s.Tasks), title))
+		}
+		b.WriteString("Use /sessions <id> to switch")
+		return m.addSystemMessage(b.String()), nil
+	}
+	if m.streaming {
+		return m.addSystemMessage("Wait for the current response before switching sessions"), nil
+	}
+	if args[0] == m.session.ID {
+		return m.addSystemMessage(fmt.Sprintf("Already in session %s", args[0])), nil
+	}
+	m.saveSession()
+	resumed, err := m.resumeSession(args[0])
+	if err != nil {
+		return m.addSystemMessage(err.Error()), nil
+	}
+	return resumed.addSystemMessage(fmt.Sprintf("Resumed session %s", resumed.session.ID)), nil
+}
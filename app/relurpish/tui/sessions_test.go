@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+func newSessionTestModel(t *testing.T, store persistence.SessionStore) Model {
+	t.Helper()
+	rt := &runtimesvc.Runtime{Sessions: store, Context: framework.NewContext()}
+	return NewModel(rt)
+}
+
+func TestSessionSaveAndResume(t *testing.T) {
+	store, err := persistence.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	m := newSessionTestModel(t, store)
+	m.messages = append(m.messages, Message{ID: "msg-1", Role: RoleUser, Content: MessageContent{Text: "add retries to the client"}})
+	m.tasks = append(m.tasks, *sessionTask("add retries to the client", &framework.Result{Success: true}, nil))
+	m.session.Strategy = "plan_execute"
+	m.session.TotalTokens = 42
+	if err := m.context.AddFile("client.go"); err != nil {
+		t.Fatalf("add file: %v", err)
+	}
+	m.runtime.Context.Set("task.instruction", "add retries to the client")
+	m.saveSession()
+
+	fresh := newSessionTestModel(t, store)
+	resumed, err := fresh.resumeSession(lastSession)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if resumed.session.ID != m.session.ID {
+		t.Fatalf("expected session %s, got %s", m.session.ID, resumed.session.ID)
+	}
+	if len(resumed.messages) != 1 || resumed.messages[0].Content.Text != "add retries to the client" {
+		t.Fatalf("unexpected transcript %+v", resumed.messages)
+	}
+	if len(resumed.tasks) != 1 || !resumed.tasks[0].Success {
+		t.Fatalf("unexpected tasks %+v", resumed.tasks)
+	}
+	if resumed.session.Strategy != "plan_execute" || resumed.statusBar.tokens != 42 {
+		t.Fatalf("session metadata not restored: %+v", resumed.session)
+	}
+	if files := resumed.context.List(); len(files) != 1 || files[0] != "client.go" {
+		t.Fatalf("unexpected context files %v", files)
+	}
+	if got := resumed.runtime.Context.GetString("task.instruction"); got != "add retries to the client" {
+		t.Fatalf("agent context not restored, got %q", got)
+	}
+}
+
+func TestSessionsCommandListsAndSwitches(t *testing.T) {
+	store, err := persistence.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	saved := newSessionTestModel(t, store)
+	saved.session.ID = "session-old"
+	saved.messages = append(saved.messages, Message{ID: "msg-1", Role: RoleUser, Content: MessageContent{Text: "old prompt"}})
+	saved.saveSession()
+
+	m := newSessionTestModel(t, store)
+	m, _ = handleSessions(m, nil)
+	listing := m.messages[len(m.messages)-1].Content.Text
+	if !strings.Contains(listing, "session-old") || !strings.Contains(listing, "old prompt") {
+		t.Fatalf("expected session listed, got %q", listing)
+	}
+
+	m, _ = handleSessions(m, []string{"session-old"})
+	if m.session.ID != "session-old" {
+		t.Fatalf("expected switch to session-old, got %s", m.session.ID)
+	}
+	if m.messages[0].Content.Text != "old prompt" {
+		t.Fatalf("expected old transcript, got %+v", m.messages)
+	}
+
+	m, _ = handleSessions(m, []string{"missing"})
+	if !strings.Contains(m.messages[len(m.messages)-1].Content.Text, "not found") {
+		t.Fatalf("expected not found message")
+	}
+}
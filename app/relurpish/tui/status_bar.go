@@ -6,18 +6,29 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/lexcodex/relurpify/framework"
 )
 
 // StatusBar renders workspace/model/agent metadata plus tokens & duration.
 type StatusBar struct {
-	workspace  string
-	model      string
+	workspace string
+	model     string
+	// modelState shows warm-up progress or an idle unload; see
+	// handleModelEvent.
+	modelState string
 	agent      string
 	mode       string
 	strategy   string
 	tokens     int
 	duration   time.Duration
 	lastUpdate time.Time
+	// autonomy is read on every render so time boxes and quota fallbacks
+	// show up without a refresh message.
+	autonomy *framework.AutonomyController
+	// heap and messages are sampled periodically (see pollMemory).
+	heap     uint64
+	messages int
 }
 
 func (s StatusBar) View(width int) string {
@@ -25,16 +36,26 @@ func (s StatusBar) View(width int) string {
 	if s.strategy != "" {
 		modeStr = fmt.Sprintf("%s (%s)", s.mode, s.strategy)
 	}
+	model := s.model
+	if s.modelState != "" {
+		model = fmt.Sprintf("%s (%s)", s.model, s.modelState)
+	}
 	left := fmt.Sprintf("📁 %s | 🤖 %s | 👤 %s | 🔧 %s",
 		truncate(s.workspace, 20),
-		s.model,
+		model,
 		s.agent,
 		modeStr,
 	)
+	if s.autonomy != nil {
+		left += " | 🛡️ " + s.autonomy.Status().Label()
+	}
 	right := fmt.Sprintf("🪙 %s | ⏱️  %s",
 		formatTokens(s.tokens),
 		formatDuration(s.duration),
 	)
+	if s.heap > 0 {
+		right = fmt.Sprintf("🧠 %s · %d msgs | %s", formatBytes(s.heap), s.messages, right)
+	}
 	padding := width - lipgloss.Width(left) - lipgloss.Width(right)
 	if padding < 0 {
 		padding = 0
@@ -3,6 +3,8 @@ package tui
 import (
 	"strings"
 	"time"
+
+	"github.com/lexcodex/relurpify/persistence"
 )
 
 // StreamTokenMsg represents a streamed token from the agent pipeline.
@@ -23,14 +25,17 @@ const (
 )
 
 // StreamCompleteMsg signals that streaming has finished.
+// Task carries the finished instruction for the session record.
 type StreamCompleteMsg struct {
 	Duration   time.Duration
 	TokensUsed int
+	Task       *persistence.SessionTask
 }
 
 // StreamErrorMsg wraps runtime failures for display.
 type StreamErrorMsg struct {
 	Error error
+	Task  *persistence.SessionTask
 }
 
 // MessageBuilder accumulates streaming state until completion.
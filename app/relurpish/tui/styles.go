@@ -1,6 +1,10 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/lexcodex/relurpify/framework"
+)
 
 var (
 	colorPrimary   = lipgloss.Color("39")
@@ -77,8 +81,30 @@ var (
 			Border(lipgloss.RoundedBorder()).
 			Padding(0, 1)
 
+	bannerStyle = lipgloss.NewStyle().
+			Bold(true).
+			Background(colorWarning).
+			Foreground(lipgloss.Color("16")).
+			Padding(0, 1)
+
 	welcomeStyle = lipgloss.NewStyle().
 			Foreground(colorDim).
 			Italic(true).
 			Align(lipgloss.Center)
 )
+
+// errorKindStyle colors a failure by kind: problems with the setup (model,
+// sandbox) in warning yellow, blocked or broken work in error red, and
+// interruptions dimmed.
+func errorKindStyle(kind framework.ErrorKind) lipgloss.Style {
+	switch kind {
+	case framework.ErrorKindLLMUnavailable, framework.ErrorKindSandbox:
+		return lipgloss.NewStyle().Foreground(colorWarning)
+	case framework.ErrorKindToolDenied:
+		return lipgloss.NewStyle().Bold(true).Foreground(colorError)
+	case framework.ErrorKindCancelled:
+		return dimStyle
+	default:
+		return lipgloss.NewStyle().Foreground(colorError)
+	}
+}
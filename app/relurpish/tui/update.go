@@ -15,7 +15,7 @@ import (
 
 // Init fulfills the Bubble Tea Model interface.
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.spinner.Tick, listenHITLEvents(m.hitlCh))
+	return tea.Batch(textinput.Blink, m.spinner.Tick, listenHITLEvents(m.hitlCh), listenWatchEvents(m.watchCh), listenModelEvents(m.modelCh), pollMemory())
 }
 
 // Update applies incoming Bubble Tea messages to mutate the Model state.
@@ -42,6 +42,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleFilePickerMode(msg)
 		case ModeHITL:
 			return m.handleHITLMode(msg)
+		case ModeConflict:
+			return m.handleConflictMode(msg)
 		}
 	case spinner.TickMsg:
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -52,6 +54,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleStreamComplete(msg)
 	case StreamErrorMsg:
 		return m.handleStreamError(msg)
+	case conflictRequestMsg:
+		return m.handleConflictRequest(msg)
 	case UpdateTaskMsg:
 		return m.handleUpdateTask(msg)
 	case hitlMsg:
@@ -60,6 +64,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleHITLResolved(msg)
 	case hitlEventMsg:
 		return m.handleHITLEvent(msg)
+	case watchEventMsg:
+		return m.handleWatchEvent(msg)
+	case modelEventMsg:
+		return m.handleModelEvent(msg)
+	case memoryTickMsg:
+		return m.handleMemoryTick()
 	}
 	return m, nil
 }
@@ -71,7 +81,11 @@ func (m Model) handleResize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 	statusBarHeight := 1
 	promptBarHeight := 1
-	feedHeight := max(1, msg.Height-statusBarHeight-promptBarHeight)
+	bannerHeight := 0
+	if m.sandboxBanner() != "" {
+		bannerHeight = 1
+	}
+	feedHeight := max(1, msg.Height-statusBarHeight-promptBarHeight-bannerHeight)
 
 	if !m.ready || m.feed == nil {
 		v := viewport.New(msg.Width, feedHeight)
@@ -81,6 +95,9 @@ func (m Model) handleResize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 		m.feed.Width = msg.Width
 		m.feed.Height = feedHeight
 	}
+	if m.diff != nil {
+		m.diff.resize(msg.Width, feedHeight)
+	}
 	m.input.Width = max(10, msg.Width-4)
 	return m, nil
 }
@@ -228,7 +245,11 @@ func (m Model) handleStreamComplete(msg StreamCompleteMsg) (tea.Model, tea.Cmd)
 	} else {
 		m.messages = append(m.messages, final)
 	}
-	m = m.refreshFeedContent()
+	m = m.capFeed().refreshFeedContent()
+	// A finished task reloaded the model if it had been unloaded.
+	if m.statusBar.modelState == "unloaded" {
+		m.statusBar.modelState = ""
+	}
 
 	m.session.TotalTokens += msg.TokensUsed
 	m.session.TotalDuration += msg.Duration
@@ -240,15 +261,43 @@ func (m Model) handleStreamComplete(msg StreamCompleteMsg) (tea.Model, tea.Cmd)
 	m.streaming = false
 	m.streamBuf = nil
 	m.streamCh = nil
+	if msg.Task != nil {
+		m.tasks = append(m.tasks, *msg.Task)
+	}
+	if m.runtime != nil && m.runtime.Autonomy != nil {
+		if suggestions := m.runtime.Autonomy.Suggestions(); len(suggestions) > 0 {
+			var b strings.Builder
+			b.WriteString("Suggested changes (not applied in suggest mode):\n")
+			for _, s := range suggestions {
+				b.WriteString(fmt.Sprintf("  • %s %v\n", s.Tool, s.Args))
+			}
+			m = m.addSystemMessage(strings.TrimRight(b.String(), "\n"))
+		}
+	}
+	m.saveSession()
 	return m, nil
 }
 
 // handleStreamError writes system level errors whenever streaming fails.
 func (m Model) handleStreamError(msg StreamErrorMsg) (tea.Model, tea.Cmd) {
+	m = m.closeConflict()
 	m.streaming = false
 	m.streamBuf = nil
 	m.streamCh = nil
-	return m.addSystemMessage(fmt.Sprintf("⚠️  agent error: %v", msg.Error)), nil
+	if msg.Task != nil {
+		m.tasks = append(m.tasks, *msg.Task)
+	}
+	kind := framework.ErrorKindOf(msg.Error)
+	switch {
+	case framework.IsTimeout(msg.Error):
+		m = m.addErrorMessage(kind, fmt.Sprintf("⏱️  task %s: %v", framework.ResultStatusTimedOut, msg.Error))
+	case kind != "":
+		m = m.addErrorMessage(kind, fmt.Sprintf("⚠️  %s: %v", kind.Describe(), msg.Error))
+	default:
+		m = m.addSystemMessage(fmt.Sprintf("⚠️  agent error: %v", msg.Error))
+	}
+	m.saveSession()
+	return m, nil
 }
 
 // UpdateTaskMsg allows external messages to update plan status in-place.
@@ -282,14 +331,20 @@ func (m Model) handleUpdateTask(msg UpdateTaskMsg) (tea.Model, tea.Cmd) {
 // handleCommand dispatchers -------------------------------------------------
 
 func (m Model) addSystemMessage(text string) Model {
+	return m.addErrorMessage("", text)
+}
+
+// addErrorMessage adds a system message colored by the error's kind.
+func (m Model) addErrorMessage(kind framework.ErrorKind, text string) Model {
 	sys := Message{
 		ID:        generateID(),
 		Timestamp: time.Now(),
 		Role:      RoleSystem,
 		Content:   MessageContent{Text: text},
+		Metadata:  MessageMetadata{ErrorKind: kind},
 	}
 	m.messages = append(m.messages, sys)
-	return m.refreshFeedContent()
+	return m.capFeed().refreshFeedContent()
 }
 
 func (m Model) approveCurrentChange() (tea.Model, tea.Cmd) {
@@ -360,11 +415,14 @@ func (m Model) handleHITLMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.hitlRequest == nil {
 		return m.exitHITL(), listenHITLEvents(m.hitlCh)
 	}
+	if m.diff != nil {
+		return m.handleDiffPaneKey(msg)
+	}
 	switch msg.String() {
 	case "y", "Y":
 		return m, approveHITLCmd(m.hitl, m.hitlRequest.ID)
 	case "n", "N", "esc":
-		return m, denyHITLCmd(m.hitl, m.hitlRequest.ID)
+		return m, denyHITLCmd(m.hitl, m.hitlRequest.ID, "denied in TUI")
 	default:
 		return m, nil
 	}
@@ -14,17 +14,40 @@ func (m Model) View() string {
 	}
 
 	feed := m.feed.View()
+	if m.diff != nil {
+		feed = m.diff.View()
+	}
 	prompt := m.renderPromptBar()
 	status := m.statusBar.View(m.width)
 
+	if banner := m.sandboxBanner(); banner != "" {
+		return lipgloss.JoinVertical(lipgloss.Left, banner, feed, prompt, status)
+	}
 	return lipgloss.JoinVertical(lipgloss.Left, feed, prompt, status)
 }
 
+// sandboxBanner stays pinned above the feed while the sandbox is degraded so
+// the user never forgets commands run on the host.
+func (m Model) sandboxBanner() string {
+	if m.runtime == nil {
+		return ""
+	}
+	status := m.runtime.SandboxStatus()
+	if !status.Degraded {
+		return ""
+	}
+	text := "⚠ Sandbox unavailable: " + status.Reason + " — commands run on the host and need approval"
+	return bannerStyle.Width(max(0, m.width)).MaxHeight(1).Render(text)
+}
+
 func (m Model) renderMessages() string {
 	if len(m.messages) == 0 {
 		return welcomeStyle.Render("Welcome! Type a message or use /help for commands.")
 	}
-	rendered := make([]string, 0, len(m.messages))
+	rendered := make([]string, 0, len(m.messages)+1)
+	if m.archived > 0 {
+		rendered = append(rendered, dimStyle.Render(fmt.Sprintf("… %d earlier messages archived to disk", m.archived)))
+	}
 	spinnerView := m.spinner.View()
 	for _, msg := range m.messages {
 		rendered = append(rendered, RenderMessage(msg, m.width, spinnerView))
@@ -47,15 +70,23 @@ func (m Model) renderPromptBar() string {
 	case ModeHITL:
 		prefix = "! "
 		hint = dimStyle.Render(" y approve | n deny | Esc cancel")
+		if m.diff != nil {
+			hint = dimStyle.Render(" y accept file | n reject | a accept all | tab next file | s side-by-side")
+		}
 		if m.hitlRequest != nil {
 			promptText = fmt.Sprintf("Approve %s: %s (%s)?", m.hitlRequest.ID, m.hitlRequest.Permission.Action, m.hitlRequest.Justification)
 		} else {
 			promptText = "Approve pending permission?"
 		}
+	case ModeConflict:
+		prefix = "? "
+		var conflictHint string
+		promptText, conflictHint = m.conflictPrompt()
+		hint = dimStyle.Render(conflictHint)
 	}
 
 	content := prefix
-	if m.mode == ModeHITL {
+	if m.mode == ModeHITL || m.mode == ModeConflict {
 		content += promptText
 	} else {
 		content += m.input.View()
@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestViewShowsDegradedSandboxBanner(t *testing.T) {
+	rt := &runtimesvc.Runtime{
+		Context: framework.NewContext(),
+		Registration: &framework.AgentRegistration{Sandbox: framework.SandboxStatus{
+			Runtime:  "host",
+			Degraded: true,
+			Reason:   "runsc not found",
+		}},
+	}
+	updated, _ := NewModel(rt).Update(tea.WindowSizeMsg{Width: 200, Height: 20})
+	m := updated.(Model)
+	m = m.addSystemMessage("hello")
+	view := m.View()
+	if !strings.Contains(view, "Sandbox unavailable: runsc not found") {
+		t.Fatalf("expected degraded banner, got:\n%s", view)
+	}
+
+	plain := &runtimesvc.Runtime{Context: framework.NewContext()}
+	updated, _ = NewModel(plain).Update(tea.WindowSizeMsg{Width: 200, Height: 20})
+	sandboxed := updated.(Model).addSystemMessage("hello").View()
+	if strings.Contains(sandboxed, "Sandbox unavailable") {
+		t.Fatal("banner should only show in degraded mode")
+	}
+	if got, want := strings.Count(view, "\n"), strings.Count(sandboxed, "\n"); got != want {
+		t.Fatalf("banner should shrink the feed, not grow the view: %d lines vs %d", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+)
+
+type watchEventMsg struct{ event runtimesvc.WatchEvent }
+
+func listenWatchEvents(ch <-chan runtimesvc.WatchEvent) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ev, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return watchEventMsg{event: ev}
+	}
+}
+
+// handleWatchEvent notes workspace changes and watch analysis results in the
+// feed as system messages.
+func (m Model) handleWatchEvent(msg watchEventMsg) (tea.Model, tea.Cmd) {
+	text := runtimesvc.FormatWatchEvent(msg.event)
+	if msg.event.Type == runtimesvc.WatchEventAnalysis {
+		if summary := summarizeResult(msg.event.Result); summary != "" {
+			text += "\n" + summary
+		}
+	}
+	return m.addSystemMessage(text), listenWatchEvents(m.watchCh)
+}
@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	runtimesvc "github.com/lexcodex/relurpify/app/relurpish/runtime"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/watch"
+)
+
+func TestWatchEventsAppearInFeed(t *testing.T) {
+	ch := make(chan runtimesvc.WatchEvent, 1)
+	m := Model{watchCh: ch, messages: []Message{}}
+
+	updatedAny, cmd := m.Update(watchEventMsg{event: runtimesvc.WatchEvent{
+		Type:    runtimesvc.WatchEventChanged,
+		Changes: []watch.Change{{Path: "main.go", Op: watch.OpWrite}},
+	}})
+	updated := updatedAny.(Model)
+	if cmd == nil {
+		t.Fatal("expected the model to keep listening for watch events")
+	}
+	if len(updated.messages) != 1 || !strings.Contains(updated.messages[0].Content.Text, "write main.go") {
+		t.Fatalf("unexpected feed: %+v", updated.messages)
+	}
+
+	ch <- runtimesvc.WatchEvent{
+		Type:    runtimesvc.WatchEventAnalysis,
+		TaskID:  "watch-1",
+		Changes: []watch.Change{{Path: "main.go", Op: watch.OpWrite}},
+		Result:  &framework.Result{NodeID: "analyze", Success: true},
+	}
+	updatedAny, _ = updated.Update(cmd())
+	updated = updatedAny.(Model)
+	if len(updated.messages) != 2 {
+		t.Fatalf("expected analysis message, got %+v", updated.messages)
+	}
+	text := updated.messages[1].Content.Text
+	if !strings.Contains(text, "analysis watch-1 of main.go finished") || !strings.Contains(text, "Task node: analyze") {
+		t.Fatalf("unexpected analysis message %q", text)
+	}
+	if updated.messages[1].Role != RoleSystem {
+		t.Fatalf("expected system message, got %s", updated.messages[1].Role)
+	}
+}
@@ -0,0 +1,62 @@
+// Command embedding shows the smallest useful program built on the public
+// Relurpify API: a ReAct agent with file tools, driven by a local Ollama model,
+// without the relurpish CLI, sandbox, or HTTP server.
+//
+//	go run ./examples/embedding -workspace . -model qwen2.5-coder:14b "Summarize README.md"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lexcodex/relurpify/agents"
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+func main() {
+	workspace := flag.String("workspace", ".", "Directory the file tools may access")
+	endpoint := flag.String("endpoint", "http://localhost:11434", "Ollama endpoint")
+	model := flag.String("model", "qwen2.5-coder:14b", "Ollama model name")
+	flag.Parse()
+	instruction := strings.Join(flag.Args(), " ")
+	if instruction == "" {
+		fmt.Fprintln(os.Stderr, "usage: embedding [flags] <instruction>")
+		os.Exit(2)
+	}
+
+	registry := framework.NewToolRegistry()
+	for _, tool := range tools.FileOperations(*workspace) {
+		if err := registry.Register(tool); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	agent := &agents.ReActAgent{
+		Model: llm.NewClient(*endpoint, *model),
+		Tools: registry,
+	}
+	if err := agent.Initialize(&framework.Config{Model: *model, MaxIterations: 6}); err != nil {
+		log.Fatal(err)
+	}
+
+	state := framework.NewContext()
+	result, err := agent.Execute(context.Background(), &framework.Task{
+		ID:          "embedding-example",
+		Type:        framework.TaskTypeAnalysis,
+		Instruction: instruction,
+	}, state)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if final, ok := state.Get("react.final_output"); ok {
+		fmt.Println(final)
+		return
+	}
+	fmt.Printf("%+v\n", result.Data)
+}
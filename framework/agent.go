@@ -24,6 +24,9 @@ const (
 	TaskTypePlanning         TaskType = "planning"
 	TaskTypeReview           TaskType = "review"
 	TaskTypeAnalysis         TaskType = "analysis"
+	TaskTypeExplain          TaskType = "explain"
+	TaskTypeQuestion         TaskType = "question"
+	TaskTypeTestGeneration   TaskType = "test_generation"
 )
 
 // Task encapsulates the information sent to an agent. The Context provides
@@ -35,28 +38,30 @@ type Task struct {
 	Instruction string
 	Context     map[string]any
 	Metadata    map[string]string
+	// Budget, when set, stops the task once it exceeds one of the limits.
+	Budget *TaskBudget `json:",omitempty"`
 }
 
 // Plan encapsulates planning information. Planner-like agents persist their
 // reasoning by filling this struct and storing it inside Context so subsequent
 // nodes can execute or verify each step.
 type Plan struct {
-	Goal         string
-	Steps        []PlanStep
-	Dependencies map[int][]int
+	Goal         string        `json:"goal"`
+	Steps        []PlanStep    `json:"steps"`
+	Dependencies map[int][]int `json:"dependencies,omitempty"`
 }
 
 // PlanStep describes a single actionable step. The Tool/Params fields point to
 // entries in the ToolRegistry so the planner can decide between filesystem,
 // git, execution, and LSP-powered capabilities at runtime.
 type PlanStep struct {
-	ID           int
-	Description  string
-	Tool         string
-	Params       map[string]interface{}
-	Expected     string
-	Verification string
-	Status       string
+	ID           int                    `json:"id"`
+	Description  string                 `json:"description"`
+	Tool         string                 `json:"tool,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Expected     string                 `json:"expected,omitempty"`
+	Verification string                 `json:"verification,omitempty"`
+	Status       string                 `json:"status,omitempty"`
 }
 
 // Config contains per-agent configuration knobs supplied by the server or CLI.
@@ -75,6 +80,63 @@ type Config struct {
 	DebugAgent         bool
 	AgentSpec          *AgentRuntimeSpec
 	Telemetry          Telemetry
+	// Models routes planning, coding, review, and summarization calls to
+	// different models. Nil means every step uses the agent's Model.
+	Models *ModelRouter
+	// ReviewModel enables cross-review: a second model, which may run on
+	// another endpoint, critiques the coding agent's changes, and rejected
+	// work runs again with the critique. Nil disables it.
+	ReviewModel LanguageModel
+	// ReviewRounds caps the runs of a cross-reviewed task. Zero means
+	// DefaultReviewRounds.
+	ReviewRounds int
+	// Timeouts bounds graph and node execution; see WithGraphTimeouts.
+	Timeouts GraphTimeouts
+	// Retry governs retries of transient LLM and tool failures inside
+	// agents. The zero value behaves like DefaultRetryPolicy.
+	Retry RetryPolicy
+	// ModelContext is the selected model's metadata, used to size context
+	// budgets. The zero value sizes for DefaultContextWindow.
+	ModelContext ModelContext
+	// ContextWindow overrides the sizing derived from ModelContext.
+	ContextWindow ContextWindowConfig
+	// HITL answers the human nodes of declarative graph agents.
+	HITL HITLProvider
+	// ToolOutput bounds the tool results agents pass back to the model. The
+	// zero value truncates at DefaultToolOutputMaxBytes.
+	ToolOutput ToolOutputPolicy
+	// StructuredOutput governs nodes that expect JSON from the model. The
+	// zero value constrains responses with their schema and re-asks up to
+	// DefaultStructuredAttempts times.
+	StructuredOutput StructuredOutputPolicy
+	// Summarizer condenses files and history when agents compress their
+	// context. Nil uses SimpleSummarizer.
+	Summarizer Summarizer
+}
+
+// DefaultReviewRounds caps the runs of a cross-reviewed task; see
+// Config.ReviewModel.
+const DefaultReviewRounds = 3
+
+// ContextSizing resolves the context budget for the configured model.
+func (c *Config) ContextSizing() ContextSizing {
+	if c == nil {
+		return SizeContextWindow(ModelContext{}, ContextWindowConfig{})
+	}
+	return SizeContextWindow(c.ModelContext, c.ContextWindow)
+}
+
+// ModelFor returns the model routed for role, or fallback when the config has
+// no router. Agents call it when wiring delegates so explicitly assigned
+// models still take effect.
+func (c *Config) ModelFor(role ModelRole, fallback LanguageModel) LanguageModel {
+	if c == nil || c.Models == nil {
+		return fallback
+	}
+	if model := c.Models.For(role); model != nil {
+		return model
+	}
+	return fallback
 }
 
 // Result captures the result of a graph or agent execution. Creating a shared
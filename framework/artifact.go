@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"context"
+	"time"
+)
+
+// ArtifactType classifies a non-code output a task produced.
+type ArtifactType string
+
+const (
+	ArtifactTypeReport  ArtifactType = "report"
+	ArtifactTypeDiagram ArtifactType = "diagram"
+	ArtifactTypeData    ArtifactType = "data"
+	ArtifactTypeLog     ArtifactType = "log"
+	ArtifactTypeOther   ArtifactType = "other"
+)
+
+// ParseArtifactType validates a type name, defaulting to ArtifactTypeOther
+// when empty.
+func ParseArtifactType(name string) (ArtifactType, bool) {
+	switch kind := ArtifactType(name); kind {
+	case "":
+		return ArtifactTypeOther, true
+	case ArtifactTypeReport, ArtifactTypeDiagram, ArtifactTypeData, ArtifactTypeLog, ArtifactTypeOther:
+		return kind, true
+	default:
+		return "", false
+	}
+}
+
+// Artifact describes a stored output. Content is addressed by Digest, so the
+// same bytes saved by several tasks are stored once.
+type Artifact struct {
+	ID        string       `json:"id"`
+	TaskID    string       `json:"task_id"`
+	Name      string       `json:"name"`
+	Type      ArtifactType `json:"type"`
+	MediaType string       `json:"media_type"`
+	// Digest is the hex SHA-256 of the content.
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Tool      string    `json:"tool,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArtifactStore keeps the reports, diagrams, and data files tasks produce
+// alongside the code they change.
+type ArtifactStore interface {
+	// PutArtifact stores content and fills in ID, Digest, Size, and
+	// CreatedAt. Saving the same name and content for a task again returns
+	// the existing artifact.
+	PutArtifact(ctx context.Context, artifact Artifact, content []byte) (Artifact, error)
+	// ListArtifacts returns the artifacts of taskID, or of every task when
+	// taskID is empty, oldest first.
+	ListArtifacts(ctx context.Context, taskID string) ([]Artifact, error)
+	// LoadArtifact returns an artifact and its content.
+	LoadArtifact(ctx context.Context, id string) (Artifact, []byte, bool, error)
+}
@@ -224,3 +224,120 @@ func TestIndexManagerSymbolFallback(t *testing.T) {
 		t.Fatalf("expected symbol nodes, got %d", len(nodes))
 	}
 }
+
+func TestIndexWorkspaceIsIncremental(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("sqlite init failed: %v", err)
+	}
+	defer store.Close()
+	manager := NewIndexManager(store, IndexConfig{WorkspacePath: tmpDir})
+	statuses := make(map[string]IndexFileStatus)
+	manager.SetProgressHandler(func(p IndexProgress) {
+		statuses[filepath.Base(p.Path)] = p.Status
+	})
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n\nfunc A() {}\n")
+	write("b.go", "package a\n\nfunc B() {}\n")
+	write("notes.txt", "unsupported")
+	if err := manager.IndexWorkspace(); err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+	if statuses["a.go"] != IndexStatusIndexed || statuses["notes.txt"] != IndexStatusSkipped {
+		t.Fatalf("unexpected first pass statuses: %v", statuses)
+	}
+
+	write("b.go", "package a\n\nfunc B2() {}\n")
+	if err := os.Remove(filepath.Join(tmpDir, "a.go")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := manager.IndexWorkspace(); err != nil {
+		t.Fatalf("reindex failed: %v", err)
+	}
+	if statuses["a.go"] != IndexStatusRemoved || statuses["b.go"] != IndexStatusIndexed {
+		t.Fatalf("unexpected second pass statuses: %v", statuses)
+	}
+	if err := manager.IndexWorkspace(); err != nil {
+		t.Fatalf("third pass failed: %v", err)
+	}
+	if statuses["b.go"] != IndexStatusUnchanged {
+		t.Fatalf("expected unchanged b.go, got %s", statuses["b.go"])
+	}
+}
+
+func TestEnsureIndexedRunsOnFirstUse(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n\nfunc Lazy() {}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("sqlite init failed: %v", err)
+	}
+	defer store.Close()
+	manager := NewIndexManager(store, IndexConfig{WorkspacePath: tmpDir})
+	if manager.Activated() {
+		t.Fatal("manager should not scan before first use")
+	}
+	if err := manager.EnsureIndexed(); err != nil {
+		t.Fatalf("ensure indexed: %v", err)
+	}
+	if !manager.Activated() {
+		t.Fatal("expected manager to be activated")
+	}
+	nodes, err := manager.QuerySymbol("Lazy")
+	if err != nil || len(nodes) == 0 {
+		t.Fatalf("cold index should be complete after EnsureIndexed: %v %v", nodes, err)
+	}
+	if err := manager.EnsureIndexed(); err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+}
+
+func TestRemoveFileDropsFilesAndDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pkg"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"a.go":     "package a\n\nfunc A() {}\n",
+		"pkg/b.go": "package pkg\n\nfunc B() {}\n",
+		"pkg/c.go": "package pkg\n\nfunc C() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("sqlite init failed: %v", err)
+	}
+	defer store.Close()
+	manager := NewIndexManager(store, IndexConfig{WorkspacePath: tmpDir})
+	if err := manager.IndexWorkspace(); err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+
+	if err := manager.RemoveFile(filepath.Join(tmpDir, "a.go")); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	if err := manager.RemoveFile(filepath.Join(tmpDir, "pkg")); err != nil {
+		t.Fatalf("remove dir: %v", err)
+	}
+	if err := manager.RemoveFile(filepath.Join(tmpDir, "never-indexed.go")); err != nil {
+		t.Fatalf("remove unknown path: %v", err)
+	}
+	stored, err := store.ListFiles("")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Fatalf("expected empty index, got %d files", len(stored))
+	}
+}
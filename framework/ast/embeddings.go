@@ -0,0 +1,463 @@
+package ast
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Embedder turns texts into vectors, one per text. llm.Client implements it
+// with Ollama's /api/embed endpoint.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingConfig configures an EmbeddingIndex.
+type EmbeddingConfig struct {
+	WorkspacePath string
+	// Model is recorded with every embedded file; switching models re-embeds
+	// the workspace on the next pass.
+	Model string
+	// ChunkLines and ChunkOverlap size the line windows that get embedded.
+	// They default to 40 and 8 lines; a negative overlap disables it.
+	ChunkLines   int
+	ChunkOverlap int
+	// BatchSize bounds how many chunks are sent to the embedder per call.
+	BatchSize      int
+	IgnorePatterns []string
+	// MaxFileBytes skips larger files, which are usually generated.
+	MaxFileBytes int64
+}
+
+const (
+	defaultChunkLines   = 40
+	defaultChunkOverlap = 8
+	defaultEmbedBatch   = 16
+	defaultMaxFileBytes = 512 * 1024
+)
+
+// EmbeddingChunk is an embedded span of a workspace file. Lines are 1-based
+// and inclusive.
+type EmbeddingChunk struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Content   string
+	Vector    []float32
+}
+
+// EmbeddingMatch is a chunk ranked by cosine similarity to a query.
+type EmbeddingMatch struct {
+	EmbeddingChunk
+	Score float64
+}
+
+// EmbeddingIndex keeps embeddings of chunked workspace files in the AST index
+// database. Passes are incremental: files whose content hash and model match
+// the stored entry are not re-embedded.
+type EmbeddingIndex struct {
+	store      *SQLiteStore
+	embedder   Embedder
+	config     EmbeddingConfig
+	detector   *LanguageDetector
+	mu         sync.Mutex
+	pathFilter func(path string, isDir bool) bool
+	progress   func(IndexProgress)
+}
+
+// NewEmbeddingIndex builds an index over store using embedder.
+func NewEmbeddingIndex(store *SQLiteStore, embedder Embedder, config EmbeddingConfig) *EmbeddingIndex {
+	if config.ChunkLines <= 0 {
+		config.ChunkLines = defaultChunkLines
+	}
+	if config.ChunkOverlap < 0 || config.ChunkOverlap >= config.ChunkLines {
+		config.ChunkOverlap = 0
+	} else if config.ChunkOverlap == 0 {
+		config.ChunkOverlap = min(defaultChunkOverlap, config.ChunkLines/2)
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultEmbedBatch
+	}
+	if config.MaxFileBytes <= 0 {
+		config.MaxFileBytes = defaultMaxFileBytes
+	}
+	return &EmbeddingIndex{
+		store:    store,
+		embedder: embedder,
+		config:   config,
+		detector: NewLanguageDetector(),
+	}
+}
+
+// SetPathFilter installs an optional filter that skips directories/files
+// while indexing and drops matches from search results.
+func (ei *EmbeddingIndex) SetPathFilter(filter func(path string, isDir bool) bool) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	ei.pathFilter = filter
+}
+
+// SetProgressHandler installs a callback invoked after each file is processed
+// by IndexWorkspace.
+func (ei *EmbeddingIndex) SetProgressHandler(handler func(IndexProgress)) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	ei.progress = handler
+}
+
+func (ei *EmbeddingIndex) filter() func(path string, isDir bool) bool {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	return ei.pathFilter
+}
+
+// IndexWorkspace embeds new and changed files and drops files that no longer
+// exist. Hidden directories and files of unknown languages are skipped.
+func (ei *EmbeddingIndex) IndexWorkspace(ctx context.Context) error {
+	root := ei.config.WorkspacePath
+	if root == "" {
+		root = "."
+	}
+	filter := ei.filter()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if matchesIgnore(path, ei.config.IgnorePatterns) || (filter != nil && !filter(path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchesIgnore(path, ei.config.IgnorePatterns) || ei.detector.Detect(path) == "unknown" {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tracker := &indexTracker{total: len(files)}
+	ei.mu.Lock()
+	tracker.handler = ei.progress
+	ei.mu.Unlock()
+	if err := ei.pruneDeleted(files, tracker); err != nil {
+		return err
+	}
+	var firstErr error
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		status, err := ei.IndexFile(ctx, file)
+		tracker.record(file, status, err)
+		if err != nil && status != IndexStatusSkipped && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return firstErr
+}
+
+// pruneDeleted drops embeddings of files that are gone from disk.
+func (ei *EmbeddingIndex) pruneDeleted(files []string, tracker *indexTracker) error {
+	stored, err := ei.store.embeddedPaths()
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+	for _, path := range stored {
+		if present[path] {
+			continue
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := ei.store.deleteEmbeddings(path); err == nil {
+			tracker.report(IndexProgress{Path: path, Status: IndexStatusRemoved})
+		}
+	}
+	return nil
+}
+
+// IndexFile re-embeds path when its content or the configured model changed
+// since the last pass.
+func (ei *EmbeddingIndex) IndexFile(ctx context.Context, path string) (IndexFileStatus, error) {
+	if filter := ei.filter(); filter != nil && !filter(path, false) {
+		return IndexStatusSkipped, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return IndexStatusFailed, err
+	}
+	if info.Size() > ei.config.MaxFileBytes {
+		return IndexStatusSkipped, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return IndexStatusFailed, err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return IndexStatusSkipped, nil
+	}
+	hash := HashContent(string(content))
+	storedHash, storedModel, err := ei.store.embeddingFile(path)
+	if err != nil {
+		return IndexStatusFailed, err
+	}
+	if storedHash == hash && storedModel == ei.config.Model {
+		return IndexStatusUnchanged, nil
+	}
+	chunks := ChunkLines(path, string(content), ei.config.ChunkLines, ei.config.ChunkOverlap)
+	for start := 0; start < len(chunks); start += ei.config.BatchSize {
+		end := min(start+ei.config.BatchSize, len(chunks))
+		texts := make([]string, end-start)
+		for i := range texts {
+			texts[i] = chunks[start+i].Content
+		}
+		vectors, err := ei.embedder.Embed(ctx, texts)
+		if err != nil {
+			return IndexStatusFailed, fmt.Errorf("embed: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return IndexStatusFailed, fmt.Errorf("embed: got %d vectors for %d chunks", len(vectors), len(texts))
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Vector = vector
+		}
+	}
+	if err := ei.store.replaceEmbeddings(path, hash, ei.config.Model, chunks); err != nil {
+		return IndexStatusFailed, err
+	}
+	return IndexStatusIndexed, nil
+}
+
+// RemoveFile drops the embeddings of a deleted path, or of every file below
+// it when the path was a directory.
+func (ei *EmbeddingIndex) RemoveFile(path string) error {
+	if err := ei.store.deleteEmbeddings(path); err != nil {
+		return err
+	}
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	return ei.store.deleteEmbeddingsUnder(prefix)
+}
+
+// Count reports how many chunks are embedded with the configured model. Zero
+// means the index has not been built.
+func (ei *EmbeddingIndex) Count() (int, error) {
+	return ei.store.countEmbeddings(ei.config.Model)
+}
+
+// Search embeds query and returns up to limit chunks ranked by cosine
+// similarity. It returns nothing when the index has not been built.
+func (ei *EmbeddingIndex) Search(ctx context.Context, query string, limit int) ([]EmbeddingMatch, error) {
+	return ei.SearchUnder(ctx, query, "", limit)
+}
+
+// SearchUnder is Search limited to chunks of files below dir; an empty dir
+// searches the whole index. The query is not embedded when no chunk
+// qualifies.
+func (ei *EmbeddingIndex) SearchUnder(ctx context.Context, query, dir string, limit int) ([]EmbeddingMatch, error) {
+	chunks, err := ei.store.embeddingChunks(ei.config.Model)
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	filter := ei.filter()
+	prefix := ""
+	if dir != "" {
+		prefix = strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	}
+	candidates := chunks[:0]
+	for _, chunk := range chunks {
+		if filter != nil && !filter(chunk.Path, false) {
+			continue
+		}
+		if prefix != "" && chunk.Path != dir && !strings.HasPrefix(chunk.Path, prefix) {
+			continue
+		}
+		candidates = append(candidates, chunk)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	vectors, err := ei.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, errors.New("embed query: no vector returned")
+	}
+	matches := make([]EmbeddingMatch, 0, len(candidates))
+	for _, chunk := range candidates {
+		matches = append(matches, EmbeddingMatch{EmbeddingChunk: chunk, Score: cosine(vectors[0], chunk.Vector)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// ChunkLines splits content into windows of size lines that overlap by
+// overlap lines. Blank windows are dropped.
+func ChunkLines(path, content string, size, overlap int) []EmbeddingChunk {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	step := size - overlap
+	if size <= 0 || step <= 0 {
+		step, size = len(lines), len(lines)
+	}
+	var chunks []EmbeddingChunk
+	for start := 0; start < len(lines); start += step {
+		end := min(start+size, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, EmbeddingChunk{Path: path, StartLine: start + 1, EndLine: end, Content: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// cosine returns the cosine similarity of a and b, or 0 when their
+// dimensions differ.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
+
+func (s *SQLiteStore) embeddingFile(path string) (hash, model string, err error) {
+	err = s.db.QueryRow(`SELECT content_hash, model FROM embedding_files WHERE path = ?`, path).Scan(&hash, &model)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	return hash, model, err
+}
+
+func (s *SQLiteStore) replaceEmbeddings(path, hash, model string, chunks []EmbeddingChunk) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM embedding_files WHERE path = ?`, path); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO embedding_files (path, content_hash, model, indexed_at) VALUES (?, ?, ?, ?)`,
+		path, hash, model, time.Now().UTC()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO embedding_chunks (path, start_line, end_line, content, vector) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, chunk := range chunks {
+		if _, err := stmt.Exec(path, chunk.StartLine, chunk.EndLine, chunk.Content, encodeVector(chunk.Vector)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) deleteEmbeddings(path string) error {
+	_, err := s.db.Exec(`DELETE FROM embedding_files WHERE path = ?`, path)
+	return err
+}
+
+func (s *SQLiteStore) deleteEmbeddingsUnder(prefix string) error {
+	_, err := s.db.Exec(`DELETE FROM embedding_files WHERE substr(path, 1, ?) = ?`, len(prefix), prefix)
+	return err
+}
+
+func (s *SQLiteStore) embeddedPaths() ([]string, error) {
+	rows, err := s.db.Query(`SELECT path FROM embedding_files`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+func (s *SQLiteStore) countEmbeddings(model string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM embedding_chunks c
+		JOIN embedding_files f ON f.path = c.path WHERE f.model = ?`, model).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStore) embeddingChunks(model string) ([]EmbeddingChunk, error) {
+	rows, err := s.db.Query(`SELECT c.path, c.start_line, c.end_line, c.content, c.vector FROM embedding_chunks c
+		JOIN embedding_files f ON f.path = c.path WHERE f.model = ?`, model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var chunks []EmbeddingChunk
+	for rows.Next() {
+		var chunk EmbeddingChunk
+		var vector []byte
+		if err := rows.Scan(&chunk.Path, &chunk.StartLine, &chunk.EndLine, &chunk.Content, &vector); err != nil {
+			return nil, err
+		}
+		chunk.Vector = decodeVector(vector)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
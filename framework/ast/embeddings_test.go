@@ -0,0 +1,114 @@
+package ast
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder maps text onto a fixed vocabulary so related chunks score
+// higher than unrelated ones.
+type wordEmbedder struct {
+	vocabulary []string
+	calls      int
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, len(e.vocabulary))
+		lower := strings.ToLower(text)
+		for j, word := range e.vocabulary {
+			vector[j] = float32(strings.Count(lower, word))
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func TestChunkLinesOverlaps(t *testing.T) {
+	content := "1\n2\n3\n4\n5\n6\n7\n"
+	chunks := ChunkLines("f.go", content, 4, 1)
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 4 || chunks[1].StartLine != 4 || chunks[1].EndLine != 7 {
+		t.Fatalf("unexpected chunk bounds: %+v", chunks)
+	}
+}
+
+func TestEmbeddingIndexIsIncrementalAndRanksChunks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStore(filepath.Join(dir, "index.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	ws := filepath.Join(dir, "ws")
+	if err := os.MkdirAll(filepath.Join(ws, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) string {
+		path := filepath.Join(ws, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	auth := write("auth.go", "package ws\n\n// login checks the password token\nfunc login() {}\n")
+	write("render.go", "package ws\n\n// render draws the table\nfunc render() {}\n")
+	write(".git/config", "token")
+
+	embedder := &wordEmbedder{vocabulary: []string{"password", "token", "table", "draws"}}
+	index := NewEmbeddingIndex(store, embedder, EmbeddingConfig{WorkspacePath: ws, Model: "test"})
+	counts := map[IndexFileStatus]int{}
+	index.SetProgressHandler(func(p IndexProgress) { counts[p.Status]++ })
+	if err := index.IndexWorkspace(context.Background()); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	if counts[IndexStatusIndexed] != 2 {
+		t.Fatalf("expected 2 indexed files, got %v", counts)
+	}
+
+	matches, err := index.Search(context.Background(), "where is the password checked", 1)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != auth || matches[0].StartLine != 1 {
+		t.Fatalf("expected auth.go first, got %+v", matches)
+	}
+
+	counts = map[IndexFileStatus]int{}
+	calls := embedder.calls
+	if err := index.IndexWorkspace(context.Background()); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if counts[IndexStatusUnchanged] != 2 || embedder.calls != calls {
+		t.Fatalf("expected an unchanged pass without embedding, got %v", counts)
+	}
+
+	if err := os.Remove(auth); err != nil {
+		t.Fatal(err)
+	}
+	counts = map[IndexFileStatus]int{}
+	if err := index.IndexWorkspace(context.Background()); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if counts[IndexStatusRemoved] != 1 {
+		t.Fatalf("expected the deleted file to be pruned, got %v", counts)
+	}
+	matches, _ = index.Search(context.Background(), "password", 5)
+	for _, match := range matches {
+		if match.Path == auth {
+			t.Fatalf("deleted file still searchable")
+		}
+	}
+
+	other := NewEmbeddingIndex(store, embedder, EmbeddingConfig{WorkspacePath: ws, Model: "other"})
+	if n, _ := other.Count(); n != 0 {
+		t.Fatalf("chunks of another model should not count, got %d", n)
+	}
+}
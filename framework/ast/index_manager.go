@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,6 +31,33 @@ type IndexManager struct {
 	config           IndexConfig
 	symbolProvider   DocumentSymbolProvider
 	pathFilter       func(path string, isDir bool) bool
+	progress         func(IndexProgress)
+	warmOnce         sync.Once
+	warmStarted      atomic.Bool
+	scanned          atomic.Bool
+	warmDone         chan struct{}
+	warmErr          error
+}
+
+// IndexFileStatus describes what happened to a file during an index pass.
+type IndexFileStatus string
+
+const (
+	IndexStatusIndexed   IndexFileStatus = "indexed"
+	IndexStatusUnchanged IndexFileStatus = "unchanged"
+	IndexStatusSkipped   IndexFileStatus = "skipped"
+	IndexStatusFailed    IndexFileStatus = "failed"
+	IndexStatusRemoved   IndexFileStatus = "removed"
+)
+
+// IndexProgress is reported once per file visited by IndexWorkspace.
+type IndexProgress struct {
+	Path   string
+	Status IndexFileStatus
+	Err    error
+	// Done counts files processed so far out of Total.
+	Done  int
+	Total int
 }
 
 // NewIndexManager builds a manager with default parsers.
@@ -40,6 +68,7 @@ func NewIndexManager(store IndexStore, config IndexConfig) *IndexManager {
 		languageDetector: NewLanguageDetector(),
 		indexing:         make(map[string]bool),
 		config:           config,
+		warmDone:         make(chan struct{}),
 	}
 	manager.registerDefaultParsers()
 	return manager
@@ -74,18 +103,32 @@ func (im *IndexManager) SetPathFilter(filter func(path string, isDir bool) bool)
 	im.pathFilter = filter
 }
 
-// IndexFile parses and stores AST for a file path.
+// SetProgressHandler installs a callback invoked after each file is processed
+// by IndexWorkspace. Handlers may be called from multiple workers.
+func (im *IndexManager) SetProgressHandler(handler func(IndexProgress)) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.progress = handler
+}
+
+// IndexFile parses and stores AST for a file path. Files whose content hash
+// matches the stored entry are left untouched.
 func (im *IndexManager) IndexFile(path string) error {
+	_, err := im.indexFile(path)
+	return err
+}
+
+func (im *IndexManager) indexFile(path string) (IndexFileStatus, error) {
 	im.mu.Lock()
 	filter := im.pathFilter
 	im.mu.Unlock()
 	if filter != nil && !filter(path, false) {
-		return nil
+		return IndexStatusSkipped, nil
 	}
 	im.mu.Lock()
 	if im.indexing[path] {
 		im.mu.Unlock()
-		return fmt.Errorf("index already running for %s", path)
+		return IndexStatusFailed, fmt.Errorf("index already running for %s", path)
 	}
 	im.indexing[path] = true
 	im.mu.Unlock()
@@ -99,33 +142,100 @@ func (im *IndexManager) IndexFile(path string) error {
 	category := im.languageDetector.DetectCategory(language)
 	parser, ok := im.parserRegistry.GetParser(language)
 
+	if !ok {
+		im.mu.Lock()
+		provider := im.symbolProvider
+		im.mu.Unlock()
+		if provider == nil {
+			return IndexStatusSkipped, fmt.Errorf("no parser for %s", language)
+		}
+	}
+
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return IndexStatusFailed, err
 	}
 	contentHash := HashContent(string(content))
 
 	if existing, err := im.store.GetFileByPath(path); err == nil && existing != nil {
 		if existing.ContentHash == contentHash {
-			return nil
+			return IndexStatusUnchanged, nil
 		}
 		if err := im.store.DeleteFile(existing.ID); err != nil {
-			return fmt.Errorf("delete previous index: %w", err)
+			return IndexStatusFailed, fmt.Errorf("delete previous index: %w", err)
 		}
 	}
 
 	if !ok {
-		return im.indexWithSymbols(path, string(content), language, category, contentHash)
+		if err := im.indexWithSymbols(path, string(content), language, category, contentHash); err != nil {
+			return IndexStatusFailed, err
+		}
+		return IndexStatusIndexed, nil
 	}
 
 	result, err := parser.Parse(string(content), path)
 	if err != nil {
 		if symErr := im.indexWithSymbols(path, string(content), language, category, contentHash); symErr == nil {
-			return nil
+			return IndexStatusIndexed, nil
 		}
+		return IndexStatusFailed, err
+	}
+	if err := im.persist(result, contentHash); err != nil {
+		return IndexStatusFailed, err
+	}
+	return IndexStatusIndexed, nil
+}
+
+// RemoveFile drops the index entry for a deleted path, or for every file
+// below it when the path was a directory. Paths that were never indexed are
+// ignored.
+func (im *IndexManager) RemoveFile(path string) error {
+	if existing, err := im.store.GetFileByPath(path); err == nil && existing != nil {
+		return im.store.DeleteFile(existing.ID)
+	}
+	stored, err := im.store.ListFiles("")
+	if err != nil {
 		return err
 	}
-	return im.persist(result, contentHash)
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	for _, meta := range stored {
+		if meta == nil || !strings.HasPrefix(meta.Path, prefix) {
+			continue
+		}
+		if err := im.store.DeleteFile(meta.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureIndexed starts the workspace scan on first use so sessions that never
+// query the AST do not pay for it. When the store already holds an index from
+// an earlier session the scan refreshes it in the background; a cold store
+// waits for the scan so the first query sees results. Nothing is scanned when
+// IndexWorkspace already ran.
+func (im *IndexManager) EnsureIndexed() error {
+	im.warmOnce.Do(func() {
+		im.warmStarted.Store(true)
+		if im.scanned.Load() {
+			close(im.warmDone)
+			return
+		}
+		go func() {
+			im.warmErr = im.IndexWorkspace()
+			close(im.warmDone)
+		}()
+	})
+	if stats, err := im.store.GetStats(); err == nil && stats.TotalFiles > 0 {
+		return nil
+	}
+	<-im.warmDone
+	return im.warmErr
+}
+
+// Activated reports whether the workspace has been scanned or a scan started.
+func (im *IndexManager) Activated() bool {
+	return im.warmStarted.Load() || im.scanned.Load()
 }
 
 // IndexWorkspace walks the workspace and indexes files.
@@ -160,14 +270,80 @@ func (im *IndexManager) IndexWorkspace() error {
 	if err != nil {
 		return err
 	}
+	tracker := &indexTracker{total: len(files)}
+	im.mu.Lock()
+	tracker.handler = im.progress
+	im.mu.Unlock()
+	im.pruneDeleted(files, tracker)
 	if im.config.ParallelWorkers > 1 {
-		return im.indexFilesParallel(files)
+		err = im.indexFilesParallel(files, tracker)
+	} else {
+		err = im.indexFilesSequential(files, tracker)
+	}
+	if err == nil {
+		im.scanned.Store(true)
+	}
+	return err
+}
+
+// pruneDeleted drops index entries for files that no longer exist on disk.
+func (im *IndexManager) pruneDeleted(files []string, tracker *indexTracker) {
+	stored, err := im.store.ListFiles("")
+	if err != nil {
+		return
+	}
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+	for _, meta := range stored {
+		if meta == nil || present[meta.Path] {
+			continue
+		}
+		if _, err := os.Stat(meta.Path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := im.store.DeleteFile(meta.ID); err == nil {
+			tracker.report(IndexProgress{Path: meta.Path, Status: IndexStatusRemoved})
+		}
+	}
+}
+
+// indexTracker serializes progress reporting across workers.
+type indexTracker struct {
+	mu      sync.Mutex
+	handler func(IndexProgress)
+	done    int
+	total   int
+}
+
+func (t *indexTracker) record(path string, status IndexFileStatus, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done++
+	if t.handler != nil {
+		t.handler(IndexProgress{Path: path, Status: status, Err: err, Done: t.done, Total: t.total})
+	}
+}
+
+func (t *indexTracker) report(progress IndexProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handler != nil {
+		progress.Done = t.done
+		progress.Total = t.total
+		t.handler(progress)
 	}
-	return im.indexFilesSequential(files)
 }
 
 func (im *IndexManager) shouldIgnore(path string) bool {
-	for _, pattern := range im.config.IgnorePatterns {
+	return matchesIgnore(path, im.config.IgnorePatterns)
+}
+
+// matchesIgnore reports whether path's base name matches a pattern or the
+// path contains one.
+func matchesIgnore(path string, patterns []string) bool {
+	for _, pattern := range patterns {
 		match, err := filepath.Match(pattern, filepath.Base(path))
 		if err == nil && match {
 			return true
@@ -179,31 +355,41 @@ func (im *IndexManager) shouldIgnore(path string) bool {
 	return false
 }
 
-func (im *IndexManager) indexFilesSequential(files []string) error {
+func (im *IndexManager) indexFilesSequential(files []string, tracker *indexTracker) error {
 	for _, file := range files {
-		if err := im.IndexFile(file); err != nil {
+		status, err := im.indexFile(file)
+		tracker.record(file, status, err)
+		if err != nil && status != IndexStatusSkipped {
 			log.Printf("AST index warning: %v", err)
 		}
 	}
 	return nil
 }
 
-func (im *IndexManager) indexFilesParallel(files []string) error {
+func (im *IndexManager) indexFilesParallel(files []string, tracker *indexTracker) error {
 	workerCount := im.config.ParallelWorkers
 	if workerCount <= 0 {
 		workerCount = 2
 	}
 	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var firstErr error
 	fileCh := make(chan string)
-	errCh := make(chan error, workerCount)
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for file := range fileCh {
-				if err := im.IndexFile(file); err != nil {
-					errCh <- fmt.Errorf("%s: %w", file, err)
+				status, err := im.indexFile(file)
+				tracker.record(file, status, err)
+				if err == nil || status == IndexStatusSkipped {
+					continue
+				}
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", file, err)
 				}
+				errMu.Unlock()
 			}
 		}()
 	}
@@ -212,11 +398,7 @@ func (im *IndexManager) indexFilesParallel(files []string) error {
 	}
 	close(fileCh)
 	wg.Wait()
-	close(errCh)
-	if len(errCh) > 0 {
-		return <-errCh
-	}
-	return nil
+	return firstErr
 }
 
 func (im *IndexManager) indexWithSymbols(path, content, language string, category Category, contentHash string) error {
@@ -89,6 +89,7 @@ func (gp *GoParser) Parse(content string, filePath string) (*ParseResult, error)
 		}
 		return true
 	})
+	result.Edges = gp.detachUnresolvedCalls(result)
 
 	result.Metadata = &FileMetadata{
 		ID:            fileID,
@@ -109,6 +110,46 @@ func (gp *GoParser) Parse(content string, filePath string) (*ParseResult, error)
 	return result, nil
 }
 
+// detachUnresolvedCalls keeps call edges whose target is declared in this
+// file and records the remaining callee names on the caller's "calls"
+// attribute. Edges must reference stored nodes, so calls into other files or
+// packages cannot be persisted as edges.
+func (gp *GoParser) detachUnresolvedCalls(result *ParseResult) []*Edge {
+	nodes := make(map[string]*Node, len(result.Nodes))
+	for _, node := range result.Nodes {
+		nodes[node.ID] = node
+	}
+	edges := make([]*Edge, 0, len(result.Edges))
+	for _, edge := range result.Edges {
+		if edge.Type != EdgeTypeCalls || nodes[edge.TargetID] != nil {
+			edges = append(edges, edge)
+			continue
+		}
+		source := nodes[edge.SourceID]
+		if source == nil {
+			continue
+		}
+		name := edge.TargetID[strings.LastIndex(edge.TargetID, ":")+1:]
+		if source.Attributes == nil {
+			source.Attributes = map[string]interface{}{}
+		}
+		calls, _ := source.Attributes["calls"].([]string)
+		if !containsCall(calls, name) {
+			source.Attributes["calls"] = append(calls, name)
+		}
+	}
+	return edges
+}
+
+func containsCall(calls []string, name string) bool {
+	for _, call := range calls {
+		if call == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (gp *GoParser) buildFunctionNode(decl *goast.FuncDecl, fileID, parentID string) *Node {
 	now := time.Now().UTC()
 	name := decl.Name.Name
@@ -83,6 +83,22 @@ func (s *SQLiteStore) initSchema() error {
 		FOREIGN KEY(source_id) REFERENCES nodes(id) ON DELETE CASCADE,
 		FOREIGN KEY(target_id) REFERENCES nodes(id) ON DELETE CASCADE
 	);
+	CREATE TABLE IF NOT EXISTS embedding_files (
+		path TEXT PRIMARY KEY,
+		content_hash TEXT NOT NULL,
+		model TEXT NOT NULL,
+		indexed_at TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS embedding_chunks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		path TEXT NOT NULL,
+		start_line INTEGER,
+		end_line INTEGER,
+		content TEXT,
+		vector BLOB,
+		FOREIGN KEY(path) REFERENCES embedding_files(path) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_embedding_chunks_path ON embedding_chunks(path);
 	`
 	_, err := s.db.Exec(schema)
 	return err
@@ -88,32 +88,35 @@ func (l *InMemoryAuditLogger) Query(_ context.Context, filter AuditQuery) ([]Aud
 	defer l.mu.RUnlock()
 	var result []AuditRecord
 	for _, record := range l.buffer {
-		if filter.AgentID != "" && record.AgentID != filter.AgentID {
-			continue
+		if filter.Matches(record) {
+			result = append(result, record)
 		}
-		if filter.Type != "" && record.Type != filter.Type {
-			continue
-		}
-		if filter.Action != "" && record.Action != filter.Action {
-			continue
-		}
-		if !filter.TimeStart.IsZero() && record.Timestamp.Before(filter.TimeStart) {
-			continue
-		}
-		if !filter.TimeEnd.IsZero() && record.Timestamp.After(filter.TimeEnd) {
-			continue
-		}
-		if filter.Permission != "" && record.Permission != filter.Permission {
-			continue
-		}
-		if filter.Result != "" && record.Result != filter.Result {
-			continue
-		}
-		result = append(result, record)
 	}
 	return result, nil
 }
 
+// Matches reports whether record passes every set field of the query. Time
+// bounds are inclusive.
+func (q AuditQuery) Matches(record AuditRecord) bool {
+	switch {
+	case q.AgentID != "" && record.AgentID != q.AgentID:
+		return false
+	case q.Type != "" && record.Type != q.Type:
+		return false
+	case q.Action != "" && record.Action != q.Action:
+		return false
+	case !q.TimeStart.IsZero() && record.Timestamp.Before(q.TimeStart):
+		return false
+	case !q.TimeEnd.IsZero() && record.Timestamp.After(q.TimeEnd):
+		return false
+	case q.Permission != "" && record.Permission != q.Permission:
+		return false
+	case q.Result != "" && record.Result != q.Result:
+		return false
+	}
+	return true
+}
+
 // AuditStore exposes a read API for servers or dashboards.
 type AuditStore struct {
 	logger AuditLogger
@@ -0,0 +1,447 @@
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditSink receives batches of audit records for export. Sinks run on their
+// own goroutine behind a BatchingAuditLogger, so WriteAudit may be slow
+// without delaying permission checks.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, records []AuditRecord) error
+}
+
+// AuditSinkOptions tunes batching for one sink.
+type AuditSinkOptions struct {
+	// Primary sinks get at-least-once delivery: failed batches are retried
+	// with backoff and records are never dropped. Secondary sinks drop
+	// records when their queue is full or a batch fails.
+	Primary bool
+	// BatchSize caps records per WriteAudit call. Defaults to 64.
+	BatchSize int
+	// FlushInterval bounds how long a partial batch waits. Defaults to 1s.
+	FlushInterval time.Duration
+	// QueueSize bounds buffered records for secondary sinks; records arriving
+	// at a full queue are dropped. Defaults to 4096.
+	QueueSize int
+	// RetryBackoff is the initial delay after a failed primary write; it
+	// doubles up to 30s. Defaults to 250ms.
+	RetryBackoff time.Duration
+}
+
+func (o AuditSinkOptions) withDefaults() AuditSinkOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 64
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 4096
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 250 * time.Millisecond
+	}
+	return o
+}
+
+// AuditSinkRegistration attaches a named sink to an agent's audit log via
+// RuntimeConfig.AuditSinks.
+type AuditSinkRegistration struct {
+	Name    string
+	Sink    AuditSink
+	Options AuditSinkOptions
+}
+
+// AuditSinkStats reports delivery counters for one sink.
+type AuditSinkStats struct {
+	Name      string `json:"name"`
+	Primary   bool   `json:"primary"`
+	Delivered int    `json:"delivered"`
+	Dropped   int    `json:"dropped"`
+	Failures  int    `json:"failures"`
+	Pending   int    `json:"pending"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// BatchingAuditLogger fans audit records out to registered sinks. Log writes
+// synchronously to the wrapped store (which still answers Query) and only
+// enqueues for sinks, so a slow syslog or HTTP collector never blocks a
+// permission decision.
+type BatchingAuditLogger struct {
+	store AuditLogger
+
+	mu     sync.RWMutex
+	sinks  []*auditSinkWorker
+	closed bool
+}
+
+// NewBatchingAuditLogger wraps store, which defaults to an in-memory logger.
+func NewBatchingAuditLogger(store AuditLogger) *BatchingAuditLogger {
+	if store == nil {
+		store = NewInMemoryAuditLogger(0)
+	}
+	return &BatchingAuditLogger{store: store}
+}
+
+// AddSink registers a sink and starts its delivery goroutine.
+func (l *BatchingAuditLogger) AddSink(name string, sink AuditSink, opts AuditSinkOptions) error {
+	if sink == nil {
+		return errors.New("audit sink required")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return errors.New("audit logger closed")
+	}
+	worker := newAuditSinkWorker(name, sink, opts.withDefaults())
+	l.sinks = append(l.sinks, worker)
+	go worker.run()
+	return nil
+}
+
+// Log records to the store and enqueues for every sink.
+func (l *BatchingAuditLogger) Log(ctx context.Context, record AuditRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+	err := l.store.Log(ctx, record)
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, worker := range l.sinks {
+		worker.enqueue(record)
+	}
+	return err
+}
+
+// Query delegates to the wrapped store.
+func (l *BatchingAuditLogger) Query(ctx context.Context, filter AuditQuery) ([]AuditRecord, error) {
+	return l.store.Query(ctx, filter)
+}
+
+// Flush waits until every sink has drained its queue or ctx ends. Secondary
+// sinks count as drained once their records were delivered or dropped.
+func (l *BatchingAuditLogger) Flush(ctx context.Context) error {
+	l.mu.RLock()
+	sinks := append([]*auditSinkWorker(nil), l.sinks...)
+	l.mu.RUnlock()
+	for _, worker := range sinks {
+		if err := worker.flush(ctx); err != nil {
+			return fmt.Errorf("flush audit sink %s: %w", worker.name, err)
+		}
+	}
+	return nil
+}
+
+// Close flushes and stops all sinks. Records still pending for a primary sink
+// when ctx ends are reported in the returned error.
+func (l *BatchingAuditLogger) Close(ctx context.Context) error {
+	flushErr := l.Flush(ctx)
+	l.mu.Lock()
+	l.closed = true
+	sinks := l.sinks
+	l.sinks = nil
+	l.mu.Unlock()
+	for _, worker := range sinks {
+		worker.stop()
+	}
+	return flushErr
+}
+
+// Stats returns delivery counters for every sink.
+func (l *BatchingAuditLogger) Stats() []AuditSinkStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	stats := make([]AuditSinkStats, 0, len(l.sinks))
+	for _, worker := range l.sinks {
+		stats = append(stats, worker.stats())
+	}
+	return stats
+}
+
+// auditSinkWorker owns the queue for one sink. Records stay in pending until
+// WriteAudit succeeds (primary) or the batch is abandoned (secondary).
+type auditSinkWorker struct {
+	name string
+	sink AuditSink
+	opts AuditSinkOptions
+
+	mu       sync.Mutex
+	pending  []AuditRecord
+	inFlight int
+	counters AuditSinkStats
+	drained  *sync.Cond
+
+	wake chan struct{}
+	done chan struct{}
+	quit chan struct{}
+}
+
+func newAuditSinkWorker(name string, sink AuditSink, opts AuditSinkOptions) *auditSinkWorker {
+	w := &auditSinkWorker{
+		name:     name,
+		sink:     sink,
+		opts:     opts,
+		counters: AuditSinkStats{Name: name, Primary: opts.Primary},
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+	w.drained = sync.NewCond(&w.mu)
+	return w
+}
+
+// enqueue never blocks on the sink. Primary queues grow without bound to
+// preserve at-least-once delivery; full secondary queues drop the new record.
+func (w *auditSinkWorker) enqueue(record AuditRecord) {
+	w.mu.Lock()
+	if !w.opts.Primary && len(w.pending) >= w.opts.QueueSize {
+		w.counters.Dropped++
+		w.mu.Unlock()
+		w.signal()
+		return
+	}
+	w.pending = append(w.pending, record)
+	full := len(w.pending) >= w.opts.BatchSize
+	w.mu.Unlock()
+	if full {
+		w.signal()
+	}
+}
+
+func (w *auditSinkWorker) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *auditSinkWorker) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	backoff := w.opts.RetryBackoff
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+		for {
+			ok, more := w.deliver()
+			if !ok {
+				// Primary write failed; wait before retrying the same batch.
+				select {
+				case <-w.quit:
+					return
+				case <-time.After(backoff):
+				}
+				if backoff *= 2; backoff > 30*time.Second {
+					backoff = 30 * time.Second
+				}
+				continue
+			}
+			backoff = w.opts.RetryBackoff
+			if !more {
+				break
+			}
+		}
+	}
+}
+
+// deliver writes one batch. ok is false when a primary write failed and must
+// be retried; more reports whether records remain queued.
+func (w *auditSinkWorker) deliver() (ok bool, more bool) {
+	w.mu.Lock()
+	n := len(w.pending)
+	if n == 0 {
+		w.drained.Broadcast()
+		w.mu.Unlock()
+		return true, false
+	}
+	if n > w.opts.BatchSize {
+		n = w.opts.BatchSize
+	}
+	batch := append([]AuditRecord(nil), w.pending[:n]...)
+	w.inFlight = n
+	w.mu.Unlock()
+
+	err := w.sink.WriteAudit(context.Background(), batch)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight = 0
+	if err != nil {
+		w.counters.Failures++
+		w.counters.LastError = err.Error()
+		if w.opts.Primary {
+			return false, true
+		}
+		w.counters.Dropped += n
+	} else {
+		w.counters.Delivered += n
+	}
+	w.pending = w.pending[n:]
+	if len(w.pending) == 0 {
+		w.drained.Broadcast()
+	}
+	return true, len(w.pending) > 0
+}
+
+func (w *auditSinkWorker) flush(ctx context.Context) error {
+	w.signal()
+	stop := context.AfterFunc(ctx, func() {
+		w.mu.Lock()
+		w.drained.Broadcast()
+		w.mu.Unlock()
+	})
+	defer stop()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.pending) > 0 || w.inFlight > 0 {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%d records pending: %w", len(w.pending), err)
+		}
+		w.drained.Wait()
+	}
+	return nil
+}
+
+func (w *auditSinkWorker) stop() {
+	close(w.quit)
+	<-w.done
+}
+
+func (w *auditSinkWorker) stats() AuditSinkStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.counters
+	stats.Pending = len(w.pending)
+	return stats
+}
+
+// FileAuditSink appends records as newline-delimited JSON.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink opens (or creates) path for appending.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: f}, nil
+}
+
+// WriteAudit appends the batch and syncs so delivered records survive a crash.
+func (s *FileAuditSink) WriteAudit(_ context.Context, records []AuditRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close releases the file handle.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// AuditFileEntry is a record read back from a FileAuditSink. Line is its
+// 1-based position in the file, which stays stable because the sink only
+// appends, so it doubles as the record's ID.
+type AuditFileEntry struct {
+	Line int `json:"line"`
+	AuditRecord
+}
+
+// ReadAuditFile returns the records in a FileAuditSink file that match
+// filter, oldest first. Blank lines are skipped; a malformed line fails the
+// read with its line number.
+func ReadAuditFile(path string, filter AuditQuery) ([]AuditFileEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []AuditFileEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if filter.Matches(record) {
+			entries = append(entries, AuditFileEntry{Line: line, AuditRecord: record})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// HTTPAuditSink POSTs each batch as a JSON array to a collector endpoint.
+type HTTPAuditSink struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// WriteAudit sends the batch; any non-2xx response counts as a failure.
+func (s *HTTPAuditSink) WriteAudit(ctx context.Context, records []AuditRecord) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu       sync.Mutex
+	records  []AuditRecord
+	failures int
+	block    chan struct{}
+}
+
+func (s *recordingSink) WriteAudit(_ context.Context, records []AuditRecord) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("collector unavailable")
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+func TestBatchingAuditLoggerRetriesPrimary(t *testing.T) {
+	logger := NewBatchingAuditLogger(nil)
+	sink := &recordingSink{failures: 2}
+	require.NoError(t, logger.AddSink("primary", sink, AuditSinkOptions{
+		Primary:       true,
+		BatchSize:     2,
+		FlushInterval: 5 * time.Millisecond,
+		RetryBackoff:  time.Millisecond,
+	}))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, logger.Log(context.Background(), AuditRecord{AgentID: "a", Action: "read"}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, logger.Close(ctx))
+	assert.Equal(t, 5, sink.count())
+
+	records, err := logger.Query(context.Background(), AuditQuery{AgentID: "a"})
+	require.NoError(t, err)
+	assert.Len(t, records, 5)
+}
+
+func TestBatchingAuditLoggerNeverBlocksOnSlowSink(t *testing.T) {
+	logger := NewBatchingAuditLogger(nil)
+	slow := &recordingSink{block: make(chan struct{})}
+	require.NoError(t, logger.AddSink("slow", slow, AuditSinkOptions{
+		BatchSize:     1,
+		QueueSize:     3,
+		FlushInterval: time.Millisecond,
+	}))
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			_ = logger.Log(context.Background(), AuditRecord{Action: "exec"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Log blocked on a slow sink")
+	}
+	stats := logger.Stats()
+	require.Len(t, stats, 1)
+	assert.Greater(t, stats[0].Dropped, 0)
+	assert.LessOrEqual(t, stats[0].Pending, 3)
+
+	close(slow.block)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, logger.Close(ctx))
+	assert.Equal(t, 50-stats[0].Dropped, slow.count())
+}
+
+func TestReadAuditFileFiltersSinkOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileAuditSink(path)
+	require.NoError(t, err)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, sink.WriteAudit(context.Background(), []AuditRecord{
+		{Timestamp: base, AgentID: "coder", Type: "filesystem", Action: "fs_read", Result: "granted"},
+		{Timestamp: base.Add(time.Hour), AgentID: "coder", Type: "executable", Action: "exec", Result: "denied"},
+		{Timestamp: base.Add(2 * time.Hour), AgentID: "planner", Type: "executable", Action: "exec", Result: "denied"},
+	}))
+	require.NoError(t, sink.Close())
+
+	all, err := ReadAuditFile(path, AuditQuery{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, 3, all[2].Line)
+
+	denied, err := ReadAuditFile(path, AuditQuery{Result: "denied", TimeEnd: base.Add(90 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, denied, 1)
+	assert.Equal(t, 2, denied[0].Line)
+	assert.Equal(t, "coder", denied[0].AgentID)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"agent_id\":\"a\"}\n\nnot json\n"), 0o644))
+	_, err = ReadAuditFile(path, AuditQuery{})
+	assert.ErrorContains(t, err, ":3:")
+}
@@ -0,0 +1,52 @@
+//go:build !windows && !plan9
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogAuditSink writes each record as a JSON line to the local syslog
+// daemon. Denied decisions are logged at warning level, everything else at
+// info.
+type SyslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogAuditSink connects to the local syslog daemon under tag.
+func NewSyslogAuditSink(tag string) (*SyslogAuditSink, error) {
+	if tag == "" {
+		tag = "relurpify"
+	}
+	writer, err := syslog.New(syslog.LOG_AUTH|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogAuditSink{writer: writer}, nil
+}
+
+// WriteAudit sends the batch one record at a time.
+func (s *SyslogAuditSink) WriteAudit(_ context.Context, records []AuditRecord) error {
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if record.Result == "denied" {
+			err = s.writer.Warning(string(data))
+		} else {
+			err = s.writer.Info(string(data))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close disconnects from syslog.
+func (s *SyslogAuditSink) Close() error {
+	return s.writer.Close()
+}
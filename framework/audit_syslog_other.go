@@ -0,0 +1,24 @@
+//go:build windows || plan9
+
+package framework
+
+import (
+	"context"
+	"errors"
+)
+
+// SyslogAuditSink is unavailable on this platform.
+type SyslogAuditSink struct{}
+
+// NewSyslogAuditSink reports that syslog is unsupported.
+func NewSyslogAuditSink(tag string) (*SyslogAuditSink, error) {
+	return nil, errors.New("syslog audit sink not supported on this platform")
+}
+
+// WriteAudit always fails.
+func (s *SyslogAuditSink) WriteAudit(context.Context, []AuditRecord) error {
+	return errors.New("syslog audit sink not supported on this platform")
+}
+
+// Close is a no-op.
+func (s *SyslogAuditSink) Close() error { return nil }
@@ -0,0 +1,320 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AutonomyLevel coordinates dry-run, approval, and quota settings for a
+// session so users pick one knob instead of three.
+type AutonomyLevel string
+
+const (
+	// AutonomySuggest runs read-only tools but turns every mutating call
+	// (file writes, command execution) into a recorded suggestion.
+	AutonomySuggest AutonomyLevel = "suggest"
+	// AutonomyApprove executes mutating calls only after HITL approval.
+	AutonomyApprove AutonomyLevel = "approve"
+	// AutonomyAutonomous executes without prompts until the quota or time
+	// box runs out, then falls back to AutonomyApprove.
+	AutonomyAutonomous AutonomyLevel = "autonomous"
+)
+
+// AutonomyLevels lists the selectable levels, most restrictive first.
+func AutonomyLevels() []AutonomyLevel {
+	return []AutonomyLevel{AutonomySuggest, AutonomyApprove, AutonomyAutonomous}
+}
+
+// ParseAutonomyLevel accepts level names plus the long-form aliases
+// suggest-only, auto-edit, and full.
+func ParseAutonomyLevel(s string) (AutonomyLevel, error) {
+	switch s {
+	case "suggest", "suggest-only":
+		return AutonomySuggest, nil
+	case "approve", "auto-edit", "auto-edit-with-approval":
+		return AutonomyApprove, nil
+	case "autonomous", "full", "fully-autonomous":
+		return AutonomyAutonomous, nil
+	}
+	return "", fmt.Errorf("unknown autonomy level %q (suggest, approve, autonomous)", s)
+}
+
+// AutonomyQuota bounds a fully autonomous window. Zero fields are unlimited.
+type AutonomyQuota struct {
+	MaxToolCalls int `json:"max_tool_calls,omitempty" yaml:"max_tool_calls,omitempty"`
+	MaxMutations int `json:"max_mutations,omitempty" yaml:"max_mutations,omitempty"`
+	MaxTokens    int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+}
+
+// AutonomySuggestion is a mutating call that suggest mode did not execute.
+type AutonomySuggestion struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Tool      string                 `json:"tool"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// AutonomyStatus is a snapshot for status bars and the API.
+type AutonomyStatus struct {
+	Level       AutonomyLevel `json:"level"`
+	Selected    AutonomyLevel `json:"selected"`
+	ExpiresAt   *time.Time    `json:"expires_at,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	Quota       AutonomyQuota `json:"quota"`
+	ToolCalls   int           `json:"tool_calls"`
+	Mutations   int           `json:"mutations"`
+	Tokens      int           `json:"tokens"`
+	Suggestions int           `json:"suggestions"`
+}
+
+// Label renders the status compactly, e.g. "autonomous 12m 4/50 calls".
+func (s AutonomyStatus) Label() string {
+	label := string(s.Level)
+	if s.Level != s.Selected {
+		label = fmt.Sprintf("%s (was %s)", s.Level, s.Selected)
+	}
+	if s.ExpiresAt != nil {
+		if remaining := time.Until(*s.ExpiresAt); remaining > 0 {
+			label += " " + remaining.Round(time.Minute).String()
+		}
+	}
+	if s.Level == AutonomyAutonomous && s.Quota.MaxToolCalls > 0 {
+		label += fmt.Sprintf(" %d/%d calls", s.ToolCalls, s.Quota.MaxToolCalls)
+	}
+	return label
+}
+
+// AutonomyController holds the session's autonomy level and enforces it for
+// every tool in a ToolRegistry (see ToolRegistry.UseAutonomy). Levels can be
+// time-boxed; when the box or quota runs out the session drops to
+// AutonomyApprove rather than failing the task.
+type AutonomyController struct {
+	mu          sync.Mutex
+	selected    AutonomyLevel
+	level       AutonomyLevel
+	reason      string
+	quota       AutonomyQuota
+	since       time.Time
+	expiresAt   time.Time
+	toolCalls   int
+	mutations   int
+	suggestions []AutonomySuggestion
+	usage       *UsageTracker
+	clock       func() time.Time
+}
+
+// NewAutonomyController starts at level. usage, when set, backs the token
+// quota.
+func NewAutonomyController(level AutonomyLevel, quota AutonomyQuota, usage *UsageTracker) *AutonomyController {
+	if level == "" {
+		level = AutonomyApprove
+	}
+	c := &AutonomyController{quota: quota, usage: usage, clock: time.Now}
+	c.reset(level, 0)
+	return c
+}
+
+// Set switches level. A positive duration time-boxes it. Counters restart.
+func (c *AutonomyController) Set(level AutonomyLevel, duration time.Duration) error {
+	if _, err := ParseAutonomyLevel(string(level)); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset(level, duration)
+	return nil
+}
+
+func (c *AutonomyController) reset(level AutonomyLevel, duration time.Duration) {
+	now := c.clock()
+	c.selected = level
+	c.level = level
+	c.reason = ""
+	c.since = now
+	c.expiresAt = time.Time{}
+	if duration > 0 {
+		c.expiresAt = now.Add(duration)
+	}
+	c.toolCalls = 0
+	c.mutations = 0
+}
+
+// SetQuota replaces the autonomous quota without resetting counters.
+func (c *AutonomyController) SetQuota(quota AutonomyQuota) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quota = quota
+}
+
+// Level returns the effective level after applying the time box and quota.
+func (c *AutonomyController) Level() AutonomyLevel {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh()
+	return c.level
+}
+
+// Status returns a snapshot of the controller.
+func (c *AutonomyController) Status() AutonomyStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh()
+	status := AutonomyStatus{
+		Level:       c.level,
+		Selected:    c.selected,
+		Reason:      c.reason,
+		Quota:       c.quota,
+		ToolCalls:   c.toolCalls,
+		Mutations:   c.mutations,
+		Tokens:      c.tokensLocked(),
+		Suggestions: len(c.suggestions),
+	}
+	if !c.expiresAt.IsZero() && c.level == c.selected {
+		expires := c.expiresAt
+		status.ExpiresAt = &expires
+	}
+	return status
+}
+
+// Suggestions returns and clears the calls suggest mode skipped.
+func (c *AutonomyController) Suggestions() []AutonomySuggestion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.suggestions
+	c.suggestions = nil
+	return out
+}
+
+// refresh demotes the level when the time box or quota is exhausted. Callers
+// hold c.mu.
+func (c *AutonomyController) refresh() {
+	if c.level != c.selected {
+		return
+	}
+	demote := func(reason string) {
+		if c.level == AutonomySuggest || c.level == AutonomyApprove {
+			// Restrictive levels stay put when their box ends.
+			c.expiresAt = time.Time{}
+			return
+		}
+		c.level = AutonomyApprove
+		c.reason = reason
+	}
+	if !c.expiresAt.IsZero() && !c.clock().Before(c.expiresAt) {
+		demote("time box expired")
+		return
+	}
+	if c.level != AutonomyAutonomous {
+		return
+	}
+	switch {
+	case c.quota.MaxToolCalls > 0 && c.toolCalls >= c.quota.MaxToolCalls:
+		demote("tool call quota exhausted")
+	case c.quota.MaxMutations > 0 && c.mutations >= c.quota.MaxMutations:
+		demote("mutation quota exhausted")
+	case c.quota.MaxTokens > 0 && c.tokensLocked() >= c.quota.MaxTokens:
+		demote("token quota exhausted")
+	}
+}
+
+func (c *AutonomyController) tokensLocked() int {
+	if c.usage == nil {
+		return 0
+	}
+	return c.usage.Summary(UsageFilter{Since: c.since}).Total.TotalTokens
+}
+
+// admit applies the effective level to one tool call. It returns a non-nil
+// result when the call must not run (suggest mode), and reports whether the
+// call needs approval first.
+func (c *AutonomyController) admit(tool Tool, args map[string]interface{}) (*ToolResult, bool) {
+	mutating := IsMutatingTool(tool)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh()
+	switch c.level {
+	case AutonomySuggest:
+		if !mutating {
+			return nil, false
+		}
+		c.suggestions = append(c.suggestions, AutonomySuggestion{
+			Timestamp: c.clock().UTC(),
+			Tool:      tool.Name(),
+			Args:      args,
+		})
+		return &ToolResult{
+			Success: true,
+			Data: map[string]interface{}{
+				"dry_run": true,
+				"message": fmt.Sprintf("suggest-only mode: %s was not executed; describe the change for the user instead", tool.Name()),
+			},
+		}, false
+	case AutonomyAutonomous:
+		c.toolCalls++
+		if mutating {
+			c.mutations++
+		}
+		return nil, false
+	default:
+		return nil, mutating
+	}
+}
+
+// approvalResource identifies one call for the HITL prompt and grant cache so
+// approving one edit does not approve the next.
+func approvalResource(tool Tool, args map[string]interface{}) string {
+	encoded, err := json.Marshal(args)
+	if err != nil || len(args) == 0 {
+		return tool.Name()
+	}
+	resource := tool.Name() + " " + string(encoded)
+	if len(resource) > 240 {
+		resource = resource[:240] + "…"
+	}
+	return resource
+}
+
+// IsMutatingTool reports whether a tool can change the workspace or run
+// commands, judged from its declared permissions.
+func IsMutatingTool(tool Tool) bool {
+	perms := tool.Permissions().Permissions
+	if perms == nil {
+		return false
+	}
+	if len(perms.Executables) > 0 {
+		return true
+	}
+	for _, fs := range perms.FileSystem {
+		if fs.Action == FileSystemWrite || fs.Action == FileSystemExecute {
+			return true
+		}
+	}
+	return false
+}
+
+// gateAutonomy enforces the registry's autonomy controller for one call.
+func (t *instrumentedTool) gateAutonomy(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	if t.autonomy == nil {
+		return nil, nil
+	}
+	result, needsApproval := t.autonomy.admit(t.Tool, args)
+	if result != nil || !needsApproval {
+		return result, nil
+	}
+	if t.manager == nil {
+		return nil, fmt.Errorf("tool %s blocked: approval required but permission manager missing", t.Tool.Name())
+	}
+	err := t.manager.RequireApproval(ctx, t.agentID, PermissionDescriptor{
+		Type:         PermissionTypeHITL,
+		Action:       fmt.Sprintf("autonomy:%s", t.Tool.Name()),
+		Resource:     approvalResource(t.Tool, args),
+		Metadata:     t.approvalMetadata(ctx, args),
+		RequiresHITL: true,
+	}, "autonomy level requires approval for changes", GrantScopeOneTime, RiskLevelMedium, 0)
+	return nil, err
+}
@@ -0,0 +1,117 @@
+package framework
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func autonomyTestRegistry(t *testing.T, controller *AutonomyController, hitl HITLProvider) (*ToolRegistry, *stubHITLProvider) {
+	t.Helper()
+	perms := &PermissionSet{FileSystem: []FileSystemPermission{
+		{Action: FileSystemRead, Path: "/workspace/**"},
+		{Action: FileSystemWrite, Path: "/workspace/**"},
+	}}
+	manager, err := NewPermissionManager("/workspace", perms, nil, hitl)
+	require.NoError(t, err)
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(stubTool{name: "read", perms: &PermissionSet{
+		FileSystem: []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}},
+	}}))
+	require.NoError(t, registry.Register(stubTool{name: "write", perms: &PermissionSet{
+		FileSystem: []FileSystemPermission{{Action: FileSystemWrite, Path: "/workspace/**"}},
+	}}))
+	registry.UsePermissionManager("agent", manager)
+	registry.UseAutonomy(controller)
+	stub, _ := hitl.(*stubHITLProvider)
+	return registry, stub
+}
+
+func executeTool(t *testing.T, registry *ToolRegistry, name string, args map[string]interface{}) (*ToolResult, error) {
+	t.Helper()
+	tool, ok := registry.Get(name)
+	require.True(t, ok)
+	return tool.Execute(context.Background(), NewContext(), args)
+}
+
+func TestAutonomySuggestRecordsMutations(t *testing.T) {
+	controller := NewAutonomyController(AutonomySuggest, AutonomyQuota{}, nil)
+	registry, _ := autonomyTestRegistry(t, controller, &stubHITLProvider{})
+
+	res, err := executeTool(t, registry, "read", nil)
+	require.NoError(t, err)
+	assert.Nil(t, res.Data, "read-only tools run normally")
+
+	res, err = executeTool(t, registry, "write", map[string]interface{}{"path": "a.go"})
+	require.NoError(t, err)
+	assert.Equal(t, true, res.Data["dry_run"])
+
+	suggestions := controller.Suggestions()
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "write", suggestions[0].Tool)
+	assert.Empty(t, controller.Suggestions(), "suggestions are drained")
+}
+
+func TestAutonomyApproveRequiresHITLForMutations(t *testing.T) {
+	controller := NewAutonomyController(AutonomyApprove, AutonomyQuota{}, nil)
+	registry, hitl := autonomyTestRegistry(t, controller, &stubHITLProvider{})
+
+	_, err := executeTool(t, registry, "read", nil)
+	require.NoError(t, err)
+	assert.Empty(t, hitl.requests)
+
+	res, err := executeTool(t, registry, "write", map[string]interface{}{"path": "a.go"})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	require.Len(t, hitl.requests, 1)
+	assert.Equal(t, "autonomy:write", hitl.requests[0].Permission.Action)
+	assert.Contains(t, hitl.requests[0].Permission.Resource, "a.go")
+}
+
+func TestAutonomyQuotaFallsBackToApprove(t *testing.T) {
+	controller := NewAutonomyController(AutonomyAutonomous, AutonomyQuota{MaxMutations: 1}, nil)
+	registry, hitl := autonomyTestRegistry(t, controller, &stubHITLProvider{})
+
+	_, err := executeTool(t, registry, "write", nil)
+	require.NoError(t, err)
+	assert.Empty(t, hitl.requests)
+
+	status := controller.Status()
+	assert.Equal(t, AutonomyApprove, status.Level)
+	assert.Equal(t, AutonomyAutonomous, status.Selected)
+	assert.Equal(t, "mutation quota exhausted", status.Reason)
+
+	_, err = executeTool(t, registry, "write", nil)
+	require.NoError(t, err)
+	assert.Len(t, hitl.requests, 1)
+}
+
+func TestAutonomyTimeBoxExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	controller := NewAutonomyController(AutonomyApprove, AutonomyQuota{}, nil)
+	controller.clock = func() time.Time { return now }
+	require.NoError(t, controller.Set(AutonomyAutonomous, 10*time.Minute))
+	assert.Equal(t, AutonomyAutonomous, controller.Level())
+	require.NotNil(t, controller.Status().ExpiresAt)
+
+	now = now.Add(11 * time.Minute)
+	assert.Equal(t, AutonomyApprove, controller.Level())
+	assert.Equal(t, "time box expired", controller.Status().Reason)
+
+	assert.Error(t, controller.Set("reckless", 0))
+}
+
+func TestParseAutonomyLevelAliases(t *testing.T) {
+	for input, want := range map[string]AutonomyLevel{
+		"suggest-only":            AutonomySuggest,
+		"auto-edit-with-approval": AutonomyApprove,
+		"full":                    AutonomyAutonomous,
+	} {
+		got, err := ParseAutonomyLevel(input)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
@@ -0,0 +1,184 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ChangePreviewer is implemented by tools that write files, so approval
+// prompts can show the change before the tool makes it.
+type ChangePreviewer interface {
+	// PreviewChange returns the unified diff the call would apply, with
+	// git-style headers per file. It must not modify the workspace.
+	PreviewChange(ctx context.Context, args map[string]interface{}) (string, error)
+}
+
+// ApprovalDiffKey is the PermissionDescriptor.Metadata key holding the
+// previewed diff of a tool call awaiting approval.
+const ApprovalDiffKey = "diff"
+
+// diffContext is the number of unchanged lines kept around each hunk.
+const diffContext = 3
+
+// maxDiffCells bounds the line-matching table; larger files are diffed as a
+// whole-file replacement.
+const maxDiffCells = 4 << 20
+
+// approvalMetadata previews the call for approval prompts when the wrapped
+// tool supports it. Preview failures only cost the prompt its diff.
+func (t *instrumentedTool) approvalMetadata(ctx context.Context, args map[string]interface{}) map[string]string {
+	previewer, ok := t.Tool.(ChangePreviewer)
+	if !ok {
+		return nil
+	}
+	diff, err := previewer.PreviewChange(ctx, args)
+	if err != nil || diff == "" {
+		return nil
+	}
+	if t.redactor != nil {
+		diff = t.redactor.Redact(diff)
+	}
+	return map[string]string{ApprovalDiffKey: diff}
+}
+
+// UnifiedDiff renders the change from before to after as a git-style unified
+// diff. An empty oldPath marks a created file and an empty newPath a deleted
+// one. Identical contents yield an empty string.
+func UnifiedDiff(oldPath, newPath, before, after string) string {
+	if before == after {
+		return ""
+	}
+	a := splitDiffLines(before)
+	b := splitDiffLines(after)
+	name := newPath
+	if name == "" {
+		name = oldPath
+	}
+	from, to := "a/"+oldPath, "b/"+newPath
+	if oldPath == "" {
+		from = "/dev/null"
+	}
+	if newPath == "" {
+		to = "/dev/null"
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "diff --git a/%s b/%s\n--- %s\n+++ %s\n", name, name, from, to)
+	ops := diffLines(a, b)
+	for start := 0; start < len(ops); {
+		// Find the next change and grow the hunk while changes are within
+		// twice the context of each other.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		lo := max(start, first-diffContext)
+		hi := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				hi = i + 1
+				continue
+			}
+			if i-hi >= 2*diffContext {
+				break
+			}
+		}
+		hi = min(len(ops), hi+diffContext)
+		writeHunk(&out, ops[lo:hi])
+		start = hi
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind         byte // ' ', '-', or '+'
+	text         string
+	aLine, bLine int
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines matches a and b with a longest common subsequence and returns
+// the edit script. Line numbers are 1-based.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	var ops []diffOp
+	if n*m > maxDiffCells {
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', text: line, aLine: i + 1, bLine: 1})
+		}
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', text: line, aLine: n + 1, bLine: j + 1})
+		}
+		return ops
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], aLine: i + 1, bLine: j + 1})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', text: a[i], aLine: i + 1, bLine: j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', text: b[j], aLine: i + 1, bLine: j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	var aCount, bCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	aStart, bStart := ops[0].aLine, ops[0].bLine
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
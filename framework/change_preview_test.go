@@ -0,0 +1,42 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffGroupsHunks(t *testing.T) {
+	var before, after strings.Builder
+	for i := 1; i <= 20; i++ {
+		line := "line " + string(rune('a'+i-1)) + "\n"
+		before.WriteString(line)
+		switch i {
+		case 2:
+			after.WriteString("changed b\n")
+		case 18:
+		default:
+			after.WriteString(line)
+		}
+	}
+	got := UnifiedDiff("f.txt", "f.txt", before.String(), after.String())
+	want := "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n" +
+		"@@ -1,5 +1,5 @@\n line a\n-line b\n+changed b\n line c\n line d\n line e\n" +
+		"@@ -15,6 +15,5 @@\n line o\n line p\n line q\n-line r\n line s\n line t\n"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffCreatesAndDeletes(t *testing.T) {
+	created := UnifiedDiff("", "new.go", "", "package x")
+	if !strings.Contains(created, "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,1 @@\n+package x\n\\ No newline at end of file\n") {
+		t.Fatalf("unexpected create diff:\n%s", created)
+	}
+	deleted := UnifiedDiff("old.go", "", "a\nb\n", "")
+	if !strings.Contains(deleted, "--- a/old.go\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n") {
+		t.Fatalf("unexpected delete diff:\n%s", deleted)
+	}
+	if UnifiedDiff("same", "same", "x\n", "x\n") != "" {
+		t.Fatal("identical contents should not produce a diff")
+	}
+}
@@ -0,0 +1,364 @@
+package framework
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CodeStage names one step of a CodePipeline.
+type CodeStage string
+
+const (
+	// CodeStageFences unwraps code the model put in a markdown fence,
+	// dropping the prose around it.
+	CodeStageFences CodeStage = "fences"
+	// CodeStageLanguage prefers the fence whose language tag matches the
+	// file being written when a response holds several.
+	CodeStageLanguage CodeStage = "language"
+	// CodeStageTrailingProse drops explanation paragraphs the model appended
+	// after the closing fence, for when the fences stage is off.
+	CodeStageTrailingProse CodeStage = "trailing_prose"
+	// CodeStageEOL converts line endings to \n and ends the file with
+	// exactly one newline.
+	CodeStageEOL CodeStage = "eol"
+)
+
+// DefaultCodeStages is the pipeline file writes use unless configured
+// otherwise.
+var DefaultCodeStages = []CodeStage{CodeStageFences, CodeStageLanguage, CodeStageTrailingProse, CodeStageEOL}
+
+// ParseCodeStages validates stage names from configuration.
+func ParseCodeStages(names []string) ([]CodeStage, error) {
+	stages := make([]CodeStage, 0, len(names))
+	for _, name := range names {
+		stage := CodeStage(strings.ToLower(strings.TrimSpace(name)))
+		switch stage {
+		case CodeStageFences, CodeStageLanguage, CodeStageTrailingProse, CodeStageEOL:
+			stages = append(stages, stage)
+		default:
+			return nil, fmt.Errorf("unknown code extraction stage %q", name)
+		}
+	}
+	return stages, nil
+}
+
+// CodePipeline turns the content a model supplies for a file into the code to
+// write. Models wrap code in markdown fences and explanations inconsistently;
+// the pipeline strips what is not meant for the file. A nil pipeline leaves
+// content unchanged.
+type CodePipeline struct {
+	stages map[CodeStage]bool
+}
+
+// NewCodePipeline runs stages in their fixed order: fences (refined by
+// language), trailing prose, then line endings.
+func NewCodePipeline(stages ...CodeStage) *CodePipeline {
+	p := &CodePipeline{stages: make(map[CodeStage]bool, len(stages))}
+	for _, stage := range stages {
+		p.stages[stage] = true
+	}
+	return p
+}
+
+// DefaultCodePipeline runs every stage.
+func DefaultCodePipeline() *CodePipeline {
+	return NewCodePipeline(DefaultCodeStages...)
+}
+
+// Process returns the code in content for the file at path.
+func (p *CodePipeline) Process(content, path string) string {
+	if p == nil || len(p.stages) == 0 {
+		return content
+	}
+	lang := CodeLanguage(path)
+	unwrapped := false
+	if p.stages[CodeStageFences] {
+		if code, ok := p.unwrapFence(content, lang); ok {
+			content, unwrapped = code, true
+		}
+	}
+	// Unwrapped content is the fence's code, with the prose around it gone.
+	if p.stages[CodeStageTrailingProse] && !unwrapped {
+		content = stripTrailingProse(content, lang)
+	}
+	if p.stages[CodeStageEOL] {
+		content = normalizeEOL(content)
+	}
+	return content
+}
+
+// unwrapFence picks the fence holding the file's code. Content is only
+// unwrapped when it arrived fenced (see arrivedFenced), so code that merely
+// contains fences, such as a docstring with an example, is left alone. The
+// language stage then prefers the largest fence tagged with the file's
+// language. Markdown files are unwrapped only when one fence spans the whole
+// content.
+func (p *CodePipeline) unwrapFence(content, lang string) (string, bool) {
+	if !arrivedFenced(content) {
+		return "", false
+	}
+	fences := ExtractCodeFences(content)
+	if len(fences) == 0 {
+		return "", false
+	}
+	if lang == "markdown" {
+		if len(fences) == 1 && isFence(firstLine(content)) && strings.TrimSpace(content[fences[0].end:]) == "" {
+			return fences[0].Content, true
+		}
+		return "", false
+	}
+	if p.stages[CodeStageLanguage] && lang != "" {
+		var best *CodeFence
+		for i := range fences {
+			if fenceLanguage(fences[i].Language) != lang {
+				continue
+			}
+			if best == nil || len(fences[i].Content) > len(best.Content) {
+				best = &fences[i]
+			}
+		}
+		if best != nil {
+			return best.Content, true
+		}
+	}
+	return fences[0].Content, true
+}
+
+// arrivedFenced reports whether content opens with a fence, or with a line
+// introducing one ("Here is the file:") directly followed by the fence.
+func arrivedFenced(content string) bool {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+			if len(lines) == 2 {
+				break
+			}
+		}
+	}
+	switch {
+	case len(lines) == 0:
+		return false
+	case isFence(lines[0]):
+		return true
+	default:
+		return len(lines) == 2 && strings.HasSuffix(lines[0], ":") && isFence(lines[1])
+	}
+}
+
+// CodeFence is one fenced block of a markdown response.
+type CodeFence struct {
+	// Language is the first word of the info string, lower-cased.
+	Language string
+	Content  string
+	// end is the byte offset just past the closing fence line.
+	end int
+}
+
+// ExtractCodeFences returns the fenced code blocks of text in order. A fence
+// the response never closes (output cut off by the token limit) runs to the
+// end of text.
+func ExtractCodeFences(text string) []CodeFence {
+	var fences []CodeFence
+	var (
+		open    bool
+		marker  string
+		current CodeFence
+		body    []string
+	)
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		offset += len(line)
+		trimmed := strings.TrimRight(line, "\r\n")
+		indented := strings.TrimLeft(trimmed, " ")
+		if len(trimmed)-len(indented) > 3 {
+			if open {
+				body = append(body, trimmed)
+			}
+			continue
+		}
+		if !open {
+			run := fenceRun(indented)
+			if run == "" {
+				continue
+			}
+			info := strings.TrimSpace(indented[len(run):])
+			if run[0] == '`' && strings.Contains(info, "`") {
+				continue
+			}
+			open, marker, body = true, run, nil
+			current = CodeFence{}
+			if fields := strings.Fields(info); len(fields) > 0 {
+				current.Language = strings.ToLower(fields[0])
+			}
+			continue
+		}
+		if run := fenceRun(indented); run != "" && run[0] == marker[0] && len(run) >= len(marker) && strings.TrimSpace(indented[len(run):]) == "" {
+			current.Content = fenceBody(body)
+			current.end = offset
+			fences = append(fences, current)
+			open = false
+			continue
+		}
+		body = append(body, trimmed)
+	}
+	if open {
+		current.Content = fenceBody(body)
+		current.end = len(text)
+		fences = append(fences, current)
+	}
+	return fences
+}
+
+// fenceRun returns the run of three or more backticks or tildes line starts
+// with, or "".
+func fenceRun(line string) string {
+	if len(line) < 3 || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+func isFence(line string) bool {
+	return fenceRun(strings.TrimLeft(line, " ")) != ""
+}
+
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// fenceBody joins the lines of a fence, ending with a newline.
+func fenceBody(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// codeLanguages maps file extensions to the language names CodeLanguage
+// reports.
+var codeLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "javascript", ".mjs": "javascript",
+	".ts": "typescript", ".tsx": "typescript", ".rs": "rust", ".java": "java", ".kt": "kotlin",
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hpp": "cpp",
+	".cs": "csharp", ".rb": "ruby", ".php": "php", ".swift": "swift", ".sh": "shell", ".bash": "shell",
+	".sql": "sql", ".yaml": "yaml", ".yml": "yaml", ".json": "json", ".toml": "toml",
+	".html": "html", ".css": "css", ".md": "markdown", ".markdown": "markdown",
+}
+
+// fenceAliases maps fence tags that differ from the CodeLanguage names.
+var fenceAliases = map[string]string{
+	"golang": "go", "py": "python", "python3": "python", "js": "javascript", "jsx": "javascript",
+	"node": "javascript", "ts": "typescript", "tsx": "typescript", "rs": "rust", "kt": "kotlin",
+	"c++": "cpp", "cc": "cpp", "cs": "csharp", "c#": "csharp", "rb": "ruby", "sh": "shell",
+	"bash": "shell", "zsh": "shell", "yml": "yaml", "md": "markdown",
+}
+
+// CodeLanguage names the language of path from its extension, "" when
+// unknown.
+func CodeLanguage(path string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(path))]
+}
+
+func fenceLanguage(tag string) string {
+	if lang, ok := fenceAliases[tag]; ok {
+		return lang
+	}
+	return tag
+}
+
+// proseOpeners are first words of the explanations models append after
+// code.
+var proseOpeners = map[string]bool{
+	"this": true, "the": true, "these": true, "here": true, "here's": true, "note": true,
+	"explanation": true, "i've": true, "i'm": true, "now": true, "changes": true, "key": true,
+	"summary": true, "let": true,
+}
+
+// stripTrailingProse removes the paragraphs after the last closing fence of
+// content that arrived fenced, while they read as prose. Raw content is never
+// touched, and neither are markdown, data formats, or unknown file types,
+// whose trailing text may be their content.
+func stripTrailingProse(content, lang string) string {
+	switch lang {
+	case "", "markdown", "yaml", "json", "toml":
+		return content
+	}
+	if !arrivedFenced(content) {
+		return content
+	}
+	fences := ExtractCodeFences(content)
+	if len(fences) == 0 {
+		return content
+	}
+	code := content[:fences[len(fences)-1].end]
+	lines := strings.Split(strings.TrimRight(content[len(code):], "\r\n\t "), "\n")
+	for {
+		start := len(lines)
+		for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+			start--
+		}
+		if start == len(lines) || !isProse(lines[start]) {
+			break
+		}
+		lines = lines[:start]
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+	}
+	if len(lines) == 0 {
+		return code
+	}
+	out := code + strings.Join(lines, "\n")
+	if strings.HasSuffix(content, "\n") {
+		out += "\n"
+	}
+	return out
+}
+
+// isProse reports whether a paragraph's first line opens an explanation:
+// it starts with a proseOpeners word and holds no assignment, block, or
+// statement punctuation. Comments and "key:" lines are never prose; a
+// label reads as prose only in bold ("**Note:**").
+func isProse(line string) bool {
+	line = strings.TrimSpace(line)
+	bold := strings.HasPrefix(line, "**")
+	line = strings.TrimLeft(line, "*> ")
+	if strings.ContainsAny(line, "={};") {
+		return false
+	}
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return false
+	}
+	word := strings.TrimRight(fields[0], "*")
+	if strings.HasSuffix(word, ":") && !bold {
+		return false
+	}
+	return proseOpeners[strings.TrimRight(word, ":,")]
+}
+
+// normalizeEOL converts \r\n and \r to \n and leaves exactly one trailing
+// newline on non-empty content.
+func normalizeEOL(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	content = strings.TrimRight(content, "\n")
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return content + "\n"
+}
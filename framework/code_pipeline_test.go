@@ -0,0 +1,129 @@
+package framework
+
+import "testing"
+
+func TestCodePipelineProcess(t *testing.T) {
+	for name, tc := range map[string]struct {
+		stages  []CodeStage
+		path    string
+		content string
+		want    string
+	}{
+		"fenced with prose": {
+			path:    "main.go",
+			content: "Here is the updated file:\n\n```go\npackage main\r\n\nfunc main() {}\n```\n\nThis adds an entry point.",
+			want:    "package main\n\nfunc main() {}\n",
+		},
+		"language picks the matching fence": {
+			path:    "app.py",
+			content: "Run it with:\n```bash\npython app.py\n```\nThe code:\n```python\nprint('hi')\n```\n",
+			want:    "print('hi')\n",
+		},
+		"fences inside code stay": {
+			path:    "doc.go",
+			content: "package doc\n\nconst usage = `\n```\nrun\n```\n`\n",
+			want:    "package doc\n\nconst usage = `\n```\nrun\n```\n`\n",
+		},
+		"unclosed fence": {
+			path:    "main.go",
+			content: "```go\npackage main\n",
+			want:    "package main\n",
+		},
+		"raw code keeps trailing text": {
+			path:    "main.go",
+			content: "package main\n\nvar x = 1\n\n**Explanation:** x starts at one.\n",
+			want:    "package main\n\nvar x = 1\n\n**Explanation:** x starts at one.\n",
+		},
+		"docstring with a fenced example stays": {
+			path:    "mod.py",
+			content: "\"\"\"Runs things.\n\nUsage:\n\n```python\nimport mod\nmod.run()\n```\n\"\"\"\n\n\ndef run():\n    pass\n",
+			want:    "\"\"\"Runs things.\n\nUsage:\n\n```python\nimport mod\nmod.run()\n```\n\"\"\"\n\n\ndef run():\n    pass\n",
+		},
+		"block opener is not an introduction": {
+			path:    "app.py",
+			content: "class Usage:\n    text = \"\"\"\n```python\nrun()\n```\n\"\"\"\n",
+			want:    "class Usage:\n    text = \"\"\"\n```python\nrun()\n```\n\"\"\"\n",
+		},
+		"yaml keys that read like prose stay": {
+			path:    "c.yaml",
+			content: "name: app\nversion: 1\n\nsummary: short\nkey: abc\n",
+			want:    "name: app\nversion: 1\n\nsummary: short\nkey: abc\n",
+		},
+		"trailing shell comment stays": {
+			path:    "s.sh",
+			content: "echo hi\n\n# Note: run as root\n",
+			want:    "echo hi\n\n# Note: run as root\n",
+		},
+		"trailing python comment paragraph stays": {
+			path:    "defaults.py",
+			content: "TIMEOUT = 30\n\n# The values above are defaults\n# and may be overridden.\n",
+			want:    "TIMEOUT = 30\n\n# The values above are defaults\n# and may be overridden.\n",
+		},
+		"prose after the closing fence": {
+			stages:  []CodeStage{CodeStageTrailingProse},
+			path:    "main.go",
+			content: "```go\npackage main\n```\n\n**Explanation:** adds a package.\n\nNote that it is empty.\n",
+			want:    "```go\npackage main\n```\n",
+		},
+		"comments after the closing fence stay": {
+			stages:  []CodeStage{CodeStageTrailingProse},
+			path:    "q.sql",
+			content: "```sql\nSELECT 1;\n```\n-- The values above are defaults\n",
+			want:    "```sql\nSELECT 1;\n```\n-- The values above are defaults\n",
+		},
+		"code that starts like prose stays": {
+			path:    "main.py",
+			content: "import os\n\nthe = os.getcwd()\n",
+			want:    "import os\n\nthe = os.getcwd()\n",
+		},
+		"markdown keeps its fences and prose": {
+			path:    "README.md",
+			content: "# Title\n\n```go\nx := 1\n```\n\nThis is the end.\n",
+			want:    "# Title\n\n```go\nx := 1\n```\n\nThis is the end.\n",
+		},
+		"markdown wrapped whole": {
+			path:    "README.md",
+			content: "```markdown\n# Title\n```\n",
+			want:    "# Title\n",
+		},
+		"stages off": {
+			stages:  []CodeStage{},
+			path:    "main.go",
+			content: "```go\npackage main\n```",
+			want:    "```go\npackage main\n```",
+		},
+		"eol only": {
+			stages:  []CodeStage{CodeStageEOL},
+			path:    "main.go",
+			content: "```go\r\npackage main\r\n```\r\n\r\n",
+			want:    "```go\npackage main\n```\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pipeline := DefaultCodePipeline()
+			if tc.stages != nil {
+				pipeline = NewCodePipeline(tc.stages...)
+			}
+			if got := pipeline.Process(tc.content, tc.path); got != tc.want {
+				t.Fatalf("Process() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCodeStagesRejectsUnknown(t *testing.T) {
+	if _, err := ParseCodeStages([]string{"fences", "beautify"}); err == nil {
+		t.Fatal("expected an error for an unknown stage")
+	}
+	stages, err := ParseCodeStages([]string{" EOL "})
+	if err != nil || len(stages) != 1 || stages[0] != CodeStageEOL {
+		t.Fatalf("ParseCodeStages() = %v, %v", stages, err)
+	}
+}
+
+func TestParseToolCallsFromTextReadsJSONFences(t *testing.T) {
+	calls := ParseToolCallsFromText("I'll read it.\n```json\n{\"tool\": \"file_read\", \"arguments\": {\"path\": \"a.go\"}}\n```\n```go\n{}\n```")
+	if len(calls) != 1 || calls[0].Name != "file_read" || calls[0].Args["path"] != "a.go" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
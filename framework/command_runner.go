@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
@@ -63,7 +64,7 @@ func NewSandboxCommandRunner(manifest *AgentManifest, runtime SandboxRuntime, wo
 // Run executes the requested command inside the sandboxed container runtime.
 func (r *SandboxCommandRunner) Run(ctx context.Context, req CommandRequest) (string, string, error) {
 	if r == nil {
-		return "", "", errors.New("sandbox command runner missing")
+		return "", "", WrapError(ErrorKindSandbox, errors.New("sandbox command runner missing"))
 	}
 	if len(req.Args) == 0 {
 		return "", "", errors.New("command arguments required")
@@ -110,7 +111,24 @@ func (r *SandboxCommandRunner) Run(ctx context.Context, req CommandRequest) (str
 		cmd.Stdin = strings.NewReader(req.Input)
 	}
 	err = cmd.Run()
-	return stdout.String(), stderr.String(), err
+	return stdout.String(), stderr.String(), sandboxRunError(ctx, err)
+}
+
+// dockerRunFailed is the exit code docker run uses for its own failures,
+// such as a missing image or runtime, rather than the command's.
+const dockerRunFailed = 125
+
+// sandboxRunError tags failures of the container runtime itself as
+// ErrorKindSandbox. A command exiting non-zero is returned as is.
+func sandboxRunError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != dockerRunFailed {
+		return err
+	}
+	return WrapError(ErrorKindSandbox, err)
 }
 
 // containerWorkdir maps the host workdir into the container mount.
@@ -137,3 +155,61 @@ func (r *SandboxCommandRunner) containerWorkdir(workdir string) (string, error)
 	}
 	return containerPath, nil
 }
+
+// LocalCommandRunner runs commands directly on the host. It backs degraded
+// mode when no sandbox is available and only confines the working directory
+// to the workspace; nothing else is isolated.
+type LocalCommandRunner struct {
+	workspace string
+}
+
+// NewLocalCommandRunner resolves the workspace root.
+func NewLocalCommandRunner(workspace string) (*LocalCommandRunner, error) {
+	if workspace == "" {
+		return nil, errors.New("workspace required")
+	}
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("resolve workspace: %w", err)
+	}
+	return &LocalCommandRunner{workspace: filepath.Clean(abs)}, nil
+}
+
+// Run executes the command on the host with the workspace environment.
+func (r *LocalCommandRunner) Run(ctx context.Context, req CommandRequest) (string, string, error) {
+	if r == nil {
+		return "", "", errors.New("local command runner missing")
+	}
+	if len(req.Args) == 0 {
+		return "", "", errors.New("command arguments required")
+	}
+	workdir := r.workspace
+	if req.Workdir != "" {
+		workdir = req.Workdir
+		if !filepath.IsAbs(workdir) {
+			workdir = filepath.Join(r.workspace, workdir)
+		}
+		workdir = filepath.Clean(workdir)
+		rel, err := filepath.Rel(r.workspace, workdir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", "", fmt.Errorf("workdir %s outside workspace %s", workdir, r.workspace)
+		}
+	}
+	execCtx := ctx
+	cancel := func() {}
+	if req.Timeout > 0 {
+		execCtx, cancel = context.WithTimeout(ctx, req.Timeout)
+	}
+	defer cancel()
+	cmd := exec.CommandContext(execCtx, req.Args[0], req.Args[1:]...)
+	cmd.Dir = workdir
+	cmd.Env = append(os.Environ(), req.Env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if req.Input != "" {
+		cmd.Stdin = strings.NewReader(req.Input)
+	}
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
@@ -0,0 +1,30 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalCommandRunnerConfinesWorkdir(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workspace, "sub"), 0o755))
+	runner, err := NewLocalCommandRunner(workspace)
+	require.NoError(t, err)
+
+	stdout, _, err := runner.Run(context.Background(), CommandRequest{Args: []string{"pwd"}, Workdir: "sub"})
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(filepath.Join(workspace, "sub"))
+	require.NoError(t, err)
+	require.Equal(t, resolved+"\n", stdout)
+
+	stdout, _, err = runner.Run(context.Background(), CommandRequest{Args: []string{"cat"}, Input: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "hello", stdout)
+
+	_, _, err = runner.Run(context.Background(), CommandRequest{Args: []string{"pwd"}, Workdir: "../"})
+	require.ErrorContains(t, err, "outside workspace")
+}
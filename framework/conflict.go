@@ -0,0 +1,127 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ClarificationsKey holds the []string clarifications people gave while a
+// task ran, in Task.Context.
+const ClarificationsKey = "clarifications"
+
+// ConflictAction is how a person settles a Conflict.
+type ConflictAction string
+
+const (
+	// ConflictAccept keeps the work as it is and ends the task.
+	ConflictAccept ConflictAction = "accept"
+	// ConflictRevisePlan replaces the task's plan and runs it again. Without
+	// a new plan, the clarification says how the plan should change and the
+	// agent plans afresh.
+	ConflictRevisePlan ConflictAction = "revise_plan"
+	// ConflictClarify adds the clarification to the task and runs it again.
+	ConflictClarify ConflictAction = "clarify"
+)
+
+// Conflict is raised when the work cannot continue without a person: the
+// reviewer found the instruction contradicting the plan, or the changes
+// diverging from it.
+type Conflict struct {
+	Reason string   `json:"reason"`
+	Issues []string `json:"issues,omitempty"`
+	Plan   *Plan    `json:"plan,omitempty"`
+}
+
+// ConflictResolution is a person's answer to a Conflict.
+type ConflictResolution struct {
+	Action        ConflictAction `json:"action"`
+	Clarification string         `json:"clarification,omitempty"`
+	Plan          *Plan          `json:"plan,omitempty"`
+}
+
+// ConflictResolver asks a person to settle a conflict, blocking until they
+// answer or ctx ends.
+type ConflictResolver interface {
+	ResolveConflict(ctx context.Context, task *Task, conflict Conflict) (ConflictResolution, error)
+}
+
+type conflictResolverKey struct{}
+
+// WithConflictResolver attaches resolver to ctx. Agents executed under ctx
+// pause on conflicts and resume with the answer; without a resolver they
+// stop and report the conflict.
+func WithConflictResolver(ctx context.Context, resolver ConflictResolver) context.Context {
+	if resolver == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, conflictResolverKey{}, resolver)
+}
+
+// ConflictResolverFromContext returns the resolver attached by
+// WithConflictResolver.
+func ConflictResolverFromContext(ctx context.Context) (ConflictResolver, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	resolver, ok := ctx.Value(conflictResolverKey{}).(ConflictResolver)
+	return resolver, ok
+}
+
+// PlanOf returns the plan attached to task under Context["plan"].
+func PlanOf(task *Task) (*Plan, bool) {
+	if task == nil || task.Context == nil {
+		return nil, false
+	}
+	switch plan := task.Context["plan"].(type) {
+	case Plan:
+		return &plan, true
+	case *Plan:
+		return plan, plan != nil
+	}
+	return nil, false
+}
+
+// Resume returns the task to run after resolution, leaving task unchanged:
+// clarifications are appended to the instruction and to
+// Context[ClarificationsKey], and a revised plan replaces Context["plan"].
+// Accepting returns task itself.
+func (r ConflictResolution) Resume(task *Task) (*Task, error) {
+	if task == nil {
+		return nil, fmt.Errorf("task required")
+	}
+	clarification := strings.TrimSpace(r.Clarification)
+	switch r.Action {
+	case ConflictAccept:
+		return task, nil
+	case ConflictClarify:
+		if clarification == "" {
+			return nil, fmt.Errorf("clarification required")
+		}
+	case ConflictRevisePlan:
+		if r.Plan == nil && clarification == "" {
+			return nil, fmt.Errorf("revised plan or plan change required")
+		}
+	default:
+		return nil, fmt.Errorf("unknown conflict action %q", r.Action)
+	}
+	resumed := *task
+	resumed.Context = make(map[string]interface{}, len(task.Context)+1)
+	for key, value := range task.Context {
+		resumed.Context[key] = value
+	}
+	if r.Action == ConflictRevisePlan {
+		if r.Plan != nil {
+			resumed.Context["plan"] = *r.Plan
+		} else {
+			delete(resumed.Context, "plan")
+			clarification = "Revise the plan: " + clarification
+		}
+	}
+	if clarification != "" {
+		previous, _ := task.Context[ClarificationsKey].([]string)
+		resumed.Context[ClarificationsKey] = append(append([]string(nil), previous...), clarification)
+		resumed.Instruction = task.Instruction + "\n\nClarification from the user: " + clarification
+	}
+	return &resumed, nil
+}
@@ -0,0 +1,46 @@
+package framework
+
+import "testing"
+
+func TestConflictResolutionResume(t *testing.T) {
+	plan := Plan{Goal: "old"}
+	task := &Task{Instruction: "do it", Context: map[string]interface{}{"plan": plan}}
+
+	resumed, err := ConflictResolution{Action: ConflictClarify, Clarification: "use v2"}.Resume(task)
+	if err != nil {
+		t.Fatalf("clarify: %v", err)
+	}
+	if resumed.Instruction != "do it\n\nClarification from the user: use v2" {
+		t.Fatalf("instruction = %q", resumed.Instruction)
+	}
+	if got := resumed.Context[ClarificationsKey].([]string); len(got) != 1 || got[0] != "use v2" {
+		t.Fatalf("clarifications = %v", got)
+	}
+	if _, ok := task.Context[ClarificationsKey]; ok {
+		t.Fatalf("original task changed")
+	}
+
+	revised := Plan{Goal: "new"}
+	resumed, err = ConflictResolution{Action: ConflictRevisePlan, Plan: &revised}.Resume(task)
+	if err != nil {
+		t.Fatalf("revise: %v", err)
+	}
+	if got, _ := PlanOf(resumed); got == nil || got.Goal != "new" || resumed.Instruction != "do it" {
+		t.Fatalf("revised task = %+v", resumed)
+	}
+
+	resumed, err = ConflictResolution{Action: ConflictRevisePlan, Clarification: "skip step 2"}.Resume(task)
+	if err != nil {
+		t.Fatalf("describe plan change: %v", err)
+	}
+	if _, ok := PlanOf(resumed); ok {
+		t.Fatalf("described plan change should drop the old plan")
+	}
+	if resumed.Instruction != "do it\n\nClarification from the user: Revise the plan: skip step 2" {
+		t.Fatalf("instruction = %q", resumed.Instruction)
+	}
+
+	if _, err := (ConflictResolution{Action: ConflictClarify}).Resume(task); err == nil {
+		t.Fatalf("expected empty clarification to fail")
+	}
+}
@@ -58,6 +58,19 @@ type Context struct {
 	phase             string
 	maxHistory        int
 	maxSnapshot       int
+	historySpill      func([]Interaction)
+	spilledThrough    int
+}
+
+func init() {
+	// Clone relies on gob, which refuses interface values whose concrete type
+	// was never registered. Register the containers agents routinely store so a
+	// clone does not silently degrade to an empty context.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register([]map[string]interface{}{})
+	gob.Register(Plan{})
+	gob.Register(time.Time{})
 }
 
 // NewContext builds an empty execution context with sensible history limits so
@@ -73,9 +86,46 @@ func NewContext() *Context {
 		phase:             "planning",
 		maxHistory:        200,
 		maxSnapshot:       32,
+		spilledThrough:    -1,
 	}
 }
 
+// SetHistoryLimit changes how many interactions are retained. spill, when
+// non-nil, receives interactions as truncation drops them so long-running
+// shells can archive them instead of losing them; it runs under the context
+// lock and must not call back into the context.
+func (c *Context) SetHistoryLimit(limit int, spill func([]Interaction)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if limit > 1 {
+		c.maxHistory = limit
+	}
+	c.historySpill = spill
+	c.smartTruncateHistoryLocked()
+}
+
+// HistoryLen reports how many interactions are retained.
+func (c *Context) HistoryLen() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.history)
+}
+
+// TrimHistory shrinks the history to the first interaction plus the latest
+// keep, like automatic truncation, and returns how many were dropped.
+func (c *Context) TrimHistory(keep int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if keep < 0 {
+		keep = 0
+	}
+	before := len(c.history)
+	c.truncateHistoryLocked(keep)
+	// Copy so the dropped interactions' backing array can be collected now.
+	c.history = append([]Interaction(nil), c.history...)
+	return before - len(c.history)
+}
+
 // SetExecutionPhase stores the current execution phase.
 func (c *Context) SetExecutionPhase(phase string) {
 	c.mu.Lock()
@@ -274,6 +324,7 @@ func (c *Context) Restore(snapshot *ContextSnapshot) error {
 	c.compressionLog = snapshot.CompressionLog
 	c.interactionIDCtr = snapshot.InteractionIDCounter
 	c.phase = snapshot.Phase
+	c.spilledThrough = -1
 	c.smartTruncateHistoryLocked()
 	return nil
 }
@@ -341,10 +392,29 @@ func (c *Context) LatestInteraction() (Interaction, bool) {
 // middle portion is dropped so that downstream reasoning retains enough
 // context without exhausting memory.
 func (c *Context) smartTruncateHistoryLocked() {
-	if len(c.history) <= c.maxHistory {
+	c.truncateHistoryLocked(c.maxHistory)
+}
+
+// truncateHistoryLocked drops the oldest middle interactions beyond limit,
+// spilling them first. Merge can re-append interactions a clone copied, so
+// only IDs newer than the last spill are handed to the spill hook.
+func (c *Context) truncateHistoryLocked(limit int) {
+	if len(c.history) <= limit {
 		return
 	}
-	start := len(c.history) - c.maxHistory
+	start := len(c.history) - limit
+	if c.historySpill != nil {
+		var dropped []Interaction
+		for _, interaction := range c.history[1:start] {
+			if interaction.ID > c.spilledThrough {
+				dropped = append(dropped, interaction)
+				c.spilledThrough = interaction.ID
+			}
+		}
+		if len(dropped) > 0 {
+			c.historySpill(dropped)
+		}
+	}
 	c.history = append(c.history[:1], c.history[start:]...)
 }
 
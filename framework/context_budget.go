@@ -163,7 +163,7 @@ func (cb *ContextBudget) Allocate(category string, tokens int, item BudgetItem)
 	}
 	if !cb.ensureCapacityLocked(alloc, tokens) {
 		cb.emitExceeded(category, tokens, alloc.MaxTokens-alloc.UsedTokens)
-		return fmt.Errorf("context budget exhausted for %s", category)
+		return WrapError(ErrorKindContextBudgetExceeded, fmt.Errorf("context budget exhausted for %s", category))
 	}
 	alloc.UsedTokens += tokens
 	if item != nil {
@@ -39,3 +39,45 @@ func TestContextBudgetStates(t *testing.T) {
 		t.Fatal("expected OK budget state")
 	}
 }
+
+func TestSizeContextWindow(t *testing.T) {
+	legacy := SizeContextWindow(ModelContext{}, ContextWindowConfig{})
+	if legacy.MaxTokens != DefaultContextWindow || legacy.SystemReserved != 1000 || legacy.ToolsReserved != 2000 || legacy.OutputReserved != 1000 {
+		t.Fatalf("unknown model should keep the 8K defaults, got %s", legacy)
+	}
+
+	large := SizeContextWindow(ModelContext{ContextLength: 131072, ParameterSize: "14B"}, ContextWindowConfig{})
+	if large.MaxTokens != MaxAutoContextWindow {
+		t.Fatalf("expected automatic sizing to cap at %d, got %d", MaxAutoContextWindow, large.MaxTokens)
+	}
+	if large.ToolsReserved != 8192 || large.Policies.CompressionThreshold != 0.85 {
+		t.Fatalf("unexpected large sizing %s", large)
+	}
+
+	small := SizeContextWindow(ModelContext{ContextLength: 4096, ParameterSize: "1.5B"}, ContextWindowConfig{OutputReserved: 600})
+	if small.MaxTokens != 4096 || small.OutputReserved != 600 {
+		t.Fatalf("unexpected small sizing %s", small)
+	}
+	if small.Policies.CompressionThreshold != 0.7 {
+		t.Fatalf("small models should compress earlier, got %v", small.Policies.CompressionThreshold)
+	}
+
+	explicit := SizeContextWindow(ModelContext{ContextLength: 131072}, ContextWindowConfig{MaxTokens: 65536, CompressionThreshold: 0.9})
+	if explicit.MaxTokens != 65536 || explicit.Policies.CriticalThreshold != 0.95 {
+		t.Fatalf("overrides should win, got %s", explicit)
+	}
+}
+
+func TestContextBudgetResizeKeepsAllocations(t *testing.T) {
+	budget := NewContextBudget(8000)
+	if err := budget.Allocate("immediate", 500, nil); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	budget.Resize(SizeContextWindow(ModelContext{ContextLength: 32768}, ContextWindowConfig{}))
+	if budget.MaxTokens != 32768 || budget.AvailableForContext != 32768-2048-8192-4096 {
+		t.Fatalf("unexpected resized budget: max %d available %d", budget.MaxTokens, budget.AvailableForContext)
+	}
+	if used := budget.GetUsage().Categories["immediate"].UsedTokens; used != 500 {
+		t.Fatalf("expected allocation to survive resize, got %d", used)
+	}
+}
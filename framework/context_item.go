@@ -31,6 +31,8 @@ type InteractionContextItem struct {
 	Interaction Interaction
 	Relevance   float64
 	PriorityVal int
+	// Sources lists what the interaction quotes; see ContextItemSources.
+	Sources []ContextSource
 }
 
 func (ici *InteractionContextItem) TokenCount() int {
@@ -58,6 +60,7 @@ func (ici *InteractionContextItem) Compress() (ContextItem, error) {
 		},
 		Relevance:   ici.Relevance * 0.8,
 		PriorityVal: ici.PriorityVal + 1,
+		Sources:     ici.Sources,
 	}, nil
 }
 
@@ -78,6 +81,8 @@ type FileContextItem struct {
 	Relevance    float64
 	PriorityVal  int
 	Pinned       bool
+	// Sources narrows Path to the line ranges the content came from.
+	Sources []ContextSource
 }
 
 func (fci *FileContextItem) TokenCount() int {
@@ -113,6 +118,7 @@ func (fci *FileContextItem) Compress() (ContextItem, error) {
 		Relevance:    fci.Relevance * 0.9,
 		PriorityVal:  fci.PriorityVal + 1,
 		Pinned:       fci.Pinned,
+		Sources:      fci.Sources,
 	}, nil
 }
 
@@ -131,6 +137,8 @@ type ToolResultContextItem struct {
 	LastAccessed time.Time
 	Relevance    float64
 	PriorityVal  int
+	// Sources lists the files the result was read from.
+	Sources []ContextSource
 }
 
 func (tr *ToolResultContextItem) tokenPayload() string {
@@ -171,6 +179,7 @@ func (tr *ToolResultContextItem) Compress() (ContextItem, error) {
 		LastAccessed: tr.LastAccessed,
 		Relevance:    tr.Relevance * 0.9,
 		PriorityVal:  tr.PriorityVal + 1,
+		Sources:      tr.Sources,
 	}, nil
 }
 
@@ -1,6 +1,9 @@
 package framework
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 // TestContextSnapshotRestore verifies snapshot and restore round-trips all
 // portions of the context (values, variables, history) without data loss.
@@ -29,3 +32,27 @@ func TestContextSnapshotRestore(t *testing.T) {
 		t.Fatalf("expected history size 1, got %d", len(ctx.History()))
 	}
 }
+
+func TestContextHistoryLimitSpillsDroppedInteractions(t *testing.T) {
+	ctx := NewContext()
+	var spilled []Interaction
+	ctx.SetHistoryLimit(3, func(dropped []Interaction) {
+		spilled = append(spilled, dropped...)
+	})
+	for i := 0; i < 6; i++ {
+		ctx.AddInteraction("user", fmt.Sprintf("msg-%d", i), nil)
+	}
+	if ctx.HistoryLen() != 4 {
+		t.Fatalf("expected first interaction plus 3 retained, got %d", ctx.HistoryLen())
+	}
+	if len(spilled) != 2 || spilled[0].Content != "msg-1" {
+		t.Fatalf("unexpected spilled interactions: %+v", spilled)
+	}
+
+	if dropped := ctx.TrimHistory(1); dropped != 2 {
+		t.Fatalf("expected trim to drop 2, got %d", dropped)
+	}
+	if ctx.HistoryLen() != 2 || len(spilled) != 4 {
+		t.Fatalf("expected 2 retained and 4 spilled, got %d and %d", ctx.HistoryLen(), len(spilled))
+	}
+}
@@ -0,0 +1,175 @@
+package framework
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Context sizing defaults. DefaultContextWindow applies when the backend does
+// not report a context length; MaxAutoContextWindow caps automatic sizing,
+// because backends allocate the whole window up front and a 128K window can
+// exhaust local GPU memory. Explicit overrides are not capped.
+const (
+	DefaultContextWindow = 8000
+	MaxAutoContextWindow = 32768
+)
+
+// ModelContext describes the selected model as reported by the backend.
+type ModelContext struct {
+	// Name is the model the metadata was read for.
+	Name string
+	// ContextLength is the model's trained context window in tokens; zero
+	// when unknown.
+	ContextLength int
+	// ParameterSize is the backend's size label, e.g. "7.6B" or "350M".
+	ParameterSize string
+	// Capabilities lists the features the backend reports for the model,
+	// e.g. "completion", "tools", or "vision"; nil when it reports none.
+	Capabilities []string
+}
+
+// ParameterBillions parses ParameterSize, returning zero when it is missing
+// or malformed.
+func (m ModelContext) ParameterBillions() float64 {
+	size := strings.ToUpper(strings.TrimSpace(m.ParameterSize))
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(size, "B"):
+		size = strings.TrimSuffix(size, "B")
+	case strings.HasSuffix(size, "M"):
+		size, scale = strings.TrimSuffix(size, "M"), 0.001
+	case strings.HasSuffix(size, "K"):
+		size, scale = strings.TrimSuffix(size, "K"), 0.000001
+	}
+	v, err := strconv.ParseFloat(size, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v * scale
+}
+
+// ContextWindowConfig overrides automatic context sizing. Zero fields are
+// derived from the model metadata.
+type ContextWindowConfig struct {
+	MaxTokens      int `yaml:"max_tokens,omitempty"`
+	SystemReserved int `yaml:"system_reserved,omitempty"`
+	ToolsReserved  int `yaml:"tools_reserved,omitempty"`
+	OutputReserved int `yaml:"output_reserved,omitempty"`
+	// CompressionThreshold is the share of the context allowance at which
+	// history is compressed; the warning and critical thresholds follow it.
+	CompressionThreshold float64 `yaml:"compression_threshold,omitempty"`
+}
+
+// ContextSizing is a resolved context budget.
+type ContextSizing struct {
+	MaxTokens      int
+	SystemReserved int
+	ToolsReserved  int
+	OutputReserved int
+	Policies       BudgetPolicies
+}
+
+// String summarizes the sizing for logs.
+func (s ContextSizing) String() string {
+	return fmt.Sprintf("%d tokens (system %d, tools %d, output %d), compress at %.0f%%",
+		s.MaxTokens, s.SystemReserved, s.ToolsReserved, s.OutputReserved, s.Policies.CompressionThreshold*100)
+}
+
+// SizeContextWindow derives a budget from model metadata and overrides.
+// Reservations scale with the window; an 8K window keeps the historical
+// 1000/2000/1000 split. Small models lose track of long histories sooner, so
+// models under 4B parameters compress earlier.
+func SizeContextWindow(model ModelContext, overrides ContextWindowConfig) ContextSizing {
+	window := overrides.MaxTokens
+	if window <= 0 {
+		window = model.ContextLength
+		if window <= 0 {
+			window = DefaultContextWindow
+		}
+		if window > MaxAutoContextWindow {
+			window = MaxAutoContextWindow
+		}
+	}
+	sizing := ContextSizing{
+		MaxTokens:      window,
+		SystemReserved: clampInt(window/16, 500, 4096),
+		ToolsReserved:  clampInt(window/4, 1000, 8192),
+		OutputReserved: clampInt(window/8, 500, 8192),
+	}
+	if window <= 8192 {
+		sizing.SystemReserved = clampInt(window/8, 250, 1000)
+		sizing.ToolsReserved = clampInt(window/4, 500, 2000)
+		sizing.OutputReserved = clampInt(window/8, 250, 1000)
+	}
+	if overrides.SystemReserved > 0 {
+		sizing.SystemReserved = overrides.SystemReserved
+	}
+	if overrides.ToolsReserved > 0 {
+		sizing.ToolsReserved = overrides.ToolsReserved
+	}
+	if overrides.OutputReserved > 0 {
+		sizing.OutputReserved = overrides.OutputReserved
+	}
+	threshold := overrides.CompressionThreshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.85
+		if params := model.ParameterBillions(); params > 0 && params < 4 {
+			threshold = 0.7
+		}
+	}
+	sizing.Policies = BudgetPolicies{
+		WarningThreshold:     threshold - 0.15,
+		CompressionThreshold: threshold,
+		CriticalThreshold:    minFloat(threshold+0.1, 0.95),
+		AutoCompress:         true,
+		AutoPrune:            true,
+	}
+	return sizing
+}
+
+// Resize applies sizing to the budget. Category limits are recomputed for the
+// new window; tokens and items already allocated are kept.
+func (cb *ContextBudget) Resize(sizing ContextSizing) {
+	cb.mu.Lock()
+	if sizing.MaxTokens > 0 {
+		cb.MaxTokens = sizing.MaxTokens
+	}
+	cb.ReservedForSystem = sizing.SystemReserved
+	cb.ReservedForTools = sizing.ToolsReserved
+	cb.ReservedForOutput = sizing.OutputReserved
+	if sizing.Policies.CompressionThreshold > 0 {
+		cb.legacyPolicies = sizing.Policies
+	}
+	cb.calculateAvailableLocked()
+	previous := cb.allocations
+	cb.mu.Unlock()
+
+	cb.recomputeAllocations()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	for name, alloc := range cb.allocations {
+		if old, ok := previous[name]; ok {
+			alloc.UsedTokens = old.UsedTokens
+			alloc.Items = old.Items
+		}
+	}
+	cb.updateUsageLocked()
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,540 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConventionsMemoryKey is the project-scoped memory key holding the
+// workspace's conventions.
+const ConventionsMemoryKey = "workspace.conventions"
+
+// ConventionTool is a linter or formatter the project configures, with the
+// file that configures it.
+type ConventionTool struct {
+	Name   string `json:"name"`
+	Config string `json:"config,omitempty"`
+}
+
+// TestPattern is a test file naming pattern and how many files follow it.
+type TestPattern struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// LayoutDir is a top-level directory and how many files it holds.
+type LayoutDir struct {
+	Dir   string `json:"dir"`
+	Files int    `json:"files"`
+}
+
+// ProjectConventions captures how a workspace is linted, formatted, tested,
+// laid out, and committed to, so agents follow the project's habits.
+type ProjectConventions struct {
+	Linters    []ConventionTool `json:"linters,omitempty"`
+	Formatters []ConventionTool `json:"formatters,omitempty"`
+	// Indentation is "tabs" or "N spaces".
+	Indentation    string        `json:"indentation,omitempty"`
+	Tests          []TestPattern `json:"tests,omitempty"`
+	Layout         []LayoutDir   `json:"layout,omitempty"`
+	CommitStyle    string        `json:"commit_style,omitempty"`
+	CommitExamples []string      `json:"commit_examples,omitempty"`
+	GeneratedAt    time.Time     `json:"generated_at"`
+}
+
+// ConventionsAnalyzer inspects a workspace's config files, sources, and git
+// history for its conventions. Like GlossaryExtractor it needs no model.
+type ConventionsAnalyzer struct {
+	Root string
+	// Runner reads the git log for the commit style; without one the
+	// commit style is left empty.
+	Runner CommandRunner
+	// MaxFiles bounds the scan on large workspaces.
+	MaxFiles int
+	// Filter lets callers exclude paths, e.g. via the permission manager.
+	Filter func(path string, isDir bool) bool
+}
+
+// conventionConfigs maps config file names to the linter or formatter they
+// configure.
+var conventionConfigs = []struct {
+	file      string
+	name      string
+	formatter bool
+}{
+	{".golangci.yml", "golangci-lint", false},
+	{".golangci.yaml", "golangci-lint", false},
+	{".golangci.toml", "golangci-lint", false},
+	{"staticcheck.conf", "staticcheck", false},
+	{".eslintrc", "eslint", false},
+	{".eslintrc.js", "eslint", false},
+	{".eslintrc.cjs", "eslint", false},
+	{".eslintrc.json", "eslint", false},
+	{".eslintrc.yml", "eslint", false},
+	{".eslintrc.yaml", "eslint", false},
+	{"eslint.config.js", "eslint", false},
+	{"eslint.config.mjs", "eslint", false},
+	{".stylelintrc", "stylelint", false},
+	{".stylelintrc.json", "stylelint", false},
+	{".flake8", "flake8", false},
+	{".pylintrc", "pylint", false},
+	{"ruff.toml", "ruff", false},
+	{".ruff.toml", "ruff", false},
+	{"mypy.ini", "mypy", false},
+	{".rubocop.yml", "rubocop", false},
+	{"clippy.toml", "clippy", false},
+	{".shellcheckrc", "shellcheck", false},
+	{".hadolint.yaml", "hadolint", false},
+	{".markdownlint.json", "markdownlint", false},
+	{".markdownlint.yaml", "markdownlint", false},
+	{".prettierrc", "prettier", true},
+	{".prettierrc.json", "prettier", true},
+	{".prettierrc.yml", "prettier", true},
+	{".prettierrc.yaml", "prettier", true},
+	{".prettierrc.js", "prettier", true},
+	{"prettier.config.js", "prettier", true},
+	{"rustfmt.toml", "rustfmt", true},
+	{".rustfmt.toml", "rustfmt", true},
+	{".clang-format", "clang-format", true},
+	{"go.mod", "gofmt", true},
+}
+
+// pyprojectTools are the pyproject.toml sections that configure a tool.
+var pyprojectTools = []struct {
+	section   string
+	name      string
+	formatter bool
+}{
+	{"[tool.ruff", "ruff", false},
+	{"[tool.pylint", "pylint", false},
+	{"[tool.mypy", "mypy", false},
+	{"[tool.black", "black", true},
+	{"[tool.isort", "isort", true},
+}
+
+// testPatterns recognise test files by name.
+var testPatterns = []struct {
+	pattern string
+	match   func(name string) bool
+}{
+	{"*_test.go", func(n string) bool { return strings.HasSuffix(n, "_test.go") }},
+	{"test_*.py", func(n string) bool { return strings.HasPrefix(n, "test_") && strings.HasSuffix(n, ".py") }},
+	{"*_test.py", func(n string) bool { return strings.HasSuffix(n, "_test.py") }},
+	{"*.test.ts", func(n string) bool { return strings.HasSuffix(n, ".test.ts") || strings.HasSuffix(n, ".test.tsx") }},
+	{"*.spec.ts", func(n string) bool { return strings.HasSuffix(n, ".spec.ts") || strings.HasSuffix(n, ".spec.tsx") }},
+	{"*.test.js", func(n string) bool { return strings.HasSuffix(n, ".test.js") || strings.HasSuffix(n, ".test.jsx") }},
+	{"*.spec.js", func(n string) bool { return strings.HasSuffix(n, ".spec.js") || strings.HasSuffix(n, ".spec.jsx") }},
+	{"*_spec.rb", func(n string) bool { return strings.HasSuffix(n, "_spec.rb") }},
+	{"*Test.java", func(n string) bool { return strings.HasSuffix(n, "Test.java") }},
+}
+
+// commitStyles recognise commit subject conventions, most specific first.
+var commitStyles = []struct {
+	style   string
+	pattern *regexp.Regexp
+}{
+	{"conventional commits (type(scope): summary)", regexp.MustCompile(`^(feat|fix|chore|docs|refactor|test|perf|build|ci|style|revert)(\([^)]*\))?!?: `)},
+	{"bracketed prefix ([id] summary)", regexp.MustCompile(`^\[[^\]]+\] `)},
+	{"ticket prefix (ABC-123 summary)", regexp.MustCompile(`^[A-Z][A-Z0-9]+-[0-9]+[: ]`)},
+	{"component prefix (area: summary)", regexp.MustCompile(`^[a-z0-9_./-]+: `)},
+	{"capitalized imperative summary", regexp.MustCompile(`^[A-Z][a-z]+ `)},
+}
+
+// indentExtensions are the sources sampled for indentation when there is no
+// .editorconfig.
+var indentExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".ts": true, ".tsx": true, ".jsx": true,
+	".rs": true, ".java": true, ".rb": true, ".c": true, ".h": true, ".cpp": true,
+}
+
+// Analyze inspects the workspace and returns its conventions.
+func (a *ConventionsAnalyzer) Analyze(ctx context.Context) (*ProjectConventions, error) {
+	root := a.Root
+	if root == "" {
+		root = "."
+	}
+	maxFiles := a.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = 5000
+	}
+	conventions := &ProjectConventions{GeneratedAt: time.Now().UTC()}
+	a.detectTools(root, conventions)
+
+	tests := make(map[string]int)
+	layout := make(map[string]int)
+	tabs, spaces := 0, make(map[int]int)
+	scanned := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			if a.Filter != nil && !a.Filter(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if a.Filter != nil && !a.Filter(path, false) {
+			return nil
+		}
+		if scanned >= maxFiles {
+			return filepath.SkipAll
+		}
+		scanned++
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		if parts := strings.SplitN(filepath.ToSlash(rel), "/", 2); len(parts) == 2 {
+			layout[parts[0]]++
+		}
+		for _, test := range testPatterns {
+			if test.match(name) {
+				tests[test.pattern]++
+				break
+			}
+		}
+		if indentExtensions[filepath.Ext(name)] && scanned <= 500 {
+			sampleIndentation(path, &tabs, spaces)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for pattern, count := range tests {
+		conventions.Tests = append(conventions.Tests, TestPattern{Pattern: pattern, Count: count})
+	}
+	sort.Slice(conventions.Tests, func(i, j int) bool {
+		if conventions.Tests[i].Count == conventions.Tests[j].Count {
+			return conventions.Tests[i].Pattern < conventions.Tests[j].Pattern
+		}
+		return conventions.Tests[i].Count > conventions.Tests[j].Count
+	})
+	for dir, files := range layout {
+		conventions.Layout = append(conventions.Layout, LayoutDir{Dir: dir, Files: files})
+	}
+	sort.Slice(conventions.Layout, func(i, j int) bool {
+		if conventions.Layout[i].Files == conventions.Layout[j].Files {
+			return conventions.Layout[i].Dir < conventions.Layout[j].Dir
+		}
+		return conventions.Layout[i].Files > conventions.Layout[j].Files
+	})
+	if len(conventions.Layout) > 12 {
+		conventions.Layout = conventions.Layout[:12]
+	}
+	if conventions.Indentation == "" {
+		conventions.Indentation = dominantIndentation(tabs, spaces)
+	}
+	a.detectCommitStyle(ctx, root, conventions)
+	return conventions, nil
+}
+
+// detectTools records linters and formatters from their config files, and
+// the indentation from .editorconfig.
+func (a *ConventionsAnalyzer) detectTools(root string, conventions *ProjectConventions) {
+	seen := make(map[string]bool)
+	add := func(name, config string, formatter bool) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		tool := ConventionTool{Name: name, Config: config}
+		if formatter {
+			conventions.Formatters = append(conventions.Formatters, tool)
+		} else {
+			conventions.Linters = append(conventions.Linters, tool)
+		}
+	}
+	for _, entry := range conventionConfigs {
+		if a.exists(filepath.Join(root, entry.file)) {
+			add(entry.name, entry.file, entry.formatter)
+		}
+	}
+	if data, err := a.read(filepath.Join(root, "pyproject.toml")); err == nil {
+		for _, entry := range pyprojectTools {
+			if strings.Contains(data, entry.section+"]") || strings.Contains(data, entry.section+".") {
+				add(entry.name, "pyproject.toml", entry.formatter)
+			}
+		}
+	}
+	if data, err := a.read(filepath.Join(root, "setup.cfg")); err == nil && strings.Contains(data, "[flake8]") {
+		add("flake8", "setup.cfg", false)
+	}
+	if data, err := a.read(filepath.Join(root, "package.json")); err == nil {
+		for _, name := range []string{"eslint", "prettier"} {
+			if strings.Contains(data, `"`+name+`"`) {
+				add(name, "package.json", name == "prettier")
+			}
+		}
+	}
+	if data, err := a.read(filepath.Join(root, ".editorconfig")); err == nil {
+		conventions.Indentation = editorconfigIndentation(data)
+	}
+}
+
+func (a *ConventionsAnalyzer) exists(path string) bool {
+	if a.Filter != nil && !a.Filter(path, false) {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (a *ConventionsAnalyzer) read(path string) (string, error) {
+	if a.Filter != nil && !a.Filter(path, false) {
+		return "", fs.ErrPermission
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// detectCommitStyle classifies recent commit subjects, keeping a style
+// only when most subjects follow it.
+func (a *ConventionsAnalyzer) detectCommitStyle(ctx context.Context, root string, conventions *ProjectConventions) {
+	if a.Runner == nil {
+		return
+	}
+	stdout, _, err := a.Runner.Run(ctx, CommandRequest{
+		Workdir: root,
+		Args:    []string{"git", "log", "--no-merges", "--format=%s", "-n", "50"},
+		Timeout: 10 * time.Second,
+	})
+	if err != nil {
+		return
+	}
+	var subjects []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	if len(subjects) == 0 {
+		return
+	}
+	for _, style := range commitStyles {
+		var examples []string
+		matched := 0
+		for _, subject := range subjects {
+			if style.pattern.MatchString(subject) {
+				matched++
+				if len(examples) < 3 {
+					examples = append(examples, subject)
+				}
+			}
+		}
+		if matched*2 > len(subjects) {
+			conventions.CommitStyle = style.style
+			conventions.CommitExamples = examples
+			return
+		}
+	}
+}
+
+// editorconfigIndentation reads indent_style and indent_size from the [*]
+// section.
+func editorconfigIndentation(data string) string {
+	global := false
+	style, size := "", ""
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			global = line == "[*]"
+			continue
+		}
+		if !global {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "indent_style":
+			style = strings.TrimSpace(value)
+		case "indent_size":
+			size = strings.TrimSpace(value)
+		}
+	}
+	switch {
+	case style == "tab":
+		return "tabs"
+	case style == "space" && size != "":
+		return size + " spaces"
+	case style == "space":
+		return "spaces"
+	}
+	return ""
+}
+
+// sampleIndentation counts the leading whitespace of a file's indented
+// lines: tabs, or the width of space indents.
+func sampleIndentation(path string, tabs *int, spaces map[int]int) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := 0
+	for scanner.Scan() && lines < 200 {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			*tabs++
+			lines++
+		case strings.HasPrefix(line, " "):
+			width := len(line) - len(strings.TrimLeft(line, " "))
+			if width > 0 && width <= 8 && strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "*") {
+				spaces[width]++
+				lines++
+			}
+		}
+	}
+}
+
+// dominantIndentation picks tabs or the most likely space indent unit.
+func dominantIndentation(tabs int, spaces map[int]int) string {
+	total := 0
+	for _, count := range spaces {
+		total += count
+	}
+	if tabs == 0 && total == 0 {
+		return ""
+	}
+	if tabs >= total {
+		return "tabs"
+	}
+	// Nested lines are indented by multiples of the unit, so prefer the
+	// smallest width that accounts for a fair share of the lines.
+	for _, unit := range []int{2, 4, 8} {
+		if spaces[unit]*4 >= total {
+			return fmt.Sprintf("%d spaces", unit)
+		}
+	}
+	return "spaces"
+}
+
+// PromptSection renders the conventions for a system prompt. It returns an
+// empty string when nothing was detected.
+func (c *ProjectConventions) PromptSection() string {
+	if c == nil {
+		return ""
+	}
+	var lines []string
+	if names := conventionToolNames(c.Linters); names != "" {
+		lines = append(lines, "- Linters: "+names+"; keep their checks passing")
+	}
+	formatting := conventionToolNames(c.Formatters)
+	if c.Indentation != "" {
+		if formatting != "" {
+			formatting += "; "
+		}
+		formatting += "indent with " + c.Indentation
+	}
+	if formatting != "" {
+		lines = append(lines, "- Formatting: "+formatting)
+	}
+	if len(c.Tests) > 0 {
+		var patterns []string
+		for i, test := range c.Tests {
+			if i >= 3 {
+				break
+			}
+			patterns = append(patterns, fmt.Sprintf("%s (%d files)", test.Pattern, test.Count))
+		}
+		lines = append(lines, "- Test files: "+strings.Join(patterns, ", "))
+	}
+	if len(c.Layout) > 0 {
+		var dirs []string
+		for i, dir := range c.Layout {
+			if i >= 8 {
+				break
+			}
+			dirs = append(dirs, dir.Dir+"/")
+		}
+		lines = append(lines, "- Main directories: "+strings.Join(dirs, ", "))
+	}
+	if c.CommitStyle != "" {
+		line := "- Commit messages: " + c.CommitStyle
+		if len(c.CommitExamples) > 0 {
+			line += fmt.Sprintf(", e.g. %q", c.CommitExamples[0])
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Project conventions:\n" + strings.Join(lines, "\n") + "\n"
+}
+
+func conventionToolNames(tools []ConventionTool) string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// SaveConventions stores the conventions in project-scoped memory.
+func SaveConventions(ctx context.Context, store MemoryStore, conventions *ProjectConventions) error {
+	if store == nil || conventions == nil {
+		return nil
+	}
+	data, err := json.Marshal(conventions)
+	if err != nil {
+		return err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return store.Remember(ctx, ConventionsMemoryKey, value, MemoryScopeProject)
+}
+
+// LoadConventions reads the conventions from project memory. It returns nil
+// when they have not been captured yet.
+func LoadConventions(ctx context.Context, store MemoryStore) (*ProjectConventions, error) {
+	if store == nil {
+		return nil, nil
+	}
+	record, ok, err := store.Recall(ctx, ConventionsMemoryKey, MemoryScopeProject)
+	if err != nil || !ok {
+		return nil, err
+	}
+	data, err := json.Marshal(record.Value)
+	if err != nil {
+		return nil, err
+	}
+	var conventions ProjectConventions
+	if err := json.Unmarshal(data, &conventions); err != nil {
+		return nil, err
+	}
+	return &conventions, nil
+}
+
+// RefreshConventions analyzes and stores the conventions in one step.
+func RefreshConventions(ctx context.Context, analyzer *ConventionsAnalyzer, store MemoryStore) (*ProjectConventions, error) {
+	conventions, err := analyzer.Analyze(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conventions, SaveConventions(ctx, store, conventions)
+}
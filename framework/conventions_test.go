@@ -0,0 +1,94 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gitLogRunner struct {
+	subjects string
+}
+
+func (r gitLogRunner) Run(ctx context.Context, req CommandRequest) (string, string, error) {
+	return r.subjects, "", nil
+}
+
+func TestConventionsAnalyzerDetectsProjectHabits(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                "module demo\n",
+		".golangci.yml":         "linters:\n  enable: [errcheck]\n",
+		"pyproject.toml":        "[tool.black]\nline-length = 100\n[tool.ruff.lint]\nselect = [\"E\"]\n",
+		"api/server.go":         "package api\n\nfunc Serve() {\n\tif true {\n\t\treturn\n\t}\n}\n",
+		"api/server_test.go":    "package api\n",
+		"api/handler_test.go":   "package api\n",
+		"scripts/test_build.py": "def test_build():\n    pass\n",
+		".git/HEAD":             "ref: refs/heads/main\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	runner := gitLogRunner{subjects: "feat(api): add server\nfix: handle empty body\nUpdate docs\n"}
+
+	conventions, err := (&ConventionsAnalyzer{Root: dir, Runner: runner}).Analyze(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []ConventionTool{{Name: "golangci-lint", Config: ".golangci.yml"}, {Name: "ruff", Config: "pyproject.toml"}}, conventions.Linters)
+	assert.Equal(t, []ConventionTool{{Name: "gofmt", Config: "go.mod"}, {Name: "black", Config: "pyproject.toml"}}, conventions.Formatters)
+	assert.Equal(t, "tabs", conventions.Indentation)
+	assert.Equal(t, []TestPattern{{Pattern: "*_test.go", Count: 2}, {Pattern: "test_*.py", Count: 1}}, conventions.Tests)
+	assert.Equal(t, []LayoutDir{{Dir: "api", Files: 3}, {Dir: "scripts", Files: 1}}, conventions.Layout)
+	assert.Equal(t, "conventional commits (type(scope): summary)", conventions.CommitStyle)
+	assert.Equal(t, []string{"feat(api): add server", "fix: handle empty body"}, conventions.CommitExamples)
+}
+
+func TestConventionsPreferEditorconfig(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte("root = true\n\n[*]\nindent_style = space\nindent_size = 2\n\n[Makefile]\nindent_style = tab\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0o644))
+
+	conventions, err := (&ConventionsAnalyzer{Root: dir}).Analyze(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2 spaces", conventions.Indentation)
+	assert.Empty(t, conventions.CommitStyle)
+}
+
+func TestConventionsRoundTripThroughMemory(t *testing.T) {
+	store, err := NewHybridMemory(t.TempDir())
+	assert.NoError(t, err)
+	loaded, err := LoadConventions(context.Background(), store)
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	conventions := &ProjectConventions{
+		Linters:        []ConventionTool{{Name: "golangci-lint", Config: ".golangci.yml"}},
+		Formatters:     []ConventionTool{{Name: "gofmt", Config: "go.mod"}},
+		Indentation:    "tabs",
+		Tests:          []TestPattern{{Pattern: "*_test.go", Count: 12}},
+		Layout:         []LayoutDir{{Dir: "framework", Files: 40}, {Dir: "tools", Files: 30}},
+		CommitStyle:    "bracketed prefix ([id] summary)",
+		CommitExamples: []string{"[#12] Add cache"},
+	}
+	assert.NoError(t, SaveConventions(context.Background(), store, conventions))
+
+	loaded, err = LoadConventions(context.Background(), store)
+	assert.NoError(t, err)
+	section := loaded.PromptSection()
+	for _, want := range []string{
+		"Project conventions:",
+		"- Linters: golangci-lint; keep their checks passing",
+		"- Formatting: gofmt; indent with tabs",
+		"- Test files: *_test.go (12 files)",
+		"- Main directories: framework/, tools/",
+		`- Commit messages: bracketed prefix ([id] summary), e.g. "[#12] Add cache"`,
+	} {
+		assert.True(t, strings.Contains(section, want), "missing %q in:\n%s", want, section)
+	}
+	assert.Empty(t, (&ProjectConventions{}).PromptSection())
+}
@@ -0,0 +1,8 @@
+// Package framework is the core of Relurpify: the Agent, Tool, LanguageModel,
+// and MemoryStore interfaces, the Graph execution engine, the shared Context
+// blackboard, and the manifest-driven permission manager.
+//
+// Embedders depend on this package directly. Its exported API follows the
+// module's semantic versioning policy (see Version and API.md); identifiers
+// marked Experimental in their doc comment are excluded from that guarantee.
+package framework
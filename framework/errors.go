@@ -0,0 +1,145 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrorKind is a category of failure that users and scripts can act on, so
+// a model that is down is not mistaken for a denied permission.
+type ErrorKind string
+
+const (
+	// ErrorKindLLMUnavailable means the model endpoint could not be reached,
+	// does not have the model, or kept failing with server errors.
+	ErrorKindLLMUnavailable ErrorKind = "llm_unavailable"
+	// ErrorKindToolDenied means a permission check blocked a tool.
+	ErrorKindToolDenied ErrorKind = "tool_denied"
+	// ErrorKindContextBudgetExceeded means the context window had no room
+	// left for what the task needed.
+	ErrorKindContextBudgetExceeded ErrorKind = "context_budget_exceeded"
+	// ErrorKindParseFailure means model output or a response could not be
+	// decoded into what the caller expected.
+	ErrorKindParseFailure ErrorKind = "parse_failure"
+	// ErrorKindSandbox means the sandbox runtime failed to start or run a
+	// command, as opposed to the command itself failing.
+	ErrorKindSandbox ErrorKind = "sandbox_error"
+	// ErrorKindTimeout means a graph or node deadline expired.
+	ErrorKindTimeout ErrorKind = "timeout"
+	// ErrorKindCancelled means the run was interrupted.
+	ErrorKindCancelled ErrorKind = "cancelled"
+	// ErrorKindBudgetExceeded means the task hit one of its TaskBudget
+	// limits.
+	ErrorKindBudgetExceeded ErrorKind = "budget_exceeded"
+)
+
+// Describe returns a short human label for the kind, e.g. for a message
+// prefix. Unknown kinds read as a generic error.
+func (k ErrorKind) Describe() string {
+	switch k {
+	case ErrorKindLLMUnavailable:
+		return "model unavailable"
+	case ErrorKindToolDenied:
+		return "permission denied"
+	case ErrorKindContextBudgetExceeded:
+		return "context budget exceeded"
+	case ErrorKindParseFailure:
+		return "could not parse output"
+	case ErrorKindSandbox:
+		return "sandbox error"
+	case ErrorKindTimeout:
+		return "timed out"
+	case ErrorKindCancelled:
+		return "cancelled"
+	case ErrorKindBudgetExceeded:
+		return "task budget exceeded"
+	}
+	return "error"
+}
+
+// Error attaches a kind to an error. It keeps the wrapped message and
+// unwraps to it, so errors.Is and errors.As see through it.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorKind implements the interface ErrorKindOf looks for.
+func (e *Error) ErrorKind() ErrorKind { return e.Kind }
+
+// WrapError tags err with kind. Nil stays nil, and errors that already
+// carry a kind keep it.
+func WrapError(kind ErrorKind, err error) error {
+	if err == nil || ErrorKindOf(err) != "" {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// ErrorKindOf returns the kind of the first error in err's chain that has
+// one, or "" when none does. Besides *Error, PermissionDeniedError,
+// TimeoutError, BudgetExceededError, StructuredOutputError, and
+// llm.StatusError report kinds.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var kinded interface{ ErrorKind() ErrorKind }
+	if errors.As(err, &kinded) {
+		if kind := kinded.ErrorKind(); kind != "" {
+			return kind
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCancelled
+	}
+	return ""
+}
+
+// errorJSON is how errors are serialized in results.
+type errorJSON struct {
+	Kind    ErrorKind `json:"kind,omitempty"`
+	Message string    `json:"message"`
+}
+
+// MarshalJSON writes the kind and message.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{Kind: e.Kind, Message: e.Error()})
+}
+
+// resultJSON mirrors Result with a serializable error.
+type resultJSON struct {
+	NodeID  string
+	Success bool
+	Data    map[string]any
+	Error   *errorJSON `json:",omitempty"`
+}
+
+// MarshalJSON writes Error as {"kind", "message"} instead of the empty
+// object encoding/json produces for most errors.
+func (r Result) MarshalJSON() ([]byte, error) {
+	out := resultJSON{NodeID: r.NodeID, Success: r.Success, Data: r.Data}
+	if r.Error != nil {
+		out.Error = &errorJSON{Kind: ErrorKindOf(r.Error), Message: r.Error.Error()}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON restores a result written by MarshalJSON. The error comes
+// back as an *Error, so its kind survives persistence.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var in resultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = Result{NodeID: in.NodeID, Success: in.Success, Data: in.Data}
+	if in.Error != nil {
+		r.Error = &Error{Kind: in.Error.Kind, Err: errors.New(in.Error.Message)}
+	}
+	return nil
+}
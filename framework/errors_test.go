@@ -0,0 +1,63 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	refused := errors.New("connection refused")
+	cases := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{name: "nil", err: nil, kind: ""},
+		{name: "plain", err: errors.New("boom"), kind: ""},
+		{name: "wrapped", err: fmt.Errorf("node act: %w", WrapError(ErrorKindLLMUnavailable, refused)), kind: ErrorKindLLMUnavailable},
+		{name: "denied", err: fmt.Errorf("tool: %w", &PermissionDeniedError{Message: "no"}), kind: ErrorKindToolDenied},
+		{name: "timeout", err: &TimeoutError{Scope: TimeoutScopeGraph, NodeID: "act"}, kind: ErrorKindTimeout},
+		{name: "structured output", err: &StructuredOutputError{Attempts: 2, Err: ErrNoJSON}, kind: ErrorKindParseFailure},
+		{name: "cancelled", err: fmt.Errorf("run: %w", context.Canceled), kind: ErrorKindCancelled},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.kind, ErrorKindOf(tc.err))
+		})
+	}
+
+	wrapped := WrapError(ErrorKindSandbox, refused)
+	assert.Equal(t, "connection refused", wrapped.Error())
+	assert.ErrorIs(t, wrapped, refused)
+	assert.Same(t, wrapped, WrapError(ErrorKindParseFailure, wrapped), "an existing kind is kept")
+	assert.Nil(t, WrapError(ErrorKindSandbox, nil))
+}
+
+func TestContextBudgetExhaustedHasKind(t *testing.T) {
+	budget := NewContextBudget(1000)
+	err := budget.Allocate("immediate", 1_000_000, nil)
+	require.Error(t, err)
+	assert.Equal(t, ErrorKindContextBudgetExceeded, ErrorKindOf(err))
+}
+
+func TestResultJSONKeepsErrorKind(t *testing.T) {
+	res := Result{NodeID: "act", Data: map[string]any{"n": 1.0}, Error: fmt.Errorf("generate: %w", WrapError(ErrorKindLLMUnavailable, errors.New("refused")))}
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"NodeID":"act","Success":false,"Data":{"n":1},"Error":{"kind":"llm_unavailable","message":"generate: refused"}}`, string(data))
+
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "generate: refused", decoded.Error.Error())
+	assert.Equal(t, ErrorKindLLMUnavailable, ErrorKindOf(decoded.Error))
+
+	data, err = json.Marshal(&Result{NodeID: "ok", Success: true})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"NodeID":"ok","Success":true,"Data":null}`, string(data))
+}
@@ -0,0 +1,390 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// GlossaryMemoryKey is the project-scoped memory key holding the glossary.
+const GlossaryMemoryKey = "workspace.glossary"
+
+// Glossary term kinds.
+const (
+	GlossaryKindTerm         = "term"
+	GlossaryKindAbbreviation = "abbreviation"
+)
+
+// GlossaryTerm is a piece of project vocabulary.
+type GlossaryTerm struct {
+	Term        string   `json:"term"`
+	Kind        string   `json:"kind"`
+	Expansion   string   `json:"expansion,omitempty"`
+	Occurrences int      `json:"occurrences"`
+	Files       []string `json:"files,omitempty"`
+}
+
+// NamingConvention records a recurring type-name suffix such as "*Tool".
+type NamingConvention struct {
+	Suffix   string   `json:"suffix"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// Glossary captures the vocabulary extracted from a workspace.
+type Glossary struct {
+	Terms       []GlossaryTerm     `json:"terms"`
+	Conventions []NamingConvention `json:"conventions"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// GlossaryExtractor scans source and documentation for domain vocabulary. The
+// pass is purely lexical so it can run in the background without a model.
+type GlossaryExtractor struct {
+	Root string
+	// MaxFiles bounds the scan on large workspaces.
+	MaxFiles int
+	// MaxTerms bounds how many terms are kept.
+	MaxTerms int
+	// MinOccurrences drops words that are not used often enough to matter.
+	MinOccurrences int
+	// Filter lets callers exclude paths, e.g. via the permission manager.
+	Filter func(path string, isDir bool) bool
+}
+
+var (
+	glossaryIdentifier   = regexp.MustCompile(`[A-Za-z][A-Za-z0-9]+`)
+	glossaryTypeDecl     = regexp.MustCompile(`^\s*type\s+([A-Z][A-Za-z0-9]*)\s+(struct|interface)\b`)
+	glossaryAbbreviation = regexp.MustCompile(`\b([A-Z][A-Z0-9]{1,5})s?\b`)
+	glossaryExpansion    = regexp.MustCompile(`((?:[A-Z][a-z]+[\s-]){1,5}[A-Z][a-z]+)\s+\(([A-Z][A-Z0-9]{1,5})\)`)
+	glossaryStopWords    = map[string]bool{
+		"the": true, "and": true, "for": true, "with": true, "that": true, "this": true, "from": true,
+		"return": true, "func": true, "type": true, "struct": true, "string": true, "error": true,
+		"interface": true, "package": true, "import": true, "nil": true, "err": true, "var": true,
+		"const": true, "int": true, "bool": true, "true": true, "false": true, "map": true, "range": true,
+		"else": true, "case": true, "switch": true, "default": true, "make": true, "len": true, "append": true,
+		"get": true, "set": true, "new": true, "are": true, "not": true, "when": true, "into": true,
+		"will": true, "can": true, "use": true, "uses": true, "used": true, "all": true, "any": true,
+		"fmt": true, "ctx": true, "context": true, "args": true, "value": true, "data": true, "res": true,
+	}
+	glossaryCommonAbbreviations = map[string]bool{"OK": true, "ID": true, "TODO": true, "NOTE": true, "FIXME": true}
+)
+
+// Extract walks the workspace and builds a glossary.
+func (e *GlossaryExtractor) Extract(ctx context.Context) (*Glossary, error) {
+	root := e.Root
+	if root == "" {
+		root = "."
+	}
+	maxFiles := e.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = 2000
+	}
+	minOccurrences := e.MinOccurrences
+	if minOccurrences <= 0 {
+		minOccurrences = 3
+	}
+	terms := make(map[string]*GlossaryTerm)
+	suffixes := make(map[string][]string)
+	scanned := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			if e.Filter != nil && !e.Filter(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".go" && ext != ".md" {
+			return nil
+		}
+		if e.Filter != nil && !e.Filter(path, false) {
+			return nil
+		}
+		if scanned >= maxFiles {
+			return filepath.SkipAll
+		}
+		scanned++
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		return e.scanFile(path, rel, ext == ".md", terms, suffixes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	glossary := &Glossary{GeneratedAt: time.Now().UTC()}
+	for _, term := range terms {
+		if term.Kind == GlossaryKindTerm && term.Occurrences < minOccurrences {
+			continue
+		}
+		if term.Kind == GlossaryKindAbbreviation && term.Expansion == "" && term.Occurrences < minOccurrences {
+			continue
+		}
+		glossary.Terms = append(glossary.Terms, *term)
+	}
+	sort.Slice(glossary.Terms, func(i, j int) bool {
+		if glossary.Terms[i].Occurrences == glossary.Terms[j].Occurrences {
+			return glossary.Terms[i].Term < glossary.Terms[j].Term
+		}
+		return glossary.Terms[i].Occurrences > glossary.Terms[j].Occurrences
+	})
+	maxTerms := e.MaxTerms
+	if maxTerms <= 0 {
+		maxTerms = 200
+	}
+	if len(glossary.Terms) > maxTerms {
+		glossary.Terms = glossary.Terms[:maxTerms]
+	}
+	for suffix, examples := range suffixes {
+		if len(examples) < 3 {
+			continue
+		}
+		sort.Strings(examples)
+		convention := NamingConvention{Suffix: suffix, Count: len(examples), Examples: examples}
+		if len(convention.Examples) > 4 {
+			convention.Examples = convention.Examples[:4]
+		}
+		glossary.Conventions = append(glossary.Conventions, convention)
+	}
+	sort.Slice(glossary.Conventions, func(i, j int) bool {
+		if glossary.Conventions[i].Count == glossary.Conventions[j].Count {
+			return glossary.Conventions[i].Suffix < glossary.Conventions[j].Suffix
+		}
+		return glossary.Conventions[i].Count > glossary.Conventions[j].Count
+	})
+	return glossary, nil
+}
+
+// scanFile records identifiers, abbreviations, and type-name suffixes.
+func (e *GlossaryExtractor) scanFile(path, rel string, doc bool, terms map[string]*GlossaryTerm, suffixes map[string][]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	record := func(word, kind, expansion string) {
+		key := strings.ToLower(word)
+		if kind == GlossaryKindAbbreviation {
+			key = "abbr:" + word
+		}
+		term, ok := terms[key]
+		if !ok {
+			term = &GlossaryTerm{Term: word, Kind: kind}
+			terms[key] = term
+		}
+		term.Occurrences++
+		if expansion != "" && term.Expansion == "" {
+			term.Expansion = expansion
+		}
+		if len(term.Files) < 3 && !containsString(term.Files, rel) {
+			term.Files = append(term.Files, rel)
+		}
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, match := range glossaryExpansion.FindAllStringSubmatch(line, -1) {
+			if initialsMatch(match[1], match[2]) {
+				record(match[2], GlossaryKindAbbreviation, match[1])
+			}
+		}
+		if doc || strings.Contains(line, "//") {
+			for _, match := range glossaryAbbreviation.FindAllStringSubmatch(line, -1) {
+				if !glossaryCommonAbbreviations[match[1]] {
+					record(match[1], GlossaryKindAbbreviation, "")
+				}
+			}
+		}
+		if match := glossaryTypeDecl.FindStringSubmatch(line); match != nil {
+			parts := splitIdentifier(match[1])
+			if len(parts) > 1 {
+				suffix := parts[len(parts)-1]
+				suffixes[suffix] = append(suffixes[suffix], match[1])
+			}
+		}
+		for _, ident := range glossaryIdentifier.FindAllString(line, -1) {
+			for _, part := range splitIdentifier(ident) {
+				word := strings.ToLower(part)
+				if len(word) < 4 || glossaryStopWords[word] || !isAlpha(word) {
+					continue
+				}
+				record(word, GlossaryKindTerm, "")
+			}
+		}
+	}
+	return nil
+}
+
+// Relevant returns glossary entries mentioned in text, most frequent first.
+func (g *Glossary) Relevant(text string, limit int) []GlossaryTerm {
+	if g == nil {
+		return nil
+	}
+	words := make(map[string]bool)
+	for _, ident := range glossaryIdentifier.FindAllString(text, -1) {
+		words[strings.ToLower(ident)] = true
+		words[ident] = true
+		for _, part := range splitIdentifier(ident) {
+			words[strings.ToLower(part)] = true
+		}
+	}
+	var res []GlossaryTerm
+	for _, term := range g.Terms {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		if words[term.Term] {
+			res = append(res, term)
+		}
+	}
+	return res
+}
+
+// PromptSection renders the glossary entries relevant to text plus the naming
+// conventions. It returns an empty string when nothing applies.
+func (g *Glossary) PromptSection(text string, limit int) string {
+	if g == nil {
+		return ""
+	}
+	terms := g.Relevant(text, limit)
+	if len(terms) == 0 && len(g.Conventions) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("Project vocabulary:\n")
+	for _, term := range terms {
+		builder.WriteString("- ")
+		builder.WriteString(term.Term)
+		if term.Expansion != "" {
+			builder.WriteString(" = ")
+			builder.WriteString(term.Expansion)
+		}
+		if len(term.Files) > 0 {
+			builder.WriteString(fmt.Sprintf(" (see %s)", strings.Join(term.Files, ", ")))
+		}
+		builder.WriteRune('\n')
+	}
+	for i, convention := range g.Conventions {
+		if i >= 5 {
+			break
+		}
+		builder.WriteString(fmt.Sprintf("- Types named *%s, e.g. %s\n", convention.Suffix, strings.Join(convention.Examples, ", ")))
+	}
+	return builder.String()
+}
+
+// SaveGlossary stores the glossary in project-scoped memory.
+func SaveGlossary(ctx context.Context, store MemoryStore, glossary *Glossary) error {
+	if store == nil || glossary == nil {
+		return nil
+	}
+	data, err := json.Marshal(glossary)
+	if err != nil {
+		return err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return store.Remember(ctx, GlossaryMemoryKey, value, MemoryScopeProject)
+}
+
+// LoadGlossary reads the glossary from project memory. It returns nil when no
+// glossary has been extracted yet.
+func LoadGlossary(ctx context.Context, store MemoryStore) (*Glossary, error) {
+	if store == nil {
+		return nil, nil
+	}
+	record, ok, err := store.Recall(ctx, GlossaryMemoryKey, MemoryScopeProject)
+	if err != nil || !ok {
+		return nil, err
+	}
+	data, err := json.Marshal(record.Value)
+	if err != nil {
+		return nil, err
+	}
+	var glossary Glossary
+	if err := json.Unmarshal(data, &glossary); err != nil {
+		return nil, err
+	}
+	return &glossary, nil
+}
+
+// RefreshGlossary extracts and stores the glossary in one step; runtimes call
+// it in the background after startup.
+func RefreshGlossary(ctx context.Context, extractor *GlossaryExtractor, store MemoryStore) error {
+	glossary, err := extractor.Extract(ctx)
+	if err != nil {
+		return err
+	}
+	return SaveGlossary(ctx, store, glossary)
+}
+
+// splitIdentifier breaks camelCase, PascalCase, and snake_case identifiers.
+func splitIdentifier(ident string) []string {
+	var parts []string
+	for _, chunk := range strings.FieldsFunc(ident, func(r rune) bool { return r == '_' || r == '-' }) {
+		runes := []rune(chunk)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower)) {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
+
+// initialsMatch reports whether abbr spells the initials of phrase.
+func initialsMatch(phrase, abbr string) bool {
+	var initials strings.Builder
+	for _, word := range strings.FieldsFunc(phrase, func(r rune) bool { return r == ' ' || r == '-' }) {
+		initials.WriteRune(unicode.ToUpper([]rune(word)[0]))
+	}
+	return initials.String() == abbr
+}
+
+func isAlpha(word string) bool {
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlossaryExtractorFindsVocabulary(t *testing.T) {
+	dir := t.TempDir()
+	src := `package demo
+
+// ReadTool uses the Language Server Protocol (LSP) for lookups.
+type ReadTool struct{}
+type WriteTool struct{}
+type SearchTool struct{}
+type Ledger struct{ ledgerEntries int }
+
+func ledgerBalance(l Ledger) int { return l.ledgerEntries }
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.go"), []byte(src), 0o644))
+
+	glossary, err := (&GlossaryExtractor{Root: dir}).Extract(context.Background())
+	assert.NoError(t, err)
+
+	var ledger, lsp *GlossaryTerm
+	for i := range glossary.Terms {
+		switch glossary.Terms[i].Term {
+		case "ledger":
+			ledger = &glossary.Terms[i]
+		case "LSP":
+			lsp = &glossary.Terms[i]
+		}
+	}
+	if assert.NotNil(t, ledger) {
+		assert.Equal(t, []string{"demo.go"}, ledger.Files)
+	}
+	if assert.NotNil(t, lsp) {
+		assert.Equal(t, "Language Server Protocol", lsp.Expansion)
+	}
+	if assert.NotEmpty(t, glossary.Conventions) {
+		assert.Equal(t, "Tool", glossary.Conventions[0].Suffix)
+	}
+}
+
+func TestGlossaryRoundTripsThroughMemory(t *testing.T) {
+	store, err := NewHybridMemory(t.TempDir())
+	assert.NoError(t, err)
+	glossary := &Glossary{Terms: []GlossaryTerm{{Term: "LSP", Kind: GlossaryKindAbbreviation, Expansion: "Language Server Protocol", Occurrences: 4}}}
+	assert.NoError(t, SaveGlossary(context.Background(), store, glossary))
+
+	loaded, err := LoadGlossary(context.Background(), store)
+	assert.NoError(t, err)
+	section := loaded.PromptSection("Restart the LSP client", 5)
+	assert.True(t, strings.Contains(section, "LSP = Language Server Protocol"))
+	assert.Empty(t, loaded.PromptSection("Rename the parser", 5))
+}
@@ -31,12 +31,14 @@ type Node interface {
 // ConditionFunc determines whether an edge should be followed.
 type ConditionFunc func(result *Result, state *Context) bool
 
-// Edge describes a transition between nodes.
+// Edge describes a transition between nodes. Label describes the condition
+// for exports, since the condition itself is opaque code.
 type Edge struct {
 	From      string
 	To        string
 	Condition ConditionFunc
 	Parallel  bool
+	Label     string
 }
 
 // Graph orchestrates a workflow of nodes. It behaves like a tiny, deterministic
@@ -56,6 +58,7 @@ type Graph struct {
 	checkpointInterval int
 	checkpointCallback CheckpointCallback
 	lastCheckpointNode string
+	timeouts           GraphTimeouts
 }
 
 // CheckpointCallback receives checkpoints generated during execution.
@@ -81,6 +84,15 @@ func (g *Graph) WithCheckpointing(interval int, callback CheckpointCallback) *Gr
 	return g
 }
 
+// WithTimeouts bounds the whole execution and each node. Graphs without
+// timeouts fall back to any attached with WithGraphTimeouts.
+func (g *Graph) WithTimeouts(timeouts GraphTimeouts) *Graph {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timeouts = timeouts
+	return g
+}
+
 // SetTelemetry wires a telemetry sink for execution traces.
 func (g *Graph) SetTelemetry(t Telemetry) {
 	g.mu.Lock()
@@ -152,6 +164,24 @@ func (g *Graph) AddEdge(from, to string, condition ConditionFunc, parallel bool)
 	return nil
 }
 
+// LabelEdge names the condition on the edges from one node to another, so
+// exported graphs read "react.done" instead of an anonymous condition.
+func (g *Graph) LabelEdge(from, to, label string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	found := false
+	for i, edge := range g.edges[from] {
+		if edge.To == to {
+			g.edges[from][i].Label = label
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no edge from %s to %s", from, to)
+	}
+	return nil
+}
+
 // GraphSnapshot stores enough state to resume an execution.
 type GraphSnapshot struct {
 	NodeID string
@@ -180,7 +210,9 @@ func (g *Graph) ExecuteFromSnapshot(ctx context.Context, state *Context, snapsho
 	var execErr error
 	defer func() {
 		status := "success"
-		if execErr != nil {
+		if IsTimeout(execErr) {
+			status = ResultStatusTimedOut
+		} else if execErr != nil {
 			status = "error"
 		}
 		g.emit(Event{
@@ -222,10 +254,26 @@ func (g *Graph) run(ctx context.Context, state *Context, current string, reset b
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
+	timeouts := g.timeouts
+	if timeouts.IsZero() {
+		timeouts, _ = GraphTimeoutsFromContext(ctx)
+	}
+	if timeouts.Graph > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeouts.Graph)
+		defer cancel()
+	}
+
 	var lastResult *Result
 	for current != "" {
 		select {
 		case <-ctx.Done():
+			if exceeded := BudgetExceeded(ctx); exceeded != nil {
+				return g.budgetExceeded(state, lastResult, exceeded, current, g.executionPath, taskID)
+			}
+			if timeouts.Graph > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return g.timedOut(state, lastResult, &TimeoutError{Scope: TimeoutScopeGraph, NodeID: current, Timeout: timeouts.Graph, Completed: g.executionPath}, taskID)
+			}
 			return nil, ctx.Err()
 		default:
 		}
@@ -246,9 +294,20 @@ func (g *Graph) run(ctx context.Context, state *Context, current string, reset b
 		})
 		taskType := TaskType(fmt.Sprint(taskMetaValue(state, "task.type")))
 		instruction := fmt.Sprint(taskMetaValue(state, "task.instruction"))
-		nodeCtx := WithTaskContext(ctx, TaskContext{ID: taskID, Type: taskType, Instruction: instruction})
-		result, err := node.Execute(nodeCtx, state)
+		agentName := ""
+		if v := taskMetaValue(state, "task.agent"); v != nil {
+			agentName = fmt.Sprint(v)
+		}
+		nodeCtx := WithTaskContext(ctx, TaskContext{ID: taskID, Type: taskType, Instruction: instruction, NodeID: current, Agent: agentName})
+		result, err := executeNode(nodeCtx, node, state, timeouts.ForNode(current))
 		if err != nil {
+			if exceeded := BudgetExceeded(ctx); exceeded != nil {
+				return g.budgetExceeded(state, lastResult, exceeded, current, g.executionPath[:len(g.executionPath)-1], taskID)
+			}
+			if timeoutErr := nodeTimeout(ctx, err, current, timeouts); timeoutErr != nil {
+				timeoutErr.Completed = g.executionPath[:len(g.executionPath)-1]
+				return g.timedOut(state, lastResult, timeoutErr, taskID)
+			}
 			err = fmt.Errorf("node %s execution failed: %w", current, err)
 			g.emit(Event{
 				Type:      EventNodeError,
@@ -286,6 +345,52 @@ func (g *Graph) run(ctx context.Context, state *Context, current string, reset b
 	return lastResult, nil
 }
 
+// nodeTimeout classifies a node failure caused by one of the graph's own
+// deadlines. Deadlines inherited from the caller are left as plain errors.
+func nodeTimeout(graphCtx context.Context, err error, nodeID string, timeouts GraphTimeouts) *TimeoutError {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	if timeouts.Graph > 0 && errors.Is(graphCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Scope: TimeoutScopeGraph, NodeID: nodeID, Timeout: timeouts.Graph}
+	}
+	if limit := timeouts.ForNode(nodeID); limit > 0 {
+		return &TimeoutError{Scope: TimeoutScopeNode, NodeID: nodeID, Timeout: limit}
+	}
+	return nil
+}
+
+// timedOut records the partial run in state (graph.status,
+// graph.timed_out_node, graph.completed_nodes) so callers that only keep the
+// context can still persist it, and returns a timed_out result carrying the
+// last completed node's data.
+func (g *Graph) timedOut(state *Context, lastResult *Result, timeoutErr *TimeoutError, taskID string) (*Result, error) {
+	completed := append([]string{}, timeoutErr.Completed...)
+	timeoutErr.Completed = completed
+	g.emit(Event{
+		Type:      EventNodeError,
+		NodeID:    timeoutErr.NodeID,
+		TaskID:    taskID,
+		Timestamp: time.Now().UTC(),
+		Message:   timeoutErr.Error(),
+		Metadata:  map[string]interface{}{"status": ResultStatusTimedOut},
+	})
+	data := map[string]interface{}{
+		"status":          ResultStatusTimedOut,
+		"timed_out_node":  timeoutErr.NodeID,
+		"completed_nodes": completed,
+	}
+	if lastResult != nil {
+		data["partial"] = lastResult.Data
+	}
+	if state != nil {
+		state.Set("graph.status", ResultStatusTimedOut)
+		state.Set("graph.timed_out_node", timeoutErr.NodeID)
+		state.Set("graph.completed_nodes", completed)
+	}
+	return &Result{NodeID: timeoutErr.NodeID, Success: false, Data: data}, timeoutErr
+}
+
 func taskMetaValue(state *Context, key string) interface{} {
 	if state == nil {
 		return nil
@@ -430,6 +535,7 @@ func (g *Graph) executeBranch(ctx context.Context, start string, state *Context)
 		startNodeID:   start,
 		maxNodeVisits: g.maxNodeVisits,
 		telemetry:     g.telemetry,
+		timeouts:      g.timeouts,
 	}
 	return subGraph.Execute(ctx, state)
 }
@@ -474,6 +580,9 @@ type LLMOptions struct {
 	Stop        []string
 	TopP        float64
 	Stream      bool
+	// Schema, when set, constrains the response to JSON matching this JSON
+	// schema. Ollama receives it as the format parameter.
+	Schema map[string]interface{}
 }
 
 // ToolCall encodes a function invocation requested by the LLM.
@@ -0,0 +1,195 @@
+package framework
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GraphFormat names a text format Graph.Export can render.
+type GraphFormat string
+
+const (
+	GraphFormatDOT     GraphFormat = "dot"
+	GraphFormatMermaid GraphFormat = "mermaid"
+)
+
+// exportEdge is an edge prepared for rendering.
+type exportEdge struct {
+	Edge
+	loop bool
+}
+
+// Export renders the graph's nodes and edges as Graphviz DOT or a Mermaid
+// flowchart. Conditional edges are dashed and carry their label (or
+// "condition" when unlabeled), parallel edges are marked, and edges that loop
+// back to a node already on the path from the start are flagged as loops.
+// Output is deterministic so it can be checked into documentation.
+func (g *Graph) Export(format GraphFormat) (string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ids := make([]string, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	edges := g.exportEdges(ids)
+	switch format {
+	case GraphFormatDOT:
+		return g.exportDOT(ids, edges), nil
+	case GraphFormatMermaid:
+		return g.exportMermaid(ids, edges), nil
+	default:
+		return "", fmt.Errorf("unknown graph format %q (want dot or mermaid)", format)
+	}
+}
+
+// exportEdges lists edges in node order and marks the back edges found by a
+// depth-first walk from the start node.
+func (g *Graph) exportEdges(ids []string) []exportEdge {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int, len(ids))
+	loops := make(map[string]map[int]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onPath
+		for i, edge := range g.edges[id] {
+			switch state[edge.To] {
+			case onPath:
+				if loops[id] == nil {
+					loops[id] = make(map[int]bool)
+				}
+				loops[id][i] = true
+			case unvisited:
+				visit(edge.To)
+			}
+		}
+		state[id] = done
+	}
+	if g.startNodeID != "" {
+		visit(g.startNodeID)
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	var edges []exportEdge
+	for _, id := range ids {
+		for i, edge := range g.edges[id] {
+			edges = append(edges, exportEdge{Edge: edge, loop: loops[id][i]})
+		}
+	}
+	return edges
+}
+
+// edgeLabel describes why an edge is taken, or "" for unconditional ones.
+func edgeLabel(edge exportEdge) string {
+	var parts []string
+	if edge.loop {
+		parts = append(parts, "loop")
+	}
+	if edge.Parallel {
+		parts = append(parts, "parallel")
+	}
+	switch {
+	case edge.Label != "":
+		parts = append(parts, edge.Label)
+	case edge.Condition != nil:
+		parts = append(parts, "condition")
+	}
+	return strings.Join(parts, ": ")
+}
+
+func (g *Graph) exportDOT(ids []string, edges []exportEdge) string {
+	var b strings.Builder
+	b.WriteString("digraph agent {\n")
+	b.WriteString("  rankdir=TB;\n")
+	for _, id := range ids {
+		node := g.nodes[id]
+		attrs := []string{
+			fmt.Sprintf("label=%q", fmt.Sprintf("%s\n(%s)", id, node.Type())),
+			"shape=" + dotShape(node.Type()),
+		}
+		if id == g.startNodeID {
+			attrs = append(attrs, "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", id, strings.Join(attrs, ", "))
+	}
+	for _, edge := range edges {
+		var attrs []string
+		if label := edgeLabel(edge); label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", label))
+		}
+		if edge.Condition != nil {
+			attrs = append(attrs, "style=dashed")
+		}
+		if edge.loop {
+			attrs = append(attrs, "constraint=false")
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+			continue
+		}
+		fmt.Fprintf(&b, "  %q -> %q [%s];\n", edge.From, edge.To, strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotShape(kind NodeType) string {
+	switch kind {
+	case NodeTypeConditional:
+		return "diamond"
+	case NodeTypeHuman:
+		return "parallelogram"
+	case NodeTypeTerminal:
+		return "doublecircle"
+	default:
+		return "box"
+	}
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func (g *Graph) exportMermaid(ids []string, edges []exportEdge) string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, id := range ids {
+		label := fmt.Sprintf("%q", fmt.Sprintf("%s (%s)", id, g.nodes[id].Type()))
+		var shape string
+		switch g.nodes[id].Type() {
+		case NodeTypeConditional:
+			shape = "{" + label + "}"
+		case NodeTypeHuman:
+			shape = "[/" + label + "/]"
+		case NodeTypeTerminal:
+			shape = "((" + label + "))"
+		default:
+			shape = "[" + label + "]"
+		}
+		fmt.Fprintf(&b, "  %s%s\n", mermaidUnsafe.ReplaceAllString(id, "_"), shape)
+	}
+	for _, edge := range edges {
+		from := mermaidUnsafe.ReplaceAllString(edge.From, "_")
+		to := mermaidUnsafe.ReplaceAllString(edge.To, "_")
+		arrow := "-->"
+		if edge.Condition != nil {
+			arrow = "-.->"
+		}
+		if label := edgeLabel(edge); label != "" {
+			fmt.Fprintf(&b, "  %s %s|%q| %s\n", from, arrow, label, to)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", from, arrow, to)
+	}
+	if g.startNodeID != "" {
+		fmt.Fprintf(&b, "  style %s stroke-width:3px\n", mermaidUnsafe.ReplaceAllString(g.startNodeID, "_"))
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+)
+
+// buildLoopGraph wires think → act → check, with check looping back to think
+// until done.
+func buildLoopGraph(t *testing.T) *Graph {
+	t.Helper()
+	graph := NewGraph()
+	for _, node := range []Node{
+		testNode{id: "think"},
+		testNode{id: "act"},
+		testNode{id: "check", kind: NodeTypeConditional},
+		NewTerminalNode("done"),
+	} {
+		if err := graph.AddNode(node); err != nil {
+			t.Fatalf("add node: %v", err)
+		}
+	}
+	if err := graph.SetStart("think"); err != nil {
+		t.Fatalf("set start: %v", err)
+	}
+	never := func(*Result, *Context) bool { return false }
+	for _, edge := range []Edge{
+		{From: "think", To: "act"},
+		{From: "act", To: "check"},
+		{From: "check", To: "think", Condition: never},
+		{From: "check", To: "done", Condition: never},
+	} {
+		if err := graph.AddEdge(edge.From, edge.To, edge.Condition, false); err != nil {
+			t.Fatalf("add edge: %v", err)
+		}
+	}
+	if err := graph.LabelEdge("check", "done", "finished"); err != nil {
+		t.Fatalf("label edge: %v", err)
+	}
+	return graph
+}
+
+// TestGraphExportMermaid checks node shapes, labels, and loop detection.
+func TestGraphExportMermaid(t *testing.T) {
+	out, err := buildLoopGraph(t).Export(GraphFormatMermaid)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	for _, want := range []string{
+		"flowchart TD\n",
+		`check{"check (conditional)"}`,
+		`done(("done (terminal)"))`,
+		"think --> act\n",
+		`check -.->|"loop: condition"| think`,
+		`check -.->|"finished"| done`,
+		"style think stroke-width:3px",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestGraphExportDOT checks edge attributes and rejects unknown formats.
+func TestGraphExportDOT(t *testing.T) {
+	graph := buildLoopGraph(t)
+	out, err := graph.Export(GraphFormatDOT)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	for _, want := range []string{
+		"digraph agent {",
+		`"think" [label="think\n(tool)", shape=box, penwidth=2];`,
+		`"check" -> "think" [label="loop: condition", style=dashed, constraint=false];`,
+		`"check" -> "done" [label="finished", style=dashed];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+	if _, err := graph.Export("svg"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if err := graph.LabelEdge("think", "done", "x"); err == nil {
+		t.Fatal("expected error labeling a missing edge")
+	}
+}
@@ -0,0 +1,444 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// GraphNodeKind names the node types a declarative graph can use.
+type GraphNodeKind string
+
+const (
+	GraphNodeLLM       GraphNodeKind = "llm-prompt"
+	GraphNodeTool      GraphNodeKind = "tool-call"
+	GraphNodeCondition GraphNodeKind = "condition"
+	GraphNodeHuman     GraphNodeKind = "human"
+	GraphNodeTerminal  GraphNodeKind = "terminal"
+)
+
+// GraphSpec declares an agent workflow in YAML instead of Go:
+//
+//	graph:
+//	  start: draft
+//	  nodes:
+//	    - id: draft
+//	      type: llm-prompt
+//	      prompt: "Write a changelog entry for: {{.Instruction}}"
+//	    - id: approve
+//	      type: human
+//	      prompt: "Publish this entry?\n{{state \"draft.output\"}}"
+//	    - id: publish
+//	      type: tool-call
+//	      tool: file_write
+//	      args: {path: CHANGELOG.md, content: "{{state \"draft.output\"}}"}
+//	    - id: done
+//	      type: terminal
+//	  edges:
+//	    - {from: draft, to: approve}
+//	    - {from: approve, to: publish, when: approve.approved}
+//	    - {from: approve, to: done, when: "not approve.approved"}
+//	    - {from: publish, to: done}
+//
+// Prompts and string tool arguments are Go templates over the task; the
+// state function reads context keys. Every node stores what it produced
+// under "<id>.output" (and tool nodes "<id>.success", condition nodes
+// "<id>.result", human nodes "<id>.approved"), which later prompts and edge
+// conditions can refer to. Edge conditions are "key", "not key",
+// "key == value", "key != value", or "key contains value".
+type GraphSpec struct {
+	Start string          `yaml:"start" json:"start"`
+	Nodes []GraphNodeSpec `yaml:"nodes" json:"nodes"`
+	Edges []GraphEdgeSpec `yaml:"edges" json:"edges"`
+}
+
+// GraphNodeSpec declares one node. Prompt applies to llm-prompt and human
+// nodes, Tool and Args to tool-call nodes, and Expr to condition nodes.
+type GraphNodeSpec struct {
+	ID     string         `yaml:"id" json:"id"`
+	Type   GraphNodeKind  `yaml:"type" json:"type"`
+	Prompt string         `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Tool   string         `yaml:"tool,omitempty" json:"tool,omitempty"`
+	Args   map[string]any `yaml:"args,omitempty" json:"args,omitempty"`
+	Expr   string         `yaml:"expr,omitempty" json:"expr,omitempty"`
+}
+
+// GraphEdgeSpec declares a transition, taken only when When holds if set.
+type GraphEdgeSpec struct {
+	From     string `yaml:"from" json:"from"`
+	To       string `yaml:"to" json:"to"`
+	When     string `yaml:"when,omitempty" json:"when,omitempty"`
+	Parallel bool   `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+}
+
+// Validate checks node types, references, templates, and conditions without
+// needing a model or tools.
+func (s *GraphSpec) Validate() error {
+	if s == nil {
+		return errors.New("graph spec missing")
+	}
+	if len(s.Nodes) == 0 {
+		return errors.New("graph has no nodes")
+	}
+	ids := make(map[string]bool, len(s.Nodes))
+	for _, node := range s.Nodes {
+		if strings.TrimSpace(node.ID) == "" {
+			return errors.New("graph node missing id")
+		}
+		if ids[node.ID] {
+			return fmt.Errorf("graph node %s declared twice", node.ID)
+		}
+		ids[node.ID] = true
+		switch node.Type {
+		case GraphNodeLLM, GraphNodeHuman:
+			if strings.TrimSpace(node.Prompt) == "" {
+				return fmt.Errorf("graph node %s: prompt required", node.ID)
+			}
+			if _, err := parseGraphTemplate(node.ID, node.Prompt); err != nil {
+				return err
+			}
+		case GraphNodeTool:
+			if node.Tool == "" {
+				return fmt.Errorf("graph node %s: tool required", node.ID)
+			}
+		case GraphNodeCondition:
+			if _, err := parseGraphCondition(node.Expr); err != nil {
+				return fmt.Errorf("graph node %s: %w", node.ID, err)
+			}
+		case GraphNodeTerminal:
+		default:
+			return fmt.Errorf("graph node %s: unknown type %q", node.ID, node.Type)
+		}
+	}
+	if s.Start == "" {
+		return errors.New("graph start node required")
+	}
+	if !ids[s.Start] {
+		return fmt.Errorf("graph start node %s not declared", s.Start)
+	}
+	for _, edge := range s.Edges {
+		if !ids[edge.From] || !ids[edge.To] {
+			return fmt.Errorf("graph edge %s -> %s references an undeclared node", edge.From, edge.To)
+		}
+		if edge.When != "" {
+			if _, err := parseGraphCondition(edge.When); err != nil {
+				return fmt.Errorf("graph edge %s -> %s: %w", edge.From, edge.To, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GraphLoader turns a GraphSpec into an executable Graph. Model answers
+// llm-prompt nodes, Tools resolves tool-call nodes when they run, and HITL
+// asks a person at human nodes; a loader without HITL fails human nodes.
+type GraphLoader struct {
+	Model   LanguageModel
+	Tools   *ToolRegistry
+	HITL    HITLProvider
+	Options *LLMOptions
+}
+
+// Load validates spec and builds its graph for task.
+func (l *GraphLoader) Load(spec *GraphSpec, task *Task) (*Graph, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	graph := NewGraph()
+	for _, ns := range spec.Nodes {
+		node, err := l.buildNode(ns, task)
+		if err != nil {
+			return nil, err
+		}
+		if err := graph.AddNode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := graph.SetStart(spec.Start); err != nil {
+		return nil, err
+	}
+	for _, es := range spec.Edges {
+		var condition ConditionFunc
+		if es.When != "" {
+			cond, _ := parseGraphCondition(es.When)
+			condition = func(_ *Result, state *Context) bool { return cond.eval(state) }
+		}
+		if err := graph.AddEdge(es.From, es.To, condition, es.Parallel); err != nil {
+			return nil, err
+		}
+		if es.When != "" {
+			if err := graph.LabelEdge(es.From, es.To, es.When); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return graph, nil
+}
+
+func (l *GraphLoader) buildNode(ns GraphNodeSpec, task *Task) (Node, error) {
+	switch ns.Type {
+	case GraphNodeLLM:
+		if l.Model == nil {
+			return nil, fmt.Errorf("graph node %s: no language model", ns.ID)
+		}
+		tmpl, _ := parseGraphTemplate(ns.ID, ns.Prompt)
+		return &graphPromptNode{id: ns.ID, model: l.Model, options: l.Options, prompt: tmpl, task: task}, nil
+	case GraphNodeTool:
+		return &graphToolNode{id: ns.ID, tools: l.Tools, tool: ns.Tool, args: ns.Args, task: task}, nil
+	case GraphNodeCondition:
+		cond, _ := parseGraphCondition(ns.Expr)
+		return &graphConditionNode{id: ns.ID, cond: cond}, nil
+	case GraphNodeHuman:
+		tmpl, _ := parseGraphTemplate(ns.ID, ns.Prompt)
+		return &graphHumanNode{id: ns.ID, hitl: l.HITL, prompt: tmpl, task: task}, nil
+	default:
+		return NewTerminalNode(ns.ID), nil
+	}
+}
+
+// graphTemplateData is what node templates see as ".".
+type graphTemplateData struct {
+	Instruction string
+	Task        *Task
+}
+
+func parseGraphTemplate(id, text string) (*template.Template, error) {
+	tmpl, err := template.New(id).Option("missingkey=zero").Funcs(template.FuncMap{
+		"state": func(string) string { return "" },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("graph node %s: %w", id, err)
+	}
+	return tmpl, nil
+}
+
+// renderGraphTemplate executes tmpl with the state function bound to state.
+func renderGraphTemplate(tmpl *template.Template, task *Task, state *Context) (string, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	clone.Funcs(template.FuncMap{
+		"state": func(key string) string { return graphStateString(state, key) },
+	})
+	data := graphTemplateData{Task: task}
+	if task != nil {
+		data.Instruction = task.Instruction
+	}
+	var b strings.Builder
+	if err := clone.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func graphStateString(state *Context, key string) string {
+	value, ok := state.Get(key)
+	if !ok || value == nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// setGraphOutput records a node's output, which also becomes the agent's
+// final output unless a later node produces one.
+func setGraphOutput(state *Context, id string, output any) {
+	state.Set(id+".output", output)
+	state.Set("graph.final_output", output)
+}
+
+type graphPromptNode struct {
+	id      string
+	model   LanguageModel
+	options *LLMOptions
+	prompt  *template.Template
+	task    *Task
+}
+
+func (n *graphPromptNode) ID() string     { return n.id }
+func (n *graphPromptNode) Type() NodeType { return NodeTypeLLM }
+
+func (n *graphPromptNode) Execute(ctx context.Context, state *Context) (*Result, error) {
+	prompt, err := renderGraphTemplate(n.prompt, n.task, state)
+	if err != nil {
+		return nil, fmt.Errorf("graph node %s: %w", n.id, err)
+	}
+	resp, err := n.model.Generate(ctx, prompt, n.options)
+	if err != nil {
+		return nil, err
+	}
+	state.AddInteraction("assistant", resp.Text, map[string]interface{}{"node": n.id})
+	setGraphOutput(state, n.id, resp.Text)
+	return &Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"text": resp.Text}}, nil
+}
+
+type graphToolNode struct {
+	id    string
+	tools *ToolRegistry
+	tool  string
+	args  map[string]any
+	task  *Task
+}
+
+func (n *graphToolNode) ID() string     { return n.id }
+func (n *graphToolNode) Type() NodeType { return NodeTypeTool }
+
+// Execute renders string arguments as templates and runs the tool. A failed
+// tool run is recorded rather than returned, so edges can branch on
+// "<id>.success".
+func (n *graphToolNode) Execute(ctx context.Context, state *Context) (*Result, error) {
+	args := make(map[string]interface{}, len(n.args))
+	for key, value := range n.args {
+		if text, ok := value.(string); ok {
+			tmpl, err := parseGraphTemplate(n.id, text)
+			if err != nil {
+				return nil, err
+			}
+			if value, err = renderGraphTemplate(tmpl, n.task, state); err != nil {
+				return nil, fmt.Errorf("graph node %s: arg %s: %w", n.id, key, err)
+			}
+		}
+		args[key] = value
+	}
+	var tool Tool
+	if n.tools != nil {
+		tool, _ = n.tools.Get(n.tool)
+	}
+	if tool == nil || !tool.IsAvailable(ctx, state) {
+		return nil, fmt.Errorf("graph node %s: tool %s unavailable", n.id, n.tool)
+	}
+	res, err := tool.Execute(ctx, state, args)
+	if err != nil {
+		return nil, err
+	}
+	state.Set(n.id+".success", res.Success)
+	if res.Error != "" {
+		state.Set(n.id+".error", res.Error)
+	}
+	setGraphOutput(state, n.id, res.Data)
+	return &Result{NodeID: n.id, Success: res.Success, Data: res.Data}, nil
+}
+
+type graphConditionNode struct {
+	id   string
+	cond graphCondition
+}
+
+func (n *graphConditionNode) ID() string     { return n.id }
+func (n *graphConditionNode) Type() NodeType { return NodeTypeConditional }
+
+func (n *graphConditionNode) Execute(ctx context.Context, state *Context) (*Result, error) {
+	result := n.cond.eval(state)
+	state.Set(n.id+".result", result)
+	return &Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"result": result}}, nil
+}
+
+type graphHumanNode struct {
+	id     string
+	hitl   HITLProvider
+	prompt *template.Template
+	task   *Task
+}
+
+func (n *graphHumanNode) ID() string     { return n.id }
+func (n *graphHumanNode) Type() NodeType { return NodeTypeHuman }
+
+// Execute asks for approval through HITL. A denial is recorded as
+// "<id>.approved" = false for edges to branch on; cancellation aborts.
+func (n *graphHumanNode) Execute(ctx context.Context, state *Context) (*Result, error) {
+	if n.hitl == nil {
+		return nil, fmt.Errorf("graph node %s: no approver available for human node", n.id)
+	}
+	prompt, err := renderGraphTemplate(n.prompt, n.task, state)
+	if err != nil {
+		return nil, fmt.Errorf("graph node %s: %w", n.id, err)
+	}
+	_, err = n.hitl.RequestPermission(ctx, PermissionRequest{
+		Permission: PermissionDescriptor{
+			Type:         PermissionTypeHITL,
+			Action:       "graph:approve",
+			Resource:     n.id,
+			RequiresHITL: true,
+		},
+		Justification: prompt,
+		Scope:         GrantScopeOneTime,
+		Risk:          RiskLevelMedium,
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	approved := err == nil
+	state.Set(n.id+".approved", approved)
+	if err != nil {
+		state.Set(n.id+".output", err.Error())
+	}
+	return &Result{NodeID: n.id, Success: true, Data: map[string]interface{}{"approved": approved}}, nil
+}
+
+// graphCondition is a parsed edge or condition-node expression.
+type graphCondition struct {
+	key    string
+	op     string
+	value  string
+	negate bool
+}
+
+// parseGraphCondition accepts "key", "not key", "!key", and
+// "key <op> value" with op ==, !=, or contains.
+func parseGraphCondition(expr string) (graphCondition, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return graphCondition{}, errors.New("empty condition")
+	}
+	for _, op := range []string{"==", "!=", " contains "} {
+		if key, value, ok := strings.Cut(expr, op); ok {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if key == "" || value == "" {
+				return graphCondition{}, fmt.Errorf("condition %q needs a key and a value", expr)
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			return graphCondition{key: key, op: strings.TrimSpace(op), value: value}, nil
+		}
+	}
+	cond := graphCondition{key: expr}
+	if rest, ok := strings.CutPrefix(expr, "not "); ok {
+		cond = graphCondition{key: strings.TrimSpace(rest), negate: true}
+	} else if rest, ok := strings.CutPrefix(expr, "!"); ok {
+		cond = graphCondition{key: strings.TrimSpace(rest), negate: true}
+	}
+	if cond.key == "" || strings.ContainsAny(cond.key, " \t") {
+		return graphCondition{}, fmt.Errorf("invalid condition %q", expr)
+	}
+	return cond, nil
+}
+
+func (c graphCondition) eval(state *Context) bool {
+	actual := graphStateString(state, c.key)
+	switch c.op {
+	case "==":
+		return actual == c.value
+	case "!=":
+		return actual != c.value
+	case "contains":
+		return strings.Contains(actual, c.value)
+	}
+	truthy := actual != "" && actual != "false" && actual != "0"
+	return truthy != c.negate
+}
@@ -0,0 +1,109 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestGraphSpecValidate checks declared graphs reject bad references, node
+// types, templates, and conditions.
+func TestGraphSpecValidate(t *testing.T) {
+	valid := func() *GraphSpec {
+		return &GraphSpec{
+			Start: "ask",
+			Nodes: []GraphNodeSpec{
+				{ID: "ask", Type: GraphNodeLLM, Prompt: "{{.Instruction}}"},
+				{ID: "done", Type: GraphNodeTerminal},
+			},
+			Edges: []GraphEdgeSpec{{From: "ask", To: "done", When: "ask.output contains yes"}},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected valid spec, got %v", err)
+	}
+	cases := map[string]func(*GraphSpec){
+		"missing start":     func(s *GraphSpec) { s.Start = "nope" },
+		"unknown type":      func(s *GraphSpec) { s.Nodes[1].Type = "loop" },
+		"duplicate node":    func(s *GraphSpec) { s.Nodes[1].ID = "ask" },
+		"bad template":      func(s *GraphSpec) { s.Nodes[0].Prompt = "{{.Instruction" },
+		"missing prompt":    func(s *GraphSpec) { s.Nodes[0].Prompt = "" },
+		"dangling edge":     func(s *GraphSpec) { s.Edges[0].To = "gone" },
+		"bad condition":     func(s *GraphSpec) { s.Edges[0].When = "two words" },
+		"tool without name": func(s *GraphSpec) { s.Nodes[1] = GraphNodeSpec{ID: "done", Type: GraphNodeTool} },
+	}
+	for name, mutate := range cases {
+		spec := valid()
+		mutate(spec)
+		if err := spec.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+// TestGraphConditionEval covers each supported condition form.
+func TestGraphConditionEval(t *testing.T) {
+	state := NewContext()
+	state.Set("check.result", true)
+	state.Set("review.verdict", "approved")
+	state.Set("lint.success", false)
+	cases := map[string]bool{
+		"check.result":                  true,
+		"not check.result":              false,
+		"!lint.success":                 true,
+		"missing.key":                   false,
+		"review.verdict == approved":    true,
+		`review.verdict == "approved"`:  true,
+		"review.verdict != approved":    false,
+		"review.verdict contains prove": true,
+	}
+	for expr, want := range cases {
+		cond, err := parseGraphCondition(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if got := cond.eval(state); got != want {
+			t.Fatalf("%s: expected %v, got %v", expr, want, got)
+		}
+	}
+}
+
+// TestGraphLoaderLoadsYAML checks a YAML definition becomes a graph whose
+// conditional edges carry their expressions as labels.
+func TestGraphLoaderLoadsYAML(t *testing.T) {
+	var spec GraphSpec
+	err := yaml.Unmarshal([]byte(`
+start: ask
+nodes:
+  - {id: ask, type: llm-prompt, prompt: "{{.Instruction}}"}
+  - {id: gate, type: condition, expr: ask.output contains yes}
+  - {id: done, type: terminal}
+edges:
+  - {from: ask, to: gate}
+  - {from: gate, to: ask, when: not gate.result}
+  - {from: gate, to: done, when: gate.result}
+`), &spec)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	graph, err := (&GraphLoader{Model: &stubLLM{text: "yes"}}).Load(&spec, &Task{Instruction: "go?"})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	out, err := graph.Export(GraphFormatMermaid)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if !strings.Contains(out, `gate -.->|"loop: not gate.result"| ask`) {
+		t.Fatalf("expected labelled loop edge in:\n%s", out)
+	}
+	state := NewContext()
+	result, err := graph.Execute(t.Context(), state)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if result.NodeID != "done" {
+		t.Fatalf("expected to finish at done, got %s", result.NodeID)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 )
 
 type testNode struct {
@@ -193,3 +194,121 @@ func TestGraphNodeError(t *testing.T) {
 		t.Fatalf("expected error from err node")
 	}
 }
+
+// buildTimeoutGraph wires fast -> slow -> done, where slow blocks until its
+// context ends or release is closed. The slow node ignores ctx when ignoreCtx
+// is set, mimicking a hung tool call.
+func buildTimeoutGraph(t *testing.T, ignoreCtx bool, release chan struct{}) *Graph {
+	t.Helper()
+	graph := NewGraph()
+	fast := testNode{id: "fast", run: func(ctx context.Context, state *Context) (*Result, error) {
+		return &Result{Success: true, Data: map[string]interface{}{"files": 3}}, nil
+	}}
+	slow := testNode{id: "slow", run: func(ctx context.Context, state *Context) (*Result, error) {
+		if ignoreCtx {
+			<-release
+			return &Result{Success: true}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return &Result{Success: true}, nil
+		}
+	}}
+	for _, node := range []Node{fast, slow, testNode{id: "done", kind: NodeTypeTerminal}} {
+		if err := graph.AddNode(node); err != nil {
+			t.Fatalf("add node: %v", err)
+		}
+	}
+	if err := graph.SetStart("fast"); err != nil {
+		t.Fatalf("set start: %v", err)
+	}
+	if err := graph.AddEdge("fast", "slow", nil, false); err != nil {
+		t.Fatalf("edge fast->slow: %v", err)
+	}
+	if err := graph.AddEdge("slow", "done", nil, false); err != nil {
+		t.Fatalf("edge slow->done: %v", err)
+	}
+	return graph
+}
+
+// TestGraphNodeTimeout ensures a per-node deadline stops a node that ignores
+// cancellation and reports the partial run.
+func TestGraphNodeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	graph := buildTimeoutGraph(t, true, release)
+	graph.WithTimeouts(GraphTimeouts{Nodes: map[string]time.Duration{"slow": 20 * time.Millisecond}})
+	state := NewContext()
+
+	result, err := graph.Execute(context.Background(), state)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if timeoutErr.Scope != TimeoutScopeNode || timeoutErr.NodeID != "slow" {
+		t.Fatalf("unexpected timeout %+v", timeoutErr)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timeouts should unwrap to context.DeadlineExceeded")
+	}
+	if result == nil || result.Data["status"] != ResultStatusTimedOut {
+		t.Fatalf("expected timed_out result, got %+v", result)
+	}
+	partial, _ := result.Data["partial"].(map[string]interface{})
+	if partial["files"] != 3 {
+		t.Fatalf("expected partial data from fast node, got %+v", result.Data)
+	}
+	if state.GetString("graph.status") != ResultStatusTimedOut {
+		t.Fatalf("expected graph.status recorded in state")
+	}
+	if got := timeoutErr.Completed; len(got) != 1 || got[0] != "fast" {
+		t.Fatalf("expected completed [fast], got %v", got)
+	}
+}
+
+// TestGraphTimeoutFromContext ensures graphs pick up timeouts attached to the
+// context and report graph-scoped expiry.
+func TestGraphTimeoutFromContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	graph := buildTimeoutGraph(t, false, release)
+	ctx := WithGraphTimeouts(context.Background(), GraphTimeouts{Graph: 20 * time.Millisecond})
+
+	_, err := graph.Execute(ctx, NewContext())
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Scope != TimeoutScopeGraph {
+		t.Fatalf("expected graph timeout, got %v", err)
+	}
+}
+
+// TestGraphCallerDeadlineIsNotTimedOut keeps deadlines owned by the caller as
+// plain errors.
+func TestGraphCallerDeadlineIsNotTimedOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	graph := buildTimeoutGraph(t, false, release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := graph.Execute(ctx, NewContext())
+	if err == nil || IsTimeout(err) {
+		t.Fatalf("expected plain deadline error, got %v", err)
+	}
+}
+
+// TestAgentTimeoutSpecParsing covers manifest duration parsing.
+func TestAgentTimeoutSpecParsing(t *testing.T) {
+	spec := &AgentTimeoutSpec{Graph: "10m", Node: "90s", Nodes: map[string]string{"plan": "3m"}}
+	timeouts, err := spec.GraphTimeouts()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if timeouts.Graph != 10*time.Minute || timeouts.ForNode("plan") != 3*time.Minute || timeouts.ForNode("act") != 90*time.Second {
+		t.Fatalf("unexpected timeouts %+v", timeouts)
+	}
+	if _, err := (&AgentTimeoutSpec{Node: "soon"}).GraphTimeouts(); err == nil {
+		t.Fatalf("expected invalid duration error")
+	}
+}
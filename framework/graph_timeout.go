@@ -0,0 +1,119 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ResultStatusTimedOut marks results and workflows cut short by a graph or
+// node deadline, as distinct from ordinary failures.
+const ResultStatusTimedOut = "timed_out"
+
+// GraphTimeouts bounds graph execution. Zero durations disable a limit; Nodes
+// overrides Node for specific node IDs.
+type GraphTimeouts struct {
+	Graph time.Duration
+	Node  time.Duration
+	Nodes map[string]time.Duration
+}
+
+// IsZero reports whether no limit is configured.
+func (t GraphTimeouts) IsZero() bool {
+	return t.Graph <= 0 && t.Node <= 0 && len(t.Nodes) == 0
+}
+
+// ForNode returns the deadline applied to one node execution.
+func (t GraphTimeouts) ForNode(id string) time.Duration {
+	if d, ok := t.Nodes[id]; ok {
+		return d
+	}
+	return t.Node
+}
+
+type graphTimeoutsKey struct{}
+
+// WithGraphTimeouts attaches timeouts to ctx. Graphs without their own
+// timeouts (see Graph.WithTimeouts) pick them up, which lets a runtime bound
+// every agent without each agent threading config into its graphs.
+func WithGraphTimeouts(ctx context.Context, timeouts GraphTimeouts) context.Context {
+	if timeouts.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, graphTimeoutsKey{}, timeouts)
+}
+
+// GraphTimeoutsFromContext returns timeouts attached by WithGraphTimeouts.
+func GraphTimeoutsFromContext(ctx context.Context) (GraphTimeouts, bool) {
+	if ctx == nil {
+		return GraphTimeouts{}, false
+	}
+	timeouts, ok := ctx.Value(graphTimeoutsKey{}).(GraphTimeouts)
+	return timeouts, ok
+}
+
+// TimeoutScope identifies which deadline expired.
+type TimeoutScope string
+
+const (
+	TimeoutScopeGraph TimeoutScope = "graph"
+	TimeoutScopeNode  TimeoutScope = "node"
+)
+
+// TimeoutError reports an expired graph or node deadline. Completed lists
+// the nodes that finished before it fired.
+type TimeoutError struct {
+	Scope     TimeoutScope
+	NodeID    string
+	Timeout   time.Duration
+	Completed []string
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Scope == TimeoutScopeNode {
+		return fmt.Sprintf("node %s timed out after %s", e.NodeID, e.Timeout)
+	}
+	return fmt.Sprintf("graph timed out after %s at node %s", e.Timeout, e.NodeID)
+}
+
+// Unwrap lets errors.Is(err, context.DeadlineExceeded) keep working.
+func (e *TimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// ErrorKind reports ErrorKindTimeout.
+func (e *TimeoutError) ErrorKind() ErrorKind { return ErrorKindTimeout }
+
+// IsTimeout reports whether err came from a graph or node deadline.
+func IsTimeout(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// executeNode runs node under an optional per-node deadline. When any
+// deadline applies the node runs on its own goroutine so one that ignores
+// ctx cannot hold the graph past its limit; its late result is discarded.
+func executeNode(ctx context.Context, node Node, state *Context, timeout time.Duration) (*Result, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return node.Execute(ctx, state)
+	}
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := node.Execute(ctx, state)
+		done <- outcome{result: result, err: err}
+	}()
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -27,6 +27,10 @@ const (
 	GrantScopeConditional GrantScope = "conditional"
 )
 
+// ErrHITLResolved is returned when approving or denying a request that was
+// already decided.
+var ErrHITLResolved = errors.New("hitl request already resolved")
+
 // PermissionRequest captures a pending permission escalation.
 type PermissionRequest struct {
 	ID            string               `json:"id"`
@@ -198,12 +202,13 @@ func (h *HITLBroker) SubmitAsync(req PermissionRequest) (string, error) {
 	req.RequestedAt = h.clock()
 	req.State = "pending"
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	if _, exists := h.requests[req.ID]; exists {
+		h.mu.Unlock()
 		return "", fmt.Errorf("request %s already registered", req.ID)
 	}
 	h.requests[req.ID] = &req
 	h.waiters[req.ID] = make(chan PermissionDecision, 1)
+	h.mu.Unlock()
 	h.broadcast(HITLEvent{Type: HITLEventRequested, Request: &req})
 	return req.ID, nil
 }
@@ -216,6 +221,9 @@ func (h *HITLBroker) Approve(decision PermissionDecision) error {
 	if !ok {
 		return fmt.Errorf("request %s not found", decision.RequestID)
 	}
+	if req.State != "pending" {
+		return fmt.Errorf("request %s: %w", decision.RequestID, ErrHITLResolved)
+	}
 	req.State = "approved"
 	if decision.Scope == "" {
 		decision.Scope = req.Scope
@@ -241,6 +249,9 @@ func (h *HITLBroker) Deny(requestID, reason string) error {
 	if !ok {
 		return fmt.Errorf("request %s not found", requestID)
 	}
+	if req.State != "pending" {
+		return fmt.Errorf("request %s: %w", requestID, ErrHITLResolved)
+	}
 	req.State = "denied"
 	if waiter, ok := h.waiters[requestID]; ok {
 		waiter <- PermissionDecision{
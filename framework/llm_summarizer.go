@@ -0,0 +1,190 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Defaults for LLMSummarizer knobs left at zero.
+const (
+	DefaultSummaryMaxTokens      = 256
+	DefaultSummaryMaxInputTokens = 4000
+	defaultSummaryTimeout        = 30 * time.Second
+)
+
+// LLMSummarizer writes summaries with a language model, typically a small
+// one. Whenever the model fails or answers with nothing, it falls back to
+// Fallback, so compression keeps working while the model is unavailable.
+type LLMSummarizer struct {
+	Model LanguageModel
+	// Fallback summarizes when the model call fails; nil uses
+	// SimpleSummarizer.
+	Fallback Summarizer
+	// MaxTokens caps the tokens the model may generate per summary; zero
+	// uses DefaultSummaryMaxTokens.
+	MaxTokens int
+	// MaxInputTokens bounds how much of the content the model reads. Larger
+	// values give better summaries of long files at the cost of slower
+	// calls; zero uses DefaultSummaryMaxInputTokens.
+	MaxInputTokens int
+	// Length scales the word targets of each SummaryLevel; zero means 1.
+	Length float64
+	// Timeout bounds one model call; zero means thirty seconds.
+	Timeout time.Duration
+}
+
+// NewLLMSummarizer builds a summarizer for model with default knobs.
+func NewLLMSummarizer(model LanguageModel) *LLMSummarizer {
+	return &LLMSummarizer{Model: model}
+}
+
+// Summarize returns a summary of content sized for level. SummaryFull
+// returns content unchanged.
+func (s *LLMSummarizer) Summarize(content string, level SummaryLevel) (string, error) {
+	if content == "" || level == SummaryFull {
+		return content, nil
+	}
+	if s.Model == nil {
+		return s.fallback().Summarize(content, level)
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultSummaryTimeout
+	}
+	maxTokens := s.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultSummaryMaxTokens
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := s.Model.Generate(ctx, s.prompt(content, level), &LLMOptions{
+		MaxTokens:   maxTokens,
+		Temperature: 0.2,
+	})
+	if err != nil || resp == nil || strings.TrimSpace(resp.Text) == "" {
+		return s.fallback().Summarize(content, level)
+	}
+	return strings.TrimSpace(resp.Text), nil
+}
+
+// SummarizeFile implements Summarizer.
+func (s *LLMSummarizer) SummarizeFile(path string, content string, level SummaryLevel) (*FileSummary, error) {
+	text, err := s.Summarize(content, level)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSummary{
+		Path:       path,
+		Level:      level,
+		Summary:    text,
+		TokenCount: estimateTokens(text),
+	}, nil
+}
+
+// SummarizeDirectory implements Summarizer by summarizing the file
+// summaries together.
+func (s *LLMSummarizer) SummarizeDirectory(path string, files []FileSummary, level SummaryLevel) (*DirectorySummary, error) {
+	chunks := make([]string, 0, len(files))
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Path)
+		if file.Summary != "" {
+			chunks = append(chunks, fmt.Sprintf("%s: %s", file.Path, file.Summary))
+		}
+	}
+	text, err := s.Summarize(strings.Join(chunks, "\n"), level)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectorySummary{
+		Path:       path,
+		Level:      level,
+		Summary:    text,
+		Files:      names,
+		TokenCount: estimateTokens(text),
+	}, nil
+}
+
+// SummarizeChunk implements Summarizer for code chunks.
+func (s *LLMSummarizer) SummarizeChunk(chunk CodeChunk, content string, level SummaryLevel) (*ChunkSummary, error) {
+	text, err := s.Summarize(content, level)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkSummary{
+		ChunkID:    chunk.ID,
+		Level:      level,
+		Summary:    text,
+		TokenCount: estimateTokens(text),
+		Version:    chunk.ID,
+	}, nil
+}
+
+func (s *LLMSummarizer) fallback() Summarizer {
+	if s.Fallback != nil {
+		return s.Fallback
+	}
+	return &SimpleSummarizer{}
+}
+
+func (s *LLMSummarizer) prompt(content string, level SummaryLevel) string {
+	maxInput := s.MaxInputTokens
+	if maxInput <= 0 {
+		maxInput = DefaultSummaryMaxInputTokens
+	}
+	// estimateTokens counts four characters per token.
+	content = truncate(content, maxInput*4)
+	length := s.Length
+	if length <= 0 {
+		length = 1
+	}
+	words := 60
+	switch level {
+	case SummaryDetailed:
+		words = 150
+	case SummaryMinimal:
+		words = 20
+	}
+	words = int(float64(words)*length + 0.5)
+	if words < 5 {
+		words = 5
+	}
+	return fmt.Sprintf(`Summarize the content below in at most %d words for a coding agent that will not see the original.
+Keep file names, identifiers, decisions, errors, and open problems; drop pleasantries and repetition.
+Answer with the summary only.
+
+Content:
+%s`, words, content)
+}
+
+// summaryCompression compresses history with a Summarizer, leaving when and
+// how much to compress to the wrapped strategy.
+type summaryCompression struct {
+	CompressionStrategy
+	summarizer Summarizer
+}
+
+// Compress summarizes interactions with the summarizer; the model argument
+// is ignored in favor of the summarizer's own.
+func (c summaryCompression) Compress(interactions []Interaction, _ LanguageModel) (*CompressedContext, error) {
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("no interactions to compress")
+	}
+	var b strings.Builder
+	for _, interaction := range interactions {
+		fmt.Fprintf(&b, "[%s] %s\n", interaction.Role, interaction.Content)
+	}
+	summary, err := c.summarizer.Summarize(b.String(), SummaryDetailed)
+	if err != nil {
+		return nil, fmt.Errorf("compression failed: %w", err)
+	}
+	return &CompressedContext{
+		Summary:           summary,
+		CompressedAt:      time.Now().UTC(),
+		OriginalTokens:    estimateTokens(interactions),
+		CompressedTokens:  estimateTokens(summary),
+		InteractionsCount: len(interactions),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// promptLLM records prompts and options, failing while err is set.
+type promptLLM struct {
+	stubLLM
+	err     error
+	prompts []string
+	options []*LLMOptions
+}
+
+func (p *promptLLM) Generate(ctx context.Context, prompt string, options *LLMOptions) (*LLMResponse, error) {
+	p.prompts = append(p.prompts, prompt)
+	p.options = append(p.options, options)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.stubLLM.Generate(ctx, prompt, options)
+}
+
+func TestLLMSummarizerAppliesKnobs(t *testing.T) {
+	model := &promptLLM{stubLLM: stubLLM{text: "  handler added; tests pending  "}}
+	summarizer := &LLMSummarizer{Model: model, MaxTokens: 64, MaxInputTokens: 10, Length: 2}
+
+	summary, err := summarizer.Summarize(strings.Repeat("x", 100)+"TAIL", SummaryMinimal)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "handler added; tests pending" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	if model.options[0].MaxTokens != 64 {
+		t.Fatalf("expected token cap 64, got %d", model.options[0].MaxTokens)
+	}
+	if !strings.Contains(model.prompts[0], "at most 40 words") {
+		t.Fatalf("expected length scaled to 40 words:\n%s", model.prompts[0])
+	}
+	if strings.Contains(model.prompts[0], "TAIL") {
+		t.Fatalf("expected input truncated to 40 characters:\n%s", model.prompts[0])
+	}
+
+	full, _ := summarizer.Summarize("keep me", SummaryFull)
+	if full != "keep me" || len(model.prompts) != 1 {
+		t.Fatalf("expected SummaryFull to skip the model, got %q", full)
+	}
+}
+
+func TestLLMSummarizerFallsBackWhenModelFails(t *testing.T) {
+	model := &promptLLM{err: errors.New("connection refused")}
+	summarizer := &LLMSummarizer{Model: model}
+	content := strings.Repeat("word ", 100)
+
+	summary, err := summarizer.Summarize(content, SummaryMinimal)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	want, _ := (&SimpleSummarizer{}).Summarize(content, SummaryMinimal)
+	if summary != want {
+		t.Fatalf("expected simple summary %q, got %q", want, summary)
+	}
+}
+
+func TestSharedContextCompressHistoryUsesLLMSummarizer(t *testing.T) {
+	model := &promptLLM{stubLLM: stubLLM{text: "renamed Foo to Bar in api.go"}}
+	sc := NewSharedContext(NewContext(), nil, &LLMSummarizer{Model: model})
+	for _, content := range []string{"rename Foo", "read api.go", "edited api.go", "done"} {
+		sc.AddInteraction("assistant", content, nil)
+	}
+
+	strategy := &stubCompressionStrategy{compressed: &CompressedContext{Summary: "strategy"}}
+	if err := sc.CompressHistory(1, nil, strategy); err != nil {
+		t.Fatalf("CompressHistory: %v", err)
+	}
+	compressed, recent := sc.GetFullHistory()
+	if len(compressed) != 1 || compressed[0].Summary != "renamed Foo to Bar in api.go" {
+		t.Fatalf("expected the summarizer's summary, got %+v", compressed)
+	}
+	if compressed[0].InteractionsCount != 3 || len(recent) != 1 {
+		t.Fatalf("expected three interactions compressed and one kept, got %d and %d", compressed[0].InteractionsCount, len(recent))
+	}
+	if !strings.Contains(model.prompts[0], "[assistant] edited api.go") {
+		t.Fatalf("expected interactions in the prompt:\n%s", model.prompts[0])
+	}
+
+	plain := NewSharedContext(NewContext(), nil, &SimpleSummarizer{})
+	plain.AddInteraction("user", "one", nil)
+	plain.AddInteraction("user", "two", nil)
+	if err := plain.CompressHistory(1, nil, strategy); err != nil {
+		t.Fatalf("CompressHistory: %v", err)
+	}
+	if compressed, _ := plain.GetFullHistory(); compressed[0].Summary != "strategy" {
+		t.Fatalf("expected the strategy to compress without an LLM summarizer, got %q", compressed[0].Summary)
+	}
+}
@@ -2,8 +2,11 @@ package framework
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -115,6 +118,7 @@ type AgentRuntimeSpec struct {
 	Version           string               `yaml:"version,omitempty" json:"version,omitempty"`
 	Prompt            string               `yaml:"prompt,omitempty" json:"prompt,omitempty"`
 	Model             AgentModelConfig     `yaml:"model" json:"model"`
+	Models            map[ModelRole]string `yaml:"models,omitempty" json:"models,omitempty"` // role -> model name, e.g. summarization: qwen2.5:3b
 	Tools             AgentToolMatrix      `yaml:"tools" json:"tools"`
 	ToolPolicies      map[string]ToolPolicy `yaml:"tool_policies,omitempty" json:"tool_policies,omitempty"`
 	Bash              AgentBashPermissions `yaml:"bash_permissions,omitempty" json:"bash_permissions,omitempty"`
@@ -126,6 +130,206 @@ type AgentRuntimeSpec struct {
 	Metadata          AgentMetadata        `yaml:"metadata,omitempty" json:"metadata,omitempty"`
 	OllamaToolCalling *bool                `yaml:"ollama_tool_calling,omitempty" json:"ollama_tool_calling,omitempty"`
 	Logging           *AgentLoggingSpec    `yaml:"logging,omitempty" json:"logging,omitempty"`
+	Plugins           []AgentPluginSpec    `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+	MCPServers        []AgentMCPServerSpec `yaml:"mcp_servers,omitempty" json:"mcp_servers,omitempty"`
+	Timeouts          *AgentTimeoutSpec    `yaml:"timeouts,omitempty" json:"timeouts,omitempty"`
+	Retry             *AgentRetrySpec      `yaml:"retry,omitempty" json:"retry,omitempty"`
+	Graph             *GraphSpec           `yaml:"graph,omitempty" json:"graph,omitempty"` // workflow for implementation "graph"
+	// Isolated runs headless tasks against a temporary copy of the workspace
+	// and merges the changes back only on success; see relurpish task --isolated.
+	Isolated bool `yaml:"isolated,omitempty" json:"isolated,omitempty"`
+}
+
+// AgentTimeoutSpec bounds task execution with Go duration strings. Graph
+// limits a whole run, Node each node, and Nodes overrides Node by node ID
+// (e.g. a slow "plan" step).
+type AgentTimeoutSpec struct {
+	Graph string            `yaml:"graph,omitempty" json:"graph,omitempty"`
+	Node  string            `yaml:"node,omitempty" json:"node,omitempty"`
+	Nodes map[string]string `yaml:"nodes,omitempty" json:"nodes,omitempty"`
+}
+
+// GraphTimeouts parses the spec. A nil spec yields no limits.
+func (s *AgentTimeoutSpec) GraphTimeouts() (GraphTimeouts, error) {
+	var timeouts GraphTimeouts
+	if s == nil {
+		return timeouts, nil
+	}
+	parse := func(field, value string) (time.Duration, error) {
+		if value == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("timeouts.%s invalid: %w", field, err)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("timeouts.%s must not be negative", field)
+		}
+		return d, nil
+	}
+	var err error
+	if timeouts.Graph, err = parse("graph", s.Graph); err != nil {
+		return GraphTimeouts{}, err
+	}
+	if timeouts.Node, err = parse("node", s.Node); err != nil {
+		return GraphTimeouts{}, err
+	}
+	for id, value := range s.Nodes {
+		d, err := parse("nodes."+id, value)
+		if err != nil {
+			return GraphTimeouts{}, err
+		}
+		if timeouts.Nodes == nil {
+			timeouts.Nodes = make(map[string]time.Duration)
+		}
+		timeouts.Nodes[id] = d
+	}
+	return timeouts, nil
+}
+
+// AgentRetrySpec tunes retries of transient LLM and tool failures. Unset
+// fields fall back to DefaultRetryPolicy; max_attempts: 1 disables retries.
+type AgentRetrySpec struct {
+	MaxAttempts    int          `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	InitialBackoff string       `yaml:"initial_backoff,omitempty" json:"initial_backoff,omitempty"`
+	MaxBackoff     string       `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+	Multiplier     float64      `yaml:"multiplier,omitempty" json:"multiplier,omitempty"`
+	RetryOn        []RetryClass `yaml:"retry_on,omitempty" json:"retry_on,omitempty"`
+}
+
+// RetryPolicy parses the spec. A nil spec yields the zero policy, which
+// behaves like DefaultRetryPolicy.
+func (s *AgentRetrySpec) RetryPolicy() (RetryPolicy, error) {
+	var policy RetryPolicy
+	if s == nil {
+		return policy, nil
+	}
+	if s.MaxAttempts < 0 {
+		return RetryPolicy{}, fmt.Errorf("retry.max_attempts must not be negative")
+	}
+	if s.Multiplier != 0 && s.Multiplier < 1 {
+		return RetryPolicy{}, fmt.Errorf("retry.multiplier must be at least 1")
+	}
+	parse := func(field, value string) (time.Duration, error) {
+		if value == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("retry.%s invalid: %w", field, err)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("retry.%s must not be negative", field)
+		}
+		return d, nil
+	}
+	var err error
+	if policy.InitialBackoff, err = parse("initial_backoff", s.InitialBackoff); err != nil {
+		return RetryPolicy{}, err
+	}
+	if policy.MaxBackoff, err = parse("max_backoff", s.MaxBackoff); err != nil {
+		return RetryPolicy{}, err
+	}
+	for _, class := range s.RetryOn {
+		if !class.Valid() {
+			return RetryPolicy{}, fmt.Errorf("retry.retry_on: unknown class %s", class)
+		}
+	}
+	policy.MaxAttempts = s.MaxAttempts
+	policy.Multiplier = s.Multiplier
+	policy.RetryOn = append([]RetryClass(nil), s.RetryOn...)
+	return policy, nil
+}
+
+// AgentPluginSpec declares an external executable that contributes tools over
+// the JSON-over-stdio plugin protocol. Permissions lists what the plugin's
+// tools need; it must fit inside the manifest's permissions, and the command
+// binary must be declared as an executable there as well.
+type AgentPluginSpec struct {
+	Name        string        `yaml:"name" json:"name"`
+	Command     []string      `yaml:"command" json:"command"`
+	Timeout     string        `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Permissions PermissionSet `yaml:"permissions,omitempty" json:"permissions,omitempty"`
+}
+
+// Validate ensures the plugin can be launched.
+func (p AgentPluginSpec) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("plugin name required")
+	}
+	if len(p.Command) == 0 || strings.TrimSpace(p.Command[0]) == "" {
+		return fmt.Errorf("plugin %s command required", p.Name)
+	}
+	if strings.Contains(p.Command[0], "/") {
+		return fmt.Errorf("plugin %s binary must be referenced by name", p.Name)
+	}
+	if p.Timeout != "" {
+		if _, err := time.ParseDuration(p.Timeout); err != nil {
+			return fmt.Errorf("plugin %s timeout invalid: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// AgentMCPServerSpec declares a Model Context Protocol server whose tools are
+// registered for the agent. Exactly one of Command (stdio transport) or URL
+// (streamable HTTP transport) is set. Tools is an allowlist of remote tool
+// names; entries may use path.Match globs and "*" exposes every tool. The
+// command binary, or the URL's host and port, must also be declared in the
+// manifest permissions.
+type AgentMCPServerSpec struct {
+	Name    string            `yaml:"name" json:"name"`
+	Command []string          `yaml:"command,omitempty" json:"command,omitempty"`
+	Env     []string          `yaml:"env,omitempty" json:"env,omitempty"`
+	URL     string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Tools   []string          `yaml:"tools" json:"tools"`
+	Timeout string            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Validate ensures the server can be reached and exposes at least one tool.
+func (s AgentMCPServerSpec) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("mcp server name required")
+	}
+	hasCommand := len(s.Command) > 0 && strings.TrimSpace(s.Command[0]) != ""
+	if hasCommand == (s.URL != "") {
+		return fmt.Errorf("mcp server %s requires exactly one of command or url", s.Name)
+	}
+	if hasCommand && strings.Contains(s.Command[0], "/") {
+		return fmt.Errorf("mcp server %s binary must be referenced by name", s.Name)
+	}
+	if s.URL != "" {
+		parsed, err := url.Parse(s.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+			return fmt.Errorf("mcp server %s url invalid: %s", s.Name, s.URL)
+		}
+	}
+	if len(s.Tools) == 0 {
+		return fmt.Errorf("mcp server %s tools allowlist required", s.Name)
+	}
+	for _, pattern := range s.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("mcp server %s tool pattern %q invalid: %w", s.Name, pattern, err)
+		}
+	}
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("mcp server %s timeout invalid: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// AllowsTool reports whether the allowlist admits the remote tool name.
+func (s AgentMCPServerSpec) AllowsTool(name string) bool {
+	for _, pattern := range s.Tools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // AgentLSPSpec configures Language Server Protocol features.
@@ -264,6 +468,32 @@ func (a *AgentRuntimeSpec) Validate() error {
 	if err := a.Model.Validate(); err != nil {
 		return fmt.Errorf("model invalid: %w", err)
 	}
+	for _, plugin := range a.Plugins {
+		if err := plugin.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, server := range a.MCPServers {
+		if err := server.Validate(); err != nil {
+			return err
+		}
+	}
+	if a.Implementation == "graph" || a.Graph != nil {
+		if err := a.Graph.Validate(); err != nil {
+			return fmt.Errorf("graph invalid: %w", err)
+		}
+	}
+	if _, err := a.Timeouts.GraphTimeouts(); err != nil {
+		return err
+	}
+	if _, err := a.Retry.RetryPolicy(); err != nil {
+		return err
+	}
+	for role := range a.Models {
+		if !role.Valid() {
+			return fmt.Errorf("models: unknown role %s", role)
+		}
+	}
 	if err := a.Tools.Validate(); err != nil {
 		return err
 	}
@@ -0,0 +1,219 @@
+package framework
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lint rules.
+const (
+	LintRuleSchema               = "schema"
+	LintRuleBroadGlob            = "broad-glob"
+	LintRuleMissingJustification = "missing-justification"
+	LintRuleUndeclaredBinary     = "undeclared-binary"
+)
+
+// Lint finding severities. Errors make a manifest unusable or unsafe;
+// warnings flag grants a reviewer should look at.
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+)
+
+// ManifestFinding is one lint result. Field is the manifest path of the
+// offending entry, e.g. "spec.permissions.filesystem[2]".
+type ManifestFinding struct {
+	File     string `json:"file,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintManifestFile reads and lints the manifest at path. tools are the tools
+// the runtime would register, used to find binaries the manifest must grant.
+func LintManifestFile(path string, tools []Tool) ([]ManifestFinding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	findings := LintManifest(data, tools)
+	for i := range findings {
+		findings[i].File = path
+	}
+	return findings, nil
+}
+
+// LintManifest checks a manifest against the schema and flags risky or
+// incomplete grants: write/execute globs that reach outside the workspace,
+// HITL and capability entries without a justification, and binaries used by
+// allowed tools, plugins, or MCP servers that spec.permissions.executables
+// does not declare.
+func LintManifest(data []byte, tools []Tool) []ManifestFinding {
+	var manifest AgentManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return []ManifestFinding{{Rule: LintRuleSchema, Severity: LintSeverityError, Message: err.Error()}}
+	}
+	var findings []ManifestFinding
+	if err := manifest.Validate(); err != nil {
+		findings = append(findings, ManifestFinding{Rule: LintRuleSchema, Severity: LintSeverityError, Message: err.Error()})
+	}
+	perms := manifest.Spec.Permissions
+	findings = append(findings, lintFileSystem(perms)...)
+	findings = append(findings, lintJustifications(perms)...)
+	findings = append(findings, lintBinaries(manifest.Spec.Agent, perms, tools)...)
+	return findings
+}
+
+func lintFileSystem(perms PermissionSet) []ManifestFinding {
+	var findings []ManifestFinding
+	for i, perm := range perms.FileSystem {
+		if perm.Action != FileSystemWrite && perm.Action != FileSystemExecute {
+			continue
+		}
+		field := fmt.Sprintf("spec.permissions.filesystem[%d]", i)
+		scope := strings.TrimPrefix(perm.Path, "${workspace}")
+		switch {
+		case scope == perm.Path && unanchoredGlob(scope):
+			findings = append(findings, ManifestFinding{
+				Rule: LintRuleBroadGlob, Severity: LintSeverityError, Field: field,
+				Message: fmt.Sprintf("%s on %q matches paths outside the workspace; anchor it with ${workspace}/", perm.Action, perm.Path),
+			})
+		case unanchoredGlob(scope) && !perm.HITLRequired:
+			findings = append(findings, ManifestFinding{
+				Rule: LintRuleBroadGlob, Severity: LintSeverityWarning, Field: field,
+				Message: fmt.Sprintf("%s on %q covers the whole workspace; narrow the path or set hitl_required", perm.Action, perm.Path),
+			})
+		}
+	}
+	return findings
+}
+
+// unanchoredGlob reports whether a glob starts with a wildcard rather than a
+// fixed directory, e.g. "**", "/**", or "*/src/**".
+func unanchoredGlob(path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	return path == "" || strings.HasPrefix(path, "*")
+}
+
+func lintJustifications(perms PermissionSet) []ManifestFinding {
+	var findings []ManifestFinding
+	missing := func(field, what string) {
+		findings = append(findings, ManifestFinding{
+			Rule: LintRuleMissingJustification, Severity: LintSeverityWarning, Field: field,
+			Message: what + " has no justification; approvers see it when asked",
+		})
+	}
+	for i, perm := range perms.FileSystem {
+		if perm.HITLRequired && strings.TrimSpace(perm.Justification) == "" {
+			missing(fmt.Sprintf("spec.permissions.filesystem[%d]", i), fmt.Sprintf("HITL %s on %s", perm.Action, perm.Path))
+		}
+	}
+	for i, perm := range perms.Network {
+		if perm.HITLRequired && strings.TrimSpace(perm.Description) == "" {
+			missing(fmt.Sprintf("spec.permissions.network[%d]", i), fmt.Sprintf("HITL %s %s", perm.Direction, perm.Host))
+		}
+	}
+	for i, perm := range perms.IPC {
+		if perm.HITLRequired && strings.TrimSpace(perm.Description) == "" {
+			missing(fmt.Sprintf("spec.permissions.ipc[%d]", i), fmt.Sprintf("HITL %s %s", perm.Kind, perm.Target))
+		}
+	}
+	for i, perm := range perms.Capabilities {
+		if strings.TrimSpace(perm.Justification) == "" {
+			missing(fmt.Sprintf("spec.permissions.capabilities[%d]", i), "capability "+perm.Capability)
+		}
+	}
+	return findings
+}
+
+// lintBinaries reports one finding per undeclared binary, listing every
+// allowed tool that needs it. The runtime denies such calls, so the tools are
+// offered to the model but always fail.
+func lintBinaries(spec *AgentRuntimeSpec, perms PermissionSet, tools []Tool) []ManifestFinding {
+	declared := make(map[string]bool, len(perms.Executables))
+	for _, exec := range perms.Executables {
+		declared[exec.Binary] = true
+	}
+	users := make(map[string][]string)
+	fields := make(map[string]string)
+	use := func(binary, user, field string) {
+		if binary == "" || declared[binary] || declared[PermissionAnyBinary] {
+			return
+		}
+		users[binary] = append(users[binary], user)
+		if _, ok := fields[binary]; !ok {
+			fields[binary] = field
+		}
+	}
+	for _, tool := range tools {
+		if !lintToolAllowed(spec, tool) {
+			continue
+		}
+		toolPerms := tool.Permissions().Permissions
+		if toolPerms == nil {
+			continue
+		}
+		for _, exec := range toolPerms.Executables {
+			use(exec.Binary, "tool "+tool.Name(), "")
+		}
+	}
+	if spec != nil {
+		for i, plugin := range spec.Plugins {
+			if len(plugin.Command) > 0 {
+				use(plugin.Command[0], "plugin "+plugin.Name, fmt.Sprintf("spec.agent.plugins[%d].command", i))
+			}
+		}
+		for i, server := range spec.MCPServers {
+			if len(server.Command) > 0 {
+				use(server.Command[0], "mcp server "+server.Name, fmt.Sprintf("spec.agent.mcp_servers[%d].command", i))
+			}
+		}
+	}
+	binaries := make([]string, 0, len(users))
+	for binary := range users {
+		binaries = append(binaries, binary)
+	}
+	sort.Strings(binaries)
+	findings := make([]ManifestFinding, 0, len(binaries))
+	for _, binary := range binaries {
+		field := fields[binary]
+		if field == "" {
+			field = "spec.permissions.executables"
+		}
+		findings = append(findings, ManifestFinding{
+			Rule: LintRuleUndeclaredBinary, Severity: LintSeverityWarning, Field: field,
+			Message: fmt.Sprintf("binary %s is not declared but is used by %s", binary, strings.Join(users[binary], ", ")),
+		})
+	}
+	return findings
+}
+
+// lintToolAllowed mirrors the runtime: the tool matrix and tool_policies
+// decide which tools the agent may call. Without an agent section the
+// manifest allows no builtin tools of its own.
+func lintToolAllowed(spec *AgentRuntimeSpec, tool Tool) bool {
+	if spec == nil {
+		return false
+	}
+	if !toolAllowedByMatrix(tool, spec.Tools) {
+		return false
+	}
+	policy, ok := spec.ToolPolicies[tool.Name()]
+	if !ok {
+		return true
+	}
+	if policy.Visible != nil && !*policy.Visible {
+		return false
+	}
+	return policy.Execute != AgentPermissionDeny
+}
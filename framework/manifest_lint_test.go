@@ -0,0 +1,78 @@
+package framework
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintManifestBase = `apiVersion: relurpify/v1alpha1
+kind: AgentManifest
+metadata:
+  name: lint
+spec:
+  image: runtime:latest
+  runtime: gvisor
+`
+
+func TestLintManifestFlagsRiskyGrants(t *testing.T) {
+	manifest := lintManifestBase + `  permissions:
+    filesystem:
+      - action: fs:read
+        path: "**"
+      - action: fs:write
+        path: "**"
+      - action: fs:write
+        path: ${workspace}/**
+      - action: fs:write
+        path: ${workspace}/src/**
+        hitl_required: true
+    executables:
+      - binary: git
+    capabilities:
+      - capability: CAP_NET_ADMIN
+  agent:
+    mode: primary
+    model: {provider: ollama, name: stub}
+    tools: {bash_execute: true}
+    tool_policies:
+      hidden_lint: {visible: false}
+    plugins:
+      - name: jira
+        command: [jira-plugin]
+`
+	tools := []Tool{
+		stubTool{name: "git_diff", perms: &PermissionSet{Executables: []ExecutablePermission{{Binary: "git"}}}},
+		execStubTool{stubTool{name: "exec_build", perms: &PermissionSet{Executables: []ExecutablePermission{{Binary: "go"}}}}},
+		execStubTool{stubTool{name: "exec_test", perms: &PermissionSet{Executables: []ExecutablePermission{{Binary: "go"}}}}},
+		execStubTool{stubTool{name: "hidden_lint", perms: &PermissionSet{Executables: []ExecutablePermission{{Binary: "golangci-lint"}}}}},
+	}
+	findings := LintManifest([]byte(manifest), tools)
+	got := make(map[string]ManifestFinding)
+	for _, f := range findings {
+		got[f.Rule+" "+f.Field] = f
+	}
+	require.Len(t, findings, 6, "%+v", findings)
+
+	assert.Equal(t, LintSeverityError, got["broad-glob spec.permissions.filesystem[1]"].Severity)
+	assert.Equal(t, LintSeverityWarning, got["broad-glob spec.permissions.filesystem[2]"].Severity)
+	assert.Contains(t, got, "missing-justification spec.permissions.filesystem[3]")
+	assert.Contains(t, got, "missing-justification spec.permissions.capabilities[0]")
+	assert.Equal(t, "binary go is not declared but is used by tool exec_build, tool exec_test", got["undeclared-binary spec.permissions.executables"].Message)
+	assert.Contains(t, got, "undeclared-binary spec.agent.plugins[0].command")
+}
+
+func TestLintManifestSchema(t *testing.T) {
+	findings := LintManifest([]byte(lintManifestBase+"  permisions: {}\n"), nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, LintRuleSchema, findings[0].Rule)
+	assert.Contains(t, findings[0].Message, "permisions")
+
+	findings = LintManifest([]byte(lintManifestBase+"  permissions:\n    filesystem:\n      - action: fs:read\n        path: ${workspace}/**\n"), nil)
+	assert.Empty(t, findings)
+
+	findings = LintManifest([]byte("kind: AgentManifest\n"), nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, LintSeverityError, findings[0].Severity)
+}
@@ -0,0 +1,222 @@
+// Package mcp is a Model Context Protocol client. It connects to MCP servers
+// over stdio or streamable HTTP, discovers their tools, and adapts them to
+// framework.Tool so agents can call filesystem, database, or browser servers
+// like any builtin tool.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ProtocolVersion is the MCP revision requested during initialization.
+const ProtocolVersion = "2025-03-26"
+
+// Config describes how to reach one server.
+type Config struct {
+	// Name labels the server in errors and tool names.
+	Name string
+	// Command launches a stdio server. The binary is resolved on PATH.
+	Command []string
+	Env     []string
+	Workdir string
+	// URL selects the streamable HTTP transport.
+	URL        string
+	Headers    map[string]string
+	HTTPClient *http.Client
+	// Permissions, when set, must allow launching Command or reaching URL.
+	Permissions *framework.PermissionManager
+	AgentID     string
+}
+
+// ServerInfo identifies the connected server.
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// RemoteTool is a tool advertised by tools/list.
+type RemoteTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is one item of a tool result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Data     string `json:"data,omitempty"`
+}
+
+// CallResult is the response to tools/call.
+type CallResult struct {
+	Content           []Content              `json:"content"`
+	StructuredContent map[string]interface{} `json:"structuredContent,omitempty"`
+	IsError           bool                   `json:"isError,omitempty"`
+}
+
+// Text joins the text items of the result.
+func (r *CallResult) Text() string {
+	var parts []string
+	for _, item := range r.Content {
+		if item.Type == "text" && item.Text != "" {
+			parts = append(parts, item.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// transport carries JSON-RPC messages to a server.
+type transport interface {
+	call(ctx context.Context, method string, params, result interface{}) error
+	notify(ctx context.Context, method string, params interface{}) error
+	close() error
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	name   string
+	t      transport
+	server ServerInfo
+}
+
+// Connect checks permissions, starts the transport, and performs the
+// initialize handshake.
+func Connect(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("mcp server name required")
+	}
+	var (
+		t   transport
+		err error
+	)
+	switch {
+	case len(cfg.Command) > 0 && cfg.URL != "":
+		return nil, fmt.Errorf("mcp server %s: set command or url, not both", cfg.Name)
+	case len(cfg.Command) > 0:
+		if cfg.Permissions != nil {
+			if err := cfg.Permissions.CheckExecutable(ctx, cfg.AgentID, cfg.Command[0], cfg.Command[1:], cfg.Env); err != nil {
+				return nil, err
+			}
+		}
+		t, err = newStdioTransport(cfg)
+	case cfg.URL != "":
+		if cfg.Permissions != nil {
+			host, port, perr := hostPort(cfg.URL)
+			if perr != nil {
+				return nil, perr
+			}
+			if err := cfg.Permissions.CheckNetwork(ctx, cfg.AgentID, "egress", "tcp", host, port); err != nil {
+				return nil, err
+			}
+		}
+		t, err = newHTTPTransport(cfg)
+	default:
+		return nil, fmt.Errorf("mcp server %s: command or url required", cfg.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %w", cfg.Name, err)
+	}
+	client := &Client{name: cfg.Name, t: t}
+	if err := client.initialize(ctx); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("mcp server %s: initialize: %w", cfg.Name, err)
+	}
+	return client, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "relurpify", "version": framework.Version},
+	}
+	var result struct {
+		ProtocolVersion string     `json:"protocolVersion"`
+		ServerInfo      ServerInfo `json:"serverInfo"`
+	}
+	if err := c.t.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.server = result.ServerInfo
+	return c.t.notify(ctx, "notifications/initialized", nil)
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string { return c.name }
+
+// Server returns the identity reported during initialization.
+func (c *Client) Server() ServerInfo { return c.server }
+
+// ListTools returns every tool the server advertises, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]RemoteTool, error) {
+	var tools []RemoteTool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []RemoteTool `json:"tools"`
+			NextCursor string       `json:"nextCursor,omitempty"`
+		}
+		if err := c.t.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("mcp server %s: tools/list: %w", c.name, err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a remote tool. Tool-level failures come back as a result
+// with IsError set; the error return is reserved for protocol failures.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result CallResult
+	if err := c.t.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result); err != nil {
+		return nil, fmt.Errorf("mcp server %s: tools/call %s: %w", c.name, name, err)
+	}
+	return &result, nil
+}
+
+// Close shuts down the transport, terminating stdio servers.
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+// hostPort extracts the host and port a URL will dial.
+func hostPort(raw string) (string, int, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", 0, err
+	}
+	host := parsed.Hostname()
+	portStr := parsed.Port()
+	if portStr == "" {
+		if parsed.Scheme == "https" {
+			return host, 443, nil
+		}
+		return host, 80, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %s", raw)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary double as a stdio MCP server.
+func TestMain(m *testing.M) {
+	if os.Getenv("RELURPIFY_MCP_TEST_SERVER") == "1" {
+		serveStdio()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+}
+
+// handleTestRequest implements initialize, tools/list, and tools/call for an
+// "echo" tool and a "fail" tool.
+func handleTestRequest(msg testMessage) interface{} {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"serverInfo":      map[string]string{"name": "test-server", "version": "1.0"},
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		}
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			return map[string]interface{}{
+				"tools": []map[string]interface{}{{
+					"name":        "echo",
+					"description": "Echo text",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"text":  map[string]interface{}{"type": "string", "description": "Text to echo"},
+							"times": map[string]interface{}{"type": []string{"integer", "null"}, "default": 1},
+						},
+						"required": []string{"text"},
+					},
+				}},
+				"nextCursor": "page2",
+			}
+		}
+		return map[string]interface{}{"tools": []map[string]interface{}{{"name": "fail"}}}
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		if params.Name == "fail" {
+			return map[string]interface{}{"isError": true, "content": []map[string]string{{"type": "text", "text": "boom"}}}
+		}
+		return map[string]interface{}{
+			"content":           []map[string]string{{"type": "text", "text": fmt.Sprint(params.Arguments["text"])}},
+			"structuredContent": map[string]interface{}{"echoed": true},
+		}
+	}
+	return nil
+}
+
+func serveStdio() {
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var msg testMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil {
+			continue
+		}
+		_ = enc.Encode(testMessage{JSONRPC: "2.0", ID: msg.ID, Result: handleTestRequest(msg)})
+	}
+}
+
+func TestStdioClientDiscoversAndCallsTools(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cfg := Config{
+		Name:    "files",
+		Command: []string{os.Args[0]},
+		Env:     []string{"RELURPIFY_MCP_TEST_SERVER=1"},
+	}
+	client, err := Connect(ctx, cfg)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "test-server", client.Server().Name)
+
+	perms, err := ServerPermissions(cfg)
+	require.NoError(t, err)
+	tools, err := LoadTools(ctx, client, perms, func(name string) bool { return true })
+	require.NoError(t, err)
+	require.Len(t, tools, 2, "pagination should be followed")
+
+	echo := tools[0]
+	assert.Equal(t, "files_echo", echo.Name())
+	assert.Equal(t, []framework.ToolParameter{
+		{Name: "text", Type: "string", Description: "Text to echo", Required: true},
+		{Name: "times", Type: "integer", Default: float64(1)},
+	}, echo.Parameters())
+	assert.Equal(t, os.Args[0], echo.Permissions().Permissions.Executables[0].Binary)
+
+	res, err := echo.Execute(ctx, framework.NewContext(), map[string]interface{}{"text": "hi"})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, "hi", res.Data["output"])
+	assert.Equal(t, map[string]interface{}{"echoed": true}, res.Data["structured"])
+
+	res, err = tools[1].Execute(ctx, framework.NewContext(), nil)
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.Equal(t, "boom", res.Error)
+}
+
+func TestHTTPClientHandlesEventStreamAndSessions(t *testing.T) {
+	var sessions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var msg testMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		sessions = append(sessions, r.Header.Get("Mcp-Session-Id"))
+		if msg.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		w.Header().Set("Content-Type", "text/event-stream")
+		note, _ := json.Marshal(testMessage{JSONRPC: "2.0", Method: "notifications/message"})
+		reply, _ := json.Marshal(testMessage{JSONRPC: "2.0", ID: msg.ID, Result: handleTestRequest(msg)})
+		fmt.Fprintf(w, "data: %s\n\ndata: %s\n\n", note, reply)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := Connect(ctx, Config{Name: "remote", URL: server.URL})
+	require.NoError(t, err)
+	defer client.Close()
+
+	remote, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Len(t, remote, 2)
+	assert.Equal(t, "", sessions[0])
+	assert.Equal(t, "session-1", sessions[len(sessions)-1])
+
+	perms, err := ServerPermissions(Config{URL: server.URL})
+	require.NoError(t, err)
+	require.Len(t, perms.Network, 1)
+	assert.Equal(t, "127.0.0.1", perms.Network[0].Host)
+}
+
+func TestConnectEnforcesPermissions(t *testing.T) {
+	manager, err := framework.NewPermissionManager("/workspace", &framework.PermissionSet{
+		FileSystem: []framework.FileSystemPermission{{Action: framework.FileSystemRead, Path: "/workspace/**"}},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	_, err = Connect(context.Background(), Config{Name: "db", Command: []string{"mcp-db"}, Permissions: manager, AgentID: "agent"})
+	var denied *framework.PermissionDeniedError
+	assert.ErrorAs(t, err, &denied)
+
+	_, err = Connect(context.Background(), Config{Name: "web", URL: "https://mcp.example.com/mcp", Permissions: manager, AgentID: "agent"})
+	assert.ErrorAs(t, err, &denied)
+}
+
+func TestToolNameSanitizes(t *testing.T) {
+	assert.Equal(t, "my_server_read_file", ToolName("my.server", "read/file"))
+}
+
+func TestServerSpecAllowlist(t *testing.T) {
+	spec := framework.AgentMCPServerSpec{Name: "fs", Command: []string{"mcp-fs"}, Tools: []string{"read_*"}}
+	require.NoError(t, spec.Validate())
+	assert.True(t, spec.AllowsTool("read_file"))
+	assert.False(t, spec.AllowsTool("write_file"))
+
+	spec.Tools = nil
+	assert.Error(t, spec.Validate(), "allowlist is required")
+	spec.Tools = []string{"*"}
+	spec.URL = "http://localhost:8931/mcp"
+	assert.Error(t, spec.Validate(), "command and url are exclusive")
+}
@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Tool adapts one remote MCP tool to framework.Tool. Its name is prefixed
+// with the server name so tools from different servers cannot collide.
+type Tool struct {
+	client  *Client
+	remote  RemoteTool
+	params  []framework.ToolParameter
+	perms   framework.PermissionSet
+	display string
+}
+
+// NewTool wraps remote. perms lists what reaching the server requires (see
+// ServerPermissions) so AuthorizeTool checks it against the manifest.
+func NewTool(client *Client, remote RemoteTool, perms framework.PermissionSet) *Tool {
+	return &Tool{
+		client:  client,
+		remote:  remote,
+		params:  ParametersFromSchema(remote.InputSchema),
+		perms:   perms,
+		display: ToolName(client.Name(), remote.Name),
+	}
+}
+
+// ToolName builds the registry name for a remote tool: server_tool with
+// characters outside [A-Za-z0-9_-] replaced so LLM function calling accepts
+// it.
+func ToolName(server, tool string) string {
+	sanitize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+				return r
+			default:
+				return '_'
+			}
+		}, s)
+	}
+	return sanitize(server) + "_" + sanitize(tool)
+}
+
+// LoadTools lists the server's tools and wraps those allow admits.
+func LoadTools(ctx context.Context, client *Client, perms framework.PermissionSet, allow func(name string) bool) ([]framework.Tool, error) {
+	remote, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tools []framework.Tool
+	for _, rt := range remote {
+		if allow != nil && !allow(rt.Name) {
+			continue
+		}
+		tools = append(tools, NewTool(client, rt, perms))
+	}
+	return tools, nil
+}
+
+// ServerPermissions returns the permissions needed to reach a server: its
+// stdio binary, or egress to its URL's host and port.
+func ServerPermissions(cfg Config) (framework.PermissionSet, error) {
+	if len(cfg.Command) > 0 {
+		return framework.PermissionSet{Executables: []framework.ExecutablePermission{{
+			Binary: cfg.Command[0],
+			Args:   append([]string(nil), cfg.Command[1:]...),
+		}}}, nil
+	}
+	host, port, err := hostPort(cfg.URL)
+	if err != nil {
+		return framework.PermissionSet{}, err
+	}
+	return framework.PermissionSet{Network: []framework.NetworkPermission{{
+		Direction: "egress",
+		Protocol:  "tcp",
+		Host:      host,
+		Port:      port,
+	}}}, nil
+}
+
+func (t *Tool) Name() string { return t.display }
+
+func (t *Tool) Description() string {
+	if t.remote.Description == "" {
+		return fmt.Sprintf("%s tool from the %s MCP server.", t.remote.Name, t.client.Name())
+	}
+	return t.remote.Description
+}
+
+func (t *Tool) Category() string { return "mcp" }
+
+func (t *Tool) Parameters() []framework.ToolParameter { return t.params }
+
+// Execute calls the remote tool. Text content is returned under "output" and
+// structured content, when present, under "structured".
+func (t *Tool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	result, err := t.client.CallTool(ctx, t.remote.Name, args)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{"output": result.Text()}
+	if result.StructuredContent != nil {
+		data["structured"] = result.StructuredContent
+	}
+	res := &framework.ToolResult{Success: !result.IsError, Data: data}
+	if result.IsError {
+		res.Error = result.Text()
+	}
+	return res, nil
+}
+
+func (t *Tool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.client != nil
+}
+
+func (t *Tool) Permissions() framework.ToolPermissions {
+	perms := t.perms
+	return framework.ToolPermissions{Permissions: &perms}
+}
+
+// ParametersFromSchema translates a JSON Schema object (the MCP inputSchema)
+// into tool parameters, sorted by name. Nested objects and arrays keep their
+// top-level type; the model sees their description only.
+func ParametersFromSchema(raw json.RawMessage) []framework.ToolParameter {
+	if len(raw) == 0 {
+		return nil
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type        interface{}   `json:"type"`
+			Description string        `json:"description"`
+			Default     interface{}   `json:"default"`
+			Enum        []interface{} `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	params := make([]framework.ToolParameter, 0, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		desc := prop.Description
+		if len(prop.Enum) > 0 {
+			values := make([]string, len(prop.Enum))
+			for i, v := range prop.Enum {
+				values[i] = fmt.Sprint(v)
+			}
+			desc = strings.TrimSpace(desc + " (one of: " + strings.Join(values, ", ") + ")")
+		}
+		params = append(params, framework.ToolParameter{
+			Name:        name,
+			Type:        schemaType(prop.Type),
+			Description: desc,
+			Required:    required[name],
+			Default:     prop.Default,
+		})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+// schemaType resolves "type" which may be a string or a list such as
+// ["string", "null"].
+func schemaType(v interface{}) string {
+	switch typed := v.(type) {
+	case string:
+		return typed
+	case []interface{}:
+		for _, item := range typed {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return "string"
+}
@@ -0,0 +1,290 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// stdioTransport speaks newline-delimited JSON-RPC to a child process.
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	conn   *jsonrpc2.Conn
+	stdin  io.Closer
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	stderr []string
+}
+
+func newStdioTransport(cfg Config) (*stdioTransport, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, cfg.Command[0], cfg.Command[1:]...)
+	cmd.Dir = cfg.Workdir
+	if len(cfg.Env) > 0 {
+		cmd.Env = append(os.Environ(), cfg.Env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	t := &stdioTransport{cmd: cmd, stdin: stdin, cancel: cancel}
+	go t.consumeStderr(stderr)
+	stream := jsonrpc2.NewBufferedStream(&stdioPipe{reader: stdout, writer: stdin}, jsonrpc2.PlainObjectCodec{})
+	t.conn = jsonrpc2.NewConn(ctx, stream, jsonrpc2.HandlerWithError(handleServerRequest))
+	return t, nil
+}
+
+// handleServerRequest answers the few requests a server may send a client.
+// Notifications (logging, list_changed) are ignored.
+func handleServerRequest(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+	if req.Notif {
+		return nil, nil
+	}
+	if req.Method == "ping" {
+		return map[string]interface{}{}, nil
+	}
+	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not supported by client"}
+}
+
+// consumeStderr keeps the last lines of server output for error messages.
+func (t *stdioTransport) consumeStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		t.mu.Lock()
+		t.stderr = append(t.stderr, scanner.Text())
+		if len(t.stderr) > 20 {
+			t.stderr = t.stderr[1:]
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *stdioTransport) call(ctx context.Context, method string, params, result interface{}) error {
+	err := t.conn.Call(ctx, method, params, result)
+	if errors.Is(err, jsonrpc2.ErrClosed) {
+		t.mu.Lock()
+		tail := strings.Join(t.stderr, "\n")
+		t.mu.Unlock()
+		if tail != "" {
+			return fmt.Errorf("%w: %s", err, tail)
+		}
+	}
+	return err
+}
+
+func (t *stdioTransport) notify(ctx context.Context, method string, params interface{}) error {
+	return t.conn.Notify(ctx, method, params)
+}
+
+// close ends stdin so the server can exit cleanly, then kills it if it
+// lingers.
+func (t *stdioTransport) close() error {
+	_ = t.conn.Close()
+	_ = t.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		_ = t.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.cancel()
+		<-done
+	}
+	t.cancel()
+	return nil
+}
+
+type stdioPipe struct {
+	reader io.ReadCloser
+	writer io.WriteCloser
+}
+
+func (p *stdioPipe) Read(b []byte) (int, error)  { return p.reader.Read(b) }
+func (p *stdioPipe) Write(b []byte) (int, error) { return p.writer.Write(b) }
+func (p *stdioPipe) Close() error {
+	werr := p.writer.Close()
+	rerr := p.reader.Close()
+	if werr != nil {
+		return werr
+	}
+	return rerr
+}
+
+// httpTransport implements the streamable HTTP transport: each message is a
+// POST and the reply is either a JSON body or an SSE stream carrying it.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	nextID  atomic.Int64
+
+	mu      sync.Mutex
+	session string
+}
+
+func newHTTPTransport(cfg Config) (*httpTransport, error) {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &httpTransport{url: cfg.URL, headers: cfg.Headers, client: client}, nil
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpc2.Error `json:"error,omitempty"`
+}
+
+func (t *httpTransport) call(ctx context.Context, method string, params, result interface{}) error {
+	id := t.nextID.Add(1)
+	resp, err := t.post(ctx, rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, err := readResponse(resp, id)
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+func (t *httpTransport) notify(ctx context.Context, method string, params interface{}) error {
+	resp, err := t.post(ctx, rpcMessage{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) post(ctx context.Context, msg rpcMessage) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	if t.session != "" {
+		req.Header.Set("Mcp-Session-Id", t.session)
+	}
+	t.mu.Unlock()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp endpoint returned %s", resp.Status)
+	}
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		t.mu.Lock()
+		t.session = session
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// readResponse extracts the reply with id from a JSON or SSE body.
+func readResponse(resp *http.Response, id int64) (*rpcMessage, error) {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var msg rpcMessage
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("mcp response: %w", err)
+		}
+		return &msg, nil
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var msg rpcMessage
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.ID != nil && *msg.ID == id && msg.Method == "" {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("mcp event stream: %w", err)
+	}
+	return nil, errors.New("mcp event stream ended without a response")
+}
+
+// close ends the session; servers that do not track sessions ignore it.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Mcp-Session-Id", session)
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
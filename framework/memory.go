@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,9 +19,18 @@ type MemoryScope string
 const (
 	MemoryScopeSession MemoryScope = "session"
 	MemoryScopeProject MemoryScope = "project"
-	MemoryScopeGlobal  MemoryScope = "global"
+	// MemoryScopeUser holds one user's preferences and learned conventions,
+	// shared by every workspace they open.
+	MemoryScopeUser   MemoryScope = "user"
+	MemoryScopeGlobal MemoryScope = "global"
 )
 
+// MemoryPrecedence lists the scopes from most to least specific. Layered
+// lookups (RecallLayered, SearchLayered) let a key in an earlier scope shadow
+// the same key in later ones, so a workspace can override a user preference
+// and a user can override a global default.
+var MemoryPrecedence = []MemoryScope{MemoryScopeSession, MemoryScopeProject, MemoryScopeUser, MemoryScopeGlobal}
+
 // MemoryRecord represents a stored memory item. Value is intentionally
 // unstructured JSON so agents can stash anything from LLM responses to plan
 // summaries without evolving the schema.
@@ -29,9 +40,32 @@ type MemoryRecord struct {
 	Scope     MemoryScope            `json:"scope"`
 	Timestamp time.Time              `json:"timestamp"`
 	Tags      []string               `json:"tags,omitempty"`
+	// ExpiresAt is set for records stored through TTLMemoryStore.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the record's TTL has elapsed at now.
+func (r MemoryRecord) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
 }
 
-// MemoryStore describes the memory system operations.
+// MemoryStore describes the memory system operations. Custom backends
+// (Postgres, Redis, ...) must honour the following contract, which
+// framework/memorytest verifies:
+//
+//   - Scopes are isolated: a key stored in one scope is invisible to Recall,
+//     Search, Forget, and Summarize in every other scope.
+//   - Remember overwrites an existing key and stamps Key, Scope, and a
+//     non-zero Timestamp on the stored record.
+//   - Recall of a missing key returns (nil, false, nil), not an error.
+//   - Search matches query case-insensitively as a substring of the
+//     JSON-encoded Value and returns only records from the given scope. Order
+//     is unspecified; no match yields an empty result and nil error.
+//   - Forget of a missing key is a no-op.
+//   - Summarize returns human-readable text mentioning every live key.
+//   - Every method returns ctx.Err() when called with a cancelled context.
+//
+// Stores that support expiry also implement TTLMemoryStore.
 type MemoryStore interface {
 	Remember(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope) error
 	Recall(ctx context.Context, key string, scope MemoryScope) (*MemoryRecord, bool, error)
@@ -40,17 +74,78 @@ type MemoryStore interface {
 	Summarize(ctx context.Context, scope MemoryScope) (string, error)
 }
 
+// TTLMemoryStore is implemented by stores that can expire records. Expired
+// records behave exactly like forgotten ones: Recall misses, and Search and
+// Summarize omit them.
+type TTLMemoryStore interface {
+	MemoryStore
+	RememberWithTTL(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope, ttl time.Duration) error
+}
+
 // HybridMemory combines in-memory caching with JSON persistence on disk. The
 // design keeps session data transient (great for experiments) while persisting
-// project/global scopes across runs for longer-term recall.
+// project/user/global scopes across runs for longer-term recall.
 type HybridMemory struct {
 	mu       sync.RWMutex
 	cache    map[MemoryScope]map[string]MemoryRecord
 	basePath string
+	// dirs places scopes outside basePath; see MemoryLayout.
+	dirs map[MemoryScope]string
+}
+
+// MemoryLayout places the persisted scopes of a HybridMemory. Workspace holds
+// the project scope; User and Global are usually shared across workspaces
+// (see DefaultMemoryLayout). An empty path keeps that scope in Workspace.
+type MemoryLayout struct {
+	Workspace string
+	User      string
+	Global    string
+}
+
+// DefaultMemoryLayout keeps the project scope in workspaceDir, the global
+// scope in ~/.relurpify/memory, and the user scope in a per-user directory
+// below it. Without a home directory every scope stays in workspaceDir.
+func DefaultMemoryLayout(workspaceDir string) MemoryLayout {
+	layout := MemoryLayout{Workspace: workspaceDir}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return layout
+	}
+	layout.Global = filepath.Join(home, ".relurpify", "memory")
+	layout.User = filepath.Join(layout.Global, "users", memoryUserName())
+	return layout
+}
+
+// memoryUserName names the per-user memory directory, preferring
+// RELURPIFY_USER so shared accounts can still keep separate preferences.
+func memoryUserName() string {
+	name := os.Getenv("RELURPIFY_USER")
+	if name == "" {
+		if current, err := user.Current(); err == nil {
+			name = current.Username
+		}
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "default"
+	}
+	return name
 }
 
-// NewHybridMemory creates a new memory store.
+// NewHybridMemory creates a new memory store keeping every scope in basePath.
 func NewHybridMemory(basePath string) (*HybridMemory, error) {
+	return NewLayeredMemory(MemoryLayout{Workspace: basePath})
+}
+
+// NewLayeredMemory creates a memory store whose user and global scopes live
+// outside the workspace, so they follow the user across projects.
+func NewLayeredMemory(layout MemoryLayout) (*HybridMemory, error) {
+	basePath := layout.Workspace
 	if basePath == "" {
 		basePath = ".memory"
 	}
@@ -61,9 +156,17 @@ func NewHybridMemory(basePath string) (*HybridMemory, error) {
 		cache: map[MemoryScope]map[string]MemoryRecord{
 			MemoryScopeSession: {},
 			MemoryScopeProject: {},
+			MemoryScopeUser:    {},
 			MemoryScopeGlobal:  {},
 		},
 		basePath: basePath,
+		dirs:     map[MemoryScope]string{},
+	}
+	if layout.User != "" {
+		store.dirs[MemoryScopeUser] = layout.User
+	}
+	if layout.Global != "" {
+		store.dirs[MemoryScopeGlobal] = layout.Global
 	}
 	if err := store.loadFromDisk(); err != nil {
 		return nil, err
@@ -105,13 +208,22 @@ func (m *HybridMemory) persist(scope MemoryScope) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.scopePath(scope), data, 0o644)
+	path := m.scopePath(scope)
+	// Shared scope directories are created on first write rather than
+	// whenever a workspace opens its memory.
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
 }
 
 // scopePath resolves the JSON file associated with a scope so all persistence
 // logic shares the same directory layout.
 func (m *HybridMemory) scopePath(scope MemoryScope) string {
 	filename := string(scope) + ".json"
+	if dir, ok := m.dirs[scope]; ok {
+		return filepath.Join(dir, filename)
+	}
 	return filepath.Join(m.basePath, filename)
 }
 
@@ -119,6 +231,13 @@ func (m *HybridMemory) scopePath(scope MemoryScope) string {
 // to avoid excessive disk churn during fast agent loops, while project/global
 // scopes are flushed to JSON for durability.
 func (m *HybridMemory) Remember(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope) error {
+	return m.RememberWithTTL(ctx, key, value, scope, 0)
+}
+
+// RememberWithTTL stores data that expires after ttl. A non-positive ttl keeps
+// the record until it is forgotten. Expired records are dropped lazily the
+// next time the scope is written.
+func (m *HybridMemory) RememberWithTTL(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope, ttl time.Duration) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -126,12 +245,18 @@ func (m *HybridMemory) Remember(ctx context.Context, key string, value map[strin
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	now := time.Now().UTC()
 	record := MemoryRecord{
 		Key:       key,
 		Value:     value,
 		Scope:     scope,
-		Timestamp: time.Now().UTC(),
+		Timestamp: now,
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		record.ExpiresAt = &expires
 	}
+	m.pruneExpired(scope, now)
 	m.cache[scope][key] = record
 	if scope == MemoryScopeSession {
 		return nil
@@ -139,6 +264,15 @@ func (m *HybridMemory) Remember(ctx context.Context, key string, value map[strin
 	return m.persist(scope)
 }
 
+// pruneExpired drops expired records from a scope. Callers hold the write lock.
+func (m *HybridMemory) pruneExpired(scope MemoryScope, now time.Time) {
+	for key, record := range m.cache[scope] {
+		if record.Expired(now) {
+			delete(m.cache[scope], key)
+		}
+	}
+}
+
 // Recall retrieves a memory record.
 func (m *HybridMemory) Recall(ctx context.Context, key string, scope MemoryScope) (*MemoryRecord, bool, error) {
 	select {
@@ -149,7 +283,7 @@ func (m *HybridMemory) Recall(ctx context.Context, key string, scope MemoryScope
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	record, ok := m.cache[scope][key]
-	if !ok {
+	if !ok || record.Expired(time.Now()) {
 		return nil, false, nil
 	}
 	return &record, true, nil
@@ -168,8 +302,12 @@ func (m *HybridMemory) Search(ctx context.Context, query string, scope MemorySco
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	now := time.Now()
 	var results []MemoryRecord
 	for _, record := range m.cache[scope] {
+		if record.Expired(now) {
+			continue
+		}
 		data, _ := json.Marshal(record.Value)
 		if strings.Contains(strings.ToLower(string(data)), lower) {
 			results = append(results, record)
@@ -209,7 +347,11 @@ func (m *HybridMemory) Summarize(ctx context.Context, scope MemoryScope) (string
 	builder.WriteString("Summary for scope ")
 	builder.WriteString(string(scope))
 	builder.WriteString(":\n")
+	now := time.Now()
 	for _, record := range m.cache[scope] {
+		if record.Expired(now) {
+			continue
+		}
 		builder.WriteString("- ")
 		builder.WriteString(record.Key)
 		builder.WriteString(": ")
@@ -219,3 +361,46 @@ func (m *HybridMemory) Summarize(ctx context.Context, scope MemoryScope) (string
 	}
 	return builder.String(), nil
 }
+
+// RecallLayered looks key up in every scope in MemoryPrecedence order and
+// returns the first live record, so more specific scopes win.
+func RecallLayered(ctx context.Context, store MemoryStore, key string) (*MemoryRecord, bool, error) {
+	for _, scope := range MemoryPrecedence {
+		record, ok, err := store.Recall(ctx, key, scope)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return record, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// SearchLayered searches every scope and merges the matches in
+// MemoryPrecedence order. A key stored in a more specific scope shadows the
+// same key in later scopes, even when only the shadowed record matches.
+func SearchLayered(ctx context.Context, store MemoryStore, query string) ([]MemoryRecord, error) {
+	shadowed := make(map[string]bool)
+	var merged []MemoryRecord
+	for _, scope := range MemoryPrecedence {
+		records, err := store.Search(ctx, query, scope)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+		for _, record := range records {
+			if !shadowed[record.Key] {
+				merged = append(merged, record)
+			}
+		}
+		all, err := store.Search(ctx, "", scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range all {
+			shadowed[record.Key] = true
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,290 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConsolidatedMemoryType is the "type" value of records written by
+// MemoryConsolidator.
+const ConsolidatedMemoryType = "consolidated_summary"
+
+// Consolidation defaults.
+const (
+	DefaultConsolidationMinRecords   = 20
+	DefaultConsolidationKeepRecent   = 5
+	DefaultConsolidationMaxSummaries = 20
+	// maxConsolidatedSummary bounds the digest written when no model is
+	// configured or the model fails.
+	maxConsolidatedSummary = 4000
+)
+
+// MemoryConsolidator folds raw records of one scope into summary records in a
+// longer-lived scope and prunes the raw records. Each summary lists the keys
+// it was built from under "sources", so provenance survives the pruning. When
+// the target holds more than MaxSummaries summaries, the oldest are merged
+// into one so long-lived workspaces stay bounded.
+type MemoryConsolidator struct {
+	Store MemoryStore
+	// Model writes the summaries. Without one, or when it fails, summaries
+	// are a truncated digest of the records.
+	Model LanguageModel
+	// Source defaults to MemoryScopeSession, Target to MemoryScopeProject.
+	Source MemoryScope
+	Target MemoryScope
+	// MinRecords is the number of raw records needed before a pass
+	// consolidates anything.
+	MinRecords int
+	// KeepRecent raw records are left in Source as working memory.
+	KeepRecent   int
+	MaxSummaries int
+
+	mu sync.Mutex
+}
+
+// ConsolidationReport describes one consolidation pass.
+type ConsolidationReport struct {
+	// Consolidated raw records were summarized and pruned.
+	Consolidated int
+	// Merged older summaries were folded into a single summary.
+	Merged int
+	// Written lists the keys of the summaries written in Target.
+	Written []string
+}
+
+// Consolidate runs one pass. Summaries are written before their sources are
+// forgotten, so a failed pass never loses records.
+func (c *MemoryConsolidator) Consolidate(ctx context.Context) (ConsolidationReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var report ConsolidationReport
+	if c.Store == nil {
+		return report, fmt.Errorf("memory consolidation requires a store")
+	}
+	source, target := c.scopes()
+
+	records, err := c.Store.Search(ctx, "", source)
+	if err != nil {
+		return report, err
+	}
+	raw := filterRecords(records, false)
+	minRecords := defaultInt(c.MinRecords, DefaultConsolidationMinRecords)
+	keep := defaultInt(c.KeepRecent, DefaultConsolidationKeepRecent)
+	if len(raw) >= minRecords && len(raw) > keep {
+		batch := raw[:len(raw)-keep]
+		key, err := c.write(ctx, batch, source, target, c.summarizeRecords(ctx, batch))
+		if err != nil {
+			return report, err
+		}
+		report.Written = append(report.Written, key)
+		if err := c.forget(ctx, batch, source); err != nil {
+			return report, err
+		}
+		report.Consolidated = len(batch)
+	}
+
+	records, err = c.Store.Search(ctx, "", target)
+	if err != nil {
+		return report, err
+	}
+	summaries := filterRecords(records, true)
+	maxSummaries := defaultInt(c.MaxSummaries, DefaultConsolidationMaxSummaries)
+	if len(summaries) > maxSummaries {
+		batch := summaries[:len(summaries)-maxSummaries+1]
+		key, err := c.write(ctx, batch, target, target, c.mergeSummaries(ctx, batch))
+		if err != nil {
+			return report, err
+		}
+		report.Written = append(report.Written, key)
+		if err := c.forget(ctx, batch, target); err != nil {
+			return report, err
+		}
+		report.Merged = len(batch)
+	}
+	return report, nil
+}
+
+func (c *MemoryConsolidator) scopes() (MemoryScope, MemoryScope) {
+	source, target := c.Source, c.Target
+	if source == "" {
+		source = MemoryScopeSession
+	}
+	if target == "" {
+		target = MemoryScopeProject
+	}
+	return source, target
+}
+
+// filterRecords returns the raw records, or only the summaries, oldest first.
+// Summaries are ordered by the oldest record they cover, so a merged summary
+// is merged again before newer ones.
+func filterRecords(records []MemoryRecord, summaries bool) []MemoryRecord {
+	out := make([]MemoryRecord, 0, len(records))
+	for _, record := range records {
+		if isConsolidated(record) == summaries {
+			out = append(out, record)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ti, tj := coveredFrom(out[i]), coveredFrom(out[j])
+		if ti.Equal(tj) {
+			return out[i].Key < out[j].Key
+		}
+		return ti.Before(tj)
+	})
+	return out
+}
+
+func coveredFrom(record MemoryRecord) time.Time {
+	if from, ok := record.Value["from"].(string); ok && isConsolidated(record) {
+		if t, err := time.Parse(time.RFC3339, from); err == nil {
+			return t
+		}
+	}
+	return record.Timestamp
+}
+
+func isConsolidated(record MemoryRecord) bool {
+	kind, _ := record.Value["type"].(string)
+	return kind == ConsolidatedMemoryType
+}
+
+func (c *MemoryConsolidator) write(ctx context.Context, batch []MemoryRecord, source, target MemoryScope, summary string) (string, error) {
+	now := time.Now().UTC()
+	sources := make([]string, len(batch))
+	count := 0
+	for i, record := range batch {
+		sources[i] = record.Key
+		count += recordCount(record)
+	}
+	key := fmt.Sprintf("consolidated-%d", now.UnixNano())
+	value := map[string]interface{}{
+		"type":            ConsolidatedMemoryType,
+		"summary":         summary,
+		"source_scope":    string(source),
+		"sources":         sources,
+		"count":           count,
+		"from":            coveredFrom(batch[0]).Format(time.RFC3339),
+		"to":              batch[len(batch)-1].Timestamp.Format(time.RFC3339),
+		"consolidated_at": now.Format(time.RFC3339),
+	}
+	if err := c.Store.Remember(ctx, key, value, target); err != nil {
+		return "", fmt.Errorf("write consolidated memory: %w", err)
+	}
+	return key, nil
+}
+
+// recordCount is the number of raw records a record stands for. Counts read
+// back from disk decode as float64.
+func recordCount(record MemoryRecord) int {
+	if !isConsolidated(record) {
+		return 1
+	}
+	switch n := record.Value["count"].(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 1
+}
+
+func (c *MemoryConsolidator) forget(ctx context.Context, batch []MemoryRecord, scope MemoryScope) error {
+	for _, record := range batch {
+		if err := c.Store.Forget(ctx, record.Key, scope); err != nil {
+			return fmt.Errorf("prune memory %s: %w", record.Key, err)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryConsolidator) summarizeRecords(ctx context.Context, batch []MemoryRecord) string {
+	lines := make([]string, len(batch))
+	for i, record := range batch {
+		data, _ := json.Marshal(record.Value)
+		lines[i] = "- " + truncateParagraph(string(data), 500)
+	}
+	return c.summarize(ctx, "Condense these agent memory entries into durable notes for future tasks in this workspace.", lines)
+}
+
+func (c *MemoryConsolidator) mergeSummaries(ctx context.Context, batch []MemoryRecord) string {
+	lines := make([]string, len(batch))
+	for i, record := range batch {
+		summary, _ := record.Value["summary"].(string)
+		lines[i] = summary
+	}
+	return c.summarize(ctx, "Merge these notes from earlier sessions in this workspace into one set of durable notes.", lines)
+}
+
+func (c *MemoryConsolidator) summarize(ctx context.Context, instruction string, lines []string) string {
+	if c.Model != nil {
+		prompt := instruction + " Keep facts, decisions, file paths, and unresolved problems; drop step-by-step chatter. Answer with plain bullet points.\n\n" + strings.Join(lines, "\n")
+		resp, err := c.Model.Generate(ctx, prompt, &LLMOptions{Temperature: 0.1})
+		if err == nil && strings.TrimSpace(resp.Text) != "" {
+			return strings.TrimSpace(resp.Text)
+		}
+	}
+	per := maxConsolidatedSummary / len(lines)
+	digest := make([]string, len(lines))
+	for i, line := range lines {
+		digest[i] = truncateParagraph(line, per)
+	}
+	return strings.Join(digest, "\n")
+}
+
+func defaultInt(v, fallback int) int {
+	if v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+// ConsolidatingMemory wraps a store and runs Consolidator in the background
+// after every Every writes to the consolidator's source scope. Passes never
+// overlap; writes during a pass are counted toward the next one. The wrapper
+// does not implement TTLMemoryStore.
+type ConsolidatingMemory struct {
+	MemoryStore
+	Consolidator *MemoryConsolidator
+	Every        int
+	// OnResult, when set, receives the outcome of each background pass.
+	OnResult func(ConsolidationReport, error)
+
+	writes  atomic.Int64
+	running atomic.Bool
+}
+
+// NewConsolidatingMemory wraps store; consolidator.Store is set to store.
+func NewConsolidatingMemory(store MemoryStore, consolidator *MemoryConsolidator, every int) *ConsolidatingMemory {
+	consolidator.Store = store
+	return &ConsolidatingMemory{MemoryStore: store, Consolidator: consolidator, Every: every}
+}
+
+// Remember stores the record and starts a consolidation pass when due.
+func (m *ConsolidatingMemory) Remember(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope) error {
+	if err := m.MemoryStore.Remember(ctx, key, value, scope); err != nil {
+		return err
+	}
+	source, _ := m.Consolidator.scopes()
+	if m.Every <= 0 || scope != source {
+		return nil
+	}
+	if m.writes.Add(1) < int64(m.Every) || !m.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	m.writes.Store(0)
+	go func() {
+		defer m.running.Store(false)
+		report, err := m.Consolidator.Consolidate(context.Background())
+		if m.OnResult != nil {
+			m.OnResult(report, err)
+		}
+	}()
+	return nil
+}
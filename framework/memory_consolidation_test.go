@@ -0,0 +1,94 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryConsolidatorSummarizesAndPrunes(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHybridMemory(t.TempDir())
+	require.NoError(t, err)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, store.Remember(ctx, fmt.Sprintf("step-%d", i), map[string]interface{}{"iteration": i}, MemoryScopeSession))
+		time.Sleep(time.Millisecond)
+	}
+	consolidator := &MemoryConsolidator{Store: store, Model: &stubLLM{text: "- edited main.go"}, MinRecords: 4, KeepRecent: 2}
+
+	report, err := consolidator.Consolidate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Consolidated)
+	require.Len(t, report.Written, 1)
+
+	remaining, err := store.Search(ctx, "", MemoryScopeSession)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 2, "the most recent records stay as working memory")
+	record, ok, err := store.Recall(ctx, report.Written[0], MemoryScopeProject)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, ConsolidatedMemoryType, record.Value["type"])
+	assert.Equal(t, "- edited main.go", record.Value["summary"])
+	assert.Equal(t, []string{"step-0", "step-1", "step-2", "step-3"}, record.Value["sources"])
+
+	report, err = consolidator.Consolidate(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Consolidated, "below MinRecords nothing is consolidated")
+}
+
+func TestMemoryConsolidatorMergesOldSummaries(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewHybridMemory(dir)
+	require.NoError(t, err)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, store.Remember(ctx, fmt.Sprintf("summary-%d", i), map[string]interface{}{
+			"type":    ConsolidatedMemoryType,
+			"summary": fmt.Sprintf("note %d", i),
+			"count":   3,
+			"from":    start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+		}, MemoryScopeProject))
+	}
+	// Reload so counts decode from JSON as they do in a fresh process.
+	store, err = NewHybridMemory(dir)
+	require.NoError(t, err)
+
+	report, err := (&MemoryConsolidator{Store: store, MaxSummaries: 2}).Consolidate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Merged)
+	summaries, err := store.Search(ctx, ConsolidatedMemoryType, MemoryScopeProject)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	merged, ok, err := store.Recall(ctx, report.Written[0], MemoryScopeProject)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 9, merged.Value["count"])
+	assert.Equal(t, "note 0\nnote 1\nnote 2", merged.Value["summary"], "without a model the summaries are joined")
+	assert.Equal(t, start.Format(time.RFC3339), merged.Value["from"])
+}
+
+func TestConsolidatingMemoryRunsInBackground(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHybridMemory(t.TempDir())
+	require.NoError(t, err)
+	done := make(chan ConsolidationReport, 1)
+	memory := NewConsolidatingMemory(store, &MemoryConsolidator{MinRecords: 3, KeepRecent: 1}, 3)
+	memory.OnResult = func(report ConsolidationReport, err error) {
+		assert.NoError(t, err)
+		done <- report
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, memory.Remember(ctx, fmt.Sprintf("k%d", i), map[string]interface{}{"i": i}, MemoryScopeSession))
+	}
+	select {
+	case report := <-done:
+		assert.Equal(t, 2, report.Consolidated)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consolidation did not run")
+	}
+}
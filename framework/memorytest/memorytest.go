@@ -0,0 +1,212 @@
+// Package memorytest provides a conformance suite for framework.MemoryStore
+// implementations. Backend authors call Run from their own tests:
+//
+//	func TestRedisMemory(t *testing.T) {
+//		memorytest.Run(t, func(t *testing.T) framework.MemoryStore {
+//			return newRedisMemory(t)
+//		})
+//	}
+//
+// Each subtest receives a fresh, empty store from the factory.
+package memorytest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an empty store. Use t.Cleanup to release resources.
+type Factory func(t *testing.T) framework.MemoryStore
+
+// Options tunes the suite for backends with coarse expiry resolution.
+type Options struct {
+	// TTL is the lifetime used by the expiry tests. Defaults to 50ms.
+	TTL time.Duration
+	// ExpiryWait is how long the suite sleeps before expecting a record to be
+	// gone. Defaults to 3x TTL.
+	ExpiryWait time.Duration
+}
+
+var scopes = []framework.MemoryScope{
+	framework.MemoryScopeSession,
+	framework.MemoryScopeProject,
+	framework.MemoryScopeUser,
+	framework.MemoryScopeGlobal,
+}
+
+// Run executes the conformance suite with default options.
+func Run(t *testing.T, newStore Factory) {
+	RunWithOptions(t, newStore, Options{})
+}
+
+// RunWithOptions executes the conformance suite. Expiry tests run only when
+// the store implements framework.TTLMemoryStore.
+func RunWithOptions(t *testing.T, newStore Factory, opts Options) {
+	if opts.TTL <= 0 {
+		opts.TTL = 50 * time.Millisecond
+	}
+	if opts.ExpiryWait <= 0 {
+		opts.ExpiryWait = 3 * opts.TTL
+	}
+	t.Run("RememberRecall", func(t *testing.T) { testRememberRecall(t, newStore(t)) })
+	t.Run("RecallMissing", func(t *testing.T) { testRecallMissing(t, newStore(t)) })
+	t.Run("Overwrite", func(t *testing.T) { testOverwrite(t, newStore(t)) })
+	t.Run("ScopeIsolation", func(t *testing.T) { testScopeIsolation(t, newStore(t)) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, newStore(t)) })
+	t.Run("Forget", func(t *testing.T) { testForget(t, newStore(t)) })
+	t.Run("Summarize", func(t *testing.T) { testSummarize(t, newStore(t)) })
+	t.Run("CancelledContext", func(t *testing.T) { testCancelledContext(t, newStore(t)) })
+	t.Run("TTL", func(t *testing.T) {
+		store, ok := newStore(t).(framework.TTLMemoryStore)
+		if !ok {
+			t.Skip("store does not implement framework.TTLMemoryStore")
+		}
+		testTTL(t, store, opts)
+	})
+}
+
+func testRememberRecall(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	for _, scope := range scopes {
+		before := time.Now().Add(-time.Second)
+		require.NoError(t, store.Remember(ctx, "greeting", map[string]interface{}{"text": "hello"}, scope))
+		record, ok, err := store.Recall(ctx, "greeting", scope)
+		require.NoError(t, err)
+		require.True(t, ok, scope)
+		assert.Equal(t, "greeting", record.Key)
+		assert.Equal(t, scope, record.Scope)
+		assert.Equal(t, "hello", record.Value["text"])
+		assert.True(t, record.Timestamp.After(before), "timestamp must be set")
+	}
+}
+
+func testRecallMissing(t *testing.T, store framework.MemoryStore) {
+	record, ok, err := store.Recall(context.Background(), "missing", framework.MemoryScopeProject)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, record)
+}
+
+func testOverwrite(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	scope := framework.MemoryScopeProject
+	require.NoError(t, store.Remember(ctx, "k", map[string]interface{}{"v": "first"}, scope))
+	require.NoError(t, store.Remember(ctx, "k", map[string]interface{}{"v": "second"}, scope))
+	record, ok, err := store.Recall(ctx, "k", scope)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "second", record.Value["v"])
+	results, err := store.Search(ctx, "second", scope)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func testScopeIsolation(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	require.NoError(t, store.Remember(ctx, "shared", map[string]interface{}{"v": "session-only"}, framework.MemoryScopeSession))
+	for _, scope := range scopes[1:] {
+		_, ok, err := store.Recall(ctx, "shared", scope)
+		require.NoError(t, err)
+		assert.False(t, ok, scope)
+		results, err := store.Search(ctx, "session-only", scope)
+		require.NoError(t, err)
+		assert.Empty(t, results, scope)
+	}
+	require.NoError(t, store.Forget(ctx, "shared", framework.MemoryScopeProject))
+	_, ok, err := store.Recall(ctx, "shared", framework.MemoryScopeSession)
+	require.NoError(t, err)
+	assert.True(t, ok, "forget in another scope must not remove the record")
+}
+
+func testSearch(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	scope := framework.MemoryScopeProject
+	require.NoError(t, store.Remember(ctx, "a", map[string]interface{}{"note": "Parser handles Unicode"}, scope))
+	require.NoError(t, store.Remember(ctx, "b", map[string]interface{}{"note": "lexer rewrite"}, scope))
+	require.NoError(t, store.Remember(ctx, "c", map[string]interface{}{"note": "unrelated"}, framework.MemoryScopeGlobal))
+
+	results, err := store.Search(ctx, "unicode", scope)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "search must be case-insensitive")
+	assert.Equal(t, "a", results[0].Key)
+
+	results, err = store.Search(ctx, "PARSER HANDLES", scope)
+	require.NoError(t, err)
+	assert.Len(t, results, 1, "search must match substrings")
+
+	results, err = store.Search(ctx, "no such text", scope)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func testForget(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	scope := framework.MemoryScopeGlobal
+	require.NoError(t, store.Remember(ctx, "k", map[string]interface{}{"v": "gone soon"}, scope))
+	require.NoError(t, store.Forget(ctx, "k", scope))
+	_, ok, err := store.Recall(ctx, "k", scope)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	results, err := store.Search(ctx, "gone soon", scope)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.NoError(t, store.Forget(ctx, "never-stored", scope), "forgetting a missing key is a no-op")
+}
+
+func testSummarize(t *testing.T, store framework.MemoryStore) {
+	ctx := context.Background()
+	scope := framework.MemoryScopeSession
+	require.NoError(t, store.Remember(ctx, "alpha", map[string]interface{}{"v": 1}, scope))
+	require.NoError(t, store.Remember(ctx, "beta", map[string]interface{}{"v": 2}, scope))
+	summary, err := store.Summarize(ctx, scope)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(summary, "alpha") && strings.Contains(summary, "beta"), summary)
+}
+
+func testCancelledContext(t *testing.T, store framework.MemoryStore) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scope := framework.MemoryScopeSession
+	assert.ErrorIs(t, store.Remember(ctx, "k", map[string]interface{}{}, scope), context.Canceled)
+	_, _, err := store.Recall(ctx, "k", scope)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = store.Search(ctx, "k", scope)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, store.Forget(ctx, "k", scope), context.Canceled)
+	_, err = store.Summarize(ctx, scope)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testTTL(t *testing.T, store framework.TTLMemoryStore, opts Options) {
+	ctx := context.Background()
+	scope := framework.MemoryScopeProject
+	require.NoError(t, store.RememberWithTTL(ctx, "short", map[string]interface{}{"v": "ephemeral"}, scope, opts.TTL))
+	require.NoError(t, store.RememberWithTTL(ctx, "forever", map[string]interface{}{"v": "durable"}, scope, 0))
+
+	record, ok, err := store.Recall(ctx, "short", scope)
+	require.NoError(t, err)
+	require.True(t, ok, "record must be visible before its TTL elapses")
+	require.NotNil(t, record.ExpiresAt)
+
+	time.Sleep(opts.ExpiryWait)
+
+	_, ok, err = store.Recall(ctx, "short", scope)
+	require.NoError(t, err)
+	assert.False(t, ok, "expired record must not be recalled")
+	results, err := store.Search(ctx, "ephemeral", scope)
+	require.NoError(t, err)
+	assert.Empty(t, results, "expired record must not be searchable")
+	summary, err := store.Summarize(ctx, scope)
+	require.NoError(t, err)
+	assert.NotContains(t, summary, "short")
+
+	_, ok, err = store.Recall(ctx, "forever", scope)
+	require.NoError(t, err)
+	assert.True(t, ok, "non-positive TTL must not expire")
+}
@@ -0,0 +1,73 @@
+package memorytest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHybridMemoryConformance(t *testing.T) {
+	Run(t, func(t *testing.T) framework.MemoryStore {
+		store, err := framework.NewHybridMemory(t.TempDir())
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestLayeredMemoryConformance(t *testing.T) {
+	Run(t, func(t *testing.T) framework.MemoryStore {
+		root := t.TempDir()
+		store, err := framework.NewLayeredMemory(framework.MemoryLayout{
+			Workspace: filepath.Join(root, "workspace"),
+			User:      filepath.Join(root, "user"),
+			Global:    filepath.Join(root, "global"),
+		})
+		require.NoError(t, err)
+		return store
+	})
+}
+
+// TestLayeredMemoryFollowsTheUser checks user and global records persist
+// outside the workspace and that more specific scopes shadow them.
+func TestLayeredMemoryFollowsTheUser(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	layout := framework.MemoryLayout{
+		Workspace: filepath.Join(root, "project-a"),
+		User:      filepath.Join(root, "users", "ada"),
+		Global:    filepath.Join(root, "global"),
+	}
+	store, err := framework.NewLayeredMemory(layout)
+	require.NoError(t, err)
+	require.NoError(t, store.Remember(ctx, "indent", map[string]interface{}{"style": "tabs"}, framework.MemoryScopeGlobal))
+	require.NoError(t, store.Remember(ctx, "indent", map[string]interface{}{"style": "spaces"}, framework.MemoryScopeUser))
+	require.NoError(t, store.Remember(ctx, "tests", map[string]interface{}{"style": "table driven"}, framework.MemoryScopeGlobal))
+	require.FileExists(t, filepath.Join(layout.User, "user.json"))
+	require.FileExists(t, filepath.Join(layout.Global, "global.json"))
+
+	layout.Workspace = filepath.Join(root, "project-b")
+	other, err := framework.NewLayeredMemory(layout)
+	require.NoError(t, err)
+	record, ok, err := framework.RecallLayered(ctx, other, "indent")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, framework.MemoryScopeUser, record.Scope)
+
+	require.NoError(t, other.Remember(ctx, "indent", map[string]interface{}{"style": "gofmt"}, framework.MemoryScopeProject))
+	record, _, err = framework.RecallLayered(ctx, other, "indent")
+	require.NoError(t, err)
+	require.Equal(t, framework.MemoryScopeProject, record.Scope)
+
+	results, err := framework.SearchLayered(ctx, other, "s")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, framework.MemoryScopeProject, results[0].Scope)
+	require.Equal(t, "tests", results[1].Key)
+
+	results, err = framework.SearchLayered(ctx, other, "spaces")
+	require.NoError(t, err)
+	require.Empty(t, results, "the project record shadows the user one")
+}
@@ -0,0 +1,329 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBusChannelDenied is returned when an agent publishes to, or subscribes
+// to, a channel outside its BusScope.
+var ErrBusChannelDenied = errors.New("message bus channel not allowed")
+
+// Defaults for MessageBus.
+const (
+	// DefaultBusHistory bounds the messages a bus keeps for late readers.
+	DefaultBusHistory = 1024
+	// DefaultBusBuffer is how many undelivered messages a subscription
+	// holds before newer ones are dropped for it.
+	DefaultBusBuffer = 64
+)
+
+// busMemoryPrefix prefixes the session memory keys of published messages.
+const busMemoryPrefix = "bus."
+
+// busChannelPattern accepts dotted channel names such as
+// "findings.security".
+var busChannelPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// BusMessage is one finding shared on a MessageBus. Seq orders the messages
+// of a bus, starting at 1.
+type BusMessage struct {
+	Seq     int64                  `json:"seq"`
+	Channel string                 `json:"channel"`
+	Sender  string                 `json:"sender,omitempty"`
+	TaskID  string                 `json:"task_id,omitempty"`
+	Text    string                 `json:"text"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Time    time.Time              `json:"time"`
+}
+
+// BusScope limits the channels an agent may publish to and read. Entries
+// are channel names, "name.*" for a channel and everything below it, or "*".
+// An empty list allows every channel.
+type BusScope struct {
+	Publish   []string `json:"publish,omitempty" yaml:"publish,omitempty"`
+	Subscribe []string `json:"subscribe,omitempty" yaml:"subscribe,omitempty"`
+}
+
+// MatchBusChannel reports whether channel matches pattern: the exact name,
+// "name.*" for name and the channels below it, or "*".
+func MatchBusChannel(pattern, channel string) bool {
+	if pattern == "*" || pattern == channel {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, ".*")
+	return ok && (channel == prefix || strings.HasPrefix(channel, prefix+"."))
+}
+
+func busScopeAllows(patterns []string, channel string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if MatchBusChannel(pattern, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageBus lets the agents of one task, including delegates running as
+// sub-tasks and parallel graph branches, share findings while they run,
+// instead of only through the state merged when they finish. Agents reach
+// it through a BusClient attached to their context (see WithMessageBus).
+// When Memory is set every message is also remembered in session memory
+// under "bus.<id>.<channel>.<seq>", so later recall sees it.
+type MessageBus struct {
+	// ID keeps the memory keys of different buses apart, since every bus
+	// numbers its messages from 1. It is normally the task's ID.
+	ID     string
+	Memory MemoryStore
+	// History bounds the messages kept for Messages; zero uses
+	// DefaultBusHistory.
+	History int
+
+	mu      sync.Mutex
+	seq     int64
+	history []BusMessage
+	subs    map[*BusSubscription]struct{}
+}
+
+// NewMessageBus creates bus id, which remembers its messages in memory. An
+// empty id gets a random one; memory may be nil.
+func NewMessageBus(id string, memory MemoryStore) *MessageBus {
+	if id == "" {
+		id = newOTLPID(8)
+	}
+	return &MessageBus{ID: id, Memory: memory}
+}
+
+// Client returns a view of the bus for sender limited to scope.
+func (b *MessageBus) Client(sender string, scope BusScope) *BusClient {
+	return &BusClient{bus: b, sender: sender, scope: scope}
+}
+
+func (b *MessageBus) publish(ctx context.Context, msg BusMessage) (BusMessage, error) {
+	b.mu.Lock()
+	b.seq++
+	msg.Seq = b.seq
+	msg.Time = time.Now().UTC()
+	b.history = append(b.history, msg)
+	limit := b.History
+	if limit <= 0 {
+		limit = DefaultBusHistory
+	}
+	if len(b.history) > limit {
+		b.history = append([]BusMessage(nil), b.history[len(b.history)-limit:]...)
+	}
+	for sub := range b.subs {
+		if sub.accepts(msg.Channel) {
+			select {
+			case sub.ch <- msg:
+			default:
+				sub.dropped++
+			}
+		}
+	}
+	b.mu.Unlock()
+	if b.Memory == nil {
+		return msg, nil
+	}
+	record := map[string]interface{}{
+		"seq":     msg.Seq,
+		"channel": msg.Channel,
+		"sender":  msg.Sender,
+		"task_id": msg.TaskID,
+		"text":    msg.Text,
+		"time":    msg.Time.Format(time.RFC3339Nano),
+	}
+	if len(msg.Data) > 0 {
+		record["data"] = msg.Data
+	}
+	key := fmt.Sprintf("%s%s.%s.%d", busMemoryPrefix, b.ID, msg.Channel, msg.Seq)
+	if err := b.Memory.Remember(ctx, key, record, MemoryScopeSession); err != nil {
+		return msg, fmt.Errorf("remember bus message: %w", err)
+	}
+	return msg, nil
+}
+
+func (b *MessageBus) subscribe(client *BusClient, pattern string) *BusSubscription {
+	sub := &BusSubscription{bus: b, client: client, pattern: pattern, ch: make(chan BusMessage, DefaultBusBuffer)}
+	sub.C = sub.ch
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[*BusSubscription]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// BusSubscription delivers the messages published on matching channels
+// after it was created. A subscriber that falls DefaultBusBuffer messages
+// behind misses the newer ones; Messages still returns them.
+type BusSubscription struct {
+	// C receives the messages; it is closed by Close.
+	C <-chan BusMessage
+
+	bus     *MessageBus
+	client  *BusClient
+	pattern string
+	ch      chan BusMessage
+	dropped int
+}
+
+// accepts reports whether channel matches the subscription and its
+// client's scope. Callers hold the bus lock.
+func (s *BusSubscription) accepts(channel string) bool {
+	return MatchBusChannel(s.pattern, channel) && s.client.canRead(channel)
+}
+
+// Dropped reports how many messages were not delivered because C was full.
+func (s *BusSubscription) Dropped() int {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops delivery and closes C.
+func (s *BusSubscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.ch)
+	}
+}
+
+// BusClient is one agent's view of a MessageBus: its messages carry the
+// client's sender, and it may only use the channels its scope, and the
+// scope of the client it was narrowed from, allow.
+type BusClient struct {
+	bus    *MessageBus
+	sender string
+	scope  BusScope
+	parent *BusClient
+}
+
+// Bus returns the underlying bus.
+func (c *BusClient) Bus() *MessageBus { return c.bus }
+
+// Sender names the agent publishing through c.
+func (c *BusClient) Sender() string { return c.sender }
+
+// Narrow returns a client for sender that is limited to scope on top of
+// c's own limits. An empty sender keeps c's.
+func (c *BusClient) Narrow(sender string, scope BusScope) *BusClient {
+	if sender == "" {
+		sender = c.sender
+	}
+	return &BusClient{bus: c.bus, sender: sender, scope: scope, parent: c}
+}
+
+func (c *BusClient) canPublish(channel string) bool {
+	for client := c; client != nil; client = client.parent {
+		if !busScopeAllows(client.scope.Publish, channel) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *BusClient) canRead(channel string) bool {
+	for client := c; client != nil; client = client.parent {
+		if !busScopeAllows(client.scope.Subscribe, channel) {
+			return false
+		}
+	}
+	return true
+}
+
+// Publish shares text, with optional structured data, on channel. The
+// message is stamped with the sender and the running task's ID.
+func (c *BusClient) Publish(ctx context.Context, channel, text string, data map[string]interface{}) (BusMessage, error) {
+	if !busChannelPattern.MatchString(channel) {
+		return BusMessage{}, fmt.Errorf("invalid bus channel %q", channel)
+	}
+	if !c.canPublish(channel) {
+		return BusMessage{}, fmt.Errorf("%w: publish to %s", ErrBusChannelDenied, channel)
+	}
+	msg := BusMessage{Channel: channel, Sender: c.sender, Text: text, Data: data}
+	if task, ok := TaskContextFrom(ctx); ok {
+		msg.TaskID = task.ID
+		if msg.Sender == "" {
+			msg.Sender = task.Agent
+		}
+	}
+	return c.bus.publish(ctx, msg)
+}
+
+// Subscribe delivers later messages on channels matching pattern that c may
+// read. Naming a single channel c may not read fails.
+func (c *BusClient) Subscribe(pattern string) (*BusSubscription, error) {
+	if err := c.checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	return c.bus.subscribe(c, pattern), nil
+}
+
+// Messages returns the kept messages after seq on channels matching pattern
+// that c may read, oldest first.
+func (c *BusClient) Messages(pattern string, since int64) ([]BusMessage, error) {
+	if err := c.checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	var out []BusMessage
+	for _, msg := range c.bus.history {
+		if msg.Seq > since && MatchBusChannel(pattern, msg.Channel) && c.canRead(msg.Channel) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+func (c *BusClient) checkPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("bus channel pattern required")
+	}
+	if !strings.HasSuffix(pattern, "*") && !c.canRead(pattern) {
+		return fmt.Errorf("%w: read %s", ErrBusChannelDenied, pattern)
+	}
+	return nil
+}
+
+type messageBusKey struct{}
+
+// WithMessageBus attaches an unrestricted client of bus to ctx. Sub-tasks
+// spawned under ctx get a client narrowed to their SubTask.Channels.
+func WithMessageBus(ctx context.Context, bus *MessageBus) context.Context {
+	if bus == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, messageBusKey{}, bus.Client("", BusScope{}))
+}
+
+// WithBusScope narrows the client attached to ctx for sender; without a bus
+// it returns ctx unchanged.
+func WithBusScope(ctx context.Context, sender string, scope BusScope) context.Context {
+	client := MessageBusFrom(ctx)
+	if client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, messageBusKey{}, client.Narrow(sender, scope))
+}
+
+// MessageBusFrom returns the bus client attached to ctx, or nil.
+func MessageBusFrom(ctx context.Context) *BusClient {
+	if ctx == nil {
+		return nil
+	}
+	client, _ := ctx.Value(messageBusKey{}).(*BusClient)
+	return client
+}
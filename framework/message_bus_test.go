@@ -0,0 +1,120 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBusesKeepSeparateMemoryKeys(t *testing.T) {
+	memory, err := NewHybridMemory(t.TempDir())
+	require.NoError(t, err)
+	for _, id := range []string{"task-1", "task-2"} {
+		ctx := WithMessageBus(context.Background(), NewMessageBus(id, memory))
+		_, err := MessageBusFrom(ctx).Publish(ctx, "findings", "from "+id, nil)
+		require.NoError(t, err)
+	}
+	for _, id := range []string{"task-1", "task-2"} {
+		record, ok, err := memory.Recall(context.Background(), "bus."+id+".findings.1", MemoryScopeSession)
+		require.NoError(t, err)
+		require.True(t, ok, id)
+		require.Equal(t, "from "+id, record.Value["text"], "a later bus must not overwrite an earlier one")
+	}
+}
+
+func TestMessageBusDeliversAndRemembersFindings(t *testing.T) {
+	memory, err := NewHybridMemory(t.TempDir())
+	require.NoError(t, err)
+	bus := NewMessageBus("job", memory)
+	ctx := WithTaskContext(WithMessageBus(context.Background(), bus), TaskContext{ID: "job", Agent: "coding"})
+	client := MessageBusFrom(ctx)
+	require.NotNil(t, client)
+
+	sub, err := client.Subscribe("findings.*")
+	require.NoError(t, err)
+	defer sub.Close()
+	_, err = client.Publish(ctx, "findings.security", "hardcoded secret in config.go", map[string]interface{}{"file": "config.go"})
+	require.NoError(t, err)
+	_, err = client.Publish(ctx, "progress", "halfway", nil)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-sub.C:
+		require.Equal(t, int64(1), msg.Seq)
+		require.Equal(t, "coding", msg.Sender)
+		require.Equal(t, "job", msg.TaskID)
+		require.Equal(t, "hardcoded secret in config.go", msg.Text)
+	case <-time.After(time.Second):
+		t.Fatal("finding not delivered")
+	}
+	select {
+	case msg := <-sub.C:
+		t.Fatalf("unexpected delivery from %s", msg.Channel)
+	default:
+	}
+
+	all, err := client.Messages("*", 0)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	newer, err := client.Messages("*", 1)
+	require.NoError(t, err)
+	require.Equal(t, "progress", newer[0].Channel)
+
+	record, ok, err := memory.Recall(ctx, "bus.job.findings.security.1", MemoryScopeSession)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "hardcoded secret in config.go", record.Value["text"])
+
+	_, err = client.Publish(ctx, "Bad Channel", "x", nil)
+	require.ErrorContains(t, err, "invalid bus channel")
+}
+
+func TestBusClientScopes(t *testing.T) {
+	bus := NewMessageBus("", nil)
+	root := bus.Client("", BusScope{Subscribe: []string{"findings.*"}})
+	reviewer := root.Narrow("reviewer", BusScope{Publish: []string{"findings.review"}, Subscribe: []string{"*"}})
+	ctx := context.Background()
+
+	_, err := reviewer.Publish(ctx, "findings.review", "missing test", nil)
+	require.NoError(t, err)
+	_, err = reviewer.Publish(ctx, "findings.security", "x", nil)
+	require.True(t, errors.Is(err, ErrBusChannelDenied))
+	_, err = root.Publish(ctx, "progress", "started", nil)
+	require.NoError(t, err)
+
+	// The reviewer's own scope allows every channel, but the parent's does not.
+	seen, err := reviewer.Messages("*", 0)
+	require.NoError(t, err)
+	require.Len(t, seen, 1)
+	require.Equal(t, "reviewer", seen[0].Sender)
+	_, err = reviewer.Subscribe("progress")
+	require.True(t, errors.Is(err, ErrBusChannelDenied))
+}
+
+// TestDelegatorScopesChildBus checks sub-tasks publish under their agent
+// name and within SubTask.Channels.
+func TestDelegatorScopesChildBus(t *testing.T) {
+	d := NewDelegator()
+	d.Register("security", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		client := MessageBusFrom(ctx)
+		if _, err := client.Publish(ctx, "findings.security", "found a secret", nil); err != nil {
+			return nil, err
+		}
+		if _, err := client.Publish(ctx, "findings.style", "tabs", nil); !errors.Is(err, ErrBusChannelDenied) {
+			return nil, errors.New("publish outside scope allowed")
+		}
+		return &Result{Success: true}, nil
+	}))
+	bus := NewMessageBus("", nil)
+	ctx := WithMessageBus(context.Background(), bus)
+
+	_, err := d.Run(ctx, NewContext(), SubTask{Agent: "security", Channels: &BusScope{Publish: []string{"findings.security"}}})
+	require.NoError(t, err)
+	messages, err := MessageBusFrom(ctx).Messages("*", 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "security", messages[0].Sender)
+}
@@ -0,0 +1,145 @@
+package framework
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelRole names the kind of step a language model call serves. Routing by
+// role lets cheap steps (summaries) use a small model while code generation
+// uses a larger one.
+type ModelRole string
+
+const (
+	ModelRolePlanning      ModelRole = "planning"
+	ModelRoleCoding        ModelRole = "coding"
+	ModelRoleReview        ModelRole = "review"
+	ModelRoleSummarization ModelRole = "summarization"
+)
+
+// ModelRoles lists every routable role.
+func ModelRoles() []ModelRole {
+	return []ModelRole{ModelRolePlanning, ModelRoleCoding, ModelRoleReview, ModelRoleSummarization}
+}
+
+// Valid reports whether the role is one of ModelRoles.
+func (r ModelRole) Valid() bool {
+	for _, role := range ModelRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelRoleForTask maps a task type onto the role that should serve it.
+func ModelRoleForTask(taskType TaskType) ModelRole {
+	switch taskType {
+	case TaskTypePlanning:
+		return ModelRolePlanning
+	case TaskTypeReview:
+		return ModelRoleReview
+	default:
+		return ModelRoleCoding
+	}
+}
+
+// ModelRouter resolves the language model for a role. Roles without a route
+// use Default, so an empty router behaves like a single-model setup.
+type ModelRouter struct {
+	Default LanguageModel
+
+	mu     sync.RWMutex
+	routes map[ModelRole]LanguageModel
+	names  map[ModelRole]string
+}
+
+// NewModelRouter builds a router that falls back to def.
+func NewModelRouter(def LanguageModel) *ModelRouter {
+	return &ModelRouter{
+		Default: def,
+		routes:  make(map[ModelRole]LanguageModel),
+		names:   make(map[ModelRole]string),
+	}
+}
+
+// BuildModelRouter creates a router from role -> model name assignments.
+// factory is called once per distinct model name so roles sharing a model
+// share a client.
+func BuildModelRouter(def LanguageModel, assignments map[ModelRole]string, factory func(name string) LanguageModel) (*ModelRouter, error) {
+	router := NewModelRouter(def)
+	clients := make(map[string]LanguageModel)
+	for role, name := range assignments {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !role.Valid() {
+			return nil, fmt.Errorf("unknown model role %q", role)
+		}
+		client, ok := clients[name]
+		if !ok {
+			client = factory(name)
+			clients[name] = client
+		}
+		router.Route(role, name, client)
+	}
+	return router, nil
+}
+
+// Route assigns model to role. name is informational and reported by Names.
+func (r *ModelRouter) Route(role ModelRole, name string, model LanguageModel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.routes == nil {
+		r.routes = make(map[ModelRole]LanguageModel)
+		r.names = make(map[ModelRole]string)
+	}
+	r.routes[role] = model
+	r.names[role] = name
+}
+
+// For returns the model routed for role, or Default. A nil router returns nil.
+func (r *ModelRouter) For(role ModelRole) LanguageModel {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if model, ok := r.routes[role]; ok && model != nil {
+		return model
+	}
+	return r.Default
+}
+
+// Names returns the configured role -> model name assignments as sorted
+// "role=model" pairs, for status output.
+func (r *ModelRouter) Names() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pairs := make([]string, 0, len(r.names))
+	for role, name := range r.names {
+		pairs = append(pairs, string(role)+"="+name)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// MergeModelAssignments overlays later assignments on earlier ones; empty
+// names never clear an existing route.
+func MergeModelAssignments(layers ...map[ModelRole]string) map[ModelRole]string {
+	merged := make(map[ModelRole]string)
+	for _, layer := range layers {
+		for role, name := range layer {
+			if strings.TrimSpace(name) != "" {
+				merged[role] = name
+			}
+		}
+	}
+	return merged
+}
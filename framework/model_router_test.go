@@ -0,0 +1,45 @@
+package framework
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildModelRouterSharesClientsAndFallsBack(t *testing.T) {
+	def := &stubLLM{text: "default"}
+	created := map[string]int{}
+	router, err := BuildModelRouter(def, map[ModelRole]string{
+		ModelRolePlanning:      "big",
+		ModelRoleReview:        "big",
+		ModelRoleSummarization: "small",
+	}, func(name string) LanguageModel {
+		created[name]++
+		return &stubLLM{text: name}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"big": 1, "small": 1}, created)
+	assert.Same(t, router.For(ModelRolePlanning), router.For(ModelRoleReview))
+	assert.Equal(t, "small", router.For(ModelRoleSummarization).(*stubLLM).text)
+	assert.Same(t, def, router.For(ModelRoleCoding))
+	assert.Equal(t, []string{"planning=big", "review=big", "summarization=small"}, router.Names())
+
+	cfg := &Config{Models: router}
+	assert.Same(t, def, cfg.ModelFor(ModelRoleCoding, nil))
+	var empty *Config
+	assert.Same(t, def, empty.ModelFor(ModelRoleCoding, def))
+}
+
+func TestBuildModelRouterRejectsUnknownRole(t *testing.T) {
+	_, err := BuildModelRouter(nil, map[ModelRole]string{"drafting": "m"}, func(string) LanguageModel { return nil })
+	assert.Error(t, err)
+}
+
+func TestMergeModelAssignmentsOverlays(t *testing.T) {
+	merged := MergeModelAssignments(
+		map[ModelRole]string{ModelRoleCoding: "manifest", ModelRoleReview: "manifest"},
+		map[ModelRole]string{ModelRoleCoding: "workspace", ModelRoleReview: ""},
+	)
+	assert.Equal(t, map[ModelRole]string{ModelRoleCoding: "workspace", ModelRoleReview: "manifest"}, merged)
+}
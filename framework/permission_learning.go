@@ -0,0 +1,337 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PermissionUse is one permission a task needed, recorded in learning mode
+// whether or not the manifest declared it.
+type PermissionUse struct {
+	Type PermissionType `json:"type"`
+	// Action is the filesystem action, network direction, or IPC kind.
+	Action string `json:"action,omitempty"`
+	// Resource is the path, binary, host, capability, or IPC target. Tool
+	// requirements may carry globs such as the workspace scope.
+	Resource string   `json:"resource"`
+	Args     []string `json:"args,omitempty"`
+	Protocol string   `json:"protocol,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	// Tool names the tool whose declared requirements produced the use;
+	// empty for runtime checks.
+	Tool string `json:"tool,omitempty"`
+	// Declared reports whether the manifest already allowed the use.
+	Declared bool `json:"declared"`
+}
+
+// PermissionRecorder receives every permission check made in learning mode;
+// see PermissionManager.SetLearning.
+type PermissionRecorder interface {
+	RecordPermission(ctx context.Context, use PermissionUse)
+}
+
+// SetLearning switches the manager into learning mode: every permission
+// check is reported to recorder and requests the manifest does not declare
+// are allowed instead of denied. Policy decisions and HITL approvals for
+// declared permissions still apply. Passing nil restores enforcement.
+func (m *PermissionManager) SetLearning(recorder PermissionRecorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.learner = recorder
+}
+
+// learn records use when learning and reports whether learning is on, in
+// which case the caller allows the request even when it is undeclared.
+func (m *PermissionManager) learn(ctx context.Context, use PermissionUse) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	learner := m.learner
+	m.mu.RUnlock()
+	if learner == nil {
+		return false
+	}
+	learner.RecordPermission(ctx, use)
+	return true
+}
+
+// learning reports whether SetLearning installed a recorder.
+func (m *PermissionManager) learning() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.learner != nil
+}
+
+// learnRequirements records the permissions tool declares, which a manifest
+// must cover before the tool may run at all.
+func (m *PermissionManager) learnRequirements(ctx context.Context, tool string, requirements *PermissionSet) {
+	if requirements == nil {
+		return
+	}
+	for _, perm := range requirements.FileSystem {
+		m.learn(ctx, PermissionUse{
+			Type:     PermissionTypeFilesystem,
+			Action:   string(perm.Action),
+			Resource: perm.Path,
+			Tool:     tool,
+			Declared: m.findFilesystemPermission(perm.Action, perm.Path) != nil,
+		})
+	}
+	for _, perm := range requirements.Executables {
+		m.learn(ctx, PermissionUse{
+			Type:     PermissionTypeExecutable,
+			Resource: perm.Binary,
+			Tool:     tool,
+			Declared: m.findExecutablePermission(perm.Binary) != nil,
+		})
+	}
+	for _, perm := range requirements.Network {
+		m.learn(ctx, PermissionUse{
+			Type:     PermissionTypeNetwork,
+			Action:   perm.Direction,
+			Resource: perm.Host,
+			Protocol: perm.Protocol,
+			Port:     perm.Port,
+			Tool:     tool,
+			Declared: m.findNetworkPermission(perm.Direction, perm.Protocol, perm.Host, perm.Port) != nil,
+		})
+	}
+}
+
+// PermissionLearner is a PermissionRecorder that appends each task's
+// distinct permission uses to <task-id>.jsonl under Dir. Uses are
+// attributed through the TaskContext on ctx; uses outside a task are
+// dropped.
+type PermissionLearner struct {
+	Dir string
+
+	mu   sync.Mutex
+	seen map[string]map[string]bool
+}
+
+// NewPermissionLearner records into dir, creating it as needed.
+func NewPermissionLearner(dir string) *PermissionLearner {
+	return &PermissionLearner{Dir: dir, seen: make(map[string]map[string]bool)}
+}
+
+// RecordPermission implements PermissionRecorder.
+func (l *PermissionLearner) RecordPermission(ctx context.Context, use PermissionUse) {
+	task, ok := TaskContextFrom(ctx)
+	if !ok || task.ID == "" {
+		return
+	}
+	data, err := json.Marshal(use)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seen := l.seen[task.ID]
+	if seen == nil {
+		seen = make(map[string]bool)
+		l.seen[task.ID] = seen
+	}
+	if seen[string(data)] {
+		return
+	}
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(PermissionLogPath(l.Dir, task.ID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err == nil {
+		seen[string(data)] = true
+	}
+}
+
+// PermissionLogPath returns the learned-permission log for taskID under dir.
+func PermissionLogPath(dir, taskID string) string {
+	return filepath.Join(dir, replayFileUnsafe.ReplaceAllString(taskID, "_")+".jsonl")
+}
+
+// ReadPermissionUses loads the uses a PermissionLearner recorded for taskID.
+func ReadPermissionUses(dir, taskID string) ([]PermissionUse, error) {
+	file, err := os.Open(PermissionLogPath(dir, taskID))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var uses []PermissionUse
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var use PermissionUse
+		if err := json.Unmarshal(scanner.Bytes(), &use); err != nil {
+			return nil, err
+		}
+		uses = append(uses, use)
+	}
+	return uses, scanner.Err()
+}
+
+// SuggestPermissions returns the smallest permission set allowing every
+// use. Paths other recorded patterns already cover are dropped, workspace
+// paths are written with the ${workspace} placeholder, and a binary run with
+// several argument lists is allowed their common prefix followed by "*".
+// Entries matching one in base keep its justification and HITL flag.
+func SuggestPermissions(workspace string, uses []PermissionUse, base *PermissionSet) PermissionSet {
+	if base == nil {
+		base = &PermissionSet{}
+	}
+	ws := filepath.ToSlash(filepath.Clean(workspace))
+	var set PermissionSet
+
+	paths := make(map[FileSystemAction]map[string]bool)
+	binaries := make(map[string]bool)
+	argLists := make(map[string]map[string][]string)
+	network := make(map[NetworkPermission]bool)
+	capabilities := make(map[string]bool)
+	ipc := make(map[IPCPermission]bool)
+	for _, use := range uses {
+		switch use.Type {
+		case PermissionTypeFilesystem:
+			action := FileSystemAction(use.Action)
+			if paths[action] == nil {
+				paths[action] = make(map[string]bool)
+			}
+			paths[action][filepath.ToSlash(use.Resource)] = true
+		case PermissionTypeExecutable:
+			binaries[use.Resource] = true
+			if use.Tool == "" {
+				if argLists[use.Resource] == nil {
+					argLists[use.Resource] = make(map[string][]string)
+				}
+				argLists[use.Resource][strings.Join(use.Args, "\x00")] = use.Args
+			}
+		case PermissionTypeNetwork:
+			network[NetworkPermission{Direction: use.Action, Protocol: use.Protocol, Host: use.Resource, Port: use.Port}] = true
+		case PermissionTypeCapability:
+			capabilities[use.Resource] = true
+		case PermissionTypeIPC:
+			ipc[IPCPermission{Kind: use.Action, Target: use.Resource}] = true
+		}
+	}
+
+	for action, resources := range paths {
+		for path := range resources {
+			covered := false
+			for other := range resources {
+				if other != path && matchGlob(other, path) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				continue
+			}
+			perm := FileSystemPermission{Action: action, Path: workspacePlaceholder(ws, path)}
+			for _, declared := range base.FileSystem {
+				if declared.Action == action && declared.Path == perm.Path {
+					perm.Justification = declared.Justification
+					perm.HITLRequired = declared.HITLRequired
+					perm.ReadOnlyMount = declared.ReadOnlyMount
+				}
+			}
+			set.FileSystem = append(set.FileSystem, perm)
+		}
+	}
+	for binary := range binaries {
+		perm := ExecutablePermission{Binary: binary, Args: suggestArgs(argLists[binary])}
+		for _, declared := range base.Executables {
+			if declared.Binary == binary {
+				perm.HITLRequired = declared.HITLRequired
+				perm.ProxyRequired = declared.ProxyRequired
+				perm.Env = declared.Env
+			}
+		}
+		set.Executables = append(set.Executables, perm)
+	}
+	for perm := range network {
+		for _, declared := range base.Network {
+			if declared.Direction == perm.Direction && declared.Host == perm.Host && declared.Port == perm.Port {
+				perm.Description = declared.Description
+				perm.HITLRequired = declared.HITLRequired
+			}
+		}
+		set.Network = append(set.Network, perm)
+	}
+	for capability := range capabilities {
+		set.Capabilities = append(set.Capabilities, CapabilityPermission{Capability: capability})
+	}
+	for perm := range ipc {
+		set.IPC = append(set.IPC, perm)
+	}
+	set.HITLRequired = append(set.HITLRequired, base.HITLRequired...)
+	set.Sort()
+	// Sort orders by path alone; keep each path's actions in a stable order.
+	sort.SliceStable(set.FileSystem, func(i, j int) bool {
+		if set.FileSystem[i].Path != set.FileSystem[j].Path {
+			return set.FileSystem[i].Path < set.FileSystem[j].Path
+		}
+		return set.FileSystem[i].Action < set.FileSystem[j].Action
+	})
+	return set
+}
+
+// SuggestManifest returns a copy of base whose permissions are exactly
+// those SuggestPermissions derives from uses.
+func SuggestManifest(base *AgentManifest, workspace string, uses []PermissionUse) *AgentManifest {
+	suggested := *base
+	suggested.SourcePath = ""
+	suggested.Spec.Permissions = SuggestPermissions(workspace, uses, &base.Spec.Permissions)
+	return &suggested
+}
+
+// suggestArgs allows every recorded argument list of one binary: the list
+// itself when there is one, else their common prefix and "*". Nil allows
+// any arguments.
+func suggestArgs(lists map[string][]string) []string {
+	if len(lists) == 0 {
+		return nil
+	}
+	var prefix []string
+	first := true
+	for _, args := range lists {
+		if first {
+			prefix = append([]string(nil), args...)
+			first = false
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(args) && prefix[n] == args[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	if len(lists) == 1 {
+		return prefix
+	}
+	if len(prefix) == 0 {
+		return nil
+	}
+	return append(prefix, "*")
+}
+
+// workspacePlaceholder rewrites paths inside ws relative to ${workspace}.
+func workspacePlaceholder(ws, path string) string {
+	if ws == "" || ws == "." {
+		return path
+	}
+	if path == ws {
+		return "${workspace}"
+	}
+	if strings.HasPrefix(path, ws+"/") {
+		return "${workspace}" + strings.TrimPrefix(path, ws)
+	}
+	return path
+}
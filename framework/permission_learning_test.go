@@ -0,0 +1,74 @@
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionManagerLearningAllowsAndRecordsUndeclared(t *testing.T) {
+	manager := newTestManager(t, "/workspace", &PermissionSet{
+		FileSystem:  []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/docs/**"}},
+		Executables: []ExecutablePermission{{Binary: "go", Args: []string{"test", "*"}}},
+	})
+	dir := t.TempDir()
+	ctx := WithTaskContext(context.Background(), TaskContext{ID: "task/1"})
+
+	require.Error(t, manager.CheckFileAccess(ctx, "agent", FileSystemWrite, "main.go"))
+	manager.SetLearning(NewPermissionLearner(dir))
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent", FileSystemWrite, "main.go"))
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent", FileSystemWrite, "main.go"))
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent", FileSystemRead, "docs/a.md"))
+	require.NoError(t, manager.CheckExecutable(ctx, "agent", "go", []string{"build", "./..."}, nil))
+	require.NoError(t, manager.CheckNetwork(ctx, "agent", "egress", "tcp", "proxy.golang.org", 443))
+	require.NoError(t, manager.AuthorizeTool(ctx, "agent", stubTool{name: "rg", perms: &PermissionSet{
+		Executables: []ExecutablePermission{{Binary: "rg"}},
+	}}, nil))
+	require.NoError(t, manager.CheckFileAccess(context.Background(), "agent", FileSystemRead, "outside-task.txt"))
+
+	uses, err := ReadPermissionUses(dir, "task/1")
+	require.NoError(t, err)
+	require.Equal(t, []PermissionUse{
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemWrite), Resource: "/workspace/main.go"},
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemRead), Resource: "/workspace/docs/a.md", Declared: true},
+		{Type: PermissionTypeExecutable, Resource: "go", Args: []string{"build", "./..."}},
+		{Type: PermissionTypeNetwork, Action: "egress", Resource: "proxy.golang.org", Protocol: "tcp", Port: 443},
+		{Type: PermissionTypeExecutable, Resource: "rg", Tool: "rg"},
+	}, uses, "repeats and uses outside a task are not recorded")
+
+	manager.SetLearning(nil)
+	require.Error(t, manager.CheckFileAccess(ctx, "agent", FileSystemWrite, "main.go"))
+}
+
+func TestSuggestPermissionsCoversExactlyTheUses(t *testing.T) {
+	uses := []PermissionUse{
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemRead), Resource: "/workspace/**", Tool: "file_read"},
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemRead), Resource: "/workspace/main.go"},
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemWrite), Resource: "/workspace/main.go"},
+		{Type: PermissionTypeFilesystem, Action: string(FileSystemRead), Resource: "/etc/hosts"},
+		{Type: PermissionTypeExecutable, Resource: "go", Args: []string{"test", "./pkg"}},
+		{Type: PermissionTypeExecutable, Resource: "go", Args: []string{"test", "./..."}},
+		{Type: PermissionTypeExecutable, Resource: "gofmt", Args: []string{"-l", "."}},
+		{Type: PermissionTypeExecutable, Resource: "git", Tool: "git_status"},
+		{Type: PermissionTypeNetwork, Action: "egress", Protocol: "tcp", Resource: "proxy.golang.org", Port: 443},
+	}
+	base := &PermissionSet{
+		FileSystem:  []FileSystemPermission{{Action: FileSystemWrite, Path: "${workspace}/main.go", HITLRequired: true}},
+		Executables: []ExecutablePermission{{Binary: "git", HITLRequired: true}, {Binary: "npm"}},
+	}
+
+	set := SuggestPermissions("/workspace", uses, base)
+	require.Equal(t, []FileSystemPermission{
+		{Action: FileSystemRead, Path: "${workspace}/**"},
+		{Action: FileSystemWrite, Path: "${workspace}/main.go", HITLRequired: true},
+		{Action: FileSystemRead, Path: "/etc/hosts"},
+	}, set.FileSystem)
+	require.Equal(t, []ExecutablePermission{
+		{Binary: "git", HITLRequired: true},
+		{Binary: "go", Args: []string{"test", "*"}},
+		{Binary: "gofmt", Args: []string{"-l", "."}},
+	}, set.Executables)
+	require.Equal(t, []NetworkPermission{{Direction: "egress", Protocol: "tcp", Host: "proxy.golang.org", Port: 443}}, set.Network)
+	require.NoError(t, set.Validate())
+}
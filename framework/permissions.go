@@ -23,6 +23,9 @@ const (
 	PermissionTypeIPC        PermissionType = "ipc"
 	PermissionTypeHITL       PermissionType = "hitl"
 	permissionMatchAll                      = "**"
+	// PermissionAnyBinary as an executable permission's binary allows every
+	// binary, for fully trusted environments.
+	PermissionAnyBinary = "*"
 )
 
 // FileSystemAction enumerates filesystem operations.
@@ -163,6 +166,9 @@ func (e *PermissionDeniedError) Error() string {
 	return fmt.Sprintf("permission denied: %s (%s)", e.Descriptor.Action, e.Message)
 }
 
+// ErrorKind reports ErrorKindToolDenied.
+func (e *PermissionDeniedError) ErrorKind() ErrorKind { return ErrorKindToolDenied }
+
 // PermissionManager enforces the declared permission set for runtime actions.
 type PermissionManager struct {
 	basePath   string
@@ -174,6 +180,9 @@ type PermissionManager struct {
 	mu         sync.RWMutex
 	grantClock func() time.Time
 	netPolicy  []NetworkRule
+	policy     PolicyEvaluator
+	writeHook  func(ctx context.Context, path string)
+	learner    PermissionRecorder
 }
 
 // NewPermissionManager creates an enforcement instance.
@@ -206,6 +215,24 @@ func (m *PermissionManager) AttachRuntime(runtime SandboxRuntime) {
 	}
 }
 
+// SetPolicyEvaluator installs an optional policy-as-code hook consulted after
+// manifest matching. Passing nil removes it.
+func (m *PermissionManager) SetPolicyEvaluator(evaluator PolicyEvaluator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = evaluator
+}
+
+// SetFileWriteHook installs a hook called with the resolved path after each
+// granted fs:write check. Tools check before they write, so the hook sees
+// the file's content from before the write, e.g. to checkpoint it. Passing
+// nil removes it.
+func (m *PermissionManager) SetFileWriteHook(hook func(ctx context.Context, path string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeHook = hook
+}
+
 // inflateScopes rewrites any workspace placeholders inside the declared
 // filesystem permissions so later matching can operate on concrete paths.
 func (m *PermissionManager) inflateScopes() {
@@ -250,7 +277,12 @@ func (m *PermissionManager) AuthorizeTool(ctx context.Context, agentID string, t
 	if err := requirements.Validate(); err != nil {
 		return fmt.Errorf("tool %s permission invalid: %w", tool.Name(), err)
 	}
-	if err := m.ensureSubset(requirements.Permissions); err != nil {
+	err := m.ensureSubset(requirements.Permissions)
+	if m.learning() {
+		m.learnRequirements(ctx, tool.Name(), requirements.Permissions)
+		err = nil
+	}
+	if err != nil {
 		return fmt.Errorf("tool %s exceeds agent permissions: %w", tool.Name(), err)
 	}
 	desc := PermissionDescriptor{
@@ -258,6 +290,9 @@ func (m *PermissionManager) AuthorizeTool(ctx context.Context, agentID string, t
 		Action:   fmt.Sprintf("tool:%s", tool.Name()),
 		Resource: agentID,
 	}
+	if err := m.checkPolicy(ctx, agentID, desc, args); err != nil {
+		return err
+	}
 	m.log(ctx, agentID, desc, "tool_allowed", nil)
 	return nil
 }
@@ -272,6 +307,9 @@ func (m *PermissionManager) CheckFileAccess(ctx context.Context, agentID string,
 		return err
 	}
 	perm := m.findFilesystemPermission(action, clean)
+	if m.learn(ctx, PermissionUse{Type: PermissionTypeFilesystem, Action: string(action), Resource: clean, Declared: perm != nil}) && perm == nil {
+		perm = &FileSystemPermission{Action: action, Path: clean}
+	}
 	if perm == nil {
 		return m.deny(ctx, agentID, PermissionDescriptor{
 			Type:     PermissionTypeFilesystem,
@@ -289,6 +327,14 @@ func (m *PermissionManager) CheckFileAccess(ctx context.Context, agentID string,
 			return err
 		}
 	}
+	if err := m.checkPolicy(ctx, agentID, PermissionDescriptor{
+		Type:         PermissionTypeFilesystem,
+		Action:       string(action),
+		Resource:     clean,
+		RequiresHITL: perm.HITLRequired,
+	}, nil); err != nil {
+		return err
+	}
 	m.log(ctx, agentID, PermissionDescriptor{
 		Type:     PermissionTypeFilesystem,
 		Action:   string(action),
@@ -296,12 +342,24 @@ func (m *PermissionManager) CheckFileAccess(ctx context.Context, agentID string,
 	}, "granted", map[string]interface{}{
 		"pattern": perm.Path,
 	})
+	if action == FileSystemWrite {
+		m.mu.RLock()
+		hook := m.writeHook
+		m.mu.RUnlock()
+		if hook != nil {
+			hook(ctx, clean)
+		}
+	}
 	return nil
 }
 
 // CheckExecutable validates binary execution.
 func (m *PermissionManager) CheckExecutable(ctx context.Context, agentID, binary string, args []string, env []string) error {
 	perm := m.findExecutablePermission(binary)
+	declared := perm != nil && matchArgs(perm.Args, args) && matchEnv(perm.Env, env)
+	if m.learn(ctx, PermissionUse{Type: PermissionTypeExecutable, Resource: binary, Args: args, Declared: declared}) && !declared {
+		perm = &ExecutablePermission{Binary: binary}
+	}
 	if perm == nil {
 		return m.deny(ctx, agentID, PermissionDescriptor{
 			Type:     PermissionTypeExecutable,
@@ -333,6 +391,15 @@ func (m *PermissionManager) CheckExecutable(ctx context.Context, agentID, binary
 			return err
 		}
 	}
+	if err := m.checkPolicy(ctx, agentID, PermissionDescriptor{
+		Type:         PermissionTypeExecutable,
+		Action:       fmt.Sprintf("exec:binary:%s", binary),
+		Resource:     binary,
+		Metadata:     map[string]string{"args": strings.Join(args, " ")},
+		RequiresHITL: perm.HITLRequired,
+	}, nil); err != nil {
+		return err
+	}
 	m.log(ctx, agentID, PermissionDescriptor{
 		Type:     PermissionTypeExecutable,
 		Action:   fmt.Sprintf("exec:%s", binary),
@@ -347,6 +414,9 @@ func (m *PermissionManager) CheckExecutable(ctx context.Context, agentID, binary
 // CheckNetwork validates network access.
 func (m *PermissionManager) CheckNetwork(ctx context.Context, agentID string, direction string, protocol string, host string, port int) error {
 	perm := m.findNetworkPermission(direction, protocol, host, port)
+	if m.learn(ctx, PermissionUse{Type: PermissionTypeNetwork, Action: direction, Resource: host, Protocol: protocol, Port: port, Declared: perm != nil}) && perm == nil {
+		perm = &NetworkPermission{Direction: direction, Protocol: protocol, Host: host, Port: port}
+	}
 	if perm == nil {
 		return m.deny(ctx, agentID, PermissionDescriptor{
 			Type:     PermissionTypeNetwork,
@@ -364,6 +434,14 @@ func (m *PermissionManager) CheckNetwork(ctx context.Context, agentID string, di
 			return err
 		}
 	}
+	if err := m.checkPolicy(ctx, agentID, PermissionDescriptor{
+		Type:         PermissionTypeNetwork,
+		Action:       fmt.Sprintf("net:%s:%s", direction, protocol),
+		Resource:     fmt.Sprintf("%s:%d", host, port),
+		RequiresHITL: perm.HITLRequired,
+	}, nil); err != nil {
+		return err
+	}
 	m.log(ctx, agentID, PermissionDescriptor{
 		Type:     PermissionTypeNetwork,
 		Action:   fmt.Sprintf("net:%s", direction),
@@ -397,13 +475,21 @@ func (m *PermissionManager) recordNetworkRule(direction, protocol, host string,
 
 // CheckCapability verifies capability usage.
 func (m *PermissionManager) CheckCapability(ctx context.Context, agentID string, capability string) error {
-	if !m.hasCapability(capability) {
+	declared := m.hasCapability(capability)
+	if !m.learn(ctx, PermissionUse{Type: PermissionTypeCapability, Resource: capability, Declared: declared}) && !declared {
 		return m.deny(ctx, agentID, PermissionDescriptor{
 			Type:     PermissionTypeCapability,
 			Action:   fmt.Sprintf("cap:%s", capability),
 			Resource: capability,
 		}, "capability not declared")
 	}
+	if err := m.checkPolicy(ctx, agentID, PermissionDescriptor{
+		Type:     PermissionTypeCapability,
+		Action:   fmt.Sprintf("cap:%s", capability),
+		Resource: capability,
+	}, nil); err != nil {
+		return err
+	}
 	m.log(ctx, agentID, PermissionDescriptor{
 		Type:     PermissionTypeCapability,
 		Action:   fmt.Sprintf("cap:%s", capability),
@@ -415,6 +501,9 @@ func (m *PermissionManager) CheckCapability(ctx context.Context, agentID string,
 // CheckIPC validates IPC usage.
 func (m *PermissionManager) CheckIPC(ctx context.Context, agentID string, kind string, target string) error {
 	perm := m.findIPCPermission(kind, target)
+	if m.learn(ctx, PermissionUse{Type: PermissionTypeIPC, Action: kind, Resource: target, Declared: perm != nil}) && perm == nil {
+		perm = &IPCPermission{Kind: kind, Target: target}
+	}
 	if perm == nil {
 		return m.deny(ctx, agentID, PermissionDescriptor{
 			Type:     PermissionTypeIPC,
@@ -503,17 +592,28 @@ func (m *PermissionManager) findFilesystemPermission(action FileSystemAction, pa
 	return nil
 }
 
-// findExecutablePermission locates the manifest entry authorizing a binary.
+// DeclaresExecutable reports whether the manifest lets agents run binary,
+// without recording a denial. Tools use it to pick optional backends.
+func (m *PermissionManager) DeclaresExecutable(binary string) bool {
+	return m.findExecutablePermission(binary) != nil
+}
+
+// findExecutablePermission locates the manifest entry authorizing a binary,
+// preferring an entry for the binary itself over a PermissionAnyBinary one.
 func (m *PermissionManager) findExecutablePermission(binary string) *ExecutablePermission {
 	if m == nil || m.declared == nil {
 		return nil
 	}
+	var wildcard *ExecutablePermission
 	for _, perm := range m.declared.Executables {
 		if perm.Binary == binary {
 			return &perm
 		}
+		if perm.Binary == PermissionAnyBinary && wildcard == nil {
+			wildcard = &perm
+		}
 	}
-	return nil
+	return wildcard
 }
 
 // findNetworkPermission resolves whether the host/port pair is authorized for
@@ -646,6 +746,36 @@ func (m *PermissionManager) RequireApproval(ctx context.Context, agentID string,
 	return nil
 }
 
+// checkPolicy runs the policy evaluator, if any, for a request the manifest
+// already allows. Deny decisions and evaluation errors reject the request;
+// hitl decisions route through the usual grant cache.
+func (m *PermissionManager) checkPolicy(ctx context.Context, agentID string, desc PermissionDescriptor, args map[string]interface{}) error {
+	m.mu.RLock()
+	evaluator := m.policy
+	m.mu.RUnlock()
+	if evaluator == nil {
+		return nil
+	}
+	input := NewPolicyInput(ctx, agentID, desc)
+	input.Args = args
+	decision, err := evaluator.Evaluate(ctx, input)
+	if err != nil {
+		return m.deny(ctx, agentID, desc, fmt.Sprintf("policy evaluation failed: %v", err))
+	}
+	switch decision.Effect {
+	case PolicyDeny:
+		reason := decision.Reason
+		if reason == "" {
+			reason = "denied by policy"
+		}
+		return m.deny(ctx, agentID, desc, reason)
+	case PolicyHITL:
+		desc.RequiresHITL = true
+		return m.ensureGrant(ctx, agentID, desc)
+	}
+	return nil
+}
+
 // deny records an audit event and returns a structured error describing why an
 // action was blocked.
 func (m *PermissionManager) deny(ctx context.Context, agentID string, desc PermissionDescriptor, reason string) error {
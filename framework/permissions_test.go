@@ -87,6 +87,27 @@ func TestPermissionManagerCheckFileAccess(t *testing.T) {
 	require.Error(t, err, "write action not declared should be denied")
 }
 
+// TestPermissionManagerFileWriteHook checks the hook sees granted writes
+// only, with the resolved path.
+func TestPermissionManagerFileWriteHook(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t, "/workspace", &PermissionSet{
+		FileSystem: []FileSystemPermission{
+			{Action: FileSystemRead, Path: "/workspace/**"},
+			{Action: FileSystemWrite, Path: "/workspace/src/**"},
+		},
+	})
+	var written []string
+	manager.SetFileWriteHook(func(ctx context.Context, path string) {
+		written = append(written, path)
+	})
+
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent-1", FileSystemRead, "src/main.go"))
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent-1", FileSystemWrite, "src/main.go"))
+	require.Error(t, manager.CheckFileAccess(ctx, "agent-1", FileSystemWrite, "docs/readme.md"))
+	require.Equal(t, []string{"/workspace/src/main.go"}, written)
+}
+
 // TestPermissionHelpers confirms helper constructors produce intuitive globs
 // and executable permissions.
 func TestPermissionHelpers(t *testing.T) {
@@ -183,6 +204,20 @@ func TestPermissionManagerCapabilityCheck(t *testing.T) {
 	require.Error(t, manager.CheckCapability(ctx, "agent", "SYS_PTRACE"))
 }
 
+func TestPermissionManagerAnyBinary(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t, "/workspace", &PermissionSet{
+		FileSystem: []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}},
+		Executables: []ExecutablePermission{
+			{Binary: "git", Args: []string{"status"}},
+			{Binary: PermissionAnyBinary},
+		},
+	})
+
+	require.NoError(t, manager.CheckExecutable(ctx, "agent", "make", []string{"build"}, nil))
+	require.Error(t, manager.CheckExecutable(ctx, "agent", "git", []string{"push"}, nil), "a binary's own entry wins over the wildcard")
+}
+
 type stubHITLProvider struct {
 	grants   []*PermissionGrant
 	requests []PermissionRequest
@@ -202,3 +237,82 @@ func (s *stubHITLProvider) RequestPermission(ctx context.Context, req Permission
 	}
 	return grant, nil
 }
+
+type execStubTool struct{ stubTool }
+
+func (t execStubTool) Category() string { return "execution" }
+
+// TestRequireApprovalForGatesExecTools covers the degraded sandbox policy:
+// exec tools need approval, others run as before, and denials stick.
+func TestRequireApprovalForGatesExecTools(t *testing.T) {
+	hitl := &stubHITLProvider{}
+	manager, err := NewPermissionManager("/workspace", &PermissionSet{FileSystem: []FileSystemPermission{
+		{Action: FileSystemRead, Path: "/workspace/**"},
+	}}, nil, hitl)
+	require.NoError(t, err)
+	read := &PermissionSet{FileSystem: []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}}}
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(execStubTool{stubTool{name: "exec_run_tests", perms: read}}))
+	require.NoError(t, registry.Register(execStubTool{stubTool{name: "exec_denied", perms: read}}))
+	require.NoError(t, registry.Register(stubTool{name: "search", perms: read}))
+	registry.UsePermissionManager("agent", manager)
+	registry.UseAgentSpec("agent", &AgentRuntimeSpec{ToolPolicies: map[string]ToolPolicy{
+		"exec_denied": {Execute: AgentPermissionDeny},
+	}})
+
+	gated := registry.RequireApprovalFor(IsExecTool)
+	require.Equal(t, []string{"exec_run_tests"}, gated)
+
+	tool, ok := registry.Get("exec_run_tests")
+	require.True(t, ok)
+	_, err = tool.Execute(context.Background(), NewContext(), nil)
+	require.NoError(t, err)
+	require.Len(t, hitl.requests, 1)
+	require.Equal(t, "tool_exec:exec_run_tests", hitl.requests[0].Permission.Action)
+
+	tool, _ = registry.Get("search")
+	_, err = tool.Execute(context.Background(), NewContext(), nil)
+	require.NoError(t, err)
+	require.Len(t, hitl.requests, 1)
+
+	tool, _ = registry.Get("exec_denied")
+	_, err = tool.Execute(context.Background(), NewContext(), nil)
+	require.ErrorContains(t, err, "denied by policy")
+}
+
+type gitStubTool struct{ stubTool }
+
+func (t gitStubTool) Category() string { return "git" }
+
+// runnerStubTool declares no executables but holds a runner, like a plugin
+// tool whose manifest forgot its binary.
+type runnerStubTool struct {
+	stubTool
+	Runner CommandRunner
+}
+
+// TestRequireApprovalForGatesHostCommands covers tools outside the execution
+// category that still run commands on a degraded sandbox.
+func TestRequireApprovalForGatesHostCommands(t *testing.T) {
+	hitl := &stubHITLProvider{}
+	manager, err := NewPermissionManager("/workspace", NewExecutionPermissionSet("/workspace", "git", []string{"*"}), nil, hitl)
+	require.NoError(t, err)
+	read := &PermissionSet{FileSystem: []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}}}
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(gitStubTool{stubTool{name: "git_commit", perms: NewExecutionPermissionSet("/workspace", "git", []string{"*"})}}))
+	require.NoError(t, registry.Register(&runnerStubTool{stubTool: stubTool{name: "plugin_lint", perms: read}, Runner: &LocalCommandRunner{}}))
+	require.NoError(t, registry.Register(&runnerStubTool{stubTool: stubTool{name: "no_runner", perms: read}}))
+	require.NoError(t, registry.Register(stubTool{name: "search", perms: read}))
+	registry.UsePermissionManager("agent", manager)
+
+	gated := registry.RequireApprovalFor(RunsCommands)
+	require.Equal(t, []string{"git_commit", "plugin_lint"}, gated)
+
+	tool, ok := registry.Get("git_commit")
+	require.True(t, ok)
+	require.True(t, RunsCommands(tool), "the instrumented wrapper is seen through")
+	_, err = tool.Execute(context.Background(), NewContext(), nil)
+	require.NoError(t, err)
+	require.Len(t, hitl.requests, 1)
+	require.Equal(t, "tool_exec:git_commit", hitl.requests[0].Permission.Action)
+}
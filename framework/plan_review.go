@@ -0,0 +1,69 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrPlanRejected is returned when a reviewer declines a plan. Agents stop
+// without executing any step.
+var ErrPlanRejected = errors.New("plan rejected by reviewer")
+
+// PlanReviewer approves a plan before a planner executes it. Reviewers may
+// return an edited plan, which replaces the generated one, or
+// ErrPlanRejected.
+type PlanReviewer interface {
+	ReviewPlan(ctx context.Context, task *Task, plan Plan) (Plan, error)
+}
+
+type planReviewerKey struct{}
+
+// WithPlanReviewer attaches reviewer to ctx. Planners executed under ctx
+// pause for review between planning and execution; without a reviewer they
+// run the plan directly.
+func WithPlanReviewer(ctx context.Context, reviewer PlanReviewer) context.Context {
+	if reviewer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, planReviewerKey{}, reviewer)
+}
+
+// PlanReviewerFromContext returns the reviewer attached by WithPlanReviewer.
+func PlanReviewerFromContext(ctx context.Context) (PlanReviewer, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	reviewer, ok := ctx.Value(planReviewerKey{}).(PlanReviewer)
+	return reviewer, ok
+}
+
+// WritePlanFile saves plan as indented JSON so people can edit it by hand.
+func WritePlanFile(path string, plan Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadPlanFile loads a plan written by WritePlanFile, possibly edited since.
+func ReadPlanFile(path string) (Plan, error) {
+	var plan Plan
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, err
+	}
+	if plan.Dependencies == nil {
+		plan.Dependencies = make(map[int][]int)
+	}
+	return plan, nil
+}
@@ -0,0 +1,192 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PolicyEffect is the outcome of a policy evaluation.
+type PolicyEffect string
+
+const (
+	// PolicyAbstain leaves the manifest decision unchanged.
+	PolicyAbstain PolicyEffect = ""
+	PolicyAllow   PolicyEffect = "allow"
+	PolicyDeny    PolicyEffect = "deny"
+	// PolicyHITL requires human approval even when the manifest does not.
+	PolicyHITL PolicyEffect = "hitl"
+)
+
+// PolicyDecision is returned by a PolicyEvaluator.
+type PolicyDecision struct {
+	Effect PolicyEffect `json:"effect"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// PolicyInput is the document a policy sees. In rego it is available as
+// `input`, e.g. input.permission.action or input.task.type.
+type PolicyInput struct {
+	Agent      string                 `json:"agent"`
+	Permission PolicyPermission       `json:"permission"`
+	Task       *PolicyTask            `json:"task,omitempty"`
+	Args       map[string]interface{} `json:"args,omitempty"`
+}
+
+// PolicyPermission mirrors PermissionDescriptor for policy input.
+type PolicyPermission struct {
+	Type         PermissionType    `json:"type"`
+	Action       string            `json:"action"`
+	Resource     string            `json:"resource"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	RequiresHITL bool              `json:"requires_hitl"`
+}
+
+// PolicyTask carries the TaskContext attached to the request, if any.
+type PolicyTask struct {
+	ID          string   `json:"id"`
+	Type        TaskType `json:"type"`
+	Instruction string   `json:"instruction,omitempty"`
+	NodeID      string   `json:"node_id,omitempty"`
+	Agent       string   `json:"agent,omitempty"`
+}
+
+// NewPolicyInput builds the evaluation input for desc, attaching task metadata
+// from ctx.
+func NewPolicyInput(ctx context.Context, agentID string, desc PermissionDescriptor) PolicyInput {
+	input := PolicyInput{
+		Agent: agentID,
+		Permission: PolicyPermission{
+			Type:         desc.Type,
+			Action:       desc.Action,
+			Resource:     desc.Resource,
+			Metadata:     desc.Metadata,
+			RequiresHITL: desc.RequiresHITL,
+		},
+	}
+	if task, ok := TaskContextFrom(ctx); ok {
+		input.Task = &PolicyTask{
+			ID:          task.ID,
+			Type:        task.Type,
+			Instruction: task.Instruction,
+			NodeID:      task.NodeID,
+			Agent:       task.Agent,
+		}
+	}
+	return input
+}
+
+// PolicyEvaluator decides permission requests in addition to the manifest.
+// It is only consulted for requests the manifest already allows, so a policy
+// can narrow or add approval to what an agent may do but never widen it.
+// Returning an error denies the request.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+}
+
+// PolicyEvaluatorFunc adapts a function to PolicyEvaluator.
+type PolicyEvaluatorFunc func(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+
+// Evaluate calls f.
+func (f PolicyEvaluatorFunc) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	return f(ctx, input)
+}
+
+// OPAPolicyEvaluator evaluates rego policies through an Open Policy Agent
+// server's data API (POST /v1/data/<Path>). The rule at Path may produce:
+//
+//   - a boolean: true allows, false denies
+//   - a string: "allow", "deny", or "hitl"
+//   - an object: {"effect": "deny", "reason": "..."}
+//
+// An undefined rule abstains. A minimal policy:
+//
+//	package relurpify.authz
+//
+//	decision := {"effect": "deny", "reason": "no pushes"} if {
+//	    input.permission.type == "executable"
+//	    input.permission.resource == "git"
+//	    input.task.type == "review"
+//	}
+type OPAPolicyEvaluator struct {
+	// URL is the OPA server base address, e.g. http://127.0.0.1:8181.
+	URL string
+	// Path is the rule to query, e.g. relurpify/authz/decision.
+	Path   string
+	Client *http.Client
+	// FailOpen abstains instead of denying when OPA is unreachable or
+	// returns an unusable result.
+	FailOpen bool
+}
+
+// Evaluate queries OPA with input.
+func (e *OPAPolicyEvaluator) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	decision, err := e.query(ctx, input)
+	if err != nil && e.FailOpen {
+		return PolicyDecision{Effect: PolicyAbstain, Reason: err.Error()}, nil
+	}
+	return decision, err
+}
+
+func (e *OPAPolicyEvaluator) query(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	endpoint := strings.TrimRight(e.URL, "/") + "/v1/data/" + strings.Trim(strings.ReplaceAll(e.Path, ".", "/"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("opa query: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return PolicyDecision{}, fmt.Errorf("opa query returned %s", resp.Status)
+	}
+	var payload struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return PolicyDecision{}, fmt.Errorf("opa response: %w", err)
+	}
+	return parseOPAResult(payload.Result)
+}
+
+// parseOPAResult interprets the shapes documented on OPAPolicyEvaluator.
+func parseOPAResult(raw json.RawMessage) (PolicyDecision, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(raw) == "null" {
+		return PolicyDecision{Effect: PolicyAbstain}, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(raw, &allowed); err == nil {
+		if allowed {
+			return PolicyDecision{Effect: PolicyAllow}, nil
+		}
+		return PolicyDecision{Effect: PolicyDeny, Reason: "denied by policy"}, nil
+	}
+	var decision PolicyDecision
+	var effect string
+	if err := json.Unmarshal(raw, &effect); err == nil {
+		decision.Effect = PolicyEffect(effect)
+	} else if err := json.Unmarshal(raw, &decision); err != nil {
+		return PolicyDecision{}, fmt.Errorf("unsupported opa result %s", string(raw))
+	}
+	switch decision.Effect {
+	case PolicyAbstain, PolicyAllow, PolicyDeny, PolicyHITL:
+		return decision, nil
+	default:
+		return PolicyDecision{}, fmt.Errorf("unknown policy effect %q", decision.Effect)
+	}
+}
@@ -0,0 +1,120 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionManagerPolicyDeniesDeclaredExecutable(t *testing.T) {
+	manager := newTestManager(t, "/workspace", &PermissionSet{
+		FileSystem:  []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}},
+		Executables: []ExecutablePermission{{Binary: "git"}, {Binary: "go"}},
+	})
+	var seen []PolicyInput
+	manager.SetPolicyEvaluator(PolicyEvaluatorFunc(func(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+		seen = append(seen, input)
+		if input.Permission.Resource == "git" && input.Task != nil && input.Task.Type == TaskTypeReview {
+			return PolicyDecision{Effect: PolicyDeny, Reason: "reviews are read-only"}, nil
+		}
+		return PolicyDecision{Effect: PolicyAbstain}, nil
+	}))
+	ctx := WithTaskContext(context.Background(), TaskContext{ID: "t1", Type: TaskTypeReview})
+
+	err := manager.CheckExecutable(ctx, "agent", "git", []string{"push"}, nil)
+	var denied *PermissionDeniedError
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "reviews are read-only", denied.Message)
+	require.NoError(t, manager.CheckExecutable(ctx, "agent", "go", []string{"test"}, nil))
+
+	require.Len(t, seen, 2)
+	assert.Equal(t, "agent", seen[0].Agent)
+	assert.Equal(t, "t1", seen[0].Task.ID)
+	assert.Equal(t, "push", seen[0].Permission.Metadata["args"])
+}
+
+func TestPermissionManagerPolicyNeverWidensManifest(t *testing.T) {
+	manager := newTestManager(t, "/workspace", &PermissionSet{
+		FileSystem: []FileSystemPermission{{Action: FileSystemRead, Path: "/workspace/**"}},
+	})
+	manager.SetPolicyEvaluator(PolicyEvaluatorFunc(func(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+		return PolicyDecision{Effect: PolicyAllow}, nil
+	}))
+
+	require.Error(t, manager.CheckExecutable(context.Background(), "agent", "rm", nil, nil))
+}
+
+func TestPermissionManagerPolicyHITLAndErrors(t *testing.T) {
+	hitl := &stubHITLProvider{}
+	manager, err := NewPermissionManager("/workspace", &PermissionSet{
+		FileSystem: []FileSystemPermission{
+			{Action: FileSystemRead, Path: "/workspace/**"},
+			{Action: FileSystemWrite, Path: "/workspace/**"},
+		},
+	}, nil, hitl)
+	require.NoError(t, err)
+	manager.SetPolicyEvaluator(PolicyEvaluatorFunc(func(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+		if input.Permission.Action == string(FileSystemWrite) {
+			return PolicyDecision{Effect: PolicyHITL}, nil
+		}
+		return PolicyDecision{}, errors.New("opa down")
+	}))
+	ctx := context.Background()
+
+	require.NoError(t, manager.CheckFileAccess(ctx, "agent", FileSystemWrite, "main.go"))
+	require.Len(t, hitl.requests, 1)
+	assert.True(t, hitl.requests[0].Permission.RequiresHITL)
+
+	err = manager.CheckFileAccess(ctx, "agent", FileSystemRead, "main.go")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "policy evaluation failed")
+}
+
+func TestOPAPolicyEvaluatorResultShapes(t *testing.T) {
+	cases := map[string]PolicyDecision{
+		`{}`:                 {Effect: PolicyAbstain},
+		`{"result": true}`:   {Effect: PolicyAllow},
+		`{"result": false}`:  {Effect: PolicyDeny, Reason: "denied by policy"},
+		`{"result": "hitl"}`: {Effect: PolicyHITL},
+		`{"result": {"effect": "deny", "reason": "no"}}`: {Effect: PolicyDeny, Reason: "no"},
+	}
+	for body, want := range cases {
+		var gotPath string
+		var gotInput map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&gotInput)
+			_, _ = w.Write([]byte(body))
+		}))
+		evaluator := &OPAPolicyEvaluator{URL: server.URL, Path: "relurpify.authz.decision"}
+		decision, err := evaluator.Evaluate(context.Background(), PolicyInput{Agent: "coder"})
+		server.Close()
+
+		require.NoError(t, err, body)
+		assert.Equal(t, want, decision, body)
+		assert.Equal(t, "/v1/data/relurpify/authz/decision", gotPath)
+		assert.Equal(t, "coder", gotInput["input"].(map[string]interface{})["agent"])
+	}
+}
+
+func TestOPAPolicyEvaluatorFailOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	closed := &OPAPolicyEvaluator{URL: server.URL, Path: "authz"}
+	_, err := closed.Evaluate(context.Background(), PolicyInput{})
+	require.Error(t, err)
+
+	open := &OPAPolicyEvaluator{URL: server.URL, Path: "authz", FailOpen: true}
+	decision, err := open.Evaluate(context.Background(), PolicyInput{})
+	require.NoError(t, err)
+	assert.Equal(t, PolicyAbstain, decision.Effect)
+}
@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context keys written after the agent edits files. PostEditDiagnosticsKey
+// holds the []EditDiagnostic errors language servers report for the edited
+// files; PostEditNewErrorsKey counts the ones the latest edits introduced,
+// until an observer acts on them and resets it to zero.
+const (
+	PostEditDiagnosticsKey = "post_edit.diagnostics"
+	PostEditNewErrorsKey   = "post_edit.new_errors"
+)
+
+// EditDiagnostic is a language server error in an edited file, at a 1-based
+// line. New marks errors the file did not have before the edit.
+type EditDiagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Source  string `json:"source,omitempty"`
+	New     bool   `json:"new,omitempty"`
+}
+
+// PostEditDiagnostics returns the errors recorded for edited files.
+func PostEditDiagnostics(state *Context) []EditDiagnostic {
+	if state == nil {
+		return nil
+	}
+	value, ok := state.Get(PostEditDiagnosticsKey)
+	if !ok {
+		return nil
+	}
+	diags, _ := value.([]EditDiagnostic)
+	return diags
+}
+
+// RenderEditDiagnostics lists diagnostics one per line for prompts.
+func RenderEditDiagnostics(diags []EditDiagnostic) string {
+	var b strings.Builder
+	for _, d := range diags {
+		marker := ""
+		if d.New {
+			marker = " (new)"
+		}
+		source := ""
+		if d.Source != "" {
+			source = d.Source + ": "
+		}
+		fmt.Fprintf(&b, "- %s:%d%s %s%s\n", d.File, d.Line, marker, source, d.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PreviousTask is the outcome of the task before this one in a session. The
+// shell attaches it under task.Context["previous_task"] so follow-ups such as
+// "now add tests for that" know what "that" is.
+type PreviousTask struct {
+	Instruction  string   `json:"instruction"`
+	Output       string   `json:"output,omitempty"`
+	FilesChanged []string `json:"files_changed,omitempty"`
+	Plan         *Plan    `json:"plan,omitempty"`
+}
+
+// PreviousTaskOf returns the previous task attached to task, if any.
+func PreviousTaskOf(task *Task) (PreviousTask, bool) {
+	if task == nil || task.Context == nil {
+		return PreviousTask{}, false
+	}
+	previous, ok := task.Context["previous_task"].(PreviousTask)
+	return previous, ok
+}
+
+// Render formats the previous task for a prompt.
+func (p PreviousTask) Render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Instruction: %s\n", p.Instruction)
+	if p.Output != "" {
+		fmt.Fprintf(&b, "Result: %s\n", p.Output)
+	}
+	if len(p.FilesChanged) > 0 {
+		fmt.Fprintf(&b, "Files changed: %s\n", strings.Join(p.FilesChanged, ", "))
+	}
+	if p.Plan != nil && len(p.Plan.Steps) > 0 {
+		if data, err := json.Marshal(p.Plan); err == nil {
+			fmt.Fprintf(&b, "Plan: %s\n", data)
+		}
+	}
+	return b.String()
+}
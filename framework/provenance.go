@@ -0,0 +1,173 @@
+package framework
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContextSourcesKey holds the []ContextSource a task's context was built
+// from, in the order they were first seen.
+const ContextSourcesKey = "context.sources"
+
+// SourceKind says what a ContextSource points at.
+type SourceKind string
+
+const (
+	SourceFile   SourceKind = "file"
+	SourceMemory SourceKind = "memory"
+	SourceTool   SourceKind = "tool"
+)
+
+// ContextSource is one thing the agent read while working: a file (with
+// the 1-based line range it saw, or the whole file when StartLine is zero),
+// a memory record, or a tool whose result is not tied to files.
+type ContextSource struct {
+	Kind      SourceKind `json:"kind"`
+	Path      string     `json:"path,omitempty"`
+	StartLine int        `json:"start_line,omitempty"`
+	EndLine   int        `json:"end_line,omitempty"`
+	Key       string     `json:"key,omitempty"`
+	Tool      string     `json:"tool,omitempty"`
+}
+
+// String renders the source as a citation, such as "tools/files.go:40-68".
+func (s ContextSource) String() string {
+	switch s.Kind {
+	case SourceFile:
+		switch {
+		case s.StartLine == 0:
+			return s.Path
+		case s.EndLine <= s.StartLine:
+			return fmt.Sprintf("%s:%d", s.Path, s.StartLine)
+		default:
+			return fmt.Sprintf("%s:%d-%d", s.Path, s.StartLine, s.EndLine)
+		}
+	case SourceMemory:
+		return "memory " + s.Key
+	default:
+		return "tool " + s.Tool
+	}
+}
+
+// ContextSources returns the sources recorded on state.
+func ContextSources(state *Context) []ContextSource {
+	if state == nil {
+		return nil
+	}
+	value, ok := state.Get(ContextSourcesKey)
+	if !ok {
+		return nil
+	}
+	sources, _ := value.([]ContextSource)
+	return sources
+}
+
+// RecordContextSources adds sources to state. Line ranges of one file are
+// merged when they overlap or touch, and a whole file absorbs its ranges.
+func RecordContextSources(state *Context, sources ...ContextSource) {
+	if state == nil || len(sources) == 0 {
+		return
+	}
+	merged := MergeContextSources(append(ContextSources(state), sources...))
+	state.Set(ContextSourcesKey, merged)
+}
+
+// MergeContextSources drops duplicate sources and merges the line ranges of
+// each file, keeping files in first-seen order.
+func MergeContextSources(sources []ContextSource) []ContextSource {
+	var (
+		out    []ContextSource
+		ranges = map[string][]ContextSource{}
+		seen   = map[ContextSource]bool{}
+	)
+	for _, source := range sources {
+		if source.Kind != SourceFile {
+			if !seen[source] {
+				seen[source] = true
+				out = append(out, source)
+			}
+			continue
+		}
+		if source.Path == "" {
+			continue
+		}
+		if source.EndLine < source.StartLine {
+			source.EndLine = source.StartLine
+		}
+		if _, ok := ranges[source.Path]; !ok {
+			// Placeholder keeping the file's position; filled in below.
+			out = append(out, ContextSource{Kind: SourceFile, Path: source.Path})
+		}
+		ranges[source.Path] = append(ranges[source.Path], source)
+	}
+	var result []ContextSource
+	for _, source := range out {
+		if source.Kind == SourceFile {
+			result = append(result, mergeLineRanges(ranges[source.Path])...)
+			continue
+		}
+		result = append(result, source)
+	}
+	return result
+}
+
+func mergeLineRanges(sources []ContextSource) []ContextSource {
+	for _, source := range sources {
+		if source.StartLine == 0 {
+			return []ContextSource{{Kind: SourceFile, Path: source.Path}}
+		}
+	}
+	sorted := append([]ContextSource(nil), sources...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartLine < sorted[j].StartLine })
+	out := []ContextSource{sorted[0]}
+	for _, source := range sorted[1:] {
+		last := &out[len(out)-1]
+		if source.StartLine <= last.EndLine+1 {
+			if source.EndLine > last.EndLine {
+				last.EndLine = source.EndLine
+			}
+			continue
+		}
+		out = append(out, source)
+	}
+	return out
+}
+
+// RenderContextSources formats sources as the "Sources" section of a final
+// answer, one citation per line.
+func RenderContextSources(sources []ContextSource) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Sources:\n")
+	for _, source := range sources {
+		fmt.Fprintf(&b, "- %s\n", source)
+	}
+	return b.String()
+}
+
+// ContextItemSources returns what a context item was built from. Items that
+// do not track sources return nil.
+func ContextItemSources(item ContextItem) []ContextSource {
+	switch item := item.(type) {
+	case *FileContextItem:
+		if len(item.Sources) > 0 {
+			return item.Sources
+		}
+		if item.Path != "" {
+			return []ContextSource{{Kind: SourceFile, Path: item.Path}}
+		}
+	case *ToolResultContextItem:
+		if len(item.Sources) > 0 {
+			return item.Sources
+		}
+		if item.ToolName != "" {
+			return []ContextSource{{Kind: SourceTool, Tool: item.ToolName}}
+		}
+	case *InteractionContextItem:
+		return item.Sources
+	}
+	return nil
+}
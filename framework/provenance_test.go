@@ -0,0 +1,52 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecordContextSourcesMergesLineRanges(t *testing.T) {
+	state := NewContext()
+	RecordContextSources(state,
+		ContextSource{Kind: SourceFile, Path: "a.go", StartLine: 10, EndLine: 20},
+		ContextSource{Kind: SourceMemory, Key: GlossaryMemoryKey},
+		ContextSource{Kind: SourceFile, Path: "b.go", StartLine: 5},
+	)
+	RecordContextSources(state,
+		ContextSource{Kind: SourceFile, Path: "a.go", StartLine: 21, EndLine: 30},
+		ContextSource{Kind: SourceFile, Path: "a.go", StartLine: 50, EndLine: 55},
+		ContextSource{Kind: SourceFile, Path: "b.go"},
+		ContextSource{Kind: SourceMemory, Key: GlossaryMemoryKey},
+		ContextSource{Kind: SourceTool, Tool: "git_diff"},
+	)
+	want := []ContextSource{
+		{Kind: SourceFile, Path: "a.go", StartLine: 10, EndLine: 30},
+		{Kind: SourceFile, Path: "a.go", StartLine: 50, EndLine: 55},
+		{Kind: SourceMemory, Key: GlossaryMemoryKey},
+		{Kind: SourceFile, Path: "b.go"},
+		{Kind: SourceTool, Tool: "git_diff"},
+	}
+	got := ContextSources(state)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sources = %+v, want %+v", got, want)
+	}
+	rendered := RenderContextSources(got)
+	wantRendered := "Sources:\n- a.go:10-30\n- a.go:50-55\n- memory workspace.glossary\n- b.go\n- tool git_diff\n"
+	if rendered != wantRendered {
+		t.Fatalf("rendered = %q, want %q", rendered, wantRendered)
+	}
+}
+
+func TestContextItemSourcesSurviveCompression(t *testing.T) {
+	item := &FileContextItem{Path: "a.go", Content: "package a", Sources: []ContextSource{{Kind: SourceFile, Path: "a.go", StartLine: 1, EndLine: 40}}}
+	compressed, err := item.Compress()
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if got := ContextItemSources(compressed); !reflect.DeepEqual(got, item.Sources) {
+		t.Fatalf("sources = %+v, want %+v", got, item.Sources)
+	}
+	if got := ContextItemSources(&FileContextItem{Path: "b.go"}); !reflect.DeepEqual(got, []ContextSource{{Kind: SourceFile, Path: "b.go"}}) {
+		t.Fatalf("file item without sources = %+v", got)
+	}
+}
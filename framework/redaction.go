@@ -0,0 +1,303 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minRedactedLiteral is the shortest .env value treated as a secret; shorter
+// values ("true", ports) would mask ordinary text.
+const minRedactedLiteral = 8
+
+// redactionRule masks matches of pattern. When the pattern has a group named
+// "secret" only that group is masked, so "API_KEY=..." keeps its key.
+type redactionRule struct {
+	name    string
+	pattern *regexp.Regexp
+	secret  int
+}
+
+// defaultRedactionPatterns cover common credential formats. Assignments are
+// only matched when quoted or in KEY=value lines, so code such as
+// `token = next()` is left alone.
+var defaultRedactionPatterns = []struct{ name, pattern string }{
+	{"private_key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{"aws_key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"github_token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`},
+	{"api_key", `\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}`},
+	{"google_key", `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{"slack_token", `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{"jwt", `\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`},
+	{"bearer", `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]{20,}=*)`},
+	{"assignment", `(?i)(?:password|passwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["'](?P<secret>[^"'\s]{8,})["']`},
+	{"env", `(?m)^[ \t]*(?:export[ \t]+)?[A-Z0-9_]*(?:PASSWORD|SECRET|TOKEN|API_KEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*[ \t]*=[ \t]*["']?(?P<secret>[^\s"']{8,})`},
+}
+
+// RedactionOptions configures NewRedactor.
+type RedactionOptions struct {
+	// Patterns are extra regular expressions. A group named "secret" limits
+	// the mask to that group.
+	Patterns []string
+	// Literals are exact secret values, such as those loaded from .env files.
+	Literals []string
+	// NoDefaults drops the built-in credential patterns.
+	NoDefaults bool
+}
+
+// Redactor masks secrets in text before it reaches an LLM, a log, or disk.
+// Masks name the rule that matched, e.g. "[REDACTED:aws_key]", so the agent
+// can tell a value was withheld. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	rules    []redactionRule
+	literals *strings.Replacer
+}
+
+// NewRedactor compiles the built-in and extra patterns.
+func NewRedactor(opts RedactionOptions) (*Redactor, error) {
+	r := &Redactor{}
+	if !opts.NoDefaults {
+		for _, p := range defaultRedactionPatterns {
+			r.rules = append(r.rules, newRedactionRule(p.name, regexp.MustCompile(p.pattern)))
+		}
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, newRedactionRule("custom", re))
+	}
+	literals := make([]string, 0, len(opts.Literals))
+	for _, literal := range opts.Literals {
+		if len(literal) >= minRedactedLiteral {
+			literals = append(literals, literal)
+		}
+	}
+	if len(literals) > 0 {
+		// Longest first, so a secret containing another is masked whole.
+		sort.Slice(literals, func(i, j int) bool { return len(literals[i]) > len(literals[j]) })
+		pairs := make([]string, 0, 2*len(literals))
+		for _, literal := range literals {
+			pairs = append(pairs, literal, "[REDACTED:env]")
+		}
+		r.literals = strings.NewReplacer(pairs...)
+	}
+	return r, nil
+}
+
+func newRedactionRule(name string, re *regexp.Regexp) redactionRule {
+	return redactionRule{name: name, pattern: re, secret: re.SubexpIndex("secret")}
+}
+
+// Redact masks every secret in s.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	if r.literals != nil {
+		s = r.literals.Replace(s)
+	}
+	for _, rule := range r.rules {
+		s = rule.apply(s)
+	}
+	return s
+}
+
+func (rule redactionRule) apply(s string) string {
+	mask := "[REDACTED:" + rule.name + "]"
+	if rule.secret < 0 {
+		return rule.pattern.ReplaceAllLiteralString(s, mask)
+	}
+	matches := rule.pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*rule.secret], m[2*rule.secret+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(mask)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// redactionMaskPattern matches the masks Redact inserts.
+var redactionMaskPattern = regexp.MustCompile(`\[REDACTED:[a-z_]+\]`)
+
+// FindRedactionMask returns the first redaction mask in s, or "". Tools that
+// write files use it to refuse content an agent copied from a redacted view,
+// which would replace the real secret on disk with the mask.
+func FindRedactionMask(s string) string {
+	return redactionMaskPattern.FindString(s)
+}
+
+// RedactValue returns a copy of v with every string inside maps and slices
+// redacted. Other values are returned as is.
+func (r *Redactor) RedactValue(v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+	switch val := v.(type) {
+	case string:
+		return r.Redact(val)
+	case map[string]interface{}:
+		return r.RedactMap(val)
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, item := range val {
+			out[k] = r.Redact(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = r.RedactValue(item)
+		}
+		return out
+	case []string:
+		out := make([]string, len(val))
+		for i, item := range val {
+			out[i] = r.Redact(item)
+		}
+		return out
+	}
+	return v
+}
+
+// RedactMap returns a redacted copy of m.
+func (r *Redactor) RedactMap(m map[string]interface{}) map[string]interface{} {
+	if r == nil || m == nil {
+		return m
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = r.RedactValue(v)
+	}
+	return out
+}
+
+// LoadEnvSecrets returns the values assigned in a .env file. Comments and
+// blank lines are skipped and surrounding quotes removed.
+func LoadEnvSecrets(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var values []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// RedactingModel masks secrets in prompts, messages, and tool call arguments
+// before they reach the wrapped model.
+type RedactingModel struct {
+	Inner    LanguageModel
+	Redactor *Redactor
+}
+
+// Generate implements LanguageModel.
+func (m *RedactingModel) Generate(ctx context.Context, prompt string, options *LLMOptions) (*LLMResponse, error) {
+	return m.Inner.Generate(ctx, m.Redactor.Redact(prompt), options)
+}
+
+// GenerateStream implements LanguageModel.
+func (m *RedactingModel) GenerateStream(ctx context.Context, prompt string, options *LLMOptions) (<-chan string, error) {
+	return m.Inner.GenerateStream(ctx, m.Redactor.Redact(prompt), options)
+}
+
+// Chat implements LanguageModel.
+func (m *RedactingModel) Chat(ctx context.Context, messages []Message, options *LLMOptions) (*LLMResponse, error) {
+	return m.Inner.Chat(ctx, m.redactMessages(messages), options)
+}
+
+// ChatWithTools implements LanguageModel.
+func (m *RedactingModel) ChatWithTools(ctx context.Context, messages []Message, tools []Tool, options *LLMOptions) (*LLMResponse, error) {
+	return m.Inner.ChatWithTools(ctx, m.redactMessages(messages), tools, options)
+}
+
+func (m *RedactingModel) redactMessages(messages []Message) []Message {
+	out := make([]Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = m.Redactor.Redact(msg.Content)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Args = m.Redactor.RedactMap(call.Args)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// RedactingTelemetry masks secrets in event messages and metadata before
+// forwarding them, covering logs, telemetry files, and task timelines.
+type RedactingTelemetry struct {
+	Inner    Telemetry
+	Redactor *Redactor
+}
+
+// Emit implements Telemetry.
+func (t RedactingTelemetry) Emit(event Event) {
+	event.Message = t.Redactor.Redact(event.Message)
+	event.Metadata = t.Redactor.RedactMap(event.Metadata)
+	t.Inner.Emit(event)
+}
+
+// RedactingMemory masks secrets in values before they are stored. Values
+// holding secrets are redacted in their JSON form, which reaches structs that
+// RedactValue does not walk; the store keeps JSON anyway.
+type RedactingMemory struct {
+	MemoryStore
+	Redactor *Redactor
+}
+
+// Remember implements MemoryStore.
+func (m RedactingMemory) Remember(ctx context.Context, key string, value map[string]interface{}, scope MemoryScope) error {
+	return m.MemoryStore.Remember(ctx, key, m.redact(value), scope)
+}
+
+func (m RedactingMemory) redact(value map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return m.Redactor.RedactMap(value)
+	}
+	redacted := m.Redactor.Redact(string(data))
+	if redacted == string(data) {
+		return value
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(redacted), &out); err != nil {
+		return m.Redactor.RedactMap(value)
+	}
+	return out
+}
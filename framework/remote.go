@@ -0,0 +1,201 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RemoteHost describes a workspace that lives on another machine and is
+// reached with the ssh client. Root is the workspace directory on the remote
+// machine; Local is where it appears on this one (normally an sshfs mount),
+// so local paths can be translated before a command runs remotely.
+type RemoteHost struct {
+	Host string
+	User string
+	// Port is the ssh port; zero uses the ssh client's default.
+	Port         int
+	IdentityFile string
+	// Options are extra ssh -o options, such as "StrictHostKeyChecking=yes".
+	Options []string
+	Root    string
+	Local   string
+}
+
+// Validate reports a missing host or a relative remote root.
+func (h RemoteHost) Validate() error {
+	if strings.TrimSpace(h.Host) == "" {
+		return errors.New("remote host required")
+	}
+	if !path.IsAbs(h.Root) {
+		return fmt.Errorf("remote path %q must be absolute", h.Root)
+	}
+	if h.Port < 0 {
+		return fmt.Errorf("invalid remote port %d", h.Port)
+	}
+	return nil
+}
+
+// Destination returns the ssh destination, user@host or host.
+func (h RemoteHost) Destination() string {
+	if h.User != "" {
+		return h.User + "@" + h.Host
+	}
+	return h.Host
+}
+
+// SSHOptions returns the ssh flags for Port, IdentityFile, and Options.
+// BatchMode is always set so a missing key fails instead of prompting.
+func (h RemoteHost) SSHOptions() []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if h.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(h.Port))
+	}
+	if h.IdentityFile != "" {
+		args = append(args, "-i", h.IdentityFile)
+	}
+	for _, opt := range h.Options {
+		args = append(args, "-o", opt)
+	}
+	return args
+}
+
+// RemotePath translates a path under Local to the same path under Root.
+// Relative paths are taken relative to Root.
+func (h RemoteHost) RemotePath(local string) (string, error) {
+	if !filepath.IsAbs(local) {
+		return path.Join(h.Root, filepath.ToSlash(local)), nil
+	}
+	rel, err := filepath.Rel(filepath.Clean(h.Local), filepath.Clean(local))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s outside remote workspace %s", local, h.Local)
+	}
+	return path.Join(h.Root, filepath.ToSlash(rel)), nil
+}
+
+// Command returns the ssh arguments that run args in workdir on the remote
+// machine with env (KEY=VALUE entries) added to its environment. Arguments
+// under Local are translated to Root.
+func (h RemoteHost) Command(workdir string, env []string, args []string) []string {
+	var script strings.Builder
+	script.WriteString("cd ")
+	script.WriteString(shellQuote(workdir))
+	script.WriteString(" && exec")
+	if len(env) > 0 {
+		script.WriteString(" env")
+		for _, entry := range env {
+			if entry != "" {
+				script.WriteString(" " + shellQuote(entry))
+			}
+		}
+	}
+	for _, arg := range args {
+		if filepath.IsAbs(arg) {
+			if remote, err := h.RemotePath(arg); err == nil {
+				arg = remote
+			}
+		}
+		script.WriteString(" " + shellQuote(arg))
+	}
+	return append(h.SSHOptions(), h.Destination(), "--", script.String())
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:@%+,", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SSHCommandRunner runs commands on a RemoteHost over ssh. Workdirs are local
+// paths under the host's Local directory and run in the matching directory
+// under Root. Commands run directly on the remote machine, outside any
+// sandbox.
+type SSHCommandRunner struct {
+	host RemoteHost
+	// Binary is the ssh client; empty uses "ssh" from PATH.
+	Binary string
+}
+
+// NewSSHCommandRunner validates host and returns a runner for it.
+func NewSSHCommandRunner(host RemoteHost) (*SSHCommandRunner, error) {
+	if err := host.Validate(); err != nil {
+		return nil, err
+	}
+	if host.Local != "" {
+		abs, err := filepath.Abs(host.Local)
+		if err != nil {
+			return nil, fmt.Errorf("resolve remote mount: %w", err)
+		}
+		host.Local = filepath.Clean(abs)
+	}
+	return &SSHCommandRunner{host: host}, nil
+}
+
+// Host returns the machine commands run on.
+func (r *SSHCommandRunner) Host() RemoteHost {
+	return r.host
+}
+
+// Run executes the command on the remote machine.
+func (r *SSHCommandRunner) Run(ctx context.Context, req CommandRequest) (string, string, error) {
+	if r == nil {
+		return "", "", errors.New("ssh command runner missing")
+	}
+	if len(req.Args) == 0 {
+		return "", "", errors.New("command arguments required")
+	}
+	workdir := r.host.Root
+	if req.Workdir != "" {
+		var err error
+		if workdir, err = r.host.RemotePath(req.Workdir); err != nil {
+			return "", "", err
+		}
+	}
+	execCtx := ctx
+	cancel := func() {}
+	if req.Timeout > 0 {
+		execCtx, cancel = context.WithTimeout(ctx, req.Timeout)
+	}
+	defer cancel()
+	binary := r.Binary
+	if binary == "" {
+		binary = "ssh"
+	}
+	cmd := exec.CommandContext(execCtx, binary, r.host.Command(workdir, req.Env, req.Args)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if req.Input != "" {
+		cmd.Stdin = strings.NewReader(req.Input)
+	}
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), r.runError(ctx, err)
+}
+
+// sshConnectFailed is the exit code ssh uses for its own failures rather
+// than the command's.
+const sshConnectFailed = 255
+
+// runError names the host when ssh itself failed, such as on an
+// unreachable host or a rejected key. A command exiting non-zero is returned
+// as is.
+func (r *SSHCommandRunner) runError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != sshConnectFailed {
+		return err
+	}
+	return fmt.Errorf("ssh %s: %w", r.host.Destination(), err)
+}
@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteHostCommand(t *testing.T) {
+	host := RemoteHost{Host: "dev", User: "alice", Port: 2222, IdentityFile: "/keys/id", Options: []string{"StrictHostKeyChecking=yes"}, Root: "/srv/app", Local: "/mnt/app"}
+	require.NoError(t, host.Validate())
+
+	args := host.Command("/srv/app/pkg", []string{"GOFLAGS=-count=1"}, []string{"go", "test", "/mnt/app/pkg/it's_test.go"})
+	require.Equal(t, []string{
+		"-o", "BatchMode=yes", "-p", "2222", "-i", "/keys/id", "-o", "StrictHostKeyChecking=yes",
+		"alice@dev", "--",
+		`cd /srv/app/pkg && exec env GOFLAGS=-count=1 go test '/srv/app/pkg/it'\''s_test.go'`,
+	}, args)
+
+	remote, err := host.RemotePath("/mnt/app/cmd/main.go")
+	require.NoError(t, err)
+	require.Equal(t, "/srv/app/cmd/main.go", remote)
+	_, err = host.RemotePath("/mnt/other")
+	require.ErrorContains(t, err, "outside remote workspace")
+
+	require.ErrorContains(t, RemoteHost{Host: "dev", Root: "app"}.Validate(), "must be absolute")
+}
+
+// TestSSHCommandRunnerRunsInRemoteWorkdir stands in for ssh with a script
+// that runs the remote command locally, with one directory as the remote
+// workspace and another as its mount.
+func TestSSHCommandRunnerRunsInRemoteWorkdir(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "sub"), 0o755))
+	fakeSSH := filepath.Join(t.TempDir(), "ssh")
+	require.NoError(t, os.WriteFile(fakeSSH, []byte("#!/bin/sh\nfor last; do :; done\nexec sh -c \"$last\"\n"), 0o755))
+	local := t.TempDir()
+	runner, err := NewSSHCommandRunner(RemoteHost{Host: "dev", Root: root, Local: local})
+	require.NoError(t, err)
+	runner.Binary = fakeSSH
+
+	stdout, _, err := runner.Run(context.Background(), CommandRequest{Args: []string{"pwd"}, Workdir: filepath.Join(local, "sub")})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(root, "sub")+"\n", stdout)
+
+	stdout, _, err = runner.Run(context.Background(), CommandRequest{Args: []string{"sh", "-c", "cat; echo $GREETING"}, Env: []string{"GREETING=hi"}, Input: "hello "})
+	require.NoError(t, err)
+	require.Equal(t, "hello hi\n", stdout)
+
+	_, _, err = runner.Run(context.Background(), CommandRequest{Args: []string{"pwd"}, Workdir: filepath.Dir(local)})
+	require.ErrorContains(t, err, "outside remote workspace")
+}
@@ -0,0 +1,328 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Replay entry kinds.
+const (
+	ReplayKindTask  = "task"
+	ReplayKindModel = "model"
+	ReplayKindTool  = "tool"
+)
+
+// ReplayEntry is one line of a replay log. The first entry of a log is the
+// task itself; the rest are the task's model and tool calls in the order
+// they completed.
+type ReplayEntry struct {
+	Seq  int       `json:"seq"`
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+	// Task and ModelContext are set on the task entry. ModelContext sizes
+	// the replayed context window exactly as the recorded one was.
+	Task         *Task         `json:"task,omitempty"`
+	ModelContext *ModelContext `json:"model_context,omitempty"`
+	// Method (generate, chat, chat_with_tools), Digest, Prompt, and Response
+	// describe model calls. Digest hashes the full request; Prompt keeps the
+	// prompt or last message for people reading the log.
+	Method   string       `json:"method,omitempty"`
+	Digest   string       `json:"digest,omitempty"`
+	Prompt   string       `json:"prompt,omitempty"`
+	Response *LLMResponse `json:"response,omitempty"`
+	// Tool, Args, and Result describe tool calls.
+	Tool   string                 `json:"tool,omitempty"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+	Result *ToolResult            `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// ToolTap observes tool executions on a ToolRegistry; see UseToolTap.
+type ToolTap interface {
+	// ReplayTool returns a result to use instead of running the tool, with
+	// ok set, or ok false to run it.
+	ReplayTool(ctx context.Context, name string, args map[string]interface{}) (result *ToolResult, err error, ok bool)
+	// RecordTool is told the outcome of every tool that ran.
+	RecordTool(ctx context.Context, name string, args map[string]interface{}, result *ToolResult, err error)
+}
+
+var replayFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ReplayPath returns the log file for taskID under dir.
+func ReplayPath(dir, taskID string) string {
+	return filepath.Join(dir, replayFileUnsafe.ReplaceAllString(taskID, "_")+".jsonl")
+}
+
+// ReplayRecorder writes one replay log per task under Dir. Calls are
+// attributed to a task through the TaskContext on ctx; calls for tasks that
+// were never started with StartTask are dropped.
+type ReplayRecorder struct {
+	Dir string
+
+	mu    sync.Mutex
+	tasks map[string]*replayLog
+}
+
+type replayLog struct {
+	file *os.File
+	seq  int
+}
+
+// NewReplayRecorder records into dir, creating it as needed.
+func NewReplayRecorder(dir string) *ReplayRecorder {
+	return &ReplayRecorder{Dir: dir, tasks: make(map[string]*replayLog)}
+}
+
+// StartTask begins a fresh log for task, replacing an earlier recording of
+// the same task ID.
+func (r *ReplayRecorder) StartTask(task *Task, info ModelContext) error {
+	if task == nil || task.ID == "" {
+		return errors.New("replay: task id required")
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(ReplayPath(r.Dir, task.ID))
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous, ok := r.tasks[task.ID]; ok {
+		previous.file.Close()
+	}
+	log := &replayLog{file: file}
+	r.tasks[task.ID] = log
+	return log.append(ReplayEntry{Kind: ReplayKindTask, Task: task, ModelContext: &info})
+}
+
+// FinishTask closes the task's log.
+func (r *ReplayRecorder) FinishTask(taskID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log, ok := r.tasks[taskID]
+	if !ok {
+		return nil
+	}
+	delete(r.tasks, taskID)
+	return log.file.Close()
+}
+
+// Record appends entry to the log of the task on ctx.
+func (r *ReplayRecorder) Record(ctx context.Context, entry ReplayEntry) {
+	task, ok := TaskContextFrom(ctx)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if log, ok := r.tasks[task.ID]; ok {
+		_ = log.append(entry)
+	}
+}
+
+// ReplayTool never replays; the recorder lets every tool run.
+func (r *ReplayRecorder) ReplayTool(context.Context, string, map[string]interface{}) (*ToolResult, error, bool) {
+	return nil, nil, false
+}
+
+// RecordTool implements ToolTap.
+func (r *ReplayRecorder) RecordTool(ctx context.Context, name string, args map[string]interface{}, result *ToolResult, err error) {
+	entry := ReplayEntry{Kind: ReplayKindTool, Tool: name, Args: args, Result: result}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.Record(ctx, entry)
+}
+
+// Close closes every open log.
+func (r *ReplayRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for id, log := range r.tasks {
+		errs = append(errs, log.file.Close())
+		delete(r.tasks, id)
+	}
+	return errors.Join(errs...)
+}
+
+func (l *replayLog) append(entry ReplayEntry) error {
+	entry.Seq = l.seq
+	entry.Time = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.seq++
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// ErrReplayDiverged reports a replayed run asking for a call the recording
+// does not have next.
+var ErrReplayDiverged = errors.New("replay diverged")
+
+// Replayer serves a recorded task's model responses and tool results in
+// order, so the task re-runs without a model and without touching the
+// workspace. It is a ToolTap.
+type Replayer struct {
+	Task         *Task
+	ModelContext ModelContext
+	// OnStep, when set, is called with each recorded call before it is
+	// served, e.g. to print it or to pause between steps.
+	OnStep func(ReplayEntry)
+
+	mu      sync.Mutex
+	entries []ReplayEntry
+	next    int
+	// mismatches lists calls served despite a different prompt or
+	// arguments than recorded.
+	mismatches []string
+}
+
+// LoadReplay reads the replay log at path.
+func LoadReplay(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	replayer := &Replayer{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for scanner.Scan() {
+		var entry ReplayEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("replay %s: %w", path, err)
+		}
+		if entry.Kind == ReplayKindTask {
+			replayer.Task = entry.Task
+			if entry.ModelContext != nil {
+				replayer.ModelContext = *entry.ModelContext
+			}
+			continue
+		}
+		replayer.entries = append(replayer.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if replayer.Task == nil {
+		return nil, fmt.Errorf("replay %s: no task entry", path)
+	}
+	return replayer, nil
+}
+
+// Steps returns the recorded model and tool calls.
+func (p *Replayer) Steps() []ReplayEntry {
+	return append([]ReplayEntry(nil), p.entries...)
+}
+
+// Served reports how many recorded calls were replayed.
+func (p *Replayer) Served() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.next
+}
+
+// Mismatches lists calls that were served although their prompt or
+// arguments differed from the recording.
+func (p *Replayer) Mismatches() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.mismatches...)
+}
+
+// NextModel returns the recorded response to the next model call.
+func (p *Replayer) NextModel(method, digest string) (*LLMResponse, error) {
+	entry, err := p.take(ReplayKindModel, method)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Digest != digest {
+		p.mismatch(fmt.Sprintf("step %d: %s prompt differs from the recording", entry.Seq, method))
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	if entry.Response == nil {
+		return &LLMResponse{}, nil
+	}
+	resp := *entry.Response
+	return &resp, nil
+}
+
+// ReplayTool implements ToolTap by returning the recorded result.
+func (p *Replayer) ReplayTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error, bool) {
+	entry, err := p.take(ReplayKindTool, name)
+	if err != nil {
+		return nil, err, true
+	}
+	if !sameArgs(entry.Args, args) {
+		p.mismatch(fmt.Sprintf("step %d: %s arguments differ from the recording", entry.Seq, name))
+	}
+	if entry.Error != "" {
+		return entry.Result, errors.New(entry.Error), true
+	}
+	return entry.Result, nil, true
+}
+
+// RecordTool implements ToolTap; replayed tools are not recorded again.
+func (p *Replayer) RecordTool(context.Context, string, map[string]interface{}, *ToolResult, error) {}
+
+func (p *Replayer) take(kind, name string) (ReplayEntry, error) {
+	p.mu.Lock()
+	if p.next >= len(p.entries) {
+		p.mu.Unlock()
+		return ReplayEntry{}, fmt.Errorf("%w: the agent made a %s call (%s) after the last of %d recorded calls", ErrReplayDiverged, kind, name, len(p.entries))
+	}
+	entry := p.entries[p.next]
+	recorded := entry.Method
+	if entry.Kind == ReplayKindTool {
+		recorded = entry.Tool
+	}
+	if entry.Kind != kind || recorded != name {
+		p.mu.Unlock()
+		return ReplayEntry{}, fmt.Errorf("%w at step %d: the agent made a %s call (%s), the recording has a %s call (%s)", ErrReplayDiverged, entry.Seq, kind, name, entry.Kind, recorded)
+	}
+	p.next++
+	onStep := p.OnStep
+	p.mu.Unlock()
+	if onStep != nil {
+		onStep(entry)
+	}
+	return entry, nil
+}
+
+func (p *Replayer) mismatch(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mismatches = append(p.mismatches, msg)
+}
+
+// sameArgs compares arguments after a JSON round trip, since recorded
+// numbers decode as float64.
+func sameArgs(recorded, args map[string]interface{}) bool {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return false
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+	if len(recorded) == 0 && len(normalized) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(recorded, normalized)
+}
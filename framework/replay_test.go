@@ -0,0 +1,79 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTool echoes its "path" argument and counts executions.
+type countingTool struct {
+	stubTool
+	calls int
+}
+
+func (t *countingTool) Execute(_ context.Context, _ *Context, args map[string]interface{}) (*ToolResult, error) {
+	t.calls++
+	return &ToolResult{Success: true, Data: map[string]interface{}{"path": args["path"]}}, nil
+}
+
+func TestReplayRecordsAndReplaysToolCalls(t *testing.T) {
+	dir := t.TempDir()
+	task := &Task{ID: "task-1", Type: TaskTypeAnalysis, Instruction: "read a file"}
+	ctx := WithTaskContext(context.Background(), TaskContext{ID: task.ID})
+
+	tool := &countingTool{stubTool: stubTool{name: "file_read"}}
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(tool))
+	recorder := NewReplayRecorder(dir)
+	registry.UseToolTap(recorder)
+	require.NoError(t, recorder.StartTask(task, ModelContext{Name: "qwen", ContextLength: 8192}))
+	recorder.Record(ctx, ReplayEntry{Kind: ReplayKindModel, Method: "chat", Digest: "d1", Response: &LLMResponse{Text: "read it"}})
+	wrapped, _ := registry.Get("file_read")
+	_, err := wrapped.Execute(ctx, NewContext(), map[string]interface{}{"path": "a.go", "limit": 10})
+	require.NoError(t, err)
+	// Calls for tasks that were not started are dropped.
+	recorder.Record(WithTaskContext(context.Background(), TaskContext{ID: "other"}), ReplayEntry{Kind: ReplayKindModel})
+	require.NoError(t, recorder.FinishTask(task.ID))
+
+	replayer, err := LoadReplay(ReplayPath(dir, task.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "read a file", replayer.Task.Instruction)
+	assert.Equal(t, 8192, replayer.ModelContext.ContextLength)
+	require.Len(t, replayer.Steps(), 2)
+
+	var seen []string
+	replayer.OnStep = func(entry ReplayEntry) { seen = append(seen, entry.Kind) }
+	registry.UseToolTap(replayer)
+	resp, err := replayer.NextModel("chat", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "read it", resp.Text)
+	result, err := wrapped.Execute(ctx, NewContext(), map[string]interface{}{"path": "a.go", "limit": 10})
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", result.Data["path"])
+	assert.Equal(t, 1, tool.calls, "replayed tools do not run")
+	assert.Equal(t, []string{ReplayKindModel, ReplayKindTool}, seen)
+	assert.Empty(t, replayer.Mismatches())
+	assert.Equal(t, 2, replayer.Served())
+
+	_, err = replayer.NextModel("chat", "d2")
+	assert.True(t, errors.Is(err, ErrReplayDiverged))
+}
+
+func TestReplayReportsDivergence(t *testing.T) {
+	replayer := &Replayer{Task: &Task{ID: "t"}, entries: []ReplayEntry{
+		{Seq: 1, Kind: ReplayKindModel, Method: "chat", Digest: "d1", Response: &LLMResponse{Text: "x"}},
+		{Seq: 2, Kind: ReplayKindTool, Tool: "file_read", Args: map[string]interface{}{"path": "a.go"}, Result: &ToolResult{Success: true}},
+	}}
+	_, err := replayer.NextModel("chat", "changed")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"step 1: chat prompt differs from the recording"}, replayer.Mismatches())
+
+	_, err, ok := replayer.ReplayTool(context.Background(), "file_write", nil)
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, ErrReplayDiverged)
+	assert.ErrorContains(t, err, "recording has a tool call (file_read)")
+}
@@ -0,0 +1,215 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryClass names a family of transient failures a RetryPolicy may retry.
+type RetryClass string
+
+const (
+	// RetryNetwork covers refused or reset connections and other transport
+	// failures, typically Ollama restarting or still loading a model.
+	RetryNetwork RetryClass = "network"
+	// RetryTimeout covers request timeouts that did not come from the
+	// caller's context.
+	RetryTimeout RetryClass = "timeout"
+	// RetryServer covers 5xx and 429 responses from errors exposing a
+	// StatusCode() int method.
+	RetryServer RetryClass = "server"
+	// RetryMalformedJSON covers responses that failed to decode, including
+	// model output that should have been JSON.
+	RetryMalformedJSON RetryClass = "malformed_json"
+)
+
+// Valid reports whether c is a known class.
+func (c RetryClass) Valid() bool {
+	switch c {
+	case RetryNetwork, RetryTimeout, RetryServer, RetryMalformedJSON:
+		return true
+	}
+	return false
+}
+
+// RetryPolicy bounds how transient LLM and tool failures are retried. The
+// zero value behaves like DefaultRetryPolicy; set MaxAttempts to 1 to disable
+// retries.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	RetryOn        []RetryClass
+}
+
+// DefaultRetryPolicy retries every class three times with exponential
+// backoff from half a second.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     8 * time.Second,
+		Multiplier:     2,
+		RetryOn:        []RetryClass{RetryNetwork, RetryTimeout, RetryServer, RetryMalformedJSON},
+	}
+}
+
+// withDefaults fills unset fields from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = def.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = def.Multiplier
+	}
+	if len(p.RetryOn) == 0 {
+		p.RetryOn = def.RetryOn
+	}
+	return p
+}
+
+// Backoff returns the delay after the given failed attempt (1-based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(delay)
+}
+
+// ForTools returns p without RetryMalformedJSON. Decoding failures come
+// from model output, which asking again can fix; running a tool again cannot.
+func (p RetryPolicy) ForTools() RetryPolicy {
+	p = p.withDefaults()
+	classes := make([]RetryClass, 0, len(p.RetryOn))
+	for _, c := range p.RetryOn {
+		if c != RetryMalformedJSON {
+			classes = append(classes, c)
+		}
+	}
+	if len(classes) == 0 {
+		p.MaxAttempts = 1
+		return p
+	}
+	p.RetryOn = classes
+	return p
+}
+
+// retries reports whether the policy retries class.
+func (p RetryPolicy) retries(class RetryClass) bool {
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyError maps err onto a RetryClass, or "" when it is not transient.
+// Cancellation and graph/node timeouts are never retried.
+func ClassifyError(err error) RetryClass {
+	if err == nil || errors.Is(err, context.Canceled) || IsTimeout(err) {
+		return ""
+	}
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) {
+		if code := status.StatusCode(); code >= 500 || code == 429 {
+			return RetryServer
+		}
+		return ""
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return RetryMalformedJSON
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return RetryTimeout
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) {
+		return RetryNetwork
+	}
+	return ""
+}
+
+// Retry runs fn until it succeeds, fails with an error the policy does not
+// retry, or exhausts MaxAttempts. Each retry emits an EventRetry on
+// telemetry (when non-nil) carrying the operation, attempt, and class, so
+// retry counts show up alongside the task's other events. The final error
+// notes the attempt count and wraps the last failure.
+func Retry(ctx context.Context, policy RetryPolicy, telemetry Telemetry, op string, fn func() error) error {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		class := ClassifyError(err)
+		if class == "" || !policy.retries(class) || ctx.Err() != nil {
+			return retryError(op, attempt, err)
+		}
+		if attempt >= policy.MaxAttempts {
+			return retryError(op, attempt, err)
+		}
+		delay := policy.Backoff(attempt)
+		emitRetry(ctx, telemetry, op, attempt, policy.MaxAttempts, class, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryError(op, attempt, err)
+		case <-timer.C:
+		}
+	}
+}
+
+func retryError(op string, attempts int, err error) error {
+	if attempts <= 1 {
+		return err
+	}
+	return fmt.Errorf("%s failed after %d attempts: %w", op, attempts, err)
+}
+
+func emitRetry(ctx context.Context, telemetry Telemetry, op string, attempt, maxAttempts int, class RetryClass, delay time.Duration, err error) {
+	if telemetry == nil {
+		return
+	}
+	event := Event{
+		Type:      EventRetry,
+		Timestamp: time.Now().UTC(),
+		Message:   fmt.Sprintf("retrying %s after %s", op, class),
+		Metadata: map[string]interface{}{
+			"operation":    op,
+			"attempt":      attempt,
+			"max_attempts": maxAttempts,
+			"class":        string(class),
+			"delay_ms":     delay.Milliseconds(),
+			"error":        err.Error(),
+		},
+	}
+	if task, ok := TaskContextFrom(ctx); ok {
+		event.TaskID = task.ID
+		event.NodeID = task.NodeID
+	}
+	telemetry.Emit(event)
+}
@@ -0,0 +1,149 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statusErr int
+
+func (e statusErr) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusErr) StatusCode() int { return int(e) }
+
+type recordingTelemetry struct {
+	events []Event
+}
+
+func (r *recordingTelemetry) Emit(event Event) { r.events = append(r.events, event) }
+
+func fastRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func TestClassifyError(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	cases := map[string]struct {
+		err  error
+		want RetryClass
+	}{
+		"nil":         {nil, ""},
+		"plain":       {errors.New("boom"), ""},
+		"canceled":    {context.Canceled, ""},
+		"deadline":    {fmt.Errorf("post: %w", context.DeadlineExceeded), RetryTimeout},
+		"graph":       {&TimeoutError{Scope: TimeoutScopeNode, NodeID: "think"}, ""},
+		"refused":     {&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, RetryNetwork},
+		"server":      {statusErr(503), RetryServer},
+		"rate_limit":  {statusErr(429), RetryServer},
+		"bad_request": {statusErr(400), ""},
+		"json":        {syntaxErr, RetryMalformedJSON},
+	}
+	for name, tc := range cases {
+		assert.Equal(t, tc.want, ClassifyError(tc.err), name)
+	}
+}
+
+func TestRetryRecoversFromTransientFailure(t *testing.T) {
+	telemetry := &recordingTelemetry{}
+	ctx := WithTaskContext(context.Background(), TaskContext{ID: "task-1", NodeID: "think"})
+	calls := 0
+	err := Retry(ctx, fastRetryPolicy(), telemetry, "llm.generate", func() error {
+		calls++
+		if calls < 3 {
+			return statusErr(502)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, telemetry.events, 2)
+	assert.Equal(t, EventRetry, telemetry.events[1].Type)
+	assert.Equal(t, "task-1", telemetry.events[1].TaskID)
+	assert.Equal(t, 2, telemetry.events[1].Metadata["attempt"])
+	assert.Equal(t, "server", telemetry.events[1].Metadata["class"])
+}
+
+func TestRetryStopsOnPermanentOrExhaustedFailure(t *testing.T) {
+	calls := 0
+	permanent := errors.New("file not found")
+	err := Retry(context.Background(), fastRetryPolicy(), nil, "tool.read", func() error {
+		calls++
+		return permanent
+	})
+	assert.Same(t, permanent, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	policy := fastRetryPolicy()
+	policy.RetryOn = []RetryClass{RetryServer}
+	err = Retry(context.Background(), policy, nil, "llm.chat", func() error {
+		calls++
+		return statusErr(500)
+	})
+	assert.Equal(t, 3, calls)
+	assert.EqualError(t, err, "llm.chat failed after 3 attempts: status 500")
+	var status statusErr
+	assert.True(t, errors.As(err, &status))
+
+	calls = 0
+	err = Retry(context.Background(), policy, nil, "llm.chat", func() error {
+		calls++
+		return malformedJSONError()
+	})
+	assert.Equal(t, 1, calls, "classes outside RetryOn are not retried")
+	assert.Error(t, err)
+}
+
+func malformedJSONError() error {
+	return json.Unmarshal([]byte(`{"a":`), &struct{}{})
+}
+
+func TestRetryPolicyBackoffAndSpec(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(3))
+
+	spec := &AgentRetrySpec{MaxAttempts: 5, InitialBackoff: "1s", RetryOn: []RetryClass{RetryNetwork}}
+	parsed, err := spec.RetryPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, parsed.MaxAttempts)
+	assert.Equal(t, time.Second, parsed.InitialBackoff)
+
+	_, err = (&AgentRetrySpec{RetryOn: []RetryClass{"flaky"}}).RetryPolicy()
+	assert.Error(t, err)
+	_, err = (&AgentRetrySpec{MaxBackoff: "soon"}).RetryPolicy()
+	assert.Error(t, err)
+}
+
+func TestToolIdempotent(t *testing.T) {
+	read := stubTool{name: "read", perms: NewFileSystemPermissionSet("", FileSystemRead, FileSystemList)}
+	write := stubTool{name: "write", perms: NewFileSystemPermissionSet("", FileSystemRead, FileSystemWrite)}
+	run := stubTool{name: "run", perms: &PermissionSet{Executables: []ExecutablePermission{{Binary: "go"}}}}
+	assert.True(t, ToolIdempotent(read))
+	assert.False(t, ToolIdempotent(write))
+	assert.False(t, ToolIdempotent(run))
+	assert.False(t, ToolIdempotent(stubTool{name: "none"}))
+	assert.True(t, ToolIdempotent(idempotentTool{run}), "tools may declare themselves idempotent")
+
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(read))
+	wrapped, _ := registry.Get("read")
+	assert.True(t, ToolIdempotent(wrapped))
+
+	policy := RetryPolicy{RetryOn: []RetryClass{RetryNetwork, RetryMalformedJSON}}.ForTools()
+	assert.Equal(t, []RetryClass{RetryNetwork}, policy.RetryOn)
+	assert.Equal(t, 1, RetryPolicy{RetryOn: []RetryClass{RetryMalformedJSON}}.ForTools().MaxAttempts)
+}
+
+type idempotentTool struct{ stubTool }
+
+func (idempotentTool) Idempotent() bool { return true }
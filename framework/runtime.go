@@ -15,6 +15,29 @@ type RuntimeConfig struct {
 	AuditLimit   int
 	BaseFS       string
 	HITLTimeout  time.Duration
+	// Permissions, when set, replaces the manifest's permission set, e.g.
+	// with a preset chosen for one run.
+	Permissions *PermissionSet
+	// AuditSinks export permission decisions beyond the in-memory log. When
+	// set, AgentRegistration.Audit is a *BatchingAuditLogger.
+	AuditSinks []AuditSinkRegistration
+	// Policy, when set, is consulted after manifest matching for every
+	// permission decision (see PolicyEvaluator).
+	Policy PolicyEvaluator
+	// SandboxCache, when set, lets RegisterAgent skip the runsc and container
+	// runtime probes after a previous successful verification.
+	SandboxCache SandboxVerificationCache
+	// RequireSandbox makes RegisterAgent fail when the sandbox cannot be
+	// verified. Otherwise registration continues in degraded mode (see
+	// SandboxStatus) and commands run on the host.
+	RequireSandbox bool
+}
+
+// SandboxVerificationCache remembers successful sandbox verifications.
+// Implementations decide when an entry is stale (binary upgrades, TTLs).
+type SandboxVerificationCache interface {
+	LookupSandbox(cfg SandboxConfig) (version string, ok bool)
+	StoreSandbox(cfg SandboxConfig, version string)
 }
 
 // AgentRegistration stores runtime metadata.
@@ -25,6 +48,8 @@ type AgentRegistration struct {
 	Permissions *PermissionManager
 	Audit       AuditLogger
 	HITL        *HITLBroker
+	// Sandbox reports whether commands run sandboxed or degraded on the host.
+	Sandbox SandboxStatus
 }
 
 // RegisterAgent validates the manifest and builds enforcement primitives.
@@ -36,17 +61,39 @@ func RegisterAgent(ctx context.Context, cfg RuntimeConfig) (*AgentRegistration,
 	if err != nil {
 		return nil, fmt.Errorf("load manifest: %w", err)
 	}
+	if cfg.Permissions != nil {
+		if err := cfg.Permissions.Validate(); err != nil {
+			return nil, fmt.Errorf("permissions: %w", err)
+		}
+		manifest.Spec.Permissions = *cfg.Permissions
+	}
 	runtime := NewGVisorRuntime(cfg.Sandbox)
-	if err := runtime.Verify(ctx); err != nil {
-		return nil, fmt.Errorf("sandbox verification failed: %w", err)
+	sandbox := SandboxStatus{Runtime: runtime.Name()}
+	if err := verifySandbox(ctx, runtime, cfg.SandboxCache); err != nil {
+		if cfg.RequireSandbox {
+			return nil, fmt.Errorf("sandbox verification failed: %w", err)
+		}
+		sandbox = degradedSandbox(err)
 	}
 	hitl := NewHITLBroker(cfg.HITLTimeout)
-	audit := NewInMemoryAuditLogger(cfg.AuditLimit)
+	var audit AuditLogger = NewInMemoryAuditLogger(cfg.AuditLimit)
+	if len(cfg.AuditSinks) > 0 {
+		batching := NewBatchingAuditLogger(audit)
+		for _, reg := range cfg.AuditSinks {
+			if err := batching.AddSink(reg.Name, reg.Sink, reg.Options); err != nil {
+				return nil, fmt.Errorf("audit sink %s: %w", reg.Name, err)
+			}
+		}
+		audit = batching
+	}
 	permissions, err := NewPermissionManager(cfg.BaseFS, &manifest.Spec.Permissions, audit, hitl)
 	if err != nil {
 		return nil, fmt.Errorf("permission manager init: %w", err)
 	}
 	permissions.AttachRuntime(runtime)
+	if cfg.Policy != nil {
+		permissions.SetPolicyEvaluator(cfg.Policy)
+	}
 	networkRules := buildNetworkPolicy(manifest.Spec.Permissions.Network)
 	policy := SandboxPolicy{
 		NetworkRules: networkRules,
@@ -60,9 +107,38 @@ func RegisterAgent(ctx context.Context, cfg RuntimeConfig) (*AgentRegistration,
 		Permissions: permissions,
 		Audit:       audit,
 		HITL:        hitl,
+		Sandbox:     sandbox,
 	}, nil
 }
 
+// CommandRunner returns the runner for workspace commands: the gVisor runner
+// normally, or a host runner when the sandbox is degraded.
+func (r *AgentRegistration) CommandRunner(workspace string) (CommandRunner, error) {
+	if r.Sandbox.Degraded {
+		return NewLocalCommandRunner(workspace)
+	}
+	return NewSandboxCommandRunner(r.Manifest, r.Runtime, workspace)
+}
+
+// verifySandbox consults cache before running the sandbox probes and records
+// a successful verification for the next startup.
+func verifySandbox(ctx context.Context, runtime *GVisorRuntime, cache SandboxVerificationCache) error {
+	cfg := runtime.RunConfig()
+	if cache != nil {
+		if version, ok := cache.LookupSandbox(cfg); ok {
+			runtime.MarkVerified(version)
+			return nil
+		}
+	}
+	if err := runtime.Verify(ctx); err != nil {
+		return err
+	}
+	if cache != nil {
+		cache.StoreSandbox(cfg, runtime.Version())
+	}
+	return nil
+}
+
 // buildNetworkPolicy converts network permissions into sandbox-friendly rules
 // so gVisor enforces the same view of allowed hosts/ports as the permission
 // manager.
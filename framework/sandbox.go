@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	goruntime "runtime"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,25 @@ type SandboxRuntime interface {
 	EnforcePolicy(policy SandboxPolicy) error
 }
 
+// SandboxStatus reports whether agent commands run inside the sandbox. When
+// Degraded is set no sandbox could be verified: commands run directly on the
+// host and Reason explains why.
+type SandboxStatus struct {
+	Runtime  string `json:"runtime"`
+	Degraded bool   `json:"degraded"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// degradedSandbox describes a failed verification. gVisor only runs on Linux,
+// so other platforms get a reason that says so rather than a missing binary.
+func degradedSandbox(err error) SandboxStatus {
+	reason := err.Error()
+	if goruntime.GOOS != "linux" {
+		reason = fmt.Sprintf("gVisor requires Linux, running on %s (%v)", goruntime.GOOS, err)
+	}
+	return SandboxStatus{Runtime: "host", Degraded: true, Reason: reason}
+}
+
 // SandboxConfig exposes runtime knobs.
 type SandboxConfig struct {
 	RunscPath        string
@@ -88,15 +108,31 @@ func (g *GVisorRuntime) Verify(ctx context.Context) error {
 		return nil
 	}
 	if err := g.checkRunsc(ctx); err != nil {
-		return err
+		return WrapError(ErrorKindSandbox, err)
 	}
 	if err := g.checkContainerRuntime(ctx); err != nil {
-		return err
+		return WrapError(ErrorKindSandbox, err)
 	}
 	g.verified = true
 	return nil
 }
 
+// MarkVerified records a verification performed earlier (for example by a
+// startup cache) so Verify returns immediately.
+func (g *GVisorRuntime) MarkVerified(version string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.verified = true
+	g.version = version
+}
+
+// Version returns the runsc version captured during verification.
+func (g *GVisorRuntime) Version() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.version
+}
+
 // EnforcePolicy stores the effective sandbox policies for future launches.
 func (g *GVisorRuntime) EnforcePolicy(policy SandboxPolicy) error {
 	g.mu.Lock()
@@ -357,6 +357,17 @@ func (sc *SharedContext) SetChangeLogSummary(summary string) {
 	sc.changeLogSummary = summary
 }
 
+// CompressHistory summarizes older interactions like
+// Context.CompressHistory. When the shared summarizer is an LLMSummarizer it
+// writes the summary, so its model, token cap, and fallback apply; strategy
+// still decides how much history to keep.
+func (sc *SharedContext) CompressHistory(keepRecentCount int, llm LanguageModel, strategy CompressionStrategy) error {
+	if summarizer, ok := sc.summarizer.(*LLMSummarizer); ok && strategy != nil {
+		strategy = summaryCompression{CompressionStrategy: strategy, summarizer: summarizer}
+	}
+	return sc.Context.CompressHistory(keepRecentCount, llm, strategy)
+}
+
 // RefreshConversationSummary rebuilds the summary using either the latest
 // compressed chunk or a fresh summarization of recent history.
 func (sc *SharedContext) RefreshConversationSummary() {
@@ -0,0 +1,376 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefaultStructuredAttempts is how many responses GenerateJSON requests
+// before giving up when the policy sets no limit.
+const DefaultStructuredAttempts = 3
+
+// ErrNoJSON reports a response that contains no JSON value at all.
+var ErrNoJSON = errors.New("response contains no JSON")
+
+// StructuredOutputPolicy governs the nodes that expect JSON from the model.
+// Each request carries a JSON schema, which Ollama enforces through its
+// format parameter. Responses are still repaired and validated, because not
+// every model honours the constraint, and a response that does not match is
+// sent back with the validation error for another try.
+type StructuredOutputPolicy struct {
+	// MaxAttempts bounds the responses requested per call. Zero means
+	// DefaultStructuredAttempts; 1 disables re-asking.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// Unconstrained stops sending the schema with the request, for backends
+	// that reject Ollama's format parameter. Responses are still validated.
+	Unconstrained bool `yaml:"unconstrained,omitempty"`
+}
+
+func (p StructuredOutputPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultStructuredAttempts
+	}
+	return p.MaxAttempts
+}
+
+// StructuredGenerateFunc sends prompt to the model. schema is the
+// constraint to pass as LLMOptions.Schema; it is nil when the policy is
+// unconstrained.
+type StructuredGenerateFunc func(ctx context.Context, prompt string, schema map[string]interface{}) (*LLMResponse, error)
+
+// StructuredOutputError reports that no response matched the schema. Raw is
+// the last response, so callers with a plain-text fallback can still use it.
+type StructuredOutputError struct {
+	Attempts int
+	Raw      string
+	Err      error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("no valid structured output after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error { return e.Err }
+
+// ErrorKind reports ErrorKindParseFailure.
+func (e *StructuredOutputError) ErrorKind() ErrorKind { return ErrorKindParseFailure }
+
+// GenerateJSON asks generate for a response matching schema and decodes it
+// into out, which may be nil to only validate. The returned response's Text
+// is the repaired JSON. When every attempt fails validation it returns the
+// last response and a *StructuredOutputError, as it does when re-asking
+// fails. Errors from the first request are returned as they are.
+func (p StructuredOutputPolicy) GenerateJSON(ctx context.Context, schema map[string]interface{}, prompt string, generate StructuredGenerateFunc, out interface{}) (*LLMResponse, error) {
+	constraint := schema
+	if p.Unconstrained {
+		constraint = nil
+	}
+	attempts := p.attempts()
+	current := prompt
+	var resp *LLMResponse
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		next, err := generate(ctx, current, constraint)
+		if err != nil {
+			// A failed re-ask still leaves the previous response to fall
+			// back on.
+			if resp == nil || ctx.Err() != nil {
+				return next, err
+			}
+			lastErr = fmt.Errorf("%v; re-asking failed: %w", lastErr, err)
+			return resp, &StructuredOutputError{Attempts: attempt - 1, Raw: resp.Text, Err: lastErr}
+		}
+		resp = next
+		if resp == nil {
+			resp = &LLMResponse{}
+		}
+		text, err := DecodeStructured(resp.Text, schema, out)
+		if err == nil {
+			repaired := *resp
+			repaired.Text = text
+			return &repaired, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return resp, ctx.Err()
+		}
+		current = prompt + structuredFeedback(err, schema)
+	}
+	return resp, &StructuredOutputError{Attempts: attempts, Raw: resp.Text, Err: lastErr}
+}
+
+// structuredFeedback tells the model why its last response was rejected.
+func structuredFeedback(err error, schema map[string]interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\nYour previous response was rejected: %v.\n", err)
+	b.WriteString("Respond with only a JSON value")
+	if encoded, jsonErr := json.Marshal(schema); jsonErr == nil && len(schema) > 0 {
+		fmt.Fprintf(&b, " matching this schema:\n%s", encoded)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// DecodeStructured repairs raw into JSON, validates it against schema (when
+// set), and decodes it into out (when non-nil). It returns the repaired
+// JSON.
+func DecodeStructured(raw string, schema map[string]interface{}, out interface{}) (string, error) {
+	text := RepairJSON(raw)
+	if text == "" {
+		return "", ErrNoJSON
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return "", err
+	}
+	if err := ValidateJSON(schema, value); err != nil {
+		return "", err
+	}
+	if out != nil {
+		if err := json.Unmarshal([]byte(text), out); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// RepairJSON extracts the first JSON object or array from a model response
+// and fixes the mistakes models commonly make: surrounding prose and code
+// fences, trailing commas, and output cut off by the token limit, whose open
+// strings and brackets are closed. It returns "" when raw holds no object or
+// array.
+func RepairJSON(raw string) string {
+	start := strings.IndexAny(raw, "{[")
+	if start < 0 {
+		return ""
+	}
+	var out strings.Builder
+	var stack []byte
+	inString, escaped := false, false
+	for i := start; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			trimTrailingComma(&out)
+			if len(stack) > 0 {
+				c = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		}
+		out.WriteByte(c)
+		if len(stack) == 0 {
+			return out.String()
+		}
+	}
+	if inString {
+		if escaped {
+			trimLast(&out)
+		}
+		out.WriteByte('"')
+	}
+	text := strings.TrimRight(out.String(), " \t\r\n")
+	switch {
+	case strings.HasSuffix(text, ","):
+		text = text[:len(text)-1]
+	case strings.HasSuffix(text, ":"):
+		text += "null"
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		text += string(stack[i])
+	}
+	return text
+}
+
+func trimTrailingComma(b *strings.Builder) {
+	text := strings.TrimRight(b.String(), " \t\r\n")
+	if !strings.HasSuffix(text, ",") {
+		return
+	}
+	text = text[:len(text)-1]
+	b.Reset()
+	b.WriteString(text)
+}
+
+func trimLast(b *strings.Builder) {
+	text := b.String()
+	b.Reset()
+	b.WriteString(text[:len(text)-1])
+}
+
+// SchemaError reports where a value fails its schema.
+type SchemaError struct {
+	Path   string
+	Reason string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+}
+
+// ValidateJSON checks a decoded JSON value against the subset of JSON Schema
+// the agents use: type, enum, properties, required, additionalProperties
+// (false only), items, and minItems. A nil schema accepts anything.
+func ValidateJSON(schema map[string]interface{}, value interface{}) error {
+	return validateSchema("$", schema, value)
+}
+
+func validateSchema(path string, schema map[string]interface{}, value interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if types := schemaStrings(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, typ := range types {
+			if jsonTypeMatches(typ, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &SchemaError{Path: path, Reason: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))}
+		}
+	}
+	if enum, ok := schema["enum"]; ok {
+		if !enumContains(enum, value) {
+			return &SchemaError{Path: path, Reason: fmt.Sprintf("%v is not one of %v", value, enum)}
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return &SchemaError{Path: path, Reason: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, ok := props[key].(map[string]interface{})
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+					return &SchemaError{Path: path, Reason: fmt.Sprintf("unexpected property %q", key)}
+				}
+				continue
+			}
+			if err := validateSchema(path+"."+key, sub, v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if min, ok := schema["minItems"].(int); ok && len(v) < min {
+			return &SchemaError{Path: path, Reason: fmt.Sprintf("expected at least %d items, got %d", min, len(v))}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range v {
+			if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaStrings reads a string or list of strings, as written in Go
+// ([]string) or decoded from JSON ([]interface{}).
+func schemaStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func jsonTypeMatches(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func enumContains(enum interface{}, value interface{}) bool {
+	rv := reflect.ValueOf(enum)
+	if rv.Kind() != reflect.Slice {
+		return true
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if reflect.DeepEqual(rv.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAnswerSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"answer", "confidence"},
+	"properties": map[string]interface{}{
+		"answer":     map[string]interface{}{"type": "string"},
+		"confidence": map[string]interface{}{"type": "string", "enum": []string{"high", "low"}},
+		"lines": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items":    map[string]interface{}{"type": "integer"},
+		},
+	},
+}
+
+func TestRepairJSON(t *testing.T) {
+	cases := map[string]string{
+		"prose and fence":  "Sure!\n```json\n{\"a\": 1}\n```\nHope that helps.",
+		"trailing commas":  `{"a": [1, 2,], "b": {"c": 3,},}`,
+		"cut off":          `{"a": [1, 2`,
+		"open string":      `{"a": "unfinished`,
+		"dangling key":     `{"a": 1, "b":`,
+		"mismatched close": `{"a": [1}`,
+	}
+	want := map[string]string{
+		"prose and fence":  `{"a": 1}`,
+		"trailing commas":  `{"a": [1, 2], "b": {"c": 3}}`,
+		"cut off":          `{"a": [1, 2]}`,
+		"open string":      `{"a": "unfinished"}`,
+		"dangling key":     `{"a": 1, "b":null}`,
+		"mismatched close": `{"a": [1]}`,
+	}
+	for name, raw := range cases {
+		assert.Equal(t, want[name], RepairJSON(raw), name)
+	}
+	assert.Equal(t, `{"s": "a } b"}`, RepairJSON(`{"s": "a } b"} trailing`))
+	assert.Equal(t, "", RepairJSON("no json here"))
+}
+
+func TestValidateJSON(t *testing.T) {
+	valid := map[string]interface{}{"answer": "yes", "confidence": "high", "lines": []interface{}{float64(3)}}
+	assert.NoError(t, ValidateJSON(testAnswerSchema, valid))
+	assert.NoError(t, ValidateJSON(nil, "anything"))
+
+	cases := map[string]struct {
+		value map[string]interface{}
+		err   string
+	}{
+		"missing":    {map[string]interface{}{"answer": "yes"}, `$: missing required property "confidence"`},
+		"wrong type": {map[string]interface{}{"answer": 1.0, "confidence": "high"}, "$.answer: expected string, got number"},
+		"enum":       {map[string]interface{}{"answer": "yes", "confidence": "maybe"}, "$.confidence: maybe is not one of [high low]"},
+		"integer":    {map[string]interface{}{"answer": "yes", "confidence": "low", "lines": []interface{}{1.5}}, "$.lines[0]: expected integer, got number"},
+		"min items":  {map[string]interface{}{"answer": "yes", "confidence": "low", "lines": []interface{}{}}, "$.lines: expected at least 1 items, got 0"},
+	}
+	for name, tc := range cases {
+		err := ValidateJSON(testAnswerSchema, tc.value)
+		var schemaErr *SchemaError
+		require.True(t, errors.As(err, &schemaErr), name)
+		assert.Equal(t, tc.err, err.Error(), name)
+	}
+}
+
+func TestGenerateJSONReasksWithValidationError(t *testing.T) {
+	responses := []string{
+		`{"answer": "yes"}`,
+		"```json\n{\"answer\": \"yes\", \"confidence\": \"high\",}\n```",
+	}
+	var prompts []string
+	var schemas []map[string]interface{}
+	generate := func(ctx context.Context, prompt string, schema map[string]interface{}) (*LLMResponse, error) {
+		prompts = append(prompts, prompt)
+		schemas = append(schemas, schema)
+		text := responses[0]
+		responses = responses[1:]
+		return &LLMResponse{Text: text}, nil
+	}
+	var out struct {
+		Answer     string `json:"answer"`
+		Confidence string `json:"confidence"`
+	}
+	resp, err := StructuredOutputPolicy{}.GenerateJSON(context.Background(), testAnswerSchema, "question", generate, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "high", out.Confidence)
+	assert.Equal(t, `{"answer": "yes", "confidence": "high"}`, resp.Text)
+	require.Len(t, prompts, 2)
+	assert.Equal(t, "question", prompts[0])
+	assert.Contains(t, prompts[1], `missing required property "confidence"`)
+	assert.Contains(t, prompts[1], `"required":["answer","confidence"]`)
+	assert.NotNil(t, schemas[0], "the schema is sent as a constraint")
+}
+
+func TestGenerateJSONGivesUpWithLastResponse(t *testing.T) {
+	calls := 0
+	generate := func(ctx context.Context, prompt string, schema map[string]interface{}) (*LLMResponse, error) {
+		calls++
+		assert.Nil(t, schema, "unconstrained policies send no schema")
+		return &LLMResponse{Text: "plain answer"}, nil
+	}
+	policy := StructuredOutputPolicy{MaxAttempts: 2, Unconstrained: true}
+	resp, err := policy.GenerateJSON(context.Background(), testAnswerSchema, "q", generate, nil)
+	var invalid *StructuredOutputError
+	require.True(t, errors.As(err, &invalid))
+	assert.True(t, errors.Is(err, ErrNoJSON))
+	assert.Equal(t, "plain answer", invalid.Raw)
+	assert.Equal(t, "plain answer", resp.Text)
+	assert.Equal(t, 2, calls)
+
+	failing := func(ctx context.Context, prompt string, schema map[string]interface{}) (*LLMResponse, error) {
+		return nil, errors.New("connection refused")
+	}
+	_, err = policy.GenerateJSON(context.Background(), testAnswerSchema, "q", failing, nil)
+	assert.EqualError(t, err, "connection refused")
+}
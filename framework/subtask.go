@@ -0,0 +1,320 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSubTaskDepth is returned when a sub-task would nest deeper than the
+// delegator allows, which stops agents from delegating to each other forever.
+var ErrSubTaskDepth = errors.New("sub-task depth limit reached")
+
+// DefaultSubTaskDepth bounds nesting when a Delegator sets no MaxDepth.
+const DefaultSubTaskDepth = 3
+
+// SubTask is a unit of work handed to another registered agent. The child
+// runs with its own Task and Context, and with Timeout as its time budget.
+type SubTask struct {
+	// Agent names the registered agent that runs the task.
+	Agent string
+	Task  *Task
+	// State is the child's starting context; it defaults to a clone of the
+	// parent's, so the child sees what the parent knew when it spawned it.
+	State   *Context
+	Timeout time.Duration
+	// MergeKeys are copied from the child's final state into the parent's
+	// when the child succeeds. Outputs are always recorded; see Run.
+	MergeKeys []string
+	// Channels limits the message bus channels the child may use; nil
+	// leaves it the parent's. Either way the child publishes as Agent.
+	Channels *BusScope
+}
+
+// SubTaskResult is the outcome of one sub-task.
+type SubTaskResult struct {
+	Agent  string
+	Task   *Task
+	Result *Result
+	State  *Context
+	Err    error
+}
+
+// Output returns the child's final output, falling back to its text.
+func (r SubTaskResult) Output() any {
+	if r.Result == nil || r.Result.Data == nil {
+		return nil
+	}
+	if output, ok := r.Result.Data["final_output"]; ok {
+		return output
+	}
+	return r.Result.Data["text"]
+}
+
+// Delegator lets agents spawn child tasks on other registered agents.
+// Attach it with WithDelegator so graph nodes can reach it. MaxDepth bounds
+// nesting (DefaultSubTaskDepth when zero) and MaxParallel bounds how many
+// children run at once (unlimited when zero).
+type Delegator struct {
+	MaxDepth    int
+	MaxParallel int
+
+	mu     sync.RWMutex
+	agents map[string]Agent
+	seq    atomic.Int64
+	slots  chan struct{}
+	once   sync.Once
+}
+
+// NewDelegator creates an empty delegator.
+func NewDelegator() *Delegator {
+	return &Delegator{agents: make(map[string]Agent)}
+}
+
+// Register makes agent available to sub-tasks under name.
+func (d *Delegator) Register(name string, agent Agent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.agents == nil {
+		d.agents = make(map[string]Agent)
+	}
+	d.agents[name] = agent
+}
+
+// SubTaskHandle tracks a spawned sub-task.
+type SubTaskHandle struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	result SubTaskResult
+}
+
+// Wait blocks until the sub-task finishes and returns its result.
+func (h *SubTaskHandle) Wait() SubTaskResult {
+	<-h.done
+	return h.result
+}
+
+// Done is closed when the sub-task finishes.
+func (h *SubTaskHandle) Done() <-chan struct{} { return h.done }
+
+// Cancel stops the sub-task; Wait still returns once it has unwound.
+func (h *SubTaskHandle) Cancel() { h.cancel() }
+
+// Spawn starts sub in the background under ctx and returns immediately.
+// Cancelling ctx cancels the child. Sub-tasks the child spawns count one
+// level deeper; past MaxDepth the handle resolves to ErrSubTaskDepth.
+func (d *Delegator) Spawn(ctx context.Context, parent *Context, sub SubTask) *SubTaskHandle {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var childCtx context.Context
+	var cancel context.CancelFunc
+	if sub.Timeout > 0 {
+		childCtx, cancel = context.WithTimeout(ctx, sub.Timeout)
+	} else {
+		childCtx, cancel = context.WithCancel(ctx)
+	}
+	handle := &SubTaskHandle{done: make(chan struct{}), cancel: cancel}
+	task := d.prepareTask(ctx, sub)
+	handle.result = SubTaskResult{Agent: sub.Agent, Task: task}
+
+	depth := SubTaskDepth(ctx)
+	d.mu.RLock()
+	agent, ok := d.agents[sub.Agent]
+	d.mu.RUnlock()
+	var err error
+	switch {
+	case depth >= d.maxDepth():
+		err = fmt.Errorf("%w (%d)", ErrSubTaskDepth, d.maxDepth())
+	case !ok:
+		err = fmt.Errorf("sub-task agent %s not registered", sub.Agent)
+	}
+	if err != nil {
+		handle.result.Err = err
+		cancel()
+		close(handle.done)
+		return handle
+	}
+
+	state := sub.State
+	if state == nil {
+		state = NewContext()
+		if parent != nil {
+			state = parent.Clone()
+		}
+	}
+	state.Set("task.id", task.ID)
+	state.Set("task.type", string(task.Type))
+	state.Set("task.instruction", task.Instruction)
+	handle.result.State = state
+	childCtx = context.WithValue(childCtx, subTaskDepthKey{}, depth+1)
+	if MessageBusFrom(childCtx) != nil {
+		var scope BusScope
+		if sub.Channels != nil {
+			scope = *sub.Channels
+		}
+		childCtx = WithBusScope(childCtx, sub.Agent, scope)
+	}
+
+	go func() {
+		defer close(handle.done)
+		defer cancel()
+		if err := d.acquire(childCtx); err != nil {
+			handle.result.Err = err
+			return
+		}
+		defer d.release()
+		result, err := agent.Execute(childCtx, task, state)
+		if err == nil && result != nil && !result.Success {
+			err = result.Error
+			if err == nil {
+				err = errors.New("sub-task reported failure")
+			}
+		}
+		handle.result.Result = result
+		handle.result.Err = err
+	}()
+	return handle
+}
+
+// Run spawns every sub-task, waits for all of them, and merges their
+// outputs into parent under "subtask.<task id>.output" (with ".success" and
+// ".error"), plus each successful child's MergeKeys. The first failure
+// cancels the children still running; the returned error joins every
+// failure, and results keep the order of subs.
+func (d *Delegator) Run(ctx context.Context, parent *Context, subs ...SubTask) ([]SubTaskResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	handles := make([]*SubTaskHandle, len(subs))
+	for i, sub := range subs {
+		handles[i] = d.Spawn(ctx, parent, sub)
+	}
+	var failed sync.Once
+	var wg sync.WaitGroup
+	for _, handle := range handles {
+		wg.Add(1)
+		go func(handle *SubTaskHandle) {
+			defer wg.Done()
+			if handle.Wait().Err != nil {
+				failed.Do(cancel)
+			}
+		}(handle)
+	}
+	wg.Wait()
+
+	results := make([]SubTaskResult, len(handles))
+	var errs []error
+	for i, handle := range handles {
+		results[i] = handle.Wait()
+		if parent != nil {
+			mergeSubTask(parent, results[i], subs[i].MergeKeys)
+		}
+		if results[i].Err != nil {
+			errs = append(errs, fmt.Errorf("sub-task %s (%s): %w", results[i].Task.ID, results[i].Agent, results[i].Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func mergeSubTask(parent *Context, result SubTaskResult, keys []string) {
+	prefix := "subtask." + result.Task.ID
+	parent.Set(prefix+".success", result.Err == nil)
+	if result.Err != nil {
+		parent.Set(prefix+".error", result.Err.Error())
+		return
+	}
+	parent.Set(prefix+".output", result.Output())
+	if result.State == nil {
+		return
+	}
+	for _, key := range keys {
+		if value, ok := result.State.Get(key); ok {
+			parent.Set(key, value)
+		}
+	}
+}
+
+// prepareTask copies sub.Task and gives it an ID derived from the parent
+// task, e.g. "task-1.sub2".
+func (d *Delegator) prepareTask(ctx context.Context, sub SubTask) *Task {
+	task := &Task{}
+	if sub.Task != nil {
+		clone := *sub.Task
+		task = &clone
+	}
+	if task.ID == "" {
+		parentID := "task"
+		if tc, ok := TaskContextFrom(ctx); ok && tc.ID != "" {
+			parentID = tc.ID
+		}
+		task.ID = fmt.Sprintf("%s.sub%d", parentID, d.seq.Add(1))
+	}
+	if task.Type == "" {
+		task.Type = TaskTypeCodeGeneration
+	}
+	return task
+}
+
+func (d *Delegator) maxDepth() int {
+	if d.MaxDepth > 0 {
+		return d.MaxDepth
+	}
+	return DefaultSubTaskDepth
+}
+
+func (d *Delegator) acquire(ctx context.Context) error {
+	if d.MaxParallel <= 0 {
+		return ctx.Err()
+	}
+	d.once.Do(func() { d.slots = make(chan struct{}, d.MaxParallel) })
+	select {
+	case d.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Delegator) release() {
+	if d.MaxParallel > 0 {
+		<-d.slots
+	}
+}
+
+type delegatorKey struct{}
+
+type subTaskDepthKey struct{}
+
+// WithDelegator attaches d to ctx so agents and graph nodes executed under
+// ctx can spawn sub-tasks.
+func WithDelegator(ctx context.Context, d *Delegator) context.Context {
+	if d == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, delegatorKey{}, d)
+}
+
+// DelegatorFromContext returns the delegator attached by WithDelegator.
+func DelegatorFromContext(ctx context.Context) (*Delegator, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	d, ok := ctx.Value(delegatorKey{}).(*Delegator)
+	return d, ok
+}
+
+// SubTaskDepth reports how deeply ctx is nested in sub-tasks; top-level
+// tasks are at depth zero.
+func SubTaskDepth(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	depth, _ := ctx.Value(subTaskDepthKey{}).(int)
+	return depth
+}
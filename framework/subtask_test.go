@@ -0,0 +1,123 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// funcAgent runs a function as an agent.
+type funcAgent func(ctx context.Context, task *Task, state *Context) (*Result, error)
+
+func (f funcAgent) Initialize(*Config) error         { return nil }
+func (f funcAgent) Capabilities() []Capability       { return nil }
+func (f funcAgent) BuildGraph(*Task) (*Graph, error) { return NewGraph(), nil }
+func (f funcAgent) Execute(ctx context.Context, task *Task, state *Context) (*Result, error) {
+	return f(ctx, task, state)
+}
+
+// TestDelegatorRunMergesOutputs checks children get their own task and
+// state and that outputs and merge keys land in the parent.
+func TestDelegatorRunMergesOutputs(t *testing.T) {
+	d := NewDelegator()
+	d.Register("echo", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		state.Set("echo.seen", state.GetString("parent.note")+"/"+state.GetString("task.id"))
+		return &Result{Success: true, Data: map[string]any{"final_output": "did " + task.Instruction}}, nil
+	}))
+	parent := NewContext()
+	parent.Set("parent.note", "hello")
+	ctx := WithTaskContext(context.Background(), TaskContext{ID: "job"})
+
+	results, err := d.Run(ctx, parent,
+		SubTask{Agent: "echo", Task: &Task{Instruction: "a"}, MergeKeys: []string{"echo.seen"}},
+		SubTask{Agent: "echo", Task: &Task{ID: "named", Instruction: "b"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "job.sub1", results[0].Task.ID)
+	require.Equal(t, "did a", parent.GetString("subtask.job.sub1.output"))
+	require.Equal(t, "did b", parent.GetString("subtask.named.output"))
+	require.Equal(t, "hello/job.sub1", parent.GetString("echo.seen"))
+	_, leaked := parent.Get("task.id")
+	require.False(t, leaked, "child task ids must not overwrite the parent state")
+}
+
+// TestDelegatorRunCancelsSiblingsOnFailure checks the first failure cancels
+// children still running and is reported in the joined error.
+func TestDelegatorRunCancelsSiblingsOnFailure(t *testing.T) {
+	d := NewDelegator()
+	d.Register("fail", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		return nil, errors.New("boom")
+	}))
+	d.Register("slow", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return &Result{Success: true}, nil
+		}
+	}))
+	parent := NewContext()
+	start := time.Now()
+	results, err := d.Run(context.Background(), parent, SubTask{Agent: "slow"}, SubTask{Agent: "fail"})
+	require.Error(t, err)
+	require.ErrorContains(t, err, "boom")
+	require.ErrorIs(t, results[0].Err, context.Canceled)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, false, mustGet(t, parent, "subtask."+results[1].Task.ID+".success"))
+}
+
+// TestDelegatorLimits covers the depth limit, unknown agents, timeouts, and
+// the parallelism cap.
+func TestDelegatorLimits(t *testing.T) {
+	d := NewDelegator()
+	d.MaxDepth = 2
+	d.MaxParallel = 1
+	var running, peak atomic.Int32
+	d.Register("recurse", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		delegator, ok := DelegatorFromContext(ctx)
+		require.True(t, ok)
+		result := delegator.Spawn(ctx, state, SubTask{Agent: "recurse"}).Wait()
+		return nil, result.Err
+	}))
+	d.Register("busy", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		now := running.Add(1)
+		if now > peak.Load() {
+			peak.Store(now)
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return &Result{Success: true}, nil
+	}))
+	d.Register("hang", funcAgent(func(ctx context.Context, task *Task, state *Context) (*Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	// MaxParallel 1 would deadlock a child waiting on its own sub-task, so
+	// depth is checked on a delegator without the cap.
+	nested := NewDelegator()
+	nested.MaxDepth = 2
+	nested.Register("recurse", d.agents["recurse"])
+	ctx := WithDelegator(context.Background(), nested)
+	err := nested.Spawn(ctx, nil, SubTask{Agent: "recurse"}).Wait().Err
+	require.ErrorIs(t, err, ErrSubTaskDepth)
+
+	require.ErrorContains(t, d.Spawn(context.Background(), nil, SubTask{Agent: "missing"}).Wait().Err, "not registered")
+	require.ErrorIs(t, d.Spawn(context.Background(), nil, SubTask{Agent: "hang", Timeout: 10 * time.Millisecond}).Wait().Err, context.DeadlineExceeded)
+
+	_, err = d.Run(context.Background(), nil, SubTask{Agent: "busy"}, SubTask{Agent: "busy"}, SubTask{Agent: "busy"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, peak.Load())
+}
+
+func mustGet(t *testing.T, state *Context, key string) any {
+	t.Helper()
+	value, ok := state.Get(key)
+	require.True(t, ok, key)
+	return value
+}
@@ -0,0 +1,261 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ResultStatusBudgetExceeded marks results and workflows stopped because the
+// task used up one of its TaskBudget limits.
+const ResultStatusBudgetExceeded = "budget_exceeded"
+
+// TaskBudget caps what one task may spend before it is stopped. Zero fields
+// are unlimited. In JSON, MaxDuration is a duration string such as "10m".
+type TaskBudget struct {
+	MaxDuration      time.Duration `json:"max_duration,omitempty" yaml:"max_duration,omitempty"`
+	MaxLLMCalls      int           `json:"max_llm_calls,omitempty" yaml:"max_llm_calls,omitempty"`
+	MaxTokens        int           `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	MaxFilesModified int           `json:"max_files_modified,omitempty" yaml:"max_files_modified,omitempty"`
+}
+
+// IsZero reports whether no limit is configured.
+func (b TaskBudget) IsZero() bool {
+	return b.MaxDuration <= 0 && b.MaxLLMCalls <= 0 && b.MaxTokens <= 0 && b.MaxFilesModified <= 0
+}
+
+// Validate rejects negative limits.
+func (b TaskBudget) Validate() error {
+	if b.MaxDuration < 0 || b.MaxLLMCalls < 0 || b.MaxTokens < 0 || b.MaxFilesModified < 0 {
+		return errors.New("task budget limits must not be negative")
+	}
+	return nil
+}
+
+type taskBudgetJSON struct {
+	MaxDuration      string `json:"max_duration,omitempty"`
+	MaxLLMCalls      int    `json:"max_llm_calls,omitempty"`
+	MaxTokens        int    `json:"max_tokens,omitempty"`
+	MaxFilesModified int    `json:"max_files_modified,omitempty"`
+}
+
+// MarshalJSON writes MaxDuration as a duration string.
+func (b TaskBudget) MarshalJSON() ([]byte, error) {
+	out := taskBudgetJSON{MaxLLMCalls: b.MaxLLMCalls, MaxTokens: b.MaxTokens, MaxFilesModified: b.MaxFilesModified}
+	if b.MaxDuration > 0 {
+		out.MaxDuration = b.MaxDuration.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON parses MaxDuration with time.ParseDuration.
+func (b *TaskBudget) UnmarshalJSON(data []byte) error {
+	var in taskBudgetJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*b = TaskBudget{MaxLLMCalls: in.MaxLLMCalls, MaxTokens: in.MaxTokens, MaxFilesModified: in.MaxFilesModified}
+	if in.MaxDuration != "" {
+		d, err := time.ParseDuration(in.MaxDuration)
+		if err != nil {
+			return fmt.Errorf("invalid max_duration: %w", err)
+		}
+		b.MaxDuration = d
+	}
+	return nil
+}
+
+// BudgetLimit names one TaskBudget limit, using its JSON field name.
+type BudgetLimit string
+
+const (
+	BudgetLimitDuration      BudgetLimit = "max_duration"
+	BudgetLimitLLMCalls      BudgetLimit = "max_llm_calls"
+	BudgetLimitTokens        BudgetLimit = "max_tokens"
+	BudgetLimitFilesModified BudgetLimit = "max_files_modified"
+)
+
+// BudgetExceededError reports the limit that stopped a task. Max and Used
+// are nanoseconds for BudgetLimitDuration and counts otherwise. NodeID is
+// the node that was running and Completed the nodes that finished before.
+type BudgetExceededError struct {
+	Limit     BudgetLimit
+	Max       int64
+	Used      int64
+	NodeID    string
+	Completed []string
+}
+
+func (e *BudgetExceededError) Error() string {
+	msg := fmt.Sprintf("task budget exceeded: %s (%s)", e.Limit, e.Usage())
+	if e.NodeID != "" {
+		msg += " at node " + e.NodeID
+	}
+	return msg
+}
+
+// Usage describes how much of the limit was used, e.g. "used 12 of 10".
+func (e *BudgetExceededError) Usage() string {
+	if e.Limit == BudgetLimitDuration {
+		return fmt.Sprintf("ran %s of %s", time.Duration(e.Used).Round(time.Millisecond), time.Duration(e.Max))
+	}
+	return fmt.Sprintf("used %d of %d", e.Used, e.Max)
+}
+
+// ErrorKind reports ErrorKindBudgetExceeded.
+func (e *BudgetExceededError) ErrorKind() ErrorKind { return ErrorKindBudgetExceeded }
+
+// BudgetTracker counts what a task spends against its TaskBudget. When a
+// limit is exceeded it cancels the task's context with a
+// *BudgetExceededError as the cause, so agents stop at their next context
+// check and graphs report the partial run. A nil tracker ignores charges.
+type BudgetTracker struct {
+	budget TaskBudget
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu     sync.Mutex
+	calls  int
+	tokens int
+	files  map[string]struct{}
+}
+
+type taskBudgetKey struct{}
+
+// WithTaskBudget starts budget's clock and attaches a tracker to ctx for the
+// model and tool wrappers to charge. Call stop once the task is done. A zero
+// budget returns ctx unchanged and a nil tracker.
+func WithTaskBudget(ctx context.Context, budget TaskBudget) (context.Context, *BudgetTracker, context.CancelFunc) {
+	if budget.IsZero() {
+		return ctx, nil, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := func() { cancel(nil) }
+	if budget.MaxDuration > 0 {
+		var stopDeadline context.CancelFunc
+		ctx, stopDeadline = context.WithTimeoutCause(ctx, budget.MaxDuration, &BudgetExceededError{
+			Limit: BudgetLimitDuration,
+			Max:   int64(budget.MaxDuration),
+			Used:  int64(budget.MaxDuration),
+		})
+		stop = func() {
+			stopDeadline()
+			cancel(nil)
+		}
+	}
+	tracker := &BudgetTracker{budget: budget, ctx: ctx, cancel: cancel, files: make(map[string]struct{})}
+	return context.WithValue(ctx, taskBudgetKey{}, tracker), tracker, stop
+}
+
+// TaskBudgetFrom returns the tracker attached by WithTaskBudget, or nil.
+func TaskBudgetFrom(ctx context.Context) *BudgetTracker {
+	if ctx == nil {
+		return nil
+	}
+	tracker, _ := ctx.Value(taskBudgetKey{}).(*BudgetTracker)
+	return tracker
+}
+
+// BudgetExceeded returns the limit that cancelled ctx, or nil when ctx is
+// live or was cancelled for another reason.
+func BudgetExceeded(ctx context.Context) *BudgetExceededError {
+	if ctx == nil || ctx.Err() == nil {
+		return nil
+	}
+	var exceeded *BudgetExceededError
+	if errors.As(context.Cause(ctx), &exceeded) {
+		return exceeded
+	}
+	return nil
+}
+
+// Budget returns the limits being tracked.
+func (b *BudgetTracker) Budget() TaskBudget {
+	if b == nil {
+		return TaskBudget{}
+	}
+	return b.budget
+}
+
+// Exceeded returns the limit that stopped the task, or nil.
+func (b *BudgetTracker) Exceeded() *BudgetExceededError {
+	if b == nil {
+		return nil
+	}
+	return BudgetExceeded(b.ctx)
+}
+
+// ChargeLLM counts one model response.
+func (b *BudgetTracker) ChargeLLM(usage LLMUsage) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls += usage.Calls
+	b.tokens += usage.TotalTokens
+	if b.budget.MaxLLMCalls > 0 && b.calls > b.budget.MaxLLMCalls {
+		b.trip(BudgetLimitLLMCalls, b.budget.MaxLLMCalls, b.calls)
+	}
+	if b.budget.MaxTokens > 0 && b.tokens > b.budget.MaxTokens {
+		b.trip(BudgetLimitTokens, b.budget.MaxTokens, b.tokens)
+	}
+}
+
+// ChargeFiles counts the distinct files a task changed. Changing a file
+// again does not count twice.
+func (b *BudgetTracker) ChargeFiles(paths ...string) {
+	if b == nil || len(paths) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, path := range paths {
+		b.files[path] = struct{}{}
+	}
+	if b.budget.MaxFilesModified > 0 && len(b.files) > b.budget.MaxFilesModified {
+		b.trip(BudgetLimitFilesModified, b.budget.MaxFilesModified, len(b.files))
+	}
+}
+
+// trip cancels the task; only the first exceeded limit is reported.
+func (b *BudgetTracker) trip(limit BudgetLimit, max, used int) {
+	b.cancel(&BudgetExceededError{Limit: limit, Max: int64(max), Used: int64(used)})
+}
+
+// budgetExceeded records the partial run in state (graph.status,
+// graph.budget_limit, graph.stopped_node, graph.completed_nodes) like
+// timedOut, and returns a budget_exceeded result with the last completed
+// node's data.
+func (g *Graph) budgetExceeded(state *Context, lastResult *Result, exceeded *BudgetExceededError, nodeID string, completed []string, taskID string) (*Result, error) {
+	stopped := *exceeded
+	stopped.NodeID = nodeID
+	stopped.Completed = append([]string{}, completed...)
+	g.emit(Event{
+		Type:      EventNodeError,
+		NodeID:    nodeID,
+		TaskID:    taskID,
+		Timestamp: time.Now().UTC(),
+		Message:   stopped.Error(),
+		Metadata:  map[string]interface{}{"status": ResultStatusBudgetExceeded, "budget_limit": string(stopped.Limit)},
+	})
+	data := map[string]interface{}{
+		"status":          ResultStatusBudgetExceeded,
+		"budget_limit":    string(stopped.Limit),
+		"stopped_node":    nodeID,
+		"completed_nodes": stopped.Completed,
+	}
+	if lastResult != nil {
+		data["partial"] = lastResult.Data
+	}
+	if state != nil {
+		state.Set("graph.status", ResultStatusBudgetExceeded)
+		state.Set("graph.budget_limit", string(stopped.Limit))
+		state.Set("graph.stopped_node", nodeID)
+		state.Set("graph.completed_nodes", stopped.Completed)
+	}
+	return &Result{NodeID: nodeID, Success: false, Data: data}, &stopped
+}
@@ -0,0 +1,111 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskBudgetJSONUsesDurationStrings(t *testing.T) {
+	var budget TaskBudget
+	require.NoError(t, json.Unmarshal([]byte(`{"max_duration":"10m","max_llm_calls":40,"max_files_modified":5}`), &budget))
+	assert.Equal(t, TaskBudget{MaxDuration: 10 * time.Minute, MaxLLMCalls: 40, MaxFilesModified: 5}, budget)
+
+	data, err := json.Marshal(budget)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"max_duration":"10m0s","max_llm_calls":40,"max_files_modified":5}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"max_duration":"soon"}`), &budget))
+	assert.Error(t, TaskBudget{MaxTokens: -1}.Validate())
+}
+
+func TestBudgetTrackerReportsFirstExceededLimit(t *testing.T) {
+	ctx, tracker, stop := WithTaskBudget(context.Background(), TaskBudget{MaxLLMCalls: 2, MaxTokens: 1000, MaxFilesModified: 1})
+	defer stop()
+	assert.Same(t, tracker, TaskBudgetFrom(ctx))
+
+	tracker.ChargeLLM(LLMUsage{Calls: 1, TotalTokens: 300})
+	tracker.ChargeLLM(LLMUsage{Calls: 1, TotalTokens: 300})
+	tracker.ChargeFiles("/w/a.go", "/w/a.go")
+	assert.Nil(t, tracker.Exceeded())
+	assert.NoError(t, ctx.Err())
+
+	tracker.ChargeLLM(LLMUsage{Calls: 1, TotalTokens: 600})
+	exceeded := tracker.Exceeded()
+	require.NotNil(t, exceeded)
+	assert.Equal(t, BudgetLimitLLMCalls, exceeded.Limit)
+	assert.Equal(t, "task budget exceeded: max_llm_calls (used 3 of 2)", exceeded.Error())
+	assert.Equal(t, ErrorKindBudgetExceeded, ErrorKindOf(exceeded))
+	assert.Error(t, ctx.Err())
+
+	tracker.ChargeFiles("/w/b.go")
+	assert.Equal(t, BudgetLimitLLMCalls, BudgetExceeded(ctx).Limit, "later limits do not replace the first")
+}
+
+func TestWithTaskBudgetZeroIsUntracked(t *testing.T) {
+	ctx, tracker, stop := WithTaskBudget(context.Background(), TaskBudget{})
+	defer stop()
+	assert.Nil(t, tracker)
+	assert.Nil(t, TaskBudgetFrom(ctx))
+	tracker.ChargeLLM(LLMUsage{Calls: 1})
+	assert.Nil(t, tracker.Exceeded())
+}
+
+func TestGraphStopsWhenBudgetExceeded(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	graph := NewGraph()
+	fast := testNode{id: "fast", run: func(ctx context.Context, state *Context) (*Result, error) {
+		return &Result{Success: true, Data: map[string]interface{}{"files": 3}}, nil
+	}}
+	spend := testNode{id: "spend", run: func(ctx context.Context, state *Context) (*Result, error) {
+		TaskBudgetFrom(ctx).ChargeLLM(LLMUsage{Calls: 1, TotalTokens: 500})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return &Result{Success: true}, nil
+		}
+	}}
+	for _, node := range []Node{fast, spend, testNode{id: "done", kind: NodeTypeTerminal}} {
+		require.NoError(t, graph.AddNode(node))
+	}
+	require.NoError(t, graph.SetStart("fast"))
+	require.NoError(t, graph.AddEdge("fast", "spend", nil, false))
+	require.NoError(t, graph.AddEdge("spend", "done", nil, false))
+	ctx, _, stop := WithTaskBudget(context.Background(), TaskBudget{MaxTokens: 100})
+	defer stop()
+	state := NewContext()
+
+	result, err := graph.Execute(ctx, state)
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr), "got %v", err)
+	assert.Equal(t, BudgetLimitTokens, budgetErr.Limit)
+	assert.Equal(t, "spend", budgetErr.NodeID)
+	assert.Equal(t, []string{"fast"}, budgetErr.Completed)
+	require.NotNil(t, result)
+	assert.Equal(t, ResultStatusBudgetExceeded, result.Data["status"])
+	assert.Equal(t, map[string]interface{}{"files": 3}, result.Data["partial"])
+	assert.Equal(t, ResultStatusBudgetExceeded, state.GetString("graph.status"))
+	assert.Equal(t, string(BudgetLimitTokens), state.GetString("graph.budget_limit"))
+}
+
+func TestGraphStopsAtBudgetDuration(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	graph := buildTimeoutGraph(t, true, release)
+	ctx, _, stop := WithTaskBudget(context.Background(), TaskBudget{MaxDuration: 20 * time.Millisecond})
+	defer stop()
+
+	_, err := graph.Execute(ctx, NewContext())
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr), "got %v", err)
+	assert.Equal(t, BudgetLimitDuration, budgetErr.Limit)
+	assert.Equal(t, "slow", budgetErr.NodeID)
+	assert.False(t, IsTimeout(err), "budget stops are not graph timeouts")
+}
@@ -10,6 +10,10 @@ type TaskContext struct {
 	ID          string
 	Type        TaskType
 	Instruction string
+	// NodeID is the graph node currently executing.
+	NodeID string
+	// Agent labels the agent running the task (state key "task.agent").
+	Agent string
 }
 
 // WithTaskContext attaches task metadata to the context.
@@ -29,4 +33,3 @@ func TaskContextFrom(ctx context.Context) (TaskContext, bool) {
 	task, ok := val.(TaskContext)
 	return task, ok
 }
-
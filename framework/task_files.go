@@ -0,0 +1,39 @@
+package framework
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskFile is a file attached to a task, e.g. with `relurpish task --file`,
+// so the agent starts with its contents instead of reading it with tools.
+// Summarized files carry a summary in Content because the full set did not
+// fit the context budget.
+type TaskFile struct {
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	Summarized bool   `json:"summarized,omitempty"`
+}
+
+// TaskFiles returns the files attached under task.Context["files"].
+func TaskFiles(task *Task) []TaskFile {
+	if task == nil || task.Context == nil {
+		return nil
+	}
+	files, _ := task.Context["files"].([]TaskFile)
+	return files
+}
+
+// RenderTaskFiles formats attached files for a prompt, one fenced block per
+// file.
+func RenderTaskFiles(files []TaskFile) string {
+	var b strings.Builder
+	for _, file := range files {
+		label := file.Path
+		if file.Summarized {
+			label += " (summary; read the file for full contents)"
+		}
+		fmt.Fprintf(&b, "--- %s\n```\n%s\n```\n", label, strings.TrimRight(file.Content, "\n"))
+	}
+	return b.String()
+}
@@ -24,6 +24,7 @@ const (
 	EventToolCall     EventType = "tool_call"
 	EventToolResult   EventType = "tool_result"
 	EventStateChange  EventType = "state_change"
+	EventRetry        EventType = "retry"
 )
 
 // Event captures structured telemetry data.
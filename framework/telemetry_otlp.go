@@ -0,0 +1,523 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLPOptions configures an OTLPTelemetry exporter.
+type OTLPOptions struct {
+	// Endpoint is the collector's base URL (e.g. http://localhost:4318);
+	// spans are POSTed to <Endpoint>/v1/traces unless it already ends in
+	// /v1/traces.
+	Endpoint string
+	Headers  map[string]string
+	// ServiceName is reported as the service.name resource attribute.
+	// Defaults to "relurpify".
+	ServiceName string
+	// FlushInterval bounds how long finished spans wait before export.
+	// Defaults to 5s.
+	FlushInterval time.Duration
+	// BatchSize triggers an export once this many spans are pending.
+	// Defaults to 256.
+	BatchSize int
+	// MaxPending drops the oldest spans when the collector falls behind.
+	// Defaults to 8192.
+	MaxPending int
+	Client     *http.Client
+}
+
+// OTLPTelemetry is a Telemetry sink that turns graph events into trace spans
+// and exports them to an OTLP/HTTP collector using the JSON encoding. Each
+// top-level graph execution of a task is a trace; nodes, tool calls, and LLM
+// requests are spans beneath it, so a multi-node run can be followed in any
+// tracing UI. Nested graphs (delegated agents) hang off the node that was
+// running when they started.
+type OTLPTelemetry struct {
+	url     string
+	headers map[string]string
+	service string
+	client  *http.Client
+	batch   int
+	max     int
+
+	mu      sync.Mutex
+	traces  map[string]*otlpTrace
+	pending []*otlpSpan
+	dropped int
+
+	exportMu sync.Mutex
+	kick     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	closed   bool
+}
+
+type otlpTrace struct {
+	id     string
+	frames []*otlpFrame
+}
+
+// otlpFrame is one running graph. Graphs run their nodes one at a time, so a
+// frame has at most one open node.
+type otlpFrame struct {
+	graph *otlpSpan
+	node  *otlpSpan
+}
+
+type otlpSpan struct {
+	traceID string
+	spanID  string
+	parent  string
+	name    string
+	start   time.Time
+	end     time.Time
+	attrs   map[string]interface{}
+	failed  bool
+	message string
+}
+
+// NewOTLPTelemetry starts an exporter that flushes in the background until
+// Close.
+func NewOTLPTelemetry(opts OTLPOptions) (*OTLPTelemetry, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(opts.Endpoint), "/")
+	if endpoint == "" {
+		return nil, errors.New("otlp endpoint required")
+	}
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("otlp endpoint %q must be an http(s) URL", opts.Endpoint)
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	t := &OTLPTelemetry{
+		url:     endpoint,
+		headers: opts.Headers,
+		service: opts.ServiceName,
+		client:  opts.Client,
+		batch:   opts.BatchSize,
+		max:     opts.MaxPending,
+		traces:  make(map[string]*otlpTrace),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if t.service == "" {
+		t.service = "relurpify"
+	}
+	if t.client == nil {
+		t.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if t.batch <= 0 {
+		t.batch = 256
+	}
+	if t.max <= 0 {
+		t.max = 8192
+	}
+	interval := opts.FlushInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	go t.loop(interval)
+	return t, nil
+}
+
+// Emit implements Telemetry. Events without a task ID cannot be placed in a
+// trace and are ignored.
+func (t *OTLPTelemetry) Emit(event Event) {
+	if t == nil || event.TaskID == "" {
+		return
+	}
+	ts := event.Timestamp
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace := t.traces[event.TaskID]
+	switch event.Type {
+	case EventGraphStart:
+		if trace == nil {
+			trace = &otlpTrace{id: newOTLPID(16)}
+			t.traces[event.TaskID] = trace
+		}
+		span := t.newSpan(trace, trace.parentFor(""), "graph", ts)
+		span.attrs["task.id"] = event.TaskID
+		if taskType, ok := event.Metadata["task_type"]; ok {
+			span.attrs["task.type"] = fmt.Sprint(taskType)
+		}
+		trace.frames = append(trace.frames, &otlpFrame{graph: span})
+	case EventGraphFinish:
+		if trace == nil || len(trace.frames) == 0 {
+			return
+		}
+		frame := trace.frames[len(trace.frames)-1]
+		trace.frames = trace.frames[:len(trace.frames)-1]
+		if frame.node != nil {
+			t.finish(frame.node, ts)
+		}
+		status := fmt.Sprint(event.Metadata["status"])
+		frame.graph.attrs["graph.status"] = status
+		frame.graph.failed = status != "success"
+		t.finish(frame.graph, ts)
+		if len(trace.frames) == 0 {
+			delete(t.traces, event.TaskID)
+		}
+	case EventNodeStart:
+		frame := trace.top()
+		if frame == nil {
+			return
+		}
+		if frame.node != nil {
+			t.finish(frame.node, ts)
+		}
+		frame.node = t.newSpan(trace, frame.graph.spanID, "node "+event.NodeID, ts)
+		frame.node.attrs["node.id"] = event.NodeID
+	case EventNodeFinish, EventNodeError:
+		frame := trace.top()
+		if frame == nil || frame.node == nil || frame.node.attrs["node.id"] != event.NodeID {
+			// e.g. a checkpoint failure reported after the node finished.
+			return
+		}
+		span := frame.node
+		frame.node = nil
+		if event.Type == EventNodeError {
+			span.failed = true
+			span.message = event.Message
+		} else if success, ok := event.Metadata["success"].(bool); ok {
+			span.attrs["success"] = success
+			span.failed = !success
+		}
+		t.finish(span, ts)
+	case EventToolResult:
+		if trace == nil {
+			return
+		}
+		tool := fmt.Sprint(event.Metadata["tool"])
+		span := t.newSpan(trace, trace.parentFor(event.NodeID), "tool "+tool, ts.Add(-eventElapsed(event)))
+		span.attrs["tool.name"] = tool
+		if success, ok := event.Metadata["success"].(bool); ok {
+			span.attrs["success"] = success
+			span.failed = !success
+		}
+		if denied, _ := event.Metadata["denied"].(bool); denied {
+			span.attrs["tool.denied"] = true
+			span.failed = true
+		}
+		if msg, ok := event.Metadata["error"]; ok {
+			span.failed = true
+			span.message = fmt.Sprint(msg)
+		} else if msg, ok := event.Metadata["tool_error"]; ok {
+			span.message = fmt.Sprint(msg)
+		}
+		t.finish(span, ts)
+	case EventLLMResponse:
+		if trace == nil {
+			return
+		}
+		kind := fmt.Sprint(event.Metadata["kind"])
+		span := t.newSpan(trace, trace.parentFor(event.NodeID), "llm "+kind, ts.Add(-eventElapsed(event)))
+		span.attrs["llm.kind"] = kind
+		if reason, ok := event.Metadata["finish_reason"]; ok {
+			span.attrs["llm.finish_reason"] = fmt.Sprint(reason)
+		}
+		if usage, ok := event.Metadata["usage"].(map[string]int); ok {
+			for key, value := range usage {
+				span.attrs["llm.usage."+key] = value
+			}
+			if node := trace.openNode(event.NodeID); node != nil {
+				total, _ := node.attrs["llm.usage.total_tokens"].(int)
+				node.attrs["llm.usage.total_tokens"] = total + usage["total_tokens"]
+			}
+		}
+		if msg, ok := event.Metadata["error"]; ok {
+			span.failed = true
+			span.message = fmt.Sprint(msg)
+		}
+		t.finish(span, ts)
+	}
+}
+
+func (tr *otlpTrace) top() *otlpFrame {
+	if tr == nil || len(tr.frames) == 0 {
+		return nil
+	}
+	return tr.frames[len(tr.frames)-1]
+}
+
+// openNode returns the innermost open node span with nodeID.
+func (tr *otlpTrace) openNode(nodeID string) *otlpSpan {
+	for i := len(tr.frames) - 1; i >= 0; i-- {
+		if node := tr.frames[i].node; node != nil && node.attrs["node.id"] == nodeID {
+			return node
+		}
+	}
+	return nil
+}
+
+// parentFor picks the span that encloses work done by nodeID: that node when
+// it is open, else the innermost open node or graph.
+func (tr *otlpTrace) parentFor(nodeID string) string {
+	if nodeID != "" {
+		if node := tr.openNode(nodeID); node != nil {
+			return node.spanID
+		}
+	}
+	frame := tr.top()
+	switch {
+	case frame == nil:
+		return ""
+	case frame.node != nil:
+		return frame.node.spanID
+	default:
+		return frame.graph.spanID
+	}
+}
+
+func (t *OTLPTelemetry) newSpan(trace *otlpTrace, parent, name string, start time.Time) *otlpSpan {
+	return &otlpSpan{
+		traceID: trace.id,
+		spanID:  newOTLPID(8),
+		parent:  parent,
+		name:    name,
+		start:   start,
+		attrs:   make(map[string]interface{}),
+	}
+}
+
+// finish queues span for export. Callers hold t.mu.
+func (t *OTLPTelemetry) finish(span *otlpSpan, end time.Time) {
+	span.end = end
+	if span.end.Before(span.start) {
+		span.end = span.start
+	}
+	t.pending = append(t.pending, span)
+	if over := len(t.pending) - t.max; over > 0 {
+		t.pending = append([]*otlpSpan(nil), t.pending[over:]...)
+		t.dropped += over
+	}
+	if len(t.pending) >= t.batch {
+		select {
+		case t.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func eventElapsed(event Event) time.Duration {
+	switch v := event.Metadata["duration_ms"].(type) {
+	case int64:
+		return time.Duration(v) * time.Millisecond
+	case int:
+		return time.Duration(v) * time.Millisecond
+	case float64:
+		return time.Duration(v * float64(time.Millisecond))
+	}
+	return 0
+}
+
+func (t *OTLPTelemetry) loop(interval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		case <-t.kick:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval+10*time.Second)
+		// Failed batches are dropped; tracing must never hold up the agent.
+		_ = t.Flush(ctx)
+		cancel()
+	}
+}
+
+// Flush exports every finished span. Spans of graphs still running are sent
+// once they finish.
+func (t *OTLPTelemetry) Flush(ctx context.Context) error {
+	t.exportMu.Lock()
+	defer t.exportMu.Unlock()
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	dropped := t.dropped
+	t.dropped = 0
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		if dropped > 0 {
+			return fmt.Errorf("otlp exporter dropped %d spans", dropped)
+		}
+		return nil
+	}
+	if err := t.export(ctx, spans); err != nil {
+		return fmt.Errorf("otlp export of %d spans: %w", len(spans), err)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("otlp exporter dropped %d spans", dropped)
+	}
+	return nil
+}
+
+// Close stops the background exporter and flushes what is left.
+func (t *OTLPTelemetry) Close(ctx context.Context) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+	close(t.stop)
+	<-t.done
+	return t.Flush(ctx)
+}
+
+func (t *OTLPTelemetry) export(ctx context.Context, spans []*otlpSpan) error {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON payload types; see opentelemetry-proto's trace service.
+type (
+	otlpExportRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope      `json:"scope"`
+		Spans []otlpSpanJSON `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpanJSON struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP span kind and status codes.
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+func (t *OTLPTelemetry) encode(spans []*otlpSpan) otlpExportRequest {
+	out := make([]otlpSpanJSON, len(spans))
+	for i, span := range spans {
+		status := otlpStatus{Code: otlpStatusOK}
+		if span.failed {
+			status = otlpStatus{Code: otlpStatusError, Message: span.message}
+		}
+		out[i] = otlpSpanJSON{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parent,
+			Name:              span.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attrs),
+			Status:            status,
+		}
+	}
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": t.service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/lexcodex/relurpify/framework"}, Spans: out}},
+	}}}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value otlpAnyValue
+		switch v := attrs[key].(type) {
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: key, Value: value})
+	}
+	return out
+}
+
+func newOTLPID(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b) // never fails; see crypto/rand.Read
+	return hex.EncodeToString(b)
+}
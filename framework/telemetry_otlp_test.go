@@ -0,0 +1,105 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPTelemetryExportsGraphAsTrace(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpExportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "Bearer t", r.Header.Get("Authorization"))
+		var req otlpExportRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPTelemetry(OTLPOptions{Endpoint: collector.URL + "/", Headers: map[string]string{"Authorization": "Bearer t"}, FlushInterval: time.Hour})
+	require.NoError(t, err)
+	start := time.Unix(1700000000, 0).UTC()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	for _, event := range []Event{
+		{Type: EventGraphStart, TaskID: "t1", Timestamp: at(0), Metadata: map[string]interface{}{"task_type": "analysis"}},
+		{Type: EventNodeStart, TaskID: "t1", NodeID: "plan", Timestamp: at(1)},
+		{Type: EventLLMResponse, TaskID: "t1", NodeID: "plan", Timestamp: at(50), Metadata: map[string]interface{}{"kind": "chat", "duration_ms": int64(40), "usage": map[string]int{"total_tokens": 12}}},
+		{Type: EventNodeFinish, TaskID: "t1", NodeID: "plan", Timestamp: at(60), Metadata: map[string]interface{}{"success": true}},
+		{Type: EventNodeStart, TaskID: "t1", NodeID: "act", Timestamp: at(61)},
+		// A delegated agent runs its own graph inside "act".
+		{Type: EventGraphStart, TaskID: "t1", Timestamp: at(62)},
+		{Type: EventNodeStart, TaskID: "t1", NodeID: "edit", Timestamp: at(63)},
+		{Type: EventToolResult, TaskID: "t1", NodeID: "edit", Timestamp: at(70), Metadata: map[string]interface{}{"tool": "file_write", "duration_ms": int64(5), "denied": true, "error": "blocked"}},
+		{Type: EventNodeError, TaskID: "t1", NodeID: "edit", Timestamp: at(71), Message: "node edit execution failed"},
+		{Type: EventGraphFinish, TaskID: "t1", Timestamp: at(72), Metadata: map[string]interface{}{"status": "error"}},
+		{Type: EventGraphFinish, TaskID: "t1", Timestamp: at(80), Metadata: map[string]interface{}{"status": "success"}},
+		{Type: EventToolResult, Metadata: map[string]interface{}{"tool": "untracked"}},
+	} {
+		exporter.Emit(event)
+	}
+	require.NoError(t, exporter.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].ResourceSpans, 1)
+	assert.Equal(t, "relurpify", *requests[0].ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	exported := requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, exported, 7, "the event without a task is ignored")
+	spans := make(map[string]otlpSpanJSON)
+	for _, span := range exported {
+		// The outer graph closes last, so "graph" keys the inner one.
+		if _, seen := spans[span.Name]; !seen {
+			spans[span.Name] = span
+		}
+	}
+
+	root := spans["node plan"]
+	traceID := root.TraceID
+	assert.Len(t, traceID, 32)
+	for name, span := range spans {
+		assert.Equal(t, traceID, span.TraceID, name)
+	}
+	llm := spans["llm chat"]
+	assert.Equal(t, spans["node plan"].SpanID, llm.ParentSpanID)
+	assert.Equal(t, "1700000000010000000", llm.StartTimeUnixNano, "start is derived from duration_ms")
+	assert.Equal(t, "12", *attr(t, llm, "llm.usage.total_tokens").IntValue)
+	assert.Equal(t, "12", *attr(t, spans["node plan"], "llm.usage.total_tokens").IntValue)
+
+	edit := spans["node edit"]
+	assert.Equal(t, otlpStatusError, edit.Status.Code)
+	tool := spans["tool file_write"]
+	assert.Equal(t, edit.SpanID, tool.ParentSpanID)
+	assert.True(t, *attr(t, tool, "tool.denied").BoolValue)
+	assert.Equal(t, "blocked", tool.Status.Message)
+	assert.Equal(t, spans["node act"].SpanID, spans["graph"].ParentSpanID, "the inner graph nests under the running node")
+}
+
+func attr(t *testing.T, span otlpSpanJSON, key string) otlpAnyValue {
+	t.Helper()
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	t.Fatalf("span %s has no attribute %s", span.Name, key)
+	return otlpAnyValue{}
+}
+
+func TestNewOTLPTelemetryValidatesEndpoint(t *testing.T) {
+	_, err := NewOTLPTelemetry(OTLPOptions{})
+	assert.Error(t, err)
+	_, err = NewOTLPTelemetry(OTLPOptions{Endpoint: "localhost:4318"})
+	assert.Error(t, err)
+}
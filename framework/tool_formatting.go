@@ -3,7 +3,6 @@ package framework
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"strings"
 )
 
@@ -45,15 +44,12 @@ func ParseToolCallsFromText(text string) []ToolCall {
 	var calls []ToolCall
 	
 	// Attempt to find Markdown JSON blocks
-	jsonBlockRegex := regexp.MustCompile("`json\n(.*?)\n`")
-	matches := jsonBlockRegex.FindAllStringSubmatch(text, -1)
-	
-	for _, match := range matches {
-		if len(match) > 1 {
-			call, ok := tryParseSingleToolCall(match[1])
-			if ok {
-				calls = append(calls, call)
-			}
+	for _, fence := range ExtractCodeFences(text) {
+		if fence.Language != "" && fence.Language != "json" {
+			continue
+		}
+		if call, ok := tryParseSingleToolCall(fence.Content); ok {
+			calls = append(calls, call)
 		}
 	}
 	
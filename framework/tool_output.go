@@ -0,0 +1,195 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultToolOutputMaxBytes bounds a tool result's data when the policy sets
+// no limit. It is about 8k tokens.
+const DefaultToolOutputMaxBytes = 32 << 10
+
+// minToolFieldBytes is the least a truncated field keeps, so a result with
+// many fields still shows the start and end of each.
+const minToolFieldBytes = 512
+
+// ToolOutputPolicy bounds the tool results agents feed back to the model so
+// a broad grep or a large file_read cannot exhaust the context budget. The
+// zero value truncates results to DefaultToolOutputMaxBytes and keeps no
+// copy of what was cut.
+type ToolOutputPolicy struct {
+	// MaxBytes caps the JSON size of a result's data. Negative disables
+	// truncation.
+	MaxBytes int
+	// PerTool overrides MaxBytes by tool name.
+	PerTool map[string]int
+	// SpillDir, when set, receives the full text of every truncated field.
+	// The truncation marker names the file so the agent can read the rest.
+	SpillDir string
+}
+
+// Limit returns the byte limit for tool, or 0 when its output is unbounded.
+func (p ToolOutputPolicy) Limit(tool string) int {
+	limit := p.MaxBytes
+	if n, ok := p.PerTool[tool]; ok {
+		limit = n
+	}
+	switch {
+	case limit < 0:
+		return 0
+	case limit == 0:
+		return DefaultToolOutputMaxBytes
+	}
+	return limit
+}
+
+// Apply shrinks res in place until its data fits the tool's limit. The
+// largest fields go first: each is rendered as text (JSON for non-strings)
+// and cut to its head and tail around a marker saying how much was dropped.
+// Truncated field names are listed in res.Metadata["truncated"].
+func (p ToolOutputPolicy) Apply(tool string, res *ToolResult) *ToolResult {
+	limit := p.Limit(tool)
+	if res == nil || limit == 0 || len(res.Data) == 0 {
+		return res
+	}
+	sizes := make(map[string]int, len(res.Data))
+	total := 0
+	for key, value := range res.Data {
+		sizes[key] = jsonSize(value)
+		total += sizes[key]
+	}
+	if total <= limit {
+		return res
+	}
+	keys := make([]string, 0, len(sizes))
+	for key := range sizes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	var truncated []string
+	for _, key := range keys {
+		if total <= limit {
+			break
+		}
+		keep := limit - (total - sizes[key])
+		if keep < minToolFieldBytes {
+			keep = minToolFieldBytes
+		}
+		text := toolFieldText(res.Data[key])
+		if len(text) <= keep {
+			continue
+		}
+		spill := p.spill(tool, key, text)
+		res.Data[key] = TruncateMiddle(text, keep, spill)
+		total += jsonSize(res.Data[key]) - sizes[key]
+		truncated = append(truncated, key)
+	}
+	if len(truncated) > 0 {
+		if res.Metadata == nil {
+			res.Metadata = make(map[string]interface{})
+		}
+		res.Metadata["truncated"] = truncated
+	}
+	return res
+}
+
+// spill writes text under SpillDir and returns the file path, or "" when
+// spilling is off or fails.
+func (p ToolOutputPolicy) spill(tool, key, text string) string {
+	if p.SpillDir == "" {
+		return ""
+	}
+	if err := os.MkdirAll(p.SpillDir, 0o755); err != nil {
+		return ""
+	}
+	name := fmt.Sprintf("%s-%s-%d.txt", sanitizeSpillName(tool), sanitizeSpillName(key), time.Now().UnixNano())
+	path := filepath.Join(p.SpillDir, name)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return ""
+	}
+	return path
+}
+
+// TruncateMiddle cuts s to about max bytes, keeping two thirds from the
+// start and one third from the end. Cuts snap to line breaks when one is
+// close, and never split a UTF-8 sequence. When spill is set the marker
+// points to the full text.
+func TruncateMiddle(s string, max int, spill string) string {
+	if len(s) <= max {
+		return s
+	}
+	marker := func(dropped int) string {
+		if spill != "" {
+			return fmt.Sprintf("\n... [truncated %d of %d bytes; full output in %s] ...\n", dropped, len(s), spill)
+		}
+		return fmt.Sprintf("\n... [truncated %d of %d bytes] ...\n", dropped, len(s))
+	}
+	// Sizing with len(s) as the dropped count overestimates the marker.
+	budget := max - len(marker(len(s)))
+	if budget < 0 {
+		budget = 0
+	}
+	head := budget * 2 / 3
+	tail := budget - head
+	headEnd := head
+	if i := strings.LastIndexByte(s[:headEnd], '\n'); i >= head/2 {
+		headEnd = i + 1
+	}
+	for headEnd > 0 && !utf8.RuneStart(s[headEnd]) {
+		headEnd--
+	}
+	tailStart := len(s) - tail
+	if i := strings.IndexByte(s[tailStart:], '\n'); i >= 0 && i < tail/2 {
+		tailStart += i + 1
+	}
+	for tailStart < len(s) && !utf8.RuneStart(s[tailStart]) {
+		tailStart++
+	}
+	mark := marker(tailStart - headEnd)
+	if headEnd > 0 && s[headEnd-1] == '\n' {
+		mark = mark[1:]
+	}
+	return s[:headEnd] + mark + s[tailStart:]
+}
+
+func toolFieldText(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.MarshalIndent(value, "", " ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func jsonSize(value interface{}) int {
+	if s, ok := value.(string); ok {
+		return len(s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return len(fmt.Sprint(value))
+	}
+	return len(data)
+}
+
+func sanitizeSpillName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
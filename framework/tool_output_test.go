@@ -0,0 +1,58 @@
+package framework
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateMiddleKeepsHeadAndTail(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		b.WriteString(strings.Repeat("x", 20) + "\n")
+	}
+	s := "HEAD\n" + b.String() + "TAIL\n"
+	out := TruncateMiddle(s, 600, "/tmp/spill.txt")
+	assert.LessOrEqual(t, len(out), 600)
+	assert.True(t, strings.HasPrefix(out, "HEAD\n"))
+	assert.True(t, strings.HasSuffix(out, "TAIL\n"))
+	assert.Contains(t, out, "; full output in /tmp/spill.txt] ...\n")
+	head, _, ok := strings.Cut(out, "... [truncated")
+	require.True(t, ok)
+	assert.True(t, strings.HasSuffix(head, "x\n"), "cuts snap to line breaks")
+
+	assert.Equal(t, "short", TruncateMiddle("short", 600, ""))
+	utf := TruncateMiddle(strings.Repeat("é", 500), 200, "")
+	assert.True(t, strings.HasPrefix(utf, "é") && strings.HasSuffix(utf, "é"))
+	assert.NotContains(t, utf, "�")
+}
+
+func TestToolOutputPolicyApply(t *testing.T) {
+	dir := t.TempDir()
+	policy := ToolOutputPolicy{MaxBytes: 1024, PerTool: map[string]int{"file_read": -1}, SpillDir: dir}
+	matches := make([]map[string]interface{}, 100)
+	for i := range matches {
+		matches[i] = map[string]interface{}{"file": "main.go", "line": i, "content": "func handler() error {"}
+	}
+	res := policy.Apply("search_grep", &ToolResult{Success: true, Data: map[string]interface{}{"matches": matches, "pattern": "handler"}})
+	assert.Equal(t, "handler", res.Data["pattern"], "small fields are kept")
+	text, ok := res.Data["matches"].(string)
+	require.True(t, ok, "oversized structured data is rendered as text")
+	assert.LessOrEqual(t, len(text)+len("handler"), 1024)
+	assert.Equal(t, []string{"matches"}, res.Metadata["truncated"])
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, text, entries[0].Name())
+
+	big := strings.Repeat("a", 4096)
+	res = policy.Apply("file_read", &ToolResult{Data: map[string]interface{}{"content": big}})
+	assert.Equal(t, big, res.Data["content"], "a negative per-tool limit disables truncation")
+
+	res = ToolOutputPolicy{}.Apply("exec", &ToolResult{Data: map[string]interface{}{"stdout": strings.Repeat("a", DefaultToolOutputMaxBytes+1)}})
+	assert.LessOrEqual(t, len(res.Data["stdout"].(string)), DefaultToolOutputMaxBytes)
+	assert.NotContains(t, res.Data["stdout"], "full output in", "no spill directory, no reference")
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -43,6 +45,52 @@ type AgentSpecAware interface {
 	SetAgentSpec(spec *AgentRuntimeSpec, agentID string)
 }
 
+// LazyTool is implemented by tools whose backing service (a language server,
+// the AST index) starts on first call rather than at registration.
+type LazyTool interface {
+	Activated() bool
+}
+
+// PendingActivation reports whether tool will start its backing service on
+// its next call, so prompts can warn that the first call may be slow.
+func PendingActivation(tool Tool) bool {
+	if instrumented, ok := tool.(*instrumentedTool); ok {
+		tool = instrumented.Tool
+	}
+	lazy, ok := tool.(LazyTool)
+	return ok && !lazy.Activated()
+}
+
+// IdempotentTool is implemented by tools that declare whether running them
+// again after a failed call is safe.
+type IdempotentTool interface {
+	Idempotent() bool
+}
+
+// ToolIdempotent reports whether tool may be retried after a transient
+// failure: it says so through IdempotentTool, or its permissions only read
+// and list files. Anything that writes, executes, or reaches the network may
+// have taken effect before failing, so it runs once.
+func ToolIdempotent(tool Tool) bool {
+	if instrumented, ok := tool.(*instrumentedTool); ok {
+		tool = instrumented.Tool
+	}
+	if idempotent, ok := tool.(IdempotentTool); ok {
+		return idempotent.Idempotent()
+	}
+	perms := tool.Permissions().Permissions
+	if perms == nil || len(perms.FileSystem) == 0 || len(perms.Executables) > 0 || len(perms.Network) > 0 ||
+		len(perms.Capabilities) > 0 || len(perms.IPC) > 0 {
+		return false
+	}
+	for _, perm := range perms.FileSystem {
+		if perm.Action != FileSystemRead && perm.Action != FileSystemList {
+			return false
+		}
+	}
+	return true
+}
+
 // ToolResult is returned by every tool execution.
 type ToolResult struct {
 	Success  bool
@@ -62,6 +110,10 @@ type ToolRegistry struct {
 	agentSpec         *AgentRuntimeSpec
 	toolPolicies      map[string]ToolPolicy
 	telemetry         Telemetry
+	autonomy          *AutonomyController
+	redactor          *Redactor
+	tap               ToolTap
+	hooks             []ToolHook
 }
 
 // NewToolRegistry builds a registry instance.
@@ -152,10 +204,19 @@ func (r *ToolRegistry) UseAgentSpec(agentID string, spec *AgentRuntimeSpec) {
 	r.registeredAgentID = agentID
 	r.agentSpec = spec
 	if spec.ToolPolicies != nil {
+		forced := r.toolPolicies
 		r.toolPolicies = make(map[string]ToolPolicy, len(spec.ToolPolicies))
 		for name, pol := range spec.ToolPolicies {
 			r.toolPolicies[name] = pol
 		}
+		// Approvals forced by RequireApprovalFor survive a later spec.
+		for name, pol := range forced {
+			if pol.Execute == AgentPermissionAsk && r.toolPolicies[name].Execute != AgentPermissionDeny {
+				current := r.toolPolicies[name]
+				current.Execute = AgentPermissionAsk
+				r.toolPolicies[name] = current
+			}
+		}
 	}
 	// Apply visibility policies by removing hidden tools.
 	for name, pol := range r.toolPolicies {
@@ -177,6 +238,88 @@ func (r *ToolRegistry) UseAgentSpec(agentID string, spec *AgentRuntimeSpec) {
 	}
 }
 
+// RequireApprovalFor raises the execution policy of every registered tool
+// matching match to ask, leaving denied tools denied, and returns the names
+// it changed. Degraded sandbox mode uses it to gate exec tools behind HITL.
+func (r *ToolRegistry) RequireApprovalFor(match func(Tool) bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changed []string
+	for name, tool := range r.tools {
+		inner := tool
+		if instrumented, ok := tool.(*instrumentedTool); ok {
+			inner = instrumented.Tool
+		}
+		if !match(inner) {
+			continue
+		}
+		policy := r.toolPolicies[name]
+		if policy.Execute == AgentPermissionDeny || policy.Execute == AgentPermissionAsk {
+			continue
+		}
+		policy.Execute = AgentPermissionAsk
+		r.toolPolicies[name] = policy
+		r.tools[name] = r.wrapTool(inner)
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// IsExecTool reports whether tool runs external commands: the execution
+// category and the cli_* command-line wrappers.
+func IsExecTool(tool Tool) bool {
+	category := tool.Category()
+	return category == "execution" || strings.HasPrefix(category, "cli")
+}
+
+// RunsCommands reports whether tool may start processes on the host: exec
+// tools, tools whose permissions declare executables (git, go, psql,
+// plugins), and tools holding a CommandRunner. Degraded sandboxes and remote
+// workspaces gate these behind HITL since nothing contains their commands.
+func RunsCommands(tool Tool) bool {
+	if instrumented, ok := tool.(*instrumentedTool); ok {
+		tool = instrumented.Tool
+	}
+	if IsExecTool(tool) {
+		return true
+	}
+	if perms := tool.Permissions().Permissions; perms != nil && len(perms.Executables) > 0 {
+		return true
+	}
+	return holdsCommandRunner(reflect.ValueOf(tool))
+}
+
+var commandRunnerType = reflect.TypeOf((*CommandRunner)(nil)).Elem()
+
+// holdsCommandRunner looks for a non-nil CommandRunner field in v, following
+// pointers and embedded structs.
+func holdsCommandRunner(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case v.Type().Field(i).Type == commandRunnerType:
+			if !field.IsNil() {
+				return true
+			}
+		case v.Type().Field(i).Anonymous:
+			if holdsCommandRunner(field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // UseTelemetry wires a telemetry sink for all tool executions.
 func (r *ToolRegistry) UseTelemetry(telemetry Telemetry) {
 	r.mu.Lock()
@@ -192,6 +335,63 @@ func (r *ToolRegistry) UseTelemetry(telemetry Telemetry) {
 	}
 }
 
+// UseAutonomy applies a session autonomy controller to all tool executions.
+func (r *ToolRegistry) UseAutonomy(controller *AutonomyController) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autonomy = controller
+	for name, tool := range r.tools {
+		var inner Tool = tool
+		if instrumented, ok := tool.(*instrumentedTool); ok {
+			inner = instrumented.Tool
+		}
+		r.tools[name] = r.wrapTool(inner)
+	}
+}
+
+// UseRedactor masks secrets in the tool calls and results the registry
+// writes out: telemetry events and tool tap recordings. Results reach the
+// agent unchanged, so a file it reads and writes back keeps its real
+// values; RedactingModel keeps them out of prompts.
+func (r *ToolRegistry) UseRedactor(redactor *Redactor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactor = redactor
+	for name, tool := range r.tools {
+		r.tools[name] = r.wrapTool(tool)
+	}
+}
+
+// UseToolTap records every tool execution with tap, or replays recorded
+// results in place of running the tools; see ReplayRecorder and Replayer.
+func (r *ToolRegistry) UseToolTap(tap ToolTap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tap = tap
+	for name, tool := range r.tools {
+		r.tools[name] = r.wrapTool(tool)
+	}
+}
+
+// ToolHook runs around every tool execution that passes the permission and
+// policy checks; see UseToolHook.
+type ToolHook interface {
+	// BeforeTool runs just before the tool.
+	BeforeTool(ctx context.Context, state *Context, tool Tool, args map[string]interface{})
+	// AfterTool sees the outcome and may add to result.Data.
+	AfterTool(ctx context.Context, state *Context, tool Tool, args map[string]interface{}, result *ToolResult, err error)
+}
+
+// UseToolHook adds hook to every tool, e.g. to check files after edits.
+func (r *ToolRegistry) UseToolHook(hook ToolHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+	for name, tool := range r.tools {
+		r.tools[name] = r.wrapTool(tool)
+	}
+}
+
 // RestrictTo removes tools not present in the allowed set.
 func (r *ToolRegistry) RestrictTo(allowed []string) {
 	if len(allowed) == 0 {
@@ -225,7 +425,11 @@ func (r *ToolRegistry) wrapTool(tool Tool) Tool {
 		existing.agentID = r.registeredAgentID
 		existing.telemetry = r.telemetry
 		existing.policy = r.toolPolicies[existing.Tool.Name()]
-		existing.hasPolicy = r.agentSpec != nil
+		existing.hasPolicy = r.hasPolicy(existing.Tool.Name())
+		existing.autonomy = r.autonomy
+		existing.redactor = r.redactor
+		existing.tap = r.tap
+		existing.hooks = r.hooks
 		return existing
 	}
 	return &instrumentedTool{
@@ -234,10 +438,24 @@ func (r *ToolRegistry) wrapTool(tool Tool) Tool {
 		agentID:   r.registeredAgentID,
 		telemetry: r.telemetry,
 		policy:    r.toolPolicies[tool.Name()],
-		hasPolicy: r.agentSpec != nil,
+		hasPolicy: r.hasPolicy(tool.Name()),
+		autonomy:  r.autonomy,
+		redactor:  r.redactor,
+		tap:       r.tap,
+		hooks:     r.hooks,
 	}
 }
 
+// hasPolicy reports whether policies apply to name: always once a spec is
+// attached, otherwise only for explicitly set policies.
+func (r *ToolRegistry) hasPolicy(name string) bool {
+	if r.agentSpec != nil {
+		return true
+	}
+	_, ok := r.toolPolicies[name]
+	return ok
+}
+
 type instrumentedTool struct {
 	Tool
 	manager   *PermissionManager
@@ -245,15 +463,58 @@ type instrumentedTool struct {
 	telemetry Telemetry
 	policy    ToolPolicy
 	hasPolicy bool
+	autonomy  *AutonomyController
+	redactor  *Redactor
+	tap       ToolTap
+	hooks     []ToolHook
 }
 
 // Execute authorizes the wrapped tool before delegating to the original
 // implementation to ensure permission checks happen even for direct callers.
+// A tool tap sees every outcome, denials included, and may serve a recorded
+// result instead.
 func (t *instrumentedTool) Execute(ctx context.Context, state *Context, args map[string]interface{}) (*ToolResult, error) {
+	if t.tap == nil {
+		return t.execute(ctx, state, args)
+	}
+	tapArgs := args
+	if t.redactor != nil {
+		tapArgs = t.redactor.RedactMap(args)
+	}
+	if result, err, ok := t.tap.ReplayTool(ctx, t.Tool.Name(), tapArgs); ok {
+		return result, err
+	}
+	result, err := t.execute(ctx, state, args)
+	t.tap.RecordTool(ctx, t.Tool.Name(), tapArgs, t.redactResult(result), err)
+	return result, err
+}
+
+// redactResult returns a masked copy of result for recording, or result
+// itself without a redactor.
+func (t *instrumentedTool) redactResult(result *ToolResult) *ToolResult {
+	if t.redactor == nil || result == nil {
+		return result
+	}
+	return &ToolResult{
+		Success:  result.Success,
+		Data:     t.redactor.RedactMap(result.Data),
+		Error:    t.redactor.Redact(result.Error),
+		Metadata: t.redactor.RedactMap(result.Metadata),
+	}
+}
+
+func (t *instrumentedTool) execute(ctx context.Context, state *Context, args map[string]interface{}) (*ToolResult, error) {
+	if exceeded := BudgetExceeded(ctx); exceeded != nil {
+		// Tools that ignore ctx must not keep changing the workspace after
+		// the task was stopped.
+		return nil, exceeded
+	}
 	if t.hasPolicy {
 		switch t.policy.Execute {
 		case AgentPermissionDeny:
-			return nil, fmt.Errorf("tool %s blocked: execution denied by policy", t.Tool.Name())
+			err := fmt.Errorf("tool %s blocked: execution denied by policy", t.Tool.Name())
+			t.emitDenied(ctx, err)
+			return nil, err
 		case AgentPermissionAsk:
 			if t.manager == nil {
 				return nil, fmt.Errorf("tool %s blocked: approval required but permission manager missing", t.Tool.Name())
@@ -262,8 +523,10 @@ func (t *instrumentedTool) Execute(ctx context.Context, state *Context, args map
 				Type:         PermissionTypeHITL,
 				Action:       fmt.Sprintf("tool_exec:%s", t.Tool.Name()),
 				Resource:     t.agentID,
+				Metadata:     t.approvalMetadata(ctx, args),
 				RequiresHITL: true,
 			}, "tool execution approval", GrantScopeOneTime, RiskLevelMedium, 0); err != nil {
+				t.emitDenied(ctx, err)
 				return nil, err
 			}
 		}
@@ -272,46 +535,65 @@ func (t *instrumentedTool) Execute(ctx context.Context, state *Context, args map
 		if err := t.manager.AuthorizeTool(ctx, t.agentID, t.Tool, args); err != nil {
 			var denied *PermissionDeniedError
 			if errors.As(err, &denied) {
-				return nil, fmt.Errorf("tool %s blocked: %w", t.Tool.Name(), err)
+				err = fmt.Errorf("tool %s blocked: %w", t.Tool.Name(), err)
+				t.emitDenied(ctx, err)
 			}
 			return nil, err
 		}
 	}
+	if dryRun, err := t.gateAutonomy(ctx, args); err != nil || dryRun != nil {
+		return dryRun, err
+	}
+	task, _ := TaskContextFrom(ctx)
 	if t.telemetry != nil {
 		t.telemetry.Emit(Event{
 			Type:      EventToolCall,
+			TaskID:    task.ID,
+			NodeID:    task.NodeID,
 			Timestamp: time.Now().UTC(),
 			Message:   fmt.Sprintf("tool %s invoked", t.Tool.Name()),
 			Metadata: map[string]interface{}{
 				"tool":     t.Tool.Name(),
 				"agent_id": t.agentID,
-				"args":     summarizeArgs(args),
+				"args":     summarizeArgs(t.redactor.RedactMap(args)),
 			},
 		})
 	}
+	for _, hook := range t.hooks {
+		hook.BeforeTool(ctx, state, t.Tool, args)
+	}
+	start := time.Now()
 	result, err := t.Tool.Execute(ctx, state, args)
-	if err != nil {
-		var denied *PermissionDeniedError
-		if errors.As(err, &denied) {
-			err = fmt.Errorf("tool %s blocked: %w", t.Tool.Name(), err)
-		}
+	elapsed := time.Since(start)
+	for _, hook := range t.hooks {
+		hook.AfterTool(ctx, state, t.Tool, args, result, err)
+	}
+	var denied *PermissionDeniedError
+	if errors.As(err, &denied) {
+		err = fmt.Errorf("tool %s blocked: %w", t.Tool.Name(), err)
 	}
 	if t.telemetry != nil {
 		metadata := map[string]interface{}{
-			"tool":     t.Tool.Name(),
-			"agent_id": t.agentID,
+			"tool":        t.Tool.Name(),
+			"agent_id":    t.agentID,
+			"duration_ms": elapsed.Milliseconds(),
+		}
+		if denied != nil {
+			metadata["denied"] = true
 		}
 		if result != nil {
 			metadata["success"] = result.Success
 			if result.Error != "" {
-				metadata["tool_error"] = result.Error
+				metadata["tool_error"] = t.redactor.Redact(result.Error)
 			}
 		}
 		if err != nil {
-			metadata["error"] = err.Error()
+			metadata["error"] = t.redactor.Redact(err.Error())
 		}
 		t.telemetry.Emit(Event{
 			Type:      EventToolResult,
+			TaskID:    task.ID,
+			NodeID:    task.NodeID,
 			Timestamp: time.Now().UTC(),
 			Message:   fmt.Sprintf("tool %s completed", t.Tool.Name()),
 			Metadata:  metadata,
@@ -320,6 +602,28 @@ func (t *instrumentedTool) Execute(ctx context.Context, state *Context, args map
 	return result, err
 }
 
+// emitDenied reports a call blocked before the tool ran as a tool_result
+// event with "denied" set, so every denial shows up in telemetry.
+func (t *instrumentedTool) emitDenied(ctx context.Context, err error) {
+	if t.telemetry == nil {
+		return
+	}
+	task, _ := TaskContextFrom(ctx)
+	t.telemetry.Emit(Event{
+		Type:      EventToolResult,
+		TaskID:    task.ID,
+		NodeID:    task.NodeID,
+		Timestamp: time.Now().UTC(),
+		Message:   fmt.Sprintf("tool %s denied", t.Tool.Name()),
+		Metadata: map[string]interface{}{
+			"tool":     t.Tool.Name(),
+			"agent_id": t.agentID,
+			"denied":   true,
+			"error":    err.Error(),
+		},
+	})
+}
+
 func summarizeArgs(args map[string]interface{}) interface{} {
 	if len(args) == 0 {
 		return nil
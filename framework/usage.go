@@ -0,0 +1,249 @@
+package framework
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LLMUsage counts tokens and estimated cost for one or more LLM calls.
+type LLMUsage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *LLMUsage) Add(other LLMUsage) {
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
+
+// LLMUsageFromMap converts the provider usage map carried on LLMResponse.
+// Missing totals are derived from prompt + completion counts.
+func LLMUsageFromMap(usage map[string]int) LLMUsage {
+	u := LLMUsage{
+		Calls:            1,
+		PromptTokens:     usage["prompt_tokens"],
+		CompletionTokens: usage["completion_tokens"],
+		TotalTokens:      usage["total_tokens"],
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return u
+}
+
+// ModelPrice is the cost per 1000 tokens for a model. Local Ollama models
+// default to zero; set prices to compare against hosted alternatives.
+type ModelPrice struct {
+	PromptPer1K     float64 `json:"prompt_per_1k" yaml:"prompt_per_1k"`
+	CompletionPer1K float64 `json:"completion_per_1k" yaml:"completion_per_1k"`
+}
+
+// UsageRecord is the usage of a single LLM call.
+type UsageRecord struct {
+	LLMUsage
+	Timestamp time.Time `json:"timestamp"`
+	TaskID    string    `json:"task_id,omitempty"`
+	NodeID    string    `json:"node_id,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Model     string    `json:"model,omitempty"`
+}
+
+// UsageFilter narrows Records and Summary. Empty fields match everything.
+type UsageFilter struct {
+	TaskID string
+	Agent  string
+	Since  time.Time
+}
+
+func (f UsageFilter) matches(record UsageRecord) bool {
+	if f.TaskID != "" && record.TaskID != f.TaskID {
+		return false
+	}
+	if f.Agent != "" && record.Agent != f.Agent {
+		return false
+	}
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// UsageSummary aggregates records by task, node, agent, and model.
+type UsageSummary struct {
+	Total   LLMUsage            `json:"total"`
+	ByTask  map[string]LLMUsage `json:"by_task,omitempty"`
+	ByNode  map[string]LLMUsage `json:"by_node,omitempty"`
+	ByAgent map[string]LLMUsage `json:"by_agent,omitempty"`
+	ByModel map[string]LLMUsage `json:"by_model,omitempty"`
+}
+
+// SummarizeUsage aggregates records.
+func SummarizeUsage(records []UsageRecord) UsageSummary {
+	summary := UsageSummary{
+		ByTask:  make(map[string]LLMUsage),
+		ByNode:  make(map[string]LLMUsage),
+		ByAgent: make(map[string]LLMUsage),
+		ByModel: make(map[string]LLMUsage),
+	}
+	add := func(bucket map[string]LLMUsage, key string, usage LLMUsage) {
+		if key == "" {
+			return
+		}
+		current := bucket[key]
+		current.Add(usage)
+		bucket[key] = current
+	}
+	for _, record := range records {
+		summary.Total.Add(record.LLMUsage)
+		add(summary.ByTask, record.TaskID, record.LLMUsage)
+		add(summary.ByNode, record.NodeID, record.LLMUsage)
+		add(summary.ByAgent, record.Agent, record.LLMUsage)
+		add(summary.ByModel, record.Model, record.LLMUsage)
+	}
+	return summary
+}
+
+// UsageTracker records token usage for every LLM call it is told about. It
+// keeps the most recent records (default 10000) for per-task breakdowns plus
+// a lifetime total that survives trimming.
+type UsageTracker struct {
+	mu       sync.RWMutex
+	records  []UsageRecord
+	limit    int
+	lifetime LLMUsage
+	prices   map[string]ModelPrice
+	spill    func([]UsageRecord)
+}
+
+// NewUsageTracker builds a tracker that prices calls with prices, keyed by
+// model name.
+func NewUsageTracker(prices map[string]ModelPrice) *UsageTracker {
+	copied := make(map[string]ModelPrice, len(prices))
+	for model, price := range prices {
+		copied[model] = price
+	}
+	return &UsageTracker{limit: 10000, prices: copied}
+}
+
+// Record stores the usage of one call. Task, node, and agent labels come from
+// the TaskContext attached to ctx by the graph.
+func (t *UsageTracker) Record(ctx context.Context, model string, usage map[string]int) UsageRecord {
+	record := UsageRecord{
+		LLMUsage:  LLMUsageFromMap(usage),
+		Timestamp: time.Now().UTC(),
+		Model:     model,
+	}
+	if task, ok := TaskContextFrom(ctx); ok {
+		record.TaskID = task.ID
+		record.NodeID = task.NodeID
+		record.Agent = task.Agent
+	}
+	if t == nil {
+		return record
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if price, ok := t.prices[model]; ok {
+		record.Cost = float64(record.PromptTokens)/1000*price.PromptPer1K +
+			float64(record.CompletionTokens)/1000*price.CompletionPer1K
+	}
+	t.lifetime.Add(record.LLMUsage)
+	if len(t.records) >= t.limit {
+		t.dropLocked(len(t.records) - t.limit + 1)
+	}
+	t.records = append(t.records, record)
+	return record
+}
+
+// SetLimit changes how many records are retained. spill, when non-nil,
+// receives records as they are trimmed so they can be archived; it runs
+// under the tracker lock and must not call back into the tracker.
+func (t *UsageTracker) SetLimit(limit int, spill func([]UsageRecord)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit > 0 {
+		t.limit = limit
+	}
+	t.spill = spill
+	if len(t.records) > t.limit {
+		t.dropLocked(len(t.records) - t.limit)
+	}
+}
+
+// Len reports how many records are retained.
+func (t *UsageTracker) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.records)
+}
+
+// Trim drops all but the newest keep records and returns how many were
+// dropped. Lifetime totals are unaffected.
+func (t *UsageTracker) Trim(keep int) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if keep < 0 {
+		keep = 0
+	}
+	if len(t.records) <= keep {
+		return 0
+	}
+	n := len(t.records) - keep
+	t.dropLocked(n)
+	// Copy so the dropped records' backing array can be collected now.
+	t.records = append([]UsageRecord(nil), t.records...)
+	return n
+}
+
+// dropLocked removes the oldest n records, spilling them first.
+func (t *UsageTracker) dropLocked(n int) {
+	if t.spill != nil {
+		t.spill(append([]UsageRecord(nil), t.records[:n]...))
+	}
+	t.records = t.records[n:]
+}
+
+// Records returns retained records matching filter, oldest first.
+func (t *UsageTracker) Records(filter UsageFilter) []UsageRecord {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var out []UsageRecord
+	for _, record := range t.records {
+		if filter.matches(record) {
+			out = append(out, record)
+		}
+	}
+	return out
+}
+
+// Summary aggregates retained records matching filter. An empty filter
+// reports the lifetime total even if older records were trimmed.
+func (t *UsageTracker) Summary(filter UsageFilter) UsageSummary {
+	summary := SummarizeUsage(t.Records(filter))
+	if t != nil && filter == (UsageFilter{}) {
+		t.mu.RLock()
+		summary.Total = t.lifetime
+		t.mu.RUnlock()
+	}
+	return summary
+}
@@ -0,0 +1,50 @@
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageTrackerAggregatesByTaskNodeAndAgent(t *testing.T) {
+	tracker := NewUsageTracker(map[string]ModelPrice{"big": {PromptPer1K: 1, CompletionPer1K: 2}})
+	ctxA := WithTaskContext(context.Background(), TaskContext{ID: "t1", NodeID: "plan", Agent: "planner"})
+	ctxB := WithTaskContext(context.Background(), TaskContext{ID: "t1", NodeID: "act", Agent: "planner"})
+	ctxC := WithTaskContext(context.Background(), TaskContext{ID: "t2", NodeID: "act", Agent: "coding"})
+
+	tracker.Record(ctxA, "big", map[string]int{"prompt_tokens": 1000, "completion_tokens": 500})
+	tracker.Record(ctxB, "small", map[string]int{"prompt_tokens": 10, "completion_tokens": 5})
+	tracker.Record(ctxC, "small", map[string]int{"prompt_tokens": 20, "completion_tokens": 10})
+
+	all := tracker.Summary(UsageFilter{})
+	assert.Equal(t, 3, all.Total.Calls)
+	assert.Equal(t, 1545, all.Total.TotalTokens)
+	assert.InDelta(t, 2.0, all.Total.Cost, 1e-9)
+	assert.Equal(t, 1515, all.ByTask["t1"].TotalTokens)
+	assert.Equal(t, 45, all.ByNode["act"].TotalTokens)
+	assert.Equal(t, 30, all.ByAgent["coding"].TotalTokens)
+	assert.Equal(t, 2, all.ByModel["small"].Calls)
+
+	t1 := tracker.Summary(UsageFilter{TaskID: "t1"})
+	assert.Equal(t, 2, t1.Total.Calls)
+	assert.Len(t, tracker.Records(UsageFilter{Agent: "coding"}), 1)
+}
+
+func TestUsageTrackerLimitSpillsAndTrims(t *testing.T) {
+	tracker := NewUsageTracker(nil)
+	var spilled []UsageRecord
+	tracker.SetLimit(3, func(dropped []UsageRecord) {
+		spilled = append(spilled, dropped...)
+	})
+	for i := 0; i < 5; i++ {
+		tracker.Record(context.Background(), "m", map[string]int{"prompt_tokens": 10})
+	}
+	assert.Equal(t, 3, tracker.Len())
+	assert.Len(t, spilled, 2)
+
+	assert.Equal(t, 2, tracker.Trim(1))
+	assert.Equal(t, 1, tracker.Len())
+	assert.Len(t, spilled, 4)
+	assert.Equal(t, 5, tracker.Summary(UsageFilter{}).Total.Calls)
+}
@@ -0,0 +1,6 @@
+package framework
+
+// Version is the semantic version of the public API (framework, llm, tools,
+// and agents). Within a major version, exported identifiers in those packages
+// are only added, never removed or changed incompatibly.
+const Version = "0.8.0"
@@ -0,0 +1,284 @@
+// Package watch reports file changes under a workspace. Events are collected
+// from fsnotify, coalesced per path, and delivered in debounced batches so a
+// save that touches several files (or an editor writing a file twice) causes
+// one round of cache invalidation instead of many.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op describes what happened to a path.
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpWrite  Op = "write"
+	OpRemove Op = "remove"
+	OpRename Op = "rename"
+)
+
+// Removed reports whether the path no longer exists under its old name.
+func (op Op) Removed() bool { return op == OpRemove || op == OpRename }
+
+// Change is one changed path within a batch. Several events on the same path
+// collapse into the most recent one.
+type Change struct {
+	Path string `json:"path"`
+	Op   Op     `json:"op"`
+}
+
+// Invalidator drops cached state derived from changed files. LSP proxies, the
+// AST index, and search engines implement it through small adapters.
+type Invalidator interface {
+	Invalidate(changes []Change)
+}
+
+// InvalidatorFunc adapts a function to Invalidator.
+type InvalidatorFunc func(changes []Change)
+
+// Invalidate calls f.
+func (f InvalidatorFunc) Invalidate(changes []Change) { f(changes) }
+
+// DefaultDebounce is the quiet period before a batch is delivered.
+const DefaultDebounce = 300 * time.Millisecond
+
+// DefaultIgnore lists directory names never watched: VCS metadata, the
+// relurpify state directory (whose logs and indexes change on every run),
+// and dependency trees.
+var DefaultIgnore = []string{".git", "relurpify_cfg", "node_modules", "vendor"}
+
+// Options configures a Watcher.
+type Options struct {
+	// Debounce is how long the workspace must be quiet before a batch is
+	// delivered. Zero uses DefaultDebounce.
+	Debounce time.Duration
+	// Ignore lists directory names or glob patterns matched against base
+	// names. Hidden directories are always skipped. Nil uses DefaultIgnore.
+	Ignore []string
+}
+
+// Watcher recursively watches a directory tree.
+type Watcher struct {
+	root     string
+	debounce time.Duration
+	ignore   []string
+	fs       *fsnotify.Watcher
+
+	mu      sync.Mutex
+	pending map[string]Op
+	closed  bool
+}
+
+// New starts watching root and every directory below it that is not ignored.
+// Directories created later are added as they appear.
+func New(root string, opts Options) (*Watcher, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		root:     abs,
+		debounce: opts.Debounce,
+		ignore:   opts.Ignore,
+		fs:       fw,
+		pending:  make(map[string]Op),
+	}
+	if w.debounce <= 0 {
+		w.debounce = DefaultDebounce
+	}
+	if w.ignore == nil {
+		w.ignore = DefaultIgnore
+	}
+	if err := w.addTree(abs); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Root returns the absolute path being watched.
+func (w *Watcher) Root() string { return w.root }
+
+// Run delivers batches of changes to handle until ctx is cancelled or the
+// watcher is closed. Paths are absolute and batches are sorted by path.
+// handle runs on the Run goroutine; events arriving meanwhile are queued for
+// the next batch.
+func (w *Watcher) Run(ctx context.Context, handle func([]Change)) error {
+	timer := time.NewTimer(w.debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			if w.record(event) {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// Some events were lost; callers treat a write to the root
+				// as "anything may have changed".
+				w.mu.Lock()
+				w.pending[w.root] = OpWrite
+				w.mu.Unlock()
+				timer.Reset(w.debounce)
+			}
+		case <-timer.C:
+			if batch := w.drain(); len(batch) > 0 {
+				handle(batch)
+			}
+		}
+	}
+}
+
+// Close stops watching. Run returns once the underlying watcher shuts down.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	return w.fs.Close()
+}
+
+// record queues event and reports whether it is relevant.
+func (w *Watcher) record(event fsnotify.Event) bool {
+	if w.ignored(event.Name) {
+		return false
+	}
+	var op Op
+	switch {
+	case event.Has(fsnotify.Remove):
+		op = OpRemove
+	case event.Has(fsnotify.Rename):
+		op = OpRename
+	case event.Has(fsnotify.Create):
+		op = OpCreate
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if hiddenDir(event.Name) {
+				return false
+			}
+			// Files written before the watch was added produce no events
+			// of their own, so report what the new directory holds.
+			_ = w.addTree(event.Name)
+			w.mu.Lock()
+			_ = filepath.WalkDir(event.Name, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && !w.ignored(path) {
+					w.pending[path] = OpCreate
+				}
+				return nil
+			})
+			w.mu.Unlock()
+			return true
+		}
+	case event.Has(fsnotify.Write):
+		op = OpWrite
+	default:
+		// Chmod alone does not change content.
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if prev, ok := w.pending[event.Name]; ok && prev == OpCreate && op == OpWrite {
+		// Still new as far as the batch is concerned.
+		op = OpCreate
+	}
+	w.pending[event.Name] = op
+	return true
+}
+
+func (w *Watcher) drain() []Change {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return nil
+	}
+	batch := make([]Change, 0, len(w.pending))
+	for path, op := range w.pending {
+		batch = append(batch, Change{Path: path, Op: op})
+	}
+	w.pending = make(map[string]Op)
+	sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+	return batch
+}
+
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			// Directories may vanish mid-walk.
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.root && (w.ignored(path) || hiddenDir(path)) {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+}
+
+func hiddenDir(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// ignored reports whether path or one of its parents below the root is
+// hidden or matches an ignore pattern.
+func (w *Watcher) ignored(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		if part == ".." {
+			return true
+		}
+		// Dotfiles at the leaf (e.g. .golangci.yml) are watched; hidden
+		// directories and editor swap files are not.
+		if strings.HasPrefix(part, ".") && (i < len(parts)-1 || strings.HasSuffix(part, ".swp")) {
+			return true
+		}
+		if strings.HasSuffix(part, "~") {
+			return true
+		}
+		for _, pattern := range w.ignore {
+			if part == pattern {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startWatcher(t *testing.T, root string) <-chan []Change {
+	t.Helper()
+	w, err := New(root, Options{Debounce: 20 * time.Millisecond})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []Change, 16)
+	done := make(chan struct{})
+	go func() {
+		_ = w.Run(ctx, func(batch []Change) { batches <- batch })
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		w.Close()
+	})
+	return batches
+}
+
+// collect gathers batches until want has been seen for every path or the
+// deadline passes, returning the last op per path.
+func collect(t *testing.T, batches <-chan []Change, want map[string]Op) map[string]Op {
+	t.Helper()
+	got := make(map[string]Op)
+	deadline := time.After(3 * time.Second)
+	for {
+		complete := true
+		for path, op := range want {
+			if got[path] != op {
+				complete = false
+			}
+		}
+		if complete {
+			return got
+		}
+		select {
+		case batch := <-batches:
+			for _, change := range batch {
+				got[change.Path] = change.Op
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %v, got %v", want, got)
+		}
+	}
+}
+
+func TestWatcherReportsChangesRecursively(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	existing := filepath.Join(root, "main.go")
+	require.NoError(t, os.WriteFile(existing, []byte("package main\n"), 0o644))
+	batches := startWatcher(t, root)
+
+	require.NoError(t, os.WriteFile(existing, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	nested := filepath.Join(root, "pkg", "util")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	added := filepath.Join(nested, "util.go")
+	require.NoError(t, os.WriteFile(added, []byte("package util\n"), 0o644))
+	collect(t, batches, map[string]Op{existing: OpWrite, added: OpCreate})
+
+	require.NoError(t, os.Remove(existing))
+	got := collect(t, batches, map[string]Op{existing: OpRemove})
+	assert.True(t, got[existing].Removed())
+}
+
+func TestWatcherSkipsIgnoredDirectories(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	for _, dir := range []string{".git", "relurpify_cfg/logs", "node_modules/x"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+	batches := startWatcher(t, root)
+
+	for _, file := range []string{".git/index", "relurpify_cfg/logs/run.log", "node_modules/x/a.js", "notes.txt~"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, file), []byte("x"), 0o644))
+	}
+	marker := filepath.Join(root, "marker.go")
+	require.NoError(t, os.WriteFile(marker, []byte("package x\n"), 0o644))
+
+	got := collect(t, batches, map[string]Op{marker: OpCreate})
+	assert.Len(t, got, 1, "only the marker should be reported: %v", got)
+}
+
+func TestIgnoredPaths(t *testing.T) {
+	w := &Watcher{root: "/ws", ignore: []string{"vendor", "*.tmp"}}
+	for path, want := range map[string]bool{
+		"/ws/main.go":           false,
+		"/ws/.golangci.yml":     false,
+		"/ws/.cache/x":          true,
+		"/ws/.main.go.swp":      true,
+		"/ws/vendor/a/b.go":     true,
+		"/ws/build/out.tmp":     true,
+		"/ws/pkg/vendorized.go": false,
+		"/elsewhere/main.go":    true,
+	} {
+		assert.Equal(t, want, w.ignored(path), path)
+	}
+}
@@ -1,4 +1,4 @@
-package pattern
+package agentutil
 
 import (
 	"crypto/rand"
@@ -0,0 +1,17 @@
+// Package agentutil holds helpers shared by the agent implementations. It is
+// internal so the helpers can change without affecting the public API.
+package agentutil
+
+import "strings"
+
+// ExtractJSON returns the outermost JSON object inside a string response. When
+// no braces are present it returns an empty JSON object so downstream
+// unmarshalling still succeeds.
+func ExtractJSON(raw string) string {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start >= 0 && end >= start {
+		return raw[start : end+1]
+	}
+	return "{}"
+}
@@ -0,0 +1,246 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Defaults for ResponseCacheConfig.
+const (
+	DefaultResponseCacheTTL      = 24 * time.Hour
+	DefaultResponseCacheMaxBytes = 64 << 20
+)
+
+// ResponseCacheConfig configures a ResponseCache.
+type ResponseCacheConfig struct {
+	Path string
+	// TTL expires entries this long after they were stored.
+	TTL time.Duration
+	// MaxBytes bounds the stored responses; the least recently used entries
+	// are evicted first.
+	MaxBytes int64
+}
+
+// ResponseCache stores model responses in SQLite, keyed on the model, the
+// prompt or messages, the offered tools, and the generation options.
+type ResponseCache struct {
+	db       *sql.DB
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// OpenResponseCache opens (creating when needed) the cache database.
+func OpenResponseCache(cfg ResponseCacheConfig) (*ResponseCache, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("response cache path required")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultResponseCacheTTL
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultResponseCacheMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	// A single connection serializes writers instead of failing them with
+	// SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS responses (
+		key TEXT PRIMARY KEY,
+		model TEXT,
+		response BLOB NOT NULL,
+		size INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		used_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_responses_used_at ON responses(used_at);`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &ResponseCache{db: db, ttl: cfg.TTL, maxBytes: cfg.MaxBytes, now: time.Now}, nil
+}
+
+// Close releases the database.
+func (c *ResponseCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// Len reports how many entries are stored, expired ones included.
+func (c *ResponseCache) Len() (int, error) {
+	var n int
+	err := c.db.QueryRow(`SELECT COUNT(*) FROM responses`).Scan(&n)
+	return n, err
+}
+
+// Clear drops every entry.
+func (c *ResponseCache) Clear() error {
+	_, err := c.db.Exec(`DELETE FROM responses`)
+	return err
+}
+
+func (c *ResponseCache) get(ctx context.Context, key string) (*framework.LLMResponse, bool) {
+	now := c.now()
+	var data []byte
+	var created int64
+	err := c.db.QueryRowContext(ctx, `SELECT response, created_at FROM responses WHERE key = ?`, key).Scan(&data, &created)
+	if err != nil {
+		return nil, false
+	}
+	if now.Sub(time.Unix(0, created)) >= c.ttl {
+		_, _ = c.db.ExecContext(ctx, `DELETE FROM responses WHERE key = ?`, key)
+		return nil, false
+	}
+	var resp framework.LLMResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	_, _ = c.db.ExecContext(ctx, `UPDATE responses SET used_at = ? WHERE key = ?`, now.UnixNano(), key)
+	return &resp, true
+}
+
+func (c *ResponseCache) put(ctx context.Context, key, model string, resp *framework.LLMResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	now := c.now()
+	if _, err := c.db.ExecContext(ctx, `INSERT OR REPLACE INTO responses (key, model, response, size, created_at, used_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key, model, data, len(data), now.UnixNano(), now.UnixNano()); err != nil {
+		return err
+	}
+	return c.evict(ctx, now)
+}
+
+// evict drops expired entries, then the least recently used ones until the
+// stored responses fit in maxBytes.
+func (c *ResponseCache) evict(ctx context.Context, now time.Time) error {
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM responses WHERE created_at <= ?`, now.Add(-c.ttl).UnixNano()); err != nil {
+		return err
+	}
+	_, err := c.db.ExecContext(ctx, `DELETE FROM responses WHERE key IN (
+		SELECT key FROM (
+			SELECT key, SUM(size) OVER (ORDER BY used_at DESC, key) AS running FROM responses
+		) WHERE running > ?
+	)`, c.maxBytes)
+	return err
+}
+
+// CachedModel serves repeated calls from a ResponseCache. Streaming calls
+// always reach the inner model. Cached responses carry no token usage, since
+// replaying them costs nothing.
+type CachedModel struct {
+	Inner framework.LanguageModel
+	Cache *ResponseCache
+	// Model names the inner model in cache keys when the call options do not.
+	// An inner *Client's current model takes precedence, so switching models
+	// never replays another model's answers.
+	Model string
+}
+
+// NewCachedModel wraps inner, whose default model is model, with cache.
+func NewCachedModel(inner framework.LanguageModel, cache *ResponseCache, model string) *CachedModel {
+	return &CachedModel{Inner: inner, Cache: cache, Model: model}
+}
+
+func (m *CachedModel) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.cached(ctx, options, map[string]interface{}{"kind": "generate", "prompt": prompt}, func() (*framework.LLMResponse, error) {
+		return m.Inner.Generate(ctx, prompt, options)
+	})
+}
+
+func (m *CachedModel) GenerateStream(ctx context.Context, prompt string, options *framework.LLMOptions) (<-chan string, error) {
+	return m.Inner.GenerateStream(ctx, prompt, options)
+}
+
+func (m *CachedModel) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.cached(ctx, options, map[string]interface{}{"kind": "chat", "messages": messages}, func() (*framework.LLMResponse, error) {
+		return m.Inner.Chat(ctx, messages, options)
+	})
+}
+
+func (m *CachedModel) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	offered := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		offered = append(offered, map[string]interface{}{
+			"name":        tool.Name(),
+			"description": tool.Description(),
+			"parameters":  tool.Parameters(),
+		})
+	}
+	return m.cached(ctx, options, map[string]interface{}{"kind": "chat_with_tools", "messages": messages, "tools": offered}, func() (*framework.LLMResponse, error) {
+		return m.Inner.ChatWithTools(ctx, messages, tools, options)
+	})
+}
+
+// cached answers from the cache when request was seen before and stores
+// successful responses otherwise. Cache failures fall through to call.
+func (m *CachedModel) cached(ctx context.Context, options *framework.LLMOptions, request map[string]interface{}, call func() (*framework.LLMResponse, error)) (*framework.LLMResponse, error) {
+	if m.Cache == nil {
+		return call()
+	}
+	model := m.modelName()
+	if options != nil && options.Model != "" {
+		model = options.Model
+	}
+	request["model"] = model
+	if options != nil {
+		opts := *options
+		opts.Model = ""
+		request["options"] = opts
+	}
+	key, err := cacheKey(request)
+	if err != nil {
+		return call()
+	}
+	if resp, ok := m.Cache.get(ctx, key); ok {
+		resp.Usage = nil
+		return resp, nil
+	}
+	resp, err := call()
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	_ = m.Cache.put(ctx, key, model, resp)
+	return resp, nil
+}
+
+func (m *CachedModel) modelName() string {
+	if client, ok := m.Inner.(*Client); ok && client.Model != "" {
+		return client.Model
+	}
+	if pool, ok := m.Inner.(*Pool); ok && pool.Primary.Model != "" {
+		return pool.Primary.Model
+	}
+	return m.Model
+}
+
+// cacheKey hashes the JSON encoding of request; map keys are sorted, so
+// equal requests always hash alike.
+func cacheKey(request map[string]interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
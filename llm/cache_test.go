@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func newTestCache(t *testing.T, cfg ResponseCacheConfig) *ResponseCache {
+	t.Helper()
+	cfg.Path = filepath.Join(t.TempDir(), "responses.db")
+	cache, err := OpenResponseCache(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestCachedModelReplaysRepeatedCalls(t *testing.T) {
+	ctx := context.Background()
+	inner := &MockModel{Respond: func(call MockCall) *framework.LLMResponse {
+		return &framework.LLMResponse{Text: "plan for " + call.Prompt, Usage: map[string]int{"total_tokens": 10}}
+	}}
+	model := NewCachedModel(inner, newTestCache(t, ResponseCacheConfig{}), "llama3")
+	opts := &framework.LLMOptions{Temperature: 0.2}
+
+	first, err := model.Generate(ctx, "fix the parser", opts)
+	require.NoError(t, err)
+	second, err := model.Generate(ctx, "fix the parser", opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.Calls())
+	assert.Equal(t, first.Text, second.Text)
+	assert.Nil(t, second.Usage, "replayed responses cost no tokens")
+
+	_, err = model.Generate(ctx, "fix the parser", &framework.LLMOptions{Temperature: 0.9})
+	require.NoError(t, err)
+	_, err = model.Generate(ctx, "fix the parser", &framework.LLMOptions{Model: "qwen", Temperature: 0.2})
+	require.NoError(t, err)
+	_, err = model.Chat(ctx, []framework.Message{{Role: "user", Content: "fix the parser"}}, opts)
+	require.NoError(t, err)
+	_, err = model.ChatWithTools(ctx, []framework.Message{{Role: "user", Content: "fix the parser"}}, []framework.Tool{stubTool{name: "file_read"}}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 5, inner.Calls(), "options, model, and call kind are part of the key")
+}
+
+func TestResponseCacheExpiresAndEvicts(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t, ResponseCacheConfig{TTL: time.Hour, MaxBytes: 100})
+	now := time.Unix(1000, 0)
+	cache.now = func() time.Time { return now }
+	resp := &framework.LLMResponse{Text: "a response of about forty bytes"}
+
+	require.NoError(t, cache.put(ctx, "a", "m", resp))
+	now = now.Add(time.Second)
+	require.NoError(t, cache.put(ctx, "b", "m", resp))
+	now = now.Add(time.Second)
+	_, ok := cache.get(ctx, "a")
+	require.True(t, ok)
+	now = now.Add(time.Second)
+	require.NoError(t, cache.put(ctx, "c", "m", resp))
+	_, ok = cache.get(ctx, "b")
+	assert.False(t, ok, "the least recently used entry is evicted first")
+	_, ok = cache.get(ctx, "a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Hour)
+	_, ok = cache.get(ctx, "c")
+	assert.False(t, ok, "entries expire after the TTL")
+}
@@ -0,0 +1,4 @@
+// Package llm provides framework.LanguageModel implementations: an Ollama
+// client and a wrapper that reports calls to framework.Telemetry. It is part
+// of the stable public API.
+package llm
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/lexcodex/relurpify/framework/ast"
+)
+
+// Defaults for embedding batches and EmbeddingCache.
+const (
+	DefaultEmbedBatchSize     = 32
+	DefaultEmbeddingCacheSize = 4096
+)
+
+// EmbedBatches embeds texts in requests of at most size texts
+// (DefaultEmbedBatchSize when size is not positive), so a large input does
+// not become one oversized request. Vectors are returned in input order.
+func EmbedBatches(ctx context.Context, embedder ast.Embedder, texts []string, size int) ([][]float32, error) {
+	if size <= 0 {
+		size = DefaultEmbedBatchSize
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		batch := texts[start:min(start+size, len(texts))]
+		got, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(got) != len(batch) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d inputs", len(got), len(batch))
+		}
+		vectors = append(vectors, got...)
+	}
+	return vectors, nil
+}
+
+// EmbeddingCache is an ast.Embedder that keeps the vectors of recently
+// embedded texts in memory and sends only the misses to Inner, in batches.
+// Repeated queries and the unchanged chunks of an edited file are then not
+// embedded again. Keys include Model, so switching models never returns
+// another model's vectors.
+type EmbeddingCache struct {
+	Inner ast.Embedder
+	Model string
+	// Size bounds the cached vectors; the least recently used are evicted
+	// first. Zero uses DefaultEmbeddingCacheSize.
+	Size int
+	// BatchSize bounds the texts per Inner call; zero uses
+	// DefaultEmbedBatchSize.
+	BatchSize int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type embeddingEntry struct {
+	key    string
+	vector []float32
+}
+
+// NewEmbeddingCache wraps inner, which embeds with model, in a cache of size
+// vectors.
+func NewEmbeddingCache(inner ast.Embedder, model string, size int) *EmbeddingCache {
+	return &EmbeddingCache{Inner: inner, Model: model, Size: size}
+}
+
+// Embed returns cached vectors and embeds the rest. Texts repeated within
+// one call are embedded once.
+func (c *EmbeddingCache) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	pending := make(map[string][]int)
+	var missing []string
+	c.mu.Lock()
+	for i, text := range texts {
+		key := c.key(text)
+		if vector, ok := c.lookup(key); ok {
+			vectors[i] = vector
+			continue
+		}
+		if _, seen := pending[key]; !seen {
+			missing = append(missing, text)
+		}
+		pending[key] = append(pending[key], i)
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	embedded, err := EmbedBatches(ctx, c.Inner, missing, c.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, text := range missing {
+		key := c.key(text)
+		for _, pos := range pending[key] {
+			vectors[pos] = embedded[i]
+		}
+		c.store(key, embedded[i])
+	}
+	return vectors, nil
+}
+
+// Len reports how many vectors are cached.
+func (c *EmbeddingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *EmbeddingCache) key(text string) string {
+	sum := sha256.Sum256([]byte(c.Model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *EmbeddingCache) lookup(key string) ([]float32, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*embeddingEntry).vector, true
+}
+
+func (c *EmbeddingCache) store(key string, vector []float32) {
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*embeddingEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&embeddingEntry{key: key, vector: vector})
+	size := c.Size
+	if size <= 0 {
+		size = DefaultEmbeddingCacheSize
+	}
+	for c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingEntry).key)
+	}
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder embeds each text as its length and records every call.
+type countingEmbedder struct {
+	calls [][]string
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestEmbedBatchesSplitsRequests(t *testing.T) {
+	inner := &countingEmbedder{}
+	vectors, err := EmbedBatches(context.Background(), inner, []string{"a", "bb", "ccc", "dddd", "eeeee"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc", "dddd"}, {"eeeee"}}, inner.calls)
+	assert.Equal(t, [][]float32{{1}, {2}, {3}, {4}, {5}}, vectors)
+}
+
+func TestEmbeddingCacheEmbedsOnlyMisses(t *testing.T) {
+	inner := &countingEmbedder{}
+	cache := NewEmbeddingCache(inner, "nomic-embed-text", 2)
+
+	vectors, err := cache.Embed(context.Background(), []string{"a", "bb", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1}, {2}, {1}}, vectors)
+	assert.Equal(t, [][]string{{"a", "bb"}}, inner.calls, "repeats within a call are embedded once")
+
+	vectors, err = cache.Embed(context.Background(), []string{"bb", "ccc"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{2}, {3}}, vectors)
+	assert.Equal(t, []string{"ccc"}, inner.calls[1])
+	assert.Equal(t, 2, cache.Len())
+
+	// "a" was least recently used and is evicted.
+	_, err = cache.Embed(context.Background(), []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, inner.calls[2])
+
+	cache.Model = "other-model"
+	_, err = cache.Embed(context.Background(), []string{"a"})
+	require.NoError(t, err)
+	assert.Len(t, inner.calls, 4, "vectors are keyed by model")
+}
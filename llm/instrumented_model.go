@@ -15,6 +15,8 @@ type InstrumentedModel struct {
 	Inner     framework.LanguageModel
 	Telemetry framework.Telemetry
 	Debug     bool
+	// Usage, when set, records token counts for every completed call.
+	Usage *framework.UsageTracker
 }
 
 func NewInstrumentedModel(inner framework.LanguageModel, telemetry framework.Telemetry, debug bool) *InstrumentedModel {
@@ -27,8 +29,10 @@ func (m *InstrumentedModel) Generate(ctx context.Context, prompt string, options
 		"prompt_chars":  len(prompt),
 		"prompt_preview": clip(prompt, 1024),
 	}, m.Debug, map[string]interface{}{"prompt": clip(prompt, 8192)})
-	resp, err := m.Inner.Generate(ctx, prompt, options)
-	m.emitResponse(ctx, "generate", resp, err)
+	start := time.Now()
+	callCtx, trace := withEndpointTrace(ctx)
+	resp, err := m.Inner.Generate(callCtx, prompt, options)
+	m.emitResponse(ctx, "generate", resp, err, time.Since(start), trace)
 	return resp, err
 }
 
@@ -38,12 +42,14 @@ func (m *InstrumentedModel) GenerateStream(ctx context.Context, prompt string, o
 		"prompt_chars":  len(prompt),
 		"prompt_preview": clip(prompt, 1024),
 	}, m.Debug, map[string]interface{}{"prompt": clip(prompt, 8192)})
-	ch, err := m.Inner.GenerateStream(ctx, prompt, options)
+	start := time.Now()
+	callCtx, trace := withEndpointTrace(ctx)
+	ch, err := m.Inner.GenerateStream(callCtx, prompt, options)
 	// For stream, we only emit that a stream started; callers can still see tool calls/results via other telemetry.
 	if err != nil {
-		m.emitResponse(ctx, "generate_stream", nil, err)
+		m.emitResponse(ctx, "generate_stream", nil, err, time.Since(start), trace)
 	} else {
-		m.emitResponse(ctx, "generate_stream", &framework.LLMResponse{FinishReason: "stream"}, nil)
+		m.emitResponse(ctx, "generate_stream", &framework.LLMResponse{FinishReason: "stream"}, nil, time.Since(start), trace)
 	}
 	return ch, err
 }
@@ -51,16 +57,20 @@ func (m *InstrumentedModel) GenerateStream(ctx context.Context, prompt string, o
 func (m *InstrumentedModel) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
 	meta := chatMeta(messages, nil, options)
 	m.emitPrompt(ctx, "chat", meta.base, m.Debug, meta.debug)
-	resp, err := m.Inner.Chat(ctx, messages, options)
-	m.emitResponse(ctx, "chat", resp, err)
+	start := time.Now()
+	callCtx, trace := withEndpointTrace(ctx)
+	resp, err := m.Inner.Chat(callCtx, messages, options)
+	m.emitResponse(ctx, "chat", resp, err, time.Since(start), trace)
 	return resp, err
 }
 
 func (m *InstrumentedModel) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
 	meta := chatMeta(messages, tools, options)
 	m.emitPrompt(ctx, "chat_with_tools", meta.base, m.Debug, meta.debug)
-	resp, err := m.Inner.ChatWithTools(ctx, messages, tools, options)
-	m.emitResponse(ctx, "chat_with_tools", resp, err)
+	start := time.Now()
+	callCtx, trace := withEndpointTrace(ctx)
+	resp, err := m.Inner.ChatWithTools(callCtx, messages, tools, options)
+	m.emitResponse(ctx, "chat_with_tools", resp, err, time.Since(start), trace)
 	return resp, err
 }
 
@@ -135,23 +145,38 @@ func (m *InstrumentedModel) emitPrompt(ctx context.Context, kind string, base ma
 	m.Telemetry.Emit(framework.Event{
 		Type:      framework.EventLLMPrompt,
 		TaskID:    taskID,
+		NodeID:    nodeID(ctx),
 		Timestamp: time.Now().UTC(),
 		Message:   fmt.Sprintf("llm %s prompt", kind),
 		Metadata:  metadata,
 	})
 }
 
-func (m *InstrumentedModel) emitResponse(ctx context.Context, kind string, resp *framework.LLMResponse, err error) {
-	if m == nil || m.Telemetry == nil {
+// emitResponse reports a finished call; elapsed becomes the event's
+// duration_ms so metrics can track model latency, and trace names the
+// endpoint that served it.
+func (m *InstrumentedModel) emitResponse(ctx context.Context, kind string, resp *framework.LLMResponse, err error, elapsed time.Duration, trace *endpointTrace) {
+	if m == nil {
+		return
+	}
+	if m.Usage != nil && err == nil && resp != nil && len(resp.Usage) > 0 {
+		m.Usage.Record(ctx, m.modelName(), resp.Usage)
+	}
+	if err == nil && resp != nil {
+		framework.TaskBudgetFrom(ctx).ChargeLLM(framework.LLMUsageFromMap(resp.Usage))
+	}
+	if m.Telemetry == nil {
 		return
 	}
 	taskID, taskMeta := taskInfo(ctx)
 	metadata := map[string]interface{}{
-		"kind": kind,
+		"kind":        kind,
+		"duration_ms": elapsed.Milliseconds(),
 	}
 	for k, v := range taskMeta {
 		metadata[k] = v
 	}
+	trace.annotate(metadata)
 	if resp != nil {
 		metadata["finish_reason"] = resp.FinishReason
 		metadata["text_preview"] = clip(resp.Text, 1024)
@@ -167,12 +192,26 @@ func (m *InstrumentedModel) emitResponse(ctx context.Context, kind string, resp
 	m.Telemetry.Emit(framework.Event{
 		Type:      framework.EventLLMResponse,
 		TaskID:    taskID,
+		NodeID:    nodeID(ctx),
 		Timestamp: time.Now().UTC(),
 		Message:   fmt.Sprintf("llm %s response", kind),
 		Metadata:  metadata,
 	})
 }
 
+// modelName reports the wrapped Ollama client's model for usage records.
+func (m *InstrumentedModel) modelName() string {
+	switch inner := m.Inner.(type) {
+	case *Client:
+		return inner.Model
+	case *CachedModel:
+		return inner.modelName()
+	case *Pool:
+		return inner.Primary.Model
+	}
+	return ""
+}
+
 func modelFromOptions(options *framework.LLMOptions) string {
 	if options != nil && options.Model != "" {
 		return options.Model
@@ -194,6 +233,12 @@ func taskInfo(ctx context.Context) (string, map[string]interface{}) {
 	return task.ID, meta
 }
 
+// nodeID returns the graph node making the call, if any.
+func nodeID(ctx context.Context) string {
+	task, _ := framework.TaskContextFrom(ctx)
+	return task.NodeID
+}
+
 func clip(s string, max int) string {
 	s = strings.ReplaceAll(s, "\r\n", "\n")
 	if max <= 0 {
@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModelEventType distinguishes model lifecycle notifications.
+type ModelEventType string
+
+const (
+	// ModelLoading is reported when a warm-up starts and then every
+	// WarmProgressInterval until the model is loaded.
+	ModelLoading ModelEventType = "loading"
+	// ModelReady reports a loaded model.
+	ModelReady ModelEventType = "ready"
+	// ModelLoadFailed reports a warm-up that did not load the model.
+	ModelLoadFailed ModelEventType = "failed"
+	// ModelUnloaded reports a model unloaded after sitting idle.
+	ModelUnloaded ModelEventType = "unloaded"
+)
+
+// ModelEvent reports progress loading or unloading a model. Elapsed is the
+// time spent loading so far, or the total load time on ModelReady.
+type ModelEvent struct {
+	Type    ModelEventType
+	Model   string
+	Elapsed time.Duration
+	Err     error
+}
+
+// WarmProgressInterval paces the ModelLoading events sent during a warm-up.
+var WarmProgressInterval = time.Second
+
+// RunningModel is a model Ollama holds in memory, from /api/ps.
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RunningModels lists the models Ollama currently has loaded.
+func (c *Client) RunningModels(ctx context.Context) ([]RunningModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+"/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, unavailable(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(msg))}
+	}
+	var raw struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw.Models, nil
+}
+
+// Loaded reports whether the client's model is in Ollama's memory.
+func (c *Client) Loaded(ctx context.Context) (bool, error) {
+	running, err := c.RunningModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	model := c.model(nil)
+	for _, m := range running {
+		if sameModel(m.Name, model) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Warm loads the client's model ahead of the first task, so a cold load
+// happens while the user is still typing. Ollama reports no load progress,
+// so report receives ModelLoading with the elapsed time every
+// WarmProgressInterval, then ModelReady or ModelLoadFailed. A model that is
+// already loaded is reported ready straight away.
+func (c *Client) Warm(ctx context.Context, report func(ModelEvent)) error {
+	if report == nil {
+		report = func(ModelEvent) {}
+	}
+	model := c.model(nil)
+	if loaded, err := c.Loaded(ctx); err == nil && loaded {
+		c.touch()
+		report(ModelEvent{Type: ModelReady, Model: model})
+		return nil
+	}
+	started := time.Now()
+	report(ModelEvent{Type: ModelLoading, Model: model})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(WarmProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				report(ModelEvent{Type: ModelLoading, Model: model, Elapsed: time.Since(started)})
+			}
+		}
+	}()
+	// A generate request without a prompt only loads the model.
+	payload := map[string]interface{}{"model": model}
+	if c.KeepAlive != "" {
+		payload["keep_alive"] = keepAliveValue(c.KeepAlive)
+	}
+	_, err := c.doRequest(ctx, "/api/generate", payload)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		report(ModelEvent{Type: ModelLoadFailed, Model: model, Elapsed: time.Since(started), Err: err})
+		return err
+	}
+	c.touch()
+	report(ModelEvent{Type: ModelReady, Model: model, Elapsed: time.Since(started)})
+	return nil
+}
+
+// Unload asks Ollama to release the client's model now.
+func (c *Client) Unload(ctx context.Context) error {
+	_, err := c.doRequest(ctx, "/api/generate", map[string]interface{}{"model": c.model(nil), "keep_alive": 0})
+	if err == nil {
+		c.lastUsed.Store(0)
+	}
+	return err
+}
+
+// LastUsed returns when the client last sent a request or loaded its model,
+// or the zero time if it has not since it was created or unloaded.
+func (c *Client) LastUsed() time.Time {
+	if n := c.lastUsed.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+func (c *Client) touch() {
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+// keepAliveValue sends plain numbers as seconds, which Ollama only accepts
+// as JSON numbers, and anything else as a duration string.
+func keepAliveValue(value string) interface{} {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return value
+}
+
+// sameModel compares model names, treating a missing tag as ":latest".
+func sameModel(a, b string) bool {
+	return withTag(a) == withTag(b)
+}
+
+func withTag(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return name + ":latest"
+}
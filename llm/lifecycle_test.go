@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWarmLoadsModelAndReportsProgress(t *testing.T) {
+	previous := WarmProgressInterval
+	WarmProgressInterval = 5 * time.Millisecond
+	defer func() { WarmProgressInterval = previous }()
+
+	var loads []map[string]interface{}
+	client := NewClient("http://fake", "qwen")
+	client.KeepAlive = "-1"
+	client.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		body := `{"models":[]}`
+		if req.URL.Path == "/api/generate" {
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+			loads = append(loads, payload)
+			time.Sleep(30 * time.Millisecond)
+			body = `{"model":"qwen","response":"","done":true}`
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	})}
+
+	var events []ModelEvent
+	require.NoError(t, client.Warm(context.Background(), func(ev ModelEvent) { events = append(events, ev) }))
+	require.Len(t, loads, 1)
+	assert.Equal(t, "qwen", loads[0]["model"])
+	assert.NotContains(t, loads[0], "prompt")
+	assert.Equal(t, float64(-1), loads[0]["keep_alive"], "numeric keep_alive is sent as seconds")
+	require.GreaterOrEqual(t, len(events), 3)
+	assert.Equal(t, ModelLoading, events[0].Type)
+	assert.Equal(t, ModelLoading, events[1].Type)
+	assert.Positive(t, events[1].Elapsed)
+	last := events[len(events)-1]
+	assert.Equal(t, ModelReady, last.Type)
+	assert.Positive(t, last.Elapsed)
+	assert.False(t, client.LastUsed().IsZero())
+}
+
+func TestClientWarmSkipsLoadedModelAndUnloads(t *testing.T) {
+	var paths []string
+	var unload map[string]interface{}
+	client := NewClient("http://fake", "qwen")
+	client.KeepAlive = "30m"
+	client.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		paths = append(paths, req.URL.Path)
+		body := `{"models":[{"name":"qwen:latest","size":100,"size_vram":100}]}`
+		if req.URL.Path == "/api/generate" {
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&unload))
+			body = `{"done":true}`
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	})}
+
+	var events []ModelEvent
+	require.NoError(t, client.Warm(context.Background(), func(ev ModelEvent) { events = append(events, ev) }))
+	assert.Equal(t, []string{"/api/ps"}, paths)
+	assert.Equal(t, []ModelEvent{{Type: ModelReady, Model: "qwen"}}, events)
+
+	require.NoError(t, client.Unload(context.Background()))
+	assert.Equal(t, float64(0), unload["keep_alive"])
+	assert.True(t, client.LastUsed().IsZero())
+}
+
+func TestClientSendsKeepAlive(t *testing.T) {
+	client := NewClient("http://fake", "qwen")
+	client.KeepAlive = "30m"
+	client.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		var payload map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		assert.Equal(t, "30m", payload["keep_alive"])
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"response":"ok"}`)), Header: make(http.Header)}
+	})}
+	_, err := client.Generate(context.Background(), "hi", nil)
+	require.NoError(t, err)
+}
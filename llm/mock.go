@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// MockCall describes one call to a MockModel.
+type MockCall struct {
+	// TaskID comes from the framework.TaskContext the graph attaches to ctx.
+	TaskID string
+	// Seq counts prior calls for the same task, starting at zero, so a
+	// responder can script multi-step conversations per task.
+	Seq    int
+	Prompt string
+	Tools  []framework.Tool
+}
+
+// MockModel is a deterministic LanguageModel that never contacts Ollama. Each
+// call waits Latency, to mimic inference time, then returns Respond's
+// response. Without Respond it answers with a completed decision. It is safe
+// for concurrent use and also reports how many calls were in flight at once.
+//
+// Experimental: intended for self-tests and demos; the shape may change.
+type MockModel struct {
+	Latency time.Duration
+	Respond func(call MockCall) *framework.LLMResponse
+
+	mu          sync.Mutex
+	seq         map[string]int
+	inFlight    int
+	maxInFlight int
+	calls       int
+}
+
+// Generate implements framework.LanguageModel.
+func (m *MockModel) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.call(ctx, prompt, nil)
+}
+
+// GenerateStream emits the response text as a single chunk.
+func (m *MockModel) GenerateStream(ctx context.Context, prompt string, options *framework.LLMOptions) (<-chan string, error) {
+	resp, err := m.call(ctx, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan string, 1)
+	ch <- resp.Text
+	close(ch)
+	return ch, nil
+}
+
+// Chat implements framework.LanguageModel using the last message as prompt.
+func (m *MockModel) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.call(ctx, lastContent(messages), nil)
+}
+
+// ChatWithTools implements framework.LanguageModel.
+func (m *MockModel) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.call(ctx, lastContent(messages), tools)
+}
+
+// Calls reports how many calls completed.
+func (m *MockModel) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// MaxInFlight reports the highest number of concurrent calls observed.
+func (m *MockModel) MaxInFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.maxInFlight
+}
+
+func (m *MockModel) call(ctx context.Context, prompt string, tools []framework.Tool) (*framework.LLMResponse, error) {
+	call := MockCall{Prompt: prompt, Tools: tools}
+	if task, ok := framework.TaskContextFrom(ctx); ok {
+		call.TaskID = task.ID
+	}
+	m.mu.Lock()
+	if m.seq == nil {
+		m.seq = make(map[string]int)
+	}
+	call.Seq = m.seq[call.TaskID]
+	m.seq[call.TaskID]++
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.calls++
+		m.mu.Unlock()
+	}()
+
+	if m.Latency > 0 {
+		timer := time.NewTimer(m.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	var resp *framework.LLMResponse
+	if m.Respond != nil {
+		resp = m.Respond(call)
+	}
+	if resp == nil {
+		resp = &framework.LLMResponse{Text: `{"thought":"done","complete":true}`}
+	}
+	if resp.FinishReason == "" {
+		resp.FinishReason = "stop"
+	}
+	if resp.Usage == nil {
+		resp.Usage = map[string]int{"prompt_tokens": len(prompt) / 4, "completion_tokens": len(resp.Text) / 4}
+	}
+	return resp, nil
+}
+
+func lastContent(messages []framework.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestMockModelSequencesCallsPerTask(t *testing.T) {
+	var seen []MockCall
+	mock := &MockModel{Respond: func(call MockCall) *framework.LLMResponse {
+		seen = append(seen, call)
+		return nil
+	}}
+	ctxA := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "a"})
+	ctxB := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "b"})
+
+	_, err := mock.Generate(ctxA, "first", nil)
+	assert.NoError(t, err)
+	_, err = mock.Generate(ctxB, "first", nil)
+	assert.NoError(t, err)
+	resp, err := mock.Chat(ctxA, []framework.Message{{Role: "user", Content: "second"}}, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{0, 0, 1}, []int{seen[0].Seq, seen[1].Seq, seen[2].Seq})
+	assert.Equal(t, "second", seen[2].Prompt)
+	assert.Contains(t, resp.Text, `"complete":true`)
+	assert.Equal(t, 3, mock.Calls())
+	assert.Equal(t, 1, mock.MaxInFlight())
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/lexcodex/relurpify/framework"
@@ -21,6 +22,19 @@ type Client struct {
 	Model    string
 	client   *http.Client
 	Debug    bool
+	// ContextLength, when set, is sent as num_ctx so Ollama allocates the
+	// window the agent budgets for instead of its small default.
+	ContextLength int
+	// Throttle, when set, queues generation and embedding requests so the
+	// endpoint is not overloaded. Share one per endpoint (SharedThrottle).
+	Throttle *Throttle
+	// KeepAlive, when set, is sent as keep_alive so Ollama keeps the model
+	// loaded that long after each request: a duration such as "30m", or
+	// seconds, where -1 keeps it loaded until unloaded.
+	KeepAlive string
+
+	// lastUsed is the UnixNano time of the last request; see LastUsed.
+	lastUsed atomic.Int64
 }
 
 type toolFunction struct {
@@ -62,6 +76,33 @@ type ollamaResponse struct {
 	PromptEvalCount int              `json:"prompt_eval_count"`
 }
 
+// StatusError reports a non-2xx Ollama response. StatusCode lets
+// framework.ClassifyError retry 5xx and 429 responses.
+type StatusError struct {
+	Code   int
+	Status string
+	Detail string
+}
+
+func (e *StatusError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("ollama error: %s: %s", e.Status, e.Detail)
+	}
+	return fmt.Sprintf("ollama error: %s", e.Status)
+}
+
+// StatusCode returns the HTTP status code.
+func (e *StatusError) StatusCode() int { return e.Code }
+
+// ErrorKind reports server errors, rate limits, and missing models as
+// framework.ErrorKindLLMUnavailable; other statuses have no kind.
+func (e *StatusError) ErrorKind() framework.ErrorKind {
+	if e.Code >= 500 || e.Code == http.StatusTooManyRequests || e.Code == http.StatusNotFound {
+		return framework.ErrorKindLLMUnavailable
+	}
+	return ""
+}
+
 // NewClient builds a new Ollama client.
 func NewClient(endpoint, model string) *Client {
 	if endpoint == "" {
@@ -99,17 +140,24 @@ func (c *Client) GenerateStream(ctx context.Context, prompt string, options *fra
 	if err != nil {
 		return nil, err
 	}
+	noteEndpoint(ctx, c.Endpoint)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+"/api/generate", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.getHTTPClient().Do(req)
+	release, err := c.Throttle.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		release()
+		return nil, unavailable(ctx, err)
+	}
 	ch := make(chan string)
 	go func() {
+		defer release()
 		defer resp.Body.Close()
 		defer close(ch)
 		scanner := bufio.NewScanner(resp.Body)
@@ -167,6 +215,13 @@ func (c *Client) model(options *framework.LLMOptions) string {
 }
 
 func (c *Client) applyOptions(payload map[string]interface{}, options *framework.LLMOptions) {
+	c.touch()
+	if c.ContextLength > 0 {
+		payload["options"] = map[string]interface{}{"num_ctx": c.ContextLength}
+	}
+	if c.KeepAlive != "" {
+		payload["keep_alive"] = keepAliveValue(c.KeepAlive)
+	}
 	if options == nil {
 		return
 	}
@@ -182,9 +237,13 @@ func (c *Client) applyOptions(payload map[string]interface{}, options *framework
 	if options.TopP != 0 {
 		payload["top_p"] = options.TopP
 	}
+	if options.Schema != nil {
+		payload["format"] = options.Schema
+	}
 }
 
 func (c *Client) doRequest(ctx context.Context, path string, payload interface{}) (*framework.LLMResponse, error) {
+	noteEndpoint(ctx, c.Endpoint)
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
@@ -195,25 +254,140 @@ func (c *Client) doRequest(ctx context.Context, path string, payload interface{}
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.getHTTPClient().Do(req)
+	release, err := c.Throttle.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, unavailable(ctx, err)
+	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		detail := strings.TrimSpace(string(msg))
-		if detail != "" {
-			return nil, fmt.Errorf("ollama error: %s: %s", resp.Status, detail)
-		}
-		return nil, fmt.Errorf("ollama error: %s", resp.Status)
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(msg))}
 	}
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 }
 	c.logResponse(path, responseBody)
-	return decodeLLMResponse(bytes.NewReader(responseBody))
+	res, err := decodeLLMResponse(bytes.NewReader(responseBody))
+	if err != nil {
+		return nil, framework.WrapError(framework.ErrorKindParseFailure, err)
+	}
+	return res, nil
+}
+
+// unavailable tags a failed request as framework.ErrorKindLLMUnavailable,
+// unless the caller cancelled it.
+func unavailable(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return framework.WrapError(framework.ErrorKindLLMUnavailable, err)
+}
+
+type showResponse struct {
+	Details struct {
+		ParameterSize string `json:"parameter_size"`
+	} `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+// ShowModel reads the context length, parameter size, and capabilities of
+// model (the client's model when empty) from /api/show. Ollama releases
+// before capabilities were reported leave them nil.
+func (c *Client) ShowModel(ctx context.Context, model string) (framework.ModelContext, error) {
+	if model == "" {
+		model = c.model(nil)
+	}
+	info := framework.ModelContext{Name: model}
+	body, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return info, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return info, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return info, unavailable(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return info, &StatusError{Code: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(msg))}
+	}
+	var raw showResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return info, err
+	}
+	info.ParameterSize = raw.Details.ParameterSize
+	info.ContextLength = contextLength(raw.ModelInfo)
+	info.Capabilities = raw.Capabilities
+	return info, nil
+}
+
+// Embed returns one embedding per text from Ollama's /api/embed endpoint,
+// using the client's model. Pull an embedding model such as nomic-embed-text
+// first; chat models reject the request.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.touch()
+	body, err := json.Marshal(map[string]interface{}{"model": c.model(nil), "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	release, err := c.Throttle.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, unavailable(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(msg))}
+	}
+	var raw struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(raw.Embeddings), len(texts))
+	}
+	return raw.Embeddings, nil
+}
+
+// contextLength finds "<architecture>.context_length" in model_info, falling
+// back to any context_length key when the architecture is not reported.
+func contextLength(modelInfo map[string]interface{}) int {
+	if arch, ok := modelInfo["general.architecture"].(string); ok {
+		if n, ok := modelInfo[arch+".context_length"].(float64); ok && n > 0 {
+			return int(n)
+		}
+	}
+	longest := 0
+	for name, value := range modelInfo {
+		if n, ok := value.(float64); ok && strings.HasSuffix(name, "context_length") && int(n) > longest {
+			longest = int(n)
+		}
+	}
+	return longest
 }
 
 func convertMessages(messages []framework.Message) []map[string]interface{} {
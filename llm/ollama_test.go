@@ -69,6 +69,26 @@ func TestClientGenerate(t *testing.T) {
 	assert.Equal(t, "response", resp.Text)
 }
 
+func TestClientGenerateSendsSchemaAsFormat(t *testing.T) {
+	schema := map[string]interface{}{"type": "object", "required": []string{"answer"}}
+	client := NewClient("http://fake", "test")
+	client.client = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+			assert.Equal(t, map[string]interface{}{"type": "object", "required": []interface{}{"answer"}}, payload["format"])
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"text":"{}"}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := client.Generate(context.Background(), "hello", &framework.LLMOptions{Schema: schema})
+	assert.NoError(t, err)
+}
+
 func TestClientChat(t *testing.T) {
 	client := NewClient("http://fake", "chat-model")
 	client.client = &http.Client{
@@ -122,3 +142,93 @@ func TestClientChatWithToolsParsesToolCalls(t *testing.T) {
 		assert.Equal(t, map[string]interface{}{"value": "hi"}, resp.ToolCalls[0].Args)
 	}
 }
+
+func TestClientStatusErrorIsRetryable(t *testing.T) {
+	client := NewClient("http://fake", "model")
+	client.client = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 503,
+				Status:     "503 Service Unavailable",
+				Body:       io.NopCloser(strings.NewReader("model loading")),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := client.Generate(context.Background(), "hello", nil)
+	assert.EqualError(t, err, "ollama error: 503 Service Unavailable: model loading")
+	assert.Equal(t, framework.RetryServer, framework.ClassifyError(err))
+	assert.Equal(t, framework.ErrorKindLLMUnavailable, framework.ErrorKindOf(err))
+}
+
+func TestClientErrorKinds(t *testing.T) {
+	client := NewClient("http://127.0.0.1:1", "model")
+	_, err := client.Generate(context.Background(), "hello", nil)
+	assert.Equal(t, framework.ErrorKindLLMUnavailable, framework.ErrorKindOf(err))
+	assert.Equal(t, framework.RetryNetwork, framework.ClassifyError(err), "tagging keeps retries working")
+
+	client.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{not json")), Header: make(http.Header)}
+	})}
+	_, err = client.Generate(context.Background(), "hello", nil)
+	assert.Equal(t, framework.ErrorKindParseFailure, framework.ErrorKindOf(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewClient("http://127.0.0.1:1", "model").Generate(ctx, "hello", nil)
+	assert.Equal(t, framework.ErrorKindCancelled, framework.ErrorKindOf(err))
+}
+
+func TestClientShowModelAndNumCtx(t *testing.T) {
+	client := NewClient("http://fake", "qwen")
+	client.client = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+			body := `{"text":"ok"}`
+			if req.URL.Path == "/api/show" {
+				assert.Equal(t, "qwen", payload["model"])
+				body = `{"details":{"parameter_size":"7.6B"},"model_info":{"general.architecture":"qwen2","qwen2.context_length":32768},"capabilities":["completion","tools"]}`
+			} else {
+				assert.Equal(t, map[string]interface{}{"num_ctx": float64(16384)}, payload["options"])
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	info, err := client.ShowModel(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, framework.ModelContext{Name: "qwen", ContextLength: 32768, ParameterSize: "7.6B", Capabilities: []string{"completion", "tools"}}, info)
+	assert.InDelta(t, 7.6, info.ParameterBillions(), 0.001)
+
+	client.ContextLength = 16384
+	_, err = client.Generate(context.Background(), "hello", nil)
+	assert.NoError(t, err)
+}
+
+func TestClientEmbed(t *testing.T) {
+	client := NewClient("http://fake", "nomic-embed-text")
+	client.client = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			assert.Equal(t, "/api/embed", req.URL.Path)
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+			assert.Equal(t, "nomic-embed-text", payload["model"])
+			assert.Equal(t, []interface{}{"a", "b"}, payload["input"])
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"embeddings":[[0.1,0.2],[0.3,0.4]]}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	vectors, err := client.Embed(context.Background(), []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2}, {0.3, 0.4}}, vectors)
+}
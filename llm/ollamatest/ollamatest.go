@@ -0,0 +1,302 @@
+// Package ollamatest serves a scripted, Ollama-compatible HTTP API so the
+// agent stack can be exercised end to end without a model:
+//
+//	stub := ollamatest.Start(t, ollamatest.Script{Rules: []ollamatest.Rule{
+//		{Times: 1, Reply: ollamatest.Reply{ToolCalls: []ollamatest.ToolCall{
+//			{Name: "file_write", Args: map[string]interface{}{"path": "a.txt", "content": "hi"}},
+//		}}},
+//	}, Fallback: &ollamatest.Reply{Text: `{"thought":"done","complete":true}`}})
+//	client := llm.NewClient(stub.URL, "stub")
+//
+// Rules are tried in order against the last message of a chat request (or the
+// prompt of a generate request); the first matching rule with uses left
+// answers. Scripts are plain JSON, so the same stub runs out of process via
+// app/ollama-stub, e.g. inside a container.
+package ollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ToolCall is a scripted tool invocation.
+type ToolCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// Reply is one scripted model response.
+type Reply struct {
+	Text      string     `json:"text,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Status, when non-zero, answers with that HTTP status and Text as body
+	// to simulate Ollama failures.
+	Status int `json:"status,omitempty"`
+}
+
+// Rule answers requests whose last message contains Match. Empty Match
+// matches everything; Times limits how often the rule fires (0 is
+// unlimited).
+type Rule struct {
+	Match string `json:"match,omitempty"`
+	Times int    `json:"times,omitempty"`
+	Reply Reply  `json:"reply"`
+}
+
+// Script is the stub's full behavior.
+type Script struct {
+	Rules []Rule `json:"rules"`
+	// Fallback answers requests no rule matched. Without it they get a 500.
+	Fallback *Reply `json:"fallback,omitempty"`
+	// Models is reported by /api/tags. Defaults to ["stub"].
+	Models []string `json:"models,omitempty"`
+	// ContextLength is reported by /api/show for every model; zero reports
+	// no context length.
+	ContextLength int `json:"context_length,omitempty"`
+	// Capabilities is reported by /api/show for every model. Defaults to
+	// ["completion", "tools"].
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// Request records one model call the stub received.
+type Request struct {
+	Path     string    `json:"path"`
+	Model    string    `json:"model"`
+	Prompt   string    `json:"prompt,omitempty"`
+	Messages []Message `json:"messages,omitempty"`
+	// Tools lists the names of the tools offered with the request.
+	Tools []string `json:"tools,omitempty"`
+}
+
+// Message is a chat message as sent by the client.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Name    string `json:"name,omitempty"`
+}
+
+// Server is an http.Handler speaking the subset of the Ollama API the llm
+// client uses: /api/chat, /api/generate (streaming and not), /api/show,
+// /api/tags and /api/version. It is safe for concurrent use.
+type Server struct {
+	// URL is set by Start.
+	URL string
+
+	mu       sync.Mutex
+	script   Script
+	used     []int
+	requests []Request
+}
+
+// New builds a stub for script.
+func New(script Script) *Server {
+	return &Server{script: script, used: make([]int, len(script.Rules))}
+}
+
+// Start serves a stub on a loopback port until the test ends.
+func Start(t testing.TB, script Script) *Server {
+	t.Helper()
+	stub := New(script)
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	stub.URL = srv.URL
+	return stub
+}
+
+// Requests returns the model calls received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/tags":
+		s.handleTags(w)
+	case "/api/show":
+		s.handleShow(w, r)
+	case "/api/version":
+		writeJSON(w, map[string]string{"version": "0.0.0-stub"})
+	case "/api/chat", "/api/generate":
+		s.handleModel(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleTags(w http.ResponseWriter) {
+	models := s.models()
+	type model struct {
+		Name string `json:"name"`
+	}
+	payload := struct {
+		Models []model `json:"models"`
+	}{}
+	for _, name := range models {
+		payload.Models = append(payload.Models, model{Name: name})
+	}
+	writeJSON(w, payload)
+}
+
+func (s *Server) models() []string {
+	if len(s.script.Models) == 0 {
+		return []string{"stub"}
+	}
+	return s.script.Models
+}
+
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	known := false
+	for _, name := range s.models() {
+		known = known || name == body.Model
+	}
+	if !known {
+		http.Error(w, fmt.Sprintf(`{"error":"model '%s' not found"}`, body.Model), http.StatusNotFound)
+		return
+	}
+	info := map[string]interface{}{"general.architecture": "stub"}
+	if s.script.ContextLength > 0 {
+		info["stub.context_length"] = s.script.ContextLength
+	}
+	capabilities := s.script.Capabilities
+	if len(capabilities) == 0 {
+		capabilities = []string{"completion", "tools"}
+	}
+	writeJSON(w, map[string]interface{}{"model_info": info, "capabilities": capabilities})
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Model    string    `json:"model"`
+		Prompt   string    `json:"prompt"`
+		Stream   bool      `json:"stream"`
+		Messages []Message `json:"messages"`
+		Tools    []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := Request{Path: r.URL.Path, Model: body.Model, Prompt: body.Prompt, Messages: body.Messages}
+	for _, tool := range body.Tools {
+		req.Tools = append(req.Tools, tool.Function.Name)
+	}
+	reply, seq, ok := s.answer(req)
+	if !ok {
+		http.Error(w, "ollamatest: no rule matched", http.StatusInternalServerError)
+		return
+	}
+	if reply.Status != 0 {
+		http.Error(w, reply.Text, reply.Status)
+		return
+	}
+	usage := map[string]int{"prompt_eval_count": len(lastText(req)) / 4, "eval_count": len(reply.Text) / 4}
+	if r.URL.Path == "/api/generate" {
+		if body.Stream {
+			writeStream(w, reply.Text)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"model":             body.Model,
+			"response":          reply.Text,
+			"done":              true,
+			"done_reason":       "stop",
+			"prompt_eval_count": usage["prompt_eval_count"],
+			"eval_count":        usage["eval_count"],
+		})
+		return
+	}
+	message := map[string]interface{}{"role": "assistant", "content": reply.Text}
+	if len(reply.ToolCalls) > 0 {
+		calls := make([]map[string]interface{}, 0, len(reply.ToolCalls))
+		for i, call := range reply.ToolCalls {
+			args := call.Args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			calls = append(calls, map[string]interface{}{
+				"id":       fmt.Sprintf("call_%d_%d", seq, i),
+				"function": map[string]interface{}{"name": call.Name, "arguments": args},
+			})
+		}
+		message["tool_calls"] = calls
+	}
+	writeJSON(w, map[string]interface{}{
+		"model":             body.Model,
+		"message":           message,
+		"done":              true,
+		"done_reason":       "stop",
+		"prompt_eval_count": usage["prompt_eval_count"],
+		"eval_count":        usage["eval_count"],
+	})
+}
+
+// answer records req and picks its reply; seq numbers requests from one.
+func (s *Server) answer(req Request) (Reply, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	seq := len(s.requests)
+	text := lastText(req)
+	for i, rule := range s.script.Rules {
+		if rule.Times > 0 && s.used[i] >= rule.Times {
+			continue
+		}
+		if rule.Match != "" && !strings.Contains(text, rule.Match) {
+			continue
+		}
+		s.used[i]++
+		return rule.Reply, seq, true
+	}
+	if s.script.Fallback != nil {
+		return *s.script.Fallback, seq, true
+	}
+	return Reply{}, seq, false
+}
+
+func lastText(req Request) string {
+	if len(req.Messages) == 0 {
+		return req.Prompt
+	}
+	return req.Messages[len(req.Messages)-1].Content
+}
+
+// writeStream emits text as Ollama's newline-delimited chunks.
+func writeStream(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, word := range strings.SplitAfter(text, " ") {
+		if word == "" {
+			continue
+		}
+		_ = enc.Encode(map[string]interface{}{"response": word, "done": false})
+	}
+	_ = enc.Encode(map[string]interface{}{"response": "", "done": true, "done_reason": "stop"})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
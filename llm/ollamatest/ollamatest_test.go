@@ -0,0 +1,47 @@
+package ollamatest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm"
+)
+
+func TestServerScriptsClientCalls(t *testing.T) {
+	stub := Start(t, Script{
+		Rules: []Rule{
+			{Match: "write", Times: 1, Reply: Reply{ToolCalls: []ToolCall{{Name: "file_write", Args: map[string]interface{}{"path": "a.txt"}}}}},
+			{Match: "boom", Reply: Reply{Status: http.StatusServiceUnavailable, Text: "loading model"}},
+		},
+		Fallback: &Reply{Text: "done"},
+	})
+	client := llm.NewClient(stub.URL, "stub")
+	ctx := context.Background()
+
+	resp, err := client.ChatWithTools(ctx, []framework.Message{{Role: "user", Content: "please write a.txt"}}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	require.Equal(t, "file_write", resp.ToolCalls[0].Name)
+	require.Equal(t, "a.txt", resp.ToolCalls[0].Args["path"])
+
+	resp, err = client.Chat(ctx, []framework.Message{{Role: "user", Content: "please write again"}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "done", resp.Text, "rule with Times: 1 is spent")
+
+	resp, err = client.Generate(ctx, "summarize", nil)
+	require.NoError(t, err)
+	require.Equal(t, "done", resp.Text)
+	require.NotZero(t, resp.Usage["prompt_tokens"])
+
+	_, err = client.Generate(ctx, "boom", nil)
+	require.Equal(t, framework.RetryServer, framework.ClassifyError(err))
+
+	requests := stub.Requests()
+	require.Len(t, requests, 4)
+	require.Equal(t, "/api/chat", requests[0].Path)
+	require.Equal(t, "summarize", requests[2].Prompt)
+}
@@ -0,0 +1,325 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// errPoolEmpty is returned by a Pool without members.
+var errPoolEmpty = errors.New("ollama pool has no endpoints")
+
+// DefaultPoolCooldown is how long a Pool skips an endpoint after a request
+// to it failed, when no health check has seen it recover sooner.
+const DefaultPoolCooldown = 30 * time.Second
+
+// Pool spreads one model's requests over several Ollama endpoints, such as a
+// laptop and a LAN GPU box. Each request goes to the healthy endpoint with
+// the fewest requests in flight, ties going to the earlier endpoint. When
+// an endpoint cannot be reached the request is retried on the next one, so
+// a task keeps running when a machine drops out mid-task; the endpoint is
+// then skipped until a health check or Cooldown lets it back in.
+//
+// Members take Model, ContextLength, and KeepAlive from Primary before each
+// request, so switching or resizing the primary model applies to the pool.
+type Pool struct {
+	Primary *Client
+	// Cooldown defaults to DefaultPoolCooldown.
+	Cooldown time.Duration
+	// Logger, when set, reports endpoints going down and recovering.
+	Logger *log.Logger
+
+	mu      sync.Mutex
+	members []*poolMember
+	now     func() time.Time
+}
+
+type poolMember struct {
+	client    *Client
+	inFlight  int
+	down      bool
+	downSince time.Time
+	lastErr   error
+}
+
+// PoolEndpoint is one endpoint's state, as reported by Pool.Endpoints.
+type PoolEndpoint struct {
+	Endpoint  string `json:"endpoint"`
+	Healthy   bool   `json:"healthy"`
+	InFlight  int    `json:"in_flight"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewPool builds a pool of primary and others. Endpoints are tried in that
+// order when equally busy.
+func NewPool(primary *Client, others ...*Client) *Pool {
+	p := &Pool{Primary: primary, now: time.Now}
+	for _, client := range append([]*Client{primary}, others...) {
+		p.members = append(p.members, &poolMember{client: client})
+	}
+	return p
+}
+
+// Endpoints reports every member's health and load.
+func (p *Pool) Endpoints() []PoolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]PoolEndpoint, 0, len(p.members))
+	for _, m := range p.members {
+		status := PoolEndpoint{Endpoint: m.client.Endpoint, Healthy: !m.down, InFlight: m.inFlight}
+		if m.lastErr != nil {
+			status.LastError = m.lastErr.Error()
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+func (p *Pool) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return poolCall(p, ctx, func(c *Client) (*framework.LLMResponse, error) {
+		return c.Generate(ctx, prompt, options)
+	})
+}
+
+// GenerateStream fails over only while opening the stream; a stream cut off
+// midway ends early like it would against a single endpoint.
+func (p *Pool) GenerateStream(ctx context.Context, prompt string, options *framework.LLMOptions) (<-chan string, error) {
+	return poolCall(p, ctx, func(c *Client) (<-chan string, error) {
+		return c.GenerateStream(ctx, prompt, options)
+	})
+}
+
+func (p *Pool) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return poolCall(p, ctx, func(c *Client) (*framework.LLMResponse, error) {
+		return c.Chat(ctx, messages, options)
+	})
+}
+
+func (p *Pool) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return poolCall(p, ctx, func(c *Client) (*framework.LLMResponse, error) {
+		return c.ChatWithTools(ctx, messages, tools, options)
+	})
+}
+
+// poolCall runs call on the least busy member, moving on to the next member
+// while the failure is the endpoint's rather than the request's. Failed
+// endpoints are recorded on ctx's endpoint trace.
+func poolCall[T any](p *Pool, ctx context.Context, call func(*Client) (T, error)) (T, error) {
+	tried := make(map[*poolMember]bool, len(p.members))
+	var (
+		out     T
+		lastErr error
+	)
+	for {
+		m := p.acquire(tried)
+		if m == nil {
+			if lastErr == nil {
+				lastErr = errPoolEmpty
+			}
+			return out, lastErr
+		}
+		tried[m] = true
+		out, lastErr = call(m.client)
+		failover := lastErr != nil && ctx.Err() == nil && framework.ErrorKindOf(lastErr) == framework.ErrorKindLLMUnavailable
+		p.release(m, failover, lastErr)
+		if !failover {
+			return out, lastErr
+		}
+		noteFailedEndpoint(ctx, m.client.Endpoint)
+	}
+}
+
+// acquire picks the member for the next attempt and counts it in flight.
+// Members that are down sort after every healthy one, so they are only
+// tried once the rest have failed.
+func (p *Pool) acquire(tried map[*poolMember]bool) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	cooldown := p.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultPoolCooldown
+	}
+	var best *poolMember
+	bestDown := false
+	for _, m := range p.members {
+		if tried[m] {
+			continue
+		}
+		down := m.down && now.Sub(m.downSince) < cooldown
+		switch {
+		case best == nil,
+			bestDown && !down,
+			bestDown == down && m.inFlight < best.inFlight:
+			best, bestDown = m, down
+		}
+	}
+	if best != nil {
+		best.inFlight++
+		p.syncMember(best.client)
+	}
+	return best
+}
+
+func (p *Pool) syncMember(c *Client) {
+	if c == p.Primary {
+		return
+	}
+	if c.Model != p.Primary.Model {
+		c.Model = p.Primary.Model
+	}
+	if c.ContextLength != p.Primary.ContextLength {
+		c.ContextLength = p.Primary.ContextLength
+	}
+	if c.KeepAlive != p.Primary.KeepAlive {
+		c.KeepAlive = p.Primary.KeepAlive
+	}
+}
+
+func (p *Pool) release(m *poolMember, failed bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m.inFlight--
+	if failed {
+		p.markLocked(m, err)
+	} else if err == nil {
+		p.markLocked(m, nil)
+	}
+}
+
+// markLocked records m's health, logging changes.
+func (p *Pool) markLocked(m *poolMember, err error) {
+	if err != nil {
+		if !m.down && p.Logger != nil {
+			p.Logger.Printf("ollama endpoint %s unavailable: %v", m.client.Endpoint, err)
+		}
+		m.down = true
+		m.downSince = p.now()
+		m.lastErr = err
+		return
+	}
+	if m.down && p.Logger != nil {
+		p.Logger.Printf("ollama endpoint %s recovered", m.client.Endpoint)
+	}
+	m.down = false
+	m.lastErr = nil
+}
+
+// Check pings every endpoint once and records which respond.
+func (p *Pool) Check(ctx context.Context) {
+	p.mu.Lock()
+	members := append([]*poolMember(nil), p.members...)
+	p.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, m := range members {
+		wg.Add(1)
+		go func(m *poolMember) {
+			defer wg.Done()
+			err := m.client.Ping(ctx)
+			// A ping cut off by ctx's deadline counts against the
+			// endpoint; one cut off by cancellation does not.
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			p.mu.Lock()
+			p.markLocked(m, err)
+			p.mu.Unlock()
+		}(m)
+	}
+	wg.Wait()
+}
+
+// Watch runs Check every interval until ctx ends.
+func (p *Pool) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		p.Check(checkCtx)
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ping checks that Ollama answers at the client's endpoint.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+"/api/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return unavailable(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+type endpointTraceKey struct{}
+
+// endpointTrace collects which endpoints served a model call, for the
+// call's telemetry.
+type endpointTrace struct {
+	mu       sync.Mutex
+	endpoint string
+	failed   []string
+}
+
+// withEndpointTrace attaches a fresh trace to ctx.
+func withEndpointTrace(ctx context.Context) (context.Context, *endpointTrace) {
+	trace := &endpointTrace{}
+	return context.WithValue(ctx, endpointTraceKey{}, trace), trace
+}
+
+func endpointTraceFrom(ctx context.Context) *endpointTrace {
+	trace, _ := ctx.Value(endpointTraceKey{}).(*endpointTrace)
+	return trace
+}
+
+// noteEndpoint records the endpoint a client sent ctx's request to.
+func noteEndpoint(ctx context.Context, endpoint string) {
+	if trace := endpointTraceFrom(ctx); trace != nil {
+		trace.mu.Lock()
+		trace.endpoint = endpoint
+		trace.mu.Unlock()
+	}
+}
+
+func noteFailedEndpoint(ctx context.Context, endpoint string) {
+	if trace := endpointTraceFrom(ctx); trace != nil {
+		trace.mu.Lock()
+		trace.failed = append(trace.failed, endpoint)
+		trace.mu.Unlock()
+	}
+}
+
+// annotate adds "endpoint" and, after a failover, "failed_endpoints" to
+// telemetry metadata.
+func (t *endpointTrace) annotate(metadata map[string]interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.endpoint != "" {
+		metadata["endpoint"] = t.endpoint
+	}
+	if len(t.failed) > 0 {
+		metadata["failed_endpoints"] = append([]string(nil), t.failed...)
+	}
+}
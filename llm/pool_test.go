@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []framework.Event
+}
+
+func (r *eventRecorder) Emit(event framework.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) responses() []framework.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []framework.Event
+	for _, event := range r.events {
+		if event.Type == framework.EventLLMResponse {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// TestPoolFailsOverAndRecordsEndpoint sends a request while the primary
+// endpoint is down: it is answered by the second endpoint, telemetry names
+// both, and the primary is skipped until a health check sees it again.
+func TestPoolFailsOverAndRecordsEndpoint(t *testing.T) {
+	fallback := &ollamatest.Reply{Text: "ok"}
+	down := httptest.NewServer(ollamatest.New(ollamatest.Script{Fallback: fallback}))
+	downURL := down.URL
+	down.Close()
+	backup := ollamatest.Start(t, ollamatest.Script{Fallback: fallback})
+
+	primary := NewClient(downURL, "stub")
+	pool := NewPool(primary, NewClient(backup.URL, "other"))
+	events := &eventRecorder{}
+	model := NewInstrumentedModel(pool, events, false)
+
+	resp, err := model.Chat(context.Background(), []framework.Message{{Role: "user", Content: "hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Text)
+	requests := backup.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "stub", requests[0].Model, "members use the primary's model")
+
+	responses := events.responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, backup.URL, responses[0].Metadata["endpoint"])
+	assert.Equal(t, []string{downURL}, responses[0].Metadata["failed_endpoints"])
+
+	status := pool.Endpoints()
+	assert.False(t, status[0].Healthy)
+	assert.NotEmpty(t, status[0].LastError)
+	assert.True(t, status[1].Healthy)
+
+	_, err = model.Chat(context.Background(), []framework.Message{{Role: "user", Content: "again"}}, nil)
+	require.NoError(t, err)
+	assert.Len(t, backup.Requests(), 2)
+	assert.NotContains(t, events.responses()[1].Metadata, "failed_endpoints", "a down endpoint is not retried within the cooldown")
+
+	primary.Endpoint = ollamatest.Start(t, ollamatest.Script{Fallback: fallback}).URL
+	pool.Check(context.Background())
+	assert.True(t, pool.Endpoints()[0].Healthy)
+}
+
+// TestPoolRoutesToLeastBusyEndpoint prefers the idle endpoint and keeps
+// request errors from marking endpoints down.
+func TestPoolRoutesToLeastBusyEndpoint(t *testing.T) {
+	first := ollamatest.Start(t, ollamatest.Script{Fallback: &ollamatest.Reply{Text: "first"}})
+	second := ollamatest.Start(t, ollamatest.Script{Fallback: &ollamatest.Reply{Status: 400, Text: "bad request"}})
+	pool := NewPool(NewClient(first.URL, "stub"), NewClient(second.URL, "stub"))
+
+	pool.members[0].inFlight = 1
+	_, err := pool.Generate(context.Background(), "hi", nil)
+	require.Error(t, err)
+	assert.Len(t, second.Requests(), 1)
+	assert.Empty(t, first.Requests(), "a 400 is the request's fault and is not retried elsewhere")
+	assert.True(t, pool.Endpoints()[1].Healthy)
+
+	pool.members[0].inFlight = 0
+	resp, err := pool.Generate(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", resp.Text)
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// RecordingModel appends every call and its response to a task's replay
+// log; see framework.ReplayRecorder.
+type RecordingModel struct {
+	Inner    framework.LanguageModel
+	Recorder *framework.ReplayRecorder
+}
+
+func (m *RecordingModel) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	resp, err := m.Inner.Generate(ctx, prompt, options)
+	m.record(ctx, "generate", generateRequest(prompt), prompt, resp, err)
+	return resp, err
+}
+
+// GenerateStream is not recorded; replays fail if the task streams.
+func (m *RecordingModel) GenerateStream(ctx context.Context, prompt string, options *framework.LLMOptions) (<-chan string, error) {
+	return m.Inner.GenerateStream(ctx, prompt, options)
+}
+
+func (m *RecordingModel) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	resp, err := m.Inner.Chat(ctx, messages, options)
+	m.record(ctx, "chat", chatRequest(messages, nil), lastContent(messages), resp, err)
+	return resp, err
+}
+
+func (m *RecordingModel) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	resp, err := m.Inner.ChatWithTools(ctx, messages, tools, options)
+	m.record(ctx, "chat_with_tools", chatRequest(messages, tools), lastContent(messages), resp, err)
+	return resp, err
+}
+
+func (m *RecordingModel) record(ctx context.Context, method string, request map[string]interface{}, prompt string, resp *framework.LLMResponse, err error) {
+	digest, _ := cacheKey(request)
+	entry := framework.ReplayEntry{
+		Kind:     framework.ReplayKindModel,
+		Method:   method,
+		Digest:   digest,
+		Prompt:   clip(prompt, 4096),
+		Response: resp,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	m.Recorder.Record(ctx, entry)
+}
+
+// ReplayModel answers from a recording and never contacts a model.
+type ReplayModel struct {
+	Replayer *framework.Replayer
+}
+
+func (m *ReplayModel) Generate(ctx context.Context, prompt string, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.next("generate", generateRequest(prompt))
+}
+
+func (m *ReplayModel) GenerateStream(ctx context.Context, prompt string, options *framework.LLMOptions) (<-chan string, error) {
+	return nil, errors.New("replay: streaming calls are not recorded")
+}
+
+func (m *ReplayModel) Chat(ctx context.Context, messages []framework.Message, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.next("chat", chatRequest(messages, nil))
+}
+
+func (m *ReplayModel) ChatWithTools(ctx context.Context, messages []framework.Message, tools []framework.Tool, options *framework.LLMOptions) (*framework.LLMResponse, error) {
+	return m.next("chat_with_tools", chatRequest(messages, tools))
+}
+
+func (m *ReplayModel) next(method string, request map[string]interface{}) (*framework.LLMResponse, error) {
+	digest, _ := cacheKey(request)
+	return m.Replayer.NextModel(method, digest)
+}
+
+// generateRequest and chatRequest describe a call for its replay digest.
+// Unlike cache keys they leave out the model and options, so a replay with
+// another --ollama-model still lines up.
+func generateRequest(prompt string) map[string]interface{} {
+	return map[string]interface{}{"prompt": prompt}
+}
+
+func chatRequest(messages []framework.Message, tools []framework.Tool) map[string]interface{} {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name())
+	}
+	sort.Strings(names)
+	return map[string]interface{}{"messages": messages, "tools": names}
+}
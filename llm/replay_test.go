@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestReplayModelServesRecordedResponses(t *testing.T) {
+	dir := t.TempDir()
+	task := &framework.Task{ID: "task-1", Instruction: "plan"}
+	ctx := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: task.ID})
+	mock := &MockModel{Respond: func(call MockCall) *framework.LLMResponse {
+		return &framework.LLMResponse{Text: "answer to " + call.Prompt}
+	}}
+	recorder := framework.NewReplayRecorder(dir)
+	require.NoError(t, recorder.StartTask(task, framework.ModelContext{}))
+	recording := &RecordingModel{Inner: mock, Recorder: recorder}
+	_, err := recording.Generate(ctx, "first", nil)
+	require.NoError(t, err)
+	_, err = recording.Chat(ctx, []framework.Message{{Role: "user", Content: "second"}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, recorder.FinishTask(task.ID))
+
+	replayer, err := framework.LoadReplay(framework.ReplayPath(dir, task.ID))
+	require.NoError(t, err)
+	replay := &ReplayModel{Replayer: replayer}
+	resp, err := replay.Generate(ctx, "first", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "answer to first", resp.Text)
+	resp, err = replay.Chat(ctx, []framework.Message{{Role: "user", Content: "edited"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "answer to second", resp.Text, "responses are served in order")
+	assert.Len(t, replayer.Mismatches(), 1)
+	assert.Equal(t, 2, mock.Calls(), "replays never reach the model")
+
+	_, err = replay.Chat(ctx, nil, nil)
+	assert.ErrorIs(t, err, framework.ErrReplayDiverged)
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig bounds the load one model endpoint sees. Zero fields are
+// unlimited.
+type ThrottleConfig struct {
+	MaxConcurrent     int
+	RequestsPerMinute int
+}
+
+func (c ThrottleConfig) enabled() bool {
+	return c.MaxConcurrent > 0 || c.RequestsPerMinute > 0
+}
+
+// Throttle caps concurrent requests and spaces them to a per-minute rate.
+// Callers over the cap queue until a slot frees up or their context ends. A
+// nil Throttle lets every request through.
+type Throttle struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+	now  func() time.Time
+}
+
+// NewThrottle builds a Throttle, or returns nil when cfg sets no limit.
+func NewThrottle(cfg ThrottleConfig) *Throttle {
+	if !cfg.enabled() {
+		return nil
+	}
+	t := &Throttle{now: time.Now}
+	if cfg.MaxConcurrent > 0 {
+		t.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	if cfg.RequestsPerMinute > 0 {
+		t.interval = time.Minute / time.Duration(cfg.RequestsPerMinute)
+	}
+	return t
+}
+
+type throttleKey struct {
+	endpoint string
+	config   ThrottleConfig
+}
+
+var (
+	sharedMu        sync.Mutex
+	sharedThrottles = map[throttleKey]*Throttle{}
+)
+
+// SharedThrottle returns the process-wide Throttle for endpoint, so every
+// client of one Ollama instance (the session model, routed models, the
+// embeddings index, and server jobs) draws from the same limits.
+func SharedThrottle(endpoint string, cfg ThrottleConfig) *Throttle {
+	if !cfg.enabled() {
+		return nil
+	}
+	key := throttleKey{endpoint: strings.TrimRight(endpoint, "/"), config: cfg}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	t, ok := sharedThrottles[key]
+	if !ok {
+		t = NewThrottle(cfg)
+		sharedThrottles[key] = t
+	}
+	return t
+}
+
+// Acquire waits for a request slot. Call release once the response has been
+// read. It returns ctx.Err() if ctx ends while queued.
+func (t *Throttle) Acquire(ctx context.Context) (release func(), err error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	free := func() {
+		if t.slots != nil {
+			<-t.slots
+		}
+	}
+	if err := t.waitTurn(ctx); err != nil {
+		free()
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(free) }, nil
+}
+
+// waitTurn reserves the next start time allowed by the rate and sleeps until
+// it. A caller that gives up hands its reservation back when no later one
+// was made.
+func (t *Throttle) waitTurn(ctx context.Context) error {
+	if t.interval <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := t.now()
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		if t.next.Equal(at.Add(t.interval)) {
+			t.next = at
+		}
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleQueuesOverConcurrencyCap(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{MaxConcurrent: 1})
+	release, err := throttle.Acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = throttle.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "queued callers give up with their context")
+
+	acquired := make(chan struct{})
+	go func() {
+		next, err := throttle.Acquire(context.Background())
+		if err == nil {
+			next()
+		}
+		close(acquired)
+	}()
+	release()
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("queued caller never acquired the freed slot")
+	}
+}
+
+func TestThrottleSpacesRequestsToRate(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{RequestsPerMinute: 1200}) // one per 50ms
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := throttle.Acquire(context.Background())
+		require.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.Nil(t, NewThrottle(ThrottleConfig{}), "no limits means no throttle")
+	assert.Same(t, SharedThrottle("http://ollama:11434/", ThrottleConfig{MaxConcurrent: 2}), SharedThrottle("http://ollama:11434", ThrottleConfig{MaxConcurrent: 2}))
+}
+
+func TestClientHonoursThrottle(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"response":"ok"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, "stub")
+	client.Throttle = NewThrottle(ThrottleConfig{MaxConcurrent: 1})
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := client.Generate(context.Background(), "hi", nil)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), peak.Load())
+}
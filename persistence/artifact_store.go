@@ -0,0 +1,180 @@
+package persistence
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// FileArtifactStore keeps an index of artifacts in artifacts.json and their
+// content under objects/, named by SHA-256 digest.
+type FileArtifactStore struct {
+	path    string
+	objects string
+	mu      sync.RWMutex
+	cache   map[string]framework.Artifact
+}
+
+var _ framework.ArtifactStore = (*FileArtifactStore)(nil)
+
+// NewFileArtifactStore creates a store under the provided directory.
+func NewFileArtifactStore(root string) (*FileArtifactStore, error) {
+	if root == "" {
+		return nil, errors.New("artifact store root required")
+	}
+	objects := filepath.Join(root, "objects")
+	if err := os.MkdirAll(objects, 0o755); err != nil {
+		return nil, err
+	}
+	store := &FileArtifactStore{
+		path:    filepath.Join(root, "artifacts.json"),
+		objects: objects,
+		cache:   make(map[string]framework.Artifact),
+	}
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []framework.Artifact
+	if err := json.Unmarshal(data, &artifacts); err != nil {
+		return nil, err
+	}
+	for _, artifact := range artifacts {
+		store.cache[artifact.ID] = artifact
+	}
+	return store, nil
+}
+
+// persist writes the index back to disk in creation order.
+func (s *FileArtifactStore) persist() error {
+	data, err := json.MarshalIndent(s.sorted(""), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+func (s *FileArtifactStore) sorted(taskID string) []framework.Artifact {
+	artifacts := make([]framework.Artifact, 0, len(s.cache))
+	for _, artifact := range s.cache {
+		if taskID == "" || artifact.TaskID == taskID {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		if !artifacts[i].CreatedAt.Equal(artifacts[j].CreatedAt) {
+			return artifacts[i].CreatedAt.Before(artifacts[j].CreatedAt)
+		}
+		return artifacts[i].ID < artifacts[j].ID
+	})
+	return artifacts
+}
+
+// PutArtifact implements framework.ArtifactStore. The ID is derived from the
+// task, name, and digest, so re-saving identical output is a no-op.
+func (s *FileArtifactStore) PutArtifact(ctx context.Context, artifact framework.Artifact, content []byte) (framework.Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return framework.Artifact{}, err
+	}
+	artifact.Name = strings.TrimSpace(artifact.Name)
+	if artifact.Name == "" {
+		return framework.Artifact{}, errors.New("artifact name required")
+	}
+	if artifact.Type == "" {
+		artifact.Type = framework.ArtifactTypeOther
+	}
+	sum := sha256.Sum256(content)
+	artifact.Digest = hex.EncodeToString(sum[:])
+	artifact.Size = int64(len(content))
+	if artifact.MediaType == "" {
+		artifact.MediaType = artifactMediaType(artifact.Name, content)
+	}
+	id := sha256.Sum256([]byte(artifact.TaskID + "\x00" + artifact.Name + "\x00" + artifact.Digest))
+	artifact.ID = hex.EncodeToString(id[:6])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.cache[artifact.ID]; ok {
+		return existing, nil
+	}
+	object := filepath.Join(s.objects, artifact.Digest)
+	if _, err := os.Stat(object); errors.Is(err, os.ErrNotExist) {
+		tmp := object + ".tmp"
+		if err := os.WriteFile(tmp, content, 0o644); err != nil {
+			return framework.Artifact{}, err
+		}
+		if err := os.Rename(tmp, object); err != nil {
+			return framework.Artifact{}, err
+		}
+	} else if err != nil {
+		return framework.Artifact{}, err
+	}
+	artifact.CreatedAt = time.Now().UTC()
+	s.cache[artifact.ID] = artifact
+	return artifact, s.persist()
+}
+
+// ListArtifacts implements framework.ArtifactStore.
+func (s *FileArtifactStore) ListArtifacts(ctx context.Context, taskID string) ([]framework.Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(taskID), nil
+}
+
+// LoadArtifact implements framework.ArtifactStore.
+func (s *FileArtifactStore) LoadArtifact(ctx context.Context, id string) (framework.Artifact, []byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return framework.Artifact{}, nil, false, err
+	}
+	s.mu.RLock()
+	artifact, ok := s.cache[id]
+	s.mu.RUnlock()
+	if !ok {
+		return framework.Artifact{}, nil, false, nil
+	}
+	content, err := os.ReadFile(filepath.Join(s.objects, artifact.Digest))
+	if err != nil {
+		return artifact, nil, true, fmt.Errorf("artifact %s content: %w", id, err)
+	}
+	return artifact, content, true, nil
+}
+
+// artifactMediaType guesses a media type from the name's extension, then
+// from the content. Text formats agents commonly write are mapped first,
+// since system MIME tables rarely know them.
+func artifactMediaType(name string, content []byte) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".md", ".markdown":
+		return "text/markdown; charset=utf-8"
+	case ".mmd", ".mermaid":
+		return "text/vnd.mermaid; charset=utf-8"
+	case ".dot", ".gv":
+		return "text/vnd.graphviz; charset=utf-8"
+	case ".yaml", ".yml":
+		return "application/yaml"
+	}
+	if byExt := mime.TypeByExtension(ext); byExt != "" {
+		return byExt
+	}
+	return http.DetectContentType(content)
+}
@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TestFileArtifactStoreDeduplicatesContent saves the same bytes for two
+// tasks, checks they share one object, and reloads the index.
+func TestFileArtifactStoreDeduplicatesContent(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewFileArtifactStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	report := []byte("# Coverage\n\n81%\n")
+	first, err := store.PutArtifact(ctx, framework.Artifact{TaskID: "task-1", Name: "coverage.md", Type: framework.ArtifactTypeReport}, report)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if first.ID == "" || first.Size != int64(len(report)) || first.MediaType != "text/markdown; charset=utf-8" {
+		t.Fatalf("unexpected artifact: %+v", first)
+	}
+	again, err := store.PutArtifact(ctx, framework.Artifact{TaskID: "task-1", Name: "coverage.md", Type: framework.ArtifactTypeReport}, report)
+	if err != nil || again.ID != first.ID || !again.CreatedAt.Equal(first.CreatedAt) {
+		t.Fatalf("re-saving identical output should return the existing artifact, got %+v, %v", again, err)
+	}
+	second, err := store.PutArtifact(ctx, framework.Artifact{TaskID: "task-2", Name: "coverage.md"}, report)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if second.ID == first.ID || second.Digest != first.Digest || second.Type != framework.ArtifactTypeOther {
+		t.Fatalf("unexpected second artifact: %+v", second)
+	}
+	objects, err := os.ReadDir(filepath.Join(root, "objects"))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("expected one stored object, got %d (%v)", len(objects), err)
+	}
+	if _, err := store.PutArtifact(ctx, framework.Artifact{TaskID: "task-1"}, report); err == nil {
+		t.Fatal("expected an error for a missing name")
+	}
+
+	reopened, err := NewFileArtifactStore(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	listed, err := reopened.ListArtifacts(ctx, "task-1")
+	if err != nil || len(listed) != 1 || listed[0].ID != first.ID {
+		t.Fatalf("ListArtifacts(task-1) = %+v, %v", listed, err)
+	}
+	all, err := reopened.ListArtifacts(ctx, "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListArtifacts() = %+v, %v", all, err)
+	}
+	artifact, content, ok, err := reopened.LoadArtifact(ctx, second.ID)
+	if err != nil || !ok || string(content) != string(report) || artifact.TaskID != "task-2" {
+		t.Fatalf("LoadArtifact() = %+v, %q, %v, %v", artifact, content, ok, err)
+	}
+	if _, _, ok, err := reopened.LoadArtifact(ctx, "missing"); ok || err != nil {
+		t.Fatalf("missing artifact: ok=%v err=%v", ok, err)
+	}
+}
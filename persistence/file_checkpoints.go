@@ -0,0 +1,228 @@
+package persistence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileCheckpoint records the files one job modified and what they held
+// before the job first wrote to them.
+type FileCheckpoint struct {
+	JobID        string             `json:"job_id"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Files        []CheckpointedFile `json:"files"`
+	RolledBackAt *time.Time         `json:"rolled_back_at,omitempty"`
+}
+
+// CheckpointedFile is one file's pre-job state. Hash names the stored
+// content; an empty Hash means the job created the file, so rolling back
+// removes it.
+type CheckpointedFile struct {
+	Path string      `json:"path"`
+	Hash string      `json:"hash,omitempty"`
+	Mode fs.FileMode `json:"mode,omitempty"`
+}
+
+// FileCheckpointStore snapshots files before agents modify them. Contents
+// are stored once under objects/<sha256>, and each job's file list under
+// jobs/<job-id>.json, so unchanged files shared by many jobs cost nothing.
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCheckpointStore creates the store directories under dir.
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	for _, sub := range []string{"objects", "jobs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+// Snapshot saves path's current content for jobID unless the job already
+// snapshotted it, so the checkpoint always holds the pre-job version.
+func (s *FileCheckpointStore) Snapshot(jobID, path string) error {
+	if jobID == "" {
+		return errors.New("job id required")
+	}
+	path = filepath.Clean(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, err := s.load(jobID)
+	if errors.Is(err, fs.ErrNotExist) {
+		checkpoint = &FileCheckpoint{JobID: jobID, CreatedAt: time.Now().UTC()}
+	} else if err != nil {
+		return err
+	}
+	for _, file := range checkpoint.Files {
+		if file.Path == path {
+			return nil
+		}
+	}
+	entry := CheckpointedFile{Path: path}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case info.IsDir():
+		return nil
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if entry.Hash, err = s.storeObject(data); err != nil {
+			return err
+		}
+		entry.Mode = info.Mode().Perm()
+	}
+	checkpoint.Files = append(checkpoint.Files, entry)
+	return s.save(checkpoint)
+}
+
+// Load returns the checkpoint recorded for jobID.
+func (s *FileCheckpointStore) Load(jobID string) (*FileCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(jobID)
+}
+
+// List returns every checkpoint, newest first.
+func (s *FileCheckpointStore) List() ([]FileCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(filepath.Join(s.dir, "jobs"))
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]FileCheckpoint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		checkpoint, err := s.loadFile(filepath.Join(s.dir, "jobs", entry.Name()))
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, *checkpoint)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].CreatedAt.After(checkpoints[j].CreatedAt) })
+	return checkpoints, nil
+}
+
+// Rollback restores every file jobID modified to its pre-job content and
+// removes the files it created. It returns the paths it restored or removed
+// and keeps going past individual failures, reporting them together.
+func (s *FileCheckpointStore) Rollback(jobID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, err := s.load(jobID)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint for job %s", jobID)
+		}
+		return nil, err
+	}
+	var restored []string
+	var errs []error
+	for i := len(checkpoint.Files) - 1; i >= 0; i-- {
+		file := checkpoint.Files[i]
+		if err := s.restore(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Path, err))
+			continue
+		}
+		restored = append(restored, file.Path)
+	}
+	sort.Strings(restored)
+	now := time.Now().UTC()
+	checkpoint.RolledBackAt = &now
+	if err := s.save(checkpoint); err != nil {
+		errs = append(errs, err)
+	}
+	return restored, errors.Join(errs...)
+}
+
+func (s *FileCheckpointStore) restore(file CheckpointedFile) error {
+	if file.Hash == "" {
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, "objects", file.Hash))
+	if err != nil {
+		return err
+	}
+	mode := file.Mode
+	if mode == 0 {
+		mode = 0o644
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file.Path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(file.Path, mode)
+}
+
+func (s *FileCheckpointStore) storeObject(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.dir, "objects", hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	return hash, os.Rename(tmp, path)
+}
+
+func (s *FileCheckpointStore) jobPath(jobID string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(jobID)
+	return filepath.Join(s.dir, "jobs", name+".json")
+}
+
+func (s *FileCheckpointStore) load(jobID string) (*FileCheckpoint, error) {
+	return s.loadFile(s.jobPath(jobID))
+}
+
+func (s *FileCheckpointStore) loadFile(path string) (*FileCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint FileCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (s *FileCheckpointStore) save(checkpoint *FileCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.jobPath(checkpoint.JobID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
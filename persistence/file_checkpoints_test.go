@@ -0,0 +1,90 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFileCheckpointRollbackRestoresAndRemoves checks rollback brings back
+// the pre-job content of modified files and deletes files the job created.
+func TestFileCheckpointRollbackRestoresAndRemoves(t *testing.T) {
+	ws := t.TempDir()
+	store, err := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	existing := filepath.Join(ws, "main.go")
+	created := filepath.Join(ws, "pkg", "new.go")
+	if err := os.WriteFile(existing, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Snapshot("job-1", existing); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := os.WriteFile(existing, []byte("first edit"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A second write in the same job must not replace the original snapshot.
+	if err := store.Snapshot("job-1", existing); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := os.WriteFile(existing, []byte("second edit"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Snapshot("job-1", created); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(created), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(created, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := store.Load("job-1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(checkpoint.Files) != 2 {
+		t.Fatalf("expected 2 checkpointed files, got %+v", checkpoint.Files)
+	}
+
+	restored, err := store.Rollback("job-1")
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("expected 2 restored paths, got %v", restored)
+	}
+	data, err := os.ReadFile(existing)
+	if err != nil || string(data) != "original" {
+		t.Fatalf("expected original content, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v (%v)", info, err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("expected created file removed, got %v", err)
+	}
+
+	checkpoints, err := store.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].RolledBackAt == nil {
+		t.Fatalf("expected one rolled back checkpoint, got %+v", checkpoints)
+	}
+}
+
+// TestFileCheckpointRollbackUnknownJob checks a missing job is an error.
+func TestFileCheckpointRollbackUnknownJob(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if _, err := store.Rollback("missing"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+}
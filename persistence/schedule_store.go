@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Schedule submits a task, or a recipe with parameters, whenever its cron
+// expression fires.
+type Schedule struct {
+	ID   string `json:"id"`
+	Cron string `json:"cron"`
+	// Instruction, Type, and Context describe the task. They are ignored
+	// when Recipe is set.
+	Instruction string                 `json:"instruction,omitempty"`
+	Type        framework.TaskType     `json:"type,omitempty"`
+	Context     map[string]interface{} `json:"context,omitempty"`
+	Recipe      string                 `json:"recipe,omitempty"`
+	Params      map[string]string      `json:"params,omitempty"`
+	Paused      bool                   `json:"paused"`
+	CreatedAt   time.Time              `json:"created_at"`
+	LastRunAt   *time.Time             `json:"last_run_at,omitempty"`
+	LastTaskID  string                 `json:"last_task_id,omitempty"`
+	// ResumedAt is when the schedule was last unpaused; runs due while it
+	// was paused are skipped.
+	ResumedAt *time.Time `json:"resumed_at,omitempty"`
+}
+
+// ScheduleStore persists schedules between server runs.
+type ScheduleStore interface {
+	Save(ctx context.Context, schedule *Schedule) error
+	Load(ctx context.Context, id string) (*Schedule, bool, error)
+	List(ctx context.Context) ([]Schedule, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// FileScheduleStore stores schedules as JSON on disk.
+type FileScheduleStore struct {
+	path  string
+	mu    sync.RWMutex
+	cache map[string]Schedule
+}
+
+// NewFileScheduleStore creates a store under the provided directory.
+func NewFileScheduleStore(root string) (*FileScheduleStore, error) {
+	if root == "" {
+		return nil, errors.New("schedule store root required")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	store := &FileScheduleStore{
+		path:  filepath.Join(root, "schedules.json"),
+		cache: make(map[string]Schedule),
+	}
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		store.cache[schedule.ID] = schedule
+	}
+	return store, nil
+}
+
+// persist writes the cached schedules back to disk, sorted by ID so the
+// file diffs cleanly.
+func (s *FileScheduleStore) persist() error {
+	schedules := s.sorted()
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+func (s *FileScheduleStore) sorted() []Schedule {
+	schedules := make([]Schedule, 0, len(s.cache))
+	for _, schedule := range s.cache {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules
+}
+
+// Save creates or replaces a schedule.
+func (s *FileScheduleStore) Save(ctx context.Context, schedule *Schedule) error {
+	if schedule == nil || schedule.ID == "" {
+		return errors.New("schedule id required")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[schedule.ID] = *schedule
+	return s.persist()
+}
+
+// Load retrieves a schedule by ID.
+func (s *FileScheduleStore) Load(ctx context.Context, id string) (*Schedule, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	schedule, ok := s.cache[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &schedule, true, nil
+}
+
+// List returns all schedules sorted by ID.
+func (s *FileScheduleStore) List(ctx context.Context) ([]Schedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(), nil
+}
+
+// Delete removes a schedule.
+func (s *FileScheduleStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, id)
+	return s.persist()
+}
@@ -0,0 +1,233 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// SessionTask records one instruction executed during a chat session.
+type SessionTask struct {
+	ID          string                 `json:"id"`
+	Instruction string                 `json:"instruction"`
+	Success     bool                   `json:"success"`
+	Error       string                 `json:"error,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	CompletedAt time.Time              `json:"completed_at"`
+}
+
+// SessionRecord persists a chat session so it can be resumed later. The
+// transcript is owned by the shell that wrote it and is stored verbatim.
+type SessionRecord struct {
+	ID            string                     `json:"id"`
+	Title         string                     `json:"title,omitempty"`
+	Workspace     string                     `json:"workspace"`
+	Agent         string                     `json:"agent,omitempty"`
+	Model         string                     `json:"model,omitempty"`
+	Mode          string                     `json:"mode,omitempty"`
+	Strategy      string                     `json:"strategy,omitempty"`
+	Metadata      map[string]string          `json:"metadata,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+	Messages      json.RawMessage            `json:"messages,omitempty"`
+	Tasks         []SessionTask              `json:"tasks,omitempty"`
+	Context       *framework.ContextSnapshot `json:"context,omitempty"`
+	ContextFiles  []string                   `json:"context_files,omitempty"`
+	TotalTokens   int                        `json:"total_tokens"`
+	TotalDuration time.Duration              `json:"total_duration"`
+}
+
+// SessionStore persists chat sessions between shell runs.
+type SessionStore interface {
+	Save(ctx context.Context, record *SessionRecord) error
+	Load(ctx context.Context, id string) (*SessionRecord, bool, error)
+	List(ctx context.Context) ([]SessionRecord, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// FileSessionStore keeps one JSON file per session so large transcripts do
+// not force rewriting every other session on save.
+type FileSessionStore struct {
+	root string
+	mu   sync.RWMutex
+}
+
+// NewFileSessionStore builds a store in the provided root directory.
+func NewFileSessionStore(root string) (*FileSessionStore, error) {
+	if root == "" {
+		return nil, errors.New("session store root required")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSessionStore{root: root}, nil
+}
+
+// pathFor builds the JSON file path for a session, rejecting IDs that would
+// escape the store directory.
+func (s *FileSessionStore) pathFor(id string) (string, error) {
+	if id == "" {
+		return "", errors.New("session id required")
+	}
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(s.root, id+".session.json"), nil
+}
+
+// Save writes the session to disk, stamping UpdatedAt.
+func (s *FileSessionStore) Save(ctx context.Context, record *SessionRecord) error {
+	if record == nil {
+		return errors.New("nil session")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	path, err := s.pathFor(record.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+	stored := *record
+	stored.Context = portableSnapshot(record.Context)
+	stored.Tasks = make([]SessionTask, len(record.Tasks))
+	for i, task := range record.Tasks {
+		if task.Data != nil {
+			task.Data = portableValues(task.Data)
+		}
+		stored.Tasks[i] = task
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load retrieves a session by ID.
+func (s *FileSessionStore) Load(ctx context.Context, id string) (*SessionRecord, bool, error) {
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	default:
+	}
+	path, err := s.pathFor(id)
+	if err != nil {
+		return nil, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, err := readSession(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+// List returns every stored session, most recently updated first.
+func (s *FileSessionStore) List(ctx context.Context) ([]SessionRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches, err := filepath.Glob(filepath.Join(s.root, "*.session.json"))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SessionRecord, 0, len(matches))
+	for _, path := range matches {
+		record, err := readSession(path)
+		if err != nil {
+			continue
+		}
+		result = append(result, *record)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UpdatedAt.After(result[j].UpdatedAt)
+	})
+	return result, nil
+}
+
+// Delete removes a session.
+func (s *FileSessionStore) Delete(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	path, err := s.pathFor(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// readSession decodes a session file.
+func readSession(path string) (*SessionRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record SessionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return &record, nil
+}
+
+// portableSnapshot drops context values that cannot be encoded as JSON
+// (callbacks, channels) so one live-only entry does not lose the whole
+// session. Task result data goes through portableValues for the same reason.
+func portableSnapshot(snapshot *framework.ContextSnapshot) *framework.ContextSnapshot {
+	if snapshot == nil {
+		return nil
+	}
+	clone := *snapshot
+	clone.State = portableValues(snapshot.State)
+	clone.Variables = portableValues(snapshot.Variables)
+	clone.Knowledge = portableValues(snapshot.Knowledge)
+	return &clone
+}
+
+func portableValues(values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if _, err := json.Marshal(value); err != nil {
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TestFileSessionStoreRoundTrip saves two sessions, reloads them, and checks
+// ordering plus that non-JSON context values are dropped instead of failing.
+func TestFileSessionStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	state := framework.NewContext()
+	state.Set("task.instruction", "fix the build")
+	state.Set("stream_callback", func(string) {})
+	first := &SessionRecord{
+		ID:       "session-1",
+		Messages: json.RawMessage(`[{"ID":"msg-1"}]`),
+		Tasks:    []SessionTask{{ID: "task-1", Instruction: "fix the build", Success: true}},
+		Context:  state.Snapshot(),
+	}
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("save first: %v", err)
+	}
+	if err := store.Save(ctx, &SessionRecord{ID: "session-2"}); err != nil {
+		t.Fatalf("save second: %v", err)
+	}
+
+	reopened, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	loaded, ok, err := reopened.Load(ctx, "session-1")
+	if err != nil || !ok {
+		t.Fatalf("load: ok=%v err=%v", ok, err)
+	}
+	if string(loaded.Messages) != `[{"ID":"msg-1"}]` {
+		t.Fatalf("unexpected transcript %s", loaded.Messages)
+	}
+	if len(loaded.Tasks) != 1 || loaded.Tasks[0].Instruction != "fix the build" {
+		t.Fatalf("unexpected tasks %+v", loaded.Tasks)
+	}
+	restored := framework.NewContext()
+	if err := restored.Restore(loaded.Context); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := restored.GetString("task.instruction"); got != "fix the build" {
+		t.Fatalf("expected restored instruction, got %q", got)
+	}
+	if _, ok := restored.Get("stream_callback"); ok {
+		t.Fatalf("callbacks should not be persisted")
+	}
+
+	sessions, err := reopened.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "session-2" {
+		t.Fatalf("expected newest session first, got %+v", sessions)
+	}
+
+	if _, _, err := reopened.Load(ctx, "../escape"); err == nil {
+		t.Fatalf("expected path traversal to be rejected")
+	}
+	if err := reopened.Delete(ctx, "session-1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok, _ := reopened.Load(ctx, "session-1"); ok {
+		t.Fatalf("expected session to be deleted")
+	}
+}
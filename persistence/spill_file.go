@@ -0,0 +1,98 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SpillEntry is one archived item in a spill file.
+type SpillEntry struct {
+	Kind      string          `json:"kind"`
+	SpilledAt time.Time       `json:"spilled_at"`
+	Entry     json.RawMessage `json:"entry"`
+}
+
+// SpillFile appends entries evicted from in-memory buffers (feed messages,
+// context history, usage records) to a JSONL file so capping memory does not
+// destroy them. The file is opened per write, keeping idle shells free of
+// open descriptors.
+type SpillFile struct {
+	path    string
+	mu      sync.Mutex
+	written int
+}
+
+// NewSpillFile creates the parent directory for path.
+func NewSpillFile(path string) (*SpillFile, error) {
+	if path == "" {
+		return nil, errors.New("spill path required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return &SpillFile{path: path}, nil
+}
+
+// Path returns the file entries are appended to.
+func (s *SpillFile) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// Written reports how many entries this process has spilled.
+func (s *SpillFile) Written() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
+
+// Append writes one line per entry under kind. Entries that cannot be
+// encoded are skipped rather than failing the batch.
+func (s *SpillFile) Append(kind string, entries ...interface{}) error {
+	if s == nil || len(entries) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	var buf []byte
+	count := 0
+	for _, entry := range entries {
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		line, err := json.Marshal(SpillEntry{Kind: kind, SpilledAt: now, Entry: raw})
+		if err != nil {
+			continue
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.written += count
+	return nil
+}
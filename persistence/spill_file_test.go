@@ -0,0 +1,56 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSpillFileAppendsJSONLines checks entries land one per line under their
+// kind and that unencodable entries are skipped.
+func TestSpillFileAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill", "today.jsonl")
+	spill, err := NewSpillFile(path)
+	if err != nil {
+		t.Fatalf("new spill file: %v", err)
+	}
+	if err := spill.Append("feed", map[string]string{"text": "one"}, func() {}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := spill.Append("usage", map[string]int{"tokens": 2}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if spill.Written() != 2 {
+		t.Fatalf("expected 2 written, got %d", spill.Written())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	var kinds []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry SpillEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		kinds = append(kinds, entry.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != "feed" || kinds[1] != "usage" {
+		t.Fatalf("unexpected kinds: %v", kinds)
+	}
+}
+
+func TestNilSpillFileIsNoop(t *testing.T) {
+	var spill *SpillFile
+	if err := spill.Append("feed", "x"); err != nil {
+		t.Fatalf("expected nil spill append to succeed: %v", err)
+	}
+	if spill.Path() != "" || spill.Written() != 0 {
+		t.Fatalf("expected empty nil spill file")
+	}
+}
@@ -0,0 +1,219 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteWorkflowStore keeps snapshots in a SQLite database indexed on status,
+// agent, and update time, so filtered listings stay fast as history grows.
+type SQLiteWorkflowStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteWorkflowStore opens (creating when needed) workflows.db under
+// root. Snapshots a FileWorkflowStore left in the same directory are
+// imported once and its workflows.json is renamed to workflows.json.migrated.
+func NewSQLiteWorkflowStore(root string) (*SQLiteWorkflowStore, error) {
+	if root == "" {
+		return nil, errors.New("workflow store root required")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(root, "workflows.db"))
+	if err != nil {
+		return nil, err
+	}
+	// A single connection serializes writers instead of failing them with
+	// SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS workflows (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		agent TEXT NOT NULL DEFAULT '',
+		updated_at INTEGER NOT NULL,
+		snapshot BLOB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_workflows_status ON workflows(status, updated_at);
+	CREATE INDEX IF NOT EXISTS idx_workflows_agent ON workflows(agent, updated_at);
+	CREATE INDEX IF NOT EXISTS idx_workflows_updated_at ON workflows(updated_at);`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	store := &SQLiteWorkflowStore{db: db}
+	if err := store.migrateFileStore(context.Background(), root); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// migrateFileStore imports workflows.json from root, keeping each
+// snapshot's UpdatedAt and any snapshot the database already has.
+func (s *SQLiteWorkflowStore) migrateFileStore(ctx context.Context, root string) error {
+	path := filepath.Join(root, "workflows.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snapshots []WorkflowSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i := range snapshots {
+		if err := insertWorkflow(ctx, tx, &snapshots[i], false); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return os.Rename(path, path+".migrated")
+}
+
+// insertWorkflow writes snapshot, replacing an existing row only when
+// replace is set.
+func insertWorkflow(ctx context.Context, tx *sql.Tx, snapshot *WorkflowSnapshot, replace bool) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	verb := "INSERT OR IGNORE"
+	if replace {
+		verb = "INSERT OR REPLACE"
+	}
+	_, err = tx.ExecContext(ctx, verb+` INTO workflows (id, status, agent, updated_at, snapshot) VALUES (?, ?, ?, ?, ?)`,
+		snapshot.ID, string(snapshot.Status), snapshot.Agent, snapshot.UpdatedAt.UnixNano(), data)
+	return err
+}
+
+// Close releases the database.
+func (s *SQLiteWorkflowStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Save upserts a snapshot, stamping its UpdatedAt.
+func (s *SQLiteWorkflowStore) Save(ctx context.Context, snapshot *WorkflowSnapshot) error {
+	if snapshot == nil {
+		return errors.New("nil snapshot")
+	}
+	snapshot.UpdatedAt = time.Now().UTC()
+	if snapshot.Metadata != nil {
+		snapshot.Metadata = portableValues(snapshot.Metadata)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insertWorkflow(ctx, tx, snapshot, true); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Load retrieves a snapshot by ID.
+func (s *SQLiteWorkflowStore) Load(ctx context.Context, id string) (*WorkflowSnapshot, bool, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT snapshot FROM workflows WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var snapshot WorkflowSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, false, err
+	}
+	return &snapshot, true, nil
+}
+
+// List returns all snapshots, newest first.
+func (s *SQLiteWorkflowStore) List(ctx context.Context) ([]WorkflowSnapshot, error) {
+	return s.Query(ctx, WorkflowQuery{})
+}
+
+// Query returns the snapshots matching q, newest first, filtering and
+// paging in SQL.
+func (s *SQLiteWorkflowStore) Query(ctx context.Context, q WorkflowQuery) ([]WorkflowSnapshot, error) {
+	var where []string
+	var args []any
+	if len(q.Statuses) > 0 {
+		marks := make([]string, len(q.Statuses))
+		for i, status := range q.Statuses {
+			marks[i] = "?"
+			args = append(args, string(status))
+		}
+		where = append(where, "status IN ("+strings.Join(marks, ", ")+")")
+	}
+	if q.Agent != "" {
+		where = append(where, "agent = ?")
+		args = append(args, q.Agent)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "updated_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "updated_at < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+	query := `SELECT snapshot FROM workflows`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY updated_at DESC, id"
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := q.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, q.Offset)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []WorkflowSnapshot{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var snapshot WorkflowSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, err
+		}
+		result = append(result, snapshot)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes a snapshot.
+func (s *SQLiteWorkflowStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM workflows WHERE id = ?`, id)
+	return err
+}
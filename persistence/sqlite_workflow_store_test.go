@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSQLiteWorkflowStoreMigratesFileStore imports a FileWorkflowStore's
+// snapshots with their timestamps and moves workflows.json aside.
+func TestSQLiteWorkflowStoreMigratesFileStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	files, err := NewFileWorkflowStore(root)
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	if err := files.Save(ctx, &WorkflowSnapshot{ID: "wf-old", Status: WorkflowStatusCompleted, Agent: "coder"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	old, _, _ := files.Load(ctx, "wf-old")
+
+	store, err := NewSQLiteWorkflowStore(root)
+	if err != nil {
+		t.Fatalf("new sqlite store: %v", err)
+	}
+	defer store.Close()
+	snapshot, ok, err := store.Load(ctx, "wf-old")
+	if err != nil || !ok {
+		t.Fatalf("expected migrated snapshot, got ok=%v err=%v", ok, err)
+	}
+	if !snapshot.UpdatedAt.Equal(old.UpdatedAt) || snapshot.Agent != "coder" {
+		t.Fatalf("migration changed the snapshot: %+v", snapshot)
+	}
+	if _, err := os.Stat(filepath.Join(root, "workflows.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected workflows.json moved aside, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "workflows.json.migrated")); err != nil {
+		t.Fatalf("expected workflows.json.migrated: %v", err)
+	}
+}
+
+// TestWorkflowStoresQuery runs the same filters against both backends.
+func TestWorkflowStoresQuery(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := NewFileWorkflowStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	sqliteStore, err := NewSQLiteWorkflowStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new sqlite store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	for name, store := range map[string]WorkflowStore{"file": fileStore, "sqlite": sqliteStore} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now().UTC()
+			for _, snapshot := range []WorkflowSnapshot{
+				{ID: "wf-1", Status: WorkflowStatusCompleted, Agent: "coder"},
+				{ID: "wf-2", Status: WorkflowStatusFailed, Agent: "planner"},
+				{ID: "wf-3", Status: WorkflowStatusCompleted, Agent: "coder"},
+				{ID: "wf-4", Status: WorkflowStatusTimedOut, Agent: "coder"},
+			} {
+				snapshot := snapshot
+				if err := store.Save(ctx, &snapshot); err != nil {
+					t.Fatalf("save %s: %v", snapshot.ID, err)
+				}
+				time.Sleep(2 * time.Millisecond)
+			}
+
+			for _, tc := range []struct {
+				query WorkflowQuery
+				want  []string
+			}{
+				{WorkflowQuery{}, []string{"wf-4", "wf-3", "wf-2", "wf-1"}},
+				{WorkflowQuery{Statuses: []WorkflowStatus{WorkflowStatusCompleted, WorkflowStatusTimedOut}}, []string{"wf-4", "wf-3", "wf-1"}},
+				{WorkflowQuery{Agent: "coder", Limit: 2}, []string{"wf-4", "wf-3"}},
+				{WorkflowQuery{Agent: "coder", Offset: 2}, []string{"wf-1"}},
+				{WorkflowQuery{Since: start.Add(time.Hour)}, nil},
+				{WorkflowQuery{Until: start}, nil},
+			} {
+				snapshots, err := QueryWorkflows(ctx, store, tc.query)
+				if err != nil {
+					t.Fatalf("query %+v: %v", tc.query, err)
+				}
+				var got []string
+				for _, snapshot := range snapshots {
+					got = append(got, snapshot.ID)
+				}
+				if len(got) != len(tc.want) {
+					t.Fatalf("query %+v: got %v, want %v", tc.query, got, tc.want)
+				}
+				for i := range got {
+					if got[i] != tc.want[i] {
+						t.Fatalf("query %+v: got %v, want %v", tc.query, got, tc.want)
+					}
+				}
+			}
+
+			if err := store.Delete(ctx, "wf-1"); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if _, ok, _ := store.Load(ctx, "wf-1"); ok {
+				t.Fatal("expected wf-1 deleted")
+			}
+		})
+	}
+}
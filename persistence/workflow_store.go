@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,16 +21,24 @@ const (
 	WorkflowStatusRunning   WorkflowStatus = "running"
 	WorkflowStatusCompleted WorkflowStatus = "completed"
 	WorkflowStatusFailed    WorkflowStatus = "failed"
+	WorkflowStatusTimedOut  WorkflowStatus = "timed_out"
+	// WorkflowStatusBudgetExceeded marks a task stopped by its TaskBudget.
+	// Its Graph holds the context at the stop for `relurpish workflow
+	// resume`.
+	WorkflowStatusBudgetExceeded WorkflowStatus = "budget_exceeded"
 )
 
 // WorkflowSnapshot persists graph execution state on disk.
 type WorkflowSnapshot struct {
-	ID        string                   `json:"id"`
-	Task      *framework.Task          `json:"task"`
-	Graph     *framework.GraphSnapshot `json:"graph"`
-	Status    WorkflowStatus           `json:"status"`
-	Metadata  map[string]interface{}   `json:"metadata,omitempty"`
-	UpdatedAt time.Time                `json:"updated_at"`
+	ID     string                   `json:"id"`
+	Task   *framework.Task          `json:"task"`
+	Graph  *framework.GraphSnapshot `json:"graph"`
+	Status WorkflowStatus           `json:"status"`
+	// Agent names the agent that ran the workflow, when known.
+	Agent     string                  `json:"agent,omitempty"`
+	Metadata  map[string]interface{}  `json:"metadata,omitempty"`
+	Usage     *framework.UsageSummary `json:"usage,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at"`
 }
 
 // WorkflowStore persists snapshots between runs.
@@ -40,6 +49,90 @@ type WorkflowStore interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// WorkflowQuery filters and pages workflow snapshots. Zero fields match
+// every snapshot; Limit zero returns all matches after Offset.
+type WorkflowQuery struct {
+	Statuses []WorkflowStatus
+	Agent    string
+	// Since and Until bound UpdatedAt, inclusive of Since and exclusive of
+	// Until.
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// Matches reports whether snapshot passes every filter of q.
+func (q WorkflowQuery) Matches(snapshot WorkflowSnapshot) bool {
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, status := range q.Statuses {
+			if snapshot.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Agent != "" && snapshot.Agent != q.Agent {
+		return false
+	}
+	if !q.Since.IsZero() && snapshot.UpdatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !snapshot.UpdatedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// WorkflowQuerier is implemented by stores that filter and page snapshots
+// themselves, returning them newest first.
+type WorkflowQuerier interface {
+	Query(ctx context.Context, q WorkflowQuery) ([]WorkflowSnapshot, error)
+}
+
+// QueryWorkflows returns the snapshots of store matching q, newest first.
+// Stores without a WorkflowQuerier are listed in full and filtered here.
+func QueryWorkflows(ctx context.Context, store WorkflowStore, q WorkflowQuery) ([]WorkflowSnapshot, error) {
+	if querier, ok := store.(WorkflowQuerier); ok {
+		return querier.Query(ctx, q)
+	}
+	snapshots, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterWorkflows(snapshots, q), nil
+}
+
+// filterWorkflows applies q to snapshots in memory.
+func filterWorkflows(snapshots []WorkflowSnapshot, q WorkflowQuery) []WorkflowSnapshot {
+	matched := make([]WorkflowSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if q.Matches(snapshot) {
+			matched = append(matched, snapshot)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].UpdatedAt.Equal(matched[j].UpdatedAt) {
+			return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if q.Offset > 0 {
+		if q.Offset >= len(matched) {
+			return matched[:0]
+		}
+		matched = matched[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(matched) {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
 // FileWorkflowStore stores snapshots as JSON on disk.
 type FileWorkflowStore struct {
 	path  string
@@ -111,6 +204,9 @@ func (s *FileWorkflowStore) Save(ctx context.Context, snapshot *WorkflowSnapshot
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	snapshot.UpdatedAt = time.Now().UTC()
+	if snapshot.Metadata != nil {
+		snapshot.Metadata = portableValues(snapshot.Metadata)
+	}
 	s.cache[snapshot.ID] = *snapshot
 	return s.persist()
 }
@@ -147,6 +243,15 @@ func (s *FileWorkflowStore) List(ctx context.Context) ([]WorkflowSnapshot, error
 	return result, nil
 }
 
+// Query returns the snapshots matching q, newest first.
+func (s *FileWorkflowStore) Query(ctx context.Context, q WorkflowQuery) ([]WorkflowSnapshot, error) {
+	snapshots, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterWorkflows(snapshots, q), nil
+}
+
 // Delete removes a snapshot.
 func (s *FileWorkflowStore) Delete(ctx context.Context, id string) error {
 	select {
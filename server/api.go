@@ -6,16 +6,67 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
 )
 
 // APIServer exposes HTTP endpoints for testing agents without an editor.
+// Synchronous requests go through /api/task while /api/tasks feeds a queue
+// so multiple clients can submit work without blocking each other.
 type APIServer struct {
 	Agent   framework.Agent
 	Context *framework.Context
 	Logger  *log.Logger
+	Queue   TaskQueueConfig
+	Usage   *framework.UsageTracker
+	// NewAgent builds a fresh agent for each task. Agents keep per-run
+	// state, so without it tasks take turns on Agent instead of running
+	// concurrently.
+	NewAgent func() (framework.Agent, error)
+	// Autonomy, when set, is exposed at /api/autonomy so clients can read
+	// and change the session's autonomy level.
+	Autonomy *framework.AutonomyController
+	// Timeouts bounds each task's graph and nodes.
+	Timeouts framework.GraphTimeouts
+	// Sandbox is reported at /api/status so clients can warn when commands
+	// run unsandboxed.
+	Sandbox framework.SandboxStatus
+	// The dashboard at /ui reads these when set: Events for task
+	// timelines, HITL for the approval queue, Memory for the memory browser,
+	// and Workflows for saved snapshots.
+	Events    *EventLog
+	HITL      *framework.HITLBroker
+	Memory    framework.MemoryStore
+	Workflows persistence.WorkflowStore
+	// HITLWebhooks are notified of HITL requests while the server runs so
+	// approvals can be handled headless through /v1/hitl.
+	HITLWebhooks []*HITLWebhook
+	// Metrics, when set, is served at /metrics and records every task the
+	// server runs. It should also be among the telemetry sinks so LLM and
+	// tool latencies are counted.
+	Metrics *Metrics
+	// Auth, when it has keys, requires an API key with the endpoint's role
+	// on every request.
+	Auth *APIAuth
+	// Schedules, when set, enables /api/schedules and submits each
+	// schedule's task to the queue when its cron expression fires. Runs are
+	// recorded in Workflows.
+	Schedules persistence.ScheduleStore
+	// Recipes resolves the recipes schedules refer to.
+	Recipes RecipeResolver
+	// Artifacts, when set, enables /api/artifacts for listing and
+	// downloading the outputs tasks saved.
+	Artifacts framework.ArtifactStore
+
+	queueOnce  sync.Once
+	queue      *TaskQueue
+	scheduleMu sync.Mutex
+	// agentMu serializes runs of the shared Agent when NewAgent is unset.
+	agentMu sync.Mutex
 }
 
 // TaskRequest describes incoming API payload.
@@ -23,12 +74,25 @@ type TaskRequest struct {
 	Instruction string                 `json:"instruction"`
 	Type        framework.TaskType     `json:"type"`
 	Context     map[string]interface{} `json:"context"`
+	// Priority orders queued tasks; higher runs first. Ignored by the
+	// synchronous /api/task endpoint.
+	Priority int `json:"priority,omitempty"`
+	// Budget stops the task once it exceeds a limit, e.g.
+	// {"max_duration": "10m", "max_tokens": 50000}.
+	Budget *framework.TaskBudget `json:"budget,omitempty"`
 }
 
 // TaskResponse describes API response.
 type TaskResponse struct {
-	Result *framework.Result `json:"result"`
-	Error  string            `json:"error,omitempty"`
+	Result    *framework.Result   `json:"result"`
+	Error     string              `json:"error,omitempty"`
+	ErrorKind framework.ErrorKind `json:"error_kind,omitempty"`
+}
+
+// TaskSubmission is returned when a task is accepted by the queue.
+type TaskSubmission struct {
+	ID     string     `json:"id"`
+	Status TaskStatus `json:"status"`
 }
 
 // Serve starts listening on the provided address.
@@ -39,6 +103,10 @@ func (s *APIServer) Serve(addr string) error {
 // ServeContext allows the caller to control shutdown via context cancellation.
 func (s *APIServer) ServeContext(ctx context.Context, addr string) error {
 	server := s.newHTTPServer(addr)
+	s.tasks().Start(ctx)
+	go RunHITLWebhooks(ctx, s.HITL, s.HITLWebhooks)
+	go s.Metrics.WatchHITL(ctx, s.HITL)
+	go s.runScheduler(ctx)
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- server.ListenAndServe()
@@ -62,11 +130,31 @@ func (s *APIServer) ServeContext(ctx context.Context, addr string) error {
 
 func (s *APIServer) newHTTPServer(addr string) *http.Server {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/task", s.handleTask)
-	mux.HandleFunc("/api/context", s.handleContext)
+	mux.HandleFunc("/api/task", s.guard(APIRoleSubmitTasks, APIRoleSubmitTasks, s.handleTask))
+	mux.HandleFunc("/api/context", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleContext))
+	mux.HandleFunc("/api/tasks", s.guard(APIRoleReadOnly, APIRoleSubmitTasks, s.handleTasks))
+	mux.HandleFunc("/api/tasks/", s.guard(APIRoleReadOnly, APIRoleSubmitTasks, s.handleTaskStatus))
+	mux.HandleFunc("/api/usage", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleUsage))
+	// /v1/usage is the stable path for external cost dashboards.
+	mux.HandleFunc("/v1/usage", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleUsage))
+	mux.HandleFunc("/api/autonomy", s.guard(APIRoleReadOnly, APIRoleAdmin, s.handleAutonomy))
+	mux.HandleFunc("/api/status", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleStatus))
+	if s.Metrics != nil {
+		mux.Handle("/metrics", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.Metrics.ServeHTTP))
+	}
+	s.registerHITL(mux)
+	s.registerDashboard(mux)
+	s.registerSchedules(mux)
+	s.registerArtifacts(mux)
+	s.registerGRPC(mux)
+	// HTTP/2 without TLS carries the gRPC API; HTTP/1 clients are unaffected.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
 	return &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr:      addr,
+		Handler:   mux,
+		Protocols: protocols,
 	}
 }
 
@@ -80,6 +168,12 @@ func (s *APIServer) handleTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.Budget != nil {
+		if err := req.Budget.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	if req.Type == "" {
 		req.Type = framework.TaskTypeCodeModification
 	}
@@ -90,19 +184,283 @@ func (s *APIServer) handleTask(w http.ResponseWriter, r *http.Request) {
 		Type:        req.Type,
 		Instruction: req.Instruction,
 		Context:     req.Context,
+		Budget:      req.Budget,
 	}
-	state := s.Context.Clone()
-	result, err := s.Agent.Execute(ctx, task, state)
+	result, err := s.runTask(ctx, task)
 	resp := TaskResponse{Result: result}
 	if err != nil {
 		resp.Error = err.Error()
+		resp.ErrorKind = framework.ErrorKindOf(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(errorStatus(resp.ErrorKind))
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// errorStatus maps a failed task's error kind to the HTTP status of the
+// synchronous task endpoint, so clients can tell a model that is down (503)
+// from a denied tool (403).
+func errorStatus(kind framework.ErrorKind) int {
+	switch kind {
+	case framework.ErrorKindLLMUnavailable:
+		return http.StatusServiceUnavailable
+	case framework.ErrorKindToolDenied:
+		return http.StatusForbidden
+	case framework.ErrorKindContextBudgetExceeded:
+		return http.StatusRequestEntityTooLarge
+	case framework.ErrorKindParseFailure:
+		return http.StatusBadGateway
+	case framework.ErrorKindTimeout:
+		return http.StatusGatewayTimeout
+	case framework.ErrorKindBudgetExceeded:
+		return http.StatusTooManyRequests
+	case framework.ErrorKindCancelled:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// runTask executes the agent against a per-task clone of the shared context
+// and merges the clone back only when the run succeeds.
+func (s *APIServer) runTask(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+	ctx = framework.WithGraphTimeouts(ctx, s.Timeouts)
+	if task.Budget != nil {
+		var stopBudget context.CancelFunc
+		ctx, _, stopBudget = framework.WithTaskBudget(ctx, *task.Budget)
+		defer stopBudget()
 	}
+	if framework.MessageBusFrom(ctx) == nil {
+		ctx = framework.WithMessageBus(ctx, framework.NewMessageBus(task.ID, s.Memory))
+	}
+	agent := s.Agent
+	if s.NewAgent != nil {
+		var err error
+		if agent, err = s.NewAgent(); err != nil {
+			return nil, err
+		}
+	} else {
+		s.agentMu.Lock()
+		defer s.agentMu.Unlock()
+	}
+	state := s.Context.Clone()
+	state.Set("task.id", task.ID)
+	state.Set("task.type", string(task.Type))
+	state.Set("task.instruction", task.Instruction)
+	start := time.Now()
+	result, err := agent.Execute(ctx, task, state)
+	s.Metrics.ObserveTask(task, result, err, time.Since(start))
 	if err == nil {
 		s.Context.Merge(state)
+	} else if result != nil && result.Error == nil {
+		result.Error = err
+	}
+	return result, err
+}
+
+// UsageResponse reports aggregated token usage, optionally with the raw
+// per-call records.
+type UsageResponse struct {
+	Summary framework.UsageSummary  `json:"summary"`
+	Records []framework.UsageRecord `json:"records,omitempty"`
+}
+
+// handleUsage serves token accounting filtered by ?task_id= and ?agent=.
+// Pass ?records=true to include per-call records.
+func (s *APIServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Usage == nil {
+		http.Error(w, "usage accounting disabled", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	filter := framework.UsageFilter{TaskID: query.Get("task_id"), Agent: query.Get("agent")}
+	resp := UsageResponse{Summary: s.Usage.Summary(filter)}
+	if query.Get("records") == "true" {
+		resp.Records = s.Usage.Records(filter)
 	}
 	writeJSON(w, resp)
 }
 
+// StatusResponse summarizes server health for /api/status.
+type StatusResponse struct {
+	Sandbox framework.SandboxStatus `json:"sandbox"`
+	// Degraded mirrors Sandbox.Degraded for clients that only check a flag.
+	Degraded bool `json:"degraded"`
+}
+
+func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, StatusResponse{Sandbox: s.Sandbox, Degraded: s.Sandbox.Degraded})
+}
+
+// AutonomyRequest changes the session autonomy level. Duration (e.g. "30m")
+// time-boxes the level; Quota, when present, replaces the autonomous quota.
+type AutonomyRequest struct {
+	Level    string                   `json:"level"`
+	Duration string                   `json:"duration,omitempty"`
+	Quota    *framework.AutonomyQuota `json:"quota,omitempty"`
+}
+
+// handleAutonomy reports the autonomy status on GET and changes the level on
+// POST.
+func (s *APIServer) handleAutonomy(w http.ResponseWriter, r *http.Request) {
+	if s.Autonomy == nil {
+		http.Error(w, "autonomy levels disabled", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req AutonomyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := framework.ParseAutonomyLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var duration time.Duration
+		if req.Duration != "" {
+			if duration, err = time.ParseDuration(req.Duration); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Quota != nil {
+			s.Autonomy.SetQuota(*req.Quota)
+		}
+		if err := s.Autonomy.Set(level, duration); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.Autonomy.Status())
+}
+
+// tasks lazily builds the queue so handlers work even when the server is
+// driven directly (tests) rather than through ServeContext.
+func (s *APIServer) tasks() *TaskQueue {
+	s.queueOnce.Do(func() {
+		s.queue = NewTaskQueue(s.Queue, s.runQueuedTask)
+	})
+	return s.queue
+}
+
+// handleTasks accepts new queued tasks (POST) or lists known tasks (GET).
+func (s *APIServer) handleTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.tasks().List())
+	case http.MethodPost:
+		var req TaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Budget != nil {
+			if err := req.Budget.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Type == "" {
+			req.Type = framework.TaskTypeCodeModification
+		}
+		queue := s.tasks()
+		queue.Start(context.Background())
+		record, err := queue.SubmitWithPriority(&framework.Task{
+			Type:        req.Type,
+			Instruction: req.Instruction,
+			Context:     req.Context,
+			Budget:      req.Budget,
+		}, req.Priority)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrQueueFull) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Location", "/api/tasks/"+record.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(TaskSubmission{ID: record.ID, Status: record.Status})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTaskStatus returns the status/result of a queued task. POST to
+// /api/tasks/{id}/cancel, /pause, or /resume controls it.
+func (s *APIServer) handleTaskStatus(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/")
+	if r.Method == http.MethodPost {
+		s.handleTaskControl(w, id)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if id == "" {
+		writeJSON(w, s.tasks().List())
+		return
+	}
+	if taskID, ok := strings.CutSuffix(id, "/events"); ok {
+		s.handleTaskEvents(w, taskID)
+		return
+	}
+	record, ok := s.tasks().Get(id)
+	if !ok {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, record)
+}
+
+// handleTaskControl applies "{id}/cancel", "{id}/pause", or "{id}/resume"
+// and returns the updated record.
+func (s *APIServer) handleTaskControl(w http.ResponseWriter, path string) {
+	id, action, _ := strings.Cut(path, "/")
+	queue := s.tasks()
+	var control func(string) (TaskRecord, error)
+	switch action {
+	case "cancel":
+		control = queue.Cancel
+	case "pause":
+		control = queue.Pause
+	case "resume":
+		control = queue.Resume
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	record, err := control(id)
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		writeJSON(w, record)
+	}
+}
+
 func (s *APIServer) handleContext(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, s.Context)
 }
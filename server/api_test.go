@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lexcodex/relurpify/framework"
 )
@@ -47,3 +51,187 @@ func TestAPIServerHandleTask(t *testing.T) {
 	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
 	assert.Equal(t, "stub", resp.Result.NodeID)
 }
+
+// failingAgent fails every task with err.
+type failingAgent struct {
+	stubAgent
+	err error
+}
+
+func (a failingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	return &framework.Result{NodeID: "plan"}, a.err
+}
+
+func TestAPIServerHandleTaskMapsErrorKinds(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		kind   framework.ErrorKind
+	}{
+		{err: framework.WrapError(framework.ErrorKindLLMUnavailable, errors.New("connection refused")), status: http.StatusServiceUnavailable, kind: framework.ErrorKindLLMUnavailable},
+		{err: fmt.Errorf("node act: %w", &framework.PermissionDeniedError{Message: "write denied"}), status: http.StatusForbidden, kind: framework.ErrorKindToolDenied},
+		{err: errors.New("gave up"), status: http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		api := &APIServer{
+			Agent:   failingAgent{err: tc.err},
+			Context: framework.NewContext(),
+			Logger:  log.New(io.Discard, "", 0),
+		}
+		reqBody, _ := json.Marshal(TaskRequest{Instruction: "test"})
+		rec := httptest.NewRecorder()
+		api.handleTask(rec, httptest.NewRequest(http.MethodPost, "/api/task", bytes.NewReader(reqBody)))
+
+		assert.Equal(t, tc.status, rec.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, tc.err.Error(), resp["error"])
+		if tc.kind != "" {
+			assert.Equal(t, string(tc.kind), resp["error_kind"])
+			assert.Equal(t, string(tc.kind), resp["result"].(map[string]interface{})["Error"].(map[string]interface{})["kind"])
+		} else {
+			assert.NotContains(t, resp, "error_kind")
+		}
+	}
+}
+
+func TestAPIServerQueuedTask(t *testing.T) {
+	api := &APIServer{
+		Agent:   stubAgent{},
+		Context: framework.NewContext(),
+		Logger:  log.New(io.Discard, "", 0),
+		Queue:   TaskQueueConfig{Workers: 2},
+	}
+	reqBody, _ := json.Marshal(TaskRequest{Instruction: "queued"})
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	api.handleTasks(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var submission TaskSubmission
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &submission))
+	assert.NotEmpty(t, submission.ID)
+
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		api.handleTaskStatus(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/"+submission.ID, nil))
+		var record TaskRecord
+		if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil {
+			return false
+		}
+		return record.Status == TaskStatusSucceeded && record.Result != nil && record.Result.NodeID == "stub"
+	}, time.Second, 10*time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	api.handleTaskStatus(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIServerUsage(t *testing.T) {
+	usage := framework.NewUsageTracker(nil)
+	ctx := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "t1", NodeID: "act"})
+	usage.Record(ctx, "m", map[string]int{"prompt_tokens": 7, "completion_tokens": 3})
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Usage: usage}
+	handler := api.newHTTPServer("").Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/usage?task_id=t1&records=true", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp UsageResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 10, resp.Summary.Total.TotalTokens)
+	assert.Len(t, resp.Records, 1)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/usage?task_id=other", nil))
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Summary.Total.Calls)
+}
+
+func TestAPIServerStatusReportsDegradedSandbox(t *testing.T) {
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Sandbox: framework.SandboxStatus{
+		Runtime:  "host",
+		Degraded: true,
+		Reason:   "runsc not found",
+	}}
+	rec := httptest.NewRecorder()
+	api.newHTTPServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp StatusResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Degraded)
+	assert.Equal(t, "runsc not found", resp.Sandbox.Reason)
+}
+
+func TestAPIServerAutonomy(t *testing.T) {
+	controller := framework.NewAutonomyController(framework.AutonomyApprove, framework.AutonomyQuota{}, nil)
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Autonomy: controller}
+	handler := api.newHTTPServer("").Handler
+
+	body, _ := json.Marshal(AutonomyRequest{Level: "autonomous", Duration: "30m", Quota: &framework.AutonomyQuota{MaxToolCalls: 5}})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/autonomy", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status framework.AutonomyStatus
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, framework.AutonomyAutonomous, status.Level)
+	assert.Equal(t, 5, status.Quota.MaxToolCalls)
+	assert.NotNil(t, status.ExpiresAt)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/autonomy", bytes.NewReader([]byte(`{"level":"yolo"}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/autonomy", nil))
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, framework.AutonomyAutonomous, status.Level)
+}
+
+// hangingNode blocks until its context ends, standing in for a stuck tool call.
+type hangingNode struct{}
+
+func (hangingNode) ID() string               { return "hang" }
+func (hangingNode) Type() framework.NodeType { return framework.NodeTypeTool }
+func (hangingNode) Execute(ctx context.Context, state *framework.Context) (*framework.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type hangingAgent struct{ stubAgent }
+
+func (hangingAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	graph := framework.NewGraph()
+	if err := graph.AddNode(hangingNode{}); err != nil {
+		return nil, err
+	}
+	if err := graph.SetStart("hang"); err != nil {
+		return nil, err
+	}
+	return graph.Execute(ctx, state)
+}
+
+func TestAPIServerQueuedTaskTimesOut(t *testing.T) {
+	api := &APIServer{
+		Agent:    hangingAgent{},
+		Context:  framework.NewContext(),
+		Logger:   log.New(io.Discard, "", 0),
+		Queue:    TaskQueueConfig{Workers: 1},
+		Timeouts: framework.GraphTimeouts{Node: 20 * time.Millisecond},
+	}
+	reqBody, _ := json.Marshal(TaskRequest{Instruction: "hang"})
+	rec := httptest.NewRecorder()
+	api.handleTasks(rec, httptest.NewRequest(http.MethodPost, "/api/tasks", bytes.NewReader(reqBody)))
+	var submission TaskSubmission
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &submission))
+
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		api.handleTaskStatus(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/"+submission.ID, nil))
+		var record TaskRecord
+		if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil {
+			return false
+		}
+		return record.Status == TaskStatusTimedOut && record.Error == "node hang timed out after 20ms"
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func (s *APIServer) registerArtifacts(mux *http.ServeMux) {
+	mux.HandleFunc("/api/artifacts", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleArtifacts))
+	mux.HandleFunc("/api/artifacts/", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleArtifact))
+}
+
+// handleArtifacts lists saved artifacts oldest first, filtered to one task
+// by ?task=.
+func (s *APIServer) handleArtifacts(w http.ResponseWriter, r *http.Request) {
+	if s.Artifacts == nil {
+		http.Error(w, "artifact store disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	artifacts, err := s.Artifacts.ListArtifacts(r.Context(), r.URL.Query().Get("task"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if artifacts == nil {
+		artifacts = []framework.Artifact{}
+	}
+	writeJSON(w, artifacts)
+}
+
+// handleArtifact downloads one artifact's content. The digest doubles as
+// an ETag since content never changes under an ID.
+func (s *APIServer) handleArtifact(w http.ResponseWriter, r *http.Request) {
+	if s.Artifacts == nil {
+		http.Error(w, "artifact store disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/artifacts/"), "/")
+	if id == "" {
+		s.handleArtifacts(w, r)
+		return
+	}
+	artifact, content, ok, err := s.Artifacts.LoadArtifact(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	etag := `"` + artifact.Digest + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", artifact.MediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Name}))
+	_, _ = w.Write(content)
+}
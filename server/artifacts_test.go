@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+	"github.com/lexcodex/relurpify/tools"
+)
+
+// TestArtifactEndpoints saves an artifact through artifact_save within a
+// task, then lists and downloads it over the API.
+func TestArtifactEndpoints(t *testing.T) {
+	store, err := persistence.NewFileArtifactStore(t.TempDir())
+	require.NoError(t, err)
+	tool := &tools.ArtifactTool{Store: store}
+	ctx := framework.WithTaskContext(context.Background(), framework.TaskContext{ID: "task-42"})
+	result, err := tool.Execute(ctx, framework.NewContext(), map[string]interface{}{
+		"name":    "deps.mmd",
+		"type":    "diagram",
+		"content": "graph TD\n  api --> store\n",
+	})
+	require.NoError(t, err)
+	id := result.Data["id"].(string)
+	_, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"name": "x", "type": "video", "content": "x"})
+	assert.ErrorContains(t, err, "unknown artifact type")
+
+	handler := (&APIServer{Artifacts: store}).newHTTPServer("").Handler
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts?task=task-42", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed []framework.Artifact
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, id, listed[0].ID)
+	assert.Equal(t, framework.ArtifactTypeDiagram, listed[0].Type)
+	assert.Equal(t, "artifact_save", listed[0].Tool)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts?task=other", nil))
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts/"+id, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "graph TD\n  api --> store\n", rec.Body.String())
+	assert.Equal(t, "text/vnd.mermaid; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=deps.mmd`, rec.Header().Get("Content-Disposition"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/artifacts/"+id, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// APIRole grants access to a group of endpoints.
+type APIRole string
+
+const (
+	// APIRoleReadOnly reads tasks, status, usage, memory, and pending
+	// approvals. Every other role includes it.
+	APIRoleReadOnly APIRole = "read-only"
+	// APIRoleSubmitTasks submits tasks.
+	APIRoleSubmitTasks APIRole = "submit-tasks"
+	// APIRoleApproveHITL approves and denies permission requests.
+	APIRoleApproveHITL APIRole = "approve-hitl"
+	// APIRoleAdmin may do everything, including changing the autonomy level.
+	APIRoleAdmin APIRole = "admin"
+)
+
+// ParseAPIRole validates a role name from configuration.
+func ParseAPIRole(name string) (APIRole, error) {
+	switch role := APIRole(strings.ToLower(strings.TrimSpace(name))); role {
+	case APIRoleReadOnly, APIRoleSubmitTasks, APIRoleApproveHITL, APIRoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown api role %q (read-only, submit-tasks, approve-hitl, admin)", name)
+}
+
+// APIKey is a bearer token and the roles it grants. Name identifies the
+// caller in the audit trail and as the default HITL approver.
+type APIKey struct {
+	Name  string
+	Key   string
+	Roles []APIRole
+}
+
+// Allows reports whether the key grants role.
+func (k APIKey) Allows(role APIRole) bool {
+	for _, granted := range k.Roles {
+		if granted == role || granted == APIRoleAdmin || role == APIRoleReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// APIAuth requires an API key on every endpoint except the dashboard's static
+// files. Clients send "Authorization: Bearer <key>" or "X-API-Key: <key>".
+// Denied requests and granted changes (non-GET requests) are written to
+// Audit; reads are not, so polling clients do not flood the trail.
+type APIAuth struct {
+	Keys  []APIKey
+	Audit framework.AuditLogger
+}
+
+// Enabled reports whether any key is configured. Without keys the server is
+// open, which is only safe on localhost.
+func (a *APIAuth) Enabled() bool {
+	return a != nil && len(a.Keys) > 0
+}
+
+// authenticate returns the key matching the request's token.
+func (a *APIAuth) authenticate(r *http.Request) (APIKey, bool) {
+	token := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); token == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		token = strings.TrimSpace(auth[7:])
+	}
+	if token == "" {
+		return APIKey{}, false
+	}
+	for _, key := range a.Keys {
+		if key.Key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key.Key)) == 1 {
+			return key, true
+		}
+	}
+	return APIKey{}, false
+}
+
+func (a *APIAuth) audit(r *http.Request, key APIKey, role APIRole, result, reason string) {
+	if a.Audit == nil {
+		return
+	}
+	metadata := map[string]interface{}{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"remote": r.RemoteAddr,
+	}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	_ = a.Audit.Log(r.Context(), framework.AuditRecord{
+		Timestamp:  time.Now().UTC(),
+		AgentID:    "api",
+		Action:     r.Method + " " + r.URL.Path,
+		Type:       "api",
+		Permission: string(role),
+		Result:     result,
+		Metadata:   metadata,
+		User:       key.Name,
+	})
+}
+
+type apiKeyContextKey struct{}
+
+// apiCaller returns the name of the key that authenticated the request, or
+// "" when auth is disabled.
+func apiCaller(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return name
+}
+
+// guard enforces read on GET and HEAD requests and write on every other
+// method.
+func (s *APIServer) guard(read, write APIRole, next http.HandlerFunc) http.HandlerFunc {
+	if !s.Auth.Enabled() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		reading := r.Method == http.MethodGet || r.Method == http.MethodHead
+		role := write
+		if reading {
+			role = read
+		}
+		key, ok := s.Auth.authenticate(r)
+		if !ok {
+			s.Auth.audit(r, key, role, "denied", "missing or unknown api key")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="relurpify"`)
+			http.Error(w, "api key required", http.StatusUnauthorized)
+			return
+		}
+		if !key.Allows(role) {
+			s.Auth.audit(r, key, role, "denied", "role not granted")
+			http.Error(w, fmt.Sprintf("api key %s lacks role %s", key.Name, role), http.StatusForbidden)
+			return
+		}
+		if !reading {
+			s.Auth.audit(r, key, role, "granted", "")
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key.Name)))
+	}
+}
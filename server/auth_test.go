@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestAPIAuthEnforcesRolesPerEndpoint(t *testing.T) {
+	broker := framework.NewHITLBroker(time.Minute)
+	audit := framework.NewInMemoryAuditLogger(0)
+	api := &APIServer{
+		Agent:    stubAgent{},
+		Context:  framework.NewContext(),
+		HITL:     broker,
+		Autonomy: framework.NewAutonomyController(framework.AutonomyApprove, framework.AutonomyQuota{}, nil),
+		Auth: &APIAuth{Audit: audit, Keys: []APIKey{
+			{Name: "viewer", Key: "view-key", Roles: []APIRole{APIRoleReadOnly}},
+			{Name: "ci", Key: "ci-key", Roles: []APIRole{APIRoleSubmitTasks}},
+			{Name: "oncall", Key: "oncall-key", Roles: []APIRole{APIRoleApproveHITL}},
+			{Name: "root", Key: "root-key", Roles: []APIRole{APIRoleAdmin}},
+		}},
+	}
+	handler := api.newHTTPServer("").Handler
+	do := func(method, path, key, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/status", "", ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/status", "wrong", ""))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/status", "view-key", ""))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/tasks", "oncall-key", ""), "every role can read")
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/tasks", "view-key", `{"instruction":"x"}`))
+	assert.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/tasks", "ci-key", `{"instruction":"x"}`))
+
+	id := submitHITL(t, broker, framework.PermissionTypeFilesystem, "a.txt")
+	updates, cancel := broker.Subscribe(4)
+	defer cancel()
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/v1/hitl/"+id+"/approve", "ci-key", ""))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/v1/hitl/"+id+"/approve", "oncall-key", `{"by":"root"}`))
+	select {
+	case event := <-updates:
+		require.NotNil(t, event.Decision)
+		assert.Equal(t, "oncall", event.Decision.ApprovedBy, "the key name is the approver, whatever by says")
+		assert.Equal(t, "by root", event.Decision.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the hitl decision")
+	}
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/autonomy", "oncall-key", `{"level":"autonomous"}`))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/autonomy", "root-key", `{"level":"autonomous"}`))
+
+	denied, err := audit.Query(context.Background(), framework.AuditQuery{Result: "denied"})
+	require.NoError(t, err)
+	assert.Len(t, denied, 5)
+	granted, err := audit.Query(context.Background(), framework.AuditQuery{Result: "granted"})
+	require.NoError(t, err)
+	require.Len(t, granted, 3, "reads are not audited")
+	assert.Equal(t, "oncall", granted[1].User)
+	assert.Equal(t, string(APIRoleApproveHITL), granted[1].Permission)
+}
+
+func TestAPIAuthAcceptsAPIKeyHeaderAndStaysOpenWithoutKeys(t *testing.T) {
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Auth: &APIAuth{Keys: []APIKey{
+		{Name: "viewer", Key: "view-key", Roles: []APIRole{APIRoleReadOnly}},
+	}}}
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Header.Set("X-API-Key", "view-key")
+	rec := httptest.NewRecorder()
+	api.newHTTPServer("").Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	api.newHTTPServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "dashboard assets need no key")
+
+	open := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Auth: &APIAuth{}}
+	rec = httptest.NewRecorder()
+	open.newHTTPServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err := ParseAPIRole("superuser")
+	assert.Error(t, err)
+}
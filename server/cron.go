@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros expand the @-shortcuts into five-field expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField bounds one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// CronSchedule is a parsed five-field cron expression: minute, hour, day
+// of month, month, and day of week (0 or 7 is Sunday). Fields accept *,
+// lists, ranges, and steps such as "*/15" or "1-5". As in cron, when
+// neither day field starts with * a day matching either one fires.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// ParseCron parses a five-field expression or one of @hourly, @daily,
+// @weekly, @monthly, and @yearly.
+func ParseCron(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+	schedule := &CronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	return schedule, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", bounds.name, stepPart)
+			}
+			step = n
+		}
+		lo, hi := bounds.min, bounds.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(from, bounds); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(to, bounds); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = bounds.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("%s: range %q runs backwards", bounds.name, rangePart)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func cronValue(s string, bounds cronField) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < bounds.min || n > bounds.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", bounds.name, s, bounds.min, bounds.max)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t, truncated to the minute, that the
+// schedule fires, in t's location. It returns the zero time when nothing
+// matches within five years, as for "0 0 30 2 *".
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+//go:embed ui
+var dashboardFiles embed.FS
+
+// TaskEventsResponse is the timeline served at /api/tasks/{id}/events.
+type TaskEventsResponse struct {
+	TaskID string            `json:"task_id"`
+	Events []framework.Event `json:"events"`
+}
+
+// registerDashboard mounts the embedded web UI at /ui and the JSON endpoints
+// it polls.
+func (s *APIServer) registerDashboard(mux *http.ServeMux) {
+	static, err := fs.Sub(dashboardFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(static))))
+	mux.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+	mux.HandleFunc("/api/memory", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleMemory))
+	mux.HandleFunc("/api/workflows", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleWorkflows))
+	mux.HandleFunc("/api/workflows/", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleWorkflow))
+}
+
+// handleTaskEvents returns the recorded timeline for one task.
+func (s *APIServer) handleTaskEvents(w http.ResponseWriter, taskID string) {
+	if s.Events == nil {
+		http.Error(w, "event log disabled", http.StatusNotFound)
+		return
+	}
+	events := s.Events.Events(taskID)
+	if events == nil {
+		events = []framework.Event{}
+	}
+	writeJSON(w, TaskEventsResponse{TaskID: taskID, Events: events})
+}
+
+// handleMemory searches memory; scope defaults to session and q to all
+// records.
+func (s *APIServer) handleMemory(w http.ResponseWriter, r *http.Request) {
+	if s.Memory == nil {
+		http.Error(w, "memory disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	scope := framework.MemoryScope(r.URL.Query().Get("scope"))
+	switch scope {
+	case "":
+		scope = framework.MemoryScopeSession
+	case framework.MemoryScopeSession, framework.MemoryScopeProject, framework.MemoryScopeUser, framework.MemoryScopeGlobal:
+	default:
+		http.Error(w, "unknown memory scope "+string(scope), http.StatusBadRequest)
+		return
+	}
+	records, err := s.Memory.Search(r.Context(), r.URL.Query().Get("q"), scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []framework.MemoryRecord{}
+	}
+	writeJSON(w, records)
+}
+
+// handleWorkflows lists saved workflow snapshots, newest first.
+func (s *APIServer) handleWorkflows(w http.ResponseWriter, r *http.Request) {
+	if s.Workflows == nil {
+		http.Error(w, "workflow store disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query, err := parseWorkflowQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snapshots, err := persistence.QueryWorkflows(r.Context(), s.Workflows, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []persistence.WorkflowSnapshot{}
+	}
+	writeJSON(w, snapshots)
+}
+
+// parseWorkflowQuery reads the filters of GET /api/workflows: status
+// (repeatable or comma separated), agent, since and until (RFC 3339), limit,
+// and offset.
+func parseWorkflowQuery(values url.Values) (persistence.WorkflowQuery, error) {
+	query := persistence.WorkflowQuery{Agent: values.Get("agent")}
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, persistence.WorkflowStatus(status))
+			}
+		}
+	}
+	for name, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := values.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	for name, target := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if value := values.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return query, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = n
+		}
+	}
+	return query, nil
+}
+
+// handleWorkflow returns one workflow snapshot.
+func (s *APIServer) handleWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.Workflows == nil {
+		http.Error(w, "workflow store disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/workflows/"), "/")
+	if id == "" {
+		s.handleWorkflows(w, r)
+		return
+	}
+	snapshot, ok, err := s.Workflows.Load(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "workflow not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, snapshot)
+}
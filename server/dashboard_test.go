@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+func TestEventLogKeepsBoundedTimelines(t *testing.T) {
+	events := &EventLog{PerTask: 2, MaxTasks: 2}
+	events.Emit(framework.Event{Type: framework.EventGraphStart})
+	for _, id := range []string{"a", "a", "a", "b", "c"} {
+		events.Emit(framework.Event{Type: framework.EventNodeStart, TaskID: id})
+	}
+	assert.Empty(t, events.Events("a"), "oldest task should be evicted")
+	assert.Len(t, events.Events("b"), 1)
+	assert.Len(t, events.Events("c"), 1)
+
+	events.Emit(framework.Event{Type: framework.EventNodeStart, TaskID: "c", NodeID: "1"})
+	events.Emit(framework.Event{Type: framework.EventNodeFinish, TaskID: "c", NodeID: "2"})
+	timeline := events.Events("c")
+	require.Len(t, timeline, 2)
+	assert.Equal(t, "1", timeline[0].NodeID)
+	assert.Equal(t, "2", timeline[1].NodeID)
+}
+
+func TestDashboardServesEmbeddedUI(t *testing.T) {
+	handler := (&APIServer{}).newHTTPServer("").Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/ui/", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "app.js")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui/app.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDashboardEndpointsDisabledWithoutBackends(t *testing.T) {
+	handler := (&APIServer{}).newHTTPServer("").Handler
+	for _, path := range []string{"/api/hitl", "/api/memory", "/api/workflows", "/api/workflows/x", "/api/tasks/x/events"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
+
+func TestDashboardTaskEvents(t *testing.T) {
+	events := NewEventLog()
+	events.Emit(framework.Event{Type: framework.EventGraphStart, TaskID: "t1", Message: "start"})
+	handler := (&APIServer{Events: events}).newHTTPServer("").Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/t1/events", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp TaskEventsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "t1", resp.TaskID)
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, "start", resp.Events[0].Message)
+}
+
+func TestDashboardMemoryAndWorkflows(t *testing.T) {
+	ctx := context.Background()
+	memory, err := framework.NewHybridMemory(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, memory.Remember(ctx, "style", map[string]interface{}{"indent": "tabs"}, framework.MemoryScopeProject))
+	workflows, err := persistence.NewFileWorkflowStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, workflows.Save(ctx, &persistence.WorkflowSnapshot{ID: "wf-1", Status: persistence.WorkflowStatusCompleted}))
+	handler := (&APIServer{Memory: memory, Workflows: workflows}).newHTTPServer("").Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/memory?scope=project", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var records []framework.MemoryRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+	require.Len(t, records, 1)
+	assert.Equal(t, "style", records[0].Key)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/memory?scope=galaxy", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `"wf-1"`))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows?status=failed,timed_out", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows?limit=ten", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/wf-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var snapshot persistence.WorkflowSnapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshot))
+	assert.Equal(t, persistence.WorkflowStatusCompleted, snapshot.Status)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// defaultEventsPerTask bounds the timeline kept for each task.
+const defaultEventsPerTask = 500
+
+// EventLog is a framework.Telemetry sink that keeps recent events per task so
+// the dashboard can render live timelines. Events without a task ID are
+// dropped, and only the newest MaxTasks tasks are retained.
+type EventLog struct {
+	// PerTask caps events kept per task; older ones are discarded first.
+	PerTask int
+	// MaxTasks caps how many task timelines are kept. Defaults to 200.
+	MaxTasks int
+
+	mu        sync.RWMutex
+	events    map[string][]framework.Event
+	order     []string
+	followers map[int]eventFollower
+	nextID    int
+}
+
+type eventFollower struct {
+	taskID string
+	ch     chan framework.Event
+}
+
+// NewEventLog builds an EventLog with default bounds.
+func NewEventLog() *EventLog {
+	return &EventLog{PerTask: defaultEventsPerTask, MaxTasks: 200}
+}
+
+// Emit implements framework.Telemetry.
+func (l *EventLog) Emit(event framework.Event) {
+	if l == nil || event.TaskID == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.events == nil {
+		l.events = make(map[string][]framework.Event)
+	}
+	existing, ok := l.events[event.TaskID]
+	if !ok {
+		l.order = append(l.order, event.TaskID)
+		if limit := l.maxTasks(); len(l.order) > limit {
+			for _, id := range l.order[:len(l.order)-limit] {
+				delete(l.events, id)
+			}
+			l.order = append([]string(nil), l.order[len(l.order)-limit:]...)
+		}
+	}
+	existing = append(existing, event)
+	if limit := l.perTask(); len(existing) > limit {
+		existing = append([]framework.Event(nil), existing[len(existing)-limit:]...)
+	}
+	l.events[event.TaskID] = existing
+	for _, f := range l.followers {
+		if f.taskID != event.TaskID {
+			continue
+		}
+		select {
+		case f.ch <- event:
+		default:
+		}
+	}
+}
+
+// Follow returns the timeline recorded for taskID so far and a channel of
+// the events emitted after it. Followers that fall more than a buffer behind
+// miss events. Call cancel to stop following.
+func (l *EventLog) Follow(taskID string) ([]framework.Event, <-chan framework.Event, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.followers == nil {
+		l.followers = make(map[int]eventFollower)
+	}
+	id := l.nextID
+	l.nextID++
+	ch := make(chan framework.Event, 256)
+	l.followers[id] = eventFollower{taskID: taskID, ch: ch}
+	cancel := func() {
+		l.mu.Lock()
+		delete(l.followers, id)
+		l.mu.Unlock()
+	}
+	return append([]framework.Event(nil), l.events[taskID]...), ch, cancel
+}
+
+// Events returns the timeline recorded for taskID, oldest first.
+func (l *EventLog) Events(taskID string) []framework.Event {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]framework.Event(nil), l.events[taskID]...)
+}
+
+func (l *EventLog) perTask() int {
+	if l.PerTask > 0 {
+		return l.PerTask
+	}
+	return defaultEventsPerTask
+}
+
+func (l *EventLog) maxTasks() int {
+	if l.MaxTasks > 0 {
+		return l.MaxTasks
+	}
+	return 200
+}
@@ -0,0 +1,727 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// The gRPC API in relurpify.proto is served on the HTTP port over HTTP/2
+// cleartext, next to the JSON endpoints and behind the same API keys.
+
+// grpcServicePath prefixes every method path of the Relurpify service.
+const grpcServicePath = "/relurpify.v1.Relurpify/"
+
+// maxGRPCMessage bounds request messages, matching gRPC's default.
+const maxGRPCMessage = 4 << 20
+
+// grpcEventPoll is how often a followed event stream checks whether its
+// task finished.
+const grpcEventPoll = 250 * time.Millisecond
+
+// gRPC status codes used by the service.
+const (
+	grpcOK                 = 0
+	grpcCanceled           = 1
+	grpcInvalidArgument    = 3
+	grpcDeadlineExceeded   = 4
+	grpcNotFound           = 5
+	grpcResourceExhausted  = 8
+	grpcFailedPrecondition = 9
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+)
+
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string { return e.msg }
+
+func grpcErrorf(code int, format string, args ...interface{}) error {
+	return &grpcError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// grpcHandler serves one method. Unary methods call send once; streaming
+// methods call it per message.
+type grpcHandler func(ctx context.Context, req []byte, send func(protoMarshaler) error) error
+
+func (s *APIServer) registerGRPC(mux *http.ServeMux) {
+	methods := []struct {
+		name   string
+		role   APIRole
+		handle grpcHandler
+	}{
+		{"SubmitTask", APIRoleSubmitTasks, s.grpcSubmitTask},
+		{"GetTask", APIRoleReadOnly, s.grpcGetTask},
+		{"StreamEvents", APIRoleReadOnly, s.grpcStreamEvents},
+		{"ListWorkflows", APIRoleReadOnly, s.grpcListWorkflows},
+		{"ListHitl", APIRoleReadOnly, s.grpcListHITL},
+		{"ApproveHitl", APIRoleApproveHITL, s.grpcResolveHITL("approve")},
+		{"DenyHitl", APIRoleApproveHITL, s.grpcResolveHITL("deny")},
+	}
+	for _, method := range methods {
+		mux.HandleFunc(grpcServicePath+method.name, s.guard(method.role, method.role, serveGRPC(method.handle)))
+	}
+}
+
+// serveGRPC frames handle's messages and reports its error in the
+// grpc-status trailer.
+func serveGRPC(handle grpcHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if r.Method != http.MethodPost || (contentType != "application/grpc" && contentType != "application/grpc+proto") {
+			http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		req, err := readGRPCMessage(r.Body)
+		if err == nil {
+			err = handle(r.Context(), req, func(msg protoMarshaler) error {
+				return writeGRPCMessage(w, msg)
+			})
+		}
+		code, message := grpcStatus(err)
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+		if message != "" {
+			w.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcPercentEncode(message))
+		}
+	}
+}
+
+func readGRPCMessage(body io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, grpcErrorf(grpcInvalidArgument, "read request: %v", err)
+	}
+	if prefix[0] != 0 {
+		return nil, grpcErrorf(grpcUnimplemented, "compressed requests are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxGRPCMessage {
+		return nil, grpcErrorf(grpcResourceExhausted, "request of %d bytes exceeds %d", size, maxGRPCMessage)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, grpcErrorf(grpcInvalidArgument, "read request: %v", err)
+	}
+	return msg, nil
+}
+
+func writeGRPCMessage(w http.ResponseWriter, msg protoMarshaler) error {
+	data := msg.marshalProto()
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	if _, err := w.Write(append(frame, data...)); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+func grpcStatus(err error) (int, string) {
+	var gerr *grpcError
+	switch {
+	case err == nil:
+		return grpcOK, ""
+	case errors.As(err, &gerr):
+		return gerr.code, gerr.msg
+	case errors.Is(err, context.Canceled):
+		return grpcCanceled, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return grpcDeadlineExceeded, err.Error()
+	default:
+		return grpcInternal, err.Error()
+	}
+}
+
+// grpcPercentEncode escapes grpc-message as the gRPC HTTP/2 spec requires.
+func grpcPercentEncode(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func decodeRequest(data []byte, msg protoUnmarshaler) error {
+	if err := msg.unmarshalProto(data); err != nil {
+		return grpcErrorf(grpcInvalidArgument, "decode request: %v", err)
+	}
+	return nil
+}
+
+func (s *APIServer) grpcSubmitTask(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+	var req pbSubmitTaskRequest
+	if err := decodeRequest(data, &req); err != nil {
+		return err
+	}
+	task := &framework.Task{Type: framework.TaskType(req.Type), Instruction: req.Instruction}
+	if task.Type == "" {
+		task.Type = framework.TaskTypeCodeModification
+	}
+	if req.ContextJSON != "" {
+		if err := json.Unmarshal([]byte(req.ContextJSON), &task.Context); err != nil {
+			return grpcErrorf(grpcInvalidArgument, "context_json: %v", err)
+		}
+	}
+	queue := s.tasks()
+	queue.Start(context.Background())
+	record, err := queue.Submit(task)
+	if errors.Is(err, ErrQueueFull) {
+		return grpcErrorf(grpcResourceExhausted, "%v", err)
+	}
+	if err != nil {
+		return err
+	}
+	return send(newPBTask(record))
+}
+
+func (s *APIServer) grpcGetTask(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+	var req pbGetTaskRequest
+	if err := decodeRequest(data, &req); err != nil {
+		return err
+	}
+	record, ok := s.tasks().Get(req.ID)
+	if !ok {
+		return grpcErrorf(grpcNotFound, "task %s not found", req.ID)
+	}
+	return send(newPBTask(record))
+}
+
+// grpcStreamEvents sends the task's timeline and, when following, new events
+// until the task finishes. Tasks the queue does not know are followed until
+// the client hangs up.
+func (s *APIServer) grpcStreamEvents(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+	var req pbStreamEventsRequest
+	if err := decodeRequest(data, &req); err != nil {
+		return err
+	}
+	if s.Events == nil {
+		return grpcErrorf(grpcFailedPrecondition, "event log disabled")
+	}
+	if !req.Follow {
+		for _, event := range s.Events.Events(req.TaskID) {
+			if err := send(newPBEvent(event)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	events, updates, cancel := s.Events.Follow(req.TaskID)
+	defer cancel()
+	for _, event := range events {
+		if err := send(newPBEvent(event)); err != nil {
+			return err
+		}
+	}
+	ticker := time.NewTicker(grpcEventPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-updates:
+			if err := send(newPBEvent(event)); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if record, ok := s.tasks().Get(req.TaskID); !ok || record.CompletedAt == nil {
+				continue
+			}
+			for {
+				select {
+				case event := <-updates:
+					if err := send(newPBEvent(event)); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (s *APIServer) grpcListWorkflows(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+	if s.Workflows == nil {
+		return grpcErrorf(grpcFailedPrecondition, "workflow store disabled")
+	}
+	snapshots, err := s.Workflows.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].UpdatedAt.After(snapshots[j].UpdatedAt) })
+	resp := &pbListWorkflowsResponse{}
+	for _, snapshot := range snapshots {
+		workflow, err := newPBWorkflow(snapshot)
+		if err != nil {
+			return err
+		}
+		resp.Workflows = append(resp.Workflows, workflow)
+	}
+	return send(resp)
+}
+
+func (s *APIServer) grpcListHITL(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+	if s.HITL == nil {
+		return grpcErrorf(grpcFailedPrecondition, "hitl broker disabled")
+	}
+	pending := s.HITL.PendingRequests()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].RequestedAt.Before(pending[j].RequestedAt) })
+	resp := &pbListHITLResponse{}
+	for _, req := range pending {
+		resp.Requests = append(resp.Requests, newPBHITLRequest(req))
+	}
+	return send(resp)
+}
+
+func (s *APIServer) grpcResolveHITL(action string) grpcHandler {
+	return func(ctx context.Context, data []byte, send func(protoMarshaler) error) error {
+		var req pbHITLDecisionRequest
+		if err := decodeRequest(data, &req); err != nil {
+			return err
+		}
+		if s.HITL == nil {
+			return grpcErrorf(grpcFailedPrecondition, "hitl broker disabled")
+		}
+		err := s.resolveHITL(ctx, req.ID, action, HITLDecisionRequest{
+			Scope:  framework.GrantScope(req.Scope),
+			Reason: req.Reason,
+			By:     req.By,
+		})
+		if errors.Is(err, framework.ErrHITLResolved) {
+			return grpcErrorf(grpcFailedPrecondition, "%v", err)
+		}
+		if err != nil {
+			return grpcErrorf(grpcNotFound, "%v", err)
+		}
+		return send(&pbEmpty{})
+	}
+}
+
+// Messages of relurpify.proto; field numbers must match it.
+
+type pbSubmitTaskRequest struct {
+	Instruction string
+	Type        string
+	ContextJSON string
+}
+
+func (m *pbSubmitTaskRequest) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.Instruction)
+	e.string(2, m.Type)
+	e.string(3, m.ContextJSON)
+	return e.buf
+}
+
+func (m *pbSubmitTaskRequest) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Instruction = f.string()
+		case 2:
+			m.Type = f.string()
+		case 3:
+			m.ContextJSON = f.string()
+		}
+		return nil
+	})
+}
+
+type pbGetTaskRequest struct {
+	ID string
+}
+
+func (m *pbGetTaskRequest) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.ID)
+	return e.buf
+}
+
+func (m *pbGetTaskRequest) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		if f.num == 1 {
+			m.ID = f.string()
+		}
+		return nil
+	})
+}
+
+type pbTask struct {
+	ID          string
+	Status      string
+	Type        string
+	Instruction string
+	SubmittedAt int64
+	StartedAt   int64
+	CompletedAt int64
+	ResultJSON  string
+	Error       string
+	ErrorKind   string
+}
+
+func newPBTask(record TaskRecord) *pbTask {
+	task := &pbTask{
+		ID:          record.ID,
+		Status:      string(record.Status),
+		Type:        string(record.Type),
+		Instruction: record.Instruction,
+		SubmittedAt: unixMilli(&record.SubmittedAt),
+		StartedAt:   unixMilli(record.StartedAt),
+		CompletedAt: unixMilli(record.CompletedAt),
+		Error:       record.Error,
+		ErrorKind:   string(record.ErrorKind),
+	}
+	if record.Result != nil {
+		if data, err := json.Marshal(record.Result); err == nil {
+			task.ResultJSON = string(data)
+		}
+	}
+	return task
+}
+
+func (m *pbTask) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.ID)
+	e.string(2, m.Status)
+	e.string(3, m.Type)
+	e.string(4, m.Instruction)
+	e.int64(5, m.SubmittedAt)
+	e.int64(6, m.StartedAt)
+	e.int64(7, m.CompletedAt)
+	e.string(8, m.ResultJSON)
+	e.string(9, m.Error)
+	e.string(10, m.ErrorKind)
+	return e.buf
+}
+
+func (m *pbTask) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ID = f.string()
+		case 2:
+			m.Status = f.string()
+		case 3:
+			m.Type = f.string()
+		case 4:
+			m.Instruction = f.string()
+		case 5:
+			m.SubmittedAt = f.int64()
+		case 6:
+			m.StartedAt = f.int64()
+		case 7:
+			m.CompletedAt = f.int64()
+		case 8:
+			m.ResultJSON = f.string()
+		case 9:
+			m.Error = f.string()
+		case 10:
+			m.ErrorKind = f.string()
+		}
+		return nil
+	})
+}
+
+type pbStreamEventsRequest struct {
+	TaskID string
+	Follow bool
+}
+
+func (m *pbStreamEventsRequest) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.TaskID)
+	e.bool(2, m.Follow)
+	return e.buf
+}
+
+func (m *pbStreamEventsRequest) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.TaskID = f.string()
+		case 2:
+			m.Follow = f.bool()
+		}
+		return nil
+	})
+}
+
+type pbEvent struct {
+	Type         string
+	NodeID       string
+	TaskID       string
+	Message      string
+	Timestamp    int64
+	MetadataJSON string
+}
+
+func newPBEvent(event framework.Event) *pbEvent {
+	msg := &pbEvent{
+		Type:      string(event.Type),
+		NodeID:    event.NodeID,
+		TaskID:    event.TaskID,
+		Message:   event.Message,
+		Timestamp: unixMilli(&event.Timestamp),
+	}
+	if len(event.Metadata) > 0 {
+		if data, err := json.Marshal(event.Metadata); err == nil {
+			msg.MetadataJSON = string(data)
+		}
+	}
+	return msg
+}
+
+func (m *pbEvent) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.Type)
+	e.string(2, m.NodeID)
+	e.string(3, m.TaskID)
+	e.string(4, m.Message)
+	e.int64(5, m.Timestamp)
+	e.string(6, m.MetadataJSON)
+	return e.buf
+}
+
+func (m *pbEvent) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Type = f.string()
+		case 2:
+			m.NodeID = f.string()
+		case 3:
+			m.TaskID = f.string()
+		case 4:
+			m.Message = f.string()
+		case 5:
+			m.Timestamp = f.int64()
+		case 6:
+			m.MetadataJSON = f.string()
+		}
+		return nil
+	})
+}
+
+type pbWorkflow struct {
+	ID           string
+	Status       string
+	Instruction  string
+	UpdatedAt    int64
+	SnapshotJSON string
+}
+
+func newPBWorkflow(snapshot persistence.WorkflowSnapshot) (*pbWorkflow, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	workflow := &pbWorkflow{
+		ID:           snapshot.ID,
+		Status:       string(snapshot.Status),
+		UpdatedAt:    unixMilli(&snapshot.UpdatedAt),
+		SnapshotJSON: string(data),
+	}
+	if snapshot.Task != nil {
+		workflow.Instruction = snapshot.Task.Instruction
+	}
+	return workflow, nil
+}
+
+func (m *pbWorkflow) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.ID)
+	e.string(2, m.Status)
+	e.string(3, m.Instruction)
+	e.int64(4, m.UpdatedAt)
+	e.string(5, m.SnapshotJSON)
+	return e.buf
+}
+
+func (m *pbWorkflow) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ID = f.string()
+		case 2:
+			m.Status = f.string()
+		case 3:
+			m.Instruction = f.string()
+		case 4:
+			m.UpdatedAt = f.int64()
+		case 5:
+			m.SnapshotJSON = f.string()
+		}
+		return nil
+	})
+}
+
+type pbListWorkflowsResponse struct {
+	Workflows []*pbWorkflow
+}
+
+func (m *pbListWorkflowsResponse) marshalProto() []byte {
+	var e protoEncoder
+	for _, workflow := range m.Workflows {
+		e.message(1, workflow)
+	}
+	return e.buf
+}
+
+func (m *pbListWorkflowsResponse) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		workflow := &pbWorkflow{}
+		m.Workflows = append(m.Workflows, workflow)
+		return workflow.unmarshalProto(f.raw)
+	})
+}
+
+type pbHITLRequest struct {
+	ID            string
+	Action        string
+	Resource      string
+	Justification string
+	Risk          string
+	RequestedAt   int64
+	Metadata      map[string]string
+}
+
+func newPBHITLRequest(req *framework.PermissionRequest) *pbHITLRequest {
+	return &pbHITLRequest{
+		ID:            req.ID,
+		Action:        req.Permission.Action,
+		Resource:      req.Permission.Resource,
+		Justification: req.Justification,
+		Risk:          string(req.Risk),
+		RequestedAt:   unixMilli(&req.RequestedAt),
+		Metadata:      req.Permission.Metadata,
+	}
+}
+
+func (m *pbHITLRequest) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.ID)
+	e.string(2, m.Action)
+	e.string(3, m.Resource)
+	e.string(4, m.Justification)
+	e.string(5, m.Risk)
+	e.int64(6, m.RequestedAt)
+	e.stringMap(7, m.Metadata)
+	return e.buf
+}
+
+func (m *pbHITLRequest) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ID = f.string()
+		case 2:
+			m.Action = f.string()
+		case 3:
+			m.Resource = f.string()
+		case 4:
+			m.Justification = f.string()
+		case 5:
+			m.Risk = f.string()
+		case 6:
+			m.RequestedAt = f.int64()
+		case 7:
+			if m.Metadata == nil {
+				m.Metadata = map[string]string{}
+			}
+			return decodeStringMapEntry(f.raw, m.Metadata)
+		}
+		return nil
+	})
+}
+
+type pbListHITLResponse struct {
+	Requests []*pbHITLRequest
+}
+
+func (m *pbListHITLResponse) marshalProto() []byte {
+	var e protoEncoder
+	for _, req := range m.Requests {
+		e.message(1, req)
+	}
+	return e.buf
+}
+
+func (m *pbListHITLResponse) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		req := &pbHITLRequest{}
+		m.Requests = append(m.Requests, req)
+		return req.unmarshalProto(f.raw)
+	})
+}
+
+type pbHITLDecisionRequest struct {
+	ID     string
+	By     string
+	Reason string
+	Scope  string
+}
+
+func (m *pbHITLDecisionRequest) marshalProto() []byte {
+	var e protoEncoder
+	e.string(1, m.ID)
+	e.string(2, m.By)
+	e.string(3, m.Reason)
+	e.string(4, m.Scope)
+	return e.buf
+}
+
+func (m *pbHITLDecisionRequest) unmarshalProto(data []byte) error {
+	return decodeProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ID = f.string()
+		case 2:
+			m.By = f.string()
+		case 3:
+			m.Reason = f.string()
+		case 4:
+			m.Scope = f.string()
+		}
+		return nil
+	})
+}
+
+// pbEmpty stands for the field-less messages: ListWorkflowsRequest,
+// ListHitlRequest, and HitlDecisionResponse.
+type pbEmpty struct{}
+
+func (m *pbEmpty) marshalProto() []byte { return nil }
+
+func (m *pbEmpty) unmarshalProto(data []byte) error { return nil }
+
+func unixMilli(t *time.Time) int64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// relurpifyProto compiles relurpify.proto, so the interop tests speak the
+// published schema rather than the server's own encoders.
+func relurpifyProto(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{})}
+	files, err := compiler.Compile(context.Background(), "relurpify.proto")
+	require.NoError(t, err)
+	service := files[0].Services().ByName("Relurpify")
+	require.NotNil(t, service)
+	return service
+}
+
+// grpcGoClient calls APIServer through grpc-go with dynamic messages built
+// from relurpify.proto.
+type grpcGoClient struct {
+	conn    *grpc.ClientConn
+	service protoreflect.ServiceDescriptor
+}
+
+func newGRPCGoClient(t *testing.T, api *APIServer) *grpcGoClient {
+	t.Helper()
+	url, _ := startGRPCServer(t, api)
+	conn, err := grpc.NewClient(strings.TrimPrefix(url, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &grpcGoClient{conn: conn, service: relurpifyProto(t)}
+}
+
+func (c *grpcGoClient) method(t *testing.T, name string) protoreflect.MethodDescriptor {
+	t.Helper()
+	method := c.service.Methods().ByName(protoreflect.Name(name))
+	require.NotNil(t, method, name)
+	return method
+}
+
+// request builds the input message of method from field values.
+func (c *grpcGoClient) request(t *testing.T, method protoreflect.MethodDescriptor, fields map[string]interface{}) *dynamicpb.Message {
+	t.Helper()
+	msg := dynamicpb.NewMessage(method.Input())
+	for name, value := range fields {
+		field := method.Input().Fields().ByName(protoreflect.Name(name))
+		require.NotNil(t, field, name)
+		msg.Set(field, protoreflect.ValueOf(value))
+	}
+	return msg
+}
+
+func (c *grpcGoClient) call(ctx context.Context, t *testing.T, name string, fields map[string]interface{}) (*dynamicpb.Message, error) {
+	t.Helper()
+	method := c.method(t, name)
+	resp := dynamicpb.NewMessage(method.Output())
+	err := c.conn.Invoke(ctx, grpcServicePath+name, c.request(t, method, fields), resp)
+	return resp, err
+}
+
+func field(msg *dynamicpb.Message, name string) protoreflect.Value {
+	return msg.Get(msg.Descriptor().Fields().ByName(protoreflect.Name(name)))
+}
+
+func TestGRPCGoClientInterop(t *testing.T) {
+	events := NewEventLog()
+	release := make(chan struct{})
+	broker := framework.NewHITLBroker(time.Minute)
+	hitlID := submitHITL(t, broker, framework.PermissionTypeFilesystem, "a.txt")
+	client := newGRPCGoClient(t, &APIServer{
+		Agent:   gatedAgent{events: events, release: release},
+		Context: framework.NewContext(),
+		Events:  events,
+		HITL:    broker,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	task, err := client.call(ctx, t, "SubmitTask", map[string]interface{}{"instruction": "add tests", "context_json": `{"path":"main.go"}`})
+	require.NoError(t, err)
+	taskID := field(task, "id").String()
+	require.NotEmpty(t, taskID)
+	assert.Equal(t, string(framework.TaskTypeCodeModification), field(task, "type").String())
+	assert.Equal(t, "add tests", field(task, "instruction").String())
+
+	require.Eventually(t, func() bool { return len(events.Events(taskID)) == 1 }, time.Second, 5*time.Millisecond)
+	method := client.method(t, "StreamEvents")
+	stream, err := client.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, grpcServicePath+"StreamEvents")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(client.request(t, method, map[string]interface{}{"task_id": taskID, "follow": true})))
+	require.NoError(t, stream.CloseSend())
+	event := dynamicpb.NewMessage(method.Output())
+	require.NoError(t, stream.RecvMsg(event))
+	assert.Equal(t, "started", field(event, "message").String())
+	assert.Equal(t, taskID, field(event, "task_id").String())
+	close(release)
+	require.NoError(t, stream.RecvMsg(event))
+	assert.Equal(t, "finished", field(event, "message").String())
+	assert.Equal(t, io.EOF, stream.RecvMsg(event), "the stream ends with the task")
+
+	task, err = client.call(ctx, t, "GetTask", map[string]interface{}{"id": taskID})
+	require.NoError(t, err)
+	assert.Equal(t, string(TaskStatusSucceeded), field(task, "status").String())
+	assert.Contains(t, field(task, "result_json").String(), `"gated"`)
+	assert.NotZero(t, field(task, "completed_at").Int())
+
+	_, err = client.call(ctx, t, "GetTask", map[string]interface{}{"id": "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	pending, err := client.call(ctx, t, "ListHitl", nil)
+	require.NoError(t, err)
+	requests := field(pending, "requests").List()
+	require.Equal(t, 1, requests.Len())
+	request := requests.Get(0).Message()
+	assert.Equal(t, hitlID, request.Get(request.Descriptor().Fields().ByName("id")).String())
+	assert.Equal(t, "a.txt", request.Get(request.Descriptor().Fields().ByName("resource")).String())
+
+	_, err = client.call(ctx, t, "ApproveHitl", map[string]interface{}{"id": hitlID})
+	require.NoError(t, err)
+	_, err = client.call(ctx, t, "DenyHitl", map[string]interface{}{"id": hitlID, "reason": "late"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "resolving twice fails")
+}
+
+func TestGRPCGoClientAuth(t *testing.T) {
+	client := newGRPCGoClient(t, &APIServer{
+		Agent:   stubAgent{},
+		Context: framework.NewContext(),
+		HITL:    framework.NewHITLBroker(time.Minute),
+		Auth:    &APIAuth{Keys: []APIKey{{Name: "viewer", Key: "view-key", Roles: []APIRole{APIRoleReadOnly}}}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := client.call(ctx, t, "ListHitl", nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer view-key")
+	_, err = client.call(authed, t, "ListHitl", nil)
+	assert.NoError(t, err)
+	_, err = client.call(authed, t, "SubmitTask", map[string]interface{}{"instruction": "x"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "the key lacks submit_tasks")
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// grpcStream reads the framed responses of one call.
+type grpcStream struct {
+	resp *http.Response
+}
+
+func (s grpcStream) next(t *testing.T, msg protoUnmarshaler) bool {
+	t.Helper()
+	var prefix [5]byte
+	if _, err := io.ReadFull(s.resp.Body, prefix[:]); err != nil {
+		return false
+	}
+	data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	_, err := io.ReadFull(s.resp.Body, data)
+	require.NoError(t, err)
+	require.NoError(t, msg.unmarshalProto(data))
+	return true
+}
+
+// status drains the stream and returns its grpc-status trailer.
+func (s grpcStream) status(t *testing.T) string {
+	t.Helper()
+	_, _ = io.Copy(io.Discard, s.resp.Body)
+	s.resp.Body.Close()
+	return s.resp.Trailer.Get("Grpc-Status")
+}
+
+func startGRPCServer(t *testing.T, api *APIServer) (string, *http.Client) {
+	t.Helper()
+	srv := api.newHTTPServer("")
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config.Protocols = srv.Protocols
+	ts.Start()
+	t.Cleanup(ts.Close)
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	return ts.URL, &http.Client{Transport: &http.Transport{Protocols: protocols}}
+}
+
+func callGRPC(t *testing.T, client *http.Client, url, method string, req protoMarshaler) grpcStream {
+	t.Helper()
+	data := req.marshalProto()
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	httpReq, err := http.NewRequest(http.MethodPost, url+grpcServicePath+method, bytes.NewReader(append(frame, data...)))
+	require.NoError(t, err)
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+	resp, err := client.Do(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.ProtoMajor)
+	return grpcStream{resp: resp}
+}
+
+// gatedAgent records an event and waits for release before finishing.
+type gatedAgent struct {
+	stubAgent
+	events  *EventLog
+	release chan struct{}
+}
+
+func (a gatedAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	a.events.Emit(framework.Event{Type: framework.EventNodeStart, TaskID: task.ID, Message: "started"})
+	<-a.release
+	a.events.Emit(framework.Event{Type: framework.EventNodeFinish, TaskID: task.ID, Message: "finished"})
+	return &framework.Result{NodeID: "gated", Success: true}, nil
+}
+
+func TestGRPCSubmitTaskAndFollowEvents(t *testing.T) {
+	events := NewEventLog()
+	release := make(chan struct{})
+	api := &APIServer{
+		Agent:   gatedAgent{events: events, release: release},
+		Context: framework.NewContext(),
+		Events:  events,
+	}
+	url, client := startGRPCServer(t, api)
+
+	call := callGRPC(t, client, url, "SubmitTask", &pbSubmitTaskRequest{Instruction: "add tests", ContextJSON: `{"path":"main.go"}`})
+	var task pbTask
+	require.True(t, call.next(t, &task))
+	assert.Equal(t, "0", call.status(t))
+	assert.NotEmpty(t, task.ID)
+	assert.Equal(t, string(framework.TaskTypeCodeModification), task.Type)
+
+	require.Eventually(t, func() bool { return len(events.Events(task.ID)) == 1 }, time.Second, 5*time.Millisecond)
+	stream := callGRPC(t, client, url, "StreamEvents", &pbStreamEventsRequest{TaskID: task.ID, Follow: true})
+	var event pbEvent
+	require.True(t, stream.next(t, &event))
+	assert.Equal(t, "started", event.Message)
+	close(release)
+	require.True(t, stream.next(t, &event))
+	assert.Equal(t, "finished", event.Message)
+	assert.Equal(t, "0", stream.status(t), "the stream ends with the task")
+
+	call = callGRPC(t, client, url, "GetTask", &pbGetTaskRequest{ID: task.ID})
+	require.True(t, call.next(t, &task))
+	call.status(t)
+	assert.Equal(t, string(TaskStatusSucceeded), task.Status)
+	assert.Contains(t, task.ResultJSON, `"gated"`)
+
+	call = callGRPC(t, client, url, "GetTask", &pbGetTaskRequest{ID: "missing"})
+	assert.Equal(t, "5", call.status(t))
+}
+
+func TestGRPCHITLAndAuth(t *testing.T) {
+	broker := framework.NewHITLBroker(time.Minute)
+	id := submitHITL(t, broker, framework.PermissionTypeFilesystem, "a.txt")
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), HITL: broker}
+	url, client := startGRPCServer(t, api)
+
+	call := callGRPC(t, client, url, "ListHitl", &pbEmpty{})
+	var pending pbListHITLResponse
+	require.True(t, call.next(t, &pending))
+	call.status(t)
+	require.Len(t, pending.Requests, 1)
+	assert.Equal(t, id, pending.Requests[0].ID)
+	assert.Equal(t, "a.txt", pending.Requests[0].Resource)
+
+	call = callGRPC(t, client, url, "ApproveHitl", &pbHITLDecisionRequest{ID: id, By: "ide"})
+	assert.Equal(t, "0", call.status(t))
+	call = callGRPC(t, client, url, "DenyHitl", &pbHITLDecisionRequest{ID: id})
+	assert.Equal(t, "9", call.status(t), "resolving twice fails")
+
+	api.Auth = &APIAuth{Keys: []APIKey{{Name: "viewer", Key: "view-key", Roles: []APIRole{APIRoleReadOnly}}}}
+	url, client = startGRPCServer(t, api)
+	call = callGRPC(t, client, url, "ListHitl", &pbEmpty{})
+	assert.Equal(t, http.StatusUnauthorized, call.resp.StatusCode)
+	call.status(t)
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	in := &pbHITLRequest{ID: "hitl-1", Risk: "high", RequestedAt: 1700000000000, Metadata: map[string]string{"diff": "+x", "args": ""}}
+	var out pbHITLRequest
+	require.NoError(t, out.unmarshalProto(in.marshalProto()))
+	assert.Equal(t, in, &out)
+
+	assert.Error(t, out.unmarshalProto([]byte{0x0a, 0x05, 'a'}), "truncated fields are rejected")
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// HITLDecisionRequest is the optional body of the approve/deny endpoints.
+// The approver is always the authenticated API key; By is only a free-form
+// note, such as the chat user who relayed the decision, kept in the reason.
+type HITLDecisionRequest struct {
+	Scope  framework.GrantScope `json:"scope,omitempty"`
+	Reason string               `json:"reason,omitempty"`
+	By     string               `json:"by,omitempty"`
+}
+
+// registerHITL mounts the approval endpoints. /v1/hitl is the stable path for
+// headless integrations (chat bots, CI); /api/hitl backs the dashboard.
+func (s *APIServer) registerHITL(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/hitl/pending", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleHITL))
+	mux.HandleFunc("/v1/hitl/", s.guard(APIRoleApproveHITL, APIRoleApproveHITL, s.handleHITLDecision))
+	mux.HandleFunc("/api/hitl", s.guard(APIRoleReadOnly, APIRoleReadOnly, s.handleHITL))
+	mux.HandleFunc("/api/hitl/", s.guard(APIRoleApproveHITL, APIRoleApproveHITL, s.handleHITLDecision))
+}
+
+// handleHITL lists pending approval requests, oldest first.
+func (s *APIServer) handleHITL(w http.ResponseWriter, r *http.Request) {
+	if s.HITL == nil {
+		http.Error(w, "hitl broker disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pending := s.HITL.PendingRequests()
+	if pending == nil {
+		pending = []*framework.PermissionRequest{}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].RequestedAt.Before(pending[j].RequestedAt) })
+	writeJSON(w, pending)
+}
+
+// handleHITLDecision resolves {prefix}/{id}/approve and {prefix}/{id}/deny.
+func (s *APIServer) handleHITLDecision(w http.ResponseWriter, r *http.Request) {
+	if s.HITL == nil {
+		http.Error(w, "hitl broker disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/hitl/"), "/api/hitl/")
+	id, action, ok := strings.Cut(strings.Trim(rest, "/"), "/")
+	if !ok || id == "" {
+		http.Error(w, "want /v1/hitl/{id}/approve or /v1/hitl/{id}/deny", http.StatusNotFound)
+		return
+	}
+	var req HITLDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.resolveHITL(r.Context(), id, action, req); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, framework.ErrHITLResolved) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveHITL approves or denies request id; action is "approve" or "deny".
+func (s *APIServer) resolveHITL(ctx context.Context, id, action string, req HITLDecisionRequest) error {
+	switch action {
+	case "approve":
+		approver := apiCaller(ctx)
+		if approver == "" {
+			approver = "api"
+		}
+		return s.HITL.Approve(framework.PermissionDecision{
+			RequestID:  id,
+			Approved:   true,
+			ApprovedBy: approver,
+			Scope:      req.Scope,
+			Reason:     decisionNote(req.Reason, req.By),
+		})
+	case "deny":
+		reason := decisionNote(req.Reason, req.By)
+		if reason == "" {
+			reason = "denied via api"
+		}
+		return s.HITL.Deny(id, reason)
+	default:
+		return fmt.Errorf("unknown hitl action %s", action)
+	}
+}
+
+// decisionNote joins a decision's reason with the caller's free-form by note.
+func decisionNote(reason, by string) string {
+	if by == "" {
+		return reason
+	}
+	if reason == "" {
+		return "by " + by
+	}
+	return reason + " (by " + by + ")"
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func submitHITL(t *testing.T, broker *framework.HITLBroker, kind framework.PermissionType, resource string) string {
+	t.Helper()
+	id, err := broker.SubmitAsync(framework.PermissionRequest{
+		Permission:    framework.PermissionDescriptor{Type: kind, Action: "write", Resource: resource},
+		Justification: "agent wants to write " + resource,
+		Scope:         framework.GrantScopeOneTime,
+		Risk:          framework.RiskLevelMedium,
+	})
+	require.NoError(t, err)
+	return id
+}
+
+func TestHITLApproveAndDenyOverHTTP(t *testing.T) {
+	broker := framework.NewHITLBroker(time.Minute)
+	approveID := submitHITL(t, broker, framework.PermissionTypeFilesystem, "a.txt")
+	denyID := submitHITL(t, broker, framework.PermissionTypeExecutable, "rm")
+	updates, cancel := broker.Subscribe(4)
+	defer cancel()
+	handler := (&APIServer{HITL: broker}).newHTTPServer("").Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/hitl/pending", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var pending []framework.PermissionRequest
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pending))
+	require.Len(t, pending, 2)
+	assert.Equal(t, approveID, pending[0].ID)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/hitl/"+approveID+"/approve", bytes.NewReader([]byte(`{"by":"ci"}`))))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/hitl/"+denyID+"/deny", bytes.NewReader([]byte(`{"reason":"too risky"}`))))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	decisions := map[string]*framework.PermissionDecision{}
+	for len(decisions) < 2 {
+		select {
+		case event := <-updates:
+			if event.Decision != nil {
+				decisions[event.Decision.RequestID] = event.Decision
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for hitl decisions")
+		}
+	}
+	assert.True(t, decisions[approveID].Approved)
+	assert.Equal(t, "api", decisions[approveID].ApprovedBy, "by is not the approver")
+	assert.Equal(t, "by ci", decisions[approveID].Reason)
+	assert.False(t, decisions[denyID].Approved)
+	assert.Equal(t, "too risky", decisions[denyID].Reason)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/hitl/"+approveID+"/approve", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code, "resolving twice should conflict")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/hitl/missing/approve", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/hitl/"+denyID+"/maybe", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/hitl/"+approveID+"/approve", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHITLEndpointsDisabledWithoutBroker(t *testing.T) {
+	handler := (&APIServer{}).newHTTPServer("").Handler
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/hitl/pending", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/hitl/x/approve", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHITLWebhookPayload(t *testing.T) {
+	hook := &HITLWebhook{PublicURL: "https://agent.example/"}
+	req := &framework.PermissionRequest{
+		ID:         "hitl-1",
+		Permission: framework.PermissionDescriptor{Type: framework.PermissionTypeExecutable, Action: "exec", Resource: "make"},
+		Risk:       framework.RiskLevelHigh,
+	}
+	payload := hook.Payload(framework.HITLEvent{Type: framework.HITLEventRequested, Request: req})
+	assert.Equal(t, "https://agent.example/v1/hitl/hitl-1/approve", payload.ApproveURL)
+	assert.Equal(t, "https://agent.example/v1/hitl/hitl-1/deny", payload.DenyURL)
+	assert.Contains(t, payload.Text, "approval needed for executable exec make")
+
+	payload = hook.Payload(framework.HITLEvent{
+		Type:     framework.HITLEventResolved,
+		Request:  req,
+		Decision: &framework.PermissionDecision{RequestID: "hitl-1", Approved: true, ApprovedBy: "ci"},
+	})
+	assert.Empty(t, payload.ApproveURL)
+	assert.Contains(t, payload.Text, "approved by ci")
+}
+
+func TestHITLWebhookSignsAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	var body []byte
+	var signature string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Relurpify-Signature")
+	}))
+	defer collector.Close()
+
+	hook := &HITLWebhook{
+		URL:    collector.URL,
+		Secret: "s3cret",
+		Retry:  framework.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}
+	event := framework.HITLEvent{Type: framework.HITLEventRequested, Request: &framework.PermissionRequest{ID: "hitl-2"}}
+	require.NoError(t, hook.Notify(context.Background(), event))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+	var payload HITLWebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, framework.HITLEventRequested, payload.Event)
+	assert.Equal(t, "hitl-2", payload.Request.ID)
+}
+
+func TestRunHITLWebhooksForwardsSubscribedEvents(t *testing.T) {
+	received := make(chan HITLWebhookPayload, 4)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload HITLWebhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer collector.Close()
+
+	broker := framework.NewHITLBroker(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hook := &HITLWebhook{URL: collector.URL, Events: []framework.HITLEventType{framework.HITLEventRequested}}
+	done := make(chan struct{})
+	go func() {
+		RunHITLWebhooks(ctx, broker, []*HITLWebhook{hook})
+		close(done)
+	}()
+
+	// Requests submitted before the subscription exists are not forwarded,
+	// so resubmit until one arrives.
+	var id string
+	require.Eventually(t, func() bool {
+		id = submitHITL(t, broker, framework.PermissionTypeFilesystem, "b.txt")
+		select {
+		case payload := <-received:
+			return payload.Request != nil && payload.Request.ID == id
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 2*time.Second, time.Millisecond)
+	require.NoError(t, broker.Deny(id, "no"))
+
+	deadline := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case payload := <-received:
+			assert.Equal(t, framework.HITLEventRequested, payload.Event, "resolved events should be filtered")
+			continue
+		case <-deadline:
+		}
+		break
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// HITLWebhook posts HITL lifecycle events to an external endpoint so
+// approvals can be handled from chat or CI when no TUI is attached. Payloads
+// carry a Slack-compatible "text" field, so a Slack incoming webhook URL works
+// as-is; richer integrations read the structured fields and answer through
+// /v1/hitl/{id}/approve or /v1/hitl/{id}/deny.
+type HITLWebhook struct {
+	URL     string
+	Headers map[string]string
+	// Secret, when set, signs each body with HMAC-SHA256; the hex digest is
+	// sent as "X-Relurpify-Signature: sha256=<digest>".
+	Secret string
+	// PublicURL is the API server's externally reachable base URL. When set,
+	// payloads include ready-made approve and deny URLs.
+	PublicURL string
+	// Events limits which lifecycle events are sent; empty sends all.
+	Events []framework.HITLEventType
+	Client *http.Client
+	Retry  framework.RetryPolicy
+	Logger *log.Logger
+}
+
+// HITLWebhookPayload is the JSON body posted for each event.
+type HITLWebhookPayload struct {
+	Event      framework.HITLEventType       `json:"event"`
+	Text       string                        `json:"text"`
+	Request    *framework.PermissionRequest  `json:"request,omitempty"`
+	Decision   *framework.PermissionDecision `json:"decision,omitempty"`
+	Error      string                        `json:"error,omitempty"`
+	ApproveURL string                        `json:"approve_url,omitempty"`
+	DenyURL    string                        `json:"deny_url,omitempty"`
+	Timestamp  time.Time                     `json:"timestamp"`
+}
+
+// hitlWebhookError exposes the response status so framework.Retry treats 5xx
+// and 429 answers as transient.
+type hitlWebhookError struct {
+	status int
+	text   string
+}
+
+func (e *hitlWebhookError) Error() string {
+	return fmt.Sprintf("hitl webhook returned %s", e.text)
+}
+
+func (e *hitlWebhookError) StatusCode() int { return e.status }
+
+// wants reports whether the webhook subscribes to eventType.
+func (h *HITLWebhook) wants(eventType framework.HITLEventType) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, t := range h.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload builds the body sent for event.
+func (h *HITLWebhook) Payload(event framework.HITLEvent) HITLWebhookPayload {
+	payload := HITLWebhookPayload{
+		Event:     event.Type,
+		Request:   event.Request,
+		Decision:  event.Decision,
+		Error:     event.Error,
+		Timestamp: time.Now().UTC(),
+	}
+	if event.Request == nil {
+		payload.Text = fmt.Sprintf("relurpify: hitl %s", event.Type)
+		return payload
+	}
+	req := event.Request
+	subject := strings.TrimSpace(fmt.Sprintf("%s %s %s", req.Permission.Type, req.Permission.Action, req.Permission.Resource))
+	switch event.Type {
+	case framework.HITLEventRequested:
+		payload.Text = fmt.Sprintf("relurpify: approval needed for %s (risk %s, request %s)", subject, req.Risk, req.ID)
+		if req.Justification != "" {
+			payload.Text += ": " + req.Justification
+		}
+		if base := strings.TrimRight(h.PublicURL, "/"); base != "" {
+			id := url.PathEscape(req.ID)
+			payload.ApproveURL = base + "/v1/hitl/" + id + "/approve"
+			payload.DenyURL = base + "/v1/hitl/" + id + "/deny"
+			payload.Text += fmt.Sprintf("\napprove: POST %s\ndeny: POST %s", payload.ApproveURL, payload.DenyURL)
+		}
+	case framework.HITLEventResolved:
+		outcome := "denied"
+		if event.Decision != nil && event.Decision.Approved {
+			outcome = "approved"
+			if event.Decision.ApprovedBy != "" {
+				outcome += " by " + event.Decision.ApprovedBy
+			}
+		}
+		payload.Text = fmt.Sprintf("relurpify: %s %s (request %s)", subject, outcome, req.ID)
+	default:
+		payload.Text = fmt.Sprintf("relurpify: approval for %s %s (request %s)", subject, event.Type, req.ID)
+		if event.Error != "" {
+			payload.Text += ": " + event.Error
+		}
+	}
+	return payload
+}
+
+// Notify posts event, retrying transient failures per h.Retry. Events the
+// webhook does not subscribe to are skipped.
+func (h *HITLWebhook) Notify(ctx context.Context, event framework.HITLEvent) error {
+	if !h.wants(event.Type) {
+		return nil
+	}
+	body, err := json.Marshal(h.Payload(event))
+	if err != nil {
+		return err
+	}
+	return framework.Retry(ctx, h.Retry, nil, "hitl webhook", func() error {
+		return h.post(ctx, body)
+	})
+}
+
+func (h *HITLWebhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.Headers {
+		req.Header.Set(key, value)
+	}
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Relurpify-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &hitlWebhookError{status: resp.StatusCode, text: resp.Status}
+	}
+	return nil
+}
+
+// RunHITLWebhooks forwards broker events to hooks until ctx is cancelled.
+// Each hook is delivered to in order on its own goroutine so a slow endpoint
+// does not delay the others; failures are logged and dropped.
+func RunHITLWebhooks(ctx context.Context, broker *framework.HITLBroker, hooks []*HITLWebhook) {
+	if broker == nil || len(hooks) == 0 {
+		return
+	}
+	events, cancel := broker.Subscribe(64)
+	defer cancel()
+	queues := make([]chan framework.HITLEvent, len(hooks))
+	for i, hook := range hooks {
+		queues[i] = make(chan framework.HITLEvent, 64)
+		go func(hook *HITLWebhook, queue <-chan framework.HITLEvent) {
+			for event := range queue {
+				if err := hook.Notify(ctx, event); err != nil && hook.Logger != nil {
+					hook.Logger.Printf("hitl webhook %s: %v", hook.URL, err)
+				}
+			}
+		}(hook, queues[i])
+	}
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			for i, queue := range queues {
+				select {
+				case queue <- event:
+				default:
+					if hooks[i].Logger != nil {
+						hooks[i].Logger.Printf("hitl webhook %s: queue full, dropping %s event", hooks[i].URL, event.Type)
+					}
+				}
+			}
+		}
+	}
+}
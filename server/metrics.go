@@ -0,0 +1,331 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Metrics collects Prometheus metrics for /metrics. It is a framework.Telemetry
+// sink for LLM and tool events; task outcomes are recorded with ObserveTask
+// and HITL wait times with WatchHITL. Output uses the Prometheus text
+// exposition format, so no client library is needed.
+type Metrics struct {
+	// Memory, when set, is sampled at scrape time for the record count per
+	// scope.
+	Memory framework.MemoryStore
+
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+// Metric names.
+const (
+	metricTasks         = "relurpify_tasks_total"
+	metricTaskDuration  = "relurpify_task_duration_seconds"
+	metricLLMDuration   = "relurpify_llm_request_duration_seconds"
+	metricToolDuration  = "relurpify_tool_duration_seconds"
+	metricDenials       = "relurpify_permission_denials_total"
+	metricHITLWait      = "relurpify_hitl_wait_seconds"
+	metricMemoryRecords = "relurpify_memory_records"
+)
+
+const (
+	metricKindCounter   = "counter"
+	metricKindHistogram = "histogram"
+	metricKindGauge     = "gauge"
+)
+
+// memorySampleTimeout bounds the memory scan done on each scrape.
+const memorySampleTimeout = 5 * time.Second
+
+var (
+	// latencyBuckets suits tool calls and LLM requests, which range from
+	// milliseconds to minutes on local models.
+	latencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	// longBuckets suits whole tasks and human approvals.
+	longBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+)
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type metricSeries struct {
+	values []string
+	// value is the counter total or, for histograms, the sum.
+	value  float64
+	count  uint64
+	counts []uint64
+}
+
+// NewMetrics builds an empty metric set.
+func NewMetrics() *Metrics {
+	m := &Metrics{families: make(map[string]*metricFamily)}
+	m.define(metricTasks, "Tasks finished, by task type and status.", metricKindCounter, nil, "type", "status")
+	m.define(metricTaskDuration, "Task run time in seconds, by task type.", metricKindHistogram, longBuckets, "type")
+	m.define(metricLLMDuration, "LLM request latency in seconds, by call kind and status.", metricKindHistogram, latencyBuckets, "kind", "status")
+	m.define(metricToolDuration, "Tool execution latency in seconds, by tool and status.", metricKindHistogram, latencyBuckets, "tool", "status")
+	m.define(metricDenials, "Tool calls blocked by permission checks, by tool.", metricKindCounter, nil, "tool")
+	m.define(metricHITLWait, "Time HITL requests waited for a decision in seconds, by outcome.", metricKindHistogram, longBuckets, "outcome")
+	return m
+}
+
+func (m *Metrics) define(name, help, kind string, buckets []float64, labels ...string) {
+	m.families[name] = &metricFamily{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*metricSeries),
+	}
+}
+
+// add increments a counter, or observes v for a histogram.
+func (m *Metrics) add(name string, v float64, values ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	family := m.families[name]
+	key := strings.Join(values, "\xff")
+	s, ok := family.series[key]
+	if !ok {
+		s = &metricSeries{values: values}
+		if family.kind == metricKindHistogram {
+			s.counts = make([]uint64, len(family.buckets))
+		}
+		family.series[key] = s
+	}
+	s.value += v
+	if family.kind != metricKindHistogram {
+		return
+	}
+	s.count++
+	for i, bound := range family.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+}
+
+// ObserveTask records a finished task from the agent's return values. The
+// status label is "success", "error", or framework.ResultStatusTimedOut.
+func (m *Metrics) ObserveTask(task *framework.Task, result *framework.Result, err error, elapsed time.Duration) {
+	if m == nil || task == nil {
+		return
+	}
+	status := "success"
+	switch {
+	case framework.IsTimeout(err):
+		status = framework.ResultStatusTimedOut
+	case err != nil, result != nil && !result.Success:
+		status = "error"
+	}
+	m.add(metricTasks, 1, string(task.Type), status)
+	m.add(metricTaskDuration, elapsed.Seconds(), string(task.Type))
+}
+
+// Emit implements framework.Telemetry.
+func (m *Metrics) Emit(event framework.Event) {
+	if m == nil {
+		return
+	}
+	switch event.Type {
+	case framework.EventLLMResponse:
+		status := "success"
+		if _, failed := event.Metadata["error"]; failed {
+			status = "error"
+		}
+		if elapsed, ok := eventDuration(event); ok {
+			m.add(metricLLMDuration, elapsed.Seconds(), metadataString(event, "kind"), status)
+		}
+	case framework.EventToolResult:
+		tool := metadataString(event, "tool")
+		if denied, _ := event.Metadata["denied"].(bool); denied {
+			m.add(metricDenials, 1, tool)
+		}
+		status := "success"
+		if _, failed := event.Metadata["error"]; failed {
+			status = "error"
+		} else if ok, _ := event.Metadata["success"].(bool); !ok {
+			status = "failure"
+		}
+		// Calls denied before running carry no duration.
+		if elapsed, ok := eventDuration(event); ok {
+			m.add(metricToolDuration, elapsed.Seconds(), tool, status)
+		}
+	}
+}
+
+func eventDuration(event framework.Event) (time.Duration, bool) {
+	switch v := event.Metadata["duration_ms"].(type) {
+	case int64:
+		return time.Duration(v) * time.Millisecond, true
+	case int:
+		return time.Duration(v) * time.Millisecond, true
+	case float64:
+		return time.Duration(v * float64(time.Millisecond)), true
+	}
+	return 0, false
+}
+
+func metadataString(event framework.Event, key string) string {
+	if v, ok := event.Metadata[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// WatchHITL records how long each HITL request on broker waited until it was
+// approved, denied, or expired. It returns when ctx ends.
+func (m *Metrics) WatchHITL(ctx context.Context, broker *framework.HITLBroker) {
+	if m == nil || broker == nil {
+		return
+	}
+	events, cancel := broker.Subscribe(64)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			m.observeHITL(event, time.Now())
+		}
+	}
+}
+
+func (m *Metrics) observeHITL(event framework.HITLEvent, now time.Time) {
+	if event.Request == nil || event.Request.RequestedAt.IsZero() {
+		return
+	}
+	var outcome string
+	switch {
+	case event.Type == framework.HITLEventExpired:
+		outcome = "expired"
+	case event.Type != framework.HITLEventResolved:
+		return
+	case event.Decision != nil && event.Decision.Approved:
+		outcome = "approved"
+	default:
+		outcome = "denied"
+	}
+	m.add(metricHITLWait, now.Sub(event.Request.RequestedAt).Seconds(), outcome)
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	m.mu.Lock()
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeFamily(&b, m.families[name])
+	}
+	m.mu.Unlock()
+	m.writeMemory(&b)
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeFamily(b *strings.Builder, family *metricFamily) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := family.series[key]
+		labels := formatLabels(family.labels, s.values)
+		if family.kind != metricKindHistogram {
+			fmt.Fprintf(b, "%s%s %s\n", family.name, labels, formatValue(s.value))
+			continue
+		}
+		for i, bound := range family.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", family.name, withLabel(labels, "le", formatValue(bound)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", family.name, withLabel(labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", family.name, labels, formatValue(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", family.name, labels, s.count)
+	}
+}
+
+// writeMemory samples the memory store. A scope that fails to load is left
+// out rather than reported as empty.
+func (m *Metrics) writeMemory(b *strings.Builder) {
+	if m.Memory == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), memorySampleTimeout)
+	defer cancel()
+	fmt.Fprintf(b, "# HELP %s Records in the memory store, by scope.\n# TYPE %s %s\n", metricMemoryRecords, metricMemoryRecords, metricKindGauge)
+	for _, scope := range []framework.MemoryScope{framework.MemoryScopeGlobal, framework.MemoryScopeUser, framework.MemoryScopeProject, framework.MemoryScopeSession} {
+		records, err := m.Memory.Search(ctx, "", scope)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %d\n", metricMemoryRecords, formatLabels([]string{"scope"}, []string{string(scope)}), len(records))
+	}
+}
+
+// labelEscaper escapes label values as the text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func withLabel(labels, name, value string) string {
+	label := name + `="` + labelEscaper.Replace(value) + `"`
+	if labels == "" {
+		return "{" + label + "}"
+	}
+	return labels[:len(labels)-1] + "," + label + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ServeHTTP serves the metrics at /metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(w)
+}
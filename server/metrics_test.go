@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestMetricsFromTelemetryAndTasks(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Emit(framework.Event{Type: framework.EventLLMResponse, Metadata: map[string]interface{}{"kind": "chat", "duration_ms": int64(1500)}})
+	metrics.Emit(framework.Event{Type: framework.EventLLMResponse, Metadata: map[string]interface{}{"kind": "chat", "duration_ms": int64(20), "error": "refused"}})
+	metrics.Emit(framework.Event{Type: framework.EventToolResult, Metadata: map[string]interface{}{"tool": "file_read", "duration_ms": int64(3), "success": true}})
+	metrics.Emit(framework.Event{Type: framework.EventToolResult, Metadata: map[string]interface{}{"tool": "exec_run_tests", "duration_ms": int64(4000), "success": false}})
+	metrics.Emit(framework.Event{Type: framework.EventToolResult, Metadata: map[string]interface{}{"tool": "file_write", "denied": true, "error": "blocked"}})
+	metrics.Emit(framework.Event{Type: framework.EventNodeStart})
+
+	task := &framework.Task{Type: framework.TaskTypeAnalysis}
+	metrics.ObserveTask(task, &framework.Result{Success: true}, nil, 2*time.Second)
+	metrics.ObserveTask(task, nil, errors.New("boom"), time.Second)
+	metrics.ObserveTask(task, nil, &framework.TimeoutError{}, time.Second)
+
+	requested := time.Now().Add(-90 * time.Second)
+	metrics.observeHITL(framework.HITLEvent{
+		Type:     framework.HITLEventResolved,
+		Request:  &framework.PermissionRequest{RequestedAt: requested},
+		Decision: &framework.PermissionDecision{Approved: true},
+	}, requested.Add(90*time.Second))
+	metrics.observeHITL(framework.HITLEvent{Type: framework.HITLEventRequested, Request: &framework.PermissionRequest{RequestedAt: requested}}, time.Now())
+
+	api := &APIServer{Agent: stubAgent{}, Context: framework.NewContext(), Metrics: metrics}
+	rec := httptest.NewRecorder()
+	api.newHTTPServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "version=0.0.4")
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		"# TYPE relurpify_tasks_total counter",
+		`relurpify_tasks_total{type="analysis",status="success"} 1`,
+		`relurpify_tasks_total{type="analysis",status="error"} 1`,
+		`relurpify_tasks_total{type="analysis",status="timed_out"} 1`,
+		`relurpify_task_duration_seconds_count{type="analysis"} 3`,
+		`relurpify_llm_request_duration_seconds_bucket{kind="chat",status="success",le="1"} 0`,
+		`relurpify_llm_request_duration_seconds_bucket{kind="chat",status="success",le="2.5"} 1`,
+		`relurpify_llm_request_duration_seconds_sum{kind="chat",status="success"} 1.5`,
+		`relurpify_llm_request_duration_seconds_count{kind="chat",status="error"} 1`,
+		`relurpify_tool_duration_seconds_count{tool="file_read",status="success"} 1`,
+		`relurpify_tool_duration_seconds_bucket{tool="exec_run_tests",status="failure",le="+Inf"} 1`,
+		`relurpify_permission_denials_total{tool="file_write"} 1`,
+		`relurpify_hitl_wait_seconds_sum{outcome="approved"} 90`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, `tool="file_write",status`, "calls denied before running have no latency")
+	assert.NotContains(t, body, "relurpify_memory_records", "memory is only reported when set")
+}
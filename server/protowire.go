@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// A minimal protobuf encoding for the gRPC messages in relurpify.proto:
+// strings, bools, int64s, nested messages, and string maps are all it needs.
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+type protoEncoder struct{ buf []byte }
+
+func (e *protoEncoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (e *protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// string, int64, and bool skip zero values like proto3 does.
+func (e *protoEncoder) string(field int, s string) {
+	if s != "" {
+		e.bytes(field, []byte(s))
+	}
+}
+
+func (e *protoEncoder) int64(field int, v int64) {
+	if v != 0 {
+		e.tag(field, wireVarint)
+		e.buf = binary.AppendUvarint(e.buf, uint64(v))
+	}
+}
+
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.tag(field, wireVarint)
+		e.buf = append(e.buf, 1)
+	}
+}
+
+// message always writes, so repeated fields keep empty elements.
+func (e *protoEncoder) message(field int, m protoMarshaler) {
+	e.bytes(field, m.marshalProto())
+}
+
+// stringMap encodes a map<string, string> as sorted key/value entries.
+func (e *protoEncoder) stringMap(field int, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry protoEncoder
+		entry.string(1, k)
+		entry.string(2, m[k])
+		e.bytes(field, entry.buf)
+	}
+}
+
+type protoMarshaler interface {
+	marshalProto() []byte
+}
+
+type protoUnmarshaler interface {
+	unmarshalProto(data []byte) error
+}
+
+// protoField is one decoded field: numeric values land in u and length
+// delimited ones in raw.
+type protoField struct {
+	num  int
+	wire int
+	u    uint64
+	raw  []byte
+}
+
+func (f protoField) string() string { return string(f.raw) }
+func (f protoField) int64() int64   { return int64(f.u) }
+func (f protoField) bool() bool     { return f.u != 0 }
+
+// decodeProto calls fn for each field of data; fields fn ignores are skipped,
+// so older servers accept newer clients.
+func decodeProto(data []byte, fn func(protoField) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+		f := protoField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.u, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			data = data[n:]
+		case wireI64:
+			if len(data) < 8 {
+				return errProtoTruncated
+			}
+			f.u = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireI32:
+			if len(data) < 4 {
+				return errProtoTruncated
+			}
+			f.u = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errProtoTruncated
+			}
+			f.raw = data[n : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", f.wire)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeStringMapEntry adds one map<string, string> entry to m.
+func decodeStringMapEntry(raw []byte, m map[string]string) error {
+	var key, value string
+	err := decodeProto(raw, func(f protoField) error {
+		switch f.num {
+		case 1:
+			key = f.string()
+		case 2:
+			value = f.string()
+		}
+		return nil
+	})
+	m[key] = value
+	return err
+}
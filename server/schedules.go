@@ -0,0 +1,390 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+// scheduleContextKey marks queued tasks submitted by a schedule; its value
+// is the schedule ID.
+const scheduleContextKey = "schedule"
+
+// scheduleHistoryLimit bounds the runs reported for one schedule.
+const scheduleHistoryLimit = 20
+
+var scheduleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// RecipeResolver renders the named recipe with params into a task, so
+// schedules can run reviewed recipes rather than free-form instructions.
+type RecipeResolver func(name string, params map[string]string) (*framework.Task, error)
+
+// ScheduleStatus is a schedule as served by /api/schedules, with its next
+// run and, for a single schedule, its recent runs newest first.
+type ScheduleStatus struct {
+	persistence.Schedule
+	NextRunAt *time.Time                     `json:"next_run_at,omitempty"`
+	History   []persistence.WorkflowSnapshot `json:"history,omitempty"`
+}
+
+func (s *APIServer) registerSchedules(mux *http.ServeMux) {
+	mux.HandleFunc("/api/schedules", s.guard(APIRoleReadOnly, APIRoleSubmitTasks, s.handleSchedules))
+	mux.HandleFunc("/api/schedules/", s.guard(APIRoleReadOnly, APIRoleSubmitTasks, s.handleSchedule))
+}
+
+// runScheduler submits due schedules once a minute until ctx is cancelled.
+// Runs missed while the server was down fire once when it starts.
+func (s *APIServer) runScheduler(ctx context.Context) {
+	if s.Schedules == nil {
+		return
+	}
+	for {
+		now := time.Now()
+		s.fireDueSchedules(ctx, now)
+		timer := time.NewTimer(time.Until(now.Truncate(time.Minute).Add(time.Minute)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// fireDueSchedules submits every active schedule whose next run is at or
+// before now.
+func (s *APIServer) fireDueSchedules(ctx context.Context, now time.Time) {
+	s.scheduleMu.Lock()
+	defer s.scheduleMu.Unlock()
+	schedules, err := s.Schedules.List(ctx)
+	if err != nil {
+		s.logf("schedules unavailable: %v", err)
+		return
+	}
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.Paused {
+			continue
+		}
+		next := nextScheduleRun(schedule)
+		if next == nil || next.After(now) {
+			continue
+		}
+		s.fireSchedule(ctx, schedule, now)
+	}
+}
+
+// fireSchedule queues one run of schedule and records it. A run that cannot
+// be queued is recorded as failed and still counts as the schedule's run,
+// so a broken schedule fails once per cron time rather than every minute.
+func (s *APIServer) fireSchedule(ctx context.Context, schedule *persistence.Schedule, now time.Time) {
+	task, err := s.scheduledTask(schedule)
+	if task == nil {
+		task = &framework.Task{Instruction: schedule.Instruction, Type: schedule.Type}
+	}
+	task.ID = fmt.Sprintf("schedule-%s-%s", schedule.ID, now.UTC().Format("20060102T150405"))
+	if task.Context == nil {
+		task.Context = map[string]interface{}{}
+	}
+	task.Context[scheduleContextKey] = schedule.ID
+	if err == nil {
+		queue := s.tasks()
+		queue.Start(ctx)
+		_, err = queue.Submit(task)
+	}
+	if err != nil {
+		s.logf("schedule %s: %v", schedule.ID, err)
+		s.recordScheduledRun(ctx, task, persistence.WorkflowStatusFailed, err)
+	} else {
+		s.recordScheduledRun(ctx, task, persistence.WorkflowStatusPending, nil)
+	}
+	ran := now.UTC()
+	schedule.LastRunAt = &ran
+	schedule.LastTaskID = task.ID
+	if err := s.Schedules.Save(ctx, schedule); err != nil {
+		s.logf("schedule %s: %v", schedule.ID, err)
+	}
+}
+
+// scheduledTask builds the task a schedule submits.
+func (s *APIServer) scheduledTask(schedule *persistence.Schedule) (*framework.Task, error) {
+	if schedule.Recipe != "" {
+		if s.Recipes == nil {
+			return nil, errors.New("recipes are not available on this server")
+		}
+		task, err := s.Recipes(schedule.Recipe, schedule.Params)
+		if err != nil {
+			return nil, err
+		}
+		if task.Context == nil {
+			task.Context = map[string]interface{}{}
+		}
+		task.Context["recipe"] = schedule.Recipe
+		return task, nil
+	}
+	taskType := schedule.Type
+	if taskType == "" {
+		taskType = framework.TaskTypeCodeModification
+	}
+	taskContext := make(map[string]interface{}, len(schedule.Context)+1)
+	for key, value := range schedule.Context {
+		taskContext[key] = value
+	}
+	return &framework.Task{Type: taskType, Instruction: schedule.Instruction, Context: taskContext}, nil
+}
+
+// runQueuedTask runs a queued task, recording scheduled runs in the
+// workflow store as they start and finish.
+func (s *APIServer) runQueuedTask(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+	if scheduleOf(task) == "" {
+		return s.runTask(ctx, task)
+	}
+	s.recordScheduledRun(ctx, task, persistence.WorkflowStatusRunning, nil)
+	result, err := s.runTask(ctx, task)
+	status := persistence.WorkflowStatusCompleted
+	if framework.IsTimeout(err) {
+		status = persistence.WorkflowStatusTimedOut
+	} else if framework.ErrorKindOf(err) == framework.ErrorKindBudgetExceeded {
+		status = persistence.WorkflowStatusBudgetExceeded
+	} else if err != nil {
+		status = persistence.WorkflowStatusFailed
+	}
+	s.recordScheduledRun(ctx, task, status, err)
+	return result, err
+}
+
+// recordScheduledRun saves a scheduled run to the workflow store, with its
+// token usage once it has finished.
+func (s *APIServer) recordScheduledRun(ctx context.Context, task *framework.Task, status persistence.WorkflowStatus, runErr error) {
+	if s.Workflows == nil {
+		return
+	}
+	snapshot := &persistence.WorkflowSnapshot{
+		ID:       task.ID,
+		Task:     task,
+		Status:   status,
+		Metadata: map[string]interface{}{scheduleContextKey: scheduleOf(task)},
+	}
+	if runErr != nil {
+		snapshot.Metadata["error"] = runErr.Error()
+	}
+	if s.Usage != nil && status != persistence.WorkflowStatusPending && status != persistence.WorkflowStatusRunning {
+		summary := s.Usage.Summary(framework.UsageFilter{TaskID: task.ID})
+		snapshot.Usage = &summary
+	}
+	if err := s.Workflows.Save(context.WithoutCancel(ctx), snapshot); err != nil {
+		s.logf("schedule run %s: %v", task.ID, err)
+	}
+}
+
+func scheduleOf(task *framework.Task) string {
+	if task == nil || task.Context == nil {
+		return ""
+	}
+	id, _ := task.Context[scheduleContextKey].(string)
+	return id
+}
+
+// nextScheduleRun is the first cron time after the schedule last ran, was
+// resumed, or was created. It is nil when the expression never fires.
+func nextScheduleRun(schedule *persistence.Schedule) *time.Time {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil
+	}
+	since := schedule.CreatedAt
+	for _, t := range []*time.Time{schedule.LastRunAt, schedule.ResumedAt} {
+		if t != nil && t.After(since) {
+			since = *t
+		}
+	}
+	next := cron.Next(since.In(time.Local))
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+func (s *APIServer) scheduleStatus(schedule persistence.Schedule) ScheduleStatus {
+	status := ScheduleStatus{Schedule: schedule}
+	if !schedule.Paused {
+		status.NextRunAt = nextScheduleRun(&schedule)
+	}
+	return status
+}
+
+// handleSchedules lists schedules (GET) or creates one (POST).
+func (s *APIServer) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if s.Schedules == nil {
+		http.Error(w, "schedules disabled", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := s.Schedules.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		statuses := make([]ScheduleStatus, 0, len(schedules))
+		for _, schedule := range schedules {
+			statuses = append(statuses, s.scheduleStatus(schedule))
+		}
+		writeJSON(w, statuses)
+	case http.MethodPost:
+		var schedule persistence.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.validateSchedule(&schedule); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.scheduleMu.Lock()
+		defer s.scheduleMu.Unlock()
+		if _, exists, err := s.Schedules.Load(r.Context(), schedule.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if exists {
+			http.Error(w, fmt.Sprintf("schedule %s already exists", schedule.ID), http.StatusConflict)
+			return
+		}
+		schedule.CreatedAt = time.Now().UTC()
+		schedule.LastRunAt, schedule.ResumedAt, schedule.LastTaskID = nil, nil, ""
+		if err := s.Schedules.Save(r.Context(), &schedule); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/api/schedules/"+schedule.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(s.scheduleStatus(schedule))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSchedule shows (GET) or deletes (DELETE) one schedule, and pauses
+// or resumes it on POST to /pause and /resume.
+func (s *APIServer) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.Schedules == nil {
+		http.Error(w, "schedules disabled", http.StatusNotFound)
+		return
+	}
+	id, action, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/schedules/"), "/"), "/")
+	if id == "" {
+		s.handleSchedules(w, r)
+		return
+	}
+	s.scheduleMu.Lock()
+	defer s.scheduleMu.Unlock()
+	schedule, ok, err := s.Schedules.Load(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		status := s.scheduleStatus(*schedule)
+		history, err := s.scheduleHistory(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status.History = history
+		writeJSON(w, status)
+	case action == "" && r.Method == http.MethodDelete:
+		if err := s.Schedules.Delete(r.Context(), id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case (action == "pause" || action == "resume") && r.Method == http.MethodPost:
+		paused := action == "pause"
+		if schedule.Paused != paused {
+			schedule.Paused = paused
+			if !paused {
+				// Runs missed while paused are skipped.
+				resumed := time.Now().UTC()
+				schedule.ResumedAt = &resumed
+			}
+			if err := s.Schedules.Save(r.Context(), schedule); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		writeJSON(w, s.scheduleStatus(*schedule))
+	case action == "" || action == "pause" || action == "resume":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "unknown schedule action "+action, http.StatusNotFound)
+	}
+}
+
+// scheduleHistory returns the schedule's recorded runs, newest first.
+func (s *APIServer) scheduleHistory(ctx context.Context, id string) ([]persistence.WorkflowSnapshot, error) {
+	if s.Workflows == nil {
+		return nil, nil
+	}
+	snapshots, err := s.Workflows.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var history []persistence.WorkflowSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.Metadata[scheduleContextKey] == id {
+			history = append(history, snapshot)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].ID > history[j].ID })
+	if len(history) > scheduleHistoryLimit {
+		history = history[:scheduleHistoryLimit]
+	}
+	return history, nil
+}
+
+// validateSchedule checks the ID and cron expression, and that the
+// schedule names exactly one of an instruction or a recipe that renders.
+func (s *APIServer) validateSchedule(schedule *persistence.Schedule) error {
+	if !scheduleIDPattern.MatchString(schedule.ID) {
+		return fmt.Errorf("invalid schedule id %q (letters, digits, '.', '_', '-')", schedule.ID)
+	}
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		return err
+	}
+	if nextScheduleRun(&persistence.Schedule{Cron: schedule.Cron, CreatedAt: time.Now()}) == nil {
+		return fmt.Errorf("cron %q never fires", schedule.Cron)
+	}
+	hasInstruction := strings.TrimSpace(schedule.Instruction) != ""
+	if hasInstruction == (schedule.Recipe != "") {
+		return errors.New("schedule needs exactly one of instruction or recipe")
+	}
+	if schedule.Recipe != "" {
+		_, err := s.scheduledTask(schedule)
+		return err
+	}
+	if len(schedule.Params) > 0 {
+		return errors.New("params only apply to recipes")
+	}
+	return nil
+}
+
+func (s *APIServer) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
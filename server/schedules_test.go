@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/persistence"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// Wednesday.
+	from := time.Date(2026, 1, 7, 10, 30, 15, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"@hourly", time.Date(2026, 1, 7, 11, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2026, 1, 7, 10, 40, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, 1, 8, 2, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 1, 8, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// Restricted day-of-month and day-of-week fire on either.
+		{"0 0 15 * 5", time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		cron, err := ParseCron(tc.expr)
+		require.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.want, cron.Next(from), tc.expr)
+	}
+
+	never, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(from).IsZero())
+
+	for expr, reason := range map[string]string{
+		"* * * *":      "want 5 fields",
+		"60 * * * *":   `minute: "60" is not between 0 and 59`,
+		"* * * * 1-9":  "day of week",
+		"*/0 * * * *":  "invalid step",
+		"0 5-2 * * *":  "runs backwards",
+		"@fortnightly": "want 5 fields",
+	} {
+		_, err := ParseCron(expr)
+		assert.ErrorContains(t, err, reason, expr)
+	}
+}
+
+// scheduleServer returns a server with file-backed schedule and workflow
+// stores and a recipe resolver that knows one recipe, "audit".
+func scheduleServer(t *testing.T) *APIServer {
+	t.Helper()
+	schedules, err := persistence.NewFileScheduleStore(t.TempDir())
+	require.NoError(t, err)
+	workflows, err := persistence.NewFileWorkflowStore(t.TempDir())
+	require.NoError(t, err)
+	return &APIServer{
+		Agent:     stubAgent{},
+		Context:   framework.NewContext(),
+		Logger:    log.New(io.Discard, "", 0),
+		Schedules: schedules,
+		Workflows: workflows,
+		Recipes: func(name string, params map[string]string) (*framework.Task, error) {
+			if name != "audit" {
+				return nil, errors.New("recipe " + name + " not found")
+			}
+			return &framework.Task{Type: framework.TaskTypeAnalysis, Instruction: "Audit " + params["scope"]}, nil
+		},
+	}
+}
+
+func postSchedule(handler http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/schedules", strings.NewReader(body)))
+	return rec
+}
+
+func TestSchedulesEndpoints(t *testing.T) {
+	api := scheduleServer(t)
+	handler := api.newHTTPServer("").Handler
+
+	rec := postSchedule(handler, `{"id":"nightly-audit","cron":"0 2 * * *","recipe":"audit","params":{"scope":"deps"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created ScheduleStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotNil(t, created.NextRunAt)
+	assert.Equal(t, 2, created.NextRunAt.Hour())
+
+	assert.Equal(t, http.StatusConflict, postSchedule(handler, `{"id":"nightly-audit","cron":"@daily","instruction":"x"}`).Code)
+	for body, reason := range map[string]string{
+		`{"id":"a b","cron":"@daily","instruction":"x"}`:                   "invalid schedule id",
+		`{"id":"bad","cron":"0 25 * * *","instruction":"x"}`:               "hour",
+		`{"id":"both","cron":"@daily","instruction":"x","recipe":"audit"}`: "exactly one of instruction or recipe",
+		`{"id":"typo","cron":"@daily","recipe":"audti"}`:                   "recipe audti not found",
+	} {
+		rec := postSchedule(handler, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), reason, body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/schedules/nightly-audit/pause", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var paused ScheduleStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &paused))
+	assert.True(t, paused.Paused)
+	assert.Nil(t, paused.NextRunAt)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/schedules", nil))
+	var listed []ScheduleStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.True(t, listed[0].Paused)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/schedules/nightly-audit", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/schedules/nightly-audit", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSchedulerSubmitsDueSchedulesAndRecordsHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := scheduleServer(t)
+	created := time.Date(2026, 1, 7, 1, 0, 0, 0, time.Local)
+	require.NoError(t, api.Schedules.Save(ctx, &persistence.Schedule{
+		ID: "nightly-audit", Cron: "0 2 * * *", Recipe: "audit", Params: map[string]string{"scope": "deps"}, CreatedAt: created,
+	}))
+	require.NoError(t, api.Schedules.Save(ctx, &persistence.Schedule{
+		ID: "docs", Cron: "0 2 * * *", Instruction: "Regenerate docs", Paused: true, CreatedAt: created,
+	}))
+
+	api.fireDueSchedules(ctx, created.Add(30*time.Minute))
+	assert.Empty(t, api.tasks().List(), "nothing is due before 02:00")
+
+	due := created.Add(time.Hour)
+	api.fireDueSchedules(ctx, due)
+	records := api.tasks().List()
+	require.Len(t, records, 1, "paused schedules do not run")
+	assert.Equal(t, "Audit deps", records[0].Instruction)
+	api.fireDueSchedules(ctx, due.Add(time.Minute))
+	assert.Len(t, api.tasks().List(), 1, "a schedule runs once per cron time")
+
+	var status ScheduleStatus
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		api.newHTTPServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/schedules/nightly-audit", nil))
+		status = ScheduleStatus{}
+		return json.Unmarshal(rec.Body.Bytes(), &status) == nil &&
+			len(status.History) == 1 && status.History[0].Status == persistence.WorkflowStatusCompleted
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, records[0].ID, status.LastTaskID)
+	assert.Equal(t, "nightly-audit", status.History[0].Metadata["schedule"])
+	assert.Equal(t, "audit", status.History[0].Task.Context["recipe"])
+	require.NotNil(t, status.NextRunAt)
+	assert.Equal(t, due.Add(24*time.Hour), status.NextRunAt.In(time.Local))
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	goruntime "runtime"
+	"sort"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// SelfTestOptions configures RunSelfTest.
+type SelfTestOptions struct {
+	Tasks int
+	// Instruction is formatted with the task number (%d).
+	Instruction string
+	// PollInterval paces status polling and resubmission after the queue
+	// rejects a task as full.
+	PollInterval time.Duration
+	// Verify, when set, checks a succeeded task's side effects.
+	Verify func(record TaskRecord) error
+}
+
+// LatencyStats summarizes a set of durations.
+type LatencyStats struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// SelfTestReport is the outcome of a RunSelfTest load run. Failures lists
+// every validation problem; an empty list with all tasks succeeded is a pass.
+type SelfTestReport struct {
+	Tasks     int `json:"tasks"`
+	Workers   int `json:"workers"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	TimedOut  int `json:"timed_out"`
+	// Rejected counts submissions refused with 503 (queue full) and retried.
+	Rejected int `json:"rejected"`
+	// MaxRunning is the most tasks observed running in one poll.
+	MaxRunning int           `json:"max_running"`
+	Duration   time.Duration `json:"duration"`
+	Throughput float64       `json:"throughput"`
+	Latency    LatencyStats  `json:"latency"`
+	QueueWait  LatencyStats  `json:"queue_wait"`
+	LLMCalls   int           `json:"llm_calls"`
+	Tokens     int           `json:"tokens"`
+	HeapStart  uint64        `json:"heap_start"`
+	HeapPeak   uint64        `json:"heap_peak"`
+	HeapEnd    uint64        `json:"heap_end"`
+	Failures   []string      `json:"failures,omitempty"`
+}
+
+// OK reports whether every task succeeded without validation failures.
+func (r *SelfTestReport) OK() bool {
+	return len(r.Failures) == 0 && r.Succeeded == r.Tasks
+}
+
+// Write prints a human-readable summary.
+func (r *SelfTestReport) Write(w io.Writer) {
+	fmt.Fprintf(w, "tasks:       %d (%d workers)\n", r.Tasks, r.Workers)
+	fmt.Fprintf(w, "outcomes:    %d succeeded, %d failed, %d timed out, %d rejected then resubmitted\n", r.Succeeded, r.Failed, r.TimedOut, r.Rejected)
+	fmt.Fprintf(w, "duration:    %s (%.1f tasks/s)\n", r.Duration.Round(time.Millisecond), r.Throughput)
+	fmt.Fprintf(w, "latency:     %s\n", r.Latency)
+	fmt.Fprintf(w, "queue wait:  %s\n", r.QueueWait)
+	fmt.Fprintf(w, "concurrency: max %d running\n", r.MaxRunning)
+	fmt.Fprintf(w, "llm:         %d calls, %d tokens\n", r.LLMCalls, r.Tokens)
+	growth := int64(r.HeapEnd) - int64(r.HeapStart)
+	perTask := int64(0)
+	if r.Tasks > 0 {
+		perTask = growth / int64(r.Tasks)
+	}
+	fmt.Fprintf(w, "heap:        start %s, peak %s, end %s (%+d bytes/task)\n", formatHeap(r.HeapStart), formatHeap(r.HeapPeak), formatHeap(r.HeapEnd), perTask)
+	if len(r.Failures) == 0 {
+		fmt.Fprintln(w, "result:      PASS")
+		return
+	}
+	fmt.Fprintf(w, "result:      FAIL (%d problems)\n", len(r.Failures))
+	for _, failure := range r.Failures {
+		fmt.Fprintf(w, "  - %s\n", failure)
+	}
+}
+
+func (s LatencyStats) String() string {
+	round := func(d time.Duration) time.Duration { return d.Round(100 * time.Microsecond) }
+	return fmt.Sprintf("min %s, mean %s, p50 %s, p95 %s, p99 %s, max %s",
+		round(s.Min), round(s.Mean), round(s.P50), round(s.P95), round(s.P99), round(s.Max))
+}
+
+func formatHeap(n uint64) string {
+	return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+}
+
+// RunSelfTest drives api through its HTTP queue endpoints on a loopback
+// listener: it submits opts.Tasks tasks, resubmitting when the queue is full,
+// polls until each one finishes, and reports latency, throughput, heap
+// growth, and any validation failures. The api's agent decides what the
+// tasks do; callers typically wire a mock model. Queue.MaxRecords is raised
+// to opts.Tasks so finished tasks stay pollable.
+func RunSelfTest(ctx context.Context, api *APIServer, opts SelfTestOptions) (*SelfTestReport, error) {
+	if opts.Tasks <= 0 {
+		return nil, errors.New("selftest needs at least one task")
+	}
+	if opts.Instruction == "" {
+		opts.Instruction = "selftest task %d"
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Millisecond
+	}
+	if api.Queue.MaxRecords < opts.Tasks {
+		api.Queue.MaxRecords = opts.Tasks
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("selftest listen: %w", err)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	srv := api.newHTTPServer(listener.Addr().String())
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer func() {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancelShutdown()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	queue := api.tasks()
+	queue.Start(runCtx)
+
+	report := &SelfTestReport{Tasks: opts.Tasks, Workers: queue.config.Workers}
+	report.HeapStart = heapAlloc(true)
+	report.HeapPeak = report.HeapStart
+	sample := func() {
+		if heap := heapAlloc(false); heap > report.HeapPeak {
+			report.HeapPeak = heap
+		}
+	}
+	driver := &selfTestClient{base: "http://" + listener.Addr().String(), client: &http.Client{Timeout: 10 * time.Second}}
+
+	started := time.Now()
+	ids := make([]string, 0, opts.Tasks)
+	seen := make(map[string]bool, opts.Tasks)
+	for i := 0; i < opts.Tasks; i++ {
+		id, full, err := driver.submit(runCtx, fmt.Sprintf(opts.Instruction, i))
+		if err != nil {
+			return nil, err
+		}
+		if full {
+			report.Rejected++
+			i--
+			if err := sleepContext(runCtx, opts.PollInterval); err != nil {
+				return nil, err
+			}
+			sample()
+			continue
+		}
+		if seen[id] {
+			report.Failures = append(report.Failures, fmt.Sprintf("task id %s issued twice", id))
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	var records map[string]TaskRecord
+	for {
+		sample()
+		list, err := driver.list(runCtx)
+		if err != nil {
+			return nil, err
+		}
+		records = make(map[string]TaskRecord, len(list))
+		running, pending := 0, 0
+		for _, record := range list {
+			records[record.ID] = record
+			if record.Status == TaskStatusRunning {
+				running++
+			}
+		}
+		if running > report.MaxRunning {
+			report.MaxRunning = running
+		}
+		for _, id := range ids {
+			if record, ok := records[id]; !ok || !terminalStatus(record.Status) {
+				pending++
+			}
+		}
+		if pending == 0 {
+			break
+		}
+		if err := sleepContext(runCtx, opts.PollInterval); err != nil {
+			return nil, err
+		}
+	}
+	report.Duration = time.Since(started)
+	if report.Duration > 0 {
+		report.Throughput = float64(opts.Tasks) / report.Duration.Seconds()
+	}
+
+	var latencies, waits []time.Duration
+	for _, id := range ids {
+		record := records[id]
+		switch record.Status {
+		case TaskStatusSucceeded:
+			report.Succeeded++
+			if opts.Verify != nil {
+				if err := opts.Verify(record); err != nil {
+					report.Failures = append(report.Failures, fmt.Sprintf("task %s: %v", id, err))
+				}
+			}
+		case TaskStatusTimedOut:
+			report.TimedOut++
+			report.Failures = append(report.Failures, fmt.Sprintf("task %s timed out: %s", id, record.Error))
+		default:
+			report.Failed++
+			report.Failures = append(report.Failures, fmt.Sprintf("task %s failed: %s", id, record.Error))
+		}
+		if record.StartedAt != nil && record.CompletedAt != nil {
+			latencies = append(latencies, record.CompletedAt.Sub(record.SubmittedAt))
+			waits = append(waits, record.StartedAt.Sub(record.SubmittedAt))
+		}
+	}
+	report.Latency = latencyStats(latencies)
+	report.QueueWait = latencyStats(waits)
+	if report.MaxRunning > report.Workers {
+		report.Failures = append(report.Failures, fmt.Sprintf("%d tasks running at once with %d workers", report.MaxRunning, report.Workers))
+	}
+	if api.Usage != nil {
+		total := api.Usage.Summary(framework.UsageFilter{}).Total
+		report.LLMCalls = total.Calls
+		report.Tokens = total.TotalTokens
+	}
+	report.HeapEnd = heapAlloc(true)
+	return report, nil
+}
+
+// selfTestClient talks to the queue endpoints the way an external client
+// would, so the run covers request decoding and status encoding too.
+type selfTestClient struct {
+	base   string
+	client *http.Client
+}
+
+// submit posts one task. full reports a 503 queue-full rejection.
+func (c *selfTestClient) submit(ctx context.Context, instruction string) (id string, full bool, err error) {
+	body, err := json.Marshal(TaskRequest{Instruction: instruction, Type: framework.TaskTypeCodeModification})
+	if err != nil {
+		return "", false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/api/tasks", bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("selftest submit: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		var submission TaskSubmission
+		if err := json.NewDecoder(resp.Body).Decode(&submission); err != nil {
+			return "", false, fmt.Errorf("selftest submit: %w", err)
+		}
+		return submission.ID, false, nil
+	case http.StatusServiceUnavailable:
+		return "", true, nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", false, fmt.Errorf("selftest submit: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+}
+
+func (c *selfTestClient) list(ctx context.Context) ([]TaskRecord, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/api/tasks", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("selftest poll: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("selftest poll: %s", resp.Status)
+	}
+	var records []TaskRecord
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("selftest poll: %w", err)
+	}
+	return records, nil
+}
+
+func terminalStatus(status TaskStatus) bool {
+	return status == TaskStatusSucceeded || status == TaskStatusFailed || status == TaskStatusTimedOut
+}
+
+// heapAlloc reads the live heap, optionally after a GC so start and end
+// samples compare retained memory rather than garbage.
+func heapAlloc(collect bool) uint64 {
+	if collect {
+		goruntime.GC()
+	}
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func latencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	percentile := func(p float64) time.Duration {
+		idx := int(float64(len(sorted)-1) * p)
+		return sorted[idx]
+	}
+	return LatencyStats{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(0.50),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
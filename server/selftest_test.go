@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestRunSelfTestReportsQueueBehavior(t *testing.T) {
+	api := &APIServer{
+		Agent:   stubAgent{},
+		Context: framework.NewContext(),
+		Queue:   TaskQueueConfig{Workers: 2, QueueSize: 4},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	verified := 0
+	report, err := RunSelfTest(ctx, api, SelfTestOptions{
+		Tasks: 30,
+		Verify: func(record TaskRecord) error {
+			verified++
+			if verified == 1 {
+				return errors.New("marker missing")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 30, report.Succeeded)
+	assert.Equal(t, 30, verified)
+	assert.LessOrEqual(t, report.MaxRunning, 2)
+	assert.GreaterOrEqual(t, report.Latency.Max, report.Latency.P50)
+	assert.False(t, report.OK())
+	assert.Len(t, report.Failures, 1)
+	assert.Contains(t, report.Failures[0], "marker missing")
+}
+
+func TestLatencyStats(t *testing.T) {
+	stats := latencyStats([]time.Duration{4 * time.Millisecond, time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond})
+	assert.Equal(t, time.Millisecond, stats.Min)
+	assert.Equal(t, 2500*time.Microsecond, stats.Mean)
+	assert.Equal(t, 2*time.Millisecond, stats.P50)
+	assert.Equal(t, 4*time.Millisecond, stats.Max)
+}
@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TaskStatus tracks where a queued task is in its lifecycle.
+type TaskStatus string
+
+const (
+	TaskStatusQueued    TaskStatus = "queued"
+	TaskStatusPaused    TaskStatus = "paused"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusSucceeded TaskStatus = "succeeded"
+	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+	TaskStatusTimedOut  TaskStatus = framework.ResultStatusTimedOut
+)
+
+var (
+	// ErrQueueFull is returned when the pending buffer cannot accept more
+	// work.
+	ErrQueueFull = errors.New("task queue full")
+	// ErrTaskNotFound is returned for IDs the queue does not know, including
+	// finished tasks that aged out of the records.
+	ErrTaskNotFound = errors.New("task not found")
+)
+
+// TaskRecord is the pollable view of a submitted task.
+type TaskRecord struct {
+	ID          string              `json:"id"`
+	Status      TaskStatus          `json:"status"`
+	Type        framework.TaskType  `json:"type"`
+	Instruction string              `json:"instruction"`
+	Priority    int                 `json:"priority,omitempty"`
+	SubmittedAt time.Time           `json:"submitted_at"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	Result      *framework.Result   `json:"result,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	ErrorKind   framework.ErrorKind `json:"error_kind,omitempty"`
+
+	task *framework.Task
+	// seq orders records of equal priority by submission.
+	seq uint64
+	// cancel stops a running task; cancelled marks that Cancel asked for it.
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// TaskRunner executes a single task. The queue hands each invocation its own
+// task so implementations can allocate per-task state.
+type TaskRunner func(ctx context.Context, task *framework.Task) (*framework.Result, error)
+
+// TaskQueueConfig tunes worker concurrency and buffering.
+type TaskQueueConfig struct {
+	// Workers caps how many tasks run at once.
+	Workers int
+	// QueueSize bounds how many tasks may wait, paused ones included.
+	QueueSize   int
+	TaskTimeout time.Duration
+	// MaxRecords bounds how many finished tasks remain pollable.
+	MaxRecords int
+}
+
+// TaskQueue runs submitted tasks, highest priority first and in submission
+// order within a priority, with at most Workers running at once. Waiting
+// tasks can be paused and resumed, and any unfinished task cancelled, while
+// their status stays available for polling.
+type TaskQueue struct {
+	config TaskQueueConfig
+	run    TaskRunner
+	seq    atomic.Uint64
+	// wake nudges the dispatcher when work is queued or a slot frees up.
+	wake chan struct{}
+
+	mu       sync.RWMutex
+	records  map[string]*TaskRecord
+	waiting  []*TaskRecord
+	running  int
+	finished []string
+
+	startOnce sync.Once
+}
+
+// NewTaskQueue builds a queue; call Start to launch workers.
+func NewTaskQueue(config TaskQueueConfig, run TaskRunner) *TaskQueue {
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 64
+	}
+	if config.TaskTimeout <= 0 {
+		config.TaskTimeout = 5 * time.Minute
+	}
+	if config.MaxRecords <= 0 {
+		config.MaxRecords = 256
+	}
+	return &TaskQueue{
+		config:  config,
+		run:     run,
+		wake:    make(chan struct{}, 1),
+		records: make(map[string]*TaskRecord),
+	}
+}
+
+// Start launches the dispatcher. It stops once ctx is cancelled; tasks still
+// waiting are marked failed and running ones see ctx cancelled.
+func (q *TaskQueue) Start(ctx context.Context) {
+	q.startOnce.Do(func() {
+		go q.dispatch(ctx)
+	})
+}
+
+// Submit enqueues a task at the default priority and returns its record
+// immediately.
+func (q *TaskQueue) Submit(task *framework.Task) (TaskRecord, error) {
+	return q.SubmitWithPriority(task, 0)
+}
+
+// SubmitWithPriority enqueues a task ahead of every waiting task with a
+// lower priority. Running tasks are not preempted.
+func (q *TaskQueue) SubmitWithPriority(task *framework.Task, priority int) (TaskRecord, error) {
+	if task == nil {
+		return TaskRecord{}, errors.New("task required")
+	}
+	seq := q.seq.Add(1)
+	if task.ID == "" {
+		task.ID = fmt.Sprintf("task-%d-%d", time.Now().UnixNano(), seq)
+	}
+	record := &TaskRecord{
+		ID:          task.ID,
+		Status:      TaskStatusQueued,
+		Type:        task.Type,
+		Instruction: task.Instruction,
+		Priority:    priority,
+		SubmittedAt: time.Now().UTC(),
+		task:        task,
+		seq:         seq,
+	}
+	q.mu.Lock()
+	if _, exists := q.records[task.ID]; exists {
+		q.mu.Unlock()
+		return TaskRecord{}, fmt.Errorf("task %s already submitted", task.ID)
+	}
+	if len(q.waiting) >= q.config.QueueSize {
+		q.mu.Unlock()
+		return TaskRecord{}, ErrQueueFull
+	}
+	q.records[task.ID] = record
+	q.enqueueLocked(record)
+	snapshot := *record
+	q.mu.Unlock()
+	q.signal()
+	return snapshot, nil
+}
+
+// Cancel stops a task. A waiting task is finished as cancelled right away;
+// a running task has its context cancelled, which reaches the agent's
+// Execute, and is recorded as cancelled once the agent returns.
+func (q *TaskQueue) Cancel(id string) (TaskRecord, error) {
+	q.mu.Lock()
+	record, ok := q.records[id]
+	if !ok {
+		q.mu.Unlock()
+		return TaskRecord{}, ErrTaskNotFound
+	}
+	switch record.Status {
+	case TaskStatusQueued, TaskStatusPaused:
+		q.removeWaitingLocked(record)
+		record.cancelled = true
+		q.finishLocked(record, nil, context.Canceled)
+	case TaskStatusRunning:
+		record.cancelled = true
+		record.cancel()
+	default:
+		q.mu.Unlock()
+		return TaskRecord{}, fmt.Errorf("task %s already %s", id, record.Status)
+	}
+	snapshot := *record
+	q.mu.Unlock()
+	return snapshot, nil
+}
+
+// Pause holds a waiting task in the queue until Resume.
+func (q *TaskQueue) Pause(id string) (TaskRecord, error) {
+	return q.setWaitingStatus(id, TaskStatusQueued, TaskStatusPaused)
+}
+
+// Resume lets a paused task run again in its original place in line.
+func (q *TaskQueue) Resume(id string) (TaskRecord, error) {
+	record, err := q.setWaitingStatus(id, TaskStatusPaused, TaskStatusQueued)
+	if err == nil {
+		q.signal()
+	}
+	return record, err
+}
+
+func (q *TaskQueue) setWaitingStatus(id string, from, to TaskStatus) (TaskRecord, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record, ok := q.records[id]
+	if !ok {
+		return TaskRecord{}, ErrTaskNotFound
+	}
+	if record.Status != from {
+		return TaskRecord{}, fmt.Errorf("task %s is %s, not %s", id, record.Status, from)
+	}
+	record.Status = to
+	return *record, nil
+}
+
+// Get returns a copy of the task record.
+func (q *TaskQueue) Get(id string) (TaskRecord, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	record, ok := q.records[id]
+	if !ok {
+		return TaskRecord{}, false
+	}
+	return *record, true
+}
+
+// List returns all known tasks ordered by submission time.
+func (q *TaskQueue) List() []TaskRecord {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	res := make([]TaskRecord, 0, len(q.records))
+	for _, record := range q.records {
+		res = append(res, *record)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].SubmittedAt.Before(res[j].SubmittedAt)
+	})
+	return res
+}
+
+func (q *TaskQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueLocked inserts record into waiting, keeping it sorted by priority
+// and then submission.
+func (q *TaskQueue) enqueueLocked(record *TaskRecord) {
+	i := sort.Search(len(q.waiting), func(i int) bool {
+		w := q.waiting[i]
+		if w.Priority != record.Priority {
+			return w.Priority < record.Priority
+		}
+		return w.seq > record.seq
+	})
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[i+1:], q.waiting[i:])
+	q.waiting[i] = record
+}
+
+func (q *TaskQueue) removeWaitingLocked(record *TaskRecord) {
+	for i, w := range q.waiting {
+		if w == record {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch starts the first unpaused waiting tasks whenever a worker slot
+// is free.
+func (q *TaskQueue) dispatch(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		for q.running < q.config.Workers {
+			record := q.nextLocked()
+			if record == nil {
+				break
+			}
+			q.startLocked(ctx, record)
+		}
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			q.drain(ctx.Err())
+			return
+		case <-q.wake:
+		}
+	}
+}
+
+func (q *TaskQueue) nextLocked() *TaskRecord {
+	for i, record := range q.waiting {
+		if record.Status == TaskStatusQueued {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return record
+		}
+	}
+	return nil
+}
+
+func (q *TaskQueue) startLocked(ctx context.Context, record *TaskRecord) {
+	started := time.Now().UTC()
+	record.Status = TaskStatusRunning
+	record.StartedAt = &started
+	taskCtx, cancel := context.WithTimeout(ctx, q.config.TaskTimeout)
+	record.cancel = cancel
+	q.running++
+	go q.execute(taskCtx, record)
+}
+
+func (q *TaskQueue) execute(ctx context.Context, record *TaskRecord) {
+	result, err := q.run(ctx, record.task)
+	q.mu.Lock()
+	record.cancel()
+	q.running--
+	q.finishLocked(record, result, err)
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *TaskQueue) finishLocked(record *TaskRecord, result *framework.Result, err error) {
+	completed := time.Now().UTC()
+	record.CompletedAt = &completed
+	record.Result = result
+	// A cancelled task that finished anyway keeps its outcome.
+	switch {
+	case record.cancelled && (err != nil || result == nil):
+		record.Status = TaskStatusCancelled
+		record.Error = "cancelled"
+		record.ErrorKind = framework.ErrorKindCancelled
+	case framework.IsTimeout(err):
+		record.Status = TaskStatusTimedOut
+		record.Error = err.Error()
+		record.ErrorKind = framework.ErrorKindTimeout
+	case err != nil:
+		record.Status = TaskStatusFailed
+		record.Error = err.Error()
+		record.ErrorKind = framework.ErrorKindOf(err)
+	default:
+		record.Status = TaskStatusSucceeded
+	}
+	record.task = nil
+	record.cancel = nil
+	q.finished = append(q.finished, record.ID)
+	for len(q.finished) > q.config.MaxRecords {
+		delete(q.records, q.finished[0])
+		q.finished = q.finished[1:]
+	}
+}
+
+// drain fails any task still waiting after shutdown so pollers do not wait
+// forever on work that will never run.
+func (q *TaskQueue) drain(cause error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, record := range q.waiting {
+		q.finishLocked(record, nil, cause)
+	}
+	q.waiting = nil
+}
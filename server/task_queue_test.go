@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// gatedRunner blocks every task until release is closed or its context is
+// cancelled, recording the order tasks started in.
+type gatedRunner struct {
+	release chan struct{}
+	mu      sync.Mutex
+	started []string
+}
+
+func (r *gatedRunner) run(ctx context.Context, task *framework.Task) (*framework.Result, error) {
+	r.mu.Lock()
+	r.started = append(r.started, task.Instruction)
+	r.mu.Unlock()
+	select {
+	case <-r.release:
+		return &framework.Result{Success: true}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *gatedRunner) order() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.started...)
+}
+
+func waitForStatus(t *testing.T, queue *TaskQueue, id string, status TaskStatus) TaskRecord {
+	t.Helper()
+	var record TaskRecord
+	require.Eventually(t, func() bool {
+		record, _ = queue.Get(id)
+		return record.Status == status
+	}, time.Second, 5*time.Millisecond)
+	return record
+}
+
+func TestTaskQueueRunsHigherPriorityFirst(t *testing.T) {
+	runner := &gatedRunner{release: make(chan struct{})}
+	queue := NewTaskQueue(TaskQueueConfig{Workers: 1}, runner.run)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue.Start(ctx)
+
+	first, err := queue.Submit(&framework.Task{Instruction: "first"})
+	require.NoError(t, err)
+	waitForStatus(t, queue, first.ID, TaskStatusRunning)
+	low, err := queue.SubmitWithPriority(&framework.Task{Instruction: "low"}, -1)
+	require.NoError(t, err)
+	normal, err := queue.Submit(&framework.Task{Instruction: "normal"})
+	require.NoError(t, err)
+	urgent, err := queue.SubmitWithPriority(&framework.Task{Instruction: "urgent"}, 10)
+	require.NoError(t, err)
+
+	close(runner.release)
+	for _, id := range []string{first.ID, low.ID, normal.ID, urgent.ID} {
+		waitForStatus(t, queue, id, TaskStatusSucceeded)
+	}
+	assert.Equal(t, []string{"first", "urgent", "normal", "low"}, runner.order())
+}
+
+func TestTaskQueuePauseResumeAndCancel(t *testing.T) {
+	runner := &gatedRunner{release: make(chan struct{})}
+	queue := NewTaskQueue(TaskQueueConfig{Workers: 1}, runner.run)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue.Start(ctx)
+
+	running, err := queue.Submit(&framework.Task{Instruction: "running"})
+	require.NoError(t, err)
+	waitForStatus(t, queue, running.ID, TaskStatusRunning)
+	held, err := queue.SubmitWithPriority(&framework.Task{Instruction: "held"}, 5)
+	require.NoError(t, err)
+	dropped, err := queue.Submit(&framework.Task{Instruction: "dropped"})
+	require.NoError(t, err)
+	next, err := queue.Submit(&framework.Task{Instruction: "next"})
+	require.NoError(t, err)
+
+	record, err := queue.Pause(held.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPaused, record.Status)
+	_, err = queue.Pause(held.ID)
+	assert.ErrorContains(t, err, "is paused, not queued")
+
+	record, err = queue.Cancel(dropped.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, record.Status)
+	assert.Equal(t, framework.ErrorKindCancelled, record.ErrorKind)
+
+	// Cancelling the running task reaches its context; the paused task is
+	// skipped even though it outranks the next one.
+	record, err = queue.Cancel(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusRunning, record.Status)
+	record = waitForStatus(t, queue, running.ID, TaskStatusCancelled)
+	assert.Equal(t, "cancelled", record.Error)
+	waitForStatus(t, queue, next.ID, TaskStatusRunning)
+	assert.Equal(t, []string{"running", "next"}, runner.order())
+
+	_, err = queue.Resume(held.ID)
+	require.NoError(t, err)
+	close(runner.release)
+	waitForStatus(t, queue, held.ID, TaskStatusSucceeded)
+	assert.Equal(t, []string{"running", "next", "held"}, runner.order())
+
+	_, err = queue.Cancel(held.ID)
+	assert.ErrorContains(t, err, "already succeeded")
+	_, err = queue.Cancel("missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+func TestTaskQueueRejectsWhenWaitingIsFull(t *testing.T) {
+	runner := &gatedRunner{release: make(chan struct{})}
+	queue := NewTaskQueue(TaskQueueConfig{Workers: 1, QueueSize: 1}, runner.run)
+	_, err := queue.Submit(&framework.Task{Instruction: "one"})
+	require.NoError(t, err)
+	_, err = queue.Submit(&framework.Task{Instruction: "two"})
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestAPIServerTaskControl(t *testing.T) {
+	api := &APIServer{
+		Agent:   hangingAgent{},
+		Context: framework.NewContext(),
+		Logger:  log.New(io.Discard, "", 0),
+		Queue:   TaskQueueConfig{Workers: 1},
+	}
+	queue := api.tasks()
+	queue.Start(context.Background())
+	running, err := queue.Submit(&framework.Task{Instruction: "hang"})
+	require.NoError(t, err)
+	waitForStatus(t, queue, running.ID, TaskStatusRunning)
+	waiting, err := queue.Submit(&framework.Task{Instruction: "wait"})
+	require.NoError(t, err)
+
+	control := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.handleTaskStatus(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/"+path, nil))
+		return rec
+	}
+	rec := control(waiting.ID + "/pause")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"paused"`)
+	assert.Equal(t, http.StatusConflict, control(waiting.ID+"/pause").Code)
+	assert.Equal(t, http.StatusOK, control(waiting.ID+"/resume").Code)
+	assert.Equal(t, http.StatusNotFound, control("missing/cancel").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, control(waiting.ID+"/restart").Code)
+
+	assert.Equal(t, http.StatusOK, control(running.ID+"/cancel").Code)
+	waitForStatus(t, queue, running.ID, TaskStatusCancelled)
+	assert.Equal(t, http.StatusOK, control(waiting.ID+"/cancel").Code)
+}
+
+// runStateAgent keeps per-run state in its fields like the pattern agents,
+// so sharing one between concurrent tasks is a data race.
+type runStateAgent struct {
+	current  *framework.Task
+	inFlight *atomic.Int32
+	peak     *atomic.Int32
+	release  chan struct{}
+}
+
+func (a *runStateAgent) Initialize(*framework.Config) error                   { return nil }
+func (a *runStateAgent) Capabilities() []framework.Capability                 { return nil }
+func (a *runStateAgent) BuildGraph(*framework.Task) (*framework.Graph, error) { return nil, nil }
+func (a *runStateAgent) Execute(ctx context.Context, task *framework.Task, state *framework.Context) (*framework.Result, error) {
+	a.current = task
+	running := a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+	for {
+		peak := a.peak.Load()
+		if running <= peak || a.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	select {
+	case <-a.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &framework.Result{Success: true, Data: map[string]interface{}{"task": a.current.ID}}, nil
+}
+
+// TestAPIServerRunsConcurrentTasksOnSeparateAgents runs two queued tasks at
+// once; run it with -race to check they share no agent state.
+func TestAPIServerRunsConcurrentTasksOnSeparateAgents(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	api := &APIServer{
+		NewAgent: func() (framework.Agent, error) {
+			return &runStateAgent{inFlight: &inFlight, peak: &peak, release: release}, nil
+		},
+		Context: framework.NewContext(),
+		Logger:  log.New(io.Discard, "", 0),
+		Queue:   TaskQueueConfig{Workers: 2},
+	}
+	queue := api.tasks()
+	queue.Start(context.Background())
+	first, err := queue.Submit(&framework.Task{Instruction: "one"})
+	require.NoError(t, err)
+	second, err := queue.Submit(&framework.Task{Instruction: "two"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return inFlight.Load() == 2 }, time.Second, 5*time.Millisecond)
+	close(release)
+
+	for _, id := range []string{first.ID, second.ID} {
+		record := waitForStatus(t, queue, id, TaskStatusSucceeded)
+		assert.Equal(t, id, record.Result.Data["task"])
+	}
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+// TestAPIServerSerializesSharedAgent checks tasks take turns on Agent when
+// no NewAgent is set.
+func TestAPIServerSerializesSharedAgent(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	close(release)
+	api := &APIServer{
+		Agent:   &runStateAgent{inFlight: &inFlight, peak: &peak, release: release},
+		Context: framework.NewContext(),
+		Logger:  log.New(io.Discard, "", 0),
+		Queue:   TaskQueueConfig{Workers: 2},
+	}
+	queue := api.tasks()
+	queue.Start(context.Background())
+	var ids []string
+	for _, instruction := range []string{"one", "two", "three"} {
+		record, err := queue.Submit(&framework.Task{Instruction: instruction})
+		require.NoError(t, err)
+		ids = append(ids, record.ID)
+	}
+	for _, id := range ids {
+		waitForStatus(t, queue, id, TaskStatusSucceeded)
+	}
+	assert.Equal(t, int32(1), peak.Load())
+}
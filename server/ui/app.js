@@ -0,0 +1,207 @@
+// Relurpify dashboard: polls the API server's JSON endpoints and renders
+// tasks, the selected task's timeline, pending approvals, memory and workflow
+// snapshots. Panels whose endpoint answers 404 are hidden.
+(function () {
+  'use strict';
+
+  const POLL_MS = 1500;
+  let selectedTask = '';
+  let selectedWorkflow = '';
+
+  const $ = (id) => document.getElementById(id);
+
+  function el(tag, attrs, ...children) {
+    const node = document.createElement(tag);
+    for (const [key, value] of Object.entries(attrs || {})) {
+      if (key === 'onclick') node.addEventListener('click', value);
+      else node.setAttribute(key, value);
+    }
+    for (const child of children) {
+      if (child === null || child === undefined) continue;
+      node.append(child instanceof Node ? child : String(child));
+    }
+    return node;
+  }
+
+  function fmtTime(value) {
+    if (!value) return '';
+    const d = new Date(value);
+    return isNaN(d) ? '' : d.toLocaleTimeString();
+  }
+
+  // When the server requires API keys, the key is asked for once and kept in
+  // localStorage.
+  const KEY_STORAGE = 'relurpify.apiKey';
+  let askedForKey = false;
+
+  function headers(extra) {
+    const key = localStorage.getItem(KEY_STORAGE);
+    return key ? { ...extra, Authorization: 'Bearer ' + key } : extra;
+  }
+
+  async function authFetch(path, options) {
+    let res = await fetch(path, { ...options, headers: headers(options.headers) });
+    if (res.status === 401 && !askedForKey) {
+      askedForKey = true;
+      const key = prompt('API key for this server:');
+      if (key) {
+        localStorage.setItem(KEY_STORAGE, key.trim());
+        res = await fetch(path, { ...options, headers: headers(options.headers) });
+      }
+    }
+    return res;
+  }
+
+  async function getJSON(path) {
+    const res = await authFetch(path, { headers: { Accept: 'application/json' } });
+    if (res.status === 404) return null;
+    if (!res.ok) throw new Error(path + ': ' + res.status + ' ' + (await res.text()));
+    return res.json();
+  }
+
+  function setPanel(id, enabled) {
+    $(id).hidden = !enabled;
+  }
+
+  async function refreshStatus() {
+    const status = await getJSON('/api/status');
+    const banner = $('banner');
+    if (status && status.degraded) {
+      banner.textContent = 'Sandbox unavailable: commands run directly on the host and require approval. ' +
+        (status.sandbox.reason || '');
+      banner.hidden = false;
+    } else {
+      banner.hidden = true;
+    }
+  }
+
+  async function refreshTasks() {
+    const tasks = (await getJSON('/api/tasks')) || [];
+    tasks.sort((a, b) => (b.submitted_at || '').localeCompare(a.submitted_at || ''));
+    const body = $('tasks');
+    body.replaceChildren(...tasks.map((task) => el('tr', {
+      class: task.id === selectedTask ? 'selected' : '',
+      onclick: () => { selectedTask = task.id; refreshTasks(); refreshTimeline(); },
+    },
+    el('td', { class: 'status-' + task.status }, task.status),
+    el('td', { title: task.error || '' }, task.instruction),
+    el('td', {}, fmtTime(task.submitted_at)))));
+    if (!tasks.length) body.replaceChildren(el('tr', {}, el('td', { colspan: 3, class: 'muted' }, 'No tasks yet.')));
+    if (!selectedTask && tasks.length) selectedTask = tasks[0].id;
+  }
+
+  async function refreshTimeline() {
+    $('timeline-task').textContent = selectedTask;
+    if (!selectedTask) return;
+    const data = await getJSON('/api/tasks/' + encodeURIComponent(selectedTask) + '/events');
+    setPanel('timeline-panel', data !== null);
+    if (!data) return;
+    const list = $('timeline');
+    list.replaceChildren(...data.events.map((event) => el('li', {},
+      el('span', { class: 'muted' }, fmtTime(event.timestamp) + ' '),
+      el('span', { class: 'type' }, event.type),
+      event.node_id ? event.node_id + ' ' : '',
+      event.message || '')));
+    if (!data.events.length) list.replaceChildren(el('li', { class: 'muted' }, 'No events recorded.'));
+  }
+
+  async function decide(id, action) {
+    const body = action === 'deny'
+      ? { reason: prompt('Reason for denying?') || 'denied from dashboard' }
+      : {};
+    const res = await authFetch('/v1/hitl/' + encodeURIComponent(id) + '/' + action, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(body),
+    });
+    if (!res.ok) alert(await res.text());
+    refreshHITL();
+  }
+
+  async function refreshHITL() {
+    const pending = await getJSON('/v1/hitl/pending');
+    setPanel('hitl-panel', pending !== null);
+    if (!pending) return;
+    const list = $('hitl');
+    list.replaceChildren(...pending.map((req) => el('li', {},
+      el('div', {}, el('strong', {}, req.permission.Type + ' ' + req.permission.Action), ' ', req.permission.Resource),
+      el('div', { class: 'risk-' + req.risk }, 'risk: ' + req.risk + ', scope: ' + req.scope),
+      req.justification ? el('div', { class: 'muted' }, req.justification) : null,
+      el('div', { class: 'actions' },
+        el('button', { onclick: () => decide(req.id, 'approve') }, 'Approve'),
+        el('button', { onclick: () => decide(req.id, 'deny') }, 'Deny')))));
+    if (!pending.length) list.replaceChildren(el('li', { class: 'muted' }, 'Nothing waiting for approval.'));
+  }
+
+  async function refreshMemory() {
+    const params = new URLSearchParams({ scope: $('memory-scope').value, q: $('memory-query').value });
+    const records = await getJSON('/api/memory?' + params);
+    setPanel('memory-panel', records !== null);
+    if (!records) return;
+    const list = $('memory');
+    list.replaceChildren(...records.map((record) => el('li', {},
+      el('strong', {}, record.key), ' ', el('span', { class: 'muted' }, fmtTime(record.timestamp)),
+      el('pre', {}, JSON.stringify(record.value, null, 2)))));
+    if (!records.length) list.replaceChildren(el('li', { class: 'muted' }, 'No memory records.'));
+  }
+
+  async function refreshWorkflows() {
+    const snapshots = await getJSON('/api/workflows');
+    setPanel('workflows-panel', snapshots !== null);
+    if (!snapshots) return;
+    const body = $('workflows');
+    body.replaceChildren(...snapshots.map((snap) => el('tr', {
+      class: snap.id === selectedWorkflow ? 'selected' : '',
+      onclick: () => showWorkflow(snap.id),
+    },
+    el('td', {}, snap.id),
+    el('td', {}, snap.status),
+    el('td', {}, fmtTime(snap.updated_at)))));
+    if (!snapshots.length) body.replaceChildren(el('tr', {}, el('td', { colspan: 3, class: 'muted' }, 'No workflow snapshots.')));
+  }
+
+  async function showWorkflow(id) {
+    selectedWorkflow = id;
+    const detail = $('workflow-detail');
+    const snap = await getJSON('/api/workflows/' + encodeURIComponent(id));
+    detail.textContent = snap ? JSON.stringify(snap, null, 2) : 'Workflow not found.';
+    detail.hidden = false;
+    refreshWorkflows();
+  }
+
+  $('submit').addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const instruction = $('instruction').value.trim();
+    if (!instruction) return;
+    const res = await authFetch('/api/tasks', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ instruction }),
+    });
+    if (!res.ok) { alert(await res.text()); return; }
+    selectedTask = (await res.json()).id;
+    $('instruction').value = '';
+    refreshTasks();
+  });
+
+  $('memory-search').addEventListener('submit', (event) => {
+    event.preventDefault();
+    refreshMemory();
+  });
+
+  async function poll() {
+    try {
+      await Promise.all([refreshStatus(), refreshTasks(), refreshHITL()]);
+      await refreshTimeline();
+      $('status').textContent = 'updated ' + new Date().toLocaleTimeString();
+    } catch (err) {
+      $('status').textContent = String(err);
+    }
+    setTimeout(poll, POLL_MS);
+  }
+
+  poll();
+  refreshMemory().catch(() => {});
+  refreshWorkflows().catch(() => {});
+  setInterval(() => refreshWorkflows().catch(() => {}), POLL_MS * 4);
+})();
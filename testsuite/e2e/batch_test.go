@@ -0,0 +1,61 @@
+package e2e
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+func TestBatchRunsTasksAndFailsOnError(t *testing.T) {
+	ws := newWorkspace(t)
+	stub := ollamatest.Start(t, ollamatest.Script{
+		Rules: []ollamatest.Rule{
+			{Match: "Write the greeting", Times: 1, Reply: ollamatest.Reply{ToolCalls: []ollamatest.ToolCall{{
+				Name: "file_write",
+				Args: map[string]interface{}{"path": "notes/greeting.txt", "content": "hi\n"},
+			}}}},
+			{Match: "Reject this", Reply: ollamatest.Reply{Status: 400, Text: "bad request"}},
+		},
+		Fallback: &ollamatest.Reply{Text: completeReply},
+	})
+	tasks := `tasks:
+  - id: greeting
+    instruction: Write the greeting to notes/greeting.txt
+  - id: rejected
+    instruction: Reject this task
+`
+	if err := os.WriteFile(ws.Path("tasks.yaml"), []byte(tasks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := ws.Run(stub, "batch", "--file", ws.Path("tasks.yaml"), "--out", ws.Path("out"))
+	if res.Err == nil {
+		t.Fatalf("expected batch with a failing task to exit non-zero\nstdout:\n%s", res.Stdout)
+	}
+	if got := ws.ReadFile("notes/greeting.txt"); got != "hi\n" {
+		t.Fatalf("unexpected file content %q", got)
+	}
+
+	var summary struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+	}
+	if err := json.Unmarshal([]byte(ws.ReadFile("out/summary.json")), &summary); err != nil {
+		t.Fatalf("parse summary: %v", err)
+	}
+	if summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Fatalf("expected 1 succeeded and 1 failed, got %+v\nstdout:\n%s\nstderr:\n%s", summary, res.Stdout, res.Stderr)
+	}
+	var rejected struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(ws.ReadFile("out/rejected.json")), &rejected); err != nil {
+		t.Fatalf("parse rejected result: %v", err)
+	}
+	if rejected.Status != "failed" || rejected.Error == "" {
+		t.Fatalf("expected rejected task to record its failure, got %+v", rejected)
+	}
+}
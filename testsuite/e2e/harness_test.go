@@ -0,0 +1,139 @@
+// Package e2e runs the real relurpish binary against a copy of a fixture
+// workspace, with an in-process Ollama stub (llm/ollamatest) standing in for
+// the model. Tests assert on what the run leaves behind: workspace files,
+// workflow snapshots, and the audit log. The suite builds the binary once and
+// is skipped with -short.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+// relurpishBin is the binary built by TestMain.
+var relurpishBin string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if testing.Short() {
+		fmt.Println("skipping end-to-end suite in -short mode")
+		os.Exit(0)
+	}
+	dir, err := os.MkdirTemp("", "relurpify-e2e-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	relurpishBin = filepath.Join(dir, "relurpish")
+	build := exec.Command("go", "build", "-o", relurpishBin, "github.com/lexcodex/relurpify/app/relurpish")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "build relurpish: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// workspace is a per-test copy of testdata/workspace.
+type workspace struct {
+	t    *testing.T
+	Root string
+}
+
+func newWorkspace(t *testing.T) *workspace {
+	t.Helper()
+	root := t.TempDir()
+	src := filepath.Join("testdata", "workspace")
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(root, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+	if err != nil {
+		t.Fatalf("copy fixture workspace: %v", err)
+	}
+	return &workspace{t: t, Root: root}
+}
+
+// Path joins rel onto the workspace root.
+func (w *workspace) Path(rel string) string {
+	return filepath.Join(w.Root, filepath.FromSlash(rel))
+}
+
+// ReadFile returns a workspace file's contents, failing the test if missing.
+func (w *workspace) ReadFile(rel string) string {
+	w.t.Helper()
+	data, err := os.ReadFile(w.Path(rel))
+	if err != nil {
+		w.t.Fatalf("read %s: %v", rel, err)
+	}
+	return string(data)
+}
+
+// result is the outcome of one CLI invocation.
+type result struct {
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// Run invokes relurpish inside the workspace, pointed at stub. It fails the
+// test only when the process cannot start or exceeds the deadline; callers
+// check Err for the exit status.
+func (w *workspace) Run(stub *ollamatest.Server, args ...string) result {
+	w.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	full := append([]string{"--workspace", w.Root, "--ollama-endpoint", stub.URL}, args...)
+	cmd := exec.CommandContext(ctx, relurpishBin, full...)
+	cmd.Dir = w.Root
+	// Keep the stub and host tools reachable while hiding runsc, so runs
+	// are deterministic whether or not the host has gVisor installed.
+	cmd.Env = append(os.Environ(), "PATH="+hostPathWithoutRunsc())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		w.t.Fatalf("relurpish %v timed out\nstdout:\n%s\nstderr:\n%s", args, stdout.String(), stderr.String())
+	}
+	return result{Stdout: stdout.String(), Stderr: stderr.String(), Err: err}
+}
+
+// hostPathWithoutRunsc drops PATH entries that provide runsc.
+func hostPathWithoutRunsc() string {
+	var kept []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if _, err := os.Stat(filepath.Join(dir, "runsc")); err == nil {
+			continue
+		}
+		kept = append(kept, dir)
+	}
+	return strings.Join(kept, string(os.PathListSeparator))
+}
@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/llm/ollamatest"
+)
+
+const completeReply = `{"thought":"done","complete":true}`
+
+func TestTaskWritesFileAndRecordsWorkflow(t *testing.T) {
+	ws := newWorkspace(t)
+	stub := ollamatest.Start(t, ollamatest.Script{
+		Rules: []ollamatest.Rule{{
+			Times: 1,
+			Reply: ollamatest.Reply{ToolCalls: []ollamatest.ToolCall{{
+				Name: "file_write",
+				Args: map[string]interface{}{"path": "notes/hello.txt", "content": "hello from e2e\n"},
+			}}},
+		}},
+		Fallback: &ollamatest.Reply{Text: completeReply},
+	})
+
+	res := ws.Run(stub, "task", "Create notes/hello.txt")
+	if res.Err != nil {
+		t.Fatalf("task failed: %v\nstdout:\n%s\nstderr:\n%s", res.Err, res.Stdout, res.Stderr)
+	}
+	if got := ws.ReadFile("notes/hello.txt"); got != "hello from e2e\n" {
+		t.Fatalf("unexpected file content %q", got)
+	}
+	if len(stub.Requests()) < 2 {
+		t.Fatalf("expected the agent to call the model at least twice, got %d", len(stub.Requests()))
+	}
+
+	workflowID := ""
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if id, ok := strings.CutPrefix(line, "Workflow: "); ok {
+			workflowID = strings.TrimSpace(id)
+		}
+	}
+	if workflowID == "" {
+		t.Fatalf("no workflow id in output:\n%s", res.Stdout)
+	}
+	show := ws.Run(stub, "workflow", "show", workflowID)
+	if show.Err != nil {
+		t.Fatalf("workflow show failed: %v\n%s", show.Err, show.Stderr)
+	}
+	if !strings.Contains(show.Stdout, "completed") {
+		t.Fatalf("expected completed workflow snapshot, got:\n%s", show.Stdout)
+	}
+
+	entries, err := framework.ReadAuditFile(ws.Path("relurpify_cfg/logs/audit.jsonl"), framework.AuditQuery{
+		Type:   string(framework.PermissionTypeFilesystem),
+		Result: "granted",
+	})
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	found := false
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Permission, "notes/hello.txt") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a granted filesystem audit record for notes/hello.txt, got %+v", entries)
+	}
+}
+
+func TestTaskDeniedWriteIsAudited(t *testing.T) {
+	ws := newWorkspace(t)
+	stub := ollamatest.Start(t, ollamatest.Script{
+		Rules: []ollamatest.Rule{{
+			Times: 1,
+			Reply: ollamatest.Reply{ToolCalls: []ollamatest.ToolCall{{
+				Name: "file_write",
+				Args: map[string]interface{}{"path": "../escape.txt", "content": "nope"},
+			}}},
+		}},
+		Fallback: &ollamatest.Reply{Text: completeReply},
+	})
+
+	ws.Run(stub, "task", "Write outside the workspace")
+	entries, err := framework.ReadAuditFile(ws.Path("relurpify_cfg/logs/audit.jsonl"), framework.AuditQuery{Result: "denied"})
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	found := false
+	for _, entry := range entries {
+		if strings.Contains(entry.Permission, "escape.txt") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a denied audit record for the escaping write, got %+v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(ws.Root), "escape.txt")); err == nil {
+		t.Fatal("escaping write reached the disk")
+	}
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ArtifactTool registers a report, diagram, or data file the agent produced
+// as an artifact of the running task, so it can be listed and downloaded
+// after the task ends instead of being left in the workspace or the
+// transcript.
+type ArtifactTool struct {
+	Store    framework.ArtifactStore
+	BasePath string
+	manager  *framework.PermissionManager
+	agentID  string
+}
+
+func (t *ArtifactTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *ArtifactTool) Name() string { return "artifact_save" }
+func (t *ArtifactTool) Description() string {
+	return "Saves a report, diagram, or data file as an artifact of the current task. Pass content directly or the path of a workspace file."
+}
+func (t *ArtifactTool) Category() string { return "artifact" }
+func (t *ArtifactTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "name", Type: "string", Description: "File name for the artifact, e.g. coverage.md; defaults to the base name of path", Required: false},
+		{Name: "type", Type: "string", Description: "report, diagram, data, log, or other", Required: false, Default: string(framework.ArtifactTypeOther)},
+		{Name: "content", Type: "string", Description: "Artifact content", Required: false},
+		{Name: "path", Type: "string", Description: "Workspace file to save instead of content", Required: false},
+		{Name: "media_type", Type: "string", Description: "MIME type; guessed from the name when omitted", Required: false},
+	}
+}
+
+func (t *ArtifactTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	kind, ok := framework.ParseArtifactType(stringArg(args, "type"))
+	if !ok {
+		return nil, fmt.Errorf("unknown artifact type %q", stringArg(args, "type"))
+	}
+	artifact := framework.Artifact{
+		Name:      stringArg(args, "name"),
+		Type:      kind,
+		MediaType: stringArg(args, "media_type"),
+		TaskID:    artifactTaskID(ctx, state),
+		Tool:      t.Name(),
+	}
+	var content []byte
+	if path := stringArg(args, "path"); path != "" {
+		if !filepath.IsAbs(path) && t.BasePath != "" {
+			path = filepath.Join(t.BasePath, path)
+		}
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
+				return nil, err
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = data
+		if artifact.Name == "" {
+			artifact.Name = filepath.Base(path)
+		}
+	} else if raw, ok := args["content"]; ok && raw != nil {
+		content = []byte(fmt.Sprint(raw))
+	} else {
+		return nil, fmt.Errorf("content or path required")
+	}
+	saved, err := t.Store.PutArtifact(ctx, artifact, content)
+	if err != nil {
+		return nil, err
+	}
+	return &framework.ToolResult{
+		Success: true,
+		Data: map[string]interface{}{
+			"id":         saved.ID,
+			"name":       saved.Name,
+			"type":       string(saved.Type),
+			"media_type": saved.MediaType,
+			"digest":     saved.Digest,
+			"size":       saved.Size,
+		},
+	}, nil
+}
+
+func (t *ArtifactTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.Store != nil
+}
+
+func (t *ArtifactTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead)}
+}
+
+// artifactTaskID names the task an artifact belongs to, preferring the task
+// context over the "task.id" state key.
+func artifactTaskID(ctx context.Context, state *framework.Context) string {
+	if task, ok := framework.TaskContextFrom(ctx); ok && task.ID != "" {
+		return task.ID
+	}
+	if state != nil {
+		return state.GetString("task.id")
+	}
+	return ""
+}
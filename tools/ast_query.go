@@ -0,0 +1,361 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+)
+
+// ASTQueryTool exposes one focused query over the AST index. Unlike the
+// multi-action query_ast tool, each instance has a small fixed parameter set
+// so models with weaker tool calling can still use the index reliably.
+type ASTQueryTool struct {
+	Query   string
+	manager *ast.IndexManager
+}
+
+// ASTQueryTools returns the ast_* tool family backed by manager.
+func ASTQueryTools(manager *ast.IndexManager) []framework.Tool {
+	return []framework.Tool{
+		&ASTQueryTool{Query: "find_symbol", manager: manager},
+		&ASTQueryTool{Query: "callers", manager: manager},
+		&ASTQueryTool{Query: "callees", manager: manager},
+		&ASTQueryTool{Query: "dependencies", manager: manager},
+	}
+}
+
+func (t *ASTQueryTool) Name() string { return "ast_" + t.Query }
+
+func (t *ASTQueryTool) Description() string {
+	switch t.Query {
+	case "find_symbol":
+		return "Finds symbols in the AST index by name (supports * wildcards) and reports their file and line."
+	case "callers":
+		return "Lists functions that call the given symbol, including calls from other files."
+	case "callees":
+		return "Lists functions called by the given symbol."
+	case "dependencies":
+		return "Shows what a file imports and which indexed files depend on it, or a symbol's dependency graph."
+	default:
+		return "AST index query"
+	}
+}
+
+func (t *ASTQueryTool) Category() string { return "search" }
+
+func (t *ASTQueryTool) Parameters() []framework.ToolParameter {
+	switch t.Query {
+	case "find_symbol":
+		return []framework.ToolParameter{
+			{Name: "name", Type: "string", Description: "Symbol name or pattern, e.g. Parse*", Required: true},
+			{Name: "type", Type: "string", Description: "Optional node type filter (function, method, struct, ...)", Required: false},
+			{Name: "exported_only", Type: "boolean", Required: false},
+			{Name: "limit", Type: "int", Required: false, Default: 20},
+		}
+	case "callers", "callees":
+		return []framework.ToolParameter{{Name: "symbol", Type: "string", Required: true}}
+	case "dependencies":
+		return []framework.ToolParameter{
+			{Name: "file", Type: "string", Description: "File path to inspect", Required: false},
+			{Name: "symbol", Type: "string", Description: "Symbol to inspect when no file is given", Required: false},
+		}
+	default:
+		return nil
+	}
+}
+
+func (t *ASTQueryTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	if t.manager == nil {
+		return nil, fmt.Errorf("ast index unavailable")
+	}
+	if err := t.manager.EnsureIndexed(); err != nil {
+		return nil, fmt.Errorf("ast index: %w", err)
+	}
+	switch t.Query {
+	case "find_symbol":
+		return t.findSymbol(args)
+	case "callers":
+		return t.callers(args)
+	case "callees":
+		return t.callees(args)
+	case "dependencies":
+		if file := stringArg(args, "file"); file != "" {
+			return t.fileDependencies(file)
+		}
+		return t.symbolDependencies(args)
+	default:
+		return nil, fmt.Errorf("unsupported ast query %s", t.Query)
+	}
+}
+
+func (t *ASTQueryTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.manager != nil
+}
+
+// Activated reports whether the workspace scan has started; until then the
+// first call triggers it.
+func (t *ASTQueryTool) Activated() bool {
+	return t.manager == nil || t.manager.Activated()
+}
+
+func (t *ASTQueryTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{
+		Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList),
+	}
+}
+
+func (t *ASTQueryTool) findSymbol(args map[string]interface{}) (*framework.ToolResult, error) {
+	name := stringArg(args, "name")
+	if name == "" {
+		return nil, fmt.Errorf("name parameter required")
+	}
+	limit := toInt(args["limit"])
+	if limit <= 0 {
+		limit = 20
+	}
+	query := ast.NodeQuery{NamePattern: strings.ReplaceAll(name, "*", "%"), Limit: limit}
+	if nodeType := stringArg(args, "type"); nodeType != "" {
+		query.Types = []ast.NodeType{ast.NodeType(nodeType)}
+	}
+	if exportedOnly, ok := args["exported_only"].(bool); ok && exportedOnly {
+		query.IsExported = &exportedOnly
+	}
+	nodes, err := t.manager.SearchNodes(query)
+	if err != nil {
+		return nil, err
+	}
+	return successResult(map[string]interface{}{
+		"symbols": t.describeNodes(nodes),
+		"count":   len(nodes),
+	}), nil
+}
+
+// callers merges call edges resolved inside a file with functions whose
+// recorded "calls" attribute names the symbol. The Go parser can only resolve
+// same-file calls as edges, so the attribute is what surfaces cross-file
+// callers.
+func (t *ASTQueryTool) callers(args map[string]interface{}) (*framework.ToolResult, error) {
+	node, err := t.resolveSymbol(args)
+	if err != nil {
+		return nil, err
+	}
+	store := t.manager.Store()
+	direct, err := store.GetCallers(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var callers []*ast.Node
+	for _, caller := range direct {
+		if caller != nil && !seen[caller.ID] {
+			seen[caller.ID] = true
+			callers = append(callers, caller)
+		}
+	}
+	functions, err := store.SearchNodes(ast.NodeQuery{Types: []ast.NodeType{ast.NodeTypeFunction, ast.NodeTypeMethod}})
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range functions {
+		if seen[fn.ID] {
+			continue
+		}
+		for _, call := range nodeCalls(fn) {
+			if call == node.Name {
+				seen[fn.ID] = true
+				callers = append(callers, fn)
+				break
+			}
+		}
+	}
+	return successResult(map[string]interface{}{
+		"symbol":  node.Name,
+		"callers": t.describeNodes(callers),
+		"count":   len(callers),
+	}), nil
+}
+
+// callees lists callees resolved in the same file plus the names of functions
+// called in other files or packages.
+func (t *ASTQueryTool) callees(args map[string]interface{}) (*framework.ToolResult, error) {
+	node, err := t.resolveSymbol(args)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := t.manager.Store().GetCallees(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	external := nodeCalls(node)
+	sort.Strings(external)
+	return successResult(map[string]interface{}{
+		"symbol":   node.Name,
+		"callees":  t.describeNodes(resolved),
+		"external": external,
+	}), nil
+}
+
+// fileDependencies reports a file's imports and the indexed files importing
+// the package that contains it.
+func (t *ASTQueryTool) fileDependencies(file string) (*framework.ToolResult, error) {
+	store := t.manager.Store()
+	meta, err := t.lookupFile(file)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := store.GetNodesByFile(meta.ID)
+	if err != nil {
+		return nil, err
+	}
+	imports := make([]string, 0)
+	for _, node := range nodes {
+		if node.Type == ast.NodeTypeImport {
+			imports = append(imports, node.Name)
+		}
+	}
+	sort.Strings(imports)
+
+	dir := filepath.ToSlash(filepath.Dir(meta.Path))
+	importers, err := store.SearchNodes(ast.NodeQuery{Types: []ast.NodeType{ast.NodeTypeImport}})
+	if err != nil {
+		return nil, err
+	}
+	dependentIDs := make(map[string]bool)
+	for _, imp := range importers {
+		if imp.FileID != meta.ID && importMatchesDir(imp.Name, dir) {
+			dependentIDs[imp.FileID] = true
+		}
+	}
+	dependents := make([]string, 0, len(dependentIDs))
+	for id := range dependentIDs {
+		if dep, err := store.GetFile(id); err == nil && dep != nil {
+			dependents = append(dependents, dep.Path)
+		}
+	}
+	sort.Strings(dependents)
+	return successResult(map[string]interface{}{
+		"file":       meta.Path,
+		"imports":    imports,
+		"dependents": dependents,
+	}), nil
+}
+
+func (t *ASTQueryTool) symbolDependencies(args map[string]interface{}) (*framework.ToolResult, error) {
+	symbol := stringArg(args, "symbol")
+	if symbol == "" {
+		return nil, fmt.Errorf("file or symbol parameter required")
+	}
+	graph, err := t.manager.GetDependencyGraph(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return successResult(map[string]interface{}{
+		"symbol":       symbol,
+		"dependencies": t.describeNodes(graph.Dependencies),
+		"dependents":   t.describeNodes(graph.Dependents),
+	}), nil
+}
+
+func (t *ASTQueryTool) resolveSymbol(args map[string]interface{}) (*ast.Node, error) {
+	symbol := stringArg(args, "symbol")
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol parameter required")
+	}
+	nodes, err := t.manager.QuerySymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if node.Type == ast.NodeTypeFunction || node.Type == ast.NodeTypeMethod {
+			return node, nil
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("symbol %s not found", symbol)
+	}
+	return nodes[0], nil
+}
+
+// lookupFile accepts absolute paths as well as paths relative to any indexed
+// root by falling back to a suffix match.
+func (t *ASTQueryTool) lookupFile(file string) (*ast.FileMetadata, error) {
+	store := t.manager.Store()
+	if meta, err := store.GetFileByPath(file); err == nil && meta != nil {
+		return meta, nil
+	}
+	files, err := store.ListFiles("")
+	if err != nil {
+		return nil, err
+	}
+	want := "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(file)), "./")
+	for _, meta := range files {
+		if strings.HasSuffix(filepath.ToSlash(meta.Path), want) {
+			return meta, nil
+		}
+	}
+	return nil, fmt.Errorf("file %s not indexed", file)
+}
+
+// describeNodes extends summarizeNodes with the file path of each node.
+func (t *ASTQueryTool) describeNodes(nodes []*ast.Node) []map[string]interface{} {
+	summaries := summarizeNodes(nodes)
+	paths := make(map[string]string)
+	for _, summary := range summaries {
+		fileID := fmt.Sprint(summary["file_id"])
+		path, ok := paths[fileID]
+		if !ok {
+			if meta, err := t.manager.Store().GetFile(fileID); err == nil && meta != nil {
+				path = meta.Path
+			}
+			paths[fileID] = path
+		}
+		if path != "" {
+			summary["file"] = path
+		}
+	}
+	return summaries
+}
+
+// importMatchesDir reports whether an import path plausibly names the package
+// in dir. The index does not know module paths, so the trailing path segment
+// is compared; single-segment (standard library) imports never match.
+func importMatchesDir(importPath, dir string) bool {
+	importPath = strings.Trim(importPath, "/")
+	idx := strings.LastIndex(importPath, "/")
+	if idx < 0 {
+		return false
+	}
+	return strings.HasSuffix(dir, importPath[idx:])
+}
+
+// nodeCalls reads the unresolved callee names recorded by the parser. The
+// attribute round-trips through JSON, so both slice shapes are accepted.
+func nodeCalls(node *ast.Node) []string {
+	if node == nil || node.Attributes == nil {
+		return nil
+	}
+	switch calls := node.Attributes["calls"].(type) {
+	case []string:
+		return append([]string(nil), calls...)
+	case []interface{}:
+		res := make([]string, 0, len(calls))
+		for _, call := range calls {
+			res = append(res, fmt.Sprint(call))
+		}
+		return res
+	}
+	return nil
+}
+
+func stringArg(args map[string]interface{}, key string) string {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
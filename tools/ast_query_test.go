@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework/ast"
+)
+
+func newIndexedWorkspace(t *testing.T, files map[string]string) *ast.IndexManager {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	store, err := ast.NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	manager := ast.NewIndexManager(store, ast.IndexConfig{WorkspacePath: dir})
+	assert.NoError(t, manager.IndexWorkspace())
+	return manager
+}
+
+func astQueryTool(manager *ast.IndexManager, name string) *ASTQueryTool {
+	for _, tool := range ASTQueryTools(manager) {
+		if tool.Name() == name {
+			return tool.(*ASTQueryTool)
+		}
+	}
+	return nil
+}
+
+func TestASTQueryToolsResolveCallersAcrossFiles(t *testing.T) {
+	manager := newIndexedWorkspace(t, map[string]string{
+		"store/store.go": "package store\n\nfunc Save() { validate() }\n\nfunc validate() {}\n",
+		"api/handler.go": "package api\n\nimport \"example.com/app/store\"\n\nfunc Handle() { store.Save() }\n",
+	})
+	ctx := context.Background()
+
+	res, err := astQueryTool(manager, "ast_find_symbol").Execute(ctx, nil, map[string]interface{}{"name": "Sav*"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Data["count"])
+
+	res, err = astQueryTool(manager, "ast_callers").Execute(ctx, nil, map[string]interface{}{"symbol": "Save"})
+	assert.NoError(t, err)
+	callers := res.Data["callers"].([]map[string]interface{})
+	if assert.Len(t, callers, 1) {
+		assert.Equal(t, "Handle", callers[0]["name"])
+	}
+
+	res, err = astQueryTool(manager, "ast_callees").Execute(ctx, nil, map[string]interface{}{"symbol": "Save"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Data["callees"], 1)
+
+	res, err = astQueryTool(manager, "ast_dependencies").Execute(ctx, nil, map[string]interface{}{"file": "store/store.go"})
+	assert.NoError(t, err)
+	dependents := res.Data["dependents"].([]string)
+	if assert.Len(t, dependents, 1) {
+		assert.Equal(t, "handler.go", filepath.Base(dependents[0]))
+	}
+}
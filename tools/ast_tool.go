@@ -37,6 +37,9 @@ func (t *ASTTool) Execute(ctx context.Context, state *framework.Context, args ma
 	if t.manager == nil {
 		return nil, fmt.Errorf("ast index unavailable")
 	}
+	if err := t.manager.EnsureIndexed(); err != nil {
+		return nil, fmt.Errorf("ast index: %w", err)
+	}
 	action := fmt.Sprint(args["action"])
 	switch action {
 	case "list_symbols", "search":
@@ -97,7 +100,7 @@ func (t *ASTTool) handleSignature(args map[string]interface{}) (*framework.ToolR
 	if err != nil {
 		return nil, err
 	}
-	return successResult(map[string]interface{}{
+	data := map[string]interface{}{
 		"name":       node.Name,
 		"type":       node.Type,
 		"signature":  node.Signature,
@@ -105,7 +108,11 @@ func (t *ASTTool) handleSignature(args map[string]interface{}) (*framework.ToolR
 		"file_id":    node.FileID,
 		"line":       node.StartLine,
 		"exported":   node.IsExported,
-	}), nil
+	}
+	if meta, err := t.manager.Store().GetFile(node.FileID); err == nil && meta != nil {
+		data["file"] = meta.Path
+	}
+	return successResult(data), nil
 }
 
 func (t *ASTTool) handleCallers(args map[string]interface{}) (*framework.ToolResult, error) {
@@ -173,6 +180,12 @@ func (t *ASTTool) IsAvailable(ctx context.Context, state *framework.Context) boo
 	return t.manager != nil
 }
 
+// Activated reports whether the workspace scan has started; until then the
+// first call triggers it.
+func (t *ASTTool) Activated() bool {
+	return t.manager == nil || t.manager.Activated()
+}
+
 func (t *ASTTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{
 		Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList),
@@ -0,0 +1,296 @@
+package tools
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+	"github.com/lexcodex/relurpify/tools/testrunner"
+)
+
+// CoverageRunStateKey is where coverage_gaps leaves the CoverageRun of its
+// latest measurement in the task state.
+const CoverageRunStateKey = "coverage_gaps.last_run"
+
+// CoverageGap is a function with statements no test executes. Without an
+// AST entry for the file, the gap spans the file's uncovered lines and
+// Function is empty.
+type CoverageGap struct {
+	File       string `json:"file"`
+	Function   string `json:"function,omitempty"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Covered    int    `json:"covered"`
+	Statements int    `json:"statements"`
+}
+
+// Name renders the gap as file:function, or file:start-end for file gaps.
+func (g CoverageGap) Name() string {
+	if g.Function != "" {
+		return g.File + ":" + g.Function
+	}
+	return fmt.Sprintf("%s:%d-%d", g.File, g.StartLine, g.EndLine)
+}
+
+// CoverageRun is one coverage measurement: the statement coverage of the
+// tested code, its largest gaps, and the outcome of the tests that ran.
+type CoverageRun struct {
+	Language   string        `json:"language"`
+	Command    string        `json:"command"`
+	Percent    float64       `json:"percent"`
+	Covered    int           `json:"covered"`
+	Statements int           `json:"statements"`
+	Gaps       []CoverageGap `json:"gaps"`
+	testrunner.Report
+}
+
+func init() {
+	gob.Register(CoverageGap{})
+	gob.Register([]CoverageGap{})
+	gob.Register(CoverageRun{})
+}
+
+const defaultCoverageGaps = 10
+
+// CoverageGapsTool runs the workspace tests with a per-line coverage report
+// and lists the functions with the most uncovered statements, located through
+// the AST index. It can also read a report written earlier (go test
+// -coverprofile, or coverage.py JSON) instead of running the tests.
+type CoverageGapsTool struct {
+	Toolchains []testrunner.Toolchain
+	Workdir    string
+	Timeout    time.Duration
+	Runner     framework.CommandRunner
+	Index      *ast.IndexManager
+	manager    *framework.PermissionManager
+	agentID    string
+	spec       *framework.AgentRuntimeSpec
+}
+
+func (t *CoverageGapsTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *CoverageGapsTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *CoverageGapsTool) Name() string { return "coverage_gaps" }
+func (t *CoverageGapsTool) Description() string {
+	return "Runs the tests with coverage and lists the functions with the most uncovered statements."
+}
+func (t *CoverageGapsTool) Category() string { return "execution" }
+func (t *CoverageGapsTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "target", Type: "string", Description: "Package, directory, or file to measure (default: everything)", Required: false},
+		{Name: "language", Type: "string", Description: "Toolchain to use when the workspace has several: " + strings.Join(testrunner.Languages(t.Toolchains), ", "), Required: false},
+		{Name: "report", Type: "string", Description: "Read this existing coverage report instead of running the tests", Required: false},
+		{Name: "limit", Type: "integer", Description: "Maximum gaps to list", Required: false, Default: defaultCoverageGaps},
+	}
+}
+
+func (t *CoverageGapsTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	tc, err := pickToolchain(t.Toolchains, stringArg(args, "language"))
+	if err != nil {
+		return nil, err
+	}
+	if tc.ReadCoverage == nil {
+		return nil, fmt.Errorf("the %s toolchain cannot write a per-line coverage report", tc.Language)
+	}
+	limit := defaultCoverageGaps
+	if raw := stringArg(args, "limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	run := CoverageRun{Language: tc.Language}
+	var runErr error
+	report := stringArg(args, "report")
+	if report != "" {
+		if !filepath.IsAbs(report) {
+			report = filepath.Join(t.Workdir, report)
+		}
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, report); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		report = filepath.Join(t.Workdir, ".relurpify-coverage-"+tc.Language)
+		defer os.Remove(report)
+		cmdline := tc.Command(testrunner.Request{Target: stringArg(args, "target"), CoverProfile: report})
+		if err := authorizeCommand(ctx, t.manager, t.agentID, t.spec, cmdline); err != nil {
+			return nil, err
+		}
+		if t.Runner == nil {
+			return nil, fmt.Errorf("command runner missing")
+		}
+		var stdout, stderr string
+		stdout, stderr, runErr = t.Runner.Run(ctx, framework.CommandRequest{Workdir: t.Workdir, Args: cmdline, Timeout: t.Timeout})
+		run.Command = strings.Join(cmdline, " ")
+		run.Report = tc.Parse(stdout + "\n" + stderr)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && runErr != nil {
+			return &framework.ToolResult{Success: false, Data: map[string]interface{}{"failures": run.Failures}, Error: "tests failed before writing a coverage report: " + runErr.Error()}, nil
+		}
+		return nil, err
+	}
+	coverage, err := tc.ReadCoverage(data, t.Workdir)
+	if err != nil {
+		return nil, err
+	}
+	run.Covered, run.Statements = coverage.Statements()
+	run.Percent = coverage.Percent()
+	run.Gaps = t.gaps(coverage)
+	if len(run.Gaps) > limit {
+		run.Gaps = run.Gaps[:limit]
+	}
+	if state != nil {
+		state.Set(CoverageRunStateKey, run)
+	}
+	gaps := make([]map[string]interface{}, 0, len(run.Gaps))
+	for _, gap := range run.Gaps {
+		gaps = append(gaps, map[string]interface{}{
+			"file":       gap.File,
+			"function":   gap.Function,
+			"start_line": gap.StartLine,
+			"end_line":   gap.EndLine,
+			"covered":    gap.Covered,
+			"statements": gap.Statements,
+		})
+	}
+	result := &framework.ToolResult{
+		Success: runErr == nil && len(run.Failures) == 0,
+		Data: map[string]interface{}{
+			"language":   run.Language,
+			"coverage":   run.Percent,
+			"covered":    run.Covered,
+			"statements": run.Statements,
+			"gaps":       gaps,
+			"failed":     run.Failed,
+			"failures":   run.Failures,
+		},
+	}
+	switch {
+	case run.Failed > 0:
+		result.Error = fmt.Sprintf("%d of %d tests failed", run.Failed, run.Passed+run.Failed)
+	case len(run.Failures) > 0:
+		result.Error = "test run failed: " + run.Failures[0].Name
+	case runErr != nil:
+		result.Error = runErr.Error()
+	}
+	return result, nil
+}
+
+// gaps lists the functions of each file with uncovered statements, most
+// uncovered first. Files the AST index does not know become one gap each.
+func (t *CoverageGapsTool) gaps(coverage testrunner.Coverage) []CoverageGap {
+	if t.Index != nil {
+		_ = t.Index.EnsureIndexed()
+	}
+	var gaps []CoverageGap
+	for _, file := range coverage.Files() {
+		covered, total := coverage.Range(file, 0, int(^uint(0)>>1))
+		if covered == total {
+			continue
+		}
+		functions := t.functions(file)
+		found := false
+		for _, fn := range functions {
+			covered, total := coverage.Range(file, fn.StartLine, fn.EndLine)
+			if covered == total {
+				continue
+			}
+			found = true
+			gaps = append(gaps, CoverageGap{File: file, Function: fn.Name, StartLine: fn.StartLine, EndLine: fn.EndLine, Covered: covered, Statements: total})
+		}
+		if found {
+			continue
+		}
+		gap := CoverageGap{File: file, Covered: covered, Statements: total}
+		for _, block := range coverage[file] {
+			if block.Covered {
+				continue
+			}
+			if gap.StartLine == 0 || block.StartLine < gap.StartLine {
+				gap.StartLine = block.StartLine
+			}
+			if block.EndLine > gap.EndLine {
+				gap.EndLine = block.EndLine
+			}
+		}
+		gaps = append(gaps, gap)
+	}
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gaps[i].Statements-gaps[i].Covered > gaps[j].Statements-gaps[j].Covered
+	})
+	return gaps
+}
+
+// functions returns the indexed functions and methods of file.
+func (t *CoverageGapsTool) functions(file string) []*ast.Node {
+	if t.Index == nil {
+		return nil
+	}
+	store := t.Index.Store()
+	for _, path := range []string{filepath.Join(t.Workdir, filepath.FromSlash(file)), file} {
+		meta, err := store.GetFileByPath(path)
+		if err != nil || meta == nil {
+			continue
+		}
+		nodes, err := store.GetNodesByFile(meta.ID)
+		if err != nil {
+			return nil
+		}
+		var functions []*ast.Node
+		for _, node := range nodes {
+			if node.Type == ast.NodeTypeFunction || node.Type == ast.NodeTypeMethod {
+				functions = append(functions, node)
+			}
+		}
+		return functions
+	}
+	return nil
+}
+
+func (t *CoverageGapsTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	for _, tc := range t.Toolchains {
+		if tc.ReadCoverage != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *CoverageGapsTool) Permissions() framework.ToolPermissions {
+	var perms *framework.PermissionSet
+	for _, tc := range t.Toolchains {
+		if tc.ReadCoverage == nil {
+			continue
+		}
+		cmdline := tc.Command(testrunner.Request{})
+		set := framework.NewExecutionPermissionSet(t.Workdir, cmdline[0], []string{cmdline[1], "*"})
+		if perms == nil {
+			perms = set
+			continue
+		}
+		perms.Executables = append(perms.Executables, set.Executables...)
+	}
+	if perms == nil {
+		perms = framework.NewFileSystemPermissionSet(t.Workdir, framework.FileSystemRead, framework.FileSystemList)
+	}
+	return framework.ToolPermissions{Permissions: perms}
+}
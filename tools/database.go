@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools/db"
+)
+
+// DatabaseTools returns db_query and db_schema over the configured
+// databases. SQLite paths resolve against base and need filesystem read
+// access; Postgres databases run psql through runner and need the psql
+// binary and egress to their host in the manifest.
+func DatabaseTools(configs []db.Config, base string, runner framework.CommandRunner) []framework.Tool {
+	shared := &databaseTool{Base: base, Runner: runner, Databases: map[string]db.Config{}}
+	for _, cfg := range configs {
+		shared.Databases[cfg.Name] = cfg
+	}
+	return []framework.Tool{
+		&DatabaseQueryTool{shared},
+		&DatabaseSchemaTool{shared},
+	}
+}
+
+// databaseTool holds what the database tools share: the configured
+// databases, the command runner for psql, and the agent's permissions.
+type databaseTool struct {
+	Base      string
+	Runner    framework.CommandRunner
+	Databases map[string]db.Config
+	// Timeout bounds each psql command; zero uses thirty seconds.
+	Timeout time.Duration
+	manager *framework.PermissionManager
+	agentID string
+	spec    *framework.AgentRuntimeSpec
+}
+
+func (t *databaseTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *databaseTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *databaseTool) Category() string { return "database" }
+
+func (t *databaseTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return len(t.Databases) > 0
+}
+
+// sortedNames lists the configured databases in name order.
+func (t *databaseTool) sortedNames() []string {
+	names := make([]string, 0, len(t.Databases))
+	for name := range t.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// names joins the configured databases for parameter descriptions and
+// errors.
+func (t *databaseTool) names() string {
+	return strings.Join(t.sortedNames(), ", ")
+}
+
+// open checks the agent may reach the named database and connects to it.
+func (t *databaseTool) open(ctx context.Context, name string) (db.Config, db.Conn, error) {
+	cfg, ok := t.Databases[name]
+	if !ok {
+		return cfg, nil, fmt.Errorf("unknown database %q (configured: %s)", name, t.names())
+	}
+	switch cfg.Driver {
+	case db.SQLite:
+		path := preparePath(t.Base, cfg.Path)
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
+				return cfg, nil, err
+			}
+		}
+		conn, err := db.OpenSQLite(path)
+		if err != nil {
+			return cfg, nil, fmt.Errorf("database %s: %w", name, err)
+		}
+		return cfg, conn, nil
+	case db.Postgres:
+		host, port, err := cfg.Endpoint()
+		if err != nil {
+			return cfg, nil, fmt.Errorf("database %s: %w", name, err)
+		}
+		if t.manager != nil {
+			if err := t.manager.CheckNetwork(ctx, t.agentID, "egress", "tcp", host, port); err != nil {
+				return cfg, nil, err
+			}
+		}
+		timeout := t.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		return cfg, &db.PostgresConn{
+			Config:  cfg,
+			Runner:  t.Runner,
+			Workdir: t.Base,
+			Timeout: timeout,
+			Authorize: func(ctx context.Context, cmdline []string) error {
+				return authorizeCommand(ctx, t.manager, t.agentID, t.spec, cmdline)
+			},
+		}, nil
+	}
+	return cfg, nil, fmt.Errorf("database %s: unknown driver %q", name, cfg.Driver)
+}
+
+// Permissions declares read access to every SQLite file plus psql and
+// egress to every Postgres host.
+func (t *databaseTool) Permissions() framework.ToolPermissions {
+	perms := &framework.PermissionSet{}
+	psql := false
+	for _, name := range t.sortedNames() {
+		cfg := t.Databases[name]
+		switch cfg.Driver {
+		case db.SQLite:
+			perms.FileSystem = append(perms.FileSystem, framework.FileSystemPermission{
+				Action: framework.FileSystemRead,
+				Path:   filepath.ToSlash(preparePath(t.Base, cfg.Path)),
+			})
+		case db.Postgres:
+			host, port, err := cfg.Endpoint()
+			if err != nil {
+				continue
+			}
+			psql = true
+			perms.Network = append(perms.Network, framework.NetworkPermission{
+				Direction: "egress",
+				Protocol:  "tcp",
+				Host:      host,
+				Port:      port,
+			})
+		}
+	}
+	if psql {
+		perms.Executables = append(perms.Executables, framework.ExecutablePermission{Binary: db.PsqlBinary, Args: []string{"*"}})
+	}
+	return framework.ToolPermissions{Permissions: perms}
+}
+
+// DatabaseQueryTool runs one read-only SQL statement.
+type DatabaseQueryTool struct {
+	*databaseTool
+}
+
+func (t *DatabaseQueryTool) Name() string { return "db_query" }
+func (t *DatabaseQueryTool) Description() string {
+	return "Runs one read-only SQL statement (SELECT, WITH, VALUES, EXPLAIN, SHOW) against a configured database and returns the rows."
+}
+func (t *DatabaseQueryTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "database", Type: "string", Description: "Database name: " + t.names(), Required: true},
+		{Name: "sql", Type: "string", Description: "A single read-only statement", Required: true},
+		{Name: "limit", Type: "int", Description: "Maximum rows to return", Required: false, Default: db.DefaultMaxRows},
+	}
+}
+
+func (t *DatabaseQueryTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	name := stringArg(args, "database")
+	stmt := stringArg(args, "sql")
+	if err := db.CheckReadOnly(stmt); err != nil {
+		return nil, err
+	}
+	cfg, conn, err := t.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	limit := cfg.Limit(toInt(args["limit"]))
+	result, err := conn.Query(ctx, stmt, limit)
+	if err != nil {
+		return &framework.ToolResult{Success: false, Data: map[string]interface{}{"database": name}, Error: err.Error()}, nil
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"database":  name,
+		"columns":   result.Columns,
+		"rows":      result.Rows,
+		"row_count": len(result.Rows),
+		"truncated": result.Truncated,
+	}}, nil
+}
+
+// DatabaseSchemaTool describes the tables and views of a database.
+type DatabaseSchemaTool struct {
+	*databaseTool
+}
+
+func (t *DatabaseSchemaTool) Name() string { return "db_schema" }
+func (t *DatabaseSchemaTool) Description() string {
+	return "Lists the tables and views of a configured database with their columns, types, defaults, and primary keys."
+}
+func (t *DatabaseSchemaTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "database", Type: "string", Description: "Database name: " + t.names(), Required: true},
+		{Name: "table", Type: "string", Description: "Only describe this table or view", Required: false},
+	}
+}
+
+func (t *DatabaseSchemaTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	name := stringArg(args, "database")
+	_, conn, err := t.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	tables, err := conn.Schema(ctx, stringArg(args, "table"))
+	if err != nil {
+		return &framework.ToolResult{Success: false, Data: map[string]interface{}{"database": name}, Error: err.Error()}, nil
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"database": name,
+		"tables":   tables,
+	}}, nil
+}
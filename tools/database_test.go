@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools/db"
+)
+
+func TestDatabaseToolsQuerySQLite(t *testing.T) {
+	base := t.TempDir()
+	conn, err := sql.Open("sqlite3", filepath.Join(base, "app.db"))
+	require.NoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (name) VALUES ('ada'), ('grace')`)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	dbTools := DatabaseTools([]db.Config{{Name: "app", Driver: db.SQLite, Path: "app.db", MaxRows: 1}}, base, nil)
+	require.Len(t, dbTools, 2)
+	query, schema := dbTools[0], dbTools[1]
+	assert.Equal(t, "db_query", query.Name())
+	assert.Equal(t, "db_schema", schema.Name())
+
+	res, err := query.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"database": "app",
+		"sql":      "SELECT name FROM users ORDER BY id",
+		"limit":    10,
+	})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	assert.Equal(t, [][]string{{"ada"}}, res.Data["rows"])
+	assert.Equal(t, true, res.Data["truncated"])
+
+	_, err = query.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"database": "app",
+		"sql":      "DELETE FROM users",
+	})
+	require.Error(t, err)
+
+	_, err = query.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"database": "other",
+		"sql":      "SELECT 1",
+	})
+	require.ErrorContains(t, err, "configured: app")
+
+	res, err = schema.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"database": "app"})
+	require.NoError(t, err)
+	tables := res.Data["tables"].([]db.Table)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].Name)
+}
+
+func TestDatabaseToolsPermissions(t *testing.T) {
+	dbTools := DatabaseTools([]db.Config{
+		{Name: "app", Driver: db.SQLite, Path: "data/app.db"},
+		{Name: "wh", Driver: db.Postgres, URL: "postgres://reader@db.internal:6543/wh"},
+	}, "/work", nil)
+	perms := dbTools[0].Permissions().Permissions
+	require.Len(t, perms.FileSystem, 1)
+	assert.Equal(t, framework.FileSystemRead, perms.FileSystem[0].Action)
+	assert.Equal(t, "/work/data/app.db", perms.FileSystem[0].Path)
+	require.Len(t, perms.Executables, 1)
+	assert.Equal(t, db.PsqlBinary, perms.Executables[0].Binary)
+	require.Len(t, perms.Network, 1)
+	assert.Equal(t, framework.NetworkPermission{Direction: "egress", Protocol: "tcp", Host: "db.internal", Port: 6543}, perms.Network[0])
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// PsqlBinary is the client PostgresConn runs.
+const PsqlBinary = "psql"
+
+// PostgresConn reaches a Postgres database through psql. Connection details
+// travel in PG* environment variables so the password never shows up in the
+// command line, and the session defaults to read-only transactions.
+type PostgresConn struct {
+	Config  Config
+	Runner  framework.CommandRunner
+	Workdir string
+	Timeout time.Duration
+	// Authorize, when set, vets each psql command line before it runs.
+	Authorize func(ctx context.Context, cmdline []string) error
+}
+
+// Query implements Conn.
+func (c *PostgresConn) Query(ctx context.Context, stmt string, limit int) (*Result, error) {
+	if err := CheckReadOnly(stmt); err != nil {
+		return nil, err
+	}
+	stmt = strings.TrimSpace(stmt)
+	for strings.HasSuffix(stmt, ";") {
+		stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+	}
+	if limitable(stmt) {
+		// The newline keeps a trailing line comment from swallowing the
+		// closing parenthesis.
+		stmt = fmt.Sprintf("SELECT * FROM (%s\n) AS relurpify_query LIMIT %d", stmt, limit+1)
+	}
+	records, err := c.run(ctx, []string{"--command", stmt}, "")
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Rows: [][]string{}}
+	if len(records) == 0 {
+		return result, nil
+	}
+	result.Columns = records[0]
+	for _, record := range records[1:] {
+		if len(result.Rows) >= limit {
+			result.Truncated = true
+			break
+		}
+		row := make([]string, len(record))
+		for i, value := range record {
+			row[i] = truncateCell(value)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// schemaQuery lists the columns of user tables and views, optionally only
+// those of :'table'.
+const schemaQuery = `SELECT c.table_schema, c.table_name, t.table_type, c.column_name, c.data_type,
+  c.is_nullable, COALESCE(c.column_default, ''),
+  EXISTS (
+    SELECT 1 FROM information_schema.table_constraints tc
+    JOIN information_schema.key_column_usage k
+      ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+    WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+      AND tc.table_name = c.table_name AND k.column_name = c.column_name
+  )
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema NOT IN ('pg_catalog', 'information_schema')
+  AND (:'table' = '' OR c.table_name = :'table' OR c.table_schema || '.' || c.table_name = :'table')
+ORDER BY c.table_schema, c.table_name, c.ordinal_position;
+`
+
+// Schema implements Conn.
+func (c *PostgresConn) Schema(ctx context.Context, table string) ([]Table, error) {
+	records, err := c.run(ctx, []string{"--set", "table=" + table}, schemaQuery)
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) != 8 {
+			return nil, fmt.Errorf("unexpected schema row with %d fields", len(record))
+		}
+		name := record[1]
+		if record[0] != "public" {
+			name = record[0] + "." + record[1]
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != name {
+			kind := "table"
+			if record[2] == "VIEW" {
+				kind = "view"
+			}
+			tables = append(tables, Table{Name: name, Kind: kind})
+		}
+		current := &tables[len(tables)-1]
+		current.Columns = append(current.Columns, Column{
+			Name:       record[3],
+			Type:       record[4],
+			Nullable:   record[5] == "YES",
+			Default:    record[6],
+			PrimaryKey: record[7] == "t",
+		})
+	}
+	if table != "" && len(tables) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return tables, nil
+}
+
+// Close implements Conn; psql connections last one command.
+func (c *PostgresConn) Close() error {
+	return nil
+}
+
+func (c *PostgresConn) run(ctx context.Context, args []string, input string) ([][]string, error) {
+	if c.Runner == nil {
+		return nil, fmt.Errorf("command runner required")
+	}
+	env, err := c.env()
+	if err != nil {
+		return nil, err
+	}
+	cmdline := append([]string{PsqlBinary, "--no-psqlrc", "--csv", "--pset", "null=NULL", "--set", "ON_ERROR_STOP=1"}, args...)
+	if c.Authorize != nil {
+		if err := c.Authorize(ctx, cmdline); err != nil {
+			return nil, err
+		}
+	}
+	stdout, stderr, err := c.Runner.Run(ctx, framework.CommandRequest{
+		Workdir: c.Workdir,
+		Args:    cmdline,
+		Env:     env,
+		Input:   input,
+		Timeout: c.Timeout,
+	})
+	if err != nil {
+		host, port, _ := c.Config.Endpoint()
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return nil, fmt.Errorf("psql %s: %s", hostPort(host, port), msg)
+		}
+		return nil, fmt.Errorf("psql %s: %w", hostPort(host, port), err)
+	}
+	reader := csv.NewReader(strings.NewReader(stdout))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse psql output: %w", err)
+	}
+	return records, nil
+}
+
+// env translates the connection URL into psql's environment variables.
+func (c *PostgresConn) env() ([]string, error) {
+	u, err := c.Config.parseURL()
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", c.Config.Name, err)
+	}
+	host, port, err := c.Config.Endpoint()
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", c.Config.Name, err)
+	}
+	env := []string{
+		"PGHOST=" + host,
+		"PGPORT=" + strconv.Itoa(port),
+		"PGOPTIONS=-c default_transaction_read_only=on",
+	}
+	if u.User != nil {
+		env = append(env, "PGUSER="+u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			env = append(env, "PGPASSWORD="+password)
+		}
+	}
+	if database := strings.TrimPrefix(u.Path, "/"); database != "" {
+		env = append(env, "PGDATABASE="+database)
+	}
+	if mode := u.Query().Get("sslmode"); mode != "" {
+		env = append(env, "PGSSLMODE="+mode)
+	}
+	return env, nil
+}
+
+// limitable reports whether stmt can be wrapped in a LIMITed subquery.
+func limitable(stmt string) bool {
+	code, err := stripLiterals(stmt)
+	if err != nil {
+		return false
+	}
+	words := keywords(code)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "select", "with", "values", "table":
+		return true
+	}
+	return false
+}
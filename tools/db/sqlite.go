@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteConn reads a SQLite file. The file is opened read-only with
+// query_only set, so even a statement CheckReadOnly missed cannot write.
+type SQLiteConn struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the SQLite file at path read-only. A missing file is an
+// error rather than a new empty database.
+func OpenSQLite(path string) (*SQLiteConn, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro&_query_only=true"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteConn{db: db}, nil
+}
+
+// Query implements Conn.
+func (c *SQLiteConn) Query(ctx context.Context, stmt string, limit int) (*Result, error) {
+	if err := CheckReadOnly(stmt); err != nil {
+		return nil, err
+	}
+	rows, err := c.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Columns: columns, Rows: [][]string{}}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result.Rows) >= limit {
+			result.Truncated = true
+			break
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = truncateCell(formatValue(value))
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+// Schema implements Conn.
+func (c *SQLiteConn) Schema(ctx context.Context, table string) ([]Table, error) {
+	query := `SELECT name, type FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'`
+	args := []any{}
+	if table != "" {
+		query += ` AND name = ?`
+		args = append(args, table)
+	}
+	rows, err := c.db.QueryContext(ctx, query+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Name, &t.Kind); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if table != "" && len(tables) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	for i := range tables {
+		if tables[i].Columns, err = c.columns(ctx, tables[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+func (c *SQLiteConn) columns(ctx context.Context, table string) ([]Column, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var (
+			col     Column
+			notNull bool
+			dflt    sql.NullString
+			pk      int
+		)
+		if err := rows.Scan(&col.Name, &col.Type, &notNull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		col.Nullable = !notNull && pk == 0
+		col.Default = dflt.String
+		col.PrimaryKey = pk > 0
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// Close implements Conn.
+func (c *SQLiteConn) Close() error {
+	return c.db.Close()
+}
+
+// formatValue renders a scanned SQLite value as text.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("x'%x'", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case string:
+		return strings.ToValidUTF8(v, "�")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,4 @@
+// Package tools contains the builtin framework.Tool implementations (files,
+// search, git, execution, LSP, and AST queries). Constructors and exported
+// tool structs are part of the stable public API; tools/cli_nix is not.
+package tools
@@ -0,0 +1,495 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// envMask replaces secret values in env tool results, matching the mask the
+// redactor uses for .env literals.
+const envMask = "[REDACTED:env]"
+
+var (
+	envKeyPattern       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	envLinePattern      = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.]*)\s*=(.*)$`)
+	envSecretKey        = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|SECRET|TOKEN|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIAL|AUTH)`)
+	envValueRedactor, _ = framework.NewRedactor(framework.RedactionOptions{})
+)
+
+// EnvTools returns the tools that read and change project environment
+// configuration: .env files and the environment of docker-compose services.
+// They are the mediated path to files that hold secrets, so they skip the
+// file permission matrix: reads mask secret values and every write needs
+// human approval. Manifests can deny the files to the file tools and still
+// let agents wire configuration through these.
+func EnvTools(basePath string) []framework.Tool {
+	return []framework.Tool{
+		&EnvGetTool{BasePath: basePath},
+		&EnvSetTool{BasePath: basePath},
+	}
+}
+
+// EnvGetTool lists the variables of a .env or compose file with secret
+// values masked.
+type EnvGetTool struct {
+	BasePath string
+}
+
+func (t *EnvGetTool) Name() string { return "env_get" }
+func (t *EnvGetTool) Description() string {
+	return "Lists environment variables from a .env file or the environment of docker-compose services. Secret values are masked."
+}
+func (t *EnvGetTool) Category() string { return "env" }
+func (t *EnvGetTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "path", Type: "string", Description: "A .env or docker-compose file", Required: false, Default: ".env"},
+		{Name: "service", Type: "string", Description: "Compose service to list (default all)", Required: false},
+		{Name: "key", Type: "string", Description: "Only report this variable", Required: false},
+	}
+}
+
+func (t *EnvGetTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	file, err := openEnvFile(t.BasePath, envArg(args, "path", ".env"))
+	if err != nil {
+		return nil, err
+	}
+	unlock := fileLocks.Lock(file.path)
+	defer unlock()
+	if err := file.load(); err != nil {
+		return nil, err
+	}
+	service := envArg(args, "service", "")
+	key := envArg(args, "key", "")
+	data := map[string]interface{}{"path": file.rel}
+	if file.compose {
+		services, err := file.composeVariables(service)
+		if err != nil {
+			return &framework.ToolResult{Success: false, Error: err.Error(), Data: data}, nil
+		}
+		out := make(map[string]interface{}, len(services))
+		for name, vars := range services {
+			out[name] = maskEnvVariables(vars, key)
+		}
+		data["services"] = out
+	} else {
+		data["variables"] = maskEnvVariables(file.dotenvVariables(), key)
+	}
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+func (t *EnvGetTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return true
+}
+
+func (t *EnvGetTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead)}
+}
+
+// EnvSetTool sets one variable in a .env or compose file after a human
+// approves the change.
+type EnvSetTool struct {
+	BasePath string
+	manager  *framework.PermissionManager
+	agentID  string
+}
+
+func (t *EnvSetTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *EnvSetTool) Name() string { return "env_set" }
+func (t *EnvSetTool) Description() string {
+	return "Sets an environment variable in a .env file or a docker-compose service's environment. Every change is approved by a human."
+}
+func (t *EnvSetTool) Category() string { return "env" }
+func (t *EnvSetTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "path", Type: "string", Description: "A .env or docker-compose file", Required: false, Default: ".env"},
+		{Name: "key", Type: "string", Required: true},
+		{Name: "value", Type: "string", Required: true},
+		{Name: "service", Type: "string", Description: "Compose service to change; required for compose files", Required: false},
+	}
+}
+
+func (t *EnvSetTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	key := envArg(args, "key", "")
+	if !envKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("invalid env key %q", key)
+	}
+	value := fmt.Sprint(args["value"])
+	if args["value"] == nil || strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("env value for %s must be a single line", key)
+	}
+	file, err := openEnvFile(t.BasePath, envArg(args, "path", ".env"))
+	if err != nil {
+		return nil, err
+	}
+	service := envArg(args, "service", "")
+	if file.compose && service == "" {
+		return nil, fmt.Errorf("service required to set %s in %s", key, file.rel)
+	}
+	if t.manager == nil {
+		return nil, fmt.Errorf("env_set blocked: approval required but permission manager missing")
+	}
+
+	unlock := fileLocks.Lock(file.path)
+	defer unlock()
+	if err := file.load(); err != nil {
+		return nil, err
+	}
+	var previous string
+	var existed bool
+	if file.compose {
+		previous, existed, err = file.setComposeVariable(service, key, value)
+		if err != nil {
+			return &framework.ToolResult{Success: false, Error: err.Error(), Data: map[string]interface{}{"path": file.rel, "applied": false}}, nil
+		}
+	} else {
+		previous, existed = file.setDotenvVariable(key, value)
+	}
+	target := file.rel
+	if service != "" {
+		target += "#" + service
+	}
+	metadata := map[string]string{"path": file.rel, "key": key, "value": maskEnvValue(key, value)}
+	if existed {
+		metadata["previous"] = maskEnvValue(key, previous)
+	}
+	if service != "" {
+		metadata["service"] = service
+	}
+	if err := t.manager.RequireApproval(ctx, t.agentID, framework.PermissionDescriptor{
+		Type:         framework.PermissionTypeHITL,
+		Action:       "env:set",
+		Resource:     target + ":" + key,
+		Metadata:     metadata,
+		RequiresHITL: true,
+	}, fmt.Sprintf("set %s in %s", key, target), framework.GrantScopeOneTime, framework.RiskLevelMedium, 0); err != nil {
+		return nil, err
+	}
+	if err := file.save(); err != nil {
+		return nil, err
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"path":    file.rel,
+		"key":     key,
+		"value":   maskEnvValue(key, value),
+		"created": !existed,
+		"applied": true,
+	}}, nil
+}
+
+func (t *EnvSetTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return true
+}
+
+func (t *EnvSetTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemWrite)}
+}
+
+func envArg(args map[string]interface{}, name, fallback string) string {
+	if v, ok := args[name]; ok && v != nil {
+		if s := strings.TrimSpace(fmt.Sprint(v)); s != "" {
+			return s
+		}
+	}
+	return fallback
+}
+
+// maskEnvValue hides value when its key names a secret, when it matches a
+// redaction pattern, or when it is a URL carrying a password.
+func maskEnvValue(key, value string) string {
+	if value == "" {
+		return ""
+	}
+	if envSecretKey.MatchString(key) {
+		return envMask
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			value = strings.Replace(value, ":"+password+"@", ":"+envMask+"@", 1)
+		}
+	}
+	return envValueRedactor.Redact(value)
+}
+
+// maskEnvVariables masks vars, keeping only key when it is set.
+func maskEnvVariables(vars map[string]string, key string) map[string]string {
+	out := make(map[string]string, len(vars))
+	for k, v := range vars {
+		if key == "" || k == key {
+			out[k] = maskEnvValue(k, v)
+		}
+	}
+	return out
+}
+
+// envFile is a .env or compose file loaded for reading or editing. Edits
+// keep comments, ordering, and unrelated lines.
+type envFile struct {
+	path    string
+	rel     string
+	compose bool
+	lines   []string
+	doc     yaml.Node
+}
+
+// openEnvFile resolves path inside base and checks it names a .env or
+// compose file. The env tools bypass file permissions, so they must not
+// reach anything else.
+func openEnvFile(base, path string) (*envFile, error) {
+	full := preparePath(base, path)
+	if base != "" {
+		rel, err := filepath.Rel(base, full)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside the workspace", path)
+		}
+	}
+	name := filepath.Base(full)
+	file := &envFile{path: full, rel: path}
+	switch {
+	case name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env"):
+	case (strings.HasPrefix(name, "docker-compose") || strings.HasPrefix(name, "compose")) &&
+		(strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")):
+		file.compose = true
+	default:
+		return nil, fmt.Errorf("%s is not a .env or docker-compose file", path)
+	}
+	return file, nil
+}
+
+// load reads the file. A missing .env file loads empty so env_set can
+// create it; a missing compose file is an error.
+func (f *envFile) load() error {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) && !f.compose {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if f.compose {
+		if err := yaml.Unmarshal(data, &f.doc); err != nil {
+			return fmt.Errorf("parse %s: %w", f.rel, err)
+		}
+		return nil
+	}
+	f.lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		f.lines = nil
+	}
+	return nil
+}
+
+func (f *envFile) save() error {
+	var data []byte
+	if f.compose {
+		var b strings.Builder
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(&f.doc); err != nil {
+			return err
+		}
+		data = []byte(b.String())
+	} else {
+		data = []byte(strings.Join(f.lines, "\n") + "\n")
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, data, 0o644)
+}
+
+func (f *envFile) dotenvVariables() map[string]string {
+	vars := make(map[string]string)
+	for _, line := range f.lines {
+		if m := envLinePattern.FindStringSubmatch(line); m != nil {
+			vars[m[2]] = unquoteEnvValue(m[3])
+		}
+	}
+	return vars
+}
+
+// setDotenvVariable rewrites the last assignment of key, where the value
+// in effect lives, or appends one.
+func (f *envFile) setDotenvVariable(key, value string) (string, bool) {
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		m := envLinePattern.FindStringSubmatch(f.lines[i])
+		if m != nil && m[2] == key {
+			f.lines[i] = m[1] + key + "=" + quoteEnvValue(value)
+			return unquoteEnvValue(m[3]), true
+		}
+	}
+	f.lines = append(f.lines, key+"="+quoteEnvValue(value))
+	return "", false
+}
+
+// unquoteEnvValue strips quotes, or a trailing comment from unquoted values.
+func unquoteEnvValue(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') {
+		if end := strings.LastIndexByte(raw, raw[0]); end > 0 {
+			inner := raw[1:end]
+			if raw[0] == '"' {
+				inner = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(inner)
+			}
+			return inner
+		}
+	}
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw
+}
+
+func quoteEnvValue(value string) string {
+	if value == "" || !strings.ContainsAny(value, " \t#\"'\\$`") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// composeServices returns the services mapping of the compose document.
+func (f *envFile) composeServices() (*yaml.Node, error) {
+	if len(f.doc.Content) == 0 {
+		return nil, fmt.Errorf("%s is empty", f.rel)
+	}
+	services := yamlMapValue(f.doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s has no services", f.rel)
+	}
+	return services, nil
+}
+
+func (f *envFile) composeService(name string) (*yaml.Node, error) {
+	services, err := f.composeServices()
+	if err != nil {
+		return nil, err
+	}
+	service := yamlMapValue(services, name)
+	if service == nil || service.Kind != yaml.MappingNode {
+		var names []string
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			names = append(names, services.Content[i].Value)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("service %q not found in %s (services: %s)", name, f.rel, strings.Join(names, ", "))
+	}
+	return service, nil
+}
+
+// composeVariables maps service names to their environment, for one
+// service or all of them.
+func (f *envFile) composeVariables(service string) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string)
+	if service != "" {
+		node, err := f.composeService(service)
+		if err != nil {
+			return nil, err
+		}
+		out[service] = composeEnvironment(node)
+		return out, nil
+	}
+	services, err := f.composeServices()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		out[services.Content[i].Value] = composeEnvironment(services.Content[i+1])
+	}
+	return out, nil
+}
+
+// composeEnvironment reads a service's environment in either the mapping
+// or the KEY=value list form. Variables passed through from the host read
+// as empty.
+func composeEnvironment(service *yaml.Node) map[string]string {
+	vars := make(map[string]string)
+	env := yamlMapValue(service, "environment")
+	if env == nil {
+		return vars
+	}
+	switch env.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			vars[env.Content[i].Value] = composeScalar(env.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for _, item := range env.Content {
+			key, value, _ := strings.Cut(item.Value, "=")
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+func composeScalar(node *yaml.Node) string {
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+// setComposeVariable sets key in the service's environment, keeping the
+// form the file already uses and adding a mapping when there is none.
+func (f *envFile) setComposeVariable(service, key, value string) (string, bool, error) {
+	node, err := f.composeService(service)
+	if err != nil {
+		return "", false, err
+	}
+	env := yamlMapValue(node, "environment")
+	if env == nil {
+		env = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "environment"}, env)
+	}
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	switch env.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			if env.Content[i].Value == key {
+				previous := composeScalar(env.Content[i+1])
+				scalar.HeadComment, scalar.LineComment = env.Content[i+1].HeadComment, env.Content[i+1].LineComment
+				env.Content[i+1] = scalar
+				return previous, true, nil
+			}
+		}
+		env.Content = append(env.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, scalar)
+	case yaml.SequenceNode:
+		scalar.Value = key + "=" + value
+		for i, item := range env.Content {
+			if k, previous, _ := strings.Cut(item.Value, "="); k == key {
+				scalar.HeadComment, scalar.LineComment = item.HeadComment, item.LineComment
+				env.Content[i] = scalar
+				return previous, true, nil
+			}
+		}
+		env.Content = append(env.Content, scalar)
+	default:
+		return "", false, fmt.Errorf("environment of service %q in %s is neither a mapping nor a list", service, f.rel)
+	}
+	return "", false, nil
+}
+
+// yamlMapValue returns the value node for key in a mapping node.
+func yamlMapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const envSample = `# database
+DATABASE_URL=postgres://app:hunter2hunter2@db:5432/app
+export API_TOKEN="abc def 123456"
+PORT=8080 # http
+`
+
+const composeSample = `services:
+  web:
+    image: app
+    environment:
+      - PORT=8080
+      - STRIPE_SECRET=sk_live_abcdefgh
+  worker:
+    image: app
+    environment:
+      QUEUE: jobs # main queue
+`
+
+// approvingHITL grants every request and records what was asked.
+type approvingHITL struct {
+	requests []framework.PermissionRequest
+	deny     bool
+}
+
+func (h *approvingHITL) RequestPermission(ctx context.Context, req framework.PermissionRequest) (*framework.PermissionGrant, error) {
+	h.requests = append(h.requests, req)
+	if h.deny {
+		return nil, assert.AnError
+	}
+	return &framework.PermissionGrant{Permission: req.Permission, Scope: req.Scope}, nil
+}
+
+func envSetTool(t *testing.T, dir string, hitl framework.HITLProvider) *EnvSetTool {
+	t.Helper()
+	manager, err := framework.NewPermissionManager(dir, framework.NewFileSystemPermissionSet(dir, framework.FileSystemRead, framework.FileSystemWrite), nil, hitl)
+	require.NoError(t, err)
+	tool := &EnvSetTool{BasePath: dir}
+	tool.SetPermissionManager(manager, "agent")
+	return tool
+}
+
+func TestEnvGetToolMasksSecrets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte(envSample), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(composeSample), 0o644))
+	tool := &EnvGetTool{BasePath: dir}
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	assert.Equal(t, map[string]string{
+		"DATABASE_URL": "postgres://app:[REDACTED:env]@db:5432/app",
+		"API_TOKEN":    "[REDACTED:env]",
+		"PORT":         "8080",
+	}, res.Data["variables"])
+
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "docker-compose.yml"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"web":    map[string]string{"PORT": "8080", "STRIPE_SECRET": "[REDACTED:env]"},
+		"worker": map[string]string{"QUEUE": "jobs"},
+	}, res.Data["services"])
+
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go"})
+	assert.ErrorContains(t, err, "not a .env or docker-compose file")
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "../.env"})
+	assert.ErrorContains(t, err, "outside the workspace")
+}
+
+func TestEnvSetToolRequiresApproval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(path, []byte(envSample), 0o644))
+
+	denied := &approvingHITL{deny: true}
+	_, err := envSetTool(t, dir, denied).Execute(context.Background(), framework.NewContext(), map[string]interface{}{"key": "API_TOKEN", "value": "new-token-value"})
+	require.Error(t, err)
+	assert.Equal(t, envSample, readFile(t, path), "denied changes write nothing")
+
+	hitl := &approvingHITL{}
+	tool := envSetTool(t, dir, hitl)
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"key": "API_TOKEN", "value": "new-token-value"})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, "[REDACTED:env]", res.Data["value"])
+	require.Len(t, hitl.requests, 1)
+	assert.Equal(t, "env:set", hitl.requests[0].Permission.Action)
+	assert.Equal(t, "[REDACTED:env]", hitl.requests[0].Permission.Metadata["value"], "approvals never show secrets")
+	assert.Equal(t, "[REDACTED:env]", hitl.requests[0].Permission.Metadata["previous"])
+
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"key": "LOG_LEVEL", "value": "debug"})
+	require.NoError(t, err)
+	assert.Equal(t, "# database\nDATABASE_URL=postgres://app:hunter2hunter2@db:5432/app\nexport API_TOKEN=new-token-value\nPORT=8080 # http\nLOG_LEVEL=debug\n", readFile(t, path))
+
+	_, err = (&EnvSetTool{BasePath: dir}).Execute(context.Background(), framework.NewContext(), map[string]interface{}{"key": "A", "value": "b"})
+	assert.ErrorContains(t, err, "permission manager missing")
+}
+
+func TestEnvSetToolEditsComposeEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, os.WriteFile(path, []byte(composeSample), 0o644))
+	tool := envSetTool(t, dir, &approvingHITL{})
+	set := func(args map[string]interface{}) (*framework.ToolResult, error) {
+		args["path"] = "docker-compose.yml"
+		return tool.Execute(context.Background(), framework.NewContext(), args)
+	}
+
+	_, err := set(map[string]interface{}{"key": "PORT", "value": "9090"})
+	assert.ErrorContains(t, err, "service required")
+	res, err := set(map[string]interface{}{"service": "db", "key": "PORT", "value": "9090"})
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.Contains(t, res.Error, "services: web, worker")
+
+	for _, args := range []map[string]interface{}{
+		{"service": "web", "key": "PORT", "value": "9090"},
+		{"service": "worker", "key": "QUEUE", "value": "urgent"},
+		{"service": "worker", "key": "WORKERS", "value": "4"},
+	} {
+		res, err := set(args)
+		require.NoError(t, err)
+		require.True(t, res.Success, res.Error)
+	}
+	assert.Equal(t, `services:
+  web:
+    image: app
+    environment:
+      - PORT=9090
+      - STRIPE_SECRET=sk_live_abcdefgh
+  worker:
+    image: app
+    environment:
+      QUEUE: urgent # main queue
+      WORKERS: "4"
+`, readFile(t, path))
+}
@@ -7,17 +7,34 @@ import (
 	"time"
 
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools/testrunner"
 )
 
-// RunTestsTool executes test commands.
+// RunTestsTool executes test commands. With Toolchains set (see
+// testrunner.Detect) it picks the workspace's test runner, can narrow the run
+// to a package, file, or test name, and returns parsed counts and failures.
+// An explicit Command takes precedence and returns raw output.
+// TestRunStateKey is where exec_run_tests leaves the TestRun of its latest
+// toolchain run in the task state, so the run can be reported after the agent
+// finishes.
+const TestRunStateKey = "exec_run_tests.last_run"
+
+// TestRun is the parsed outcome of one toolchain test run.
+type TestRun struct {
+	Language string `json:"language"`
+	Command  string `json:"command"`
+	testrunner.Report
+}
+
 type RunTestsTool struct {
-	Command []string
-	Workdir string
-	Timeout time.Duration
-	Runner  framework.CommandRunner
-	manager *framework.PermissionManager
-	agentID string
-	spec    *framework.AgentRuntimeSpec
+	Command    []string
+	Toolchains []testrunner.Toolchain
+	Workdir    string
+	Timeout    time.Duration
+	Runner     framework.CommandRunner
+	manager    *framework.PermissionManager
+	agentID    string
+	spec       *framework.AgentRuntimeSpec
 }
 
 func (t *RunTestsTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
@@ -34,12 +51,23 @@ func (t *RunTestsTool) Name() string        { return "exec_run_tests" }
 func (t *RunTestsTool) Description() string { return "Runs project tests." }
 func (t *RunTestsTool) Category() string    { return "execution" }
 func (t *RunTestsTool) Parameters() []framework.ToolParameter {
+	if len(t.Command) > 0 {
+		return []framework.ToolParameter{
+			{Name: "pattern", Type: "string", Required: false},
+		}
+	}
 	return []framework.ToolParameter{
-		{Name: "pattern", Type: "string", Required: false},
+		{Name: "target", Type: "string", Description: "Package, directory, or file to test (default: everything)", Required: false},
+		{Name: "name", Type: "string", Description: "Only run tests whose name matches", Required: false},
+		{Name: "language", Type: "string", Description: "Toolchain to use when the workspace has several: " + strings.Join(testrunner.Languages(t.Toolchains), ", "), Required: false},
+		{Name: "coverage", Type: "bool", Description: "Report coverage", Required: false, Default: false},
 	}
 }
 func (t *RunTestsTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
-	pattern := fmt.Sprint(args["pattern"])
+	if len(t.Command) == 0 {
+		return t.runToolchain(ctx, state, args)
+	}
+	pattern := stringArg(args, "pattern")
 	cmdline := append([]string{}, t.Command...)
 	if pattern != "" {
 		cmdline = append(cmdline, pattern)
@@ -66,15 +94,104 @@ func (t *RunTestsTool) Execute(ctx context.Context, state *framework.Context, ar
 		},
 	}, nil
 }
+
+// runToolchain runs the selected toolchain and reports parsed results. Raw
+// output is only included when the parser recognized none of it.
+func (t *RunTestsTool) runToolchain(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	tc, err := t.toolchain(stringArg(args, "language"))
+	if err != nil {
+		return nil, err
+	}
+	coverage, _ := args["coverage"].(bool)
+	cmdline := tc.Command(testrunner.Request{
+		Target:   stringArg(args, "target"),
+		Name:     stringArg(args, "name"),
+		Coverage: coverage,
+	})
+	if err := t.authorizeCommand(ctx, cmdline); err != nil {
+		return nil, err
+	}
+	stdout, stderr, runErr := t.run(ctx, cmdline, "")
+	report := tc.Parse(stdout + "\n" + stderr)
+	if state != nil {
+		state.Set(TestRunStateKey, TestRun{Language: tc.Language, Command: strings.Join(cmdline, " "), Report: report})
+	}
+	data := map[string]interface{}{
+		"language": tc.Language,
+		"command":  strings.Join(cmdline, " "),
+		"passed":   report.Passed,
+		"failed":   report.Failed,
+		"skipped":  report.Skipped,
+		"failures": report.Failures,
+	}
+	if len(report.Coverage) > 0 {
+		data["coverage"] = report.Coverage
+	}
+	if !report.Parsed {
+		data["stdout"] = stdout
+		data["stderr"] = stderr
+	}
+	result := &framework.ToolResult{Success: runErr == nil && len(report.Failures) == 0, Data: data}
+	switch {
+	case report.Failed > 0:
+		result.Error = fmt.Sprintf("%d of %d tests failed", report.Failed, report.Passed+report.Failed)
+	case len(report.Failures) > 0:
+		// Build errors and crashes outside any single test.
+		result.Error = "test run failed: " + report.Failures[0].Name
+		if pkg := report.Failures[0].Package; pkg != "" {
+			result.Error = "package " + pkg + " failed"
+		}
+	case runErr != nil:
+		result.Error = runErr.Error()
+	}
+	return result, nil
+}
+
+func (t *RunTestsTool) toolchain(language string) (testrunner.Toolchain, error) {
+	return pickToolchain(t.Toolchains, language)
+}
+
+// pickToolchain returns the toolchain for language, or the first detected
+// one when language is empty.
+func pickToolchain(toolchains []testrunner.Toolchain, language string) (testrunner.Toolchain, error) {
+	if language == "" {
+		if len(toolchains) == 0 {
+			return testrunner.Toolchain{}, fmt.Errorf("no test toolchain detected")
+		}
+		return toolchains[0], nil
+	}
+	for _, tc := range toolchains {
+		if strings.EqualFold(tc.Language, language) {
+			return tc, nil
+		}
+	}
+	return testrunner.Toolchain{}, fmt.Errorf("no %s test toolchain in this workspace (have %s)", language, strings.Join(testrunner.Languages(toolchains), ", "))
+}
+
 func (t *RunTestsTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
-	return len(t.Command) > 0
+	return len(t.Command) > 0 || len(t.Toolchains) > 0
 }
 
 func (t *RunTestsTool) Permissions() framework.ToolPermissions {
-	if len(t.Command) == 0 {
+	if len(t.Command) > 0 {
+		return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.Workdir, t.Command[0], t.Command[1:])}
+	}
+	if len(t.Toolchains) == 0 {
 		return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.Workdir, framework.FileSystemRead, framework.FileSystemList)}
 	}
-	return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.Workdir, t.Command[0], t.Command[1:])}
+	var perms *framework.PermissionSet
+	for _, tc := range t.Toolchains {
+		cmdline := tc.Command(testrunner.Request{})
+		// Targets and filters vary per call, so only the subcommand is
+		// pinned.
+		set := framework.NewExecutionPermissionSet(t.Workdir, cmdline[0], []string{cmdline[1], "*"})
+		if perms == nil {
+			perms = set
+			continue
+		}
+		perms.Executables = append(perms.Executables, set.Executables...)
+	}
+	return framework.ToolPermissions{Permissions: perms}
 }
 
 func (t *RunTestsTool) run(ctx context.Context, args []string, input string) (string, string, error) {
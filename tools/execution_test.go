@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/tools/testrunner"
+)
+
+func TestRunTestsToolUsesToolchain(t *testing.T) {
+	runner := &stubRunner{stdout: `{"Action":"pass","Package":"example.com/a","Test":"TestA"}
+{"Action":"output","Package":"example.com/a","Test":"TestB","Output":"boom\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestB"}
+`}
+	tool := &RunTestsTool{Toolchains: []testrunner.Toolchain{testrunner.Go}, Workdir: t.TempDir(), Runner: runner}
+	require.NoError(t, tool.Permissions().Validate())
+	ctx := context.Background()
+
+	state := framework.NewContext()
+	res, err := tool.Execute(ctx, state, map[string]interface{}{"target": "pkg/a", "name": "TestB"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "test", "-json", "-run", "TestB", "./pkg/a"}, runner.calls[0])
+	assert.False(t, res.Success)
+	assert.Equal(t, "1 of 2 tests failed", res.Error)
+	assert.Equal(t, 1, res.Data["passed"])
+	failures := res.Data["failures"].([]testrunner.Failure)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "boom", failures[0].Output)
+	assert.NotContains(t, res.Data, "stdout", "parsed runs omit raw output")
+	run, ok := state.Get(TestRunStateKey)
+	require.True(t, ok)
+	assert.Equal(t, "go test -json -run TestB ./pkg/a", run.(TestRun).Command)
+	assert.Equal(t, 1, run.(TestRun).Failed)
+
+	_, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"language": "rust"})
+	assert.ErrorContains(t, err, "no rust test toolchain")
+}
+
+func TestRunTestsToolCommandOverride(t *testing.T) {
+	runner := &stubRunner{stdout: "ok"}
+	tool := &RunTestsTool{Command: []string{"make", "test"}, Workdir: t.TempDir(), Runner: runner}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, "ok", res.Data["stdout"])
+	assert.Equal(t, []string{"make", "test"}, runner.calls[0], "a missing pattern adds no argument")
+}
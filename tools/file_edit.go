@@ -0,0 +1,372 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// DefaultMaxEditShrink is the share of a file an edit may remove when
+// EditFileTool.MaxShrink is unset.
+const DefaultMaxEditShrink = 0.5
+
+// editShrinkMinBytes exempts small files from the shrink check, where
+// removing most of the content is an ordinary edit.
+const editShrinkMinBytes = 1024
+
+// EditFileTool changes part of a file without resending the rest, so large
+// files are not truncated when the model cuts its output short. Each call
+// makes one edit, located by line range, by an anchor line, or by a pair of
+// marker lines. Anchors must match exactly one line, and edits that remove
+// more than MaxShrink of a file are refused.
+type EditFileTool struct {
+	BasePath string
+	Backup   bool
+	// MaxShrink is the largest share of the file, between 0 and 1, an edit
+	// may remove. Zero means DefaultMaxEditShrink; 1 disables the check.
+	MaxShrink float64
+	manager   *framework.PermissionManager
+	agentID   string
+	spec      *framework.AgentRuntimeSpec
+}
+
+func (t *EditFileTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *EditFileTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *EditFileTool) Name() string { return "file_edit" }
+func (t *EditFileTool) Description() string {
+	return "Edits part of a file: replaces lines start_line..end_line, inserts after the line containing insert_after, or replaces the lines between the lines containing start_marker and end_marker. Prefer it to file_write for large files."
+}
+func (t *EditFileTool) Category() string { return "file" }
+func (t *EditFileTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "path", Type: "string", Required: true},
+		{Name: "content", Type: "string", Description: "Replacement or inserted lines", Required: true},
+		{Name: "start_line", Type: "int", Description: "First line to replace (1-based)", Required: false},
+		{Name: "end_line", Type: "int", Description: "Last line to replace, inclusive (default start_line)", Required: false},
+		{Name: "insert_after", Type: "string", Description: "Text of the line to insert after; must match one line", Required: false},
+		{Name: "start_marker", Type: "string", Description: "Text of the line before the replaced block; must match one line", Required: false},
+		{Name: "end_marker", Type: "string", Description: "Text of the first line after start_marker that ends the replaced block", Required: false},
+	}
+}
+
+func (t *EditFileTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	edit, err := parseFileEdit(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNoRedactionMask(edit.content); err != nil {
+		return nil, err
+	}
+	path := t.preparePath(fmt.Sprint(args["path"]))
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
+			return nil, err
+		}
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, path); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.enforceFileMatrix(ctx, "edit", path); err != nil {
+		return nil, err
+	}
+
+	unlock := fileLocks.Lock(path)
+	defer unlock()
+	var conflict *FileConflictError
+	if err := checkFileFresh(state, path); errors.As(err, &conflict) {
+		return conflictResult(conflict), nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isText(data) {
+		return nil, errBinaryFile
+	}
+	original := string(data)
+	updated, applied, err := edit.apply(original)
+	var rejected *editRejectedError
+	if errors.As(err, &rejected) {
+		return &framework.ToolResult{Success: false, Error: rejected.Error(), Data: map[string]interface{}{"path": path, "applied": false}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := t.checkShrink(original, updated); err != nil {
+		return &framework.ToolResult{Success: false, Error: err.Error(), Data: map[string]interface{}{"path": path, "applied": false}}, nil
+	}
+
+	if t.Backup {
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, path+".bak"); err != nil {
+				return nil, fmt.Errorf("backup blocked: %w", err)
+			}
+		}
+		if err := copyFile(path, path+".bak"); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.WriteFile(path, []byte(updated), 0o644); err != nil {
+		return nil, err
+	}
+	recordFileHash(state, path, []byte(updated))
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"path":          path,
+		"applied":       true,
+		"start_line":    applied.start,
+		"lines_removed": applied.removed,
+		"lines_added":   applied.added,
+		"total_lines":   len(splitEditLines(updated)),
+	}}, nil
+}
+
+// checkShrink refuses edits removing more than MaxShrink of a file, the
+// signature of a model that dropped content it meant to keep.
+func (t *EditFileTool) checkShrink(original, updated string) error {
+	limit := t.MaxShrink
+	if limit <= 0 {
+		limit = DefaultMaxEditShrink
+	}
+	if limit >= 1 || len(original) < editShrinkMinBytes || len(updated) >= len(original) {
+		return nil
+	}
+	removed := float64(len(original)-len(updated)) / float64(len(original))
+	if removed <= limit {
+		return nil
+	}
+	return fmt.Errorf("edit would remove %.0f%% of the file (limit %.0f%%); nothing was written. Edit a smaller range, or use file_write if the rewrite is intended", removed*100, limit*100)
+}
+
+func (t *EditFileTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return true
+}
+
+func (t *EditFileTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemWrite)}
+}
+
+func (t *EditFileTool) preparePath(path string) string { return preparePath(t.BasePath, path) }
+
+func (t *EditFileTool) enforceFileMatrix(ctx context.Context, action string, absPath string) error {
+	if t == nil || t.spec == nil {
+		return nil
+	}
+	return enforceFileMatrix(ctx, t.manager, t.agentID, t.BasePath, action, absPath, t.spec.Files)
+}
+
+// fileEdit is one parsed file_edit call. Exactly one of the line range,
+// insertAfter, or the marker pair is set.
+type fileEdit struct {
+	content     string
+	startLine   int
+	endLine     int
+	insertAfter string
+	startMarker string
+	endMarker   string
+}
+
+// appliedEdit describes where an edit landed, with 1-based line numbers.
+type appliedEdit struct {
+	start   int
+	removed int
+	added   int
+}
+
+// editRejectedError reports an edit that does not fit the file, such as a
+// missing anchor. It goes back to the agent as a failed result.
+type editRejectedError struct {
+	msg string
+}
+
+func (e *editRejectedError) Error() string { return e.msg + "; nothing was written" }
+
+func rejectEdit(format string, args ...interface{}) error {
+	return &editRejectedError{msg: fmt.Sprintf(format, args...)}
+}
+
+func parseFileEdit(args map[string]interface{}) (*fileEdit, error) {
+	if args["path"] == nil {
+		return nil, errors.New("path required")
+	}
+	if args["content"] == nil {
+		return nil, errors.New("content required")
+	}
+	edit := &fileEdit{
+		content:     fmt.Sprint(args["content"]),
+		insertAfter: editStringArg(args, "insert_after"),
+		startMarker: editStringArg(args, "start_marker"),
+		endMarker:   editStringArg(args, "end_marker"),
+	}
+	var err error
+	if edit.startLine, err = editIntArg(args, "start_line"); err != nil {
+		return nil, err
+	}
+	if edit.endLine, err = editIntArg(args, "end_line"); err != nil {
+		return nil, err
+	}
+	forms := 0
+	if edit.startLine != 0 || edit.endLine != 0 {
+		forms++
+		if edit.startLine < 1 {
+			return nil, errors.New("start_line must be at least 1")
+		}
+		if edit.endLine == 0 {
+			edit.endLine = edit.startLine
+		}
+		if edit.endLine < edit.startLine {
+			return nil, fmt.Errorf("end_line %d is before start_line %d", edit.endLine, edit.startLine)
+		}
+	}
+	if edit.insertAfter != "" {
+		forms++
+	}
+	if edit.startMarker != "" || edit.endMarker != "" {
+		forms++
+		if edit.startMarker == "" || edit.endMarker == "" {
+			return nil, errors.New("start_marker and end_marker must be given together")
+		}
+	}
+	if forms != 1 {
+		return nil, errors.New("give exactly one of start_line/end_line, insert_after, or start_marker/end_marker")
+	}
+	return edit, nil
+}
+
+func editStringArg(args map[string]interface{}, name string) string {
+	if args[name] == nil {
+		return ""
+	}
+	return fmt.Sprint(args[name])
+}
+
+// editIntArg accepts the numbers and numeric strings models send.
+func editIntArg(args map[string]interface{}, name string) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%s must be a whole number", name)
+		}
+		return int(v), nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", name)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+}
+
+// apply returns content with the edit made.
+func (e *fileEdit) apply(content string) (string, appliedEdit, error) {
+	lines := splitEditLines(content)
+	var from, to int // replace lines[from:to]
+	switch {
+	case e.insertAfter != "":
+		at, err := findEditAnchor(lines, e.insertAfter, "insert_after")
+		if err != nil {
+			return "", appliedEdit{}, err
+		}
+		from, to = at+1, at+1
+	case e.startMarker != "":
+		start, err := findEditAnchor(lines, e.startMarker, "start_marker")
+		if err != nil {
+			return "", appliedEdit{}, err
+		}
+		end := -1
+		for i := start + 1; i < len(lines); i++ {
+			if strings.Contains(lines[i], e.endMarker) {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return "", appliedEdit{}, rejectEdit("end_marker %q not found after line %d", e.endMarker, start+1)
+		}
+		from, to = start+1, end
+	default:
+		if e.startLine > len(lines) {
+			return "", appliedEdit{}, rejectEdit("start_line %d is past the end of the file (%d lines)", e.startLine, len(lines))
+		}
+		if e.endLine > len(lines) {
+			return "", appliedEdit{}, rejectEdit("end_line %d is past the end of the file (%d lines)", e.endLine, len(lines))
+		}
+		from, to = e.startLine-1, e.endLine
+	}
+
+	block := splitEditLines(e.content)
+	if len(block) > 0 && !strings.HasSuffix(block[len(block)-1], "\n") {
+		block[len(block)-1] += "\n"
+	}
+	if from > 0 && !strings.HasSuffix(lines[from-1], "\n") {
+		lines[from-1] += "\n"
+	}
+	updated := make([]string, 0, len(lines)-(to-from)+len(block))
+	updated = append(updated, lines[:from]...)
+	updated = append(updated, block...)
+	updated = append(updated, lines[to:]...)
+	result := strings.Join(updated, "")
+	// Keep a file that ended without a newline that way.
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		result = strings.TrimSuffix(result, "\n")
+	}
+	return result, appliedEdit{start: from + 1, removed: to - from, added: len(block)}, nil
+}
+
+// findEditAnchor returns the index of the one line containing text.
+func findEditAnchor(lines []string, text, param string) (int, error) {
+	var matches []string
+	at := -1
+	for i := range lines {
+		if strings.Contains(lines[i], text) {
+			if at < 0 {
+				at = i
+			}
+			matches = append(matches, strconv.Itoa(i+1))
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, rejectEdit("%s %q not found", param, text)
+	case 1:
+		return at, nil
+	default:
+		return 0, rejectEdit("%s %q matches lines %s; use text unique to one line", param, text, strings.Join(matches, ", "))
+	}
+}
+
+// splitEditLines splits content into lines that keep their newlines.
+func splitEditLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const editSample = "package main\n\nfunc a() {}\n\n// BEGIN handlers\nfunc old() {}\n// END handlers\n"
+
+func runFileEdit(t *testing.T, tool *EditFileTool, content string, args map[string]interface{}) (*framework.ToolResult, string) {
+	t.Helper()
+	path := filepath.Join(tool.BasePath, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	args["path"] = "main.go"
+	res, err := tool.Execute(context.Background(), framework.NewContext(), args)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return res, string(data)
+}
+
+func TestEditFileToolEditForms(t *testing.T) {
+	tool := &EditFileTool{BasePath: t.TempDir()}
+	cases := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{
+			name: "line range",
+			args: map[string]interface{}{"start_line": 3, "end_line": "3", "content": "func a() { return }"},
+			want: "package main\n\nfunc a() { return }\n\n// BEGIN handlers\nfunc old() {}\n// END handlers\n",
+		},
+		{
+			name: "insert after",
+			args: map[string]interface{}{"insert_after": "func a()", "content": "func b() {}\n"},
+			want: "package main\n\nfunc a() {}\nfunc b() {}\n\n// BEGIN handlers\nfunc old() {}\n// END handlers\n",
+		},
+		{
+			name: "between markers",
+			args: map[string]interface{}{"start_marker": "BEGIN handlers", "end_marker": "END handlers", "content": "func x() {}\nfunc y() {}"},
+			want: "package main\n\nfunc a() {}\n\n// BEGIN handlers\nfunc x() {}\nfunc y() {}\n// END handlers\n",
+		},
+	}
+	for _, tc := range cases {
+		res, got := runFileEdit(t, tool, editSample, tc.args)
+		require.True(t, res.Success, "%s: %s", tc.name, res.Error)
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+
+	res, got := runFileEdit(t, tool, "a\nb", map[string]interface{}{"insert_after": "b", "content": "c"})
+	require.True(t, res.Success)
+	assert.Equal(t, "a\nb\nc", got, "a missing final newline stays missing")
+}
+
+func TestEditFileToolRejectsBadAnchors(t *testing.T) {
+	tool := &EditFileTool{BasePath: t.TempDir()}
+	for _, tc := range []struct {
+		args   map[string]interface{}
+		reason string
+	}{
+		{map[string]interface{}{"insert_after": "func missing", "content": "x"}, `insert_after "func missing" not found`},
+		{map[string]interface{}{"insert_after": "func", "content": "x"}, "matches lines 3, 6"},
+		{map[string]interface{}{"start_marker": "END handlers", "end_marker": "BEGIN handlers", "content": ""}, "not found after line 7"},
+		{map[string]interface{}{"start_line": 9, "content": "x"}, "past the end of the file (7 lines)"},
+	} {
+		res, got := runFileEdit(t, tool, editSample, tc.args)
+		assert.False(t, res.Success)
+		assert.Contains(t, res.Error, tc.reason)
+		assert.Equal(t, editSample, got, "rejected edits write nothing")
+	}
+
+	_, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"path": "main.go", "content": "x", "start_line": 1, "insert_after": "a",
+	})
+	assert.ErrorContains(t, err, "exactly one of")
+}
+
+func TestEditFileToolRefusesLargeShrinks(t *testing.T) {
+	body := strings.Repeat("line of code that matters\n", 100)
+	tool := &EditFileTool{BasePath: t.TempDir()}
+	res, got := runFileEdit(t, tool, body, map[string]interface{}{"start_line": 1, "end_line": 80, "content": "// trimmed"})
+	assert.False(t, res.Success)
+	assert.Contains(t, res.Error, "would remove 80% of the file (limit 50%)")
+	assert.Equal(t, body, got)
+
+	res, _ = runFileEdit(t, tool, body, map[string]interface{}{"start_line": 1, "end_line": 40, "content": ""})
+	assert.True(t, res.Success, res.Error)
+
+	tool.MaxShrink = 1
+	res, _ = runFileEdit(t, tool, body, map[string]interface{}{"start_line": 1, "end_line": 100, "content": ""})
+	assert.True(t, res.Success, "a limit of 1 disables the check")
+}
+
+func TestEditFileToolPreview(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(editSample), 0o644))
+	diff, err := (&EditFileTool{BasePath: dir}).PreviewChange(context.Background(), map[string]interface{}{
+		"path": "main.go", "start_line": 6, "content": "func renamed() {}",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-func old() {}\n+func renamed() {}")
+}
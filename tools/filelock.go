@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// PathLocks serializes writers per file. Locks are created on demand and
+// dropped once no caller holds or waits for them.
+type PathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewPathLocks returns an empty lock manager.
+func NewPathLocks() *PathLocks {
+	return &PathLocks{locks: make(map[string]*pathLock)}
+}
+
+// fileLocks is shared by every file tool in the process, so concurrent jobs
+// writing the same file take turns.
+var fileLocks = NewPathLocks()
+
+// Lock blocks until it holds every path and returns the function releasing
+// them. Paths are locked in sorted order, so callers locking overlapping
+// sets cannot deadlock.
+func (l *PathLocks) Lock(paths ...string) func() {
+	sorted := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		path = canonicalPath(path)
+		if !seen[path] {
+			seen[path] = true
+			sorted = append(sorted, path)
+		}
+	}
+	sort.Strings(sorted)
+	held := make([]*pathLock, 0, len(sorted))
+	for _, path := range sorted {
+		l.mu.Lock()
+		lock, ok := l.locks[path]
+		if !ok {
+			lock = &pathLock{}
+			l.locks[path] = lock
+		}
+		lock.refs++
+		l.mu.Unlock()
+		lock.mu.Lock()
+		held = append(held, lock)
+	}
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].mu.Unlock()
+			l.mu.Lock()
+			held[i].refs--
+			if held[i].refs == 0 {
+				delete(l.locks, sorted[i])
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// readHashPrefix keys, in a task's context variables, the hash of each file
+// as the task last read or wrote it.
+const readHashPrefix = "file.hash:"
+
+// FileConflictError reports a write to a file that changed on disk since the
+// task last read it, typically because another job wrote it. Nothing was
+// written; the agent should read the file again and redo its change.
+type FileConflictError struct {
+	Path string
+	// Deleted is set when the file no longer exists.
+	Deleted bool
+}
+
+func (e *FileConflictError) Error() string {
+	if e.Deleted {
+		return fmt.Sprintf("%s was deleted since it was last read; nothing was written", e.Path)
+	}
+	return fmt.Sprintf("%s changed on disk since it was last read; read it again and reapply the change", e.Path)
+}
+
+// conflictResult reports err to the agent as a failed tool call rather than
+// an error, so it can re-read the file and carry on.
+func conflictResult(err *FileConflictError) *framework.ToolResult {
+	return &framework.ToolResult{
+		Success: false,
+		Error:   err.Error(),
+		Data: map[string]interface{}{
+			"path":     err.Path,
+			"conflict": true,
+			"deleted":  err.Deleted,
+		},
+	}
+}
+
+// canonicalPath makes the lock and hash keys of one file agree across tools
+// with different base paths.
+func canonicalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordFileHash remembers the content the task last saw for path.
+func recordFileHash(state *framework.Context, path string, data []byte) {
+	if state == nil {
+		return
+	}
+	state.SetVariable(readHashPrefix+canonicalPath(path), contentHash(data))
+}
+
+// forgetFileHash drops the record for a file the task removed.
+func forgetFileHash(state *framework.Context, path string) {
+	if state == nil {
+		return
+	}
+	state.SetVariable(readHashPrefix+canonicalPath(path), "")
+}
+
+// checkFileFresh returns a *FileConflictError when path no longer holds the
+// content the task last read or wrote. Files the task never read pass.
+// Callers hold the path's lock.
+func checkFileFresh(state *framework.Context, path string) error {
+	if state == nil {
+		return nil
+	}
+	value, ok := state.GetVariable(readHashPrefix + canonicalPath(path))
+	want, _ := value.(string)
+	if !ok || want == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FileConflictError{Path: path, Deleted: true}
+	}
+	if err != nil {
+		return err
+	}
+	if contentHash(data) != want {
+		return &FileConflictError{Path: path}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestWriteFileToolDetectsStaleReads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shared.go")
+	require.NoError(t, os.WriteFile(path, []byte("v1\n"), 0o644))
+	ctx := context.Background()
+	read := &ReadFileTool{BasePath: dir}
+	write := &WriteFileTool{BasePath: dir}
+	jobA, jobB := framework.NewContext(), framework.NewContext()
+	args := func(content string) map[string]interface{} {
+		return map[string]interface{}{"path": "shared.go", "content": content}
+	}
+
+	for _, state := range []*framework.Context{jobA, jobB} {
+		_, err := read.Execute(ctx, state, map[string]interface{}{"path": "shared.go"})
+		require.NoError(t, err)
+	}
+	res, err := write.Execute(ctx, jobB, args("from B\n"))
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	res, err = write.Execute(ctx, jobB, args("from B again\n"))
+	require.NoError(t, err)
+	require.True(t, res.Success, "a job's own writes do not make its view stale")
+
+	res, err = write.Execute(ctx, jobA, args("from A\n"))
+	require.NoError(t, err, "conflicts are reported to the agent, not raised")
+	assert.False(t, res.Success)
+	assert.Equal(t, true, res.Data["conflict"])
+	assert.Contains(t, res.Error, "changed on disk since it was last read")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "from B again\n", string(data))
+
+	_, err = read.Execute(ctx, jobA, map[string]interface{}{"path": "shared.go"})
+	require.NoError(t, err)
+	res, err = write.Execute(ctx, jobA, args("from A\n"))
+	require.NoError(t, err)
+	assert.True(t, res.Success, "re-reading clears the conflict")
+
+	fresh := framework.NewContext()
+	res, err = write.Execute(ctx, fresh, args("blind\n"))
+	require.NoError(t, err)
+	assert.True(t, res.Success, "files a job never read are not checked")
+}
+
+func TestDeleteFileToolDetectsStaleReads(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644))
+	ctx := context.Background()
+	state := framework.NewContext()
+	_, err := (&ReadFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "a.txt"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("edited elsewhere"), 0o644))
+
+	res, err := (&DeleteFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "a.txt"})
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.FileExists(t, filepath.Join(dir, "a.txt"))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.txt")))
+	res, err = (&WriteFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "a.txt", "content": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, true, res.Data["deleted"])
+}
+
+func TestApplyPatchToolRecordsWrittenContent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	ctx := context.Background()
+	state := framework.NewContext()
+	_, err := (&ReadFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "main.go"})
+	require.NoError(t, err)
+	patch := "--- a/main.go\n+++ b/main.go\n@@ -1 +1,2 @@\n package main\n+// patched\n"
+	res, err := (&ApplyPatchTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	require.True(t, res.Success, res.Error)
+
+	res, err = (&WriteFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "main.go", "content": "package main\n"})
+	require.NoError(t, err)
+	assert.True(t, res.Success, "the patch updated the job's view of the file")
+}
+
+func TestPathLocksSerializeOverlappingSets(t *testing.T) {
+	locks := NewPathLocks()
+	var mu sync.Mutex
+	active := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		paths := []string{"/ws/a", "/ws/b"}
+		if i%2 == 1 {
+			paths = []string{"/ws/b", "/ws/a", fmt.Sprintf("/ws/%d", i)}
+		}
+		wg.Add(1)
+		go func(paths []string) {
+			defer wg.Done()
+			unlock := locks.Lock(paths...)
+			defer unlock()
+			mu.Lock()
+			for _, path := range paths {
+				active[path]++
+				assert.Equal(t, 1, active[path], path)
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			for _, path := range paths {
+				active[path]--
+			}
+			mu.Unlock()
+		}(paths)
+	}
+	wg.Wait()
+	assert.Empty(t, locks.locks, "released locks are dropped")
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -56,6 +55,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, state *framework.Context, ar
 	if !isText(data) {
 		return nil, errBinaryFile
 	}
+	recordFileHash(state, path, data)
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -81,9 +81,12 @@ func (t *ReadFileTool) Permissions() framework.ToolPermissions {
 type WriteFileTool struct {
 	BasePath string
 	Backup   bool
-	manager  *framework.PermissionManager
-	agentID  string
-	spec     *framework.AgentRuntimeSpec
+	// Code, when set, extracts the code from model-written content before
+	// it is written.
+	Code    *framework.CodePipeline
+	manager *framework.PermissionManager
+	agentID string
+	spec    *framework.AgentRuntimeSpec
 }
 
 func (t *WriteFileTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
@@ -107,6 +110,9 @@ func (t *WriteFileTool) Parameters() []framework.ToolParameter {
 }
 func (t *WriteFileTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
 	path := t.preparePath(fmt.Sprint(args["path"]))
+	if err := checkNoRedactionMask(fmt.Sprint(args["content"])); err != nil {
+		return nil, err
+	}
 
 	if t.manager != nil {
 		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, path); err != nil {
@@ -117,11 +123,19 @@ func (t *WriteFileTool) Execute(ctx context.Context, state *framework.Context, a
 		return nil, err
 	}
 
+	unlock := fileLocks.Lock(path)
+	defer unlock()
+	var conflict *FileConflictError
+	if err := checkFileFresh(state, path); errors.As(err, &conflict) {
+		return conflictResult(conflict), nil
+	} else if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
 
-	content := []byte(fmt.Sprint(args["content"]))
+	content := []byte(t.Code.Process(fmt.Sprint(args["content"]), path))
 	if t.Backup {
 		if _, err := os.Stat(path); err == nil {
 			backup := path + ".bak"
@@ -142,6 +156,7 @@ func (t *WriteFileTool) Execute(ctx context.Context, state *framework.Context, a
 	if err := os.WriteFile(path, content, 0o644); err != nil {
 		return nil, err
 	}
+	recordFileHash(state, path, content)
 	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"path": path}}, nil
 }
 func (t *WriteFileTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
@@ -250,17 +265,20 @@ func (t *SearchInFilesTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agent
 	t.agentID = agentID
 }
 
-func (t *SearchInFilesTool) Name() string        { return "file_search" }
-func (t *SearchInFilesTool) Description() string { return "Searches text inside files." }
-func (t *SearchInFilesTool) Category() string    { return "file" }
+func (t *SearchInFilesTool) Name() string { return "file_search" }
+func (t *SearchInFilesTool) Description() string {
+	return "Searches text inside files, case-sensitively unless asked otherwise."
+}
+func (t *SearchInFilesTool) Category() string { return "file" }
 func (t *SearchInFilesTool) Parameters() []framework.ToolParameter {
-	return []framework.ToolParameter{
-		{Name: "directory", Type: "string", Required: false, Default: "."},
-		{Name: "pattern", Type: "string", Required: true},
-	}
+	return grepParameters()
 }
 func (t *SearchInFilesTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
-	dir := t.preparePath(fmt.Sprint(args["directory"]))
+	dir := "."
+	if args["directory"] != nil {
+		dir = fmt.Sprint(args["directory"])
+	}
+	dir = t.preparePath(dir)
 
 	if t.manager != nil {
 		// Search implies reading files
@@ -269,59 +287,21 @@ func (t *SearchInFilesTool) Execute(ctx context.Context, state *framework.Contex
 		}
 	}
 
-	pattern := fmt.Sprint(args["pattern"])
-	type match struct {
-		File    string `json:"file"`
-		Line    int    `json:"line"`
-		Content string `json:"content"`
+	opts, err := parseGrepOptions(args, true)
+	if err != nil {
+		return nil, err
 	}
-	var matches []match
-	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			if strings.HasPrefix(d.Name(), ".git") {
-				return fs.SkipDir
-			}
-			if t.manager != nil {
-				if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemList, path); err != nil {
-					return fs.SkipDir
-				}
-			}
-			return nil
-		}
-
-		// Verify read access for each file while walking.
-		if t.manager != nil {
-			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
-				return nil // Skip unreadable
-			}
-		}
-
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		line := 1
-		for scanner.Scan() {
-			if strings.Contains(scanner.Text(), pattern) {
-				matches = append(matches, match{
-					File:    path,
-					Line:    line,
-					Content: scanner.Text(),
-				})
-			}
-			line++
+	var check func(framework.FileSystemAction, string) error
+	if t.manager != nil {
+		check = func(action framework.FileSystemAction, path string) error {
+			return t.manager.CheckFileAccess(ctx, t.agentID, action, path)
 		}
-		return scanner.Err()
-	})
-	if err != nil {
+	}
+	search := newGrepSearch(t.BasePath, dir, opts, check)
+	if err := search.walk(ctx); err != nil {
 		return nil, err
 	}
-	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"matches": matches}}, nil
+	return grepResult(search, "builtin"), nil
 }
 func (t *SearchInFilesTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
 	return true
@@ -334,9 +314,12 @@ func (t *SearchInFilesTool) Permissions() framework.ToolPermissions {
 // CreateFileTool creates a file from a template string.
 type CreateFileTool struct {
 	BasePath string
-	manager  *framework.PermissionManager
-	agentID  string
-	spec     *framework.AgentRuntimeSpec
+	// Code, when set, extracts the code from model-written content before
+	// it is written.
+	Code    *framework.CodePipeline
+	manager *framework.PermissionManager
+	agentID string
+	spec    *framework.AgentRuntimeSpec
 }
 
 func (t *CreateFileTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
@@ -370,15 +353,22 @@ func (t *CreateFileTool) Execute(ctx context.Context, state *framework.Context,
 		return nil, err
 	}
 
+	unlock := fileLocks.Lock(path)
+	defer unlock()
 	if _, err := os.Stat(path); err == nil {
 		return nil, fmt.Errorf("file %s already exists", path)
 	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
-	if err := os.WriteFile(path, []byte(fmt.Sprint(args["content"])), 0o644); err != nil {
+	var content []byte
+	if args["content"] != nil {
+		content = []byte(t.Code.Process(fmt.Sprint(args["content"]), path))
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
 		return nil, err
 	}
+	recordFileHash(state, path, content)
 	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"path": path}}, nil
 }
 func (t *CreateFileTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
@@ -426,6 +416,14 @@ func (t *DeleteFileTool) Execute(ctx context.Context, state *framework.Context,
 		return nil, err
 	}
 
+	unlock := fileLocks.Lock(path)
+	defer unlock()
+	var conflict *FileConflictError
+	if err := checkFileFresh(state, path); errors.As(err, &conflict) {
+		return conflictResult(conflict), nil
+	} else if err != nil {
+		return nil, err
+	}
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -441,6 +439,7 @@ func (t *DeleteFileTool) Execute(ctx context.Context, state *framework.Context,
 	if err := os.Rename(path, dest); err != nil {
 		return nil, err
 	}
+	forgetFileHash(state, path)
 	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"path": dest}}, nil
 }
 func (t *DeleteFileTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
@@ -491,6 +490,16 @@ func preparePath(base, path string) string {
 	return filepath.Join(base, path)
 }
 
+// checkNoRedactionMask refuses content holding a redaction mask. The model
+// only sees redacted tool output, so copying it back would replace a real
+// secret on disk with the mask.
+func checkNoRedactionMask(content string) error {
+	if mask := framework.FindRedactionMask(content); mask != "" {
+		return fmt.Errorf("content contains redaction placeholder %s; edit around the secret instead of rewriting it", mask)
+	}
+	return nil
+}
+
 func isText(data []byte) bool {
 	if len(data) == 0 {
 		return true
@@ -566,11 +575,13 @@ func enforceFileMatrix(ctx context.Context, manager *framework.PermissionManager
 func FileOperations(basePath string) []framework.Tool {
 	return []framework.Tool{
 		&ReadFileTool{BasePath: basePath},
-		&WriteFileTool{BasePath: basePath, Backup: true},
+		&WriteFileTool{BasePath: basePath, Backup: true, Code: framework.DefaultCodePipeline()},
+		&EditFileTool{BasePath: basePath, Backup: true},
 		&ListFilesTool{BasePath: basePath},
 		&SearchInFilesTool{BasePath: basePath},
-		&CreateFileTool{BasePath: basePath},
+		&CreateFileTool{BasePath: basePath, Code: framework.DefaultCodePipeline()},
 		&DeleteFileTool{BasePath: basePath},
+		&ApplyPatchTool{BasePath: basePath, Backup: true},
 	}
 }
 
@@ -43,6 +43,16 @@ func (t *GitCommandTool) Description() string {
 		return "Creates a commit (without pushing)."
 	case "blame":
 		return "Shows blame information."
+	case "branch_create":
+		return "Creates a branch to isolate changes, optionally switching to it."
+	case "stage":
+		return "Stages files for the next commit."
+	case "commit_with_message":
+		return "Commits staged changes with the given message."
+	case "stash":
+		return "Saves, restores, lists, or drops stashed changes."
+	case "revert":
+		return "Reverts a commit by creating an inverse commit."
 	default:
 		return "Git command"
 	}
@@ -70,6 +80,31 @@ func (t *GitCommandTool) Parameters() []framework.ToolParameter {
 			{Name: "start", Type: "int", Required: false, Default: 1},
 			{Name: "end", Type: "int", Required: false, Default: 1},
 		}
+	case "branch_create":
+		return []framework.ToolParameter{
+			{Name: "name", Type: "string", Required: true},
+			{Name: "from", Type: "string", Required: false},
+			{Name: "checkout", Type: "bool", Required: false, Default: true},
+		}
+	case "stage":
+		return []framework.ToolParameter{{Name: "files", Type: "array", Required: true}}
+	case "commit_with_message":
+		return []framework.ToolParameter{
+			{Name: "message", Type: "string", Required: true},
+			{Name: "allow_empty", Type: "bool", Required: false, Default: false},
+		}
+	case "stash":
+		return []framework.ToolParameter{
+			{Name: "action", Type: "string", Required: false, Default: "push"},
+			{Name: "message", Type: "string", Required: false},
+			{Name: "include_untracked", Type: "bool", Required: false, Default: false},
+			{Name: "ref", Type: "string", Required: false},
+		}
+	case "revert":
+		return []framework.ToolParameter{
+			{Name: "commit", Type: "string", Required: true},
+			{Name: "no_commit", Type: "bool", Required: false, Default: false},
+		}
 	default:
 		return []framework.ToolParameter{}
 	}
@@ -91,12 +126,15 @@ func (t *GitCommandTool) Execute(ctx context.Context, state *framework.Context,
 		return t.runGit(ctx, []string{"log", fmt.Sprintf("-n%d", limit), "--oneline", "--", file})
 	case "branch":
 		name := fmt.Sprint(args["name"])
+		if err := checkGitRef("name", name); err != nil {
+			return nil, err
+		}
 		return t.runGit(ctx, []string{"checkout", "-b", name})
 	case "commit":
 		message := fmt.Sprint(args["message"])
 		filesAny, ok := args["files"].([]string)
 		if ok && len(filesAny) > 0 {
-			if _, err := t.runGit(ctx, append([]string{"add"}, filesAny...)); err != nil {
+			if _, err := t.runGit(ctx, append([]string{"add", "--"}, filesAny...)); err != nil {
 				return nil, err
 			}
 		} else {
@@ -110,12 +148,124 @@ func (t *GitCommandTool) Execute(ctx context.Context, state *framework.Context,
 		start := toInt(args["start"])
 		end := toInt(args["end"])
 		rangeArg := fmt.Sprintf("-L%d,%d", start, end)
-		return t.runGit(ctx, []string{"blame", rangeArg, file})
+		return t.runGit(ctx, []string{"blame", rangeArg, "--", file})
+	case "branch_create":
+		name := strings.TrimSpace(fmt.Sprint(args["name"]))
+		if name == "" || args["name"] == nil {
+			return nil, fmt.Errorf("branch name required")
+		}
+		if err := checkGitRef("name", name); err != nil {
+			return nil, err
+		}
+		gitArgs := []string{"branch", name}
+		if checkout, ok := args["checkout"].(bool); !ok || checkout {
+			gitArgs = []string{"checkout", "-b", name}
+		}
+		if from, ok := args["from"].(string); ok && from != "" {
+			if err := checkGitRef("from", from); err != nil {
+				return nil, err
+			}
+			gitArgs = append(gitArgs, from)
+		}
+		return t.runGit(ctx, gitArgs)
+	case "stage":
+		files := gitStringList(args["files"])
+		if len(files) == 0 {
+			return nil, fmt.Errorf("files required")
+		}
+		return t.runGit(ctx, append([]string{"add", "--"}, files...))
+	case "commit_with_message":
+		message := strings.TrimSpace(fmt.Sprint(args["message"]))
+		if message == "" || args["message"] == nil {
+			return nil, fmt.Errorf("commit message required")
+		}
+		gitArgs := []string{"commit", "-m", message}
+		if allow, ok := args["allow_empty"].(bool); ok && allow {
+			gitArgs = append(gitArgs, "--allow-empty")
+		}
+		return t.runGit(ctx, gitArgs)
+	case "stash":
+		gitArgs, err := stashArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return t.runGit(ctx, gitArgs)
+	case "revert":
+		commit := strings.TrimSpace(fmt.Sprint(args["commit"]))
+		if commit == "" || args["commit"] == nil {
+			return nil, fmt.Errorf("commit required")
+		}
+		if err := checkGitRef("commit", commit); err != nil {
+			return nil, err
+		}
+		gitArgs := []string{"revert", "--no-edit"}
+		if noCommit, ok := args["no_commit"].(bool); ok && noCommit {
+			gitArgs = append(gitArgs, "--no-commit")
+		}
+		return t.runGit(ctx, append(gitArgs, commit))
 	default:
 		return nil, fmt.Errorf("unsupported git command %s", t.Command)
 	}
 }
 
+// checkGitRef rejects a branch, commit, or stash name that git would parse
+// as an option, such as "-D" turning branch creation into a deletion.
+func checkGitRef(param, value string) error {
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("invalid %s %q: must not start with '-'", param, value)
+	}
+	return nil
+}
+
+// stashArgs maps the stash tool's action onto git stash arguments.
+func stashArgs(args map[string]interface{}) ([]string, error) {
+	action, _ := args["action"].(string)
+	ref, _ := args["ref"].(string)
+	switch action {
+	case "pop", "apply", "drop":
+		if ref != "" {
+			if err := checkGitRef("ref", ref); err != nil {
+				return nil, err
+			}
+			return []string{"stash", action, ref}, nil
+		}
+		return []string{"stash", action}, nil
+	case "list":
+		return []string{"stash", "list"}, nil
+	default:
+		res := []string{"stash", "push"}
+		if untracked, ok := args["include_untracked"].(bool); ok && untracked {
+			res = append(res, "--include-untracked")
+		}
+		if message, ok := args["message"].(string); ok && message != "" {
+			res = append(res, "-m", message)
+		}
+		return res, nil
+	}
+}
+
+// gitStringList accepts both []string and the []interface{} produced by JSON
+// decoding of tool calls.
+func gitStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			if str := strings.TrimSpace(fmt.Sprint(item)); str != "" {
+				res = append(res, str)
+			}
+		}
+		return res
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+	return nil
+}
+
 func (t *GitCommandTool) runGit(ctx context.Context, args []string) (*framework.ToolResult, error) {
 	if t.Runner == nil {
 		return nil, fmt.Errorf("command runner missing for git tool")
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitChangeManagementArgs(t *testing.T) {
+	cases := []struct {
+		command string
+		args    map[string]interface{}
+		want    []string
+	}{
+		{"branch_create", map[string]interface{}{"name": "agent/fix"}, []string{"git", "checkout", "-b", "agent/fix"}},
+		{"branch_create", map[string]interface{}{"name": "agent/fix", "checkout": false, "from": "main"}, []string{"git", "branch", "agent/fix", "main"}},
+		{"stage", map[string]interface{}{"files": []interface{}{"a.go", "b.go"}}, []string{"git", "add", "--", "a.go", "b.go"}},
+		{"commit_with_message", map[string]interface{}{"message": "fix parser"}, []string{"git", "commit", "-m", "fix parser"}},
+		{"stash", map[string]interface{}{"include_untracked": true, "message": "wip"}, []string{"git", "stash", "push", "--include-untracked", "-m", "wip"}},
+		{"stash", map[string]interface{}{"action": "pop"}, []string{"git", "stash", "pop"}},
+		{"revert", map[string]interface{}{"commit": "abc123"}, []string{"git", "revert", "--no-edit", "abc123"}},
+	}
+	for _, tc := range cases {
+		runner := &stubRunner{}
+		tool := &GitCommandTool{RepoPath: t.TempDir(), Command: tc.command, Runner: runner}
+		_, err := tool.Execute(context.Background(), nil, tc.args)
+		assert.NoError(t, err, tc.command)
+		// The first call is the availability probe.
+		assert.Equal(t, tc.want, runner.calls[len(runner.calls)-1], tc.command)
+	}
+}
+
+func TestGitStageRequiresFiles(t *testing.T) {
+	tool := &GitCommandTool{RepoPath: t.TempDir(), Command: "stage", Runner: &stubRunner{}}
+	_, err := tool.Execute(context.Background(), nil, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestGitRejectsOptionLikeRefs(t *testing.T) {
+	cases := []struct {
+		command string
+		args    map[string]interface{}
+	}{
+		{"branch", map[string]interface{}{"name": "--orphan"}},
+		{"branch_create", map[string]interface{}{"name": "-D", "from": "main", "checkout": false}},
+		{"branch_create", map[string]interface{}{"name": "agent/fix", "from": "--force"}},
+		{"revert", map[string]interface{}{"commit": "--abort"}},
+		{"stash", map[string]interface{}{"action": "drop", "ref": "--quiet"}},
+	}
+	for _, tc := range cases {
+		runner := &stubRunner{}
+		tool := &GitCommandTool{RepoPath: t.TempDir(), Command: tc.command, Runner: runner}
+		_, err := tool.Execute(context.Background(), nil, tc.args)
+		assert.ErrorContains(t, err, "must not start with '-'", tc.command)
+		// Only the availability probe ran.
+		assert.Len(t, runner.calls, 1, tc.command)
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// GitHubIssueTool files issues through the GitHub CLI so agents never handle
+// API tokens directly; `gh` uses whatever credentials the operator configured.
+type GitHubIssueTool struct {
+	RepoPath string
+	Runner   framework.CommandRunner
+	manager  *framework.PermissionManager
+	agentID  string
+}
+
+func (t *GitHubIssueTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *GitHubIssueTool) Name() string { return "github_create_issue" }
+func (t *GitHubIssueTool) Description() string {
+	return "Creates a GitHub issue in the workspace repository and returns its URL and number."
+}
+func (t *GitHubIssueTool) Category() string { return "git" }
+func (t *GitHubIssueTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "title", Type: "string", Required: true},
+		{Name: "body", Type: "string", Required: false},
+		{Name: "labels", Type: "array", Required: false},
+	}
+}
+
+func (t *GitHubIssueTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	if t.Runner == nil {
+		return nil, fmt.Errorf("command runner missing for github tool")
+	}
+	title := strings.TrimSpace(fmt.Sprint(args["title"]))
+	if args["title"] == nil || title == "" {
+		return nil, fmt.Errorf("issue title required")
+	}
+	body, _ := args["body"].(string)
+	cmdArgs := []string{"issue", "create", "--title", title, "--body", body}
+	for _, label := range gitStringList(args["labels"]) {
+		cmdArgs = append(cmdArgs, "--label", label)
+	}
+	if t.manager != nil {
+		if err := t.manager.CheckExecutable(ctx, t.agentID, "gh", cmdArgs, nil); err != nil {
+			return nil, err
+		}
+	}
+	stdout, stderr, err := t.Runner.Run(ctx, framework.CommandRequest{
+		Workdir: t.RepoPath,
+		Args:    append([]string{"gh"}, cmdArgs...),
+		Timeout: 30 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gh issue create failed: %s", stderr)
+	}
+	url := strings.TrimSpace(stdout)
+	if lines := strings.Split(url, "\n"); len(lines) > 1 {
+		url = strings.TrimSpace(lines[len(lines)-1])
+	}
+	return &framework.ToolResult{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":    url,
+			"number": IssueNumberFromURL(url),
+		},
+	}, nil
+}
+
+func (t *GitHubIssueTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.Runner != nil
+}
+
+func (t *GitHubIssueTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.RepoPath, "gh", []string{"issue", "create", "*"})}
+}
+
+// IssueNumberFromURL extracts the trailing issue number from a GitHub issue
+// URL, returning 0 when the URL does not end in a number.
+func IssueNumberFromURL(url string) int {
+	idx := strings.LastIndex(url, "/")
+	if idx < 0 {
+		return 0
+	}
+	number, err := strconv.Atoi(url[idx+1:])
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,445 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+var (
+	goModulePathPattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._~/+-]*$`)
+	goModuleVersionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+)
+
+// goModuleFiles are the files go mod tidy and go get rewrite.
+var goModuleFiles = []string{"go.mod", "go.sum"}
+
+// GoTools returns the dependency and import tools for the Go module rooted
+// at workdir: go_mod_tidy, go_get, go_imports and go_list_modules. They run
+// the go and goimports binaries, so manifests must list them, and agents use
+// them instead of hand-editing go.mod or import blocks.
+func GoTools(workdir string, runner framework.CommandRunner) []framework.Tool {
+	base := goModuleTool{Workdir: workdir, Runner: runner}
+	return []framework.Tool{
+		&GoModTidyTool{base},
+		&GoGetTool{base},
+		&GoImportsTool{base},
+		&GoListModulesTool{base},
+	}
+}
+
+// goModuleTool holds what the Go tools share: the module directory, the
+// command runner, and the agent's permissions.
+type goModuleTool struct {
+	Workdir string
+	Runner  framework.CommandRunner
+	// Timeout bounds each command; zero uses five minutes, enough for go
+	// get to download modules.
+	Timeout time.Duration
+	manager *framework.PermissionManager
+	agentID string
+	spec    *framework.AgentRuntimeSpec
+}
+
+func (t *goModuleTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *goModuleTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *goModuleTool) Category() string { return "go" }
+
+func (t *goModuleTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	if t.Runner == nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(t.Workdir, "go.mod"))
+	return err == nil
+}
+
+func (t *goModuleTool) run(ctx context.Context, cmdline []string) (string, string, error) {
+	if t.Runner == nil {
+		return "", "", fmt.Errorf("command runner missing")
+	}
+	if err := authorizeCommand(ctx, t.manager, t.agentID, t.spec, cmdline); err != nil {
+		return "", "", err
+	}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return t.Runner.Run(ctx, framework.CommandRequest{
+		Workdir: t.Workdir,
+		Args:    cmdline,
+		Timeout: timeout,
+	})
+}
+
+// checkWrite asks for write access to files before a command rewrites them,
+// which also checkpoints them for rollback.
+func (t *goModuleTool) checkWrite(ctx context.Context, files ...string) error {
+	if t.manager == nil {
+		return nil
+	}
+	for _, file := range files {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moduleFiles returns the absolute paths of go.mod and go.sum.
+func (t *goModuleTool) moduleFiles() []string {
+	files := make([]string, len(goModuleFiles))
+	for i, name := range goModuleFiles {
+		files[i] = filepath.Join(t.Workdir, name)
+	}
+	return files
+}
+
+// readFiles captures the content of files, missing ones as nil.
+func readFiles(files []string) map[string][]byte {
+	out := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			out[file] = data
+		}
+	}
+	return out
+}
+
+// changedFiles lists, relative to the module, the files whose content
+// differs from before.
+func (t *goModuleTool) changedFiles(before map[string][]byte, files []string) []string {
+	after := readFiles(files)
+	changed := []string{}
+	for _, file := range files {
+		old, hadOld := before[file]
+		now, hasNow := after[file]
+		if hadOld != hasNow || !bytes.Equal(old, now) {
+			changed = append(changed, t.relative(file))
+		}
+	}
+	return changed
+}
+
+func (t *goModuleTool) relative(file string) string {
+	if rel, err := filepath.Rel(t.Workdir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+// commandFailure reports a failed command with the go tool's message, which
+// names the package or module at fault.
+func commandFailure(data map[string]interface{}, stderr string, err error) *framework.ToolResult {
+	msg := strings.TrimSpace(stderr)
+	if msg == "" {
+		msg = err.Error()
+	}
+	return &framework.ToolResult{Success: false, Data: data, Error: msg}
+}
+
+// GoModTidyTool runs go mod tidy and reports whether go.mod or go.sum
+// changed.
+type GoModTidyTool struct {
+	goModuleTool
+}
+
+func (t *GoModTidyTool) Name() string { return "go_mod_tidy" }
+func (t *GoModTidyTool) Description() string {
+	return "Runs go mod tidy to add missing and remove unused module requirements."
+}
+func (t *GoModTidyTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "check", Type: "bool", Description: "Report the changes tidy would make without writing them", Required: false, Default: false},
+	}
+}
+
+func (t *GoModTidyTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	check, _ := args["check"].(bool)
+	cmdline := []string{"go", "mod", "tidy"}
+	if check {
+		cmdline = append(cmdline, "-diff")
+	} else if err := t.checkWrite(ctx, t.moduleFiles()...); err != nil {
+		return nil, err
+	}
+	before := readFiles(t.moduleFiles())
+	stdout, stderr, err := t.run(ctx, cmdline)
+	data := map[string]interface{}{"command": strings.Join(cmdline, " ")}
+	if check {
+		// -diff exits non-zero when changes are needed.
+		data["diff"] = stdout
+		data["tidy"] = strings.TrimSpace(stdout) == ""
+		if err != nil && strings.TrimSpace(stdout) == "" {
+			return commandFailure(data, stderr, err), nil
+		}
+		return &framework.ToolResult{Success: true, Data: data}, nil
+	}
+	data["changed"] = t.changedFiles(before, t.moduleFiles())
+	if err != nil {
+		return commandFailure(data, stderr, err), nil
+	}
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+func (t *GoModTidyTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.Workdir, "go", []string{"mod", "*"})}
+}
+
+// GoGetTool adds, upgrades, downgrades, or removes one module requirement
+// with go get.
+type GoGetTool struct {
+	goModuleTool
+}
+
+func (t *GoGetTool) Name() string { return "go_get" }
+func (t *GoGetTool) Description() string {
+	return "Adds or changes a module dependency with go get, updating go.mod and go.sum."
+}
+func (t *GoGetTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "module", Type: "string", Description: "Module or package path, e.g. github.com/google/uuid", Required: true},
+		{Name: "version", Type: "string", Description: "Version, branch, or commit; \"latest\", \"upgrade\", \"patch\", or \"none\" to remove the requirement", Required: false, Default: "latest"},
+	}
+}
+
+func (t *GoGetTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	module := stringArg(args, "module")
+	if !goModulePathPattern.MatchString(module) {
+		return nil, fmt.Errorf("invalid module path %q", module)
+	}
+	version := stringArg(args, "version")
+	if version == "" {
+		version = "latest"
+	}
+	if !goModuleVersionPattern.MatchString(version) {
+		return nil, fmt.Errorf("invalid module version %q", version)
+	}
+	if err := t.checkWrite(ctx, t.moduleFiles()...); err != nil {
+		return nil, err
+	}
+	previous := t.requiredVersion(ctx, module)
+	before := readFiles(t.moduleFiles())
+	cmdline := []string{"go", "get", module + "@" + version}
+	_, stderr, err := t.run(ctx, cmdline)
+	data := map[string]interface{}{
+		"command":  strings.Join(cmdline, " "),
+		"module":   module,
+		"previous": previous,
+		"changed":  t.changedFiles(before, t.moduleFiles()),
+	}
+	if err != nil {
+		return commandFailure(data, stderr, err), nil
+	}
+	data["version"] = t.requiredVersion(ctx, module)
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+// requiredVersion returns the version of module in the build list, or ""
+// when it is not required. Package paths resolve to no module and also
+// report "".
+func (t *goModuleTool) requiredVersion(ctx context.Context, module string) string {
+	stdout, _, err := t.run(ctx, []string{"go", "list", "-m", "-json", module})
+	if err != nil {
+		return ""
+	}
+	modules, err := decodeGoModules(stdout)
+	if err != nil || len(modules) == 0 {
+		return ""
+	}
+	return modules[0].Version
+}
+
+func (t *GoGetTool) Permissions() framework.ToolPermissions {
+	perms := framework.NewExecutionPermissionSet(t.Workdir, "go", []string{"get", "*"})
+	perms.Executables = append(perms.Executables, framework.ExecutablePermission{Binary: "go", Args: []string{"list", "*"}})
+	return framework.ToolPermissions{Permissions: perms}
+}
+
+// GoImportsTool adds missing and removes unused imports with goimports. The
+// fixed files are written through file_write, so the agent's file
+// permissions apply to each of them.
+type GoImportsTool struct {
+	goModuleTool
+}
+
+func (t *GoImportsTool) Name() string { return "go_imports" }
+func (t *GoImportsTool) Description() string {
+	return "Fixes the import blocks of Go files with goimports, adding missing and removing unused imports."
+}
+func (t *GoImportsTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "path", Type: "string", Description: "Go file or directory to fix (directories are walked)", Required: false, Default: "."},
+	}
+}
+
+func (t *GoImportsTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	path := stringArg(args, "path")
+	if path == "" {
+		path = "."
+	}
+	target := preparePath(t.Workdir, path)
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, target); err != nil {
+			return nil, err
+		}
+	}
+	stdout, stderr, err := t.run(ctx, []string{"goimports", "-l", target})
+	if err != nil {
+		return commandFailure(map[string]interface{}{"path": path}, stderr, err), nil
+	}
+	var files []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, preparePath(t.Workdir, line))
+		}
+	}
+	sort.Strings(files)
+	write := &WriteFileTool{BasePath: t.Workdir, manager: t.manager, agentID: t.agentID, spec: t.spec}
+	fixed := []string{}
+	for _, file := range files {
+		content, stderr, err := t.run(ctx, []string{"goimports", file})
+		if err != nil {
+			return commandFailure(map[string]interface{}{"path": path, "files": fixed, "applied": len(fixed) > 0}, stderr, err), nil
+		}
+		if _, err := write.Execute(ctx, state, map[string]interface{}{"path": file, "content": content}); err != nil {
+			return nil, fmt.Errorf("write %s: %w", t.relative(file), err)
+		}
+		fixed = append(fixed, t.relative(file))
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"path":    path,
+		"files":   fixed,
+		"applied": len(fixed) > 0,
+	}}, nil
+}
+
+func (t *GoImportsTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.Workdir, "goimports", []string{"*"})}
+}
+
+// GoModule is one entry of the module build list as go list -m -json
+// reports it.
+type GoModule struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Main    bool   `json:"main,omitempty"`
+	// Indirect marks requirements go.mod lists as // indirect.
+	Indirect  bool   `json:"indirect,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+	// Replace is the replacement as path@version, or a directory.
+	Replace string `json:"replace,omitempty"`
+	// Update is the newer version available, when asked for.
+	Update string `json:"update,omitempty"`
+}
+
+// goListModule mirrors the JSON go list -m -json prints.
+type goListModule struct {
+	Path      string
+	Version   string
+	Main      bool
+	Indirect  bool
+	GoVersion string
+	Replace   *goListModule
+	Update    *goListModule
+}
+
+// decodeGoModules parses the concatenated JSON objects go list prints.
+func decodeGoModules(output string) ([]GoModule, error) {
+	dec := json.NewDecoder(strings.NewReader(output))
+	var modules []GoModule
+	for {
+		var raw goListModule
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return modules, nil
+			}
+			return modules, fmt.Errorf("parse go list output: %w", err)
+		}
+		module := GoModule{
+			Path:      raw.Path,
+			Version:   raw.Version,
+			Main:      raw.Main,
+			Indirect:  raw.Indirect,
+			GoVersion: raw.GoVersion,
+		}
+		if raw.Replace != nil {
+			module.Replace = raw.Replace.Path
+			if raw.Replace.Version != "" {
+				module.Replace += "@" + raw.Replace.Version
+			}
+		}
+		if raw.Update != nil {
+			module.Update = raw.Update.Version
+		}
+		modules = append(modules, module)
+	}
+}
+
+// GoListModulesTool queries the module build list with go list -m -json.
+type GoListModulesTool struct {
+	goModuleTool
+}
+
+func (t *GoListModulesTool) Name() string { return "go_list_modules" }
+func (t *GoListModulesTool) Description() string {
+	return "Lists the modules the Go module depends on, with versions, replacements, and available updates."
+}
+func (t *GoListModulesTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "filter", Type: "string", Description: "Only list modules whose path contains this text", Required: false},
+		{Name: "updates", Type: "bool", Description: "Report newer versions (queries the module proxy)", Required: false, Default: false},
+	}
+}
+
+func (t *GoListModulesTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	cmdline := []string{"go", "list", "-m", "-json"}
+	if updates, _ := args["updates"].(bool); updates {
+		cmdline = append(cmdline, "-u")
+	}
+	cmdline = append(cmdline, "all")
+	stdout, stderr, err := t.run(ctx, cmdline)
+	if err != nil {
+		return commandFailure(map[string]interface{}{"command": strings.Join(cmdline, " ")}, stderr, err), nil
+	}
+	modules, err := decodeGoModules(stdout)
+	if err != nil {
+		return nil, err
+	}
+	filter := stringArg(args, "filter")
+	listed := []GoModule{}
+	for _, module := range modules {
+		if filter != "" && !strings.Contains(module.Path, filter) {
+			continue
+		}
+		listed = append(listed, module)
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"command": strings.Join(cmdline, " "),
+		"modules": listed,
+	}}, nil
+}
+
+func (t *GoListModulesTool) Permissions() framework.ToolPermissions {
+	perms := framework.NewFileSystemPermissionSet(t.Workdir, framework.FileSystemRead, framework.FileSystemList)
+	perms.Executables = append(perms.Executables, framework.ExecutablePermission{Binary: "go", Args: []string{"list", "*"}})
+	return framework.ToolPermissions{Permissions: perms}
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// goRunner answers go and goimports commands from a table keyed by the
+// command line; run, when set, runs first to change files like the real
+// command would.
+type goRunner struct {
+	outputs map[string]string
+	failing map[string]string
+	run     map[string]func()
+	calls   []string
+}
+
+func (r *goRunner) Run(ctx context.Context, req framework.CommandRequest) (string, string, error) {
+	cmdline := strings.Join(req.Args, " ")
+	r.calls = append(r.calls, cmdline)
+	if fn := r.run[cmdline]; fn != nil {
+		fn()
+	}
+	if stderr, ok := r.failing[cmdline]; ok {
+		return "", stderr, errors.New("exit status 1")
+	}
+	return r.outputs[cmdline], "", nil
+}
+
+func newGoModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0o644))
+	return dir
+}
+
+func TestGoGetToolReportsResolvedVersion(t *testing.T) {
+	dir := newGoModule(t)
+	runner := &goRunner{
+		outputs: map[string]string{"go list -m -json github.com/google/uuid": `{"Path":"github.com/google/uuid","Version":"v1.6.0"}`},
+		failing: map[string]string{},
+		run: map[string]func(){
+			"go get github.com/google/uuid@latest": func() {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\nrequire github.com/google/uuid v1.6.0\n"), 0o644))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "go.sum"), []byte("github.com/google/uuid v1.6.0 h1:x\n"), 0o644))
+			},
+		},
+	}
+	tool := GoTools(dir, runner)[1]
+	require.Equal(t, "go_get", tool.Name())
+	require.NoError(t, tool.Permissions().Validate())
+	require.True(t, tool.IsAvailable(context.Background(), nil))
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"module": "github.com/google/uuid"})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, "v1.6.0", res.Data["version"])
+	assert.Equal(t, []string{"go.mod", "go.sum"}, res.Data["changed"])
+	assert.Contains(t, runner.calls, "go get github.com/google/uuid@latest")
+
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"module": "-modfile=/etc/passwd"})
+	assert.ErrorContains(t, err, "invalid module path")
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"module": "github.com/google/uuid", "version": "v1 -x"})
+	assert.ErrorContains(t, err, "invalid module version")
+
+	runner.failing["go get example.com/missing@v9.9.9"] = "go: example.com/missing@v9.9.9: unrecognized import path\n"
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"module": "example.com/missing", "version": "v9.9.9"})
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.Equal(t, "go: example.com/missing@v9.9.9: unrecognized import path", res.Error)
+}
+
+func TestGoModTidyToolCheckDoesNotWrite(t *testing.T) {
+	dir := newGoModule(t)
+	runner := &goRunner{
+		outputs: map[string]string{"go mod tidy -diff": "--- go.mod\n+++ go.mod\n"},
+		failing: map[string]string{},
+	}
+	tool := &GoModTidyTool{goModuleTool{Workdir: dir, Runner: runner}}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"check": true})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, false, res.Data["tidy"])
+
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, res.Data["changed"])
+}
+
+func TestGoImportsToolWritesFixedFiles(t *testing.T) {
+	dir := newGoModule(t)
+	main := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(main, []byte("package main\n\nfunc main() { fmt.Println() }\n"), 0o644))
+	fixed := "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n"
+	runner := &goRunner{outputs: map[string]string{
+		"goimports -l " + dir: main + "\n",
+		"goimports " + main:   fixed,
+	}}
+	tool := &GoImportsTool{goModuleTool{Workdir: dir, Runner: runner}}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, res.Data["files"])
+	data, err := os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, fixed, string(data))
+}
+
+func TestGoListModulesToolFilters(t *testing.T) {
+	dir := newGoModule(t)
+	runner := &goRunner{outputs: map[string]string{"go list -m -json -u all": `{"Path":"example.com/app","Main":true,"GoVersion":"1.22"}
+{"Path":"github.com/google/uuid","Version":"v1.5.0","Update":{"Path":"github.com/google/uuid","Version":"v1.6.0"}}
+{"Path":"golang.org/x/mod","Version":"v0.17.0","Indirect":true,"Replace":{"Path":"../mod"}}
+`}}
+	tool := &GoListModulesTool{goModuleTool{Workdir: dir, Runner: runner}}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"updates": true})
+	require.NoError(t, err)
+	modules := res.Data["modules"].([]GoModule)
+	require.Len(t, modules, 3)
+	assert.Equal(t, GoModule{Path: "github.com/google/uuid", Version: "v1.5.0", Update: "v1.6.0"}, modules[1])
+	assert.Equal(t, "../mod", modules[2].Replace)
+
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"filter": "uuid", "updates": true})
+	require.NoError(t, err)
+	assert.Len(t, res.Data["modules"], 1)
+}
@@ -0,0 +1,378 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// defaultGrepMaxResults caps matches when the caller does not, so a broad
+// pattern cannot flood the context window.
+const defaultGrepMaxResults = 200
+
+// grepParameters are the options shared by search_grep and file_search.
+func grepParameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "pattern", Type: "string", Description: "Text to find, or a regular expression when regex is true.", Required: true},
+		{Name: "directory", Type: "string", Required: false, Default: "."},
+		{Name: "regex", Type: "boolean", Description: "Treat pattern as a regular expression (RE2 syntax).", Required: false, Default: false},
+		{Name: "case_sensitive", Type: "boolean", Required: false},
+		{Name: "max_results", Type: "integer", Description: "Stop after this many matching lines.", Required: false, Default: defaultGrepMaxResults},
+		{Name: "context_lines", Type: "integer", Description: "Lines of context to return before and after each match.", Required: false, Default: 0},
+	}
+}
+
+// grepOptions are the parsed search arguments.
+type grepOptions struct {
+	re           *regexp.Regexp
+	regex        bool
+	ignoreCase   bool
+	pattern      string
+	maxResults   int
+	contextLines int
+}
+
+// parseGrepOptions reads the grepParameters arguments. caseSensitive is the
+// default when the caller does not choose.
+func parseGrepOptions(args map[string]interface{}, caseSensitive bool) (grepOptions, error) {
+	opts := grepOptions{
+		pattern:    fmt.Sprint(args["pattern"]),
+		maxResults: defaultGrepMaxResults,
+	}
+	if args["pattern"] == nil || opts.pattern == "" {
+		return opts, fmt.Errorf("pattern required")
+	}
+	if v, ok := args["regex"].(bool); ok {
+		opts.regex = v
+	}
+	if v, ok := args["case_sensitive"].(bool); ok {
+		caseSensitive = v
+	}
+	opts.ignoreCase = !caseSensitive
+	var err error
+	if opts.maxResults, err = grepInt(args, "max_results", defaultGrepMaxResults); err != nil {
+		return opts, err
+	}
+	if opts.contextLines, err = grepInt(args, "context_lines", 0); err != nil {
+		return opts, err
+	}
+	expr := opts.pattern
+	if !opts.regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	if opts.re, err = regexp.Compile(expr); err != nil {
+		return opts, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return opts, nil
+}
+
+// grepInt reads a non-negative integer argument, accepting the float64 JSON
+// decoding produces and numeric strings.
+func grepInt(args map[string]interface{}, name string, fallback int) (int, error) {
+	var n int
+	switch v := args[name].(type) {
+	case nil:
+		return fallback, nil
+	case int:
+		n = v
+	case float64:
+		n = int(v)
+	case string:
+		if v == "" {
+			return fallback, nil
+		}
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", name)
+		}
+		n = parsed
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", name)
+	}
+	return n, nil
+}
+
+// grepMatch is one matching line. Column is the 1-based byte column of the
+// first match on the line and ByteOffset its offset from the start of the
+// file.
+type grepMatch struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	ByteOffset int64    `json:"byte_offset"`
+	Match      string   `json:"match"`
+	Content    string   `json:"content"`
+	Before     []string `json:"before,omitempty"`
+	After      []string `json:"after,omitempty"`
+}
+
+// grepSearch walks a directory for grepOptions matches, skipping .git,
+// ignored paths, binary files, and anything check rejects.
+type grepSearch struct {
+	root    string
+	opts    grepOptions
+	ignore  *ignoreMatcher
+	check   func(action framework.FileSystemAction, path string) error
+	matches []grepMatch
+	// truncated is set when a match past max_results was dropped.
+	truncated bool
+}
+
+// newGrepSearch searches root. Ignore files are read from base down, so a
+// search of a subdirectory still honours the workspace .gitignore.
+func newGrepSearch(base, root string, opts grepOptions, check func(framework.FileSystemAction, string) error) *grepSearch {
+	ignoreRoot := root
+	if base != "" {
+		if rel, err := filepath.Rel(base, root); err == nil && !strings.HasPrefix(rel, "..") {
+			ignoreRoot = base
+		}
+	}
+	if check == nil {
+		check = func(framework.FileSystemAction, string) error { return nil }
+	}
+	return &grepSearch{root: root, opts: opts, ignore: newIgnoreMatcher(ignoreRoot), check: check}
+}
+
+// add records match unless max_results has been reached.
+func (s *grepSearch) add(match grepMatch) {
+	if s.opts.maxResults > 0 && len(s.matches) >= s.opts.maxResults {
+		s.truncated = true
+		return
+	}
+	s.matches = append(s.matches, match)
+}
+
+// walk runs the built-in search.
+func (s *grepSearch) walk(ctx context.Context) error {
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path == s.root {
+				return nil
+			}
+			if d.Name() == ".git" || s.ignore.Ignored(path, true) {
+				return fs.SkipDir
+			}
+			if s.check(framework.FileSystemList, path) != nil {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if s.truncated {
+			return fs.SkipAll
+		}
+		if s.ignore.Ignored(path, false) || s.check(framework.FileSystemRead, path) != nil {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		head := data
+		if len(head) > 8192 {
+			head = head[:8192]
+		}
+		if bytes.IndexByte(head, 0) >= 0 {
+			return nil
+		}
+		s.searchFile(path, data)
+		return nil
+	})
+	return err
+}
+
+// searchFile records the matching lines of one file.
+func (s *grepSearch) searchFile(path string, data []byte) {
+	lines := strings.SplitAfter(string(data), "\n")
+	var offset int64
+	for i, raw := range lines {
+		if s.truncated || (raw == "" && i == len(lines)-1) {
+			return
+		}
+		text := strings.TrimRight(raw, "\r\n")
+		if loc := s.opts.re.FindStringIndex(text); loc != nil {
+			match := grepMatch{
+				File:       path,
+				Line:       i + 1,
+				Column:     loc[0] + 1,
+				ByteOffset: offset + int64(loc[0]),
+				Match:      text[loc[0]:loc[1]],
+				Content:    text,
+			}
+			if n := s.opts.contextLines; n > 0 {
+				for j := max(0, i-n); j < i; j++ {
+					match.Before = append(match.Before, strings.TrimRight(lines[j], "\r\n"))
+				}
+				for j := i + 1; j <= i+n && j < len(lines); j++ {
+					if j == len(lines)-1 && lines[j] == "" {
+						break
+					}
+					match.After = append(match.After, strings.TrimRight(lines[j], "\r\n"))
+				}
+			}
+			s.add(match)
+		}
+		offset += int64(len(raw))
+	}
+}
+
+// ripgrepArgs builds the rg invocation for the options, searching the
+// working directory.
+func (s *grepSearch) ripgrepArgs() []string {
+	args := []string{"rg", "--json", "--no-require-git", "--no-config"}
+	if !s.opts.regex {
+		args = append(args, "--fixed-strings")
+	}
+	if s.opts.ignoreCase {
+		args = append(args, "--ignore-case")
+	} else {
+		args = append(args, "--case-sensitive")
+	}
+	if s.opts.contextLines > 0 {
+		args = append(args, "--context", strconv.Itoa(s.opts.contextLines))
+	}
+	return append(args, "--regexp", s.opts.pattern, ".")
+}
+
+// ripgrepEvent is the subset of an `rg --json` message the search reads.
+type ripgrepEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber     int   `json:"line_number"`
+		AbsoluteOffset int64 `json:"absolute_offset"`
+		Submatches     []struct {
+			Match struct {
+				Text string `json:"text"`
+			} `json:"match"`
+			Start int `json:"start"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+// ripgrep runs the search through rg. It returns false when rg did not
+// produce a complete report, e.g. because it is not installed, so the caller can
+// fall back to walk. Results are filtered through the same ignore rules and
+// permission checks as the built-in search, since rg only knows .gitignore.
+func (s *grepSearch) ripgrep(ctx context.Context, runner framework.CommandRunner) bool {
+	stdout, _, _ := runner.Run(ctx, framework.CommandRequest{
+		Workdir: s.root,
+		Args:    s.ripgrepArgs(),
+		Timeout: 60 * time.Second,
+	})
+	type fileLines struct {
+		path  string
+		lines map[int]string
+	}
+	var (
+		files    []*fileLines
+		current  *fileLines
+		found    []grepMatch
+		complete bool
+	)
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var ev ripgrepEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			return false
+		}
+		switch ev.Type {
+		case "begin":
+			path := filepath.Join(s.root, filepath.FromSlash(ev.Data.Path.Text))
+			current = &fileLines{path: path, lines: make(map[int]string)}
+			if s.ignore.Ignored(path, false) || s.check(framework.FileSystemRead, path) != nil {
+				current = nil
+				continue
+			}
+			files = append(files, current)
+		case "match", "context":
+			if current == nil {
+				continue
+			}
+			text := strings.TrimRight(ev.Data.Lines.Text, "\r\n")
+			current.lines[ev.Data.LineNumber] = text
+			if ev.Type != "match" || len(ev.Data.Submatches) == 0 {
+				continue
+			}
+			sub := ev.Data.Submatches[0]
+			found = append(found, grepMatch{
+				File:       current.path,
+				Line:       ev.Data.LineNumber,
+				Column:     sub.Start + 1,
+				ByteOffset: ev.Data.AbsoluteOffset + int64(sub.Start),
+				Match:      sub.Match.Text,
+				Content:    text,
+			})
+		case "summary":
+			complete = true
+		}
+	}
+	if !complete {
+		return false
+	}
+	lines := make(map[string]map[int]string, len(files))
+	for _, f := range files {
+		lines[f.path] = f.lines
+	}
+	for _, match := range found {
+		if s.truncated {
+			break
+		}
+		if n := s.opts.contextLines; n > 0 {
+			fileLines := lines[match.File]
+			for j := match.Line - n; j < match.Line; j++ {
+				if text, ok := fileLines[j]; ok {
+					match.Before = append(match.Before, text)
+				}
+			}
+			for j := match.Line + 1; j <= match.Line+n; j++ {
+				if text, ok := fileLines[j]; ok {
+					match.After = append(match.After, text)
+				}
+			}
+		}
+		s.add(match)
+	}
+	return true
+}
+
+// grepResult is the tool result shared by the grep tools.
+func grepResult(s *grepSearch, backend string) *framework.ToolResult {
+	matches := s.matches
+	if matches == nil {
+		matches = []grepMatch{}
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{
+		"matches":   matches,
+		"truncated": s.truncated,
+		"backend":   backend,
+	}}
+}
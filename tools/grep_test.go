@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func writeGrepFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestGrepToolRegexContextAndOffsets(t *testing.T) {
+	dir := t.TempDir()
+	writeGrepFiles(t, dir, map[string]string{
+		"main.go": "package main\n\nfunc handleA() {}\nfunc other() {}\nfunc HandleB() {}\n",
+	})
+	tool := &GrepTool{BasePath: dir}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"pattern":        `func handle\w+`,
+		"regex":          true,
+		"case_sensitive": true,
+		"context_lines":  float64(1),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "builtin", res.Data["backend"])
+	matches := res.Data["matches"].([]grepMatch)
+	require.Len(t, matches, 1)
+	m := matches[0]
+	assert.Equal(t, filepath.Join(dir, "main.go"), m.File)
+	assert.Equal(t, 3, m.Line)
+	assert.Equal(t, 1, m.Column)
+	assert.Equal(t, int64(len("package main\n\n")), m.ByteOffset)
+	assert.Equal(t, "func handleA", m.Match)
+	assert.Equal(t, []string{""}, m.Before)
+	assert.Equal(t, []string{"func other() {}"}, m.After)
+
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "HANDLE"})
+	require.NoError(t, err)
+	assert.Len(t, res.Data["matches"], 2, "literal search ignores case by default")
+
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "(", "regex": true})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+func TestGrepToolHonoursIgnoreFilesAndMaxResults(t *testing.T) {
+	dir := t.TempDir()
+	writeGrepFiles(t, dir, map[string]string{
+		".gitignore":           "build/\n*.log\n!keep.log\n",
+		".relurpifyignore":     "secrets.txt\n",
+		"src/.relurpifyignore": "/gen.go\n",
+		"build/out.txt":        "needle\n",
+		"debug.log":            "needle\n",
+		"keep.log":             "needle\n",
+		"secrets.txt":          "needle\n",
+		"src/gen.go":           "needle\n",
+		"src/app.go":           "needle\nneedle\n",
+		"src/sub/gen.go":       "needle\n",
+	})
+	tool := &GrepTool{BasePath: dir}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "needle"})
+	require.NoError(t, err)
+	var files []string
+	for _, m := range res.Data["matches"].([]grepMatch) {
+		rel, _ := filepath.Rel(dir, m.File)
+		files = append(files, filepath.ToSlash(rel))
+	}
+	assert.ElementsMatch(t, []string{"keep.log", "src/app.go", "src/app.go", "src/sub/gen.go"}, files)
+	assert.Equal(t, false, res.Data["truncated"])
+
+	res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "needle", "directory": "src", "max_results": 1})
+	require.NoError(t, err)
+	assert.Len(t, res.Data["matches"], 1)
+	assert.Equal(t, true, res.Data["truncated"])
+}
+
+// ripgrepRunner answers rg --version and replays canned rg --json output.
+type ripgrepRunner struct {
+	output string
+	calls  [][]string
+}
+
+func (r *ripgrepRunner) Run(ctx context.Context, req framework.CommandRequest) (string, string, error) {
+	r.calls = append(r.calls, req.Args)
+	if len(req.Args) == 2 && req.Args[1] == "--version" {
+		if r.output == "" {
+			return "", "rg: not found", errors.New("exit status 127")
+		}
+		return "ripgrep 14.1.0\n", "", nil
+	}
+	return r.output, "", nil
+}
+
+func TestGrepToolRipgrepBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeGrepFiles(t, dir, map[string]string{".relurpifyignore": "hidden.go\n"})
+	runner := &ripgrepRunner{output: `{"type":"begin","data":{"path":{"text":"./a.go"}}}
+{"type":"context","data":{"path":{"text":"./a.go"},"lines":{"text":"package a\n"},"line_number":1,"absolute_offset":0,"submatches":[]}}
+{"type":"match","data":{"path":{"text":"./a.go"},"lines":{"text":"var Needle = 1\n"},"line_number":2,"absolute_offset":10,"submatches":[{"match":{"text":"Needle"},"start":4,"end":10}]}}
+{"type":"end","data":{"path":{"text":"./a.go"}}}
+{"type":"begin","data":{"path":{"text":"./hidden.go"}}}
+{"type":"match","data":{"path":{"text":"./hidden.go"},"lines":{"text":"needle\n"},"line_number":1,"absolute_offset":0,"submatches":[{"match":{"text":"needle"},"start":0,"end":6}]}}
+{"type":"end","data":{"path":{"text":"./hidden.go"}}}
+{"type":"summary","data":{}}
+`}
+	tool := &GrepTool{BasePath: dir, Runner: runner}
+	require.Equal(t, "rg", tool.Permissions().Permissions.Executables[0].Binary)
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "needle", "context_lines": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "ripgrep", res.Data["backend"])
+	matches := res.Data["matches"].([]grepMatch)
+	require.Len(t, matches, 1, ".relurpifyignore filters rg results")
+	assert.Equal(t, grepMatch{
+		File:       filepath.Join(dir, "a.go"),
+		Line:       2,
+		Column:     5,
+		ByteOffset: 14,
+		Match:      "Needle",
+		Content:    "var Needle = 1",
+		Before:     []string{"package a"},
+	}, matches[0])
+	assert.Contains(t, runner.calls[1], "--fixed-strings")
+	assert.Contains(t, runner.calls[1], "--ignore-case")
+
+	// Without rg the built-in search runs, and the probe is not repeated.
+	writeGrepFiles(t, dir, map[string]string{"b.go": "needle\n"})
+	missing := &ripgrepRunner{}
+	tool = &GrepTool{BasePath: dir, Runner: missing}
+	for i := 0; i < 2; i++ {
+		res, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"pattern": "needle"})
+		require.NoError(t, err)
+		assert.Equal(t, "builtin", res.Data["backend"])
+		assert.Len(t, res.Data["matches"], 1)
+	}
+	assert.Len(t, missing.calls, 1)
+}
+
+func TestGrepToolDeclaresRipgrepOnlyWithRunner(t *testing.T) {
+	tool := &GrepTool{BasePath: "/ws"}
+	assert.Empty(t, tool.Permissions().Permissions.Executables)
+}
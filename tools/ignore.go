@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read from every directory a search visits. Their rules
+// use .gitignore syntax; .relurpifyignore hides files from the agent without
+// touching what git tracks.
+var ignoreFiles = []string{".gitignore", ".relurpifyignore"}
+
+// ignoreRule is one compiled line of an ignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher answers whether paths below root are ignored, loading the
+// ignore files of each directory the first time it is consulted. Rules in
+// deeper directories take precedence, and the last matching rule wins, as in
+// git.
+type ignoreMatcher struct {
+	root  string
+	rules map[string][]ignoreRule
+}
+
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	return &ignoreMatcher{root: filepath.Clean(root), rules: make(map[string][]ignoreRule)}
+}
+
+// Ignored reports whether path, or a directory between it and the root, is
+// excluded. Paths outside the root are never ignored.
+func (m *ignoreMatcher) Ignored(path string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		last := i == len(parts)-1
+		if m.matches(parts[:i+1], isDir || !last) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the rules of every directory above the path given as
+// slash-separated parts relative to the root.
+func (m *ignoreMatcher) matches(parts []string, isDir bool) bool {
+	ignored := false
+	dir := m.root
+	for depth := 0; depth < len(parts); depth++ {
+		rel := strings.Join(parts[depth:], "/")
+		for _, rule := range m.load(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[depth])
+	}
+	return ignored
+}
+
+func (m *ignoreMatcher) load(dir string) []ignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if rule, ok := parseIgnoreRule(line); ok {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// parseIgnoreRule compiles one .gitignore line. Patterns without a slash
+// match at any depth; a leading or inner slash anchors them to the file's
+// directory.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates gitignore wildcards: * and ? stay within a path
+// segment, ** spans segments, and [...] classes pass through.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lexcodex/relurpify/framework"
@@ -36,6 +37,8 @@ type SymbolInformation struct {
 	Name     string `json:"name"`
 	Kind     string `json:"kind"`
 	Location string `json:"location"`
+	// Language is the server's language key, set on proxy search results.
+	Language string `json:"language,omitempty"`
 }
 
 // LSPClient defines required operations for the language server proxy.
@@ -88,12 +91,19 @@ type FormatRequest struct {
 	Code string
 }
 
-// Proxy manages multiple LSP clients.
+// Proxy manages multiple LSP clients. Clients registered with RegisterLazy
+// start on the first request for one of their extensions, so a session only
+// pays for the language servers it actually uses.
 type Proxy struct {
 	mu      sync.RWMutex
 	clients map[string]LSPClient
-	cache   map[string]cacheEntry
-	ttl     time.Duration
+	lazy    map[string]*lazyLSPClient
+	// languages names the language each started client serves.
+	languages map[LSPClient]string
+	cacheMu   sync.Mutex
+	cache     map[string]cacheEntry
+	ttl       time.Duration
+	symbols   symbolIndex
 }
 
 type cacheEntry struct {
@@ -101,15 +111,28 @@ type cacheEntry struct {
 	expiration time.Time
 }
 
+// lazyLSPClient starts one language server shared by several extensions.
+type lazyLSPClient struct {
+	once       sync.Once
+	attempted  atomic.Bool
+	language   string
+	extensions []string
+	start      func() (LSPClient, error)
+	client     LSPClient
+	err        error
+}
+
 // NewProxy creates a proxy instance.
 func NewProxy(ttl time.Duration) *Proxy {
 	if ttl == 0 {
 		ttl = time.Minute
 	}
 	return &Proxy{
-		clients: make(map[string]LSPClient),
-		cache:   make(map[string]cacheEntry),
-		ttl:     ttl,
+		clients:   make(map[string]LSPClient),
+		lazy:      make(map[string]*lazyLSPClient),
+		languages: make(map[LSPClient]string),
+		cache:     make(map[string]cacheEntry),
+		ttl:       ttl,
 	}
 }
 
@@ -118,22 +141,115 @@ func (p *Proxy) Register(language string, client LSPClient) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.clients[language] = client
+	p.languages[client] = language
+}
+
+// RegisterLazy defers start until a file with one of extensions is first
+// requested. A failed start is remembered and reported on every request.
+// The server is named after its first extension; see RegisterLanguage.
+func (p *Proxy) RegisterLazy(extensions []string, start func() (LSPClient, error)) {
+	language := ""
+	if len(extensions) > 0 {
+		language = strings.TrimPrefix(extensions[0], ".")
+	}
+	p.RegisterLanguage(language, extensions, start)
+}
+
+// RegisterLanguage is RegisterLazy for a server named by its manifest
+// language key, which labels its symbols and its symbol index.
+func (p *Proxy) RegisterLanguage(language string, extensions []string, start func() (LSPClient, error)) {
+	entry := &lazyLSPClient{language: language, extensions: extensions, start: start}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ext := range extensions {
+		p.lazy[strings.TrimPrefix(ext, ".")] = entry
+	}
+}
+
+// Activated reports whether every lazily registered server has been started
+// (or failed to start).
+func (p *Proxy) Activated() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, entry := range p.lazy {
+		if !entry.attempted.Load() {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *Proxy) clientForFile(file string) (LSPClient, error) {
 	ext := strings.TrimPrefix(filepath.Ext(file), ".")
 	p.mu.RLock()
-	defer p.mu.RUnlock()
 	client, ok := p.clients[ext]
-	if !ok {
+	entry := p.lazy[ext]
+	p.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+	if entry == nil {
 		return nil, fmt.Errorf("no LSP client for extension %s", ext)
 	}
-	return client, nil
+	return p.activate(entry)
+}
+
+// activate starts entry once and promotes it to a regular client. Failed
+// entries stay registered so later requests report the start error.
+func (p *Proxy) activate(entry *lazyLSPClient) (LSPClient, error) {
+	entry.once.Do(func() {
+		entry.client, entry.err = entry.start()
+		entry.attempted.Store(true)
+		if entry.err != nil {
+			return
+		}
+		p.mu.Lock()
+		for _, ext := range entry.extensions {
+			ext = strings.TrimPrefix(ext, ".")
+			delete(p.lazy, ext)
+			p.clients[ext] = entry.client
+		}
+		p.languages[entry.client] = entry.language
+		p.mu.Unlock()
+		p.serverStarted(entry.language)
+	})
+	if entry.err != nil {
+		return nil, fmt.Errorf("start language server: %w", entry.err)
+	}
+	return entry.client, nil
+}
+
+// allClients starts any pending servers and returns each distinct client,
+// for workspace-wide queries that cannot be routed by extension.
+func (p *Proxy) allClients() []LSPClient {
+	p.mu.RLock()
+	pending := make(map[*lazyLSPClient]struct{})
+	for _, entry := range p.lazy {
+		if !entry.attempted.Load() {
+			pending[entry] = struct{}{}
+		}
+	}
+	p.mu.RUnlock()
+	for entry := range pending {
+		_, _ = p.activate(entry)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := make(map[LSPClient]struct{})
+	var clients []LSPClient
+	for _, client := range p.clients {
+		if _, ok := seen[client]; ok {
+			continue
+		}
+		seen[client] = struct{}{}
+		clients = append(clients, client)
+	}
+	return clients
 }
 
 func (p *Proxy) cached(key string, fetch func() (interface{}, error)) (interface{}, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
 	if entry, ok := p.cache[key]; ok && time.Now().Before(entry.expiration) {
 		return entry.value, nil
 	}
@@ -145,6 +261,30 @@ func (p *Proxy) cached(key string, fetch func() (interface{}, error)) (interface
 	return val, nil
 }
 
+// ClearCache drops every cached response. Results for one file can depend on
+// others (references, diagnostics), so a change anywhere clears everything.
+func (p *Proxy) ClearCache() {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cache = make(map[string]cacheEntry)
+}
+
+// LSPTools returns every LSP-backed tool sharing proxy. Edits made by the
+// rename and code action tools are confined to basePath.
+func LSPTools(proxy *Proxy, basePath string) []framework.Tool {
+	return []framework.Tool{
+		&DefinitionTool{Proxy: proxy},
+		&ReferencesTool{Proxy: proxy},
+		&HoverTool{Proxy: proxy},
+		&DiagnosticsTool{Proxy: proxy},
+		&SearchSymbolsTool{Proxy: proxy},
+		&DocumentSymbolsTool{Proxy: proxy},
+		&FormatTool{Proxy: proxy},
+		NewRenameSymbolTool(proxy, basePath),
+		NewCodeActionsTool(proxy, basePath),
+	}
+}
+
 // DefinitionTool implements the GetDefinition tool.
 type DefinitionTool struct {
 	Proxy *Proxy
@@ -221,6 +361,9 @@ func (t *DefinitionTool) IsAvailable(ctx context.Context, state *framework.Conte
 	return t.Proxy != nil
 }
 
+// Activated reports whether the proxy's language servers have started.
+func (t *DefinitionTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *DefinitionTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -287,6 +430,8 @@ func (t *ReferencesTool) IsAvailable(ctx context.Context, state *framework.Conte
 	return t.Proxy != nil
 }
 
+func (t *ReferencesTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *ReferencesTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -352,6 +497,8 @@ func (t *HoverTool) IsAvailable(ctx context.Context, state *framework.Context) b
 	return t.Proxy != nil
 }
 
+func (t *HoverTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *HoverTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -405,6 +552,8 @@ func (t *DiagnosticsTool) IsAvailable(ctx context.Context, state *framework.Cont
 	return t.Proxy != nil
 }
 
+func (t *DiagnosticsTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *DiagnosticsTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -432,17 +581,7 @@ func (t *SearchSymbolsTool) Parameters() []framework.ToolParameter {
 func (t *SearchSymbolsTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
 	query := fmt.Sprint(args["query"])
 	resAny, err := t.Proxy.cached("symbols:"+query, func() (interface{}, error) {
-		t.Proxy.mu.RLock()
-		defer t.Proxy.mu.RUnlock()
-		var combined []SymbolInformation
-		for _, client := range t.Proxy.clients {
-			items, err := client.SearchSymbols(ctx, query)
-			if err != nil {
-				return nil, err
-			}
-			combined = append(combined, items...)
-		}
-		return combined, nil
+		return t.Proxy.SearchSymbols(ctx, query)
 	})
 	if err != nil {
 		return nil, err
@@ -454,6 +593,8 @@ func (t *SearchSymbolsTool) IsAvailable(ctx context.Context, state *framework.Co
 	return t.Proxy != nil
 }
 
+func (t *SearchSymbolsTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *SearchSymbolsTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -507,6 +648,8 @@ func (t *DocumentSymbolsTool) IsAvailable(ctx context.Context, state *framework.
 	return t.Proxy != nil
 }
 
+func (t *DocumentSymbolsTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *DocumentSymbolsTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemList)}
 }
@@ -561,6 +704,8 @@ func (t *FormatTool) IsAvailable(ctx context.Context, state *framework.Context)
 	return t.Proxy != nil
 }
 
+func (t *FormatTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
 func (t *FormatTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet("", framework.FileSystemRead, framework.FileSystemWrite)}
 }
@@ -0,0 +1,522 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Range is a span within a document. Like LSP positions, lines and
+// characters are zero-based, characters count UTF-16 code units, and End is
+// exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextEdit replaces Range with NewText.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit groups text edits by file path.
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+// Files returns the edited paths in sorted order.
+func (e WorkspaceEdit) Files() []string {
+	files := make([]string, 0, len(e.Changes))
+	for file, edits := range e.Changes {
+		if len(edits) > 0 {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (e *WorkspaceEdit) merge(other WorkspaceEdit) {
+	if e.Changes == nil {
+		e.Changes = make(map[string][]TextEdit)
+	}
+	for file, edits := range other.Changes {
+		e.Changes[file] = append(e.Changes[file], edits...)
+	}
+}
+
+// RenameRequest describes a rename at Position.
+type RenameRequest struct {
+	File     string
+	Position Position
+	NewName  string
+}
+
+// CodeActionRequest asks for fixes and refactorings within Range. Kinds
+// narrows the result, e.g. "quickfix" or "refactor.extract".
+type CodeActionRequest struct {
+	File  string
+	Range Range
+	Kinds []string
+}
+
+// CodeAction is a fix or refactoring offered by the language server. Edit is
+// nil when the server only applies the action by running Command.
+type CodeAction struct {
+	Title     string         `json:"title"`
+	Kind      string         `json:"kind,omitempty"`
+	Preferred bool           `json:"preferred,omitempty"`
+	Disabled  string         `json:"disabled,omitempty"`
+	Edit      *WorkspaceEdit `json:"edit,omitempty"`
+	Command   string         `json:"command,omitempty"`
+	// raw is the server's original action, sent back when resolving it.
+	raw json.RawMessage
+}
+
+// LSPEditClient is implemented by clients that can compute workspace edits.
+// Edits are returned rather than written so callers can apply them through
+// the permission-checked file tools.
+type LSPEditClient interface {
+	Rename(ctx context.Context, req RenameRequest) (WorkspaceEdit, error)
+	CodeActions(ctx context.Context, req CodeActionRequest) ([]CodeAction, error)
+	// ResolveCodeAction returns the full edit for action, running its
+	// command when the server delivers edits through workspace/applyEdit.
+	ResolveCodeAction(ctx context.Context, action CodeAction) (WorkspaceEdit, error)
+}
+
+// LSPDocumentSyncer is implemented by clients that keep documents open on the
+// server; FilesChanged makes them re-read files changed on disk.
+type LSPDocumentSyncer interface {
+	FilesChanged(ctx context.Context, files []string) error
+}
+
+// editClientForFile returns the edit-capable client serving file.
+func (p *Proxy) editClientForFile(file string) (LSPEditClient, error) {
+	client, err := p.clientForFile(file)
+	if err != nil {
+		return nil, err
+	}
+	editor, ok := client.(LSPEditClient)
+	if !ok {
+		return nil, fmt.Errorf("language server for %s does not support edits", filepath.Base(file))
+	}
+	return editor, nil
+}
+
+// FilesChanged drops cached responses and tells every started client that
+// files changed on disk. Servers that have not started yet are left alone;
+// they read the files fresh when they do.
+func (p *Proxy) FilesChanged(ctx context.Context, files []string) {
+	p.ClearCache()
+	p.mu.RLock()
+	seen := make(map[LSPClient]struct{})
+	var syncers []LSPDocumentSyncer
+	for _, client := range p.clients {
+		if _, ok := seen[client]; ok {
+			continue
+		}
+		seen[client] = struct{}{}
+		if syncer, ok := client.(LSPDocumentSyncer); ok {
+			syncers = append(syncers, syncer)
+		}
+	}
+	p.mu.RUnlock()
+	for _, syncer := range syncers {
+		_ = syncer.FilesChanged(ctx, files)
+	}
+	p.refreshSymbols(ctx, files)
+}
+
+// lspEditTool holds what the editing tools share: the proxy and the file
+// tools that apply edits under the agent's permissions.
+type lspEditTool struct {
+	Proxy    *Proxy
+	BasePath string
+	manager  *framework.PermissionManager
+	agentID  string
+	spec     *framework.AgentRuntimeSpec
+}
+
+func (t *lspEditTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *lspEditTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *lspEditTool) Category() string { return "lsp" }
+
+func (t *lspEditTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.Proxy != nil
+}
+
+func (t *lspEditTool) Activated() bool { return t.Proxy == nil || t.Proxy.Activated() }
+
+func (t *lspEditTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemWrite)}
+}
+
+func (t *lspEditTool) editClient(ctx context.Context, file string) (LSPEditClient, error) {
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, file); err != nil {
+			return nil, err
+		}
+	}
+	return t.Proxy.editClientForFile(file)
+}
+
+// apply writes edit (unless dryRun) and describes it for the tool result.
+func (t *lspEditTool) apply(ctx context.Context, state *framework.Context, edit WorkspaceEdit, dryRun bool) (map[string]interface{}, error) {
+	files := edit.Files()
+	if len(files) == 0 {
+		return nil, errors.New("language server returned no edits")
+	}
+	data := map[string]interface{}{
+		"files":   t.relativePaths(files),
+		"edits":   edit.count(),
+		"applied": false,
+	}
+	if dryRun {
+		preview := make(map[string][]TextEdit, len(files))
+		for _, file := range files {
+			preview[t.relativePath(file)] = edit.Changes[file]
+		}
+		data["changes"] = preview
+		return data, nil
+	}
+	read := &ReadFileTool{BasePath: t.BasePath, manager: t.manager, agentID: t.agentID, spec: t.spec}
+	write := &WriteFileTool{BasePath: t.BasePath, manager: t.manager, agentID: t.agentID, spec: t.spec}
+	if err := applyWorkspaceEdit(ctx, state, edit, read, write); err != nil {
+		return nil, err
+	}
+	t.Proxy.FilesChanged(ctx, files)
+	data["applied"] = true
+	return data, nil
+}
+
+func (t *lspEditTool) relativePaths(files []string) []string {
+	out := make([]string, len(files))
+	for i, file := range files {
+		out[i] = t.relativePath(file)
+	}
+	return out
+}
+
+func (t *lspEditTool) relativePath(file string) string {
+	if t.BasePath == "" {
+		return file
+	}
+	if rel, err := filepath.Rel(t.BasePath, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+func (e WorkspaceEdit) count() int {
+	n := 0
+	for _, edits := range e.Changes {
+		n += len(edits)
+	}
+	return n
+}
+
+// applyWorkspaceEdit applies edit through file_read and file_write so the
+// agent's filesystem permissions and file matrix cover every touched file.
+// Every file is read and edited in memory before anything is written, and
+// files already written are restored if a later write fails. The reads and
+// writes go through state, so the file hashes stay current for later edits.
+func applyWorkspaceEdit(ctx context.Context, state *framework.Context, edit WorkspaceEdit, read *ReadFileTool, write *WriteFileTool) error {
+	files := edit.Files()
+	originals := make(map[string]string, len(files))
+	updated := make(map[string]string, len(files))
+	for _, file := range files {
+		res, err := read.Execute(ctx, state, map[string]interface{}{"path": file})
+		if err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+		if !res.Success {
+			return fmt.Errorf("read %s: %s", file, res.Error)
+		}
+		content := fmt.Sprint(res.Data["content"])
+		next, err := applyTextEdits(content, edit.Changes[file])
+		if err != nil {
+			return fmt.Errorf("edit %s: %w", file, err)
+		}
+		originals[file] = content
+		updated[file] = next
+	}
+	var written []string
+	for _, file := range files {
+		res, err := write.Execute(ctx, state, map[string]interface{}{"path": file, "content": updated[file]})
+		if err == nil && !res.Success {
+			err = errors.New(res.Error)
+		}
+		if err != nil {
+			for _, done := range written {
+				if os.WriteFile(done, []byte(originals[done]), 0o644) == nil {
+					recordFileHash(state, done, []byte(originals[done]))
+				}
+			}
+			return fmt.Errorf("write %s: %w", file, err)
+		}
+		written = append(written, file)
+	}
+	return nil
+}
+
+// applyTextEdits applies non-overlapping edits to content.
+func applyTextEdits(content string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	lineStarts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	spans := make([]span, 0, len(edits))
+	for _, edit := range edits {
+		start, err := byteOffset(content, lineStarts, edit.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := byteOffset(content, lineStarts, edit.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("edit range ends before it starts at line %d", edit.Range.Start.Line+1)
+		}
+		spans = append(spans, span{start: start, end: end, text: edit.NewText})
+	}
+	// Stable so several inserts at one position keep the server's order.
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return "", errors.New("overlapping edits")
+		}
+		b.WriteString(content[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+// byteOffset converts an LSP position to a byte offset. Characters past the
+// end of a line clamp to the line end, as the specification requires.
+func byteOffset(content string, lineStarts []int, pos Position) (int, error) {
+	if pos.Line < 0 || pos.Character < 0 {
+		return 0, fmt.Errorf("invalid position %d:%d", pos.Line, pos.Character)
+	}
+	if pos.Line >= len(lineStarts) {
+		if pos.Line == len(lineStarts) && pos.Character == 0 {
+			return len(content), nil
+		}
+		return 0, fmt.Errorf("line %d is past the end of the file", pos.Line+1)
+	}
+	offset := lineStarts[pos.Line]
+	units := 0
+	for offset < len(content) && content[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(content[offset:])
+		units++
+		if r >= 0x10000 {
+			units++
+		}
+		offset += size
+	}
+	return offset, nil
+}
+
+// RenameSymbolTool renames a symbol across the workspace using the language
+// server's semantic rename.
+type RenameSymbolTool struct {
+	lspEditTool
+}
+
+// NewRenameSymbolTool returns lsp_rename_symbol for files under basePath.
+func NewRenameSymbolTool(proxy *Proxy, basePath string) *RenameSymbolTool {
+	return &RenameSymbolTool{lspEditTool{Proxy: proxy, BasePath: basePath}}
+}
+
+func (t *RenameSymbolTool) Name() string { return "lsp_rename_symbol" }
+func (t *RenameSymbolTool) Description() string {
+	return "Renames the symbol at a position in every file that references it."
+}
+func (t *RenameSymbolTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "file", Type: "string", Description: "File containing the symbol", Required: true},
+		{Name: "line", Type: "int", Description: "Zero-based line of the symbol", Required: true},
+		{Name: "character", Type: "int", Description: "Zero-based character offset within the line", Required: true},
+		{Name: "new_name", Type: "string", Description: "New symbol name", Required: true},
+		{Name: "dry_run", Type: "bool", Description: "Return the edits without writing them", Required: false, Default: false},
+	}
+}
+func (t *RenameSymbolTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	file := preparePath(t.BasePath, fmt.Sprint(args["file"]))
+	newName := strings.TrimSpace(fmt.Sprint(args["new_name"]))
+	if newName == "" || args["new_name"] == nil {
+		return nil, errors.New("new_name required")
+	}
+	client, err := t.editClient(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := client.Rename(ctx, RenameRequest{
+		File:     file,
+		Position: Position{Line: toInt(args["line"]), Character: toInt(args["character"])},
+		NewName:  newName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dryRun, _ := args["dry_run"].(bool)
+	data, err := t.apply(ctx, state, edit, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+// CodeActionsTool lists the language server's quick-fixes and refactorings
+// for a range and applies one on request.
+type CodeActionsTool struct {
+	lspEditTool
+}
+
+// NewCodeActionsTool returns lsp_code_actions for files under basePath.
+func NewCodeActionsTool(proxy *Proxy, basePath string) *CodeActionsTool {
+	return &CodeActionsTool{lspEditTool{Proxy: proxy, BasePath: basePath}}
+}
+
+func (t *CodeActionsTool) Name() string { return "lsp_code_actions" }
+func (t *CodeActionsTool) Description() string {
+	return "Lists quick-fixes and refactorings for a range; pass apply to perform one."
+}
+func (t *CodeActionsTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "file", Type: "string", Description: "File path", Required: true},
+		{Name: "line", Type: "int", Description: "Zero-based start line", Required: true},
+		{Name: "character", Type: "int", Description: "Zero-based start character", Required: false},
+		{Name: "end_line", Type: "int", Description: "Zero-based end line (defaults to line)", Required: false},
+		{Name: "end_character", Type: "int", Description: "End character (defaults to the end of end_line)", Required: false},
+		{Name: "kind", Type: "string", Description: "Only return actions of this kind, e.g. quickfix", Required: false},
+		{Name: "apply", Type: "string", Description: "Index or title of the action to apply", Required: false},
+		{Name: "dry_run", Type: "bool", Description: "With apply, return the edits without writing them", Required: false, Default: false},
+	}
+}
+func (t *CodeActionsTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	file := preparePath(t.BasePath, fmt.Sprint(args["file"]))
+	client, err := t.editClient(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	req := CodeActionRequest{File: file}
+	req.Range.Start = Position{Line: toInt(args["line"]), Character: toInt(args["character"])}
+	req.Range.End = Position{Line: req.Range.Start.Line, Character: lineEndCharacter}
+	if _, ok := args["end_line"]; ok {
+		req.Range.End.Line = toInt(args["end_line"])
+	}
+	if _, ok := args["end_character"]; ok {
+		req.Range.End.Character = toInt(args["end_character"])
+	}
+	if kind, ok := args["kind"].(string); ok && kind != "" {
+		req.Kinds = []string{kind}
+	}
+	actions, err := client.CodeActions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	choice := ""
+	if args["apply"] != nil {
+		choice = strings.TrimSpace(fmt.Sprint(args["apply"]))
+	}
+	if choice == "" {
+		listed := make([]map[string]interface{}, len(actions))
+		for i, action := range actions {
+			entry := map[string]interface{}{"index": i, "title": action.Title}
+			if action.Kind != "" {
+				entry["kind"] = action.Kind
+			}
+			if action.Preferred {
+				entry["preferred"] = true
+			}
+			if action.Disabled != "" {
+				entry["disabled"] = action.Disabled
+			}
+			listed[i] = entry
+		}
+		return &framework.ToolResult{Success: true, Data: map[string]interface{}{"actions": listed}}, nil
+	}
+	action, err := selectCodeAction(actions, choice)
+	if err != nil {
+		return nil, err
+	}
+	if action.Disabled != "" {
+		return nil, fmt.Errorf("code action %q is disabled: %s", action.Title, action.Disabled)
+	}
+	dryRun, _ := args["dry_run"].(bool)
+	if dryRun && action.Edit == nil {
+		// Resolving a command-based action runs it on the server, so there
+		// is nothing side-effect free to preview.
+		return nil, fmt.Errorf("code action %q has no edit to preview; it runs %s on the server", action.Title, action.Command)
+	}
+	edit, err := client.ResolveCodeAction(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.apply(ctx, state, edit, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	data["action"] = action.Title
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+// lineEndCharacter is past the end of any line; byteOffset clamps it.
+const lineEndCharacter = 1 << 30
+
+// selectCodeAction finds an action by index, exact title, or unique
+// case-insensitive title prefix.
+func selectCodeAction(actions []CodeAction, choice string) (CodeAction, error) {
+	if idx, err := strconv.Atoi(choice); err == nil {
+		if idx < 0 || idx >= len(actions) {
+			return CodeAction{}, fmt.Errorf("code action %d out of range (%d available)", idx, len(actions))
+		}
+		return actions[idx], nil
+	}
+	var matches []CodeAction
+	for _, action := range actions {
+		if action.Title == choice {
+			return action, nil
+		}
+		if strings.HasPrefix(strings.ToLower(action.Title), strings.ToLower(choice)) {
+			matches = append(matches, action)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return CodeAction{}, fmt.Errorf("no code action matches %q", choice)
+	default:
+		return CodeAction{}, fmt.Errorf("%d code actions match %q; use the index", len(matches), choice)
+	}
+}
@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// fakeEditClient returns canned edits and records synced files.
+type fakeEditClient struct {
+	fakeLSPClient
+	rename   WorkspaceEdit
+	actions  []CodeAction
+	resolved WorkspaceEdit
+	changed  []string
+}
+
+func (c *fakeEditClient) Rename(ctx context.Context, req RenameRequest) (WorkspaceEdit, error) {
+	return c.rename, nil
+}
+func (c *fakeEditClient) CodeActions(ctx context.Context, req CodeActionRequest) ([]CodeAction, error) {
+	return c.actions, nil
+}
+func (c *fakeEditClient) ResolveCodeAction(ctx context.Context, action CodeAction) (WorkspaceEdit, error) {
+	if action.Edit != nil {
+		return *action.Edit, nil
+	}
+	return c.resolved, nil
+}
+func (c *fakeEditClient) FilesChanged(ctx context.Context, files []string) error {
+	c.changed = append(c.changed, files...)
+	return nil
+}
+
+func replaceAt(line, start, end int, text string) TextEdit {
+	return TextEdit{Range: Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}, NewText: text}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRenameSymbolToolAppliesWorkspaceEdit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a\n\nfunc Old() {}\n",
+		"b.go": "package a\n\nvar _ = Old\n",
+	})
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	client := &fakeEditClient{rename: WorkspaceEdit{Changes: map[string][]TextEdit{
+		a: {replaceAt(2, 5, 8, "New")},
+		b: {replaceAt(2, 8, 11, "New")},
+	}}}
+	proxy := NewProxy(0)
+	proxy.Register("go", client)
+	tool := NewRenameSymbolTool(proxy, dir)
+	ctx := context.Background()
+	args := map[string]interface{}{"file": "a.go", "line": 2, "character": 6, "new_name": "New", "dry_run": true}
+
+	res, err := tool.Execute(ctx, framework.NewContext(), args)
+	require.NoError(t, err)
+	assert.Equal(t, false, res.Data["applied"])
+	assert.Contains(t, res.Data["changes"], "b.go")
+	assert.Equal(t, "package a\n\nfunc Old() {}\n", readFile(t, a), "dry runs leave files alone")
+
+	args["dry_run"] = false
+	res, err = tool.Execute(ctx, framework.NewContext(), args)
+	require.NoError(t, err)
+	assert.Equal(t, true, res.Data["applied"])
+	assert.Equal(t, []string{"a.go", "b.go"}, res.Data["files"])
+	assert.Equal(t, "package a\n\nfunc New() {}\n", readFile(t, a))
+	assert.Equal(t, "package a\n\nvar _ = New\n", readFile(t, b))
+	assert.Equal(t, []string{a, b}, client.changed, "the server is told to re-read edited files")
+}
+
+func TestRenameSymbolToolRollsBackOnDeniedWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.go": "Old\n", "b.go": "Old\n"})
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	client := &fakeEditClient{rename: WorkspaceEdit{Changes: map[string][]TextEdit{
+		a: {replaceAt(0, 0, 3, "New")},
+		b: {replaceAt(0, 0, 3, "New")},
+	}}}
+	proxy := NewProxy(0)
+	proxy.Register("go", client)
+	manager, err := framework.NewPermissionManager(dir, &framework.PermissionSet{FileSystem: []framework.FileSystemPermission{
+		{Action: framework.FileSystemRead, Path: filepath.Join(dir, "**")},
+		{Action: framework.FileSystemWrite, Path: a},
+	}}, nil, nil)
+	require.NoError(t, err)
+	tool := NewRenameSymbolTool(proxy, dir)
+	tool.SetPermissionManager(manager, "agent")
+
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{
+		"file": "a.go", "line": 0, "character": 0, "new_name": "New",
+	})
+	require.Error(t, err)
+	assert.Equal(t, "Old\n", readFile(t, a), "earlier writes are restored")
+	assert.Equal(t, "Old\n", readFile(t, b))
+	assert.Empty(t, client.changed)
+}
+
+func TestRenameSymbolToolKeepsFileHashesCurrent(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.go": "Old\n"})
+	a := filepath.Join(dir, "a.go")
+	proxy := NewProxy(0)
+	proxy.Register("go", &fakeEditClient{rename: WorkspaceEdit{Changes: map[string][]TextEdit{a: {replaceAt(0, 0, 3, "New")}}}})
+	ctx := context.Background()
+	state := framework.NewContext()
+
+	_, err := (&ReadFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "a.go"})
+	require.NoError(t, err)
+	_, err = NewRenameSymbolTool(proxy, dir).Execute(ctx, state, map[string]interface{}{"file": "a.go", "line": 0, "character": 0, "new_name": "New"})
+	require.NoError(t, err)
+
+	res, err := (&WriteFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": "a.go", "content": "Newer\n"})
+	require.NoError(t, err)
+	assert.True(t, res.Success, "the rename's own write is not a stale-read conflict: %s", res.Error)
+	assert.Equal(t, "Newer\n", readFile(t, a))
+}
+
+func TestCodeActionsToolListsAndApplies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n\nimport \"os\"\n"})
+	file := filepath.Join(dir, "main.go")
+	removeImport := WorkspaceEdit{Changes: map[string][]TextEdit{file: {{
+		Range: Range{Start: Position{Line: 2}, End: Position{Line: 3}},
+	}}}}
+	client := &fakeEditClient{
+		actions: []CodeAction{
+			{Title: "Remove unused import", Kind: "quickfix", Preferred: true, Edit: &removeImport},
+			{Title: "Organize imports", Kind: "source.organizeImports", Command: "gopls.organize_imports"},
+			{Title: "Extract function", Kind: "refactor.extract", Disabled: "no selection"},
+		},
+	}
+	proxy := NewProxy(0)
+	proxy.Register("go", client)
+	tool := NewCodeActionsTool(proxy, dir)
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "main.go", "line": 2})
+	require.NoError(t, err)
+	listed := res.Data["actions"].([]map[string]interface{})
+	require.Len(t, listed, 3)
+	assert.Equal(t, "Remove unused import", listed[0]["title"])
+	assert.Equal(t, true, listed[0]["preferred"])
+	assert.Equal(t, "no selection", listed[2]["disabled"])
+
+	_, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "main.go", "line": 2, "apply": "2"})
+	assert.ErrorContains(t, err, "disabled")
+	_, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "main.go", "line": 2, "apply": "organize", "dry_run": true})
+	assert.ErrorContains(t, err, "no edit to preview")
+
+	res, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "main.go", "line": 2, "apply": "remove unused"})
+	require.NoError(t, err)
+	assert.Equal(t, "Remove unused import", res.Data["action"])
+	assert.Equal(t, "package main\n\n", readFile(t, file))
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	content := "héllo 😀 world\nsecond\n"
+	out, err := applyTextEdits(content, []TextEdit{
+		replaceAt(0, 9, 14, "there"), // the emoji is two UTF-16 units
+		replaceAt(1, 0, 0, ">> "),
+		replaceAt(1, 6, 99, "!"), // past the line end clamps
+		{Range: Range{Start: Position{Line: 2}, End: Position{Line: 2}}, NewText: "third\n"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "héllo 😀 there\n>> second!\nthird\n", out)
+
+	_, err = applyTextEdits(content, []TextEdit{replaceAt(0, 0, 5, "a"), replaceAt(0, 3, 6, "b")})
+	assert.ErrorContains(t, err, "overlapping")
+	_, err = applyTextEdits(content, []TextEdit{replaceAt(5, 0, 1, "x")})
+	assert.Error(t, err)
+}
@@ -15,6 +15,8 @@ func TestLSPToolPermissionsValidate(t *testing.T) {
 		&SearchSymbolsTool{},
 		&DocumentSymbolsTool{},
 		&FormatTool{},
+		NewRenameSymbolTool(nil, ""),
+		NewCodeActionsTool(nil, ""),
 	}
 	for _, tool := range tools {
 		if err := tool.Permissions().Validate(); err != nil {
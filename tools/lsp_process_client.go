@@ -26,6 +26,9 @@ type ProcessLSPConfig struct {
 	Args       []string
 	RootDir    string
 	LanguageID string
+	// Remote runs the server on a remote machine over ssh, with RootDir
+	// under Remote.Local; see lspCommand.
+	Remote *framework.RemoteHost
 }
 
 type processLSPClient struct {
@@ -37,6 +40,8 @@ type processLSPClient struct {
 	mu          sync.Mutex
 	openedFiles map[protocol.DocumentURI]bool
 	diagnostics map[protocol.DocumentURI][]protocol.Diagnostic
+	editMu      sync.Mutex
+	collecting  *WorkspaceEdit
 	logCh       chan string
 	manager     *framework.PermissionManager
 	agentID     string
@@ -96,8 +101,11 @@ func NewProcessLSPClientWithPermissions(cfg ProcessLSPConfig, manager *framework
 			}
 		}
 	}
-	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
-	cmd.Dir = absRoot
+	cmd, codec, err := lspCommand(ctx, cfg, absRoot)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -116,7 +124,7 @@ func NewProcessLSPClientWithPermissions(cfg ProcessLSPConfig, manager *framework
 	}
 
 	rwc := &stdioReadWriteCloser{reader: stdout, writer: stdin}
-	stream := jsonrpc2.NewBufferedStream(rwc, jsonrpc2.VSCodeObjectCodec{})
+	stream := jsonrpc2.NewBufferedStream(rwc, codec)
 
 	client := &processLSPClient{
 		cfg:         cfg,
@@ -133,6 +141,9 @@ func NewProcessLSPClientWithPermissions(cfg ProcessLSPConfig, manager *framework
 
 	handler := jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
 		if !req.Notif {
+			if req.Method == "workspace/applyEdit" && req.Params != nil {
+				return client.handleApplyEdit(*req.Params)
+			}
 			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not handled"}
 		}
 		switch req.Method {
@@ -204,9 +215,32 @@ func (c *processLSPClient) initialize(ctx context.Context, root string) error {
 				DocumentSymbol:     &protocol.DocumentSymbolClientCapabilities{},
 				Formatting:         &protocol.DocumentFormattingClientCapabilities{},
 				PublishDiagnostics: &protocol.PublishDiagnosticsClientCapabilities{},
+				Rename:             &protocol.RenameClientCapabilities{},
+				CodeAction: &protocol.CodeActionClientCapabilities{
+					CodeActionLiteralSupport: &protocol.CodeActionClientCapabilitiesLiteralSupport{
+						CodeActionKind: &protocol.CodeActionClientCapabilitiesKind{
+							ValueSet: []protocol.CodeActionKind{
+								protocol.QuickFix,
+								protocol.Refactor,
+								protocol.RefactorExtract,
+								protocol.RefactorInline,
+								protocol.RefactorRewrite,
+								protocol.Source,
+								protocol.SourceOrganizeImports,
+							},
+						},
+					},
+					IsPreferredSupport: true,
+					DisabledSupport:    true,
+					DataSupport:        true,
+					ResolveSupport:     &protocol.CodeActionClientCapabilitiesResolveSupport{Properties: []string{"edit"}},
+				},
 			},
 			Workspace: &protocol.WorkspaceClientCapabilities{
-				Symbol: &protocol.WorkspaceClientCapabilitiesSymbol{},
+				ApplyEdit:      true,
+				WorkspaceEdit:  &protocol.WorkspaceClientCapabilitiesWorkspaceEdit{DocumentChanges: true},
+				Symbol:         &protocol.WorkspaceClientCapabilitiesSymbol{},
+				ExecuteCommand: &protocol.ExecuteCommandClientCapabilities{},
 			},
 		},
 	}
@@ -294,10 +328,11 @@ func (c *processLSPClient) GetDefinition(ctx context.Context, req DefinitionRequ
 			Position:     protocol.Position{Line: uint32(req.Position.Line), Character: uint32(req.Position.Character)},
 		},
 	}
-	var resp []protocol.Location
-	if err := c.conn.Call(ctx, "textDocument/definition", params, &resp); err != nil {
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/definition", params, &raw); err != nil {
 		return DefinitionResult{}, err
 	}
+	resp := decodeLocations(raw)
 	if len(resp) == 0 {
 		return DefinitionResult{}, errors.New("definition not found")
 	}
@@ -348,13 +383,16 @@ func (c *processLSPClient) GetHover(ctx context.Context, req HoverRequest) (Hove
 			Position:     protocol.Position{Line: uint32(req.Position.Line), Character: uint32(req.Position.Character)},
 		},
 	}
-	var resp protocol.Hover
+	var resp struct {
+		Contents json.RawMessage `json:"contents"`
+	}
 	if err := c.conn.Call(ctx, "textDocument/hover", params, &resp); err != nil {
 		return HoverResult{}, err
 	}
+	text := hoverText(resp.Contents)
 	return HoverResult{
-		TypeInfo: fmt.Sprint(resp.Contents.Value),
-		Docs:     fmt.Sprint(resp.Contents.Value),
+		TypeInfo: text,
+		Docs:     text,
 	}, nil
 }
 
@@ -394,8 +432,8 @@ func (c *processLSPClient) SearchSymbols(ctx context.Context, query string) ([]S
 	for _, sym := range resp {
 		result = append(result, SymbolInformation{
 			Name:     sym.Name,
-			Kind:     fmt.Sprintf("%d", int(sym.Kind)),
-			Location: fmt.Sprintf("%s:%d", string(sym.Location.URI), int(sym.Location.Range.Start.Line)),
+			Kind:     symbolKindName(sym.Kind),
+			Location: fmt.Sprintf("%s:%d", uriToPath(string(sym.Location.URI)), int(sym.Location.Range.Start.Line)),
 		})
 	}
 	return result, nil
@@ -423,8 +461,8 @@ func (c *processLSPClient) GetDocumentSymbols(ctx context.Context, file string)
 		for _, sym := range infoSymbols {
 			symbols = append(symbols, SymbolInformation{
 				Name:     sym.Name,
-				Kind:     fmt.Sprintf("%d", int(sym.Kind)),
-				Location: fmt.Sprintf("%s:%d", string(sym.Location.URI), int(sym.Location.Range.Start.Line)),
+				Kind:     symbolKindName(sym.Kind),
+				Location: fmt.Sprintf("%s:%d", uriToPath(string(sym.Location.URI)), int(sym.Location.Range.Start.Line)),
 			})
 		}
 		return symbols, nil
@@ -432,12 +470,36 @@ func (c *processLSPClient) GetDocumentSymbols(ctx context.Context, file string)
 	return nil, errors.New("document symbol response not understood")
 }
 
+// Format formats req.Code as the content of req.File. When the code differs
+// from the file on disk, the server is shown the code for the request and
+// re-reads the file afterwards.
 func (c *processLSPClient) Format(ctx context.Context, req FormatRequest) (string, error) {
-	if err := c.ensureOpen(ctx, req.File); err != nil {
-		return "", err
+	uri := protocol.DocumentURI(pathToURI(req.File))
+	if disk, err := os.ReadFile(req.File); err == nil && string(disk) == req.Code {
+		if err := c.ensureOpen(ctx, req.File); err != nil {
+			return "", err
+		}
+	} else {
+		if err := c.FilesChanged(ctx, []string{req.File}); err != nil {
+			return "", err
+		}
+		if err := c.conn.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{
+				URI:        uri,
+				LanguageID: protocol.LanguageIdentifier(c.cfg.LanguageID),
+				Version:    1,
+				Text:       req.Code,
+			},
+		}); err != nil {
+			return "", err
+		}
+		c.mu.Lock()
+		c.openedFiles[uri] = true
+		c.mu.Unlock()
+		defer c.FilesChanged(context.Background(), []string{req.File})
 	}
 	params := protocol.DocumentFormattingParams{
-		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(pathToURI(req.File))},
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
 		Options: protocol.FormattingOptions{
 			TabSize:      4,
 			InsertSpaces: true,
@@ -450,15 +512,103 @@ func (c *processLSPClient) Format(ctx context.Context, req FormatRequest) (strin
 	if len(edits) == 0 {
 		return req.Code, nil
 	}
-	// If server replaces entire document, return that.
-	if len(edits) == 1 && edits[0].Range.Start.Line == 0 {
-		return edits[0].NewText, nil
+	converted := convertWorkspaceEdit(protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits}})
+	return applyTextEdits(req.Code, converted.Changes[uriToPath(string(uri))])
+}
+
+// decodeLocations accepts every shape servers return for definitions: a
+// single Location, a Location array, or a LocationLink array.
+func decodeLocations(raw json.RawMessage) []protocol.Location {
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
 	}
-	content := req.Code
-	for _, edit := range edits {
-		content = edit.NewText
+	if raw[0] == '{' {
+		raw = json.RawMessage("[" + string(raw) + "]")
+	}
+	var entries []struct {
+		URI         protocol.DocumentURI `json:"uri"`
+		Range       protocol.Range       `json:"range"`
+		TargetURI   protocol.DocumentURI `json:"targetUri"`
+		TargetRange protocol.Range       `json:"targetRange"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil
 	}
-	return content, nil
+	locations := make([]protocol.Location, 0, len(entries))
+	for _, entry := range entries {
+		if entry.TargetURI != "" {
+			locations = append(locations, protocol.Location{URI: entry.TargetURI, Range: entry.TargetRange})
+		} else if entry.URI != "" {
+			locations = append(locations, protocol.Location{URI: entry.URI, Range: entry.Range})
+		}
+	}
+	return locations
+}
+
+// hoverText flattens hover contents, which servers send as MarkupContent, a
+// MarkedString, or an array of MarkedStrings (typescript-language-server and
+// older servers).
+func hoverText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var marked struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &marked); err == nil {
+		return marked.Value
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err == nil {
+		texts := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if t := hoverText(part); t != "" {
+				texts = append(texts, t)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	}
+	return ""
+}
+
+var symbolKindNames = map[protocol.SymbolKind]string{
+	protocol.SymbolKindFile:          "file",
+	protocol.SymbolKindModule:        "module",
+	protocol.SymbolKindNamespace:     "namespace",
+	protocol.SymbolKindPackage:       "package",
+	protocol.SymbolKindClass:         "class",
+	protocol.SymbolKindMethod:        "method",
+	protocol.SymbolKindProperty:      "property",
+	protocol.SymbolKindField:         "field",
+	protocol.SymbolKindConstructor:   "constructor",
+	protocol.SymbolKindEnum:          "enum",
+	protocol.SymbolKindInterface:     "interface",
+	protocol.SymbolKindFunction:      "function",
+	protocol.SymbolKindVariable:      "variable",
+	protocol.SymbolKindConstant:      "constant",
+	protocol.SymbolKindString:        "string",
+	protocol.SymbolKindNumber:        "number",
+	protocol.SymbolKindBoolean:       "boolean",
+	protocol.SymbolKindArray:         "array",
+	protocol.SymbolKindObject:        "object",
+	protocol.SymbolKindKey:           "key",
+	protocol.SymbolKindNull:          "null",
+	protocol.SymbolKindEnumMember:    "enum_member",
+	protocol.SymbolKindStruct:        "struct",
+	protocol.SymbolKindEvent:         "event",
+	protocol.SymbolKindOperator:      "operator",
+	protocol.SymbolKindTypeParameter: "type_parameter",
+}
+
+// symbolKindName names an LSP symbol kind, falling back to its number for
+// kinds newer than this table.
+func symbolKindName(kind protocol.SymbolKind) string {
+	if name, ok := symbolKindNames[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", int(kind))
 }
 
 func convertDiagnostics(diags []protocol.Diagnostic) []Diagnostic {
@@ -530,7 +680,7 @@ func collectDocumentSymbols(dst *[]SymbolInformation, file string, symbols []pro
 	for _, sym := range symbols {
 		*dst = append(*dst, SymbolInformation{
 			Name:     sym.Name,
-			Kind:     fmt.Sprintf("%d", int(sym.Kind)),
+			Kind:     symbolKindName(sym.Kind),
 			Location: fmt.Sprintf("%s:%d", file, int(sym.Range.Start.Line)),
 		})
 		if len(sym.Children) > 0 {
@@ -539,6 +689,59 @@ func collectDocumentSymbols(dst *[]SymbolInformation, file string, symbols []pro
 	}
 }
 
+// lspLanguage maps a manifest language key to its LSP language ID and the file
+// extensions routed to its server.
+type lspLanguage struct {
+	id         string
+	extensions []string
+}
+
+var lspLanguages = map[string]lspLanguage{
+	"go":         {id: "go", extensions: []string{"go"}},
+	"rust":       {id: "rust", extensions: []string{"rs"}},
+	"python":     {id: "python", extensions: []string{"py"}},
+	"typescript": {id: "typescript", extensions: []string{"ts", "tsx", "js", "jsx"}},
+	"javascript": {id: "javascript", extensions: []string{"js", "jsx"}},
+	"c":          {id: "c", extensions: []string{"c", "h"}},
+	"cpp":        {id: "cpp", extensions: []string{"cc", "cpp", "cxx", "hpp", "hh"}},
+	"haskell":    {id: "haskell", extensions: []string{"hs"}},
+	"lua":        {id: "lua", extensions: []string{"lua"}},
+}
+
+// lspServerCommands lists the stdio invocation of servers whose bare name
+// does not speak LSP on stdin/stdout.
+var lspServerCommands = map[string][]string{
+	"gopls":                           {"gopls", "serve"},
+	"pyright":                         {"pyright-langserver", "--stdio"},
+	"pyright-langserver":              {"pyright-langserver", "--stdio"},
+	"typescript-language-server":      {"typescript-language-server", "--stdio"},
+	"haskell-language-server-wrapper": {"haskell-language-server-wrapper", "--lsp"},
+	"haskell-language-server":         {"haskell-language-server", "--lsp"},
+}
+
+// LSPServerConfig resolves a manifest lsp.servers entry (for example
+// "go": "gopls") into a launch config and the file extensions it serves.
+// server may include arguments, which are used verbatim.
+func LSPServerConfig(language, server, root string) (ProcessLSPConfig, []string, error) {
+	lang, ok := lspLanguages[strings.ToLower(language)]
+	if !ok {
+		return ProcessLSPConfig{}, nil, fmt.Errorf("unsupported LSP language %q", language)
+	}
+	command := strings.Fields(server)
+	if len(command) == 0 {
+		return ProcessLSPConfig{}, nil, fmt.Errorf("LSP server for %s is empty", language)
+	}
+	if known, ok := lspServerCommands[command[0]]; ok && len(command) == 1 {
+		command = known
+	}
+	return ProcessLSPConfig{
+		Command:    command[0],
+		Args:       command[1:],
+		RootDir:    root,
+		LanguageID: lang.id,
+	}, lang.extensions, nil
+}
+
 // Wrapper helpers for known servers.
 
 func NewRustAnalyzerClient(root string) (LSPClient, error) {
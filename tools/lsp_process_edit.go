@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// Rename asks the server for the edits renaming the symbol at req.Position.
+func (c *processLSPClient) Rename(ctx context.Context, req RenameRequest) (WorkspaceEdit, error) {
+	if err := c.ensureOpen(ctx, req.File); err != nil {
+		return WorkspaceEdit{}, err
+	}
+	params := protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(pathToURI(req.File))},
+			Position:     protocol.Position{Line: uint32(req.Position.Line), Character: uint32(req.Position.Character)},
+		},
+		NewName: req.NewName,
+	}
+	var resp *protocol.WorkspaceEdit
+	if err := c.conn.Call(ctx, "textDocument/rename", params, &resp); err != nil {
+		return WorkspaceEdit{}, err
+	}
+	if resp == nil {
+		return WorkspaceEdit{}, errors.New("no symbol to rename at that position")
+	}
+	return convertWorkspaceEdit(*resp), nil
+}
+
+// CodeActions lists the actions for req.Range, passing along the diagnostics
+// the server published for the file so quick-fixes are offered.
+func (c *processLSPClient) CodeActions(ctx context.Context, req CodeActionRequest) ([]CodeAction, error) {
+	if err := c.ensureOpen(ctx, req.File); err != nil {
+		return nil, err
+	}
+	uri := protocol.DocumentURI(pathToURI(req.File))
+	rng := protocol.Range{
+		Start: protocol.Position{Line: uint32(req.Range.Start.Line), Character: uint32(req.Range.Start.Character)},
+		End:   protocol.Position{Line: uint32(req.Range.End.Line), Character: uint32(req.Range.End.Character)},
+	}
+	diagnostics := []protocol.Diagnostic{}
+	c.mu.Lock()
+	for _, diag := range c.diagnostics[uri] {
+		if diag.Range.Start.Line <= rng.End.Line && diag.Range.End.Line >= rng.Start.Line {
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+	c.mu.Unlock()
+	params := protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Context:      protocol.CodeActionContext{Diagnostics: diagnostics},
+		Range:        rng,
+	}
+	for _, kind := range req.Kinds {
+		params.Context.Only = append(params.Context.Only, protocol.CodeActionKind(kind))
+	}
+	var resp []json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/codeAction", params, &resp); err != nil {
+		return nil, err
+	}
+	actions := make([]CodeAction, 0, len(resp))
+	for _, raw := range resp {
+		action, err := parseCodeAction(raw)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// ResolveCodeAction returns the edit for action. Actions without an edit are
+// resolved with codeAction/resolve when the server attached data; otherwise
+// their command is executed and the edits the server sends back through
+// workspace/applyEdit are collected instead of written. A command that
+// accompanies an edit is not run, since it would see the unedited files.
+func (c *processLSPClient) ResolveCodeAction(ctx context.Context, action CodeAction) (WorkspaceEdit, error) {
+	if command, ok := bareCommand(action.raw); ok {
+		return c.executeCommand(ctx, command)
+	}
+	var full protocol.CodeAction
+	if err := json.Unmarshal(action.raw, &full); err != nil {
+		return WorkspaceEdit{}, fmt.Errorf("code action %q: %w", action.Title, err)
+	}
+	if full.Edit == nil && full.Data != nil {
+		if err := c.conn.Call(ctx, "codeAction/resolve", action.raw, &full); err != nil {
+			return WorkspaceEdit{}, err
+		}
+	}
+	if full.Edit != nil {
+		return convertWorkspaceEdit(*full.Edit), nil
+	}
+	if full.Command != nil {
+		return c.executeCommand(ctx, *full.Command)
+	}
+	return WorkspaceEdit{}, fmt.Errorf("code action %q has neither an edit nor a command", action.Title)
+}
+
+// executeCommand runs command while collecting workspace/applyEdit requests.
+// Commands run one at a time so edits are attributed to the right command.
+func (c *processLSPClient) executeCommand(ctx context.Context, command protocol.Command) (WorkspaceEdit, error) {
+	c.editMu.Lock()
+	defer c.editMu.Unlock()
+	c.mu.Lock()
+	c.collecting = &WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+	c.mu.Unlock()
+	params := protocol.ExecuteCommandParams{Command: command.Command, Arguments: command.Arguments}
+	err := c.conn.Call(ctx, "workspace/executeCommand", params, nil)
+	c.mu.Lock()
+	collected := *c.collecting
+	c.collecting = nil
+	c.mu.Unlock()
+	if err != nil {
+		return WorkspaceEdit{}, err
+	}
+	return collected, nil
+}
+
+// handleApplyEdit answers a server's workspace/applyEdit request. Edits are
+// only accepted while a command is executing on the agent's behalf, and they
+// are reported as applied because the caller writes them once the command
+// returns.
+func (c *processLSPClient) handleApplyEdit(params json.RawMessage) (*protocol.ApplyWorkspaceEditResponse, error) {
+	var req protocol.ApplyWorkspaceEditParams
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.collecting == nil {
+		return &protocol.ApplyWorkspaceEditResponse{FailureReason: "edits are applied through relurpify file tools"}, nil
+	}
+	c.collecting.merge(convertWorkspaceEdit(req.Edit))
+	return &protocol.ApplyWorkspaceEditResponse{Applied: true}, nil
+}
+
+// FilesChanged closes open documents among files so the next request re-opens
+// them with their contents on disk.
+func (c *processLSPClient) FilesChanged(ctx context.Context, files []string) error {
+	var closed []protocol.DocumentURI
+	c.mu.Lock()
+	for _, file := range files {
+		uri := protocol.DocumentURI(pathToURI(file))
+		if c.openedFiles[uri] {
+			delete(c.openedFiles, uri)
+			delete(c.diagnostics, uri)
+			closed = append(closed, uri)
+		}
+	}
+	c.mu.Unlock()
+	for _, uri := range closed {
+		params := protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}}
+		if err := c.conn.Notify(ctx, "textDocument/didClose", params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCodeAction accepts either a CodeAction or a bare Command, both of
+// which servers may return from textDocument/codeAction.
+func parseCodeAction(raw json.RawMessage) (CodeAction, error) {
+	if command, ok := bareCommand(raw); ok {
+		return CodeAction{Title: command.Title, Command: command.Command, raw: raw}, nil
+	}
+	var action protocol.CodeAction
+	if err := json.Unmarshal(raw, &action); err != nil {
+		return CodeAction{}, fmt.Errorf("code action response not understood: %w", err)
+	}
+	out := CodeAction{
+		Title:     action.Title,
+		Kind:      string(action.Kind),
+		Preferred: action.IsPreferred,
+		raw:       raw,
+	}
+	if action.Disabled != nil {
+		out.Disabled = action.Disabled.Reason
+	}
+	if action.Edit != nil {
+		edit := convertWorkspaceEdit(*action.Edit)
+		out.Edit = &edit
+	}
+	if action.Command != nil {
+		out.Command = action.Command.Command
+	}
+	return out, nil
+}
+
+// bareCommand reports whether raw is a Command rather than a CodeAction; a
+// Command's "command" field is its identifier string.
+func bareCommand(raw json.RawMessage) (protocol.Command, bool) {
+	var probe struct {
+		Command json.RawMessage `json:"command"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil || len(probe.Command) == 0 || probe.Command[0] != '"' {
+		return protocol.Command{}, false
+	}
+	var command protocol.Command
+	if err := json.Unmarshal(raw, &command); err != nil {
+		return protocol.Command{}, false
+	}
+	return command, true
+}
+
+func convertWorkspaceEdit(edit protocol.WorkspaceEdit) WorkspaceEdit {
+	out := WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+	add := func(uri protocol.DocumentURI, edits []protocol.TextEdit) {
+		path := uriToPath(string(uri))
+		for _, e := range edits {
+			out.Changes[path] = append(out.Changes[path], TextEdit{
+				Range: Range{
+					Start: Position{Line: int(e.Range.Start.Line), Character: int(e.Range.Start.Character)},
+					End:   Position{Line: int(e.Range.End.Line), Character: int(e.Range.End.Character)},
+				},
+				NewText: e.NewText,
+			})
+		}
+	}
+	// Servers send documentChanges when the client supports them and fall
+	// back to changes otherwise; resource operations are not advertised, so
+	// entries without a document are skipped.
+	for _, doc := range edit.DocumentChanges {
+		if doc.TextDocument.URI != "" {
+			add(doc.TextDocument.URI, doc.Edits)
+		}
+	}
+	for uri, edits := range edit.Changes {
+		add(uri, edits)
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type fakeLSPClient struct {
+	symbols []SymbolInformation
+}
+
+func (c *fakeLSPClient) GetDefinition(ctx context.Context, req DefinitionRequest) (DefinitionResult, error) {
+	return DefinitionResult{Location: Location{URI: req.File}}, nil
+}
+func (c *fakeLSPClient) GetReferences(ctx context.Context, req ReferencesRequest) ([]Location, error) {
+	return nil, nil
+}
+func (c *fakeLSPClient) GetHover(ctx context.Context, req HoverRequest) (HoverResult, error) {
+	return HoverResult{}, nil
+}
+func (c *fakeLSPClient) GetDiagnostics(ctx context.Context, file string) ([]Diagnostic, error) {
+	return nil, nil
+}
+func (c *fakeLSPClient) SearchSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	return c.symbols, nil
+}
+func (c *fakeLSPClient) GetDocumentSymbols(ctx context.Context, file string) ([]SymbolInformation, error) {
+	return nil, nil
+}
+func (c *fakeLSPClient) Format(ctx context.Context, req FormatRequest) (string, error) {
+	return "", nil
+}
+
+func TestProxyStartsLazyClientsOnFirstUse(t *testing.T) {
+	proxy := NewProxy(0)
+	starts := map[string]int{}
+	proxy.RegisterLazy([]string{"go"}, func() (LSPClient, error) {
+		starts["go"]++
+		return &fakeLSPClient{symbols: []SymbolInformation{{Name: "GoSym"}}}, nil
+	})
+	proxy.RegisterLazy([]string{"ts", "tsx"}, func() (LSPClient, error) {
+		starts["ts"]++
+		return &fakeLSPClient{symbols: []SymbolInformation{{Name: "TsSym"}}}, nil
+	})
+	proxy.RegisterLazy([]string{"rs"}, func() (LSPClient, error) {
+		starts["rs"]++
+		return nil, errors.New("rust-analyzer missing")
+	})
+
+	def := &DefinitionTool{Proxy: proxy}
+	assert.True(t, framework.PendingActivation(def))
+	ctx := context.Background()
+	args := map[string]interface{}{"file": "main.go", "symbol": "main", "line": 1, "character": 1}
+	_, err := def.Execute(ctx, framework.NewContext(), args)
+	require.NoError(t, err)
+	_, err = def.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "util.go", "symbol": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"go": 1}, starts, "only the go server starts")
+
+	_, err = def.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "lib.rs"})
+	require.ErrorContains(t, err, "rust-analyzer missing")
+	_, err = def.Execute(ctx, framework.NewContext(), map[string]interface{}{"file": "lib.rs"})
+	require.ErrorContains(t, err, "rust-analyzer missing")
+	assert.Equal(t, 1, starts["rs"], "failed starts are not retried")
+
+	search := &SearchSymbolsTool{Proxy: proxy}
+	res, err := search.Execute(ctx, framework.NewContext(), map[string]interface{}{"query": "Sym"})
+	require.NoError(t, err)
+	assert.Len(t, res.Data["symbols"], 2, "workspace queries start pending servers")
+	assert.Equal(t, 1, starts["ts"])
+	assert.False(t, framework.PendingActivation(def))
+}
+
+func TestLSPServerConfig(t *testing.T) {
+	cfg, exts, err := LSPServerConfig("go", "gopls", "/ws")
+	require.NoError(t, err)
+	assert.Equal(t, ProcessLSPConfig{Command: "gopls", Args: []string{"serve"}, RootDir: "/ws", LanguageID: "go"}, cfg)
+	assert.Equal(t, []string{"go"}, exts)
+
+	cfg, _, err = LSPServerConfig("python", "pylsp --verbose", "/ws")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--verbose"}, cfg.Args)
+
+	_, _, err = LSPServerConfig("cobol", "cobol-ls", "/ws")
+	assert.Error(t, err)
+}
+
+type countingLSPClient struct {
+	fakeLSPClient
+	diagnostics int
+}
+
+func (c *countingLSPClient) GetDiagnostics(ctx context.Context, file string) ([]Diagnostic, error) {
+	c.diagnostics++
+	return nil, nil
+}
+
+func TestProxyClearCacheRefetches(t *testing.T) {
+	proxy := NewProxy(0)
+	client := &countingLSPClient{}
+	proxy.Register("go", client)
+	tool := &DiagnosticsTool{Proxy: proxy}
+	ctx := context.Background()
+	args := map[string]interface{}{"file": "main.go"}
+
+	for i := 0; i < 2; i++ {
+		_, err := tool.Execute(ctx, framework.NewContext(), args)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, client.diagnostics, "second call should be served from cache")
+
+	proxy.ClearCache()
+	_, err := tool.Execute(ctx, framework.NewContext(), args)
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.diagnostics)
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// lspCommand prepares cfg's server process and the codec for its stdio. A
+// remote server is started over ssh in the remote counterpart of root, and
+// its messages are rewritten so it sees remote paths while the tools keep
+// using the local mount.
+func lspCommand(ctx context.Context, cfg ProcessLSPConfig, root string) (*exec.Cmd, jsonrpc2.ObjectCodec, error) {
+	if cfg.Remote == nil {
+		cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+		cmd.Dir = root
+		return cmd, jsonrpc2.VSCodeObjectCodec{}, nil
+	}
+	remoteRoot, err := cfg.Remote.RemotePath(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lsp %s: %w", cfg.Command, err)
+	}
+	args := cfg.Remote.Command(remoteRoot, nil, append([]string{cfg.Command}, cfg.Args...))
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Dir = root
+	return cmd, newRemotePathCodec(cfg.Remote.Local, cfg.Remote.Root), nil
+}
+
+// remotePathCodec frames messages like jsonrpc2.VSCodeObjectCodec and maps
+// the local mount directory to the remote root in outgoing messages, and
+// back in incoming ones. Paths and file URIs are both covered because URIs
+// embed the path unescaped (see pathToURI).
+type remotePathCodec struct {
+	toRemote *strings.Replacer
+	toLocal  *strings.Replacer
+}
+
+func newRemotePathCodec(local, remote string) remotePathCodec {
+	local = filepath.ToSlash(filepath.Clean(local))
+	remote = path.Clean(remote)
+	return remotePathCodec{
+		toRemote: strings.NewReplacer(local+"/", remote+"/", local+`"`, remote+`"`),
+		toLocal:  strings.NewReplacer(remote+"/", local+"/", remote+`"`, local+`"`),
+	}
+}
+
+func (c remotePathCodec) WriteObject(stream io.Writer, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return jsonrpc2.VSCodeObjectCodec{}.WriteObject(stream, json.RawMessage(c.toRemote.Replace(string(data))))
+}
+
+func (c remotePathCodec) ReadObject(stream *bufio.Reader, v interface{}) error {
+	var raw json.RawMessage
+	if err := (jsonrpc2.VSCodeObjectCodec{}).ReadObject(stream, &raw); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(c.toLocal.Replace(string(raw))), v)
+}
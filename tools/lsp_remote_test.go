@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemotePathCodecRewritesWorkspacePaths(t *testing.T) {
+	codec := newRemotePathCodec("/mnt/app", "/srv/app")
+	var wire bytes.Buffer
+	require.NoError(t, codec.WriteObject(&wire, map[string]string{
+		"rootUri": "file:///mnt/app",
+		"uri":     "file:///mnt/app/main.go",
+		"other":   "/mnt/application/x.go",
+	}))
+
+	var sent map[string]string
+	require.NoError(t, jsonrpc2.VSCodeObjectCodec{}.ReadObject(bufio.NewReader(bytes.NewReader(wire.Bytes())), &sent))
+	assert.Equal(t, "file:///srv/app", sent["rootUri"])
+	assert.Equal(t, "file:///srv/app/main.go", sent["uri"])
+	assert.Equal(t, "/mnt/application/x.go", sent["other"])
+
+	reply := `{"uri":"file:///srv/app/util/util.go","message":"unused"}`
+	incoming := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(reply), reply)
+	var received map[string]string
+	require.NoError(t, codec.ReadObject(bufio.NewReader(bytes.NewBufferString(incoming)), &received))
+	assert.Equal(t, "file:///mnt/app/util/util.go", received["uri"])
+	assert.Equal(t, "unused", received["message"])
+}
@@ -0,0 +1,447 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSymbolIndexFiles caps the files indexed per language.
+const DefaultSymbolIndexFiles = 2000
+
+// symbolIndexFileTimeout bounds one documentSymbol request while indexing.
+const symbolIndexFileTimeout = 10 * time.Second
+
+// symbolIndexSkipDirs are dependency and build directories whose files are
+// not indexed; hidden directories are skipped too.
+var symbolIndexSkipDirs = map[string]bool{
+	"node_modules":  true,
+	"vendor":        true,
+	"relurpify_cfg": true,
+	"target":        true,
+	"dist-newstyle": true,
+}
+
+// symbolIndex caches the document symbols of every workspace file per
+// language. Servers differ widely in how they answer workspace/symbol: some
+// need a file of the project open first, and some return nothing for broad
+// queries. Walking documentSymbol over the workspace gives every language the
+// same answers, and later queries skip the server entirely.
+type symbolIndex struct {
+	mu        sync.RWMutex
+	root      string
+	maxFiles  int
+	filter    func(path string, isDir bool) bool
+	languages map[string]*languageSymbols
+}
+
+// languageSymbols is the index of one language. Until ready, queries for
+// the language go to its server.
+type languageSymbols struct {
+	indexing bool
+	ready    bool
+	err      error
+	files    map[string][]SymbolInformation
+}
+
+// SymbolIndexStatus summarizes the index of one language.
+type SymbolIndexStatus struct {
+	Language string `json:"language"`
+	Ready    bool   `json:"ready"`
+	Files    int    `json:"files"`
+	Symbols  int    `json:"symbols"`
+	Error    string `json:"error,omitempty"`
+}
+
+// EnableSymbolIndex indexes the symbols of files under root in the
+// background, one language at a time as its server starts, so lazily
+// started servers stay lazy. maxFiles caps the files per language; zero
+// means DefaultSymbolIndexFiles. Servers already running are indexed now.
+func (p *Proxy) EnableSymbolIndex(root string, maxFiles int) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultSymbolIndexFiles
+	}
+	p.symbols.mu.Lock()
+	p.symbols.root = root
+	p.symbols.maxFiles = maxFiles
+	p.symbols.mu.Unlock()
+	p.mu.RLock()
+	started := make(map[string]struct{})
+	for _, language := range p.languages {
+		started[language] = struct{}{}
+	}
+	p.mu.RUnlock()
+	for language := range started {
+		p.serverStarted(language)
+	}
+}
+
+// SetSymbolIndexFilter restricts the symbol index to paths for which filter
+// returns true, such as those the agent may read. Rejected directories are
+// skipped entirely.
+func (p *Proxy) SetSymbolIndexFilter(filter func(path string, isDir bool) bool) {
+	p.symbols.mu.Lock()
+	defer p.symbols.mu.Unlock()
+	p.symbols.filter = filter
+}
+
+// serverStarted indexes language in the background when indexing is on.
+func (p *Proxy) serverStarted(language string) {
+	if language == "" || !p.beginIndex(language) {
+		return
+	}
+	go func() {
+		_ = p.indexLanguage(context.Background(), language)
+	}()
+}
+
+// beginIndex marks language as being indexed. It reports false when
+// indexing is off or the language is already indexed or in progress.
+func (p *Proxy) beginIndex(language string) bool {
+	p.symbols.mu.Lock()
+	defer p.symbols.mu.Unlock()
+	if p.symbols.root == "" {
+		return false
+	}
+	if p.symbols.languages == nil {
+		p.symbols.languages = make(map[string]*languageSymbols)
+	}
+	entry, ok := p.symbols.languages[language]
+	if ok && (entry.indexing || entry.ready) {
+		return false
+	}
+	if !ok {
+		entry = &languageSymbols{}
+		p.symbols.languages[language] = entry
+	}
+	entry.indexing = true
+	return true
+}
+
+// IndexSymbols starts every registered server and indexes each language
+// that is not indexed yet, waiting until all are done. Indexing must be
+// enabled with EnableSymbolIndex. Languages that fail are reported together;
+// the others are still indexed.
+func (p *Proxy) IndexSymbols(ctx context.Context) error {
+	p.symbols.mu.RLock()
+	enabled := p.symbols.root != ""
+	p.symbols.mu.RUnlock()
+	if !enabled {
+		return errors.New("symbol index not enabled")
+	}
+	p.allClients()
+	p.mu.RLock()
+	languages := make(map[string]struct{})
+	for _, language := range p.languages {
+		languages[language] = struct{}{}
+	}
+	var errs []error
+	for _, entry := range p.lazy {
+		if entry.err != nil {
+			errs = append(errs, fmt.Errorf("%s: start language server: %w", entry.language, entry.err))
+		}
+	}
+	p.mu.RUnlock()
+	var wg sync.WaitGroup
+	for language := range languages {
+		if !p.beginIndex(language) {
+			continue
+		}
+		wg.Add(1)
+		go func(language string) {
+			defer wg.Done()
+			_ = p.indexLanguage(ctx, language)
+		}(language)
+	}
+	wg.Wait()
+	// Languages already being indexed in the background may still be busy.
+	for _, status := range p.waitForIndex(ctx, languages) {
+		if _, ok := languages[status.Language]; ok && status.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", status.Language, status.Error))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// waitForIndex polls until none of languages is still indexing.
+func (p *Proxy) waitForIndex(ctx context.Context, languages map[string]struct{}) []SymbolIndexStatus {
+	for {
+		statuses := p.SymbolIndexStatus()
+		busy := false
+		p.symbols.mu.RLock()
+		for language := range languages {
+			if entry, ok := p.symbols.languages[language]; ok && entry.indexing {
+				busy = true
+			}
+		}
+		p.symbols.mu.RUnlock()
+		if !busy || ctx.Err() != nil {
+			return statuses
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// indexLanguage collects the document symbols of language's files.
+func (p *Proxy) indexLanguage(ctx context.Context, language string) error {
+	symbols, err := p.collectSymbols(ctx, language)
+	p.symbols.mu.Lock()
+	defer p.symbols.mu.Unlock()
+	entry := p.symbols.languages[language]
+	entry.indexing = false
+	entry.err = err
+	if err != nil {
+		return err
+	}
+	entry.ready = true
+	entry.files = symbols
+	return nil
+}
+
+func (p *Proxy) collectSymbols(ctx context.Context, language string) (map[string][]SymbolInformation, error) {
+	client, extensions := p.languageClient(language)
+	if client == nil {
+		return nil, errors.New("language server not started")
+	}
+	p.symbols.mu.RLock()
+	root, maxFiles, filter := p.symbols.root, p.symbols.maxFiles, p.symbols.filter
+	p.symbols.mu.RUnlock()
+	files, err := symbolIndexFiles(root, extensions, maxFiles, filter)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make(map[string][]SymbolInformation, len(files))
+	var firstErr error
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fileCtx, cancel := context.WithTimeout(ctx, symbolIndexFileTimeout)
+		items, err := client.GetDocumentSymbols(fileCtx, file)
+		cancel()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", file, err)
+			}
+			continue
+		}
+		symbols[file] = labelSymbols(items, language)
+	}
+	if len(files) > 0 && len(symbols) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return symbols, nil
+}
+
+// languageClient returns the started client serving language and the
+// extensions routed to it.
+func (p *Proxy) languageClient(language string) (LSPClient, []string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var client LSPClient
+	for c, l := range p.languages {
+		if l == language {
+			client = c
+		}
+	}
+	if client == nil {
+		return nil, nil
+	}
+	var extensions []string
+	for ext, c := range p.clients {
+		if c == client {
+			extensions = append(extensions, ext)
+		}
+	}
+	sort.Strings(extensions)
+	return client, extensions
+}
+
+// symbolIndexFiles lists up to maxFiles files under root with one of
+// extensions that filter (when set) accepts, in walk order.
+func symbolIndexFiles(root string, extensions []string, maxFiles int, filter func(path string, isDir bool) bool) ([]string, error) {
+	wanted := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		wanted["."+strings.TrimPrefix(ext, ".")] = true
+	}
+	var files []string
+	errLimit := errors.New("limit reached")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || symbolIndexSkipDirs[name] || (filter != nil && !filter(path, true))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !wanted[filepath.Ext(path)] || (filter != nil && !filter(path, false)) {
+			return nil
+		}
+		files = append(files, path)
+		if len(files) >= maxFiles {
+			return errLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return nil, err
+	}
+	return files, nil
+}
+
+func labelSymbols(items []SymbolInformation, language string) []SymbolInformation {
+	out := make([]SymbolInformation, len(items))
+	for i, item := range items {
+		item.Language = language
+		out[i] = item
+	}
+	return out
+}
+
+// refreshSymbols re-reads the symbols of changed files in indexed
+// languages and drops removed files.
+func (p *Proxy) refreshSymbols(ctx context.Context, files []string) {
+	p.mu.RLock()
+	byLanguage := make(map[string][]string)
+	clients := make(map[string]LSPClient)
+	for _, file := range files {
+		client, ok := p.clients[strings.TrimPrefix(filepath.Ext(file), ".")]
+		if !ok {
+			continue
+		}
+		language := p.languages[client]
+		byLanguage[language] = append(byLanguage[language], file)
+		clients[language] = client
+	}
+	p.mu.RUnlock()
+	for language, changed := range byLanguage {
+		p.symbols.mu.RLock()
+		entry, ok := p.symbols.languages[language]
+		ready := ok && entry.ready
+		p.symbols.mu.RUnlock()
+		if !ready {
+			continue
+		}
+		for _, file := range changed {
+			var items []SymbolInformation
+			if _, err := os.Stat(file); err == nil {
+				fileCtx, cancel := context.WithTimeout(ctx, symbolIndexFileTimeout)
+				symbols, err := clients[language].GetDocumentSymbols(fileCtx, file)
+				cancel()
+				if err != nil {
+					continue
+				}
+				items = labelSymbols(symbols, language)
+			}
+			p.symbols.mu.Lock()
+			if items == nil {
+				delete(entry.files, file)
+			} else {
+				entry.files[file] = items
+			}
+			p.symbols.mu.Unlock()
+		}
+	}
+}
+
+// SymbolIndexStatus reports the index of every language seen so far, by
+// language.
+func (p *Proxy) SymbolIndexStatus() []SymbolIndexStatus {
+	p.symbols.mu.RLock()
+	defer p.symbols.mu.RUnlock()
+	statuses := make([]SymbolIndexStatus, 0, len(p.symbols.languages))
+	for language, entry := range p.symbols.languages {
+		status := SymbolIndexStatus{Language: language, Ready: entry.ready, Files: len(entry.files)}
+		for _, items := range entry.files {
+			status.Symbols += len(items)
+		}
+		if entry.err != nil {
+			status.Error = entry.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Language < statuses[j].Language })
+	return statuses
+}
+
+// SearchSymbols finds workspace symbols matching query across every
+// language server, starting pending ones. Indexed languages answer from the
+// index: exact names first, then prefixes, then other case-insensitive
+// substring matches. The rest are asked with workspace/symbol.
+func (p *Proxy) SearchSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	var combined []SymbolInformation
+	for _, client := range p.allClients() {
+		p.mu.RLock()
+		language := p.languages[client]
+		p.mu.RUnlock()
+		if indexed, ok := p.searchIndex(language, query); ok {
+			combined = append(combined, indexed...)
+			continue
+		}
+		items, err := client.SearchSymbols(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		combined = append(combined, labelSymbols(items, language)...)
+	}
+	return combined, nil
+}
+
+func (p *Proxy) searchIndex(language, query string) ([]SymbolInformation, bool) {
+	p.symbols.mu.RLock()
+	defer p.symbols.mu.RUnlock()
+	entry, ok := p.symbols.languages[language]
+	if !ok || !entry.ready {
+		return nil, false
+	}
+	q := strings.ToLower(query)
+	type match struct {
+		symbol SymbolInformation
+		rank   int
+	}
+	var matches []match
+	for _, items := range entry.files {
+		for _, item := range items {
+			name := strings.ToLower(item.Name)
+			switch {
+			case name == q:
+				matches = append(matches, match{item, 0})
+			case strings.HasPrefix(name, q):
+				matches = append(matches, match{item, 1})
+			case strings.Contains(name, q):
+				matches = append(matches, match{item, 2})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.symbol.Name != b.symbol.Name {
+			return a.symbol.Name < b.symbol.Name
+		}
+		return a.symbol.Location < b.symbol.Location
+	})
+	out := make([]SymbolInformation, len(matches))
+	for i, m := range matches {
+		out[i] = m.symbol
+	}
+	return out, true
+}
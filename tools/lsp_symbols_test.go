@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/protocol"
+)
+
+// documentLSPClient answers documentSymbol with a symbol per line of the
+// file, named after the line.
+type documentLSPClient struct {
+	fakeLSPClient
+	mu       sync.Mutex
+	opened   []string
+	searches int
+}
+
+func (c *documentLSPClient) GetDocumentSymbols(ctx context.Context, file string) ([]SymbolInformation, error) {
+	c.mu.Lock()
+	c.opened = append(c.opened, filepath.Base(file))
+	c.mu.Unlock()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []SymbolInformation
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		symbols = append(symbols, SymbolInformation{Name: line, Kind: "function", Location: fmt.Sprintf("%s:%d", file, i)})
+	}
+	return symbols, nil
+}
+
+func (c *documentLSPClient) SearchSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	c.mu.Lock()
+	c.searches++
+	c.mu.Unlock()
+	return c.fakeLSPClient.SearchSymbols(ctx, query)
+}
+
+func writeSymbolFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func symbolNames(symbols []SymbolInformation) []string {
+	names := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		names[i] = symbol.Language + ":" + symbol.Name
+	}
+	return names
+}
+
+func TestProxySymbolIndexRanksMatchesPerLanguage(t *testing.T) {
+	dir := t.TempDir()
+	writeSymbolFiles(t, dir, map[string]string{
+		"main.go":                 "ParseConfig\nConfig\nloadConfigFile",
+		"lib/util.go":             "configure",
+		"vendor/dep/dep.go":       "ConfigVendored",
+		".cache/gen.go":           "ConfigHidden",
+		"web/app.ts":              "AppConfig",
+		"web/node_modules/x/x.ts": "ConfigModule",
+		"Main.hs":                 "config",
+	})
+	goClient := &documentLSPClient{}
+	tsClient := &documentLSPClient{}
+	hsClient := &documentLSPClient{fakeLSPClient: fakeLSPClient{symbols: []SymbolInformation{{Name: "configLive"}}}}
+	proxy := NewProxy(0)
+	proxy.RegisterLanguage("go", []string{"go"}, func() (LSPClient, error) { return goClient, nil })
+	proxy.RegisterLanguage("typescript", []string{"ts", "tsx"}, func() (LSPClient, error) { return tsClient, nil })
+	proxy.RegisterLanguage("haskell", []string{"hs"}, func() (LSPClient, error) { return hsClient, nil })
+	proxy.SetSymbolIndexFilter(func(path string, isDir bool) bool {
+		return filepath.Ext(path) != ".hs"
+	})
+	proxy.EnableSymbolIndex(dir, 0)
+
+	ctx := context.Background()
+	require.NoError(t, proxy.IndexSymbols(ctx))
+	assert.ElementsMatch(t, []string{"main.go", "util.go"}, goClient.opened, "vendored and hidden files are skipped")
+	assert.Equal(t, []string{"app.ts"}, tsClient.opened)
+	assert.Empty(t, hsClient.opened, "filtered files are not indexed")
+
+	found, err := proxy.SearchSymbols(ctx, "config")
+	require.NoError(t, err)
+	var goNames []string
+	for _, name := range symbolNames(found) {
+		if strings.HasPrefix(name, "go:") {
+			goNames = append(goNames, name)
+		}
+	}
+	assert.Equal(t, []string{"go:Config", "go:configure", "go:ParseConfig", "go:loadConfigFile"}, goNames)
+	assert.Contains(t, symbolNames(found), "typescript:AppConfig")
+	assert.NotContains(t, symbolNames(found), "haskell:configLive", "an indexed language is answered from its index")
+	assert.Zero(t, goClient.searches, "indexed languages are not asked again")
+
+	var ready []string
+	for _, status := range proxy.SymbolIndexStatus() {
+		if status.Ready {
+			ready = append(ready, status.Language)
+		}
+	}
+	assert.Equal(t, []string{"go", "haskell", "typescript"}, ready)
+}
+
+func TestProxySymbolIndexFollowsFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeSymbolFiles(t, dir, map[string]string{"a.go": "Alpha", "b.go": "Beta"})
+	client := &documentLSPClient{}
+	proxy := NewProxy(0)
+	proxy.RegisterLanguage("go", []string{"go"}, func() (LSPClient, error) { return client, nil })
+	proxy.EnableSymbolIndex(dir, 0)
+	ctx := context.Background()
+	require.NoError(t, proxy.IndexSymbols(ctx))
+
+	writeSymbolFiles(t, dir, map[string]string{"a.go": "Alphabet"})
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.go")))
+	proxy.FilesChanged(ctx, []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")})
+
+	found, err := proxy.SearchSymbols(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go:Alphabet"}, symbolNames(found))
+}
+
+func TestProxySymbolIndexIndexesServersAsTheyStart(t *testing.T) {
+	dir := t.TempDir()
+	writeSymbolFiles(t, dir, map[string]string{"a.go": "Alpha"})
+	client := &documentLSPClient{}
+	proxy := NewProxy(0)
+	proxy.RegisterLanguage("go", []string{"go"}, func() (LSPClient, error) { return client, nil })
+	proxy.RegisterLanguage("rust", []string{"rs"}, func() (LSPClient, error) { return nil, errors.New("rust-analyzer missing") })
+	proxy.EnableSymbolIndex(dir, 0)
+	assert.Empty(t, proxy.SymbolIndexStatus(), "enabling the index starts no server")
+
+	_, err := proxy.clientForFile(filepath.Join(dir, "a.go"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		statuses := proxy.SymbolIndexStatus()
+		return len(statuses) == 1 && statuses[0].Ready && statuses[0].Symbols == 1
+	}, time.Second, 10*time.Millisecond)
+
+	err = proxy.IndexSymbols(context.Background())
+	require.ErrorContains(t, err, "rust: start language server: rust-analyzer missing")
+}
+
+func TestDecodeLocationsAcceptsEveryShape(t *testing.T) {
+	location := `{"uri":"file:///ws/a.go","range":{"start":{"line":3,"character":1},"end":{"line":3,"character":4}}}`
+	link := `{"targetUri":"file:///ws/b.go","targetRange":{"start":{"line":7,"character":0},"end":{"line":9,"character":1}},"targetSelectionRange":{"start":{"line":7,"character":5},"end":{"line":7,"character":8}}}`
+	for name, raw := range map[string]string{
+		"location": location,
+		"list":     "[" + location + "]",
+		"links":    "[" + link + "]",
+	} {
+		locs := decodeLocations(json.RawMessage(raw))
+		require.Len(t, locs, 1, name)
+		assert.True(t, strings.HasPrefix(string(locs[0].URI), "file:///ws/"), name)
+	}
+	assert.Empty(t, decodeLocations(json.RawMessage("null")))
+}
+
+func TestHoverTextAcceptsEveryShape(t *testing.T) {
+	assert.Equal(t, "func F()", hoverText(json.RawMessage(`{"kind":"markdown","value":"func F()"}`)))
+	assert.Equal(t, "plain", hoverText(json.RawMessage(`"plain"`)))
+	assert.Equal(t, "f :: Int\n\ndocs", hoverText(json.RawMessage(`[{"language":"haskell","value":"f :: Int"},"docs"]`)))
+}
+
+func TestSymbolKindName(t *testing.T) {
+	assert.Equal(t, "function", symbolKindName(protocol.SymbolKindFunction))
+	assert.Equal(t, "enum_member", symbolKindName(protocol.SymbolKindEnumMember))
+	assert.Equal(t, "99", symbolKindName(protocol.SymbolKind(99)))
+}
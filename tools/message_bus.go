@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// errNoMessageBus is returned when a bus tool runs outside a task with a
+// message bus.
+var errNoMessageBus = errors.New("no message bus for this task")
+
+// BusPublishTool shares a finding with the other agents of the task, such
+// as delegates running in parallel, through the task's message bus.
+type BusPublishTool struct{}
+
+func (t *BusPublishTool) Name() string { return "bus_publish" }
+func (t *BusPublishTool) Description() string {
+	return `Shares a finding with the other agents working on this task, e.g. channel "security" and message "hardcoded secret in config/prod.go".`
+}
+func (t *BusPublishTool) Category() string { return "coordination" }
+func (t *BusPublishTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "channel", Type: "string", Description: "Dotted lowercase channel, e.g. security or findings.tests", Required: true},
+		{Name: "message", Type: "string", Description: "The finding", Required: true},
+		{Name: "data", Type: "object", Description: "Optional structured details", Required: false},
+	}
+}
+
+func (t *BusPublishTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	client := framework.MessageBusFrom(ctx)
+	if client == nil {
+		return nil, errNoMessageBus
+	}
+	data, _ := args["data"].(map[string]interface{})
+	msg, err := client.Publish(ctx, stringArg(args, "channel"), stringArg(args, "message"), data)
+	if err != nil {
+		return nil, err
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"seq": msg.Seq, "channel": msg.Channel}}, nil
+}
+
+func (t *BusPublishTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return framework.MessageBusFrom(ctx) != nil
+}
+
+func (t *BusPublishTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{}
+}
+
+// BusReadTool reads the findings other agents of the task shared.
+type BusReadTool struct{}
+
+func (t *BusReadTool) Name() string { return "bus_read" }
+func (t *BusReadTool) Description() string {
+	return `Reads findings other agents of this task shared. Pass since (the last seq you saw) to get only newer ones.`
+}
+func (t *BusReadTool) Category() string { return "coordination" }
+func (t *BusReadTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "channel", Type: "string", Description: `Channel, "name.*" for a channel and those below it, or "*"`, Required: false, Default: "*"},
+		{Name: "since", Type: "integer", Description: "Only messages after this seq", Required: false, Default: 0},
+	}
+}
+
+func (t *BusReadTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	client := framework.MessageBusFrom(ctx)
+	if client == nil {
+		return nil, errNoMessageBus
+	}
+	pattern := stringArg(args, "channel")
+	if pattern == "" {
+		pattern = "*"
+	}
+	messages, err := client.Messages(pattern, int64(toInt(args["since"])))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		entry := map[string]interface{}{"seq": msg.Seq, "channel": msg.Channel, "sender": msg.Sender, "message": msg.Text}
+		if len(msg.Data) > 0 {
+			entry["data"] = msg.Data
+		}
+		out = append(out, entry)
+	}
+	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"messages": out}}, nil
+}
+
+func (t *BusReadTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return framework.MessageBusFrom(ctx) != nil
+}
+
+func (t *BusReadTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{}
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestBusToolsShareFindings(t *testing.T) {
+	publish, read := &BusPublishTool{}, &BusReadTool{}
+	assert.False(t, publish.IsAvailable(context.Background(), nil))
+
+	ctx := framework.WithMessageBus(context.Background(), framework.NewMessageBus("", nil))
+	ctx = framework.WithBusScope(ctx, "security", framework.BusScope{})
+	require.True(t, publish.IsAvailable(ctx, nil))
+	res, err := publish.Execute(ctx, framework.NewContext(), map[string]interface{}{
+		"channel": "security",
+		"message": "hardcoded secret in config.go",
+		"data":    map[string]interface{}{"line": 12},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Data["seq"])
+
+	res, err = read.Execute(ctx, framework.NewContext(), map[string]interface{}{"since": float64(0)})
+	require.NoError(t, err)
+	messages := res.Data["messages"].([]map[string]interface{})
+	require.Len(t, messages, 1)
+	assert.Equal(t, "security", messages[0]["sender"])
+	assert.Equal(t, "hardcoded secret in config.go", messages[0]["message"])
+	assert.Equal(t, map[string]interface{}{"line": 12}, messages[0]["data"])
+
+	res, err = read.Execute(ctx, framework.NewContext(), map[string]interface{}{"since": float64(1)})
+	require.NoError(t, err)
+	assert.Empty(t, res.Data["messages"])
+}
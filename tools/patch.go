@@ -0,0 +1,611 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ApplyPatchTool applies unified diffs. Every hunk is checked against the
+// current file contents before anything is written, so a patch either
+// applies completely or leaves the workspace untouched and reports the hunks
+// that did not match.
+type ApplyPatchTool struct {
+	BasePath string
+	Backup   bool
+	manager  *framework.PermissionManager
+	agentID  string
+	spec     *framework.AgentRuntimeSpec
+}
+
+func (t *ApplyPatchTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *ApplyPatchTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *ApplyPatchTool) Name() string { return "file_patch" }
+func (t *ApplyPatchTool) Description() string {
+	return "Applies a unified diff to one or more files. Nothing is written unless every hunk matches."
+}
+func (t *ApplyPatchTool) Category() string { return "file" }
+func (t *ApplyPatchTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "patch", Type: "string", Description: "Unified diff with ---/+++ file headers and @@ hunks", Required: true},
+		{Name: "dry_run", Type: "bool", Description: "Validate the patch without writing", Required: false, Default: false},
+	}
+}
+
+// patchTarget is the pending change to one file.
+type patchTarget struct {
+	path     string
+	rel      string
+	exists   bool
+	original string
+	updated  string
+	create   bool
+	delete   bool
+	hunks    int
+	added    int
+	removed  int
+	offsets  []int
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	if args["patch"] == nil {
+		return nil, errors.New("patch required")
+	}
+	files, err := parseUnifiedDiff(fmt.Sprint(args["patch"]))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("patch contains no file changes")
+	}
+	for _, fp := range files {
+		for _, hunk := range fp.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Op != '+' {
+					continue
+				}
+				if err := checkNoRedactionMask(line.Text); err != nil {
+					return nil, fmt.Errorf("patch for %s: %w", fp.NewPath, err)
+				}
+			}
+		}
+	}
+	// Hold every target from the first read to the last write, so no other
+	// job changes a file between the hunks matching and the patch landing.
+	paths := make([]string, 0, len(files))
+	for _, fp := range files {
+		name := fp.NewPath
+		if name == "" {
+			name = fp.OldPath
+		}
+		paths = append(paths, t.preparePath(name))
+	}
+	unlock := fileLocks.Lock(paths...)
+	defer unlock()
+	var (
+		targets  []*patchTarget
+		byPath   = make(map[string]*patchTarget)
+		rejected []map[string]interface{}
+		total    int
+	)
+	for _, fp := range files {
+		if fp.OldPath != "" && fp.NewPath != "" && fp.OldPath != fp.NewPath {
+			return nil, fmt.Errorf("patch renames %s to %s; renames are not supported", fp.OldPath, fp.NewPath)
+		}
+		name := fp.NewPath
+		if name == "" {
+			name = fp.OldPath
+		}
+		path := t.preparePath(name)
+		target, ok := byPath[path]
+		if !ok {
+			target, err = t.loadTarget(ctx, path, name)
+			if err != nil {
+				return nil, err
+			}
+			byPath[path] = target
+			targets = append(targets, target)
+		}
+		switch {
+		case fp.OldPath == "" && target.exists && target.updated != "":
+			return nil, fmt.Errorf("patch creates %s, which already exists", name)
+		case fp.OldPath != "" && !target.exists && !target.create:
+			return nil, fmt.Errorf("patch modifies %s, which does not exist", name)
+		}
+		if fp.OldPath == "" {
+			target.create = !target.exists
+		}
+		result := applyHunks(target.updated, fp.Hunks)
+		total += len(fp.Hunks)
+		for _, reject := range result.rejected {
+			rejected = append(rejected, map[string]interface{}{
+				"file":   name,
+				"hunk":   reject.index + 1,
+				"header": fp.Hunks[reject.index].Header,
+				"reason": reject.reason,
+			})
+		}
+		if fp.NewPath == "" {
+			if result.content != "" && len(result.rejected) == 0 {
+				rejected = append(rejected, map[string]interface{}{
+					"file":   name,
+					"reason": "patch deletes the file but does not remove all of its content",
+				})
+			}
+			target.delete = true
+		}
+		target.updated = result.content
+		target.hunks += len(fp.Hunks)
+		target.added += result.added
+		target.removed += result.removed
+		target.offsets = append(target.offsets, result.offsets...)
+	}
+	if len(rejected) > 0 {
+		return &framework.ToolResult{
+			Success: false,
+			Data:    map[string]interface{}{"rejected": rejected, "applied": false},
+			Error:   fmt.Sprintf("%d of %d hunks rejected; no files were changed", len(rejected), total),
+		}, nil
+	}
+	summary := make([]map[string]interface{}, 0, len(targets))
+	for _, target := range targets {
+		entry := map[string]interface{}{
+			"path":    target.rel,
+			"hunks":   target.hunks,
+			"added":   target.added,
+			"removed": target.removed,
+		}
+		switch {
+		case target.create:
+			entry["created"] = true
+		case target.delete:
+			entry["deleted"] = true
+		}
+		if len(target.offsets) > 0 {
+			entry["offsets"] = target.offsets
+		}
+		summary = append(summary, entry)
+	}
+	data := map[string]interface{}{"files": summary, "applied": false}
+	if dryRun, _ := args["dry_run"].(bool); dryRun {
+		return &framework.ToolResult{Success: true, Data: data}, nil
+	}
+	for _, target := range targets {
+		if err := t.authorizeWrite(ctx, target); err != nil {
+			return nil, err
+		}
+	}
+	backups, err := t.write(targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		if target.delete {
+			forgetFileHash(state, target.path)
+		} else {
+			recordFileHash(state, target.path, []byte(target.updated))
+		}
+	}
+	data["applied"] = true
+	if len(backups) > 0 {
+		data["backups"] = backups
+	}
+	return &framework.ToolResult{Success: true, Data: data}, nil
+}
+
+func (t *ApplyPatchTool) loadTarget(ctx context.Context, path, name string) (*patchTarget, error) {
+	target := &patchTarget{path: path, rel: filepath.ToSlash(name)}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return target, nil
+	case err != nil:
+		return nil, err
+	case info.IsDir():
+		return nil, fmt.Errorf("patch target %s is a directory", name)
+	}
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isText(data) {
+		return nil, fmt.Errorf("%s: %w", name, errBinaryFile)
+	}
+	target.exists = true
+	target.original = string(data)
+	target.updated = target.original
+	return target, nil
+}
+
+// authorizeWrite checks every permission a write needs so that a denial is
+// discovered before any file changes.
+func (t *ApplyPatchTool) authorizeWrite(ctx context.Context, target *patchTarget) error {
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, target.path); err != nil {
+			return err
+		}
+	}
+	action := "edit"
+	if target.create {
+		action = "write"
+	}
+	if err := t.enforceFileMatrix(ctx, action, target.path); err != nil {
+		return err
+	}
+	if t.Backup && target.exists && t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemWrite, target.path+".bak"); err != nil {
+			return fmt.Errorf("backup blocked: %w", err)
+		}
+	}
+	return nil
+}
+
+// write applies targets, restoring every file already touched if one fails.
+func (t *ApplyPatchTool) write(targets []*patchTarget) ([]string, error) {
+	var (
+		backups []string
+		done    []*patchTarget
+	)
+	rollback := func() {
+		for _, target := range done {
+			if target.exists {
+				_ = os.WriteFile(target.path, []byte(target.original), 0o644)
+			} else {
+				_ = os.Remove(target.path)
+			}
+		}
+	}
+	for _, target := range targets {
+		if t.Backup && target.exists {
+			backup := target.path + ".bak"
+			if err := copyFile(target.path, backup); err != nil {
+				rollback()
+				return nil, err
+			}
+			backups = append(backups, backup)
+		}
+		var err error
+		if target.delete {
+			if target.exists {
+				err = os.Remove(target.path)
+			}
+		} else {
+			if err = os.MkdirAll(filepath.Dir(target.path), 0o755); err == nil {
+				err = os.WriteFile(target.path, []byte(target.updated), 0o644)
+			}
+		}
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		done = append(done, target)
+	}
+	return backups, nil
+}
+
+func (t *ApplyPatchTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return true
+}
+
+func (t *ApplyPatchTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemWrite)}
+}
+
+func (t *ApplyPatchTool) preparePath(path string) string { return preparePath(t.BasePath, path) }
+
+func (t *ApplyPatchTool) enforceFileMatrix(ctx context.Context, action string, absPath string) error {
+	if t == nil || t.spec == nil {
+		return nil
+	}
+	return enforceFileMatrix(ctx, t.manager, t.agentID, t.BasePath, action, absPath, t.spec.Files)
+}
+
+// filePatch is the part of a unified diff that touches one file. An empty
+// OldPath creates the file and an empty NewPath deletes it.
+type filePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []patchHunk
+}
+
+// patchHunk is one @@ section. OldStart is zero when the header carries no
+// line numbers, in which case the hunk is located by its context alone.
+type patchHunk struct {
+	Header   string
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []patchLine
+}
+
+// patchLine is a context (' '), removed ('-'), or added ('+') line. NoEOL
+// marks a line followed by "\ No newline at end of file".
+type patchLine struct {
+	Op    byte
+	Text  string
+	NoEOL bool
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff parses the file sections of a unified diff. Git headers,
+// prose, and markdown fences around the diff are ignored, and hunk line
+// counts are treated as hints because model-written diffs often get them
+// wrong.
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var (
+		files   []filePatch
+		current *filePatch
+	)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if isFileHeader(lines, i) {
+			oldPath, newPath := patchPath(line[4:]), patchPath(lines[i+1][4:])
+			// Git prefixes the two sides with a/ and b/.
+			if (oldPath == "" || strings.HasPrefix(oldPath, "a/")) && (newPath == "" || strings.HasPrefix(newPath, "b/")) {
+				oldPath = strings.TrimPrefix(oldPath, "a/")
+				newPath = strings.TrimPrefix(newPath, "b/")
+			}
+			if oldPath == "" && newPath == "" {
+				return nil, fmt.Errorf("line %d: both sides of the file header are /dev/null", i+1)
+			}
+			files = append(files, filePatch{OldPath: oldPath, NewPath: newPath})
+			current = &files[len(files)-1]
+			i++
+			continue
+		}
+		if !strings.HasPrefix(line, "@@") {
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+		}
+		hunk, next, err := parseHunk(lines, i)
+		if err != nil {
+			return nil, err
+		}
+		current.Hunks = append(current.Hunks, hunk)
+		i = next - 1
+	}
+	for _, fp := range files {
+		if len(fp.Hunks) == 0 && fp.OldPath != "" && fp.NewPath != "" {
+			return nil, fmt.Errorf("patch for %s has no hunks", fp.NewPath)
+		}
+	}
+	return files, nil
+}
+
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// patchPath extracts the path from a ---/+++ header, dropping any timestamp.
+func patchPath(header string) string {
+	if idx := strings.IndexByte(header, '\t'); idx >= 0 {
+		header = header[:idx]
+	}
+	header = strings.TrimSpace(header)
+	if header == "/dev/null" {
+		return ""
+	}
+	return header
+}
+
+// parseHunk reads the hunk whose header is lines[start] and returns the index
+// of the first line after it.
+func parseHunk(lines []string, start int) (patchHunk, int, error) {
+	hunk := patchHunk{Header: strings.TrimSpace(lines[start]), OldLines: -1, NewLines: -1}
+	if m := hunkHeader.FindStringSubmatch(lines[start]); m != nil {
+		hunk.OldStart, _ = strconv.Atoi(m[1])
+		hunk.NewStart, _ = strconv.Atoi(m[3])
+		hunk.OldLines, hunk.NewLines = 1, 1
+		if m[2] != "" {
+			hunk.OldLines, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			hunk.NewLines, _ = strconv.Atoi(m[4])
+		}
+		if hunk.OldLines == 0 {
+			// "-5,0" inserts after line 5; make OldStart the line the
+			// insertion lands before, like every other hunk.
+			hunk.OldStart++
+		}
+	}
+	oldSeen := 0
+	i := start + 1
+body:
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") || isFileHeader(lines, i) {
+			break
+		}
+		if line == "" {
+			// Editors and models strip the space from blank context lines.
+			line = " "
+		}
+		switch line[0] {
+		case ' ', '-', '+':
+			hunk.Lines = append(hunk.Lines, patchLine{Op: line[0], Text: line[1:]})
+			if line[0] != '+' {
+				oldSeen++
+			}
+		case '\\':
+			if n := len(hunk.Lines); n > 0 {
+				hunk.Lines[n-1].NoEOL = true
+			}
+		default:
+			// Prose or a closing markdown fence.
+			break body
+		}
+	}
+	// Blank lines past the declared count (or after a hunk without counts)
+	// separate the hunk from what follows; they are not context.
+	for n := len(hunk.Lines); n > 0; n = len(hunk.Lines) {
+		last := hunk.Lines[n-1]
+		if last.Op != ' ' || last.Text != "" || (hunk.OldLines >= 0 && oldSeen <= hunk.OldLines) {
+			break
+		}
+		hunk.Lines = hunk.Lines[:n-1]
+		oldSeen--
+	}
+	if len(hunk.Lines) == 0 {
+		return hunk, i, fmt.Errorf("line %d: empty hunk", start+1)
+	}
+	return hunk, i, nil
+}
+
+type hunkReject struct {
+	index  int
+	reason string
+}
+
+type hunkResult struct {
+	content  string
+	added    int
+	removed  int
+	offsets  []int
+	rejected []hunkReject
+}
+
+// applyHunks applies hunks in order. A hunk whose context has moved is
+// searched for nearby, preferring exact matches over ones that differ only
+// in trailing whitespace; offsets lists how far each moved hunk was found
+// from its stated position.
+func applyHunks(content string, hunks []patchHunk) hunkResult {
+	var res hunkResult
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	eol := "\n"
+	if len(lines) > 0 && strings.HasSuffix(lines[0], "\r\n") {
+		eol = "\r\n"
+	}
+	var out []string
+	emit := func(line string) {
+		if n := len(out); n > 0 && !strings.HasSuffix(out[n-1], "\n") {
+			out[n-1] += eol
+		}
+		out = append(out, line)
+	}
+	pos := 0
+	for idx, hunk := range hunks {
+		var old []string
+		for _, line := range hunk.Lines {
+			if line.Op != '+' {
+				old = append(old, line.Text)
+			}
+		}
+		at, ok := locateHunk(lines, old, hunk.OldStart-1, pos)
+		if !ok {
+			reason := "context does not match the file"
+			if len(old) == 0 && hunk.OldStart == 0 {
+				reason = "hunk has no line numbers or context to place it"
+			}
+			res.rejected = append(res.rejected, hunkReject{index: idx, reason: reason})
+			continue
+		}
+		if hunk.OldStart > 0 && at != hunk.OldStart-1 {
+			res.offsets = append(res.offsets, at-(hunk.OldStart-1))
+		}
+		for _, line := range lines[pos:at] {
+			emit(line)
+		}
+		cursor := at
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case ' ':
+				emit(lines[cursor])
+				cursor++
+			case '-':
+				cursor++
+				res.removed++
+			case '+':
+				text := line.Text
+				if !line.NoEOL {
+					text += eol
+				}
+				emit(text)
+				res.added++
+			}
+		}
+		pos = cursor
+	}
+	for _, line := range lines[pos:] {
+		emit(line)
+	}
+	res.content = strings.Join(out, "")
+	return res
+}
+
+// locateHunk finds old within lines at or after min, nearest to want. A
+// negative want means the hunk has no line numbers.
+func locateHunk(lines, old []string, want, min int) (int, bool) {
+	last := len(lines) - len(old)
+	if last < min {
+		return 0, false
+	}
+	if len(old) == 0 {
+		if want < 0 {
+			return 0, false
+		}
+		if want < min {
+			want = min
+		}
+		if want > len(lines) {
+			want = len(lines)
+		}
+		return want, true
+	}
+	if want < min {
+		want = min
+	}
+	if want > last {
+		want = last
+	}
+	for _, loose := range []bool{false, true} {
+		for d := 0; want-d >= min || want+d <= last; d++ {
+			for _, at := range []int{want - d, want + d} {
+				if at >= min && at <= last && matchLines(lines[at:at+len(old)], old, loose) {
+					return at, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func matchLines(lines, old []string, loose bool) bool {
+	for i, want := range old {
+		got := strings.TrimRight(lines[i], "\r\n")
+		if loose {
+			got, want = strings.TrimRight(got, " \t"), strings.TrimRight(want, " \t")
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
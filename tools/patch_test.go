@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+const mainGo = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+`
+
+func TestApplyPatchToolAppliesMultiFileDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": mainGo, "old.txt": "gone\n"})
+	patch := "Here is the change:\n\n```diff\n" +
+		"diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -5,4 +5,5 @@\n" +
+		" \n" +
+		" func main() {\n" +
+		"-\tfmt.Println(\"hello\")\n" +
+		"+\tfmt.Println(\"hello, world\")\n" +
+		"+\tfmt.Println(\"bye\")\n" +
+		" }\n" +
+		"--- /dev/null\n" +
+		"+++ b/pkg/new.go\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+package pkg\n" +
+		"--- a/old.txt\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-gone\n" +
+		"```\n"
+	tool := &ApplyPatchTool{BasePath: dir, Backup: true}
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	require.True(t, res.Success, res.Error)
+	assert.Equal(t, true, res.Data["applied"])
+	files := res.Data["files"].([]map[string]interface{})
+	require.Len(t, files, 3)
+	assert.Equal(t, []int{-1}, files[0]["offsets"], "the hunk was stated one line late")
+	assert.Equal(t, true, files[1]["created"])
+	assert.Equal(t, true, files[2]["deleted"])
+
+	assert.Equal(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n\tfmt.Println(\"bye\")\n}\n", readFile(t, filepath.Join(dir, "main.go")))
+	assert.Equal(t, "package pkg\n", readFile(t, filepath.Join(dir, "pkg", "new.go")))
+	assert.NoFileExists(t, filepath.Join(dir, "old.txt"))
+	assert.Equal(t, mainGo, readFile(t, filepath.Join(dir, "main.go.bak")))
+	assert.Equal(t, "gone\n", readFile(t, filepath.Join(dir, "old.txt.bak")))
+}
+
+func TestApplyPatchToolRejectsMismatchedHunksAtomically(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\n"})
+	patch := "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n" +
+		"--- b.txt\n+++ b.txt\n@@ -1 +1 @@\n-four\n+4\n"
+	tool := &ApplyPatchTool{BasePath: dir, Backup: true}
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.Equal(t, "1 of 2 hunks rejected; no files were changed", res.Error)
+	rejected := res.Data["rejected"].([]map[string]interface{})
+	require.Len(t, rejected, 1)
+	assert.Equal(t, "b.txt", rejected[0]["file"])
+	assert.Equal(t, 1, rejected[0]["hunk"])
+	assert.Equal(t, "one\ntwo\n", readFile(t, filepath.Join(dir, "a.txt")))
+	assert.NoFileExists(t, filepath.Join(dir, "a.txt.bak"))
+}
+
+func TestFileToolsRefuseRedactionMasks(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".env": "API_TOKEN=abcdefgh12345678\n"})
+	ctx := context.Background()
+	state := framework.NewContext()
+
+	write := &WriteFileTool{BasePath: dir}
+	_, err := write.Execute(ctx, state, map[string]interface{}{"path": ".env", "content": "API_TOKEN=[REDACTED:env]\nDEBUG=1\n"})
+	assert.ErrorContains(t, err, "[REDACTED:env]")
+
+	patch := "--- .env\n+++ .env\n@@ -1 +1,2 @@\n-API_TOKEN=abcdefgh12345678\n+API_TOKEN=[REDACTED:env]\n+DEBUG=1\n"
+	_, err = (&ApplyPatchTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"patch": patch})
+	assert.ErrorContains(t, err, "[REDACTED:env]")
+
+	_, err = (&EditFileTool{BasePath: dir}).Execute(ctx, state, map[string]interface{}{"path": ".env", "start_line": 1, "content": "API_TOKEN=[REDACTED:env]"})
+	assert.ErrorContains(t, err, "[REDACTED:env]")
+	assert.Equal(t, "API_TOKEN=abcdefgh12345678\n", readFile(t, filepath.Join(dir, ".env")))
+}
+
+func TestApplyPatchToolDryRunAndPermissions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n", "b.txt": "two\n"})
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+1\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-two\n+2\n"
+	tool := &ApplyPatchTool{BasePath: dir}
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"patch": patch, "dry_run": true})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, false, res.Data["applied"])
+	assert.Equal(t, "one\n", readFile(t, filepath.Join(dir, "a.txt")))
+
+	manager, err := framework.NewPermissionManager(dir, &framework.PermissionSet{FileSystem: []framework.FileSystemPermission{
+		{Action: framework.FileSystemRead, Path: filepath.Join(dir, "**")},
+		{Action: framework.FileSystemWrite, Path: filepath.Join(dir, "a.txt")},
+	}}, nil, nil)
+	require.NoError(t, err)
+	tool.SetPermissionManager(manager, "agent")
+	_, err = tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"patch": patch})
+	require.Error(t, err)
+	assert.Equal(t, "one\n", readFile(t, filepath.Join(dir, "a.txt")), "denials are found before writing")
+}
+
+func TestParseUnifiedDiffToleratesModelOutput(t *testing.T) {
+	// No line numbers, a stripped blank context line, and no trailing newline.
+	files, err := parseUnifiedDiff("--- x.go\n+++ x.go\n@@ ... @@\n func a() {}\n\n-func b() {}\n+func c() {}\n\\ No newline at end of file")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	hunk := files[0].Hunks[0]
+	assert.Zero(t, hunk.OldStart)
+	require.Len(t, hunk.Lines, 4)
+	assert.Equal(t, patchLine{Op: ' ', Text: ""}, hunk.Lines[1])
+	assert.True(t, hunk.Lines[3].NoEOL)
+
+	res := applyHunks("package x\n\nfunc a() {}\n\nfunc b() {}\n", files[0].Hunks)
+	require.Empty(t, res.rejected)
+	assert.Equal(t, "package x\n\nfunc a() {}\n\nfunc c() {}", res.content)
+
+	_, err = parseUnifiedDiff("@@ -1 +1 @@\n-a\n+b\n")
+	assert.Error(t, err, "hunks need a file header")
+}
+
+func TestApplyHunksPreservesCRLFAndMatchesLooseWhitespace(t *testing.T) {
+	hunks := []patchHunk{{OldStart: 2, OldLines: 1, Lines: []patchLine{{Op: '-', Text: "b"}, {Op: '+', Text: "B"}}}}
+	res := applyHunks("a\r\nb  \r\nc\r\n", hunks)
+	require.Empty(t, res.rejected)
+	assert.Equal(t, "a\r\nB\r\nc\r\n", res.content)
+
+	res = applyHunks("a\nc\n", hunks)
+	require.Len(t, res.rejected, 1)
+	assert.Equal(t, "context does not match the file", res.rejected[0].reason)
+}
+
+func TestFileOperationsIncludesApplyPatch(t *testing.T) {
+	dir := t.TempDir()
+	registry := framework.NewToolRegistry()
+	for _, tool := range FileOperations(dir) {
+		require.NoError(t, registry.Register(tool))
+	}
+	tool, ok := registry.Get("file_patch")
+	require.True(t, ok)
+	require.NoError(t, tool.Permissions().Validate())
+}
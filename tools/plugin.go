@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// Plugin protocol
+//
+// A plugin is an executable invoked once per request. The request is a single
+// JSON object on stdin and the response a single JSON object on stdout:
+//
+//	{"version":1,"method":"describe"}
+//	-> {"tools":[{"name":"jira_search","description":"...","category":"search",
+//	              "parameters":[{"name":"query","type":"string","required":true}]}]}
+//
+//	{"version":1,"method":"execute","tool":"jira_search","args":{"query":"bug"}}
+//	-> {"success":true,"data":{...}}   or   {"success":false,"error":"..."}
+//
+// Anything written to stderr is surfaced in errors. Plugins run through the
+// agent's CommandRunner, so they inherit the sandbox.
+
+// PluginProtocolVersion is sent with every request.
+const PluginProtocolVersion = 1
+
+type pluginRequest struct {
+	Version int                    `json:"version"`
+	Method  string                 `json:"method"`
+	Tool    string                 `json:"tool,omitempty"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+}
+
+// PluginToolDescriptor is one entry of a plugin's describe response.
+type PluginToolDescriptor struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Category    string                    `json:"category"`
+	Parameters  []framework.ToolParameter `json:"parameters"`
+}
+
+type pluginDescribeResponse struct {
+	Tools []PluginToolDescriptor `json:"tools"`
+	Error string                 `json:"error,omitempty"`
+}
+
+type pluginExecuteResponse struct {
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// PluginTool is one tool contributed by an external plugin executable.
+type PluginTool struct {
+	Plugin   framework.AgentPluginSpec
+	BasePath string
+	Runner   framework.CommandRunner
+
+	descriptor PluginToolDescriptor
+	manager    *framework.PermissionManager
+	agentID    string
+}
+
+// LoadPluginTools runs the plugin's describe method and returns one tool per
+// advertised entry.
+func LoadPluginTools(ctx context.Context, spec framework.AgentPluginSpec, basePath string, runner framework.CommandRunner) ([]framework.Tool, error) {
+	descriptors, err := DescribePlugin(ctx, spec, basePath, runner)
+	if err != nil {
+		return nil, err
+	}
+	return NewPluginTools(spec, basePath, runner, descriptors)
+}
+
+// DescribePlugin runs the plugin's describe method. Callers that cache the
+// result rebuild tools with NewPluginTools.
+func DescribePlugin(ctx context.Context, spec framework.AgentPluginSpec, basePath string, runner framework.CommandRunner) ([]PluginToolDescriptor, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if runner == nil {
+		return nil, fmt.Errorf("command runner missing for plugin %s", spec.Name)
+	}
+	var resp pluginDescribeResponse
+	if err := callPlugin(ctx, runner, spec, basePath, pluginRequest{Method: "describe"}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s describe: %s", spec.Name, resp.Error)
+	}
+	return resp.Tools, nil
+}
+
+// NewPluginTools builds tools from previously described descriptors.
+func NewPluginTools(spec framework.AgentPluginSpec, basePath string, runner framework.CommandRunner, descriptors []PluginToolDescriptor) ([]framework.Tool, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	tools := make([]framework.Tool, 0, len(descriptors))
+	for _, desc := range descriptors {
+		if strings.TrimSpace(desc.Name) == "" {
+			return nil, fmt.Errorf("plugin %s advertised a tool without a name", spec.Name)
+		}
+		tools = append(tools, &PluginTool{Plugin: spec, BasePath: basePath, Runner: runner, descriptor: desc})
+	}
+	return tools, nil
+}
+
+func (t *PluginTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *PluginTool) Name() string { return t.descriptor.Name }
+
+func (t *PluginTool) Description() string {
+	if t.descriptor.Description == "" {
+		return fmt.Sprintf("Tool provided by the %s plugin.", t.Plugin.Name)
+	}
+	return t.descriptor.Description
+}
+
+func (t *PluginTool) Category() string {
+	if t.descriptor.Category == "" {
+		return "plugin"
+	}
+	return t.descriptor.Category
+}
+
+func (t *PluginTool) Parameters() []framework.ToolParameter { return t.descriptor.Parameters }
+
+func (t *PluginTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	if t.manager != nil {
+		if err := t.manager.CheckExecutable(ctx, t.agentID, t.Plugin.Command[0], t.Plugin.Command[1:], nil); err != nil {
+			return nil, err
+		}
+	}
+	var resp pluginExecuteResponse
+	req := pluginRequest{Method: "execute", Tool: t.descriptor.Name, Args: args}
+	if err := callPlugin(ctx, t.Runner, t.Plugin, t.BasePath, req, &resp); err != nil {
+		return nil, err
+	}
+	return &framework.ToolResult{Success: resp.Success, Data: resp.Data, Error: resp.Error}, nil
+}
+
+func (t *PluginTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.Runner != nil
+}
+
+// Permissions combines what the manifest declared for the plugin with the
+// right to launch the plugin binary, so AuthorizeTool rejects plugins whose
+// needs exceed the agent's own permissions.
+func (t *PluginTool) Permissions() framework.ToolPermissions {
+	perms := t.Plugin.Permissions
+	perms.FileSystem = append([]framework.FileSystemPermission(nil), perms.FileSystem...)
+	perms.Executables = append([]framework.ExecutablePermission{{
+		Binary: t.Plugin.Command[0],
+		Args:   append([]string(nil), t.Plugin.Command[1:]...),
+	}}, perms.Executables...)
+	return framework.ToolPermissions{Permissions: &perms}
+}
+
+// callPlugin sends one request and decodes the response into out.
+func callPlugin(ctx context.Context, runner framework.CommandRunner, spec framework.AgentPluginSpec, basePath string, req pluginRequest, out interface{}) error {
+	req.Version = PluginProtocolVersion
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	timeout := 30 * time.Second
+	if spec.Timeout != "" {
+		if parsed, err := time.ParseDuration(spec.Timeout); err == nil {
+			timeout = parsed
+		}
+	}
+	stdout, stderr, err := runner.Run(ctx, framework.CommandRequest{
+		Workdir: basePath,
+		Args:    append([]string(nil), spec.Command...),
+		Input:   string(payload),
+		Timeout: timeout,
+	})
+	if err != nil {
+		return fmt.Errorf("plugin %s %s failed: %w: %s", spec.Name, req.Method, err, strings.TrimSpace(stderr))
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), out); err != nil {
+		return fmt.Errorf("plugin %s %s returned invalid JSON: %w", spec.Name, req.Method, err)
+	}
+	return nil
+}
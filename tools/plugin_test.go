@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pluginStubRunner struct {
+	responses map[string]string
+	requests  []pluginRequest
+}
+
+func (r *pluginStubRunner) Run(ctx context.Context, req framework.CommandRequest) (string, string, error) {
+	var decoded pluginRequest
+	if err := json.Unmarshal([]byte(req.Input), &decoded); err != nil {
+		return "", "bad request", err
+	}
+	r.requests = append(r.requests, decoded)
+	return r.responses[decoded.Method], "", nil
+}
+
+func TestLoadPluginToolsDescribesAndExecutes(t *testing.T) {
+	runner := &pluginStubRunner{responses: map[string]string{
+		"describe": `{"tools":[{"name":"jira_search","description":"Search Jira","category":"search",
+			"parameters":[{"name":"query","type":"string","required":true}]}]}`,
+		"execute": `{"success":true,"data":{"issues":2}}`,
+	}}
+	spec := framework.AgentPluginSpec{Name: "jira", Command: []string{"jira-plugin", "--stdio"}}
+
+	loaded, err := LoadPluginTools(context.Background(), spec, t.TempDir(), runner)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	tool := loaded[0]
+	assert.Equal(t, "jira_search", tool.Name())
+	assert.Equal(t, "search", tool.Category())
+	require.Len(t, tool.Parameters(), 1)
+	assert.True(t, tool.Parameters()[0].Required)
+
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"query": "bug"})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, float64(2), res.Data["issues"])
+
+	require.Len(t, runner.requests, 2)
+	assert.Equal(t, PluginProtocolVersion, runner.requests[1].Version)
+	assert.Equal(t, "jira_search", runner.requests[1].Tool)
+	assert.Equal(t, "bug", runner.requests[1].Args["query"])
+}
+
+func TestPluginToolPermissionsIncludeBinary(t *testing.T) {
+	spec := framework.AgentPluginSpec{
+		Name:    "jira",
+		Command: []string{"jira-plugin"},
+		Permissions: framework.PermissionSet{
+			Network: []framework.NetworkPermission{{Direction: "egress", Protocol: "tcp", Host: "jira.example.com", Port: 443}},
+		},
+	}
+	tool := &PluginTool{Plugin: spec, descriptor: PluginToolDescriptor{Name: "jira_search"}}
+
+	perms := tool.Permissions().Permissions
+	require.NotNil(t, perms)
+	require.Len(t, perms.Executables, 1)
+	assert.Equal(t, "jira-plugin", perms.Executables[0].Binary)
+	assert.Len(t, perms.Network, 1)
+	assert.Empty(t, spec.Permissions.Executables)
+}
+
+func TestLoadPluginToolsRejectsInvalidJSON(t *testing.T) {
+	runner := &pluginStubRunner{responses: map[string]string{"describe": "not json"}}
+	spec := framework.AgentPluginSpec{Name: "broken", Command: []string{"broken"}}
+
+	_, err := LoadPluginTools(context.Background(), spec, t.TempDir(), runner)
+	assert.ErrorContains(t, err, "invalid JSON")
+}
+
+func TestLoadPluginToolsRequiresBareBinary(t *testing.T) {
+	spec := framework.AgentPluginSpec{Name: "jira", Command: []string{"./jira-plugin"}}
+
+	_, err := LoadPluginTools(context.Background(), spec, t.TempDir(), &pluginStubRunner{})
+	assert.ErrorContains(t, err, "referenced by name")
+}
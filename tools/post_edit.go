@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// defaultPostEditTimeout bounds how long one edit waits for diagnostics.
+const defaultPostEditTimeout = 5 * time.Second
+
+// PostEditDiagnostics is a framework.ToolHook that checks every file a
+// successful edit touched with the language server for its language. Errors
+// go into the agent context under framework.PostEditDiagnosticsKey and the
+// tool result under "post_edit_diagnostics"; errors the file did not have
+// before the edit are marked new and counted under
+// framework.PostEditNewErrorsKey. Files no configured server handles are
+// skipped.
+type PostEditDiagnostics struct {
+	Proxy    *Proxy
+	BasePath string
+	// Timeout bounds the wait for diagnostics after each edit; zero uses
+	// five seconds.
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// known holds each file's errors as last seen, the baseline that tells
+	// new errors apart.
+	known map[string][]Diagnostic
+}
+
+// BeforeTool records the current errors of the file a path-based edit is
+// about to change. Multi-file edits compare against the errors seen after
+// the previous edit of each file, if any.
+func (h *PostEditDiagnostics) BeforeTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}) {
+	if h == nil || h.Proxy == nil {
+		return
+	}
+	switch tool.Name() {
+	case "file_write", "file_edit", "file_create":
+	default:
+		return
+	}
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return
+	}
+	file := preparePath(h.BasePath, path)
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		// Every error in a file the edit creates is new.
+		h.remember(file, nil)
+		return
+	}
+	if errs, ok := h.fetchErrors(ctx, file); ok {
+		h.remember(file, errs)
+	}
+}
+
+// AfterTool checks the files a successful edit changed.
+func (h *PostEditDiagnostics) AfterTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}, result *framework.ToolResult, err error) {
+	if h == nil || h.Proxy == nil || err != nil || result == nil || !result.Success {
+		return
+	}
+	files := editedFiles(h.BasePath, tool.Name(), args, result)
+	if len(files) == 0 {
+		return
+	}
+	h.Proxy.FilesChanged(ctx, files)
+	var found []framework.EditDiagnostic
+	newErrors := 0
+	for _, file := range files {
+		before, hadBaseline := h.baseline(file)
+		errs, ok := h.fetchErrors(ctx, file)
+		if !ok {
+			continue
+		}
+		h.remember(file, errs)
+		seen := make(map[string]int, len(before))
+		for _, d := range before {
+			seen[diagnosticKey(d)]++
+		}
+		for _, d := range errs {
+			isNew := true
+			if key := diagnosticKey(d); seen[key] > 0 {
+				seen[key]--
+				isNew = false
+			} else if !hadBaseline {
+				// Without a baseline there is no telling which errors
+				// are new, so they are only reported.
+				isNew = false
+			}
+			if isNew {
+				newErrors++
+			}
+			found = append(found, framework.EditDiagnostic{
+				File:    h.relative(file),
+				Line:    d.Line + 1,
+				Message: d.Message,
+				Source:  d.Source,
+				New:     isNew,
+			})
+		}
+	}
+	if len(found) > 0 {
+		if result.Data == nil {
+			result.Data = map[string]interface{}{}
+		}
+		result.Data["post_edit_diagnostics"] = found
+	}
+	if state == nil {
+		return
+	}
+	checked := make(map[string]bool, len(files))
+	for _, file := range files {
+		checked[h.relative(file)] = true
+	}
+	var merged []framework.EditDiagnostic
+	for _, d := range framework.PostEditDiagnostics(state) {
+		if !checked[d.File] {
+			merged = append(merged, d)
+		}
+	}
+	state.Set(framework.PostEditDiagnosticsKey, append(merged, found...))
+	if newErrors > 0 {
+		previous, _ := state.Get(framework.PostEditNewErrorsKey)
+		count, _ := previous.(int)
+		state.Set(framework.PostEditNewErrorsKey, count+newErrors)
+	}
+}
+
+// editedFiles lists the absolute paths an edit tool wrote, read from its
+// arguments or, for multi-file edits, its result.
+func editedFiles(base, name string, args map[string]interface{}, result *framework.ToolResult) []string {
+	var paths []string
+	switch name {
+	case "file_write", "file_edit", "file_create":
+		if path, ok := args["path"].(string); ok && path != "" {
+			paths = append(paths, path)
+		}
+	case "file_patch", "lsp_rename_symbol", "lsp_code_actions":
+		if applied, _ := result.Data["applied"].(bool); !applied {
+			return nil
+		}
+		switch files := result.Data["files"].(type) {
+		case []string:
+			paths = append(paths, files...)
+		case []map[string]interface{}:
+			for _, entry := range files {
+				if deleted, _ := entry["deleted"].(bool); deleted {
+					continue
+				}
+				paths = append(paths, fmt.Sprint(entry["path"]))
+			}
+		}
+	}
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, preparePath(base, path))
+	}
+	return files
+}
+
+// fetchErrors returns file's error diagnostics. ok is false when no language
+// server handles the file or it did not answer in time.
+func (h *PostEditDiagnostics) fetchErrors(ctx context.Context, file string) ([]Diagnostic, bool) {
+	client, err := h.Proxy.clientForFile(file)
+	if err != nil {
+		return nil, false
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultPostEditTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	diags, err := client.GetDiagnostics(ctx, file)
+	if err != nil {
+		return nil, false
+	}
+	var errs []Diagnostic
+	for _, d := range diags {
+		if isErrorSeverity(d.Severity) {
+			errs = append(errs, d)
+		}
+	}
+	return errs, true
+}
+
+func (h *PostEditDiagnostics) baseline(file string) ([]Diagnostic, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	errs, ok := h.known[file]
+	return errs, ok
+}
+
+func (h *PostEditDiagnostics) remember(file string, errs []Diagnostic) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.known == nil {
+		h.known = make(map[string][]Diagnostic)
+	}
+	h.known[file] = errs
+}
+
+func (h *PostEditDiagnostics) relative(file string) string {
+	if h.BasePath != "" {
+		if rel, err := filepath.Rel(h.BasePath, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return file
+}
+
+// diagnosticKey identifies an error across edits; lines shift as code moves,
+// so they are left out.
+func diagnosticKey(d Diagnostic) string {
+	return d.Source + "\x00" + d.Message
+}
+
+// isErrorSeverity accepts the LSP numeric severity for errors and the name.
+func isErrorSeverity(severity string) bool {
+	return severity == "1" || strings.EqualFold(severity, "error")
+}
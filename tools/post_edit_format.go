@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// defaultFormatTimeout bounds one formatter run.
+const defaultFormatTimeout = 20 * time.Second
+
+// FormatFilePlaceholder in a formatter command is replaced by the path of
+// the file being formatted, relative to the workspace.
+const FormatFilePlaceholder = "{file}"
+
+// Formatter formats one language's files. Command reads the file on stdin
+// and writes the formatted code to stdout; when it is empty and LSP is set
+// the language server's formatting request is used instead.
+type Formatter struct {
+	Command []string
+	LSP     bool
+}
+
+// DefaultFormatters maps framework.CodeLanguage names to the usual
+// formatter of each language.
+func DefaultFormatters() map[string]Formatter {
+	prettier := Formatter{Command: []string{"prettier", "--stdin-filepath", FormatFilePlaceholder}}
+	return map[string]Formatter{
+		"go":         {Command: []string{"gofmt"}},
+		"python":     {Command: []string{"black", "--quiet", "-"}},
+		"rust":       {Command: []string{"rustfmt", "--emit", "stdout"}},
+		"javascript": prettier,
+		"typescript": prettier,
+		"css":        prettier,
+		"html":       prettier,
+		"json":       prettier,
+		"yaml":       prettier,
+	}
+}
+
+// PostEditFormat is a framework.ToolHook that formats every file a
+// successful edit changed with its language's Formatter, before later hooks
+// such as PostEditDiagnostics look at it. Formatter binaries must be allowed
+// by the manifest like any other executable; a denied or failing formatter
+// leaves the file as the agent wrote it and is reported under
+// "format_errors" in the tool result. Formatted files are listed under
+// "formatted".
+type PostEditFormat struct {
+	// Formatters is keyed by framework.CodeLanguage name; languages
+	// without an entry are left alone.
+	Formatters map[string]Formatter
+	Runner     framework.CommandRunner
+	Proxy      *Proxy
+	BasePath   string
+	Manager    *framework.PermissionManager
+	AgentID    string
+	// Timeout bounds each formatter run; zero uses twenty seconds.
+	Timeout time.Duration
+	// RequireApproval asks through Manager before running each formatter
+	// command, for degraded sandboxes and remote workspaces where nothing
+	// contains it.
+	RequireApproval bool
+}
+
+// BeforeTool implements framework.ToolHook.
+func (h *PostEditFormat) BeforeTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}) {
+}
+
+// AfterTool formats the files a successful edit changed.
+func (h *PostEditFormat) AfterTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}, result *framework.ToolResult, err error) {
+	if h == nil || len(h.Formatters) == 0 || err != nil || result == nil || !result.Success {
+		return
+	}
+	var formatted []string
+	failures := map[string]string{}
+	for _, file := range editedFiles(h.BasePath, tool.Name(), args, result) {
+		formatter, ok := h.Formatters[framework.CodeLanguage(file)]
+		if !ok {
+			continue
+		}
+		changed, err := h.format(ctx, state, file, formatter)
+		switch {
+		case err != nil:
+			failures[h.relative(file)] = err.Error()
+		case changed:
+			formatted = append(formatted, h.relative(file))
+		}
+	}
+	if len(formatted) == 0 && len(failures) == 0 {
+		return
+	}
+	if result.Data == nil {
+		result.Data = map[string]interface{}{}
+	}
+	if len(formatted) > 0 {
+		result.Data["formatted"] = formatted
+	}
+	if len(failures) > 0 {
+		result.Data["format_errors"] = failures
+	}
+}
+
+// format rewrites file when formatter changes it, keeping the task's record
+// of the file's content in step so the agent's next edit is not mistaken
+// for a conflict. The file stays locked while the formatter runs so a
+// concurrent edit is not overwritten.
+func (h *PostEditFormat) format(ctx context.Context, state *framework.Context, file string, formatter Formatter) (bool, error) {
+	unlock := fileLocks.Lock(file)
+	defer unlock()
+	original, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultFormatTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var out string
+	switch {
+	case len(formatter.Command) > 0:
+		out, err = h.runCommand(ctx, file, formatter.Command, string(original))
+	case formatter.LSP:
+		out, err = h.formatLSP(ctx, file, string(original))
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(out) == "" && strings.TrimSpace(string(original)) != "" {
+		return false, fmt.Errorf("formatter returned no output")
+	}
+	if out == string(original) {
+		return false, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(file, []byte(out), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	recordFileHash(state, file, []byte(out))
+	return true, nil
+}
+
+func (h *PostEditFormat) runCommand(ctx context.Context, file string, command []string, input string) (string, error) {
+	if h.Runner == nil {
+		return "", fmt.Errorf("no command runner for %s", command[0])
+	}
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.ReplaceAll(arg, FormatFilePlaceholder, h.relative(file))
+	}
+	if h.Manager != nil {
+		if err := h.Manager.CheckExecutable(ctx, h.AgentID, args[0], args[1:], nil); err != nil {
+			return "", err
+		}
+	}
+	if h.RequireApproval {
+		if h.Manager == nil {
+			return "", fmt.Errorf("%s blocked: approval required but permission manager missing", args[0])
+		}
+		if err := h.Manager.RequireApproval(ctx, h.AgentID, framework.PermissionDescriptor{
+			Type:         framework.PermissionTypeHITL,
+			Action:       "format:exec",
+			Resource:     strings.Join(command, " "),
+			RequiresHITL: true,
+		}, "post-edit formatter runs outside the sandbox", framework.GrantScopeOneTime, framework.RiskLevelMedium, 0); err != nil {
+			return "", err
+		}
+	}
+	stdout, stderr, err := h.Runner.Run(ctx, framework.CommandRequest{
+		Workdir: h.BasePath,
+		Args:    args,
+		Input:   input,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return "", fmt.Errorf("%s: %s", args[0], firstLines(msg, 5))
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	return stdout, nil
+}
+
+func (h *PostEditFormat) formatLSP(ctx context.Context, file, code string) (string, error) {
+	if h.Proxy == nil {
+		return "", fmt.Errorf("no language server configured")
+	}
+	client, err := h.Proxy.clientForFile(file)
+	if err != nil {
+		return "", err
+	}
+	return client.Format(ctx, FormatRequest{File: file, Code: code})
+}
+
+func (h *PostEditFormat) relative(file string) string {
+	return workspaceRelative(h.BasePath, file)
+}
+
+// workspaceRelative returns file relative to base when it lies inside it.
+func workspaceRelative(base, file string) string {
+	if base != "" {
+		if rel, err := filepath.Rel(base, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return file
+}
+
+// firstLines keeps the first n lines of text.
+func firstLines(text string, n int) string {
+	lines := strings.SplitN(text, "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
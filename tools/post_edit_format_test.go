@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// indentRunner stands in for a formatter: it turns leading double spaces
+// into tabs, or fails when fail is set.
+type indentRunner struct {
+	fail  bool
+	calls []framework.CommandRequest
+}
+
+func (r *indentRunner) Run(ctx context.Context, req framework.CommandRequest) (string, string, error) {
+	r.calls = append(r.calls, req)
+	if r.fail {
+		return "", "syntax error on line 2\n", errors.New("exit status 2")
+	}
+	return strings.ReplaceAll(req.Input, "\n  ", "\n\t"), "", nil
+}
+
+func TestPostEditFormatRewritesEditedFiles(t *testing.T) {
+	dir := t.TempDir()
+	runner := &indentRunner{}
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	registry.UseToolHook(&PostEditFormat{
+		Formatters: map[string]Formatter{
+			"go":         {Command: []string{"gofmt"}},
+			"javascript": {Command: []string{"prettier", "--stdin-filepath", FormatFilePlaceholder}},
+		},
+		Runner:   runner,
+		BasePath: dir,
+	})
+	tool, ok := registry.Get("file_write")
+	require.True(t, ok)
+	state := framework.NewContext()
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, state, map[string]interface{}{
+		"path":    "main.go",
+		"content": "package main\n\nfunc main() {\n  println()\n}\n",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"main.go"}, res.Data["formatted"])
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nfunc main() {\n\tprintln()\n}\n", string(data))
+	require.Equal(t, []string{"gofmt"}, runner.calls[0].Args)
+
+	res, err = tool.Execute(ctx, state, map[string]interface{}{"path": "main.go", "content": "package main\n"})
+	require.NoError(t, err)
+	require.True(t, res.Success, "formatting is not mistaken for an outside change")
+	require.NotContains(t, res.Data, "formatted", "already formatted files are left alone")
+
+	_, err = tool.Execute(ctx, state, map[string]interface{}{"path": "web/app.js", "content": "x\n"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"prettier", "--stdin-filepath", "web/app.js"}, runner.calls[len(runner.calls)-1].Args)
+
+	calls := len(runner.calls)
+	_, err = tool.Execute(ctx, state, map[string]interface{}{"path": "notes.txt", "content": "  text\n  more\n"})
+	require.NoError(t, err)
+	require.Len(t, runner.calls, calls, "languages without a formatter are skipped")
+}
+
+func TestPostEditFormatReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	registry.UseToolHook(&PostEditFormat{
+		Formatters: map[string]Formatter{"go": {Command: []string{"gofmt"}}},
+		Runner:     &indentRunner{fail: true},
+		BasePath:   dir,
+	})
+	tool, ok := registry.Get("file_write")
+	require.True(t, ok)
+
+	content := "package main\n\nfunc main() {\n  println(\n}\n"
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go", "content": content})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	require.Equal(t, map[string]string{"main.go": "gofmt: syntax error on line 2"}, res.Data["format_errors"])
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, content, string(data), "the file keeps the agent's content")
+}
+
+func TestPostEditFormatAsksWhenApprovalRequired(t *testing.T) {
+	dir := t.TempDir()
+	perms := framework.NewFileSystemPermissionSet(dir, framework.FileSystemRead, framework.FileSystemWrite)
+	perms.Executables = []framework.ExecutablePermission{{Binary: "gofmt", Args: []string{"*"}}}
+	hitl := &approvingHITL{deny: true}
+	manager, err := framework.NewPermissionManager(dir, perms, nil, hitl)
+	require.NoError(t, err)
+	runner := &indentRunner{}
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	registry.UseToolHook(&PostEditFormat{
+		Formatters:      map[string]Formatter{"go": {Command: []string{"gofmt"}}},
+		Runner:          runner,
+		BasePath:        dir,
+		Manager:         manager,
+		AgentID:         "agent",
+		RequireApproval: true,
+	})
+	tool, ok := registry.Get("file_write")
+	require.True(t, ok)
+
+	content := "package main\n\nfunc main() {\n  println()\n}\n"
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go", "content": content})
+	require.NoError(t, err)
+	require.Len(t, hitl.requests, 1)
+	require.Equal(t, "format:exec", hitl.requests[0].Permission.Action)
+	require.Equal(t, "gofmt", hitl.requests[0].Permission.Resource)
+	require.Empty(t, runner.calls, "a denied formatter never runs")
+	require.Contains(t, res.Data["format_errors"], "main.go")
+
+	hitl.deny = false
+	_, err = tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"path": "main.go", "content": content})
+	require.NoError(t, err)
+	require.Len(t, runner.calls, 1, "an approved formatter runs")
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// wordLSPClient reports an error for every word after "undefined" in a file.
+type wordLSPClient struct {
+	fakeLSPClient
+}
+
+func (c *wordLSPClient) GetDiagnostics(ctx context.Context, file string) ([]Diagnostic, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var diags []Diagnostic
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		for j := 0; j+1 < len(fields); j++ {
+			if fields[j] == "undefined" {
+				diags = append(diags, Diagnostic{Severity: "1", Source: "compiler", Message: "undefined: " + fields[j+1], Line: i})
+			}
+		}
+		if strings.Contains(line, "unused") {
+			diags = append(diags, Diagnostic{Severity: "2", Source: "vet", Message: "unused", Line: i})
+		}
+	}
+	return diags, nil
+}
+
+func TestPostEditDiagnosticsMarksNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nundefined legacy\n"), 0o644))
+	proxy := NewProxy(0)
+	proxy.Register("go", &wordLSPClient{})
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	registry.UseToolHook(&PostEditDiagnostics{Proxy: proxy, BasePath: dir})
+	tool, ok := registry.Get("file_write")
+	require.True(t, ok)
+	state := framework.NewContext()
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, state, map[string]interface{}{
+		"path":    "main.go",
+		"content": "package main\nundefined legacy\nundefined broken // unused\n",
+	})
+	require.NoError(t, err)
+	want := []framework.EditDiagnostic{
+		{File: "main.go", Line: 2, Source: "compiler", Message: "undefined: legacy"},
+		{File: "main.go", Line: 3, Source: "compiler", Message: "undefined: broken", New: true},
+	}
+	require.Equal(t, want, res.Data["post_edit_diagnostics"])
+	require.Equal(t, want, framework.PostEditDiagnostics(state))
+	count, _ := state.Get(framework.PostEditNewErrorsKey)
+	require.Equal(t, 1, count)
+
+	_, err = tool.Execute(ctx, state, map[string]interface{}{"path": "lib.go", "content": "package main\nundefined helper\n"})
+	require.NoError(t, err)
+	diags := framework.PostEditDiagnostics(state)
+	require.Len(t, diags, 3, "other files keep their diagnostics")
+	require.Equal(t, framework.EditDiagnostic{File: "lib.go", Line: 2, Source: "compiler", Message: "undefined: helper", New: true}, diags[2])
+	count, _ = state.Get(framework.PostEditNewErrorsKey)
+	require.Equal(t, 2, count)
+
+	res, err = tool.Execute(ctx, state, map[string]interface{}{"path": "main.go", "content": "package main\n"})
+	require.NoError(t, err)
+	require.NotContains(t, res.Data, "post_edit_diagnostics")
+	require.Len(t, framework.PostEditDiagnostics(state), 1, "fixed file drops its errors")
+
+	res, err = tool.Execute(ctx, state, map[string]interface{}{"path": "notes.txt", "content": "undefined thing"})
+	require.NoError(t, err)
+	require.NotContains(t, res.Data, "post_edit_diagnostics", "files without a server are skipped")
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// The file tools implement framework.ChangePreviewer so approval prompts show
+// the diff a call would apply.
+
+func (t *WriteFileTool) PreviewChange(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, rel := previewPath(t.BasePath, fmt.Sprint(args["path"]))
+	before, existed, err := readForPreview(path)
+	if err != nil {
+		return "", err
+	}
+	oldPath := rel
+	if !existed {
+		oldPath = ""
+	}
+	return framework.UnifiedDiff(oldPath, rel, before, t.Code.Process(fmt.Sprint(args["content"]), path)), nil
+}
+
+func (t *CreateFileTool) PreviewChange(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, rel := previewPath(t.BasePath, fmt.Sprint(args["path"]))
+	content := ""
+	if args["content"] != nil {
+		content = t.Code.Process(fmt.Sprint(args["content"]), path)
+	}
+	return framework.UnifiedDiff("", rel, "", content), nil
+}
+
+func (t *DeleteFileTool) PreviewChange(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, rel := previewPath(t.BasePath, fmt.Sprint(args["path"]))
+	before, existed, err := readForPreview(path)
+	if err != nil || !existed {
+		return "", err
+	}
+	return framework.UnifiedDiff(rel, "", before, ""), nil
+}
+
+func (t *EditFileTool) PreviewChange(ctx context.Context, args map[string]interface{}) (string, error) {
+	edit, err := parseFileEdit(args)
+	if err != nil {
+		return "", err
+	}
+	path, rel := previewPath(t.BasePath, fmt.Sprint(args["path"]))
+	before, existed, err := readForPreview(path)
+	if err != nil || !existed {
+		return "", err
+	}
+	after, _, err := edit.apply(before)
+	if err != nil {
+		return "", err
+	}
+	return framework.UnifiedDiff(rel, rel, before, after), nil
+}
+
+// PreviewChange returns the patch itself; it already is the diff.
+func (t *ApplyPatchTool) PreviewChange(ctx context.Context, args map[string]interface{}) (string, error) {
+	if args["patch"] == nil {
+		return "", errors.New("patch required")
+	}
+	return fmt.Sprint(args["patch"]), nil
+}
+
+// previewPath resolves path like the tools do and returns it with its
+// workspace-relative form for diff headers.
+func previewPath(base, path string) (string, string) {
+	abs := preparePath(base, path)
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		rel = abs
+	}
+	return abs, filepath.ToSlash(rel)
+}
+
+func readForPreview(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
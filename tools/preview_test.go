@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TestWritePreviewAppliesAsPatch checks the previewed diff is exactly the
+// change file_write makes, by applying it with file_patch.
+func TestWritePreviewAppliesAsPatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	before := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	after := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n\tfmt.Println(\"bye\")\n}"
+	require.NoError(t, os.WriteFile(path, []byte(before), 0o644))
+
+	write := &WriteFileTool{BasePath: dir}
+	diff, err := write.PreviewChange(context.Background(), map[string]interface{}{"path": "main.go", "content": after})
+	require.NoError(t, err)
+	require.Contains(t, diff, "--- a/main.go\n+++ b/main.go\n")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, before, string(data), "previews must not write")
+
+	patch := &ApplyPatchTool{BasePath: dir}
+	_, err = patch.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"patch": diff})
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, after, string(data))
+
+	created, err := write.PreviewChange(context.Background(), map[string]interface{}{"path": "pkg/new.go", "content": "package pkg\n"})
+	require.NoError(t, err)
+	require.Contains(t, created, "--- /dev/null\n+++ b/pkg/new.go\n")
+}
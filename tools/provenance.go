@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// ContextProvenance is a framework.ToolHook that records what each
+// successful tool call showed the agent under framework.ContextSourcesKey,
+// so the final answer can cite the files and line ranges behind its claims.
+// Reads and searches become file sources; other tools that do not change
+// the workspace are recorded by name.
+type ContextProvenance struct {
+	BasePath string
+}
+
+// provenanceSkipped are the tools whose results are not evidence: they
+// change the workspace rather than describe it.
+var provenanceSkipped = map[string]bool{
+	"file_write":    true,
+	"file_edit":     true,
+	"file_create":   true,
+	"file_delete":   true,
+	"file_patch":    true,
+	"artifact_save": true,
+}
+
+// BeforeTool implements framework.ToolHook.
+func (h *ContextProvenance) BeforeTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}) {
+}
+
+// AfterTool records the sources of a successful call.
+func (h *ContextProvenance) AfterTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}, result *framework.ToolResult, err error) {
+	if h == nil || state == nil || err != nil || result == nil || !result.Success {
+		return
+	}
+	name := tool.Name()
+	if provenanceSkipped[name] {
+		return
+	}
+	sources := h.sources(name, args, result)
+	if len(sources) == 0 {
+		sources = []framework.ContextSource{{Kind: framework.SourceTool, Tool: name}}
+	}
+	framework.RecordContextSources(state, sources...)
+}
+
+func (h *ContextProvenance) sources(name string, args map[string]interface{}, result *framework.ToolResult) []framework.ContextSource {
+	switch name {
+	case "file_read":
+		path, _ := args["path"].(string)
+		content, _ := result.Data["content"].(string)
+		if path == "" {
+			return nil
+		}
+		lines := strings.Count(content, "\n")
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			lines++
+		}
+		return []framework.ContextSource{h.file(path, 1, lines)}
+	case "search_grep":
+		matches, _ := result.Data["matches"].([]grepMatch)
+		sources := make([]framework.ContextSource, 0, len(matches))
+		for _, m := range matches {
+			sources = append(sources, h.file(m.File, m.Line-len(m.Before), m.Line+len(m.After)))
+		}
+		return sources
+	case "search_semantic":
+		hits, _ := result.Data["results"].([]map[string]interface{})
+		sources := make([]framework.ContextSource, 0, len(hits))
+		for _, hit := range hits {
+			file, _ := hit["file"].(string)
+			if file == "" {
+				continue
+			}
+			start, _ := hit["start_line"].(int)
+			end, _ := hit["end_line"].(int)
+			sources = append(sources, h.file(file, start, end))
+		}
+		return sources
+	}
+	return nil
+}
+
+// file builds a file source with a workspace-relative path.
+func (h *ContextProvenance) file(path string, start, end int) framework.ContextSource {
+	if start < 1 || end < 1 {
+		start, end = 0, 0
+	}
+	return framework.ContextSource{Kind: framework.SourceFile, Path: workspaceRelative(h.BasePath, preparePath(h.BasePath, path)), StartLine: start, EndLine: end}
+}
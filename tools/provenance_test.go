@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestContextProvenanceRecordsReadsAndSearches(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&ReadFileTool{BasePath: dir}))
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	require.NoError(t, registry.Register(&GrepTool{BasePath: dir}))
+	registry.UseToolHook(&ContextProvenance{BasePath: dir})
+	state := framework.NewContext()
+	ctx := context.Background()
+
+	run := func(name string, args map[string]interface{}) {
+		tool, ok := registry.Get(name)
+		require.True(t, ok)
+		_, err := tool.Execute(ctx, state, args)
+		require.NoError(t, err)
+	}
+	run("file_write", map[string]interface{}{"path": "lib.go", "content": "package main\n\nfunc helper() {}\n"})
+	require.Empty(t, framework.ContextSources(state), "writes are not sources")
+
+	run("search_grep", map[string]interface{}{"pattern": "func helper"})
+	run("file_read", map[string]interface{}{"path": "main.go"})
+	require.Equal(t, []framework.ContextSource{
+		{Kind: framework.SourceFile, Path: "lib.go", StartLine: 3, EndLine: 3},
+		{Kind: framework.SourceFile, Path: "main.go", StartLine: 1, EndLine: 3},
+	}, framework.ContextSources(state))
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+)
+
+const (
+	// RiskLevelHigh marks files the planner should treat as fragile.
+	RiskLevelHigh = "high"
+	// RiskLevelMedium marks files with some history of churn or complexity.
+	RiskLevelMedium = "medium"
+	// RiskLevelLow marks files without notable risk signals.
+	RiskLevelLow = "low"
+
+	// RiskKnowledgePrefix namespaces risk reports stored as context knowledge.
+	RiskKnowledgePrefix = "file_risk:"
+)
+
+// FileRiskReport summarizes the signals that feed into a file's risk score.
+type FileRiskReport struct {
+	File               string    `json:"file"`
+	Score              float64   `json:"score"`
+	Level              string    `json:"level"`
+	Commits            int       `json:"commits"`
+	Complexity         int       `json:"complexity"`
+	Lines              int       `json:"lines"`
+	Diagnostics        int       `json:"diagnostics"`
+	DiagnosticsDensity float64   `json:"diagnostics_density"`
+	ComputedAt         time.Time `json:"computed_at"`
+}
+
+func init() {
+	// Reports are stored as context knowledge, which Context.Clone copies via gob.
+	gob.Register(FileRiskReport{})
+}
+
+// FileRiskTool combines git churn, AST complexity, and diagnostics density
+// into a single per-file score. Each signal is optional: missing git history,
+// an unindexed file, or an absent language server simply contributes zero.
+type FileRiskTool struct {
+	RepoPath string
+	Runner   framework.CommandRunner
+	Index    *ast.IndexManager
+	Proxy    *Proxy
+	// Since bounds the git history window (defaults to "6 months ago").
+	Since   string
+	manager *framework.PermissionManager
+	agentID string
+	spec    *framework.AgentRuntimeSpec
+}
+
+func (t *FileRiskTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
+	t.manager = manager
+	t.agentID = agentID
+}
+
+func (t *FileRiskTool) SetAgentSpec(spec *framework.AgentRuntimeSpec, agentID string) {
+	t.spec = spec
+	t.agentID = agentID
+}
+
+func (t *FileRiskTool) Name() string { return "analyze_file_risk" }
+func (t *FileRiskTool) Description() string {
+	return "Scores how fragile a file is using git churn, AST complexity, and diagnostics density."
+}
+func (t *FileRiskTool) Category() string { return "analysis" }
+func (t *FileRiskTool) Parameters() []framework.ToolParameter {
+	return []framework.ToolParameter{
+		{Name: "file", Type: "string", Required: true},
+		{Name: "since", Type: "string", Description: "Git history window (e.g. '6 months ago')", Required: false},
+	}
+}
+
+func (t *FileRiskTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
+	file := strings.TrimSpace(fmt.Sprint(args["file"]))
+	if file == "" || file == "<nil>" {
+		return nil, fmt.Errorf("file parameter required")
+	}
+	path := file
+	if !filepath.IsAbs(path) && t.RepoPath != "" {
+		path = filepath.Join(t.RepoPath, path)
+	}
+	if t.manager != nil {
+		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
+			return nil, err
+		}
+	}
+	since := t.Since
+	if raw, ok := args["since"]; ok && raw != nil && fmt.Sprint(raw) != "" {
+		since = fmt.Sprint(raw)
+	}
+	report := FileRiskReport{File: file, ComputedAt: time.Now().UTC()}
+	report.Commits = t.churn(ctx, file, since)
+	report.Complexity, report.Lines = t.complexity(path, file)
+	report.Diagnostics = t.diagnostics(ctx, path)
+	if report.Lines > 0 {
+		report.DiagnosticsDensity = float64(report.Diagnostics) * 100 / float64(report.Lines)
+	}
+	report.Score = ScoreFileRisk(report.Commits, report.Complexity, report.DiagnosticsDensity)
+	report.Level = RiskLevelForScore(report.Score)
+	if state != nil {
+		state.SetKnowledge(RiskKnowledgePrefix+file, report)
+	}
+	return &framework.ToolResult{
+		Success: true,
+		Data: map[string]interface{}{
+			"file":                report.File,
+			"score":               report.Score,
+			"level":               report.Level,
+			"commits":             report.Commits,
+			"complexity":          report.Complexity,
+			"lines":               report.Lines,
+			"diagnostics":         report.Diagnostics,
+			"diagnostics_density": report.DiagnosticsDensity,
+		},
+	}, nil
+}
+
+func (t *FileRiskTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
+	return t.Runner != nil || t.Index != nil || t.Proxy != nil
+}
+
+func (t *FileRiskTool) Permissions() framework.ToolPermissions {
+	return framework.ToolPermissions{Permissions: framework.NewExecutionPermissionSet(t.RepoPath, "git", []string{"log"})}
+}
+
+// churn counts commits touching the file within the configured window.
+func (t *FileRiskTool) churn(ctx context.Context, file, since string) int {
+	if t.Runner == nil {
+		return 0
+	}
+	if since == "" {
+		since = "6 months ago"
+	}
+	args := []string{"log", "--follow", "--format=%H", "--since=" + since, "--", file}
+	if err := authorizeCommand(ctx, t.manager, t.agentID, t.spec, append([]string{"git"}, args...)); err != nil {
+		return 0
+	}
+	stdout, _, err := t.Runner.Run(ctx, framework.CommandRequest{
+		Workdir: t.RepoPath,
+		Args:    append([]string{"git"}, args...),
+		Timeout: 30 * time.Second,
+	})
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// complexity reads the AST metadata for the file. Parsers that do not compute
+// cyclomatic complexity fall back to the number of indexed nodes and edges.
+func (t *FileRiskTool) complexity(paths ...string) (int, int) {
+	if t.Index == nil {
+		return 0, 0
+	}
+	store := t.Index.Store()
+	for _, path := range paths {
+		meta, err := store.GetFileByPath(path)
+		if err != nil || meta == nil {
+			continue
+		}
+		complexity := meta.Complexity
+		if complexity == 0 {
+			complexity = meta.NodeCount + meta.EdgeCount
+		}
+		return complexity, meta.LineCount
+	}
+	return 0, 0
+}
+
+func (t *FileRiskTool) diagnostics(ctx context.Context, path string) int {
+	if t.Proxy == nil {
+		return 0
+	}
+	client, err := t.Proxy.clientForFile(path)
+	if err != nil {
+		return 0
+	}
+	diags, err := client.GetDiagnostics(ctx, path)
+	if err != nil {
+		return 0
+	}
+	return len(diags)
+}
+
+// ScoreFileRisk blends the raw signals into a 0..1 score. Churn dominates
+// because historically volatile files are the strongest predictor of
+// regressions; complexity and diagnostics act as tie-breakers.
+func ScoreFileRisk(commits, complexity int, diagnosticsDensity float64) float64 {
+	churn := math.Min(1, float64(commits)/20)
+	structure := math.Min(1, float64(complexity)/200)
+	diags := math.Min(1, diagnosticsDensity/5)
+	score := 0.5*churn + 0.3*structure + 0.2*diags
+	return math.Round(score*100) / 100
+}
+
+// RiskLevelForScore buckets a score into low/medium/high.
+func RiskLevelForScore(score float64) string {
+	switch {
+	case score >= 0.6:
+		return RiskLevelHigh
+	case score >= 0.3:
+		return RiskLevelMedium
+	default:
+		return RiskLevelLow
+	}
+}
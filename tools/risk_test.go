@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+type stubRunner struct {
+	stdout string
+	calls  [][]string
+}
+
+func (r *stubRunner) Run(ctx context.Context, req framework.CommandRequest) (string, string, error) {
+	r.calls = append(r.calls, req.Args)
+	return r.stdout, "", nil
+}
+
+func TestFileRiskToolScoresChurn(t *testing.T) {
+	runner := &stubRunner{stdout: strings.Repeat("abc123\n", 25)}
+	tool := &FileRiskTool{RepoPath: t.TempDir(), Runner: runner}
+	state := framework.NewContext()
+
+	res, err := tool.Execute(context.Background(), state, map[string]interface{}{"file": "main.go"})
+	assert.NoError(t, err)
+	assert.Equal(t, 25, res.Data["commits"])
+	assert.Equal(t, 0.5, res.Data["score"])
+	assert.Equal(t, RiskLevelMedium, res.Data["level"])
+
+	fact, ok := state.GetKnowledge(RiskKnowledgePrefix + "main.go")
+	assert.True(t, ok)
+	assert.Equal(t, 25, fact.(FileRiskReport).Commits)
+	assert.Contains(t, runner.calls[0], "--follow")
+}
+
+func TestScoreFileRiskLevels(t *testing.T) {
+	assert.Equal(t, RiskLevelLow, RiskLevelForScore(ScoreFileRisk(0, 0, 0)))
+	assert.Equal(t, RiskLevelHigh, RiskLevelForScore(ScoreFileRisk(40, 200, 0)))
+}
@@ -1,21 +1,31 @@
 package tools
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
 )
 
-// GrepTool implements plain text search.
+// GrepTool searches file contents by literal text or regular expression,
+// skipping paths excluded by .gitignore and .relurpifyignore. With a Runner
+// it uses ripgrep when the runner can start rg, and falls back to the
+// built-in search otherwise.
 type GrepTool struct {
 	BasePath string
-	manager  *framework.PermissionManager
-	agentID  string
+	// Runner, when set, lets the tool run rg; Permissions then declares it.
+	Runner  framework.CommandRunner
+	manager *framework.PermissionManager
+	agentID string
+
+	rgOnce      sync.Once
+	rgAvailable bool
 }
 
 func (t *GrepTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
@@ -23,18 +33,17 @@ func (t *GrepTool) SetPermissionManager(manager *framework.PermissionManager, ag
 	t.agentID = agentID
 }
 
-func (t *GrepTool) Name() string        { return "search_grep" }
-func (t *GrepTool) Description() string { return "Searches files using substring matching." }
-func (t *GrepTool) Category() string    { return "search" }
+func (t *GrepTool) Name() string { return "search_grep" }
+func (t *GrepTool) Description() string {
+	return "Searches files for text or a regular expression, honouring .gitignore and .relurpifyignore."
+}
+func (t *GrepTool) Category() string { return "search" }
 func (t *GrepTool) Parameters() []framework.ToolParameter {
-	return []framework.ToolParameter{
-		{Name: "pattern", Type: "string", Required: true},
-		{Name: "directory", Type: "string", Required: false, Default: "."},
-	}
+	return grepParameters()
 }
 func (t *GrepTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
 	root := fmt.Sprint(args["directory"])
-	if root == "" {
+	if args["directory"] == nil || root == "" {
 		root = "."
 	}
 	root = preparePath(t.BasePath, root)
@@ -43,65 +52,89 @@ func (t *GrepTool) Execute(ctx context.Context, state *framework.Context, args m
 			return nil, err
 		}
 	}
-	pattern := strings.ToLower(fmt.Sprint(args["pattern"]))
-	type match struct {
-		File    string `json:"file"`
-		Line    int    `json:"line"`
-		Content string `json:"content"`
-	}
-	var matches []match
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			if strings.Contains(path, string(filepath.Separator)+".git") {
-				return filepath.SkipDir
-			}
-			if t.manager != nil {
-				if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemList, path); err != nil {
-					return filepath.SkipDir
-				}
-			}
-			return nil
-		}
-		if t.manager != nil {
-			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, path); err != nil {
-				return nil
-			}
-		}
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		line := 1
-		for scanner.Scan() {
-			text := scanner.Text()
-			if strings.Contains(strings.ToLower(text), pattern) {
-				matches = append(matches, match{File: path, Line: line, Content: text})
-			}
-			line++
-		}
-		return scanner.Err()
-	})
+	opts, err := parseGrepOptions(args, false)
 	if err != nil {
 		return nil, err
 	}
-	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"matches": matches}}, nil
+	search := newGrepSearch(t.BasePath, root, opts, t.checkAccess(ctx))
+	if t.useRipgrep(ctx, search) && search.ripgrep(ctx, t.Runner) {
+		return grepResult(search, "ripgrep"), nil
+	}
+	search.matches, search.truncated = nil, false
+	if err := search.walk(ctx); err != nil {
+		return nil, err
+	}
+	return grepResult(search, "builtin"), nil
+}
+
+func (t *GrepTool) checkAccess(ctx context.Context) func(framework.FileSystemAction, string) error {
+	if t.manager == nil {
+		return nil
+	}
+	return func(action framework.FileSystemAction, path string) error {
+		return t.manager.CheckFileAccess(ctx, t.agentID, action, path)
+	}
+}
+
+// useRipgrep reports whether search may run through rg: a runner is set,
+// rg answers --version in it, and the permission manager allows the call.
+func (t *GrepTool) useRipgrep(ctx context.Context, search *grepSearch) bool {
+	if t.Runner == nil {
+		return false
+	}
+	t.rgOnce.Do(func() {
+		_, _, err := t.Runner.Run(ctx, framework.CommandRequest{
+			Workdir: t.BasePath,
+			Args:    []string{"rg", "--version"},
+			Timeout: 10 * time.Second,
+		})
+		t.rgAvailable = err == nil
+	})
+	if !t.rgAvailable {
+		return false
+	}
+	if t.manager != nil {
+		args := search.ripgrepArgs()
+		if err := t.manager.CheckExecutable(ctx, t.agentID, args[0], args[1:], nil); err != nil {
+			return false
+		}
+	}
+	return true
 }
+
 func (t *GrepTool) IsAvailable(ctx context.Context, state *framework.Context) bool { return true }
 
 func (t *GrepTool) Permissions() framework.ToolPermissions {
-	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemList)}
+	perms := framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemList)
+	if t.Runner != nil {
+		perms.Executables = append(perms.Executables, framework.ExecutablePermission{Binary: "rg", Args: []string{"*"}})
+	}
+	return framework.ToolPermissions{Permissions: perms}
 }
 
-// SimilarityTool finds similar snippets using a naive approach.
+// SimilarityTool finds code similar to a snippet. With a built embeddings
+// index it ranks the indexed chunks below the directory by embedding
+// similarity; without one, or when the embedding model is unreachable, it
+// compares the character sets of whole files.
 type SimilarityTool struct {
 	BasePath string
-	manager  *framework.PermissionManager
-	agentID  string
+	// Index is the workspace embeddings index; nil disables it.
+	Index   *ast.EmbeddingIndex
+	manager *framework.PermissionManager
+	agentID string
+}
+
+// similarityLimit bounds the chunks returned from the embeddings index.
+const similarityLimit = 10
+
+// similarMatch is one search_find_similar result. Lines are set for
+// embedding matches, which cover a chunk rather than a whole file.
+type similarMatch struct {
+	File      string  `json:"file"`
+	Score     float64 `json:"score"`
+	Fragment  string  `json:"fragment"`
+	StartLine int     `json:"start_line,omitempty"`
+	EndLine   int     `json:"end_line,omitempty"`
 }
 
 func (t *SimilarityTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
@@ -125,13 +158,11 @@ func (t *SimilarityTool) Execute(ctx context.Context, state *framework.Context,
 			return nil, err
 		}
 	}
-	target := sanitizeSnippet(fmt.Sprint(args["snippet"]))
-	type match struct {
-		File     string  `json:"file"`
-		Score    float64 `json:"score"`
-		Fragment string  `json:"fragment"`
+	if matches, ok := t.similarEmbeddings(ctx, fmt.Sprint(args["snippet"]), root); ok {
+		return &framework.ToolResult{Success: true, Data: map[string]interface{}{"matches": matches, "source": "embeddings"}}, nil
 	}
-	var matches []match
+	target := sanitizeSnippet(fmt.Sprint(args["snippet"]))
+	var matches []similarMatch
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
 			if err == nil && info.IsDir() && strings.Contains(path, ".git") {
@@ -156,7 +187,7 @@ func (t *SimilarityTool) Execute(ctx context.Context, state *framework.Context,
 		content := string(data)
 		score := jaccard(target, sanitizeSnippet(content))
 		if score > 0.3 {
-			matches = append(matches, match{File: path, Score: score, Fragment: summarize(content)})
+			matches = append(matches, similarMatch{File: path, Score: score, Fragment: summarize(content)})
 		}
 		return nil
 	})
@@ -165,19 +196,56 @@ func (t *SimilarityTool) Execute(ctx context.Context, state *framework.Context,
 	}
 	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"matches": matches}}, nil
 }
+
+// similarEmbeddings ranks the indexed chunks under root by similarity to
+// snippet and reports false when the index is unavailable, so Execute can
+// fall back to character overlap.
+func (t *SimilarityTool) similarEmbeddings(ctx context.Context, snippet, root string) ([]similarMatch, bool) {
+	if t.Index == nil {
+		return nil, false
+	}
+	found, err := t.Index.SearchUnder(ctx, snippet, root, similarityLimit)
+	if err != nil || len(found) == 0 {
+		return nil, false
+	}
+	matches := make([]similarMatch, 0, len(found))
+	for _, match := range found {
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, match.Path); err != nil {
+				continue
+			}
+		}
+		matches = append(matches, similarMatch{
+			File:      match.Path,
+			Score:     match.Score,
+			Fragment:  match.Content,
+			StartLine: match.StartLine,
+			EndLine:   match.EndLine,
+		})
+	}
+	return matches, true
+}
+
 func (t *SimilarityTool) IsAvailable(ctx context.Context, state *framework.Context) bool { return true }
 
 func (t *SimilarityTool) Permissions() framework.ToolPermissions {
 	return framework.ToolPermissions{Permissions: framework.NewFileSystemPermissionSet(t.BasePath, framework.FileSystemRead, framework.FileSystemList)}
 }
 
-// SemanticSearchTool uses a vector-like heuristic (currently substring).
+// SemanticSearchTool ranks chunks of the embeddings index by similarity to
+// the query. Without a built index, or when the embedding model is
+// unreachable, it falls back to substring matching over whole files.
 type SemanticSearchTool struct {
 	BasePath string
-	manager  *framework.PermissionManager
-	agentID  string
+	// Index is the workspace embeddings index; nil disables it.
+	Index   *ast.EmbeddingIndex
+	manager *framework.PermissionManager
+	agentID string
 }
 
+// semanticSearchLimit bounds the chunks returned from the embeddings index.
+const semanticSearchLimit = 10
+
 func (t *SemanticSearchTool) SetPermissionManager(manager *framework.PermissionManager, agentID string) {
 	t.manager = manager
 	t.agentID = agentID
@@ -185,20 +253,26 @@ func (t *SemanticSearchTool) SetPermissionManager(manager *framework.PermissionM
 
 func (t *SemanticSearchTool) Name() string { return "search_semantic" }
 func (t *SemanticSearchTool) Description() string {
-	return "Performs semantic search using heuristic embeddings."
+	return "Finds code related to a natural-language query; returns ranked chunks with file and line ranges."
 }
 func (t *SemanticSearchTool) Category() string { return "search" }
 func (t *SemanticSearchTool) Parameters() []framework.ToolParameter {
-	return []framework.ToolParameter{{Name: "query", Type: "string", Required: true}}
+	return []framework.ToolParameter{
+		{Name: "query", Type: "string", Required: true},
+		{Name: "limit", Type: "integer", Required: false},
+	}
 }
 func (t *SemanticSearchTool) Execute(ctx context.Context, state *framework.Context, args map[string]interface{}) (*framework.ToolResult, error) {
-	query := strings.ToLower(fmt.Sprint(args["query"]))
-	var hits []map[string]interface{}
 	if t.manager != nil {
 		if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemList, t.BasePath); err != nil {
 			return nil, err
 		}
 	}
+	if hits, ok := t.searchEmbeddings(ctx, fmt.Sprint(args["query"]), toInt(args["limit"])); ok {
+		return &framework.ToolResult{Success: true, Data: map[string]interface{}{"results": hits, "source": "embeddings"}}, nil
+	}
+	query := strings.ToLower(fmt.Sprint(args["query"]))
+	var hits []map[string]interface{}
 	err := filepath.Walk(t.BasePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -238,6 +312,38 @@ func (t *SemanticSearchTool) Execute(ctx context.Context, state *framework.Conte
 	}
 	return &framework.ToolResult{Success: true, Data: map[string]interface{}{"results": hits}}, nil
 }
+
+// searchEmbeddings queries the embeddings index and reports false when it
+// is unavailable, so Execute can fall back to substring matching.
+func (t *SemanticSearchTool) searchEmbeddings(ctx context.Context, query string, limit int) ([]map[string]interface{}, bool) {
+	if t.Index == nil {
+		return nil, false
+	}
+	if limit <= 0 {
+		limit = semanticSearchLimit
+	}
+	matches, err := t.Index.Search(ctx, query, limit)
+	if err != nil || len(matches) == 0 {
+		return nil, false
+	}
+	hits := make([]map[string]interface{}, 0, len(matches))
+	for _, match := range matches {
+		if t.manager != nil {
+			if err := t.manager.CheckFileAccess(ctx, t.agentID, framework.FileSystemRead, match.Path); err != nil {
+				continue
+			}
+		}
+		hits = append(hits, map[string]interface{}{
+			"file":       match.Path,
+			"start_line": match.StartLine,
+			"end_line":   match.EndLine,
+			"score":      match.Score,
+			"snippet":    match.Content,
+		})
+	}
+	return hits, true
+}
+
 func (t *SemanticSearchTool) IsAvailable(ctx context.Context, state *framework.Context) bool {
 	return true
 }
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+	"github.com/lexcodex/relurpify/framework/ast"
+)
+
+// keywordEmbedder embeds text as counts of a few fixed words.
+type keywordEmbedder []string
+
+func (e keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		for _, word := range e {
+			vectors[i] = append(vectors[i], float32(strings.Count(text, word)))
+		}
+	}
+	return vectors, nil
+}
+
+func TestSemanticSearchRanksEmbeddedChunks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := ast.NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cache.go"), []byte("package ws\n\n// evict drops stale cache entries\nfunc evict() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "http.go"), []byte("package ws\n\n// serve answers http requests\nfunc serve() {}\n"), 0o644))
+	index := ast.NewEmbeddingIndex(store, keywordEmbedder{"cache", "http"}, ast.EmbeddingConfig{WorkspacePath: dir, Model: "test"})
+	require.NoError(t, index.IndexWorkspace(context.Background()))
+
+	tool := &SemanticSearchTool{BasePath: dir, Index: index}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"query": "cache", "limit": 1})
+	require.NoError(t, err)
+	require.Equal(t, "embeddings", res.Data["source"])
+	hits := res.Data["results"].([]map[string]interface{})
+	require.Len(t, hits, 1)
+	require.Equal(t, filepath.Join(dir, "cache.go"), hits[0]["file"])
+	require.Equal(t, 1, hits[0]["start_line"])
+}
+
+func TestSemanticSearchFallsBackWithoutIndex(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cache.go"), []byte("package ws // cache\n"), 0o644))
+	tool := &SemanticSearchTool{BasePath: dir}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"query": "cache"})
+	require.NoError(t, err)
+	require.Nil(t, res.Data["source"])
+	require.Len(t, res.Data["results"], 1)
+}
+
+func TestSimilarityToolRanksEmbeddedChunksUnderDirectory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := ast.NewSQLiteStore(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "store"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store", "cache.go"), []byte("package store\n\n// evict drops stale cache entries\nfunc evict() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lru.go"), []byte("package ws\n\n// cache cache cache\nfunc lru() {}\n"), 0o644))
+	index := ast.NewEmbeddingIndex(store, keywordEmbedder{"cache", "http"}, ast.EmbeddingConfig{WorkspacePath: dir, Model: "test"})
+	require.NoError(t, index.IndexWorkspace(context.Background()))
+
+	tool := &SimilarityTool{BasePath: dir, Index: index}
+	res, err := tool.Execute(context.Background(), framework.NewContext(), map[string]interface{}{"snippet": "cache := newCache()", "directory": "store"})
+	require.NoError(t, err)
+	require.Equal(t, "embeddings", res.Data["source"])
+	matches := res.Data["matches"].([]similarMatch)
+	require.Len(t, matches, 1)
+	require.Equal(t, filepath.Join(dir, "store", "cache.go"), matches[0].File)
+	require.Equal(t, 1, matches[0].StartLine)
+	require.Contains(t, matches[0].Fragment, "evict")
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+// TaskBudgetFiles is a framework.ToolHook that charges the files each
+// successful edit or delete changes to the task's budget, so
+// max_files_modified stops a task once it has touched too many files. Tasks
+// run without a budget are not tracked.
+type TaskBudgetFiles struct {
+	BasePath string
+}
+
+// BeforeTool implements framework.ToolHook.
+func (h *TaskBudgetFiles) BeforeTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}) {
+}
+
+// AfterTool charges the files a successful call changed.
+func (h *TaskBudgetFiles) AfterTool(ctx context.Context, state *framework.Context, tool framework.Tool, args map[string]interface{}, result *framework.ToolResult, err error) {
+	tracker := framework.TaskBudgetFrom(ctx)
+	if h == nil || tracker == nil || err != nil || result == nil || !result.Success {
+		return
+	}
+	files := editedFiles(h.BasePath, tool.Name(), args, result)
+	if tool.Name() == "file_delete" {
+		if path, ok := args["path"].(string); ok && path != "" {
+			files = append(files, preparePath(h.BasePath, path))
+		}
+	}
+	tracker.ChargeFiles(files...)
+}
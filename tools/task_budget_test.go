@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lexcodex/relurpify/framework"
+)
+
+func TestTaskBudgetFilesStopsEditsPastTheLimit(t *testing.T) {
+	dir := t.TempDir()
+	registry := framework.NewToolRegistry()
+	require.NoError(t, registry.Register(&WriteFileTool{BasePath: dir}))
+	registry.UseToolHook(&TaskBudgetFiles{BasePath: dir})
+	ctx, tracker, stop := framework.WithTaskBudget(context.Background(), framework.TaskBudget{MaxFilesModified: 1})
+	defer stop()
+	tool, ok := registry.Get("file_write")
+	require.True(t, ok)
+	write := func(path string) error {
+		_, err := tool.Execute(ctx, framework.NewContext(), map[string]interface{}{"path": path, "content": "x\n"})
+		return err
+	}
+
+	require.NoError(t, write("a.txt"))
+	require.NoError(t, write("a.txt"), "rewriting a file does not count twice")
+	require.Nil(t, tracker.Exceeded())
+
+	require.NoError(t, write("b.txt"))
+	exceeded := tracker.Exceeded()
+	require.NotNil(t, exceeded)
+	require.Equal(t, framework.BudgetLimitFilesModified, exceeded.Limit)
+	require.EqualValues(t, 2, exceeded.Used)
+
+	err := write("c.txt")
+	var budgetErr *framework.BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr), "got %v", err)
+	_, statErr := os.Stat(filepath.Join(dir, "c.txt"))
+	require.True(t, os.IsNotExist(statErr), "no tool runs once the budget is exceeded")
+}
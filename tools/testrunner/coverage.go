@@ -0,0 +1,174 @@
+package testrunner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoverageBlock is a run of statements that executed together: one block of
+// a Go cover profile, or one line of a coverage.py report.
+type CoverageBlock struct {
+	StartLine  int  `json:"start_line"`
+	EndLine    int  `json:"end_line"`
+	Statements int  `json:"statements"`
+	Covered    bool `json:"covered"`
+}
+
+// Coverage maps workspace-relative files, slash separated, to their blocks.
+type Coverage map[string][]CoverageBlock
+
+// Statements counts the covered and total statements in every file.
+func (c Coverage) Statements() (covered, total int) {
+	for _, blocks := range c {
+		for _, block := range blocks {
+			total += block.Statements
+			if block.Covered {
+				covered += block.Statements
+			}
+		}
+	}
+	return covered, total
+}
+
+// Percent is the statement coverage of every file, 0 without statements.
+func (c Coverage) Percent() float64 {
+	covered, total := c.Statements()
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// Range counts the covered and total statements of blocks in file that
+// start within the inclusive line range.
+func (c Coverage) Range(file string, start, end int) (covered, total int) {
+	for _, block := range c[file] {
+		if block.StartLine < start || block.StartLine > end {
+			continue
+		}
+		total += block.Statements
+		if block.Covered {
+			covered += block.Statements
+		}
+	}
+	return covered, total
+}
+
+// Files lists the files with coverage data in sorted order.
+func (c Coverage) Files() []string {
+	files := make([]string, 0, len(c))
+	for file := range c {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// ParseGoCoverProfile reads the profile `go test -coverprofile` writes.
+// Profile paths are import paths; each is mapped to the file under root it
+// names, found by dropping leading path elements until one exists. Blocks
+// reported by several packages count as covered when any run covered them.
+func ParseGoCoverProfile(data []byte, root string) (Coverage, error) {
+	coverage := make(Coverage)
+	type key struct {
+		file         string
+		start, end   int
+		startC, endC string
+	}
+	index := make(map[key]int)
+	resolved := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// name.go:start.col,end.col statements count
+		colon := strings.LastIndex(line, ":")
+		fields := strings.Fields(line[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return nil, fmt.Errorf("malformed cover profile line %q", line)
+		}
+		span := strings.SplitN(fields[0], ",", 2)
+		if len(span) != 2 {
+			return nil, fmt.Errorf("malformed cover profile line %q", line)
+		}
+		startLine, startCol, _ := strings.Cut(span[0], ".")
+		endLine, endCol, _ := strings.Cut(span[1], ".")
+		start, err1 := strconv.Atoi(startLine)
+		end, err2 := strconv.Atoi(endLine)
+		statements, err3 := strconv.Atoi(fields[1])
+		count, err4 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			return nil, fmt.Errorf("malformed cover profile line %q", line)
+		}
+		name := line[:colon]
+		file, ok := resolved[name]
+		if !ok {
+			file = resolveProfilePath(root, name)
+			resolved[name] = file
+		}
+		k := key{file: file, start: start, end: end, startC: startCol, endC: endCol}
+		if i, seen := index[k]; seen {
+			coverage[file][i].Covered = coverage[file][i].Covered || count > 0
+			continue
+		}
+		index[k] = len(coverage[file])
+		coverage[file] = append(coverage[file], CoverageBlock{StartLine: start, EndLine: end, Statements: statements, Covered: count > 0})
+	}
+	return coverage, scanner.Err()
+}
+
+// resolveProfilePath finds the file under root that the import path names,
+// keeping the path as is when none exists.
+func resolveProfilePath(root, name string) string {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	for i := range parts {
+		candidate := strings.Join(parts[i:], "/")
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(candidate))); err == nil {
+			return candidate
+		}
+	}
+	return filepath.ToSlash(name)
+}
+
+// ParseCoveragePyJSON reads the report `coverage json` (or pytest-cov's
+// --cov-report=json) writes. Each executed or missing line is a
+// one-statement block.
+func ParseCoveragePyJSON(data []byte, root string) (Coverage, error) {
+	var report struct {
+		Files map[string]struct {
+			ExecutedLines []int `json:"executed_lines"`
+			MissingLines  []int `json:"missing_lines"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("coverage.py report: %w", err)
+	}
+	coverage := make(Coverage)
+	for name, file := range report.Files {
+		path := filepath.ToSlash(name)
+		if filepath.IsAbs(name) && root != "" {
+			if rel, err := filepath.Rel(root, name); err == nil && !strings.HasPrefix(rel, "..") {
+				path = filepath.ToSlash(rel)
+			}
+		}
+		var blocks []CoverageBlock
+		for _, line := range file.ExecutedLines {
+			blocks = append(blocks, CoverageBlock{StartLine: line, EndLine: line, Statements: 1, Covered: true})
+		}
+		for _, line := range file.MissingLines {
+			blocks = append(blocks, CoverageBlock{StartLine: line, EndLine: line, Statements: 1})
+		}
+		sort.Slice(blocks, func(i, j int) bool { return blocks[i].StartLine < blocks[j].StartLine })
+		coverage[path] = blocks
+	}
+	return coverage, nil
+}
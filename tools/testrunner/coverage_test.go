@@ -0,0 +1,49 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseGoCoverProfileResolvesImportPaths maps import paths onto the
+// workspace and merges blocks several packages report.
+func TestParseGoCoverProfileResolvesImportPaths(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "calc"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "calc", "calc.go"), []byte("package calc\n"), 0o644))
+	profile := `mode: set
+example.com/acme/calc/calc.go:3.20,5.2 2 1
+example.com/acme/calc/calc.go:7.20,9.16 1 0
+example.com/acme/calc/calc.go:9.16,11.3 1 0
+example.com/acme/calc/calc.go:7.20,9.16 1 1
+`
+	coverage, err := ParseGoCoverProfile([]byte(profile), root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"calc/calc.go"}, coverage.Files())
+	covered, total := coverage.Statements()
+	assert.Equal(t, 3, covered)
+	assert.Equal(t, 4, total)
+	covered, total = coverage.Range("calc/calc.go", 7, 11)
+	assert.Equal(t, 1, covered)
+	assert.Equal(t, 2, total)
+
+	_, err = ParseGoCoverProfile([]byte("calc.go:bad"), root)
+	assert.Error(t, err)
+}
+
+func TestParseCoveragePyJSON(t *testing.T) {
+	root := t.TempDir()
+	report := `{"files":{"` + filepath.Join(root, "pkg", "mod.py") + `":{"executed_lines":[1,2],"missing_lines":[4]}}}`
+	coverage, err := ParseCoveragePyJSON([]byte(report), root)
+	require.NoError(t, err)
+	assert.Equal(t, []CoverageBlock{
+		{StartLine: 1, EndLine: 1, Statements: 1, Covered: true},
+		{StartLine: 2, EndLine: 2, Statements: 1, Covered: true},
+		{StartLine: 4, EndLine: 4, Statements: 1},
+	}, coverage["pkg/mod.py"])
+	assert.InDelta(t, 66.67, coverage.Percent(), 0.01)
+}
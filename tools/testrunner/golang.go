@@ -0,0 +1,143 @@
+package testrunner
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Go runs `go test -json` and reads its event stream.
+var Go = Toolchain{
+	Language:     "go",
+	Markers:      []string{"go.mod", "go.work"},
+	Command:      goCommand,
+	Parse:        parseGoTest,
+	ReadCoverage: ParseGoCoverProfile,
+}
+
+func goCommand(req Request) []string {
+	args := []string{"go", "test", "-json"}
+	if req.Coverage {
+		args = append(args, "-cover")
+	}
+	if req.CoverProfile != "" {
+		args = append(args, "-coverprofile="+req.CoverProfile)
+	}
+	if req.Name != "" {
+		args = append(args, "-run", req.Name)
+	}
+	return append(args, goPackage(req.Target))
+}
+
+// goPackage turns a target into a package pattern: files select their
+// directory, and workspace-relative directories gain the "./" go requires.
+func goPackage(target string) string {
+	target = filepath.ToSlash(strings.TrimSpace(target))
+	if target == "" {
+		return "./..."
+	}
+	if strings.HasSuffix(target, ".go") {
+		target = filepath.ToSlash(filepath.Dir(target))
+	}
+	if target == "." || strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") || strings.HasPrefix(target, "/") {
+		return target
+	}
+	// Import paths start with a domain; anything else is a directory.
+	if first := strings.SplitN(target, "/", 2)[0]; strings.Contains(first, ".") {
+		return target
+	}
+	return "./" + target
+}
+
+type goTestEvent struct {
+	Action     string
+	Package    string
+	Test       string
+	Output     string
+	ImportPath string
+}
+
+var goCoverage = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+
+func parseGoTest(output string) Report {
+	var report Report
+	testOutput := make(map[string]*strings.Builder)
+	pkgOutput := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	var plain strings.Builder
+	appendTo := func(m map[string]*strings.Builder, key, text string) {
+		b, ok := m[key]
+		if !ok {
+			b = &strings.Builder{}
+			m[key] = b
+		}
+		b.WriteString(text)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		var ev goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			// Build errors are printed outside the event stream.
+			if strings.TrimSpace(line) != "" {
+				plain.WriteString(line + "\n")
+			}
+			continue
+		}
+		report.Parsed = true
+		// Subtests are reported with their top-level test.
+		top := strings.SplitN(ev.Test, "/", 2)[0]
+		switch ev.Action {
+		case "output":
+			if m := goCoverage.FindStringSubmatch(ev.Output); m != nil && ev.Test == "" {
+				if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+					if report.Coverage == nil {
+						report.Coverage = make(map[string]float64)
+					}
+					report.Coverage[ev.Package] = pct
+				}
+			}
+			if ev.Test != "" {
+				appendTo(testOutput, ev.Package+" "+top, ev.Output)
+			} else {
+				appendTo(pkgOutput, ev.Package, ev.Output)
+			}
+		case "build-output":
+			appendTo(pkgOutput, ev.ImportPath, ev.Output)
+		case "pass", "fail", "skip":
+			if ev.Test == "" {
+				if ev.Action == "fail" && !failedTests[ev.Package] {
+					// The package failed without a failing test: a build
+					// error, a panic in init, or TestMain exiting non-zero.
+					out := ""
+					if b := pkgOutput[ev.Package]; b != nil {
+						out = b.String()
+					}
+					report.Failures = append(report.Failures, Failure{Name: "(package)", Package: ev.Package, Output: tailLines(out, maxFailureLines)})
+				}
+				continue
+			}
+			if top != ev.Test {
+				continue
+			}
+			switch ev.Action {
+			case "pass":
+				report.Passed++
+			case "skip":
+				report.Skipped++
+			case "fail":
+				report.Failed++
+				failedTests[ev.Package] = true
+				out := ""
+				if b := testOutput[ev.Package+" "+top]; b != nil {
+					out = b.String()
+				}
+				report.Failures = append(report.Failures, Failure{Name: ev.Test, Package: ev.Package, Output: tailLines(out, maxFailureLines)})
+			}
+		}
+	}
+	if !report.Parsed && plain.Len() > 0 {
+		report.Failures = append(report.Failures, Failure{Name: "(build)", Output: tailLines(plain.String(), maxFailureLines)})
+	}
+	return report
+}
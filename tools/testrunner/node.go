@@ -0,0 +1,108 @@
+package testrunner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Node runs the package's test script. The summary formats of jest, vitest,
+// and mocha are recognized.
+var Node = Toolchain{
+	Language: "node",
+	Markers:  []string{"package.json"},
+	Command:  npmCommand,
+	Parse:    parseNodeTest,
+}
+
+func npmCommand(req Request) []string {
+	args := []string{"npm", "test", "--"}
+	if target := strings.TrimSpace(req.Target); target != "" {
+		args = append(args, target)
+	}
+	if req.Name != "" {
+		args = append(args, "-t", req.Name)
+	}
+	if req.Coverage {
+		args = append(args, "--coverage")
+	}
+	return args
+}
+
+var (
+	// jest: "Tests:       1 failed, 2 skipped, 4 passed, 7 total"
+	// vitest: "      Tests  1 failed | 4 passed (5)"
+	nodeTests    = regexp.MustCompile(`^\s*Tests:?\s+(.*\d+ (?:passed|failed|skipped|todo).*)$`)
+	nodeCount    = regexp.MustCompile(`(\d+) (passed|failed|skipped|todo)`)
+	mochaCount   = regexp.MustCompile(`^\s*(\d+) (passing|failing|pending)`)
+	jestFailure  = regexp.MustCompile(`^\s*● (.+)$`)
+	mochaFailure = regexp.MustCompile(`^\s*\d+\) (.+?):?$`)
+	vitestFail   = regexp.MustCompile(`^\s*(?:FAIL|×)\s+(.+)$`)
+	jestCoverage = regexp.MustCompile(`^All files\s*\|\s*([\d.]+)`)
+)
+
+func parseNodeTest(output string) Report {
+	var report Report
+	seen := make(map[string]bool)
+	addFailure := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			report.Failures = append(report.Failures, Failure{Name: name})
+		}
+	}
+	mochaFailing := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := nodeTests.FindStringSubmatch(line); m != nil {
+			report.Parsed = true
+			for _, c := range nodeCount.FindAllStringSubmatch(m[1], -1) {
+				n, _ := strconv.Atoi(c[1])
+				switch c[2] {
+				case "passed":
+					report.Passed += n
+				case "failed":
+					report.Failed += n
+				default:
+					report.Skipped += n
+				}
+			}
+			continue
+		}
+		if m := mochaCount.FindStringSubmatch(line); m != nil {
+			report.Parsed = true
+			n, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "passing":
+				report.Passed += n
+			case "failing":
+				report.Failed += n
+				mochaFailing = true
+			default:
+				report.Skipped += n
+			}
+			continue
+		}
+		if m := jestCoverage.FindStringSubmatch(line); m != nil {
+			if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+				report.Coverage = map[string]float64{"total": pct}
+			}
+			continue
+		}
+		if m := jestFailure.FindStringSubmatch(line); m != nil {
+			// "● Test suite failed to run" is reported once per suite.
+			addFailure(m[1])
+			continue
+		}
+		if m := vitestFail.FindStringSubmatch(line); m != nil {
+			addFailure(m[1])
+			continue
+		}
+		if mochaFailing {
+			if m := mochaFailure.FindStringSubmatch(line); m != nil {
+				addFailure(m[1])
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,85 @@
+package testrunner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Python runs pytest in quiet mode with a short summary of failures and
+// errors.
+var Python = Toolchain{
+	Language:     "python",
+	Markers:      []string{"pyproject.toml", "setup.py", "setup.cfg", "pytest.ini", "tox.ini", "requirements.txt"},
+	Command:      pytestCommand,
+	Parse:        parsePytest,
+	ReadCoverage: ParseCoveragePyJSON,
+}
+
+func pytestCommand(req Request) []string {
+	args := []string{"pytest", "-q", "-rfE"}
+	if req.Coverage {
+		// Requires pytest-cov.
+		args = append(args, "--cov", "--cov-report=term")
+	}
+	if req.CoverProfile != "" {
+		if !req.Coverage {
+			args = append(args, "--cov")
+		}
+		args = append(args, "--cov-report=json:"+req.CoverProfile)
+	}
+	if req.Name != "" {
+		args = append(args, "-k", req.Name)
+	}
+	if target := strings.TrimSpace(req.Target); target != "" {
+		args = append(args, target)
+	}
+	return args
+}
+
+var (
+	pytestCount   = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?|xfailed|xpassed)`)
+	pytestSummary = regexp.MustCompile(`\d+ (passed|failed|skipped|errors?|deselected|xfailed|xpassed).* in [\d.]+s`)
+	pytestFailure = regexp.MustCompile(`^(FAILED|ERROR) (\S+)(?: - (.*))?$`)
+	pytestTotal   = regexp.MustCompile(`^TOTAL\s.*?(\d+(?:\.\d+)?)%\s*$`)
+)
+
+func parsePytest(output string) Report {
+	var report Report
+	lines := strings.Split(output, "\n")
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if m := pytestFailure.FindStringSubmatch(line); m != nil {
+			report.Failures = append(report.Failures, Failure{Name: m[2], Output: m[3]})
+			continue
+		}
+		if m := pytestTotal.FindStringSubmatch(line); m != nil {
+			if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+				report.Coverage = map[string]float64{"total": pct}
+			}
+		}
+	}
+	// The last summary line ("2 failed, 10 passed in 0.31s") has the totals.
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !pytestSummary.MatchString(lines[i]) {
+			continue
+		}
+		report.Parsed = true
+		for _, m := range pytestCount.FindAllStringSubmatch(lines[i], -1) {
+			n, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "passed", "xpassed":
+				report.Passed += n
+			case "failed", "error", "errors":
+				report.Failed += n
+			case "skipped", "xfailed":
+				report.Skipped += n
+			}
+		}
+		break
+	}
+	if strings.Contains(output, "no tests ran") {
+		report.Parsed = true
+	}
+	return report
+}
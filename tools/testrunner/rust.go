@@ -0,0 +1,97 @@
+package testrunner
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Rust runs cargo test. Tests keep running after the first failing binary so
+// one run reports every failure.
+var Rust = Toolchain{
+	Language: "rust",
+	Markers:  []string{"Cargo.toml"},
+	Command:  cargoCommand,
+	Parse:    parseCargoTest,
+}
+
+// cargoCommand maps a file under tests/ to its integration test target and
+// any other target to a package name.
+func cargoCommand(req Request) []string {
+	args := []string{"cargo", "test", "--no-fail-fast"}
+	target := filepath.ToSlash(strings.TrimSpace(req.Target))
+	switch {
+	case target == "":
+	case strings.HasSuffix(target, ".rs") && strings.Contains("/"+target, "/tests/"):
+		args = append(args, "--test", strings.TrimSuffix(filepath.Base(target), ".rs"))
+	case strings.HasSuffix(target, ".rs"):
+		// Unit tests are named by module path, so src/parser/lexer.rs
+		// filters on "parser::lexer". Crate roots run everything.
+		module := strings.TrimSuffix(strings.TrimPrefix(target, "src/"), ".rs")
+		module = strings.TrimSuffix(module, "/mod")
+		if req.Name == "" && module != "lib" && module != "main" {
+			req.Name = strings.ReplaceAll(module, "/", "::")
+		}
+	default:
+		args = append(args, "-p", target)
+	}
+	if req.Name != "" {
+		args = append(args, req.Name)
+	}
+	return args
+}
+
+var (
+	cargoTest   = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	cargoStdout = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+)
+
+func parseCargoTest(output string) Report {
+	var report Report
+	failed := make(map[string]int)
+	var current string
+	var captured strings.Builder
+	flush := func() {
+		if current != "" {
+			if idx, ok := failed[current]; ok {
+				report.Failures[idx].Output = tailLines(captured.String(), maxFailureLines)
+			}
+		}
+		current = ""
+		captured.Reset()
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := cargoTest.FindStringSubmatch(line); m != nil {
+			report.Parsed = true
+			switch m[2] {
+			case "ok":
+				report.Passed++
+			case "ignored":
+				report.Skipped++
+			case "FAILED":
+				report.Failed++
+				failed[m[1]] = len(report.Failures)
+				report.Failures = append(report.Failures, Failure{Name: m[1]})
+			}
+			continue
+		}
+		if m := cargoStdout.FindStringSubmatch(line); m != nil {
+			flush()
+			current = m[1]
+			continue
+		}
+		if current != "" {
+			if line == "failures:" || strings.HasPrefix(line, "test result:") {
+				flush()
+				continue
+			}
+			captured.WriteString(line + "\n")
+		}
+	}
+	flush()
+	if !report.Parsed && strings.Contains(output, "error[") {
+		report.Failures = append(report.Failures, Failure{Name: "(build)", Output: tailLines(output, maxFailureLines)})
+	}
+	return report
+}
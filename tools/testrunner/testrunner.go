@@ -0,0 +1,112 @@
+// Package testrunner maps workspace languages to their test commands and
+// parses test output into counts, failures, and coverage. Each Toolchain
+// knows how to narrow a run to one package or file, so agents can re-run just
+// the tests touching what they changed.
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Request narrows a test run. The zero value runs every test.
+type Request struct {
+	// Target is a package, directory, or file relative to the workspace.
+	Target string
+	// Name filters tests by name, using the toolchain's own matching.
+	Name string
+	// Coverage asks the toolchain to report coverage.
+	Coverage bool
+	// CoverProfile, when set, asks the toolchain to also write a
+	// per-line coverage report there for ReadCoverage.
+	CoverProfile string
+}
+
+// Failure is one failing test, or a package that failed to build.
+type Failure struct {
+	Name    string `json:"name"`
+	Package string `json:"package,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+// Report summarizes a test run.
+type Report struct {
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	Skipped  int       `json:"skipped"`
+	Failures []Failure `json:"failures,omitempty"`
+	// Coverage maps a package (or "total") to its statement coverage in
+	// percent. It is empty unless coverage was requested and reported.
+	Coverage map[string]float64 `json:"coverage,omitempty"`
+	// Parsed is false when the output did not contain anything the parser
+	// recognized, e.g. because the runner crashed before running tests.
+	Parsed bool `json:"parsed"`
+}
+
+// Toolchain runs one language's tests.
+type Toolchain struct {
+	Language string
+	// Markers are files whose presence in the workspace root selects the
+	// toolchain.
+	Markers []string
+	// Command builds the command line for req.
+	Command func(req Request) []string
+	// Parse reads the combined output of Command.
+	Parse func(output string) Report
+	// ReadCoverage reads the report written to Request.CoverProfile, with
+	// paths relative to the workspace root. Nil when the toolchain cannot
+	// write one.
+	ReadCoverage func(data []byte, root string) (Coverage, error)
+}
+
+// Toolchains lists the supported toolchains in detection order.
+var Toolchains = []Toolchain{Go, Python, Rust, Node}
+
+// Detect returns the toolchains whose markers exist in root.
+func Detect(root string) []Toolchain {
+	var found []Toolchain
+	for _, tc := range Toolchains {
+		for _, marker := range tc.Markers {
+			if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+				found = append(found, tc)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Lookup returns the toolchain for language.
+func Lookup(language string) (Toolchain, bool) {
+	for _, tc := range Toolchains {
+		if strings.EqualFold(tc.Language, language) {
+			return tc, true
+		}
+	}
+	return Toolchain{}, false
+}
+
+// Languages lists the language names of toolchains.
+func Languages(toolchains []Toolchain) []string {
+	names := make([]string, len(toolchains))
+	for i, tc := range toolchains {
+		names[i] = tc.Language
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxFailureLines bounds the output kept per failure so a panicking test
+// does not flood the agent's context.
+const maxFailureLines = 40
+
+func tailLines(text string, n int) string {
+	text = strings.TrimRight(text, "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = append([]string{"..."}, lines[len(lines)-n:]...)
+	}
+	return strings.Join(lines, "\n")
+}
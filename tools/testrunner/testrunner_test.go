@@ -0,0 +1,152 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectUsesMarkerFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, Detect(dir))
+	for _, marker := range []string{"go.mod", "package.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, marker), []byte("{}"), 0o644))
+	}
+	assert.Equal(t, []string{"go", "node"}, Languages(Detect(dir)))
+
+	tc, ok := Lookup("Python")
+	require.True(t, ok)
+	assert.Equal(t, "python", tc.Language)
+}
+
+func TestCommandsTargetPackagesAndFiles(t *testing.T) {
+	for name, tc := range map[string]struct {
+		toolchain Toolchain
+		req       Request
+		want      []string
+	}{
+		"go everything":     {Go, Request{}, []string{"go", "test", "-json", "./..."}},
+		"go file":           {Go, Request{Target: "tools/lsp.go", Name: "TestProxy", Coverage: true}, []string{"go", "test", "-json", "-cover", "-run", "TestProxy", "./tools"}},
+		"go import path":    {Go, Request{Target: "github.com/x/y/z"}, []string{"go", "test", "-json", "github.com/x/y/z"}},
+		"go root file":      {Go, Request{Target: "main_test.go"}, []string{"go", "test", "-json", "."}},
+		"pytest node":       {Python, Request{Target: "tests/test_api.py::test_get", Name: "get"}, []string{"pytest", "-q", "-rfE", "-k", "get", "tests/test_api.py::test_get"}},
+		"cargo integration": {Rust, Request{Target: "tests/parse.rs"}, []string{"cargo", "test", "--no-fail-fast", "--test", "parse"}},
+		"cargo module":      {Rust, Request{Target: "src/parser/lexer.rs"}, []string{"cargo", "test", "--no-fail-fast", "parser::lexer"}},
+		"cargo package":     {Rust, Request{Target: "core"}, []string{"cargo", "test", "--no-fail-fast", "-p", "core"}},
+		"npm file":          {Node, Request{Target: "src/app.test.ts", Name: "renders"}, []string{"npm", "test", "--", "src/app.test.ts", "-t", "renders"}},
+	} {
+		assert.Equal(t, tc.want, tc.toolchain.Command(tc.req), name)
+	}
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	output := `{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK"}
+{"Action":"run","Package":"example.com/a","Test":"TestBad"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad/sub","Output":"    a_test.go:9: want 1, got 2\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestBad/sub"}
+{"Action":"fail","Package":"example.com/a","Test":"TestBad"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/a","Output":"coverage: 71.4% of statements\n"}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"output","Package":"example.com/b","Output":"b/b.go:3:1: syntax error\n"}
+{"Action":"fail","Package":"example.com/b"}
+`
+	report := parseGoTest(output)
+	assert.True(t, report.Parsed)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed, "subtests count with their parent")
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, map[string]float64{"example.com/a": 71.4}, report.Coverage)
+	require.Len(t, report.Failures, 2)
+	assert.Equal(t, Failure{Name: "TestBad", Package: "example.com/a", Output: "    a_test.go:9: want 1, got 2"}, report.Failures[0])
+	assert.Equal(t, "(package)", report.Failures[1].Name)
+	assert.Contains(t, report.Failures[1].Output, "syntax error")
+}
+
+func TestParsePytest(t *testing.T) {
+	output := `..F.s
+=========================== short test summary info ============================
+FAILED tests/test_api.py::test_get - AssertionError: assert 404 == 200
+ERROR tests/test_db.py::test_conn
+TOTAL                               120     18    85%
+2 failed, 3 passed, 1 skipped in 0.42s
+`
+	report := parsePytest(output)
+	assert.True(t, report.Parsed)
+	assert.Equal(t, Report{
+		Passed:  3,
+		Failed:  2,
+		Skipped: 1,
+		Failures: []Failure{
+			{Name: "tests/test_api.py::test_get", Output: "AssertionError: assert 404 == 200"},
+			{Name: "tests/test_db.py::test_conn"},
+		},
+		Coverage: map[string]float64{"total": 85},
+		Parsed:   true,
+	}, report)
+}
+
+func TestParseCargoTest(t *testing.T) {
+	output := `running 3 tests
+test lexer::tests::ident ... ok
+test lexer::tests::number ... FAILED
+test lexer::tests::slow ... ignored
+
+failures:
+
+---- lexer::tests::number stdout ----
+thread 'lexer::tests::number' panicked at src/lexer.rs:40:9:
+assertion failed: tok.is_number()
+
+failures:
+    lexer::tests::number
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out
+`
+	report := parseCargoTest(output)
+	assert.True(t, report.Parsed)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, "lexer::tests::number", report.Failures[0].Name)
+	assert.Contains(t, report.Failures[0].Output, "assertion failed")
+}
+
+func TestParseNodeTestFormats(t *testing.T) {
+	jest := `FAIL src/app.test.ts
+  ● App › renders title
+
+Tests:       1 failed, 1 skipped, 4 passed, 6 total
+All files |   82.5 |    70 |   90 |   82.5 |
+`
+	report := parseNodeTest(jest)
+	assert.True(t, report.Parsed)
+	assert.Equal(t, 4, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, map[string]float64{"total": 82.5}, report.Coverage)
+	assert.Contains(t, report.Failures, Failure{Name: "App › renders title"})
+
+	mocha := `
+  5 passing (12ms)
+  1 failing
+
+  1) Parser
+       handles empty input:
+`
+	report = parseNodeTest(mocha)
+	assert.Equal(t, 5, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+
+	vitest := "      Tests  2 failed | 8 passed (10)\n"
+	report = parseNodeTest(vitest)
+	assert.Equal(t, 8, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+
+	assert.False(t, parseNodeTest("npm ERR! missing script: test\n").Parsed)
+}